@@ -82,9 +82,9 @@ func TestAUTH003_InvalidTokenFormat(t *testing.T) {
 		return
 	}
 
-	// Accept AUTH_INVALID, AUTH_REQUIRED, or AUTH_INVALID_TOKEN
-	if dbErr.Code != "AUTH_INVALID" && dbErr.Code != "AUTH_REQUIRED" && dbErr.Code != "AUTH_INVALID_TOKEN" {
-		t.Errorf("Expected AUTH_INVALID, AUTH_REQUIRED, or AUTH_INVALID_TOKEN, got %s", dbErr.Code)
+	// Accept AUTH_INVALID, AUTH_REQUIRED, AUTH_INVALID_TOKEN (older servers) or TOKEN_MALFORMED
+	if dbErr.Code != "AUTH_INVALID" && dbErr.Code != "AUTH_REQUIRED" && dbErr.Code != "AUTH_INVALID_TOKEN" && dbErr.Code != "TOKEN_MALFORMED" {
+		t.Errorf("Expected AUTH_INVALID, AUTH_REQUIRED, AUTH_INVALID_TOKEN or TOKEN_MALFORMED, got %s", dbErr.Code)
 	}
 }
 
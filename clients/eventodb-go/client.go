@@ -381,6 +381,44 @@ func (c *Client) SystemHealth(ctx context.Context) (*HealthStatus, error) {
 	return &health, nil
 }
 
+// SystemParseStreamName asks the server how it interprets a stream name and
+// whether stream.write would accept it
+func (c *Client) SystemParseStreamName(ctx context.Context, streamName string) (*StreamNameParts, error) {
+	result, err := c.rpc(ctx, "sys.parseStreamName", streamName)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts StreamNameParts
+	if err := json.Unmarshal(result, &parts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stream name parts: %w", err)
+	}
+
+	return &parts, nil
+}
+
+// SystemProfile captures a CPU profile, heap snapshot or goroutine dump on the
+// server. It requires the default namespace token. CPU profiles block for
+// their duration, so use WithHTTPClient with a longer timeout for profiles
+// close to 30 seconds.
+func (c *Client) SystemProfile(ctx context.Context, opts *ProfileOptions) (*Profile, error) {
+	if opts == nil {
+		opts = &ProfileOptions{}
+	}
+
+	result, err := c.rpc(ctx, "sys.profile", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile Profile
+	if err := json.Unmarshal(result, &profile); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profile: %w", err)
+	}
+
+	return &profile, nil
+}
+
 // Helper functions for parsing message arrays
 
 func parseStreamMessage(msg *StreamMessage, raw []interface{}) error {
@@ -28,10 +28,14 @@ func (e *Error) Is(target error) bool {
 
 // Common error codes
 var (
-	ErrAuthRequired      = &Error{Code: "AUTH_REQUIRED", Message: "authentication required"}
-	ErrAuthInvalid       = &Error{Code: "AUTH_INVALID", Message: "invalid authentication"}
-	ErrNamespaceExists   = &Error{Code: "NAMESPACE_EXISTS", Message: "namespace already exists"}
-	ErrNamespaceNotFound = &Error{Code: "NAMESPACE_NOT_FOUND", Message: "namespace not found"}
-	ErrVersionConflict   = &Error{Code: "STREAM_VERSION_CONFLICT", Message: "stream version conflict"}
-	ErrInvalidRequest    = &Error{Code: "INVALID_REQUEST", Message: "invalid request"}
+	ErrAuthRequired       = &Error{Code: "AUTH_REQUIRED", Message: "authentication required"}
+	ErrAuthInvalid        = &Error{Code: "AUTH_INVALID", Message: "invalid authentication"}
+	ErrTokenMalformed     = &Error{Code: "TOKEN_MALFORMED", Message: "token cannot be parsed"}
+	ErrTokenExpired       = &Error{Code: "TOKEN_EXPIRED", Message: "token expired"}
+	ErrTokenRevoked       = &Error{Code: "TOKEN_REVOKED", Message: "token was replaced"}
+	ErrNamespaceSuspended = &Error{Code: "NAMESPACE_SUSPENDED", Message: "namespace is suspended"}
+	ErrNamespaceExists    = &Error{Code: "NAMESPACE_EXISTS", Message: "namespace already exists"}
+	ErrNamespaceNotFound  = &Error{Code: "NAMESPACE_NOT_FOUND", Message: "namespace not found"}
+	ErrVersionConflict    = &Error{Code: "STREAM_VERSION_CONFLICT", Message: "stream version conflict"}
+	ErrInvalidRequest     = &Error{Code: "INVALID_REQUEST", Message: "invalid request"}
 )
@@ -99,6 +99,9 @@ func (c *Client) SubscribeCategory(ctx context.Context, categoryName string, opt
 	if opts.ConsumerGroup != nil {
 		params["consumer"] = strconv.Itoa(opts.ConsumerGroup.Member)
 		params["size"] = strconv.Itoa(opts.ConsumerGroup.Size)
+		if opts.ConsumerGroup.Partitioner != "" {
+			params["partitioner"] = opts.ConsumerGroup.Partitioner
+		}
 	}
 
 	return c.subscribe(ctx, "/subscribe", params)
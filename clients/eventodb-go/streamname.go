@@ -0,0 +1,135 @@
+package eventodb
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxStreamNameLength is the server's default limit on stream name
+	// length in bytes
+	MaxStreamNameLength = 255
+
+	// ReservedStreamPrefix marks system streams; clients cannot write to them
+	ReservedStreamPrefix = "$"
+)
+
+// Stream name helpers. They follow the server's rules exactly, so a client
+// resolves categories and consumer group IDs the same way the server does.
+// SystemParseStreamName asks the server directly.
+
+// Category extracts the category name from a stream name
+//
+//	Category("account-123+456") → "account"
+//	Category("account") → "account"
+func Category(streamName string) string {
+	if idx := strings.IndexByte(streamName, '-'); idx >= 0 {
+		return streamName[:idx]
+	}
+	return streamName
+}
+
+// ID extracts the ID portion from a stream name
+//
+//	ID("account-123+456") → "123+456"
+//	ID("account") → ""
+func ID(streamName string) string {
+	if idx := strings.IndexByte(streamName, '-'); idx >= 0 {
+		return streamName[idx+1:]
+	}
+	return ""
+}
+
+// CardinalID extracts the cardinal ID (before '+') used for consumer group
+// partitioning
+//
+//	CardinalID("account-123+456") → "123"
+//	CardinalID("account") → ""
+func CardinalID(streamName string) string {
+	id := ID(streamName)
+	if idx := strings.IndexByte(id, '+'); idx >= 0 {
+		return id[:idx]
+	}
+	return id
+}
+
+// CompoundIDs splits the ID portion into its '+' separated parts
+//
+//	CompoundIDs("account-123+456") → ["123", "456"]
+//	CompoundIDs("account") → []
+func CompoundIDs(streamName string) []string {
+	id := ID(streamName)
+	if id == "" {
+		return []string{}
+	}
+	return strings.Split(id, "+")
+}
+
+// IsCategory reports whether a name is a category (has no ID part)
+func IsCategory(streamName string) bool {
+	return !strings.Contains(streamName, "-")
+}
+
+// ParseStreamName breaks a stream name into its parts and reports whether
+// stream.write accepts it under the server's default rules, with the reason
+// when it does not. Names that are not in Unicode normalization form C are
+// only rejected by the server; SystemParseStreamName also applies a
+// server's own limits.
+func ParseStreamName(streamName string) *StreamNameParts {
+	reason := streamNameReason(streamName)
+	return &StreamNameParts{
+		StreamName:  streamName,
+		Category:    Category(streamName),
+		ID:          ID(streamName),
+		CardinalID:  CardinalID(streamName),
+		CompoundIDs: CompoundIDs(streamName),
+		IsCategory:  IsCategory(streamName),
+		Valid:       reason == "",
+		Reason:      reason,
+	}
+}
+
+// streamNameReason returns why the server's default rules reject a stream
+// name, with the server's wording, or "" when they accept it
+func streamNameReason(streamName string) string {
+	if streamName == "" {
+		return "must not be empty"
+	}
+	if len(streamName) > MaxStreamNameLength {
+		return fmt.Sprintf("is %d bytes, longer than the limit of %d", len(streamName), MaxStreamNameLength)
+	}
+	if !utf8.ValidString(streamName) {
+		return "is not valid UTF-8"
+	}
+	for i, r := range streamName {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return fmt.Sprintf("contains whitespace or a control character at byte %d", i)
+		}
+	}
+	if strings.HasPrefix(streamName, ReservedStreamPrefix) {
+		return fmt.Sprintf("the %q prefix is reserved for system streams", ReservedStreamPrefix)
+	}
+
+	category := Category(streamName)
+	if category == "" {
+		return "category before '-' is empty"
+	}
+	if strings.ContainsRune(category, '+') {
+		return "category contains '+', which only separates compound IDs"
+	}
+	if IsCategory(streamName) {
+		return ""
+	}
+	id := ID(streamName)
+	if id == "" {
+		return "ID after '-' is empty"
+	}
+	for _, part := range strings.Split(id, "+") {
+		if part == "" {
+			return "compound ID has an empty part"
+		}
+	}
+	return ""
+}
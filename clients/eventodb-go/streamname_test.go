@@ -0,0 +1,80 @@
+package eventodb
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStreamNameHelpers(t *testing.T) {
+	tests := []struct {
+		streamName string
+		expected   StreamNameParts
+	}{
+		{"account-123", StreamNameParts{Category: "account", ID: "123", CardinalID: "123", CompoundIDs: []string{"123"}}},
+		{"account-123+456", StreamNameParts{Category: "account", ID: "123+456", CardinalID: "123", CompoundIDs: []string{"123", "456"}}},
+		{"account-prefix-123", StreamNameParts{Category: "account", ID: "prefix-123", CardinalID: "prefix-123", CompoundIDs: []string{"prefix-123"}}},
+		{"account", StreamNameParts{Category: "account", CompoundIDs: []string{}, IsCategory: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.streamName, func(t *testing.T) {
+			tt.expected.StreamName = tt.streamName
+			tt.expected.Valid = true
+			if got := ParseStreamName(tt.streamName); !reflect.DeepEqual(*got, tt.expected) {
+				t.Errorf("ParseStreamName(%s) = %+v, expected %+v", tt.streamName, *got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseStreamNameValidation(t *testing.T) {
+	tests := []struct {
+		streamName string
+		reason     string
+	}{
+		{"account-123", ""},
+		{"account", ""},
+		{"", "must not be empty"},
+		{"-123", "category before '-' is empty"},
+		{"account-", "ID after '-' is empty"},
+		{"acc+ount-123", "category contains '+', which only separates compound IDs"},
+		{"account-123+", "compound ID has an empty part"},
+		{"$system-1", `the "$" prefix is reserved for system streams`},
+		{"account 1-2", "contains whitespace or a control character at byte 7"},
+		{"account-\x00", "contains whitespace or a control character at byte 8"},
+		{"account-\xff", "is not valid UTF-8"},
+		{"account-" + strings.Repeat("x", 248), "is 256 bytes, longer than the limit of 255"},
+	}
+
+	for _, tt := range tests {
+		got := ParseStreamName(tt.streamName)
+		if got.Valid != (tt.reason == "") || got.Reason != tt.reason {
+			t.Errorf("ParseStreamName(%q) = valid %v, reason %q; expected reason %q", tt.streamName, got.Valid, got.Reason, tt.reason)
+		}
+	}
+}
+
+func TestSYS003_ParseStreamNameMatchesServer(t *testing.T) {
+	tc := setupTest(t, "sys-003")
+	ctx := context.Background()
+
+	for _, name := range []string{"account-123", "account-123+456", "account-prefix-123", "account", "account-", "-123", "acc+ount-1", "$system-1", "account 1"} {
+		parts, err := tc.client.SystemParseStreamName(ctx, name)
+		if err != nil {
+			t.Fatalf("Failed to parse stream name: %v", err)
+		}
+		if local := ParseStreamName(name); !reflect.DeepEqual(parts, local) {
+			t.Errorf("Server parsed %s as %+v, local helpers as %+v", name, parts, local)
+		}
+	}
+
+	parts, err := tc.client.SystemParseStreamName(ctx, "account-")
+	if err != nil {
+		t.Fatalf("Failed to parse stream name: %v", err)
+	}
+	if parts.Valid || parts.Reason == "" {
+		t.Errorf("Expected account- to be invalid with a reason, got %+v", parts)
+	}
+}
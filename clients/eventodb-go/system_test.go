@@ -3,6 +3,7 @@ package eventodb
 import (
 	"context"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -48,3 +49,21 @@ func TestSYS002_GetServerHealth(t *testing.T) {
 
 	t.Logf("Server health: %s", health.Status)
 }
+
+func TestSYS004_CaptureProfile(t *testing.T) {
+	ctx := context.Background()
+
+	// Profiling needs the default namespace, which test mode grants without a token
+	client := NewClient(testBaseURL)
+	profile, err := client.SystemProfile(ctx, &ProfileOptions{Type: "goroutine", Debug: 1})
+	if err != nil {
+		t.Fatalf("Failed to capture profile: %v", err)
+	}
+
+	if profile.Format != "text" || profile.Bytes != len(profile.Data) {
+		t.Errorf("Unexpected profile: format %s, %d of %d bytes", profile.Format, len(profile.Data), profile.Bytes)
+	}
+	if !strings.Contains(string(profile.Data), "goroutine") {
+		t.Error("Expected a goroutine dump")
+	}
+}
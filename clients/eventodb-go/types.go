@@ -63,8 +63,9 @@ type GetCategoryOptions struct {
 
 // ConsumerGroup for distributed consumption
 type ConsumerGroup struct {
-	Member int `json:"member"`
-	Size   int `json:"size"`
+	Member      int    `json:"member"`
+	Size        int    `json:"size"`
+	Partitioner string `json:"partitioner,omitempty"` // md5 (default), murmur3 or jump
 }
 
 // GetLastOptions configures stream.last operations
@@ -104,3 +105,31 @@ type NamespaceInfo struct {
 type HealthStatus struct {
 	Status string `json:"status"`
 }
+
+// StreamNameParts is a stream name broken down the way the server interprets it
+type StreamNameParts struct {
+	StreamName  string   `json:"streamName"`
+	Category    string   `json:"category"`
+	ID          string   `json:"id"`
+	CardinalID  string   `json:"cardinalId"`
+	CompoundIDs []string `json:"compoundIds"`
+	IsCategory  bool     `json:"isCategory"`
+	Valid       bool     `json:"valid"`            // Whether stream.write accepts the name
+	Reason      string   `json:"reason,omitempty"` // Why the name is invalid
+}
+
+// ProfileOptions configures sys.profile
+type ProfileOptions struct {
+	Type    string `json:"type,omitempty"`    // cpu (default), heap, allocs, goroutine, block, mutex, threadcreate
+	Seconds int    `json:"seconds,omitempty"` // CPU profile duration (default: 10, max: 60)
+	Debug   int    `json:"debug,omitempty"`   // 1 or 2 for text output instead of pprof
+}
+
+// Profile is a runtime profile captured by the server
+type Profile struct {
+	Type    string `json:"type"`
+	Seconds int    `json:"seconds,omitempty"` // CPU profiles only
+	Format  string `json:"format"`            // "pprof" (open with go tool pprof) or "text"
+	Bytes   int    `json:"bytes"`
+	Data    []byte `json:"data"`
+}
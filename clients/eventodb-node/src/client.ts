@@ -14,7 +14,8 @@ import type {
   CategoryMessage,
   SubscribeOptions,
   PokeEvent,
-  Subscription
+  Subscription,
+  StreamNameParts
 } from './types.js';
 
 /**
@@ -209,6 +210,13 @@ export class EventoDBClient {
     return this.rpc('sys.health');
   }
 
+  /**
+   * Ask the server how it interprets a stream name and whether stream.write accepts it
+   */
+  async systemParseStreamName(streamName: string): Promise<StreamNameParts> {
+    return this.rpc('sys.parseStreamName', streamName);
+  }
+
   /**
    * Get current authentication token
    */
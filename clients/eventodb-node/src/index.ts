@@ -1,5 +1,6 @@
 export { EventoDBClient } from './client.js';
 export { EventoDBError, NetworkError, AuthError } from './errors.js';
+export { category, id, cardinalId, compoundIds, isCategory, parseStreamName } from './stream-name.js';
 export type {
   Message,
   WriteOptions,
@@ -15,5 +16,6 @@ export type {
   CategoryMessage,
   SubscribeOptions,
   PokeEvent,
-  Subscription
+  Subscription,
+  StreamNameParts
 } from './types.js';
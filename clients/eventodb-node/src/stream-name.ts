@@ -0,0 +1,61 @@
+import type { StreamNameParts } from './types.js';
+
+// Stream name helpers. They follow the server's rules exactly, so a client
+// resolves categories and consumer group IDs the same way the server does.
+// EventoDBClient.systemParseStreamName asks the server directly.
+
+/**
+ * Category of a stream name: `category('account-123+456')` is `'account'`
+ */
+export function category(streamName: string): string {
+  const idx = streamName.indexOf('-');
+  return idx >= 0 ? streamName.slice(0, idx) : streamName;
+}
+
+/**
+ * ID portion of a stream name: `id('account-123+456')` is `'123+456'`
+ */
+export function id(streamName: string): string {
+  const idx = streamName.indexOf('-');
+  return idx >= 0 ? streamName.slice(idx + 1) : '';
+}
+
+/**
+ * Cardinal ID used for consumer group partitioning: `cardinalId('account-123+456')` is `'123'`
+ */
+export function cardinalId(streamName: string): string {
+  const streamId = id(streamName);
+  const idx = streamId.indexOf('+');
+  return idx >= 0 ? streamId.slice(0, idx) : streamId;
+}
+
+/**
+ * Parts of a compound ID: `compoundIds('account-123+456')` is `['123', '456']`
+ */
+export function compoundIds(streamName: string): string[] {
+  const streamId = id(streamName);
+  return streamId === '' ? [] : streamId.split('+');
+}
+
+/**
+ * Whether a name is a category (has no ID part)
+ */
+export function isCategory(streamName: string): boolean {
+  return !streamName.includes('-');
+}
+
+/**
+ * Break a stream name into its parts. `valid` is always true; only the
+ * server knows its validation policy.
+ */
+export function parseStreamName(streamName: string): StreamNameParts {
+  return {
+    streamName,
+    category: category(streamName),
+    id: id(streamName),
+    cardinalId: cardinalId(streamName),
+    compoundIds: compoundIds(streamName),
+    isCategory: isCategory(streamName),
+    valid: true
+  };
+}
@@ -43,6 +43,7 @@ export interface GetCategoryOptions {
   consumerGroup?: {
     member: number;
     size: number;
+    partitioner?: 'md5' | 'murmur3' | 'jump';  // Default: md5
   };
 }
 
@@ -152,3 +153,17 @@ export interface Subscription {
   on(event: 'error', handler: (error: Error) => void): void;
   on(event: 'end', handler: () => void): void;
 }
+
+/**
+ * A stream name broken down the way the server interprets it
+ */
+export interface StreamNameParts {
+  streamName: string;
+  category: string;
+  id: string;
+  cardinalId: string;
+  compoundIds: string[];
+  isCategory: boolean;
+  valid: boolean;   // Whether stream.write accepts the name
+  reason?: string;  // Why the name is invalid
+}
@@ -1,6 +1,7 @@
 import { describe, test, expect } from 'vitest';
 import { getEventoDBURL } from './helpers.js';
 import { EventoDBClient } from '../src/client.js';
+import { parseStreamName } from '../src/stream-name.js';
 
 const ADMIN_TOKEN = process.env.EVENTODB_ADMIN_TOKEN;
 
@@ -32,4 +33,18 @@ describe('SYS Tests', () => {
     expect(health.status).toBeDefined();
     expect(typeof health.status).toBe('string');
   });
+
+  test('SYS-003: Parse stream names like the server', async () => {
+    const client = new EventoDBClient(getEventoDBURL(), {
+      token: ADMIN_TOKEN
+    });
+
+    for (const name of ['account-123', 'account-123+456', 'account-prefix-123', 'account']) {
+      expect(await client.systemParseStreamName(name)).toEqual(parseStreamName(name));
+    }
+
+    const invalid = await client.systemParseStreamName('account-');
+    expect(invalid.valid).toBe(false);
+    expect(invalid.reason).toBeDefined();
+  });
 });
@@ -529,6 +529,25 @@ defmodule EventodbEx do
     end
   end
 
+  @doc """
+  Asks the server how it interprets a stream name and whether `stream_write`
+  would accept it. `EventodbEx.StreamName.parse/1` gives the same parts locally.
+
+  ## Examples
+
+      {:ok, parts, client} = EventodbEx.system_parse_stream_name(client, "account-123+456")
+      parts.cardinal_id
+      # => "123"
+
+  """
+  @spec system_parse_stream_name(Client.t(), String.t()) ::
+          {:ok, map(), Client.t()} | {:error, Error.t()}
+  def system_parse_stream_name(client, stream_name) do
+    with {:ok, result, client} <- Client.rpc(client, "sys.parseStreamName", [stream_name]) do
+      {:ok, snake_case_keys(result), client}
+    end
+  end
+
   # Private helpers
 
   defp snake_case_keys(map) when is_map(map) do
@@ -0,0 +1,89 @@
+defmodule EventodbEx.StreamName do
+  @moduledoc """
+  Stream name helpers that follow the server's rules exactly, so a client
+  resolves categories and consumer group IDs the same way the server does.
+
+  `EventodbEx.system_parse_stream_name/2` asks the server directly.
+  """
+
+  @doc """
+  Extracts the category name.
+
+      iex> EventodbEx.StreamName.category("account-123+456")
+      "account"
+
+  """
+  @spec category(String.t()) :: String.t()
+  def category(stream_name) do
+    case String.split(stream_name, "-", parts: 2) do
+      [category, _id] -> category
+      [category] -> category
+    end
+  end
+
+  @doc """
+  Extracts the ID portion.
+
+      iex> EventodbEx.StreamName.id("account-123+456")
+      "123+456"
+
+  """
+  @spec id(String.t()) :: String.t()
+  def id(stream_name) do
+    case String.split(stream_name, "-", parts: 2) do
+      [_category, id] -> id
+      [_category] -> ""
+    end
+  end
+
+  @doc """
+  Extracts the cardinal ID used for consumer group partitioning.
+
+      iex> EventodbEx.StreamName.cardinal_id("account-123+456")
+      "123"
+
+  """
+  @spec cardinal_id(String.t()) :: String.t()
+  def cardinal_id(stream_name) do
+    stream_name |> id() |> String.split("+", parts: 2) |> hd()
+  end
+
+  @doc """
+  Splits a compound ID into its parts.
+
+      iex> EventodbEx.StreamName.compound_ids("account-123+456")
+      ["123", "456"]
+
+  """
+  @spec compound_ids(String.t()) :: [String.t()]
+  def compound_ids(stream_name) do
+    case id(stream_name) do
+      "" -> []
+      id -> String.split(id, "+")
+    end
+  end
+
+  @doc """
+  Returns whether a name is a category (has no ID part).
+  """
+  @spec category?(String.t()) :: boolean()
+  def category?(stream_name), do: not String.contains?(stream_name, "-")
+
+  @doc """
+  Breaks a stream name into its parts, with the same keys as
+  `EventodbEx.system_parse_stream_name/2`. `:valid` is always true; only the
+  server knows its validation policy.
+  """
+  @spec parse(String.t()) :: map()
+  def parse(stream_name) do
+    %{
+      stream_name: stream_name,
+      category: category(stream_name),
+      id: id(stream_name),
+      cardinal_id: cardinal_id(stream_name),
+      compound_ids: compound_ids(stream_name),
+      is_category: category?(stream_name),
+      valid: true
+    }
+  end
+end
@@ -29,7 +29,7 @@ defmodule EventodbEx.AuthTest do
     message = %{type: "TestEvent", data: %{}}
 
     assert {:error, error} = EventodbEx.stream_write(client, stream, message)
-    assert error.code in ["AUTH_INVALID", "AUTH_REQUIRED", "AUTH_INVALID_TOKEN"]
+    assert error.code in ["AUTH_INVALID", "AUTH_REQUIRED", "AUTH_INVALID_TOKEN", "TOKEN_MALFORMED"]
   end
 
   test "AUTH-004: Token namespace isolation" do
@@ -26,4 +26,15 @@ defmodule EventodbEx.SystemTest do
     assert is_map(health)
     assert health.status == "ok"
   end
+
+  test "SYS-003: Parse stream names like the server", %{client: client} do
+    for name <- ["account-123", "account-123+456", "account-prefix-123", "account"] do
+      assert {:ok, parts, _client} = EventodbEx.system_parse_stream_name(client, name)
+      assert parts == EventodbEx.StreamName.parse(name)
+    end
+
+    assert {:ok, parts, _client} = EventodbEx.system_parse_stream_name(client, "account-")
+    assert parts.valid == false
+    assert is_binary(parts.reason)
+  end
 end
@@ -0,0 +1,121 @@
+// Package main provides the describe CLI command.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/eventodb/eventodb/internal/api"
+)
+
+// DescribeConfig holds configuration for the describe command
+type DescribeConfig struct {
+	// Method is the RPC method to describe; empty lists all methods
+	Method string
+	// Output is "text" or "json"
+	Output string
+}
+
+func parseDescribeFlags(args []string) (*DescribeConfig, error) {
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+
+	output := fs.String("output", "text", "Output format: text or json")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `
+Usage: eventodb describe [METHOD] [OPTIONS]
+
+Show an RPC method's arguments, options and example requests, or list all
+methods. Works offline from the registry built into this binary, the same one
+served by sys.describe and GET /schema.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	// The method comes before the flags
+	var method string
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		method, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if method == "" && fs.NArg() > 0 {
+		method = fs.Arg(0)
+	}
+
+	if *output != "text" && *output != "json" {
+		return nil, fmt.Errorf("--output must be text or json")
+	}
+
+	return &DescribeConfig{
+		Method: method,
+		Output: *output,
+	}, nil
+}
+
+func runDescribe(cfg *DescribeConfig) error {
+	if cfg.Method == "" {
+		docs := api.MethodDocs()
+		if cfg.Output == "json" {
+			return printDescribeJSON(docs)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, doc := range docs {
+			fmt.Fprintf(w, "%s\t%s\n", doc.Method, doc.Summary)
+		}
+		return w.Flush()
+	}
+
+	doc, ok := api.DescribeMethod(cfg.Method)
+	if !ok {
+		return fmt.Errorf("unknown method: %s", cfg.Method)
+	}
+	if cfg.Output == "json" {
+		return printDescribeJSON(doc)
+	}
+
+	fmt.Printf("%s\n    %s\n", doc.Method, doc.Summary)
+	if doc.Admin {
+		fmt.Printf("    Admin method: served on the admin listener when --admin-addr is set\n")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\nARGUMENTS:\n")
+	if len(doc.Args) == 0 {
+		fmt.Fprintf(w, "    (none)\n")
+	}
+	for _, arg := range doc.Args {
+		required := "optional"
+		if arg.Required {
+			required = "required"
+		}
+		fmt.Fprintf(w, "    %s\t%s\t%s\t%s\n", arg.Name, arg.Type, required, arg.Description)
+	}
+	if len(doc.Options) > 0 {
+		fmt.Fprintf(w, "\nOPTIONS:\n")
+		for _, opt := range doc.Options {
+			fmt.Fprintf(w, "    %s\t%s\t%s\n", opt.Name, opt.Type, opt.Description)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nEXAMPLES:\n")
+	for _, example := range doc.Examples {
+		fmt.Printf("    %s\n", example)
+	}
+	return nil
+}
+
+func printDescribeJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,93 @@
+// Package main provides loading of the at-rest encryption key.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/secrets"
+	"github.com/eventodb/eventodb/internal/store/pebble"
+)
+
+// encryptionKeyCommandTimeout bounds how long --encryption-key-command may run
+const encryptionKeyCommandTimeout = 30 * time.Second
+
+// loadEncryptionKey returns the at-rest encryption key from the
+// EVENTODB_ENCRYPTION_KEY environment variable, keyFile or the output of
+// keyCommand (e.g. a KMS decrypt call). At most one may be set; nil means
+// no encryption. The key is not accepted as a flag so it never shows up in
+// process listings. EVENTODB_ENCRYPTION_KEY may be a secret reference.
+func loadEncryptionKey(keyFile, keyCommand string) ([]byte, error) {
+	envKey, err := secrets.Resolve(context.Background(), os.Getenv("EVENTODB_ENCRYPTION_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("EVENTODB_ENCRYPTION_KEY: %w", err)
+	}
+
+	sources := 0
+	for _, s := range []string{envKey, keyFile, keyCommand} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, fmt.Errorf("set only one of EVENTODB_ENCRYPTION_KEY, --encryption-key-file and --encryption-key-command")
+	}
+
+	switch {
+	case envKey != "":
+		return parseEncryptionKey(envKey)
+
+	case keyFile != "":
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+		return parseEncryptionKey(string(data))
+
+	case keyCommand != "":
+		ctx, cancel := context.WithTimeout(context.Background(), encryptionKeyCommandTimeout)
+		defer cancel()
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", keyCommand)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", keyCommand)
+		}
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("encryption key command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return parseEncryptionKey(string(out))
+	}
+	return nil, nil
+}
+
+// encryptionKeyFromEnv loads the encryption key for commands that open the
+// database directly, from the same environment variables as serve
+func encryptionKeyFromEnv() ([]byte, error) {
+	return loadEncryptionKey(getEnv("EVENTODB_ENCRYPTION_KEY_FILE", ""), getEnv("EVENTODB_ENCRYPTION_KEY_COMMAND", ""))
+}
+
+// parseEncryptionKey decodes a hex or base64 encoded 256-bit key
+func parseEncryptionKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := hex.DecodeString(s); err == nil && len(key) == pebble.EncryptionKeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == pebble.EncryptionKeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be %d bytes, hex or base64 encoded (generate one with: openssl rand -hex %d)",
+		pebble.EncryptionKeySize, pebble.EncryptionKeySize)
+}
@@ -0,0 +1,482 @@
+// Package main provides export and import of all namespaces of a server.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/api"
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// archiveBatchSize is the number of messages read or imported per call
+const archiveBatchSize = 1000
+
+// ArchiveNamespace describes a namespace on the first line of its section of
+// an all-namespaces archive, so import can re-create it with the same token
+type ArchiveNamespace struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	TokenHash   string `json:"tokenHash"`
+}
+
+// archiveHeader is the first line of a namespace's section. Per-namespace
+// archives keep the namespaceConfig line of a regular export, so they can also
+// be loaded into one namespace with eventodb import --token.
+type archiveHeader struct {
+	Namespace       *ArchiveNamespace    `json:"namespace,omitempty"`
+	NamespaceConfig *api.NamespaceConfig `json:"namespaceConfig,omitempty"`
+}
+
+// archiveLine is any line of an archive: a header or a message record
+type archiveLine struct {
+	archiveHeader
+	ExportRecord
+}
+
+// openArchiveStore opens the database named by --db-url for export or import
+func openArchiveStore(dbURL, dataDir, dbType string) (store.Store, func(), error) {
+	logger.Initialize("warn", "console")
+
+	dbCfg, err := parseDBConfig(dbURL, dataDir, dbType, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbCfg.manualMigrations = true
+	if dbCfg.encryptionKey, err = encryptionKeyFromEnv(); err != nil {
+		return nil, nil, err
+	}
+	return createStore(dbCfg)
+}
+
+// runExportAll exports every namespace from the database, one archive per
+// namespace in cfg.OutputDir or one combined archive with namespace tags
+func runExportAll(cfg *ExportConfig) error {
+	st, cleanup, err := openArchiveStore(cfg.DBURL, cfg.DataDir, cfg.DBType)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	namespaces, err := st.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].ID < namespaces[j].ID })
+
+	if cfg.OutputDir != "" {
+		if err := os.MkdirAll(cfg.OutputDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		for _, ns := range namespaces {
+			name := filepath.Join(cfg.OutputDir, ns.ID+".ndjson")
+			if cfg.Gzip {
+				name += ".gz"
+			}
+			if err := exportNamespaceFile(ctx, st, cfg, ns, name); err != nil {
+				return fmt.Errorf("namespace %s: %w", ns.ID, err)
+			}
+		}
+		return nil
+	}
+
+	var out io.Writer = os.Stdout
+	var closeOutput func() error
+	if cfg.Output != "" {
+		f, err := os.Create(cfg.Output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		out = f
+		closeOutput = f.Close
+	}
+
+	var gzWriter *gzip.Writer
+	if cfg.Gzip {
+		gzWriter = gzip.NewWriter(out)
+		out = gzWriter
+	}
+
+	encoder := json.NewEncoder(out)
+	for _, ns := range namespaces {
+		if err := exportNamespaceSection(ctx, st, cfg, ns, encoder, true); err != nil {
+			return fmt.Errorf("namespace %s: %w", ns.ID, err)
+		}
+	}
+
+	if gzWriter != nil {
+		if err := gzWriter.Close(); err != nil {
+			return fmt.Errorf("failed to finish gzip output: %w", err)
+		}
+	}
+	if closeOutput != nil {
+		if err := closeOutput(); err != nil {
+			return fmt.Errorf("failed to close output file: %w", err)
+		}
+	}
+	return nil
+}
+
+// exportNamespaceFile writes one namespace to its own archive file
+func exportNamespaceFile(ctx context.Context, st store.Store, cfg *ExportConfig, ns *store.Namespace, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer f.Close()
+
+	var out io.Writer = f
+	var gzWriter *gzip.Writer
+	if cfg.Gzip {
+		gzWriter = gzip.NewWriter(f)
+		out = gzWriter
+	}
+
+	if err := exportNamespaceSection(ctx, st, cfg, ns, json.NewEncoder(out), false); err != nil {
+		return err
+	}
+	if gzWriter != nil {
+		if err := gzWriter.Close(); err != nil {
+			return fmt.Errorf("failed to finish gzip output: %w", err)
+		}
+	}
+	return f.Close()
+}
+
+// exportNamespaceSection writes a namespace's header line and messages.
+// Records are tagged with the namespace when tag is set.
+func exportNamespaceSection(ctx context.Context, st store.Store, cfg *ExportConfig, ns *store.Namespace, encoder *json.Encoder, tag bool) error {
+	nsConfig, err := api.ExportNamespaceConfig(ctx, st, nil, ns.ID)
+	if err != nil {
+		return fmt.Errorf("failed to export namespace config: %w", err)
+	}
+	header := archiveHeader{
+		Namespace: &ArchiveNamespace{
+			ID:          ns.ID,
+			Description: ns.Description,
+			TokenHash:   ns.TokenHash,
+		},
+		NamespaceConfig: nsConfig,
+	}
+	if err := encoder.Encode(header); err != nil {
+		return fmt.Errorf("failed to write namespace header: %w", err)
+	}
+
+	categories := cfg.Categories
+	if len(categories) == 0 {
+		categories = []string{""} // Empty = all messages
+	}
+	var filter *store.Filter
+	if cfg.Where != "" {
+		if filter, err = store.ParseFilter(cfg.Where); err != nil {
+			return fmt.Errorf("invalid --where: %w", err)
+		}
+	}
+
+	var exported int64
+	for _, category := range categories {
+		position := int64(0)
+		for {
+			messages, err := st.GetCategoryMessages(ctx, ns.ID, category, &store.CategoryOpts{
+				Position:  position,
+				BatchSize: archiveBatchSize,
+				Filter:    filter,
+				Sample:    cfg.Sample,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to read messages: %w", err)
+			}
+			if len(messages) == 0 {
+				break
+			}
+
+			for _, msg := range messages {
+				if cfg.Since != nil && msg.Time.Before(*cfg.Since) {
+					continue
+				}
+				if cfg.Until != nil && !msg.Time.Before(*cfg.Until) {
+					continue
+				}
+				record := &ExportRecord{
+					ID:       msg.ID,
+					Stream:   msg.StreamName,
+					Type:     msg.Type,
+					Position: msg.Position,
+					GPos:     msg.GlobalPosition,
+					Data:     msg.Data,
+					Meta:     msg.Metadata,
+					Time:     msg.Time.UTC().Format(time.RFC3339Nano),
+				}
+				if tag {
+					record.Namespace = ns.ID
+				}
+				if err := encoder.Encode(record); err != nil {
+					return fmt.Errorf("failed to write record: %w", err)
+				}
+				exported++
+			}
+
+			position = messages[len(messages)-1].GlobalPosition + 1
+			fmt.Fprintf(os.Stderr, "\r%s: exported %d events...", ns.ID, exported)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\r%s: exported %d events total\n", ns.ID, exported)
+	return nil
+}
+
+// runImportAll imports an all-namespaces export into the database, creating
+// missing namespaces with their original token hashes
+func runImportAll(cfg *ImportConfig) error {
+	st, cleanup, err := openArchiveStore(cfg.DBURL, cfg.DataDir, cfg.DBType)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	rules := &api.ImportRules{StreamPrefixMap: cfg.StreamPrefixMap, TypeMap: cfg.TypeMap}
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	imp := &archiveImporter{store: st, cfg: cfg, rules: rules, seen: make(map[string]bool)}
+
+	if cfg.InputDir != "" {
+		names, err := filepath.Glob(filepath.Join(cfg.InputDir, "*.ndjson*"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !strings.HasSuffix(name, ".ndjson") && !strings.HasSuffix(name, ".ndjson.gz") {
+				continue
+			}
+			if err := imp.importFile(ctx, name); err != nil {
+				return fmt.Errorf("%s: %w", filepath.Base(name), err)
+			}
+		}
+	} else {
+		var input io.Reader = os.Stdin
+		if cfg.Input != "" {
+			f, err := os.Open(cfg.Input)
+			if err != nil {
+				return fmt.Errorf("failed to open input file: %w", err)
+			}
+			defer f.Close()
+			input = f
+		}
+		if cfg.Gzip {
+			gzReader, err := gzip.NewReader(input)
+			if err != nil {
+				return fmt.Errorf("failed to create gzip reader: %w", err)
+			}
+			defer gzReader.Close()
+			input = gzReader
+		}
+		if err := imp.importArchive(ctx, input); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d namespace(s)\n", len(imp.seen))
+	return nil
+}
+
+// archiveImporter loads archive sections into the database
+type archiveImporter struct {
+	store store.Store
+	cfg   *ImportConfig
+	rules *api.ImportRules
+	seen  map[string]bool // Namespaces imported so far
+}
+
+// importFile imports one archive file, gzip compressed if named *.gz
+func (imp *archiveImporter) importFile(ctx context.Context, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer f.Close()
+
+	var input io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		gzReader, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		defer gzReader.Close()
+		input = gzReader
+	}
+	return imp.importArchive(ctx, input)
+}
+
+// importArchive imports the namespace sections of an archive
+func (imp *archiveImporter) importArchive(ctx context.Context, input io.Reader) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var namespace string
+	var imported, skipped, lineNum int64
+	batch := make([]*store.Message, 0, archiveBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		kept := batch
+		if imp.cfg.Dedupe {
+			var err error
+			if kept, err = api.DedupeImportBatch(ctx, imp.store, namespace, batch); err != nil {
+				return fmt.Errorf("failed to dedupe batch ending at line %d: %w", lineNum, err)
+			}
+			skipped += int64(len(batch) - len(kept))
+		}
+		if len(kept) > 0 {
+			if err := imp.store.ImportBatch(ctx, namespace, kept); err != nil {
+				return fmt.Errorf("failed to import batch ending at line %d: %w", lineNum, err)
+			}
+		}
+		imported += int64(len(kept))
+		batch = batch[:0]
+		fmt.Fprintf(os.Stderr, "\r%s: imported %d events...", namespace, imported)
+		return nil
+	}
+	finish := func() {
+		if namespace != "" {
+			fmt.Fprintf(os.Stderr, "\r%s: imported %d events total\n", namespace, imported)
+			if skipped > 0 {
+				fmt.Fprintf(os.Stderr, "%s: skipped %d events already imported\n", namespace, skipped)
+			}
+		}
+	}
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var record archiveLine
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("malformed JSON at line %d: %w", lineNum, err)
+		}
+
+		// A header starts the next namespace
+		if record.archiveHeader.Namespace != nil || record.NamespaceConfig != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+			finish()
+			next, err := imp.startNamespace(ctx, &record.archiveHeader)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			namespace, imported, skipped = next, 0, 0
+			continue
+		}
+
+		if namespace == "" {
+			return fmt.Errorf("line %d: message before the first namespace header; use eventodb import --token for single-namespace exports without --include-config", lineNum)
+		}
+		if record.ExportRecord.Namespace != "" && record.ExportRecord.Namespace != namespace {
+			return fmt.Errorf("line %d: message of namespace %q in the section of %q", lineNum, record.ExportRecord.Namespace, namespace)
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, record.Time)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid time format: %w", lineNum, err)
+		}
+		msg := &store.Message{
+			ID:             record.ID,
+			StreamName:     record.Stream,
+			Type:           record.Type,
+			Position:       record.Position,
+			GlobalPosition: record.GPos,
+			Data:           record.Data,
+			Metadata:       record.Meta,
+			Time:           t.UTC(),
+		}
+		imp.rules.Apply(msg)
+		batch = append(batch, msg)
+		if len(batch) >= archiveBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading input: %w", err)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	finish()
+	return nil
+}
+
+// startNamespace creates or prepares the namespace of a section header and
+// applies its configuration
+func (imp *archiveImporter) startNamespace(ctx context.Context, header *archiveHeader) (string, error) {
+	var id, description, tokenHash string
+	if header.Namespace != nil {
+		id, description, tokenHash = header.Namespace.ID, header.Namespace.Description, header.Namespace.TokenHash
+	} else {
+		id, description = header.NamespaceConfig.Namespace, header.NamespaceConfig.Description
+	}
+	if id == "" {
+		return "", errors.New("namespace header without a namespace ID")
+	}
+	if imp.seen[id] {
+		return "", fmt.Errorf("namespace %s appears twice in the input", id)
+	}
+	imp.seen[id] = true
+
+	_, err := imp.store.GetNamespace(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		// Exports made with --include-config carry no token hash, so a new token is issued
+		if tokenHash == "" {
+			token, err := auth.GenerateToken(id)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate token: %w", err)
+			}
+			tokenHash = auth.HashToken(token)
+			fmt.Fprintf(os.Stderr, "%s: created with new token %s\n", id, token)
+		}
+		if err := imp.store.CreateNamespace(ctx, id, tokenHash, description); err != nil {
+			return "", fmt.Errorf("failed to create namespace %s: %w", id, err)
+		}
+	case err != nil:
+		return "", fmt.Errorf("failed to get namespace %s: %w", id, err)
+	case imp.cfg.Force:
+		if err := api.CheckWorm(ctx, imp.store, id); err != nil {
+			return "", fmt.Errorf("cannot clear namespace %s: %w", id, err)
+		}
+		if _, err := imp.store.ClearNamespaceMessages(ctx, id); err != nil {
+			return "", fmt.Errorf("failed to clear namespace %s: %w", id, err)
+		}
+	}
+
+	if header.NamespaceConfig != nil && !imp.cfg.SkipConfig {
+		if err := api.ApplyNamespaceConfig(ctx, imp.store, nil, id, header.NamespaceConfig); err != nil {
+			return "", fmt.Errorf("failed to apply namespace config of %s: %w", id, err)
+		}
+	}
+	return id, nil
+}
@@ -0,0 +1,477 @@
+// Package main provides the import-esdb CLI command.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/api"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// esdbAtomType is the media type of EventStoreDB's AtomPub JSON feeds
+	esdbAtomType = "application/vnd.eventstore.atom+json"
+
+	// esdbLinkType is the event type of EventStoreDB link events
+	esdbLinkType = "$>"
+
+	// esdbStartPosition is the first page position of the $all feed
+	esdbStartPosition = "00000000000000000000000000000000"
+)
+
+// ImportESDBConfig holds configuration for the import-esdb command
+type ImportESDBConfig struct {
+	// ESDB is the HTTP URL of an EventStoreDB node with AtomPub enabled
+	ESDB         string
+	ESDBUser     string
+	ESDBPassword string
+
+	URL   string
+	Token string
+	// CategoryTokens sends categories to other namespaces than Token's
+	CategoryTokens map[string]string
+
+	// Links is "skip" or "copy" (copy the linked event into the link's stream)
+	Links string
+	// State is the file recording the feed position, for resuming
+	State    string
+	PageSize int
+
+	// Rules rename events while they are imported (optional)
+	Rules *api.ImportRules
+}
+
+// esdbState is the resume state of an import-esdb run
+type esdbState struct {
+	// Next is the $all page to read next
+	Next       string `json:"next"`
+	Imported   int64  `json:"imported"`
+	Skipped    int64  `json:"skipped"`
+	Renumbered int64  `json:"renumbered"`
+}
+
+// esdbFeed is a page of an EventStoreDB AtomPub feed
+type esdbFeed struct {
+	HeadOfStream bool        `json:"headOfStream"`
+	Links        []esdbLink  `json:"links"`
+	Entries      []esdbEntry `json:"entries"`
+}
+
+type esdbLink struct {
+	URI      string `json:"uri"`
+	Relation string `json:"relation"`
+}
+
+// esdbEntry is an event of a feed page read with embed=tryharder
+type esdbEntry struct {
+	EventID     string          `json:"eventId"`
+	EventType   string          `json:"eventType"`
+	EventNumber int64           `json:"eventNumber"`
+	StreamID    string          `json:"streamId"`
+	Data        json.RawMessage `json:"data"`
+	MetaData    json.RawMessage `json:"metaData"`
+}
+
+// esdbEvent is a single event read from /streams/{stream}/{number}
+type esdbEvent struct {
+	Content struct {
+		EventStreamID string          `json:"eventStreamId"`
+		EventNumber   int64           `json:"eventNumber"`
+		EventType     string          `json:"eventType"`
+		Data          json.RawMessage `json:"data"`
+		Metadata      json.RawMessage `json:"metadata"`
+	} `json:"content"`
+}
+
+func parseImportESDBFlags(args []string) (*ImportESDBConfig, error) {
+	fs := flag.NewFlagSet("import-esdb", flag.ExitOnError)
+
+	esdb := fs.String("esdb", "http://localhost:2113", "EventStoreDB HTTP URL (AtomPub must be enabled)")
+	esdbUser := fs.String("esdb-user", "", "EventStoreDB user (reading $all needs an admin)")
+	esdbPassword := fs.String("esdb-password", "", "EventStoreDB password")
+	esdbPasswordFile := fs.String("esdb-password-file", "", "File holding the EventStoreDB password")
+	serverURL := fs.String("url", getEnv("EVENTODB_URL", "http://localhost:8080"), "EventoDB server URL")
+	token := fs.String("token", getEnv("EVENTODB_TOKEN", ""), "Namespace token (required)")
+	categoryTokens := fs.String("category-tokens", "", "Comma-separated category=token pairs importing categories into other namespaces")
+	links := fs.String("links", "skip", "Link events: skip, or copy the linked event into the link's stream")
+	state := fs.String("state", "esdb-import.state", "File recording progress; an interrupted import resumes from it")
+	pageSize := fs.Int("page-size", 100, "Events read from EventStoreDB per request (1-4096)")
+	streamPrefixMap := fs.String("stream-prefix-map", "", "Comma-separated old=new stream name prefixes to rename on import")
+	typeMap := fs.String("type-map", "", "Comma-separated old=new event types to rename on import")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `
+Usage: eventodb import-esdb [OPTIONS]
+
+Import the $all feed of an EventStoreDB node into EventoDB namespaces. Stream
+names, event IDs, stream positions, data and metadata are kept. System streams
+and system events ($...) are skipped.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  eventodb import-esdb --esdb http://esdb:2113 --esdb-user admin --esdb-password changeit --token $TOKEN
+  eventodb import-esdb --esdb http://esdb:2113 --esdb-user admin --esdb-password-file esdb.pass \
+    --token $TOKEN --category-tokens billing=$BILLING_TOKEN,invoice=$BILLING_TOKEN
+  eventodb import-esdb --esdb http://esdb:2113 --esdb-user admin --esdb-password changeit --token $TOKEN \
+    --links copy --state migration.state
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *token == "" {
+		return nil, fmt.Errorf("--token is required")
+	}
+	if *links != "skip" && *links != "copy" {
+		return nil, fmt.Errorf("--links must be skip or copy")
+	}
+	if *pageSize < 1 || *pageSize > 4096 {
+		return nil, fmt.Errorf("--page-size must be between 1 and 4096")
+	}
+	if *state == "" {
+		return nil, fmt.Errorf("--state is required")
+	}
+	password, err := resolveSecretFlag("esdb-password", *esdbPassword, *esdbPasswordFile)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := parseRenameMap(*categoryTokens)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --category-tokens: %w", err)
+	}
+	prefixes, err := parseRenameMap(*streamPrefixMap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --stream-prefix-map: %w", err)
+	}
+	types, err := parseRenameMap(*typeMap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --type-map: %w", err)
+	}
+	var rules *api.ImportRules
+	if prefixes != nil || types != nil {
+		rules = &api.ImportRules{StreamPrefixMap: prefixes, TypeMap: types}
+		if err := rules.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ImportESDBConfig{
+		ESDB:         strings.TrimRight(*esdb, "/"),
+		ESDBUser:     *esdbUser,
+		ESDBPassword: password,
+
+		URL:            *serverURL,
+		Token:          *token,
+		CategoryTokens: routes,
+
+		Links:    *links,
+		State:    *state,
+		PageSize: *pageSize,
+		Rules:    rules,
+	}, nil
+}
+
+// esdbImporter copies the $all feed of an EventStoreDB node into EventoDB
+type esdbImporter struct {
+	cfg     *ImportESDBConfig
+	http    *http.Client
+	clients map[string]*rpcClient
+	state   esdbState
+}
+
+func runImportESDB(cfg *ImportESDBConfig) error {
+	ctx := context.Background()
+	imp := &esdbImporter{
+		cfg:     cfg,
+		http:    &http.Client{Timeout: 30 * time.Second},
+		clients: make(map[string]*rpcClient),
+	}
+
+	if err := imp.loadState(); err != nil {
+		return err
+	}
+	if imp.state.Next == "" {
+		imp.state.Next = fmt.Sprintf("%s/streams/%%24all/%s/forward/%d", cfg.ESDB, esdbStartPosition, cfg.PageSize)
+	} else {
+		fmt.Fprintf(os.Stderr, "Resuming from %s (%d events imported)\n", cfg.State, imp.state.Imported)
+	}
+
+	for {
+		feed, err := imp.readPage(ctx, imp.state.Next)
+		if err != nil {
+			return err
+		}
+
+		// Pages list their newest event first
+		for i := len(feed.Entries) - 1; i >= 0; i-- {
+			if err := imp.importEntry(ctx, &feed.Entries[i]); err != nil {
+				return err
+			}
+		}
+
+		// The previous link of a forward page is the page after it
+		next := ""
+		for _, link := range feed.Links {
+			if link.Relation == "previous" {
+				next = link.URI
+			}
+		}
+		if next != "" {
+			imp.state.Next = next
+		}
+		if err := imp.saveState(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "\rImported: %d events (%d skipped)...", imp.state.Imported, imp.state.Skipped)
+
+		if feed.HeadOfStream || len(feed.Entries) == 0 || next == "" {
+			break
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\rImported: %d events (%d skipped)\n", imp.state.Imported, imp.state.Skipped)
+	if imp.state.Renumbered > 0 {
+		fmt.Fprintf(os.Stderr, "%d events were written at other stream positions than in EventStoreDB (truncated or scavenged streams)\n", imp.state.Renumbered)
+	}
+	fmt.Fprintf(os.Stderr, "Run again with the same --state to import events written since\n")
+	return nil
+}
+
+// importEntry writes one event of the feed, skipping system streams and
+// events and, unless links are copied, link events
+func (imp *esdbImporter) importEntry(ctx context.Context, entry *esdbEntry) error {
+	if strings.HasPrefix(entry.StreamID, "$") {
+		imp.state.Skipped++
+		return nil
+	}
+
+	eventType, data, metadata := entry.EventType, entry.Data, entry.MetaData
+	if eventType == esdbLinkType {
+		if imp.cfg.Links != "copy" {
+			imp.state.Skipped++
+			return nil
+		}
+		target, err := imp.readLinked(ctx, entry)
+		if err != nil {
+			return err
+		}
+		if target == nil || strings.HasPrefix(target.Content.EventType, "$") {
+			imp.state.Skipped++
+			return nil
+		}
+		eventType, data, metadata = target.Content.EventType, target.Content.Data, target.Content.Metadata
+	} else if strings.HasPrefix(eventType, "$") {
+		imp.state.Skipped++
+		return nil
+	}
+
+	msg := &store.Message{
+		StreamName: entry.StreamID,
+		Type:       eventType,
+		Data:       esdbObject(data),
+		Metadata:   esdbObject(metadata),
+	}
+	if entry.EventType == esdbLinkType {
+		msg.Metadata["linkTo"] = esdbString(entry.Data)
+	}
+	imp.cfg.Rules.Apply(msg)
+
+	stream := msg.StreamName
+	client := imp.client(stream)
+	body := map[string]interface{}{"type": msg.Type, "data": msg.Data}
+	if len(msg.Metadata) > 0 {
+		body["metadata"] = msg.Metadata
+	}
+
+	// Stream positions match when the stream has no gaps; an event already at
+	// its position was imported by an interrupted run
+	err := client.call(ctx, nil, "stream.write", stream, body, map[string]interface{}{
+		"id":              entry.EventID,
+		"expectedVersion": entry.EventNumber - 1,
+	})
+	if err != nil && strings.HasPrefix(err.Error(), "STREAM_VERSION_CONFLICT") {
+		var version *int64
+		if verr := client.call(ctx, &version, "stream.version", stream); verr != nil {
+			return fmt.Errorf("failed to read version of %s: %w", stream, verr)
+		}
+		if version != nil && *version >= entry.EventNumber {
+			imp.state.Skipped++
+			return nil
+		}
+		imp.state.Renumbered++
+		err = client.call(ctx, nil, "stream.write", stream, body, map[string]interface{}{"id": entry.EventID})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s/%d: %w", entry.StreamID, entry.EventNumber, err)
+	}
+	imp.state.Imported++
+	return nil
+}
+
+// client returns the RPC client of the namespace a stream is imported into
+func (imp *esdbImporter) client(stream string) *rpcClient {
+	token := imp.cfg.Token
+	if t, ok := imp.cfg.CategoryTokens[store.Category(stream)]; ok {
+		token = t
+	}
+	c, ok := imp.clients[token]
+	if !ok {
+		c = newRPCClient(imp.cfg.URL, token)
+		imp.clients[token] = c
+	}
+	return c
+}
+
+// readPage reads a page of the $all feed
+func (imp *esdbImporter) readPage(ctx context.Context, pageURL string) (*esdbFeed, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed page %q: %w", pageURL, err)
+	}
+	q := u.Query()
+	q.Set("embed", "tryharder")
+	u.RawQuery = q.Encode()
+
+	var feed esdbFeed
+	if err := imp.get(ctx, u.String(), &feed); err != nil {
+		return nil, fmt.Errorf("failed to read $all: %w", err)
+	}
+	return &feed, nil
+}
+
+// readLinked reads the event a link event points to, or nil when it is gone
+func (imp *esdbImporter) readLinked(ctx context.Context, entry *esdbEntry) (*esdbEvent, error) {
+	number, stream, ok := strings.Cut(esdbString(entry.Data), "@")
+	if !ok {
+		return nil, fmt.Errorf("invalid link event %s/%d", entry.StreamID, entry.EventNumber)
+	}
+	if _, err := strconv.ParseInt(number, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid link event %s/%d", entry.StreamID, entry.EventNumber)
+	}
+
+	var event esdbEvent
+	err := imp.get(ctx, fmt.Sprintf("%s/streams/%s/%s?embed=tryharder", imp.cfg.ESDB, url.PathEscape(stream), number), &event)
+	if errors.Is(err, errESDBNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read linked event %s@%s: %w", number, stream, err)
+	}
+	return &event, nil
+}
+
+// errESDBNotFound is returned for events and streams that do not exist
+var errESDBNotFound = errors.New("not found")
+
+// get reads a JSON document from EventStoreDB without resolving links
+func (imp *esdbImporter) get(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", esdbAtomType)
+	req.Header.Set("ES-ResolveLinkTos", "false")
+	if imp.cfg.ESDBUser != "" {
+		req.SetBasicAuth(imp.cfg.ESDBUser, imp.cfg.ESDBPassword)
+	}
+
+	resp, err := imp.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return errESDBNotFound
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (imp *esdbImporter) loadState() error {
+	data, err := os.ReadFile(imp.cfg.State)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, &imp.state); err != nil {
+		return fmt.Errorf("invalid state file %s: %w", imp.cfg.State, err)
+	}
+	return nil
+}
+
+// saveState records progress after a page, replacing the file atomically
+func (imp *esdbImporter) saveState() error {
+	data, err := json.Marshal(&imp.state)
+	if err != nil {
+		return err
+	}
+	tmp := imp.cfg.State + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp, imp.cfg.State); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// esdbObject returns event data or metadata as an object. Feeds embed JSON
+// events as objects or as strings holding JSON; anything else is kept under
+// "value".
+func esdbObject(raw json.RawMessage) map[string]interface{} {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return map[string]interface{}{}
+	}
+	var obj map[string]interface{}
+	if json.Unmarshal(raw, &obj) == nil {
+		return obj
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if s == "" {
+			return map[string]interface{}{}
+		}
+		if json.Unmarshal([]byte(s), &obj) == nil {
+			return obj
+		}
+		return map[string]interface{}{"value": s}
+	}
+	var v interface{}
+	json.Unmarshal(raw, &v)
+	return map[string]interface{}{"value": v}
+}
+
+// esdbString returns a string field of an entry, such as a link's target
+func esdbString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
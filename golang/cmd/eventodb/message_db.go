@@ -0,0 +1,222 @@
+// Package main provides conversion to and from Message DB (Eventide) databases.
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+const (
+	// messageDBDefaultSchema is the schema Message DB installs its table in
+	messageDBDefaultSchema = "message_store"
+
+	// messageDBBatchSize is the number of messages read or inserted at a time
+	messageDBBatchSize = 1000
+
+	// messageDBTimeFormat formats times for Message DB's timestamp column,
+	// which holds UTC without a time zone
+	messageDBTimeFormat = "2006-01-02 15:04:05.999999"
+)
+
+// messageDBSchemaPattern restricts schema names, which are quoted into SQL
+var messageDBSchemaPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// openMessageDB connects to a Message DB database and checks its messages table
+func openMessageDB(ctx context.Context, url, schema string) (*sql.DB, error) {
+	if !messageDBSchemaPattern.MatchString(schema) {
+		return nil, fmt.Errorf("invalid Message DB schema name %q", schema)
+	}
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Message DB: %w", err)
+	}
+
+	var exists bool
+	err = db.QueryRowContext(ctx, `SELECT EXISTS (
+		SELECT 1 FROM information_schema.tables WHERE table_schema = $1 AND table_name = 'messages')`,
+		schema).Scan(&exists)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to Message DB: %w", err)
+	}
+	if !exists {
+		db.Close()
+		return nil, fmt.Errorf("no %s.messages table; is this a Message DB database?", schema)
+	}
+	return db, nil
+}
+
+// readMessageDB writes every message of a Message DB database to w as export
+// records, in global position order. IDs, positions, global positions, times
+// and metadata are kept as they are.
+func readMessageDB(ctx context.Context, db *sql.DB, schema string, w io.Writer) (int64, error) {
+	query := fmt.Sprintf(`
+		SELECT id, stream_name, type, position, global_position, data::text, metadata::text, time
+		FROM "%s".messages
+		WHERE global_position > $1
+		ORDER BY global_position
+		LIMIT $2`, schema)
+
+	encoder := json.NewEncoder(w)
+	var read, after int64
+	for {
+		rows, err := db.QueryContext(ctx, query, after, messageDBBatchSize)
+		if err != nil {
+			return read, fmt.Errorf("failed to read Message DB: %w", err)
+		}
+
+		var batch int
+		for rows.Next() {
+			var record ExportRecord
+			var data, metadata sql.NullString
+			var t time.Time
+			if err := rows.Scan(&record.ID, &record.Stream, &record.Type, &record.Position, &record.GPos, &data, &metadata, &t); err != nil {
+				rows.Close()
+				return read, fmt.Errorf("failed to scan Message DB row: %w", err)
+			}
+			if err := decodeMessageDBJSON(data, &record.Data); err != nil {
+				rows.Close()
+				return read, fmt.Errorf("invalid data at global position %d: %w", record.GPos, err)
+			}
+			if err := decodeMessageDBJSON(metadata, &record.Meta); err != nil {
+				rows.Close()
+				return read, fmt.Errorf("invalid metadata at global position %d: %w", record.GPos, err)
+			}
+			// The column holds UTC without a zone; keep its wall clock
+			record.Time = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).Format(time.RFC3339Nano)
+
+			if err := encoder.Encode(&record); err != nil {
+				rows.Close()
+				return read, err
+			}
+			after = record.GPos
+			read++
+			batch++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return read, fmt.Errorf("failed to read Message DB: %w", err)
+		}
+
+		if batch < messageDBBatchSize {
+			return read, nil
+		}
+	}
+}
+
+// decodeMessageDBJSON decodes a nullable JSON column into an object
+func decodeMessageDBJSON(value sql.NullString, out *map[string]interface{}) error {
+	if !value.Valid || value.String == "" || value.String == "null" {
+		return nil
+	}
+	return json.Unmarshal([]byte(value.String), out)
+}
+
+// writeMessageDB inserts the export records read from r into a Message DB
+// database, keeping their IDs, positions, global positions and times, and
+// moves the global position sequence past the last one. Lines that are not
+// messages, such as a namespace config, are skipped.
+func writeMessageDB(ctx context.Context, db *sql.DB, schema string, r io.Reader) (int64, error) {
+	insert := fmt.Sprintf(`
+		INSERT INTO "%s".messages (id, stream_name, type, position, global_position, data, metadata, time)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb, $8::timestamp)`, schema)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var written, maxGPos int64
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	commit := func() error {
+		if tx == nil {
+			return nil
+		}
+		stmt.Close()
+		err := tx.Commit()
+		tx, stmt = nil, nil
+		return err
+	}
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var line int64
+	for scanner.Scan() {
+		line++
+		var record ExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return written, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Stream == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, record.Time)
+		if err != nil {
+			return written, fmt.Errorf("line %d: invalid time %q", line, record.Time)
+		}
+		data, err := json.Marshal(record.Data)
+		if err != nil {
+			return written, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Data == nil {
+			data = []byte("{}")
+		}
+		var metadata interface{}
+		if record.Meta != nil {
+			m, err := json.Marshal(record.Meta)
+			if err != nil {
+				return written, fmt.Errorf("line %d: %w", line, err)
+			}
+			metadata = string(m)
+		}
+
+		if tx == nil {
+			if tx, err = db.BeginTx(ctx, nil); err != nil {
+				return written, fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			if stmt, err = tx.PrepareContext(ctx, insert); err != nil {
+				return written, fmt.Errorf("failed to prepare insert: %w", err)
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, record.ID, record.Stream, record.Type, record.Position, record.GPos,
+			string(data), metadata, t.UTC().Format(messageDBTimeFormat)); err != nil {
+			return written, fmt.Errorf("line %d: failed to insert %s/%d: %w", line, record.Stream, record.Position, err)
+		}
+		written++
+		if record.GPos > maxGPos {
+			maxGPos = record.GPos
+		}
+
+		if written%messageDBBatchSize == 0 {
+			if err := commit(); err != nil {
+				return written, fmt.Errorf("failed to commit: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return written, err
+	}
+	if err := commit(); err != nil {
+		return written, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	// New writes through Message DB continue after the imported positions
+	if maxGPos > 0 {
+		_, err := db.ExecContext(ctx, fmt.Sprintf(
+			`SELECT setval(pg_get_serial_sequence('"%s".messages', 'global_position'), GREATEST($1::bigint, (SELECT MAX(global_position) FROM "%s".messages)))`,
+			schema, schema), maxGPos)
+		if err != nil {
+			return written, fmt.Errorf("failed to advance the global position sequence: %w", err)
+		}
+	}
+	return written, nil
+}
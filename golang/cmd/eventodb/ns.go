@@ -0,0 +1,423 @@
+// Package main provides the ns CLI command.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/api"
+)
+
+// NSConfig holds configuration for the ns command
+type NSConfig struct {
+	Action    string // create, delete, list, info, rotate-token, suspend, resume, backups or restore
+	Namespace string
+	URL       string
+	Token     string
+	// Output is "text" or "json"
+	Output string
+
+	// create
+	Description string
+	Shard       string
+	// NewToken is the token to give the namespace on create and rotate-token
+	// (default: generated by the server)
+	NewToken string
+	// Label and ExpiresIn describe the token issued by create and rotate-token
+	Label     string
+	ExpiresIn time.Duration
+
+	// delete
+	DryRun bool
+	Yes    bool
+
+	// suspend
+	Reason     string
+	BlockReads bool
+
+	// restore
+	Backup string
+}
+
+// nsActions are the ns subcommands; all but list and backups take a
+// namespace ID
+var nsActions = []string{"create", "delete", "list", "info", "rotate-token", "suspend", "resume", "backups", "restore"}
+
+// NamespaceInfo is the ns.info result, and the entries of the ns.list result
+type NamespaceInfo struct {
+	Namespace    string               `json:"namespace"`
+	Description  string               `json:"description"`
+	CreatedAt    string               `json:"createdAt"`
+	MessageCount int64                `json:"messageCount"`
+	Shard        string               `json:"shard,omitempty"`
+	Frozen       *api.FreezeState     `json:"frozen,omitempty"`
+	Suspended    *api.SuspensionState `json:"suspended,omitempty"`
+}
+
+func parseNSFlags(args []string) (*NSConfig, error) {
+	fs := flag.NewFlagSet("ns", flag.ExitOnError)
+
+	serverURL := fs.String("url", getEnv("EVENTODB_URL", "http://localhost:8080"), "EventoDB server URL (the admin listener when --admin-addr is set)")
+	token := fs.String("token", getEnv("EVENTODB_TOKEN", ""), "Admin token (required)")
+	output := fs.String("output", "text", "Output format: text or json")
+	description := fs.String("description", "", "Namespace description (create, restore)")
+	shard := fs.String("shard", "", "Shard to place the namespace on (create)")
+	newToken := fs.String("ns-token", "", "Token to give the namespace instead of a generated one (create, rotate-token)")
+	label := fs.String("label", "", "Label of the issued token (create, rotate-token)")
+	expiresIn := fs.Duration("expires-in", 0, "Lifetime of the issued token, e.g. 720h (create, rotate-token; default: no expiry)")
+	dryRun := fs.Bool("dry-run", false, "Report what would be deleted without deleting (delete)")
+	yes := fs.Bool("yes", false, "Delete without asking for confirmation (delete)")
+	reason := fs.String("reason", "", "Reason shown in ns info, e.g. \"payment overdue\" (suspend)")
+	blockReads := fs.Bool("block-reads", false, "Also reject reads and subscriptions (suspend)")
+	backup := fs.String("backup", "", "ID of the backup to restore, from ns backups (restore)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `
+Usage: eventodb ns <create|delete|list|info|rotate-token|suspend|resume|backups|restore> [NAMESPACE] [OPTIONS]
+
+Manage namespaces through the RPC API.
+
+Actions:
+  create <ns>         Create a namespace and print its token
+  delete <ns>         Delete a namespace and all its messages
+  list                List namespaces
+  info <ns>           Show a namespace's description, size and state
+  rotate-token <ns>   Replace a namespace's token; the old one stops working
+  suspend <ns>        Reject writes (and with --block-reads, reads) to a namespace, keeping its data
+  resume <ns>         Lift a namespace's suspension
+  backups             List the completed backups of the --token's namespace
+  restore <new-ns>    Create a namespace from one of the --token namespace's backups
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  eventodb ns create tenant-a --description "Tenant A" --token $ADMIN_TOKEN
+  eventodb ns create tenant-a --token $ADMIN_TOKEN --output json | jq -r .token
+  eventodb ns list --url http://127.0.0.1:9090 --token $ADMIN_TOKEN
+  eventodb ns delete tenant-a --dry-run --token $ADMIN_TOKEN
+  eventodb ns rotate-token tenant-a --label ci --expires-in 720h --token $ADMIN_TOKEN
+  eventodb ns suspend tenant-a --reason "payment overdue" --token $ADMIN_TOKEN
+  eventodb ns backups --token $TENANT_A_TOKEN
+  eventodb ns restore tenant-a-restored --backup 20240101T020000Z --token $TENANT_A_TOKEN
+`)
+	}
+
+	if len(args) == 0 || !containsString(nsActions, args[0]) {
+		fs.Usage()
+		return nil, fmt.Errorf("expected create, delete, list, info, rotate-token, suspend, resume, backups or restore")
+	}
+	action := args[0]
+
+	// The namespace ID may come before or after the options
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	var namespace string
+	if action != "list" && action != "backups" {
+		if fs.NArg() == 0 {
+			fs.Usage()
+			return nil, fmt.Errorf("expected a namespace ID")
+		}
+		namespace = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return nil, err
+		}
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if *token == "" {
+		return nil, fmt.Errorf("--token is required")
+	}
+	if *output != "text" && *output != "json" {
+		return nil, fmt.Errorf("--output must be text or json")
+	}
+	if *expiresIn < 0 {
+		return nil, fmt.Errorf("--expires-in must not be negative")
+	}
+	if action == "restore" && *backup == "" {
+		return nil, fmt.Errorf("--backup is required")
+	}
+
+	return &NSConfig{
+		Action:      action,
+		Namespace:   namespace,
+		URL:         *serverURL,
+		Token:       *token,
+		Output:      *output,
+		Description: *description,
+		Shard:       *shard,
+		NewToken:    *newToken,
+		Label:       *label,
+		ExpiresIn:   *expiresIn,
+		DryRun:      *dryRun,
+		Yes:         *yes,
+		Reason:      *reason,
+		BlockReads:  *blockReads,
+		Backup:      *backup,
+	}, nil
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func runNS(cfg *NSConfig) error {
+	ctx := context.Background()
+	client := newRPCClient(cfg.URL, cfg.Token)
+
+	var method string
+	var args []interface{}
+	switch cfg.Action {
+	case "create":
+		opts := map[string]interface{}{}
+		if cfg.Description != "" {
+			opts["description"] = cfg.Description
+		}
+		if cfg.Shard != "" {
+			opts["shard"] = cfg.Shard
+		}
+		addTokenOptions(cfg, opts)
+		method, args = "ns.create", []interface{}{cfg.Namespace, opts}
+	case "delete":
+		if !cfg.DryRun && !cfg.Yes {
+			confirmed, err := confirmNSDelete(cfg.Namespace)
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				fmt.Fprintf(os.Stderr, "Delete cancelled.\n")
+				return nil
+			}
+		}
+		method, args = "ns.delete", []interface{}{cfg.Namespace, map[string]interface{}{"dryRun": cfg.DryRun}}
+	case "list":
+		method = "ns.list"
+	case "info":
+		method, args = "ns.info", []interface{}{cfg.Namespace}
+	case "rotate-token":
+		opts := map[string]interface{}{}
+		addTokenOptions(cfg, opts)
+		method, args = "ns.rotateToken", []interface{}{cfg.Namespace, opts}
+	case "suspend":
+		opts := map[string]interface{}{"blockReads": cfg.BlockReads}
+		if cfg.Reason != "" {
+			opts["reason"] = cfg.Reason
+		}
+		method, args = "ns.suspend", []interface{}{cfg.Namespace, opts}
+	case "resume":
+		method, args = "ns.resume", []interface{}{cfg.Namespace}
+	case "backups":
+		method = "ns.backups.list"
+	case "restore":
+		opts := map[string]interface{}{}
+		if cfg.Description != "" {
+			opts["description"] = cfg.Description
+		}
+		method, args = "ns.backups.restore", []interface{}{cfg.Backup, cfg.Namespace, opts}
+	}
+
+	var result json.RawMessage
+	if err := client.call(ctx, &result, method, args...); err != nil {
+		return err
+	}
+
+	if cfg.Output == "json" {
+		var out interface{}
+		if err := json.Unmarshal(result, &out); err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	return printNSResult(os.Stdout, cfg, result)
+}
+
+// addTokenOptions sets the token options of ns.create and ns.rotateToken
+func addTokenOptions(cfg *NSConfig, opts map[string]interface{}) {
+	if cfg.NewToken != "" {
+		opts["token"] = cfg.NewToken
+	}
+	if cfg.Label != "" {
+		opts["label"] = cfg.Label
+	}
+	if cfg.ExpiresIn > 0 {
+		opts["expiresAt"] = time.Now().Add(cfg.ExpiresIn).UTC().Format(time.RFC3339)
+	}
+}
+
+// confirmNSDelete asks on stderr before a namespace is deleted
+func confirmNSDelete(namespace string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "WARNING: this will DELETE namespace '%s' and ALL ITS MESSAGES.\n", namespace)
+	fmt.Fprintf(os.Stderr, "This action cannot be undone. Use --dry-run to see what would be deleted.\n\n")
+	fmt.Fprintf(os.Stderr, "Are you sure you want to continue? [y/N]: ")
+
+	reader := bufio.NewReader(os.Stdin)
+	response, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes", nil
+}
+
+// printNSResult writes the text output of an ns action
+func printNSResult(out io.Writer, cfg *NSConfig, result json.RawMessage) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	switch cfg.Action {
+	case "create", "rotate-token":
+		var created struct {
+			Namespace string `json:"namespace"`
+			Token     string `json:"token"`
+			Shard     string `json:"shard"`
+			Label     string `json:"label"`
+			ExpiresAt string `json:"expiresAt"`
+		}
+		if err := json.Unmarshal(result, &created); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Namespace:\t%s\n", created.Namespace)
+		if created.Shard != "" {
+			fmt.Fprintf(w, "Shard:\t%s\n", created.Shard)
+		}
+		fmt.Fprintf(w, "Token:\t%s\n", created.Token)
+		if created.Label != "" {
+			fmt.Fprintf(w, "Label:\t%s\n", created.Label)
+		}
+		if created.ExpiresAt != "" {
+			fmt.Fprintf(w, "Expires:\t%s\n", created.ExpiresAt)
+		}
+
+	case "delete":
+		var deleted struct {
+			DryRun          bool  `json:"dryRun"`
+			Messages        int64 `json:"messages"`
+			Streams         int64 `json:"streams"`
+			Bytes           int64 `json:"bytes"`
+			MessagesDeleted int64 `json:"messagesDeleted"`
+		}
+		if err := json.Unmarshal(result, &deleted); err != nil {
+			return err
+		}
+		if deleted.DryRun {
+			fmt.Fprintf(w, "Dry run, nothing was deleted. Deleting namespace %s would remove:\n", cfg.Namespace)
+			fmt.Fprintf(w, "  Messages:\t%d\n", deleted.Messages)
+			fmt.Fprintf(w, "  Streams:\t%d\n", deleted.Streams)
+			fmt.Fprintf(w, "  Payload size:\t%s\n", formatBytes(deleted.Bytes))
+			return nil
+		}
+		fmt.Fprintf(w, "Deleted namespace %s (%d messages)\n", cfg.Namespace, deleted.MessagesDeleted)
+
+	case "list":
+		var namespaces []NamespaceInfo
+		if err := json.Unmarshal(result, &namespaces); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "NAMESPACE\tCREATED\tDESCRIPTION\n")
+		for _, ns := range namespaces {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", ns.Namespace, ns.CreatedAt, ns.Description)
+		}
+
+	case "info":
+		var ns NamespaceInfo
+		if err := json.Unmarshal(result, &ns); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Namespace:\t%s\n", ns.Namespace)
+		fmt.Fprintf(w, "Description:\t%s\n", ns.Description)
+		fmt.Fprintf(w, "Created:\t%s\n", ns.CreatedAt)
+		fmt.Fprintf(w, "Messages:\t%d\n", ns.MessageCount)
+		if ns.Shard != "" {
+			fmt.Fprintf(w, "Shard:\t%s\n", ns.Shard)
+		}
+		frozen := "no"
+		if ns.Frozen != nil {
+			frozen = "since " + ns.Frozen.Since
+			if ns.Frozen.Reason != "" {
+				frozen += " (" + ns.Frozen.Reason + ")"
+			}
+		}
+		fmt.Fprintf(w, "Frozen:\t%s\n", frozen)
+		fmt.Fprintf(w, "Suspended:\t%s\n", describeSuspension(ns.Suspended))
+
+	case "suspend":
+		var state api.SuspensionState
+		if err := json.Unmarshal(result, &state); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Suspended namespace %s %s\n", cfg.Namespace, describeSuspension(&state))
+
+	case "resume":
+		fmt.Fprintf(w, "Resumed namespace %s\n", cfg.Namespace)
+
+	case "backups":
+		var backups []struct {
+			api.BackupRecord
+			Available bool `json:"available"`
+		}
+		if err := json.Unmarshal(result, &backups); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "ID\tCREATED\tTRIGGER\tMESSAGES\tSIZE\tARCHIVE\n")
+		for _, b := range backups {
+			archive := b.Archive
+			if !b.Available {
+				archive += " (deleted)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", b.ID, b.CreatedAt, b.Trigger, b.Messages, formatBytes(b.Bytes), archive)
+		}
+
+	case "restore":
+		var restored struct {
+			Namespace string `json:"namespace"`
+			Token     string `json:"token"`
+			Source    string `json:"source"`
+			Restored  int64  `json:"restored"`
+		}
+		if err := json.Unmarshal(result, &restored); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Namespace:\t%s\n", restored.Namespace)
+		fmt.Fprintf(w, "Restored:\t%d messages from backup %s of %s\n", restored.Restored, cfg.Backup, restored.Source)
+		fmt.Fprintf(w, "Token:\t%s\n", restored.Token)
+	}
+	return nil
+}
+
+// describeSuspension formats a suspension state for text output
+func describeSuspension(state *api.SuspensionState) string {
+	if state == nil {
+		return "no"
+	}
+	desc := "since " + state.Since
+	if state.Reason != "" {
+		desc += " (" + state.Reason + ")"
+	}
+	if state.BlockReads {
+		desc += ", reads blocked"
+	} else {
+		desc += ", writes only"
+	}
+	return desc
+}
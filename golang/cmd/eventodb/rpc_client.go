@@ -0,0 +1,71 @@
+// Package main provides the RPC client used by CLI commands.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/api"
+)
+
+// rpcClient calls the RPC API of a server with a namespace token
+type rpcClient struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// newRPCClient creates a client for the server at url
+func newRPCClient(url, token string) *rpcClient {
+	return &rpcClient{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// call invokes method with args and decodes the result into result, which
+// may be nil. RPC errors are returned as "CODE: message".
+func (c *rpcClient) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	body, err := json.Marshal(append([]interface{}{method}, args...))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url+"/rpc", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errResp api.ErrorResponse
+		if json.Unmarshal(respBody, &errResp) == nil && errResp.Error != nil {
+			return fmt.Errorf("%s: %s", errResp.Error.Code, errResp.Error.Message)
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	return nil
+}
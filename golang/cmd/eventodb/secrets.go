@@ -0,0 +1,35 @@
+// Package main provides loading of credentials from files and secret managers.
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/secrets"
+)
+
+// resolveSecretFlag returns the credential given to a flag directly, through
+// its -file variant, or as a secret reference such as
+// vault://secret/data/eventodb#dbUrl or awssm://prod/eventodb#token. Files
+// and references keep the credential out of process arguments and the
+// environment.
+func resolveSecretFlag(name, value, file string) (string, error) {
+	if value != "" && file != "" {
+		return "", fmt.Errorf("set only one of --%s and --%s-file", name, name)
+	}
+	if file != "" {
+		v, err := secrets.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read --%s-file: %w", name, err)
+		}
+		value = v
+	}
+	if !secrets.IsReference(value) {
+		return value, nil
+	}
+	v, err := secrets.Resolve(context.Background(), value)
+	if err != nil {
+		return "", fmt.Errorf("--%s: %w", name, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,1155 @@
+// Package main provides the serve command, which runs the EventoDB server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/api"
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/eventodb/eventodb/internal/store/postgres"
+	"github.com/eventodb/eventodb/internal/systemd"
+	"github.com/valyala/fasthttp"
+)
+
+// serveConfig holds the server flags, see printHelp
+type serveConfig struct {
+	port                  int
+	grpcPort              int
+	testMode              bool
+	defaultToken          string
+	tokenFile             string
+	dbURL                 string
+	dbURLFile             string
+	dataDir               string
+	dbType                string
+	autoMigrate           bool
+	logLevel              string
+	logFormat             string
+	notifyConfig          string
+	udpPort               int
+	udpNamespaces         string
+	mqttConfig            string
+	amqpConfig            string
+	awsSourceConfig       string
+	webhookConfig         string
+	blueprintDir          string
+	logShipping           bool
+	exportSchedules       bool
+	ticks                 bool
+	exportDir             string
+	mirroring             bool
+	edgeSyncing           bool
+	snapshotting          bool
+	readOnly              bool
+	pubsubBackend         string
+	pubsubURL             string
+	shardList             string
+	pgMessageTables       int
+	pgTableAffinity       string
+	positionAllocator     string
+	nodeID                string
+	routeNodes            string
+	breakerThreshold      int
+	breakerCooldown       time.Duration
+	scrubInterval         time.Duration
+	scrubPause            time.Duration
+	compactionInterval    time.Duration
+	jobWorkers            int
+	jobNamespaceWorkers   int
+	attestationKey        string
+	attestationKeyFile    string
+	attestationInterval   time.Duration
+	storageSampleInterval time.Duration
+	writeQueueDir         string
+	writeQueueMax         int
+	maxResultMB           int
+	responseMemoryMB      int
+	pluginDir             string
+	pluginTimeout         time.Duration
+	pluginMemoryMB        int
+	streamNameValidation  bool
+	streamNameMaxLength   int
+	writeRate             int
+	writeBurst            int
+	namespaceWriteRate    int
+	namespaceWriteBurst   int
+	storeMaxConcurrency   int
+	storeTargetLatency    time.Duration
+	storeMaxWait          time.Duration
+	httpConcurrency       int
+	enablePprof           bool
+	pprofAddr             string
+	adminAddr             string
+	metricsPushURL        string
+	metricsPushInterval   time.Duration
+	metricsPushLabels     string
+	encryptionKeyFile     string
+	encryptionKeyCommand  string
+}
+
+// parseServeFlags parses the server flags in os.Args, with environment
+// variable fallbacks
+func parseServeFlags() *serveConfig {
+	flag.Usage = printHelp
+	c := &serveConfig{}
+	flag.IntVar(&c.port, "port", getEnvInt("EVENTODB_PORT", defaultPort), "")
+	flag.IntVar(&c.grpcPort, "grpc-port", getEnvInt("EVENTODB_GRPC_PORT", 0), "")
+	flag.BoolVar(&c.testMode, "test-mode", getEnvBool("EVENTODB_TEST_MODE", false), "")
+	flag.StringVar(&c.defaultToken, "token", getEnv("EVENTODB_TOKEN", ""), "")
+	flag.StringVar(&c.tokenFile, "token-file", getEnv("EVENTODB_TOKEN_FILE", ""), "")
+	flag.StringVar(&c.dbURL, "db-url", getEnv("EVENTODB_DB_URL", ""), "")
+	flag.StringVar(&c.dbURLFile, "db-url-file", getEnv("EVENTODB_DB_URL_FILE", ""), "")
+	flag.StringVar(&c.dataDir, "data-dir", getEnv("EVENTODB_DATA_DIR", ""), "")
+	flag.StringVar(&c.dbType, "db-type", getEnv("EVENTODB_DB_TYPE", ""), "")
+	flag.BoolVar(&c.autoMigrate, "auto-migrate", getEnvBool("EVENTODB_AUTO_MIGRATE", true), "")
+	flag.StringVar(&c.logLevel, "log-level", getEnv("EVENTODB_LOG_LEVEL", "info"), "")
+	flag.StringVar(&c.logFormat, "log-format", getEnv("EVENTODB_LOG_FORMAT", "console"), "")
+	flag.StringVar(&c.notifyConfig, "notify-config", getEnv("EVENTODB_NOTIFY_CONFIG", ""), "")
+	flag.IntVar(&c.udpPort, "udp-port", getEnvInt("EVENTODB_UDP_PORT", 0), "")
+	flag.StringVar(&c.udpNamespaces, "udp-namespaces", getEnv("EVENTODB_UDP_NAMESPACES", ""), "")
+	flag.StringVar(&c.mqttConfig, "mqtt-config", getEnv("EVENTODB_MQTT_CONFIG", ""), "")
+	flag.StringVar(&c.amqpConfig, "amqp-config", getEnv("EVENTODB_AMQP_CONFIG", ""), "")
+	flag.StringVar(&c.awsSourceConfig, "aws-source-config", getEnv("EVENTODB_AWS_SOURCE_CONFIG", ""), "")
+	flag.StringVar(&c.webhookConfig, "webhook-config", getEnv("EVENTODB_WEBHOOK_CONFIG", ""), "")
+	flag.StringVar(&c.blueprintDir, "blueprint-dir", getEnv("EVENTODB_BLUEPRINT_DIR", ""), "")
+	flag.BoolVar(&c.logShipping, "log-shipping", getEnvBool("EVENTODB_LOG_SHIPPING", true), "")
+	flag.BoolVar(&c.exportSchedules, "export-schedules", getEnvBool("EVENTODB_EXPORT_SCHEDULES", true), "")
+	flag.BoolVar(&c.ticks, "ticks", getEnvBool("EVENTODB_TICKS", true), "")
+	flag.StringVar(&c.exportDir, "export-dir", getEnv("EVENTODB_EXPORT_DIR", ""), "")
+	flag.BoolVar(&c.mirroring, "mirroring", getEnvBool("EVENTODB_MIRRORING", true), "")
+	flag.BoolVar(&c.edgeSyncing, "edge-sync", getEnvBool("EVENTODB_EDGE_SYNC", true), "")
+	flag.BoolVar(&c.snapshotting, "snapshots", getEnvBool("EVENTODB_SNAPSHOTS", true), "")
+	flag.BoolVar(&c.readOnly, "read-only", getEnvBool("EVENTODB_READ_ONLY", false), "")
+	flag.StringVar(&c.pubsubBackend, "pubsub", getEnv("EVENTODB_PUBSUB", "local"), "")
+	flag.StringVar(&c.pubsubURL, "pubsub-url", getEnv("EVENTODB_PUBSUB_URL", ""), "")
+	flag.StringVar(&c.shardList, "shards", getEnv("EVENTODB_SHARDS", ""), "")
+	flag.IntVar(&c.pgMessageTables, "pg-message-tables", getEnvInt("EVENTODB_PG_MESSAGE_TABLES", 0), "")
+	flag.StringVar(&c.pgTableAffinity, "pg-table-affinity", getEnv("EVENTODB_PG_TABLE_AFFINITY", string(postgres.AffinityStream)), "")
+	flag.StringVar(&c.positionAllocator, "position-allocator", getEnv("EVENTODB_POSITION_ALLOCATOR", ""), "")
+	flag.StringVar(&c.nodeID, "node-id", getEnv("EVENTODB_NODE_ID", ""), "")
+	flag.StringVar(&c.routeNodes, "route-nodes", getEnv("EVENTODB_ROUTE_NODES", ""), "")
+	flag.IntVar(&c.breakerThreshold, "breaker-threshold", getEnvInt("EVENTODB_BREAKER_THRESHOLD", store.DefaultBreakerThreshold), "")
+	flag.DurationVar(&c.breakerCooldown, "breaker-cooldown", getEnvDuration("EVENTODB_BREAKER_COOLDOWN", store.DefaultBreakerCooldown), "")
+	flag.DurationVar(&c.scrubInterval, "scrub-interval", getEnvDuration("EVENTODB_SCRUB_INTERVAL", 6*time.Hour), "")
+	flag.DurationVar(&c.scrubPause, "scrub-pause", getEnvDuration("EVENTODB_SCRUB_PAUSE", 20*time.Millisecond), "")
+	flag.DurationVar(&c.compactionInterval, "compaction-interval", getEnvDuration("EVENTODB_COMPACTION_INTERVAL", time.Hour), "")
+	flag.IntVar(&c.jobWorkers, "job-workers", getEnvInt("EVENTODB_JOB_WORKERS", api.DefaultJobWorkers), "")
+	flag.IntVar(&c.jobNamespaceWorkers, "job-namespace-workers", getEnvInt("EVENTODB_JOB_NAMESPACE_WORKERS", api.DefaultJobsPerNamespace), "")
+	flag.StringVar(&c.attestationKey, "attestation-key", getEnv("EVENTODB_ATTESTATION_KEY", ""), "")
+	flag.StringVar(&c.attestationKeyFile, "attestation-key-file", getEnv("EVENTODB_ATTESTATION_KEY_FILE", ""), "")
+	flag.DurationVar(&c.attestationInterval, "attestation-interval", getEnvDuration("EVENTODB_ATTESTATION_INTERVAL", 24*time.Hour), "")
+	flag.DurationVar(&c.storageSampleInterval, "storage-sample-interval", getEnvDuration("EVENTODB_STORAGE_SAMPLE_INTERVAL", time.Hour), "")
+	flag.StringVar(&c.writeQueueDir, "write-queue-dir", getEnv("EVENTODB_WRITE_QUEUE_DIR", ""), "")
+	flag.IntVar(&c.writeQueueMax, "write-queue-max", getEnvInt("EVENTODB_WRITE_QUEUE_MAX", 10000), "")
+	flag.IntVar(&c.maxResultMB, "max-result-mb", getEnvInt("EVENTODB_MAX_RESULT_MB", api.DefaultMaxResultMB), "")
+	flag.IntVar(&c.responseMemoryMB, "response-memory-mb", getEnvInt("EVENTODB_RESPONSE_MEMORY_MB", api.DefaultResponseMemoryMB), "")
+	flag.StringVar(&c.pluginDir, "plugin-dir", getEnv("EVENTODB_PLUGIN_DIR", ""), "")
+	flag.DurationVar(&c.pluginTimeout, "plugin-timeout", getEnvDuration("EVENTODB_PLUGIN_TIMEOUT", api.DefaultPluginTimeout), "")
+	flag.IntVar(&c.pluginMemoryMB, "plugin-memory-mb", getEnvInt("EVENTODB_PLUGIN_MEMORY_MB", api.DefaultPluginMemoryMB), "")
+	flag.BoolVar(&c.streamNameValidation, "stream-name-validation", getEnvBool("EVENTODB_STREAM_NAME_VALIDATION", true), "")
+	flag.IntVar(&c.streamNameMaxLength, "stream-name-max-length", getEnvInt("EVENTODB_STREAM_NAME_MAX_LENGTH", store.DefaultMaxStreamNameLength), "")
+	flag.IntVar(&c.writeRate, "write-rate", getEnvInt("EVENTODB_WRITE_RATE", 0), "")
+	flag.IntVar(&c.writeBurst, "write-burst", getEnvInt("EVENTODB_WRITE_BURST", 0), "")
+	flag.IntVar(&c.namespaceWriteRate, "namespace-write-rate", getEnvInt("EVENTODB_NAMESPACE_WRITE_RATE", 0), "")
+	flag.IntVar(&c.namespaceWriteBurst, "namespace-write-burst", getEnvInt("EVENTODB_NAMESPACE_WRITE_BURST", 0), "")
+	flag.IntVar(&c.storeMaxConcurrency, "store-max-concurrency", getEnvInt("EVENTODB_STORE_MAX_CONCURRENCY", 0), "")
+	flag.DurationVar(&c.storeTargetLatency, "store-target-latency", getEnvDuration("EVENTODB_STORE_TARGET_LATENCY", store.DefaultTargetLatency), "")
+	flag.DurationVar(&c.storeMaxWait, "store-max-wait", getEnvDuration("EVENTODB_STORE_MAX_WAIT", store.DefaultLimiterMaxWait), "")
+	flag.IntVar(&c.httpConcurrency, "http-concurrency", getEnvInt("EVENTODB_HTTP_CONCURRENCY", 256*1024), "")
+	flag.BoolVar(&c.enablePprof, "enable-pprof", getEnvBool("EVENTODB_ENABLE_PPROF", false), "")
+	flag.StringVar(&c.pprofAddr, "pprof-addr", getEnv("EVENTODB_PPROF_ADDR", ""), "")
+	flag.StringVar(&c.adminAddr, "admin-addr", getEnv("EVENTODB_ADMIN_ADDR", ""), "")
+	flag.StringVar(&c.metricsPushURL, "metrics-push-url", getEnv("EVENTODB_METRICS_PUSH_URL", ""), "")
+	flag.DurationVar(&c.metricsPushInterval, "metrics-push-interval", getEnvDuration("EVENTODB_METRICS_PUSH_INTERVAL", api.DefaultMetricsPushInterval), "")
+	flag.StringVar(&c.metricsPushLabels, "metrics-push-labels", getEnv("EVENTODB_METRICS_PUSH_LABELS", ""), "")
+	flag.StringVar(&c.encryptionKeyFile, "encryption-key-file", getEnv("EVENTODB_ENCRYPTION_KEY_FILE", ""), "")
+	flag.StringVar(&c.encryptionKeyCommand, "encryption-key-command", getEnv("EVENTODB_ENCRYPTION_KEY_COMMAND", ""), "")
+	flag.Parse()
+	return c
+}
+
+// server holds what serve sets up: the dependencies shared by the server
+// components and the optional components, which are nil when disabled
+type server struct {
+	opts   *serveConfig
+	cfg    *dbConfig
+	st     store.Store
+	pubsub *api.PubSub
+	writes *writePath
+
+	notifier      *api.Notifier
+	jobs          *api.JobScheduler
+	router        *api.Router
+	rpcHandler    *api.RPCHandler
+	importHandler *api.ImportHandler
+
+	relay           api.PubSubRelay
+	udpIngest       *api.UDPIngest
+	mqttBridge      *api.MQTTBridge
+	amqpSink        *api.AMQPSink
+	awsSource       *api.AWSSource
+	webhooks        *api.WebhookPublisher
+	shipper         *api.LogShipper
+	exportScheduler *api.ExportScheduler
+	tickScheduler   *api.TickScheduler
+	mirror          *api.Mirror
+	edgeSync        *api.EdgeSync
+	snapshotter     *api.Snapshotter
+	scrubber        *api.Scrubber
+	compactor       *api.Compactor
+	attestor        *api.Attestor
+	storageTracker  *api.StorageTracker
+	writeQueue      *api.WriteQueue
+	metricsPusher   *api.MetricsPusher
+}
+
+// writePath holds the features shared by all write paths
+type writePath struct {
+	guard       *api.WriteGuard
+	streamNames *store.StreamNamePolicy // nil accepts any name
+	admission   *api.AdmissionController
+	templates   *api.MetadataTemplates
+	ids         *api.MessageIDs
+	plugins     *api.PluginHost
+}
+
+// writePathUser is a write path configured by writePath
+type writePathUser interface {
+	SetWriteGuard(g *api.WriteGuard)
+	SetStreamNamePolicy(p *store.StreamNamePolicy)
+	SetAdmission(a *api.AdmissionController)
+	SetMetadataTemplates(m *api.MetadataTemplates)
+	SetMessageIDs(m *api.MessageIDs)
+	SetPluginHost(p *api.PluginHost)
+}
+
+// apply sets the write path features of u
+func (w *writePath) apply(u writePathUser) {
+	u.SetWriteGuard(w.guard)
+	u.SetStreamNamePolicy(w.streamNames)
+	u.SetAdmission(w.admission)
+	u.SetMetadataTemplates(w.templates)
+	u.SetMessageIDs(w.ids)
+	u.SetPluginHost(w.plugins)
+}
+
+// serve parses the server flags in os.Args, runs the server and returns after
+// a graceful shutdown once shutdown receives. ready is called when the store,
+// the default namespace and the listeners are initialized.
+func serve(shutdown <-chan os.Signal, ready func()) {
+	opts := parseServeFlags()
+
+	// Initialize logger
+	logger.Initialize(opts.logLevel, opts.logFormat)
+
+	// Load credentials from files and secret managers
+	resolveServeSecrets(opts)
+
+	// Initialize the store, sharded over shard databases when configured
+	cfg := parseServeDBConfig(opts)
+	st, sharded, cleanup := openServeStore(opts, cfg)
+	defer cleanup()
+	migrateServeStore(opts, st)
+
+	// Ensure default namespace exists and get/create token
+	token, err := ensureDefaultNamespace(context.Background(), st, opts.defaultToken)
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to ensure default namespace")
+	}
+
+	// Print default namespace token
+	logger.Get().Info().Msg("═══════════════════════════════════════════════════════")
+	logger.Get().Info().Msg("DEFAULT NAMESPACE TOKEN:")
+	logger.Get().Info().Msgf("%s", token)
+	logger.Get().Info().Msg("═══════════════════════════════════════════════════════")
+
+	// Fail fast during database outages and shed bursts of database calls
+	st, breaker, limiter := wrapStoreLimits(opts, st)
+
+	s := &server{opts: opts, cfg: cfg, st: st, pubsub: api.NewPubSub()}
+
+	// Relay write events to other instances (optional)
+	s.startPubSubRelay()
+
+	// Namespace metadata cache shared by the features configured by it
+	meta := api.NewNamespaceMetadata(st)
+	s.writes = newWritePath(opts, meta)
+	if s.writes.plugins != nil {
+		defer s.writes.plugins.Close()
+	}
+
+	// Create RPC handler
+	s.rpcHandler = api.NewRPCHandler(version, st, s.pubsub)
+	s.rpcHandler.SetNamespaceMetadata(meta)
+	s.writes.apply(s.rpcHandler)
+	results := api.NewResultBudget(int64(opts.maxResultMB)<<20, int64(opts.responseMemoryMB)<<20)
+	s.rpcHandler.SetResultBudget(results)
+
+	// Run background work with per-namespace fairness, reported by sys.jobs
+	s.jobs = api.NewJobScheduler(api.JobSchedulerConfig{Workers: opts.jobWorkers, PerNamespace: opts.jobNamespaceWorkers})
+	s.rpcHandler.SetJobScheduler(s.jobs)
+
+	// Rolling latency percentiles for sys.stats and /metrics
+	latency := api.NewLatencyTracker()
+	s.rpcHandler.SetLatencyTracker(latency)
+	if sharded != nil {
+		s.rpcHandler.SetShards(sharded)
+	}
+	if breaker != nil {
+		s.rpcHandler.SetBreaker(breaker)
+	}
+	if cfg.dataDir != "" && !cfg.testMode {
+		s.rpcHandler.SetDataDir(cfg.dataDir)
+	}
+	s.setupWriteRouting()
+
+	// Create SSE handler
+	sseHandler := api.NewSSEHandler(st, s.pubsub, cfg.testMode)
+	sseHandler.SetNamespaceMetadata(meta)
+	s.rpcHandler.SetDurableSubscriptions(sseHandler.Durable)
+
+	// Create import handler
+	s.importHandler = api.NewImportHandler(st)
+	s.importHandler.SetWriteGuard(s.writes.guard)
+	s.importHandler.SetAdmission(s.writes.admission)
+
+	// Create export handler
+	exportHandler := api.NewExportHandler(st, s.pubsub)
+
+	// Create system event notifier (optional, nil discards events)
+	if opts.notifyConfig != "" {
+		notifyCfg, err := api.LoadNotifierConfig(opts.notifyConfig)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to load notifier config")
+		}
+		s.notifier, err = api.NewNotifier(*notifyCfg)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Invalid notifier config")
+		}
+		defer s.notifier.Close()
+	}
+
+	// Start the optional components
+	s.startUDPIngest()
+	s.startConnectors()
+	s.startWebhooks()
+	s.startLogShipping()
+	s.startSchedulers()
+	s.startMirroring()
+	s.startMaintenance()
+	namespaces := s.startWriteQueue()
+
+	// Create fasthttp middleware
+	authMiddlewareFast := api.AuthMiddlewareFast(namespaces, cfg.testMode)
+
+	// Create wrapped RPC handler with auth and logging for fasthttp
+	rpcHandlerFast := api.FastHTTPRPCHandler(s.rpcHandler, cfg.testMode)
+	rpcWithAuthFast := authMiddlewareFast(rpcHandlerFast)
+	rpcWithLoggingFast := api.LoggingMiddlewareFast(rpcWithAuthFast)
+
+	// Create SSE handler wrapper with auth
+	sseHandlerFast := api.FastHTTPSSEHandler(sseHandler, cfg.testMode)
+	sseWithAuthFast := authMiddlewareFast(sseHandlerFast)
+	sseWithLoggingFast := api.LoggingMiddlewareFast(sseWithAuthFast)
+
+	// Create import handler wrapper with auth
+	importWithAuthFast := authMiddlewareFast(s.importHandler.HandleImport)
+	importWithLoggingFast := api.LoggingMiddlewareFast(importWithAuthFast)
+
+	// Create export handler wrapper with auth
+	exportWithAuthFast := authMiddlewareFast(exportHandler.HandleExport)
+	exportWithLoggingFast := api.LoggingMiddlewareFast(exportWithAuthFast)
+
+	// Create readiness and metrics handlers (no auth, like /health)
+	readyzHandler := api.ReadyzHandler(breaker, s.writeQueue)
+	metricsSources := api.MetricsSources{
+		Breaker:   breaker,
+		Queue:     s.writeQueue,
+		Scrubber:  s.scrubber,
+		Compactor: s.compactor,
+		Admission: s.writes.admission,
+		Limiter:   limiter,
+		Latency:   latency,
+		Results:   results,
+		Jobs:      s.jobs,
+		Durable:   sseHandler.Durable,
+	}
+	metricsHandler := api.MetricsHandler(metricsSources)
+
+	// Push metrics to a remote-write endpoint or StatsD (optional)
+	s.startMetricsPush(metricsSources)
+
+	// Take over sockets passed by systemd socket activation (optional). Sockets
+	// named admin and pprof (FileDescriptorName=) serve those listeners and the
+	// remaining one the main port.
+	inherited, err := systemd.Listeners()
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to use sockets from systemd")
+	}
+	adminListen := opts.adminAddr != "" || inherited["admin"] != nil
+	pprofListen := opts.pprofAddr != "" || inherited["pprof"] != nil
+
+	// Create pprof handler, restricted to the default namespace token (optional)
+	var pprofHandler fasthttp.RequestHandler
+	if opts.enablePprof {
+		pprofHandler = api.LoggingMiddlewareFast(api.PprofHandlerFast(authMiddlewareFast))
+	} else if pprofListen {
+		logger.Get().Fatal().Msg("--pprof-addr requires --enable-pprof")
+	}
+
+	healthHandler := func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("application/json")
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		fmt.Fprintf(ctx, `{"status":"ok"}`)
+	}
+	versionHandler := func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("application/json")
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		fmt.Fprintf(ctx, `{"version":"%s","protocol":"%s"}`, version, api.ProtocolVersion)
+	}
+	schemaHandler := api.SchemaHandler()
+
+	// Set up fasthttp routes. The admin endpoints (metrics, import, pprof and
+	// the ns.* and sys.profile RPC methods) move to --admin-addr when it is set.
+	publicRoutes := map[string]fasthttp.RequestHandler{
+		"/health":    healthHandler,
+		"/readyz":    readyzHandler,
+		"/version":   versionHandler,
+		"/schema":    schemaHandler,
+		"/rpc":       rpcWithLoggingFast,
+		"/subscribe": sseWithLoggingFast,
+		"/export":    exportWithLoggingFast,
+	}
+	adminRoutes := map[string]fasthttp.RequestHandler{
+		"/health":  healthHandler,
+		"/readyz":  readyzHandler,
+		"/version": versionHandler,
+		"/schema":  schemaHandler,
+		"/metrics": metricsHandler,
+		"/rpc":     rpcWithLoggingFast,
+		"/import":  importWithLoggingFast,
+	}
+
+	// pprof gets its own listener with --pprof-addr, else joins the admin endpoints
+	var publicPprof, adminPprof fasthttp.RequestHandler
+	if !adminListen {
+		maps.Copy(publicRoutes, adminRoutes)
+		if !pprofListen {
+			publicPprof = pprofHandler
+		}
+	} else {
+		publicRoutes["/rpc"] = api.DataPathOnlyFast(rpcWithLoggingFast)
+		if !pprofListen {
+			adminPprof = pprofHandler
+		}
+	}
+	requestHandler := newRouter(publicRoutes, publicPprof)
+
+	// Listen before serving, so systemd only hears we are ready once we are
+	var adminLn, pprofLn net.Listener
+	if adminListen {
+		if adminLn, err = listen(inherited, "admin", opts.adminAddr); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to listen on admin address")
+		}
+	}
+	if pprofHandler != nil && pprofListen {
+		if pprofLn, err = listen(inherited, "pprof", opts.pprofAddr); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to listen on pprof address")
+		}
+	}
+	if len(inherited) > 1 {
+		logger.Get().Fatal().Int("sockets", len(inherited)).Msg("systemd passed more than one socket for the main port")
+	}
+	mainSocket := ""
+	for name := range inherited {
+		mainSocket = name
+	}
+	ln, err := listen(inherited, mainSocket, fmt.Sprintf(":%d", opts.port))
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to listen")
+	}
+
+	// Create fasthttp server with optimized settings
+	server := &fasthttp.Server{
+		Handler:                       requestHandler,
+		Name:                          "EventoDB/" + version,
+		ReadTimeout:                   30 * time.Second,
+		WriteTimeout:                  0, // Disabled for SSE support
+		IdleTimeout:                   120 * time.Second,
+		MaxRequestBodySize:            100 * 1024 * 1024,    // 100 MB for bulk imports
+		Concurrency:                   opts.httpConcurrency, // Default: 256K concurrent connections
+		DisableKeepalive:              false,
+		TCPKeepalive:                  true,
+		TCPKeepalivePeriod:            30 * time.Second,
+		MaxConnsPerIP:                 0, // No limit
+		MaxRequestsPerConn:            0, // No limit
+		ReduceMemoryUsage:             false,
+		GetOnly:                       false,
+		DisableHeaderNamesNormalizing: false,
+	}
+
+	// Start server in a goroutine
+	serverErrors := make(chan error, 4)
+	go func() {
+		logger.Get().Info().
+			Str("address", ln.Addr().String()).
+			Str("version", version).
+			Str("engine", "fasthttp").
+			Bool("socketActivated", mainSocket != "").
+			Msg("EventoDB server starting")
+		serverErrors <- server.Serve(ln)
+	}()
+
+	// Serve the admin endpoints on their own listener (optional)
+	var adminServer *fasthttp.Server
+	if adminLn != nil {
+		adminServer = newInternalServer(newRouter(adminRoutes, adminPprof))
+		adminServer.MaxRequestBodySize = 100 * 1024 * 1024 // 100 MB for bulk imports
+		go func() {
+			logger.Get().Info().Str("address", adminLn.Addr().String()).Msg("Admin listener starting")
+			serverErrors <- adminServer.Serve(adminLn)
+		}()
+	}
+
+	// Serve pprof on its own listener, keeping it off the public port (optional)
+	var pprofServer *fasthttp.Server
+	if pprofLn != nil {
+		pprofServer = newInternalServer(newRouter(nil, pprofHandler))
+		go func() {
+			serverErrors <- pprofServer.Serve(pprofLn)
+		}()
+	}
+	// Serve the RPC methods over gRPC (optional). Like the main port, it
+	// serves only data-path methods when an admin listener is configured.
+	var grpcServer *api.GRPCServer
+	if opts.grpcPort > 0 {
+		grpcLn, err := net.Listen("tcp4", fmt.Sprintf(":%d", opts.grpcPort))
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to listen on gRPC port")
+		}
+		grpcServer = api.NewGRPCServer(s.rpcHandler, sseHandler, namespaces, cfg.testMode)
+		grpcServer.SetDataPathOnly(adminListen)
+		go func() {
+			logger.Get().Info().Str("address", grpcLn.Addr().String()).Msg("gRPC listener starting")
+			serverErrors <- grpcServer.Serve(grpcLn)
+		}()
+	}
+	if pprofHandler != nil {
+		pprofAt := ln.Addr()
+		if pprofLn != nil {
+			pprofAt = pprofLn.Addr()
+		} else if adminLn != nil {
+			pprofAt = adminLn.Addr()
+		}
+		logger.Get().Info().Str("address", pprofAt.String()).Msg("pprof profiling endpoints enabled at /debug/pprof/")
+	}
+
+	ready()
+
+	// Wait for shutdown signal or server error
+	select {
+	case err := <-serverErrors:
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Server error")
+		}
+
+	case sig := <-shutdown:
+		logger.Get().Info().Str("signal", sig.String()).Msg("Shutdown signal received")
+		systemd.Notify("STOPPING=1")
+
+		// Stop bridges and connectors before closing pubsub
+		s.close()
+
+		// Close all SSE subscriptions first - this unblocks all SSE handlers
+		s.pubsub.Close()
+
+		// Attempt graceful shutdown
+		if err := server.Shutdown(); err != nil {
+			logger.Get().Error().Err(err).Msg("Graceful shutdown failed")
+		}
+		if adminServer != nil {
+			adminServer.Shutdown()
+		}
+		if pprofServer != nil {
+			pprofServer.Shutdown()
+		}
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+
+		logger.Get().Info().Msg("Server stopped")
+	}
+}
+
+// resolveServeSecrets loads the credentials of opts from files and secret managers
+func resolveServeSecrets(opts *serveConfig) {
+	var err error
+	if opts.defaultToken, err = resolveSecretFlag("token", opts.defaultToken, opts.tokenFile); err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to load token")
+	}
+	if opts.dbURL, err = resolveSecretFlag("db-url", opts.dbURL, opts.dbURLFile); err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to load database URL")
+	}
+	if opts.pubsubURL, err = resolveSecretFlag("pubsub-url", opts.pubsubURL, ""); err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to load pubsub URL")
+	}
+	if opts.attestationKey, err = resolveSecretFlag("attestation-key", opts.attestationKey, opts.attestationKeyFile); err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to load attestation key")
+	}
+}
+
+// parseServeDBConfig returns the database configuration of opts, including
+// the encryption key of Pebble data directories
+func parseServeDBConfig(opts *serveConfig) *dbConfig {
+	cfg, err := parseDBConfig(opts.dbURL, opts.dataDir, opts.dbType, opts.testMode)
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid database configuration")
+	}
+	cfg.manualMigrations = !opts.autoMigrate
+	if cfg.encryptionKey, err = loadEncryptionKey(opts.encryptionKeyFile, opts.encryptionKeyCommand); err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid encryption key")
+	}
+	cfg.messageTables = opts.pgMessageTables
+	cfg.positionAllocator = opts.positionAllocator
+	if cfg.tableAffinity, err = postgres.ParseAffinity(opts.pgTableAffinity); err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid --pg-table-affinity")
+	}
+	return cfg
+}
+
+// openServeStore creates the store of cfg and, with --shards, spreads
+// namespaces over shard databases with it as the catalog. The shards are nil
+// when namespaces are not sharded; cleanup closes the store.
+func openServeStore(opts *serveConfig, cfg *dbConfig) (st store.Store, sharded *store.ShardedStore, cleanup func()) {
+	st, cleanup, err := createStore(cfg)
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to create store")
+	}
+	if opts.shardList == "" {
+		return st, nil, cleanup
+	}
+
+	shards, err := parseShards(opts.shardList)
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid --shards")
+	}
+	sharded, err = store.NewShardedStore(st, shards, func(url string) (store.Store, error) {
+		url, err := resolveSecretFlag("shards", url, "")
+		if err != nil {
+			return nil, err
+		}
+		shardCfg, err := parseDBConfig(url, "", opts.dbType, false)
+		if err != nil {
+			return nil, err
+		}
+		if shardCfg.dbType == "pebble" {
+			shardCfg.encryptionKey = cfg.encryptionKey
+		}
+		if shardCfg.dbType == "postgres" {
+			shardCfg.messageTables, shardCfg.tableAffinity = cfg.messageTables, cfg.tableAffinity
+		}
+		shardStore, _, err := createStore(shardCfg)
+		return shardStore, err
+	})
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid shard configuration")
+	}
+	logger.Get().Info().Int("shards", len(shards)).Msg("Namespaces are sharded, --db-url holds the catalog")
+	return sharded, sharded, func() { sharded.Close() }
+}
+
+// migrateServeStore applies pending namespace schema migrations, or with
+// --auto-migrate=false checks that they were applied by migrate-db
+func migrateServeStore(opts *serveConfig, st store.Store) {
+	if !opts.autoMigrate {
+		if err := checkSchemaVersions(context.Background(), st); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Namespace schema is out of date")
+		}
+		return
+	}
+	migrationsApplied, err := st.MigrateNamespaces(context.Background())
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to migrate namespaces")
+	}
+	if migrationsApplied > 0 {
+		logger.Get().Info().
+			Int("count", migrationsApplied).
+			Msg("Applied namespace schema migrations")
+	}
+}
+
+// wrapStoreLimits wraps st in the circuit breaker, which fails fast during
+// database outages instead of piling up hanging requests, and the limiter,
+// which bounds concurrent database calls so bursts are shed instead of
+// queued. Each is nil when disabled.
+func wrapStoreLimits(opts *serveConfig, st store.Store) (store.Store, *store.BreakerStore, *store.LimiterStore) {
+	var breaker *store.BreakerStore
+	if opts.breakerThreshold > 0 {
+		breaker = store.NewBreakerStore(st, store.BreakerConfig{
+			Threshold: opts.breakerThreshold,
+			Cooldown:  opts.breakerCooldown,
+			OnStateChange: func(from, to string) {
+				if to == store.BreakerOpen {
+					logger.Get().Error().Msg("Database unavailable, circuit breaker opened")
+				} else {
+					logger.Get().Info().Msg("Database reachable again, circuit breaker closed")
+				}
+			},
+		})
+		st = breaker
+	}
+
+	var limiter *store.LimiterStore
+	if opts.storeMaxConcurrency > 0 {
+		limiter = store.NewLimiterStore(st, store.ConcurrencyConfig{
+			MaxLimit:      opts.storeMaxConcurrency,
+			TargetLatency: opts.storeTargetLatency,
+			MaxWait:       opts.storeMaxWait,
+		})
+		st = limiter
+	}
+	return st, breaker, limiter
+}
+
+// newWriteAdmission creates the write admission control, nil when no rate is
+// configured
+func newWriteAdmission(opts *serveConfig) *api.AdmissionController {
+	admission := api.NewAdmissionController(api.AdmissionConfig{
+		GlobalRate:     float64(opts.writeRate),
+		GlobalBurst:    opts.writeBurst,
+		NamespaceRate:  float64(opts.namespaceWriteRate),
+		NamespaceBurst: opts.namespaceWriteBurst,
+	})
+	if admission != nil {
+		logger.Get().Info().
+			Int("write_rate", opts.writeRate).
+			Int("namespace_write_rate", opts.namespaceWriteRate).
+			Msg("Write admission control enabled")
+	}
+	return admission
+}
+
+// newWritePath creates the features shared by all write paths. The caller
+// closes the plugin host, if any.
+func newWritePath(opts *serveConfig, meta *api.NamespaceMetadata) *writePath {
+	// Write guard for frozen namespaces and --read-only
+	w := &writePath{guard: api.NewWriteGuard(meta)}
+	w.guard.SetReadOnly(opts.readOnly)
+	if opts.readOnly {
+		logger.Get().Warn().Msg("Server is in read-only mode, writes are rejected")
+	}
+
+	// Stream name rules for client writes
+	if opts.streamNameValidation {
+		w.streamNames = &store.StreamNamePolicy{MaxLength: opts.streamNameMaxLength}
+	}
+
+	// Per-type default metadata and ID strategies
+	w.templates = api.NewMetadataTemplates(meta)
+	w.ids = api.NewMessageIDs(meta)
+
+	// WASM write plugins (nil when disabled)
+	if opts.pluginDir != "" {
+		var err error
+		w.plugins, err = api.NewPluginHost(meta, api.PluginHostConfig{
+			Dir:      opts.pluginDir,
+			Timeout:  opts.pluginTimeout,
+			MemoryMB: opts.pluginMemoryMB,
+		})
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to start plugin host")
+		}
+		logger.Get().Info().Str("dir", opts.pluginDir).Msg("Write plugins enabled")
+	}
+
+	w.admission = newWriteAdmission(opts)
+	return w
+}
+
+// startPubSubRelay relays write events to other instances over --pubsub-url
+// or, with --pubsub=postgres, the Postgres database (optional)
+func (s *server) startPubSubRelay() {
+	relayURL := s.opts.pubsubURL
+	switch s.opts.pubsubBackend {
+	case "local":
+		// In-process delivery only, unless --pubsub-url is set
+	case "postgres":
+		if relayURL == "" {
+			if s.cfg.dbType != "postgres" && s.cfg.dbType != "timescale" {
+				logger.Get().Fatal().Str("db_type", s.cfg.dbType).Msg("--pubsub=postgres requires a Postgres database")
+			}
+			relayURL = s.cfg.connStr
+		}
+	default:
+		logger.Get().Fatal().Str("pubsub", s.opts.pubsubBackend).Msg("Unknown pubsub backend (use local or postgres)")
+	}
+	if relayURL == "" {
+		return
+	}
+
+	relay, err := api.NewPubSubRelay(relayURL, s.pubsub)
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid pubsub URL")
+	}
+	if err := relay.Start(); err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to start pubsub relay")
+	}
+	s.pubsub.SetRelay(relay)
+	s.relay = relay
+}
+
+// setupWriteRouting routes namespace writes to their owner nodes (optional)
+func (s *server) setupWriteRouting() {
+	if s.opts.routeNodes == "" {
+		return
+	}
+	nodes, err := api.ParseRouteNodes(s.opts.routeNodes)
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid --route-nodes")
+	}
+	s.router, err = api.NewRouter(s.opts.nodeID, nodes)
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid write routing config")
+	}
+	s.rpcHandler.SetRouter(s.router)
+	logger.Get().Info().Str("node", s.opts.nodeID).Int("nodes", len(nodes)).Msg("Routing namespace writes to owner nodes")
+}
+
+// startUDPIngest starts the UDP telemetry ingest listener (optional)
+func (s *server) startUDPIngest() {
+	if s.opts.udpPort <= 0 {
+		return
+	}
+	s.udpIngest = api.NewUDPIngest(s.rpcHandler, api.UDPIngestConfig{
+		Addr:       fmt.Sprintf(":%d", s.opts.udpPort),
+		Namespaces: splitList(s.opts.udpNamespaces),
+	})
+	if err := s.udpIngest.Start(); err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to start UDP ingest listener")
+	}
+}
+
+// startConnectors starts the MQTT bridge, the AMQP sink and the Kinesis/SQS
+// source (each optional)
+func (s *server) startConnectors() {
+	if s.opts.mqttConfig != "" {
+		bridgeCfg, err := api.LoadMQTTBridgeConfig(s.opts.mqttConfig)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to load MQTT bridge config")
+		}
+		bridgeCfg.TestMode = s.cfg.testMode
+		s.mqttBridge, err = api.NewMQTTBridge(s.st, s.pubsub, *bridgeCfg)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Invalid MQTT bridge config")
+		}
+		s.writes.apply(s.mqttBridge)
+		if err := s.mqttBridge.Start(); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to start MQTT bridge")
+		}
+	}
+
+	if s.opts.amqpConfig != "" {
+		sinkCfg, err := api.LoadAMQPSinkConfig(s.opts.amqpConfig)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to load AMQP sink config")
+		}
+		s.amqpSink, err = api.NewAMQPSink(s.st, s.pubsub, *sinkCfg)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Invalid AMQP sink config")
+		}
+		s.amqpSink.SetNotifier(s.notifier)
+		if err := s.amqpSink.Start(); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to start AMQP sink")
+		}
+	}
+
+	if s.opts.awsSourceConfig != "" {
+		sourceCfg, err := api.LoadAWSSourceConfig(s.opts.awsSourceConfig)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to load AWS source config")
+		}
+		sourceCfg.TestMode = s.cfg.testMode
+		s.awsSource, err = api.NewAWSSource(s.st, s.pubsub, *sourceCfg)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Invalid AWS source config")
+		}
+		s.writes.apply(s.awsSource)
+		s.awsSource.SetNotifier(s.notifier)
+		if err := s.awsSource.Start(); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to start AWS source")
+		}
+	}
+}
+
+// startWebhooks loads the namespace blueprints for ns.create templates and
+// starts the webhook publisher (each optional; the publisher is always on
+// with blueprints, whose namespaces may have hooks)
+func (s *server) startWebhooks() {
+	var blueprints *api.Blueprints
+	if s.opts.blueprintDir != "" {
+		var err error
+		blueprints, err = api.NewBlueprints(s.opts.blueprintDir)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to load blueprints")
+		}
+		s.rpcHandler.SetBlueprints(blueprints)
+		logger.Get().Info().Str("dir", s.opts.blueprintDir).Msg("Namespace blueprints enabled")
+	}
+	if s.opts.webhookConfig == "" && blueprints == nil {
+		return
+	}
+
+	hookCfg := &api.WebhookConfig{}
+	if s.opts.webhookConfig != "" {
+		var err error
+		hookCfg, err = api.LoadWebhookConfig(s.opts.webhookConfig)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to load webhook config")
+		}
+	}
+	if blueprints != nil {
+		hooks, err := blueprints.Hooks(context.Background(), s.st)
+		if err != nil {
+			logger.Get().Error().Err(err).Msg("Failed to load blueprint webhooks")
+		}
+		hookCfg.Hooks = append(hookCfg.Hooks, hooks...)
+	}
+	webhooks, err := api.NewWebhookPublisher(s.st, s.pubsub, *hookCfg)
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid webhook config")
+	}
+	webhooks.SetNotifier(s.notifier)
+	webhooks.SetJobScheduler(s.jobs)
+	s.rpcHandler.SetWebhookPublisher(webhooks)
+	webhooks.Start()
+	s.webhooks = webhooks
+}
+
+// startLogShipping starts per-namespace log shipping; tenants configure
+// destinations via RPC
+func (s *server) startLogShipping() {
+	if !s.opts.logShipping {
+		return
+	}
+	s.shipper = api.NewLogShipper(s.st, s.pubsub)
+	s.shipper.SetNotifier(s.notifier)
+	s.rpcHandler.SetLogShipper(s.shipper)
+	s.importHandler.SetLogShipper(s.shipper)
+	if err := s.shipper.Start(context.Background()); err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to start log shipping")
+	}
+}
+
+// startSchedulers starts the scheduled exports, run on the cron schedules
+// tenants set via RPC, and the ticks, which append the recurring events
+// tenants create with tick.create
+func (s *server) startSchedulers() {
+	if s.opts.exportSchedules {
+		s.exportScheduler = api.NewExportScheduler(s.st, s.opts.exportDir)
+		s.exportScheduler.SetNotifier(s.notifier)
+		s.exportScheduler.SetJobScheduler(s.jobs)
+		if s.router != nil {
+			s.exportScheduler.SetRouter(s.router)
+		}
+		s.rpcHandler.SetExportScheduler(s.exportScheduler)
+		if err := s.exportScheduler.Start(context.Background()); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to start scheduled exports")
+		}
+	}
+
+	if s.opts.ticks {
+		s.tickScheduler = api.NewTickScheduler(s.st, s.pubsub)
+		if s.router != nil {
+			s.tickScheduler.SetRouter(s.router)
+		}
+		s.rpcHandler.SetTickScheduler(s.tickScheduler)
+		if err := s.tickScheduler.Start(context.Background()); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to start ticks")
+		}
+	}
+}
+
+// startMirroring starts per-namespace mirroring to remote servers, which
+// tenants configure via RPC, and edge sync with hub servers, whose hub edges
+// configure via RPC
+func (s *server) startMirroring() {
+	if s.opts.mirroring {
+		s.mirror = api.NewMirror(s.st, s.pubsub)
+		s.mirror.SetNotifier(s.notifier)
+		s.rpcHandler.SetMirror(s.mirror)
+		if err := s.mirror.Start(context.Background()); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to start namespace mirroring")
+		}
+	}
+
+	if s.opts.edgeSyncing {
+		s.edgeSync = api.NewEdgeSync(s.st, s.pubsub)
+		s.edgeSync.SetNotifier(s.notifier)
+		s.rpcHandler.SetEdgeSync(s.edgeSync)
+		if err := s.edgeSync.Start(context.Background()); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to start edge sync")
+		}
+	}
+}
+
+// startMaintenance starts the background work on stored streams: snapshots,
+// integrity checks, compaction, attestation and storage sampling (each
+// optional)
+func (s *server) startMaintenance() {
+	// Snapshot entity streams by namespace rules (reducers are plugins or webhooks)
+	if s.opts.snapshotting {
+		s.snapshotter = api.NewSnapshotter(s.st, s.pubsub, s.writes.plugins)
+		s.snapshotter.SetNotifier(s.notifier)
+		s.snapshotter.SetJobScheduler(s.jobs)
+		s.rpcHandler.SetSnapshotter(s.snapshotter)
+		if err := s.snapshotter.Start(context.Background()); err != nil {
+			logger.Get().Fatal().Err(err).Msg("Failed to start snapshotting")
+		}
+	}
+
+	// Background integrity checks
+	if s.opts.scrubInterval > 0 {
+		s.scrubber = api.NewScrubber(s.st, s.pubsub, api.ScrubberConfig{
+			Interval: s.opts.scrubInterval,
+			Pause:    s.opts.scrubPause,
+		})
+		s.scrubber.SetNotifier(s.notifier)
+		s.scrubber.Start()
+	}
+
+	// Compact checkpoint streams by namespace retention rules
+	if s.opts.compactionInterval > 0 {
+		s.compactor = api.NewCompactor(s.st, api.CompactorConfig{Interval: s.opts.compactionInterval})
+		s.compactor.SetJobScheduler(s.jobs)
+		s.rpcHandler.SetCompactor(s.compactor)
+		s.compactor.Start()
+	}
+
+	// Sign head hashes of WORM namespaces
+	if s.opts.attestationKey != "" {
+		key, err := api.ParseAttestationKey(s.opts.attestationKey)
+		if err != nil {
+			logger.Get().Fatal().Err(err).Msg("Invalid attestation key")
+		}
+		s.attestor = api.NewAttestor(s.st, key, s.opts.attestationInterval)
+		s.rpcHandler.SetAttestor(s.attestor)
+		s.attestor.Start()
+	}
+
+	// Sample namespace sizes for ns.storage growth rates
+	if s.opts.storageSampleInterval > 0 {
+		if s.storageTracker = api.NewStorageTracker(s.st, s.opts.storageSampleInterval); s.storageTracker != nil {
+			s.rpcHandler.SetStorageTracker(s.storageTracker)
+			s.storageTracker.Start()
+		}
+	}
+}
+
+// startWriteQueue starts the write queue for short backend outages (optional)
+// and returns what authenticates tokens, which keeps accepting known tokens
+// while the backend is unavailable when the queue is on
+func (s *server) startWriteQueue() api.NamespaceGetter {
+	if s.opts.writeQueueDir == "" {
+		return s.st
+	}
+	var err error
+	s.writeQueue, err = api.NewWriteQueue(s.st, s.pubsub, api.WriteQueueConfig{
+		Dir:        s.opts.writeQueueDir,
+		MaxEntries: s.opts.writeQueueMax,
+	})
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Failed to open write queue")
+	}
+	s.rpcHandler.SetWriteQueue(s.writeQueue)
+	s.writeQueue.Start()
+	return api.NewFallbackNamespaceGetter(s.st)
+}
+
+// startMetricsPush pushes the metrics of sources to a remote-write endpoint
+// or StatsD (optional)
+func (s *server) startMetricsPush(sources api.MetricsSources) {
+	if s.opts.metricsPushURL == "" {
+		return
+	}
+	labels, err := api.ParseMetricsPushLabels(s.opts.metricsPushLabels)
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid metrics push labels")
+	}
+	s.metricsPusher, err = api.NewMetricsPusher(sources, api.MetricsPushConfig{
+		URL:      s.opts.metricsPushURL,
+		Interval: s.opts.metricsPushInterval,
+		Labels:   labels,
+	})
+	if err != nil {
+		logger.Get().Fatal().Err(err).Msg("Invalid metrics push configuration")
+	}
+	s.metricsPusher.Start()
+}
+
+// close stops the components that were started, bridges and connectors first
+func (s *server) close() {
+	if s.udpIngest != nil {
+		s.udpIngest.Close()
+	}
+	if s.mqttBridge != nil {
+		s.mqttBridge.Close()
+	}
+	if s.awsSource != nil {
+		s.awsSource.Close()
+	}
+	if s.amqpSink != nil {
+		s.amqpSink.Close()
+	}
+	if s.webhooks != nil {
+		s.webhooks.Close()
+	}
+	if s.shipper != nil {
+		s.shipper.Close()
+	}
+	if s.exportScheduler != nil {
+		s.exportScheduler.Close()
+	}
+	if s.tickScheduler != nil {
+		s.tickScheduler.Close()
+	}
+	if s.mirror != nil {
+		s.mirror.Close()
+	}
+	if s.edgeSync != nil {
+		s.edgeSync.Close()
+	}
+	if s.snapshotter != nil {
+		s.snapshotter.Close()
+	}
+	if s.writeQueue != nil {
+		s.writeQueue.Close()
+	}
+	if s.scrubber != nil {
+		s.scrubber.Close()
+	}
+	if s.compactor != nil {
+		s.compactor.Close()
+	}
+	if s.jobs != nil {
+		s.jobs.Close()
+	}
+	if s.attestor != nil {
+		s.attestor.Close()
+	}
+	if s.storageTracker != nil {
+		s.storageTracker.Close()
+	}
+	if s.metricsPusher != nil {
+		s.metricsPusher.Close()
+	}
+	if s.relay != nil {
+		s.relay.Close()
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// runServiceCommand is only implemented on Windows
+func runServiceCommand(args []string) error {
+	return errors.New("the service command is only available on Windows; use systemd elsewhere (see docs/DEPLOYMENT.md)")
+}
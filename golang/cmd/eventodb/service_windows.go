@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceName is the name EventoDB is registered under with the service manager
+const serviceName = "EventoDB"
+
+// runServiceCommand handles "eventodb service install|uninstall|run [flags]"
+func runServiceCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: eventodb service install|uninstall|run [server flags]")
+	}
+	switch args[0] {
+	case "install":
+		return installService(args[1:])
+	case "uninstall":
+		return uninstallService()
+	case "run":
+		return runService(args[1:])
+	default:
+		return fmt.Errorf("unknown service command %q (use install, uninstall or run)", args[0])
+	}
+}
+
+// serviceDataRoot is where the service keeps its data and log by default
+func serviceDataRoot() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "EventoDB")
+}
+
+// installService registers the service to start at boot with the given server
+// flags. Services start in System32, so relative paths are made absolute here.
+func installService(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find executable: %w", err)
+	}
+	args, err = serviceArgs(args)
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager (run as Administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s is already installed", serviceName)
+	}
+
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "EventoDB",
+		Description: "EventoDB event store",
+		StartType:   mgr.StartAutomatic,
+	}, append([]string{"service", "run"}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	defer s.Close()
+
+	// Restart after a crash, like Restart=on-failure under systemd
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+	}, uint32((24 * time.Hour).Seconds())); err != nil {
+		return fmt.Errorf("failed to set recovery actions: %w", err)
+	}
+
+	fmt.Printf("Installed service %s: %s service run %s\n", serviceName, exe, strings.Join(args, " "))
+	fmt.Printf("Start it with: sc.exe start %s\n", serviceName)
+	return nil
+}
+
+// serviceArgs makes the paths in args absolute and fills in defaults under
+// %ProgramData%\EventoDB: a Pebble database when none is configured, the
+// SQLite data directory, and JSON logs, which go to eventodb.log there.
+func serviceArgs(args []string) ([]string, error) {
+	args = append([]string(nil), args...)
+	root := serviceDataRoot()
+
+	var absErr error
+	abs := func(path string) string {
+		p, err := filepath.Abs(path)
+		if err != nil && absErr == nil {
+			absErr = fmt.Errorf("invalid path %q: %w", path, err)
+		}
+		return p
+	}
+
+	hasDBURL := rewriteFlag(args, "db-url", func(dbURL string) string {
+		if path, ok := strings.CutPrefix(dbURL, "pebble://"); ok && path != "" && path != "memory" {
+			return "pebble://" + abs(dbFilePath(path))
+		}
+		return dbURL
+	})
+	hasDataDir := rewriteFlag(args, "data-dir", abs)
+	if absErr != nil {
+		return nil, absErr
+	}
+
+	if !hasDBURL && os.Getenv("EVENTODB_DB_URL") == "" && !hasFlag(args, "test-mode") {
+		args = append(args, "--db-url", "pebble://"+filepath.Join(root, "data"))
+	}
+	if !hasDataDir && os.Getenv("EVENTODB_DATA_DIR") == "" {
+		args = append(args, "--data-dir", filepath.Join(root, "data"))
+	}
+	if !hasFlag(args, "log-format") && os.Getenv("EVENTODB_LOG_FORMAT") == "" {
+		args = append(args, "--log-format", "json")
+	}
+	return args, nil
+}
+
+// rewriteFlag replaces the value of flag name in args (-name v, --name v,
+// -name=v or --name=v) with fn(value), and reports whether it was present
+func rewriteFlag(args []string, name string, fn func(string) string) bool {
+	for i, arg := range args {
+		for _, prefix := range []string{"-" + name, "--" + name} {
+			if arg == prefix && i+1 < len(args) {
+				args[i+1] = fn(args[i+1])
+				return true
+			}
+			if value, ok := strings.CutPrefix(arg, prefix+"="); ok {
+				args[i] = prefix + "=" + fn(value)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasFlag reports whether flag name is set in args
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		arg = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if arg == name || strings.HasPrefix(arg, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// uninstallService stops the service if it is running and removes it
+func uninstallService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager (run as Administrator): %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", serviceName)
+	}
+	defer s.Close()
+
+	if status, err := s.Control(svc.Stop); err == nil {
+		for deadline := time.Now().Add(30 * time.Second); status.State != svc.Stopped && time.Now().Before(deadline); {
+			time.Sleep(300 * time.Millisecond)
+			if status, err = s.Query(); err != nil {
+				break
+			}
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("failed to delete service: %w", err)
+	}
+
+	fmt.Printf("Removed service %s; data in %s was kept\n", serviceName, serviceDataRoot())
+	return nil
+}
+
+// runService runs the server under the service manager, or in the console
+// when started by hand, which helps when debugging the installed arguments
+func runService(args []string) error {
+	os.Args = append([]string{os.Args[0]}, args...)
+
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("failed to detect the service manager: %w", err)
+	}
+	if !isService {
+		shutdown := make(chan os.Signal, 1)
+		signal.Notify(shutdown, os.Interrupt)
+		serve(shutdown, func() {})
+		return nil
+	}
+
+	// Services start in System32 with no console: resolve relative paths
+	// and write logs under the data root instead
+	root := serviceDataRoot()
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", root, err)
+	}
+	if err := os.Chdir(root); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(filepath.Join(root, "eventodb.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	os.Stdout, os.Stderr = logFile, logFile
+
+	return svc.Run(serviceName, windowsService{})
+}
+
+// windowsService runs the server for the service manager
+type windowsService struct{}
+
+// Execute reports Running once the server is ready and shuts it down
+// gracefully on Stop or system shutdown
+func (windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	shutdown := make(chan os.Signal, 1)
+	running := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(shutdown, func() { close(running) })
+	}()
+
+	for {
+		select {
+		case <-running:
+			status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+			running = nil
+
+		case <-done:
+			return false, 0
+
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				shutdown <- os.Interrupt
+				<-done
+				return false, 0
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package main provides the stream CLI command.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// StreamConfig holds configuration for the stream command
+type StreamConfig struct {
+	Action string
+	Stream string
+	URL    string
+	Token  string
+	// Recent is the number of latest messages shown by inspect
+	Recent int
+	// Output is "text" or "json"
+	Output string
+}
+
+// StreamInfo is the stream.info result
+type StreamInfo struct {
+	Stream        string `json:"stream"`
+	Version       int64  `json:"version"`
+	MessageCount  int64  `json:"messageCount"`
+	FirstPosition int64  `json:"firstPosition"`
+	FirstTime     string `json:"firstTime"`
+	LastTime      string `json:"lastTime"`
+	DataBytes     int64  `json:"dataBytes"`
+	Types         []struct {
+		Type  string `json:"type"`
+		Count int64  `json:"count"`
+	} `json:"types"`
+	Truncated bool `json:"truncated"`
+}
+
+// StreamMessage is a message of the stream.get result
+type StreamMessage struct {
+	ID             string          `json:"id"`
+	Type           string          `json:"type"`
+	Position       int64           `json:"position"`
+	GlobalPosition int64           `json:"globalPosition"`
+	Data           json.RawMessage `json:"data"`
+	Metadata       json.RawMessage `json:"metadata"`
+	Time           string          `json:"time"`
+}
+
+func parseStreamFlags(args []string) (*StreamConfig, error) {
+	fs := flag.NewFlagSet("stream", flag.ExitOnError)
+
+	serverURL := fs.String("url", getEnv("EVENTODB_URL", "http://localhost:8080"), "EventoDB server URL")
+	token := fs.String("token", getEnv("EVENTODB_TOKEN", ""), "Namespace token (required)")
+	recent := fs.Int("recent", 10, "Number of latest messages to show")
+	output := fs.String("output", "text", "Output format: text or json")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `
+Usage: eventodb stream inspect <stream> [OPTIONS]
+
+Show a stream's version, first and last message times, type histogram, payload
+size and latest messages.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  eventodb stream inspect account-123 --token $TOKEN
+  eventodb stream inspect account-123 --token $TOKEN --recent 50 --output json
+`)
+	}
+
+	if len(args) == 0 || args[0] != "inspect" {
+		fs.Usage()
+		return nil, fmt.Errorf("expected inspect")
+	}
+
+	// The stream name may come before or after the options
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return nil, fmt.Errorf("expected a stream name")
+	}
+	stream := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if *token == "" {
+		return nil, fmt.Errorf("--token is required")
+	}
+	if *recent < 0 {
+		return nil, fmt.Errorf("--recent must not be negative")
+	}
+	if *output != "text" && *output != "json" {
+		return nil, fmt.Errorf("--output must be text or json")
+	}
+
+	return &StreamConfig{
+		Action: args[0],
+		Stream: stream,
+		URL:    *serverURL,
+		Token:  *token,
+		Recent: *recent,
+		Output: *output,
+	}, nil
+}
+
+func runStream(cfg *StreamConfig) error {
+	ctx := context.Background()
+	client := newRPCClient(cfg.URL, cfg.Token)
+
+	var info *StreamInfo
+	if err := client.call(ctx, &info, "stream.info", cfg.Stream); err != nil {
+		return err
+	}
+	if info == nil {
+		return fmt.Errorf("stream %s not found", cfg.Stream)
+	}
+
+	recent, err := fetchRecentMessages(ctx, client, info, cfg.Recent)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			*StreamInfo
+			Recent []StreamMessage `json:"recent"`
+		}{info, recent})
+	}
+	printStreamInfo(os.Stdout, info, recent)
+	return nil
+}
+
+// fetchRecentMessages reads the latest n messages of a stream
+func fetchRecentMessages(ctx context.Context, client *rpcClient, info *StreamInfo, n int) ([]StreamMessage, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	position := max(info.FirstPosition, info.Version-int64(n)+1)
+
+	var rows [][]json.RawMessage
+	if err := client.call(ctx, &rows, "stream.get", info.Stream, map[string]interface{}{
+		"position":  position,
+		"batchSize": n,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Rows are [id, type, position, globalPosition, data, metadata, time]
+	msgs := make([]StreamMessage, 0, len(rows))
+	for _, row := range rows {
+		if len(row) != 7 {
+			return nil, fmt.Errorf("expected 7 fields, got %d", len(row))
+		}
+		var msg StreamMessage
+		for i, field := range []interface{}{&msg.ID, &msg.Type, &msg.Position, &msg.GlobalPosition, &msg.Data, &msg.Metadata, &msg.Time} {
+			if err := json.Unmarshal(row[i], field); err != nil {
+				return nil, fmt.Errorf("failed to parse message: %w", err)
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// printStreamInfo writes the text report of stream inspect
+func printStreamInfo(out io.Writer, info *StreamInfo, recent []StreamMessage) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Stream:\t%s\n", info.Stream)
+	fmt.Fprintf(w, "Version:\t%d\n", info.Version)
+	count := fmt.Sprintf("%d", info.MessageCount)
+	if info.Truncated {
+		count += " (scan stopped early, counts are partial)"
+	} else if info.FirstPosition > 0 {
+		count += fmt.Sprintf(" (compacted below position %d)", info.FirstPosition)
+	}
+	fmt.Fprintf(w, "Messages:\t%s\n", count)
+	fmt.Fprintf(w, "First message:\t%s\n", info.FirstTime)
+	fmt.Fprintf(w, "Last message:\t%s\n", info.LastTime)
+	fmt.Fprintf(w, "Payload size:\t%s\n", formatBytes(info.DataBytes))
+	w.Flush()
+
+	fmt.Fprintf(out, "\nTypes:\n")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, t := range info.Types {
+		bar := strings.Repeat("#", int(max(1, t.Count*30/max(info.MessageCount, 1))))
+		fmt.Fprintf(w, "  %s\t%d\t%s\n", t.Type, t.Count, bar)
+	}
+	w.Flush()
+
+	if len(recent) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\nRecent messages:\n")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  POS\tGPOS\tTIME\tTYPE\tDATA\n")
+	for _, msg := range recent {
+		fmt.Fprintf(w, "  %d\t%d\t%s\t%s\t%s\n", msg.Position, msg.GlobalPosition, msg.Time, msg.Type, compactJSON(msg.Data))
+	}
+	w.Flush()
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,232 @@
+// Package main provides the tail CLI command.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ANSI colors used by tail's table format
+const (
+	colorReset = "\x1b[0m"
+	colorDim   = "\x1b[2m"
+	colorBold  = "\x1b[1m"
+	colorCyan  = "\x1b[36m"
+)
+
+// typeColors are assigned to message types by hash, so a type keeps its color
+var typeColors = []string{"\x1b[32m", "\x1b[33m", "\x1b[34m", "\x1b[35m", "\x1b[92m", "\x1b[93m", "\x1b[94m", "\x1b[95m"}
+
+// TailConfig holds configuration for the tail command
+type TailConfig struct {
+	URL        string
+	Token      string
+	Categories []string
+	// Types shows only messages of these types (default: all)
+	Types []string
+	// From is the global position or bookmark name to start at (default: new messages only)
+	From string
+	// Format is "table" or "json"
+	Format   string
+	ShowData bool
+	ShowMeta bool
+	Color    bool
+}
+
+func parseTailFlags(args []string) (*TailConfig, error) {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+
+	serverURL := fs.String("url", getEnv("EVENTODB_URL", "http://localhost:8080"), "EventoDB server URL")
+	token := fs.String("token", getEnv("EVENTODB_TOKEN", ""), "Namespace token (required)")
+	category := fs.String("category", "", "Comma-separated categories to follow (default: all)")
+	types := fs.String("types", "", "Comma-separated message types to show (default: all)")
+	from := fs.String("from", "", "Start global position or bookmark name (default: new messages only)")
+	format := fs.String("format", "table", "Output format: table or json")
+	showData := fs.Bool("data", true, "Show message data")
+	showMeta := fs.Bool("meta", false, "Show message metadata")
+	color := fs.String("color", "auto", "Colorize table output: auto, always or never")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `
+Usage: eventodb tail [OPTIONS]
+
+Follow messages as they are written, for debugging.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  eventodb tail --token $TOKEN --category order
+  eventodb tail --token $TOKEN --category order,payment --types OrderPlaced,PaymentCaptured --meta
+  eventodb tail --token $TOKEN --category order --from 0 --format json | jq .data
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *token == "" {
+		return nil, fmt.Errorf("--token is required")
+	}
+	if *format != "table" && *format != "json" {
+		return nil, fmt.Errorf("--format must be table or json")
+	}
+
+	cfg := &TailConfig{
+		URL:        *serverURL,
+		Token:      *token,
+		Categories: splitList(*category),
+		Types:      splitList(*types),
+		From:       *from,
+		Format:     *format,
+		ShowData:   *showData,
+		ShowMeta:   *showMeta,
+	}
+
+	switch *color {
+	case "always":
+		cfg.Color = true
+	case "never":
+	case "auto":
+		cfg.Color = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	default:
+		return nil, fmt.Errorf("--color must be auto, always or never")
+	}
+
+	return cfg, nil
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func runTail(cfg *TailConfig) error {
+	params := url.Values{"follow": {"true"}, "from": {"end"}}
+	if cfg.From != "" {
+		params.Set("from", cfg.From)
+	}
+	if len(cfg.Categories) > 0 {
+		params.Set("categories", strings.Join(cfg.Categories, ","))
+	}
+
+	types := make(map[string]bool, len(cfg.Types))
+	for _, t := range cfg.Types {
+		types[t] = true
+	}
+
+	p := &tailPrinter{cfg: cfg, out: os.Stdout}
+	if cfg.Format == "table" {
+		p.printHeader()
+	}
+
+	err := streamExport(context.Background(), cfg.URL, cfg.Token, params, func(_ []byte, record *StreamedRecord) error {
+		if len(types) > 0 && !types[record.Type] {
+			return nil
+		}
+		return p.print(record)
+	})
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("server closed the connection")
+}
+
+// tailPrinter writes tailed records in the configured format
+type tailPrinter struct {
+	cfg *TailConfig
+	out io.Writer
+}
+
+// printHeader writes the column names of the table format
+func (p *tailPrinter) printHeader() {
+	header := fmt.Sprintf("%-12s  %8s  %-28s  %-24s", "TIME", "GPOS", "STREAM", "TYPE")
+	if p.cfg.ShowData {
+		header += "  DATA"
+	}
+	fmt.Fprintln(p.out, p.paint(colorBold, strings.TrimRight(header, " ")))
+}
+
+// print writes one record
+func (p *tailPrinter) print(record *StreamedRecord) error {
+	if !p.cfg.ShowData {
+		record.Data = nil
+	}
+	if !p.cfg.ShowMeta || bytes.Equal(record.Meta, []byte("null")) {
+		record.Meta = nil
+	}
+
+	if p.cfg.Format == "json" {
+		line, err := json.Marshal(struct {
+			Time     string          `json:"time"`
+			GPos     int64           `json:"gpos"`
+			Stream   string          `json:"stream"`
+			Position int64           `json:"pos"`
+			Type     string          `json:"type"`
+			ID       string          `json:"id"`
+			Data     json.RawMessage `json:"data,omitempty"`
+			Meta     json.RawMessage `json:"meta,omitempty"`
+		}{record.Time, record.GPos, record.Stream, record.Position, record.Type, record.ID, record.Data, record.Meta})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.out, "%s\n", line)
+		return err
+	}
+
+	clock := record.Time
+	if t, err := time.Parse(time.RFC3339Nano, record.Time); err == nil {
+		clock = t.Local().Format("15:04:05.000")
+	}
+	row := fmt.Sprintf("%s  %s  %s  %s",
+		p.paint(colorDim, fmt.Sprintf("%-12s", clock)),
+		p.paint(colorDim, fmt.Sprintf("%8d", record.GPos)),
+		p.paint(colorCyan, fmt.Sprintf("%-28s", fmt.Sprintf("%s@%d", record.Stream, record.Position))),
+		p.paint(typeColor(record.Type), fmt.Sprintf("%-24s", record.Type)))
+	if record.Data != nil {
+		row += "  " + string(compactJSON(record.Data))
+	}
+	if record.Meta != nil {
+		row += "  " + p.paint(colorDim, "meta="+string(compactJSON(record.Meta)))
+	}
+	_, err := fmt.Fprintln(p.out, strings.TrimRight(row, " "))
+	return err
+}
+
+// paint wraps s in an ANSI color when colors are enabled
+func (p *tailPrinter) paint(color, s string) string {
+	if !p.cfg.Color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// typeColor picks the color of a message type
+func typeColor(messageType string) string {
+	h := fnv.New32a()
+	h.Write([]byte(messageType))
+	// Fold in the high bits, the low bits alone repeat for names with the same ending
+	sum := h.Sum32()
+	return typeColors[(sum^sum>>16)%uint32(len(typeColors))]
+}
+
+// compactJSON removes insignificant whitespace from raw JSON
+func compactJSON(raw json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return raw
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,104 @@
+// Package main provides the whoami CLI command.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/eventodb/eventodb/internal/api"
+)
+
+// WhoamiConfig holds configuration for the whoami command
+type WhoamiConfig struct {
+	URL   string
+	Token string
+	// Output is "text" or "json"
+	Output string
+}
+
+// WhoamiInfo is the auth.whoami result
+type WhoamiInfo struct {
+	Namespace string               `json:"namespace"`
+	Scopes    []string             `json:"scopes"`
+	Label     *string              `json:"label"`
+	ExpiresAt *string              `json:"expiresAt"`
+	Frozen    *api.FreezeState     `json:"frozen"`
+	Suspended *api.SuspensionState `json:"suspended"`
+	TestMode  bool                 `json:"testMode"`
+}
+
+func parseWhoamiFlags(args []string) (*WhoamiConfig, error) {
+	fs := flag.NewFlagSet("whoami", flag.ExitOnError)
+
+	serverURL := fs.String("url", getEnv("EVENTODB_URL", "http://localhost:8080"), "EventoDB server URL")
+	token := fs.String("token", getEnv("EVENTODB_TOKEN", ""), "Token to describe (required)")
+	output := fs.String("output", "text", "Output format: text or json")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `
+Usage: eventodb whoami [OPTIONS]
+
+Show the namespace, scopes, label and expiry of a token, as the server sees it.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *token == "" {
+		return nil, fmt.Errorf("--token is required")
+	}
+	if *output != "text" && *output != "json" {
+		return nil, fmt.Errorf("--output must be text or json")
+	}
+
+	return &WhoamiConfig{
+		URL:    *serverURL,
+		Token:  *token,
+		Output: *output,
+	}, nil
+}
+
+func runWhoami(cfg *WhoamiConfig) error {
+	var info WhoamiInfo
+	if err := newRPCClient(cfg.URL, cfg.Token).call(context.Background(), &info, "auth.whoami"); err != nil {
+		return err
+	}
+
+	if cfg.Output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Namespace:\t%s\n", info.Namespace)
+	fmt.Fprintf(w, "Scopes:\t%s\n", strings.Join(info.Scopes, ", "))
+	if info.Label != nil {
+		fmt.Fprintf(w, "Label:\t%s\n", *info.Label)
+	}
+	expires := "never"
+	if info.ExpiresAt != nil {
+		expires = *info.ExpiresAt
+	}
+	fmt.Fprintf(w, "Expires:\t%s\n", expires)
+	if info.Frozen != nil {
+		fmt.Fprintf(w, "Frozen:\tsince %s\n", info.Frozen.Since)
+	}
+	if info.Suspended != nil {
+		fmt.Fprintf(w, "Suspended:\t%s\n", describeSuspension(info.Suspended))
+	}
+	if info.TestMode {
+		fmt.Fprintf(w, "Test mode:\tyes (tokens are not verified)\n")
+	}
+	return w.Flush()
+}
@@ -0,0 +1,43 @@
+// Package api provides the split between the public and admin listeners.
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ContextKeyDataPathOnly is the context key set for requests on the public
+// listener while a separate admin listener serves the admin endpoints
+const ContextKeyDataPathOnly contextKey = "dataPathOnly"
+
+// isAdminMethod reports whether an RPC method is served only on the admin
+// listener when one is configured: namespace management, redaction, stream
+// merges and renames, and profiling
+func isAdminMethod(method string) bool {
+	return strings.HasPrefix(method, "ns.") || method == "message.redact" || method == "stream.merge" || method == "stream.rename" || method == "sys.profile"
+}
+
+// checkListener rejects admin methods on the public listener
+func checkListener(ctx context.Context, method string) *RPCError {
+	if dataPathOnly, _ := ctx.Value(ContextKeyDataPathOnly).(bool); !dataPathOnly || !isAdminMethod(method) {
+		return nil
+	}
+	return &RPCError{
+		Code:    "ADMIN_LISTENER_ONLY",
+		Message: fmt.Sprintf("%s is only served on the admin listener", method),
+		Details: map[string]interface{}{"method": method},
+	}
+}
+
+// DataPathOnlyFast marks requests so admin RPC methods are rejected with
+// ADMIN_LISTENER_ONLY. Wrap the public /rpc handler with it when the admin
+// endpoints have their own listener.
+func DataPathOnlyFast(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetUserValue("dataPathOnly", true)
+		next(ctx)
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestAdminListener_PublicRejectsAdminMethods(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	// Without an admin listener every method is served
+	if _, rpcErr := h.route(ctx, "ns.info", []interface{}{"test-ns"}); rpcErr != nil {
+		t.Fatalf("ns.info failed: %v", rpcErr)
+	}
+
+	public := context.WithValue(ctx, ContextKeyDataPathOnly, true)
+	for _, method := range []string{"ns.info", "ns.create", "message.redact", "stream.merge", "stream.rename", "sys.profile"} {
+		_, rpcErr := h.route(public, method, []interface{}{"test-ns"})
+		if rpcErr == nil || rpcErr.Code != "ADMIN_LISTENER_ONLY" {
+			t.Errorf("Expected ADMIN_LISTENER_ONLY for %s, got %v", method, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(public, "stream.write", []interface{}{"account-1", map[string]interface{}{"type": "Noted", "data": map[string]interface{}{}}}); rpcErr != nil {
+		t.Errorf("Expected data-path methods on the public listener, got %v", rpcErr)
+	}
+}
+
+func TestAdminListener_DataPathOnlyFast(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	handler := DataPathOnlyFast(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetUserValue("namespace", "test-ns")
+		FastHTTPRPCHandler(h, false)(ctx)
+	})
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+	ctx.Request.SetRequestURI("/rpc")
+	ctx.Request.SetBodyString(`["ns.list"]`)
+	handler(&ctx)
+	if code := ctx.Response.StatusCode(); code != fasthttp.StatusForbidden {
+		t.Fatalf("Expected 403, got %d: %s", code, ctx.Response.Body())
+	}
+	if !strings.Contains(string(ctx.Response.Body()), `"ADMIN_LISTENER_ONLY"`) {
+		t.Errorf("Unexpected body: %s", ctx.Response.Body())
+	}
+}
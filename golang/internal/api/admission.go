@@ -0,0 +1,311 @@
+// Package api provides token-bucket admission control for writes.
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WritePriority ranks write sources for load shedding. Under overload the
+// lowest priority is rejected first, so interactive writes keep flowing while
+// bulk and connector traffic backs off.
+type WritePriority int
+
+const (
+	// PriorityCDC covers connector ingest (MQTT bridge, UDP ingest)
+	PriorityCDC WritePriority = iota
+
+	// PriorityImport covers bulk imports through /import
+	PriorityImport
+
+	// PriorityInteractive covers stream.write calls
+	PriorityInteractive
+)
+
+// writePriorities lists the priorities, lowest first
+var writePriorities = []WritePriority{PriorityCDC, PriorityImport, PriorityInteractive}
+
+// String returns the priority name used in errors and metrics
+func (p WritePriority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityImport:
+		return "import"
+	default:
+		return "cdc"
+	}
+}
+
+// reserve is the fraction of a bucket's burst a priority must leave for the
+// priorities above it
+func (p WritePriority) reserve() float64 {
+	switch p {
+	case PriorityInteractive:
+		return 0
+	case PriorityImport:
+		return 0.25
+	default:
+		return 0.5
+	}
+}
+
+// AdmissionConfig sets the write rates admission control enforces. A rate of
+// 0 disables that limit; a burst of 0 defaults to one second of the rate.
+type AdmissionConfig struct {
+	GlobalRate     float64 // Messages per second across all namespaces
+	GlobalBurst    int
+	NamespaceRate  float64 // Messages per second for each namespace
+	NamespaceBurst int
+}
+
+// AdmissionController admits writes against a global token bucket and one
+// bucket per namespace.
+//
+// A write is admitted while its buckets hold more tokens than the headroom
+// reserved for higher priorities, and then takes one token per message. Large
+// batches may overdraw a bucket; later writes wait until it refills, which
+// keeps the average rate at the limit. Rejected writes get the time until
+// they would be admitted, so clients back off instead of piling on a backend
+// whose latency is already climbing. A nil *AdmissionController admits
+// everything.
+type AdmissionController struct {
+	cfg AdmissionConfig
+	now func() time.Time
+
+	mu         sync.Mutex
+	global     *tokenBucket
+	namespaces map[string]*tokenBucket
+
+	admitted [3]atomic.Int64 // Messages admitted, by priority
+	rejected [3]atomic.Int64 // Writes rejected, by priority
+}
+
+// NewAdmissionController creates an admission controller, or returns nil when
+// no limit is configured
+func NewAdmissionController(cfg AdmissionConfig) *AdmissionController {
+	if cfg.GlobalRate <= 0 && cfg.NamespaceRate <= 0 {
+		return nil
+	}
+	if cfg.GlobalBurst <= 0 {
+		cfg.GlobalBurst = int(math.Ceil(cfg.GlobalRate))
+	}
+	if cfg.NamespaceBurst <= 0 {
+		cfg.NamespaceBurst = int(math.Ceil(cfg.NamespaceRate))
+	}
+
+	a := &AdmissionController{
+		cfg:        cfg,
+		now:        time.Now,
+		namespaces: make(map[string]*tokenBucket),
+	}
+	if cfg.GlobalRate > 0 {
+		a.global = newTokenBucket(cfg.GlobalRate, cfg.GlobalBurst, a.now())
+	}
+	return a
+}
+
+// Admit takes n tokens for a write to namespace. When the write is rejected
+// it returns false and how long to wait before retrying.
+func (a *AdmissionController) Admit(namespace string, priority WritePriority, n int) (time.Duration, bool) {
+	if a == nil {
+		return 0, true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	buckets := make([]*tokenBucket, 0, 2)
+	if a.global != nil {
+		buckets = append(buckets, a.global)
+	}
+	if a.cfg.NamespaceRate > 0 {
+		bucket, ok := a.namespaces[namespace]
+		if !ok {
+			bucket = newTokenBucket(a.cfg.NamespaceRate, a.cfg.NamespaceBurst, now)
+			a.namespaces[namespace] = bucket
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	// Check every bucket before taking from any, so a rejection costs nothing
+	var wait time.Duration
+	for _, bucket := range buckets {
+		if w := bucket.wait(now, priority); w > 0 {
+			bucket.rejected++
+			wait = max(wait, w)
+		}
+	}
+	if wait > 0 {
+		a.rejected[priority].Add(1)
+		return wait, false
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens -= float64(n)
+	}
+	a.admitted[priority].Add(int64(n))
+	return 0, true
+}
+
+// Wait blocks until a write of n messages is admitted or ctx is done. Imports
+// use it between batches, where the response has already started and a
+// rejection could not be reported with a status code.
+func (a *AdmissionController) Wait(ctx context.Context, namespace string, priority WritePriority, n int) error {
+	for {
+		wait, ok := a.Admit(namespace, priority, n)
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// AdmissionStats are the admission counters for one priority
+type AdmissionStats struct {
+	Admitted int64 `json:"admitted"` // Messages admitted
+	Rejected int64 `json:"rejected"` // Writes rejected or delayed
+}
+
+// Stats returns the admission counters keyed by priority name
+func (a *AdmissionController) Stats() map[string]AdmissionStats {
+	stats := make(map[string]AdmissionStats, len(writePriorities))
+	for _, p := range writePriorities {
+		stats[p.String()] = AdmissionStats{
+			Admitted: a.admitted[p].Load(),
+			Rejected: a.rejected[p].Load(),
+		}
+	}
+	return stats
+}
+
+// AdmissionBucketUsage describes one token bucket
+type AdmissionBucketUsage struct {
+	Rate      float64 `json:"rate"` // Messages per second
+	Burst     int     `json:"burst"`
+	Available float64 `json:"available"` // Tokens now; negative while an overdraw is repaid
+	Rejected  int64   `json:"rejected"`  // Writes this bucket rejected or delayed
+}
+
+// AdmissionUsage describes the buckets a namespace's writes are admitted
+// against; either is nil when that limit is disabled
+type AdmissionUsage struct {
+	Global    *AdmissionBucketUsage `json:"global,omitempty"`
+	Namespace *AdmissionBucketUsage `json:"namespace,omitempty"`
+}
+
+// Usage returns the buckets admitting namespace's writes, or nil when writes
+// are not limited. A namespace that has not written yet has a full bucket.
+func (a *AdmissionController) Usage(namespace string) *AdmissionUsage {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	usage := &AdmissionUsage{}
+	if a.global != nil {
+		u := a.global.usage(now)
+		usage.Global = &u
+	}
+	if a.cfg.NamespaceRate > 0 {
+		bucket, ok := a.namespaces[namespace]
+		if !ok {
+			bucket = newTokenBucket(a.cfg.NamespaceRate, a.cfg.NamespaceBurst, now)
+		}
+		u := bucket.usage(now)
+		usage.Namespace = &u
+	}
+	return usage
+}
+
+// NamespaceUsage returns the per-namespace buckets of the namespaces that
+// have written, keyed by namespace
+func (a *AdmissionController) NamespaceUsage() map[string]AdmissionBucketUsage {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	usage := make(map[string]AdmissionBucketUsage, len(a.namespaces))
+	for namespace, bucket := range a.namespaces {
+		usage[namespace] = bucket.usage(now)
+	}
+	return usage
+}
+
+// rateLimitedError converts a rejected admission into a RATE_LIMITED error
+// carrying the limits and their current usage, so clients can pace retries
+func (a *AdmissionController) rateLimitedError(namespace string, priority WritePriority, wait time.Duration) *RPCError {
+	return &RPCError{
+		Code:    "RATE_LIMITED",
+		Message: fmt.Sprintf("Write rate limit exceeded for namespace %s, retry later", namespace),
+		Details: map[string]interface{}{
+			"namespace":  namespace,
+			"priority":   priority.String(),
+			"retryAfter": int(math.Ceil(wait.Seconds())),
+			"limits":     a.Usage(namespace),
+		},
+	}
+}
+
+// tokenBucket refills at rate tokens per second up to burst tokens. Tokens
+// may go negative when a batch overdraws the bucket.
+type tokenBucket struct {
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	rejected int64
+}
+
+// newTokenBucket creates a full bucket
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// wait refills the bucket and returns how long until it admits priority, or
+// 0 if it admits it now
+func (b *tokenBucket) wait(now time.Time, priority WritePriority) time.Duration {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+
+	// Admission needs at least one token above the reserved headroom
+	needed := math.Min(b.burst, b.burst*priority.reserve()+1)
+	if b.tokens >= needed {
+		return 0
+	}
+	return time.Duration(math.Ceil((needed - b.tokens) / b.rate * float64(time.Second)))
+}
+
+// usage returns the bucket's state at now without refilling it
+func (b *tokenBucket) usage(now time.Time) AdmissionBucketUsage {
+	tokens := b.tokens
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		tokens = math.Min(b.burst, tokens+elapsed*b.rate)
+	}
+	return AdmissionBucketUsage{
+		Rate:      b.rate,
+		Burst:     int(b.burst),
+		Available: math.Floor(tokens*100) / 100,
+		Rejected:  b.rejected,
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAdmission returns an admission controller driven by a manual clock
+func newTestAdmission(cfg AdmissionConfig) (*AdmissionController, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	a := NewAdmissionController(cfg)
+	a.now = func() time.Time { return now }
+	if a.global != nil {
+		a.global.last = now
+	}
+	return a, &now
+}
+
+func TestAdmission_Disabled(t *testing.T) {
+	if a := NewAdmissionController(AdmissionConfig{}); a != nil {
+		t.Fatalf("Expected no controller without limits, got %v", a)
+	}
+	var a *AdmissionController
+	if _, ok := a.Admit("test-ns", PriorityCDC, 1000); !ok {
+		t.Error("Expected a nil controller to admit everything")
+	}
+}
+
+func TestAdmission_GlobalRate(t *testing.T) {
+	a, now := newTestAdmission(AdmissionConfig{GlobalRate: 10})
+
+	for i := 0; i < 10; i++ {
+		if _, ok := a.Admit("test-ns", PriorityInteractive, 1); !ok {
+			t.Fatalf("Expected write %d within the burst to be admitted", i)
+		}
+	}
+	wait, ok := a.Admit("other-ns", PriorityInteractive, 1)
+	if ok {
+		t.Fatal("Expected the global limit to apply across namespaces")
+	}
+	if wait != 100*time.Millisecond {
+		t.Errorf("Expected to wait for one token (100ms), got %s", wait)
+	}
+
+	*now = now.Add(wait)
+	if _, ok := a.Admit("other-ns", PriorityInteractive, 1); !ok {
+		t.Error("Expected the write to be admitted after the suggested wait")
+	}
+}
+
+func TestAdmission_NamespaceRate(t *testing.T) {
+	a, _ := newTestAdmission(AdmissionConfig{NamespaceRate: 5, NamespaceBurst: 2})
+
+	for i := 0; i < 2; i++ {
+		if _, ok := a.Admit("busy-ns", PriorityInteractive, 1); !ok {
+			t.Fatalf("Expected write %d within the burst to be admitted", i)
+		}
+	}
+	if _, ok := a.Admit("busy-ns", PriorityInteractive, 1); ok {
+		t.Error("Expected the busy namespace to be limited")
+	}
+	if _, ok := a.Admit("quiet-ns", PriorityInteractive, 1); !ok {
+		t.Error("Expected other namespaces to keep their own budget")
+	}
+}
+
+func TestAdmission_ShedsLowPriorityFirst(t *testing.T) {
+	a, _ := newTestAdmission(AdmissionConfig{GlobalRate: 100})
+
+	// Drain to 40 tokens: below the cdc reserve (50), above the import reserve (25)
+	if _, ok := a.Admit("test-ns", PriorityInteractive, 60); !ok {
+		t.Fatal("Expected the first write to be admitted")
+	}
+	if _, ok := a.Admit("test-ns", PriorityCDC, 1); ok {
+		t.Error("Expected cdc writes to be shed first")
+	}
+	if _, ok := a.Admit("test-ns", PriorityImport, 1); !ok {
+		t.Error("Expected imports to be admitted above their reserve")
+	}
+
+	// Drain to 10 tokens: only interactive writes get through
+	if _, ok := a.Admit("test-ns", PriorityInteractive, 29); !ok {
+		t.Fatal("Expected the interactive write to be admitted")
+	}
+	if _, ok := a.Admit("test-ns", PriorityImport, 1); ok {
+		t.Error("Expected imports to be shed before interactive writes")
+	}
+	if _, ok := a.Admit("test-ns", PriorityInteractive, 1); !ok {
+		t.Error("Expected interactive writes to use the reserved headroom")
+	}
+
+	stats := a.Stats()
+	if stats["cdc"].Rejected != 1 || stats["import"].Rejected != 1 || stats["interactive"].Admitted != 90 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestAdmission_BatchOverdraw(t *testing.T) {
+	a, now := newTestAdmission(AdmissionConfig{GlobalRate: 100})
+
+	// A batch larger than the burst is admitted once, then pays back its debt
+	if _, ok := a.Admit("test-ns", PriorityImport, 300); !ok {
+		t.Fatal("Expected an oversized batch to be admitted from a full bucket")
+	}
+	wait, ok := a.Admit("test-ns", PriorityInteractive, 1)
+	if ok || wait != 2010*time.Millisecond {
+		t.Errorf("Expected to wait 2.01s for the bucket to refill, got %s (admitted %v)", wait, ok)
+	}
+
+	*now = now.Add(wait + time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := a.Wait(ctx, "test-ns", PriorityInteractive, 1); err != nil {
+		t.Errorf("Expected Wait to admit after the refill, got %v", err)
+	}
+}
+
+func TestRPC_RateLimited(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	a, _ := newTestAdmission(AdmissionConfig{NamespaceRate: 1})
+	h.SetAdmission(a)
+
+	post := func() *httptest.ResponseRecorder {
+		body := `["stream.write", "account-1", {"type": "Noted", "data": {}}]`
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyNamespace, "test-ns"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := post()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", got)
+	}
+	if !strings.Contains(rec.Body.String(), `"RATE_LIMITED"`) || !strings.Contains(rec.Body.String(), `"priority":"interactive"`) {
+		t.Errorf("Unexpected body: %s", rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"limits":{"namespace":{"rate":1,"burst":1,"available":0,"rejected":1}}`) {
+		t.Errorf("Expected the namespace limit and its usage, got %s", rec.Body.String())
+	}
+
+	// Imports are rejected with the same body
+	importHandler := NewImportHandler(st)
+	importHandler.SetAdmission(a)
+	req := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(""))
+	rec = httptest.NewRecorder()
+	importHandler.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), ContextKeyNamespace, "test-ns")))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" || !strings.Contains(rec.Body.String(), `"limits":{"namespace"`) {
+		t.Errorf("Expected a structured 429 from import, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// sys.stats reports the limit per namespace
+	result, rpcErr := h.route(context.WithValue(context.Background(), ContextKeyNamespace, "test-ns"), "sys.stats", nil)
+	if rpcErr != nil {
+		t.Fatalf("sys.stats failed: %v", rpcErr.Message)
+	}
+	limits := result.(map[string]interface{})["limits"].(map[string]interface{})
+	if usage := limits["namespaces"].(map[string]AdmissionBucketUsage)["test-ns"]; usage.Rate != 1 || usage.Rejected != 2 {
+		t.Errorf("Expected the namespace's limit in sys.stats, got %v", limits)
+	}
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/eventodb/eventodb/internal/store/sqlite"
+	amqp "github.com/rabbitmq/amqp091-go"
+	_ "modernc.org/sqlite"
+)
+
+// fakeAMQPPublisher records published messages
+type fakeAMQPPublisher struct {
+	mu        sync.Mutex
+	published []fakeAMQPPublish
+}
+
+type fakeAMQPPublish struct {
+	exchange   string
+	routingKey string
+	msg        amqp.Publishing
+}
+
+func (p *fakeAMQPPublisher) Publish(_ context.Context, exchange, routingKey string, _ bool, msg amqp.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, fakeAMQPPublish{exchange, routingKey, msg})
+	return nil
+}
+
+func (p *fakeAMQPPublisher) Close() error { return nil }
+
+func (p *fakeAMQPPublisher) snapshot() []fakeAMQPPublish {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]fakeAMQPPublish(nil), p.published...)
+}
+
+// waitForPublished polls until n messages have been published
+func waitForPublished(t *testing.T, pub *fakeAMQPPublisher, n int) []fakeAMQPPublish {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		got := pub.snapshot()
+		if len(got) >= n {
+			return got
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d publishes, got %d", n, len(got))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestAMQPSink_PublishesCategoryAndResumes verifies publishing, routing keys, and position resume
+func TestAMQPSink_PublishesCategoryAndResumes(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	st, err := sqlite.New(db, &sqlite.Config{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateNamespace(ctx, "test-ns", "token-hash", "Test namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	write := func(stream, msgType string) {
+		t.Helper()
+		if _, err := st.WriteMessage(ctx, "test-ns", stream, &store.Message{
+			StreamName: stream,
+			Type:       msgType,
+			Data:       map[string]interface{}{"ok": true},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+
+	write("order-1", "Placed")
+	write("account-1", "Opened") // different category, must not be published
+	write("order-2", "Shipped")
+
+	cfg := AMQPSinkConfig{Sinks: []AMQPSinkRoute{
+		{Name: "orders", Namespace: "test-ns", Category: "order", Exchange: "events"},
+	}}
+
+	sink, err := NewAMQPSink(st, NewPubSub(), cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	pub := &fakeAMQPPublisher{}
+	sink.publisher = pub
+	if err := sink.Start(); err != nil {
+		t.Fatalf("Failed to start sink: %v", err)
+	}
+
+	got := waitForPublished(t, pub, 2)
+	sink.Close()
+
+	if got[0].exchange != "events" || got[0].routingKey != "order.Placed" {
+		t.Errorf("Unexpected first publish: %s %s", got[0].exchange, got[0].routingKey)
+	}
+	if got[1].routingKey != "order.Shipped" {
+		t.Errorf("Expected routing key order.Shipped, got %s", got[1].routingKey)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(got[0].msg.Body, &body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if body["stream"] != "order-1" || body["type"] != "Placed" {
+		t.Errorf("Unexpected body: %v", body)
+	}
+
+	// A restarted sink resumes after the recorded position
+	write("order-3", "Placed")
+
+	sink, err = NewAMQPSink(st, NewPubSub(), cfg)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	pub = &fakeAMQPPublisher{}
+	sink.publisher = pub
+	if err := sink.Start(); err != nil {
+		t.Fatalf("Failed to start sink: %v", err)
+	}
+
+	got = waitForPublished(t, pub, 1)
+	sink.Close()
+
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 publish after resume, got %d", len(got))
+	}
+	if got[0].msg.Headers["stream"] != "order-3" {
+		t.Errorf("Expected order-3 after resume, got %v", got[0].msg.Headers["stream"])
+	}
+}
+
+// TestRenderRoutingKey verifies routing key template substitution
+func TestRenderRoutingKey(t *testing.T) {
+	msg := &store.Message{StreamName: "order-123", Type: "Placed"}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"{category}.{type}", "order.Placed"},
+		{"orders.{id}", "orders.123"},
+		{"{stream}", "order-123"},
+		{"fixed", "fixed"},
+	}
+
+	for _, tt := range tests {
+		if got := renderRoutingKey(tt.tmpl, msg); got != tt.want {
+			t.Errorf("renderRoutingKey(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+// TestNewAMQPSink_InvalidConfig verifies sink validation
+func TestNewAMQPSink_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AMQPSinkConfig
+	}{
+		{"no sinks", AMQPSinkConfig{}},
+		{"missing category", AMQPSinkConfig{Sinks: []AMQPSinkRoute{{Name: "a", Namespace: "ns"}}}},
+		{"duplicate name", AMQPSinkConfig{Sinks: []AMQPSinkRoute{
+			{Name: "a", Namespace: "ns", Category: "x"},
+			{Name: "a", Namespace: "ns", Category: "y"},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAMQPSink(nil, nil, tt.cfg); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Package api provides audit events for rejected tokens.
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// AuthAuditStream records rejected tokens in the namespace they were presented for
+	AuthAuditStream = "eventodb:audit-auth"
+
+	// authAuditInterval is the minimum time between audit events for the same
+	// namespace and error code, so a misbehaving client cannot flood the stream
+	authAuditInterval = time.Minute
+)
+
+// auditWriter is the part of the store used to append audit events
+type auditWriter interface {
+	WriteMessage(ctx context.Context, namespace, streamName string, msg *store.Message) (*store.WriteResult, error)
+}
+
+// authAuditor logs rejected tokens and appends TokenRejected events to the
+// namespace's audit stream
+type authAuditor struct {
+	writer auditWriter // nil if the namespace getter cannot write
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// newAuthAuditor creates an auditor writing through st when it is a store
+func newAuthAuditor(st NamespaceGetter) *authAuditor {
+	if fallback, ok := st.(*FallbackNamespaceGetter); ok {
+		st = fallback.getter
+	}
+	a := &authAuditor{last: make(map[string]time.Time)}
+	a.writer, _ = st.(auditWriter)
+	return a
+}
+
+// record audits a rejected token. namespace is empty for malformed tokens,
+// which are only logged.
+func (a *authAuditor) record(namespace string, rpcErr *RPCError, remoteAddr string) {
+	key := namespace + "\x00" + rpcErr.Code
+	now := time.Now()
+
+	a.mu.Lock()
+	if last, ok := a.last[key]; ok && now.Sub(last) < authAuditInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.last[key] = now
+	a.mu.Unlock()
+
+	logger.Get().Warn().
+		Str("namespace", namespace).
+		Str("code", rpcErr.Code).
+		Str("remoteAddr", remoteAddr).
+		Msg("Token rejected")
+
+	if namespace == "" || a.writer == nil {
+		return
+	}
+	_, err := a.writer.WriteMessage(context.Background(), namespace, AuthAuditStream, &store.Message{
+		StreamName: AuthAuditStream,
+		Type:       "TokenRejected",
+		Data: map[string]interface{}{
+			"code":       rpcErr.Code,
+			"message":    rpcErr.Message,
+			"remoteAddr": remoteAddr,
+			"time":       now.UTC().Format(time.RFC3339Nano),
+		},
+	})
+	if err != nil {
+		logger.Get().Debug().Err(err).Str("namespace", namespace).Msg("Failed to record token rejection")
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthAuditRevokedToken(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.Background()
+
+	result, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-a"})
+	if rpcErr != nil {
+		t.Fatalf("ns.create failed: %v", rpcErr.Message)
+	}
+	oldToken := result.(map[string]interface{})["token"].(string)
+	result, rpcErr = h.route(ctx, "ns.rotateToken", []interface{}{"tenant-a"})
+	if rpcErr != nil {
+		t.Fatalf("ns.rotateToken failed: %v", rpcErr.Message)
+	}
+	newToken := result.(map[string]interface{})["token"].(string)
+
+	authHandler := AuthMiddleware(st, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	call := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewBufferString(`["sys.version"]`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		authHandler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := call(newToken); code != http.StatusOK {
+		t.Fatalf("Expected the new token to work, got %d", code)
+	}
+	// Repeated rejections within the audit interval are recorded once
+	for i := 0; i < 3; i++ {
+		if code := call(oldToken); code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401 for the replaced token, got %d", code)
+		}
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "tenant-a", AuthAuditStream, nil)
+	if err != nil {
+		t.Fatalf("Failed to read audit stream: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].Type != "TokenRejected" || msgs[0].Data["code"] != "TOKEN_REVOKED" {
+		t.Fatalf("Expected one TokenRejected event for TOKEN_REVOKED, got %+v", msgs)
+	}
+}
+
+// TestAuthAuditRecord tests that rejections are throttled per namespace and
+// code, and that malformed tokens, which name no namespace, are only logged
+func TestAuthAuditRecord(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+	a := newAuthAuditor(st)
+
+	a.record("test-ns", &RPCError{Code: "TOKEN_REVOKED", Message: "Token has been revoked"}, "10.0.0.1:5000")
+	a.record("test-ns", &RPCError{Code: "TOKEN_REVOKED", Message: "Token has been revoked"}, "10.0.0.2:5000")
+	a.record("test-ns", &RPCError{Code: "TOKEN_EXPIRED", Message: "Token has expired"}, "10.0.0.1:5000")
+	a.record("", &RPCError{Code: "TOKEN_MALFORMED", Message: "Invalid token format"}, "10.0.0.1:5000")
+	a.record("no-such-ns", &RPCError{Code: "TOKEN_REVOKED", Message: "Token has been revoked"}, "10.0.0.1:5000")
+
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", AuthAuditStream, nil)
+	if err != nil {
+		t.Fatalf("Failed to read audit stream: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Data["code"] != "TOKEN_REVOKED" || msgs[1].Data["code"] != "TOKEN_EXPIRED" {
+		t.Fatalf("Expected TOKEN_REVOKED then TOKEN_EXPIRED, got %+v", msgs)
+	}
+	if msgs[0].Data["remoteAddr"] != "10.0.0.1:5000" || msgs[0].Data["message"] != "Token has been revoked" {
+		t.Errorf("Unexpected event data: %v", msgs[0].Data)
+	}
+
+	// The same code is recorded again once the interval has passed
+	a.last["test-ns\x00TOKEN_REVOKED"] = time.Now().Add(-authAuditInterval)
+	a.record("test-ns", &RPCError{Code: "TOKEN_REVOKED", Message: "Token has been revoked"}, "10.0.0.3:5000")
+	if msgs, err = st.GetStreamMessages(ctx, "test-ns", AuthAuditStream, nil); err != nil || len(msgs) != 3 {
+		t.Errorf("Expected 3 audit events, got %d (%v)", len(msgs), err)
+	}
+
+	// A fallback getter writes through the store it wraps
+	if newAuthAuditor(NewFallbackNamespaceGetter(st)).writer == nil {
+		t.Error("Expected the auditor to write through the wrapped store")
+	}
+}
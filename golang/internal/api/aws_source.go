@@ -0,0 +1,949 @@
+// Package api provides a source connector that lands Kinesis and SQS records in streams.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/secrets"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// kinesisBatchSize is the number of records read from a shard per request
+	kinesisBatchSize = 500
+
+	// kinesisPollInterval is the wait after an empty read of a shard; Kinesis
+	// allows 5 reads per second and shard
+	kinesisPollInterval = time.Second
+
+	// kinesisShardRefresh is how often the shard list is read to follow resharding
+	kinesisShardRefresh = time.Minute
+
+	// sqsWaitSeconds is the long poll of an SQS receive
+	sqsWaitSeconds = 20
+
+	// awsSourceRetryInterval is the wait after a failed request or write
+	awsSourceRetryInterval = 5 * time.Second
+
+	// awsCredentialsTTL is how long credentials are reused before they are
+	// reloaded; instance and task role credentials expire
+	awsCredentialsTTL = 5 * time.Minute
+)
+
+// AWSSourceConfig configures the Kinesis/SQS source connector
+//
+// Example config file:
+//
+//	{
+//	  "region": "eu-west-1",
+//	  "sources": [
+//	    {"name": "clicks", "kind": "kinesis", "kinesisStream": "clicks",
+//	     "token": "ns_...", "stream": "visitor-{key}", "type": "Clicked"},
+//	    {"name": "orders", "kind": "sqs", "queueUrl": "https://sqs.eu-west-1.amazonaws.com/123456789012/orders",
+//	     "token": "ns_...", "stream": "order-{data.orderId}", "type": "OrderPlaced"}
+//	  ]
+//	}
+type AWSSourceConfig struct {
+	Region   string           `json:"region,omitempty"` // Default region (default: AWS_REGION)
+	Sources  []AWSSourceRoute `json:"sources"`
+	TestMode bool             `json:"-"` // Skip token hash verification
+}
+
+// AWSSourceRoute reads one Kinesis stream or SQS queue into a namespace.
+//
+// Stream is a template supporting {key} (the Kinesis partition key or SQS
+// message group ID), {id} (the Kinesis sequence number or SQS message ID),
+// {attr.NAME} (an SQS message attribute) and {data.PATH} (a field of the
+// message data, dotted for nested objects).
+//
+// Kinesis progress is recorded per shard in the namespace stream
+// kinesisSource:position-{name}+{shardId}, in the same transaction as the
+// records on backends with atomic multi-stream writes. SQS messages are
+// deleted once written.
+type AWSSourceRoute struct {
+	Name          string `json:"name"`                    // Unique source name (used for checkpoints)
+	Kind          string `json:"kind"`                    // kinesis or sqs
+	KinesisStream string `json:"kinesisStream,omitempty"` // Kinesis stream name
+	StartAt       string `json:"startAt,omitempty"`       // Kinesis start without checkpoint: TRIM_HORIZON (default) or LATEST
+	QueueURL      string `json:"queueUrl,omitempty"`      // SQS queue URL
+	Region        string `json:"region,omitempty"`        // Overrides the config's region
+	Endpoint      string `json:"endpoint,omitempty"`      // API endpoint override, e.g. LocalStack
+	Token         string `json:"token"`                   // Namespace token
+	Stream        string `json:"stream"`                  // Stream name template
+	Type          string `json:"type,omitempty"`          // Message type when the record has none
+}
+
+// LoadAWSSourceConfig reads a Kinesis/SQS source config from a JSON file
+func LoadAWSSourceConfig(path string) (*AWSSourceConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AWS source config: %w", err)
+	}
+
+	var cfg AWSSourceConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse AWS source config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// AWSSource reads Kinesis streams and SQS queues and writes their records to streams
+type AWSSource struct {
+	store  store.Store
+	pubsub *PubSub
+	cfg    AWSSourceConfig
+	client *http.Client
+	guard  *WriteGuard             // Optional, holds records while the namespace is frozen
+	names  *store.StreamNamePolicy // Optional, rejects invalid rendered stream names
+	admit  *AdmissionController    // Optional, delays records over the write rate limits
+	tmpls  *MetadataTemplates      // Optional, merges per-type default metadata
+	ids    *MessageIDs             // Optional, generates IDs with the namespace's strategy
+	plugin *PluginHost             // Optional, runs the namespace's write plugins
+	notify *Notifier               // Optional, receives connector.failed events
+
+	credsMu sync.Mutex
+	creds   *secrets.AWSCredentials
+	credsAt time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAWSSource creates a new Kinesis/SQS source after validating its routes
+func NewAWSSource(st store.Store, pubsub *PubSub, cfg AWSSourceConfig) (*AWSSource, error) {
+	if len(cfg.Sources) == 0 {
+		return nil, fmt.Errorf("AWS source config requires at least one source")
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+
+	names := make(map[string]bool, len(cfg.Sources))
+	for i := range cfg.Sources {
+		route := &cfg.Sources[i]
+		if route.Name == "" || route.Token == "" || route.Stream == "" {
+			return nil, fmt.Errorf("source %d: name, token and stream are required", i)
+		}
+		if names[route.Name] {
+			return nil, fmt.Errorf("source %d: duplicate name %s", i, route.Name)
+		}
+		names[route.Name] = true
+		if route.Region == "" {
+			route.Region = cfg.Region
+		}
+
+		switch route.Kind {
+		case "kinesis":
+			if route.KinesisStream == "" {
+				return nil, fmt.Errorf("source %d: kinesisStream is required", i)
+			}
+			switch route.StartAt {
+			case "":
+				route.StartAt = "TRIM_HORIZON"
+			case "TRIM_HORIZON", "LATEST":
+			default:
+				return nil, fmt.Errorf("source %d: startAt must be TRIM_HORIZON or LATEST", i)
+			}
+			if route.Region == "" && route.Endpoint == "" {
+				return nil, fmt.Errorf("source %d: region is required (set it or AWS_REGION)", i)
+			}
+		case "sqs":
+			u, err := url.Parse(route.QueueURL)
+			if route.QueueURL == "" || err != nil || u.Host == "" {
+				return nil, fmt.Errorf("source %d: a valid queueUrl is required", i)
+			}
+			if route.Region == "" {
+				// https://sqs.<region>.amazonaws.com/<account>/<queue>
+				if parts := strings.Split(u.Host, "."); len(parts) >= 4 && parts[0] == "sqs" {
+					route.Region = parts[1]
+				}
+			}
+			if route.Region == "" {
+				return nil, fmt.Errorf("source %d: region is required (set it or AWS_REGION)", i)
+			}
+			if route.StartAt != "" {
+				return nil, fmt.Errorf("source %d: startAt only applies to kinesis", i)
+			}
+		default:
+			return nil, fmt.Errorf("source %d: kind must be kinesis or sqs", i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AWSSource{
+		store:  st,
+		pubsub: pubsub,
+		cfg:    cfg,
+		client: &http.Client{Timeout: (sqsWaitSeconds + 10) * time.Second},
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// SetWriteGuard holds records while their namespace is frozen or the server is read-only
+func (s *AWSSource) SetWriteGuard(g *WriteGuard) {
+	s.guard = g
+}
+
+// SetMetadataTemplates merges the namespace's metadata templates into records
+func (s *AWSSource) SetMetadataTemplates(m *MetadataTemplates) {
+	s.tmpls = m
+}
+
+// SetMessageIDs generates the IDs of records with the namespace's strategy
+func (s *AWSSource) SetMessageIDs(m *MessageIDs) {
+	s.ids = m
+}
+
+// SetPluginHost runs the namespace's write plugins on records
+func (s *AWSSource) SetPluginHost(p *PluginHost) {
+	s.plugin = p
+}
+
+// SetStreamNamePolicy rejects records whose rendered stream name breaks the policy
+func (s *AWSSource) SetStreamNamePolicy(p *store.StreamNamePolicy) {
+	s.names = p
+}
+
+// SetAdmission delays records while the write rate limits are exhausted
+func (s *AWSSource) SetAdmission(a *AdmissionController) {
+	s.admit = a
+}
+
+// SetNotifier sets the notifier that receives connector failures (call before Start)
+func (s *AWSSource) SetNotifier(n *Notifier) {
+	s.notify = n
+}
+
+// Start checks every source's token and starts one worker per source
+func (s *AWSSource) Start() error {
+	namespaces := make([]string, len(s.cfg.Sources))
+	for i, route := range s.cfg.Sources {
+		namespace, err := s.authorize(s.ctx, route.Token)
+		if err != nil {
+			return fmt.Errorf("source %s: %w", route.Name, err)
+		}
+		namespaces[i] = namespace
+	}
+
+	for i, route := range s.cfg.Sources {
+		s.wg.Add(1)
+		if route.Kind == "kinesis" {
+			go s.runKinesis(route, namespaces[i])
+		} else {
+			go s.runSQS(route, namespaces[i])
+		}
+	}
+
+	logger.Get().Info().
+		Int("sources", len(s.cfg.Sources)).
+		Msg("AWS source started")
+	return nil
+}
+
+// Close stops all workers
+func (s *AWSSource) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// authorize resolves and verifies the namespace for a token
+func (s *AWSSource) authorize(ctx context.Context, token string) (string, error) {
+	namespace, err := auth.ParseToken(token)
+	if err != nil {
+		return "", fmt.Errorf("invalid token: %w", err)
+	}
+
+	ns, err := s.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return "", fmt.Errorf("namespace %s: %w", namespace, err)
+	}
+	if !s.cfg.TestMode {
+		if err := verifyNamespaceToken(ns, token, time.Now()); err != nil {
+			return "", fmt.Errorf("namespace %s: %w", namespace, err)
+		}
+	}
+	return namespace, nil
+}
+
+// sourceRecord is a Kinesis record or SQS message
+type sourceRecord struct {
+	ID    string            // Kinesis sequence number or SQS message ID
+	Key   string            // Kinesis partition key or SQS message group ID
+	Attrs map[string]string // SQS message attributes
+	Body  []byte
+}
+
+// toMessage decodes a record into the message it is written as
+func (s *AWSSource) toMessage(ctx context.Context, route AWSSourceRoute, namespace string, rec *sourceRecord) (*store.Message, error) {
+	msgType, data, metadata, err := decodeMQTTPayload(rec.Body, route.Type)
+	if err != nil {
+		return nil, err
+	}
+	streamName, err := renderRecordTemplate(route.Stream, rec, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.names.Validate(streamName); err != nil {
+		return nil, err
+	}
+
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	if route.Kind == "kinesis" {
+		metadata["kinesisSequenceNumber"] = rec.ID
+	} else {
+		metadata["sqsMessageId"] = rec.ID
+	}
+
+	msg := &store.Message{
+		StreamName: streamName,
+		Type:       msgType,
+		Data:       data,
+		Metadata:   metadata,
+	}
+	s.ids.Assign(ctx, namespace, msg)
+	s.tmpls.Apply(ctx, namespace, msg)
+	if err := s.plugin.Apply(ctx, namespace, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// write writes a batch of messages, atomically on backends that support it,
+// once the namespace accepts writes
+func (s *AWSSource) write(ctx context.Context, namespace string, msgs []*store.Message) error {
+	if err := s.guard.Check(ctx, namespace); err != nil {
+		return err
+	}
+	for {
+		wait, ok := s.admit.Admit(namespace, PriorityCDC, len(msgs))
+		if ok {
+			break
+		}
+		if !sleepCtx(ctx, wait) {
+			return ctx.Err()
+		}
+	}
+
+	var results []*store.WriteResult
+	writer, ok := s.store.(store.AtomicWriter)
+	err := store.ErrNotSupported
+	if ok {
+		results, err = writer.WriteMessages(ctx, namespace, msgs)
+	}
+	if errors.Is(err, store.ErrNotSupported) {
+		results = make([]*store.WriteResult, len(msgs))
+		err = nil
+		for i, msg := range msgs {
+			if results[i], err = s.store.WriteMessage(ctx, namespace, msg.StreamName, msg); err != nil {
+				results = results[:i]
+				break
+			}
+		}
+	}
+
+	if s.pubsub != nil {
+		for i, result := range results {
+			s.pubsub.Publish(WriteEvent{
+				Namespace:      namespace,
+				Stream:         msgs[i].StreamName,
+				Category:       store.Category(msgs[i].StreamName),
+				Position:       result.Position,
+				GlobalPosition: result.GlobalPosition,
+			})
+		}
+	}
+	return err
+}
+
+// failed logs and reports a connector failure
+func (s *AWSSource) failed(route AWSSourceRoute, namespace string, err error, msg string, fields map[string]interface{}) {
+	log := logger.Get().Warn().Err(err).Str("source", route.Name)
+	for k, v := range fields {
+		log = log.Interface(k, v)
+	}
+	log.Msg(msg)
+	s.notify.Notify(SystemEvent{
+		Type:      SystemEventConnectorFailed,
+		Severity:  "error",
+		Namespace: namespace,
+		Subject:   route.Kind + ":" + route.Name,
+		Message:   fmt.Sprintf("%s: %v", msg, err),
+		Data:      fields,
+	})
+}
+
+// runKinesis reads every shard of a Kinesis stream until the source is
+// stopped. Child shards of a reshard start once their parents are read to the end.
+func (s *AWSSource) runKinesis(route AWSSourceRoute, namespace string) {
+	defer s.wg.Done()
+
+	api := s.kinesisAPI(route)
+	started := make(map[string]bool)
+	finished := make(map[string]bool)
+	done := make(chan string)
+	var workers sync.WaitGroup
+	defer workers.Wait()
+
+	for {
+		shards, err := api.listShards(s.ctx, route.KinesisStream)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.failed(route, namespace, err, "Failed to list Kinesis shards", nil)
+		}
+
+		known := make(map[string]bool, len(shards))
+		for _, shard := range shards {
+			known[shard.ShardID] = true
+		}
+		for _, shard := range shards {
+			if started[shard.ShardID] {
+				continue
+			}
+			// Keep per-key order across a reshard
+			if shard.ParentShardID != "" && known[shard.ParentShardID] && !finished[shard.ParentShardID] {
+				continue
+			}
+			if shard.AdjacentParentShardID != "" && known[shard.AdjacentParentShardID] && !finished[shard.AdjacentParentShardID] {
+				continue
+			}
+			started[shard.ShardID] = true
+			workers.Add(1)
+			go func(shardID string) {
+				defer workers.Done()
+				if s.readShard(api, route, namespace, shardID) {
+					select {
+					case done <- shardID:
+					case <-s.ctx.Done():
+					}
+				}
+			}(shard.ShardID)
+		}
+
+		refresh := time.NewTimer(kinesisShardRefresh)
+	wait:
+		for {
+			select {
+			case <-s.ctx.Done():
+				refresh.Stop()
+				return
+			case id := <-done:
+				// Children of a finished shard start right away
+				finished[id] = true
+				refresh.Stop()
+				break wait
+			case <-refresh.C:
+				break wait
+			}
+		}
+	}
+}
+
+// shardCheckpoint is the recorded progress of a shard
+type shardCheckpoint struct {
+	SequenceNumber string
+	Closed         bool  // The shard was read to its end
+	Version        int64 // Version of the checkpoint stream, -1 if none
+}
+
+// readShard reads a shard from its checkpoint until the source is stopped.
+// It returns true when the shard was read to its end.
+func (s *AWSSource) readShard(api *awsJSONAPI, route AWSSourceRoute, namespace, shard string) bool {
+	ctx := s.ctx
+	checkpointStream := "kinesisSource:position-" + route.Name + "+" + shard
+	fields := map[string]interface{}{"shard": shard}
+
+	var cp shardCheckpoint
+	var iterator string
+	for iterator == "" {
+		var err error
+		if cp, err = s.loadCheckpoint(ctx, namespace, checkpointStream); err == nil {
+			if cp.Closed {
+				return true
+			}
+			iterator, err = api.shardIterator(ctx, route, shard, cp.SequenceNumber)
+		}
+		if err != nil {
+			s.failed(route, namespace, err, "Failed to start reading Kinesis shard", fields)
+			if !sleepCtx(ctx, awsSourceRetryInterval) {
+				return false
+			}
+		}
+	}
+
+	for {
+		out, err := api.getRecords(ctx, iterator)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			var awsErr *awsError
+			if errors.As(err, &awsErr) && awsErr.Type == "ExpiredIteratorException" {
+				if it, err := api.shardIterator(ctx, route, shard, cp.SequenceNumber); err == nil {
+					iterator = it
+					continue
+				}
+			}
+			s.failed(route, namespace, err, "Failed to read Kinesis shard", fields)
+			if !sleepCtx(ctx, awsSourceRetryInterval) {
+				return false
+			}
+			continue
+		}
+
+		if len(out.Records) > 0 || out.NextShardIterator == "" {
+			next := cp
+			msgs := make([]*store.Message, 0, len(out.Records)+1)
+			for _, r := range out.Records {
+				next.SequenceNumber = r.SequenceNumber
+				msg, err := s.toMessage(ctx, route, namespace, &sourceRecord{ID: r.SequenceNumber, Key: r.PartitionKey, Body: r.Data})
+				if err != nil {
+					// A record that can never be written must not stop the shard
+					s.failed(route, namespace, err, "Kinesis record skipped", map[string]interface{}{"shard": shard, "sequenceNumber": r.SequenceNumber})
+					continue
+				}
+				msgs = append(msgs, msg)
+			}
+			next.Closed = out.NextShardIterator == ""
+
+			// The checkpoint's expected version fences off another server reading the same shard
+			expected := cp.Version
+			msgs = append(msgs, &store.Message{
+				StreamName:      checkpointStream,
+				Type:            "Recorded",
+				Data:            map[string]interface{}{"sequenceNumber": next.SequenceNumber, "closed": next.Closed},
+				ExpectedVersion: &expected,
+			})
+			if err := s.write(ctx, namespace, msgs); err != nil {
+				if ctx.Err() != nil {
+					return false
+				}
+				if store.IsVersionConflict(err) {
+					logger.Get().Warn().Str("source", route.Name).Str("shard", shard).
+						Msg("Kinesis shard checkpoint moved by another reader, resuming from it")
+				} else {
+					s.failed(route, namespace, err, "Failed to write Kinesis records", fields)
+					sleepCtx(ctx, awsSourceRetryInterval)
+				}
+				// Read the batch again from the last checkpoint
+				iterator = ""
+				for iterator == "" && ctx.Err() == nil {
+					if cp, err = s.loadCheckpoint(ctx, namespace, checkpointStream); err == nil {
+						if cp.Closed {
+							return true
+						}
+						iterator, err = api.shardIterator(ctx, route, shard, cp.SequenceNumber)
+					}
+					if err != nil && !sleepCtx(ctx, awsSourceRetryInterval) {
+						return false
+					}
+				}
+				continue
+			}
+			next.Version = cp.Version + 1
+			cp = next
+		}
+
+		if out.NextShardIterator == "" {
+			logger.Get().Info().Str("source", route.Name).Str("shard", shard).Msg("Kinesis shard closed")
+			return true
+		}
+		iterator = out.NextShardIterator
+		if len(out.Records) == 0 && !sleepCtx(ctx, kinesisPollInterval) {
+			return false
+		}
+	}
+}
+
+// loadCheckpoint returns the last recorded progress of a shard
+func (s *AWSSource) loadCheckpoint(ctx context.Context, namespace, stream string) (shardCheckpoint, error) {
+	cp := shardCheckpoint{Version: -1}
+	msg, err := s.store.GetLastStreamMessage(ctx, namespace, stream, nil)
+	if err != nil {
+		if errors.Is(err, store.ErrStreamNotFound) {
+			return cp, nil
+		}
+		return cp, err
+	}
+	if msg == nil {
+		return cp, nil
+	}
+	cp.SequenceNumber, _ = msg.Data["sequenceNumber"].(string)
+	cp.Closed, _ = msg.Data["closed"].(bool)
+	cp.Version = msg.Position
+	return cp, nil
+}
+
+// runSQS receives messages from a queue until the source is stopped. Messages
+// are deleted once written; messages that cannot be written are left to the
+// queue's redrive policy.
+func (s *AWSSource) runSQS(route AWSSourceRoute, namespace string) {
+	defer s.wg.Done()
+	ctx := s.ctx
+	api := s.sqsAPI(route)
+
+	for ctx.Err() == nil {
+		messages, err := api.receive(ctx, route.QueueURL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.failed(route, namespace, err, "Failed to receive SQS messages", nil)
+			sleepCtx(ctx, awsSourceRetryInterval)
+			continue
+		}
+		if len(messages) == 0 {
+			continue
+		}
+
+		var msgs []*store.Message
+		var handles []string
+		for _, m := range messages {
+			rec := &sourceRecord{
+				ID:    m.MessageID,
+				Key:   m.Attributes["MessageGroupId"],
+				Attrs: make(map[string]string, len(m.MessageAttributes)),
+				Body:  []byte(m.Body),
+			}
+			for name, attr := range m.MessageAttributes {
+				rec.Attrs[name] = attr.StringValue
+			}
+			msg, err := s.toMessage(ctx, route, namespace, rec)
+			if err != nil {
+				s.failed(route, namespace, err, "SQS message not written", map[string]interface{}{"messageId": m.MessageID})
+				continue
+			}
+			msgs = append(msgs, msg)
+			handles = append(handles, m.ReceiptHandle)
+		}
+		if len(msgs) == 0 {
+			continue
+		}
+
+		if err := s.write(ctx, namespace, msgs); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// The messages become visible again and are retried
+			s.failed(route, namespace, err, "Failed to write SQS messages", nil)
+			sleepCtx(ctx, awsSourceRetryInterval)
+			continue
+		}
+		if err := api.deleteBatch(ctx, route.QueueURL, handles); err != nil {
+			s.failed(route, namespace, err, "Failed to delete written SQS messages, they will be written again", nil)
+		}
+	}
+}
+
+// credentials returns cached AWS credentials, reloading them when stale
+func (s *AWSSource) credentials(ctx context.Context) (*secrets.AWSCredentials, error) {
+	s.credsMu.Lock()
+	defer s.credsMu.Unlock()
+	if s.creds != nil && time.Since(s.credsAt) < awsCredentialsTTL {
+		return s.creds, nil
+	}
+	creds, err := secrets.LoadAWSCredentials(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+	s.creds, s.credsAt = creds, time.Now()
+	return creds, nil
+}
+
+// kinesisAPI returns a client for a route's Kinesis endpoint
+func (s *AWSSource) kinesisAPI(route AWSSourceRoute) *awsJSONAPI {
+	endpoint := route.Endpoint
+	if endpoint == "" {
+		endpoint = "https://kinesis." + route.Region + ".amazonaws.com"
+	}
+	return &awsJSONAPI{
+		source:      s,
+		service:     "kinesis",
+		region:      route.Region,
+		endpoint:    strings.TrimRight(endpoint, "/") + "/",
+		target:      "Kinesis_20131202.",
+		contentType: "application/x-amz-json-1.1",
+	}
+}
+
+// sqsAPI returns a client for a route's SQS endpoint, the queue URL's host by default
+func (s *AWSSource) sqsAPI(route AWSSourceRoute) *awsJSONAPI {
+	endpoint := route.Endpoint
+	if endpoint == "" {
+		u, _ := url.Parse(route.QueueURL)
+		endpoint = u.Scheme + "://" + u.Host
+	}
+	return &awsJSONAPI{
+		source:      s,
+		service:     "sqs",
+		region:      route.Region,
+		endpoint:    strings.TrimRight(endpoint, "/") + "/",
+		target:      "AmazonSQS.",
+		contentType: "application/x-amz-json-1.0",
+	}
+}
+
+// awsJSONAPI calls an AWS service with the JSON protocol
+type awsJSONAPI struct {
+	source      *AWSSource
+	service     string
+	region      string
+	endpoint    string
+	target      string // X-Amz-Target prefix
+	contentType string
+}
+
+// awsError is an error response of an AWS JSON API
+type awsError struct {
+	Status  int
+	Type    string
+	Message string
+}
+
+func (e *awsError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d): %s", e.Type, e.Status, e.Message)
+}
+
+// call invokes action with in as the request and decodes the response into out
+func (a *awsJSONAPI) call(ctx context.Context, action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	creds, err := a.source.credentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", a.contentType)
+	req.Header.Set("X-Amz-Target", a.target+action)
+	secrets.SignAWSRequest(req, body, a.service, a.region, creds, time.Now())
+
+	resp, err := a.source.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Type     string `json:"__type"`
+			Message  string `json:"message"`
+			MessageU string `json:"Message"`
+		}
+		json.Unmarshal(respBody, &e)
+		// Types may be qualified, e.g. com.amazonaws.kinesis.v20131202#ExpiredIteratorException
+		if i := strings.LastIndexByte(e.Type, '#'); i >= 0 {
+			e.Type = e.Type[i+1:]
+		}
+		if e.Message == "" {
+			e.Message = e.MessageU
+		}
+		return &awsError{Status: resp.StatusCode, Type: e.Type, Message: e.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// kinesisShard is a shard of ListShards
+type kinesisShard struct {
+	ShardID               string `json:"ShardId"`
+	ParentShardID         string `json:"ParentShardId"`
+	AdjacentParentShardID string `json:"AdjacentParentShardId"`
+}
+
+// kinesisRecords is a GetRecords response
+type kinesisRecords struct {
+	Records []struct {
+		SequenceNumber string `json:"SequenceNumber"`
+		PartitionKey   string `json:"PartitionKey"`
+		Data           []byte `json:"Data"`
+	} `json:"Records"`
+	NextShardIterator string `json:"NextShardIterator"`
+}
+
+// listShards returns every shard of a Kinesis stream, closed ones included
+func (a *awsJSONAPI) listShards(ctx context.Context, stream string) ([]kinesisShard, error) {
+	var shards []kinesisShard
+	in := map[string]interface{}{"StreamName": stream}
+	for {
+		var out struct {
+			Shards    []kinesisShard `json:"Shards"`
+			NextToken string         `json:"NextToken"`
+		}
+		if err := a.call(ctx, "ListShards", in, &out); err != nil {
+			return nil, err
+		}
+		shards = append(shards, out.Shards...)
+		if out.NextToken == "" {
+			return shards, nil
+		}
+		in = map[string]interface{}{"NextToken": out.NextToken}
+	}
+}
+
+// shardIterator returns an iterator after a sequence number, or at the
+// route's start position when there is none
+func (a *awsJSONAPI) shardIterator(ctx context.Context, route AWSSourceRoute, shard, after string) (string, error) {
+	in := map[string]interface{}{
+		"StreamName":        route.KinesisStream,
+		"ShardId":           shard,
+		"ShardIteratorType": route.StartAt,
+	}
+	if after != "" {
+		in["ShardIteratorType"] = "AFTER_SEQUENCE_NUMBER"
+		in["StartingSequenceNumber"] = after
+	}
+	var out struct {
+		ShardIterator string `json:"ShardIterator"`
+	}
+	if err := a.call(ctx, "GetShardIterator", in, &out); err != nil {
+		return "", err
+	}
+	return out.ShardIterator, nil
+}
+
+// getRecords reads the records at an iterator
+func (a *awsJSONAPI) getRecords(ctx context.Context, iterator string) (*kinesisRecords, error) {
+	var out kinesisRecords
+	err := a.call(ctx, "GetRecords", map[string]interface{}{"ShardIterator": iterator, "Limit": kinesisBatchSize}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// sqsMessage is a message of ReceiveMessage
+type sqsMessage struct {
+	MessageID         string            `json:"MessageId"`
+	ReceiptHandle     string            `json:"ReceiptHandle"`
+	Body              string            `json:"Body"`
+	Attributes        map[string]string `json:"Attributes"`
+	MessageAttributes map[string]struct {
+		StringValue string `json:"StringValue"`
+	} `json:"MessageAttributes"`
+}
+
+// receive long-polls a queue for up to 10 messages
+func (a *awsJSONAPI) receive(ctx context.Context, queueURL string) ([]sqsMessage, error) {
+	var out struct {
+		Messages []sqsMessage `json:"Messages"`
+	}
+	err := a.call(ctx, "ReceiveMessage", map[string]interface{}{
+		"QueueUrl":                    queueURL,
+		"MaxNumberOfMessages":         10,
+		"WaitTimeSeconds":             sqsWaitSeconds,
+		"MessageAttributeNames":       []string{"All"},
+		"MessageSystemAttributeNames": []string{"MessageGroupId"},
+	}, &out)
+	return out.Messages, err
+}
+
+// deleteBatch deletes received messages
+func (a *awsJSONAPI) deleteBatch(ctx context.Context, queueURL string, handles []string) error {
+	entries := make([]map[string]string, len(handles))
+	for i, h := range handles {
+		entries[i] = map[string]string{"Id": fmt.Sprint(i), "ReceiptHandle": h}
+	}
+	var out struct {
+		Failed []struct {
+			Message string `json:"Message"`
+		} `json:"Failed"`
+	}
+	if err := a.call(ctx, "DeleteMessageBatch", map[string]interface{}{"QueueUrl": queueURL, "Entries": entries}, &out); err != nil {
+		return err
+	}
+	if len(out.Failed) > 0 {
+		return fmt.Errorf("%d of %d deletes failed: %s", len(out.Failed), len(handles), out.Failed[0].Message)
+	}
+	return nil
+}
+
+// renderRecordTemplate substitutes {key}, {id}, {attr.NAME} and {data.PATH}
+// in a stream name template. Placeholders without a value are an error.
+func renderRecordTemplate(tmpl string, rec *sourceRecord, data map[string]interface{}) (string, error) {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			sb.WriteString(tmpl)
+			return sb.String(), nil
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			sb.WriteString(tmpl)
+			return sb.String(), nil
+		}
+		end += start
+
+		sb.WriteString(tmpl[:start])
+		key := tmpl[start+1 : end]
+		var value string
+		switch {
+		case key == "key":
+			value = rec.Key
+		case key == "id":
+			value = rec.ID
+		case strings.HasPrefix(key, "attr."):
+			value = rec.Attrs[strings.TrimPrefix(key, "attr.")]
+		case strings.HasPrefix(key, "data."):
+			var v interface{} = data
+			for _, field := range strings.Split(strings.TrimPrefix(key, "data."), ".") {
+				obj, _ := v.(map[string]interface{})
+				v = obj[field]
+			}
+			switch v := v.(type) {
+			case string:
+				value = v
+			case float64, bool:
+				value = fmt.Sprint(v)
+			}
+		default:
+			return "", fmt.Errorf("unknown placeholder {%s} in stream template", key)
+		}
+		if value == "" {
+			return "", fmt.Errorf("record has no value for {%s}", key)
+		}
+		sb.WriteString(value)
+		tmpl = tmpl[end+1:]
+	}
+}
+
+// sleepCtx waits for d and reports false if ctx is done first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// fakeAWS serves the Kinesis and SQS actions used by the AWS source
+type fakeAWS struct {
+	mu       sync.Mutex
+	records  []map[string]interface{} // Records of the single shard "shardId-0"
+	queue    []map[string]interface{} // Messages not yet received
+	deleted  []string                 // Receipt handles of deleted messages
+	reads    int                      // GetRecords calls
+	unsigned int                      // Requests without a SigV4 signature
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDTEST/") {
+		f.unsigned++
+	}
+	var in map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&in)
+
+	var out interface{}
+	switch r.Header.Get("X-Amz-Target") {
+	case "Kinesis_20131202.ListShards":
+		out = map[string]interface{}{"Shards": []map[string]string{{"ShardId": "shardId-0"}}}
+	case "Kinesis_20131202.GetShardIterator":
+		// The iterator is the index of the next record
+		next := 0
+		if seq, ok := in["StartingSequenceNumber"].(string); ok {
+			for i, rec := range f.records {
+				if rec["SequenceNumber"] == seq {
+					next = i + 1
+				}
+			}
+		}
+		out = map[string]interface{}{"ShardIterator": string(rune('0' + next))}
+	case "Kinesis_20131202.GetRecords":
+		f.reads++
+		next := int(in["ShardIterator"].(string)[0] - '0')
+		// Two records per read, then the shard is closed
+		end := next + 2
+		if end > len(f.records) {
+			end = len(f.records)
+		}
+		resp := map[string]interface{}{"Records": f.records[next:end]}
+		if end < len(f.records) {
+			resp["NextShardIterator"] = string(rune('0' + end))
+		}
+		out = resp
+	case "AmazonSQS.ReceiveMessage":
+		msgs := f.queue
+		f.queue = nil
+		if len(msgs) == 0 {
+			// Stand in for the long poll
+			f.mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			f.mu.Lock()
+		}
+		out = map[string]interface{}{"Messages": msgs}
+	case "AmazonSQS.DeleteMessageBatch":
+		for _, e := range in["Entries"].([]interface{}) {
+			f.deleted = append(f.deleted, e.(map[string]interface{})["ReceiptHandle"].(string))
+		}
+		out = map[string]interface{}{}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		out = map[string]string{"__type": "com.amazonaws#UnknownOperationException"}
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// waitForStreamVersion polls a stream until it reaches version
+func waitForStreamVersion(t *testing.T, st store.Store, namespace, stream string, version int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		v, err := st.GetStreamVersion(context.Background(), namespace, stream)
+		if err == nil && v >= version {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not reach version %d (at %d, err %v)", stream, version, v, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestRenderRecordTemplate verifies placeholder substitution in record stream templates
+func TestRenderRecordTemplate(t *testing.T) {
+	rec := &sourceRecord{ID: "m-1", Key: "visitor7", Attrs: map[string]string{"tenant": "acme"}}
+	data := map[string]interface{}{"orderId": "o-9", "count": float64(3), "customer": map[string]interface{}{"id": "c-1"}}
+
+	tests := []struct {
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{"visitor-{key}", "visitor-visitor7", false},
+		{"order-{data.orderId}", "order-o-9", false},
+		{"customer-{data.customer.id}", "customer-c-1", false},
+		{"batch-{data.count}", "batch-3", false},
+		{"{attr.tenant}Order-{id}", "acmeOrder-m-1", false},
+		{"order-{data.missing}", "", true},
+		{"order-{attr.missing}", "", true},
+		{"order-{other}", "", true},
+		{"order-{unterminated", "order-{unterminated", false},
+	}
+
+	for _, tt := range tests {
+		got, err := renderRecordTemplate(tt.tmpl, rec, data)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("renderRecordTemplate(%q) = %q, %v; want %q (error %v)", tt.tmpl, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestNewAWSSource_Validation verifies that invalid sources are rejected
+func TestNewAWSSource_Validation(t *testing.T) {
+	st := newTestStore(t)
+	base := AWSSourceRoute{Name: "s", Token: "ns_x", Stream: "a-{key}", Region: "eu-west-1"}
+
+	tests := []struct {
+		name   string
+		modify func(r *AWSSourceRoute)
+	}{
+		{"unknown kind", func(r *AWSSourceRoute) { r.Kind = "kafka" }},
+		{"kinesis without stream", func(r *AWSSourceRoute) { r.Kind = "kinesis" }},
+		{"bad startAt", func(r *AWSSourceRoute) { r.Kind, r.KinesisStream, r.StartAt = "kinesis", "k", "AT_TIMESTAMP" }},
+		{"sqs without queue", func(r *AWSSourceRoute) { r.Kind = "sqs" }},
+		{"missing token", func(r *AWSSourceRoute) { r.Kind, r.KinesisStream, r.Token = "kinesis", "k", "" }},
+	}
+	for _, tt := range tests {
+		route := base
+		tt.modify(&route)
+		if _, err := NewAWSSource(st, nil, AWSSourceConfig{Sources: []AWSSourceRoute{route}}); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	route := base
+	route.Kind, route.Region, route.QueueURL = "sqs", "", "https://sqs.us-east-2.amazonaws.com/123/q"
+	src, err := NewAWSSource(st, nil, AWSSourceConfig{Sources: []AWSSourceRoute{route}})
+	if err != nil {
+		t.Fatalf("NewAWSSource failed: %v", err)
+	}
+	if src.cfg.Sources[0].Region != "us-east-2" {
+		t.Errorf("region = %q, want it taken from the queue URL", src.cfg.Sources[0].Region)
+	}
+}
+
+// TestAWSSource_Kinesis verifies that shard records land in streams with a
+// checkpoint, invalid records are skipped, and a restart does not re-import
+func TestAWSSource_Kinesis(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDTEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	st, token := setupMQTTBridgeStore(t)
+
+	fake := &fakeAWS{records: []map[string]interface{}{
+		{"SequenceNumber": "100", "PartitionKey": "v1", "Data": []byte(`{"page": "/"}`)},
+		{"SequenceNumber": "101", "PartitionKey": "v2", "Data": []byte(`not json`)},
+		{"SequenceNumber": "102", "PartitionKey": "v1", "Data": []byte(`{"type": "Left", "data": {"page": "/buy"}}`)},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cfg := AWSSourceConfig{Sources: []AWSSourceRoute{{
+		Name: "clicks", Kind: "kinesis", KinesisStream: "clicks", Region: "eu-west-1", Endpoint: server.URL,
+		Token: token, Stream: "visitor-{key}", Type: "Clicked",
+	}}}
+	src, err := NewAWSSource(st, nil, cfg)
+	if err != nil {
+		t.Fatalf("NewAWSSource failed: %v", err)
+	}
+	if err := src.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	// The shard closes after its last record
+	waitForStreamVersion(t, st, "fleet", "kinesisSource:position-clicks+shardId-0", 1)
+	src.Close()
+
+	ctx := context.Background()
+	msgs, err := st.GetStreamMessages(ctx, "fleet", "visitor-v1", nil)
+	if err != nil {
+		t.Fatalf("GetStreamMessages failed: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Type != "Clicked" || msgs[1].Type != "Left" || msgs[1].Data["page"] != "/buy" {
+		t.Fatalf("visitor-v1 = %+v, want Clicked and Left", msgs)
+	}
+	if msgs[0].Metadata["kinesisSequenceNumber"] != "100" {
+		t.Errorf("metadata = %v, want the sequence number", msgs[0].Metadata)
+	}
+	if v, _ := st.GetStreamVersion(ctx, "fleet", "visitor-v2"); v != -1 {
+		t.Errorf("the invalid record was written (version %d)", v)
+	}
+	last, err := st.GetLastStreamMessage(ctx, "fleet", "kinesisSource:position-clicks+shardId-0", nil)
+	if err != nil || last.Data["sequenceNumber"] != "102" || last.Data["closed"] != true {
+		t.Fatalf("checkpoint = %+v (%v), want 102 and closed", last, err)
+	}
+	if fake.unsigned > 0 {
+		t.Errorf("%d requests were not signed", fake.unsigned)
+	}
+
+	// A closed shard is not read again
+	reads := fake.reads
+	src, err = NewAWSSource(st, nil, cfg)
+	if err != nil {
+		t.Fatalf("NewAWSSource failed: %v", err)
+	}
+	if err := src.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	src.Close()
+	if fake.reads != reads {
+		t.Errorf("closed shard read again (%d reads, was %d)", fake.reads, reads)
+	}
+	if v, _ := st.GetStreamVersion(ctx, "fleet", "visitor-v1"); v != 1 {
+		t.Errorf("visitor-v1 version = %d after restart, want 1", v)
+	}
+}
+
+// TestAWSSource_SQS verifies that queue messages are written and then deleted,
+// and that messages that cannot be written stay on the queue
+func TestAWSSource_SQS(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDTEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	st, token := setupMQTTBridgeStore(t)
+
+	fake := &fakeAWS{queue: []map[string]interface{}{
+		{"MessageId": "m-1", "ReceiptHandle": "h-1", "Body": `{"orderId": "o-1", "total": 10}`,
+			"MessageAttributes": map[string]interface{}{"tenant": map[string]string{"DataType": "String", "StringValue": "acme"}}},
+		{"MessageId": "m-2", "ReceiptHandle": "h-2", "Body": `{"total": 5}`},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	src, err := NewAWSSource(st, nil, AWSSourceConfig{Sources: []AWSSourceRoute{{
+		Name: "orders", Kind: "sqs", QueueURL: server.URL + "/123/orders", Region: "eu-west-1",
+		Token: token, Stream: "order-{data.orderId}", Type: "OrderPlaced",
+	}}})
+	if err != nil {
+		t.Fatalf("NewAWSSource failed: %v", err)
+	}
+	if err := src.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForStreamVersion(t, st, "fleet", "order-o-1", 0)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		fake.mu.Lock()
+		deleted := append([]string(nil), fake.deleted...)
+		fake.mu.Unlock()
+		if len(deleted) > 0 {
+			if len(deleted) != 1 || deleted[0] != "h-1" {
+				t.Errorf("deleted = %v, want only h-1", deleted)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("written message was not deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	src.Close()
+
+	msg, err := st.GetLastStreamMessage(context.Background(), "fleet", "order-o-1", nil)
+	if err != nil {
+		t.Fatalf("GetLastStreamMessage failed: %v", err)
+	}
+	if msg.Type != "OrderPlaced" || msg.Data["total"] != float64(10) || msg.Metadata["sqsMessageId"] != "m-1" {
+		t.Errorf("message = %+v", msg)
+	}
+}
@@ -0,0 +1,246 @@
+// Package api provides the catalog of a namespace's completed backups.
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// backupsMetadataKey is the namespace metadata key holding the catalog
+	// of completed backups
+	backupsMetadataKey = "backups"
+
+	// backupCatalogLimit is the number of backups a catalog keeps; older
+	// entries are dropped, their archives are not deleted
+	backupCatalogLimit = 100
+)
+
+// Backup triggers
+const (
+	BackupTriggerSchedule = "schedule"
+	BackupTriggerManual   = "manual"
+)
+
+var (
+	// ErrBackupNotFound is returned for a backup ID missing from the catalog
+	ErrBackupNotFound = errors.New("backup not found")
+
+	// ErrBackupUnavailable is returned when a cataloged archive can no longer
+	// be read from its destination
+	ErrBackupUnavailable = errors.New("backup archive is not available")
+)
+
+// BackupRecord is a completed export in a namespace's backup catalog
+type BackupRecord struct {
+	ID          string `json:"id"`
+	CreatedAt   string `json:"createdAt"`
+	Trigger     string `json:"trigger"`     // schedule or manual
+	Destination string `json:"destination"` // dir or s3
+	Archive     string `json:"archive"`     // Server path or s3://bucket/key
+	Messages    int64  `json:"messages"`
+	Bytes       int64  `json:"bytes"`
+}
+
+// backupCatalog is the stored form of a catalog, oldest backup first
+type backupCatalog struct {
+	Backups []BackupRecord `json:"backups"`
+}
+
+// BackupsFromMetadata returns the backup catalog stored in namespace
+// metadata, newest first
+func BackupsFromMetadata(metadata map[string]interface{}) []BackupRecord {
+	var catalog backupCatalog
+	if raw, ok := metadata[backupsMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, &catalog)
+	}
+	backups := catalog.Backups
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].CreatedAt > backups[j].CreatedAt
+	})
+	return backups
+}
+
+// addBackup adds a backup to the catalog in metadata. Dir archives pruned
+// from disk leave the catalog, and the oldest entries beyond
+// backupCatalogLimit are dropped.
+func addBackup(metadata map[string]interface{}, backup BackupRecord) {
+	var catalog backupCatalog
+	if raw, ok := metadata[backupsMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, &catalog)
+	}
+
+	kept := make([]BackupRecord, 0, len(catalog.Backups)+1)
+	for _, b := range catalog.Backups {
+		if b.ID == backup.ID {
+			continue
+		}
+		if b.Destination == ExportToDir {
+			if _, err := os.Stat(b.Archive); errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+		}
+		kept = append(kept, b)
+	}
+	kept = append(kept, backup)
+	if len(kept) > backupCatalogLimit {
+		kept = kept[len(kept)-backupCatalogLimit:]
+	}
+	metadata[backupsMetadataKey] = encodeMetadataValue(backupCatalog{Backups: kept})
+}
+
+// backupAvailable reports whether a backup's archive can still be read;
+// S3 archives are assumed to be
+func backupAvailable(backup BackupRecord) bool {
+	if backup.Destination != ExportToDir {
+		return true
+	}
+	_, err := os.Stat(backup.Archive)
+	return err == nil
+}
+
+// BackupRestore is the outcome of restoring a backup into a namespace
+type BackupRestore struct {
+	Backup        BackupRecord
+	Restored      int64
+	ConfigApplied bool
+}
+
+// Restore loads a cataloged backup of namespace into target, an empty
+// namespace, applying the archived config except its export schedule so
+// the copy does not back up over the original. S3 archives are read with
+// the credentials of namespace's current S3 schedule. shipper may be nil.
+func (s *ExportScheduler) Restore(ctx context.Context, shipper *LogShipper, namespace, id, target string) (*BackupRestore, error) {
+	ns, err := s.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var backup *BackupRecord
+	for _, b := range BackupsFromMetadata(ns.Metadata) {
+		if b.ID == id {
+			backup = &b
+			break
+		}
+	}
+	if backup == nil {
+		return nil, fmt.Errorf("%w: %q", ErrBackupNotFound, id)
+	}
+
+	archive, err := s.openBackup(ctx, ns.Metadata, *backup)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	restored, configApplied, err := restoreArchive(ctx, s.store, shipper, archive, target)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupRestore{Backup: *backup, Restored: restored, ConfigApplied: configApplied}, nil
+}
+
+// openBackup opens a backup's archive at its destination
+func (s *ExportScheduler) openBackup(ctx context.Context, metadata map[string]interface{}, backup BackupRecord) (io.ReadCloser, error) {
+	switch backup.Destination {
+	case ExportToDir:
+		f, err := os.Open(backup.Archive)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s was deleted", ErrBackupUnavailable, backup.Archive)
+		}
+		return f, err
+
+	case ExportToS3:
+		cfg, _ := ExportScheduleFromMetadata(metadata)
+		location := strings.TrimPrefix(backup.Archive, "s3://")
+		if cfg == nil || cfg.Type != ExportToS3 || !strings.HasPrefix(location, cfg.Bucket+"/") {
+			return nil, fmt.Errorf("%w: reading %s needs an S3 export schedule for its bucket", ErrBackupUnavailable, backup.Archive)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.Endpoint, "/")+"/"+location, nil)
+		if err != nil {
+			return nil, err
+		}
+		signS3Request(req, nil, cfg.Region, cfg.AccessKey, cfg.SecretKey, time.Now())
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %s responded with status %d", ErrBackupUnavailable, backup.Archive, resp.StatusCode)
+		}
+		return resp.Body, nil
+	}
+	return nil, fmt.Errorf("%w: unknown destination type %q", ErrBackupUnavailable, backup.Destination)
+}
+
+// restoreArchive imports a gzipped export archive into namespace and
+// returns the number of messages imported and whether a config line was
+// applied
+func restoreArchive(ctx context.Context, st store.Store, shipper *LogShipper, r io.Reader, namespace string) (int64, bool, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, false, err
+	}
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	batch := make([]*store.Message, 0, importBatchSize)
+	var restored, lineNum int64
+	var configApplied bool
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var record ExportRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return restored, configApplied, fmt.Errorf("malformed JSON at line %d: %w", lineNum, err)
+		}
+		if record.NamespaceConfig != nil {
+			delete(record.NamespaceConfig.Metadata, exportScheduleMetadataKey)
+			if err := ApplyNamespaceConfig(ctx, st, shipper, namespace, record.NamespaceConfig); err != nil {
+				return restored, configApplied, fmt.Errorf("failed to apply namespace config at line %d: %w", lineNum, err)
+			}
+			configApplied = true
+			continue
+		}
+
+		msg, err := recordToMessage(&record)
+		if err != nil {
+			return restored, configApplied, fmt.Errorf("invalid record at line %d: %w", lineNum, err)
+		}
+		batch = append(batch, msg)
+		if len(batch) >= importBatchSize {
+			if err := st.ImportBatch(ctx, namespace, batch); err != nil {
+				return restored, configApplied, err
+			}
+			restored += int64(len(batch))
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return restored, configApplied, err
+	}
+	if len(batch) > 0 {
+		if err := st.ImportBatch(ctx, namespace, batch); err != nil {
+			return restored, configApplied, err
+		}
+		restored += int64(len(batch))
+	}
+	return restored, configApplied, nil
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestBackups tests that scheduled exports are listed by ns.backups.list
+// and can be restored into a new namespace
+func TestBackups(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	for i := 0; i < 3; i++ {
+		if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{Type: "Placed", Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+
+	h := NewRPCHandler("test", st, NewPubSub())
+	h.SetExportScheduler(NewExportScheduler(st, t.TempDir()))
+	if _, rpcErr := h.route(ctx, "ns.exportSchedule.set", []interface{}{map[string]interface{}{"cron": "@daily", "type": "dir"}}); rpcErr != nil {
+		t.Fatalf("ns.exportSchedule.set failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "ns.exportSchedule.run", nil); rpcErr != nil {
+		t.Fatalf("ns.exportSchedule.run failed: %v", rpcErr.Message)
+	}
+
+	result, rpcErr := h.route(ctx, "ns.backups.list", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.backups.list failed: %v", rpcErr.Message)
+	}
+	backups := result.([]interface{})
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %d", len(backups))
+	}
+	backup := backups[0].(map[string]interface{})
+	if backup["trigger"] != BackupTriggerManual || backup["messages"] != float64(3) || backup["available"] != true {
+		t.Errorf("Expected an available manual backup of 3 messages, got %v", backup)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.backups.restore", []interface{}{"missing", "restored"}); rpcErr == nil || rpcErr.Code != "BACKUP_NOT_FOUND" {
+		t.Errorf("Expected BACKUP_NOT_FOUND, got %v", rpcErr)
+	}
+	if _, err := st.GetNamespace(ctx, "restored"); err == nil {
+		t.Error("Expected a failed restore to remove the new namespace")
+	}
+
+	result, rpcErr = h.route(ctx, "ns.backups.restore", []interface{}{backup["id"], "restored"})
+	if rpcErr != nil {
+		t.Fatalf("ns.backups.restore failed: %v", rpcErr.Message)
+	}
+	restored := result.(map[string]interface{})
+	if restored["restored"] != int64(3) || restored["token"] == "" {
+		t.Errorf("Expected 3 messages restored with a token, got %v", restored)
+	}
+	msgs, err := st.GetStreamMessages(ctx, "restored", "order-1", nil)
+	if err != nil {
+		t.Fatalf("Failed to read messages: %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Errorf("Expected 3 restored messages, got %d", len(msgs))
+	}
+	ns, err := st.GetNamespace(ctx, "restored")
+	if err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	if cfg, _ := ExportScheduleFromMetadata(ns.Metadata); cfg != nil {
+		t.Error("Expected the export schedule not to be restored")
+	}
+
+	// Deleted archives are reported as unavailable
+	os.Remove(backup["archive"].(string))
+	result, _ = h.route(ctx, "ns.backups.list", nil)
+	if entry := result.([]interface{})[0].(map[string]interface{}); entry["available"] != false {
+		t.Errorf("Expected the deleted archive to be unavailable, got %v", entry)
+	}
+}
+
+// TestBackups_Errors tests ns.backups.list options and the ways a restore
+// is refused
+func TestBackups_Errors(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	// Without scheduled exports there is nothing to list or restore
+	result, rpcErr := h.route(ctx, "ns.backups.list", nil)
+	if rpcErr != nil || len(result.([]interface{})) != 0 {
+		t.Fatalf("Expected an empty list, got %v (%v)", result, rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "ns.backups.restore", []interface{}{"any", "restored"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without export scheduling, got %v", rpcErr)
+	}
+
+	h.SetExportScheduler(NewExportScheduler(st, t.TempDir()))
+	if _, rpcErr := h.route(ctx, "ns.exportSchedule.set", []interface{}{map[string]interface{}{"cron": "@daily", "type": "dir"}}); rpcErr != nil {
+		t.Fatalf("ns.exportSchedule.set failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "ns.exportSchedule.run", nil); rpcErr != nil {
+		t.Fatalf("ns.exportSchedule.run failed: %v", rpcErr.Message)
+	}
+	err := updateNamespaceMetadata(ctx, st, "test-ns", func(metadata map[string]interface{}) {
+		addBackup(metadata, BackupRecord{ID: "20200101T000000Z", CreatedAt: "2020-01-01T00:00:00Z", Destination: ExportToS3})
+	})
+	if err != nil {
+		t.Fatalf("Failed to catalog an older backup: %v", err)
+	}
+
+	// limit keeps the newest backups
+	result, rpcErr = h.route(ctx, "ns.backups.list", []interface{}{map[string]interface{}{"limit": float64(1)}})
+	if rpcErr != nil {
+		t.Fatalf("ns.backups.list failed: %v", rpcErr.Message)
+	}
+	all, _ := h.route(ctx, "ns.backups.list", nil)
+	if backups := result.([]interface{}); len(backups) != 1 || len(all.([]interface{})) != 2 ||
+		backups[0].(map[string]interface{})["id"] != all.([]interface{})[0].(map[string]interface{})["id"] {
+		t.Errorf("Expected the newest of 2 backups, got %v of %v", backups, all)
+	}
+	newest := all.([]interface{})[0].(map[string]interface{})
+
+	for _, args := range [][]interface{}{
+		{map[string]interface{}{"limit": float64(0)}},
+		{"limit"},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.backups.list", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	for _, args := range [][]interface{}{
+		{newest["id"]},
+		{"", "restored"},
+		{newest["id"], ""},
+		{newest["id"], StagingNamespacePrefix + "x"},
+		{newest["id"], "restored", map[string]interface{}{"description": 1.0}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.backups.restore", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// An existing namespace is never overwritten
+	if _, rpcErr := h.route(ctx, "ns.backups.restore", []interface{}{newest["id"], "test-ns"}); rpcErr == nil || rpcErr.Code != "NAMESPACE_EXISTS" {
+		t.Errorf("Expected NAMESPACE_EXISTS, got %v", rpcErr)
+	}
+
+	// A deleted archive cannot be restored and leaves no namespace behind
+	os.Remove(newest["archive"].(string))
+	if _, rpcErr := h.route(ctx, "ns.backups.restore", []interface{}{newest["id"], "restored"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a deleted archive, got %v", rpcErr)
+	}
+	if _, err := st.GetNamespace(ctx, "restored"); err == nil {
+		t.Error("Expected the failed restore to remove the new namespace")
+	}
+
+	// Read-only servers do not restore
+	guard := NewWriteGuard(NewNamespaceMetadata(st))
+	guard.SetReadOnly(true)
+	h.SetWriteGuard(guard)
+	if _, rpcErr := h.route(ctx, "ns.backups.restore", []interface{}{newest["id"], "restored"}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY, got %v", rpcErr)
+	}
+}
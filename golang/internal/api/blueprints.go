@@ -0,0 +1,215 @@
+// Package api provides namespace blueprints that pre-provision new namespaces.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// blueprintMetadataKey records the blueprint a namespace was created from
+	blueprintMetadataKey = "blueprint"
+
+	// maxBlueprintSeed bounds the seed events of one blueprint
+	maxBlueprintSeed = 1000
+)
+
+var (
+	// ErrBlueprintsDisabled is returned when ns.create names a template but no
+	// blueprint directory is configured
+	ErrBlueprintsDisabled = errors.New("blueprints are not enabled (start the server with --blueprint-dir)")
+
+	// ErrBlueprintNotFound is returned for blueprints that are not in the directory
+	ErrBlueprintNotFound = errors.New("blueprint not found")
+
+	// ErrInvalidBlueprint is returned for blueprint files that cannot be applied
+	ErrInvalidBlueprint = errors.New("invalid blueprint")
+)
+
+// blueprintNamePattern restricts blueprint names to files directly in the
+// blueprint directory
+var blueprintNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Blueprint pre-provisions a namespace created with ns.create's template
+// option. It is stored on the server as {name}.json in the blueprint directory.
+//
+// Example blueprint file:
+//
+//	{
+//	  "description": "Standard tenant",
+//	  "metadata": {"retention": {"rules": [{"streams": "*:position-*", "keep": 1}]}},
+//	  "webhooks": [{"name": "billing", "category": "invoice",
+//	                "url": "https://billing.example.com/hooks/{namespace}", "secrets": ["whsec_1"]}],
+//	  "seed": [{"stream": "settings-default", "type": "Configured", "data": {"currency": "EUR"}}]
+//	}
+type Blueprint struct {
+	Description string                 `json:"description,omitempty"` // Used when ns.create gives none
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`    // Namespace configuration, as in ns.config.export
+	Webhooks    []WebhookRoute         `json:"webhooks,omitempty"`    // Hooks of the namespace; namespace is filled in
+	Seed        []BlueprintEvent       `json:"seed,omitempty"`        // Events written once the namespace is configured
+}
+
+// BlueprintEvent is a seed event of a blueprint
+type BlueprintEvent struct {
+	Stream   string                 `json:"stream"`
+	Type     string                 `json:"type"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Validate checks the parts of the blueprint that are not checked when its
+// configuration is applied
+func (b *Blueprint) Validate() error {
+	if len(b.Seed) > maxBlueprintSeed {
+		return fmt.Errorf("at most %d seed events are allowed", maxBlueprintSeed)
+	}
+	for i, event := range b.Seed {
+		if event.Stream == "" || event.Type == "" {
+			return fmt.Errorf("seed event %d: stream and type are required", i)
+		}
+	}
+	for i, hook := range b.Webhooks {
+		if hook.Namespace != "" {
+			return fmt.Errorf("webhook %d: namespace is set by ns.create", i)
+		}
+	}
+	return validateWebhookRoutes(b.hooksFor("blueprint"), nil)
+}
+
+// hooksFor returns the blueprint's webhooks for a namespace, with {namespace}
+// in their URLs replaced
+func (b *Blueprint) hooksFor(namespace string) []WebhookRoute {
+	hooks := make([]WebhookRoute, len(b.Webhooks))
+	for i, hook := range b.Webhooks {
+		hook.Namespace = namespace
+		hook.URL = strings.ReplaceAll(hook.URL, "{namespace}", namespace)
+		hook.Secrets = append([]string(nil), hook.Secrets...)
+		hooks[i] = hook
+	}
+	return hooks
+}
+
+// Blueprints loads blueprints from a directory. Files are read on every use,
+// so operators edit blueprints without restarting the server. Namespaces
+// already created keep the configuration and seed events they were created
+// with; their webhooks follow the blueprint from the next restart.
+type Blueprints struct {
+	dir string
+}
+
+// NewBlueprints creates a blueprint loader for a directory
+func NewBlueprints(dir string) (*Blueprints, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("blueprint directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("blueprint directory %s is not a directory", dir)
+	}
+	return &Blueprints{dir: dir}, nil
+}
+
+// Load reads and validates a blueprint
+func (b *Blueprints) Load(name string) (*Blueprint, error) {
+	if b == nil {
+		return nil, ErrBlueprintsDisabled
+	}
+	if !blueprintNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: %s", ErrBlueprintNotFound, name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(b.dir, name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrBlueprintNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var bp Blueprint
+	if err := json.Unmarshal(data, &bp); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBlueprint, name, err)
+	}
+	if err := bp.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBlueprint, name, err)
+	}
+	return &bp, nil
+}
+
+// Apply provisions a new namespace from a blueprint: its configuration, its
+// webhooks (hooks may be nil when the blueprint has none) and its seed events
+func (b *Blueprints) Apply(ctx context.Context, st store.Store, shipper *LogShipper, hooks *WebhookPublisher, namespace, name string, bp *Blueprint) error {
+	if len(bp.Webhooks) > 0 && hooks == nil {
+		return fmt.Errorf("%w: %s: webhooks need a running webhook publisher", ErrInvalidBlueprint, name)
+	}
+
+	cfg := &NamespaceConfig{Version: namespaceConfigVersion, Metadata: bp.Metadata}
+	if err := ApplyNamespaceConfig(ctx, st, shipper, namespace, cfg); err != nil {
+		if errors.Is(err, ErrInvalidNamespaceConfig) {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBlueprint, name, err)
+		}
+		return err
+	}
+	err := updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		metadata[blueprintMetadataKey] = name
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range bp.Seed {
+		if _, err := st.WriteMessage(ctx, namespace, event.Stream, &store.Message{
+			StreamName: event.Stream,
+			Type:       event.Type,
+			Data:       event.Data,
+			Metadata:   event.Metadata,
+		}); err != nil {
+			return fmt.Errorf("failed to write seed event to %s: %w", event.Stream, err)
+		}
+	}
+
+	// Hooks start last so they deliver the seed events too
+	if len(bp.Webhooks) > 0 {
+		if err := hooks.AddHooks(bp.hooksFor(namespace)); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBlueprint, name, err)
+		}
+	}
+	return nil
+}
+
+// Hooks returns the webhooks of every namespace created from a blueprint, to
+// be added to the webhook publisher on startup. Namespaces whose blueprint is
+// gone or invalid are skipped with an error listing them.
+func (b *Blueprints) Hooks(ctx context.Context, st store.Store) ([]WebhookRoute, error) {
+	namespaces, err := st.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []WebhookRoute
+	var failed []string
+	for _, ns := range namespaces {
+		name, _ := ns.Metadata[blueprintMetadataKey].(string)
+		if name == "" {
+			continue
+		}
+		bp, err := b.Load(name)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%v)", ns.ID, err))
+			continue
+		}
+		hooks = append(hooks, bp.hooksFor(ns.ID)...)
+	}
+	if len(failed) > 0 {
+		return hooks, fmt.Errorf("blueprint webhooks not started for %s", strings.Join(failed, ", "))
+	}
+	return hooks, nil
+}
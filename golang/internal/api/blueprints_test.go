@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestNamespaceBlueprints tests that ns.create's template option applies a
+// blueprint's configuration, seed events and webhooks
+func TestNamespaceBlueprints(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"standard.json": `{
+			"description": "Standard tenant",
+			"metadata": {"retention": {"rules": [{"streams": "*:position-*", "keep": 1}]}},
+			"webhooks": [{"name": "billing", "category": "invoice", "url": "` + server.URL + `/hooks/{namespace}", "secrets": ["whsec_1"]}],
+			"seed": [{"stream": "invoice-0", "type": "Opened", "data": {"currency": "EUR"}}]
+		}`,
+		"broken.json": `{"metadata": {"retention": {"rules": [{"streams": "*", "keep": 0}]}}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write blueprint: %v", err)
+		}
+	}
+	blueprints, err := NewBlueprints(dir)
+	if err != nil {
+		t.Fatalf("NewBlueprints failed: %v", err)
+	}
+
+	st := newTestStore(t)
+	pubsub := NewPubSub()
+	hooks, err := NewWebhookPublisher(st, pubsub, WebhookConfig{})
+	if err != nil {
+		t.Fatalf("NewWebhookPublisher failed: %v", err)
+	}
+	hooks.Start()
+	defer hooks.Close()
+
+	h := NewRPCHandler("test", st, pubsub)
+	ctx := context.Background()
+
+	// Templates need a blueprint directory
+	if _, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-a", map[string]interface{}{"template": "standard"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without blueprints, got %v", rpcErr)
+	}
+	h.SetBlueprints(blueprints)
+	h.SetWebhookPublisher(hooks)
+
+	for template, code := range map[string]string{"missing": "BLUEPRINT_NOT_FOUND", "../standard": "BLUEPRINT_NOT_FOUND", "broken": "INVALID_REQUEST"} {
+		if _, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-x", map[string]interface{}{"template": template}}); rpcErr == nil || rpcErr.Code != code {
+			t.Errorf("Expected %s for %s, got %v", code, template, rpcErr)
+		}
+	}
+	if _, err := st.GetNamespace(ctx, "tenant-x"); !errors.Is(err, store.ErrNamespaceNotFound) {
+		t.Errorf("Expected the failed namespace to be removed, got %v", err)
+	}
+
+	result, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-a", map[string]interface{}{"template": "standard"}})
+	if rpcErr != nil {
+		t.Fatalf("ns.create failed: %v", rpcErr.Message)
+	}
+	if result.(map[string]interface{})["template"] != "standard" {
+		t.Errorf("Expected template in result, got %v", result)
+	}
+
+	ns, err := st.GetNamespace(ctx, "tenant-a")
+	if err != nil {
+		t.Fatalf("GetNamespace failed: %v", err)
+	}
+	if ns.Description != "Standard tenant" || ns.Metadata[blueprintMetadataKey] != "standard" {
+		t.Errorf("Unexpected namespace: %q %v", ns.Description, ns.Metadata)
+	}
+	if rules := RetentionFromMetadata(ns.Metadata).Rules; len(rules) != 1 || rules[0].Keep != 1 {
+		t.Errorf("Expected the blueprint's retention rule, got %v", rules)
+	}
+	msgs, err := st.GetStreamMessages(ctx, "tenant-a", "invoice-0", nil)
+	if err != nil || len(msgs) != 1 || msgs[0].Data["currency"] != "EUR" {
+		t.Fatalf("Expected the seed event, got %v (%v)", msgs, err)
+	}
+
+	// The blueprint's webhook delivers the seed event to the tenant's URL
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		delivered := len(paths) > 0
+		mu.Unlock()
+		if delivered || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	if len(paths) != 1 || paths[0] != "/hooks/tenant-a" {
+		t.Errorf("Expected a delivery to /hooks/tenant-a, got %v", paths)
+	}
+	mu.Unlock()
+
+	// Restarted servers start the webhooks of namespaces created from blueprints
+	restored, err := blueprints.Hooks(ctx, st)
+	if err != nil || len(restored) != 1 || restored[0].Namespace != "tenant-a" || restored[0].Name != "billing" {
+		t.Errorf("Expected the tenant-a billing hook, got %v (%v)", restored, err)
+	}
+}
+
+// TestBlueprints_Load tests that invalid blueprint directories and files are
+// rejected, and that namespaces whose blueprint is gone are reported on startup
+func TestBlueprints_Load(t *testing.T) {
+	if _, err := NewBlueprints(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+	file := filepath.Join(t.TempDir(), "file.json")
+	if err := os.WriteFile(file, []byte(`{}`), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := NewBlueprints(file); err == nil {
+		t.Error("Expected an error for a file")
+	}
+	var disabled *Blueprints
+	if _, err := disabled.Load("standard"); !errors.Is(err, ErrBlueprintsDisabled) {
+		t.Errorf("Expected ErrBlueprintsDisabled, got %v", err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"plain.json":     `{"seed": [{"stream": "invoice-0", "type": "Opened"}]}`,
+		"hooked.json":    `{"webhooks": [{"name": "billing", "category": "invoice", "url": "http://localhost/{namespace}", "secrets": ["whsec_1"]}]}`,
+		"notjson.json":   `{"seed": [`,
+		"untyped.json":   `{"seed": [{"stream": "invoice-0"}]}`,
+		"namespace.json": `{"webhooks": [{"name": "billing", "namespace": "other", "category": "invoice", "url": "http://localhost/", "secrets": ["whsec_1"]}]}`,
+		"nourl.json":     `{"webhooks": [{"name": "billing", "category": "invoice", "secrets": ["whsec_1"]}]}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write blueprint: %v", err)
+		}
+	}
+	blueprints, err := NewBlueprints(dir)
+	if err != nil {
+		t.Fatalf("NewBlueprints failed: %v", err)
+	}
+	for _, name := range []string{"notjson", "untyped", "namespace", "nourl"} {
+		if _, err := blueprints.Load(name); !errors.Is(err, ErrInvalidBlueprint) {
+			t.Errorf("Expected ErrInvalidBlueprint for %s, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "Plain", "plain.json", "sub/plain"} {
+		if _, err := blueprints.Load(name); !errors.Is(err, ErrBlueprintNotFound) {
+			t.Errorf("Expected ErrBlueprintNotFound for %q, got %v", name, err)
+		}
+	}
+
+	// Webhooks need a running publisher
+	st := newTestStore(t)
+	ctx := context.Background()
+	bp, err := blueprints.Load("hooked")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := blueprints.Apply(ctx, st, nil, nil, "test-ns", "hooked", bp); !errors.Is(err, ErrInvalidBlueprint) {
+		t.Errorf("Expected ErrInvalidBlueprint without a webhook publisher, got %v", err)
+	}
+
+	// A namespace whose blueprint was deleted is reported, others still start
+	if bp, err = blueprints.Load("plain"); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := blueprints.Apply(ctx, st, nil, nil, "test-ns", "plain", bp); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	os.Remove(filepath.Join(dir, "plain.json"))
+	if hooks, err := blueprints.Hooks(ctx, st); err == nil || len(hooks) != 0 {
+		t.Errorf("Expected an error naming test-ns, got %v (%v)", hooks, err)
+	}
+}
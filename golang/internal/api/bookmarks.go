@@ -0,0 +1,144 @@
+// Package api provides named positions (bookmarks) per namespace.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// bookmarksMetadataKey holds the bookmarks in namespace metadata
+	bookmarksMetadataKey = "bookmarks"
+
+	// maxBookmarks bounds the bookmarks kept per namespace
+	maxBookmarks = 1000
+
+	// maxBookmarkNameLength bounds the length of a bookmark name
+	maxBookmarkNameLength = 128
+)
+
+var (
+	// ErrBookmarkNotFound is returned when a bookmark name is not set in a namespace
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+
+	// ErrTooManyBookmarks is returned when a namespace already has maxBookmarks bookmarks
+	ErrTooManyBookmarks = fmt.Errorf("namespace has the maximum of %d bookmarks", maxBookmarks)
+)
+
+// Bookmark is a named global position, such as "release-2024-10 cutover",
+// that reads, exports and subscriptions accept in place of a number
+type Bookmark struct {
+	GlobalPosition int64  `json:"globalPosition"`
+	Note           string `json:"note,omitempty"`
+	Created        string `json:"created"`
+}
+
+// BookmarksFromMetadata returns the bookmarks stored in namespace metadata (never nil)
+func BookmarksFromMetadata(metadata map[string]interface{}) map[string]Bookmark {
+	bookmarks := make(map[string]Bookmark)
+	if raw, ok := metadata[bookmarksMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, &bookmarks)
+	}
+	return bookmarks
+}
+
+// ValidateBookmarkName checks that name can be told apart from a numeric
+// position: it is non-empty, not all digits, and has no control characters
+func ValidateBookmarkName(name string) error {
+	if name == "" {
+		return errors.New("bookmark name must not be empty")
+	}
+	if len(name) > maxBookmarkNameLength {
+		return fmt.Errorf("bookmark name is longer than %d bytes", maxBookmarkNameLength)
+	}
+	if _, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return errors.New("bookmark name must not be a number")
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return errors.New("bookmark name must not contain control characters")
+		}
+	}
+	return nil
+}
+
+// SetBookmark creates or moves a bookmark and returns it
+func SetBookmark(ctx context.Context, st store.Store, namespace, name string, globalPosition int64, note string) (*Bookmark, error) {
+	bookmark := &Bookmark{
+		GlobalPosition: globalPosition,
+		Note:           note,
+		Created:        time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	var tooMany bool
+	err := updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		bookmarks := BookmarksFromMetadata(metadata)
+		if _, exists := bookmarks[name]; !exists && len(bookmarks) >= maxBookmarks {
+			tooMany = true
+			return
+		}
+		bookmarks[name] = *bookmark
+		metadata[bookmarksMetadataKey] = encodeMetadataValue(bookmarks)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tooMany {
+		return nil, ErrTooManyBookmarks
+	}
+	return bookmark, nil
+}
+
+// DeleteBookmark removes a bookmark, returning ErrBookmarkNotFound if it is not set
+func DeleteBookmark(ctx context.Context, st store.Store, namespace, name string) error {
+	var found bool
+	err := updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		bookmarks := BookmarksFromMetadata(metadata)
+		if _, found = bookmarks[name]; !found {
+			return
+		}
+		delete(bookmarks, name)
+		if len(bookmarks) == 0 {
+			delete(metadata, bookmarksMetadataKey)
+			return
+		}
+		metadata[bookmarksMetadataKey] = encodeMetadataValue(bookmarks)
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+// GetBookmark returns a bookmark of namespace, or ErrBookmarkNotFound
+func GetBookmark(ctx context.Context, st store.Store, namespace, name string) (*Bookmark, error) {
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	bookmark, ok := BookmarksFromMetadata(ns.Metadata)[name]
+	if !ok {
+		return nil, ErrBookmarkNotFound
+	}
+	return &bookmark, nil
+}
+
+// ParsePosition parses a position given as a number or a bookmark name
+func ParsePosition(ctx context.Context, st store.Store, namespace, position string) (int64, error) {
+	if pos, err := strconv.ParseInt(position, 10, 64); err == nil {
+		return pos, nil
+	}
+	bookmark, err := GetBookmark(ctx, st, namespace, position)
+	if err != nil {
+		return 0, err
+	}
+	return bookmark.GlobalPosition, nil
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestBookmarks tests that bookmarks are set, listed and deleted, and accepted
+// as positions by reads and subscriptions
+func TestBookmarks(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	var positions []int64
+	for _, stream := range []string{"account-1", "account-2", "account-3"} {
+		result, rpcErr := h.route(ctx, "stream.write", []interface{}{stream, map[string]interface{}{
+			"type": "Opened",
+			"data": map[string]interface{}{},
+		}})
+		if rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+		positions = append(positions, result.(map[string]interface{})["globalPosition"].(int64))
+	}
+
+	if _, rpcErr := h.route(ctx, "bookmark.set", []interface{}{"cutover", float64(positions[1]), map[string]interface{}{"note": "release-2024-10"}}); rpcErr != nil {
+		t.Fatalf("bookmark.set failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "bookmark.set", []interface{}{"start", float64(positions[0])}); rpcErr != nil {
+		t.Fatalf("bookmark.set failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "bookmark.set", []interface{}{"42", float64(1)}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a numeric name, got %v", rpcErr)
+	}
+
+	result, rpcErr := h.route(ctx, "bookmark.get", []interface{}{"cutover"})
+	if rpcErr != nil {
+		t.Fatalf("bookmark.get failed: %v", rpcErr.Message)
+	}
+	if bm := result.(map[string]interface{}); bm["globalPosition"] != positions[1] || bm["note"] != "release-2024-10" {
+		t.Errorf("Unexpected bookmark: %v", bm)
+	}
+
+	result, rpcErr = h.route(ctx, "bookmark.list", nil)
+	if rpcErr != nil {
+		t.Fatalf("bookmark.list failed: %v", rpcErr.Message)
+	}
+	if list := result.([]map[string]interface{}); len(list) != 2 || list[0]["name"] != "start" || list[1]["name"] != "cutover" {
+		t.Errorf("Expected bookmarks ordered by position, got %v", list)
+	}
+
+	// Reads start at the bookmarked global position
+	result, rpcErr = h.route(ctx, "category.get", []interface{}{"account", map[string]interface{}{"position": "cutover"}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr.Message)
+	}
+	if msgs := result.([]interface{}); len(msgs) != 2 {
+		t.Errorf("Expected 2 messages from the bookmark, got %d", len(msgs))
+	}
+	if _, rpcErr := h.route(ctx, "category.get", []interface{}{"account", map[string]interface{}{"position": "missing"}}); rpcErr == nil || rpcErr.Code != "BOOKMARK_NOT_FOUND" {
+		t.Errorf("Expected BOOKMARK_NOT_FOUND, got %v", rpcErr)
+	}
+
+	// Subscriptions accept bookmarks except on single streams, whose positions are per stream
+	sse := NewSSEHandler(st, NewPubSub(), true)
+	if pos, err := sse.parsePosition(ctx, "test-ns", "cutover", ""); err != nil || pos != positions[1] {
+		t.Errorf("Expected position %d, got %d (%v)", positions[1], pos, err)
+	}
+	if _, err := sse.parsePosition(ctx, "test-ns", "cutover", "account-1"); !errors.Is(err, errInvalidPosition) {
+		t.Errorf("Expected errInvalidPosition for a stream subscription, got %v", err)
+	}
+	if _, err := sse.parsePosition(ctx, "test-ns", "missing", ""); positionErrorStatus(err) != 404 {
+		t.Errorf("Expected 404 for an unknown bookmark, got %v", err)
+	}
+
+	if _, rpcErr := h.route(ctx, "bookmark.delete", []interface{}{"cutover"}); rpcErr != nil {
+		t.Fatalf("bookmark.delete failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "bookmark.get", []interface{}{"cutover"}); rpcErr == nil || rpcErr.Code != "BOOKMARK_NOT_FOUND" {
+		t.Errorf("Expected BOOKMARK_NOT_FOUND after delete, got %v", rpcErr)
+	}
+}
+
+// TestBookmarks_Errors tests invalid bookmark arguments, moving a bookmark
+// and the per-namespace limit
+func TestBookmarks_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, args := range [][]interface{}{
+		{"cutover"},
+		{"", float64(1)},
+		{float64(1), float64(1)},
+		{strings.Repeat("b", maxBookmarkNameLength+1), float64(1)},
+		{"cut\nover", float64(1)},
+		{"cutover", "1"},
+		{"cutover", float64(-1)},
+		{"cutover", float64(1), "note"},
+		{"cutover", float64(1), map[string]interface{}{"note": 1.0}},
+	} {
+		if _, rpcErr := h.route(ctx, "bookmark.set", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	for _, method := range []string{"bookmark.get", "bookmark.delete"} {
+		if _, rpcErr := h.route(ctx, method, nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %s without a name, got %v", method, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "bookmark.delete", []interface{}{"missing"}); rpcErr == nil || rpcErr.Code != "BOOKMARK_NOT_FOUND" {
+		t.Errorf("Expected BOOKMARK_NOT_FOUND, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "category.get", []interface{}{"account", map[string]interface{}{"position": true}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a boolean position, got %v", rpcErr)
+	}
+
+	// Setting a bookmark again moves it and replaces its note
+	for _, position := range []int64{5, 9} {
+		if _, rpcErr := h.route(ctx, "bookmark.set", []interface{}{"cutover", position}); rpcErr != nil {
+			t.Fatalf("bookmark.set failed: %v", rpcErr.Message)
+		}
+	}
+	result, rpcErr := h.route(ctx, "bookmark.get", []interface{}{"cutover"})
+	if rpcErr != nil {
+		t.Fatalf("bookmark.get failed: %v", rpcErr.Message)
+	}
+	if bm := result.(map[string]interface{}); bm["globalPosition"] != int64(9) || bm["note"] != nil {
+		t.Errorf("Expected the bookmark moved to 9 without a note, got %v", bm)
+	}
+
+	// Namespaces hold at most maxBookmarks; existing ones can still move
+	err := updateNamespaceMetadata(ctx, st, "test-ns", func(metadata map[string]interface{}) {
+		bookmarks := BookmarksFromMetadata(metadata)
+		for i := len(bookmarks); i < maxBookmarks; i++ {
+			bookmarks[fmt.Sprintf("b%d", i)] = Bookmark{GlobalPosition: int64(i)}
+		}
+		metadata[bookmarksMetadataKey] = encodeMetadataValue(bookmarks)
+	})
+	if err != nil {
+		t.Fatalf("Failed to add bookmarks: %v", err)
+	}
+	if _, rpcErr := h.route(ctx, "bookmark.set", []interface{}{"one-more", float64(1)}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST over the limit, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "bookmark.set", []interface{}{"cutover", float64(10)}); rpcErr != nil {
+		t.Errorf("Expected an existing bookmark to move at the limit, got %v", rpcErr)
+	}
+}
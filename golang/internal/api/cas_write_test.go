@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"testing"
+)
+
+// TestStreamCASWrite tests that stream.casWrite appends only when the last
+// message satisfies the condition
+func TestStreamCASWrite(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	reserve := map[string]interface{}{"type": "Reserved", "data": map[string]interface{}{"by": "order-7"}}
+	release := map[string]interface{}{"type": "Released", "data": map[string]interface{}{"by": "order-7"}}
+	free := map[string]interface{}{"type": []interface{}{"Released"}}
+
+	// The first reservation claims the empty stream
+	result, rpcErr := h.route(ctx, "stream.casWrite", []interface{}{"seat-12A", map[string]interface{}{"exists": false}, reserve})
+	if rpcErr != nil {
+		t.Fatalf("stream.casWrite failed: %v", rpcErr.Message)
+	}
+	if res := result.(map[string]interface{}); res["position"] != int64(0) || res["messages"] != nil {
+		t.Errorf("Unexpected response: %v", res)
+	}
+
+	// A second one fails and reports the current last message
+	_, rpcErr = h.route(ctx, "stream.casWrite", []interface{}{"seat-12A", free, reserve})
+	if rpcErr == nil || rpcErr.Code != "CONDITION_FAILED" {
+		t.Fatalf("Expected CONDITION_FAILED, got %v", rpcErr)
+	}
+	if rpcErr.Details["version"] != int64(0) || rpcErr.Details["last"].([]interface{})[1] != "Reserved" {
+		t.Errorf("Unexpected details: %v", rpcErr.Details)
+	}
+
+	// Releasing by the holder, checked with an EQL condition, frees the seat again
+	held := map[string]interface{}{"type": "Reserved", "where": "data.by = 'order-7'"}
+	if _, rpcErr := h.route(ctx, "stream.casWrite", []interface{}{"seat-12A", held, release}); rpcErr != nil {
+		t.Fatalf("stream.casWrite failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.casWrite", []interface{}{"seat-12A", free, reserve}); rpcErr != nil {
+		t.Fatalf("stream.casWrite failed: %v", rpcErr.Message)
+	}
+	if version, err := st.GetStreamVersion(ctx, "test-ns", "seat-12A"); err != nil || version != 2 {
+		t.Errorf("Expected stream version 2, got %d (%v)", version, err)
+	}
+
+	if _, rpcErr := h.route(ctx, "stream.casWrite", []interface{}{"seat-12A", map[string]interface{}{"kind": "x"}, reserve}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an unknown condition key, got %v", rpcErr)
+	}
+}
+
+// TestStreamCASWrite_Conditions tests condition parsing and how conditions
+// match empty streams, renamed streams and explicit IDs
+func TestStreamCASWrite_Conditions(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+	msg := map[string]interface{}{"type": "Reserved", "data": map[string]interface{}{"by": "order-7"}}
+
+	for _, args := range [][]interface{}{
+		{"seat-1", nil},
+		{"", nil, msg},
+		{"seat-1", nil, "msg"},
+		{"seat-1", "exists", msg},
+		{"seat-1", map[string]interface{}{"exists": "no"}, msg},
+		{"seat-1", map[string]interface{}{"type": 1.0}, msg},
+		{"seat-1", map[string]interface{}{"type": []interface{}{"Reserved", 1.0}}, msg},
+		{"seat-1", map[string]interface{}{"where": 1.0}, msg},
+		{"seat-1", map[string]interface{}{"where": "data.by ="}, msg},
+		{"seat-1", map[string]interface{}{"exists": false, "type": "Reserved"}, msg},
+		{"seat-1", nil, msg, "opts"},
+		{"seat-1", nil, msg, map[string]interface{}{"id": 1.0}},
+	} {
+		if _, rpcErr := h.route(ctx, "stream.casWrite", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// type, where and exists true need a message, so an empty stream fails them
+	for _, cond := range []map[string]interface{}{
+		{"exists": true},
+		{"type": "Released"},
+		{"where": "data.by = 'order-7'"},
+	} {
+		_, rpcErr := h.route(ctx, "stream.casWrite", []interface{}{"seat-1", cond, msg})
+		if rpcErr == nil || rpcErr.Code != "CONDITION_FAILED" {
+			t.Fatalf("Expected CONDITION_FAILED for %v, got %v", cond, rpcErr)
+		}
+		if rpcErr.Details["version"] != int64(-1) || rpcErr.Details["last"] != nil {
+			t.Errorf("Expected version -1 and no last message, got %v", rpcErr.Details)
+		}
+	}
+
+	// A null condition matches any stream, and options.id is kept
+	id := "0194a1b2-7f3a-7c4e-9a1b-2c3d4e5f6a7b"
+	if _, rpcErr := h.route(ctx, "stream.casWrite", []interface{}{"seat-1", nil, msg, map[string]interface{}{"id": id}}); rpcErr != nil {
+		t.Fatalf("stream.casWrite failed: %v", rpcErr.Message)
+	}
+	last, err := st.GetLastStreamMessage(ctx, "test-ns", "seat-1", nil)
+	if err != nil || last.ID != id {
+		t.Fatalf("Expected the message with ID %s, got %v (%v)", id, last, err)
+	}
+	if _, rpcErr := h.route(ctx, "stream.casWrite", []interface{}{"seat-1", map[string]interface{}{"where": "data.by = 'order-8'"}, msg}); rpcErr == nil || rpcErr.Code != "CONDITION_FAILED" {
+		t.Errorf("Expected CONDITION_FAILED for a where that does not match, got %v", rpcErr)
+	}
+
+	// Conditions are checked on the new name of a renamed stream
+	if _, rpcErr := h.route(ctx, "stream.rename", []interface{}{"seat-1", "seat-2"}); rpcErr != nil {
+		t.Fatalf("stream.rename failed: %v", rpcErr.Message)
+	}
+	result, rpcErr := h.route(ctx, "stream.casWrite", []interface{}{"seat-1", map[string]interface{}{"type": "Reserved"}, msg})
+	if rpcErr != nil {
+		t.Fatalf("stream.casWrite on the old name failed: %v", rpcErr.Message)
+	}
+	if res := result.(map[string]interface{}); res["position"] != int64(1) {
+		t.Errorf("Expected position 1 in the renamed stream, got %v", res)
+	}
+}
@@ -0,0 +1,153 @@
+// Package api provides keyset cursors for paging through categories.
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// ErrCursorMismatch occurs when the message a cursor points at is no longer
+// the one it was issued for, because the namespace was restored or reimported
+var ErrCursorMismatch = errors.New("cursor does not match the namespace's messages; restart paging")
+
+// categoryCursor is the last message returned by a cursor read. Cursors are
+// opaque to clients: base64url-encoded JSON.
+type categoryCursor struct {
+	GlobalPosition int64  `json:"g"`
+	Stream         string `json:"s,omitempty"`
+	Position       int64  `json:"p,omitempty"`
+}
+
+// encode returns the cursor as sent to clients
+func (c *categoryCursor) encode() string {
+	if c.GlobalPosition == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parseCursorOption reads options.cursor, which pages through a category with
+// keyset cursors; "" starts at the beginning. It returns nil without a cursor.
+func parseCursorOption(optsObj map[string]interface{}) (*categoryCursor, *RPCError) {
+	val, exists := optsObj["cursor"]
+	if !exists {
+		return nil, nil
+	}
+	s, ok := val.(string)
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options.cursor must be a string",
+		}
+	}
+	for _, name := range []string{"position", "globalPosition", "envelope"} {
+		if _, exists := optsObj[name]; exists {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("options.cursor cannot be combined with options.%s", name),
+			}
+		}
+	}
+
+	cursor := &categoryCursor{}
+	if s == "" {
+		return cursor, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, cursor)
+	}
+	if err != nil || cursor.GlobalPosition < 1 || cursor.Stream == "" || cursor.Position < 0 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options.cursor is not a cursor returned by category.get",
+		}
+	}
+	return cursor, nil
+}
+
+// readCategoryCursor reads the page of a category after cursor. It returns
+// the messages, whether more follow, and the cursor to continue from.
+//
+// The read starts at the cursor's global position, so the message the cursor
+// points at comes back first and is checked and dropped; every later message
+// has a higher position, so none is returned twice. On backends whose global
+// positions may become visible out of order, messages past an in-flight gap
+// (see store.FindPositionGaps) are held back until a later read, so a
+// concurrent write with a lower position is not skipped.
+func readCategoryCursor(ctx context.Context, st store.Store, views *CategoryViews, namespace, categoryName string, opts *store.CategoryOpts, cursor *categoryCursor) ([]*store.Message, bool, *categoryCursor, error) {
+	requested := opts.BatchSize
+	opts.Position = max(cursor.GlobalPosition, 1)
+	opts.GlobalPosition = nil
+	if requested > 0 {
+		// One for the cursor's own message, one to tell whether more follow
+		opts.BatchSize = requested + 2
+	}
+
+	msgs, err := getCategoryMessages(ctx, st, views, namespace, categoryName, opts)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if len(msgs) > 0 && msgs[0].GlobalPosition == cursor.GlobalPosition {
+		if msgs[0].StreamName != cursor.Stream || msgs[0].Position != cursor.Position {
+			return nil, false, nil, ErrCursorMismatch
+		}
+		msgs = msgs[1:]
+	}
+
+	if orderer, ok := st.(store.CommitOrderer); !ok || !orderer.CommitsInOrder(ctx, namespace) {
+		if msgs, err = holdBackInFlight(ctx, st, views, namespace, categoryName, cursor.GlobalPosition+1, msgs); err != nil {
+			return nil, false, nil, err
+		}
+	}
+
+	hasMore := false
+	if requested > 0 && int64(len(msgs)) > requested {
+		msgs, hasMore = msgs[:requested], true
+	}
+	next := cursor
+	if len(msgs) > 0 {
+		last := msgs[len(msgs)-1]
+		next = &categoryCursor{GlobalPosition: last.GlobalPosition, Stream: last.StreamName, Position: last.Position}
+	}
+	return msgs, hasMore, next, nil
+}
+
+// holdBackInFlight drops the messages of a page read from from that lie past
+// a gap a write to the category may still commit at, as category.gaps finds
+// them. The categories of a view are searched one by one. Only the last
+// store.MaxGapRange positions of the page are searched, since in-flight
+// gaps lie above the category's last visible message.
+func holdBackInFlight(ctx context.Context, st store.Store, views *CategoryViews, namespace, categoryName string, from int64, msgs []*store.Message) ([]*store.Message, error) {
+	if len(msgs) == 0 {
+		return msgs, nil
+	}
+	categories := views.Lookup(ctx, namespace, categoryName)
+	if categories == nil {
+		categories = []string{categoryName}
+	}
+
+	last := msgs[len(msgs)-1].GlobalPosition
+	held := last + 1
+	for _, category := range categories {
+		gaps, err := store.FindPositionGaps(ctx, st, namespace, category, max(from, last-store.MaxGapRange+1), last)
+		if err != nil {
+			return nil, err
+		}
+		for _, gap := range gaps {
+			if gap.InFlight {
+				held = min(held, gap.From)
+				break
+			}
+		}
+	}
+	n := sort.Search(len(msgs), func(i int) bool { return msgs[i].GlobalPosition > held })
+	return msgs[:n], nil
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestRPC_CategoryCursor tests paging through a category with keyset cursors
+func TestRPC_CategoryCursor(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	write := func(stream string) {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{stream, map[string]interface{}{"type": "Placed", "data": map[string]interface{}{}}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		write(fmt.Sprintf("order-%d", i%2))
+		write("other-1")
+	}
+
+	page := func(h *RPCHandler, opts map[string]interface{}) map[string]interface{} {
+		result, rpcErr := h.route(ctx, "category.get", []interface{}{"order", opts})
+		if rpcErr != nil {
+			t.Fatalf("category.get failed: %v", rpcErr)
+		}
+		return result.(map[string]interface{})
+	}
+
+	// Page through the category while it is written to; every message is
+	// returned once
+	seen := map[int64]bool{}
+	cursor := ""
+	for i := 0; ; i++ {
+		result := page(h, map[string]interface{}{"cursor": cursor, "batchSize": float64(2)})
+		for _, item := range result["items"].([]interface{}) {
+			gpos := item.([]interface{})[4].(int64)
+			if seen[gpos] {
+				t.Fatalf("Message %d returned twice", gpos)
+			}
+			seen[gpos] = true
+		}
+		if i == 0 {
+			write("order-9")
+		}
+		cursor = result["nextCursor"].(string)
+		if result["hasMore"] != true {
+			break
+		}
+	}
+	if len(seen) != 6 {
+		t.Errorf("Expected 6 messages, got %d", len(seen))
+	}
+
+	// A cursor at the end returns nothing until the category is written to
+	if result := page(h, map[string]interface{}{"cursor": cursor}); result["count"] != 0 || result["nextCursor"] != cursor {
+		t.Errorf("Expected an empty page at the end, got %v", result)
+	}
+
+	for _, opts := range []map[string]interface{}{
+		{"cursor": "not-a-cursor"},
+		{"cursor": "", "position": float64(3)},
+		{"cursor": (&categoryCursor{GlobalPosition: 1, Stream: "order-5", Position: 0}).encode()},
+	} {
+		if _, rpcErr := h.route(ctx, "category.get", []interface{}{"order", opts}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", opts, rpcErr)
+		}
+	}
+
+	// Without in-order commits, reads across categories stop before a gap a
+	// write may still commit at
+	write("late-1")
+	write("late-1")
+	if _, err := st.(store.StreamTruncater).TruncateStream(ctx, "test-ns", "late-1", 1); err != nil {
+		t.Fatalf("Failed to truncate stream: %v", err)
+	}
+	pending := &pendingWritesStore{Store: st, pending: &store.PendingWrites{Allocated: 13, InFlight: true}}
+	unordered := NewRPCHandler("test", pending, NewPubSub())
+	readAll := func() map[string]interface{} {
+		result, rpcErr := unordered.route(ctx, "category.get", []interface{}{"", map[string]interface{}{"cursor": ""}})
+		if rpcErr != nil {
+			t.Fatalf("category.get failed: %v", rpcErr)
+		}
+		return result.(map[string]interface{})
+	}
+	if result := readAll(); result["count"] != 11 {
+		t.Errorf("Expected the messages below the in-flight gap, got %v", result["count"])
+	}
+	pending.pending = &store.PendingWrites{Allocated: 13}
+	if result := readAll(); result["count"] != 12 {
+		t.Errorf("Expected every message once the gap is final, got %v", result["count"])
+	}
+
+	// Writes to one category are serialized, so its own reads hold nothing back
+	pending.pending = &store.PendingWrites{Allocated: 14, InFlight: true}
+	write("order-1")
+	if result := page(unordered, map[string]interface{}{"cursor": cursor}); result["count"] != 1 {
+		t.Errorf("Expected the new message, got %v", result)
+	}
+}
+
+// TestRPC_CategoryCursorErrors tests invalid cursors, unlimited batches and
+// empty categories
+func TestRPC_CategoryCursorErrors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for i := 0; i < 3; i++ {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-1", map[string]interface{}{"type": "Placed", "data": map[string]interface{}{}}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr)
+		}
+	}
+
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	for _, opts := range []map[string]interface{}{
+		{"cursor": float64(1)},
+		{"cursor": "", "globalPosition": float64(1)},
+		{"cursor": "", "envelope": true},
+		{"cursor": encode("not json")},
+		{"cursor": encode(`{"g":0,"s":"order-1"}`)},
+		{"cursor": encode(`{"g":1}`)},
+		{"cursor": encode(`{"g":1,"s":"order-1","p":-1}`)},
+		{"cursor": "!!!"},
+	} {
+		if _, rpcErr := h.route(ctx, "category.get", []interface{}{"order", opts}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", opts, rpcErr)
+		}
+	}
+
+	// A cursor whose message moved to another position must restart paging
+	moved := (&categoryCursor{GlobalPosition: 2, Stream: "order-1", Position: 0}).encode()
+	if _, rpcErr := h.route(ctx, "category.get", []interface{}{"order", map[string]interface{}{"cursor": moved}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" || rpcErr.Message != ErrCursorMismatch.Error() {
+		t.Errorf("Expected a cursor mismatch, got %v", rpcErr)
+	}
+
+	// An unlimited batch returns the rest of the category at once
+	result, rpcErr := h.route(ctx, "category.get", []interface{}{"order", map[string]interface{}{"cursor": "", "batchSize": float64(-1)}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr)
+	}
+	page := result.(map[string]interface{})
+	if page["count"] != 3 || page["hasMore"] != false {
+		t.Errorf("Expected all 3 messages without more, got %v", page)
+	}
+
+	// An empty category has no cursor to continue from
+	result, rpcErr = h.route(ctx, "category.get", []interface{}{"empty", map[string]interface{}{"cursor": ""}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr)
+	}
+	if page := result.(map[string]interface{}); page["count"] != 0 || page["nextCursor"] != "" || page["hasMore"] != false {
+		t.Errorf("Expected an empty page without a cursor, got %v", page)
+	}
+}
@@ -0,0 +1,150 @@
+// Package api provides a category tailer shared by outbound connectors.
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// tailerBatchSize is the number of category messages read per poll
+	tailerBatchSize = 100
+
+	// tailerPollInterval is the fallback poll interval when no writes are signalled
+	tailerPollInterval = time.Second
+)
+
+// categoryTailer delivers every message of a category, in global position order,
+// to a handler. Progress is recorded as "Recorded" messages in positionStream,
+// so a restarted tailer resumes after the last handled message. A handler error
+// stops the current pass; the message is retried on the next poll.
+type categoryTailer struct {
+	name           string // Connector name for logging
+	store          store.Store
+	pubsub         *PubSub
+	namespace      string
+	category       string
+	positionStream string
+	handle         func(ctx context.Context, msg *store.Message) error
+	notifier       *Notifier // Optional, receives connector.failed events
+	stop           <-chan struct{}
+}
+
+// run tails the category until stop is closed
+func (t *categoryTailer) run() {
+	ctx := context.Background()
+	log := logger.Get().With().Str("connector", t.name).Logger()
+
+	position, err := t.loadPosition(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load connector position")
+		return
+	}
+
+	var events Subscriber
+	if t.pubsub != nil {
+		events = t.pubsub.SubscribeCategory(t.namespace, t.category)
+		defer t.pubsub.UnsubscribeCategory(t.namespace, t.category, events)
+	}
+
+	ticker := time.NewTicker(tailerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		next, err := t.drain(ctx, position)
+		if next != position {
+			if err := t.savePosition(ctx, next); err != nil {
+				log.Warn().Err(err).Msg("Failed to record connector position")
+			}
+			position = next
+		}
+		if err != nil {
+			log.Warn().Err(err).Int64("position", position).Msg("Connector delivery failed, will retry")
+			t.notifier.Notify(SystemEvent{
+				Type:      SystemEventConnectorFailed,
+				Severity:  "error",
+				Namespace: t.namespace,
+				Subject:   t.name,
+				Message:   err.Error(),
+				Data:      map[string]interface{}{"position": position},
+			})
+		}
+
+		select {
+		case <-t.stop:
+			return
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain handles all messages after position and returns the last handled global position
+func (t *categoryTailer) drain(ctx context.Context, position int64) (int64, error) {
+	for {
+		msgs, err := t.store.GetCategoryMessages(ctx, t.namespace, t.category, &store.CategoryOpts{
+			Position:  position + 1,
+			BatchSize: tailerBatchSize,
+		})
+		if err != nil {
+			return position, err
+		}
+		if len(msgs) == 0 {
+			return position, nil
+		}
+
+		for _, msg := range msgs {
+			if err := t.handle(ctx, msg); err != nil {
+				return position, err
+			}
+			position = msg.GlobalPosition
+		}
+
+		select {
+		case <-t.stop:
+			return position, nil
+		default:
+		}
+	}
+}
+
+// loadPosition returns the last recorded global position (0 if none)
+func (t *categoryTailer) loadPosition(ctx context.Context) (int64, error) {
+	msg, err := t.store.GetLastStreamMessage(ctx, t.namespace, t.positionStream, nil)
+	if err != nil {
+		if errors.Is(err, store.ErrStreamNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if msg == nil {
+		return 0, nil
+	}
+
+	switch v := msg.Data["position"].(type) {
+	case float64:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	}
+	return 0, nil
+}
+
+// savePosition records the last handled global position
+func (t *categoryTailer) savePosition(ctx context.Context, position int64) error {
+	_, err := t.store.WriteMessage(ctx, t.namespace, t.positionStream, &store.Message{
+		StreamName: t.positionStream,
+		Type:       "Recorded",
+		Data:       map[string]interface{}{"position": position},
+	})
+	return err
+}
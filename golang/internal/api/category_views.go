@@ -0,0 +1,238 @@
+// Package api provides virtual categories that read several categories as one.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// categoryViewsMetadataKey holds the category views in namespace metadata
+	categoryViewsMetadataKey = "categoryViews"
+
+	// maxCategoryViews bounds the views kept per namespace
+	maxCategoryViews = 64
+
+	// maxViewCategories bounds the source categories of one view
+	maxViewCategories = 32
+)
+
+var (
+	// ErrCategoryViewExists is returned when creating a view whose name is taken
+	ErrCategoryViewExists = errors.New("category view already exists")
+
+	// ErrCategoryViewNotFound is returned when deleting an unknown view
+	ErrCategoryViewNotFound = errors.New("category view not found")
+
+	// ErrInvalidCategoryView is returned for views that cannot be created
+	ErrInvalidCategoryView = errors.New("invalid category view")
+)
+
+// CategoryView is a virtual category holding the messages of several
+// physical categories in global position order. Reading it merges reads of
+// the source categories, so no messages are copied.
+type CategoryView struct {
+	Name       string   `json:"name"`
+	Categories []string `json:"categories"`
+}
+
+// Validate checks the view's name and source categories
+func (v *CategoryView) Validate() error {
+	if !isPlainCategory(v.Name) {
+		return fmt.Errorf("name %q must be a category name without '-', '*' or '|'", v.Name)
+	}
+	if len(v.Categories) == 0 || len(v.Categories) > maxViewCategories {
+		return fmt.Errorf("a view needs 1 to %d categories", maxViewCategories)
+	}
+	seen := make(map[string]bool, len(v.Categories))
+	for _, category := range v.Categories {
+		if !isPlainCategory(category) {
+			return fmt.Errorf("category %q must be a category name without '-', '*' or '|'", category)
+		}
+		if category == v.Name {
+			return fmt.Errorf("a view cannot include itself")
+		}
+		if seen[category] {
+			return fmt.Errorf("category %q is listed twice", category)
+		}
+		seen[category] = true
+	}
+	return nil
+}
+
+// isPlainCategory reports whether name is a category name rather than a
+// stream name or pattern
+func isPlainCategory(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "-*|") && !strings.HasPrefix(name, "$")
+}
+
+// CategoryViewsConfig holds a namespace's category views
+type CategoryViewsConfig struct {
+	Views []CategoryView `json:"views"`
+}
+
+// Validate checks the views
+func (c *CategoryViewsConfig) Validate() error {
+	if len(c.Views) > maxCategoryViews {
+		return fmt.Errorf("at most %d category views are allowed", maxCategoryViews)
+	}
+	for i := range c.Views {
+		if err := c.Views[i].Validate(); err != nil {
+			return fmt.Errorf("view %q: %v", c.Views[i].Name, err)
+		}
+		if c.Find(c.Views[i].Name) != &c.Views[i] {
+			return fmt.Errorf("view %q is defined twice", c.Views[i].Name)
+		}
+	}
+	return nil
+}
+
+// Find returns the view called name, or nil
+func (c *CategoryViewsConfig) Find(name string) *CategoryView {
+	for i := range c.Views {
+		if c.Views[i].Name == name {
+			return &c.Views[i]
+		}
+	}
+	return nil
+}
+
+// CategoryViewsFromMetadata returns the category views stored in namespace metadata (never nil)
+func CategoryViewsFromMetadata(metadata map[string]interface{}) *CategoryViewsConfig {
+	cfg := &CategoryViewsConfig{}
+	if raw, ok := metadata[categoryViewsMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, cfg)
+	}
+	return cfg
+}
+
+// CategoryViews resolves category names to the source categories of views
+// kept in namespace metadata. A nil *CategoryViews resolves nothing.
+type CategoryViews struct {
+	meta *NamespaceMetadata
+}
+
+// NewCategoryViews creates a view resolver backed by namespace metadata
+func NewCategoryViews(meta *NamespaceMetadata) *CategoryViews {
+	return &CategoryViews{meta: meta}
+}
+
+// Lookup returns the source categories of the view called name in namespace,
+// or nil when name is not a view. Lookup failures resolve nothing so the
+// store reports its own error on the read.
+func (v *CategoryViews) Lookup(ctx context.Context, namespace, name string) []string {
+	if v == nil || !isPlainCategory(name) {
+		return nil
+	}
+
+	cfg, err := v.meta.decode(ctx, namespace, categoryViewsMetadataKey, func(metadata map[string]interface{}) interface{} {
+		return CategoryViewsFromMetadata(metadata)
+	})
+	if err != nil {
+		return nil
+	}
+	if view := cfg.(*CategoryViewsConfig).Find(name); view != nil {
+		return view.Categories
+	}
+	return nil
+}
+
+// Create adds a view to a namespace. Its name must not be a category that
+// already has messages, which the view would hide.
+func (v *CategoryViews) Create(ctx context.Context, namespace string, view CategoryView) error {
+	if err := view.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCategoryView, err)
+	}
+	existing, err := v.meta.store.GetCategoryMessages(ctx, namespace, view.Name, &store.CategoryOpts{Position: 1, BatchSize: 1})
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("%w: category %q has messages", ErrCategoryViewExists, view.Name)
+	}
+
+	return v.update(ctx, namespace, func(cfg *CategoryViewsConfig) error {
+		if cfg.Find(view.Name) != nil {
+			return fmt.Errorf("%w: %s", ErrCategoryViewExists, view.Name)
+		}
+		cfg.Views = append(cfg.Views, view)
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidCategoryView, err)
+		}
+		return nil
+	})
+}
+
+// Delete removes a view from a namespace; its source categories are kept
+func (v *CategoryViews) Delete(ctx context.Context, namespace, name string) error {
+	return v.update(ctx, namespace, func(cfg *CategoryViewsConfig) error {
+		for i := range cfg.Views {
+			if cfg.Views[i].Name == name {
+				cfg.Views = append(cfg.Views[:i], cfg.Views[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrCategoryViewNotFound, name)
+	})
+}
+
+// update applies change to a namespace's views and stores them unless
+// change fails
+func (v *CategoryViews) update(ctx context.Context, namespace string, change func(cfg *CategoryViewsConfig) error) error {
+	var changeErr error
+	err := v.meta.Update(ctx, namespace, func(metadata map[string]interface{}) {
+		cfg := CategoryViewsFromMetadata(metadata)
+		if changeErr = change(cfg); changeErr != nil {
+			return
+		}
+		if len(cfg.Views) == 0 {
+			delete(metadata, categoryViewsMetadataKey)
+			return
+		}
+		metadata[categoryViewsMetadataKey] = encodeMetadataValue(cfg)
+	})
+	if changeErr != nil {
+		return changeErr
+	}
+	return err
+}
+
+// getCategoryMessages reads categoryName, or the categories of the view of
+// that name, with the same options
+func getCategoryMessages(ctx context.Context, st store.Store, views *CategoryViews, namespace, categoryName string, opts *store.CategoryOpts) ([]*store.Message, error) {
+	if categories := views.Lookup(ctx, namespace, categoryName); categories != nil {
+		return getViewMessages(ctx, st, namespace, categories, opts)
+	}
+	return st.GetCategoryMessages(ctx, namespace, categoryName, opts)
+}
+
+// getViewMessages reads a batch from each category and merges them in
+// global position order. Each read uses the backend's category index, and
+// consumer group and correlation filters apply per message as they do for a
+// single category.
+func getViewMessages(ctx context.Context, st store.Store, namespace string, categories []string, opts *store.CategoryOpts) ([]*store.Message, error) {
+	var merged []*store.Message
+	for _, category := range categories {
+		categoryOpts := *opts
+		msgs, err := st.GetCategoryMessages(ctx, namespace, category, &categoryOpts)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, msgs...)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].GlobalPosition < merged[j].GlobalPosition
+	})
+	// Every category returned up to BatchSize messages from the start
+	// position, so the first BatchSize merged ones are complete
+	if opts.BatchSize > 0 && int64(len(merged)) > opts.BatchSize {
+		merged = merged[:opts.BatchSize]
+	}
+	return merged, nil
+}
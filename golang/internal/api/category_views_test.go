@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestCategoryViews tests that a view reads and subscribes to the union of
+// its categories in global position order
+func TestCategoryViews(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, stream := range []string{"order-1", "invoice-1", "other-1", "order-2", "invoice-2"} {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{stream, map[string]interface{}{
+			"type": "Recorded",
+			"data": map[string]interface{}{},
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+
+	for _, args := range [][]interface{}{
+		{"sales", []interface{}{}},
+		{"sales", []interface{}{"order-1"}},
+		{"sales", []interface{}{"sales"}},
+		{"sales|x", []interface{}{"order"}},
+	} {
+		if _, rpcErr := h.route(ctx, "viewCategory.create", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	// A category with messages cannot be hidden by a view
+	if _, rpcErr := h.route(ctx, "viewCategory.create", []interface{}{"other", []interface{}{"order"}}); rpcErr == nil || rpcErr.Code != "VIEW_EXISTS" {
+		t.Errorf("Expected VIEW_EXISTS, got %v", rpcErr)
+	}
+
+	if _, rpcErr := h.route(ctx, "viewCategory.create", []interface{}{"sales", []interface{}{"order", "invoice"}}); rpcErr != nil {
+		t.Fatalf("viewCategory.create failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "viewCategory.create", []interface{}{"sales", []interface{}{"order"}}); rpcErr == nil || rpcErr.Code != "VIEW_EXISTS" {
+		t.Errorf("Expected VIEW_EXISTS, got %v", rpcErr)
+	}
+	result, rpcErr := h.route(ctx, "viewCategory.list", nil)
+	if rpcErr != nil {
+		t.Fatalf("viewCategory.list failed: %v", rpcErr.Message)
+	}
+	if views := result.([]interface{}); len(views) != 1 {
+		t.Errorf("Expected 1 view, got %v", views)
+	}
+
+	result, rpcErr = h.route(ctx, "category.get", []interface{}{"sales", map[string]interface{}{"batchSize": float64(3)}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr.Message)
+	}
+	var streams []string
+	var last int64
+	for _, row := range result.([]interface{}) {
+		streams = append(streams, row.([]interface{})[1].(string))
+		last = row.([]interface{})[4].(int64)
+	}
+	if len(streams) != 3 || streams[0] != "order-1" || streams[1] != "invoice-1" || streams[2] != "order-2" {
+		t.Errorf("Expected order-1, invoice-1, order-2, got %v", streams)
+	}
+
+	// Paging continues after the last global position
+	result, rpcErr = h.route(ctx, "category.get", []interface{}{"sales", map[string]interface{}{"position": float64(last + 1)}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr.Message)
+	}
+	if rows := result.([]interface{}); len(rows) != 1 || rows[0].([]interface{})[1] != "invoice-2" {
+		t.Errorf("Expected invoice-2, got %v", rows)
+	}
+
+	// Subscriptions follow every category of the view
+	sse := NewSSEHandler(st, NewPubSub(), true)
+	sub, unsubscribe := sse.subscribeCategory(ctx, "test-ns", "sales")
+	defer unsubscribe()
+	for _, category := range []string{"other", "invoice"} {
+		sse.Pubsub.Publish(WriteEvent{Namespace: "test-ns", Stream: category + "-3", Category: category, GlobalPosition: 10})
+	}
+	select {
+	case event := <-sub:
+		if event.Stream != "invoice-3" {
+			t.Errorf("Expected invoice-3, got %s", event.Stream)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a write event for the view")
+	}
+
+	if _, rpcErr := h.route(ctx, "viewCategory.delete", []interface{}{"sales"}); rpcErr != nil {
+		t.Fatalf("viewCategory.delete failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "viewCategory.delete", []interface{}{"sales"}); rpcErr == nil || rpcErr.Code != "VIEW_NOT_FOUND" {
+		t.Errorf("Expected VIEW_NOT_FOUND, got %v", rpcErr)
+	}
+	result, rpcErr = h.route(ctx, "category.get", []interface{}{"sales"})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr.Message)
+	}
+	if rows := result.([]interface{}); len(rows) != 0 {
+		t.Errorf("Expected no messages after deleting the view, got %d", len(rows))
+	}
+}
+
+// TestCategoryViews_Limits tests the view limits and that consumer group
+// options split a view's messages like a category's
+func TestCategoryViews_Limits(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	tooMany := make([]interface{}, maxViewCategories+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("c%d", i)
+	}
+	for _, args := range [][]interface{}{
+		{"sales"},
+		{"sales", "order"},
+		{"sales", tooMany},
+		{"sales", []interface{}{"order", "order"}},
+		{"sales", []interface{}{"order*"}},
+		{"sales", []interface{}{1.0}},
+	} {
+		if _, rpcErr := h.route(ctx, "viewCategory.create", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "viewCategory.delete", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for viewCategory.delete without a name, got %v", rpcErr)
+	}
+
+	for i := 0; i < maxCategoryViews; i++ {
+		if _, rpcErr := h.route(ctx, "viewCategory.create", []interface{}{fmt.Sprintf("view%d", i), []interface{}{"order", "invoice"}}); rpcErr != nil {
+			t.Fatalf("viewCategory.create failed: %v", rpcErr.Message)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "viewCategory.create", []interface{}{"sales", []interface{}{"order"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST over the view limit, got %v", rpcErr)
+	}
+
+	// A view of empty categories reads nothing
+	result, rpcErr := h.route(ctx, "category.get", []interface{}{"view0"})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr.Message)
+	}
+	if rows := result.([]interface{}); len(rows) != 0 {
+		t.Errorf("Expected no messages, got %v", rows)
+	}
+
+	for i := 1; i <= 4; i++ {
+		for _, category := range []string{"order", "invoice"} {
+			if _, rpcErr := h.route(ctx, "stream.write", []interface{}{fmt.Sprintf("%s-%d", category, i), map[string]interface{}{
+				"type": "Recorded",
+				"data": map[string]interface{}{},
+			}}); rpcErr != nil {
+				t.Fatalf("stream.write failed: %v", rpcErr.Message)
+			}
+		}
+	}
+
+	// Each member of a consumer group gets its share of every category
+	seen := make(map[string]int)
+	for member := 0; member < 2; member++ {
+		result, rpcErr := h.route(ctx, "category.get", []interface{}{"view0", map[string]interface{}{
+			"consumerGroup": map[string]interface{}{"member": float64(member), "size": float64(2)},
+		}})
+		if rpcErr != nil {
+			t.Fatalf("category.get failed: %v", rpcErr.Message)
+		}
+		for _, row := range result.([]interface{}) {
+			seen[row.([]interface{})[1].(string)]++
+		}
+	}
+	if len(seen) != 8 {
+		t.Errorf("Expected the members to share all 8 streams once, got %v", seen)
+	}
+	for stream, n := range seen {
+		if n != 1 {
+			t.Errorf("Expected %s read by one member, got %d", stream, n)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package api provides change-data-capture envelope formats for outbound connectors.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// Envelope formats supported by outbound connectors
+const (
+	EnvelopeEventoDB = "eventodb" // Native message JSON (default)
+	EnvelopeDebezium = "debezium" // Debezium change event (payload only, schemas disabled)
+)
+
+// ValidEnvelope reports whether name is a supported envelope format
+func ValidEnvelope(name string) bool {
+	switch name {
+	case "", EnvelopeEventoDB, EnvelopeDebezium:
+		return true
+	}
+	return false
+}
+
+// EncodeEnvelope encodes a message in the given envelope format
+func EncodeEnvelope(format, namespace string, msg *store.Message) ([]byte, error) {
+	switch format {
+	case "", EnvelopeEventoDB:
+		return json.Marshal(nativeEnvelope(msg))
+	case EnvelopeDebezium:
+		return json.Marshal(debeziumEnvelope(namespace, msg))
+	default:
+		return nil, fmt.Errorf("unsupported envelope format: %s", format)
+	}
+}
+
+// nativeEnvelope returns the EventoDB message representation
+func nativeEnvelope(msg *store.Message) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             msg.ID,
+		"stream":         msg.StreamName,
+		"type":           msg.Type,
+		"position":       msg.Position,
+		"globalPosition": msg.GlobalPosition,
+		"data":           msg.Data,
+		"metadata":       msg.Metadata,
+		"time":           msg.Time.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// debeziumEnvelope returns a Debezium-compatible create event for a message.
+//
+// The "after" row mirrors the Message DB messages table, with data and metadata
+// as JSON strings so the row stays flat for JDBC-style sinks. Events are
+// append-only, so "before" is always null and "op" is always "c".
+func debeziumEnvelope(namespace string, msg *store.Message) map[string]interface{} {
+	data, _ := json.Marshal(msg.Data)
+
+	var metadata interface{}
+	if msg.Metadata != nil {
+		encoded, _ := json.Marshal(msg.Metadata)
+		metadata = string(encoded)
+	}
+
+	tsMs := msg.Time.UnixMilli()
+
+	return map[string]interface{}{
+		"before": nil,
+		"after": map[string]interface{}{
+			"id":              msg.ID,
+			"stream_name":     msg.StreamName,
+			"type":            msg.Type,
+			"position":        msg.Position,
+			"global_position": msg.GlobalPosition,
+			"data":            string(data),
+			"metadata":        metadata,
+			"time":            msg.Time.UTC().Format(time.RFC3339Nano),
+		},
+		"source": map[string]interface{}{
+			"connector": "eventodb",
+			"name":      namespace,
+			"ts_ms":     tsMs,
+			"db":        namespace,
+			"table":     store.Category(msg.StreamName),
+			"stream":    msg.StreamName,
+			"lsn":       msg.GlobalPosition,
+		},
+		"op":    "c",
+		"ts_ms": tsMs,
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestEncodeEnvelope_Debezium verifies the Debezium change event shape
+func TestEncodeEnvelope_Debezium(t *testing.T) {
+	msg := &store.Message{
+		ID:             "3f1c2a9e-0000-0000-0000-000000000001",
+		StreamName:     "order-123",
+		Type:           "Placed",
+		Position:       0,
+		GlobalPosition: 42,
+		Data:           map[string]interface{}{"total": 10.5},
+		Metadata:       map[string]interface{}{"correlationStreamName": "cart-9"},
+		Time:           time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	body, err := EncodeEnvelope(EnvelopeDebezium, "shop", msg)
+	if err != nil {
+		t.Fatalf("EncodeEnvelope failed: %v", err)
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("Failed to decode envelope: %v", err)
+	}
+
+	if event["op"] != "c" {
+		t.Errorf("Expected op c, got %v", event["op"])
+	}
+	if v, ok := event["before"]; !ok || v != nil {
+		t.Errorf("Expected before to be present and null, got %v", v)
+	}
+	if event["ts_ms"] != float64(msg.Time.UnixMilli()) {
+		t.Errorf("Unexpected ts_ms: %v", event["ts_ms"])
+	}
+
+	after := event["after"].(map[string]interface{})
+	if after["stream_name"] != "order-123" || after["global_position"] != float64(42) {
+		t.Errorf("Unexpected after row: %v", after)
+	}
+	if after["data"] != `{"total":10.5}` {
+		t.Errorf("Expected data as JSON string, got %v", after["data"])
+	}
+
+	source := event["source"].(map[string]interface{})
+	if source["db"] != "shop" || source["table"] != "order" {
+		t.Errorf("Unexpected source: %v", source)
+	}
+}
+
+// TestEncodeEnvelope_Native verifies the default envelope and unknown formats
+func TestEncodeEnvelope_Native(t *testing.T) {
+	msg := &store.Message{ID: "id-1", StreamName: "order-1", Type: "Placed", Data: map[string]interface{}{}}
+
+	body, err := EncodeEnvelope("", "shop", msg)
+	if err != nil {
+		t.Fatalf("EncodeEnvelope failed: %v", err)
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("Failed to decode envelope: %v", err)
+	}
+	if event["stream"] != "order-1" || event["type"] != "Placed" {
+		t.Errorf("Unexpected native envelope: %v", event)
+	}
+
+	if _, err := EncodeEnvelope("avro", "shop", msg); err == nil {
+		t.Error("Expected error for unsupported envelope")
+	}
+}
@@ -0,0 +1,260 @@
+// Package api provides background compaction of checkpoint streams.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// compactorStartDelay keeps the first pass out of server startup
+	compactorStartDelay = time.Minute
+
+	// compactorStreamPage is the number of streams listed per call
+	compactorStreamPage = 500
+)
+
+// ErrCompactionDisabled is returned when the server was started without a compactor
+var ErrCompactionDisabled = errors.New("stream compaction is not enabled")
+
+// CompactorConfig configures the checkpoint stream compactor
+type CompactorConfig struct {
+	Interval time.Duration // Time between passes (default: 1h)
+	Pause    time.Duration // Pause after each compacted stream (default: 5ms, negative for none)
+}
+
+// CompactorStats summarizes the compactor's work
+type CompactorStats struct {
+	Passes           int64         `json:"passes"`
+	LastPassAt       time.Time     `json:"lastPassAt"`
+	LastPassDuration time.Duration `json:"lastPassDuration"`
+	StreamsCompacted int64         `json:"streamsCompacted"` // In all passes
+	MessagesDeleted  int64         `json:"messagesDeleted"`  // In all passes
+}
+
+// CompactionResult reports one compaction of a namespace
+type CompactionResult struct {
+	StreamsCompacted int64 `json:"streamsCompacted"`
+	MessagesDeleted  int64 `json:"messagesDeleted"`
+}
+
+// Compactor applies namespace retention rules (ns.retention.set): streams
+// matching a rule keep only their latest messages, so consumer position
+// streams stop growing with every checkpoint.
+//
+// Compaction requires a backend implementing store.StreamTruncater. The
+// cutoff of each stream's last compaction is remembered, so idle streams are
+// not truncated again on every pass.
+type Compactor struct {
+	store store.Store
+	cfg   CompactorConfig
+	jobs  *JobScheduler
+
+	mu        sync.Mutex
+	stats     CompactorStats
+	compacted map[string]int64 // namespace|stream -> position of the last truncation
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewCompactor creates a compactor; call Start to begin compacting
+func NewCompactor(st store.Store, cfg CompactorConfig) *Compactor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	if cfg.Pause < 0 {
+		cfg.Pause = 0
+	} else if cfg.Pause == 0 {
+		cfg.Pause = 5 * time.Millisecond
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Compactor{
+		store:     st,
+		cfg:       cfg,
+		compacted: make(map[string]int64),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// SetJobScheduler runs each namespace's compaction as a job of s, so
+// namespaces are compacted side by side (call before Start)
+func (c *Compactor) SetJobScheduler(s *JobScheduler) {
+	c.jobs = s
+}
+
+// Start runs passes in the background until Close
+func (c *Compactor) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		delay := compactorStartDelay
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if err := c.pass(c.ctx); err != nil && c.ctx.Err() == nil {
+				logger.Get().Warn().Err(err).Msg("Compaction pass failed")
+			}
+			delay = c.cfg.Interval
+		}
+	}()
+	logger.Get().Info().Dur("interval", c.cfg.Interval).Msg("Stream compactor started")
+}
+
+// Close stops the compactor, abandoning a pass in progress
+func (c *Compactor) Close() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// Stats returns a snapshot of the compactor's counters
+func (c *Compactor) Stats() CompactorStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// pass compacts every namespace with retention rules once
+func (c *Compactor) pass(ctx context.Context) error {
+	started := time.Now()
+	if _, ok := c.store.(store.StreamTruncater); !ok {
+		return store.ErrNotSupported
+	}
+
+	namespaces, err := c.store.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, ns := range namespaces {
+		// Frozen namespaces are left untouched until ns.unfreeze, WORM ones forever
+		if len(RetentionFromMetadata(ns.Metadata).Rules) == 0 || FreezeStateFromMetadata(ns.Metadata) != nil || WormFromMetadata(ns.Metadata) != nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		// The job scheduler bounds how many namespaces are compacted at once
+		if c.jobs == nil {
+			c.compactNamespace(ctx, ns.ID)
+			continue
+		}
+		wg.Add(1)
+		go func(namespace string) {
+			defer wg.Done()
+			c.compactNamespace(ctx, namespace)
+		}(ns.ID)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	c.mu.Lock()
+	c.stats.Passes++
+	c.stats.LastPassAt = time.Now().UTC()
+	c.stats.LastPassDuration = time.Since(started)
+	c.mu.Unlock()
+	return nil
+}
+
+// compactNamespace compacts a namespace during a pass, logging failures
+func (c *Compactor) compactNamespace(ctx context.Context, namespace string) {
+	if _, err := c.CompactNamespace(ctx, namespace); err != nil && ctx.Err() == nil {
+		logger.Get().Warn().Err(err).Str("namespace", namespace).Msg("Compaction of namespace failed")
+	}
+}
+
+// CompactNamespace applies a namespace's retention rules now, as a job of
+// the job scheduler. It returns store.ErrNotSupported if the backend cannot
+// truncate streams.
+func (c *Compactor) CompactNamespace(ctx context.Context, namespace string) (*CompactionResult, error) {
+	var result *CompactionResult
+	err := c.jobs.Run(ctx, namespace, JobKindRetention, func(ctx context.Context) error {
+		var err error
+		result, err = c.compact(ctx, namespace)
+		return err
+	})
+	return result, err
+}
+
+// compact applies a namespace's retention rules
+func (c *Compactor) compact(ctx context.Context, namespace string) (*CompactionResult, error) {
+	truncater, ok := c.store.(store.StreamTruncater)
+	if !ok {
+		return nil, store.ErrNotSupported
+	}
+
+	ns, err := c.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if WormFromMetadata(ns.Metadata) != nil {
+		return nil, ErrWormProtected
+	}
+	cfg := RetentionFromMetadata(ns.Metadata)
+
+	result := &CompactionResult{}
+	err = forEachRetainedStream(ctx, c.store, namespace, cfg, func(info *store.StreamInfo, rule *RetentionRule) error {
+		return c.compactStream(ctx, truncater, namespace, info, rule.Keep, result)
+	})
+	if err != nil {
+		return result, err
+	}
+	JobEventf(ctx, "Compacted %d streams, deleted %d messages", result.StreamsCompacted, result.MessagesDeleted)
+	return result, nil
+}
+
+// compactStream deletes the messages of one stream older than its latest keep
+func (c *Compactor) compactStream(ctx context.Context, truncater store.StreamTruncater, namespace string, info *store.StreamInfo, keep int64, result *CompactionResult) error {
+	key := namespace + "|" + info.StreamName
+	position := info.Version - keep + 1
+	if position <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	last, seen := c.compacted[key]
+	c.mu.Unlock()
+	if seen && last == position {
+		return nil
+	}
+
+	deleted, err := truncater.TruncateStream(ctx, namespace, info.StreamName, position)
+	if err != nil {
+		return fmt.Errorf("failed to compact stream %s: %w", info.StreamName, err)
+	}
+
+	c.mu.Lock()
+	c.compacted[key] = position
+	if deleted > 0 {
+		c.stats.StreamsCompacted++
+		c.stats.MessagesDeleted += deleted
+	}
+	c.mu.Unlock()
+
+	if deleted > 0 {
+		result.StreamsCompacted++
+		result.MessagesDeleted += deleted
+	}
+	if c.cfg.Pause > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.cfg.Pause):
+		}
+	}
+	return nil
+}
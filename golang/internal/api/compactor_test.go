@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestCompactor tests that retention rules set over RPC compact matching
+// streams only, keep stream versions, and pass the integrity scrubber
+func TestCompactor(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for i := 0; i < 5; i++ {
+		for _, stream := range []string{"order:position-worker", "order-1"} {
+			if _, rpcErr := h.route(ctx, "stream.write", []interface{}{stream, map[string]interface{}{
+				"type": "Recorded",
+				"data": map[string]interface{}{"position": i},
+			}}); rpcErr != nil {
+				t.Fatalf("stream.write failed: %v", rpcErr.Message)
+			}
+		}
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.retention.set", []interface{}{map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{"streams": "*:position-*", "keep": float64(0)}},
+	}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for keep 0, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "ns.retention.set", []interface{}{map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{"streams": "*:position-*", "keep": float64(2)}},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.retention.set failed: %v", rpcErr.Message)
+	}
+	result, rpcErr := h.route(ctx, "ns.retention.get", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.retention.get failed: %v", rpcErr.Message)
+	}
+	if rules := result.(map[string]interface{})["rules"].([]interface{}); len(rules) != 1 {
+		t.Errorf("Expected 1 rule, got %v", rules)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.compact", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without a compactor, got %v", rpcErr)
+	}
+	c := NewCompactor(st, CompactorConfig{Pause: -1})
+	h.SetCompactor(c)
+
+	result, rpcErr = h.route(ctx, "ns.compact", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.compact failed: %v", rpcErr.Message)
+	}
+	if r := result.(map[string]interface{}); r["streamsCompacted"] != int64(1) || r["messagesDeleted"] != int64(3) {
+		t.Errorf("Unexpected compaction result: %v", r)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "order:position-worker", nil)
+	if err != nil || len(msgs) != 2 || msgs[0].Position != 3 {
+		t.Errorf("Expected positions 3 and 4 to be kept, got %d messages (%v)", len(msgs), err)
+	}
+	if version, err := st.GetStreamVersion(ctx, "test-ns", "order:position-worker"); err != nil || version != 4 {
+		t.Errorf("Expected version 4, got %d (%v)", version, err)
+	}
+	if msgs, _ := st.GetStreamMessages(ctx, "test-ns", "order-1", nil); len(msgs) != 5 {
+		t.Errorf("Expected order-1 to be untouched, got %d messages", len(msgs))
+	}
+
+	// Unchanged streams are skipped by later passes
+	if err := c.pass(context.Background()); err != nil {
+		t.Fatalf("Compaction pass failed: %v", err)
+	}
+	if stats := c.Stats(); stats.Passes != 1 || stats.StreamsCompacted != 1 || stats.MessagesDeleted != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Compacted streams do not start at position 0, which is not a gap
+	s := NewScrubber(st, nil, ScrubberConfig{Pause: -1, ReportNamespace: "test-ns"})
+	if err := s.pass(context.Background()); err != nil {
+		t.Fatalf("Scrub pass failed: %v", err)
+	}
+	if got := s.Stats().Anomalies; len(got) != 0 {
+		t.Errorf("Expected no anomalies, got %v", got)
+	}
+
+	// Frozen namespaces are not compacted on demand
+	if _, err := h.guard.Freeze(ctx, "test-ns", "cutover"); err != nil {
+		t.Fatalf("Freeze failed: %v", err)
+	}
+	if _, rpcErr := h.route(ctx, "ns.compact", nil); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY while frozen, got %v", rpcErr)
+	}
+}
+
+// TestCompactor_NotSupported tests that backends without stream truncation are reported
+func TestCompactor_NotSupported(t *testing.T) {
+	st := struct{ store.Store }{newTestStore(t)}
+	c := NewCompactor(st, CompactorConfig{})
+	if _, err := c.CompactNamespace(context.Background(), "test-ns"); err != store.ErrNotSupported {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
+
+// TestCompactor_DryRun tests that ns.compact with dryRun reports and audits
+// the messages compaction would delete without deleting them, and that
+// invalid options delete nothing
+func TestCompactor_DryRun(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h.SetCompactor(NewCompactor(st, CompactorConfig{Pause: -1}))
+
+	for i := 0; i < 4; i++ {
+		for _, stream := range []string{"order:position-worker", "order:position-mailer", "order-1"} {
+			if _, rpcErr := h.route(ctx, "stream.write", []interface{}{stream, map[string]interface{}{
+				"type": "Recorded",
+				"data": map[string]interface{}{"position": i},
+			}}); rpcErr != nil {
+				t.Fatalf("stream.write failed: %v", rpcErr.Message)
+			}
+		}
+	}
+
+	// Without rules there is nothing to delete
+	result, rpcErr := h.route(ctx, "ns.compact", []interface{}{map[string]interface{}{"dryRun": true}})
+	if rpcErr != nil {
+		t.Fatalf("ns.compact failed: %v", rpcErr.Message)
+	}
+	if r := result.(map[string]interface{}); r["messages"] != float64(0) || r["firstGlobalPosition"] != nil || r["operation"] != "ns.compact" {
+		t.Errorf("Expected an empty report, got %v", r)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.retention.set", []interface{}{map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{"streams": "*:position-*", "keep": float64(1)}},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.retention.set failed: %v", rpcErr.Message)
+	}
+	for _, args := range [][]interface{}{
+		{"dryRun"},
+		{map[string]interface{}{"dryRun": "true"}},
+		{map[string]interface{}{"dryRun": true, "keep": float64(0)}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.compact", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// Dry runs are allowed while frozen
+	if _, err := h.guard.Freeze(ctx, "test-ns", "cutover"); err != nil {
+		t.Fatalf("Freeze failed: %v", err)
+	}
+	result, rpcErr = h.route(ctx, "ns.compact", []interface{}{map[string]interface{}{"dryRun": true}})
+	if rpcErr != nil {
+		t.Fatalf("ns.compact failed: %v", rpcErr.Message)
+	}
+	r := result.(map[string]interface{})
+	if r["dryRun"] != true || r["messages"] != float64(6) || r["streams"] != float64(2) || r["firstGlobalPosition"] != float64(1) || r["lastGlobalPosition"] != float64(8) {
+		t.Errorf("Unexpected dry run report: %v", r)
+	}
+	for _, stream := range []string{"order:position-worker", "order:position-mailer"} {
+		if msgs, _ := st.GetStreamMessages(ctx, "test-ns", stream, nil); len(msgs) != 4 {
+			t.Errorf("Expected %s untouched, got %d messages", stream, len(msgs))
+		}
+	}
+	audit, err := st.GetStreamMessages(ctx, "test-ns", AuditStream, nil)
+	if err != nil || len(audit) != 2 || audit[1].Type != "DryRun" || audit[1].Data["operation"] != "ns.compact" || audit[1].Data["messages"] != float64(6) {
+		t.Errorf("Expected the dry runs in the audit stream, got %+v (%v)", audit, err)
+	}
+	if _, rpcErr := h.route(ctx, "ns.compact", []interface{}{map[string]interface{}{"dryRun": false}}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for a compaction while frozen, got %v", rpcErr)
+	}
+
+	// The report matches what compaction deletes
+	if err := h.guard.Unfreeze(ctx, "test-ns"); err != nil {
+		t.Fatalf("Unfreeze failed: %v", err)
+	}
+	result, rpcErr = h.route(ctx, "ns.compact", []interface{}{nil})
+	if rpcErr != nil {
+		t.Fatalf("ns.compact failed: %v", rpcErr.Message)
+	}
+	if r := result.(map[string]interface{}); r["streamsCompacted"] != int64(2) || r["messagesDeleted"] != int64(6) {
+		t.Errorf("Unexpected compaction result: %v", r)
+	}
+}
@@ -0,0 +1,163 @@
+// Package api provides cron expressions for scheduled background work.
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchLimit bounds the search for the next matching minute; a valid
+// expression matches at least once in four years (February 29th)
+const cronSearchLimit = 4 * 366 * 24 * time.Hour
+
+// cronShortcuts are the named schedules accepted in place of five fields
+var cronShortcuts = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// CronSchedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week (0 or 7 is Sunday). Fields take *, numbers,
+// ranges (1-5), steps (*/15, 0-30/10) and comma-separated lists. As in
+// Vixie cron, a day matches if either day field matches when both are
+// restricted. Schedules are evaluated in UTC.
+type CronSchedule struct {
+	expr   string
+	minute uint64 // Bit i set when minute i matches
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	anyDom bool // Day of month is *
+	anyDow bool // Day of week is *
+}
+
+// ParseCron parses a five-field cron expression or a shortcut such as @daily
+func ParseCron(expr string) (*CronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if shortcut, ok := cronShortcuts[strings.ToLower(spec)]; ok {
+		spec = shortcut
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+
+	s := &CronSchedule{expr: expr, anyDom: fields[2] == "*", anyDow: fields[4] == "*"}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron day of week: %w", err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 // 7 is Sunday too
+	}
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", expr)
+	}
+	return s, nil
+}
+
+// parseCronField returns the bit set of the values a field matches
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		first, last := lo, hi
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if first, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			last = first
+			if len(bounds) == 2 {
+				if last, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				last = hi // 5/15 means 5-hi/15
+			}
+		}
+		if first < lo || last > hi || first > last {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, lo, hi)
+		}
+		for v := first; v <= last; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// String returns the expression as given
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// Next returns the first matching minute after t, in UTC, or the zero time
+// if there is none within four years
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether the day of t matches the day fields
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.anyDom && s.anyDow:
+		return true
+	case s.anyDom:
+		return dow
+	case s.anyDow:
+		return dom
+	}
+	return dom || dow
+}
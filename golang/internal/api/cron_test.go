@@ -0,0 +1,83 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCronNext tests the next run of cron expressions
+func TestCronNext(t *testing.T) {
+	from := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC) // A Wednesday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"@daily", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2025, 1, 16, 2, 30, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2025, 1, 15, 10, 40, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2025, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 5", time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)}, // Day of month or Friday
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "0 0 30 2 *", "*/0 * * * *", "5-1 * * * *", "@often"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("Expected ParseCron(%q) to fail", expr)
+		}
+	}
+}
+
+// TestCronEdges tests lists, stepped values, minute boundaries, other time
+// zones and expressions that never match
+func TestCronEdges(t *testing.T) {
+	from := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"5/15 * * * *", from, time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC)},
+		{"0,45 * * * *", from, time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", from, time.Date(2025, 1, 16, 10, 30, 0, 0, time.UTC)}, // Strictly after from
+		{"* * * * *", from.Add(59 * time.Second), time.Date(2025, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"@hourly", from.In(time.FixedZone("UTC+2", 2*60*60)), time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", from, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2025, 1, 31, 10, 30, 0, 0, time.UTC), time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"@Monthly", from, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if s.String() != tt.expr {
+			t.Errorf("Expected String() to return %q, got %q", tt.expr, s.String())
+		}
+		got := s.Next(tt.from)
+		if !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("Next(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{
+		"a * * * *", "1-2-3 * * * *", "*/x * * * *", "1,,2 * * * *", "-1 * * * *",
+		"0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "0 0 31 2,4 *", "* * * * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("Expected ParseCron(%q) to fail", expr)
+		}
+	}
+}
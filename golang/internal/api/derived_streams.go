@@ -0,0 +1,232 @@
+// Package api provides per-category write rules that append derived events.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// derivedStreamsMetadataKey holds the derived stream rules in namespace metadata
+	derivedStreamsMetadataKey = "derivedStreams"
+
+	// maxDerivedStreamRules bounds the rules kept per namespace
+	maxDerivedStreamRules = 32
+)
+
+// derivedTemplateFields are the placeholders of DerivedStreamRule.Stream and Type
+var derivedTemplateFields = []string{"{stream}", "{category}", "{id}", "{cardinalId}", "{type}"}
+
+// DerivedStreamRule appends an event to another stream whenever a message is
+// written to a category, such as a summary event to "orderSummary-{id}" for
+// every "order-*" write.
+//
+// Stream and Type are templates: {stream}, {category}, {id}, {cardinalId}
+// and {type} are replaced with the parts of the written message.
+type DerivedStreamRule struct {
+	Category string   `json:"category"`         // Source category, e.g. "order"
+	Types    []string `json:"types,omitempty"`  // Source message types; empty matches all
+	Stream   string   `json:"stream"`           // Target stream template, e.g. "orderSummary-{id}"
+	Type     string   `json:"type,omitempty"`   // Derived message type template (default "{type}")
+	Fields   []string `json:"fields,omitempty"` // Data fields copied; empty copies all
+}
+
+// DerivedStreamsConfig holds a namespace's derived stream rules. Every rule
+// matching a write applies, in order.
+type DerivedStreamsConfig struct {
+	Rules []DerivedStreamRule `json:"rules"`
+}
+
+// Validate checks the rules
+func (c *DerivedStreamsConfig) Validate() error {
+	if len(c.Rules) > maxDerivedStreamRules {
+		return fmt.Errorf("at most %d derived stream rules are allowed", maxDerivedStreamRules)
+	}
+	for i, rule := range c.Rules {
+		if rule.Category == "" || strings.Contains(rule.Category, "-") {
+			return fmt.Errorf("rule %d: category must be a non-empty category name", i)
+		}
+		if rule.Stream == "" {
+			return fmt.Errorf("rule %d: stream must not be empty", i)
+		}
+		for _, tmpl := range []string{rule.Stream, rule.Type} {
+			if err := checkDerivedTemplate(tmpl); err != nil {
+				return fmt.Errorf("rule %d: %v", i, err)
+			}
+		}
+		// Writing back to the source category would derive from derived events
+		targetCategory := strings.ReplaceAll(store.Category(rule.Stream), "{category}", rule.Category)
+		if targetCategory == rule.Category {
+			return fmt.Errorf("rule %d: stream must be outside the %q category", i, rule.Category)
+		}
+	}
+	return nil
+}
+
+// checkDerivedTemplate rejects placeholders other than derivedTemplateFields
+func checkDerivedTemplate(tmpl string) error {
+	rest := tmpl
+	for _, field := range derivedTemplateFields {
+		rest = strings.ReplaceAll(rest, field, "")
+	}
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("unknown placeholder in %q (use %s)", tmpl, strings.Join(derivedTemplateFields, ", "))
+	}
+	return nil
+}
+
+// DerivedStreamsFromMetadata returns the derived stream rules stored in namespace metadata (never nil)
+func DerivedStreamsFromMetadata(metadata map[string]interface{}) *DerivedStreamsConfig {
+	cfg := &DerivedStreamsConfig{}
+	if raw, ok := metadata[derivedStreamsMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, cfg)
+	}
+	return cfg
+}
+
+// Derive returns the events the rules derive from a message written to
+// msg.StreamName. Derived events are not derived from again.
+func (c *DerivedStreamsConfig) Derive(msg *store.Message) []*store.Message {
+	var derived []*store.Message
+	category := store.Category(msg.StreamName)
+	id := store.ID(msg.StreamName)
+	for _, rule := range c.Rules {
+		if rule.Category != category || (len(rule.Types) > 0 && !containsType(rule.Types, msg.Type)) {
+			continue
+		}
+		// A category-only stream has no ID to derive a target from
+		if id == "" && (strings.Contains(rule.Stream, "{id}") || strings.Contains(rule.Stream, "{cardinalId}")) {
+			continue
+		}
+
+		replacer := strings.NewReplacer(
+			"{stream}", msg.StreamName,
+			"{category}", category,
+			"{id}", id,
+			"{cardinalId}", store.CardinalID(msg.StreamName),
+			"{type}", msg.Type,
+		)
+		msgType := "{type}"
+		if rule.Type != "" {
+			msgType = rule.Type
+		}
+
+		data := make(map[string]interface{}, len(msg.Data))
+		if len(rule.Fields) == 0 {
+			for k, v := range msg.Data {
+				data[k] = v
+			}
+		}
+		for _, field := range rule.Fields {
+			if v, ok := msg.Data[field]; ok {
+				data[field] = v
+			}
+		}
+
+		metadata := map[string]interface{}{
+			"causationMessageStreamName": msg.StreamName,
+			"causationMessageId":         msg.ID,
+		}
+		if correlation, ok := msg.Metadata["correlationStreamName"]; ok {
+			metadata["correlationStreamName"] = correlation
+		}
+
+		derived = append(derived, &store.Message{
+			StreamName: replacer.Replace(rule.Stream),
+			Type:       replacer.Replace(msgType),
+			Data:       data,
+			Metadata:   metadata,
+		})
+	}
+	return derived
+}
+
+// containsType reports whether types contains t
+func containsType(types []string, t string) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DerivedStreams applies the derived stream rules of namespaces to writes.
+// A nil *DerivedStreams derives nothing.
+type DerivedStreams struct {
+	meta *NamespaceMetadata
+}
+
+// NewDerivedStreams creates a rule cache backed by namespace metadata
+func NewDerivedStreams(meta *NamespaceMetadata) *DerivedStreams {
+	return &DerivedStreams{meta: meta}
+}
+
+// Derive returns the events derived from a write to msg.StreamName in
+// namespace. Lookup failures derive nothing so the store reports its own error.
+func (d *DerivedStreams) Derive(ctx context.Context, namespace string, msg *store.Message) []*store.Message {
+	if d == nil {
+		return nil
+	}
+
+	value, err := d.meta.decode(ctx, namespace, derivedStreamsMetadataKey, func(metadata map[string]interface{}) interface{} {
+		return DerivedStreamsFromMetadata(metadata)
+	})
+	if err != nil {
+		return nil
+	}
+	cfg := value.(*DerivedStreamsConfig)
+	if len(cfg.Rules) == 0 {
+		return nil
+	}
+	return cfg.Derive(msg)
+}
+
+// Set replaces a namespace's derived stream rules; no rules removes them
+func (d *DerivedStreams) Set(ctx context.Context, namespace string, cfg *DerivedStreamsConfig) error {
+	return d.meta.Update(ctx, namespace, func(metadata map[string]interface{}) {
+		if cfg == nil || len(cfg.Rules) == 0 {
+			delete(metadata, derivedStreamsMetadataKey)
+			return
+		}
+		metadata[derivedStreamsMetadataKey] = encodeMetadataValue(cfg)
+	})
+}
+
+// writeWithDerived writes msg followed by the events derived from it and
+// returns their results in the same order. Backends implementing
+// store.AtomicWriter write them in one transaction. Otherwise msg is written
+// first and derived events that fail afterwards are logged and have a nil
+// result, since msg itself was written.
+func writeWithDerived(ctx context.Context, st store.Store, namespace string, msg *store.Message, derived []*store.Message) ([]*store.WriteResult, error) {
+	msgs := append([]*store.Message{msg}, derived...)
+	if writer, ok := st.(store.AtomicWriter); ok {
+		results, err := writer.WriteMessages(ctx, namespace, msgs)
+		if !errors.Is(err, store.ErrNotSupported) {
+			return results, err
+		}
+	}
+
+	results := make([]*store.WriteResult, len(msgs))
+	for i, m := range msgs {
+		result, err := st.WriteMessage(ctx, namespace, m.StreamName, m)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			logger.Get().Warn().Err(err).
+				Str("namespace", namespace).
+				Str("stream", m.StreamName).
+				Str("source", msg.StreamName).
+				Msg("Failed to write derived event")
+			continue
+		}
+		results[i] = result
+	}
+	return results, nil
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestDerivedStreams tests that rules set over RPC append derived events to
+// their target streams in the same write
+func TestDerivedStreams(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, rule := range []map[string]interface{}{
+		{"category": "order", "stream": "order-{id}"},
+		{"category": "order", "stream": "summary-{nope}"},
+		{"category": "order-1", "stream": "summary-{id}"},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.derivedStreams.set", []interface{}{map[string]interface{}{
+			"rules": []interface{}{rule},
+		}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", rule, rpcErr)
+		}
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.derivedStreams.set", []interface{}{map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{
+			"category": "order",
+			"types":    []interface{}{"Placed"},
+			"stream":   "orderSummary-{id}",
+			"type":     "Order{type}",
+			"fields":   []interface{}{"total"},
+		}},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.derivedStreams.set failed: %v", rpcErr.Message)
+	}
+	result, rpcErr := h.route(ctx, "ns.derivedStreams.get", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.derivedStreams.get failed: %v", rpcErr.Message)
+	}
+	if rules := result.(map[string]interface{})["rules"].([]interface{}); len(rules) != 1 {
+		t.Errorf("Expected 1 rule, got %v", rules)
+	}
+
+	result, rpcErr = h.route(ctx, "stream.write", []interface{}{"order-7", map[string]interface{}{
+		"type":     "Placed",
+		"data":     map[string]interface{}{"total": 42.0, "items": 3.0},
+		"metadata": map[string]interface{}{"correlationStreamName": "checkout-1"},
+	}, map[string]interface{}{"id": "11111111-1111-4111-8111-111111111111"}})
+	if rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	derived, ok := result.(map[string]interface{})["derived"].([]interface{})
+	if !ok || len(derived) != 1 || derived[0].(map[string]interface{})["streamName"] != "orderSummary-7" {
+		t.Errorf("Expected a derived write to orderSummary-7, got %v", result)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "orderSummary-7", nil)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("Expected 1 derived message, got %d (%v)", len(msgs), err)
+	}
+	msg := msgs[0]
+	if msg.Type != "OrderPlaced" || msg.Data["total"] != 42.0 || msg.Data["items"] != nil {
+		t.Errorf("Unexpected derived message: %s %v", msg.Type, msg.Data)
+	}
+	if msg.Metadata["causationMessageId"] != "11111111-1111-4111-8111-111111111111" ||
+		msg.Metadata["causationMessageStreamName"] != "order-7" ||
+		msg.Metadata["correlationStreamName"] != "checkout-1" {
+		t.Errorf("Unexpected derived metadata: %v", msg.Metadata)
+	}
+
+	// Other types derive nothing
+	result, rpcErr = h.route(ctx, "stream.write", []interface{}{"order-7", map[string]interface{}{
+		"type": "Shipped",
+		"data": map[string]interface{}{},
+	}})
+	if rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	if _, ok := result.(map[string]interface{})["derived"]; ok {
+		t.Errorf("Expected no derived writes, got %v", result)
+	}
+
+	// A conflicting source write writes no derived event either
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-7", map[string]interface{}{
+		"type": "Placed",
+		"data": map[string]interface{}{"total": 1.0},
+	}, map[string]interface{}{"expectedVersion": 0.0}}); rpcErr == nil {
+		t.Error("Expected a version conflict")
+	}
+	if version, err := st.GetStreamVersion(ctx, "test-ns", "orderSummary-7"); err != nil || version != 0 {
+		t.Errorf("Expected orderSummary-7 at version 0, got %d (%v)", version, err)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.derivedStreams.set", []interface{}{nil}); rpcErr != nil {
+		t.Fatalf("ns.derivedStreams.set null failed: %v", rpcErr.Message)
+	}
+	ns, err := st.GetNamespace(ctx, "test-ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg := DerivedStreamsFromMetadata(ns.Metadata); len(cfg.Rules) != 0 {
+		t.Errorf("Expected the rules to be removed, got %v", cfg.Rules)
+	}
+}
+
+// TestDerivedStreamsConfig_Derive tests template expansion and matching
+func TestDerivedStreamsConfig_Derive(t *testing.T) {
+	cfg := &DerivedStreamsConfig{Rules: []DerivedStreamRule{
+		{Category: "account", Stream: "ledger-{cardinalId}"},
+		{Category: "account", Stream: "audit", Type: "{category}.{type}"},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	derived := cfg.Derive(&store.Message{StreamName: "account-12+eu", Type: "Opened", Data: map[string]interface{}{"a": 1}})
+	if len(derived) != 2 || derived[0].StreamName != "ledger-12" || derived[1].StreamName != "audit" ||
+		derived[0].Type != "Opened" || derived[1].Type != "account.Opened" || derived[0].Data["a"] != 1 {
+		t.Errorf("Unexpected derived events: %+v", derived)
+	}
+
+	// A category-only stream has no ID for ledger-{cardinalId}
+	if derived := cfg.Derive(&store.Message{StreamName: "account", Type: "Opened"}); len(derived) != 1 {
+		t.Errorf("Expected only the audit rule to apply, got %+v", derived)
+	}
+	if derived := cfg.Derive(&store.Message{StreamName: "other-1", Type: "Opened"}); len(derived) != 0 {
+		t.Errorf("Expected no derived events, got %+v", derived)
+	}
+}
@@ -0,0 +1,15 @@
+package api
+
+import "golang.org/x/sys/windows"
+
+// diskSpace returns the free and total bytes of the volume holding dir
+func diskSpace(dir string) (free, total uint64, err error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := windows.GetDiskFreeSpaceEx(path, &free, &total, nil); err != nil {
+		return 0, 0, err
+	}
+	return free, total, nil
+}
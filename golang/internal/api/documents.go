@@ -0,0 +1,362 @@
+// Package api provides a per-namespace document store for read models.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// DocumentPutType is the type of the messages that store a document
+	DocumentPutType = "DocumentPut"
+
+	// DocumentDeletedType is the type of the messages that delete a document
+	DocumentDeletedType = "DocumentDeleted"
+
+	// DocumentPositionType is the type of the messages recording the last
+	// global position applied to a collection
+	DocumentPositionType = "Recorded"
+
+	// documentAppliedKey holds the applied global position in message metadata
+	documentAppliedKey = "appliedGlobalPosition"
+
+	// maxDocumentKeyLength bounds the length of collection names and document IDs
+	maxDocumentKeyLength = 128
+
+	// maxDocumentBatch bounds the documents and messages of one doc.write
+	maxDocumentBatch = 100
+
+	// documentWriteAttempts bounds the retries of a put or delete that races
+	// another write to the same document
+	documentWriteAttempts = 5
+)
+
+var (
+	// ErrDocumentNotFound is returned when a collection has no document with an ID
+	ErrDocumentNotFound = errors.New("document not found")
+
+	// ErrInvalidDocumentBatch is returned for a batch that changes a document
+	// twice or writes a message to a document stream
+	ErrInvalidDocumentBatch = errors.New("invalid document batch")
+)
+
+// Document is the latest state of a read-model document
+type Document struct {
+	Collection string
+	ID         string
+	Data       map[string]interface{}
+	Version    int64  // Position of the document's last put or delete in its stream
+	Applied    *int64 // Global position of the last event applied to the document, if given
+	Updated    string
+	Deleted    bool
+}
+
+// DocumentWriteOpts are the options of a document put or delete
+type DocumentWriteOpts struct {
+	// GlobalPosition is the global position of the event being applied. The
+	// write is skipped when the document already reflects it, so projections
+	// can replay events safely, and the collection's position moves to it.
+	GlobalPosition *int64
+
+	// ExpectedVersion fails the write with a version conflict unless the
+	// document is at this version (-1 for a document never written)
+	ExpectedVersion *int64
+}
+
+// DocumentChange is one document put, or delete when Data is nil, of a DocumentBatch
+type DocumentChange struct {
+	Collection      string
+	ID              string
+	Data            map[string]interface{}
+	ExpectedVersion *int64 // As in DocumentWriteOpts
+}
+
+// DocumentBatch is a set of document changes and other messages, such as the
+// acknowledgment of the event a projection applied, written together
+type DocumentBatch struct {
+	Changes        []DocumentChange
+	Messages       []*store.Message
+	GlobalPosition *int64 // As in DocumentWriteOpts, for every change
+}
+
+// DocumentWrite is the outcome of one document put or delete
+type DocumentWrite struct {
+	Applied bool  // False when the document already reflected the global position
+	Version int64 // Document version after the write
+}
+
+// DocumentBatchWrite is the outcome of a DocumentBatch
+type DocumentBatchWrite struct {
+	Changes  []DocumentWrite      // Outcome of each change
+	Messages []*store.Message     // Messages written: the batch's messages first, then documents and positions
+	Results  []*store.WriteResult // Their results, for publishing
+}
+
+// DocumentStreamName returns the stream a document is stored in:
+// {collection}:doc-{id}, one stream per document, so documents replicate,
+// export and back up with the namespace's other streams
+func DocumentStreamName(collection, id string) string {
+	return collection + ":doc-" + id
+}
+
+// DocumentPositionStreamName returns the stream recording the last global
+// position applied to a collection
+func DocumentPositionStreamName(collection string) string {
+	return collection + ":docPosition"
+}
+
+// ValidateDocumentKey checks a collection name (collection true) or a
+// document ID. Collections are category names; IDs may contain '-' and '+'.
+func ValidateDocumentKey(key string, collection bool) error {
+	what := "document ID"
+	if collection {
+		what = "collection"
+	}
+	if key == "" {
+		return fmt.Errorf("%s must not be empty", what)
+	}
+	if len(key) > maxDocumentKeyLength {
+		return fmt.Errorf("%s is longer than %d bytes", what, maxDocumentKeyLength)
+	}
+	if collection && (strings.ContainsAny(key, "-+:") || strings.HasPrefix(key, store.ReservedStreamPrefix)) {
+		return fmt.Errorf("collection must not contain '-', '+' or ':' or start with '%s'", store.ReservedStreamPrefix)
+	}
+	for _, r := range key {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return fmt.Errorf("%s must not contain spaces or control characters", what)
+		}
+	}
+	return nil
+}
+
+// GetDocument returns the latest state of a document, including a deleted
+// one (Deleted true); it returns ErrDocumentNotFound for a document never written
+func GetDocument(ctx context.Context, st store.Store, namespace, collection, id string) (*Document, error) {
+	msg, err := st.GetLastStreamMessage(ctx, namespace, DocumentStreamName(collection, id), nil)
+	if errors.Is(err, store.ErrStreamNotFound) || (err == nil && msg == nil) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		Collection: collection,
+		ID:         id,
+		Version:    msg.Position,
+		Updated:    msg.Time.UTC().Format(time.RFC3339Nano),
+		Deleted:    msg.Type == DocumentDeletedType,
+	}
+	if !doc.Deleted {
+		doc.Data = msg.Data
+	}
+	if applied, ok := documentInt64(msg.Metadata[documentAppliedKey]); ok {
+		doc.Applied = &applied
+	}
+	return doc, nil
+}
+
+// DocumentsPosition returns the last global position applied to a collection,
+// or false when no write to it has given one
+func DocumentsPosition(ctx context.Context, st store.Store, namespace, collection string) (int64, bool, error) {
+	msg, err := st.GetLastStreamMessage(ctx, namespace, DocumentPositionStreamName(collection), nil)
+	if errors.Is(err, store.ErrStreamNotFound) || (err == nil && msg == nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	position, ok := documentInt64(msg.Data["globalPosition"])
+	return position, ok, nil
+}
+
+// WriteDocument puts a document, or deletes it when data is nil. The write
+// and the move of the collection's position are one transaction on backends
+// with atomic multi-stream writes. Deleting a document that does not exist
+// writes nothing but still moves the collection's position.
+func WriteDocument(ctx context.Context, st store.Store, namespace, collection, id string, data map[string]interface{}, opts DocumentWriteOpts) (*DocumentBatchWrite, error) {
+	return writeDocuments(ctx, st, namespace, DocumentBatch{
+		Changes:        []DocumentChange{{Collection: collection, ID: id, Data: data, ExpectedVersion: opts.ExpectedVersion}},
+		GlobalPosition: opts.GlobalPosition,
+	}, false)
+}
+
+// WriteDocuments writes a batch of document changes and messages in one
+// backend transaction, so a projection's read model never disagrees with the
+// acknowledgment of the events it applied. It returns store.ErrNotSupported
+// on backends without atomic multi-stream writes.
+func WriteDocuments(ctx context.Context, st store.Store, namespace string, batch DocumentBatch) (*DocumentBatchWrite, error) {
+	if _, ok := st.(store.AtomicWriter); !ok {
+		return nil, store.ErrNotSupported
+	}
+	seen := make(map[string]bool, len(batch.Changes))
+	for _, change := range batch.Changes {
+		stream := DocumentStreamName(change.Collection, change.ID)
+		if seen[stream] {
+			return nil, fmt.Errorf("%w: document %s/%s is changed twice", ErrInvalidDocumentBatch, change.Collection, change.ID)
+		}
+		seen[stream] = true
+	}
+	for _, msg := range batch.Messages {
+		if seen[msg.StreamName] || strings.HasSuffix(msg.StreamName, ":docPosition") {
+			return nil, fmt.Errorf("%w: message to %s conflicts with the document changes", ErrInvalidDocumentBatch, msg.StreamName)
+		}
+	}
+	return writeDocuments(ctx, st, namespace, batch, true)
+}
+
+// writeDocuments writes a batch, retrying when a concurrent write moved a
+// document or collection position the caller did not fence on
+func writeDocuments(ctx context.Context, st store.Store, namespace string, batch DocumentBatch, atomic bool) (*DocumentBatchWrite, error) {
+	fenced := make(map[string]bool)
+	for _, change := range batch.Changes {
+		if change.ExpectedVersion != nil {
+			fenced[DocumentStreamName(change.Collection, change.ID)] = true
+		}
+	}
+	for _, msg := range batch.Messages {
+		fenced[msg.StreamName] = true
+	}
+
+	var err error
+	for attempt := 0; attempt < documentWriteAttempts; attempt++ {
+		var write *DocumentBatchWrite
+		write, err = writeDocumentsOnce(ctx, st, namespace, batch, atomic)
+		if err == nil {
+			return write, nil
+		}
+		var conflict *store.VersionConflictError
+		if !store.IsVersionConflict(err) || (errors.As(err, &conflict) && fenced[conflict.StreamName]) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// writeDocumentsOnce reads the changed documents and their collection
+// positions and writes them with the batch's messages, fenced with expected
+// versions
+func writeDocumentsOnce(ctx context.Context, st store.Store, namespace string, batch DocumentBatch, atomic bool) (*DocumentBatchWrite, error) {
+	write := &DocumentBatchWrite{Changes: make([]DocumentWrite, len(batch.Changes))}
+	msgs := append([]*store.Message(nil), batch.Messages...)
+	docIndex := make([]int, len(batch.Changes)) // Index in msgs of each change's message, -1 for none
+	var collections []string                    // Collections with applied changes
+	gp := batch.GlobalPosition
+
+	for i, change := range batch.Changes {
+		docIndex[i] = -1
+		streamName := DocumentStreamName(change.Collection, change.ID)
+		current, err := GetDocument(ctx, st, namespace, change.Collection, change.ID)
+		if err != nil && !errors.Is(err, ErrDocumentNotFound) {
+			return nil, err
+		}
+		version := int64(-1)
+		if current != nil {
+			version = current.Version
+		}
+		if change.ExpectedVersion != nil && *change.ExpectedVersion != version {
+			return nil, &store.VersionConflictError{
+				StreamName:      streamName,
+				ExpectedVersion: *change.ExpectedVersion,
+				ActualVersion:   version,
+			}
+		}
+		write.Changes[i].Version = version
+
+		// A replayed event the document already reflects
+		if gp != nil && current != nil && current.Applied != nil && *current.Applied >= *gp {
+			continue
+		}
+		write.Changes[i].Applied = true
+		if !slices.Contains(collections, change.Collection) {
+			collections = append(collections, change.Collection)
+		}
+
+		if change.Data == nil && (current == nil || current.Deleted) {
+			continue
+		}
+		msg := &store.Message{
+			StreamName:      streamName,
+			Type:            DocumentPutType,
+			Data:            change.Data,
+			ExpectedVersion: &version,
+		}
+		if change.Data == nil {
+			msg.Type = DocumentDeletedType
+			msg.Data = map[string]interface{}{}
+		}
+		if gp != nil {
+			msg.Metadata = map[string]interface{}{documentAppliedKey: *gp}
+		}
+		docIndex[i] = len(msgs)
+		msgs = append(msgs, msg)
+	}
+
+	if gp != nil {
+		for _, collection := range collections {
+			positionStream := DocumentPositionStreamName(collection)
+			last, err := st.GetLastStreamMessage(ctx, namespace, positionStream, nil)
+			if err != nil && !errors.Is(err, store.ErrStreamNotFound) {
+				return nil, err
+			}
+			positionVersion := int64(-1)
+			applied, known := int64(0), false
+			if last != nil {
+				positionVersion = last.Position
+				applied, known = documentInt64(last.Data["globalPosition"])
+			}
+			if !known || applied < *gp {
+				msgs = append(msgs, &store.Message{
+					StreamName:      positionStream,
+					Type:            DocumentPositionType,
+					Data:            map[string]interface{}{"globalPosition": *gp},
+					ExpectedVersion: &positionVersion,
+				})
+			}
+		}
+	}
+
+	if len(msgs) == 0 {
+		return write, nil
+	}
+	var err error
+	if atomic {
+		write.Results, err = st.(store.AtomicWriter).WriteMessages(ctx, namespace, msgs)
+	} else {
+		write.Results, err = writeWithDerived(ctx, st, namespace, msgs[0], msgs[1:])
+	}
+	if err != nil {
+		return nil, err
+	}
+	write.Messages = msgs
+	for i, index := range docIndex {
+		if index >= 0 && write.Results[index] != nil {
+			write.Changes[i].Version = write.Results[index].Position
+		}
+	}
+	return write, nil
+}
+
+// documentInt64 converts a stored JSON number to an int64
+func documentInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
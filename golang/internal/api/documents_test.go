@@ -0,0 +1,403 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestDocuments tests that documents are put, read and deleted, that replayed
+// events are skipped, and that the collection's applied position is tracked
+func TestDocuments(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	put := func(id string, doc map[string]interface{}, opts map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		result, rpcErr := h.route(ctx, "doc.put", []interface{}{"accountSummary", id, doc, opts})
+		if rpcErr != nil {
+			t.Fatalf("doc.put failed: %v", rpcErr.Message)
+		}
+		return result.(map[string]interface{})
+	}
+
+	if result := put("123", map[string]interface{}{"balance": float64(10)}, map[string]interface{}{"globalPosition": float64(5)}); result["applied"] != true || result["version"] != int64(0) {
+		t.Errorf("Unexpected put result: %v", result)
+	}
+	if result := put("123", map[string]interface{}{"balance": float64(25)}, map[string]interface{}{"globalPosition": float64(8)}); result["applied"] != true || result["version"] != int64(1) {
+		t.Errorf("Unexpected put result: %v", result)
+	}
+
+	// Replaying an event the document already reflects writes nothing
+	if result := put("123", map[string]interface{}{"balance": float64(10)}, map[string]interface{}{"globalPosition": float64(5)}); result["applied"] != false || result["version"] != int64(1) {
+		t.Errorf("Expected a replayed event to be skipped, got %v", result)
+	}
+
+	result, rpcErr := h.route(ctx, "doc.get", []interface{}{"accountSummary", "123"})
+	if rpcErr != nil {
+		t.Fatalf("doc.get failed: %v", rpcErr.Message)
+	}
+	doc := result.(map[string]interface{})
+	if doc["doc"].(map[string]interface{})["balance"] != float64(25) || doc["appliedGlobalPosition"] != int64(8) || doc["version"] != int64(1) {
+		t.Errorf("Unexpected document: %v", doc)
+	}
+
+	// Documents live in ordinary streams of the namespace
+	if version, err := st.GetStreamVersion(ctx, "test-ns", "accountSummary:doc-123"); err != nil || version != 1 {
+		t.Errorf("Expected document stream at version 1, got %d, %v", version, err)
+	}
+
+	// The collection position only moves forward
+	put("456", map[string]interface{}{"balance": float64(1)}, map[string]interface{}{"globalPosition": float64(3)})
+	result, rpcErr = h.route(ctx, "doc.position", []interface{}{"accountSummary"})
+	if rpcErr != nil {
+		t.Fatalf("doc.position failed: %v", rpcErr.Message)
+	}
+	if position := result.(map[string]interface{})["globalPosition"]; position != int64(8) {
+		t.Errorf("Expected collection position 8, got %v", position)
+	}
+
+	// Optimistic concurrency on the document version
+	if _, rpcErr := h.route(ctx, "doc.put", []interface{}{"accountSummary", "123", map[string]interface{}{}, map[string]interface{}{"expectedVersion": float64(0)}}); rpcErr == nil || rpcErr.Code != "STREAM_VERSION_CONFLICT" {
+		t.Errorf("Expected STREAM_VERSION_CONFLICT, got %v", rpcErr)
+	}
+
+	result, rpcErr = h.route(ctx, "doc.delete", []interface{}{"accountSummary", "123", map[string]interface{}{"globalPosition": float64(9)}})
+	if rpcErr != nil {
+		t.Fatalf("doc.delete failed: %v", rpcErr.Message)
+	}
+	if result.(map[string]interface{})["deleted"] != true {
+		t.Errorf("Expected the document to be deleted, got %v", result)
+	}
+	if _, rpcErr := h.route(ctx, "doc.get", []interface{}{"accountSummary", "123"}); rpcErr == nil || rpcErr.Code != "DOCUMENT_NOT_FOUND" {
+		t.Errorf("Expected DOCUMENT_NOT_FOUND after delete, got %v", rpcErr)
+	}
+	result, rpcErr = h.route(ctx, "doc.delete", []interface{}{"accountSummary", "123"})
+	if rpcErr != nil || result.(map[string]interface{})["deleted"] != false {
+		t.Errorf("Expected a second delete to delete nothing, got %v, %v", result, rpcErr)
+	}
+
+	// Collections are category names
+	if _, rpcErr := h.route(ctx, "doc.get", []interface{}{"account-summary", "1"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a collection with '-', got %v", rpcErr)
+	}
+}
+
+// TestDocuments_Errors tests invalid arguments, documents deleted before they
+// exist or put again after a delete, writes without a global position, and
+// writes to frozen or missing namespaces
+func TestDocuments_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	longID := strings.Repeat("x", maxDocumentKeyLength+1)
+	for _, tt := range []struct {
+		method string
+		args   []interface{}
+	}{
+		{"doc.put", []interface{}{"accountSummary", "1"}},
+		{"doc.put", []interface{}{float64(1), "1", map[string]interface{}{}}},
+		{"doc.put", []interface{}{"accountSummary", float64(1), map[string]interface{}{}}},
+		{"doc.put", []interface{}{"accountSummary", "1", "doc"}},
+		{"doc.put", []interface{}{"accountSummary", "1", nil}},
+		{"doc.put", []interface{}{"accountSummary", "1", map[string]interface{}{}, "opts"}},
+		{"doc.put", []interface{}{"accountSummary", "1", map[string]interface{}{}, map[string]interface{}{"globalPosition": float64(-1)}}},
+		{"doc.put", []interface{}{"accountSummary", "1", map[string]interface{}{}, map[string]interface{}{"globalPosition": "5"}}},
+		{"doc.put", []interface{}{"accountSummary", "1", map[string]interface{}{}, map[string]interface{}{"expectedVersion": float64(-2)}}},
+		{"doc.put", []interface{}{"account:summary", "1", map[string]interface{}{}}},
+		{"doc.put", []interface{}{"account+summary", "1", map[string]interface{}{}}},
+		{"doc.put", []interface{}{"$system", "1", map[string]interface{}{}}},
+		{"doc.put", []interface{}{"accountSummary", "", map[string]interface{}{}}},
+		{"doc.put", []interface{}{"accountSummary", "a b", map[string]interface{}{}}},
+		{"doc.put", []interface{}{"accountSummary", longID, map[string]interface{}{}}},
+		{"doc.get", []interface{}{"accountSummary"}},
+		{"doc.get", []interface{}{"", "1"}},
+		{"doc.delete", []interface{}{"accountSummary"}},
+		{"doc.delete", []interface{}{"accountSummary", "1", map[string]interface{}{"expectedVersion": "0"}}},
+		{"doc.position", []interface{}{}},
+		{"doc.position", []interface{}{float64(1)}},
+		{"doc.position", []interface{}{"account-summary"}},
+	} {
+		if _, rpcErr := h.route(ctx, tt.method, tt.args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %s %v, got %v", tt.method, tt.args, rpcErr)
+		}
+	}
+
+	// Unknown documents and collections
+	if _, rpcErr := h.route(ctx, "doc.get", []interface{}{"accountSummary", "1"}); rpcErr == nil || rpcErr.Code != "DOCUMENT_NOT_FOUND" {
+		t.Errorf("Expected DOCUMENT_NOT_FOUND, got %v", rpcErr)
+	}
+	result, rpcErr := h.route(ctx, "doc.position", []interface{}{"accountSummary"})
+	if rpcErr != nil || result.(map[string]interface{})["globalPosition"] != nil {
+		t.Errorf("Expected a null position, got %v (%v)", result, rpcErr)
+	}
+
+	// Deleting a document that does not exist writes nothing but moves the
+	// collection's position
+	result, rpcErr = h.route(ctx, "doc.delete", []interface{}{"accountSummary", "1", map[string]interface{}{"globalPosition": float64(4)}})
+	if rpcErr != nil || result.(map[string]interface{})["deleted"] != false || result.(map[string]interface{})["applied"] != true {
+		t.Errorf("Expected an applied delete that deleted nothing, got %v (%v)", result, rpcErr)
+	}
+	if version, err := st.GetStreamVersion(ctx, "test-ns", DocumentStreamName("accountSummary", "1")); err != nil || version != -1 {
+		t.Errorf("Expected no document stream, got version %d (%v)", version, err)
+	}
+	if position, ok, err := DocumentsPosition(ctx, st, "test-ns", "accountSummary"); err != nil || !ok || position != 4 {
+		t.Errorf("Expected collection position 4, got %d %v (%v)", position, ok, err)
+	}
+
+	// An event at the position the document reflects is a replay; a document
+	// put without a position reports none and takes any later event
+	put := func(id string, opts map[string]interface{}) (map[string]interface{}, *RPCError) {
+		result, rpcErr := h.route(ctx, "doc.put", []interface{}{"accountSummary", id, map[string]interface{}{"balance": float64(1)}, opts})
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		return result.(map[string]interface{}), nil
+	}
+	if result, rpcErr := put("order-1+eu", map[string]interface{}{"expectedVersion": float64(-1), "globalPosition": float64(6)}); rpcErr != nil || result["applied"] != true {
+		t.Fatalf("Expected IDs with '-' and '+' to be put, got %v (%v)", result, rpcErr)
+	}
+	if result, _ := put("order-1+eu", map[string]interface{}{"globalPosition": float64(6)}); result["applied"] != false {
+		t.Errorf("Expected an equal position to be skipped, got %v", result)
+	}
+	if result, rpcErr := put("2", nil); rpcErr != nil || result["applied"] != true || result["version"] != int64(0) {
+		t.Fatalf("doc.put failed: %v (%v)", result, rpcErr)
+	}
+	if result, rpcErr := h.route(ctx, "doc.get", []interface{}{"accountSummary", "2"}); rpcErr != nil || result.(map[string]interface{})["appliedGlobalPosition"] != nil {
+		t.Errorf("Expected no applied position, got %v (%v)", result, rpcErr)
+	}
+	if result, _ := put("2", map[string]interface{}{"globalPosition": float64(1)}); result["applied"] != true || result["version"] != int64(1) {
+		t.Errorf("Expected the event to be applied, got %v", result)
+	}
+	if _, rpcErr := put("2", map[string]interface{}{"expectedVersion": float64(-1)}); rpcErr == nil || rpcErr.Code != "STREAM_VERSION_CONFLICT" {
+		t.Errorf("Expected STREAM_VERSION_CONFLICT for an existing document, got %v", rpcErr)
+	}
+
+	// A deleted document is put again after its delete
+	if _, rpcErr := h.route(ctx, "doc.delete", []interface{}{"accountSummary", "2", map[string]interface{}{"expectedVersion": float64(1)}}); rpcErr != nil {
+		t.Fatalf("doc.delete failed: %v", rpcErr.Message)
+	}
+	if result, rpcErr := put("2", map[string]interface{}{"expectedVersion": float64(2)}); rpcErr != nil || result["version"] != int64(3) {
+		t.Errorf("Expected the document at version 3, got %v (%v)", result, rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "doc.get", []interface{}{"accountSummary", "2"}); rpcErr != nil {
+		t.Errorf("Expected the document back, got %v", rpcErr)
+	}
+
+	// Frozen and missing namespaces
+	missing := context.WithValue(context.Background(), ContextKeyNamespace, "missing-ns")
+	if _, rpcErr := h.route(missing, "doc.put", []interface{}{"accountSummary", "1", map[string]interface{}{}}); rpcErr == nil || rpcErr.Code != "NAMESPACE_NOT_FOUND" {
+		t.Errorf("Expected NAMESPACE_NOT_FOUND, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	for _, tt := range []struct {
+		method string
+		args   []interface{}
+	}{
+		{"doc.put", []interface{}{"accountSummary", "3", map[string]interface{}{}}},
+		{"doc.delete", []interface{}{"accountSummary", "2"}},
+	} {
+		if _, rpcErr := h.route(ctx, tt.method, tt.args); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+			t.Errorf("Expected READ_ONLY for %s, got %v", tt.method, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "doc.get", []interface{}{"accountSummary", "2"}); rpcErr != nil {
+		t.Errorf("Expected reads of a frozen namespace to succeed, got %v", rpcErr)
+	}
+}
+
+// TestDocumentWrite tests that doc.write writes documents and messages in one
+// transaction: a conflict on any of them writes nothing
+func TestDocumentWrite(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	batch := func(ackVersion float64, balance float64) map[string]interface{} {
+		return map[string]interface{}{
+			"docs": []interface{}{
+				map[string]interface{}{"collection": "accountSummary", "id": "1", "doc": map[string]interface{}{"balance": balance}},
+				map[string]interface{}{"collection": "ownerIndex", "id": "ada", "doc": map[string]interface{}{"accounts": []interface{}{"1"}}},
+			},
+			"messages": []interface{}{
+				map[string]interface{}{
+					"stream":          "projection:position-summary",
+					"type":            "Recorded",
+					"data":            map[string]interface{}{"position": float64(7)},
+					"expectedVersion": ackVersion,
+				},
+			},
+			"globalPosition": float64(7),
+		}
+	}
+
+	result, rpcErr := h.route(ctx, "doc.write", []interface{}{batch(-1, 50)})
+	if rpcErr != nil {
+		t.Fatalf("doc.write failed: %v", rpcErr.Message)
+	}
+	out := result.(map[string]interface{})
+	docs := out["docs"].([]map[string]interface{})
+	if len(docs) != 2 || docs[0]["applied"] != true || docs[0]["version"] != int64(0) || docs[1]["version"] != int64(0) {
+		t.Errorf("Unexpected docs: %v", docs)
+	}
+	if msgs := out["messages"].([]map[string]interface{}); len(msgs) != 1 || msgs[0]["position"] != int64(0) {
+		t.Errorf("Unexpected messages: %v", msgs)
+	}
+
+	// A conflict on the acknowledgment leaves the documents unchanged
+	if _, rpcErr := h.route(ctx, "doc.write", []interface{}{batch(-1, 99)}); rpcErr == nil || rpcErr.Code != "STREAM_VERSION_CONFLICT" {
+		t.Fatalf("Expected STREAM_VERSION_CONFLICT, got %v", rpcErr)
+	}
+	doc, err := GetDocument(ctx, st, "test-ns", "accountSummary", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != 0 || doc.Data["balance"] != float64(50) {
+		t.Errorf("Expected the document untouched by the failed batch, got %+v", doc)
+	}
+
+	// Replaying the event skips the documents but writes the acknowledgment
+	result, rpcErr = h.route(ctx, "doc.write", []interface{}{batch(0, 99)})
+	if rpcErr != nil {
+		t.Fatalf("doc.write failed: %v", rpcErr.Message)
+	}
+	if docs := result.(map[string]interface{})["docs"].([]map[string]interface{}); docs[0]["applied"] != false {
+		t.Errorf("Expected the replayed documents to be skipped, got %v", docs)
+	}
+	if position, ok, _ := DocumentsPosition(ctx, st, "test-ns", "ownerIndex"); !ok || position != 7 {
+		t.Errorf("Expected ownerIndex position 7, got %d", position)
+	}
+
+	// Messages cannot target the batch's own document streams
+	bad := map[string]interface{}{
+		"docs": []interface{}{map[string]interface{}{"collection": "accountSummary", "id": "1"}},
+		"messages": []interface{}{map[string]interface{}{
+			"stream": "accountSummary:doc-1", "type": "DocumentPut", "data": map[string]interface{}{},
+		}},
+	}
+	if _, rpcErr := h.route(ctx, "doc.write", []interface{}{bad}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST, got %v", rpcErr)
+	}
+}
+
+// TestDocumentWrite_Errors tests invalid batches, batches that change a
+// document twice or write its streams, document conflicts, deletes, and
+// backends and namespaces that cannot take the batch
+func TestDocumentWrite_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	doc := func(id string) map[string]interface{} {
+		return map[string]interface{}{"collection": "accountSummary", "id": id, "doc": map[string]interface{}{"balance": float64(1)}}
+	}
+	msg := func(stream string) map[string]interface{} {
+		return map[string]interface{}{"stream": stream, "type": "Recorded", "data": map[string]interface{}{}}
+	}
+	with := func(base map[string]interface{}, key string, value interface{}) map[string]interface{} {
+		out := make(map[string]interface{}, len(base)+1)
+		for k, v := range base {
+			out[k] = v
+		}
+		out[key] = value
+		return out
+	}
+	tooMany := make([]interface{}, maxDocumentBatch+1)
+	for i := range tooMany {
+		tooMany[i] = doc(fmt.Sprintf("%d", i))
+	}
+	for _, args := range [][]interface{}{
+		{},
+		{"batch"},
+		{map[string]interface{}{}},
+		{map[string]interface{}{"docs": []interface{}{}}},
+		{map[string]interface{}{"docs": doc("1")}},
+		{map[string]interface{}{"docs": []interface{}{doc("1")}, "messages": msg("ack-1")}},
+		{map[string]interface{}{"docs": tooMany}},
+		{map[string]interface{}{"docs": []interface{}{"1"}}},
+		{map[string]interface{}{"docs": []interface{}{with(doc("1"), "collection", "account-summary")}}},
+		{map[string]interface{}{"docs": []interface{}{with(doc("1"), "id", nil)}}},
+		{map[string]interface{}{"docs": []interface{}{with(doc("1"), "doc", "x")}}},
+		{map[string]interface{}{"docs": []interface{}{with(doc("1"), "expectedVersion", float64(-2))}}},
+		{map[string]interface{}{"docs": []interface{}{doc("1")}, "messages": []interface{}{"ack-1"}}},
+		{map[string]interface{}{"docs": []interface{}{doc("1")}, "messages": []interface{}{with(msg("ack-1"), "type", "")}}},
+		{map[string]interface{}{"docs": []interface{}{doc("1")}, "messages": []interface{}{with(msg("ack-1"), "data", nil)}}},
+		{map[string]interface{}{"docs": []interface{}{doc("1")}, "messages": []interface{}{with(msg("ack-1"), "metadata", "x")}}},
+		{map[string]interface{}{"docs": []interface{}{doc("1")}, "messages": []interface{}{with(msg("ack-1"), "expectedVersion", "0")}}},
+		{map[string]interface{}{"docs": []interface{}{doc("1")}, "messages": []interface{}{msg("")}}},
+		{map[string]interface{}{"docs": []interface{}{doc("1")}, "globalPosition": float64(-1)}},
+		{map[string]interface{}{"docs": []interface{}{doc("1"), with(doc("1"), "doc", nil)}}},
+		{map[string]interface{}{"docs": []interface{}{doc("1")}, "messages": []interface{}{msg("accountSummary:docPosition")}}},
+	} {
+		if _, rpcErr := h.route(ctx, "doc.write", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// Messages keep their IDs or are given one; without a global position no
+	// collection position is written
+	result, rpcErr := h.route(ctx, "doc.write", []interface{}{map[string]interface{}{
+		"docs":     []interface{}{doc("1"), doc("2")},
+		"messages": []interface{}{with(msg("ack-1"), "id", "11111111-1111-4111-8111-111111111111"), msg("ack-2")},
+	}})
+	if rpcErr != nil {
+		t.Fatalf("doc.write failed: %v", rpcErr.Message)
+	}
+	msgs := result.(map[string]interface{})["messages"].([]map[string]interface{})
+	if msgs[0]["id"] != "11111111-1111-4111-8111-111111111111" || msgs[1]["id"] == "" {
+		t.Errorf("Unexpected message IDs: %v", msgs)
+	}
+	if _, ok, err := DocumentsPosition(ctx, st, "test-ns", "accountSummary"); err != nil || ok {
+		t.Errorf("Expected no collection position, got %v (%v)", ok, err)
+	}
+
+	// A conflict on any document writes none of the batch
+	_, rpcErr = h.route(ctx, "doc.write", []interface{}{map[string]interface{}{
+		"docs":     []interface{}{with(doc("1"), "doc", nil), with(doc("2"), "expectedVersion", float64(-1))},
+		"messages": []interface{}{msg("ack-3")},
+	}})
+	if rpcErr == nil || rpcErr.Code != "STREAM_VERSION_CONFLICT" {
+		t.Fatalf("Expected STREAM_VERSION_CONFLICT, got %v", rpcErr)
+	}
+	if version, err := st.GetStreamVersion(ctx, "test-ns", "ack-3"); err != nil || version != -1 {
+		t.Errorf("Expected ack-3 not to be written, got version %d (%v)", version, err)
+	}
+
+	// Documents without doc are deleted, and deleting one that does not exist
+	// writes nothing for it
+	result, rpcErr = h.route(ctx, "doc.write", []interface{}{map[string]interface{}{
+		"docs":           []interface{}{with(doc("1"), "doc", nil), with(doc("9"), "doc", nil)},
+		"globalPosition": float64(3),
+	}})
+	if rpcErr != nil {
+		t.Fatalf("doc.write failed: %v", rpcErr.Message)
+	}
+	docs := result.(map[string]interface{})["docs"].([]map[string]interface{})
+	if docs[0]["version"] != int64(1) || docs[1]["version"] != int64(-1) || docs[1]["applied"] != true {
+		t.Errorf("Unexpected docs: %v", docs)
+	}
+	if _, rpcErr := h.route(ctx, "doc.get", []interface{}{"accountSummary", "1"}); rpcErr == nil || rpcErr.Code != "DOCUMENT_NOT_FOUND" {
+		t.Errorf("Expected DOCUMENT_NOT_FOUND, got %v", rpcErr)
+	}
+
+	// Backends without atomic multi-stream writes
+	plain := NewRPCHandler("test", struct{ store.Store }{st}, NewPubSub())
+	if _, rpcErr := plain.route(ctx, "doc.write", []interface{}{map[string]interface{}{"docs": []interface{}{doc("3")}}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without atomic writes, got %v", rpcErr)
+	}
+
+	// Frozen namespaces
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "doc.write", []interface{}{map[string]interface{}{"docs": []interface{}{doc("3")}}}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY, got %v", rpcErr)
+	}
+}
@@ -0,0 +1,406 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// DeliveryMode is how a durable subscription treats messages its subscriber
+// may not have processed
+type DeliveryMode string
+
+const (
+	// DeliveryAtLeastOnce redelivers messages until they are acknowledged
+	DeliveryAtLeastOnce DeliveryMode = "at-least-once"
+	// DeliveryAtMostOnce records each message as processed before sending it
+	DeliveryAtMostOnce DeliveryMode = "at-most-once"
+)
+
+const (
+	// defaultAckTimeout is how long an at-least-once delivery waits for its ack
+	// before it is redelivered
+	defaultAckTimeout = 30 * time.Second
+	minAckTimeout     = 100 * time.Millisecond
+	maxAckTimeout     = time.Hour
+
+	// maxUnackedDeliveries bounds the messages an at-least-once subscription
+	// sends ahead of its acks
+	maxUnackedDeliveries = 1000
+	// durableBatchSize is the number of messages read per store call
+	durableBatchSize = 100
+	// durableKeepAlive is the interval of the comments that detect closed
+	// connections while no messages are sent
+	durableKeepAlive = 15 * time.Second
+)
+
+var (
+	// ErrDurableSubscriptionActive is returned when a consumer already has a
+	// durable subscription on this server
+	ErrDurableSubscriptionActive = errors.New("durable subscription already connected")
+	// ErrDurableSubscriptionNotFound is returned for an ack from a consumer
+	// without a durable subscription on this server
+	ErrDurableSubscriptionNotFound = errors.New("durable subscription not connected")
+	// ErrAckNotExpected is returned for an ack to an at-most-once subscription
+	ErrAckNotExpected = errors.New("at-most-once subscriptions are not acknowledged")
+)
+
+// ParseDeliveryMode parses the delivery parameter of a durable subscription
+func ParseDeliveryMode(s string) (DeliveryMode, error) {
+	switch DeliveryMode(s) {
+	case "", DeliveryAtLeastOnce:
+		return DeliveryAtLeastOnce, nil
+	case DeliveryAtMostOnce:
+		return DeliveryAtMostOnce, nil
+	}
+	return "", fmt.Errorf("Invalid delivery parameter: must be '%s' or '%s'", DeliveryAtLeastOnce, DeliveryAtMostOnce)
+}
+
+// parseAckTimeout parses the ackTimeout parameter of a durable subscription
+func parseAckTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultAckTimeout, nil
+	}
+	timeout, err := time.ParseDuration(s)
+	if err != nil || timeout < minAckTimeout || timeout > maxAckTimeout {
+		return 0, fmt.Errorf("Invalid ackTimeout parameter: must be a duration from %s to %s", minAckTimeout, maxAckTimeout)
+	}
+	return timeout, nil
+}
+
+// DurableSubscriptions tracks the durable subscriptions connected to this
+// server. Each consumer has at most one; its acks, made over RPC, are matched
+// to the messages it was sent.
+type DurableSubscriptions struct {
+	mu     sync.Mutex
+	active map[string]*durableSubscription // By namespace and consumer
+
+	delivered    atomic.Int64
+	redeliveries atomic.Int64
+	acks         atomic.Int64
+}
+
+// DurableStats reports the durable subscriptions of a server
+type DurableStats struct {
+	Connected    int   `json:"connected"`
+	Unacked      int   `json:"unacked"`
+	Delivered    int64 `json:"delivered"`
+	Redeliveries int64 `json:"redeliveries"`
+	Acks         int64 `json:"acks"`
+}
+
+// NewDurableSubscriptions creates an empty durable subscription registry
+func NewDurableSubscriptions() *DurableSubscriptions {
+	return &DurableSubscriptions{active: make(map[string]*durableSubscription)}
+}
+
+// AckResult is the outcome of acknowledging a message
+type AckResult struct {
+	Acked     bool  // False if the message was not awaiting an ack
+	Committed int64 // Stored position of the consumer, -1 if none
+	Unacked   int   // Messages still awaiting an ack
+}
+
+// durableSubscription is one connected durable subscription
+type durableSubscription struct {
+	owner      *DurableSubscriptions
+	key        string
+	namespace  string
+	consumer   string
+	mode       DeliveryMode
+	ackTimeout time.Duration
+	positions  store.ConsumerPositionStore
+	start      int64         // First global position to deliver
+	acked      chan struct{} // Wakes the delivery loop when acks free the window
+
+	mu        sync.Mutex
+	pending   map[int64]*pendingDelivery // Unacked deliveries by global position
+	delivered int64                      // Highest global position sent
+	committed int64                      // Last position stored for the consumer
+}
+
+// pendingDelivery is a message sent to an at-least-once subscriber and not
+// acknowledged yet
+type pendingDelivery struct {
+	msg      *store.Message
+	count    int
+	deadline time.Time
+}
+
+// durableKey identifies the subscription of a consumer
+func durableKey(namespace, consumer string) string {
+	return namespace + "\x00" + consumer
+}
+
+// open connects the durable subscription of consumer. It starts at position,
+// or if position is negative, after the consumer's stored position.
+func (d *DurableSubscriptions) open(ctx context.Context, positions store.ConsumerPositionStore, namespace, consumer string, mode DeliveryMode, ackTimeout time.Duration, position int64) (*durableSubscription, error) {
+	start := position
+	if start < 0 {
+		stored, err := positions.GetConsumerPosition(ctx, namespace, consumer)
+		switch {
+		case err == nil:
+			start = stored.GlobalPosition + 1
+		case errors.Is(err, store.ErrConsumerPositionNotFound):
+			start = 0
+		default:
+			return nil, err
+		}
+	}
+
+	sub := &durableSubscription{
+		owner:      d,
+		key:        durableKey(namespace, consumer),
+		namespace:  namespace,
+		consumer:   consumer,
+		mode:       mode,
+		ackTimeout: ackTimeout,
+		positions:  positions,
+		start:      start,
+		acked:      make(chan struct{}, 1),
+		pending:    make(map[int64]*pendingDelivery),
+		delivered:  start - 1,
+		committed:  start - 1,
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.active[sub.key]; ok {
+		return nil, ErrDurableSubscriptionActive
+	}
+	d.active[sub.key] = sub
+	return sub, nil
+}
+
+// close disconnects sub. Its unacked messages are delivered again to the
+// consumer's next subscription, which starts after the stored position.
+func (d *DurableSubscriptions) close(sub *durableSubscription) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.active[sub.key] == sub {
+		delete(d.active, sub.key)
+	}
+}
+
+// Ack acknowledges the message at globalPosition sent to consumer's durable
+// subscription. The consumer's stored position advances to just before its
+// oldest unacked message.
+func (d *DurableSubscriptions) Ack(ctx context.Context, namespace, consumer string, globalPosition int64) (*AckResult, error) {
+	d.mu.Lock()
+	sub, ok := d.active[durableKey(namespace, consumer)]
+	d.mu.Unlock()
+	if !ok {
+		return nil, ErrDurableSubscriptionNotFound
+	}
+	if sub.mode != DeliveryAtLeastOnce {
+		return nil, ErrAckNotExpected
+	}
+	return sub.ack(ctx, globalPosition)
+}
+
+// Stats returns the connected subscriptions and delivery counts
+func (d *DurableSubscriptions) Stats() DurableStats {
+	d.mu.Lock()
+	subs := make([]*durableSubscription, 0, len(d.active))
+	for _, sub := range d.active {
+		subs = append(subs, sub)
+	}
+	d.mu.Unlock()
+
+	stats := DurableStats{
+		Connected:    len(subs),
+		Delivered:    d.delivered.Load(),
+		Redeliveries: d.redeliveries.Load(),
+		Acks:         d.acks.Load(),
+	}
+	for _, sub := range subs {
+		sub.mu.Lock()
+		stats.Unacked += len(sub.pending)
+		sub.mu.Unlock()
+	}
+	return stats
+}
+
+// ack removes a pending delivery and stores the new position
+func (s *durableSubscription) ack(ctx context.Context, globalPosition int64) (*AckResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, acked := s.pending[globalPosition]
+	if acked {
+		delete(s.pending, globalPosition)
+		s.owner.acks.Add(1)
+	}
+
+	// Everything before the oldest unacked message has been processed
+	committed := s.delivered
+	for gp := range s.pending {
+		if gp-1 < committed {
+			committed = gp - 1
+		}
+	}
+	if committed > s.committed {
+		if _, err := s.positions.SetConsumerPosition(ctx, s.namespace, s.consumer, committed); err != nil {
+			return nil, err
+		}
+		s.committed = committed
+	}
+
+	if acked {
+		select {
+		case s.acked <- struct{}{}:
+		default:
+		}
+	}
+	return &AckResult{Acked: acked, Committed: s.committed, Unacked: len(s.pending)}, nil
+}
+
+// window returns how many new messages may be sent
+func (s *durableSubscription) window() int64 {
+	if s.mode == DeliveryAtMostOnce {
+		return durableBatchSize
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return min(durableBatchSize, int64(maxUnackedDeliveries-len(s.pending)))
+}
+
+// deliver sends a message for the first time. An at-most-once message is
+// recorded as processed before it is sent; an at-least-once message awaits
+// its ack.
+func (s *durableSubscription) deliver(ctx context.Context, msg *store.Message, send durableSender) error {
+	s.mu.Lock()
+	if s.mode == DeliveryAtMostOnce {
+		if _, err := s.positions.SetConsumerPosition(ctx, s.namespace, s.consumer, msg.GlobalPosition); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.committed = msg.GlobalPosition
+	} else {
+		s.pending[msg.GlobalPosition] = &pendingDelivery{msg: msg, count: 1, deadline: time.Now().Add(s.ackTimeout)}
+	}
+	s.delivered = msg.GlobalPosition
+	s.mu.Unlock()
+
+	s.owner.delivered.Add(1)
+	if s.mode == DeliveryAtMostOnce {
+		return send(msg, 0)
+	}
+	return send(msg, 1)
+}
+
+// redeliver sends the messages whose ack timed out again, oldest first
+func (s *durableSubscription) redeliver(now time.Time, send durableSender) error {
+	type redelivery struct {
+		msg   *store.Message
+		count int
+	}
+
+	s.mu.Lock()
+	var due []redelivery
+	for _, p := range s.pending {
+		if now.Before(p.deadline) {
+			continue
+		}
+		p.count++
+		p.deadline = now.Add(s.ackTimeout)
+		due = append(due, redelivery{msg: p.msg, count: p.count})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].msg.GlobalPosition < due[j].msg.GlobalPosition })
+	for _, r := range due {
+		s.owner.redeliveries.Add(1)
+		if err := send(r.msg, r.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tickInterval is how often deliveries are checked for expired acks
+func (s *durableSubscription) tickInterval() time.Duration {
+	return min(max(s.ackTimeout/4, 25*time.Millisecond), time.Second)
+}
+
+// durableSender sends a message of a durable subscription. deliveryCount is
+// 1 for the first delivery of an at-least-once message, higher for
+// redeliveries, and 0 for at-most-once messages.
+type durableSender func(msg *store.Message, deliveryCount int) error
+
+// durableFetcher reads up to limit messages of a durable subscription from
+// globalPosition on
+type durableFetcher func(globalPosition, limit int64) ([]*store.Message, error)
+
+// run delivers the messages of sub until done is closed, wake is closed, or
+// sending fails. Writes signalled on wake are read from the store, so
+// messages are sent in order and none is skipped while the window is full.
+func (s *durableSubscription) run(ctx context.Context, done <-chan struct{}, wake Subscriber, fetch durableFetcher, send durableSender, keepAlive func() error) {
+	ticker := time.NewTicker(s.tickInterval())
+	defer ticker.Stop()
+	keepAliveTicker := time.NewTicker(durableKeepAlive)
+	defer keepAliveTicker.Stop()
+
+	next := s.start
+	for {
+		// Send new messages while the window has room
+		for {
+			limit := s.window()
+			if limit <= 0 {
+				break
+			}
+			messages, err := fetch(next, limit)
+			if err != nil {
+				logger.Get().Error().
+					Err(err).
+					Str("consumer", s.consumer).
+					Str("namespace", s.namespace).
+					Int64("position", next).
+					Msg("Error fetching durable subscription messages")
+				break
+			}
+			for _, msg := range messages {
+				if err := s.deliver(ctx, msg, send); err != nil {
+					return
+				}
+				next = msg.GlobalPosition + 1
+			}
+			if int64(len(messages)) < limit {
+				break
+			}
+		}
+
+		select {
+		case <-done:
+			return
+		case _, ok := <-wake:
+			if !ok {
+				return
+			}
+			// One read covers every write signalled so far
+			for drained := false; !drained; {
+				select {
+				case <-wake:
+				default:
+					drained = true
+				}
+			}
+		case <-s.acked:
+		case now := <-ticker.C:
+			if err := s.redeliver(now, send); err != nil {
+				return
+			}
+		case <-keepAliveTicker.C:
+			if err := keepAlive(); err != nil {
+				return
+			}
+		}
+	}
+}
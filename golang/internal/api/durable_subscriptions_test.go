@@ -0,0 +1,262 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// newDurableTestServer serves subscriptions of sse in "test-ns" and returns
+// an RPC handler acking its durable subscriptions
+func newDurableTestServer(t *testing.T, st store.Store, sse *SSEHandler) (*httptest.Server, *RPCHandler) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sse.HandleSubscribe(w, r.WithContext(context.WithValue(r.Context(), ContextKeyNamespace, "test-ns")))
+	}))
+	t.Cleanup(server.Close)
+	h := NewRPCHandler("test", st, sse.Pubsub)
+	h.SetDurableSubscriptions(sse.Durable)
+	return server, h
+}
+
+// readDelivery reads the next message event of a durable subscription
+func readDelivery(t *testing.T, r *bufio.Reader) SSEMessage {
+	t.Helper()
+	event, data := readSSEEvent(t, r)
+	var msg SSEMessage
+	if err := json.Unmarshal([]byte(data), &msg); err != nil || event != "message" {
+		t.Fatalf("Expected a message event, got %s %s", event, data)
+	}
+	return msg
+}
+
+// waitDisconnected waits until no durable subscription is connected
+func waitDisconnected(t *testing.T, d *DurableSubscriptions) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for d.Stats().Connected > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Durable subscription still connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestDurableSubscription_AtLeastOnce tests that unacked messages are
+// redelivered, acks advance the consumer position past acked messages only,
+// and a new subscription resumes after that position
+func TestDurableSubscription_AtLeastOnce(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	sse := NewSSEHandler(st, NewPubSub(), true)
+	server, h := newDurableTestServer(t, st, sse)
+
+	var written []*store.WriteResult
+	for _, stream := range []string{"order-1", "order-2", "order-3"} {
+		result, err := st.WriteMessage(ctx, "test-ns", stream, &store.Message{Type: "Placed", Data: map[string]interface{}{}})
+		if err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+		written = append(written, result)
+	}
+
+	url := server.URL + "?category=order&payload=full&durable=billing&ackTimeout=200ms"
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	r := bufio.NewReader(resp.Body)
+	for i, w := range written {
+		if msg := readDelivery(t, r); msg.GlobalPosition != w.GlobalPosition || msg.DeliveryCount != 1 {
+			t.Fatalf("Delivery %d: expected position %d once, got %d (count %d)", i, w.GlobalPosition, msg.GlobalPosition, msg.DeliveryCount)
+		}
+	}
+
+	// One consumer has one durable subscription
+	second, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	second.Body.Close()
+	if second.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for a second subscription, got %d", second.StatusCode)
+	}
+
+	// The position stops before the oldest unacked message
+	for _, w := range []*store.WriteResult{written[0], written[2]} {
+		if _, rpcErr := h.route(ctx, "consumer.ack", []interface{}{"billing", w.GlobalPosition}); rpcErr != nil {
+			t.Fatalf("consumer.ack failed: %v", rpcErr.Message)
+		}
+	}
+	result, rpcErr := h.route(ctx, "consumer.getPosition", []interface{}{"billing"})
+	if rpcErr != nil {
+		t.Fatalf("consumer.getPosition failed: %v", rpcErr.Message)
+	}
+	if got := result.(map[string]interface{})["globalPosition"]; got != written[0].GlobalPosition {
+		t.Errorf("Expected position %d with a message unacked, got %v", written[0].GlobalPosition, got)
+	}
+
+	// The unacked message is redelivered once its ack times out
+	if msg := readDelivery(t, r); msg.GlobalPosition != written[1].GlobalPosition || msg.DeliveryCount != 2 {
+		t.Errorf("Expected redelivery of %d, got %d (count %d)", written[1].GlobalPosition, msg.GlobalPosition, msg.DeliveryCount)
+	}
+	if stats := sse.Durable.Stats(); stats.Redeliveries == 0 || stats.Unacked != 1 || stats.Delivered != 3 || stats.Acks != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	result, rpcErr = h.route(ctx, "consumer.ack", []interface{}{"billing", written[1].GlobalPosition})
+	if rpcErr != nil {
+		t.Fatalf("consumer.ack failed: %v", rpcErr.Message)
+	}
+	ack := result.(map[string]interface{})
+	if ack["acked"] != true || ack["position"] != written[2].GlobalPosition || ack["unacked"] != 0 {
+		t.Errorf("Unexpected ack result: %v", ack)
+	}
+
+	// Acking again is harmless
+	result, rpcErr = h.route(ctx, "consumer.ack", []interface{}{"billing", written[1].GlobalPosition})
+	if rpcErr != nil || result.(map[string]interface{})["acked"] != false {
+		t.Errorf("Expected a repeated ack to report acked=false, got %v %v", result, rpcErr)
+	}
+
+	// A new subscription resumes after the stored position
+	resp.Body.Close()
+	waitDisconnected(t, sse.Durable)
+	if _, rpcErr := h.route(ctx, "consumer.ack", []interface{}{"billing", written[2].GlobalPosition}); rpcErr == nil || rpcErr.Code != "SUBSCRIPTION_NOT_FOUND" {
+		t.Errorf("Expected SUBSCRIPTION_NOT_FOUND once disconnected, got %v", rpcErr)
+	}
+	next, err := st.WriteMessage(ctx, "test-ns", "order-4", &store.Message{Type: "Placed", Data: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if msg := readDelivery(t, bufio.NewReader(resp.Body)); msg.GlobalPosition != next.GlobalPosition {
+		t.Errorf("Expected to resume at %d, got %d", next.GlobalPosition, msg.GlobalPosition)
+	}
+}
+
+// TestDurableSubscription_AtMostOnce tests that at-most-once messages are
+// recorded as processed when sent and are not acknowledged
+func TestDurableSubscription_AtMostOnce(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	sse := NewSSEHandler(st, NewPubSub(), true)
+	server, h := newDurableTestServer(t, st, sse)
+
+	first, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{Type: "Placed", Data: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+
+	url := server.URL + "?category=order&payload=full&durable=audit&delivery=at-most-once"
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	r := bufio.NewReader(resp.Body)
+	if msg := readDelivery(t, r); msg.GlobalPosition != first.GlobalPosition || msg.DeliveryCount != 0 {
+		t.Errorf("Expected %d without a delivery count, got %d (count %d)", first.GlobalPosition, msg.GlobalPosition, msg.DeliveryCount)
+	}
+
+	// New writes wake the subscription
+	second, err := st.WriteMessage(ctx, "test-ns", "order-2", &store.Message{Type: "Placed", Data: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+	sse.Pubsub.Publish(WriteEvent{Namespace: "test-ns", Stream: "order-2", Category: "order", Position: second.Position, GlobalPosition: second.GlobalPosition})
+	if msg := readDelivery(t, r); msg.GlobalPosition != second.GlobalPosition {
+		t.Errorf("Expected %d, got %d", second.GlobalPosition, msg.GlobalPosition)
+	}
+
+	// The position was stored before the message was sent
+	result, rpcErr := h.route(ctx, "consumer.getPosition", []interface{}{"audit"})
+	if rpcErr != nil {
+		t.Fatalf("consumer.getPosition failed: %v", rpcErr.Message)
+	}
+	if got := result.(map[string]interface{})["globalPosition"]; got != second.GlobalPosition {
+		t.Errorf("Expected position %d, got %v", second.GlobalPosition, got)
+	}
+	if _, rpcErr := h.route(ctx, "consumer.ack", []interface{}{"audit", second.GlobalPosition}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an at-most-once ack, got %v", rpcErr)
+	}
+	resp.Body.Close()
+
+	// An explicit position overrides the stored one
+	waitDisconnected(t, sse.Durable)
+	resp, err = http.Get(url + "&position=0")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if msg := readDelivery(t, bufio.NewReader(resp.Body)); msg.GlobalPosition != first.GlobalPosition {
+		t.Errorf("Expected to restart at %d, got %d", first.GlobalPosition, msg.GlobalPosition)
+	}
+}
+
+// TestDurableSubscription_Errors tests invalid durable subscription
+// parameters, backends without consumer positions and invalid acks
+func TestDurableSubscription_Errors(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	sse := NewSSEHandler(st, NewPubSub(), true)
+	server, h := newDurableTestServer(t, st, sse)
+
+	for _, query := range []string{
+		"category=order&payload=full&delivery=at-most-once",
+		"category=order&payload=full&ackTimeout=1s",
+		"category=order&durable=billing",
+		"stream=order-1&payload=full&durable=billing",
+		"category=order*&payload=full&durable=billing",
+		"all=true&payload=full&durable=billing",
+		"category=order&payload=full&durable=billing&delivery=exactly-once",
+		"category=order&payload=full&durable=billing&ackTimeout=soon",
+		"category=order&payload=full&durable=billing&ackTimeout=1ms",
+		"category=order&payload=full&durable=billing&ackTimeout=2h",
+		"category=order&payload=full&durable=%01",
+	} {
+		resp, err := http.Get(server.URL + "?" + query)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
+	}
+
+	// Durable subscriptions need consumer positions
+	unsupported := NewSSEHandler(struct{ store.Store }{st}, NewPubSub(), true)
+	plain, _ := newDurableTestServer(t, st, unsupported)
+	resp, err := http.Get(plain.URL + "?category=order&payload=full&durable=billing")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without consumer positions, got %d", resp.StatusCode)
+	}
+
+	for _, args := range [][]interface{}{
+		{},
+		{"billing"},
+		{"", 1},
+		{"billing", "1"},
+	} {
+		if _, rpcErr := h.route(ctx, "consumer.ack", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("%v: expected INVALID_REQUEST, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "consumer.ack", []interface{}{"billing", 1}); rpcErr == nil || rpcErr.Code != "SUBSCRIPTION_NOT_FOUND" {
+		t.Errorf("Expected SUBSCRIPTION_NOT_FOUND, got %v", rpcErr)
+	}
+}
@@ -0,0 +1,623 @@
+// Package api provides edge sync between an offline-capable edge server and its hub.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// Namespace metadata keys holding the edge sync config and its progress
+	edgeSyncMetadataKey       = "edgeSync"
+	edgeSyncStatusMetadataKey = "edgeSyncStatus"
+
+	// edgeSyncDefaultBatch is the number of messages per pushed or pulled batch
+	edgeSyncDefaultBatch = 500
+
+	// edgeSyncMaxBatch caps the configurable batch size
+	edgeSyncMaxBatch = 1000
+
+	// edgeSyncMaxCategories bounds the categories pushed or pulled per namespace
+	edgeSyncMaxCategories = 32
+
+	// edgeSyncDedupeWindow is how many of a stream's latest messages the hub
+	// checks for the IDs of pushed messages
+	edgeSyncDedupeWindow = 1000
+
+	// edgeSyncMaxBackoff caps the retry delay while the hub is unreachable
+	edgeSyncMaxBackoff = time.Minute
+
+	// edgeSyncTimeout bounds one call to the hub
+	edgeSyncTimeout = 30 * time.Second
+)
+
+// Edge sync states reported by ns.edgeSync.status
+const (
+	EdgeSyncStateActive       = "active"
+	EdgeSyncStateDisconnected = "disconnected"
+)
+
+// ErrEdgeSyncDisabled is returned when the server was started without edge sync
+var ErrEdgeSyncDisabled = errors.New("edge sync is not enabled")
+
+// EdgeSyncConfig describes how an edge namespace syncs with its hub.
+//
+// Messages of the push categories are written locally and sent to the hub's
+// edge.push, which assigns their final positions and skips IDs it already
+// has. Messages of the pull categories are read from the hub and written
+// locally with their hub IDs; local writes to them are rejected, so each
+// category has a single writer and the two sides cannot conflict.
+type EdgeSyncConfig struct {
+	URL       string   `json:"url"`                 // Base URL of the hub server
+	Token     string   `json:"token"`               // Token of the hub namespace
+	Push      []string `json:"push,omitempty"`      // Edge-scoped categories sent to the hub
+	Pull      []string `json:"pull,omitempty"`      // Hub categories copied to the edge
+	BatchSize int      `json:"batchSize,omitempty"` // Messages per batch (default: 500, max: 1000)
+}
+
+// EdgeSyncStatus reports the progress of a namespace's edge sync
+type EdgeSyncStatus struct {
+	State        string           `json:"state"`                  // active or disconnected
+	Pushed       map[string]int64 `json:"pushed,omitempty"`       // Last local global position pushed, per category
+	Pulled       map[string]int64 `json:"pulled,omitempty"`       // Last hub global position pulled, per category
+	LastSyncedAt string           `json:"lastSyncedAt,omitempty"` // Time of the last successful sync
+	LastError    string           `json:"lastError,omitempty"`    // Most recent failure, cleared on success
+	LastErrorAt  string           `json:"lastErrorAt,omitempty"`
+}
+
+// Validate checks the config and applies defaults
+func (c *EdgeSyncConfig) Validate() error {
+	if err := validateShippingURL(c.URL); err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+	if c.Token == "" {
+		return fmt.Errorf("token must not be empty")
+	}
+	if len(c.Push) == 0 && len(c.Pull) == 0 {
+		return fmt.Errorf("at least one push or pull category is required")
+	}
+	if len(c.Push) > edgeSyncMaxCategories || len(c.Pull) > edgeSyncMaxCategories {
+		return fmt.Errorf("at most %d push and %d pull categories are allowed", edgeSyncMaxCategories, edgeSyncMaxCategories)
+	}
+	seen := make(map[string]string)
+	for _, list := range []struct {
+		name       string
+		categories []string
+	}{{"push", c.Push}, {"pull", c.Pull}} {
+		for _, category := range list.categories {
+			if category == "" || strings.Contains(category, "-") {
+				return fmt.Errorf("%s: %q is not a category name", list.name, category)
+			}
+			if previous, ok := seen[category]; ok {
+				return fmt.Errorf("%s: category %q is already listed in %s", list.name, category, previous)
+			}
+			seen[category] = list.name
+		}
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = edgeSyncDefaultBatch
+	}
+	if c.BatchSize > edgeSyncMaxBatch {
+		c.BatchSize = edgeSyncMaxBatch
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with the token masked
+func (c EdgeSyncConfig) Redacted() EdgeSyncConfig {
+	if c.Token != "" {
+		c.Token = logShippingRedacted
+	}
+	return c
+}
+
+// EdgeSyncFromMetadata returns the edge sync config and status stored in
+// namespace metadata. Either may be nil.
+func EdgeSyncFromMetadata(metadata map[string]interface{}) (*EdgeSyncConfig, *EdgeSyncStatus) {
+	var cfg *EdgeSyncConfig
+	if raw, ok := metadata[edgeSyncMetadataKey]; ok && raw != nil {
+		var c EdgeSyncConfig
+		if decodeMetadataValue(raw, &c) == nil {
+			cfg = &c
+		}
+	}
+
+	var status *EdgeSyncStatus
+	if raw, ok := metadata[edgeSyncStatusMetadataKey]; ok && raw != nil {
+		var s EdgeSyncStatus
+		if decodeMetadataValue(raw, &s) == nil {
+			status = &s
+		}
+	}
+	return cfg, status
+}
+
+// edgeSyncDuplicates returns the IDs of msgs already among the latest
+// messages of their target streams. Synced messages get new positions on
+// the other side, so the check goes by ID over a window of each stream.
+func edgeSyncDuplicates(ctx context.Context, st store.Store, namespace string, msgs []*store.Message) (map[string]bool, error) {
+	streams := make(map[string]bool)
+	for _, msg := range msgs {
+		streams[msg.StreamName] = true
+	}
+
+	existing := make(map[string]bool)
+	for stream := range streams {
+		version, err := st.GetStreamVersion(ctx, namespace, stream)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream %s: %w", stream, err)
+		}
+		if version < 0 {
+			continue
+		}
+		found, err := st.GetStreamMessages(ctx, namespace, stream, &store.GetOpts{
+			Position:  max(version-edgeSyncDedupeWindow+1, 0),
+			BatchSize: edgeSyncDedupeWindow,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream %s: %w", stream, err)
+		}
+		for _, msg := range found {
+			existing[msg.ID] = true
+		}
+	}
+	return existing, nil
+}
+
+// EdgeSync keeps edge namespaces configured with ns.edgeSync.set in sync
+// with their hub.
+//
+// Configuration and progress live in namespace metadata, so syncing resumes
+// after a restart or a lost connection from the last synced positions. One
+// worker runs per synced namespace.
+type EdgeSync struct {
+	store    store.Store
+	pubsub   *PubSub
+	client   *http.Client
+	notifier *Notifier
+
+	// mu guards workers
+	mu      sync.Mutex
+	workers map[string]*edgeSyncWorker
+	closed  bool
+}
+
+// NewEdgeSync creates an edge sync manager
+func NewEdgeSync(st store.Store, pubsub *PubSub) *EdgeSync {
+	return &EdgeSync{
+		store:   st,
+		pubsub:  pubsub,
+		client:  &http.Client{Timeout: edgeSyncTimeout},
+		workers: make(map[string]*edgeSyncWorker),
+	}
+}
+
+// SetNotifier routes sync failures to a notifier
+func (e *EdgeSync) SetNotifier(n *Notifier) {
+	e.notifier = n
+}
+
+// Start launches a worker for every namespace with edge sync configured
+func (e *EdgeSync) Start(ctx context.Context) error {
+	namespaces, err := e.store.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, ns := range namespaces {
+		cfg, _ := EdgeSyncFromMetadata(ns.Metadata)
+		if cfg == nil {
+			continue
+		}
+		if err := cfg.Validate(); err != nil {
+			logger.Get().Warn().Err(err).Str("namespace", ns.ID).Msg("Ignoring invalid edge sync config")
+			continue
+		}
+		e.startWorker(ns.ID, *cfg)
+	}
+	return nil
+}
+
+// Configure stores a namespace's edge sync config and (re)starts its worker.
+// A nil config stops syncing. Changing the hub URL restarts syncing from the
+// beginning of every category; both sides skip messages they already have.
+func (e *EdgeSync) Configure(ctx context.Context, namespace string, cfg *EdgeSyncConfig) error {
+	if cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if w, ok := e.workers[namespace]; ok {
+		w.halt()
+		delete(e.workers, namespace)
+	}
+
+	err := updateNamespaceMetadata(ctx, e.store, namespace, func(metadata map[string]interface{}) {
+		previous, _ := EdgeSyncFromMetadata(metadata)
+
+		// A masked token echoed back from ns.edgeSync.status keeps its stored value
+		if cfg != nil && previous != nil && cfg.Token == logShippingRedacted {
+			cfg.Token = previous.Token
+		}
+
+		if cfg == nil {
+			delete(metadata, edgeSyncMetadataKey)
+			delete(metadata, edgeSyncStatusMetadataKey)
+		} else {
+			metadata[edgeSyncMetadataKey] = encodeMetadataValue(cfg)
+			if previous == nil || previous.URL != cfg.URL {
+				delete(metadata, edgeSyncStatusMetadataKey)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg != nil && !e.closed {
+		e.startWorker(namespace, *cfg)
+	}
+	return nil
+}
+
+// HubOwned reports whether streamName belongs to a category the namespace
+// pulls from its hub, which only the hub may write to
+func (e *EdgeSync) HubOwned(namespace, streamName string) bool {
+	if e == nil {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	w, ok := e.workers[namespace]
+	if !ok {
+		return false
+	}
+	category := store.Category(streamName)
+	for _, pulled := range w.cfg.Pull {
+		if pulled == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops all workers
+func (e *EdgeSync) Close() error {
+	e.mu.Lock()
+	e.closed = true
+	workers := e.workers
+	e.workers = make(map[string]*edgeSyncWorker)
+	e.mu.Unlock()
+
+	for _, w := range workers {
+		w.halt()
+	}
+	return nil
+}
+
+// startWorker launches a worker; the caller holds mu
+func (e *EdgeSync) startWorker(namespace string, cfg EdgeSyncConfig) {
+	w := &edgeSyncWorker{
+		edge:      e,
+		namespace: namespace,
+		cfg:       cfg,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	e.workers[namespace] = w
+	go w.run()
+}
+
+// saveStatus records sync progress in namespace metadata
+func (e *EdgeSync) saveStatus(ctx context.Context, w *edgeSyncWorker, status EdgeSyncStatus) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// A worker that has been replaced must not overwrite its successor's status
+	if e.workers[w.namespace] != w {
+		return nil
+	}
+
+	return updateNamespaceMetadata(ctx, e.store, w.namespace, func(metadata map[string]interface{}) {
+		metadata[edgeSyncStatusMetadataKey] = encodeMetadataValue(status)
+	})
+}
+
+// edgeSyncWorker syncs one namespace
+type edgeSyncWorker struct {
+	edge      *EdgeSync
+	namespace string
+	cfg       EdgeSyncConfig
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// halt stops the worker and waits for it to exit
+func (w *edgeSyncWorker) halt() {
+	close(w.stop)
+	<-w.done
+}
+
+// run syncs the namespace until stopped. Local writes trigger a push right
+// away; the hub is polled for pulled categories.
+func (w *edgeSyncWorker) run() {
+	defer close(w.done)
+
+	ctx := context.Background()
+	e := w.edge
+	log := logger.Get().With().Str("namespace", w.namespace).Str("hub", w.cfg.URL).Logger()
+
+	var status EdgeSyncStatus
+	if ns, err := e.store.GetNamespace(ctx, w.namespace); err == nil {
+		if _, saved := EdgeSyncFromMetadata(ns.Metadata); saved != nil {
+			status = *saved
+		}
+	}
+	if status.Pushed == nil {
+		status.Pushed = make(map[string]int64)
+	}
+	if status.Pulled == nil {
+		status.Pulled = make(map[string]int64)
+	}
+
+	var events Subscriber
+	if e.pubsub != nil {
+		events = e.pubsub.SubscribeAll(w.namespace)
+		defer e.pubsub.UnsubscribeAll(w.namespace, events)
+	}
+
+	backoff := tailerPollInterval
+	for {
+		err := w.syncOnce(ctx, &status)
+		if err != nil {
+			log.Warn().Err(err).Msg("Edge sync failed, will retry")
+			status.State = EdgeSyncStateDisconnected
+			status.LastError = err.Error()
+			status.LastErrorAt = time.Now().UTC().Format(time.RFC3339Nano)
+			if err := e.saveStatus(ctx, w, status); err != nil {
+				log.Warn().Err(err).Msg("Failed to record edge sync status")
+			}
+			e.notifier.Notify(SystemEvent{
+				Type:      SystemEventConnectorFailed,
+				Severity:  "warning",
+				Namespace: w.namespace,
+				Subject:   "edgeSync",
+				Message:   err.Error(),
+			})
+		}
+
+		wait := tailerPollInterval
+		if err != nil {
+			wait = backoff
+			backoff = min(backoff*2, edgeSyncMaxBackoff)
+		} else {
+			backoff = tailerPollInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-w.stop:
+			timer.Stop()
+			return
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+			}
+			// Keep backing off while the hub is unreachable
+			if err != nil {
+				<-timer.C
+			}
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// syncOnce pushes every push category and pulls every pull category until
+// both sides are caught up
+func (w *edgeSyncWorker) syncOnce(ctx context.Context, status *EdgeSyncStatus) error {
+	synced := false
+	for _, category := range w.cfg.Push {
+		n, err := w.push(ctx, category, status)
+		if err != nil {
+			return fmt.Errorf("push %s: %w", category, err)
+		}
+		synced = synced || n > 0
+	}
+	for _, category := range w.cfg.Pull {
+		n, err := w.pull(ctx, category, status)
+		if err != nil {
+			return fmt.Errorf("pull %s: %w", category, err)
+		}
+		synced = synced || n > 0
+	}
+
+	// Record the first success after a failure even when nothing was synced
+	if !synced && status.State == EdgeSyncStateActive {
+		return nil
+	}
+	status.State = EdgeSyncStateActive
+	status.LastSyncedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	status.LastError = ""
+	status.LastErrorAt = ""
+	if err := w.edge.saveStatus(ctx, w, *status); err != nil {
+		return fmt.Errorf("failed to record positions: %w", err)
+	}
+	return nil
+}
+
+// push sends the category's local messages after its recorded position to
+// the hub and returns how many were sent
+func (w *edgeSyncWorker) push(ctx context.Context, category string, status *EdgeSyncStatus) (int, error) {
+	e := w.edge
+	total := 0
+	for {
+		msgs, err := e.store.GetCategoryMessages(ctx, w.namespace, category, &store.CategoryOpts{
+			Position:  status.Pushed[category] + 1,
+			BatchSize: int64(w.cfg.BatchSize),
+		})
+		if err != nil {
+			return total, err
+		}
+		if len(msgs) == 0 {
+			return total, nil
+		}
+
+		batch := make([]map[string]interface{}, len(msgs))
+		for i, msg := range msgs {
+			batch[i] = map[string]interface{}{
+				"id":       msg.ID,
+				"stream":   msg.StreamName,
+				"type":     msg.Type,
+				"data":     msg.Data,
+				"metadata": msg.Metadata,
+			}
+		}
+		if _, err := w.call(ctx, "edge.push", batch); err != nil {
+			return total, err
+		}
+
+		total += len(msgs)
+		status.Pushed[category] = msgs[len(msgs)-1].GlobalPosition
+		if err := e.saveStatus(ctx, w, *status); err != nil {
+			return total, fmt.Errorf("failed to record position: %w", err)
+		}
+
+		select {
+		case <-w.stop:
+			return total, nil
+		default:
+		}
+	}
+}
+
+// pull copies the hub's messages of the category after its recorded
+// position into the local namespace and returns how many were read
+func (w *edgeSyncWorker) pull(ctx context.Context, category string, status *EdgeSyncStatus) (int, error) {
+	e := w.edge
+	total := 0
+	for {
+		raw, err := w.call(ctx, "category.get", category, map[string]interface{}{
+			"position":  status.Pulled[category] + 1,
+			"batchSize": w.cfg.BatchSize,
+		})
+		if err != nil {
+			return total, err
+		}
+		var rows [][]json.RawMessage
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return total, fmt.Errorf("invalid category.get response: %w", err)
+		}
+		if len(rows) == 0 {
+			return total, nil
+		}
+
+		msgs := make([]*store.Message, len(rows))
+		for i, row := range rows {
+			// [id, streamName, type, position, globalPosition, data, metadata, time]
+			if len(row) < 7 {
+				return total, fmt.Errorf("invalid category.get response: short message")
+			}
+			msg := &store.Message{}
+			for j, dst := range []interface{}{&msg.ID, &msg.StreamName, &msg.Type, &msg.Position, &msg.GlobalPosition, &msg.Data, &msg.Metadata} {
+				if err := json.Unmarshal(row[j], dst); err != nil {
+					return total, fmt.Errorf("invalid category.get response: %w", err)
+				}
+			}
+			msgs[i] = msg
+		}
+
+		existing, err := edgeSyncDuplicates(ctx, e.store, w.namespace, msgs)
+		if err != nil {
+			return total, err
+		}
+		for _, msg := range msgs {
+			if existing[msg.ID] {
+				continue
+			}
+			result, err := e.store.WriteMessage(ctx, w.namespace, msg.StreamName, &store.Message{
+				ID:         msg.ID,
+				StreamName: msg.StreamName,
+				Type:       msg.Type,
+				Data:       msg.Data,
+				Metadata:   msg.Metadata,
+			})
+			if err != nil {
+				return total, fmt.Errorf("failed to write %s: %w", msg.ID, err)
+			}
+			if e.pubsub != nil {
+				e.pubsub.Publish(WriteEvent{
+					Namespace:      w.namespace,
+					Stream:         msg.StreamName,
+					Category:       category,
+					Position:       result.Position,
+					GlobalPosition: result.GlobalPosition,
+				})
+			}
+			status.Pulled[category] = msg.GlobalPosition
+		}
+
+		total += len(msgs)
+		status.Pulled[category] = msgs[len(msgs)-1].GlobalPosition
+		if err := e.saveStatus(ctx, w, *status); err != nil {
+			return total, fmt.Errorf("failed to record position: %w", err)
+		}
+
+		select {
+		case <-w.stop:
+			return total, nil
+		default:
+		}
+	}
+}
+
+// call invokes an RPC method on the hub and returns its raw result
+func (w *edgeSyncWorker) call(ctx context.Context, method string, args ...interface{}) (json.RawMessage, error) {
+	body, err := json.Marshal(append([]interface{}{method}, args...))
+	if err != nil {
+		return nil, err
+	}
+	target := strings.TrimRight(w.cfg.URL, "/") + "/rpc"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+w.cfg.Token)
+
+	resp, err := w.edge.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp ErrorResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error != nil {
+			return nil, fmt.Errorf("hub responded with status %d: %s: %s", resp.StatusCode, errResp.Error.Code, errResp.Error.Message)
+		}
+		return nil, fmt.Errorf("hub responded with status %d", resp.StatusCode)
+	}
+	return data, nil
+}
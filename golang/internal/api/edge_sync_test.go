@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestEdgeSync verifies that an edge pushes its categories to the hub, which
+// suppresses duplicate IDs, and copies the hub's categories, which it does
+// not accept local writes to
+func TestEdgeSync(t *testing.T) {
+	ctx := context.Background()
+	edgeStore := newTestStore(t)
+	// Test mode namespaces share one in-memory database per name, so the
+	// hub uses its own namespace
+	hubStore := newTestStore(t)
+	if err := hubStore.CreateNamespace(ctx, "hub-ns", "hub-hash", "Hub namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	hub := NewRPCHandler("test", hubStore, NewPubSub())
+	hubCtx := context.WithValue(ctx, ContextKeyNamespace, "hub-ns")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rpc" || r.Header.Get("Authorization") != "Bearer hub-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		hub.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ContextKeyNamespace, "hub-ns")))
+	}))
+	defer server.Close()
+
+	write := func(h *RPCHandler, ctx context.Context, stream string) (interface{}, *RPCError) {
+		return h.route(ctx, "stream.write", []interface{}{stream, map[string]interface{}{
+			"type": "Placed",
+			"data": map[string]interface{}{"stream": stream},
+		}})
+	}
+	if _, rpcErr := write(hub, hubCtx, "catalog-1"); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+
+	edgeSync := NewEdgeSync(edgeStore, NewPubSub())
+	defer edgeSync.Close()
+	edge := NewRPCHandler("test", edgeStore, NewPubSub())
+	edge.SetEdgeSync(edgeSync)
+	edgeCtx := context.WithValue(ctx, ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := edge.route(edgeCtx, "ns.edgeSync.set", []interface{}{map[string]interface{}{
+		"url": server.URL, "token": "hub-token", "push": []interface{}{"order"}, "pull": []interface{}{"order"},
+	}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a category both pushed and pulled, got %v", rpcErr)
+	}
+
+	// Written while the edge is not yet connected
+	if _, rpcErr := write(edge, edgeCtx, "order-1"); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := edge.route(edgeCtx, "ns.edgeSync.set", []interface{}{map[string]interface{}{
+		"url": server.URL, "token": "hub-token", "push": []interface{}{"order"}, "pull": []interface{}{"catalog"},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.edgeSync.set failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := write(edge, edgeCtx, "order-1"); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, rpcErr := edge.route(edgeCtx, "ns.edgeSync.status", nil)
+		if rpcErr != nil {
+			t.Fatalf("ns.edgeSync.status failed: %v", rpcErr.Message)
+		}
+		status, _ := result.(map[string]interface{})["status"].(map[string]interface{})
+		pushed, _ := status["pushed"].(map[string]interface{})
+		pulled, _ := status["pulled"].(map[string]interface{})
+		if pushed["order"] == 2.0 && pulled["catalog"] == 1.0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for edge sync, last: %v", result)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	local, _ := edgeStore.GetStreamMessages(ctx, "test-ns", "order-1", nil)
+	synced, err := hubStore.GetStreamMessages(ctx, "hub-ns", "order-1", nil)
+	if err != nil || len(synced) != 2 || synced[0].ID != local[0].ID || synced[1].ID != local[1].ID {
+		t.Fatalf("Expected the edge's messages on the hub, got %v (%v)", synced, err)
+	}
+	if synced[0].GlobalPosition != 2 {
+		t.Errorf("Expected a hub-assigned global position, got %d", synced[0].GlobalPosition)
+	}
+	hubMsgs, _ := hubStore.GetStreamMessages(ctx, "hub-ns", "catalog-1", nil)
+	pulled, err := edgeStore.GetStreamMessages(ctx, "test-ns", "catalog-1", nil)
+	if err != nil || len(pulled) != 1 || pulled[0].ID != hubMsgs[0].ID {
+		t.Fatalf("Expected the hub's message on the edge, got %v (%v)", pulled, err)
+	}
+
+	// A batch re-sent after a lost response is not written twice
+	result, rpcErr := hub.route(hubCtx, "edge.push", []interface{}{[]interface{}{map[string]interface{}{
+		"id": local[1].ID, "stream": "order-1", "type": "Placed",
+	}}})
+	if rpcErr != nil {
+		t.Fatalf("edge.push failed: %v", rpcErr.Message)
+	}
+	if info := result.(map[string]interface{}); info["written"] != 0 || info["duplicates"] != 1 {
+		t.Errorf("Expected the message to be skipped as a duplicate, got %v", info)
+	}
+	if version, _ := hubStore.GetStreamVersion(ctx, "hub-ns", "order-1"); version != 1 {
+		t.Errorf("Expected stream version 1, got %d", version)
+	}
+
+	// Pulled categories are only written on the hub
+	if _, rpcErr := write(edge, edgeCtx, "catalog-2"); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for a pulled category, got %v", rpcErr)
+	}
+}
+
+// TestEdgeSync_Errors verifies config validation, edge.push argument checks,
+// and that a hub rejecting the edge's token leaves the sync disconnected
+func TestEdgeSync_Errors(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	valid := map[string]interface{}{"url": "https://hub.example.com", "token": "ns_x", "push": []interface{}{"order"}}
+	if _, rpcErr := h.route(ctx, "ns.edgeSync.set", []interface{}{valid}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without edge sync, got %v", rpcErr)
+	}
+	edgeSync := NewEdgeSync(st, NewPubSub())
+	defer edgeSync.Close()
+	h.SetEdgeSync(edgeSync)
+
+	tooMany := make([]interface{}, edgeSyncMaxCategories+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("c%d", i)
+	}
+	for _, cfg := range []interface{}{
+		"config",
+		map[string]interface{}{"url": "ftp://hub.example.com", "token": "ns_x", "push": []interface{}{"order"}},
+		map[string]interface{}{"url": "https://hub.example.com", "push": []interface{}{"order"}},
+		map[string]interface{}{"url": "https://hub.example.com", "token": "ns_x"},
+		map[string]interface{}{"url": "https://hub.example.com", "token": "ns_x", "push": []interface{}{"order-1"}},
+		map[string]interface{}{"url": "https://hub.example.com", "token": "ns_x", "pull": []interface{}{""}},
+		map[string]interface{}{"url": "https://hub.example.com", "token": "ns_x", "push": tooMany},
+		map[string]interface{}{"url": "https://hub.example.com", "token": "ns_x", "push": "order"},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.edgeSync.set", []interface{}{cfg}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", cfg, rpcErr)
+		}
+	}
+
+	tooLarge := make([]interface{}, edgeSyncMaxBatch+1)
+	for i := range tooLarge {
+		tooLarge[i] = map[string]interface{}{"id": fmt.Sprintf("m%d", i), "stream": "order-1", "type": "Placed"}
+	}
+	for _, msgs := range []interface{}{
+		"messages",
+		tooLarge,
+		[]interface{}{"message"},
+		[]interface{}{map[string]interface{}{"stream": "order-1", "type": "Placed"}},
+		[]interface{}{map[string]interface{}{"id": "m1", "stream": "order-1", "type": "Placed", "data": "x"}},
+		[]interface{}{map[string]interface{}{"id": "m1", "stream": "order-1", "type": "Placed", "metadata": 1.0}},
+	} {
+		if _, rpcErr := h.route(ctx, "edge.push", []interface{}{msgs}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", msgs, rpcErr)
+		}
+	}
+
+	// A hub that rejects the token leaves the sync disconnected with the error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-1", map[string]interface{}{"type": "Placed", "data": map[string]interface{}{}}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	valid["url"] = server.URL
+	valid["batchSize"] = float64(edgeSyncMaxBatch * 2)
+	result, rpcErr := h.route(ctx, "ns.edgeSync.set", []interface{}{valid})
+	if rpcErr != nil {
+		t.Fatalf("ns.edgeSync.set failed: %v", rpcErr.Message)
+	}
+	config := result.(map[string]interface{})["config"].(map[string]interface{})
+	if config["token"] != logShippingRedacted || config["batchSize"] != float64(edgeSyncMaxBatch) {
+		t.Errorf("Expected a masked token and a capped batch size, got %v", config)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, rpcErr := h.route(ctx, "ns.edgeSync.status", nil)
+		if rpcErr != nil {
+			t.Fatalf("ns.edgeSync.status failed: %v", rpcErr.Message)
+		}
+		status := result.(map[string]interface{})["status"].(map[string]interface{})
+		if status["state"] == EdgeSyncStateDisconnected && status["lastError"] != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the sync to disconnect, last: %v", result)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// null stops the sync
+	result, rpcErr = h.route(ctx, "ns.edgeSync.set", []interface{}{nil})
+	if rpcErr != nil || result.(map[string]interface{})["enabled"] != false {
+		t.Errorf("Expected edge sync to be disabled, got %v (%v)", result, rpcErr)
+	}
+}
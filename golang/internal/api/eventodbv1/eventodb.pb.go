@@ -0,0 +1,930 @@
+// gRPC API of EventoDB, served on --grpc-port.
+//
+// Every call carries the namespace token as "authorization: Bearer <token>"
+// metadata, like the Authorization header of POST /rpc. Failed calls return
+// the RPC error code (e.g. STREAM_VERSION_CONFLICT) as the reason of a
+// google.rpc.ErrorInfo detail, with the error details JSON-encoded in its
+// metadata. See docs/API.md for the methods and their errors.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v5.28.3
+// source: eventodb/v1/eventodb.proto
+
+package eventodbv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CallRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Method        string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"` // e.g. "ns.info"
+	Args          []*structpb.Value      `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`     // Arguments after the method name
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallRequest) Reset() {
+	*x = CallRequest{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallRequest) ProtoMessage() {}
+
+func (x *CallRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallRequest.ProtoReflect.Descriptor instead.
+func (*CallRequest) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CallRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *CallRequest) GetArgs() []*structpb.Value {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type CallResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *structpb.Value        `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallResponse) Reset() {
+	*x = CallResponse{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallResponse) ProtoMessage() {}
+
+func (x *CallResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallResponse.ProtoReflect.Descriptor instead.
+func (*CallResponse) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CallResponse) GetResult() *structpb.Value {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type Message struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type           string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Position       int64                  `protobuf:"varint,3,opt,name=position,proto3" json:"position,omitempty"`
+	GlobalPosition int64                  `protobuf:"varint,4,opt,name=global_position,json=globalPosition,proto3" json:"global_position,omitempty"`
+	Data           *structpb.Struct       `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	Metadata       *structpb.Struct       `protobuf:"bytes,6,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Time           string                 `protobuf:"bytes,7,opt,name=time,proto3" json:"time,omitempty"`                               // RFC 3339, UTC
+	StreamName     string                 `protobuf:"bytes,8,opt,name=stream_name,json=streamName,proto3" json:"stream_name,omitempty"` // Set by GetCategory
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Message) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Message) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Message) GetPosition() int64 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *Message) GetGlobalPosition() int64 {
+	if x != nil {
+		return x.GlobalPosition
+	}
+	return 0
+}
+
+func (x *Message) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Message) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Message) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *Message) GetStreamName() string {
+	if x != nil {
+		return x.StreamName
+	}
+	return ""
+}
+
+type WriteRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	StreamName      string                 `protobuf:"bytes,1,opt,name=stream_name,json=streamName,proto3" json:"stream_name,omitempty"`
+	Type            string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Data            *structpb.Struct       `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Metadata        *structpb.Struct       `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Id              string                 `protobuf:"bytes,5,opt,name=id,proto3" json:"id,omitempty"` // Generated when empty
+	ExpectedVersion *int64                 `protobuf:"varint,6,opt,name=expected_version,json=expectedVersion,proto3,oneof" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WriteRequest) Reset() {
+	*x = WriteRequest{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteRequest) ProtoMessage() {}
+
+func (x *WriteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteRequest.ProtoReflect.Descriptor instead.
+func (*WriteRequest) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WriteRequest) GetStreamName() string {
+	if x != nil {
+		return x.StreamName
+	}
+	return ""
+}
+
+func (x *WriteRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *WriteRequest) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *WriteRequest) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *WriteRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *WriteRequest) GetExpectedVersion() int64 {
+	if x != nil && x.ExpectedVersion != nil {
+		return *x.ExpectedVersion
+	}
+	return 0
+}
+
+type WriteResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Position       int64                  `protobuf:"varint,1,opt,name=position,proto3" json:"position,omitempty"`
+	GlobalPosition int64                  `protobuf:"varint,2,opt,name=global_position,json=globalPosition,proto3" json:"global_position,omitempty"`
+	Claim          string                 `protobuf:"bytes,3,opt,name=claim,proto3" json:"claim,omitempty"` // Set instead of the positions when the write was queued
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *WriteResponse) Reset() {
+	*x = WriteResponse{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteResponse) ProtoMessage() {}
+
+func (x *WriteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteResponse.ProtoReflect.Descriptor instead.
+func (*WriteResponse) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WriteResponse) GetPosition() int64 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *WriteResponse) GetGlobalPosition() int64 {
+	if x != nil {
+		return x.GlobalPosition
+	}
+	return 0
+}
+
+func (x *WriteResponse) GetClaim() string {
+	if x != nil {
+		return x.Claim
+	}
+	return ""
+}
+
+type GetStreamRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	StreamName     string                 `protobuf:"bytes,1,opt,name=stream_name,json=streamName,proto3" json:"stream_name,omitempty"`
+	Position       int64                  `protobuf:"varint,2,opt,name=position,proto3" json:"position,omitempty"`
+	GlobalPosition *int64                 `protobuf:"varint,3,opt,name=global_position,json=globalPosition,proto3,oneof" json:"global_position,omitempty"` // Alternative to position
+	BatchSize      int64                  `protobuf:"varint,4,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`                      // 0 for 1000, -1 for the whole stream
+	Decode         bool                   `protobuf:"varint,5,opt,name=decode,proto3" json:"decode,omitempty"`                                             // Decode payloads with read plugins
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetStreamRequest) Reset() {
+	*x = GetStreamRequest{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStreamRequest) ProtoMessage() {}
+
+func (x *GetStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStreamRequest.ProtoReflect.Descriptor instead.
+func (*GetStreamRequest) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetStreamRequest) GetStreamName() string {
+	if x != nil {
+		return x.StreamName
+	}
+	return ""
+}
+
+func (x *GetStreamRequest) GetPosition() int64 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *GetStreamRequest) GetGlobalPosition() int64 {
+	if x != nil && x.GlobalPosition != nil {
+		return *x.GlobalPosition
+	}
+	return 0
+}
+
+func (x *GetStreamRequest) GetBatchSize() int64 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *GetStreamRequest) GetDecode() bool {
+	if x != nil {
+		return x.Decode
+	}
+	return false
+}
+
+type GetCategoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Category      string                 `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"`
+	Position      int64                  `protobuf:"varint,2,opt,name=position,proto3" json:"position,omitempty"`                    // Global position
+	BatchSize     int64                  `protobuf:"varint,3,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"` // 0 for 1000
+	Correlation   string                 `protobuf:"bytes,4,opt,name=correlation,proto3" json:"correlation,omitempty"`               // Only messages correlated with this category
+	Where         string                 `protobuf:"bytes,5,opt,name=where,proto3" json:"where,omitempty"`                           // EQL expression messages must match
+	ConsumerGroup *ConsumerGroup         `protobuf:"bytes,6,opt,name=consumer_group,json=consumerGroup,proto3" json:"consumer_group,omitempty"`
+	Decode        bool                   `protobuf:"varint,7,opt,name=decode,proto3" json:"decode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCategoryRequest) Reset() {
+	*x = GetCategoryRequest{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCategoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCategoryRequest) ProtoMessage() {}
+
+func (x *GetCategoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCategoryRequest.ProtoReflect.Descriptor instead.
+func (*GetCategoryRequest) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetCategoryRequest) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *GetCategoryRequest) GetPosition() int64 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *GetCategoryRequest) GetBatchSize() int64 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *GetCategoryRequest) GetCorrelation() string {
+	if x != nil {
+		return x.Correlation
+	}
+	return ""
+}
+
+func (x *GetCategoryRequest) GetWhere() string {
+	if x != nil {
+		return x.Where
+	}
+	return ""
+}
+
+func (x *GetCategoryRequest) GetConsumerGroup() *ConsumerGroup {
+	if x != nil {
+		return x.ConsumerGroup
+	}
+	return nil
+}
+
+func (x *GetCategoryRequest) GetDecode() bool {
+	if x != nil {
+		return x.Decode
+	}
+	return false
+}
+
+type ConsumerGroup struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Member        int64                  `protobuf:"varint,1,opt,name=member,proto3" json:"member,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Partitioner   string                 `protobuf:"bytes,3,opt,name=partitioner,proto3" json:"partitioner,omitempty"` // Default md5
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsumerGroup) Reset() {
+	*x = ConsumerGroup{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsumerGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsumerGroup) ProtoMessage() {}
+
+func (x *ConsumerGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsumerGroup.ProtoReflect.Descriptor instead.
+func (*ConsumerGroup) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ConsumerGroup) GetMember() int64 {
+	if x != nil {
+		return x.Member
+	}
+	return 0
+}
+
+func (x *ConsumerGroup) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ConsumerGroup) GetPartitioner() string {
+	if x != nil {
+		return x.Partitioner
+	}
+	return ""
+}
+
+type SubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Target:
+	//
+	//	*SubscribeRequest_Stream
+	//	*SubscribeRequest_Category
+	//	*SubscribeRequest_All
+	Target        isSubscribeRequest_Target `protobuf_oneof:"target"`
+	Position      int64                     `protobuf:"varint,4,opt,name=position,proto3" json:"position,omitempty"` // Stream position, or global position for categories
+	ConsumerGroup *ConsumerGroup            `protobuf:"bytes,5,opt,name=consumer_group,json=consumerGroup,proto3" json:"consumer_group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SubscribeRequest) GetTarget() isSubscribeRequest_Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *SubscribeRequest) GetStream() string {
+	if x != nil {
+		if x, ok := x.Target.(*SubscribeRequest_Stream); ok {
+			return x.Stream
+		}
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetCategory() string {
+	if x != nil {
+		if x, ok := x.Target.(*SubscribeRequest_Category); ok {
+			return x.Category
+		}
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetAll() bool {
+	if x != nil {
+		if x, ok := x.Target.(*SubscribeRequest_All); ok {
+			return x.All
+		}
+	}
+	return false
+}
+
+func (x *SubscribeRequest) GetPosition() int64 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetConsumerGroup() *ConsumerGroup {
+	if x != nil {
+		return x.ConsumerGroup
+	}
+	return nil
+}
+
+type isSubscribeRequest_Target interface {
+	isSubscribeRequest_Target()
+}
+
+type SubscribeRequest_Stream struct {
+	Stream string `protobuf:"bytes,1,opt,name=stream,proto3,oneof"`
+}
+
+type SubscribeRequest_Category struct {
+	Category string `protobuf:"bytes,2,opt,name=category,proto3,oneof"`
+}
+
+type SubscribeRequest_All struct {
+	All bool `protobuf:"varint,3,opt,name=all,proto3,oneof"`
+}
+
+func (*SubscribeRequest_Stream) isSubscribeRequest_Target() {}
+
+func (*SubscribeRequest_Category) isSubscribeRequest_Target() {}
+
+func (*SubscribeRequest_All) isSubscribeRequest_Target() {}
+
+type Poke struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Stream         string                 `protobuf:"bytes,1,opt,name=stream,proto3" json:"stream,omitempty"`
+	Position       int64                  `protobuf:"varint,2,opt,name=position,proto3" json:"position,omitempty"`
+	GlobalPosition int64                  `protobuf:"varint,3,opt,name=global_position,json=globalPosition,proto3" json:"global_position,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Poke) Reset() {
+	*x = Poke{}
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Poke) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Poke) ProtoMessage() {}
+
+func (x *Poke) ProtoReflect() protoreflect.Message {
+	mi := &file_eventodb_v1_eventodb_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Poke.ProtoReflect.Descriptor instead.
+func (*Poke) Descriptor() ([]byte, []int) {
+	return file_eventodb_v1_eventodb_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Poke) GetStream() string {
+	if x != nil {
+		return x.Stream
+	}
+	return ""
+}
+
+func (x *Poke) GetPosition() int64 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *Poke) GetGlobalPosition() int64 {
+	if x != nil {
+		return x.GlobalPosition
+	}
+	return 0
+}
+
+var File_eventodb_v1_eventodb_proto protoreflect.FileDescriptor
+
+const file_eventodb_v1_eventodb_proto_rawDesc = "" +
+	"\n" +
+	"\x1aeventodb/v1/eventodb.proto\x12\veventodb.v1\x1a\x1cgoogle/protobuf/struct.proto\"Q\n" +
+	"\vCallRequest\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12*\n" +
+	"\x04args\x18\x02 \x03(\v2\x16.google.protobuf.ValueR\x04args\">\n" +
+	"\fCallResponse\x12.\n" +
+	"\x06result\x18\x01 \x01(\v2\x16.google.protobuf.ValueR\x06result\"\x89\x02\n" +
+	"\aMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1a\n" +
+	"\bposition\x18\x03 \x01(\x03R\bposition\x12'\n" +
+	"\x0fglobal_position\x18\x04 \x01(\x03R\x0eglobalPosition\x12+\n" +
+	"\x04data\x18\x05 \x01(\v2\x17.google.protobuf.StructR\x04data\x123\n" +
+	"\bmetadata\x18\x06 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12\x12\n" +
+	"\x04time\x18\a \x01(\tR\x04time\x12\x1f\n" +
+	"\vstream_name\x18\b \x01(\tR\n" +
+	"streamName\"\xfa\x01\n" +
+	"\fWriteRequest\x12\x1f\n" +
+	"\vstream_name\x18\x01 \x01(\tR\n" +
+	"streamName\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12+\n" +
+	"\x04data\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x04data\x123\n" +
+	"\bmetadata\x18\x04 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12\x0e\n" +
+	"\x02id\x18\x05 \x01(\tR\x02id\x12.\n" +
+	"\x10expected_version\x18\x06 \x01(\x03H\x00R\x0fexpectedVersion\x88\x01\x01B\x13\n" +
+	"\x11_expected_version\"j\n" +
+	"\rWriteResponse\x12\x1a\n" +
+	"\bposition\x18\x01 \x01(\x03R\bposition\x12'\n" +
+	"\x0fglobal_position\x18\x02 \x01(\x03R\x0eglobalPosition\x12\x14\n" +
+	"\x05claim\x18\x03 \x01(\tR\x05claim\"\xc8\x01\n" +
+	"\x10GetStreamRequest\x12\x1f\n" +
+	"\vstream_name\x18\x01 \x01(\tR\n" +
+	"streamName\x12\x1a\n" +
+	"\bposition\x18\x02 \x01(\x03R\bposition\x12,\n" +
+	"\x0fglobal_position\x18\x03 \x01(\x03H\x00R\x0eglobalPosition\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x04 \x01(\x03R\tbatchSize\x12\x16\n" +
+	"\x06decode\x18\x05 \x01(\bR\x06decodeB\x12\n" +
+	"\x10_global_position\"\xfe\x01\n" +
+	"\x12GetCategoryRequest\x12\x1a\n" +
+	"\bcategory\x18\x01 \x01(\tR\bcategory\x12\x1a\n" +
+	"\bposition\x18\x02 \x01(\x03R\bposition\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x03 \x01(\x03R\tbatchSize\x12 \n" +
+	"\vcorrelation\x18\x04 \x01(\tR\vcorrelation\x12\x14\n" +
+	"\x05where\x18\x05 \x01(\tR\x05where\x12A\n" +
+	"\x0econsumer_group\x18\x06 \x01(\v2\x1a.eventodb.v1.ConsumerGroupR\rconsumerGroup\x12\x16\n" +
+	"\x06decode\x18\a \x01(\bR\x06decode\"]\n" +
+	"\rConsumerGroup\x12\x16\n" +
+	"\x06member\x18\x01 \x01(\x03R\x06member\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12 \n" +
+	"\vpartitioner\x18\x03 \x01(\tR\vpartitioner\"\xc7\x01\n" +
+	"\x10SubscribeRequest\x12\x18\n" +
+	"\x06stream\x18\x01 \x01(\tH\x00R\x06stream\x12\x1c\n" +
+	"\bcategory\x18\x02 \x01(\tH\x00R\bcategory\x12\x12\n" +
+	"\x03all\x18\x03 \x01(\bH\x00R\x03all\x12\x1a\n" +
+	"\bposition\x18\x04 \x01(\x03R\bposition\x12A\n" +
+	"\x0econsumer_group\x18\x05 \x01(\v2\x1a.eventodb.v1.ConsumerGroupR\rconsumerGroupB\b\n" +
+	"\x06target\"c\n" +
+	"\x04Poke\x12\x16\n" +
+	"\x06stream\x18\x01 \x01(\tR\x06stream\x12\x1a\n" +
+	"\bposition\x18\x02 \x01(\x03R\bposition\x12'\n" +
+	"\x0fglobal_position\x18\x03 \x01(\x03R\x0eglobalPosition2\xd4\x02\n" +
+	"\bEventoDB\x12;\n" +
+	"\x04Call\x12\x18.eventodb.v1.CallRequest\x1a\x19.eventodb.v1.CallResponse\x12>\n" +
+	"\x05Write\x12\x19.eventodb.v1.WriteRequest\x1a\x1a.eventodb.v1.WriteResponse\x12B\n" +
+	"\tGetStream\x12\x1d.eventodb.v1.GetStreamRequest\x1a\x14.eventodb.v1.Message0\x01\x12F\n" +
+	"\vGetCategory\x12\x1f.eventodb.v1.GetCategoryRequest\x1a\x14.eventodb.v1.Message0\x01\x12?\n" +
+	"\tSubscribe\x12\x1d.eventodb.v1.SubscribeRequest\x1a\x11.eventodb.v1.Poke0\x01BAZ?github.com/eventodb/eventodb/internal/api/eventodbv1;eventodbv1b\x06proto3"
+
+var (
+	file_eventodb_v1_eventodb_proto_rawDescOnce sync.Once
+	file_eventodb_v1_eventodb_proto_rawDescData []byte
+)
+
+func file_eventodb_v1_eventodb_proto_rawDescGZIP() []byte {
+	file_eventodb_v1_eventodb_proto_rawDescOnce.Do(func() {
+		file_eventodb_v1_eventodb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_eventodb_v1_eventodb_proto_rawDesc), len(file_eventodb_v1_eventodb_proto_rawDesc)))
+	})
+	return file_eventodb_v1_eventodb_proto_rawDescData
+}
+
+var file_eventodb_v1_eventodb_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_eventodb_v1_eventodb_proto_goTypes = []any{
+	(*CallRequest)(nil),        // 0: eventodb.v1.CallRequest
+	(*CallResponse)(nil),       // 1: eventodb.v1.CallResponse
+	(*Message)(nil),            // 2: eventodb.v1.Message
+	(*WriteRequest)(nil),       // 3: eventodb.v1.WriteRequest
+	(*WriteResponse)(nil),      // 4: eventodb.v1.WriteResponse
+	(*GetStreamRequest)(nil),   // 5: eventodb.v1.GetStreamRequest
+	(*GetCategoryRequest)(nil), // 6: eventodb.v1.GetCategoryRequest
+	(*ConsumerGroup)(nil),      // 7: eventodb.v1.ConsumerGroup
+	(*SubscribeRequest)(nil),   // 8: eventodb.v1.SubscribeRequest
+	(*Poke)(nil),               // 9: eventodb.v1.Poke
+	(*structpb.Value)(nil),     // 10: google.protobuf.Value
+	(*structpb.Struct)(nil),    // 11: google.protobuf.Struct
+}
+var file_eventodb_v1_eventodb_proto_depIdxs = []int32{
+	10, // 0: eventodb.v1.CallRequest.args:type_name -> google.protobuf.Value
+	10, // 1: eventodb.v1.CallResponse.result:type_name -> google.protobuf.Value
+	11, // 2: eventodb.v1.Message.data:type_name -> google.protobuf.Struct
+	11, // 3: eventodb.v1.Message.metadata:type_name -> google.protobuf.Struct
+	11, // 4: eventodb.v1.WriteRequest.data:type_name -> google.protobuf.Struct
+	11, // 5: eventodb.v1.WriteRequest.metadata:type_name -> google.protobuf.Struct
+	7,  // 6: eventodb.v1.GetCategoryRequest.consumer_group:type_name -> eventodb.v1.ConsumerGroup
+	7,  // 7: eventodb.v1.SubscribeRequest.consumer_group:type_name -> eventodb.v1.ConsumerGroup
+	0,  // 8: eventodb.v1.EventoDB.Call:input_type -> eventodb.v1.CallRequest
+	3,  // 9: eventodb.v1.EventoDB.Write:input_type -> eventodb.v1.WriteRequest
+	5,  // 10: eventodb.v1.EventoDB.GetStream:input_type -> eventodb.v1.GetStreamRequest
+	6,  // 11: eventodb.v1.EventoDB.GetCategory:input_type -> eventodb.v1.GetCategoryRequest
+	8,  // 12: eventodb.v1.EventoDB.Subscribe:input_type -> eventodb.v1.SubscribeRequest
+	1,  // 13: eventodb.v1.EventoDB.Call:output_type -> eventodb.v1.CallResponse
+	4,  // 14: eventodb.v1.EventoDB.Write:output_type -> eventodb.v1.WriteResponse
+	2,  // 15: eventodb.v1.EventoDB.GetStream:output_type -> eventodb.v1.Message
+	2,  // 16: eventodb.v1.EventoDB.GetCategory:output_type -> eventodb.v1.Message
+	9,  // 17: eventodb.v1.EventoDB.Subscribe:output_type -> eventodb.v1.Poke
+	13, // [13:18] is the sub-list for method output_type
+	8,  // [8:13] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_eventodb_v1_eventodb_proto_init() }
+func file_eventodb_v1_eventodb_proto_init() {
+	if File_eventodb_v1_eventodb_proto != nil {
+		return
+	}
+	file_eventodb_v1_eventodb_proto_msgTypes[3].OneofWrappers = []any{}
+	file_eventodb_v1_eventodb_proto_msgTypes[5].OneofWrappers = []any{}
+	file_eventodb_v1_eventodb_proto_msgTypes[8].OneofWrappers = []any{
+		(*SubscribeRequest_Stream)(nil),
+		(*SubscribeRequest_Category)(nil),
+		(*SubscribeRequest_All)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_eventodb_v1_eventodb_proto_rawDesc), len(file_eventodb_v1_eventodb_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_eventodb_v1_eventodb_proto_goTypes,
+		DependencyIndexes: file_eventodb_v1_eventodb_proto_depIdxs,
+		MessageInfos:      file_eventodb_v1_eventodb_proto_msgTypes,
+	}.Build()
+	File_eventodb_v1_eventodb_proto = out.File
+	file_eventodb_v1_eventodb_proto_goTypes = nil
+	file_eventodb_v1_eventodb_proto_depIdxs = nil
+}
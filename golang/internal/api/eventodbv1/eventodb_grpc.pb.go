@@ -0,0 +1,307 @@
+// gRPC API of EventoDB, served on --grpc-port.
+//
+// Every call carries the namespace token as "authorization: Bearer <token>"
+// metadata, like the Authorization header of POST /rpc. Failed calls return
+// the RPC error code (e.g. STREAM_VERSION_CONFLICT) as the reason of a
+// google.rpc.ErrorInfo detail, with the error details JSON-encoded in its
+// metadata. See docs/API.md for the methods and their errors.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: eventodb/v1/eventodb.proto
+
+package eventodbv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	EventoDB_Call_FullMethodName        = "/eventodb.v1.EventoDB/Call"
+	EventoDB_Write_FullMethodName       = "/eventodb.v1.EventoDB/Write"
+	EventoDB_GetStream_FullMethodName   = "/eventodb.v1.EventoDB/GetStream"
+	EventoDB_GetCategory_FullMethodName = "/eventodb.v1.EventoDB/GetCategory"
+	EventoDB_Subscribe_FullMethodName   = "/eventodb.v1.EventoDB/Subscribe"
+)
+
+// EventoDBClient is the client API for EventoDB service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EventoDBClient interface {
+	// Call invokes any RPC method, e.g. ns.create or stream.casWrite, with the
+	// arguments of its JSON request and returns its JSON result
+	Call(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (*CallResponse, error)
+	// Write appends a message to a stream (stream.write)
+	Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	// GetStream reads the messages of a stream (stream.get). With batch_size
+	// -1 the stream is read to its end, a page at a time.
+	GetStream(ctx context.Context, in *GetStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error)
+	// GetCategory reads the messages of a category (category.get)
+	GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error)
+	// Subscribe sends a poke for each message written to a stream, a category
+	// or the whole namespace, like GET /subscribe
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Poke], error)
+}
+
+type eventoDBClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEventoDBClient(cc grpc.ClientConnInterface) EventoDBClient {
+	return &eventoDBClient{cc}
+}
+
+func (c *eventoDBClient) Call(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (*CallResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CallResponse)
+	err := c.cc.Invoke(ctx, EventoDB_Call_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventoDBClient) Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, EventoDB_Write_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventoDBClient) GetStream(ctx context.Context, in *GetStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EventoDB_ServiceDesc.Streams[0], EventoDB_GetStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetStreamRequest, Message]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EventoDB_GetStreamClient = grpc.ServerStreamingClient[Message]
+
+func (c *eventoDBClient) GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EventoDB_ServiceDesc.Streams[1], EventoDB_GetCategory_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetCategoryRequest, Message]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EventoDB_GetCategoryClient = grpc.ServerStreamingClient[Message]
+
+func (c *eventoDBClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Poke], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EventoDB_ServiceDesc.Streams[2], EventoDB_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, Poke]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EventoDB_SubscribeClient = grpc.ServerStreamingClient[Poke]
+
+// EventoDBServer is the server API for EventoDB service.
+// All implementations must embed UnimplementedEventoDBServer
+// for forward compatibility.
+type EventoDBServer interface {
+	// Call invokes any RPC method, e.g. ns.create or stream.casWrite, with the
+	// arguments of its JSON request and returns its JSON result
+	Call(context.Context, *CallRequest) (*CallResponse, error)
+	// Write appends a message to a stream (stream.write)
+	Write(context.Context, *WriteRequest) (*WriteResponse, error)
+	// GetStream reads the messages of a stream (stream.get). With batch_size
+	// -1 the stream is read to its end, a page at a time.
+	GetStream(*GetStreamRequest, grpc.ServerStreamingServer[Message]) error
+	// GetCategory reads the messages of a category (category.get)
+	GetCategory(*GetCategoryRequest, grpc.ServerStreamingServer[Message]) error
+	// Subscribe sends a poke for each message written to a stream, a category
+	// or the whole namespace, like GET /subscribe
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Poke]) error
+	mustEmbedUnimplementedEventoDBServer()
+}
+
+// UnimplementedEventoDBServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEventoDBServer struct{}
+
+func (UnimplementedEventoDBServer) Call(context.Context, *CallRequest) (*CallResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Call not implemented")
+}
+func (UnimplementedEventoDBServer) Write(context.Context, *WriteRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Write not implemented")
+}
+func (UnimplementedEventoDBServer) GetStream(*GetStreamRequest, grpc.ServerStreamingServer[Message]) error {
+	return status.Errorf(codes.Unimplemented, "method GetStream not implemented")
+}
+func (UnimplementedEventoDBServer) GetCategory(*GetCategoryRequest, grpc.ServerStreamingServer[Message]) error {
+	return status.Errorf(codes.Unimplemented, "method GetCategory not implemented")
+}
+func (UnimplementedEventoDBServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Poke]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedEventoDBServer) mustEmbedUnimplementedEventoDBServer() {}
+func (UnimplementedEventoDBServer) testEmbeddedByValue()                  {}
+
+// UnsafeEventoDBServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EventoDBServer will
+// result in compilation errors.
+type UnsafeEventoDBServer interface {
+	mustEmbedUnimplementedEventoDBServer()
+}
+
+func RegisterEventoDBServer(s grpc.ServiceRegistrar, srv EventoDBServer) {
+	// If the following call pancis, it indicates UnimplementedEventoDBServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&EventoDB_ServiceDesc, srv)
+}
+
+func _EventoDB_Call_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CallRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventoDBServer).Call(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventoDB_Call_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventoDBServer).Call(ctx, req.(*CallRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventoDB_Write_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventoDBServer).Write(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventoDB_Write_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventoDBServer).Write(ctx, req.(*WriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventoDB_GetStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EventoDBServer).GetStream(m, &grpc.GenericServerStream[GetStreamRequest, Message]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EventoDB_GetStreamServer = grpc.ServerStreamingServer[Message]
+
+func _EventoDB_GetCategory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetCategoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EventoDBServer).GetCategory(m, &grpc.GenericServerStream[GetCategoryRequest, Message]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EventoDB_GetCategoryServer = grpc.ServerStreamingServer[Message]
+
+func _EventoDB_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EventoDBServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, Poke]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EventoDB_SubscribeServer = grpc.ServerStreamingServer[Poke]
+
+// EventoDB_ServiceDesc is the grpc.ServiceDesc for EventoDB service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EventoDB_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "eventodb.v1.EventoDB",
+	HandlerType: (*EventoDBServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Call",
+			Handler:    _EventoDB_Call_Handler,
+		},
+		{
+			MethodName: "Write",
+			Handler:    _EventoDB_Write_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetStream",
+			Handler:       _EventoDB_GetStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetCategory",
+			Handler:       _EventoDB_GetCategory_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _EventoDB_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "eventodb/v1/eventodb.proto",
+}
@@ -0,0 +1,326 @@
+// Package api provides HTTP streaming export, optionally following new writes.
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// exportBatchSize is the number of messages read per category query
+	exportBatchSize = 1000
+
+	// exportFollowInterval is how often a following export polls without a
+	// write notification. An idle export also writes an empty line then, so
+	// a client that went away is noticed.
+	exportFollowInterval = 15 * time.Second
+)
+
+// ExportHandler streams the caller's namespace as NDJSON in the import
+// format. With follow=true it keeps the response open after the historical
+// records and streams new matching records as they are written.
+type ExportHandler struct {
+	store  store.Store
+	pubsub *PubSub // Optional, wakes following exports on writes
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(st store.Store, pubsub *PubSub) *ExportHandler {
+	return &ExportHandler{
+		store:  st,
+		pubsub: pubsub,
+	}
+}
+
+// errInvalidExport is returned for /export parameters that cannot be combined
+var errInvalidExport = errors.New("invalid export parameters")
+
+// exportRequest holds the parsed /export query parameters
+type exportRequest struct {
+	categories []string // An empty category exports every stream
+	from       int64    // Global position to start at
+	follow     bool
+	end        bool               // from=end, export only records written after the request
+	priority   store.ReadPriority // Scheduling of the export's reads, low by default
+	filter     *store.Filter      // Only records matching an EQL expression
+	sample     float64            // Fraction of records by ID hash, 0 for all
+}
+
+// parseExportRequest parses the categories, from, follow, priority, where and
+// sample parameters
+func (h *ExportHandler) parseExportRequest(ctx context.Context, namespace string, get func(string) string) (*exportRequest, error) {
+	req := &exportRequest{follow: get("follow") == "true", priority: store.ReadPriorityLow}
+	if priority := get("priority"); priority != "" {
+		p, err := store.ParseReadPriority(priority)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidExport, err)
+		}
+		req.priority = p
+	}
+	if where := get("where"); where != "" {
+		filter, err := store.ParseFilter(where)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidExport, err)
+		}
+		req.filter = filter
+	}
+	if sample := get("sample"); sample != "" {
+		rate, err := strconv.ParseFloat(sample, 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return nil, fmt.Errorf("%w: sample must be a number greater than 0 and at most 1", errInvalidExport)
+		}
+		req.sample = rate
+	}
+	for _, c := range strings.Split(get("categories"), ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			req.categories = append(req.categories, c)
+		}
+	}
+	if len(req.categories) == 0 {
+		req.categories = []string{""}
+	}
+
+	if from := get("from"); from == "end" {
+		if !req.follow {
+			return nil, fmt.Errorf("%w: from=end requires follow=true", errInvalidExport)
+		}
+		req.end = true
+	} else if from != "" {
+		pos, err := ParsePosition(ctx, h.store, namespace, from)
+		if err != nil {
+			return nil, err
+		}
+		// Negative positions would export nothing, or follow from the end
+		if pos < 0 {
+			return nil, fmt.Errorf("%w: from must be a global position, a bookmark or end", errInvalidExport)
+		}
+		req.from = pos
+	}
+	return req, nil
+}
+
+// HandleExport handles GET /export requests
+func (h *ExportHandler) HandleExport(ctx *fasthttp.RequestCtx) {
+	namespace, ok := GetNamespaceFromFastHTTP(ctx)
+	if !ok {
+		writeExportErrorFast(ctx, fasthttp.StatusUnauthorized, &RPCError{Code: "AUTH_REQUIRED", Message: "Namespace not found in context"})
+		return
+	}
+
+	req, err := h.parseExportRequest(ctx, namespace, func(name string) string {
+		return string(ctx.QueryArgs().Peek(name))
+	})
+	if err != nil {
+		status, rpcErr := exportRequestError(err)
+		writeExportErrorFast(ctx, status, rpcErr)
+		return
+	}
+
+	ctx.SetContentType("application/x-ndjson")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		h.export(context.Background(), w, w.Flush, namespace, req)
+	})
+}
+
+// ServeHTTP implements http.Handler for net/http compatibility (used in tests)
+func (h *ExportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	namespace, ok := GetNamespaceFromContext(r.Context())
+	if !ok {
+		if IsTestMode(r.Context()) {
+			namespace = "default"
+		} else {
+			writeExportHTTPError(w, http.StatusUnauthorized, &RPCError{Code: "AUTH_REQUIRED", Message: "Namespace not found in context"})
+			return
+		}
+	}
+
+	req, err := h.parseExportRequest(r.Context(), namespace, r.URL.Query().Get)
+	if err != nil {
+		status, rpcErr := exportRequestError(err)
+		writeExportHTTPError(w, status, rpcErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	flush := func() error {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+	h.export(r.Context(), w, flush, namespace, req)
+}
+
+// export writes the records of req to w. Following exports then stream new
+// records until ctx is done or the client goes away. A failure after the
+// response has started is reported as a final {"error", "message"} line.
+func (h *ExportHandler) export(ctx context.Context, w io.Writer, flush func() error, namespace string, req *exportRequest) {
+	ctx = store.WithReadPriority(ctx, req.priority)
+
+	// Subscribe before reading, so writes made meanwhile are not missed
+	var events Subscriber
+	if req.follow && h.pubsub != nil {
+		events = h.pubsub.SubscribeAll(namespace)
+		defer h.pubsub.UnsubscribeAll(namespace, events)
+	}
+
+	// Send the headers now, so clients of an empty export are not kept waiting
+	if err := flush(); err != nil {
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	next := make([]int64, len(req.categories)) // Next global position per category
+	for i := range next {
+		next[i] = req.from
+		if req.end {
+			next[i] = -1 // Set by the first write to the category
+		}
+	}
+
+	// drain writes everything after the current positions and reports
+	// whether anything was written. Read failures are logged and reported to
+	// the client; write failures mean it went away.
+	drain := func() (bool, error) {
+		var wrote bool
+		for i, category := range req.categories {
+			for next[i] >= 0 {
+				msgs, err := h.store.GetCategoryMessages(ctx, namespace, category, &store.CategoryOpts{
+					Position:  next[i],
+					BatchSize: exportBatchSize,
+					Filter:    req.filter,
+					Sample:    req.sample,
+				})
+				if err != nil {
+					logger.Get().Warn().Err(err).Str("namespace", namespace).Msg("Export failed")
+					enc.Encode(map[string]string{"error": "EXPORT_FAILED", "message": err.Error()})
+					flush()
+					return wrote, err
+				}
+				if len(msgs) == 0 {
+					break
+				}
+				for _, msg := range msgs {
+					if err := enc.Encode(exportRecord(msg)); err != nil {
+						return wrote, err
+					}
+				}
+				next[i] = msgs[len(msgs)-1].GlobalPosition + 1
+				wrote = true
+				if err := flush(); err != nil {
+					return wrote, err
+				}
+			}
+		}
+		return wrote, nil
+	}
+
+	if _, err := drain(); err != nil || !req.follow {
+		return
+	}
+
+	ticker := time.NewTicker(exportFollowInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return // Server shutdown
+			}
+			matched := false
+			for i, c := range req.categories {
+				if c == "" || c == event.Category {
+					matched = true
+					if next[i] < 0 {
+						next[i] = event.GlobalPosition
+					}
+				}
+			}
+			if !matched {
+				continue
+			}
+			if _, err := drain(); err != nil {
+				return
+			}
+		case <-ticker.C:
+			wrote, err := drain()
+			if err != nil {
+				return
+			}
+			if !wrote {
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return
+				}
+				if err := flush(); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// exportRecord converts a message to the export format
+func exportRecord(msg *store.Message) *ExportRecord {
+	return &ExportRecord{
+		ID:       msg.ID,
+		Stream:   msg.StreamName,
+		Type:     msg.Type,
+		Position: msg.Position,
+		GPos:     msg.GlobalPosition,
+		Data:     msg.Data,
+		Meta:     msg.Metadata,
+		Time:     msg.Time.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// exportRequestError maps a parameter error to an HTTP status and RPC error
+func exportRequestError(err error) (int, *RPCError) {
+	switch {
+	case errors.Is(err, errInvalidExport):
+		return http.StatusBadRequest, &RPCError{Code: "INVALID_REQUEST", Message: err.Error()}
+	case errors.Is(err, ErrBookmarkNotFound):
+		return http.StatusNotFound, &RPCError{Code: "BOOKMARK_NOT_FOUND", Message: err.Error()}
+	case store.IsOverloaded(err):
+		return http.StatusServiceUnavailable, overloadedError(err)
+	}
+	return http.StatusInternalServerError, &RPCError{Code: "BACKEND_ERROR", Message: err.Error()}
+}
+
+// writeExportErrorFast writes a JSON error response, with Retry-After for
+// errors that carry one
+func writeExportErrorFast(ctx *fasthttp.RequestCtx, statusCode int, rpcErr *RPCError) {
+	if seconds, ok := retryAfter(rpcErr); ok {
+		ctx.Response.Header.Set("Retry-After", strconv.Itoa(seconds))
+	}
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(statusCode)
+	json.NewEncoder(ctx).Encode(ErrorResponse{Error: rpcErr})
+}
+
+// writeExportHTTPError writes a JSON error response, with Retry-After for
+// errors that carry one
+func writeExportHTTPError(w http.ResponseWriter, statusCode int, rpcErr *RPCError) {
+	if seconds, ok := retryAfter(rpcErr); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: rpcErr})
+}
@@ -0,0 +1,608 @@
+// Package api provides scheduled exports of namespaces to backup destinations.
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// Namespace metadata keys holding the export schedule and its last run
+	exportScheduleMetadataKey       = "exportSchedule"
+	exportScheduleStatusMetadataKey = "exportScheduleStatus"
+
+	// ExportScheduleStream records scheduled exports in the exported namespace
+	ExportScheduleStream = "eventodb:exports"
+
+	// exportScheduleDefaultKeep is the number of archives kept in a directory
+	exportScheduleDefaultKeep = 7
+
+	// exportScheduleTick is how often the scheduler looks for due exports
+	exportScheduleTick = 15 * time.Second
+
+	// exportScheduleTimeout bounds one upload to S3
+	exportScheduleTimeout = 30 * time.Minute
+)
+
+// Export schedule destination types
+const (
+	ExportToDir = "dir"
+	ExportToS3  = "s3"
+)
+
+var (
+	// ErrExportSchedulingDisabled is returned when the server was started
+	// without an export scheduler
+	ErrExportSchedulingDisabled = errors.New("scheduled exports are not enabled")
+
+	// ErrExportDirDisabled is returned for dir destinations when the server
+	// has no export directory
+	ErrExportDirDisabled = errors.New("dir destinations require the server's --export-dir")
+
+	// ErrNoExportSchedule is returned when running a namespace's export
+	// schedule that is not configured
+	ErrNoExportSchedule = errors.New("no export schedule configured")
+)
+
+// ExportScheduleConfig describes when and where a namespace is exported.
+//
+// Each run writes one gzipped archive in the export format, starting with
+// the namespace config line, that import loads back. Dir destinations write
+// {export-dir}/{namespace}/{namespace}-{time}.ndjson.gz on the server and
+// keep the newest archives; S3 destinations upload
+// {prefix}{namespace}/{namespace}-{time}.ndjson.gz.
+type ExportScheduleConfig struct {
+	Cron      string `json:"cron"`                // Five-field cron expression (UTC) or @daily etc.
+	Type      string `json:"type"`                // dir or s3
+	Keep      int    `json:"keep,omitempty"`      // Dir: archives kept (default: 7)
+	Bucket    string `json:"bucket,omitempty"`    // S3: bucket name
+	Region    string `json:"region,omitempty"`    // S3: bucket region
+	Prefix    string `json:"prefix,omitempty"`    // S3: object key prefix
+	Endpoint  string `json:"endpoint,omitempty"`  // S3: endpoint (default: https://s3.{region}.amazonaws.com)
+	AccessKey string `json:"accessKey,omitempty"` // S3: access key ID
+	SecretKey string `json:"secretKey,omitempty"` // S3: secret access key
+}
+
+// ExportScheduleStatus reports a namespace's scheduled exports
+type ExportScheduleStatus struct {
+	NextRunAt     string `json:"nextRunAt,omitempty"`
+	LastRunAt     string `json:"lastRunAt,omitempty"`
+	LastSuccessAt string `json:"lastSuccessAt,omitempty"`
+	LastArchive   string `json:"lastArchive,omitempty"`  // File or object of the last successful export
+	LastMessages  int64  `json:"lastMessages,omitempty"` // Messages in it
+	LastBytes     int64  `json:"lastBytes,omitempty"`    // Compressed size
+	LastError     string `json:"lastError,omitempty"`    // Failure of the last run, cleared on success
+	LastErrorAt   string `json:"lastErrorAt,omitempty"`
+}
+
+// Validate checks the config and applies defaults
+func (c *ExportScheduleConfig) Validate() error {
+	if _, err := ParseCron(c.Cron); err != nil {
+		return err
+	}
+	switch c.Type {
+	case ExportToDir:
+		if c.Keep <= 0 {
+			c.Keep = exportScheduleDefaultKeep
+		}
+	case ExportToS3:
+		if c.Bucket == "" || c.Region == "" {
+			return fmt.Errorf("s3 destinations require bucket and region")
+		}
+		if c.AccessKey == "" || c.SecretKey == "" {
+			return fmt.Errorf("s3 destinations require accessKey and secretKey")
+		}
+		if c.Endpoint == "" {
+			c.Endpoint = "https://s3." + c.Region + ".amazonaws.com"
+		}
+		if err := validateShippingURL(c.Endpoint); err != nil {
+			return fmt.Errorf("endpoint: %w", err)
+		}
+	default:
+		return fmt.Errorf("type must be %q or %q", ExportToDir, ExportToS3)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with secrets masked
+func (c ExportScheduleConfig) Redacted() ExportScheduleConfig {
+	if c.SecretKey != "" {
+		c.SecretKey = logShippingRedacted
+	}
+	return c
+}
+
+// ExportScheduleFromMetadata returns the export schedule and status stored
+// in namespace metadata. Either may be nil.
+func ExportScheduleFromMetadata(metadata map[string]interface{}) (*ExportScheduleConfig, *ExportScheduleStatus) {
+	var cfg *ExportScheduleConfig
+	if raw, ok := metadata[exportScheduleMetadataKey]; ok && raw != nil {
+		var c ExportScheduleConfig
+		if decodeMetadataValue(raw, &c) == nil {
+			cfg = &c
+		}
+	}
+
+	var status *ExportScheduleStatus
+	if raw, ok := metadata[exportScheduleStatusMetadataKey]; ok && raw != nil {
+		var s ExportScheduleStatus
+		if decodeMetadataValue(raw, &s) == nil {
+			status = &s
+		}
+	}
+	return cfg, status
+}
+
+// ExportScheduler exports namespaces on the cron schedules their tenants set
+// with ns.exportSchedule.set, so small installs get backups without external
+// cron jobs. Each run, successful or not, is recorded as an ExportCompleted
+// or ExportFailed event in the namespace's eventodb:exports stream; failures
+// also go to the notifier.
+//
+// Schedules live in namespace metadata. Runs missed while the server was
+// down are not caught up; the next run is the next matching minute. Exports
+// run at low read priority, one at a time, or as jobs of the job scheduler
+// when it is set.
+type ExportScheduler struct {
+	store    store.Store
+	dir      string // Root of dir destinations, empty to reject them
+	client   *http.Client
+	notifier *Notifier
+	router   *Router // Optional, only namespaces this node owns are exported
+	jobs     *JobScheduler
+
+	// mu guards schedules
+	mu        sync.Mutex
+	schedules map[string]*exportSchedule
+
+	// runMu serializes exports without a job scheduler
+	runMu sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// exportSchedule is a namespace's parsed schedule and next run
+type exportSchedule struct {
+	cfg  ExportScheduleConfig
+	cron *CronSchedule
+	next time.Time
+}
+
+// NewExportScheduler creates a scheduler writing dir destinations below dir
+// (empty to allow only S3); call Start to begin exporting
+func NewExportScheduler(st store.Store, dir string) *ExportScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ExportScheduler{
+		store:     st,
+		dir:       dir,
+		client:    &http.Client{Timeout: exportScheduleTimeout},
+		schedules: make(map[string]*exportSchedule),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// SetNotifier routes export failures to a notifier
+func (s *ExportScheduler) SetNotifier(n *Notifier) {
+	s.notifier = n
+}
+
+// SetRouter limits scheduled runs to the namespaces this node owns, so a
+// multi-node deployment exports each namespace once
+func (s *ExportScheduler) SetRouter(r *Router) {
+	s.router = r
+}
+
+// SetJobScheduler runs each export as a job of s, so the exports of
+// different namespaces run side by side (call before Start)
+func (s *ExportScheduler) SetJobScheduler(j *JobScheduler) {
+	s.jobs = j
+}
+
+// Start loads the schedules of all namespaces and begins running them
+func (s *ExportScheduler) Start(ctx context.Context) error {
+	namespaces, err := s.store.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	for _, ns := range namespaces {
+		cfg, _ := ExportScheduleFromMetadata(ns.Metadata)
+		if cfg == nil {
+			continue
+		}
+		if err := s.check(cfg); err != nil {
+			logger.Get().Warn().Err(err).Str("namespace", ns.ID).Msg("Ignoring invalid export schedule")
+			continue
+		}
+		s.schedules[ns.ID] = newExportSchedule(*cfg, now)
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.loop()
+	return nil
+}
+
+// Close stops scheduling and waits for a running export
+func (s *ExportScheduler) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// check validates cfg for this server
+func (s *ExportScheduler) check(cfg *ExportScheduleConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.Type == ExportToDir && s.dir == "" {
+		return ErrExportDirDisabled
+	}
+	return nil
+}
+
+// newExportSchedule returns the schedule of a validated config
+func newExportSchedule(cfg ExportScheduleConfig, now time.Time) *exportSchedule {
+	cron, _ := ParseCron(cfg.Cron)
+	return &exportSchedule{cfg: cfg, cron: cron, next: cron.Next(now)}
+}
+
+// Configure stores a namespace's export schedule; nil removes it
+func (s *ExportScheduler) Configure(ctx context.Context, namespace string, cfg *ExportScheduleConfig) error {
+	if cfg != nil {
+		if err := s.check(cfg); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sched *exportSchedule
+	if cfg != nil {
+		sched = newExportSchedule(*cfg, time.Now())
+	}
+	err := updateNamespaceMetadata(ctx, s.store, namespace, func(metadata map[string]interface{}) {
+		previous, status := ExportScheduleFromMetadata(metadata)
+
+		if cfg == nil {
+			delete(metadata, exportScheduleMetadataKey)
+			delete(metadata, exportScheduleStatusMetadataKey)
+			return
+		}
+		// Masked secrets echoed back from ns.exportSchedule.get keep their stored value
+		if previous != nil && cfg.SecretKey == logShippingRedacted {
+			cfg.SecretKey = previous.SecretKey
+		}
+		if status == nil {
+			status = &ExportScheduleStatus{}
+		}
+		status.NextRunAt = sched.next.Format(time.RFC3339)
+		metadata[exportScheduleMetadataKey] = encodeMetadataValue(cfg)
+		metadata[exportScheduleStatusMetadataKey] = encodeMetadataValue(status)
+	})
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		delete(s.schedules, namespace)
+	} else {
+		sched.cfg = *cfg
+		s.schedules[namespace] = sched
+	}
+	return nil
+}
+
+// loop runs due exports until the scheduler is closed
+func (s *ExportScheduler) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(exportScheduleTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		due := make(map[string]ExportScheduleConfig)
+		s.mu.Lock()
+		for namespace, sched := range s.schedules {
+			if sched.next.IsZero() || now.Before(sched.next) {
+				continue
+			}
+			sched.next = sched.cron.Next(now)
+			if s.router == nil || s.router.Owner(namespace).ID == s.router.self {
+				due[namespace] = sched.cfg
+			}
+		}
+		s.mu.Unlock()
+
+		var wg sync.WaitGroup
+		for namespace, cfg := range due {
+			if s.ctx.Err() != nil {
+				break
+			}
+			if s.jobs == nil {
+				s.run(s.ctx, namespace, cfg, BackupTriggerSchedule)
+				continue
+			}
+			wg.Add(1)
+			go func(namespace string, cfg ExportScheduleConfig) {
+				defer wg.Done()
+				s.run(s.ctx, namespace, cfg, BackupTriggerSchedule)
+			}(namespace, cfg)
+		}
+		wg.Wait()
+	}
+}
+
+// Run exports a namespace now, outside its schedule, and returns the
+// updated status
+func (s *ExportScheduler) Run(ctx context.Context, namespace string) (*ExportScheduleStatus, error) {
+	s.mu.Lock()
+	sched, ok := s.schedules[namespace]
+	var cfg ExportScheduleConfig
+	if ok {
+		cfg = sched.cfg
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrNoExportSchedule
+	}
+	return s.run(ctx, namespace, cfg, BackupTriggerManual)
+}
+
+// run exports a namespace and records the outcome in its status, its
+// eventodb:exports stream and, on failure, the notifier; completed exports
+// are added to its backup catalog
+func (s *ExportScheduler) run(ctx context.Context, namespace string, cfg ExportScheduleConfig, trigger string) (*ExportScheduleStatus, error) {
+	var status *ExportScheduleStatus
+	err := s.jobs.Run(ctx, namespace, JobKindExport, func(ctx context.Context) error {
+		var err error
+		status, err = s.runExport(ctx, namespace, cfg, trigger)
+		return err
+	})
+	return status, err
+}
+
+// runExport is run, as a job of the job scheduler when it is set
+func (s *ExportScheduler) runExport(ctx context.Context, namespace string, cfg ExportScheduleConfig, trigger string) (*ExportScheduleStatus, error) {
+	if s.jobs == nil {
+		s.runMu.Lock()
+		defer s.runMu.Unlock()
+	}
+
+	log := logger.Get().With().Str("namespace", namespace).Str("exportSchedule", cfg.Type).Logger()
+	started := time.Now().UTC()
+	archive, messages, size, err := s.export(ctx, namespace, cfg, started)
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		// Deleted namespaces take their schedule with them
+		s.mu.Lock()
+		delete(s.schedules, namespace)
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"destination": cfg.Type,
+		"startedAt":   started.Format(time.RFC3339Nano),
+		"duration":    time.Since(started).String(),
+	}
+	eventType := "ExportCompleted"
+	if err != nil {
+		eventType = "ExportFailed"
+		data["error"] = err.Error()
+		log.Warn().Err(err).Msg("Scheduled export failed")
+		s.notifier.Notify(SystemEvent{
+			Type:      SystemEventExportFailed,
+			Severity:  "error",
+			Namespace: namespace,
+			Subject:   "exportSchedule",
+			Message:   err.Error(),
+		})
+	} else {
+		data["archive"] = archive
+		data["messages"] = messages
+		data["bytes"] = size
+		log.Info().Str("archive", archive).Int64("messages", messages).Msg("Scheduled export completed")
+		JobEventf(ctx, "Exported %d messages to %s", messages, archive)
+	}
+	if _, werr := s.store.WriteMessage(context.Background(), namespace, ExportScheduleStream, &store.Message{
+		StreamName: ExportScheduleStream,
+		Type:       eventType,
+		Data:       data,
+	}); werr != nil {
+		log.Warn().Err(werr).Msg("Failed to record scheduled export")
+	}
+
+	var status ExportScheduleStatus
+	s.mu.Lock()
+	if sched, ok := s.schedules[namespace]; ok {
+		status.NextRunAt = sched.next.Format(time.RFC3339)
+	}
+	s.mu.Unlock()
+	uerr := updateNamespaceMetadata(context.Background(), s.store, namespace, func(metadata map[string]interface{}) {
+		if _, saved := ExportScheduleFromMetadata(metadata); saved != nil {
+			next := status.NextRunAt
+			status = *saved
+			status.NextRunAt = next
+		}
+		status.LastRunAt = started.Format(time.RFC3339Nano)
+		if err != nil {
+			status.LastError = err.Error()
+			status.LastErrorAt = time.Now().UTC().Format(time.RFC3339Nano)
+		} else {
+			status.LastSuccessAt = status.LastRunAt
+			status.LastArchive = archive
+			status.LastMessages = messages
+			status.LastBytes = size
+			status.LastError = ""
+			status.LastErrorAt = ""
+			addBackup(metadata, BackupRecord{
+				ID:          started.Format("20060102T150405Z"),
+				CreatedAt:   status.LastRunAt,
+				Trigger:     trigger,
+				Destination: cfg.Type,
+				Archive:     archive,
+				Messages:    messages,
+				Bytes:       size,
+			})
+		}
+		metadata[exportScheduleStatusMetadataKey] = encodeMetadataValue(status)
+	})
+	if uerr != nil {
+		log.Warn().Err(uerr).Msg("Failed to record export schedule status")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// export writes one archive of the namespace and returns its name, message
+// count and compressed size
+func (s *ExportScheduler) export(ctx context.Context, namespace string, cfg ExportScheduleConfig, started time.Time) (string, int64, int64, error) {
+	name := fmt.Sprintf("%s-%s.ndjson.gz", url.PathEscape(namespace), started.Format("20060102T150405Z"))
+
+	switch cfg.Type {
+	case ExportToDir:
+		dir := filepath.Join(s.dir, url.PathEscape(namespace))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", 0, 0, err
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path + ".tmp")
+		if err != nil {
+			return "", 0, 0, err
+		}
+		messages, err := writeExportArchive(ctx, s.store, f, namespace)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(path+".tmp", path)
+		}
+		if err != nil {
+			os.Remove(path + ".tmp")
+			return "", 0, 0, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		pruneExportArchives(dir, url.PathEscape(namespace)+"-", cfg.Keep)
+		return path, messages, info.Size(), nil
+
+	case ExportToS3:
+		var buf bytes.Buffer
+		messages, err := writeExportArchive(ctx, s.store, &buf, namespace)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		key := cfg.Prefix + url.PathEscape(namespace) + "/" + name
+		target := strings.TrimRight(cfg.Endpoint, "/") + "/" + cfg.Bucket + "/" + key
+		body := buf.Bytes()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+		if err != nil {
+			return "", 0, 0, err
+		}
+		req.Header.Set("Content-Type", "application/gzip")
+		signS3Request(req, body, cfg.Region, cfg.AccessKey, cfg.SecretKey, time.Now())
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return "", 0, 0, fmt.Errorf("destination responded with status %d", resp.StatusCode)
+		}
+		return "s3://" + cfg.Bucket + "/" + key, messages, int64(len(body)), nil
+	}
+	return "", 0, 0, fmt.Errorf("unknown destination type %q", cfg.Type)
+}
+
+// writeExportArchive writes the namespace config line and all messages of
+// a namespace to w as gzipped NDJSON and returns the number of messages
+func writeExportArchive(ctx context.Context, st store.Store, w io.Writer, namespace string) (int64, error) {
+	ctx = store.WithReadPriority(ctx, store.ReadPriorityLow)
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+
+	cfg, err := ExportNamespaceConfig(ctx, st, nil, namespace)
+	if err != nil {
+		return 0, err
+	}
+	if err := enc.Encode(&ExportRecord{NamespaceConfig: cfg}); err != nil {
+		return 0, err
+	}
+
+	var messages int64
+	position := int64(0)
+	for {
+		msgs, err := st.GetCategoryMessages(ctx, namespace, "", &store.CategoryOpts{
+			Position:  position,
+			BatchSize: exportBatchSize,
+		})
+		if err != nil {
+			return messages, err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		for _, msg := range msgs {
+			if err := enc.Encode(exportRecord(msg)); err != nil {
+				return messages, err
+			}
+		}
+		messages += int64(len(msgs))
+		position = msgs[len(msgs)-1].GlobalPosition + 1
+	}
+	return messages, gz.Close()
+}
+
+// pruneExportArchives deletes all but the newest keep archives in dir whose
+// names start with prefix; their timestamps sort by name
+func pruneExportArchives(dir, prefix string, keep int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var archives []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) && strings.HasSuffix(e.Name(), ".ndjson.gz") {
+			archives = append(archives, e.Name())
+		}
+	}
+	sort.Strings(archives)
+	for len(archives) > keep {
+		if err := os.Remove(filepath.Join(dir, archives[0])); err != nil {
+			logger.Get().Warn().Err(err).Str("archive", archives[0]).Msg("Failed to delete old export archive")
+		}
+		archives = archives[1:]
+	}
+}
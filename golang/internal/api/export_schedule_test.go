@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestExportSchedule tests that a run writes an archive to the export
+// directory, records it in the namespace and keeps only the newest archives
+func TestExportSchedule(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	for i := 0; i < 3; i++ {
+		if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{Type: "Placed", Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+
+	h := NewRPCHandler("test", st, NewPubSub())
+	if _, rpcErr := h.route(ctx, "ns.exportSchedule.set", []interface{}{map[string]interface{}{"cron": "@daily", "type": "dir"}}); rpcErr == nil {
+		t.Fatal("Expected ns.exportSchedule.set to fail without a scheduler")
+	}
+
+	dir := t.TempDir()
+	exports := NewExportScheduler(st, dir)
+	h.SetExportScheduler(exports)
+	if _, rpcErr := h.route(ctx, "ns.exportSchedule.set", []interface{}{map[string]interface{}{"cron": "0 25 * * *", "type": "dir"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an invalid cron expression, got %v", rpcErr)
+	}
+	result, rpcErr := h.route(ctx, "ns.exportSchedule.set", []interface{}{map[string]interface{}{"cron": "@daily", "type": "dir", "keep": float64(1)}})
+	if rpcErr != nil {
+		t.Fatalf("ns.exportSchedule.set failed: %v", rpcErr.Message)
+	}
+	if status := result.(map[string]interface{})["status"].(map[string]interface{}); status["nextRunAt"] == nil {
+		t.Errorf("Expected the next run in the status, got %v", status)
+	}
+
+	result, rpcErr = h.route(ctx, "ns.exportSchedule.run", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.exportSchedule.run failed: %v", rpcErr.Message)
+	}
+	status := result.(map[string]interface{})
+	if status["lastMessages"] != float64(3) {
+		t.Errorf("Expected 3 messages exported, got %v", status)
+	}
+
+	// The archive holds the config line and the messages
+	f, err := os.Open(status["lastArchive"].(string))
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	lines := 0
+	for scanner := bufio.NewScanner(gz); scanner.Scan(); {
+		lines++
+	}
+	if lines != 4 {
+		t.Errorf("Expected a config line and 3 records, got %d lines", lines)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", ExportScheduleStream, nil)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", ExportScheduleStream, err)
+	}
+	if len(msgs) != 1 || msgs[0].Type != "ExportCompleted" {
+		t.Fatalf("Expected an ExportCompleted event, got %d messages", len(msgs))
+	}
+
+	// Only the newest archive is kept
+	os.WriteFile(dir+"/test-ns/test-ns-20000101T000000Z.ndjson.gz", nil, 0o644)
+	pruneExportArchives(dir+"/test-ns", "test-ns-", 1)
+	entries, _ := os.ReadDir(dir + "/test-ns")
+	if len(entries) != 1 || entries[0].Name() == "test-ns-20000101T000000Z.ndjson.gz" {
+		t.Errorf("Expected only the newest archive to be kept, got %v", entries)
+	}
+}
+
+// TestExportSchedule_Errors tests that invalid configs are rejected, that
+// secrets are masked and kept when echoed back, and that runs fail without a
+// schedule or without an export directory
+func TestExportSchedule_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h.SetExportScheduler(NewExportScheduler(st, ""))
+
+	s3 := func(overrides map[string]interface{}) map[string]interface{} {
+		cfg := map[string]interface{}{
+			"cron":      "@hourly",
+			"type":      "s3",
+			"bucket":    "backups",
+			"region":    "eu-west-1",
+			"accessKey": "AKIA",
+			"secretKey": "secret",
+		}
+		for k, v := range overrides {
+			cfg[k] = v
+		}
+		return cfg
+	}
+	invalid := [][]interface{}{
+		{},
+		{"daily"},
+		{map[string]interface{}{"cron": "@daily", "type": "ftp"}},
+		{map[string]interface{}{"cron": "@daily", "type": "dir", "keep": "all"}},
+		{s3(map[string]interface{}{"bucket": ""})},
+		{s3(map[string]interface{}{"region": ""})},
+		{s3(map[string]interface{}{"secretKey": ""})},
+		{s3(map[string]interface{}{"endpoint": "minio:9000"})},
+		// Dir destinations need the server's export directory
+		{map[string]interface{}{"cron": "@daily", "type": "dir"}},
+	}
+	for _, args := range invalid {
+		if _, rpcErr := h.route(ctx, "ns.exportSchedule.set", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.exportSchedule.run", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a run without a schedule, got %v", rpcErr)
+	}
+
+	// Secrets are masked, and the masked value keeps the stored secret
+	result, rpcErr := h.route(ctx, "ns.exportSchedule.set", []interface{}{s3(nil)})
+	if rpcErr != nil {
+		t.Fatalf("ns.exportSchedule.set failed: %v", rpcErr.Message)
+	}
+	cfg := result.(map[string]interface{})["config"].(map[string]interface{})
+	if cfg["secretKey"] != logShippingRedacted || cfg["endpoint"] != "https://s3.eu-west-1.amazonaws.com" {
+		t.Errorf("Expected a masked secret and the default endpoint, got %v", cfg)
+	}
+	if _, rpcErr := h.route(ctx, "ns.exportSchedule.set", []interface{}{cfg}); rpcErr != nil {
+		t.Fatalf("ns.exportSchedule.set failed: %v", rpcErr.Message)
+	}
+	ns, err := st.GetNamespace(ctx, "test-ns")
+	if err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	if stored, _ := ExportScheduleFromMetadata(ns.Metadata); stored == nil || stored.SecretKey != "secret" {
+		t.Errorf("Expected the stored secret to be kept, got %+v", stored)
+	}
+
+	// null removes the schedule
+	if _, rpcErr := h.route(ctx, "ns.exportSchedule.set", []interface{}{nil}); rpcErr != nil {
+		t.Fatalf("ns.exportSchedule.set failed: %v", rpcErr.Message)
+	}
+	if result, rpcErr := h.route(ctx, "ns.exportSchedule.get", nil); rpcErr != nil || result != nil {
+		t.Errorf("Expected no schedule after removal, got %v %v", result, rpcErr)
+	}
+}
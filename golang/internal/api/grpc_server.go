@@ -0,0 +1,596 @@
+// Package api provides the gRPC API, which serves the RPC methods to
+// protobuf clients.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/api/eventodbv1"
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/store"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// grpcErrorDomain is the domain of the ErrorInfo details of failed calls
+const grpcErrorDomain = "eventodb"
+
+// GRPCServer serves the RPC methods over gRPC. Typed methods are translated
+// to the arguments of their JSON RPC method and routed like POST /rpc, so
+// both APIs authenticate, validate and fail the same way.
+type GRPCServer struct {
+	eventodbv1.UnimplementedEventoDBServer
+
+	rpc          *RPCHandler
+	sse          *SSEHandler
+	namespaces   NamespaceGetter
+	audit        *authAuditor
+	testMode     bool
+	dataPathOnly bool // Admin methods are only served on the admin listener
+	server       *grpc.Server
+}
+
+// NewGRPCServer creates a gRPC server for the methods of rpc. Subscriptions
+// read and follow streams like sse; namespaces authenticates tokens.
+func NewGRPCServer(rpc *RPCHandler, sse *SSEHandler, namespaces NamespaceGetter, testMode bool) *GRPCServer {
+	s := &GRPCServer{
+		rpc:        rpc,
+		sse:        sse,
+		namespaces: namespaces,
+		audit:      newAuthAuditor(namespaces),
+		testMode:   testMode,
+		server:     grpc.NewServer(),
+	}
+	eventodbv1.RegisterEventoDBServer(s.server, s)
+	return s
+}
+
+// SetDataPathOnly refuses the admin methods, as on the public listener when
+// an admin listener is configured
+func (s *GRPCServer) SetDataPathOnly(dataPathOnly bool) {
+	s.dataPathOnly = dataPathOnly
+}
+
+// Serve serves the gRPC API on ln until Stop is called
+func (s *GRPCServer) Serve(ln net.Listener) error {
+	return s.server.Serve(ln)
+}
+
+// Stop closes the listener and every call in progress, including
+// subscriptions
+func (s *GRPCServer) Stop() {
+	s.server.Stop()
+}
+
+// Call implements EventoDB.Call
+func (s *GRPCServer) Call(ctx context.Context, req *eventodbv1.CallRequest) (*eventodbv1.CallResponse, error) {
+	args := make([]interface{}, len(req.Args))
+	for i, arg := range req.Args {
+		args[i] = arg.AsInterface()
+	}
+	// Unary responses are built in memory, so streamed reads count against
+	// the result limits as well; GetStream streams large reads
+	result, release, err := s.call(withHeldResult(ctx), req.Method, args)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if streamed, ok := result.(*streamedResponse); ok {
+		var items []interface{}
+		if err := streamed.batches(func(batch []interface{}) error {
+			items = append(items, batch...)
+			return nil
+		}); err != nil {
+			if rpcErr, ok := resultLimit(err); ok {
+				return nil, grpcError(rpcErr)
+			}
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		result = items
+	}
+
+	value, err := toProtoValue(result)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "encoding result: %v", err)
+	}
+	return &eventodbv1.CallResponse{Result: value}, nil
+}
+
+// Write implements EventoDB.Write as stream.write
+func (s *GRPCServer) Write(ctx context.Context, req *eventodbv1.WriteRequest) (*eventodbv1.WriteResponse, error) {
+	msg := map[string]interface{}{"type": req.Type, "data": req.Data.AsMap()}
+	if req.Metadata != nil {
+		msg["metadata"] = req.Metadata.AsMap()
+	}
+	opts := map[string]interface{}{}
+	if req.Id != "" {
+		opts["id"] = req.Id
+	}
+	if req.ExpectedVersion != nil {
+		opts["expectedVersion"] = float64(*req.ExpectedVersion)
+	}
+
+	result, release, err := s.call(ctx, "stream.write", []interface{}{req.StreamName, msg, opts})
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	res, _ := result.(map[string]interface{})
+	if claim, ok := res["claim"].(string); ok {
+		return &eventodbv1.WriteResponse{Claim: claim}, nil
+	}
+	position, _ := res["position"].(int64)
+	globalPosition, _ := res["globalPosition"].(int64)
+	return &eventodbv1.WriteResponse{Position: position, GlobalPosition: globalPosition}, nil
+}
+
+// GetStream implements EventoDB.GetStream as stream.get
+func (s *GRPCServer) GetStream(req *eventodbv1.GetStreamRequest, stream eventodbv1.EventoDB_GetStreamServer) error {
+	opts := map[string]interface{}{"position": float64(req.Position)}
+	if req.GlobalPosition != nil {
+		opts["globalPosition"] = float64(*req.GlobalPosition)
+	}
+	if req.BatchSize != 0 {
+		opts["batchSize"] = float64(req.BatchSize)
+	}
+	if req.Decode {
+		opts["decode"] = true
+	}
+
+	result, release, err := s.call(stream.Context(), "stream.get", []interface{}{req.StreamName, opts})
+	if err != nil {
+		return err
+	}
+	defer release()
+	return sendMessages(result, false, stream.Send)
+}
+
+// GetCategory implements EventoDB.GetCategory as category.get
+func (s *GRPCServer) GetCategory(req *eventodbv1.GetCategoryRequest, stream eventodbv1.EventoDB_GetCategoryServer) error {
+	opts := map[string]interface{}{"position": float64(req.Position)}
+	if req.BatchSize != 0 {
+		opts["batchSize"] = float64(req.BatchSize)
+	}
+	if req.Correlation != "" {
+		opts["correlation"] = req.Correlation
+	}
+	if req.Where != "" {
+		opts["where"] = req.Where
+	}
+	if group := req.ConsumerGroup; group != nil {
+		consumerGroup := map[string]interface{}{"member": float64(group.Member), "size": float64(group.Size)}
+		if group.Partitioner != "" {
+			consumerGroup["partitioner"] = group.Partitioner
+		}
+		opts["consumerGroup"] = consumerGroup
+	}
+	if req.Decode {
+		opts["decode"] = true
+	}
+
+	result, release, err := s.call(stream.Context(), "category.get", []interface{}{req.Category, opts})
+	if err != nil {
+		return err
+	}
+	defer release()
+	return sendMessages(result, true, stream.Send)
+}
+
+// Subscribe implements EventoDB.Subscribe. Stream and category
+// subscriptions first send a poke per message from position on, then one
+// per new write, as GET /subscribe does. All and pattern subscriptions send
+// new writes only.
+func (s *GRPCServer) Subscribe(req *eventodbv1.SubscribeRequest, stream eventodbv1.EventoDB_SubscribeServer) error {
+	ctx, rpcErr := s.authenticate(stream.Context())
+	if rpcErr != nil {
+		return grpcError(rpcErr)
+	}
+	namespace, _ := GetNamespaceFromContext(ctx)
+
+	var member, size int64
+	var partitioner store.Partitioner
+	if group := req.ConsumerGroup; group != nil {
+		member, size = group.Member, group.Size
+		if size > 0 && (member < 0 || member >= size) {
+			return status.Error(codes.InvalidArgument, "Invalid consumer group: member must be < size")
+		}
+		p, err := store.ParsePartitioner(group.Partitioner)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "Invalid partitioner")
+		}
+		partitioner = p
+	}
+	assigned := func(streamName string) bool {
+		return size == 0 || partitioner.IsAssigned(streamName, member, size)
+	}
+	send := func(streamName string, position, globalPosition int64) error {
+		return stream.Send(&eventodbv1.Poke{Stream: streamName, Position: position, GlobalPosition: globalPosition})
+	}
+
+	// Subscribe before reading, so writes made meanwhile are not missed
+	pubsub := s.sse.Pubsub
+	switch target := req.Target.(type) {
+	case *eventodbv1.SubscribeRequest_Stream:
+		if IsSubjectPattern(target.Stream) {
+			return s.subscribePattern(ctx, namespace, target.Stream, false, req.Position, assigned, send)
+		}
+		streamName := s.sse.Aliases.Resolve(ctx, namespace, target.Stream)
+		sub := pubsub.SubscribeStream(namespace, streamName)
+		defer pubsub.UnsubscribeStream(namespace, streamName, sub)
+
+		next := req.Position
+		for {
+			messages, err := s.sse.Store.GetStreamMessages(ctx, namespace, streamName, &store.GetOpts{
+				Position:  next,
+				BatchSize: streamedReadPage,
+			})
+			if err != nil {
+				return grpcError(streamGetError(err))
+			}
+			for _, msg := range messages {
+				if err := send(streamName, msg.Position, msg.GlobalPosition); err != nil {
+					return err
+				}
+				next = msg.Position + 1
+			}
+			if len(messages) < streamedReadPage {
+				break
+			}
+		}
+		return follow(ctx, sub, func(event WriteEvent) error {
+			if event.Position < next {
+				return nil
+			}
+			next = event.Position + 1
+			return send(event.Stream, event.Position, event.GlobalPosition)
+		})
+
+	case *eventodbv1.SubscribeRequest_Category:
+		if IsSubjectPattern(target.Category) {
+			return s.subscribePattern(ctx, namespace, target.Category, true, req.Position, assigned, send)
+		}
+		sub, unsubscribe := s.sse.subscribeCategory(ctx, namespace, target.Category)
+		defer unsubscribe()
+
+		next := req.Position
+		for {
+			opts := &store.CategoryOpts{Position: next, BatchSize: streamedReadPage}
+			if size > 0 {
+				opts.ConsumerMember, opts.ConsumerSize, opts.Partitioner = &member, &size, partitioner
+			}
+			messages, err := getCategoryMessages(ctx, s.sse.Store, s.sse.Views, namespace, target.Category, opts)
+			if err != nil {
+				return grpcError(streamGetError(err))
+			}
+			for _, msg := range messages {
+				if err := send(msg.StreamName, msg.Position, msg.GlobalPosition); err != nil {
+					return err
+				}
+				next = msg.GlobalPosition + 1
+			}
+			if len(messages) < streamedReadPage {
+				break
+			}
+		}
+		return follow(ctx, sub, func(event WriteEvent) error {
+			if event.GlobalPosition < next || !assigned(event.Stream) {
+				return nil
+			}
+			next = event.GlobalPosition + 1
+			return send(event.Stream, event.Position, event.GlobalPosition)
+		})
+
+	case *eventodbv1.SubscribeRequest_All:
+		if !target.All {
+			break
+		}
+		sub := pubsub.SubscribeAll(namespace)
+		defer pubsub.UnsubscribeAll(namespace, sub)
+		return follow(ctx, sub, func(event WriteEvent) error {
+			if event.GlobalPosition < req.Position {
+				return nil
+			}
+			return send(event.Stream, event.Position, event.GlobalPosition)
+		})
+	}
+	return status.Error(codes.InvalidArgument, "One of stream, category or all is required")
+}
+
+// subscribePattern follows the streams or categories matching a wildcard
+// pattern. Like all, it sends new writes only.
+func (s *GRPCServer) subscribePattern(ctx context.Context, namespace, name string, category bool, position int64, assigned func(string) bool, send func(string, int64, int64) error) error {
+	pattern, err := ParseSubjectPattern(name, category)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "Invalid pattern: "+err.Error())
+	}
+	sub := s.sse.Pubsub.SubscribePattern(namespace, pattern)
+	defer s.sse.Pubsub.UnsubscribePattern(namespace, sub)
+	return follow(ctx, sub, func(event WriteEvent) error {
+		if event.GlobalPosition < position || !assigned(event.Stream) {
+			return nil
+		}
+		return send(event.Stream, event.Position, event.GlobalPosition)
+	})
+}
+
+// follow calls fn for each event of sub until ctx is done, the server shuts
+// down or fn fails
+func follow(ctx context.Context, sub Subscriber, fn func(WriteEvent) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub:
+			if !ok {
+				return nil
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// call authenticates the caller and routes an RPC method. Response headers,
+// such as paging hints, are sent as header metadata. The returned function
+// releases the result's share of the response memory budget and must be
+// called once the result is sent.
+func (s *GRPCServer) call(ctx context.Context, method string, args []interface{}) (interface{}, func(), error) {
+	reqCtx, rpcErr := s.authenticate(ctx)
+	if rpcErr != nil {
+		return nil, nil, grpcError(rpcErr)
+	}
+	reqCtx, headers := withResponseHeaders(reqCtx)
+	reqCtx, release := s.rpc.results.begin(reqCtx)
+	result, rpcErr := s.rpc.route(reqCtx, method, args)
+	if rpcErr != nil {
+		release()
+		return nil, nil, grpcError(rpcErr)
+	}
+	if len(headers) > 0 {
+		md := metadata.MD{}
+		for key, value := range headers {
+			md.Set(key, value)
+		}
+		grpc.SetHeader(ctx, md)
+	}
+	return result, release, nil
+}
+
+// authenticate checks the bearer token of the call's metadata, like
+// AuthMiddlewareFast, and returns a context for route. It is canceled when
+// the client goes away.
+func (s *GRPCServer) authenticate(ctx context.Context) (context.Context, *RPCError) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	reqCtx := ctx
+	if values := md.Get(strings.ToLower(MinVersionHeader)); len(values) > 0 {
+		reqCtx = context.WithValue(reqCtx, ContextKeyMinVersion, values[0])
+	}
+	if s.dataPathOnly {
+		reqCtx = context.WithValue(reqCtx, ContextKeyDataPathOnly, true)
+	}
+
+	// Test mode accepts calls without a valid token for the default namespace
+	testNamespace := func(namespace string) (context.Context, *RPCError) {
+		reqCtx = context.WithValue(reqCtx, ContextKeyTestMode, true)
+		return context.WithValue(reqCtx, ContextKeyNamespace, namespace), nil
+	}
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+
+	var token string
+	if values := md.Get("authorization"); len(values) > 0 {
+		if !strings.HasPrefix(values[0], "Bearer ") {
+			if s.testMode {
+				return testNamespace("default")
+			}
+			return nil, &RPCError{
+				Code:    "AUTH_REQUIRED",
+				Message: "Authorization metadata must use Bearer scheme",
+			}
+		}
+		token = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	if token == "" {
+		if s.testMode {
+			return testNamespace("default")
+		}
+		return nil, &RPCError{
+			Code:    "AUTH_REQUIRED",
+			Message: "Token required",
+		}
+	}
+
+	namespace, err := auth.ParseToken(token)
+	if err != nil {
+		if s.testMode {
+			return testNamespace("default")
+		}
+		rpcErr := malformedTokenError(err)
+		s.audit.record("", rpcErr, remoteAddr)
+		return nil, rpcErr
+	}
+
+	ns, err := s.namespaces.GetNamespace(ctx, namespace)
+	if err != nil {
+		if store.IsBackendUnavailable(err) {
+			return nil, backendUnavailableError(err)
+		}
+		if s.testMode {
+			return testNamespace(namespace)
+		}
+		return nil, &RPCError{
+			Code:    "AUTH_UNAUTHORIZED",
+			Message: "Token not authorized for namespace",
+			Details: map[string]interface{}{"namespace": namespace},
+		}
+	}
+	if !s.testMode {
+		if err := verifyNamespaceToken(ns, token, time.Now()); err != nil {
+			_, rpcErr := tokenAuthError(ns, err)
+			s.audit.record(namespace, rpcErr, remoteAddr)
+			return nil, rpcErr
+		}
+	}
+	if state := SuspensionStateFromMetadata(ns.Metadata); state != nil && state.BlockReads {
+		rpcErr := suspendedError(namespace)
+		s.audit.record(namespace, rpcErr, remoteAddr)
+		return nil, rpcErr
+	}
+	return context.WithValue(reqCtx, ContextKeyNamespace, namespace), nil
+}
+
+// sendMessages sends the messages of a stream.get or category.get result
+func sendMessages(result interface{}, withStream bool, send func(*eventodbv1.Message) error) error {
+	sendItems := func(items []interface{}) error {
+		for _, item := range items {
+			fields, _ := item.([]interface{})
+			msg, err := protoMessage(fields, withStream)
+			if err != nil {
+				return status.Errorf(codes.Internal, "encoding message: %v", err)
+			}
+			if err := send(msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if streamed, ok := result.(*streamedResponse); ok {
+		err := streamed.batches(sendItems)
+		if errors.Is(err, errStreamedRead) {
+			return status.Error(codes.Internal, err.Error())
+		}
+		return err
+	}
+	items, _ := result.([]interface{})
+	return sendItems(items)
+}
+
+// protoMessage converts a message as formatted by stream.get, or by
+// category.get when withStream is set
+func protoMessage(fields []interface{}, withStream bool) (*eventodbv1.Message, error) {
+	msg := &eventodbv1.Message{}
+	if withStream {
+		if len(fields) != 8 {
+			return nil, fmt.Errorf("unexpected message format")
+		}
+		msg.StreamName, _ = fields[1].(string)
+		fields = append(fields[:1:1], fields[2:]...)
+	}
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("unexpected message format")
+	}
+	msg.Id, _ = fields[0].(string)
+	msg.Type, _ = fields[1].(string)
+	msg.Position, _ = fields[2].(int64)
+	msg.GlobalPosition, _ = fields[3].(int64)
+	msg.Time, _ = fields[6].(string)
+
+	var err error
+	if msg.Data, err = toProtoStruct(fields[4]); err != nil {
+		return nil, err
+	}
+	if msg.Metadata, err = toProtoStruct(fields[5]); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// toProtoValue converts a JSON-encodable result to a protobuf Value
+func toProtoValue(v interface{}) (*structpb.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	value := &structpb.Value{}
+	if err := value.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// toProtoStruct converts message data or metadata to a protobuf Struct,
+// nil for none
+func toProtoStruct(v interface{}) (*structpb.Struct, error) {
+	if m, ok := v.(map[string]interface{}); !ok || m == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err := s.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// grpcError converts an RPC error to a gRPC status. The RPC error code is
+// the reason of an ErrorInfo detail, whose metadata holds the error details
+// JSON-encoded.
+func grpcError(rpcErr *RPCError) error {
+	st := status.New(grpcCode(rpcErr.Code), rpcErr.Message)
+	info := &errdetails.ErrorInfo{Reason: rpcErr.Code, Domain: grpcErrorDomain}
+	if len(rpcErr.Details) > 0 {
+		info.Metadata = make(map[string]string, len(rpcErr.Details))
+		for key, value := range rpcErr.Details {
+			if s, ok := value.(string); ok {
+				info.Metadata[key] = s
+				continue
+			}
+			data, _ := json.Marshal(value)
+			info.Metadata[key] = string(data)
+		}
+	}
+	if withDetails, err := st.WithDetails(info); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// grpcCode returns the gRPC status code of an RPC error code, matching the
+// HTTP status of POST /rpc
+func grpcCode(code string) codes.Code {
+	switch code {
+	case "INVALID_REQUEST", "INVALID_STREAM_NAME", "IMPORT_INVALID":
+		return codes.InvalidArgument
+	case "RESULT_TOO_LARGE":
+		return codes.ResourceExhausted
+	case "METHOD_NOT_FOUND":
+		return codes.Unimplemented
+	case "AUTH_REQUIRED", "TOKEN_MALFORMED", "TOKEN_EXPIRED", "TOKEN_REVOKED":
+		return codes.Unauthenticated
+	case "AUTH_UNAUTHORIZED", "READ_ONLY", "NAMESPACE_SUSPENDED", "ADMIN_LISTENER_ONLY", "WORM_PROTECTED":
+		return codes.PermissionDenied
+	case "STREAM_NOT_FOUND", "NAMESPACE_NOT_FOUND", "HOOK_NOT_FOUND", "CLAIM_NOT_FOUND", "BOOKMARK_NOT_FOUND",
+		"MESSAGE_NOT_FOUND", "VIEW_NOT_FOUND", "PLUGIN_NOT_FOUND", "BLUEPRINT_NOT_FOUND", "DOCUMENT_NOT_FOUND", "BACKUP_NOT_FOUND",
+		"TICK_NOT_FOUND", "CONSUMER_POSITION_NOT_FOUND", "SUBSCRIPTION_NOT_FOUND", "JOB_NOT_FOUND":
+		return codes.NotFound
+	case "PLUGIN_REJECTED", "CONDITION_FAILED", "MISROUTED", "UPGRADE_REQUIRED", "JOB_STATE_CONFLICT":
+		return codes.FailedPrecondition
+	case "NAMESPACE_EXISTS", "VIEW_EXISTS", "TICK_EXISTS":
+		return codes.AlreadyExists
+	case "STREAM_VERSION_CONFLICT", "PROFILE_IN_PROGRESS":
+		return codes.Aborted
+	case "RATE_LIMITED":
+		return codes.ResourceExhausted
+	case "QUEUE_FULL", "BACKEND_UNAVAILABLE", "OVERLOADED":
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
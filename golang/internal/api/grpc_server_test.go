@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/api/eventodbv1"
+	"github.com/eventodb/eventodb/internal/store"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// dialGRPC serves a gRPC server of h and st over an in-memory listener and
+// returns a client of it
+func dialGRPC(t *testing.T, h *RPCHandler, st store.Store, testMode bool) eventodbv1.EventoDBClient {
+	t.Helper()
+	server := NewGRPCServer(h, NewSSEHandler(st, h.pubsub, testMode), st, testMode)
+	ln := bufconn.Listen(1 << 20)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return ln.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return eventodbv1.NewEventoDBClient(conn)
+}
+
+// TestGRPCServer tests the typed methods, Call, errors and subscriptions of
+// the gRPC API against a test-mode server
+func TestGRPCServer(t *testing.T) {
+	st := newTestStore(t)
+	if err := st.CreateNamespace(context.Background(), "default", "default-token-hash", "Default namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	client := dialGRPC(t, NewRPCHandler("test", st, NewPubSub()), st, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Write and read back
+	for i := 0; i < 3; i++ {
+		data, _ := structpb.NewStruct(map[string]interface{}{"amount": float64(i)})
+		res, err := client.Write(ctx, &eventodbv1.WriteRequest{StreamName: "account-1", Type: "Deposited", Data: data})
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if res.Position != int64(i) {
+			t.Errorf("Expected position %d, got %d", i, res.Position)
+		}
+	}
+	read, err := client.GetStream(ctx, &eventodbv1.GetStreamRequest{StreamName: "account-1", BatchSize: -1})
+	if err != nil {
+		t.Fatalf("GetStream failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		msg, err := read.Recv()
+		if err != nil {
+			t.Fatalf("GetStream failed: %v", err)
+		}
+		if msg.Position != int64(i) || msg.Type != "Deposited" || msg.Data.Fields["amount"].GetNumberValue() != float64(i) {
+			t.Errorf("Unexpected message %d: %v", i, msg)
+		}
+	}
+	if _, err := read.Recv(); err == nil {
+		t.Error("Expected the end of the stream")
+	}
+
+	// Any method through Call
+	version, err := client.Call(ctx, &eventodbv1.CallRequest{Method: "stream.version", Args: []*structpb.Value{structpb.NewStringValue("account-1")}})
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if version.Result.GetNumberValue() != 2 {
+		t.Errorf("Expected version 2, got %v", version.Result)
+	}
+
+	// RPC errors carry their code as the ErrorInfo reason
+	expected := int64(0)
+	_, err = client.Write(ctx, &eventodbv1.WriteRequest{StreamName: "account-1", Type: "Deposited", ExpectedVersion: &expected})
+	rpcStatus := status.Convert(err)
+	if rpcStatus.Code() != codes.Aborted {
+		t.Fatalf("Expected Aborted, got %v", err)
+	}
+	if details := rpcStatus.Details(); len(details) != 1 || details[0].(*errdetails.ErrorInfo).Reason != "STREAM_VERSION_CONFLICT" {
+		t.Errorf("Unexpected details: %v", details)
+	}
+
+	// Subscriptions send the history, then new writes
+	sub, err := client.Subscribe(ctx, &eventodbv1.SubscribeRequest{Target: &eventodbv1.SubscribeRequest_Category{Category: "account"}, Position: 2})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	poke, err := sub.Recv()
+	if err != nil || poke.Stream != "account-1" || poke.Position != 1 {
+		t.Fatalf("Unexpected poke %v (%v)", poke, err)
+	}
+	if _, err := sub.Recv(); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := client.Write(ctx, &eventodbv1.WriteRequest{StreamName: "account-2", Type: "Opened"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	poke, err = sub.Recv()
+	if err != nil || poke.Stream != "account-2" || poke.Position != 0 {
+		t.Errorf("Unexpected poke %v (%v)", poke, err)
+	}
+}
+
+// TestGRPCServer_TokenErrors tests that token errors are Unauthenticated
+func TestGRPCServer_TokenErrors(t *testing.T) {
+	st := newTestStore(t)
+	client := dialGRPC(t, NewRPCHandler("test", st, NewPubSub()), st, false)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer not-a-token")
+	_, err := client.Call(ctx, &eventodbv1.CallRequest{Method: "sys.version"})
+	rpcStatus := status.Convert(err)
+	if rpcStatus.Code() != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated, got %v", err)
+	}
+	if details := rpcStatus.Details(); len(details) != 1 || details[0].(*errdetails.ErrorInfo).Reason != "TOKEN_MALFORMED" {
+		t.Errorf("Unexpected details: %v", details)
+	}
+}
+
+func TestGRPCCode(t *testing.T) {
+	for code, want := range map[string]codes.Code{
+		"AUTH_REQUIRED":           codes.Unauthenticated,
+		"TOKEN_MALFORMED":         codes.Unauthenticated,
+		"TOKEN_EXPIRED":           codes.Unauthenticated,
+		"TOKEN_REVOKED":           codes.Unauthenticated,
+		"NAMESPACE_SUSPENDED":     codes.PermissionDenied,
+		"READ_ONLY":               codes.PermissionDenied,
+		"STREAM_VERSION_CONFLICT": codes.Aborted,
+		"SOMETHING_UNKNOWN":       codes.Internal,
+	} {
+		if got := grpcCode(code); got != want {
+			t.Errorf("Expected %v for %s, got %v", want, code, got)
+		}
+	}
+}
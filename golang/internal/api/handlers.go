@@ -5,11 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
 	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/logger"
 	"github.com/eventodb/eventodb/internal/store"
-	"github.com/google/uuid"
 )
 
 // handleStreamWrite writes a message to a stream
@@ -32,6 +34,9 @@ func (h *RPCHandler) handleStreamWrite(ctx context.Context, args []interface{})
 			Message: "streamName must be a non-empty string",
 		}
 	}
+	if err := h.names.Validate(streamName); err != nil {
+		return nil, invalidStreamNameError(err)
+	}
 
 	// Parse message object
 	msgObj, ok := args[1].(map[string]interface{})
@@ -41,35 +46,9 @@ func (h *RPCHandler) handleStreamWrite(ctx context.Context, args []interface{})
 			Message: "message must be an object",
 		}
 	}
-
-	// Extract message type
-	msgType, ok := msgObj["type"].(string)
-	if !ok || msgType == "" {
-		return nil, &RPCError{
-			Code:    "INVALID_REQUEST",
-			Message: "message.type must be a non-empty string",
-		}
-	}
-
-	// Extract message data
-	data, ok := msgObj["data"].(map[string]interface{})
-	if !ok {
-		return nil, &RPCError{
-			Code:    "INVALID_REQUEST",
-			Message: "message.data must be an object",
-		}
-	}
-
-	// Extract optional metadata
-	var metadata map[string]interface{}
-	if metaVal, exists := msgObj["metadata"]; exists {
-		metadata, ok = metaVal.(map[string]interface{})
-		if !ok {
-			return nil, &RPCError{
-				Code:    "INVALID_REQUEST",
-				Message: "message.metadata must be an object",
-			}
-		}
+	msg, rpcErr := parseWriteMessage(msgObj, "message")
+	if rpcErr != nil {
+		return nil, rpcErr
 	}
 
 	// Parse optional options
@@ -98,46 +77,15 @@ func (h *RPCHandler) handleStreamWrite(ctx context.Context, args []interface{})
 
 		// Extract optional expectedVersion
 		if evVal, exists := optsObj["expectedVersion"]; exists {
-			// Handle both float64 (from JSON) and int
-			switch v := evVal.(type) {
-			case float64:
-				ev := int64(v)
-				expectedVersion = &ev
-			case int:
-				ev := int64(v)
-				expectedVersion = &ev
-			case int64:
-				expectedVersion = &v
-			default:
-				return nil, &RPCError{
-					Code:    "INVALID_REQUEST",
-					Message: "options.expectedVersion must be a number",
-				}
+			if expectedVersion, rpcErr = parseExpectedVersion(evVal); rpcErr != nil {
+				return nil, rpcErr
 			}
 		}
 	}
 
-	// Generate ID if not provided
-	if msgID == "" {
-		id, err := uuid.NewV7()
-		if err != nil {
-			return nil, &RPCError{
-				Code:    "INTERNAL_ERROR",
-				Message: fmt.Sprintf("failed to generate UUID: %v", err),
-			}
-		}
-		msgID = id.String()
-	}
-
-	// Create message
-	msg := &store.Message{
-		ID:              msgID,
-		StreamName:      streamName,
-		Type:            msgType,
-		Data:            data,
-		Metadata:        metadata,
-		ExpectedVersion: expectedVersion,
-	}
+	msg.ID = msgID
+	msg.StreamName = streamName
+	msg.ExpectedVersion = expectedVersion
 
 	// Get namespace from context
 	namespace, rpcErr := h.getNamespace(ctx)
@@ -145,54 +93,110 @@ func (h *RPCHandler) handleStreamWrite(ctx context.Context, args []interface{})
 		return nil, rpcErr
 	}
 
-	// Write message
-	result, err := h.store.WriteMessage(ctx, namespace, streamName, msg)
-	if err != nil {
-		// Check for version conflict error
-		if store.IsVersionConflict(err) {
-			// Extract details from VersionConflictError if available
-			if vcErr, ok := err.(*store.VersionConflictError); ok {
-				return nil, &RPCError{
-					Code:    "STREAM_VERSION_CONFLICT",
-					Message: fmt.Sprintf("Expected version %d, stream is at version %d", vcErr.ExpectedVersion, vcErr.ActualVersion),
-					Details: map[string]interface{}{
-						"expected": vcErr.ExpectedVersion,
-						"actual":   vcErr.ActualVersion,
-					},
-				}
-			}
-			// Fallback if we can't get details
-			return nil, &RPCError{
-				Code:    "STREAM_VERSION_CONFLICT",
-				Message: err.Error(),
-			}
+	response, rpcErr := h.appendMessages(ctx, namespace, streamName, []*store.Message{msg}, true)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// A single write answers with its own positions only
+	result := response.(map[string]interface{})
+	delete(result, "messages")
+	return result, nil
+}
+
+// parseExpectedVersion parses the expectedVersion option of writes, a
+// float64 from JSON or an int or int64 from other transports
+func parseExpectedVersion(evVal interface{}) (*int64, *RPCError) {
+	var ev int64
+	switch v := evVal.(type) {
+	case float64:
+		ev = int64(v)
+	case int:
+		ev = int64(v)
+	case int64:
+		ev = v
+	default:
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options.expectedVersion must be a number",
 		}
+	}
+	return &ev, nil
+}
 
+// parseWriteMessage parses the type, data and metadata of a message object;
+// field names the object in error messages
+func parseWriteMessage(msgObj map[string]interface{}, field string) (*store.Message, *RPCError) {
+	// Extract message type
+	msgType, ok := msgObj["type"].(string)
+	if !ok || msgType == "" {
 		return nil, &RPCError{
-			Code:    "BACKEND_ERROR",
-			Message: fmt.Sprintf("Failed to write message: %v", err),
+			Code:    "INVALID_REQUEST",
+			Message: field + ".type must be a non-empty string",
 		}
 	}
 
-	// Publish event to subscribers (real-time notification)
-	if h.pubsub != nil {
-		category := store.Category(streamName)
-		h.pubsub.Publish(WriteEvent{
-			Namespace:      namespace,
-			Stream:         streamName,
-			Category:       category,
-			Position:       result.Position,
-			GlobalPosition: result.GlobalPosition,
-		})
+	// Extract message data
+	data, ok := msgObj["data"].(map[string]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: field + ".data must be an object",
+		}
 	}
 
-	// Return result
-	return map[string]interface{}{
-		"position":       result.Position,
-		"globalPosition": result.GlobalPosition,
+	// Extract optional metadata
+	var metadata map[string]interface{}
+	if metaVal, exists := msgObj["metadata"]; exists {
+		metadata, ok = metaVal.(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: field + ".metadata must be an object",
+			}
+		}
+	}
+
+	return &store.Message{
+		Type:     msgType,
+		Data:     data,
+		Metadata: metadata,
 	}, nil
 }
 
+// streamWriteError maps a failed stream write to an RPC error
+func streamWriteError(err error) *RPCError {
+	// Shed by the store's concurrency limiter
+	if store.IsOverloaded(err) {
+		return overloadedError(err)
+	}
+
+	// Check for version conflict error
+	if store.IsVersionConflict(err) {
+		// Extract details from VersionConflictError if available
+		if vcErr, ok := err.(*store.VersionConflictError); ok {
+			return &RPCError{
+				Code:    "STREAM_VERSION_CONFLICT",
+				Message: fmt.Sprintf("Expected version %d, stream is at version %d", vcErr.ExpectedVersion, vcErr.ActualVersion),
+				Details: map[string]interface{}{
+					"expected": vcErr.ExpectedVersion,
+					"actual":   vcErr.ActualVersion,
+				},
+			}
+		}
+		// Fallback if we can't get details
+		return &RPCError{
+			Code:    "STREAM_VERSION_CONFLICT",
+			Message: err.Error(),
+		}
+	}
+
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to write message: %v", err),
+	}
+}
+
 // handleStreamGet retrieves messages from a stream
 // Request: ["stream.get", "streamName", {opts}]
 // Response: [[id, type, position, globalPosition, data, metadata, time], ...]
@@ -216,6 +220,8 @@ func (h *RPCHandler) handleStreamGet(ctx context.Context, args []interface{}) (i
 
 	// Parse options
 	opts := store.NewGetOpts()
+	var envelope, decode bool
+	var rpcErr *RPCError
 
 	if len(args) > 1 {
 		optsObj, ok := args[1].(map[string]interface{})
@@ -243,23 +249,13 @@ func (h *RPCHandler) handleStreamGet(ctx context.Context, args []interface{}) (i
 			}
 		}
 
-		// Parse globalPosition (mutually exclusive with position)
+		// Parse globalPosition (mutually exclusive with position), which may be a bookmark name
 		if gpVal, exists := optsObj["globalPosition"]; exists {
-			switch v := gpVal.(type) {
-			case float64:
-				gp := int64(v)
-				opts.GlobalPosition = &gp
-			case int:
-				gp := int64(v)
-				opts.GlobalPosition = &gp
-			case int64:
-				opts.GlobalPosition = &v
-			default:
-				return nil, &RPCError{
-					Code:    "INVALID_REQUEST",
-					Message: "options.globalPosition must be a number",
-				}
+			gp, rpcErr := h.parseGlobalPositionOption(ctx, "globalPosition", gpVal)
+			if rpcErr != nil {
+				return nil, rpcErr
 			}
+			opts.GlobalPosition = &gp
 		}
 
 		// Parse batchSize
@@ -286,6 +282,21 @@ func (h *RPCHandler) handleStreamGet(ctx context.Context, args []interface{}) (i
 				}
 			}
 		}
+
+		// Parse envelope (after batchSize, which it adjusts)
+		if envelope, rpcErr = parseEnvelopeOption(optsObj, &opts.BatchSize); rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		// Parse decode
+		if decode, rpcErr = parseDecodeOption(optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		// Parse priority
+		if ctx, rpcErr = parsePriorityOption(ctx, optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
 	}
 
 	// Get namespace from context
@@ -294,13 +305,25 @@ func (h *RPCHandler) handleStreamGet(ctx context.Context, args []interface{}) (i
 		return nil, rpcErr
 	}
 
-	// Get messages
-	messages, err := h.store.GetStreamMessages(ctx, namespace, streamName, opts)
+	// Reads of a renamed stream read its new name
+	streamName = h.aliases.Resolve(ctx, namespace, streamName)
+
+	// Unlimited reads are streamed instead of built in memory
+	if opts.BatchSize == -1 && !envelope {
+		return h.streamStreamGet(ctx, namespace, streamName, opts, decode)
+	}
+
+	// Get messages, counted against the result limits as they are read
+	messages, err := h.store.GetStreamMessages(meteredReads(ctx), namespace, streamName, opts)
 	if err != nil {
-		return nil, &RPCError{
-			Code:    "BACKEND_ERROR",
-			Message: fmt.Sprintf("Failed to get messages: %v", err),
-		}
+		return nil, streamGetError(err)
+	}
+	var hasMore bool
+	if envelope {
+		messages, hasMore = trimEnvelopeBatch(messages, opts.BatchSize)
+	}
+	if rpcErr := h.decodeMessages(ctx, namespace, decode, messages); rpcErr != nil {
+		return nil, rpcErr
 	}
 
 	// Format response as array of arrays
@@ -317,6 +340,24 @@ func (h *RPCHandler) handleStreamGet(ctx context.Context, args []interface{}) (i
 		}
 	}
 
+	// Paging hints: an empty batch continues where it started
+	var nextGpos *int64
+	if opts.GlobalPosition != nil {
+		nextGpos = opts.GlobalPosition
+	}
+	hints := newReadHints(messages, &opts.Position, nextGpos, opts.GlobalPosition == nil)
+	hints.setHeaders(ctx)
+
+	if envelope {
+		cursor := hints.NextPosition
+		if opts.GlobalPosition != nil {
+			cursor = hints.NextGpos
+		}
+		return readEnvelope(result, cursor, hasMore), nil
+	}
+	if wantsNDJSON(ctx) {
+		return &streamedResponse{ctx: ctx, ndjson: true, items: result}, nil
+	}
 	return result, nil
 }
 
@@ -343,6 +384,7 @@ func (h *RPCHandler) handleStreamLast(ctx context.Context, args []interface{}) (
 
 	// Parse optional type filter
 	var msgType *string
+	var decode bool
 
 	if len(args) > 1 {
 		optsObj, ok := args[1].(map[string]interface{})
@@ -363,6 +405,14 @@ func (h *RPCHandler) handleStreamLast(ctx context.Context, args []interface{}) (
 			}
 			msgType = &typeStr
 		}
+
+		var rpcErr *RPCError
+		if decode, rpcErr = parseDecodeOption(optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
+		if ctx, rpcErr = parsePriorityOption(ctx, optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
 	}
 
 	// Get namespace from context
@@ -371,6 +421,9 @@ func (h *RPCHandler) handleStreamLast(ctx context.Context, args []interface{}) (
 		return nil, rpcErr
 	}
 
+	// Reads of a renamed stream read its new name
+	streamName = h.aliases.Resolve(ctx, namespace, streamName)
+
 	// Get last message
 	msg, err := h.store.GetLastStreamMessage(ctx, namespace, streamName, msgType)
 	if err != nil {
@@ -378,6 +431,9 @@ func (h *RPCHandler) handleStreamLast(ctx context.Context, args []interface{}) (
 		if errors.Is(err, store.ErrStreamNotFound) {
 			return nil, nil
 		}
+		if store.IsOverloaded(err) {
+			return nil, overloadedError(err)
+		}
 		return nil, &RPCError{
 			Code:    "BACKEND_ERROR",
 			Message: fmt.Sprintf("Failed to get last message: %v", err),
@@ -388,6 +444,12 @@ func (h *RPCHandler) handleStreamLast(ctx context.Context, args []interface{}) (
 	if msg == nil {
 		return nil, nil
 	}
+	if msg.StreamName == "" {
+		msg.StreamName = streamName
+	}
+	if rpcErr := h.decodeMessages(ctx, namespace, decode, []*store.Message{msg}); rpcErr != nil {
+		return nil, rpcErr
+	}
 
 	// Format response as array
 	return []interface{}{
@@ -428,9 +490,15 @@ func (h *RPCHandler) handleStreamVersion(ctx context.Context, args []interface{}
 		return nil, rpcErr
 	}
 
+	// Reads of a renamed stream read its new name
+	streamName = h.aliases.Resolve(ctx, namespace, streamName)
+
 	// Get stream version
 	version, err := h.store.GetStreamVersion(ctx, namespace, streamName)
 	if err != nil {
+		if store.IsOverloaded(err) {
+			return nil, overloadedError(err)
+		}
 		return nil, &RPCError{
 			Code:    "BACKEND_ERROR",
 			Message: fmt.Sprintf("Failed to get stream version: %v", err),
@@ -468,6 +536,10 @@ func (h *RPCHandler) handleCategoryGet(ctx context.Context, args []interface{})
 
 	// Parse options
 	opts := store.NewCategoryOpts()
+	var envelope, decode bool
+	var cursor *categoryCursor
+	var waitForGaps *time.Duration
+	var rpcErr *RPCError
 
 	if len(args) > 1 {
 		optsObj, ok := args[1].(map[string]interface{})
@@ -478,40 +550,20 @@ func (h *RPCHandler) handleCategoryGet(ctx context.Context, args []interface{})
 			}
 		}
 
-		// Parse position
+		// Parse position (a global position or a bookmark name)
 		if posVal, exists := optsObj["position"]; exists {
-			switch v := posVal.(type) {
-			case float64:
-				opts.Position = int64(v)
-			case int:
-				opts.Position = int64(v)
-			case int64:
-				opts.Position = v
-			default:
-				return nil, &RPCError{
-					Code:    "INVALID_REQUEST",
-					Message: "options.position must be a number",
-				}
+			if opts.Position, rpcErr = h.parseGlobalPositionOption(ctx, "position", posVal); rpcErr != nil {
+				return nil, rpcErr
 			}
 		}
 
-		// Parse globalPosition (alternative to position)
+		// Parse globalPosition (alternative to position), which may be a bookmark name
 		if gpVal, exists := optsObj["globalPosition"]; exists {
-			switch v := gpVal.(type) {
-			case float64:
-				gp := int64(v)
-				opts.GlobalPosition = &gp
-			case int:
-				gp := int64(v)
-				opts.GlobalPosition = &gp
-			case int64:
-				opts.GlobalPosition = &v
-			default:
-				return nil, &RPCError{
-					Code:    "INVALID_REQUEST",
-					Message: "options.globalPosition must be a number",
-				}
+			gp, rpcErr := h.parseGlobalPositionOption(ctx, "globalPosition", gpVal)
+			if rpcErr != nil {
+				return nil, rpcErr
 			}
+			opts.GlobalPosition = &gp
 		}
 
 		// Parse batchSize
@@ -539,6 +591,26 @@ func (h *RPCHandler) handleCategoryGet(ctx context.Context, args []interface{})
 			}
 		}
 
+		// Parse cursor, which replaces position and has an envelope of its own
+		if cursor, rpcErr = parseCursorOption(optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		// Parse envelope (after batchSize, which it adjusts)
+		if envelope, rpcErr = parseEnvelopeOption(optsObj, &opts.BatchSize); rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		// Parse decode
+		if decode, rpcErr = parseDecodeOption(optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		// Parse priority
+		if ctx, rpcErr = parsePriorityOption(ctx, optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
+
 		// Parse correlation filter
 		if corrVal, exists := optsObj["correlation"]; exists {
 			corrStr, ok := corrVal.(string)
@@ -551,6 +623,42 @@ func (h *RPCHandler) handleCategoryGet(ctx context.Context, args []interface{})
 			opts.Correlation = &corrStr
 		}
 
+		// Parse where, an EQL filter
+		if whereVal, exists := optsObj["where"]; exists {
+			where, ok := whereVal.(string)
+			if !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.where must be a string",
+				}
+			}
+			filter, err := store.ParseFilter(where)
+			if err != nil {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("options.where: %v", err),
+				}
+			}
+			opts.Filter = filter
+		}
+
+		// Parse sample, the fraction of messages to return
+		if sampleVal, exists := optsObj["sample"]; exists {
+			sample, ok := sampleVal.(float64)
+			if !ok || sample <= 0 || sample > 1 {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.sample must be a number greater than 0 and at most 1",
+				}
+			}
+			opts.Sample = sample
+		}
+
+		// Parse waitForGaps, which holds the read until in-flight gaps resolve
+		if waitForGaps, rpcErr = parseWaitForGapsOption(optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
+
 		// Parse consumer group
 		if cgVal, exists := optsObj["consumerGroup"]; exists {
 			cgObj, ok := cgVal.(map[string]interface{})
@@ -599,6 +707,37 @@ func (h *RPCHandler) handleCategoryGet(ctx context.Context, args []interface{})
 				}
 			}
 
+			// Parse partitioner
+			if pVal, exists := cgObj["partitioner"]; exists {
+				name, ok := pVal.(string)
+				if !ok {
+					return nil, &RPCError{
+						Code:    "INVALID_REQUEST",
+						Message: "options.consumerGroup.partitioner must be a string",
+					}
+				}
+				partitioner, err := store.ParsePartitioner(name)
+				if err != nil {
+					return nil, &RPCError{
+						Code:    "INVALID_REQUEST",
+						Message: fmt.Sprintf("options.consumerGroup.partitioner: %v", err),
+					}
+				}
+				opts.Partitioner = partitioner
+			}
+
+			// Parse orderingKey
+			if okVal, exists := cgObj["orderingKey"]; exists {
+				orderingKey, ok := okVal.(bool)
+				if !ok {
+					return nil, &RPCError{
+						Code:    "INVALID_REQUEST",
+						Message: "options.consumerGroup.orderingKey must be a boolean",
+					}
+				}
+				opts.OrderingKey = orderingKey
+			}
+
 			// Validate consumer group parameters
 			if opts.ConsumerMember != nil && opts.ConsumerSize != nil {
 				if *opts.ConsumerMember < 0 {
@@ -634,14 +773,49 @@ func (h *RPCHandler) handleCategoryGet(ctx context.Context, args []interface{})
 		return nil, rpcErr
 	}
 
-	// Get category messages
-	messages, err := h.store.GetCategoryMessages(ctx, namespace, categoryName, opts)
+	// Get category messages, counted against the result limits as they are read
+	readCtx := meteredReads(ctx)
+	var messages []*store.Message
+	var hasMore bool
+	var err error
+	if cursor != nil {
+		messages, hasMore, cursor, err = readCategoryCursor(readCtx, h.store, h.views, namespace, categoryName, opts, cursor)
+	} else {
+		messages, err = getCategoryMessages(readCtx, h.store, h.views, namespace, categoryName, opts)
+	}
+	if err == nil && waitForGaps != nil {
+		from := opts.Position
+		if opts.GlobalPosition != nil {
+			from = *opts.GlobalPosition
+		}
+		messages, err = awaitGaps(ctx, h.store, namespace, categoryName, from, *waitForGaps, messages, func() ([]*store.Message, error) {
+			return getCategoryMessages(readCtx, h.store, h.views, namespace, categoryName, opts)
+		})
+	}
 	if err != nil {
+		if rpcErr, ok := resultLimit(err); ok {
+			return nil, rpcErr
+		}
+		if store.IsOverloaded(err) {
+			return nil, overloadedError(err)
+		}
+		if errors.Is(err, ErrCursorMismatch) {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			}
+		}
 		return nil, &RPCError{
 			Code:    "BACKEND_ERROR",
 			Message: fmt.Sprintf("Failed to get category messages: %v", err),
 		}
 	}
+	if envelope {
+		messages, hasMore = trimEnvelopeBatch(messages, opts.BatchSize)
+	}
+	if rpcErr := h.decodeMessages(ctx, namespace, decode, messages); rpcErr != nil {
+		return nil, rpcErr
+	}
 
 	// Format response as array of arrays
 	// Note: For category queries, we include the stream name in the response
@@ -659,12 +833,29 @@ func (h *RPCHandler) handleCategoryGet(ctx context.Context, args []interface{})
 		}
 	}
 
+	// Paging hints: an empty batch continues where it started
+	nextGpos := opts.Position
+	if opts.GlobalPosition != nil {
+		nextGpos = *opts.GlobalPosition
+	}
+	hints := newReadHints(messages, nil, &nextGpos, false)
+	hints.setHeaders(ctx)
+
+	if cursor != nil {
+		page := readEnvelope(result, nil, hasMore)
+		page["nextCursor"] = cursor.encode()
+		return page, nil
+	}
+	if envelope {
+		return readEnvelope(result, hints.NextGpos, hasMore), nil
+	}
 	return result, nil
 }
 
 // handleNamespaceCreate creates a new namespace
 // Request: ["ns.create", "namespace-id", {opts}]
 // opts.token: optional token to use (must be valid format for namespace)
+// opts.label, opts.expiresAt: optional token label and RFC 3339 expiry
 // Response: {"namespace": "tenant-a", "token": "ns_...", "createdAt": "..."}
 func (h *RPCHandler) handleNamespaceCreate(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
 	// Validate arguments
@@ -683,10 +874,27 @@ func (h *RPCHandler) handleNamespaceCreate(ctx context.Context, args []interface
 			Message: "namespace ID must be a non-empty string",
 		}
 	}
+	if IsStagingNamespace(namespaceID) {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("namespace IDs starting with %q are reserved for staged imports", StagingNamespacePrefix),
+		}
+	}
+
+	if h.guard.ReadOnly() {
+		return nil, &RPCError{
+			Code:    "READ_ONLY",
+			Message: ErrServerReadOnly.Error(),
+		}
+	}
 
 	// Parse optional options
 	description := ""
 	var providedToken string
+	var shard string
+	var tokenInfo *TokenInfo
+	var template string
+	var blueprint *Blueprint
 
 	if len(args) > 1 {
 		optsObj, ok := args[1].(map[string]interface{})
@@ -733,6 +941,30 @@ func (h *RPCHandler) handleNamespaceCreate(ctx context.Context, args []interface
 			}
 		}
 
+		// Extract token label and expiry
+		var rpcErr *RPCError
+		if tokenInfo, rpcErr = parseTokenOptions(optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		// Extract shard (optional - the least loaded shard is used otherwise)
+		if shardVal, exists := optsObj["shard"]; exists {
+			shard, ok = shardVal.(string)
+			if !ok || shard == "" {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.shard must be a non-empty string",
+				}
+			}
+			if h.shards == nil {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.shard requires sharding (start the server with --shards)",
+				}
+			}
+			ctx = store.WithShard(ctx, shard)
+		}
+
 		// Extract metadata (for future use)
 		if metaVal, exists := optsObj["metadata"]; exists {
 			_, ok = metaVal.(map[string]interface{})
@@ -743,6 +975,24 @@ func (h *RPCHandler) handleNamespaceCreate(ctx context.Context, args []interface
 				}
 			}
 		}
+
+		// Extract template (optional - provisions the namespace from a blueprint)
+		if templateVal, exists := optsObj["template"]; exists {
+			template, ok = templateVal.(string)
+			if !ok || template == "" {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.template must be a non-empty string",
+				}
+			}
+			var err error
+			if blueprint, err = h.bps.Load(template); err != nil {
+				return nil, blueprintError(err)
+			}
+			if description == "" {
+				description = blueprint.Description
+			}
+		}
 	}
 
 	// Use provided token or generate one
@@ -771,6 +1021,12 @@ func (h *RPCHandler) handleNamespaceCreate(ctx context.Context, args []interface
 				Message: fmt.Sprintf("Namespace '%s' already exists", namespaceID),
 			}
 		}
+		if errors.Is(err, store.ErrUnknownShard) {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Unknown shard '%s'", shard),
+			}
+		}
 
 		return nil, &RPCError{
 			Code:    "BACKEND_ERROR",
@@ -778,17 +1034,69 @@ func (h *RPCHandler) handleNamespaceCreate(ctx context.Context, args []interface
 		}
 	}
 
+	if tokenInfo != nil {
+		if err := setTokenInfo(ctx, h.store, namespaceID, tokenInfo, ""); err != nil {
+			return nil, &RPCError{
+				Code:    "BACKEND_ERROR",
+				Message: fmt.Sprintf("Failed to store token info: %v", err),
+			}
+		}
+	}
+
+	// Provision from the blueprint; a namespace that cannot be provisioned
+	// is removed so the call can be retried
+	if blueprint != nil {
+		if err := h.bps.Apply(ctx, h.store, h.shipper, h.hooks, namespaceID, template, blueprint); err != nil {
+			if delErr := h.store.DeleteNamespace(ctx, namespaceID); delErr != nil {
+				logger.Get().Error().Err(delErr).Str("namespace", namespaceID).Msg("Failed to remove namespace after blueprint failure")
+			}
+			return nil, blueprintError(err)
+		}
+	}
+
 	// Return result
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"namespace": namespaceID,
 		"token":     token,
 		"createdAt": time.Now().UTC().Format(time.RFC3339Nano),
-	}, nil
+	}
+	if template != "" {
+		result["template"] = template
+	}
+	addTokenInfo(result, tokenInfo)
+	if h.shards != nil {
+		if placed, err := h.shards.ShardOf(ctx, namespaceID); err == nil {
+			result["shard"] = placed
+		}
+	}
+	return result, nil
+}
+
+// blueprintError maps a blueprint error to an RPC error
+func blueprintError(err error) *RPCError {
+	switch {
+	case errors.Is(err, ErrBlueprintNotFound):
+		return &RPCError{
+			Code:    "BLUEPRINT_NOT_FOUND",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrBlueprintsDisabled), errors.Is(err, ErrInvalidBlueprint):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	default:
+		return &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to provision namespace: %v", err),
+		}
+	}
 }
 
 // handleNamespaceDelete deletes a namespace and all its data
-// Request: ["ns.delete", "namespace-id"]
+// Request: ["ns.delete", "namespace-id", {opts}]
 // Response: {"namespace": "tenant-a", "deletedAt": "...", "messagesDeleted": 1543}
+// With {"dryRun": true} nothing is deleted; the response is a DeletionReport.
 func (h *RPCHandler) handleNamespaceDelete(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
 	// Validate arguments
 	if len(args) < 1 {
@@ -807,6 +1115,27 @@ func (h *RPCHandler) handleNamespaceDelete(ctx context.Context, args []interface
 		}
 	}
 
+	// Parse options
+	var dryRun bool
+	if len(args) > 1 && args[1] != nil {
+		opts, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		// A mistyped dryRun must not delete the namespace
+		if v, exists := opts["dryRun"]; exists {
+			if dryRun, ok = v.(bool); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.dryRun must be a boolean",
+				}
+			}
+		}
+	}
+
 	// Get namespace from context (auth middleware should set this)
 	// For now, we use "default" - TODO: implement proper context extraction
 	// contextNamespace := "default"
@@ -814,9 +1143,67 @@ func (h *RPCHandler) handleNamespaceDelete(ctx context.Context, args []interface
 	// Verify token matches namespace - this should be done by auth middleware
 	// For now, we allow deletion if the request is made
 
-	// Get namespace info before deletion (for message count)
-	// This is optional - we'll return 0 for now as we don't have an easy way to count
-	messagesDeleted := int64(0)
+	// Dry run: report what would be removed and record it in the audit stream
+	if dryRun {
+		if _, err := h.store.GetNamespace(ctx, namespaceID); err != nil {
+			if errors.Is(err, store.ErrNamespaceNotFound) {
+				return nil, &RPCError{
+					Code:    "NAMESPACE_NOT_FOUND",
+					Message: fmt.Sprintf("Namespace '%s' not found", namespaceID),
+				}
+			}
+			return nil, &RPCError{
+				Code:    "BACKEND_ERROR",
+				Message: fmt.Sprintf("Failed to get namespace: %v", err),
+			}
+		}
+
+		report, err := scanDeletion(ctx, h.store, namespaceID, nil)
+		if err != nil {
+			return nil, &RPCError{
+				Code:    "BACKEND_ERROR",
+				Message: fmt.Sprintf("Failed to scan namespace: %v", err),
+			}
+		}
+		report.Operation = "ns.delete"
+		report.Target = namespaceID
+		if err := recordDryRun(ctx, h.store, namespaceID, report); err != nil {
+			return nil, &RPCError{
+				Code:    "BACKEND_ERROR",
+				Message: fmt.Sprintf("Failed to record dry run: %v", err),
+			}
+		}
+
+		result := report.toResult(true)
+		result["namespace"] = namespaceID
+		return result, nil
+	}
+
+	// Frozen namespaces (e.g. under legal hold) cannot be deleted; suspended
+	// ones can, so the platform can remove namespaces it stopped serving
+	if rpcErr := h.checkWritable(ctx, namespaceID); rpcErr != nil && rpcErr.Code != "NAMESPACE_SUSPENDED" {
+		return nil, rpcErr
+	}
+
+	// WORM namespaces are kept forever
+	if err := CheckWorm(ctx, h.store, namespaceID); errors.Is(err, ErrWormProtected) {
+		return nil, wormProtectedError(namespaceID)
+	}
+
+	// Get namespace message count before deletion (best effort)
+	messagesDeleted, err := h.store.GetNamespaceMessageCount(ctx, namespaceID)
+	if err != nil {
+		messagesDeleted = 0
+	}
+
+	// Its staged import goes with it
+	if _, err := AbortStagedImport(ctx, h.store, namespaceID); err != nil &&
+		!errors.Is(err, ErrNoStagedImport) && !errors.Is(err, store.ErrNamespaceNotFound) {
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to delete staged import: %v", err),
+		}
+	}
 
 	// Delete namespace
 	if err := h.store.DeleteNamespace(ctx, namespaceID); err != nil {
@@ -858,15 +1245,18 @@ func (h *RPCHandler) handleNamespaceList(ctx context.Context, args []interface{}
 		}
 	}
 
-	// Format response
-	result := make([]interface{}, len(namespaces))
-	for i, ns := range namespaces {
-		result[i] = map[string]interface{}{
+	// Format response; staging namespaces of imports are hidden
+	result := make([]interface{}, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if IsStagingNamespace(ns.ID) {
+			continue
+		}
+		result = append(result, map[string]interface{}{
 			"namespace":    ns.ID,
 			"description":  ns.Description,
 			"createdAt":    ns.CreatedAt.UTC().Format(time.RFC3339Nano),
 			"messageCount": 0, // TODO: implement message counting
-		}
+		})
 	}
 
 	return result, nil
@@ -917,21 +1307,43 @@ func (h *RPCHandler) handleNamespaceInfo(ctx context.Context, args []interface{}
 		messageCount = 0
 	}
 
+	// Report log shipping progress when configured
+	var logShipping interface{}
+	if cfg, status := LogShippingFromMetadata(ns.Metadata); cfg != nil {
+		logShipping = logShippingInfo(cfg, status)
+	}
+
 	// Return result
 	// TODO: Implement streamCount and lastActivity
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"namespace":    ns.ID,
 		"description":  ns.Description,
 		"createdAt":    ns.CreatedAt.UTC().Format(time.RFC3339Nano),
 		"messageCount": messageCount,
 		"streamCount":  0,
 		"lastActivity": nil,
-	}, nil
+		"logShipping":  logShipping,
+		"frozen":       FreezeStateFromMetadata(ns.Metadata),
+		"suspended":    SuspensionStateFromMetadata(ns.Metadata),
+	}
+	if h.shards != nil {
+		if shard, err := h.shards.ShardOf(ctx, ns.ID); err == nil {
+			result["shard"] = shard
+		}
+	}
+	return result, nil
 }
 
+// Stream orders of ns.streams
+const (
+	streamSortName    = "name"    // By name, paged with cursor (default)
+	streamSortHottest = "hottest" // By write rate, highest first
+	streamSortStalest = "stalest" // By last write, oldest first
+)
+
 // handleNamespaceStreams lists streams in the current namespace
 // Request: ["ns.streams", {opts}]
-// Response: [{"stream": "...", "version": 5, "lastActivity": "..."}, ...]
+// Response: [{"stream": "...", "version": 5, "firstActivity": "...", "lastActivity": "...", "writesPerHour": 0.5, "aliases": [...]}, ...]
 func (h *RPCHandler) handleNamespaceStreams(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
 	namespace, rpcErr := h.getNamespace(ctx)
 	if rpcErr != nil {
@@ -939,6 +1351,8 @@ func (h *RPCHandler) handleNamespaceStreams(ctx context.Context, args []interfac
 	}
 
 	opts := &store.ListStreamsOpts{Limit: 100}
+	sortBy := streamSortName
+	now := time.Now()
 
 	if len(args) > 0 {
 		optsObj, ok := args[0].(map[string]interface{})
@@ -975,24 +1389,109 @@ func (h *RPCHandler) handleNamespaceStreams(ctx context.Context, args []interfac
 				return nil, &RPCError{Code: "INVALID_REQUEST", Message: "limit must be between 1 and 1000"}
 			}
 		}
+		if v, exists := optsObj["sort"]; exists {
+			s, ok := v.(string)
+			if !ok || (s != streamSortName && s != streamSortHottest && s != streamSortStalest) {
+				return nil, &RPCError{Code: "INVALID_REQUEST", Message: "sort must be \"name\", \"hottest\" or \"stalest\""}
+			}
+			sortBy = s
+		}
+		if sortBy != streamSortName && opts.Cursor != "" {
+			return nil, &RPCError{Code: "INVALID_REQUEST", Message: "cursor requires sort \"name\""}
+		}
+		var rpcErr *RPCError
+		if opts.InactiveSince, rpcErr = parseActivityOption(optsObj, "inactiveSince", now); rpcErr != nil {
+			return nil, rpcErr
+		}
+		if opts.OlderThan, rpcErr = parseActivityOption(optsObj, "olderThan", now); rpcErr != nil {
+			return nil, rpcErr
+		}
 	}
 
-	streams, err := h.store.ListStreams(ctx, namespace, opts)
+	var streams []*store.StreamInfo
+	var err error
+	if sortBy == streamSortName {
+		streams, err = h.store.ListStreams(ctx, namespace, opts)
+	} else {
+		streams, err = h.rankStreams(ctx, namespace, opts, sortBy, now)
+	}
 	if err != nil {
 		return nil, &RPCError{Code: "BACKEND_ERROR", Message: fmt.Sprintf("Failed to list streams: %v", err)}
 	}
 
+	aliases := h.aliases.Config(ctx, namespace)
 	result := make([]interface{}, len(streams))
 	for i, s := range streams {
-		result[i] = map[string]interface{}{
-			"stream":       s.StreamName,
-			"version":      s.Version,
-			"lastActivity": s.LastActivity.UTC().Format(time.RFC3339),
-		}
+		entry := map[string]interface{}{
+			"stream":        s.StreamName,
+			"version":       s.Version,
+			"firstActivity": s.FirstActivity.UTC().Format(time.RFC3339),
+			"lastActivity":  s.LastActivity.UTC().Format(time.RFC3339),
+			"writesPerHour": math.Round(s.WritesPerHour(now)*1000) / 1000,
+		}
+		// Old names of renamed streams
+		if names := aliases.AliasesOf(s.StreamName); len(names) > 0 {
+			entry["aliases"] = names
+		}
+		result[i] = entry
 	}
 	return result, nil
 }
 
+// parseActivityOption parses an ns.streams time filter: an RFC 3339 timestamp,
+// or a duration such as "720h" before now
+func parseActivityOption(optsObj map[string]interface{}, name string, now time.Time) (time.Time, *RPCError) {
+	v, exists := optsObj[name]
+	if !exists {
+		return time.Time{}, nil
+	}
+	s, ok := v.(string)
+	if ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t, nil
+		}
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			return now.Add(-d), nil
+		}
+	}
+	return time.Time{}, &RPCError{
+		Code:    "INVALID_REQUEST",
+		Message: fmt.Sprintf("%s must be an RFC 3339 timestamp or a positive duration such as \"720h\"", name),
+	}
+}
+
+// rankStreams lists every stream matching opts and returns the first
+// opts.Limit of them in the given order. It reads all matching streams, so
+// its cost grows with the namespace.
+func (h *RPCHandler) rankStreams(ctx context.Context, namespace string, opts *store.ListStreamsOpts, sortBy string, now time.Time) ([]*store.StreamInfo, error) {
+	page := *opts
+	page.Limit = 1000
+
+	var streams []*store.StreamInfo
+	for {
+		batch, err := h.store.ListStreams(ctx, namespace, &page)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, batch...)
+		if int64(len(batch)) < page.Limit {
+			break
+		}
+		page.Cursor = batch[len(batch)-1].StreamName
+	}
+
+	sort.SliceStable(streams, func(i, j int) bool {
+		if sortBy == streamSortHottest {
+			return streams[i].WritesPerHour(now) > streams[j].WritesPerHour(now)
+		}
+		return streams[i].LastActivity.Before(streams[j].LastActivity)
+	})
+	if int64(len(streams)) > opts.Limit {
+		streams = streams[:opts.Limit]
+	}
+	return streams, nil
+}
+
 // handleNamespaceCategories lists distinct categories in the current namespace
 // Request: ["ns.categories"]
 // Response: [{"category": "...", "streamCount": 42, "messageCount": 1500}, ...]
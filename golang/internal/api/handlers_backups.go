@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleBackupsList implements ns.backups.list
+// Args: [{limit}]
+// Returns the caller's completed backups, newest first, with whether each
+// archive can still be read.
+func (h *RPCHandler) handleBackupsList(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	limit := backupCatalogLimit
+	if len(args) > 0 && args[0] != nil {
+		optsObj, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if val, exists := optsObj["limit"]; exists {
+			v, ok := val.(float64)
+			if !ok || v < 1 {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.limit must be a positive number",
+				}
+			}
+			limit = int(v)
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, backupError(namespace, err)
+	}
+
+	backups := BackupsFromMetadata(ns.Metadata)
+	if len(backups) > limit {
+		backups = backups[:limit]
+	}
+	result := make([]interface{}, 0, len(backups))
+	for _, backup := range backups {
+		entry := encodeMetadataValue(backup).(map[string]interface{})
+		entry["available"] = backupAvailable(backup)
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// handleBackupsRestore implements ns.backups.restore
+// Args: [backupId, newNamespace, {description}]
+// Creates newNamespace and loads one of the caller's cataloged backups into
+// it, leaving the caller's namespace untouched. Returns the new namespace's
+// token like ns.create.
+func (h *RPCHandler) handleBackupsRestore(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.backups.restore requires 2 arguments: backupId, newNamespace",
+		}
+	}
+	backupID, ok := args[0].(string)
+	if !ok || backupID == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "backupId must be a non-empty string",
+		}
+	}
+	target, ok := args[1].(string)
+	if !ok || target == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "newNamespace must be a non-empty string",
+		}
+	}
+	if IsStagingNamespace(target) {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("namespace IDs starting with %q are reserved for staged imports", StagingNamespacePrefix),
+		}
+	}
+	var description string
+	if len(args) > 2 && args[2] != nil {
+		optsObj, ok := args[2].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if val, exists := optsObj["description"]; exists {
+			if description, ok = val.(string); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.description must be a string",
+				}
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.exports == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrExportSchedulingDisabled.Error(),
+		}
+	}
+	if h.guard.ReadOnly() {
+		return nil, &RPCError{
+			Code:    "READ_ONLY",
+			Message: ErrServerReadOnly.Error(),
+		}
+	}
+	if description == "" {
+		description = fmt.Sprintf("Restored from backup %s of %s", backupID, namespace)
+	}
+
+	token, err := auth.GenerateToken(target)
+	if err != nil {
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to generate token: %v", err),
+		}
+	}
+	if err := h.store.CreateNamespace(ctx, target, auth.HashToken(token), description); err != nil {
+		if errors.Is(err, store.ErrNamespaceExists) {
+			return nil, &RPCError{
+				Code:    "NAMESPACE_EXISTS",
+				Message: fmt.Sprintf("Namespace '%s' already exists", target),
+			}
+		}
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to create namespace: %v", err),
+		}
+	}
+
+	// A namespace that cannot be restored is removed so the call can be retried
+	restore, err := h.exports.Restore(ctx, h.shipper, namespace, backupID, target)
+	if err != nil {
+		if delErr := h.store.DeleteNamespace(ctx, target); delErr != nil {
+			logger.Get().Error().Err(delErr).Str("namespace", target).Msg("Failed to remove namespace after restore failure")
+		}
+		return nil, backupError(namespace, err)
+	}
+
+	logger.Get().Info().
+		Str("namespace", namespace).
+		Str("backup", backupID).
+		Str("target", target).
+		Int64("restored", restore.Restored).
+		Msg("Backup restored")
+
+	return map[string]interface{}{
+		"namespace":     target,
+		"token":         token,
+		"source":        namespace,
+		"backup":        encodeMetadataValue(restore.Backup),
+		"restored":      restore.Restored,
+		"configApplied": restore.ConfigApplied,
+		"createdAt":     time.Now().UTC().Format(time.RFC3339Nano),
+	}, nil
+}
+
+// backupError maps backup catalog and restore errors to RPC errors
+func backupError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, ErrBackupNotFound):
+		return &RPCError{
+			Code:    "BACKUP_NOT_FOUND",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrBackupUnavailable), errors.Is(err, ErrInvalidNamespaceConfig):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Restore failed: %v", err),
+	}
+}
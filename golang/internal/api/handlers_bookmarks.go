@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleBookmarkSet implements bookmark.set
+// Args: [name, globalPosition, {opts}] where opts may contain "note"
+// Creates or moves a named position in the caller's namespace. Bookmarks are
+// not writes, so they can be set while the namespace is frozen for a cutover.
+func (h *RPCHandler) handleBookmarkSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "bookmark.set requires 2 arguments: name, globalPosition",
+		}
+	}
+
+	name, rpcErr := parseBookmarkName(args[0])
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var globalPosition int64
+	switch v := args[1].(type) {
+	case float64:
+		globalPosition = int64(v)
+	case int:
+		globalPosition = int64(v)
+	case int64:
+		globalPosition = v
+	default:
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "globalPosition must be a number",
+		}
+	}
+	if globalPosition < 0 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "globalPosition must not be negative",
+		}
+	}
+
+	// Parse optional options
+	var note string
+	if len(args) > 2 && args[2] != nil {
+		opts, ok := args[2].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if v, exists := opts["note"]; exists {
+			if note, ok = v.(string); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.note must be a string",
+				}
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	bookmark, err := SetBookmark(ctx, h.store, namespace, name, globalPosition, note)
+	if err != nil {
+		return nil, bookmarkError(namespace, name, err)
+	}
+	return bookmarkInfo(name, bookmark), nil
+}
+
+// handleBookmarkGet implements bookmark.get
+// Args: [name]
+// Returns the bookmark, or BOOKMARK_NOT_FOUND.
+func (h *RPCHandler) handleBookmarkGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "bookmark.get requires 1 argument: name",
+		}
+	}
+	name, ok := args[0].(string)
+	if !ok || name == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "name must be a non-empty string",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	bookmark, err := GetBookmark(ctx, h.store, namespace, name)
+	if err != nil {
+		return nil, bookmarkError(namespace, name, err)
+	}
+	return bookmarkInfo(name, bookmark), nil
+}
+
+// handleBookmarkList implements bookmark.list
+// Args: []
+// Returns the caller's bookmarks ordered by global position, then name.
+func (h *RPCHandler) handleBookmarkList(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, bookmarkError(namespace, "", err)
+	}
+
+	bookmarks := BookmarksFromMetadata(ns.Metadata)
+	names := make([]string, 0, len(bookmarks))
+	for name := range bookmarks {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := bookmarks[names[i]], bookmarks[names[j]]
+		if a.GlobalPosition != b.GlobalPosition {
+			return a.GlobalPosition < b.GlobalPosition
+		}
+		return names[i] < names[j]
+	})
+
+	result := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		bookmark := bookmarks[name]
+		result = append(result, bookmarkInfo(name, &bookmark))
+	}
+	return result, nil
+}
+
+// handleBookmarkDelete implements bookmark.delete
+// Args: [name]
+func (h *RPCHandler) handleBookmarkDelete(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "bookmark.delete requires 1 argument: name",
+		}
+	}
+	name, ok := args[0].(string)
+	if !ok || name == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "name must be a non-empty string",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := DeleteBookmark(ctx, h.store, namespace, name); err != nil {
+		return nil, bookmarkError(namespace, name, err)
+	}
+	return map[string]interface{}{
+		"name":    name,
+		"deleted": true,
+	}, nil
+}
+
+// parseBookmarkName validates a bookmark name argument
+func parseBookmarkName(arg interface{}) (string, *RPCError) {
+	name, ok := arg.(string)
+	if !ok {
+		return "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "name must be a string",
+		}
+	}
+	if err := ValidateBookmarkName(name); err != nil {
+		return "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("Invalid name: %v", err),
+		}
+	}
+	return name, nil
+}
+
+// bookmarkInfo renders a bookmark for RPC responses
+func bookmarkInfo(name string, bookmark *Bookmark) map[string]interface{} {
+	info := map[string]interface{}{
+		"name":           name,
+		"globalPosition": bookmark.GlobalPosition,
+		"created":        bookmark.Created,
+	}
+	if bookmark.Note != "" {
+		info["note"] = bookmark.Note
+	}
+	return info
+}
+
+// parseGlobalPositionOption reads a global position option given as a number
+// or a bookmark name of the caller's namespace
+func (h *RPCHandler) parseGlobalPositionOption(ctx context.Context, option string, value interface{}) (int64, *RPCError) {
+	switch v := value.(type) {
+	case float64:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case string:
+		namespace, rpcErr := h.getNamespace(ctx)
+		if rpcErr != nil {
+			return 0, rpcErr
+		}
+		bookmark, err := GetBookmark(ctx, h.store, namespace, v)
+		if err != nil {
+			return 0, bookmarkError(namespace, v, err)
+		}
+		return bookmark.GlobalPosition, nil
+	default:
+		return 0, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("options.%s must be a number or a bookmark name", option),
+		}
+	}
+}
+
+// bookmarkError maps bookmark errors to RPC errors
+func bookmarkError(namespace, name string, err error) *RPCError {
+	switch {
+	case errors.Is(err, ErrBookmarkNotFound):
+		return &RPCError{
+			Code:    "BOOKMARK_NOT_FOUND",
+			Message: fmt.Sprintf("Bookmark '%s' not found", name),
+		}
+	case errors.Is(err, ErrTooManyBookmarks):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to access bookmarks: %v", err),
+	}
+}
@@ -0,0 +1,54 @@
+// Package api provides the sys.capabilities RPC handler.
+package api
+
+import (
+	"context"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleSysCapabilities describes server behavior that clients reproduce or
+// choose between, such as how consumer groups assign streams to members
+// Request: ["sys.capabilities"]
+// Response: {"protocolVersion": "1.0", "consumerGroups": {...}, "messageIds": {"strategy": "uuidv7", "strategies": [...]}, "globalPositions": {...}}
+func (h *RPCHandler) handleSysCapabilities(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	partitioners := make([]map[string]interface{}, len(store.Partitioners))
+	for i, p := range store.Partitioners {
+		partitioners[i] = map[string]interface{}{
+			"name":        string(p),
+			"description": p.Description(),
+		}
+	}
+
+	// The caller's namespace strategy, or the default without one
+	strategy := IDStrategyUUIDv7
+	namespace, ok := GetNamespaceFromContext(ctx)
+	if ok {
+		strategy = h.ids.Strategy(ctx, namespace)
+	}
+	positions := store.PositionGuaranteeOf(ctx, h.store, namespace)
+	strategies := make([]string, len(IDStrategies))
+	for i, s := range IDStrategies {
+		strategies[i] = string(s)
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"consumerGroups": map[string]interface{}{
+			"defaultPartitioner": string(store.PartitionerMD5),
+			"partitioners":       partitioners,
+			"cardinalId":         "The stream ID before the first '+'; streams without an ID are assigned to no member",
+			"orderingKey":        "With consumerGroup.orderingKey, messages are assigned by metadata.orderingKey, falling back to the cardinal ID",
+		},
+		"messageIds": map[string]interface{}{
+			"strategy":   string(strategy),
+			"strategies": strategies,
+		},
+		"globalPositions": map[string]interface{}{
+			"allocator":     positions.Allocator,
+			"gapless":       positions.Gapless,
+			"commitOrdered": positions.CommitOrdered,
+			"description":   positions.Description,
+		},
+	}, nil
+}
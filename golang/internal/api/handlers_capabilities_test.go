@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+func TestSysCapabilities(t *testing.T) {
+	h := NewRPCHandler("test", nil, NewPubSub())
+
+	result, rpcErr := h.route(context.Background(), "sys.capabilities", nil)
+	if rpcErr != nil {
+		t.Fatalf("sys.capabilities failed: %v", rpcErr)
+	}
+	caps := result.(map[string]interface{})
+	groups := caps["consumerGroups"].(map[string]interface{})
+	if groups["defaultPartitioner"] != "md5" {
+		t.Errorf("Expected md5 as the default partitioner, got %v", groups["defaultPartitioner"])
+	}
+	if partitioners := groups["partitioners"].([]map[string]interface{}); len(partitioners) != len(store.Partitioners) {
+		t.Errorf("Expected %d partitioners, got %d", len(store.Partitioners), len(partitioners))
+	}
+	if positions := caps["globalPositions"].(map[string]interface{}); positions["allocator"] != "unknown" || positions["gapless"] != false {
+		t.Errorf("Expected no position guarantee without a store, got %v", positions)
+	}
+
+	// SQLite serializes writes and rolls its sequence back with failed ones
+	h = NewRPCHandler("test", newTestStore(t), NewPubSub())
+	result, rpcErr = h.route(context.WithValue(context.Background(), ContextKeyNamespace, "test-ns"), "sys.capabilities", nil)
+	if rpcErr != nil {
+		t.Fatalf("sys.capabilities failed: %v", rpcErr)
+	}
+	positions := result.(map[string]interface{})["globalPositions"].(map[string]interface{})
+	if positions["allocator"] != store.AllocatorSequence || positions["gapless"] != true || positions["commitOrdered"] != true {
+		t.Errorf("Unexpected SQLite position guarantee: %v", positions)
+	}
+}
+
+func TestCategoryGet_Partitioner(t *testing.T) {
+	st := newTestStore(t)
+	for i := 0; i < 10; i++ {
+		stream := fmt.Sprintf("order-%d", i)
+		if _, err := st.WriteMessage(context.Background(), "test-ns", stream, &store.Message{
+			StreamName: stream,
+			Type:       "Placed",
+			Data:       map[string]interface{}{},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	result, rpcErr := h.route(ctx, "category.get", []interface{}{"order", map[string]interface{}{
+		"consumerGroup": map[string]interface{}{"member": float64(1), "size": float64(2), "partitioner": "jump"},
+	}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr)
+	}
+	for _, item := range result.([]interface{}) {
+		stream := item.([]interface{})[1].(string)
+		if got := store.PartitionerJump.Member(stream, 2); got != 1 {
+			t.Errorf("Stream %s returned to member 1, jump assigns it to %d", stream, got)
+		}
+	}
+
+	_, rpcErr = h.route(ctx, "category.get", []interface{}{"order", map[string]interface{}{
+		"consumerGroup": map[string]interface{}{"member": float64(0), "size": float64(2), "partitioner": "crc32"},
+	}})
+	if rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Fatalf("Expected INVALID_REQUEST for an unknown partitioner, got %v", rpcErr)
+	}
+}
+
+func TestCategoryGet_OrderingKey(t *testing.T) {
+	st := newTestStore(t)
+	for i := 0; i < 12; i++ {
+		stream := fmt.Sprintf("order-%d", i)
+		if _, err := st.WriteMessage(context.Background(), "test-ns", stream, &store.Message{
+			StreamName: stream,
+			Type:       "Placed",
+			Data:       map[string]interface{}{},
+			Metadata:   map[string]interface{}{store.OrderingKeyMetadataKey: fmt.Sprintf("customer-%d", i%3)},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	total := 0
+	for member := 0; member < 2; member++ {
+		result, rpcErr := h.route(ctx, "category.get", []interface{}{"order", map[string]interface{}{
+			"batchSize":     float64(100),
+			"consumerGroup": map[string]interface{}{"member": float64(member), "size": float64(2), "orderingKey": true},
+		}})
+		if rpcErr != nil {
+			t.Fatalf("category.get failed: %v", rpcErr)
+		}
+		for _, item := range result.([]interface{}) {
+			metadata := item.([]interface{})[6].(map[string]interface{})
+			key := metadata[store.OrderingKeyMetadataKey].(string)
+			if got := store.PartitionerMD5.KeyMember(key, 2); got != int64(member) {
+				t.Errorf("Key %s returned to member %d, md5 assigns it to %d", key, member, got)
+			}
+			total++
+		}
+	}
+	if total != 12 {
+		t.Errorf("Expected the members to get all 12 messages, got %d", total)
+	}
+
+	_, rpcErr := h.route(ctx, "category.get", []interface{}{"order", map[string]interface{}{
+		"consumerGroup": map[string]interface{}{"member": float64(0), "size": float64(2), "orderingKey": "yes"},
+	}})
+	if rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Fatalf("Expected INVALID_REQUEST for a non-boolean orderingKey, got %v", rpcErr)
+	}
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// casWriteAttempts bounds the retries of stream.casWrite when the stream is
+// written between reading its last message and appending
+const casWriteAttempts = 3
+
+// casCondition is the predicate stream.casWrite checks on the last message
+type casCondition struct {
+	exists *bool         // Stream must (true) or must not (false) have messages
+	types  []string      // Last message must have one of these types
+	filter *store.Filter // Last message must match this EQL expression
+}
+
+// parseCASCondition parses a stream.casWrite condition object; null matches
+// any stream
+func parseCASCondition(v interface{}) (*casCondition, *RPCError) {
+	cond := &casCondition{}
+	if v == nil {
+		return cond, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "condition must be an object or null",
+		}
+	}
+	for key, val := range obj {
+		switch key {
+		case "exists":
+			exists, ok := val.(bool)
+			if !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "condition.exists must be a boolean",
+				}
+			}
+			cond.exists = &exists
+		case "type":
+			switch t := val.(type) {
+			case string:
+				cond.types = []string{t}
+			case []interface{}:
+				for _, item := range t {
+					s, ok := item.(string)
+					if !ok {
+						return nil, &RPCError{
+							Code:    "INVALID_REQUEST",
+							Message: "condition.type must be a string or an array of strings",
+						}
+					}
+					cond.types = append(cond.types, s)
+				}
+			default:
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "condition.type must be a string or an array of strings",
+				}
+			}
+		case "where":
+			where, ok := val.(string)
+			if !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "condition.where must be a string",
+				}
+			}
+			filter, err := store.ParseFilter(where)
+			if err != nil {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("condition.where: %v", err),
+				}
+			}
+			cond.filter = filter
+		default:
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("unknown condition key %q", key),
+			}
+		}
+	}
+	if cond.exists != nil && !*cond.exists && (len(cond.types) > 0 || cond.filter != nil) {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "condition.exists false cannot be combined with type or where",
+		}
+	}
+	return cond, nil
+}
+
+// match reports whether last, the stream's last message or nil for an
+// empty stream, satisfies the condition. type and where require a message.
+func (c *casCondition) match(last *store.Message) bool {
+	if c.exists != nil && *c.exists != (last != nil) {
+		return false
+	}
+	if len(c.types) == 0 && c.filter == nil {
+		return true
+	}
+	if last == nil {
+		return false
+	}
+	if len(c.types) > 0 {
+		matched := false
+		for _, t := range c.types {
+			if last.Type == t {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return c.filter == nil || c.filter.Match(last)
+}
+
+// handleStreamCASWrite implements stream.casWrite
+// Args: [streamName, condition, message, {id}]
+// Appends message only if the stream's last message satisfies condition
+// ({exists, type, where}). The message is written with the version the
+// condition was checked at as expectedVersion, so no write can slip in
+// between; if one does, the condition is checked again.
+func (h *RPCHandler) handleStreamCASWrite(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 3 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "stream.casWrite requires at least 3 arguments: streamName, condition and message",
+		}
+	}
+
+	// Parse stream name
+	streamName, ok := args[0].(string)
+	if !ok || streamName == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "streamName must be a non-empty string",
+		}
+	}
+	if err := h.names.Validate(streamName); err != nil {
+		return nil, invalidStreamNameError(err)
+	}
+
+	cond, rpcErr := parseCASCondition(args[1])
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Parse message object
+	msgObj, ok := args[2].(map[string]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "message must be an object",
+		}
+	}
+	parsed, rpcErr := parseWriteMessage(msgObj, "message")
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if len(args) > 3 && args[3] != nil {
+		optsObj, ok := args[3].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if idVal, exists := optsObj["id"]; exists {
+			if parsed.ID, ok = idVal.(string); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.id must be a string",
+				}
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Reads and writes of a renamed stream use its new name
+	streamName = h.aliases.Resolve(ctx, namespace, streamName)
+
+	for attempt := 0; ; attempt++ {
+		last, err := h.store.GetLastStreamMessage(ctx, namespace, streamName, nil)
+		if errors.Is(err, store.ErrStreamNotFound) {
+			last, err = nil, nil
+		}
+		if err != nil {
+			if store.IsOverloaded(err) {
+				return nil, overloadedError(err)
+			}
+			return nil, &RPCError{
+				Code:    "BACKEND_ERROR",
+				Message: fmt.Sprintf("Failed to get last message: %v", err),
+			}
+		}
+
+		version := int64(-1)
+		if last != nil {
+			version = last.Position
+		}
+		if !cond.match(last) {
+			return nil, conditionFailedError(streamName, version, last)
+		}
+
+		// Plugins may change the message, so each attempt writes a fresh copy
+		msg := *parsed
+		msg.Data = maps.Clone(parsed.Data)
+		msg.Metadata = maps.Clone(parsed.Metadata)
+		msg.ExpectedVersion = &version
+
+		result, rpcErr := h.appendMessages(ctx, namespace, streamName, []*store.Message{&msg}, false)
+		if rpcErr != nil && rpcErr.Code == "STREAM_VERSION_CONFLICT" && attempt+1 < casWriteAttempts {
+			continue
+		}
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		// Same response as stream.write
+		response := result.(map[string]interface{})
+		delete(response, "messages")
+		return response, nil
+	}
+}
+
+// conditionFailedError reports a stream.casWrite condition that did not
+// hold, with the stream's version and last message so the caller can decide
+func conditionFailedError(streamName string, version int64, last *store.Message) *RPCError {
+	var lastMessage interface{}
+	if last != nil {
+		lastMessage = []interface{}{
+			last.ID,
+			last.Type,
+			last.Position,
+			last.GlobalPosition,
+			last.Data,
+			last.Metadata,
+			last.Time.UTC().Format(time.RFC3339Nano),
+		}
+	}
+	return &RPCError{
+		Code:    "CONDITION_FAILED",
+		Message: fmt.Sprintf("Last message of %s does not satisfy the condition", streamName),
+		Details: map[string]interface{}{
+			"version": version,
+			"last":    lastMessage,
+		},
+	}
+}
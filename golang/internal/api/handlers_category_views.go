@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleCategoryViewCreate implements viewCategory.create
+// Args: [name, [category, ...]]
+// Defines a virtual category that reads, and can be subscribed to, as the
+// union of the given categories.
+func (h *RPCHandler) handleCategoryViewCreate(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "viewCategory.create requires 2 arguments: name, categories",
+		}
+	}
+
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "name must be a string",
+		}
+	}
+	list, ok := args[1].([]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "categories must be an array of strings",
+		}
+	}
+	view := CategoryView{Name: name, Categories: make([]string, 0, len(list))}
+	for _, v := range list {
+		category, ok := v.(string)
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "categories must be an array of strings",
+			}
+		}
+		view.Categories = append(view.Categories, category)
+	}
+	if err := view.Validate(); err != nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("Invalid view: %v", err),
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.views.Create(ctx, namespace, view); err != nil {
+		return nil, categoryViewError(namespace, name, err)
+	}
+	return categoryViewInfo(view), nil
+}
+
+// handleCategoryViewDelete implements viewCategory.delete
+// Args: [name]
+// Removes a view; the messages of its categories are not touched.
+func (h *RPCHandler) handleCategoryViewDelete(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "viewCategory.delete requires 1 argument: name",
+		}
+	}
+	name, ok := args[0].(string)
+	if !ok || name == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "name must be a non-empty string",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.views.Delete(ctx, namespace, name); err != nil {
+		return nil, categoryViewError(namespace, name, err)
+	}
+	return map[string]interface{}{"name": name, "deleted": true}, nil
+}
+
+// handleCategoryViewList implements viewCategory.list
+// Args: []
+// Returns the views of the caller's namespace.
+func (h *RPCHandler) handleCategoryViewList(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, categoryViewError(namespace, "", err)
+	}
+	cfg := CategoryViewsFromMetadata(ns.Metadata)
+	result := make([]interface{}, len(cfg.Views))
+	for i, view := range cfg.Views {
+		result[i] = categoryViewInfo(view)
+	}
+	return result, nil
+}
+
+// categoryViewInfo renders a view for RPC responses
+func categoryViewInfo(view CategoryView) map[string]interface{} {
+	categories := make([]interface{}, len(view.Categories))
+	for i, category := range view.Categories {
+		categories[i] = category
+	}
+	return map[string]interface{}{
+		"name":       view.Name,
+		"categories": categories,
+	}
+}
+
+// categoryViewError maps category view errors to RPC errors
+func categoryViewError(namespace, name string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, ErrCategoryViewExists):
+		return &RPCError{
+			Code:    "VIEW_EXISTS",
+			Message: err.Error(),
+			Details: map[string]interface{}{"name": name},
+		}
+	case errors.Is(err, ErrInvalidCategoryView):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrCategoryViewNotFound):
+		return &RPCError{
+			Code:    "VIEW_NOT_FOUND",
+			Message: err.Error(),
+			Details: map[string]interface{}{"name": name},
+		}
+	}
+	if store.IsOverloaded(err) {
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update category views: %v", err),
+	}
+}
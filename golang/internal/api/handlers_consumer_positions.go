@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// maxConsumerLength bounds the length of a consumer identifier
+const maxConsumerLength = 256
+
+// handleConsumerSetPosition implements consumer.setPosition
+// Args: [consumer, globalPosition]
+// Records the last global position a consumer has processed, replacing the
+// previous one. Positions are not messages, so they can be set while the
+// namespace is frozen.
+func (h *RPCHandler) handleConsumerSetPosition(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "consumer.setPosition requires 2 arguments: consumer, globalPosition",
+		}
+	}
+
+	consumer, rpcErr := parseConsumer(args[0])
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var globalPosition int64
+	switch v := args[1].(type) {
+	case float64:
+		globalPosition = int64(v)
+	case int:
+		globalPosition = int64(v)
+	case int64:
+		globalPosition = v
+	default:
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "globalPosition must be a number",
+		}
+	}
+	if globalPosition < 0 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "globalPosition must not be negative",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	positions, ok := h.store.(store.ConsumerPositionStore)
+	if !ok {
+		return nil, consumerPositionError(namespace, consumer, store.ErrNotSupported)
+	}
+	position, err := positions.SetConsumerPosition(ctx, namespace, consumer, globalPosition)
+	if err != nil {
+		return nil, consumerPositionError(namespace, consumer, err)
+	}
+	return consumerPositionInfo(position), nil
+}
+
+// handleConsumerGetPosition implements consumer.getPosition
+// Args: [consumer]
+// Returns the consumer's last recorded position, or CONSUMER_POSITION_NOT_FOUND.
+func (h *RPCHandler) handleConsumerGetPosition(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "consumer.getPosition requires 1 argument: consumer",
+		}
+	}
+
+	consumer, rpcErr := parseConsumer(args[0])
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	positions, ok := h.store.(store.ConsumerPositionStore)
+	if !ok {
+		return nil, consumerPositionError(namespace, consumer, store.ErrNotSupported)
+	}
+	position, err := positions.GetConsumerPosition(ctx, namespace, consumer)
+	if err != nil {
+		return nil, consumerPositionError(namespace, consumer, err)
+	}
+	return consumerPositionInfo(position), nil
+}
+
+// handleConsumerAck implements consumer.ack
+// Args: [consumer, globalPosition]
+// Acknowledges a message sent to the consumer's at-least-once durable
+// subscription, so it is not redelivered. The consumer's position advances to
+// just before its oldest unacked message.
+func (h *RPCHandler) handleConsumerAck(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "consumer.ack requires 2 arguments: consumer, globalPosition",
+		}
+	}
+
+	consumer, rpcErr := parseConsumer(args[0])
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var globalPosition int64
+	switch v := args[1].(type) {
+	case float64:
+		globalPosition = int64(v)
+	case int:
+		globalPosition = int64(v)
+	case int64:
+		globalPosition = v
+	default:
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "globalPosition must be a number",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	result, err := h.durable.Ack(ctx, namespace, consumer, globalPosition)
+	switch {
+	case errors.Is(err, ErrDurableSubscriptionNotFound):
+		return nil, &RPCError{
+			Code:    "SUBSCRIPTION_NOT_FOUND",
+			Message: fmt.Sprintf("Consumer '%s' has no durable subscription on this server", consumer),
+			Details: map[string]interface{}{"consumer": consumer},
+		}
+	case errors.Is(err, ErrAckNotExpected):
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("Consumer '%s' subscribes at-most-once; its messages are not acknowledged", consumer),
+		}
+	case err != nil:
+		return nil, consumerPositionError(namespace, consumer, err)
+	}
+
+	info := map[string]interface{}{
+		"consumer":       consumer,
+		"globalPosition": globalPosition,
+		"acked":          result.Acked,
+		"position":       nil,
+		"unacked":        result.Unacked,
+	}
+	if result.Committed >= 0 {
+		info["position"] = result.Committed
+	}
+	return info, nil
+}
+
+// parseConsumer validates a consumer identifier argument
+func parseConsumer(arg interface{}) (string, *RPCError) {
+	consumer, ok := arg.(string)
+	if !ok || consumer == "" {
+		return "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "consumer must be a non-empty string",
+		}
+	}
+	if len(consumer) > maxConsumerLength {
+		return "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("consumer is longer than %d bytes", maxConsumerLength),
+		}
+	}
+	for _, r := range consumer {
+		if unicode.IsControl(r) {
+			return "", &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "consumer must not contain control characters",
+			}
+		}
+	}
+	return consumer, nil
+}
+
+// consumerPositionInfo renders a consumer position for RPC responses
+func consumerPositionInfo(position *store.ConsumerPosition) map[string]interface{} {
+	return map[string]interface{}{
+		"consumer":       position.Consumer,
+		"globalPosition": position.GlobalPosition,
+		"updated":        position.Updated.Format(time.RFC3339Nano),
+	}
+}
+
+// consumerPositionError maps consumer position errors to RPC errors
+func consumerPositionError(namespace, consumer string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrConsumerPositionNotFound):
+		return &RPCError{
+			Code:    "CONSUMER_POSITION_NOT_FOUND",
+			Message: fmt.Sprintf("Consumer '%s' has no recorded position", consumer),
+			Details: map[string]interface{}{"consumer": consumer},
+		}
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, store.ErrNotSupported):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "Consumer positions are not supported by the backend",
+		}
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to access consumer position: %v", err),
+	}
+}
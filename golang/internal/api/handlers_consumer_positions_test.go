@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestConsumerPositions tests that consumer.setPosition replaces a
+// consumer's position and consumer.getPosition returns it
+func TestConsumerPositions(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := h.route(ctx, "consumer.getPosition", []interface{}{"billing"}); rpcErr == nil || rpcErr.Code != "CONSUMER_POSITION_NOT_FOUND" {
+		t.Fatalf("Expected CONSUMER_POSITION_NOT_FOUND, got %v", rpcErr)
+	}
+
+	for _, gp := range []float64{10, 42} {
+		if _, rpcErr := h.route(ctx, "consumer.setPosition", []interface{}{"billing", gp}); rpcErr != nil {
+			t.Fatalf("consumer.setPosition failed: %v", rpcErr.Message)
+		}
+	}
+
+	result, rpcErr := h.route(ctx, "consumer.getPosition", []interface{}{"billing"})
+	if rpcErr != nil {
+		t.Fatalf("consumer.getPosition failed: %v", rpcErr.Message)
+	}
+	if pos := result.(map[string]interface{}); pos["globalPosition"] != int64(42) || pos["consumer"] != "billing" || pos["updated"] == "" {
+		t.Errorf("Unexpected position: %v", pos)
+	}
+
+	for _, args := range [][]interface{}{
+		{"billing", float64(-1)},
+		{"billing", "42"},
+		{"", float64(1)},
+		{"bill\ning", float64(1)},
+	} {
+		if _, rpcErr := h.route(ctx, "consumer.setPosition", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+}
+
+// TestConsumerPositions_Errors tests invalid consumer arguments, that
+// consumers are tracked independently and that positions can be set while
+// the namespace is frozen
+func TestConsumerPositions_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, args := range [][]interface{}{
+		{},
+		{""},
+		{float64(1)},
+		{strings.Repeat("c", maxConsumerLength+1)},
+	} {
+		if _, rpcErr := h.route(ctx, "consumer.getPosition", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "consumer.setPosition", []interface{}{"billing"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a missing position, got %v", rpcErr)
+	}
+
+	// Positions may move backwards and are kept per consumer
+	for _, args := range [][]interface{}{{"billing", float64(42)}, {"billing", float64(0)}, {"shipping", float64(7)}} {
+		if _, rpcErr := h.route(ctx, "consumer.setPosition", args); rpcErr != nil {
+			t.Fatalf("consumer.setPosition failed for %v: %v", args, rpcErr.Message)
+		}
+	}
+	for consumer, want := range map[string]int64{"billing": 0, "shipping": 7} {
+		result, rpcErr := h.route(ctx, "consumer.getPosition", []interface{}{consumer})
+		if rpcErr != nil {
+			t.Fatalf("consumer.getPosition failed: %v", rpcErr.Message)
+		}
+		if pos := result.(map[string]interface{}); pos["globalPosition"] != want {
+			t.Errorf("Expected %s at %d, got %v", consumer, want, pos)
+		}
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "consumer.setPosition", []interface{}{"billing", float64(3)}); rpcErr != nil {
+		t.Errorf("Expected consumer.setPosition to work while frozen, got %v", rpcErr)
+	}
+
+	missing := context.WithValue(context.Background(), ContextKeyNamespace, "missing-ns")
+	if _, rpcErr := h.route(missing, "consumer.setPosition", []interface{}{"billing", float64(1)}); rpcErr == nil || rpcErr.Code != "NAMESPACE_NOT_FOUND" {
+		t.Errorf("Expected NAMESPACE_NOT_FOUND for a missing namespace, got %v", rpcErr)
+	}
+}
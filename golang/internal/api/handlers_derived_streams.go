@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleDerivedStreamsSet implements ns.derivedStreams.set
+// Args: [{rules: [{category, types, stream, type, fields}]}] or [null] to remove the rules
+// Replaces the derived stream rules of the caller's namespace. Rules apply to
+// stream.write calls from the next write on this instance, and within a few
+// seconds on others.
+func (h *RPCHandler) handleDerivedStreamsSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.derivedStreams.set requires 1 argument: config (or null to remove)",
+		}
+	}
+
+	cfg := &DerivedStreamsConfig{}
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.derived.Set(ctx, namespace, cfg); err != nil {
+		return nil, derivedStreamsError(namespace, err)
+	}
+	return derivedStreamsInfo(cfg), nil
+}
+
+// handleDerivedStreamsGet implements ns.derivedStreams.get
+// Args: []
+// Returns the derived stream rules of the caller's namespace.
+func (h *RPCHandler) handleDerivedStreamsGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, derivedStreamsError(namespace, err)
+	}
+	return derivedStreamsInfo(DerivedStreamsFromMetadata(ns.Metadata)), nil
+}
+
+// derivedStreamsInfo renders a derived streams config for RPC responses
+func derivedStreamsInfo(cfg *DerivedStreamsConfig) map[string]interface{} {
+	rules := make([]interface{}, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		rules = append(rules, encodeMetadataValue(rule))
+	}
+	return map[string]interface{}{"rules": rules}
+}
+
+// derivedInfo renders the streams and positions derived events were written
+// to; positions are null for events that failed after a non-atomic write
+func derivedInfo(derived []*store.Message, results []*store.WriteResult) []interface{} {
+	out := make([]interface{}, len(derived))
+	for i, d := range derived {
+		info := map[string]interface{}{
+			"streamName":     d.StreamName,
+			"position":       nil,
+			"globalPosition": nil,
+		}
+		if i < len(results) && results[i] != nil {
+			info["position"] = results[i].Position
+			info["globalPosition"] = results[i].GlobalPosition
+		}
+		out[i] = info
+	}
+	return out
+}
+
+// derivedStreamsError maps derived stream errors to RPC errors
+func derivedStreamsError(namespace string, err error) *RPCError {
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update derived streams: %v", err),
+	}
+}
@@ -0,0 +1,45 @@
+// Package api provides the sys.describe RPC handler.
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// handleSysDescribe implements sys.describe
+// Args: [method?]
+// Returns a method's arguments, option keys and example requests, or the
+// name and summary of every method when called without one. The registry
+// is the one served at GET /schema.
+func (h *RPCHandler) handleSysDescribe(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	if len(args) == 0 || args[0] == nil {
+		docs := MethodDocs()
+		methods := make([]map[string]interface{}, len(docs))
+		for i, doc := range docs {
+			methods[i] = map[string]interface{}{
+				"method":  doc.Method,
+				"summary": doc.Summary,
+			}
+		}
+		return map[string]interface{}{
+			"protocol": ProtocolVersion,
+			"methods":  methods,
+		}, nil
+	}
+
+	method, ok := args[0].(string)
+	if !ok || method == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "method must be a non-empty string",
+		}
+	}
+	doc, ok := DescribeMethod(method)
+	if !ok {
+		return nil, &RPCError{
+			Code:    "METHOD_NOT_FOUND",
+			Message: fmt.Sprintf("Unknown method: %s", method),
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,569 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleDocPut implements doc.put
+// Args: [collection, id, {doc}, {opts}] where opts may contain
+// "globalPosition" and "expectedVersion"
+// Stores a read-model document. With globalPosition, the put is skipped
+// ("applied": false) when the document already reflects that event, and the
+// collection's last-applied position moves to it.
+func (h *RPCHandler) handleDocPut(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 3 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "doc.put requires 3 arguments: collection, id, doc",
+		}
+	}
+	collection, id, rpcErr := parseDocumentKeys(args)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	data, ok := args[2].(map[string]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "doc must be an object",
+		}
+	}
+	var opts DocumentWriteOpts
+	if len(args) > 3 {
+		if opts, rpcErr = parseDocumentWriteOpts(args[3]); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	return h.writeDocument(ctx, collection, id, data, opts)
+}
+
+// handleDocGet implements doc.get
+// Args: [collection, id]
+// Returns the document, or DOCUMENT_NOT_FOUND when it was never put or was deleted.
+func (h *RPCHandler) handleDocGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "doc.get requires 2 arguments: collection, id",
+		}
+	}
+	collection, id, rpcErr := parseDocumentKeys(args)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	doc, err := GetDocument(ctx, h.store, namespace, collection, id)
+	if err == nil && doc.Deleted {
+		err = ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, documentError(namespace, collection, id, err)
+	}
+	return map[string]interface{}{
+		"collection":            collection,
+		"id":                    id,
+		"doc":                   doc.Data,
+		"version":               doc.Version,
+		"appliedGlobalPosition": documentApplied(doc.Applied),
+		"updated":               doc.Updated,
+	}, nil
+}
+
+// handleDocDelete implements doc.delete
+// Args: [collection, id, {opts}] with the options of doc.put
+// Deletes a document; "deleted" is false when there was none to delete.
+func (h *RPCHandler) handleDocDelete(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "doc.delete requires 2 arguments: collection, id",
+		}
+	}
+	collection, id, rpcErr := parseDocumentKeys(args)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	var opts DocumentWriteOpts
+	if len(args) > 2 {
+		if opts, rpcErr = parseDocumentWriteOpts(args[2]); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	return h.writeDocument(ctx, collection, id, nil, opts)
+}
+
+// handleDocWrite implements doc.write
+// Args: [{"docs": [...], "messages": [...], "globalPosition": n}] where each
+// doc is {collection, id, doc, expectedVersion} (no doc deletes it) and each
+// message is {stream, type, data, metadata, id, expectedVersion}
+// Writes the document changes and messages in one backend transaction, so a
+// projection acknowledges an event and updates its read model together.
+func (h *RPCHandler) handleDocWrite(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "doc.write requires 1 argument: batch",
+		}
+	}
+	batchObj, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "batch must be an object",
+		}
+	}
+
+	var batch DocumentBatch
+	rawDocs, _ := batchObj["docs"].([]interface{})
+	rawMsgs, _ := batchObj["messages"].([]interface{})
+	if (batchObj["docs"] != nil && rawDocs == nil) || (batchObj["messages"] != nil && rawMsgs == nil) {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "batch.docs and batch.messages must be arrays",
+		}
+	}
+	if len(rawDocs) == 0 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "batch.docs must not be empty",
+		}
+	}
+	if len(rawDocs)+len(rawMsgs) > maxDocumentBatch {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("a batch holds at most %d documents and messages", maxDocumentBatch),
+		}
+	}
+
+	for i, raw := range rawDocs {
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("doc %d must be an object", i),
+			}
+		}
+		collection, id, rpcErr := parseDocumentKeys([]interface{}{obj["collection"], obj["id"]})
+		if rpcErr != nil {
+			rpcErr.Message = fmt.Sprintf("doc %d: %s", i, rpcErr.Message)
+			return nil, rpcErr
+		}
+		change := DocumentChange{Collection: collection, ID: id}
+		if obj["doc"] != nil {
+			if change.Data, ok = obj["doc"].(map[string]interface{}); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("doc %d: doc must be an object", i),
+				}
+			}
+		}
+		if obj["expectedVersion"] != nil {
+			v, ok := obj["expectedVersion"].(float64)
+			if !ok || v < -1 {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("doc %d: expectedVersion must be a number of at least -1", i),
+				}
+			}
+			version := int64(v)
+			change.ExpectedVersion = &version
+		}
+		batch.Changes = append(batch.Changes, change)
+	}
+
+	for i, raw := range rawMsgs {
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("message %d must be an object", i),
+			}
+		}
+		msg := &store.Message{}
+		msg.ID, _ = obj["id"].(string)
+		msg.StreamName, _ = obj["stream"].(string)
+		msg.Type, _ = obj["type"].(string)
+		if msg.StreamName == "" || msg.Type == "" {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("message %d requires stream and type", i),
+			}
+		}
+		if err := h.names.Validate(msg.StreamName); err != nil {
+			return nil, invalidStreamNameError(err)
+		}
+		if msg.Data, ok = obj["data"].(map[string]interface{}); !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("message %d: data must be an object", i),
+			}
+		}
+		if obj["metadata"] != nil {
+			if msg.Metadata, ok = obj["metadata"].(map[string]interface{}); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("message %d: metadata must be an object", i),
+				}
+			}
+		}
+		if obj["expectedVersion"] != nil {
+			v, ok := obj["expectedVersion"].(float64)
+			if !ok || v < -1 {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("message %d: expectedVersion must be a number of at least -1", i),
+				}
+			}
+			version := int64(v)
+			msg.ExpectedVersion = &version
+		}
+		batch.Messages = append(batch.Messages, msg)
+	}
+
+	if val, exists := batchObj["globalPosition"]; exists {
+		v, ok := val.(float64)
+		if !ok || v < 0 {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "batch.globalPosition must be a non-negative number",
+			}
+		}
+		position := int64(v)
+		batch.GlobalPosition = &position
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Reject writes to frozen namespaces and read-only servers
+	if rpcErr := h.checkWritable(ctx, namespace); rpcErr != nil {
+		return nil, rpcErr
+	}
+	for _, change := range batch.Changes {
+		streamName := DocumentStreamName(change.Collection, change.ID)
+		if err := h.names.Validate(streamName); err != nil {
+			return nil, invalidStreamNameError(err)
+		}
+		if rpcErr := h.checkHubOwned(namespace, streamName); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+	for _, msg := range batch.Messages {
+		if rpcErr := h.checkHubOwned(namespace, msg.StreamName); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	// Shed load before it reaches the backend
+	if wait, ok := h.admit.Admit(namespace, PriorityInteractive, len(batch.Changes)+len(batch.Messages)); !ok {
+		return nil, h.admit.rateLimitedError(namespace, PriorityInteractive, wait)
+	}
+
+	// Messages get the namespace's IDs, metadata defaults and plugin checks,
+	// as with stream.write
+	for _, msg := range batch.Messages {
+		if msg.ID == "" {
+			id, err := h.ids.Strategy(ctx, namespace).NewID()
+			if err != nil {
+				return nil, &RPCError{
+					Code:    "INTERNAL_ERROR",
+					Message: fmt.Sprintf("failed to generate message ID: %v", err),
+				}
+			}
+			msg.ID = id
+		}
+		h.tmpls.Apply(ctx, namespace, msg)
+		if err := h.plugins.Apply(ctx, namespace, msg); err != nil {
+			return nil, pluginsError(namespace, err)
+		}
+	}
+
+	write, err := WriteDocuments(ctx, h.store, namespace, batch)
+	if err != nil {
+		return nil, documentError(namespace, "", "", err)
+	}
+	h.publishDocumentWrite(namespace, write)
+
+	docs := make([]map[string]interface{}, len(batch.Changes))
+	for i, change := range batch.Changes {
+		docs[i] = map[string]interface{}{
+			"collection": change.Collection,
+			"id":         change.ID,
+			"applied":    write.Changes[i].Applied,
+			"version":    write.Changes[i].Version,
+		}
+	}
+	messages := make([]map[string]interface{}, len(batch.Messages))
+	for i, msg := range batch.Messages {
+		result := write.Results[i]
+		messages[i] = map[string]interface{}{
+			"id":             msg.ID,
+			"stream":         msg.StreamName,
+			"position":       result.Position,
+			"globalPosition": result.GlobalPosition,
+		}
+	}
+	return map[string]interface{}{
+		"docs":     docs,
+		"messages": messages,
+	}, nil
+}
+
+// handleDocPosition implements doc.position
+// Args: [collection]
+// Returns the last global position applied to the collection by doc.put or
+// doc.delete, null if none was given; projections resume reading after it.
+func (h *RPCHandler) handleDocPosition(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "doc.position requires 1 argument: collection",
+		}
+	}
+	collection, ok := args[0].(string)
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "collection must be a string",
+		}
+	}
+	if err := ValidateDocumentKey(collection, true); err != nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	position, ok, err := DocumentsPosition(ctx, h.store, namespace, collection)
+	if err != nil {
+		return nil, documentError(namespace, collection, "", err)
+	}
+	result := map[string]interface{}{
+		"collection":     collection,
+		"globalPosition": nil,
+	}
+	if ok {
+		result["globalPosition"] = position
+	}
+	return result, nil
+}
+
+// writeDocument puts (data non-nil) or deletes a document after the checks
+// stream.write applies, and notifies subscribers of the written streams
+func (h *RPCHandler) writeDocument(ctx context.Context, collection, id string, data map[string]interface{}, opts DocumentWriteOpts) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Reject writes to frozen namespaces and read-only servers
+	if rpcErr := h.checkWritable(ctx, namespace); rpcErr != nil {
+		return nil, rpcErr
+	}
+	streamName := DocumentStreamName(collection, id)
+	if err := h.names.Validate(streamName); err != nil {
+		return nil, invalidStreamNameError(err)
+	}
+	if rpcErr := h.checkHubOwned(namespace, streamName); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Shed load before it reaches the backend
+	if wait, ok := h.admit.Admit(namespace, PriorityInteractive, 1); !ok {
+		return nil, h.admit.rateLimitedError(namespace, PriorityInteractive, wait)
+	}
+
+	write, err := WriteDocument(ctx, h.store, namespace, collection, id, data, opts)
+	if err != nil {
+		return nil, documentError(namespace, collection, id, err)
+	}
+	h.publishDocumentWrite(namespace, write)
+
+	result := map[string]interface{}{
+		"applied": write.Changes[0].Applied,
+		"version": write.Changes[0].Version,
+	}
+	if data == nil {
+		result["deleted"] = documentDeleted(write, DocumentStreamName(collection, id))
+	}
+	return result, nil
+}
+
+// publishDocumentWrite notifies subscribers of the streams a document write wrote to
+func (h *RPCHandler) publishDocumentWrite(namespace string, write *DocumentBatchWrite) {
+	if h.pubsub == nil {
+		return
+	}
+	for i, result := range write.Results {
+		if result == nil {
+			continue
+		}
+		stream := write.Messages[i].StreamName
+		h.pubsub.Publish(WriteEvent{
+			Namespace:      namespace,
+			Stream:         stream,
+			Category:       store.Category(stream),
+			Position:       result.Position,
+			GlobalPosition: result.GlobalPosition,
+		})
+	}
+}
+
+// documentDeleted reports whether a write deleted the document in streamName
+func documentDeleted(write *DocumentBatchWrite, streamName string) bool {
+	for _, msg := range write.Messages {
+		if msg.StreamName == streamName && msg.Type == DocumentDeletedType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDocumentKeys parses the collection and id arguments
+func parseDocumentKeys(args []interface{}) (string, string, *RPCError) {
+	collection, ok := args[0].(string)
+	if !ok {
+		return "", "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "collection must be a string",
+		}
+	}
+	id, ok := args[1].(string)
+	if !ok {
+		return "", "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "id must be a string",
+		}
+	}
+	for _, key := range []struct {
+		value      string
+		collection bool
+	}{{collection, true}, {id, false}} {
+		if err := ValidateDocumentKey(key.value, key.collection); err != nil {
+			return "", "", &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			}
+		}
+	}
+	return collection, id, nil
+}
+
+// parseDocumentWriteOpts parses the options of doc.put and doc.delete
+func parseDocumentWriteOpts(arg interface{}) (DocumentWriteOpts, *RPCError) {
+	var opts DocumentWriteOpts
+	if arg == nil {
+		return opts, nil
+	}
+	optsObj, ok := arg.(map[string]interface{})
+	if !ok {
+		return opts, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options must be an object",
+		}
+	}
+
+	if val, exists := optsObj["globalPosition"]; exists {
+		v, ok := val.(float64)
+		if !ok || v < 0 {
+			return opts, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options.globalPosition must be a non-negative number",
+			}
+		}
+		position := int64(v)
+		opts.GlobalPosition = &position
+	}
+	if val, exists := optsObj["expectedVersion"]; exists {
+		v, ok := val.(float64)
+		if !ok || v < -1 {
+			return opts, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options.expectedVersion must be a number of at least -1",
+			}
+		}
+		version := int64(v)
+		opts.ExpectedVersion = &version
+	}
+	return opts, nil
+}
+
+// documentApplied formats a document's applied global position, null if unknown
+func documentApplied(applied *int64) interface{} {
+	if applied == nil {
+		return nil
+	}
+	return *applied
+}
+
+// documentError maps document store errors to RPC errors
+func documentError(namespace, collection, id string, err error) *RPCError {
+	var conflict *store.VersionConflictError
+	switch {
+	case errors.Is(err, ErrDocumentNotFound):
+		return &RPCError{
+			Code:    "DOCUMENT_NOT_FOUND",
+			Message: fmt.Sprintf("Collection '%s' has no document '%s'", collection, id),
+			Details: map[string]interface{}{"collection": collection, "id": id},
+		}
+	case errors.Is(err, ErrInvalidDocumentBatch):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case errors.Is(err, store.ErrNotSupported):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "Transactional document writes are not supported by the backend",
+		}
+	case errors.As(err, &conflict):
+		return &RPCError{
+			Code:    "STREAM_VERSION_CONFLICT",
+			Message: fmt.Sprintf("Expected version %d, stream %s is at version %d", conflict.ExpectedVersion, conflict.StreamName, conflict.ActualVersion),
+			Details: map[string]interface{}{
+				"expected": conflict.ExpectedVersion,
+				"actual":   conflict.ActualVersion,
+			},
+		}
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case store.IsBackendUnavailable(err):
+		return backendUnavailableError(err)
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to access document: %v", err),
+	}
+}
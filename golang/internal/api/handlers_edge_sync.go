@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleEdgePush implements edge.push, called by edge servers on their hub
+// Args: [messages] where each message is {id, stream, type, data, metadata}
+// Appends the messages with hub-assigned positions, skipping IDs the hub
+// already has so that a batch re-sent after a lost response is not duplicated.
+func (h *RPCHandler) handleEdgePush(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "edge.push requires 1 argument: messages",
+		}
+	}
+	rawMsgs, ok := args[0].([]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "messages must be an array",
+		}
+	}
+	if len(rawMsgs) > edgeSyncMaxBatch {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("at most %d messages can be pushed at once", edgeSyncMaxBatch),
+		}
+	}
+
+	msgs := make([]*store.Message, len(rawMsgs))
+	for i, raw := range rawMsgs {
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("message %d must be an object", i),
+			}
+		}
+		msg := &store.Message{}
+		msg.ID, _ = obj["id"].(string)
+		msg.StreamName, _ = obj["stream"].(string)
+		msg.Type, _ = obj["type"].(string)
+		if msg.ID == "" || msg.StreamName == "" || msg.Type == "" {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("message %d requires id, stream and type", i),
+			}
+		}
+		if err := h.names.Validate(msg.StreamName); err != nil {
+			return nil, invalidStreamNameError(err)
+		}
+		if obj["data"] != nil {
+			if msg.Data, ok = obj["data"].(map[string]interface{}); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("message %d: data must be an object", i),
+				}
+			}
+		}
+		if obj["metadata"] != nil {
+			if msg.Metadata, ok = obj["metadata"].(map[string]interface{}); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("message %d: metadata must be an object", i),
+				}
+			}
+		}
+		msgs[i] = msg
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Reject writes to frozen namespaces and read-only servers
+	if rpcErr := h.checkWritable(ctx, namespace); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Edge sync is background traffic, like imports
+	if wait, ok := h.admit.Admit(namespace, PriorityImport, len(msgs)); !ok {
+		return nil, h.admit.rateLimitedError(namespace, PriorityImport, wait)
+	}
+
+	existing, err := edgeSyncDuplicates(ctx, h.store, namespace, msgs)
+	if err != nil {
+		return nil, edgeSyncError(namespace, err)
+	}
+
+	results := make([]map[string]interface{}, 0, len(msgs))
+	written := 0
+	for _, msg := range msgs {
+		if existing[msg.ID] {
+			results = append(results, map[string]interface{}{
+				"id":        msg.ID,
+				"duplicate": true,
+			})
+			continue
+		}
+		result, err := h.store.WriteMessage(ctx, namespace, msg.StreamName, msg)
+		if err != nil {
+			return nil, edgeSyncError(namespace, err)
+		}
+		existing[msg.ID] = true
+		written++
+
+		if h.pubsub != nil {
+			h.pubsub.Publish(WriteEvent{
+				Namespace:      namespace,
+				Stream:         msg.StreamName,
+				Category:       store.Category(msg.StreamName),
+				Position:       result.Position,
+				GlobalPosition: result.GlobalPosition,
+			})
+		}
+		results = append(results, map[string]interface{}{
+			"id":             msg.ID,
+			"position":       result.Position,
+			"globalPosition": result.GlobalPosition,
+		})
+	}
+
+	return map[string]interface{}{
+		"written":    written,
+		"duplicates": len(msgs) - written,
+		"results":    results,
+	}, nil
+}
+
+// handleEdgeSyncSet implements ns.edgeSync.set
+// Args: [config] where config is an EdgeSyncConfig object, or null to stop syncing
+// Configures the caller's namespace as an edge that syncs categories with a hub.
+func (h *RPCHandler) handleEdgeSyncSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.edgeSync.set requires 1 argument: config (or null to stop syncing)",
+		}
+	}
+
+	var cfg *EdgeSyncConfig
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		cfg = &EdgeSyncConfig{}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.edge == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrEdgeSyncDisabled.Error(),
+		}
+	}
+
+	if err := h.edge.Configure(ctx, namespace, cfg); err != nil {
+		return nil, edgeSyncError(namespace, err)
+	}
+	return h.handleEdgeSyncStatus(ctx, nil)
+}
+
+// handleEdgeSyncStatus implements ns.edgeSync.status
+// Args: []
+// Returns the caller's edge sync config (token masked) and progress.
+func (h *RPCHandler) handleEdgeSyncStatus(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, edgeSyncError(namespace, err)
+	}
+
+	cfg, status := EdgeSyncFromMetadata(ns.Metadata)
+	if cfg == nil {
+		return map[string]interface{}{"enabled": false}, nil
+	}
+	if status == nil {
+		status = &EdgeSyncStatus{State: EdgeSyncStateActive}
+	}
+	return map[string]interface{}{
+		"enabled": true,
+		"config":  encodeMetadataValue(cfg.Redacted()),
+		"status":  encodeMetadataValue(status),
+	}, nil
+}
+
+// checkHubOwned returns a READ_ONLY error for local writes to a category an
+// edge namespace pulls from its hub
+func (h *RPCHandler) checkHubOwned(namespace, streamName string) *RPCError {
+	if !h.edge.HubOwned(namespace, streamName) {
+		return nil
+	}
+	return &RPCError{
+		Code:    "READ_ONLY",
+		Message: fmt.Sprintf("Category '%s' is synced from the hub and only written there", store.Category(streamName)),
+	}
+}
+
+// edgeSyncError maps edge sync errors to RPC errors
+func edgeSyncError(namespace string, err error) *RPCError {
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	if store.IsOverloaded(err) {
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Edge sync failed: %v", err),
+	}
+}
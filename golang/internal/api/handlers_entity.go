@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// entityLoadBatchSize is the default number of events entity.load returns
+// after the snapshot
+const entityLoadBatchSize = 1000
+
+// SnapshotStreamName returns the stream snapshots of an entity stream are
+// recorded in by default: {category}:snapshot-{id}, as laid out by Eventide's
+// entity snapshots
+func SnapshotStreamName(streamName string) string {
+	name := store.Category(streamName) + ":snapshot"
+	if id := store.ID(streamName); id != "" {
+		name += "-" + id
+	}
+	return name
+}
+
+// snapshotVersion returns the stream position a snapshot covers, from its
+// metadata.version or else its data.version
+func snapshotVersion(msg *store.Message) (int64, bool) {
+	for _, fields := range []map[string]interface{}{msg.Metadata, msg.Data} {
+		switch v := fields["version"].(type) {
+		case float64:
+			return int64(v), v >= 0
+		case int64:
+			return v, v >= 0
+		case int:
+			return int64(v), v >= 0
+		case json.Number:
+			n, err := v.Int64()
+			return n, err == nil && n >= 0
+		}
+	}
+	return 0, false
+}
+
+// formatMessage formats a message as returned by stream.get
+func formatMessage(msg *store.Message) []interface{} {
+	return []interface{}{
+		msg.ID,
+		msg.Type,
+		msg.Position,
+		msg.GlobalPosition,
+		msg.Data,
+		msg.Metadata,
+		msg.Time.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// handleEntityLoad implements entity.load
+// Request: ["entity.load", "streamName", {opts}]
+// Response: {"snapshot": [...] or null, "snapshotVersion": 4 or null,
+// "events": [[...], ...], "version": 7, "hasMore": false}
+// Returns the stream's latest snapshot and the events after it, so an entity
+// is rehydrated in one round trip instead of stream.last then stream.get.
+func (h *RPCHandler) handleEntityLoad(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "entity.load requires at least 1 argument: streamName",
+		}
+	}
+
+	// Parse stream name
+	streamName, ok := args[0].(string)
+	if !ok || streamName == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "streamName must be a non-empty string",
+		}
+	}
+
+	// Parse options
+	snapshotStream := SnapshotStreamName(streamName)
+	batchSize := int64(entityLoadBatchSize)
+	var decode bool
+	var rpcErr *RPCError
+
+	if len(args) > 1 {
+		optsObj, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+
+		if val, exists := optsObj["snapshotStream"]; exists {
+			name, ok := val.(string)
+			if !ok || name == "" {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.snapshotStream must be a non-empty string",
+				}
+			}
+			snapshotStream = name
+		}
+
+		if val, exists := optsObj["batchSize"]; exists {
+			v, ok := val.(float64)
+			if !ok || v < 1 || v > 10000 {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.batchSize must be a number from 1 to 10000",
+				}
+			}
+			batchSize = int64(v)
+		}
+
+		if decode, rpcErr = parseDecodeOption(optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
+		if ctx, rpcErr = parsePriorityOption(ctx, optsObj); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+	if snapshotStream == streamName {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options.snapshotStream must differ from streamName",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Reads of a renamed stream read its new name
+	streamName = h.aliases.Resolve(ctx, namespace, streamName)
+
+	// Latest snapshot; one without a version cannot be applied
+	snapshot, err := h.store.GetLastStreamMessage(ctx, namespace, snapshotStream, nil)
+	if err != nil && !errors.Is(err, store.ErrStreamNotFound) {
+		return nil, entityLoadError(err)
+	}
+	version := int64(-1)
+	if snapshot != nil {
+		if v, ok := snapshotVersion(snapshot); ok {
+			version = v
+		} else {
+			snapshot = nil
+		}
+	}
+
+	events, err := h.readEntityEvents(ctx, namespace, streamName, version+1, batchSize)
+	if err != nil {
+		return nil, entityLoadError(err)
+	}
+	if snapshot != nil && len(events) == 0 {
+		// A snapshot past the end of the stream is from before the stream
+		// was deleted and rewritten
+		current, err := h.store.GetStreamVersion(ctx, namespace, streamName)
+		if err != nil {
+			return nil, entityLoadError(err)
+		}
+		if current < version {
+			snapshot, version = nil, -1
+			if events, err = h.readEntityEvents(ctx, namespace, streamName, 0, batchSize); err != nil {
+				return nil, entityLoadError(err)
+			}
+		}
+	}
+
+	hasMore := int64(len(events)) > batchSize
+	if hasMore {
+		events = events[:batchSize]
+	}
+	toDecode := events
+	if snapshot != nil {
+		if snapshot.StreamName == "" {
+			snapshot.StreamName = snapshotStream
+		}
+		toDecode = append([]*store.Message{snapshot}, events...)
+	}
+	if rpcErr := h.decodeMessages(ctx, namespace, decode, toDecode); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	result := map[string]interface{}{
+		"snapshot":        nil,
+		"snapshotVersion": nil,
+		"hasMore":         hasMore,
+	}
+	if snapshot != nil {
+		result["snapshot"] = formatMessage(snapshot)
+		result["snapshotVersion"] = version
+	}
+	items := make([]interface{}, len(events))
+	for i, msg := range events {
+		items[i] = formatMessage(msg)
+		version = msg.Position
+	}
+	result["events"] = items
+	result["version"] = version
+	return result, nil
+}
+
+// readEntityEvents reads up to batchSize+1 events of a stream from position,
+// the extra one telling whether more follow
+func (h *RPCHandler) readEntityEvents(ctx context.Context, namespace, streamName string, position, batchSize int64) ([]*store.Message, error) {
+	return h.store.GetStreamMessages(ctx, namespace, streamName, &store.GetOpts{
+		Position:  position,
+		BatchSize: batchSize + 1,
+	})
+}
+
+// entityLoadError maps a store error to an RPC error
+func entityLoadError(err error) *RPCError {
+	if store.IsOverloaded(err) {
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to load entity: %v", err),
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestEntityLoad tests that entity.load returns the latest snapshot and the
+// events after it
+func TestEntityLoad(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	write := func(stream, msgType string, data, metadata map[string]interface{}) {
+		t.Helper()
+		if _, err := st.WriteMessage(ctx, "test-ns", stream, &store.Message{
+			StreamName: stream,
+			Type:       msgType,
+			Data:       data,
+			Metadata:   metadata,
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	load := func(opts map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		args := []interface{}{"account-1"}
+		if opts != nil {
+			args = append(args, opts)
+		}
+		result, rpcErr := h.route(ctx, "entity.load", args)
+		if rpcErr != nil {
+			t.Fatalf("entity.load failed: %v", rpcErr.Message)
+		}
+		return result.(map[string]interface{})
+	}
+
+	// Without a snapshot every event is returned
+	result := load(nil)
+	if result["snapshot"] != nil || result["version"] != int64(-1) || len(result["events"].([]interface{})) != 0 {
+		t.Errorf("Expected an empty entity, got %v", result)
+	}
+	for i := 0; i < 5; i++ {
+		write("account-1", "Deposited", map[string]interface{}{"amount": float64(i)}, nil)
+	}
+	result = load(nil)
+	if result["snapshot"] != nil || len(result["events"].([]interface{})) != 5 || result["version"] != int64(4) {
+		t.Errorf("Expected 5 events without a snapshot, got %v", result)
+	}
+
+	// Events after the snapshot's version follow it
+	write("account:snapshot-1", "Recorded", map[string]interface{}{"balance": 6.0}, map[string]interface{}{"version": 3.0})
+	result = load(map[string]interface{}{"batchSize": 1.0})
+	events := result["events"].([]interface{})
+	if result["snapshot"] == nil || result["snapshotVersion"] != int64(3) || len(events) != 1 ||
+		events[0].([]interface{})[2] != int64(4) || result["version"] != int64(4) || result["hasMore"] != false {
+		t.Errorf("Expected the snapshot at 3 and the event at 4, got %v", result)
+	}
+	write("account-1", "Deposited", map[string]interface{}{"amount": 5.0}, nil)
+	if result = load(map[string]interface{}{"batchSize": 1.0}); result["hasMore"] != true || result["version"] != int64(4) {
+		t.Errorf("Expected a partial load, got %v", result)
+	}
+
+	// A custom snapshot stream with the version in data
+	write("accountSnapshot-1", "Recorded", map[string]interface{}{"balance": 15.0, "version": 5.0}, nil)
+	result = load(map[string]interface{}{"snapshotStream": "accountSnapshot-1"})
+	if result["snapshotVersion"] != int64(5) || len(result["events"].([]interface{})) != 0 || result["version"] != int64(5) {
+		t.Errorf("Expected the snapshot at 5 and no events, got %v", result)
+	}
+
+	// A snapshot past the end of the stream is ignored
+	write("account:snapshot-1", "Recorded", map[string]interface{}{}, map[string]interface{}{"version": 50.0})
+	if result = load(nil); result["snapshot"] != nil || len(result["events"].([]interface{})) != 6 {
+		t.Errorf("Expected a stale snapshot to be ignored, got %v", result)
+	}
+
+	if _, rpcErr := h.route(ctx, "entity.load", []interface{}{"account-1", map[string]interface{}{"batchSize": 0.0}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a zero batch size, got %v", rpcErr)
+	}
+	if SnapshotStreamName("account-1+retry") != "account:snapshot-1+retry" || SnapshotStreamName("account") != "account:snapshot" {
+		t.Errorf("Unexpected snapshot stream names")
+	}
+}
+
+// TestEntityLoad_Errors tests invalid arguments, that snapshots without a
+// usable version are ignored and that renamed streams load by their old name
+func TestEntityLoad_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, args := range [][]interface{}{
+		{},
+		{""},
+		{float64(1)},
+		{"account-1", "snapshots"},
+		{"account-1", map[string]interface{}{"snapshotStream": ""}},
+		{"account-1", map[string]interface{}{"snapshotStream": "account-1"}},
+		{"account-1", map[string]interface{}{"batchSize": 10001.0}},
+		{"account-1", map[string]interface{}{"batchSize": "10"}},
+	} {
+		if _, rpcErr := h.route(ctx, "entity.load", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-old", map[string]interface{}{"type": "Deposited", "data": map[string]interface{}{}}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "stream.rename", []interface{}{"account-old", "account-1"}); rpcErr != nil {
+		t.Fatalf("stream.rename failed: %v", rpcErr.Message)
+	}
+
+	// Snapshots without a version, or with a negative one, cannot be applied
+	for _, metadata := range []map[string]interface{}{nil, {"version": "2"}, {"version": -1.0}} {
+		if _, err := st.WriteMessage(ctx, "test-ns", "account:snapshot-old", &store.Message{
+			StreamName: "account:snapshot-old",
+			Type:       "Recorded",
+			Data:       map[string]interface{}{},
+			Metadata:   metadata,
+		}); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+		result, rpcErr := h.route(ctx, "entity.load", []interface{}{"account-old", map[string]interface{}{"snapshotStream": "account:snapshot-old"}})
+		if rpcErr != nil {
+			t.Fatalf("entity.load failed: %v", rpcErr.Message)
+		}
+		if loaded := result.(map[string]interface{}); loaded["snapshot"] != nil || len(loaded["events"].([]interface{})) != 3 || loaded["version"] != int64(2) {
+			t.Errorf("Expected the snapshot with metadata %v to be ignored, got %v", metadata, loaded)
+		}
+	}
+}
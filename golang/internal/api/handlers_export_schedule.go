@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleExportScheduleSet implements ns.exportSchedule.set
+// Args: [config] where config is an ExportScheduleConfig object, or null to disable
+// Exports the caller's namespace on a cron schedule to a server directory or S3.
+func (h *RPCHandler) handleExportScheduleSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.exportSchedule.set requires 1 argument: config (or null to disable)",
+		}
+	}
+
+	var cfg *ExportScheduleConfig
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		cfg = &ExportScheduleConfig{}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.exports == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrExportSchedulingDisabled.Error(),
+		}
+	}
+
+	if err := h.exports.Configure(ctx, namespace, cfg); err != nil {
+		return nil, exportScheduleError(namespace, err)
+	}
+	return h.handleExportScheduleGet(ctx, nil)
+}
+
+// handleExportScheduleGet implements ns.exportSchedule.get
+// Args: []
+// Returns the caller's export schedule (secrets masked) and status, or null.
+func (h *RPCHandler) handleExportScheduleGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, exportScheduleError(namespace, err)
+	}
+
+	cfg, status := ExportScheduleFromMetadata(ns.Metadata)
+	if cfg == nil {
+		return nil, nil
+	}
+	if status == nil {
+		status = &ExportScheduleStatus{}
+	}
+	return map[string]interface{}{
+		"config": encodeMetadataValue(cfg.Redacted()),
+		"status": encodeMetadataValue(status),
+	}, nil
+}
+
+// handleExportScheduleRun implements ns.exportSchedule.run
+// Args: []
+// Exports the caller's namespace now to its scheduled destination and
+// returns the updated status. The run is recorded like a scheduled one.
+func (h *RPCHandler) handleExportScheduleRun(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.exports == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrExportSchedulingDisabled.Error(),
+		}
+	}
+
+	status, err := h.exports.Run(ctx, namespace)
+	if err != nil {
+		return nil, exportScheduleError(namespace, err)
+	}
+	return encodeMetadataValue(status), nil
+}
+
+// exportScheduleError maps export schedule errors to RPC errors
+func exportScheduleError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, ErrNoExportSchedule), errors.Is(err, ErrExportDirDisabled):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Export failed: %v", err),
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleNamespaceFreeze implements ns.freeze
+// Args: [{opts}] where opts may contain "reason"
+// Rejects writes to the caller's namespace with READ_ONLY until ns.unfreeze.
+func (h *RPCHandler) handleNamespaceFreeze(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Parse optional options
+	var reason string
+	if len(args) > 0 && args[0] != nil {
+		opts, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if v, exists := opts["reason"]; exists {
+			if reason, ok = v.(string); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.reason must be a string",
+				}
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	state, err := h.guard.Freeze(ctx, namespace, reason)
+	if err != nil {
+		return nil, freezeError(namespace, err)
+	}
+
+	return map[string]interface{}{
+		"namespace": namespace,
+		"frozen":    true,
+		"since":     state.Since,
+		"reason":    state.Reason,
+	}, nil
+}
+
+// handleNamespaceUnfreeze implements ns.unfreeze
+// Args: []
+// Allows writes to the caller's namespace again.
+func (h *RPCHandler) handleNamespaceUnfreeze(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.guard.Unfreeze(ctx, namespace); err != nil {
+		return nil, freezeError(namespace, err)
+	}
+
+	return map[string]interface{}{
+		"namespace": namespace,
+		"frozen":    false,
+	}, nil
+}
+
+// checkWritable returns a READ_ONLY or NAMESPACE_SUSPENDED error if writes to
+// namespace are rejected, or the error of a failed namespace lookup
+func (h *RPCHandler) checkWritable(ctx context.Context, namespace string) *RPCError {
+	err := h.guard.Check(ctx, namespace)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, ErrNamespaceSuspended) {
+		return suspendedError(namespace)
+	}
+	if errors.Is(err, ErrReadOnly) {
+		return &RPCError{
+			Code:    "READ_ONLY",
+			Message: fmt.Sprintf("Namespace '%s' is frozen", namespace),
+		}
+	}
+	if errors.Is(err, ErrServerReadOnly) || errors.Is(err, ErrNamespaceMirrored) {
+		return &RPCError{
+			Code:    "READ_ONLY",
+			Message: err.Error(),
+		}
+	}
+	return guardLookupError(namespace, err)
+}
+
+// freezeError maps freeze/unfreeze errors to RPC errors
+func freezeError(namespace string, err error) *RPCError {
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update namespace: %v", err),
+	}
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// maxGapWait bounds category.get's waitForGaps option
+	maxGapWait = 30 * time.Second
+
+	// gapPollInterval is how often reads waiting for gaps search again
+	gapPollInterval = 50 * time.Millisecond
+)
+
+// handleCategoryGaps implements category.gaps
+// Args: [categoryName, {position, to}]
+// Lists the global positions from position (default 1, or a bookmark name)
+// to to that hold no message, and whether a write to the category may still
+// commit at them. At most store.MaxGapRange positions are searched.
+func (h *RPCHandler) handleCategoryGaps(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "category.gaps requires at least 1 argument: categoryName",
+		}
+	}
+	categoryName, ok := args[0].(string)
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "categoryName must be a string",
+		}
+	}
+
+	from := int64(1)
+	var to *int64
+	if len(args) > 1 {
+		optsObj, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if posVal, exists := optsObj["position"]; exists {
+			var rpcErr *RPCError
+			if from, rpcErr = h.parseGlobalPositionOption(ctx, "position", posVal); rpcErr != nil {
+				return nil, rpcErr
+			}
+		}
+		if toVal, exists := optsObj["to"]; exists {
+			v, ok := toVal.(float64)
+			if !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.to must be a number",
+				}
+			}
+			t := int64(v)
+			to = &t
+		}
+	}
+	if from < 1 {
+		from = 1
+	}
+	if to == nil {
+		t := from + store.MaxGapRange - 1
+		to = &t
+	}
+	if *to-from >= store.MaxGapRange {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("category.gaps searches at most %d positions", store.MaxGapRange),
+		}
+	}
+
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	gaps, err := store.FindPositionGaps(ctx, h.store, namespace, categoryName, from, *to)
+	if err != nil {
+		if store.IsOverloaded(err) {
+			return nil, overloadedError(err)
+		}
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to find gaps: %v", err),
+		}
+	}
+
+	result := make([]interface{}, len(gaps))
+	inFlight := false
+	for i, gap := range gaps {
+		result[i] = map[string]interface{}{
+			"from":     gap.From,
+			"to":       gap.To,
+			"inFlight": gap.InFlight,
+		}
+		inFlight = inFlight || gap.InFlight
+	}
+	return map[string]interface{}{
+		"gaps":     result,
+		"inFlight": inFlight,
+	}, nil
+}
+
+// parseWaitForGapsOption reads options.waitForGaps, the milliseconds a read
+// waits for in-flight gaps among its messages to resolve. It returns nil
+// without the option; 0 drops messages past in-flight gaps without waiting.
+func parseWaitForGapsOption(optsObj map[string]interface{}) (*time.Duration, *RPCError) {
+	val, exists := optsObj["waitForGaps"]
+	if !exists {
+		return nil, nil
+	}
+	ms, ok := val.(float64)
+	wait := time.Duration(ms) * time.Millisecond
+	if !ok || ms < 0 || wait > maxGapWait {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("options.waitForGaps must be a number of milliseconds from 0 to %d", maxGapWait.Milliseconds()),
+		}
+	}
+	if _, exists := optsObj["cursor"]; exists {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options.waitForGaps cannot be combined with options.cursor, which holds back messages past in-flight gaps itself",
+		}
+	}
+	return &wait, nil
+}
+
+// awaitGaps holds a category read until no in-flight gap lies between from
+// and its last message, re-reading as gaps resolve. After wait, messages
+// past the first in-flight gap are dropped, so the next read, continuing
+// after the last message returned, still sees them. Only the last
+// store.MaxGapRange positions of a batch are searched.
+func awaitGaps(ctx context.Context, st store.Store, namespace, categoryName string, from int64, wait time.Duration, msgs []*store.Message, read func() ([]*store.Message, error)) ([]*store.Message, error) {
+	deadline := time.Now().Add(wait)
+	for len(msgs) > 0 {
+		last := msgs[len(msgs)-1].GlobalPosition
+		gaps, err := store.FindPositionGaps(ctx, st, namespace, categoryName, max(from, last-store.MaxGapRange+1), last)
+		if err != nil {
+			return nil, err
+		}
+		var inFlight *store.PositionGap
+		for i := range gaps {
+			if gaps[i].InFlight {
+				inFlight = &gaps[i]
+				break
+			}
+		}
+		if inFlight == nil {
+			return msgs, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			for i, msg := range msgs {
+				if msg.GlobalPosition > inFlight.From {
+					return msgs[:i], nil
+				}
+			}
+			return msgs, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(gapPollInterval):
+		}
+		if msgs, err = read(); err != nil {
+			return nil, err
+		}
+	}
+	return msgs, nil
+}
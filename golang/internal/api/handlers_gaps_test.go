@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// pendingWritesStore reports fixed pending writes, as Postgres does while
+// another session holds a category lock
+type pendingWritesStore struct {
+	store.Store
+	pending *store.PendingWrites
+}
+
+func (s *pendingWritesStore) PendingWrites(ctx context.Context, namespace, category string) (*store.PendingWrites, error) {
+	return s.pending, nil
+}
+
+// TestCategoryGaps tests that category.gaps tells deleted positions from
+// in-flight writes and that waitForGaps holds back messages past them
+func TestCategoryGaps(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, stream := range []string{"account-1", "account-1", "audit-1", "account-2"} {
+		if _, err := st.WriteMessage(ctx, "test-ns", stream, &store.Message{Type: "Noted", Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	// Global position 1 is deleted
+	if _, err := st.(store.StreamTruncater).TruncateStream(ctx, "test-ns", "account-1", 1); err != nil {
+		t.Fatalf("Failed to truncate stream: %v", err)
+	}
+
+	gapsOf := func(h *RPCHandler, category string) map[string]interface{} {
+		t.Helper()
+		result, rpcErr := h.route(ctx, "category.gaps", []interface{}{category, map[string]interface{}{"to": float64(10)}})
+		if rpcErr != nil {
+			t.Fatalf("category.gaps failed: %v", rpcErr.Message)
+		}
+		return result.(map[string]interface{})
+	}
+
+	// SQLite commits in order, so gaps are final
+	h := NewRPCHandler("test", st, NewPubSub())
+	result := gapsOf(h, "account")
+	gaps := result["gaps"].([]interface{})
+	if len(gaps) != 1 || result["inFlight"] != false {
+		t.Fatalf("Expected one final gap, got %v", result)
+	}
+	if gap := gaps[0].(map[string]interface{}); gap["from"] != int64(1) || gap["to"] != int64(1) {
+		t.Errorf("Expected the gap at position 1, got %v", gap)
+	}
+
+	// Positions 5 and 6 are allocated to a write in progress
+	pending := &pendingWritesStore{Store: st, pending: &store.PendingWrites{Allocated: 6, InFlight: true}}
+	h = NewRPCHandler("test", pending, NewPubSub())
+	gaps = gapsOf(h, "account")["gaps"].([]interface{})
+	if len(gaps) != 2 {
+		t.Fatalf("Expected two gaps, got %v", gaps)
+	}
+	if gap := gaps[0].(map[string]interface{}); gap["inFlight"] != false {
+		t.Errorf("Expected the deleted position below the category's last message to be final, got %v", gap)
+	}
+	if gap := gaps[1].(map[string]interface{}); gap["from"] != int64(5) || gap["to"] != int64(6) || gap["inFlight"] != true {
+		t.Errorf("Expected positions 5 to 6 in flight, got %v", gap)
+	}
+
+	// Across categories every gap may belong to the write
+	gaps = gapsOf(h, "")["gaps"].([]interface{})
+	if gap := gaps[0].(map[string]interface{}); gap["inFlight"] != true {
+		t.Errorf("Expected all gaps in flight for all categories, got %v", gap)
+	}
+
+	// Reads past an in-flight gap stop before it
+	read, rpcErr := h.route(ctx, "category.get", []interface{}{"", map[string]interface{}{"waitForGaps": float64(0)}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr.Message)
+	}
+	if msgs := read.([]interface{}); len(msgs) != 0 {
+		t.Errorf("Expected messages after the in-flight gap to be held back, got %d", len(msgs))
+	}
+	read, rpcErr = h.route(ctx, "category.get", []interface{}{"account", map[string]interface{}{"waitForGaps": float64(10)}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr.Message)
+	}
+	if msgs := read.([]interface{}); len(msgs) != 2 {
+		t.Errorf("Expected both account messages, got %d", len(msgs))
+	}
+
+	if _, rpcErr := h.route(ctx, "category.get", []interface{}{"account", map[string]interface{}{"waitForGaps": float64(60000)}}); rpcErr == nil {
+		t.Error("Expected waitForGaps above the maximum to be rejected")
+	}
+}
+
+// TestCategoryGaps_Errors tests invalid arguments, the search range limit
+// and that contiguous positions have no gaps
+func TestCategoryGaps_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, args := range [][]interface{}{
+		{},
+		{float64(1)},
+		{"account", "all"},
+		{"account", map[string]interface{}{"to": "10"}},
+		{"account", map[string]interface{}{"position": float64(1), "to": float64(store.MaxGapRange + 1)}},
+	} {
+		if _, rpcErr := h.route(ctx, "category.gaps", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	for _, opts := range []map[string]interface{}{
+		{"waitForGaps": float64(-1)},
+		{"waitForGaps": "10"},
+		{"waitForGaps": float64(10), "cursor": "abc"},
+	} {
+		if _, rpcErr := h.route(ctx, "category.get", []interface{}{"account", opts}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", opts, rpcErr)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := st.WriteMessage(ctx, "test-ns", "account-1", &store.Message{Type: "Noted", Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	// Positions past the last message are not gaps, and a range ending
+	// before it starts is empty
+	for _, opts := range []map[string]interface{}{
+		{"to": float64(100)},
+		{"position": float64(2), "to": float64(1)},
+	} {
+		result, rpcErr := h.route(ctx, "category.gaps", []interface{}{"account", opts})
+		if rpcErr != nil {
+			t.Fatalf("category.gaps failed: %v", rpcErr.Message)
+		}
+		if gaps := result.(map[string]interface{}); len(gaps["gaps"].([]interface{})) != 0 || gaps["inFlight"] != false {
+			t.Errorf("Expected no gaps for %v, got %v", opts, gaps)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// HealthProbeStream is the stream in the default namespace the deep health
+	// check writes to; each probe appends one HealthProbed message
+	HealthProbeStream = "healthProbe-sys"
+
+	// healthProbeTimeout bounds each probe of the deep health check
+	healthProbeTimeout = 2 * time.Second
+
+	// healthMinFreeDisk is the free disk share below which the disk check fails
+	healthMinFreeDisk = 0.05
+)
+
+// SetDataDir enables the disk space check of sys.health deep mode for dir
+func (h *RPCHandler) SetDataDir(dir string) {
+	h.dataDir = dir
+}
+
+// deepHealth probes the store, pubsub and disk and adds the results to health
+// Args: [{"deep": true}]
+// Only the default namespace may run it; it writes to the database.
+func (h *RPCHandler) deepHealth(ctx context.Context, health map[string]interface{}) *RPCError {
+	if rpcErr := requireDefaultNamespace(ctx, "Deep health checks require the default namespace token"); rpcErr != nil {
+		return rpcErr
+	}
+
+	checks := map[string]interface{}{
+		"store":  h.probeStore(ctx),
+		"pubsub": h.probePubSub(),
+	}
+	if h.dataDir != "" {
+		checks["disk"] = probeDisk(h.dataDir)
+	}
+	for _, check := range checks {
+		if ok, _ := check.(map[string]interface{})["ok"].(bool); !ok {
+			health["status"] = "degraded"
+		}
+	}
+	health["checks"] = checks
+	return nil
+}
+
+// probeStore writes a message and reads it back, timing both; in read-only
+// mode only the read is timed
+func (h *RPCHandler) probeStore(ctx context.Context) map[string]interface{} {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+	result := map[string]interface{}{"ok": false}
+
+	if !h.guard.ReadOnly() {
+		started := time.Now()
+		_, err := h.store.WriteMessage(ctx, "default", HealthProbeStream, &store.Message{
+			StreamName: HealthProbeStream,
+			Type:       "HealthProbed",
+			Data:       map[string]interface{}{"at": started.UTC().Format(time.RFC3339Nano)},
+		})
+		if err != nil {
+			result["error"] = fmt.Sprintf("write failed: %v", err)
+			return result
+		}
+		result["writeMs"] = millis(time.Since(started))
+	}
+
+	started := time.Now()
+	if _, err := h.store.GetLastStreamMessage(ctx, "default", HealthProbeStream, nil); err != nil {
+		result["error"] = fmt.Sprintf("read failed: %v", err)
+		return result
+	}
+	result["readMs"] = millis(time.Since(started))
+	result["ok"] = true
+	return result
+}
+
+// probePubSub publishes an event to a private stream and times its delivery
+// on this instance
+func (h *RPCHandler) probePubSub() map[string]interface{} {
+	if h.pubsub == nil {
+		return map[string]interface{}{"ok": false, "error": "pubsub is not configured"}
+	}
+
+	stream := fmt.Sprintf("healthProbe-%d", time.Now().UnixNano())
+	sub := h.pubsub.SubscribeStream("default", stream)
+	defer h.pubsub.UnsubscribeStream("default", stream, sub)
+
+	started := time.Now()
+	h.pubsub.deliver(WriteEvent{Namespace: "default", Stream: stream, Category: "healthProbe"})
+	select {
+	case <-sub:
+		return map[string]interface{}{"ok": true, "deliveryMs": millis(time.Since(started))}
+	case <-time.After(healthProbeTimeout):
+		return map[string]interface{}{"ok": false, "error": "event was not delivered"}
+	}
+}
+
+// probeDisk reports the free space of the file system holding dir
+func probeDisk(dir string) map[string]interface{} {
+	free, total, err := diskSpace(dir)
+	if err != nil {
+		return map[string]interface{}{"ok": false, "path": dir, "error": err.Error()}
+	}
+	return map[string]interface{}{
+		"ok":         total == 0 || float64(free)/float64(total) >= healthMinFreeDisk,
+		"path":       dir,
+		"freeBytes":  free,
+		"totalBytes": total,
+	}
+}
+
+// millis converts d to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"testing"
+)
+
+// TestSysHealth_Deep tests that deep health checks probe the store, pubsub
+// and disk and are limited to the default namespace
+func TestSysHealth_Deep(t *testing.T) {
+	st := newTestStore(t)
+	if err := st.CreateNamespace(context.Background(), "default", "hash-default", ""); err != nil {
+		t.Fatalf("Failed to create default namespace: %v", err)
+	}
+	h := NewRPCHandler("test", st, NewPubSub())
+	h.SetDataDir(t.TempDir())
+
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "default")
+	result, rpcErr := h.route(ctx, "sys.health", []interface{}{map[string]interface{}{"deep": true}})
+	if rpcErr != nil {
+		t.Fatalf("sys.health deep failed: %v", rpcErr)
+	}
+	health := result.(map[string]interface{})
+	checks, ok := health["checks"].(map[string]interface{})
+	if !ok || len(checks) != 3 {
+		t.Fatalf("Expected store, pubsub and disk checks, got %v", health)
+	}
+	storeCheck := checks["store"].(map[string]interface{})
+	if storeCheck["ok"] != true || storeCheck["writeMs"] == nil || storeCheck["readMs"] == nil {
+		t.Errorf("Unexpected store check: %v", storeCheck)
+	}
+	if pubsubCheck := checks["pubsub"].(map[string]interface{}); pubsubCheck["ok"] != true {
+		t.Errorf("Unexpected pubsub check: %v", pubsubCheck)
+	}
+	if disk := checks["disk"].(map[string]interface{}); disk["error"] == nil && disk["totalBytes"] == uint64(0) {
+		t.Errorf("Unexpected disk check: %v", disk)
+	}
+	if v, err := st.GetStreamVersion(context.Background(), "default", HealthProbeStream); err != nil || v != 0 {
+		t.Errorf("Expected one probe message, got version %d (%v)", v, err)
+	}
+
+	// Other namespaces only get the shallow check
+	tenant := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	if _, rpcErr := h.route(tenant, "sys.health", []interface{}{map[string]interface{}{"deep": true}}); rpcErr == nil || rpcErr.Code != "AUTH_UNAUTHORIZED" {
+		t.Errorf("Expected AUTH_UNAUTHORIZED, got %v", rpcErr)
+	}
+	if result, rpcErr := h.route(tenant, "sys.health", nil); rpcErr != nil || result.(map[string]interface{})["checks"] != nil {
+		t.Errorf("Expected shallow health, got %v (%v)", result, rpcErr)
+	}
+}
+
+// TestSysHealth_DeepDegraded tests that read-only mode skips the write probe,
+// that the disk check is left out without a data directory and that a
+// failing check degrades the status
+func TestSysHealth_DeepDegraded(t *testing.T) {
+	st := newTestStore(t)
+	if err := st.CreateNamespace(context.Background(), "default", "hash-default", ""); err != nil {
+		t.Fatalf("Failed to create default namespace: %v", err)
+	}
+	h := NewRPCHandler("test", st, NewPubSub())
+	guard := NewWriteGuard(NewNamespaceMetadata(st))
+	guard.SetReadOnly(true)
+	h.SetWriteGuard(guard)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "default")
+
+	deep := func() map[string]interface{} {
+		t.Helper()
+		result, rpcErr := h.route(ctx, "sys.health", []interface{}{map[string]interface{}{"deep": true}})
+		if rpcErr != nil {
+			t.Fatalf("sys.health deep failed: %v", rpcErr)
+		}
+		return result.(map[string]interface{})
+	}
+
+	health := deep()
+	checks := health["checks"].(map[string]interface{})
+	if len(checks) != 2 || checks["disk"] != nil {
+		t.Errorf("Expected no disk check without a data directory, got %v", checks)
+	}
+	if storeCheck := checks["store"].(map[string]interface{}); storeCheck["writeMs"] != nil {
+		t.Errorf("Expected no write probe in read-only mode, got %v", storeCheck)
+	}
+	if v, err := st.GetStreamVersion(context.Background(), "default", HealthProbeStream); err != nil || v != -1 {
+		t.Errorf("Expected no probe message in read-only mode, got version %d (%v)", v, err)
+	}
+
+	h.SetDataDir(t.TempDir() + "/missing")
+	health = deep()
+	if disk := health["checks"].(map[string]interface{})["disk"].(map[string]interface{}); disk["ok"] != false || disk["error"] == nil {
+		t.Errorf("Expected a failed disk check for a missing directory, got %v", disk)
+	}
+	if health["status"] != "degraded" {
+		t.Errorf("Expected a degraded status, got %v", health["status"])
+	}
+}
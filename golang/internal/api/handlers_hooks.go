@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// handleHookRedeliver implements hook.redeliver
+// Args: [hookName, position]
+// Replays the failed delivery at the given position of the hook's dead-letter stream.
+func (h *RPCHandler) handleHookRedeliver(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "hook.redeliver requires 2 arguments: hookName, position",
+		}
+	}
+
+	hookName, ok := args[0].(string)
+	if !ok || hookName == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "hookName must be a non-empty string",
+		}
+	}
+
+	var position int64
+	switch v := args[1].(type) {
+	case float64:
+		position = int64(v)
+	case int:
+		position = int64(v)
+	case int64:
+		position = v
+	default:
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "position must be a number",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.hooks == nil {
+		return nil, &RPCError{
+			Code:    "HOOK_NOT_FOUND",
+			Message: fmt.Sprintf("Webhook not found: %s", hookName),
+		}
+	}
+
+	result, err := h.hooks.Redeliver(ctx, namespace, hookName, position)
+	if err != nil {
+		if errors.Is(err, ErrHookNotFound) {
+			return nil, &RPCError{
+				Code:    "HOOK_NOT_FOUND",
+				Message: fmt.Sprintf("Webhook not found: %s", hookName),
+			}
+		}
+		if errors.Is(err, ErrDeliveryNotFound) {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			}
+		}
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to redeliver: %v", err),
+		}
+	}
+
+	return result, nil
+}
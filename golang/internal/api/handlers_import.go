@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleImportCommit implements import.commit
+// Args: [{expectedCount}]
+// Validates the caller's staged import and activates it in one step: the
+// namespace's messages are replaced by the staged ones, so readers see
+// either none or all of them. With expectedCount, the staging area must
+// hold exactly that many messages.
+func (h *RPCHandler) handleImportCommit(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	var expectedCount *int64
+	if len(args) > 0 {
+		optsObj, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if val, exists := optsObj["expectedCount"]; exists {
+			v, ok := val.(float64)
+			if !ok || v < 0 {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.expectedCount must be a non-negative number",
+				}
+			}
+			n := int64(v)
+			expectedCount = &n
+		}
+	}
+
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := h.checkWritable(ctx, namespace); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	result, err := CommitStagedImport(ctx, h.store, h.shipper, namespace, expectedCount)
+	if err != nil {
+		return nil, stagedImportError(err)
+	}
+	return map[string]interface{}{
+		"namespace":   namespace,
+		"activated":   result.Activated,
+		"replaced":    result.Replaced,
+		"config":      result.Config,
+		"committedAt": time.Now().UTC().Format(time.RFC3339Nano),
+	}, nil
+}
+
+// handleImportAbort implements import.abort
+// Args: []
+// Deletes the caller's staged import; the namespace is left unchanged.
+func (h *RPCHandler) handleImportAbort(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	staged, err := AbortStagedImport(ctx, h.store, namespace)
+	if err != nil {
+		return nil, stagedImportError(err)
+	}
+	return map[string]interface{}{
+		"namespace": namespace,
+		"discarded": staged.Imported,
+		"abortedAt": time.Now().UTC().Format(time.RFC3339Nano),
+	}, nil
+}
+
+// stagedImportError maps staged import errors to RPC errors
+func stagedImportError(err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrNoStagedImport), errors.Is(err, ErrStagedImportFailed):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrStagedImportInvalid):
+		return &RPCError{
+			Code:    "IMPORT_INVALID",
+			Message: err.Error(),
+		}
+	case errors.Is(err, store.ErrNotSupported):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "Staged imports are not supported by the backend",
+		}
+	case errors.Is(err, ErrWormProtected):
+		return &RPCError{
+			Code:    "WORM_PROTECTED",
+			Message: err.Error(),
+		}
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Staged import failed: %v", err),
+	}
+}
+
+// stagedImportStatus is the HTTP status of a staged import error before an
+// import starts streaming
+func stagedImportStatus(err *RPCError) int {
+	switch err.Code {
+	case "INVALID_REQUEST":
+		return http.StatusBadRequest
+	case "NAMESPACE_NOT_FOUND":
+		return http.StatusNotFound
+	case "WORM_PROTECTED":
+		return http.StatusForbidden
+	case "OVERLOADED":
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
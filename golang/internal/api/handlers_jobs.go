@@ -0,0 +1,210 @@
+// Package api provides the sys.jobs RPC handlers.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SetJobScheduler reports the background job scheduler in sys.jobs
+func (h *RPCHandler) SetJobScheduler(s *JobScheduler) {
+	h.jobs = s
+}
+
+// handleSysJobs reports the background job scheduler: its caps, the queued
+// and running jobs with per-namespace counters, and the recent jobs. Callers
+// see their own namespace; the default namespace token (any token in test
+// mode) sees all of them.
+// Request: ["sys.jobs"]
+// Response: {"enabled": true, "workers": 16, "perNamespace": 4, "queued": 2, "running": 4, "namespaces": {"ns": {...}}, "jobs": [{"id": 7, "namespace": "ns", "kind": "retention", "state": "running", ...}]}
+func (h *RPCHandler) handleSysJobs(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	namespace, rpcErr := jobsNamespace(ctx, "sys.jobs")
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.jobs == nil {
+		return map[string]interface{}{
+			"enabled": false,
+			"jobs":    []Job{},
+		}, nil
+	}
+	stats := h.jobs.Stats(namespace)
+	return map[string]interface{}{
+		"enabled":      true,
+		"workers":      stats.Workers,
+		"perNamespace": stats.PerNamespace,
+		"queued":       stats.Queued,
+		"running":      stats.Running,
+		"namespaces":   stats.Namespaces,
+		"jobs":         h.jobs.Jobs(namespace),
+	}, nil
+}
+
+// handleSysJobsList implements sys.jobs.list
+// Args: [{namespace, kind, state, limit}] (all optional)
+// Returns the queued and running jobs, then the recently finished ones,
+// newest first, with their progress and events. Callers see their own
+// namespace; the default namespace token (any token in test mode) sees all
+// of them and may filter by namespace.
+func (h *RPCHandler) handleSysJobsList(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	namespace, rpcErr := jobsNamespace(ctx, "sys.jobs.list")
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var kind, state string
+	limit := int64(jobHistory)
+	if len(args) > 0 && args[0] != nil {
+		opts, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		for key, target := range map[string]*string{"kind": &kind, "state": &state} {
+			if v, exists := opts[key]; exists {
+				if *target, ok = v.(string); !ok {
+					return nil, &RPCError{
+						Code:    "INVALID_REQUEST",
+						Message: fmt.Sprintf("options.%s must be a string", key),
+					}
+				}
+			}
+		}
+		if v, exists := opts["namespace"]; exists && namespace == "" {
+			if namespace, ok = v.(string); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.namespace must be a string",
+				}
+			}
+		}
+		if v, exists := opts["limit"]; exists {
+			n, ok := v.(float64)
+			if !ok || n < 1 || n != float64(int64(n)) {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.limit must be a positive integer",
+				}
+			}
+			limit = int64(n)
+		}
+	}
+
+	if h.jobs == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrJobsDisabled.Error(),
+		}
+	}
+	jobs := []Job{}
+	for _, job := range h.jobs.Jobs(namespace) {
+		if (kind != "" && job.Kind != kind) || (state != "" && job.State != state) {
+			continue
+		}
+		if int64(len(jobs)) == limit {
+			break
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// handleSysJobsCancel implements sys.jobs.cancel
+// Args: [id]
+// Cancels a queued or running job and returns it. A running job stops at
+// its next check of its context; its state becomes canceled then.
+func (h *RPCHandler) handleSysJobsCancel(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	namespace, id, rpcErr := h.jobArgs(ctx, "sys.jobs.cancel", args)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	job, err := h.jobs.Cancel(namespace, id)
+	if err != nil {
+		return nil, jobError(err)
+	}
+	return job, nil
+}
+
+// handleSysJobsRetry implements sys.jobs.retry
+// Args: [id]
+// Queues a failed or canceled job again as a new job and returns the new
+// job, whose retryOf is the ID of the original.
+func (h *RPCHandler) handleSysJobsRetry(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	namespace, id, rpcErr := h.jobArgs(ctx, "sys.jobs.retry", args)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	job, err := h.jobs.Retry(namespace, id)
+	if err != nil {
+		return nil, jobError(err)
+	}
+	return job, nil
+}
+
+// jobsNamespace returns the namespace whose jobs the caller sees, or "" for
+// all of them with the default namespace token or in test mode
+func jobsNamespace(ctx context.Context, method string) (string, *RPCError) {
+	namespace, _ := GetNamespaceFromContext(ctx)
+	if namespace == "default" || IsTestMode(ctx) {
+		return "", nil
+	}
+	if namespace == "" {
+		return "", &RPCError{
+			Code:    "AUTH_REQUIRED",
+			Message: method + " requires a namespace token",
+		}
+	}
+	return namespace, nil
+}
+
+// jobArgs parses the job ID argument of sys.jobs.cancel and sys.jobs.retry
+func (h *RPCHandler) jobArgs(ctx context.Context, method string, args []interface{}) (string, int64, *RPCError) {
+	if len(args) < 1 {
+		return "", 0, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: method + " requires 1 argument: id",
+		}
+	}
+	n, ok := args[0].(float64)
+	if !ok || n < 1 || n != float64(int64(n)) {
+		return "", 0, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "id must be a positive integer",
+		}
+	}
+	namespace, rpcErr := jobsNamespace(ctx, method)
+	if rpcErr != nil {
+		return "", 0, rpcErr
+	}
+	if h.jobs == nil {
+		return "", 0, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrJobsDisabled.Error(),
+		}
+	}
+	return namespace, int64(n), nil
+}
+
+// jobError converts a job scheduler error to an RPC error
+func jobError(err error) *RPCError {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		return &RPCError{
+			Code:    "JOB_NOT_FOUND",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrJobState):
+		return &RPCError{
+			Code:    "JOB_STATE_CONFLICT",
+			Message: err.Error(),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Job update failed: %v", err),
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleLogShippingSet implements ns.logShipping.set
+// Args: [config] where config is a LogShippingConfig object, or null to disable
+// Configures continuous export of the caller's namespace to a tenant-owned destination.
+func (h *RPCHandler) handleLogShippingSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.logShipping.set requires 1 argument: config (or null to disable)",
+		}
+	}
+
+	var cfg *LogShippingConfig
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		cfg = &LogShippingConfig{}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.shipper == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrLogShippingDisabled.Error(),
+		}
+	}
+
+	if err := h.shipper.Configure(ctx, namespace, cfg); err != nil {
+		if errors.Is(err, store.ErrNamespaceNotFound) {
+			return nil, &RPCError{
+				Code:    "NAMESPACE_NOT_FOUND",
+				Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+			}
+		}
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to configure log shipping: %v", err),
+		}
+	}
+
+	if cfg == nil {
+		return map[string]interface{}{"enabled": false}, nil
+	}
+	return logShippingInfo(cfg, nil), nil
+}
+
+// handleLogShippingGet implements ns.logShipping.get
+// Args: []
+// Returns the caller's log shipping config (secrets masked) and status, or null.
+func (h *RPCHandler) handleLogShippingGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		if errors.Is(err, store.ErrNamespaceNotFound) {
+			return nil, &RPCError{
+				Code:    "NAMESPACE_NOT_FOUND",
+				Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+			}
+		}
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to get namespace: %v", err),
+		}
+	}
+
+	cfg, status := LogShippingFromMetadata(ns.Metadata)
+	if cfg == nil {
+		return nil, nil
+	}
+	return logShippingInfo(cfg, status), nil
+}
+
+// logShippingInfo renders a config and status for RPC responses
+func logShippingInfo(cfg *LogShippingConfig, status *LogShippingStatus) map[string]interface{} {
+	if status == nil {
+		status = &LogShippingStatus{State: LogShippingStateActive}
+	}
+	return map[string]interface{}{
+		"enabled": true,
+		"config":  encodeMetadataValue(cfg.Redacted()),
+		"status":  encodeMetadataValue(status),
+	}
+}
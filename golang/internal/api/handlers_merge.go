@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleNamespaceDuplicateStreams implements ns.duplicateStreams
+// Request: ["ns.duplicateStreams", {prefix}]
+// Response: [{"key": "account-123", "canonical": "account-123", "streams": [{"stream": "...", "version": 5, ...}]}]
+// Lists streams whose names differ only by case or whitespace, the
+// candidates for stream.merge.
+func (h *RPCHandler) handleNamespaceDuplicateStreams(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	var prefix string
+	if len(args) > 0 {
+		optsObj, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{Code: "INVALID_REQUEST", Message: "options must be an object"}
+		}
+		if v, exists := optsObj["prefix"]; exists {
+			if prefix, ok = v.(string); !ok {
+				return nil, &RPCError{Code: "INVALID_REQUEST", Message: "prefix must be a string"}
+			}
+		}
+	}
+
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	duplicates, err := FindDuplicateStreams(ctx, h.store, namespace, prefix)
+	if err != nil {
+		if store.IsOverloaded(err) {
+			return nil, overloadedError(err)
+		}
+		return nil, &RPCError{Code: "BACKEND_ERROR", Message: fmt.Sprintf("Failed to list streams: %v", err)}
+	}
+
+	result := make([]interface{}, len(duplicates))
+	for i, d := range duplicates {
+		streams := make([]interface{}, len(d.Streams))
+		for j, s := range d.Streams {
+			streams[j] = map[string]interface{}{
+				"stream":        s.StreamName,
+				"version":       s.Version,
+				"firstActivity": s.FirstActivity.UTC().Format(time.RFC3339),
+				"lastActivity":  s.LastActivity.UTC().Format(time.RFC3339),
+			}
+		}
+		result[i] = map[string]interface{}{
+			"key":       d.Key,
+			"canonical": d.Canonical,
+			"streams":   streams,
+		}
+	}
+	return result, nil
+}
+
+// handleStreamMerge implements stream.merge
+// Args: [streamName, [sources], {reason}]
+// Moves the messages of case or whitespace variants of streamName into it,
+// in global position order, and records the merge in MergeAuditStream.
+func (h *RPCHandler) handleStreamMerge(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "stream.merge requires at least 2 arguments: streamName and sources",
+		}
+	}
+
+	streamName, ok := args[0].(string)
+	if !ok || streamName == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "streamName must be a non-empty string",
+		}
+	}
+	if err := h.names.Validate(streamName); err != nil {
+		return nil, invalidStreamNameError(err)
+	}
+
+	sourcesArr, ok := args[1].([]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "sources must be an array of stream names",
+		}
+	}
+	sources := make([]string, len(sourcesArr))
+	for i, v := range sourcesArr {
+		if sources[i], ok = v.(string); !ok || sources[i] == "" {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "sources must be an array of stream names",
+			}
+		}
+	}
+
+	var reason string
+	if len(args) > 2 {
+		opts, ok := args[2].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if v, exists := opts["reason"]; exists {
+			if reason, ok = v.(string); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.reason must be a string",
+				}
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Merging rewrites stored messages, so frozen namespaces reject it
+	if rpcErr := h.checkWritable(ctx, namespace); rpcErr != nil {
+		return nil, rpcErr
+	}
+	for _, stream := range append([]string{streamName}, sources...) {
+		if rpcErr := h.checkHubOwned(namespace, stream); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	m, err := MergeStreams(ctx, h.store, namespace, streamName, sources, reason)
+	if err != nil {
+		return nil, mergeError(namespace, err)
+	}
+	return map[string]interface{}{
+		"streamName":          m.StreamName,
+		"sources":             m.Sources,
+		"moved":               m.Moved,
+		"version":             m.Version,
+		"mergedAt":            m.MergedAt.Format(time.RFC3339Nano),
+		"auditGlobalPosition": m.AuditGlobalPosition,
+	}, nil
+}
+
+// mergeError maps stream merge errors to RPC errors
+func mergeError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, ErrInvalidMerge):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case errors.Is(err, store.ErrNotSupported):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "Merging streams is not supported by the backend",
+		}
+	case errors.Is(err, ErrWormProtected):
+		return wormProtectedError(namespace)
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to merge streams: %v", err),
+	}
+}
+
+// handleStreamRename implements stream.rename
+// Args: [streamName, newName]
+// Moves the messages of streamName to newName and keeps streamName as an
+// alias, so readers and writers of the old name reach the new stream.
+func (h *RPCHandler) handleStreamRename(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "stream.rename requires 2 arguments: streamName and newName",
+		}
+	}
+
+	streamName, ok := args[0].(string)
+	if !ok || streamName == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "streamName must be a non-empty string",
+		}
+	}
+	newName, ok := args[1].(string)
+	if !ok || newName == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "newName must be a non-empty string",
+		}
+	}
+	if err := h.names.Validate(newName); err != nil {
+		return nil, invalidStreamNameError(err)
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Renaming rewrites stored messages, so frozen namespaces reject it
+	if rpcErr := h.checkWritable(ctx, namespace); rpcErr != nil {
+		return nil, rpcErr
+	}
+	for _, stream := range []string{streamName, newName} {
+		if rpcErr := h.checkHubOwned(namespace, stream); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	r, err := h.aliases.Rename(ctx, namespace, streamName, newName)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRename) {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: err.Error(),
+			}
+		}
+		return nil, mergeError(namespace, err)
+	}
+	return map[string]interface{}{
+		"streamName": r.From,
+		"newName":    r.To,
+		"moved":      r.Moved,
+		"version":    r.Version,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleMessageIDsSet implements ns.messageIds.set
+// Args: [strategy] where strategy is "uuidv7" (the default), "uuidv4" or "ulid"
+// Sets how IDs are generated for messages the caller's namespace receives
+// without one. Messages already written keep their IDs.
+func (h *RPCHandler) handleMessageIDsSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.messageIds.set requires 1 argument: strategy",
+		}
+	}
+	name, _ := args[0].(string)
+	strategy, err := ParseIDStrategy(name)
+	if err != nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.ids.Set(ctx, namespace, strategy); err != nil {
+		return nil, messageIDsError(namespace, err)
+	}
+	return map[string]interface{}{"strategy": string(strategy)}, nil
+}
+
+// handleMessageIDsGet implements ns.messageIds.get
+// Args: []
+// Returns the ID strategy of the caller's namespace.
+func (h *RPCHandler) handleMessageIDsGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, messageIDsError(namespace, err)
+	}
+	return map[string]interface{}{"strategy": string(IDStrategyFromMetadata(ns.Metadata))}, nil
+}
+
+// messageIDsError maps ID strategy errors to RPC errors
+func messageIDsError(namespace string, err error) *RPCError {
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update the message ID strategy: %v", err),
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleMetadataTemplatesSet implements ns.metadataTemplates.set
+// Args: [{templates: [{type, metadata, override}]}] or [null] to remove the templates
+// Replaces the metadata templates of the caller's namespace. Templates apply
+// to writes from the next write on this instance, and within a few seconds
+// on others. Messages already written are not changed.
+func (h *RPCHandler) handleMetadataTemplatesSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.metadataTemplates.set requires 1 argument: config (or null to remove)",
+		}
+	}
+
+	cfg := &MetadataTemplatesConfig{}
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.tmpls.Set(ctx, namespace, cfg); err != nil {
+		return nil, metadataTemplatesError(namespace, err)
+	}
+	return metadataTemplatesInfo(cfg), nil
+}
+
+// handleMetadataTemplatesGet implements ns.metadataTemplates.get
+// Args: []
+// Returns the metadata templates of the caller's namespace.
+func (h *RPCHandler) handleMetadataTemplatesGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, metadataTemplatesError(namespace, err)
+	}
+	return metadataTemplatesInfo(MetadataTemplatesFromMetadata(ns.Metadata)), nil
+}
+
+// metadataTemplatesInfo renders a metadata templates config for RPC responses
+func metadataTemplatesInfo(cfg *MetadataTemplatesConfig) map[string]interface{} {
+	templates := make([]interface{}, 0, len(cfg.Templates))
+	for _, tmpl := range cfg.Templates {
+		templates = append(templates, encodeMetadataValue(tmpl))
+	}
+	return map[string]interface{}{"templates": templates}
+}
+
+// metadataTemplatesError maps metadata template errors to RPC errors
+func metadataTemplatesError(namespace string, err error) *RPCError {
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update metadata templates: %v", err),
+	}
+}
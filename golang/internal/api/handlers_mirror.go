@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleMirrorSet implements ns.mirror.set
+// Args: [config] where config is a MirrorConfig object, or null to stop mirroring
+// Configures asynchronous mirroring of the caller's namespace to a remote server.
+func (h *RPCHandler) handleMirrorSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.mirror.set requires 1 argument: config (or null to stop mirroring)",
+		}
+	}
+
+	var cfg *MirrorConfig
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		cfg = &MirrorConfig{}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.mirror == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrMirrorDisabled.Error(),
+		}
+	}
+
+	if err := h.mirror.Configure(ctx, namespace, cfg); err != nil {
+		return nil, mirrorError(namespace, err)
+	}
+	return h.handleMirrorStatus(ctx, nil)
+}
+
+// handleMirrorStatus implements ns.mirror.status
+// Args: []
+// Returns the caller's mirror config (token masked) and progress, and, for a
+// namespace that receives a mirror, where it is mirrored from.
+func (h *RPCHandler) handleMirrorStatus(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, mirrorError(namespace, err)
+	}
+
+	result := map[string]interface{}{
+		"enabled":  false,
+		"mirrorOf": nil,
+	}
+	if target := MirrorTargetFromMetadata(ns.Metadata); target != nil {
+		result["mirrorOf"] = encodeMetadataValue(target)
+	}
+
+	cfg, status := MirrorFromMetadata(ns.Metadata)
+	if cfg == nil {
+		return result, nil
+	}
+	if status == nil {
+		status = &MirrorStatus{State: MirrorStateActive}
+	}
+	result["enabled"] = true
+	result["config"] = encodeMetadataValue(cfg.Redacted())
+	result["status"] = encodeMetadataValue(status)
+
+	// The age of the oldest message not yet mirrored bounds what a failover would lose
+	msgs, err := h.store.GetCategoryMessages(ctx, namespace, "", &store.CategoryOpts{
+		Position:  status.Position + 1,
+		BatchSize: 1,
+	})
+	if err != nil {
+		return nil, mirrorError(namespace, err)
+	}
+	result["caughtUp"] = len(msgs) == 0
+	result["lagSeconds"] = 0.0
+	if len(msgs) > 0 {
+		result["lagSeconds"] = max(time.Since(msgs[0].Time).Seconds(), 0)
+	}
+	return result, nil
+}
+
+// handleMirrorPromote implements ns.mirror.promote
+// Args: []
+// Makes the caller's mirror namespace accept its own writes, e.g. after a
+// failover. Further pushes from its former primary fail with MIRROR_PROMOTED.
+func (h *RPCHandler) handleMirrorPromote(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	target, err := PromoteMirror(ctx, h.store, h.guard, namespace)
+	if err != nil {
+		return nil, mirrorError(namespace, err)
+	}
+	return map[string]interface{}{
+		"namespace":  namespace,
+		"source":     target.Source,
+		"promotedAt": target.PromotedAt,
+	}, nil
+}
+
+// mirrorError maps mirror errors to RPC errors
+func mirrorError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, ErrNotMirror):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("Namespace '%s' is not a mirror", namespace),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update mirror: %v", err),
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleNamespaceConfigExport implements ns.config.export
+// Args: []
+// Returns the caller's namespace configuration as a NamespaceConfig object.
+func (h *RPCHandler) handleNamespaceConfigExport(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	cfg, err := ExportNamespaceConfig(ctx, h.store, h.hooks, namespace)
+	if err != nil {
+		return nil, namespaceConfigError(namespace, err)
+	}
+	return cfg, nil
+}
+
+// handleNamespaceConfigImport implements ns.config.import
+// Args: [config] where config is a NamespaceConfig object from ns.config.export
+// Replaces the caller's namespace configuration.
+func (h *RPCHandler) handleNamespaceConfigImport(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.config.import requires 1 argument: config",
+		}
+	}
+
+	raw, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "config must be an object",
+		}
+	}
+	var cfg NamespaceConfig
+	if err := decodeMetadataValue(raw, &cfg); err != nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("Invalid config: %v", err),
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := ApplyNamespaceConfig(ctx, h.store, h.shipper, namespace, &cfg); err != nil {
+		return nil, namespaceConfigError(namespace, err)
+	}
+	return map[string]interface{}{"applied": true}, nil
+}
+
+// namespaceConfigError maps config export/import errors to RPC errors
+func namespaceConfigError(namespace string, err error) *RPCError {
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	if errors.Is(err, ErrInvalidNamespaceConfig) {
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update namespace config: %v", err),
+	}
+}
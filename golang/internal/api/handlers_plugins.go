@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handlePluginsSet implements ns.plugins.set
+// Args: [{plugins: [name, ...]}] or [null] to remove the plugins
+// Replaces the write plugins of the caller's namespace after checking that
+// each plugin loads. Plugins run from the next write on this instance, and
+// within a few seconds on others.
+func (h *RPCHandler) handlePluginsSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.plugins.set requires 1 argument: config (or null to remove)",
+		}
+	}
+
+	cfg := &PluginsConfig{}
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.plugins.Set(ctx, namespace, cfg); err != nil {
+		return nil, pluginsError(namespace, err)
+	}
+	return pluginsInfo(cfg), nil
+}
+
+// handlePluginsGet implements ns.plugins.get
+// Args: []
+// Returns the write plugins of the caller's namespace.
+func (h *RPCHandler) handlePluginsGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, pluginsError(namespace, err)
+	}
+	return pluginsInfo(PluginsFromMetadata(ns.Metadata)), nil
+}
+
+// handleReadPluginsSet implements ns.readPlugins.set
+// Args: [{rules: [{category, plugin}]}] or [null] to remove the rules
+// Replaces the read plugin rules of the caller's namespace after checking
+// that each plugin loads. Rules apply to reads with options.decode.
+func (h *RPCHandler) handleReadPluginsSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.readPlugins.set requires 1 argument: config (or null to remove)",
+		}
+	}
+
+	cfg := &ReadPluginsConfig{}
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.plugins.SetRead(ctx, namespace, cfg); err != nil {
+		return nil, pluginsError(namespace, err)
+	}
+	return readPluginsInfo(cfg), nil
+}
+
+// handleReadPluginsGet implements ns.readPlugins.get
+// Args: []
+// Returns the read plugin rules of the caller's namespace.
+func (h *RPCHandler) handleReadPluginsGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, pluginsError(namespace, err)
+	}
+	return readPluginsInfo(ReadPluginsFromMetadata(ns.Metadata)), nil
+}
+
+// pluginsInfo renders a plugins config for RPC responses
+func pluginsInfo(cfg *PluginsConfig) map[string]interface{} {
+	plugins := make([]interface{}, 0, len(cfg.Plugins))
+	for _, name := range cfg.Plugins {
+		plugins = append(plugins, name)
+	}
+	return map[string]interface{}{"plugins": plugins}
+}
+
+// readPluginsInfo renders a read plugins config for RPC responses
+func readPluginsInfo(cfg *ReadPluginsConfig) map[string]interface{} {
+	rules := make([]interface{}, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		rules = append(rules, encodeMetadataValue(rule))
+	}
+	return map[string]interface{}{"rules": rules}
+}
+
+// pluginsError maps plugin errors to RPC errors
+func pluginsError(namespace string, err error) *RPCError {
+	var rejection *PluginRejection
+	switch {
+	case errors.As(err, &rejection):
+		return &RPCError{
+			Code:    "PLUGIN_REJECTED",
+			Message: err.Error(),
+			Details: map[string]interface{}{
+				"plugin": rejection.Plugin,
+				"reason": rejection.Reason,
+			},
+		}
+	case errors.Is(err, ErrPluginsDisabled):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrPluginNotFound):
+		return &RPCError{
+			Code:    "PLUGIN_NOT_FOUND",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrPluginFailed):
+		return &RPCError{
+			Code:    "PLUGIN_FAILED",
+			Message: err.Error(),
+		}
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update plugins: %v", err),
+	}
+}
@@ -0,0 +1,148 @@
+// Package api provides the sys.profile RPC handler.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultProfileSeconds is how long a CPU profile runs when seconds is omitted
+	defaultProfileSeconds = 10
+
+	// maxProfileSeconds bounds CPU profiles, keeping responses within client timeouts
+	maxProfileSeconds = 60
+)
+
+// profileTypes are the profiles sys.profile captures besides "cpu"
+var profileTypes = map[string]bool{
+	"heap":         true,
+	"allocs":       true,
+	"goroutine":    true,
+	"block":        true,
+	"mutex":        true,
+	"threadcreate": true,
+}
+
+// cpuProfileMu serializes CPU profiles; the runtime supports one at a time
+var cpuProfileMu sync.Mutex
+
+// requireDefaultNamespace rejects callers without the default namespace
+// token, which administers the server (any token in test mode)
+func requireDefaultNamespace(ctx context.Context, message string) *RPCError {
+	if namespace, _ := GetNamespaceFromContext(ctx); namespace != "default" && !IsTestMode(ctx) {
+		return &RPCError{
+			Code:    "AUTH_UNAUTHORIZED",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+// handleSysProfile captures a runtime profile, so production servers can be
+// profiled without exposing /debug/pprof
+// Request: ["sys.profile", {"type": "cpu", "seconds": 10, "debug": 0}]
+// Response: {"type": "cpu", "seconds": 10, "format": "pprof", "bytes": 1234, "data": "<base64>"}
+func (h *RPCHandler) handleSysProfile(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	if rpcErr := requireDefaultNamespace(ctx, "Profiling requires the default namespace token"); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	profileType := "cpu"
+	seconds := int64(defaultProfileSeconds)
+	var debug int64
+	if len(args) > 0 {
+		optsObj, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if v, exists := optsObj["type"]; exists {
+			profileType, ok = v.(string)
+			if !ok || (profileType != "cpu" && !profileTypes[profileType]) {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.type must be one of cpu, heap, allocs, goroutine, block, mutex, threadcreate",
+				}
+			}
+		}
+		if v, exists := optsObj["seconds"]; exists {
+			n, ok := v.(float64)
+			if !ok || n < 1 || n > maxProfileSeconds {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("options.seconds must be a number from 1 to %d", maxProfileSeconds),
+				}
+			}
+			seconds = int64(n)
+		}
+		if v, exists := optsObj["debug"]; exists {
+			n, ok := v.(float64)
+			if !ok || n < 0 || n > 2 {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.debug must be 0 (pprof), 1 or 2 (text)",
+				}
+			}
+			debug = int64(n)
+		}
+	}
+
+	var buf bytes.Buffer
+	result := map[string]interface{}{"type": profileType, "format": "pprof"}
+	if profileType == "cpu" {
+		if rpcErr := captureCPUProfile(ctx, &buf, time.Duration(seconds)*time.Second); rpcErr != nil {
+			return nil, rpcErr
+		}
+		result["seconds"] = seconds
+	} else {
+		if err := pprof.Lookup(profileType).WriteTo(&buf, int(debug)); err != nil {
+			return nil, &RPCError{
+				Code:    "INTERNAL_ERROR",
+				Message: fmt.Sprintf("Failed to write %s profile: %v", profileType, err),
+			}
+		}
+		if debug > 0 {
+			result["format"] = "text"
+		}
+	}
+
+	result["bytes"] = buf.Len()
+	result["data"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	return result, nil
+}
+
+// captureCPUProfile profiles the CPU for d, or until ctx is done
+func captureCPUProfile(ctx context.Context, buf *bytes.Buffer, d time.Duration) *RPCError {
+	if !cpuProfileMu.TryLock() {
+		return &RPCError{
+			Code:    "PROFILE_IN_PROGRESS",
+			Message: "A CPU profile is already being captured, retry when it finishes",
+		}
+	}
+	defer cpuProfileMu.Unlock()
+
+	if err := pprof.StartCPUProfile(buf); err != nil {
+		// Also held by /debug/pprof/profile when that endpoint is enabled
+		return &RPCError{
+			Code:    "PROFILE_IN_PROGRESS",
+			Message: fmt.Sprintf("Failed to start CPU profile: %v", err),
+		}
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	pprof.StopCPUProfile()
+	return nil
+}
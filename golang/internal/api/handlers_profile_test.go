@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSysProfile tests capturing binary, text and CPU profiles
+func TestSysProfile(t *testing.T) {
+	h := NewRPCHandler("test", nil, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "default")
+
+	decode := func(result interface{}) map[string]interface{} {
+		t.Helper()
+		profile := result.(map[string]interface{})
+		data, err := base64.StdEncoding.DecodeString(profile["data"].(string))
+		if err != nil {
+			t.Fatalf("Profile data is not base64: %v", err)
+		}
+		if len(data) != profile["bytes"].(int) {
+			t.Errorf("Expected %d bytes, got %d", profile["bytes"], len(data))
+		}
+		profile["data"] = data
+		return profile
+	}
+
+	// Binary profiles are gzipped pprof protobufs
+	result, rpcErr := h.route(ctx, "sys.profile", []interface{}{map[string]interface{}{"type": "heap"}})
+	if rpcErr != nil {
+		t.Fatalf("sys.profile heap failed: %v", rpcErr)
+	}
+	heap := decode(result)
+	if heap["format"] != "pprof" {
+		t.Errorf("Expected pprof format, got %v", heap["format"])
+	}
+	if _, err := gzip.NewReader(bytes.NewReader(heap["data"].([]byte))); err != nil {
+		t.Errorf("Expected a gzipped profile: %v", err)
+	}
+
+	// debug=2 dumps goroutine stacks as text
+	result, rpcErr = h.route(ctx, "sys.profile", []interface{}{map[string]interface{}{"type": "goroutine", "debug": float64(2)}})
+	if rpcErr != nil {
+		t.Fatalf("sys.profile goroutine failed: %v", rpcErr)
+	}
+	dump := decode(result)
+	if dump["format"] != "text" || !strings.Contains(string(dump["data"].([]byte)), "TestSysProfile") {
+		t.Errorf("Expected a text goroutine dump including this test, got %v", dump["format"])
+	}
+
+	result, rpcErr = h.route(ctx, "sys.profile", []interface{}{map[string]interface{}{"type": "cpu", "seconds": float64(1)}})
+	if rpcErr != nil {
+		t.Fatalf("sys.profile cpu failed: %v", rpcErr)
+	}
+	if cpu := decode(result); cpu["seconds"] != int64(1) || len(cpu["data"].([]byte)) == 0 {
+		t.Errorf("Unexpected CPU profile: seconds %v, %v bytes", cpu["seconds"], cpu["bytes"])
+	}
+
+	for _, opts := range []map[string]interface{}{
+		{"type": "trace"},
+		{"type": "cpu", "seconds": float64(600)},
+		{"debug": float64(3)},
+	} {
+		if _, rpcErr := h.route(ctx, "sys.profile", []interface{}{opts}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", opts, rpcErr)
+		}
+	}
+
+	// Only the default namespace may profile the server
+	tenant := context.WithValue(context.Background(), ContextKeyNamespace, "tenant")
+	if _, rpcErr := h.route(tenant, "sys.profile", []interface{}{map[string]interface{}{"type": "heap"}}); rpcErr == nil || rpcErr.Code != "AUTH_UNAUTHORIZED" {
+		t.Errorf("Expected AUTH_UNAUTHORIZED for another namespace, got %v", rpcErr)
+	}
+}
+
+// TestSysProfile_Errors tests invalid options, concurrent CPU profiles,
+// cancelled requests and test mode
+func TestSysProfile_Errors(t *testing.T) {
+	h := NewRPCHandler("test", nil, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "default")
+
+	for _, args := range [][]interface{}{
+		{"heap"},
+		{map[string]interface{}{"type": float64(1)}},
+		{map[string]interface{}{"type": "cpu", "seconds": float64(0)}},
+		{map[string]interface{}{"type": "cpu", "seconds": "10"}},
+		{map[string]interface{}{"type": "heap", "debug": float64(-1)}},
+		{map[string]interface{}{"type": "heap", "debug": "1"}},
+	} {
+		if _, rpcErr := h.route(ctx, "sys.profile", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// Only one CPU profile runs at a time
+	cpuProfileMu.Lock()
+	_, rpcErr := h.route(ctx, "sys.profile", []interface{}{map[string]interface{}{"type": "cpu", "seconds": float64(1)}})
+	cpuProfileMu.Unlock()
+	if rpcErr == nil || rpcErr.Code != "PROFILE_IN_PROGRESS" {
+		t.Errorf("Expected PROFILE_IN_PROGRESS, got %v", rpcErr)
+	}
+
+	// A cancelled request stops its CPU profile early
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	start := time.Now()
+	result, rpcErr := h.route(cancelled, "sys.profile", []interface{}{map[string]interface{}{"type": "cpu", "seconds": float64(maxProfileSeconds)}})
+	if rpcErr != nil {
+		t.Fatalf("sys.profile cpu failed: %v", rpcErr)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the cancelled profile to stop early, took %v", elapsed)
+	}
+	if result.(map[string]interface{})["format"] != "pprof" {
+		t.Errorf("Expected a pprof profile, got %v", result)
+	}
+
+	// Any namespace may profile the server in test mode
+	tenant := context.WithValue(context.WithValue(context.Background(), ContextKeyNamespace, "tenant"), ContextKeyTestMode, true)
+	if _, rpcErr := h.route(tenant, "sys.profile", []interface{}{map[string]interface{}{"type": "goroutine"}}); rpcErr != nil {
+		t.Errorf("Expected test mode to allow profiling, got %v", rpcErr)
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleMessageRedact implements message.redact
+// Args: [streamName, position, {reason, metadata}]
+// Replaces the data of one message with a tombstone recording the reason, for
+// sensitive payloads written by mistake. Stream positions are unchanged.
+func (h *RPCHandler) handleMessageRedact(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 3 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "message.redact requires 3 arguments: streamName, position, {reason}",
+		}
+	}
+
+	streamName, ok := args[0].(string)
+	if !ok || streamName == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "streamName must be a non-empty string",
+		}
+	}
+
+	var position int64
+	switch v := args[1].(type) {
+	case float64:
+		position = int64(v)
+	case int:
+		position = int64(v)
+	case int64:
+		position = v
+	default:
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "position must be a number",
+		}
+	}
+	if position < 0 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "position must not be negative",
+		}
+	}
+
+	opts, ok := args[2].(map[string]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options must be an object",
+		}
+	}
+	reason, ok := opts["reason"].(string)
+	if !ok || reason == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options.reason must be a non-empty string",
+		}
+	}
+	var metadata bool
+	if v, exists := opts["metadata"]; exists {
+		if metadata, ok = v.(bool); !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options.metadata must be a boolean",
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Redaction changes stored data, so frozen namespaces reject it
+	if rpcErr := h.checkWritable(ctx, namespace); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	r, err := RedactMessage(ctx, h.store, namespace, streamName, position, reason, metadata)
+	if err != nil {
+		return nil, redactionError(namespace, streamName, position, err)
+	}
+	return map[string]interface{}{
+		"streamName":          r.StreamName,
+		"position":            r.Position,
+		"globalPosition":      r.GlobalPosition,
+		"redactedAt":          r.RedactedAt.Format(time.RFC3339Nano),
+		"auditGlobalPosition": r.AuditGlobalPosition,
+	}, nil
+}
+
+// redactionError maps redaction errors to RPC errors
+func redactionError(namespace, streamName string, position int64, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, store.ErrMessageNotFound):
+		return &RPCError{
+			Code:    "MESSAGE_NOT_FOUND",
+			Message: fmt.Sprintf("Stream '%s' has no message at position %d", streamName, position),
+			Details: map[string]interface{}{"streamName": streamName, "position": position},
+		}
+	case errors.Is(err, ErrInvalidRedaction):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case errors.Is(err, store.ErrNotSupported):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "Redaction is not supported by the backend",
+		}
+	case errors.Is(err, ErrWormProtected):
+		return wormProtectedError(namespace)
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to redact message: %v", err),
+	}
+}
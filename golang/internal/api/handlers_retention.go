@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleRetentionSet implements ns.retention.set
+// Args: [{rules: [{streams, keep}]}] or [null] to remove the rules
+// Replaces the retention rules of the caller's namespace. Rules take effect
+// at the next compaction pass, or immediately with ns.compact.
+func (h *RPCHandler) handleRetentionSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.retention.set requires 1 argument: config (or null to remove)",
+		}
+	}
+
+	cfg := &RetentionConfig{}
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := SetRetention(ctx, h.store, namespace, cfg); err != nil {
+		return nil, retentionError(namespace, err)
+	}
+	return retentionInfo(cfg), nil
+}
+
+// handleRetentionGet implements ns.retention.get
+// Args: []
+// Returns the retention rules of the caller's namespace.
+func (h *RPCHandler) handleRetentionGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, retentionError(namespace, err)
+	}
+	return retentionInfo(RetentionFromMetadata(ns.Metadata)), nil
+}
+
+// handleNamespaceCompact implements ns.compact
+// Args: [{opts}] where opts may contain "dryRun"
+// Applies the retention rules of the caller's namespace now and reports what
+// was deleted. With dryRun, reports what would be deleted, records the report
+// in the audit stream and deletes nothing.
+func (h *RPCHandler) handleNamespaceCompact(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Parse options
+	var dryRun bool
+	if len(args) > 0 && args[0] != nil {
+		opts, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		for key, v := range opts {
+			if key != "dryRun" {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("unknown option %q", key),
+				}
+			}
+			// A mistyped dryRun must not delete messages
+			if dryRun, ok = v.(bool); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.dryRun must be a boolean",
+				}
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.compact == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrCompactionDisabled.Error(),
+		}
+	}
+
+	// Dry run: report what would be removed and record it in the audit stream
+	if dryRun {
+		report, err := RetentionDryRun(ctx, h.store, namespace)
+		if err != nil {
+			return nil, retentionError(namespace, err)
+		}
+		if err := recordDryRun(ctx, h.store, namespace, report); err != nil {
+			return nil, &RPCError{
+				Code:    "BACKEND_ERROR",
+				Message: fmt.Sprintf("Failed to record dry run: %v", err),
+			}
+		}
+		result := report.toResult(true)
+		result["namespace"] = namespace
+		return result, nil
+	}
+
+	if rpcErr := h.checkWritable(ctx, namespace); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	result, err := h.compact.CompactNamespace(ctx, namespace)
+	if err != nil {
+		return nil, retentionError(namespace, err)
+	}
+	return map[string]interface{}{
+		"namespace":        namespace,
+		"streamsCompacted": result.StreamsCompacted,
+		"messagesDeleted":  result.MessagesDeleted,
+	}, nil
+}
+
+// retentionInfo renders a retention config for RPC responses
+func retentionInfo(cfg *RetentionConfig) map[string]interface{} {
+	rules := make([]interface{}, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		rules = append(rules, map[string]interface{}{
+			"streams": rule.Streams,
+			"keep":    rule.Keep,
+		})
+	}
+	return map[string]interface{}{"rules": rules}
+}
+
+// retentionError maps retention and compaction errors to RPC errors
+func retentionError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, store.ErrNotSupported):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "Stream compaction is not supported by this backend",
+		}
+	case errors.Is(err, ErrWormProtected):
+		return wormProtectedError(namespace)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to apply retention: %v", err),
+	}
+}
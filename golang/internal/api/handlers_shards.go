@@ -0,0 +1,36 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// handleNamespaceShards implements ns.shards
+// Args: []
+// Lists the shard backends with the number of namespaces placed on each.
+func (h *RPCHandler) handleNamespaceShards(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	if h.shards == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "Sharding is not configured (start the server with --shards)",
+		}
+	}
+
+	infos, err := h.shards.Shards(ctx)
+	if err != nil {
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to list shards: %v", err),
+		}
+	}
+
+	result := make([]interface{}, len(infos))
+	for i, info := range infos {
+		result[i] = map[string]interface{}{
+			"shard":      info.Name,
+			"namespaces": info.Namespaces,
+			"open":       info.Open,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleSnapshotsSet implements ns.snapshots.set
+// Args: [{rules: [{category, every, plugin | url, secret}]}] or [null] to remove the rules
+// Replaces the automatic snapshot rules of the caller's namespace after
+// checking that each reducer plugin loads.
+func (h *RPCHandler) handleSnapshotsSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.snapshots.set requires 1 argument: config (or null to remove)",
+		}
+	}
+
+	cfg := &SnapshotsConfig{}
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.snaps == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrSnapshotsDisabled.Error(),
+		}
+	}
+
+	if err := h.snaps.Configure(ctx, namespace, cfg); err != nil {
+		return nil, snapshotsError(namespace, err)
+	}
+	return snapshotsInfo(cfg, h.snaps.Stats(namespace)), nil
+}
+
+// handleSnapshotsGet implements ns.snapshots.get
+// Args: []
+// Returns the snapshot rules of the caller's namespace (secrets masked) and
+// the snapshots written and failed since the server started.
+func (h *RPCHandler) handleSnapshotsGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, snapshotsError(namespace, err)
+	}
+
+	var stats SnapshotStats
+	if h.snaps != nil {
+		stats = h.snaps.Stats(namespace)
+	}
+	return snapshotsInfo(SnapshotsFromMetadata(ns.Metadata), stats), nil
+}
+
+// snapshotsInfo renders snapshot rules and counters for RPC responses
+func snapshotsInfo(cfg *SnapshotsConfig, stats SnapshotStats) map[string]interface{} {
+	info := encodeMetadataValue(cfg.Redacted()).(map[string]interface{})
+	info["stats"] = encodeMetadataValue(stats)
+	return info
+}
+
+// snapshotsError maps a snapshot configuration error to an RPC error
+func snapshotsError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, ErrPluginsDisabled), errors.Is(err, ErrPluginNotFound), errors.Is(err, ErrPluginFailed):
+		return pluginsError(namespace, err)
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	default:
+		return &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to configure snapshots: %v", err),
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleQueriesSet implements ns.queries.set
+// Args: [{queries: [{name, category, types, where, stream}]}] or [null] to remove the queries
+// Replaces the standing queries of the caller's namespace. Queries apply to
+// stream.write calls from the next write on this instance, and within a few
+// seconds on others.
+func (h *RPCHandler) handleQueriesSet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.queries.set requires 1 argument: config (or null to remove)",
+		}
+	}
+
+	cfg := &StandingQueriesConfig{}
+	if args[0] != nil {
+		raw, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "config must be an object or null",
+			}
+		}
+		if err := decodeMetadataValue(raw, cfg); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid config: %v", err),
+			}
+		}
+		for _, q := range cfg.Queries {
+			if err := h.names.Validate(q.ResultStream()); err != nil {
+				return nil, invalidStreamNameError(err)
+			}
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.queries.Set(ctx, namespace, cfg); err != nil {
+		return nil, standingQueriesError(namespace, err)
+	}
+	return standingQueriesInfo(cfg), nil
+}
+
+// handleQueriesGet implements ns.queries.get
+// Args: []
+// Returns the standing queries of the caller's namespace with their result streams.
+func (h *RPCHandler) handleQueriesGet(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, standingQueriesError(namespace, err)
+	}
+	return standingQueriesInfo(StandingQueriesFromMetadata(ns.Metadata)), nil
+}
+
+// standingQueriesInfo renders standing queries for RPC responses
+func standingQueriesInfo(cfg *StandingQueriesConfig) map[string]interface{} {
+	queries := make([]interface{}, 0, len(cfg.Queries))
+	for _, q := range cfg.Queries {
+		info := encodeMetadataValue(q).(map[string]interface{})
+		info["stream"] = q.ResultStream()
+		queries = append(queries, info)
+	}
+	return map[string]interface{}{"queries": queries}
+}
+
+// standingQueriesError maps standing query errors to RPC errors
+func standingQueriesError(namespace string, err error) *RPCError {
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update standing queries: %v", err),
+	}
+}
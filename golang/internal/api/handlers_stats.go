@@ -0,0 +1,60 @@
+// Package api provides the sys.stats RPC handler.
+package api
+
+import (
+	"context"
+)
+
+// SetLatencyTracker records the latency of every RPC call in tracker and
+// reports it in sys.stats
+func (h *RPCHandler) SetLatencyTracker(tracker *LatencyTracker) {
+	h.latency = tracker
+}
+
+// handleSysStats reports rolling RPC latency percentiles per method and
+// namespace, and the write rate limits with their current usage. Callers
+// see their own namespace; the default namespace token (any token in test
+// mode) sees all of them.
+// Request: ["sys.stats"]
+// Response: {"latency": [{"method": "stream.write", "namespace": "ns", "windows": {"1m": {...}, "5m": {...}, "1h": {...}}}], "limits": {"global": {...}, "namespaces": {"ns": {...}}}}
+func (h *RPCHandler) handleSysStats(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	namespace, _ := GetNamespaceFromContext(ctx)
+	if namespace == "default" || IsTestMode(ctx) {
+		namespace = ""
+	} else if namespace == "" {
+		return nil, &RPCError{
+			Code:    "AUTH_REQUIRED",
+			Message: "sys.stats requires a namespace token",
+		}
+	}
+
+	latency := h.latency.Stats(namespace)
+	if latency == nil {
+		latency = []LatencyStats{}
+	}
+	return map[string]interface{}{
+		"enabled": h.latency != nil,
+		"latency": latency,
+		"limits":  h.admissionLimits(namespace),
+	}, nil
+}
+
+// admissionLimits reports the write rate limits and their usage for
+// namespace, or for every namespace that has written when it is empty; nil
+// when writes are not rate limited
+func (h *RPCHandler) admissionLimits(namespace string) interface{} {
+	usage := h.admit.Usage(namespace)
+	if usage == nil {
+		return nil
+	}
+	namespaces := map[string]AdmissionBucketUsage{}
+	if namespace == "" {
+		namespaces = h.admit.NamespaceUsage()
+	} else if usage.Namespace != nil {
+		namespaces[namespace] = *usage.Namespace
+	}
+	return map[string]interface{}{
+		"global":     usage.Global,
+		"namespaces": namespaces,
+	}
+}
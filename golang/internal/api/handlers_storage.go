@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// SetStorageTracker reports growth rates from tracker in ns.storage
+func (h *RPCHandler) SetStorageTracker(tracker *StorageTracker) {
+	h.storage = tracker
+}
+
+// handleNamespaceStorage implements ns.storage
+// Args: [namespaceId]
+// Measures the bytes used by a namespace and their split by category. The
+// growth rate comes from the background storage tracker.
+func (h *RPCHandler) handleNamespaceStorage(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.storage requires 1 argument: namespace ID",
+		}
+	}
+	namespaceID, ok := args[0].(string)
+	if !ok || namespaceID == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "namespace ID must be a non-empty string",
+		}
+	}
+
+	reporter, ok := h.store.(store.StorageReporter)
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "Storage usage is not reported by this backend",
+		}
+	}
+
+	usage, err := reporter.NamespaceStorage(ctx, namespaceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNamespaceNotFound):
+			return nil, &RPCError{
+				Code:    "NAMESPACE_NOT_FOUND",
+				Message: fmt.Sprintf("Namespace '%s' not found", namespaceID),
+			}
+		case errors.Is(err, store.ErrNotSupported):
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "Storage usage is not reported by this backend",
+			}
+		}
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to measure namespace storage: %v", err),
+		}
+	}
+	measuredAt := time.Now().UTC()
+
+	categories := make([]interface{}, 0, len(usage.Categories))
+	names := make([]string, 0, len(usage.Categories))
+	for name := range usage.Categories {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if usage.Categories[names[i]] != usage.Categories[names[j]] {
+			return usage.Categories[names[i]] > usage.Categories[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		categories = append(categories, map[string]interface{}{
+			"category": name,
+			"bytes":    usage.Categories[name],
+		})
+	}
+
+	result := map[string]interface{}{
+		"namespace":  namespaceID,
+		"bytes":      usage.Bytes,
+		"categories": categories,
+		"measuredAt": measuredAt.Format(time.RFC3339),
+	}
+	if h.storage != nil {
+		h.storage.Record(namespaceID, usage.Bytes, measuredAt)
+		if growth, ok := h.storage.Growth(namespaceID); ok {
+			result["growthBytesPerDay"] = int64(growth)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+func TestNamespaceStorage(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	for _, stream := range []string{"account-1", "account-2", "order-1"} {
+		if _, err := st.WriteMessage(ctx, "test-ns", stream, &store.Message{
+			StreamName: stream,
+			Type:       "Created",
+			Data:       map[string]interface{}{"note": "some payload"},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+
+	h := NewRPCHandler("test", st, NewPubSub())
+	tracker := NewStorageTracker(st, time.Hour)
+	h.SetStorageTracker(tracker)
+
+	result, rpcErr := h.route(ctx, "ns.storage", []interface{}{"test-ns"})
+	if rpcErr != nil {
+		t.Fatalf("ns.storage failed: %v", rpcErr)
+	}
+	usage := result.(map[string]interface{})
+	categories := usage["categories"].([]interface{})
+	if usage["bytes"].(int64) <= 0 || len(categories) != 2 {
+		t.Fatalf("Unexpected usage: %v", usage)
+	}
+	if first := categories[0].(map[string]interface{}); first["category"] != "account" {
+		t.Errorf("Expected the largest category first, got %v", categories)
+	}
+	if _, ok := usage["growthBytesPerDay"]; ok {
+		t.Error("Growth must not be reported from a single sample")
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.storage", []interface{}{"missing"}); rpcErr == nil || rpcErr.Code != "NAMESPACE_NOT_FOUND" {
+		t.Errorf("Expected NAMESPACE_NOT_FOUND, got %v", rpcErr)
+	}
+}
+
+func TestStorageTracker_Growth(t *testing.T) {
+	tracker := NewStorageTracker(newTestStore(t), time.Hour)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tracker.Record("ns", 1000, start)
+	tracker.Record("ns", 1500, start.Add(30*time.Minute))
+	if _, ok := tracker.Growth("ns"); ok {
+		t.Error("Growth must not be reported for samples spanning less than an hour")
+	}
+
+	tracker.Record("ns", 3000, start.Add(12*time.Hour))
+	if growth, ok := tracker.Growth("ns"); !ok || growth != 4000 {
+		t.Errorf("Expected 4000 bytes/day, got %v (%v)", growth, ok)
+	}
+
+	// Samples older than the window are dropped
+	tracker.Record("ns", 3000, start.Add(storageGrowthWindow+6*time.Hour))
+	if growth, ok := tracker.Growth("ns"); !ok || growth >= 4000 {
+		t.Errorf("Expected growth over the window only, got %v (%v)", growth, ok)
+	}
+}
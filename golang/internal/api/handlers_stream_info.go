@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// streamInfoBatchSize is the number of messages read per query by stream.info
+	streamInfoBatchSize = 1000
+
+	// streamInfoMaxScan bounds the messages stream.info reads, so a very long
+	// stream cannot tie up the backend. Larger streams are reported as truncated.
+	streamInfoMaxScan = 100000
+)
+
+// handleStreamInfo summarizes a stream for support and debugging
+// Request: ["stream.info", "streamName"]
+// Response: {"stream": "account-123", "version": 5, "messageCount": 6, "firstPosition": 0,
+// "firstTime": "...", "lastTime": "...", "dataBytes": 1234, "types": [{"type": "Deposited", "count": 4}], "truncated": false}
+// or null if the stream doesn't exist
+func (h *RPCHandler) handleStreamInfo(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "stream.info requires 1 argument: streamName",
+		}
+	}
+
+	// Parse stream name
+	streamName, ok := args[0].(string)
+	if !ok || streamName == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "streamName must be a non-empty string",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Reads of a renamed stream read its new name
+	streamName = h.aliases.Resolve(ctx, namespace, streamName)
+
+	version, err := h.store.GetStreamVersion(ctx, namespace, streamName)
+	if err != nil {
+		return nil, streamInfoError(err)
+	}
+	if version == -1 {
+		return nil, nil
+	}
+
+	// Compacted streams no longer start at position 0, so the scan starts
+	// wherever the oldest remaining message is
+	var count, dataBytes int64
+	var first, last *store.Message
+	types := make(map[string]int64)
+	truncated := false
+	position := int64(0)
+	for {
+		msgs, err := h.store.GetStreamMessages(ctx, namespace, streamName, &store.GetOpts{
+			Position:  position,
+			BatchSize: streamInfoBatchSize,
+		})
+		if err != nil {
+			return nil, streamInfoError(err)
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		for _, msg := range msgs {
+			if first == nil {
+				first = msg
+			}
+			last = msg
+			count++
+			types[msg.Type]++
+			dataBytes += payloadSize(msg)
+		}
+		position = last.Position + 1
+		if count >= streamInfoMaxScan {
+			truncated = position <= version
+			break
+		}
+	}
+
+	result := map[string]interface{}{
+		"stream":       streamName,
+		"version":      version,
+		"messageCount": count,
+		"dataBytes":    dataBytes,
+		"types":        typeHistogram(types),
+		"truncated":    truncated,
+	}
+	if first != nil {
+		result["firstPosition"] = first.Position
+		result["firstTime"] = first.Time.UTC().Format(time.RFC3339Nano)
+		result["lastTime"] = last.Time.UTC().Format(time.RFC3339Nano)
+	}
+	return result, nil
+}
+
+// payloadSize is the JSON size of a message's data and metadata
+func payloadSize(msg *store.Message) int64 {
+	var size int
+	if data, err := json.Marshal(msg.Data); err == nil {
+		size += len(data)
+	}
+	if msg.Metadata != nil {
+		if meta, err := json.Marshal(msg.Metadata); err == nil {
+			size += len(meta)
+		}
+	}
+	return int64(size)
+}
+
+// typeHistogram renders message type counts, most frequent first
+func typeHistogram(types map[string]int64) []interface{} {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if types[names[i]] != types[names[j]] {
+			return types[names[i]] > types[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	histogram := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		histogram = append(histogram, map[string]interface{}{
+			"type":  name,
+			"count": types[name],
+		})
+	}
+	return histogram
+}
+
+// streamInfoError maps store errors to RPC errors
+func streamInfoError(err error) *RPCError {
+	if store.IsOverloaded(err) {
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to read stream: %v", err),
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleNamespaceSuspend implements ns.suspend
+// Request: ["ns.suspend", "namespace-id", {opts}]
+// opts.reason: optional reason, e.g. "payment overdue"
+// opts.blockReads: also reject reads and subscriptions (default: false)
+// Response: {"namespace": "tenant-a", "suspended": true, "since": "...", "reason": "...", "blockReads": false}
+// Writes are rejected with NAMESPACE_SUSPENDED until ns.resume; data is kept.
+func (h *RPCHandler) handleNamespaceSuspend(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	namespaceID, rpcErr := h.suspendTarget(ctx, "ns.suspend", args)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Parse optional options
+	var reason string
+	var blockReads bool
+	if len(args) > 1 && args[1] != nil {
+		opts, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if v, exists := opts["reason"]; exists {
+			if reason, ok = v.(string); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.reason must be a string",
+				}
+			}
+		}
+		if v, exists := opts["blockReads"]; exists {
+			if blockReads, ok = v.(bool); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.blockReads must be a boolean",
+				}
+			}
+		}
+	}
+
+	state, err := h.guard.Suspend(ctx, namespaceID, reason, blockReads)
+	if err != nil {
+		return nil, freezeError(namespaceID, err)
+	}
+
+	return map[string]interface{}{
+		"namespace":  namespaceID,
+		"suspended":  true,
+		"since":      state.Since,
+		"reason":     state.Reason,
+		"blockReads": state.BlockReads,
+	}, nil
+}
+
+// handleNamespaceResume implements ns.resume
+// Request: ["ns.resume", "namespace-id"]
+// Response: {"namespace": "tenant-a", "suspended": false}
+func (h *RPCHandler) handleNamespaceResume(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	namespaceID, rpcErr := h.suspendTarget(ctx, "ns.resume", args)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if err := h.guard.Resume(ctx, namespaceID); err != nil {
+		return nil, freezeError(namespaceID, err)
+	}
+
+	return map[string]interface{}{
+		"namespace": namespaceID,
+		"suspended": false,
+	}, nil
+}
+
+// suspendTarget parses the namespace ID of ns.suspend and ns.resume. A
+// namespace's own token cannot change its suspension.
+func (h *RPCHandler) suspendTarget(ctx context.Context, method string, args []interface{}) (string, *RPCError) {
+	if len(args) < 1 {
+		return "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("%s requires at least 1 argument: namespace ID", method),
+		}
+	}
+
+	namespaceID, ok := args[0].(string)
+	if !ok || namespaceID == "" {
+		return "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "namespace ID must be a non-empty string",
+		}
+	}
+
+	if caller, ok := GetNamespaceFromContext(ctx); ok && caller == namespaceID {
+		return "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("%s must be called with another namespace's token", method),
+		}
+	}
+	return namespaceID, nil
+}
+
+// suspendedError is the RPC error for requests to a suspended namespace
+func suspendedError(namespace string) *RPCError {
+	return &RPCError{
+		Code:    "NAMESPACE_SUSPENDED",
+		Message: fmt.Sprintf("Namespace '%s' is suspended", namespace),
+		Details: map[string]interface{}{"namespace": namespace},
+	}
+}
+
+// guardHTTPError is the HTTP status and error for a WriteGuard.Check error
+func guardHTTPError(namespace string, err error) (int, *RPCError) {
+	switch {
+	case errors.Is(err, ErrNamespaceSuspended):
+		return http.StatusForbidden, &RPCError{Code: "NAMESPACE_SUSPENDED", Message: err.Error()}
+	case errors.Is(err, ErrReadOnly), errors.Is(err, ErrServerReadOnly), errors.Is(err, ErrNamespaceMirrored):
+		return http.StatusForbidden, &RPCError{Code: "READ_ONLY", Message: err.Error()}
+	}
+	rpcErr := guardLookupError(namespace, err)
+	switch rpcErr.Code {
+	case "NAMESPACE_NOT_FOUND":
+		return http.StatusNotFound, rpcErr
+	case "BACKEND_UNAVAILABLE", "OVERLOADED":
+		return http.StatusServiceUnavailable, rpcErr
+	}
+	return http.StatusInternalServerError, rpcErr
+}
+
+// guardLookupError is the RPC error for a WriteGuard.Check that could not
+// look up the namespace. The write is rejected, as it may be frozen.
+func guardLookupError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case store.IsBackendUnavailable(err):
+		return backendUnavailableError(err)
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to check whether namespace accepts writes: %v", err),
+	}
+}
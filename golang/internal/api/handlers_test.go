@@ -3,7 +3,15 @@ package api
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/eventodb/eventodb/internal/store"
 	"github.com/eventodb/eventodb/internal/store/sqlite"
@@ -164,3 +172,729 @@ func TestRapidNamespaceCreateDelete(t *testing.T) {
 		}
 	}
 }
+
+// TestNamespaceDeleteDryRun tests that a dry run reports the deletion without deleting
+func TestNamespaceDeleteDryRun(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	st, err := sqlite.New(db, &sqlite.Config{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateNamespace(ctx, "test-ns", "token-hash", "Test namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	for _, stream := range []string{"account-1", "account-2", "account-1"} {
+		if _, err := st.WriteMessage(ctx, "test-ns", stream, &store.Message{
+			StreamName: stream,
+			Type:       "TestEvent",
+			Data:       map[string]interface{}{"a": 1},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+
+	h := NewRPCHandler("test", st, NewPubSub())
+	result, rpcErr := h.route(ctx, "ns.delete", []interface{}{"test-ns", map[string]interface{}{"dryRun": true}})
+	if rpcErr != nil {
+		t.Fatalf("ns.delete dry run failed: %v", rpcErr.Message)
+	}
+
+	report := result.(map[string]interface{})
+	if report["dryRun"] != true || report["messages"] != float64(3) || report["streams"] != float64(2) {
+		t.Errorf("Unexpected report: %v", report)
+	}
+	if report["firstGlobalPosition"] != float64(1) || report["lastGlobalPosition"] != float64(3) {
+		t.Errorf("Unexpected global position range: %v", report)
+	}
+	if report["bytes"] != float64(3*len(`{"a":1}`)) {
+		t.Errorf("Expected %d bytes, got %v", 3*len(`{"a":1}`), report["bytes"])
+	}
+
+	// Nothing was deleted, and the dry run was audited
+	if _, err := st.GetNamespace(ctx, "test-ns"); err != nil {
+		t.Fatalf("Namespace should still exist: %v", err)
+	}
+	audit, err := st.GetStreamMessages(ctx, "test-ns", AuditStream, store.NewGetOpts())
+	if err != nil || len(audit) != 1 || audit[0].Type != "DryRun" || audit[0].Data["operation"] != "ns.delete" {
+		t.Errorf("Expected one DryRun audit entry, got %v (err: %v)", audit, err)
+	}
+
+	// Unknown namespaces are reported as not found
+	if _, rpcErr := h.route(ctx, "ns.delete", []interface{}{"missing", map[string]interface{}{"dryRun": true}}); rpcErr == nil || rpcErr.Code != "NAMESPACE_NOT_FOUND" {
+		t.Errorf("Expected NAMESPACE_NOT_FOUND, got %v", rpcErr)
+	}
+}
+
+// TestNamespaceDeleteDryRun_Errors tests invalid options, which must not
+// delete anything, empty namespaces, metadata sizes and scans over several
+// batches
+func TestNamespaceDeleteDryRun_Errors(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	for _, args := range [][]interface{}{
+		{},
+		{float64(1)},
+		{""},
+		{"test-ns", "dryRun"},
+		{"test-ns", map[string]interface{}{"dryRun": "true"}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.delete", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, err := st.GetNamespace(ctx, "test-ns"); err != nil {
+		t.Fatalf("Namespace should still exist: %v", err)
+	}
+
+	// An empty namespace has no position range
+	result, rpcErr := h.route(ctx, "ns.delete", []interface{}{"test-ns", map[string]interface{}{"dryRun": true}})
+	if rpcErr != nil {
+		t.Fatalf("ns.delete dry run failed: %v", rpcErr.Message)
+	}
+	report := result.(map[string]interface{})
+	if report["messages"] != float64(0) || report["streams"] != float64(0) || report["firstGlobalPosition"] != nil || report["lastGlobalPosition"] != nil {
+		t.Errorf("Unexpected report for an empty namespace: %v", report)
+	}
+
+	// Metadata counts towards the size
+	if size := messageSize(&store.Message{Data: map[string]interface{}{"a": 1}, Metadata: map[string]interface{}{"m": 1}}); size != int64(2*len(`{"a":1}`)) {
+		t.Errorf("Expected data and metadata to be counted, got %d", size)
+	}
+
+	// Scans continue past a full batch; the earlier dry run's audit entry is
+	// counted too
+	for i := 0; i < dryRunBatchSize; i++ {
+		if _, err := st.WriteMessage(ctx, "test-ns", "account-1", &store.Message{Type: "Deposited", Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	result, rpcErr = h.route(ctx, "ns.delete", []interface{}{"test-ns", map[string]interface{}{"dryRun": true}})
+	if rpcErr != nil {
+		t.Fatalf("ns.delete dry run failed: %v", rpcErr.Message)
+	}
+	report = result.(map[string]interface{})
+	if report["messages"] != float64(dryRunBatchSize+1) || report["streams"] != float64(2) || report["lastGlobalPosition"] != float64(dryRunBatchSize+1) {
+		t.Errorf("Unexpected report across batches: %v", report)
+	}
+}
+
+// TestNamespaceFreeze tests that frozen namespaces and read-only servers reject writes but serve reads
+func TestNamespaceFreeze(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	st, err := sqlite.New(db, &sqlite.Config{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateNamespace(ctx, "test-ns", "token-hash", "Test namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	nsCtx := context.WithValue(ctx, ContextKeyNamespace, "test-ns")
+
+	h := NewRPCHandler("test", st, NewPubSub())
+	write := func() *RPCError {
+		_, rpcErr := h.route(nsCtx, "stream.write", []interface{}{"account-1", map[string]interface{}{
+			"type": "Opened",
+			"data": map[string]interface{}{},
+		}})
+		return rpcErr
+	}
+	if rpcErr := write(); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+
+	result, rpcErr := h.route(nsCtx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}})
+	if rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	if frozen := result.(map[string]interface{}); frozen["frozen"] != true || frozen["reason"] != "migration" {
+		t.Errorf("Unexpected freeze result: %v", frozen)
+	}
+
+	// Writes and deletes are rejected, reads still work
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for write to frozen namespace, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "ns.delete", []interface{}{"test-ns"}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for delete of frozen namespace, got %v", rpcErr)
+	}
+	result, rpcErr = h.route(nsCtx, "stream.get", []interface{}{"account-1"})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	if msgs := result.([]interface{}); len(msgs) != 1 {
+		t.Errorf("Expected 1 message from frozen namespace, got %d", len(msgs))
+	}
+
+	if _, rpcErr := h.route(nsCtx, "ns.unfreeze", nil); rpcErr != nil {
+		t.Fatalf("ns.unfreeze failed: %v", rpcErr.Message)
+	}
+	if rpcErr := write(); rpcErr != nil {
+		t.Errorf("Expected write after unfreeze to succeed, got %v", rpcErr.Message)
+	}
+
+	// Server-wide read-only mode rejects writes and namespace creation
+	guard := NewWriteGuard(NewNamespaceMetadata(st))
+	guard.SetReadOnly(true)
+	h.SetWriteGuard(guard)
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for write in read-only mode, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "ns.create", []interface{}{"other-ns"}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for ns.create in read-only mode, got %v", rpcErr)
+	}
+}
+
+// TestNamespaceFreeze_Errors tests invalid options, freezing twice,
+// unfreezing an unfrozen namespace, unknown namespaces and freezes over
+// suspensions
+func TestNamespaceFreeze_Errors(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	for _, args := range [][]interface{}{
+		{"migration"},
+		{map[string]interface{}{"reason": float64(1)}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.freeze", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// Unfreezing a namespace that is not frozen does nothing
+	if result, rpcErr := h.route(ctx, "ns.unfreeze", nil); rpcErr != nil || result.(map[string]interface{})["frozen"] != false {
+		t.Errorf("Expected unfreeze to succeed, got %v %v", result, rpcErr)
+	}
+
+	// Freezing again keeps the original reason and time
+	first, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "legal hold"}})
+	if rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	second, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}})
+	if rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	if a, b := first.(map[string]interface{}), second.(map[string]interface{}); b["reason"] != "legal hold" || b["since"] != a["since"] {
+		t.Errorf("Expected the original freeze to be kept, got %v", b)
+	}
+
+	// A freeze wins over a suspension
+	if _, err := h.guard.Suspend(context.Background(), "test-ns", "payment overdue", false); err != nil {
+		t.Fatalf("Suspend failed: %v", err)
+	}
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-1", map[string]interface{}{"type": "Opened", "data": map[string]interface{}{}}}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for a frozen and suspended namespace, got %v", rpcErr)
+	}
+
+	missing := context.WithValue(context.Background(), ContextKeyNamespace, "missing-ns")
+	for _, method := range []string{"ns.freeze", "ns.unfreeze"} {
+		if _, rpcErr := h.route(missing, method, nil); rpcErr == nil || rpcErr.Code != "NAMESPACE_NOT_FOUND" {
+			t.Errorf("Expected NAMESPACE_NOT_FOUND for %s, got %v", method, rpcErr)
+		}
+	}
+}
+
+// failingNamespaceStore fails namespace lookups with err while it is set
+type failingNamespaceStore struct {
+	store.Store
+	err error
+}
+
+func (s *failingNamespaceStore) GetNamespace(ctx context.Context, id string) (*store.Namespace, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.Store.GetNamespace(ctx, id)
+}
+
+// TestWriteGuard_LookupFailure tests that writes are rejected when the
+// namespace cannot be looked up, unless its last state is known
+func TestWriteGuard_LookupFailure(t *testing.T) {
+	st := &failingNamespaceStore{Store: newTestStore(t)}
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+	write := func() *RPCError {
+		_, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-1", map[string]interface{}{
+			"type": "Opened",
+			"data": map[string]interface{}{},
+		}})
+		return rpcErr
+	}
+
+	// Without a known state the lookup error is returned
+	st.err = &store.OverloadedError{RetryAfter: time.Second}
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "OVERLOADED" {
+		t.Errorf("Expected OVERLOADED, got %v", rpcErr)
+	}
+	st.err = store.ErrBackendUnavailable
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "BACKEND_UNAVAILABLE" {
+		t.Errorf("Expected BACKEND_UNAVAILABLE, got %v", rpcErr)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	NewImportHandler(st).ServeHTTP(rec, req.WithContext(ctx))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected /import to return 503 with Retry-After, got %d %s", rec.Code, rec.Body.String())
+	}
+	st.err = errors.New("disk I/O error")
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "BACKEND_ERROR" {
+		t.Errorf("Expected BACKEND_ERROR, got %v", rpcErr)
+	}
+	st.err = nil
+	missing := context.WithValue(context.Background(), ContextKeyNamespace, "missing-ns")
+	if err := h.guard.Check(missing, "missing-ns"); !errors.Is(err, store.ErrNamespaceNotFound) {
+		t.Errorf("Expected ErrNamespaceNotFound for an unknown namespace, got %v", err)
+	}
+
+	// A frozen namespace stays frozen while its state cannot be refreshed
+	if _, err := h.guard.Freeze(context.Background(), "test-ns", "legal hold"); err != nil {
+		t.Fatalf("Freeze failed: %v", err)
+	}
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Fatalf("Expected READ_ONLY, got %v", rpcErr)
+	}
+	h.guard.meta.mu.Lock()
+	h.guard.meta.entries["test-ns"].checked = time.Now().Add(-2 * namespaceMetadataTTL)
+	h.guard.meta.mu.Unlock()
+	st.err = store.ErrBackendUnavailable
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY from the last known state, got %v", rpcErr)
+	}
+}
+
+// TestNamespaceSuspend tests that suspended namespaces reject writes with
+// NAMESPACE_SUSPENDED and keep their data
+func TestNamespaceSuspend(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.Background()
+	if _, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-a"}); rpcErr != nil {
+		t.Fatalf("ns.create failed: %v", rpcErr.Message)
+	}
+
+	nsCtx := context.WithValue(ctx, ContextKeyNamespace, "tenant-a")
+	adminCtx := context.WithValue(ctx, ContextKeyNamespace, "test-ns")
+	write := func() *RPCError {
+		_, rpcErr := h.route(nsCtx, "stream.write", []interface{}{"account-1", map[string]interface{}{
+			"type": "Opened",
+			"data": map[string]interface{}{},
+		}})
+		return rpcErr
+	}
+	if rpcErr := write(); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+
+	// A namespace cannot suspend itself
+	if _, rpcErr := h.route(nsCtx, "ns.suspend", []interface{}{"tenant-a"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for self-suspension, got %v", rpcErr)
+	}
+
+	result, rpcErr := h.route(adminCtx, "ns.suspend", []interface{}{"tenant-a", map[string]interface{}{"reason": "payment overdue"}})
+	if rpcErr != nil {
+		t.Fatalf("ns.suspend failed: %v", rpcErr.Message)
+	}
+	if suspended := result.(map[string]interface{}); suspended["suspended"] != true || suspended["reason"] != "payment overdue" || suspended["blockReads"] != false {
+		t.Errorf("Unexpected suspend result: %v", suspended)
+	}
+
+	// Writes are rejected, reads still work
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "NAMESPACE_SUSPENDED" {
+		t.Errorf("Expected NAMESPACE_SUSPENDED for write to suspended namespace, got %v", rpcErr)
+	}
+	result, rpcErr = h.route(nsCtx, "stream.get", []interface{}{"account-1"})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	if msgs := result.([]interface{}); len(msgs) != 1 {
+		t.Errorf("Expected 1 message from suspended namespace, got %d", len(msgs))
+	}
+
+	// Escalating to blockReads keeps the original since
+	result, rpcErr = h.route(adminCtx, "ns.info", []interface{}{"tenant-a"})
+	if rpcErr != nil {
+		t.Fatalf("ns.info failed: %v", rpcErr.Message)
+	}
+	since := result.(map[string]interface{})["suspended"].(*SuspensionState).Since
+	result, rpcErr = h.route(adminCtx, "ns.suspend", []interface{}{"tenant-a", map[string]interface{}{"blockReads": true}})
+	if rpcErr != nil {
+		t.Fatalf("ns.suspend failed: %v", rpcErr.Message)
+	}
+	if suspended := result.(map[string]interface{}); suspended["since"] != since || suspended["blockReads"] != true {
+		t.Errorf("Expected escalation to keep since %v, got %v", since, suspended)
+	}
+
+	if _, rpcErr := h.route(adminCtx, "ns.resume", []interface{}{"tenant-a"}); rpcErr != nil {
+		t.Fatalf("ns.resume failed: %v", rpcErr.Message)
+	}
+	if rpcErr := write(); rpcErr != nil {
+		t.Errorf("Expected write after resume to succeed, got %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(adminCtx, "ns.resume", []interface{}{"missing"}); rpcErr == nil || rpcErr.Code != "NAMESPACE_NOT_FOUND" {
+		t.Errorf("Expected NAMESPACE_NOT_FOUND, got %v", rpcErr)
+	}
+
+	// Suspended namespaces can still be deleted
+	if _, rpcErr := h.route(adminCtx, "ns.suspend", []interface{}{"tenant-a"}); rpcErr != nil {
+		t.Fatalf("ns.suspend failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(adminCtx, "ns.delete", []interface{}{"tenant-a"}); rpcErr != nil {
+		t.Errorf("Expected delete of suspended namespace to succeed, got %v", rpcErr.Message)
+	}
+}
+
+func TestNamespaceCreateOnShard(t *testing.T) {
+	openStore := func(dir string) (store.Store, error) {
+		db, err := sql.Open("sqlite", filepath.Join(dir, "metadata.db"))
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.New(db, &sqlite.Config{DataDir: dir})
+	}
+	catalog, err := openStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	sharded, err := store.NewShardedStore(catalog, []store.Shard{{Name: "eu-1", URL: t.TempDir()}}, openStore)
+	if err != nil {
+		t.Fatalf("Failed to create sharded store: %v", err)
+	}
+	defer sharded.Close()
+
+	ctx := context.Background()
+	h := NewRPCHandler("test", sharded, NewPubSub())
+
+	// Without sharding the option is rejected
+	if _, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-a", map[string]interface{}{"shard": "eu-1"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without sharding, got %v", rpcErr)
+	}
+
+	h.SetShards(sharded)
+	if _, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-a", map[string]interface{}{"shard": "us-9"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for unknown shard, got %v", rpcErr)
+	}
+	result, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-a", map[string]interface{}{"shard": "eu-1"}})
+	if rpcErr != nil {
+		t.Fatalf("ns.create failed: %v", rpcErr.Message)
+	}
+	if created := result.(map[string]interface{}); created["shard"] != "eu-1" {
+		t.Errorf("Expected namespace on eu-1, got %v", created)
+	}
+
+	result, rpcErr = h.route(ctx, "ns.info", []interface{}{"tenant-a"})
+	if rpcErr != nil {
+		t.Fatalf("ns.info failed: %v", rpcErr.Message)
+	}
+	if info := result.(map[string]interface{}); info["shard"] != "eu-1" {
+		t.Errorf("Expected ns.info to report eu-1, got %v", info)
+	}
+
+	result, rpcErr = h.route(ctx, "ns.shards", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.shards failed: %v", rpcErr.Message)
+	}
+	shards := result.([]interface{})
+	if len(shards) != 2 || shards[1].(map[string]interface{})["namespaces"] != 1 {
+		t.Errorf("Unexpected shards: %v", shards)
+	}
+}
+
+// overloadedStore sheds every stream version lookup, like a saturated LimiterStore
+type overloadedStore struct {
+	store.Store
+}
+
+func (s *overloadedStore) GetStreamVersion(ctx context.Context, namespace, streamName string) (int64, error) {
+	return 0, &store.OverloadedError{RetryAfter: 1500 * time.Millisecond}
+}
+
+// TestStoreOverloaded tests that limiter rejections are reported as OVERLOADED
+func TestStoreOverloaded(t *testing.T) {
+	h := NewRPCHandler("test", &overloadedStore{Store: newTestStore(t)}, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	_, rpcErr := h.route(ctx, "stream.version", []interface{}{"account-1"})
+	if rpcErr == nil || rpcErr.Code != "OVERLOADED" {
+		t.Fatalf("Expected OVERLOADED, got %v", rpcErr)
+	}
+	if seconds, ok := retryAfter(rpcErr); !ok || seconds != 2 {
+		t.Errorf("Expected retryAfter 2, got %v", rpcErr.Details)
+	}
+}
+
+// overloadedNamespaceStore sheds every namespace lookup of a backend that
+// supports staged imports
+type overloadedNamespaceStore struct {
+	store.Store
+}
+
+func (s *overloadedNamespaceStore) GetNamespace(ctx context.Context, id string) (*store.Namespace, error) {
+	return nil, &store.OverloadedError{RetryAfter: 1500 * time.Millisecond, Limit: 8, InFlight: 8}
+}
+
+func (s *overloadedNamespaceStore) SwapNamespaceMessages(ctx context.Context, a, b string) error {
+	return s.Store.(store.NamespaceSwapper).SwapNamespaceMessages(ctx, a, b)
+}
+
+// TestImportExport_Overloaded tests that staged imports and exports shed by
+// the limiter return 503 with Retry-After and the error details
+func TestImportExport_Overloaded(t *testing.T) {
+	st := &overloadedNamespaceStore{Store: newTestStore(t)}
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, tc := range []struct {
+		name    string
+		handler http.Handler
+		req     *http.Request
+	}{
+		{"staged import", NewImportHandler(st), httptest.NewRequest(http.MethodPost, "/import?stage=true", strings.NewReader(""))},
+		{"export", NewExportHandler(st, NewPubSub()), httptest.NewRequest(http.MethodGet, "/export?from=nightly", nil)},
+	} {
+		rec := httptest.NewRecorder()
+		tc.handler.ServeHTTP(rec, tc.req.WithContext(ctx))
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "2" {
+			t.Errorf("%s: expected 503 with Retry-After 2, got %d %q", tc.name, rec.Code, rec.Header().Get("Retry-After"))
+		}
+		var resp ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Error == nil {
+			t.Fatalf("%s: failed to decode error: %s", tc.name, rec.Body.String())
+		}
+		if resp.Error.Code != "OVERLOADED" || resp.Error.Details["retryAfter"] != float64(2) ||
+			resp.Error.Details["limit"] != float64(8) || resp.Error.Details["inFlight"] != float64(8) {
+			t.Errorf("%s: unexpected error: %+v", tc.name, resp.Error)
+		}
+	}
+}
+
+// TestStreamInfo tests the stream.info summary
+func TestStreamInfo(t *testing.T) {
+	h := NewRPCHandler("test", newTestStore(t), NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, msgType := range []string{"Opened", "Deposited", "Deposited", "Withdrawn", "Deposited"} {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-1", map[string]interface{}{
+			"type": msgType,
+			"data": map[string]interface{}{"amount": 10},
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+
+	result, rpcErr := h.route(ctx, "stream.info", []interface{}{"account-1"})
+	if rpcErr != nil {
+		t.Fatalf("stream.info failed: %v", rpcErr.Message)
+	}
+	info := result.(map[string]interface{})
+	if info["version"] != int64(4) || info["messageCount"] != int64(5) || info["firstPosition"] != int64(0) {
+		t.Errorf("Unexpected info: %v", info)
+	}
+	if size := info["dataBytes"].(int64); size != 5*int64(len(`{"amount":10}`)) {
+		t.Errorf("Expected the size of 5 payloads, got %d", size)
+	}
+	types := info["types"].([]interface{})
+	if len(types) != 3 || types[0].(map[string]interface{})["type"] != "Deposited" || types[0].(map[string]interface{})["count"] != int64(3) {
+		t.Errorf("Expected Deposited first with 3 messages, got %v", types)
+	}
+
+	if result, rpcErr := h.route(ctx, "stream.info", []interface{}{"account-2"}); rpcErr != nil || result != nil {
+		t.Errorf("Expected null for a missing stream, got %v (%v)", result, rpcErr)
+	}
+}
+
+// TestStreamInfo_Edges tests invalid arguments, renamed and truncated
+// streams, metadata sizes and types with equal counts
+func TestStreamInfo_Edges(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, args := range [][]interface{}{{}, {float64(1)}, {""}} {
+		if _, rpcErr := h.route(ctx, "stream.info", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	for _, msgType := range []string{"Opened", "Deposited", "Closed"} {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-old", map[string]interface{}{
+			"type":     msgType,
+			"data":     map[string]interface{}{},
+			"metadata": map[string]interface{}{"m": 1},
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "stream.rename", []interface{}{"account-old", "account-1"}); rpcErr != nil {
+		t.Fatalf("stream.rename failed: %v", rpcErr.Message)
+	}
+	if _, err := st.(store.StreamTruncater).TruncateStream(context.Background(), "test-ns", "account-1", 1); err != nil {
+		t.Fatalf("Failed to truncate stream: %v", err)
+	}
+
+	// The old name reads the new stream, from its oldest remaining message
+	result, rpcErr := h.route(ctx, "stream.info", []interface{}{"account-old"})
+	if rpcErr != nil {
+		t.Fatalf("stream.info failed: %v", rpcErr.Message)
+	}
+	info := result.(map[string]interface{})
+	if info["stream"] != "account-1" || info["version"] != int64(2) || info["messageCount"] != int64(2) || info["firstPosition"] != int64(1) {
+		t.Errorf("Unexpected info: %v", info)
+	}
+	if size := info["dataBytes"].(int64); size != 2*int64(len(`{}`)+len(`{"m":1}`)) {
+		t.Errorf("Expected data and metadata to be counted, got %d", size)
+	}
+
+	// Types with equal counts are sorted by name
+	types := info["types"].([]interface{})
+	if len(types) != 2 || types[0].(map[string]interface{})["type"] != "Closed" || types[1].(map[string]interface{})["type"] != "Deposited" {
+		t.Errorf("Expected Closed before Deposited, got %v", types)
+	}
+	if info["truncated"] != false {
+		t.Errorf("Expected a complete scan, got %v", info["truncated"])
+	}
+}
+
+// TestCategoryGetWhere tests that category.get returns only the messages
+// matching an EQL filter
+func TestCategoryGetWhere(t *testing.T) {
+	h := NewRPCHandler("test", newTestStore(t), NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for i, msgType := range []string{"Deposited", "Withdrawn", "Deposited", "Deposited"} {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-1", map[string]interface{}{
+			"type": msgType,
+			"data": map[string]interface{}{"amount": float64(i * 100)},
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+
+	result, rpcErr := h.route(ctx, "category.get", []interface{}{"account", map[string]interface{}{
+		"where":     "type = 'Deposited' AND data.amount > 100",
+		"batchSize": float64(1),
+	}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr.Message)
+	}
+	messages := result.([]interface{})
+	if len(messages) != 1 || messages[0].([]interface{})[5].(map[string]interface{})["amount"] != float64(200) {
+		t.Errorf("Expected the deposit of 200, got %v", messages)
+	}
+
+	if _, rpcErr := h.route(ctx, "category.get", []interface{}{"account", map[string]interface{}{"where": "amount > 100"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an unknown field, got %v", rpcErr)
+	}
+}
+
+func TestCategoryGetSample(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for i := 0; i < 100; i++ {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{fmt.Sprintf("order-%d", i), map[string]interface{}{
+			"type": "Placed",
+			"data": map[string]interface{}{},
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+
+	sample := func() []interface{} {
+		t.Helper()
+		result, rpcErr := h.route(ctx, "category.get", []interface{}{"order", map[string]interface{}{"sample": 0.3}})
+		if rpcErr != nil {
+			t.Fatalf("category.get failed: %v", rpcErr.Message)
+		}
+		return result.([]interface{})
+	}
+
+	// The same messages, chosen by ID, on every read
+	first := sample()
+	if len(first) == 0 || len(first) == 100 {
+		t.Fatalf("Expected a partial sample, got %d messages", len(first))
+	}
+	for _, msg := range first {
+		if id := msg.([]interface{})[0].(string); !store.InSample(id, 0.3) {
+			t.Errorf("Message %s is not in the sample", id)
+		}
+	}
+	if second := sample(); fmt.Sprint(second) != fmt.Sprint(first) {
+		t.Errorf("Expected repeated samples to match")
+	}
+
+	for _, bad := range []interface{}{float64(0), float64(1.5), "0.1"} {
+		if _, rpcErr := h.route(ctx, "category.get", []interface{}{"order", map[string]interface{}{"sample": bad}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for sample %v, got %v", bad, rpcErr)
+		}
+	}
+}
+
+func TestAuthWhoami(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano)
+	if _, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-a", map[string]interface{}{
+		"label":     "ci",
+		"expiresAt": expiresAt,
+	}}); rpcErr != nil {
+		t.Fatalf("ns.create failed: %v", rpcErr.Message)
+	}
+
+	nsCtx := context.WithValue(ctx, ContextKeyNamespace, "tenant-a")
+	result, rpcErr := h.route(nsCtx, "auth.whoami", nil)
+	if rpcErr != nil {
+		t.Fatalf("auth.whoami failed: %v", rpcErr.Message)
+	}
+	info := result.(map[string]interface{})
+	if info["namespace"] != "tenant-a" || info["label"] != "ci" || info["expiresAt"] != expiresAt {
+		t.Errorf("Unexpected whoami result: %v", info)
+	}
+	if scopes := info["scopes"].([]string); len(scopes) != 3 || scopes[2] != "admin" {
+		t.Errorf("Expected read, write and admin scopes, got %v", scopes)
+	}
+
+	// Rotating without options clears the label and expiry
+	if _, rpcErr := h.route(ctx, "ns.rotateToken", []interface{}{"tenant-a"}); rpcErr != nil {
+		t.Fatalf("ns.rotateToken failed: %v", rpcErr.Message)
+	}
+	dataPath := context.WithValue(nsCtx, ContextKeyDataPathOnly, true)
+	result, rpcErr = h.route(dataPath, "auth.whoami", nil)
+	if rpcErr != nil {
+		t.Fatalf("auth.whoami failed: %v", rpcErr.Message)
+	}
+	info = result.(map[string]interface{})
+	if info["label"] != nil || info["expiresAt"] != nil {
+		t.Errorf("Expected no label or expiry after rotation, got %v", info)
+	}
+	if scopes := info["scopes"].([]string); len(scopes) != 2 {
+		t.Errorf("Expected no admin scope on the public listener, got %v", scopes)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-b", map[string]interface{}{
+		"expiresAt": "2001-01-01T00:00:00Z",
+	}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a past expiry, got %v", rpcErr)
+	}
+}
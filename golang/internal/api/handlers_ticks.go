@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleTickCreate implements tick.create
+// Args: [name, {cron, stream, type, data}]
+// Appends a message to stream on the cron schedule (UTC) until tick.delete.
+// Returns the tick with its first occurrence.
+func (h *RPCHandler) handleTickCreate(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "tick.create requires 2 arguments: name, config",
+		}
+	}
+	name, ok := args[0].(string)
+	if !ok || name == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "name must be a non-empty string",
+		}
+	}
+	raw, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "config must be an object",
+		}
+	}
+	var cfg TickConfig
+	if err := decodeMetadataValue(raw, &cfg); err != nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("Invalid config: %v", err),
+		}
+	}
+	cfg.Name = name
+	if err := cfg.Validate(); err != nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	}
+	if err := h.names.Validate(cfg.Stream); err != nil {
+		return nil, invalidStreamNameError(err)
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.ticks == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrTicksDisabled.Error(),
+		}
+	}
+
+	status, err := h.ticks.Create(ctx, namespace, cfg)
+	if err != nil {
+		return nil, tickError(namespace, err)
+	}
+	return encodeMetadataValue(status), nil
+}
+
+// handleTickList implements tick.list
+// Args: []
+// Returns the caller's ticks with their next occurrence, by name.
+func (h *RPCHandler) handleTickList(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.ticks == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrTicksDisabled.Error(),
+		}
+	}
+
+	ticks, err := h.ticks.List(ctx, namespace)
+	if err != nil {
+		return nil, tickError(namespace, err)
+	}
+	result := make([]interface{}, len(ticks))
+	for i, t := range ticks {
+		result[i] = encodeMetadataValue(t)
+	}
+	return result, nil
+}
+
+// handleTickDelete implements tick.delete
+// Args: [name]
+// Stops a tick; the messages it wrote are kept.
+func (h *RPCHandler) handleTickDelete(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "tick.delete requires 1 argument: name",
+		}
+	}
+	name, ok := args[0].(string)
+	if !ok || name == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "name must be a non-empty string",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.ticks == nil {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrTicksDisabled.Error(),
+		}
+	}
+
+	if err := h.ticks.Delete(ctx, namespace, name); err != nil {
+		return nil, tickError(namespace, err)
+	}
+	return map[string]interface{}{"name": name, "deleted": true}, nil
+}
+
+// tickError maps tick errors to RPC errors
+func tickError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, ErrTickNotFound):
+		return &RPCError{
+			Code:    "TICK_NOT_FOUND",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrTickExists):
+		return &RPCError{
+			Code:    "TICK_EXISTS",
+			Message: err.Error(),
+		}
+	case errors.Is(err, ErrInvalidTick):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Tick update failed: %v", err),
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleNamespaceRotateToken replaces a namespace's token
+// Request: ["ns.rotateToken", "namespace-id", {opts}]
+// opts.token: optional token to use (must be valid format for namespace)
+// opts.label, opts.expiresAt: optional label and RFC 3339 expiry of the new token
+// Response: {"namespace": "tenant-a", "token": "ns_...", "rotatedAt": "..."}
+// The previous token stops authenticating immediately, and its label and
+// expiry are replaced by the new token's.
+func (h *RPCHandler) handleNamespaceRotateToken(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "ns.rotateToken requires at least 1 argument: namespace ID",
+		}
+	}
+
+	// Parse namespace ID
+	namespaceID, ok := args[0].(string)
+	if !ok || namespaceID == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "namespace ID must be a non-empty string",
+		}
+	}
+
+	if h.guard.ReadOnly() {
+		return nil, &RPCError{
+			Code:    "READ_ONLY",
+			Message: ErrServerReadOnly.Error(),
+		}
+	}
+
+	// Parse optional options
+	var token string
+	var tokenInfo *TokenInfo
+	if len(args) > 1 && args[1] != nil {
+		opts, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if v, exists := opts["token"]; exists {
+			if token, ok = v.(string); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: "options.token must be a string",
+				}
+			}
+			tokenNS, err := auth.ParseToken(token)
+			if err != nil {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("Invalid token format: %v", err),
+				}
+			}
+			if tokenNS != namespaceID {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: fmt.Sprintf("Token is for namespace '%s', not '%s'", tokenNS, namespaceID),
+				}
+			}
+		}
+		var rpcErr *RPCError
+		if tokenInfo, rpcErr = parseTokenOptions(opts); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	if token == "" {
+		var err error
+		token, err = auth.GenerateToken(namespaceID)
+		if err != nil {
+			return nil, &RPCError{
+				Code:    "BACKEND_ERROR",
+				Message: fmt.Sprintf("Failed to generate token: %v", err),
+			}
+		}
+	}
+
+	// The replaced token is remembered so it is rejected as TOKEN_REVOKED
+	ns, err := h.store.GetNamespace(ctx, namespaceID)
+	if err == nil {
+		err = h.store.UpdateNamespaceToken(ctx, namespaceID, auth.HashToken(token))
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNamespaceNotFound) {
+			return nil, &RPCError{
+				Code:    "NAMESPACE_NOT_FOUND",
+				Message: fmt.Sprintf("Namespace '%s' not found", namespaceID),
+			}
+		}
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to update token: %v", err),
+		}
+	}
+
+	revokedHash := ns.TokenHash
+	if revokedHash == auth.HashToken(token) {
+		revokedHash = ""
+	}
+	if err := setTokenInfo(ctx, h.store, namespaceID, tokenInfo, revokedHash); err != nil {
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to store token info: %v", err),
+		}
+	}
+
+	result := map[string]interface{}{
+		"namespace": namespaceID,
+		"token":     token,
+		"rotatedAt": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	addTokenInfo(result, tokenInfo)
+	return result, nil
+}
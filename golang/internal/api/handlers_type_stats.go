@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleNamespaceTypeStats implements ns.typeStats
+// Args: []
+// Returns the number of messages of each type in the caller's namespace and
+// when one was first and last written. Counts are kept in the namespace's
+// metadata and only messages written since the previous call are read.
+func (h *RPCHandler) handleNamespaceTypeStats(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	stats, upToDate, err := UpdateTypeStats(ctx, h.store, namespace)
+	if err != nil {
+		return nil, typeStatsError(namespace, err)
+	}
+
+	types := make([]interface{}, 0, len(stats.Types))
+	for _, name := range stats.Sorted() {
+		stat := stats.Types[name]
+		types = append(types, map[string]interface{}{
+			"type":      name,
+			"count":     stat.Count,
+			"firstSeen": stat.FirstSeen,
+			"lastSeen":  stat.LastSeen,
+		})
+	}
+	return map[string]interface{}{
+		"types":          types,
+		"globalPosition": stats.Position,
+		"upToDate":       upToDate,
+	}, nil
+}
+
+// typeStatsError maps type count errors to RPC errors
+func typeStatsError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to count message types: %v", err),
+	}
+}
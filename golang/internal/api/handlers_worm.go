@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// handleWormEnable implements ns.worm.enable
+// Args: []
+// Puts the caller's namespace in WORM mode for good: deletes, truncation and
+// redaction are rejected, and configuration changes are recorded as events.
+func (h *RPCHandler) handleWormEnable(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if _, err := EnableWorm(ctx, h.store, namespace); err != nil {
+		return nil, wormError(namespace, err)
+	}
+	return h.handleWormStatus(ctx, nil)
+}
+
+// handleWormStatus implements ns.worm.status
+// Args: []
+// Returns whether the caller's namespace is in WORM mode and its latest attestation.
+func (h *RPCHandler) handleWormStatus(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, wormError(namespace, err)
+	}
+
+	result := map[string]interface{}{
+		"enabled":      false,
+		"attestations": h.attest != nil,
+		"attestation":  nil,
+	}
+	state := WormFromMetadata(ns.Metadata)
+	if state == nil {
+		return result, nil
+	}
+	result["enabled"] = true
+	result["enabledAt"] = state.EnabledAt
+	if att := AttestationFromMetadata(ns.Metadata); att != nil {
+		result["attestation"] = encodeMetadataValue(att)
+	}
+	return result, nil
+}
+
+// handleWormAttest implements ns.worm.attest
+// Args: []
+// Signs the current head hash of the caller's WORM namespace now.
+func (h *RPCHandler) handleWormAttest(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	att, err := h.attest.Attest(ctx, namespace)
+	if err != nil {
+		return nil, wormError(namespace, err)
+	}
+	return encodeMetadataValue(att), nil
+}
+
+// wormProtectedError is the error for deleting or changing messages of a WORM namespace
+func wormProtectedError(namespace string) *RPCError {
+	return &RPCError{
+		Code:    "WORM_PROTECTED",
+		Message: fmt.Sprintf("Namespace '%s' is in WORM mode: messages cannot be deleted or changed", namespace),
+	}
+}
+
+// wormError maps WORM mode and attestation errors to RPC errors
+func wormError(namespace string, err error) *RPCError {
+	switch {
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	case errors.Is(err, ErrNotWorm), errors.Is(err, ErrAttestationDisabled), errors.Is(err, ErrInvalidNamespaceConfig):
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: err.Error(),
+		}
+	case store.IsOverloaded(err):
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("WORM operation failed: %v", err),
+	}
+}
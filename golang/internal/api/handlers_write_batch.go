@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// streamWriteBatchLimit is the largest number of messages stream.writeBatch accepts
+const streamWriteBatchLimit = 1000
+
+// handleStreamWriteBatch implements stream.writeBatch
+// Args: [streamName, [{type, data, metadata, id}, ...], {expectedVersion}]
+// Appends the messages to one stream in a single transaction. expectedVersion
+// is checked once against the stream before the first message; a conflict
+// writes none of them. Derived events of every message join the transaction.
+func (h *RPCHandler) handleStreamWriteBatch(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 2 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "stream.writeBatch requires at least 2 arguments: streamName and messages",
+		}
+	}
+
+	// Parse stream name
+	streamName, ok := args[0].(string)
+	if !ok || streamName == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "streamName must be a non-empty string",
+		}
+	}
+	if err := h.names.Validate(streamName); err != nil {
+		return nil, invalidStreamNameError(err)
+	}
+
+	// Parse messages
+	list, ok := args[1].([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "messages must be a non-empty array",
+		}
+	}
+	if len(list) > streamWriteBatchLimit {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("messages must hold at most %d messages", streamWriteBatchLimit),
+		}
+	}
+	msgs := make([]*store.Message, len(list))
+	for i, item := range list {
+		field := fmt.Sprintf("messages[%d]", i)
+		msgObj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: field + " must be an object",
+			}
+		}
+		msg, rpcErr := parseWriteMessage(msgObj, field)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		if idVal, exists := msgObj["id"]; exists {
+			if msg.ID, ok = idVal.(string); !ok {
+				return nil, &RPCError{
+					Code:    "INVALID_REQUEST",
+					Message: field + ".id must be a string",
+				}
+			}
+		}
+		msgs[i] = msg
+	}
+
+	// Parse optional expectedVersion, checked on the first message only
+	if len(args) > 2 && args[2] != nil {
+		optsObj, ok := args[2].(map[string]interface{})
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options must be an object",
+			}
+		}
+		if evVal, exists := optsObj["expectedVersion"]; exists {
+			version, rpcErr := parseExpectedVersion(evVal)
+			if rpcErr != nil {
+				return nil, rpcErr
+			}
+			msgs[0].ExpectedVersion = version
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	return h.appendMessages(ctx, namespace, streamName, msgs, false)
+}
+
+// appendMessages is the write pipeline of stream.write and the writes built
+// on it: it writes parsed messages to one stream with writeMessages and
+// returns the stream.writeBatch response. queueable lets a single message
+// wait in the write queue while the backend is unavailable, answering with
+// its claim.
+func (h *RPCHandler) appendMessages(ctx context.Context, namespace, streamName string, msgs []*store.Message, queueable bool) (interface{}, *RPCError) {
+	for _, msg := range msgs {
+		msg.StreamName = streamName
+	}
+	outcome, rpcErr := h.writeMessages(ctx, namespace, msgs, PriorityInteractive, queueable)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if outcome.queued != nil {
+		return outcome.queued, nil
+	}
+
+	results := outcome.results
+	written := make([]interface{}, len(msgs))
+	for i, msg := range msgs {
+		written[i] = map[string]interface{}{
+			"id":             msg.ID,
+			"position":       results[i].Position,
+			"globalPosition": results[i].GlobalPosition,
+		}
+	}
+	last := results[len(msgs)-1]
+	response := map[string]interface{}{
+		"position":       last.Position,
+		"globalPosition": last.GlobalPosition,
+		"messages":       written,
+	}
+	if len(outcome.derived) > 0 {
+		response["derived"] = derivedInfo(outcome.derived, results[len(msgs):])
+	}
+	return response, nil
+}
+
+// writeOutcome is the result of writeMessages
+type writeOutcome struct {
+	results []*store.WriteResult // One per message, then one per derived event
+	derived []*store.Message     // Derived events and standing query results written with the messages
+	queued  interface{}          // Claim of a queued write; results are nil then
+}
+
+// writeMessages writes messages, each to its own StreamName, with their
+// derived events in a single transaction, after alias resolution, write
+// checks, admission at priority, ID generation, metadata templates and
+// plugins, and publishes the writes. Batches of several messages need a
+// store.AtomicWriter backend. queueable lets a single message wait in the
+// write queue while the backend is unavailable.
+func (h *RPCHandler) writeMessages(ctx context.Context, namespace string, msgs []*store.Message, priority WritePriority, queueable bool) (*writeOutcome, *RPCError) {
+	writer, ok := h.store.(store.AtomicWriter)
+	if !ok && len(msgs) > 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "Atomic batch writes are not supported by the backend",
+		}
+	}
+
+	// Reject writes to frozen namespaces and read-only servers
+	if rpcErr := h.checkWritable(ctx, namespace); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	for _, msg := range msgs {
+		// Writes to a renamed stream go to its new name
+		msg.StreamName = h.aliases.Resolve(ctx, namespace, msg.StreamName)
+
+		// Categories an edge pulls from its hub are only written on the hub
+		if rpcErr := h.checkHubOwned(namespace, msg.StreamName); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	// Shed load before it reaches the backend
+	if wait, ok := h.admit.Admit(namespace, priority, len(msgs)); !ok {
+		return nil, h.admit.rateLimitedError(namespace, priority, wait)
+	}
+
+	// The write queue replays messages one by one, so a batch is not queued
+	queueable = queueable && len(msgs) == 1
+	if h.queue.Active() && !queueable {
+		return nil, backendUnavailableError(store.ErrBackendUnavailable)
+	}
+
+	var derived []*store.Message
+	for _, msg := range msgs {
+		// Generate ID if not provided, with the namespace's strategy
+		if msg.ID == "" {
+			id, err := h.ids.Strategy(ctx, namespace).NewID()
+			if err != nil {
+				return nil, &RPCError{
+					Code:    "INTERNAL_ERROR",
+					Message: fmt.Sprintf("failed to generate message ID: %v", err),
+				}
+			}
+			msg.ID = id
+		}
+
+		// Default metadata and plugins, as for stream.write
+		h.tmpls.Apply(ctx, namespace, msg)
+		if err := h.plugins.Apply(ctx, namespace, msg); err != nil {
+			return nil, pluginsError(namespace, err)
+		}
+
+		derived = append(derived, h.derived.Derive(ctx, namespace, msg)...)
+		derived = append(derived, h.queries.Results(ctx, namespace, msg)...)
+	}
+	for _, d := range derived {
+		if err := h.names.Validate(d.StreamName); err != nil {
+			return nil, invalidStreamNameError(err)
+		}
+	}
+
+	// Queue behind earlier queued writes to keep arrival order
+	if queueable && h.queue.Active() {
+		return h.queuedOutcome(namespace, msgs[0], derived)
+	}
+
+	// Write the messages and their derived events in one transaction
+	all := append(append([]*store.Message{}, msgs...), derived...)
+	var results []*store.WriteResult
+	var err error
+	switch {
+	case len(all) == 1:
+		var result *store.WriteResult
+		if result, err = h.store.WriteMessage(ctx, namespace, msgs[0].StreamName, msgs[0]); err == nil {
+			results = []*store.WriteResult{result}
+		}
+	case len(msgs) == 1:
+		results, err = writeWithDerived(ctx, h.store, namespace, msgs[0], derived)
+	default:
+		results, err = writer.WriteMessages(ctx, namespace, all)
+	}
+	if err != nil {
+		switch {
+		case queueable && h.queue != nil && store.IsBackendUnavailable(err):
+			// Queue the write if the backend is briefly unavailable
+			return h.queuedOutcome(namespace, msgs[0], derived)
+		case errors.Is(err, store.ErrNotSupported):
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "Atomic batch writes are not supported by the backend",
+			}
+		case store.IsBackendUnavailable(err):
+			return nil, backendUnavailableError(err)
+		}
+		return nil, streamWriteError(err)
+	}
+
+	// Publish events to subscribers (real-time notification)
+	if h.pubsub != nil {
+		for i, result := range results {
+			if result == nil {
+				continue
+			}
+			h.pubsub.Publish(WriteEvent{
+				Namespace:      namespace,
+				Stream:         all[i].StreamName,
+				Category:       store.Category(all[i].StreamName),
+				Position:       result.Position,
+				GlobalPosition: result.GlobalPosition,
+			})
+		}
+	}
+	return &writeOutcome{results: results, derived: derived}, nil
+}
+
+// queuedOutcome queues msg and its derived events and returns the claim
+func (h *RPCHandler) queuedOutcome(namespace string, msg *store.Message, derived []*store.Message) (*writeOutcome, *RPCError) {
+	claim, rpcErr := h.queueWrite(namespace, msg, derived...)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return &writeOutcome{queued: claim}, nil
+}
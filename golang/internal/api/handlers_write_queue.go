@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// queueWrite queues a stream.write and returns its claim as the result
+func (h *RPCHandler) queueWrite(namespace string, msg *store.Message, derived ...*store.Message) (interface{}, *RPCError) {
+	claim, err := h.queue.Enqueue(namespace, msg)
+	if err == nil {
+		// Replayed one by one after msg; msg is queued even if they are not
+		for _, d := range derived {
+			if _, err := h.queue.Enqueue(namespace, d); err != nil {
+				logger.Get().Warn().Err(err).
+					Str("namespace", namespace).
+					Str("stream", d.StreamName).
+					Msg("Failed to queue derived event")
+			}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, ErrWriteQueueFull) {
+			return nil, &RPCError{
+				Code:    "QUEUE_FULL",
+				Message: "Backend unavailable and write queue is full",
+				Details: map[string]interface{}{
+					"retryAfter": int(writeQueueMaxBackoff / time.Second),
+					"limit":      h.queue.cfg.MaxEntries,
+					"pending":    h.queue.Pending(),
+				},
+			}
+		}
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to queue message: %v", err),
+		}
+	}
+
+	return map[string]interface{}{
+		"queued": true,
+		"claim":  claim.Claim,
+	}, nil
+}
+
+// handleStreamClaim implements stream.claim
+// Args: [claim]
+// Returns the state of a write queued while the backend was unavailable.
+func (h *RPCHandler) handleStreamClaim(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Validate arguments
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "stream.claim requires 1 argument: claim",
+		}
+	}
+
+	claimID, ok := args[0].(string)
+	if !ok || claimID == "" {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "claim must be a non-empty string",
+		}
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	if h.queue == nil {
+		return nil, &RPCError{
+			Code:    "CLAIM_NOT_FOUND",
+			Message: fmt.Sprintf("Claim '%s' not found", claimID),
+		}
+	}
+
+	claim, err := h.queue.Claim(namespace, claimID)
+	if err != nil {
+		return nil, &RPCError{
+			Code:    "CLAIM_NOT_FOUND",
+			Message: fmt.Sprintf("Claim '%s' not found", claimID),
+		}
+	}
+	return claim, nil
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/eventodb/eventodb/internal/store/sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// newTestStore creates an in-memory test-mode store with one namespace,
+// "test-ns"
+func newTestStore(t *testing.T) store.Store {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	st, err := sqlite.New(db, &sqlite.Config{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	if err := st.CreateNamespace(context.Background(), "test-ns", "token-hash", "Test namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	return st
+}
@@ -0,0 +1,68 @@
+// Package api provides skipping of already imported records.
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// Import dedupe modes (the dedupeBy /import parameter)
+const (
+	ImportDedupeNone = ""
+	ImportDedupeByID = "id"
+)
+
+// ParseImportDedupe validates the dedupeBy /import parameter
+func ParseImportDedupe(value string) (string, error) {
+	switch value {
+	case ImportDedupeNone, ImportDedupeByID:
+		return value, nil
+	}
+	return "", fmt.Errorf("dedupeBy must be %q", ImportDedupeByID)
+}
+
+// DedupeImportBatch returns the messages of batch whose IDs are not yet in
+// their target streams, so an import can be re-run after a partial failure.
+//
+// Each stream is read once per batch over the positions the batch covers,
+// which works on every backend whether or not it indexes message IDs.
+func DedupeImportBatch(ctx context.Context, st store.Store, namespace string, batch []*store.Message) ([]*store.Message, error) {
+	type span struct{ first, last int64 }
+	spans := make(map[string]*span)
+	for _, msg := range batch {
+		if s, ok := spans[msg.StreamName]; !ok {
+			spans[msg.StreamName] = &span{msg.Position, msg.Position}
+		} else {
+			s.first = min(s.first, msg.Position)
+			s.last = max(s.last, msg.Position)
+		}
+	}
+
+	type streamID struct{ stream, id string }
+	existing := make(map[streamID]bool)
+	for stream, s := range spans {
+		msgs, err := st.GetStreamMessages(ctx, namespace, stream, &store.GetOpts{
+			Position:  s.first,
+			BatchSize: s.last - s.first + 1,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream %s: %w", stream, err)
+		}
+		for _, msg := range msgs {
+			existing[streamID{stream, msg.ID}] = true
+		}
+	}
+	if len(existing) == 0 {
+		return batch, nil
+	}
+
+	kept := make([]*store.Message, 0, len(batch))
+	for _, msg := range batch {
+		if !existing[streamID{msg.StreamName, msg.ID}] {
+			kept = append(kept, msg)
+		}
+	}
+	return kept, nil
+}
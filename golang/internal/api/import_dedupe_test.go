@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestDedupeImportBatch tests that only records whose IDs are already in
+// their streams are skipped, across streams and positions past the end
+func TestDedupeImportBatch(t *testing.T) {
+	for _, value := range []string{"name", "ID", " id"} {
+		if _, err := ParseImportDedupe(value); err == nil {
+			t.Errorf("Expected an error for dedupeBy=%q", value)
+		}
+	}
+
+	st := newTestStore(t)
+	ctx := context.Background()
+	var ids []string
+	for i := 0; i < 2; i++ {
+		msg := &store.Message{Type: "Deposited", Data: map[string]interface{}{}}
+		if _, err := st.WriteMessage(ctx, "test-ns", "account-1", msg); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+		ids = append(ids, msg.ID)
+	}
+
+	// Nothing to skip returns the batch as is
+	fresh := []*store.Message{{ID: "order-a", StreamName: "order-1", Position: 0}}
+	if kept, err := DedupeImportBatch(ctx, st, "test-ns", fresh); err != nil || len(kept) != 1 || kept[0] != fresh[0] {
+		t.Errorf("Expected the batch unchanged, got %v (%v)", kept, err)
+	}
+
+	batch := []*store.Message{
+		{ID: ids[0], StreamName: "account-1", Position: 0},
+		{ID: "replaced", StreamName: "account-1", Position: 1}, // Another ID at a written position is kept
+		{ID: "past-end", StreamName: "account-1", Position: 5},
+		{ID: ids[1], StreamName: "order-1", Position: 0}, // IDs are matched within their own stream
+	}
+	kept, err := DedupeImportBatch(ctx, st, "test-ns", batch)
+	if err != nil {
+		t.Fatalf("DedupeImportBatch failed: %v", err)
+	}
+	if len(kept) != 3 || kept[0].ID != "replaced" || kept[1].ID != "past-end" || kept[2].ID != ids[1] {
+		t.Errorf("Expected replaced, past-end and the order-1 record, got %v", kept)
+	}
+
+	if _, err := DedupeImportBatch(ctx, st, "missing-ns", batch); err == nil {
+		t.Error("Expected an error for an unknown namespace")
+	}
+}
@@ -4,11 +4,13 @@ package api
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/eventodb/eventodb/internal/logger"
@@ -31,6 +33,13 @@ type ExportRecord struct {
 	Data     map[string]interface{} `json:"data"`
 	Meta     map[string]interface{} `json:"meta"`
 	Time     string                 `json:"time"`
+
+	// NamespaceConfig is set only on a config line (written by export --include-config)
+	NamespaceConfig *NamespaceConfig `json:"namespaceConfig,omitempty"`
+
+	// Namespace is set only in combined archives of export --all-namespaces,
+	// which are imported with import --all-namespaces
+	Namespace string `json:"ns,omitempty"`
 }
 
 // ImportProgress represents a progress event sent during import
@@ -44,6 +53,9 @@ type ImportDone struct {
 	Done     bool   `json:"done"`
 	Imported int64  `json:"imported"`
 	Elapsed  string `json:"elapsed"`
+	Config   bool   `json:"config,omitempty"`  // Namespace config was applied
+	Skipped  int64  `json:"skipped,omitempty"` // Records already imported (dedupeBy=id)
+	Staged   bool   `json:"staged,omitempty"`  // Imported into the staging area (stage=true)
 }
 
 // ImportError represents an error event during import
@@ -55,16 +67,36 @@ type ImportError struct {
 
 // ImportHandler handles streaming import of events
 type ImportHandler struct {
-	store store.Store
+	store   store.Store
+	shipper *LogShipper          // Optional, reconfigured when a config line is imported
+	guard   *WriteGuard          // Rejects imports into frozen namespaces
+	admit   *AdmissionController // Optional, paces imports under the write rate limits
 }
 
 // NewImportHandler creates a new import handler
 func NewImportHandler(st store.Store) *ImportHandler {
 	return &ImportHandler{
 		store: st,
+		guard: NewWriteGuard(NewNamespaceMetadata(st)),
 	}
 }
 
+// SetWriteGuard replaces the write guard, so it can be shared with other write paths
+func (h *ImportHandler) SetWriteGuard(g *WriteGuard) {
+	h.guard = g
+}
+
+// SetAdmission rejects imports while the write rate limits are exhausted
+// and paces the batches of running imports
+func (h *ImportHandler) SetAdmission(a *AdmissionController) {
+	h.admit = a
+}
+
+// SetLogShipper attaches the log shipper restarted by imported namespace config
+func (h *ImportHandler) SetLogShipper(s *LogShipper) {
+	h.shipper = s
+}
+
 // HandleImport handles POST /import requests with streaming NDJSON body
 func (h *ImportHandler) HandleImport(ctx *fasthttp.RequestCtx) {
 	// Get namespace from middleware
@@ -74,9 +106,58 @@ func (h *ImportHandler) HandleImport(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// Mirror pushes from the namespace's primary may write to the read-only mirror
+	mirrorSource := string(ctx.QueryArgs().Peek("mirror"))
+
+	// Reject imports into frozen or suspended namespaces and read-only servers
+	if err := h.guard.Check(ctx, namespace); err != nil && !(mirrorSource != "" && errors.Is(err, ErrNamespaceMirrored)) {
+		status, rpcErr := guardHTTPError(namespace, err)
+		if seconds, ok := retryAfter(rpcErr); ok {
+			ctx.Response.Header.Set("Retry-After", strconv.Itoa(seconds))
+		}
+		h.writeRPCError(ctx, status, rpcErr)
+		return
+	}
+	if mirrorSource != "" {
+		if err := AcceptMirror(ctx, h.store, h.guard, namespace, mirrorSource); err != nil {
+			status, code := mirrorImportError(err)
+			h.writeError(ctx, status, code, err.Error())
+			return
+		}
+	}
+
+	// Reject imports while interactive writes need the remaining capacity
+	if wait, ok := h.admit.Admit(namespace, PriorityImport, 0); !ok {
+		rpcErr := h.admit.rateLimitedError(namespace, PriorityImport, wait)
+		seconds, _ := retryAfter(rpcErr)
+		ctx.Response.Header.Set("Retry-After", strconv.Itoa(seconds))
+		h.writeRPCError(ctx, fasthttp.StatusTooManyRequests, rpcErr)
+		return
+	}
+
+	// Renaming rules are checked before anything is cleared
+	rules, err := ParseImportRules(string(ctx.QueryArgs().Peek("streamPrefixMap")), string(ctx.QueryArgs().Peek("typeMap")))
+	if err != nil {
+		h.writeError(ctx, fasthttp.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	dedupe, err := ParseImportDedupe(string(ctx.QueryArgs().Peek("dedupeBy")))
+	if err != nil {
+		h.writeError(ctx, fasthttp.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+
 	// Check for force flag (clear existing data before import)
 	forceImport := string(ctx.QueryArgs().Peek("force")) == "true"
+
+	// Namespace config lines are applied unless config=false
+	applyConfig := string(ctx.QueryArgs().Peek("config")) != "false"
 	if forceImport {
+		if err := CheckWorm(ctx, h.store, namespace); errors.Is(err, ErrWormProtected) {
+			h.writeError(ctx, fasthttp.StatusForbidden, "WORM_PROTECTED", err.Error())
+			return
+		}
 		deleted, err := h.store.ClearNamespaceMessages(ctx, namespace)
 		if err != nil {
 			h.writeError(ctx, fasthttp.StatusInternalServerError, "CLEAR_FAILED", err.Error())
@@ -86,8 +167,22 @@ func (h *ImportHandler) HandleImport(ctx *fasthttp.RequestCtx) {
 			Str("namespace", namespace).
 			Int64("deleted", deleted).
 			Msg("Cleared namespace messages before import")
+		if err := resetTypeStats(ctx, h.store, namespace); err != nil {
+			logger.Get().Warn().Err(err).Str("namespace", namespace).Msg("Failed to reset type counts after clearing namespace")
+		}
 	}
 
+	// Staged imports write to a hidden namespace that import.commit activates
+	target, shipper, rpcErr := h.stageTarget(ctx, namespace, string(ctx.QueryArgs().Peek("stage")) == "true", forceImport)
+	if rpcErr != nil {
+		if seconds, ok := retryAfter(rpcErr); ok {
+			ctx.Response.Header.Set("Retry-After", strconv.Itoa(seconds))
+		}
+		h.writeRPCError(ctx, stagedImportStatus(rpcErr), rpcErr)
+		return
+	}
+	stage := target != namespace
+
 	// Set up SSE response headers
 	ctx.SetContentType("text/event-stream")
 	ctx.Response.Header.Set("Cache-Control", "no-cache")
@@ -98,7 +193,11 @@ func (h *ImportHandler) HandleImport(ctx *fasthttp.RequestCtx) {
 	body := ctx.PostBody()
 	if len(body) == 0 {
 		// Empty body is valid - just return done with 0 imported
-		h.sendDone(ctx, 0, time.Duration(0))
+		if rpcErr := h.finishStage(ctx, namespace, stage, 0, false); rpcErr != nil {
+			h.sendError(ctx, rpcErr.Code, rpcErr.Message, 0)
+			return
+		}
+		h.sendDone(ctx, 0, 0, time.Duration(0), false, stage)
 		return
 	}
 
@@ -110,9 +209,10 @@ func (h *ImportHandler) HandleImport(ctx *fasthttp.RequestCtx) {
 	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
 
 	batch := make([]*store.Message, 0, importBatchSize)
-	var imported int64
+	var imported, skipped int64
 	var lineNum int64
 	var lastGPos int64
+	var configApplied bool
 
 	for scanner.Scan() {
 		lineNum++
@@ -130,23 +230,42 @@ func (h *ImportHandler) HandleImport(ctx *fasthttp.RequestCtx) {
 			return
 		}
 
+		// Apply namespace config instead of importing it as a message
+		if record.NamespaceConfig != nil {
+			if applyConfig {
+				if err := ApplyNamespaceConfig(ctx, h.store, shipper, target, record.NamespaceConfig); err != nil {
+					h.sendError(ctx, "CONFIG_FAILED", fmt.Sprintf("failed to apply namespace config at line %d: %v", lineNum, err), lineNum)
+					return
+				}
+				configApplied = true
+			}
+			continue
+		}
+
 		// Convert to store.Message
-		msg, err := h.recordToMessage(&record)
+		msg, err := recordToMessage(&record)
 		if err != nil {
 			h.sendError(ctx, "INVALID_RECORD", fmt.Sprintf("invalid record at line %d: %v", lineNum, err), lineNum)
 			return
 		}
+		rules.Apply(msg)
 
 		batch = append(batch, msg)
 		lastGPos = msg.GlobalPosition
 
 		// Batch insert when we reach batch size
 		if len(batch) >= importBatchSize {
-			if err := h.store.ImportBatch(ctx, namespace, batch); err != nil {
+			if err := h.admit.Wait(ctx, namespace, PriorityImport, len(batch)); err != nil {
+				h.sendError(ctx, "IMPORT_CANCELLED", fmt.Sprintf("import cancelled at line %d: %v", lineNum, err), lineNum)
+				return
+			}
+			n, err := h.importBatch(ctx, target, batch, dedupe)
+			if err != nil {
 				h.handleImportError(ctx, err, lineNum)
 				return
 			}
-			imported += int64(len(batch))
+			imported += n
+			skipped += int64(len(batch)) - n
 			h.sendProgress(ctx, imported, lastGPos)
 			batch = batch[:0] // Reset batch, reuse slice
 		}
@@ -160,15 +279,26 @@ func (h *ImportHandler) HandleImport(ctx *fasthttp.RequestCtx) {
 
 	// Flush remaining batch
 	if len(batch) > 0 {
-		if err := h.store.ImportBatch(ctx, namespace, batch); err != nil {
+		if err := h.admit.Wait(ctx, namespace, PriorityImport, len(batch)); err != nil {
+			h.sendError(ctx, "IMPORT_CANCELLED", fmt.Sprintf("import cancelled at line %d: %v", lineNum, err), lineNum)
+			return
+		}
+		n, err := h.importBatch(ctx, target, batch, dedupe)
+		if err != nil {
 			h.handleImportError(ctx, err, lineNum)
 			return
 		}
-		imported += int64(len(batch))
+		imported += n
+		skipped += int64(len(batch)) - n
+	}
+
+	if rpcErr := h.finishStage(ctx, namespace, stage, imported, configApplied); rpcErr != nil {
+		h.sendError(ctx, rpcErr.Code, rpcErr.Message, lineNum)
+		return
 	}
 
 	// Send completion event
-	h.sendDone(ctx, imported, time.Since(start))
+	h.sendDone(ctx, imported, skipped, time.Since(start), configApplied, stage)
 
 	logger.Get().Info().
 		Str("namespace", namespace).
@@ -178,7 +308,11 @@ func (h *ImportHandler) HandleImport(ctx *fasthttp.RequestCtx) {
 }
 
 // recordToMessage converts an ExportRecord to a store.Message
-func (h *ImportHandler) recordToMessage(record *ExportRecord) (*store.Message, error) {
+func recordToMessage(record *ExportRecord) (*store.Message, error) {
+	if record.Namespace != "" {
+		return nil, fmt.Errorf("record of namespace %q from a combined archive, use eventodb import --all-namespaces", record.Namespace)
+	}
+
 	// Parse time
 	t, err := time.Parse(time.RFC3339, record.Time)
 	if err != nil {
@@ -201,10 +335,74 @@ func (h *ImportHandler) recordToMessage(record *ExportRecord) (*store.Message, e
 	}, nil
 }
 
+// importBatch imports batch, first dropping records already imported when
+// dedupe is set, and returns the number of messages written
+func (h *ImportHandler) importBatch(ctx context.Context, namespace string, batch []*store.Message, dedupe string) (int64, error) {
+	if dedupe == ImportDedupeByID {
+		var err error
+		if batch, err = DedupeImportBatch(ctx, h.store, namespace, batch); err != nil {
+			return 0, err
+		}
+		if len(batch) == 0 {
+			return 0, nil
+		}
+	}
+	if err := h.store.ImportBatch(ctx, namespace, batch); err != nil {
+		return 0, err
+	}
+	return int64(len(batch)), nil
+}
+
+// stageTarget returns the namespace an import writes to and the log shipper
+// its config lines restart: namespace and the handler's shipper, or with
+// stage the staging namespace and none
+func (h *ImportHandler) stageTarget(ctx context.Context, namespace string, stage, force bool) (string, *LogShipper, *RPCError) {
+	if !stage {
+		return namespace, h.shipper, nil
+	}
+	if force {
+		return "", nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "stage cannot be combined with force; import.commit replaces all messages",
+		}
+	}
+	staged, err := BeginStagedImport(ctx, h.store, namespace)
+	if err != nil {
+		return "", nil, stagedImportError(err)
+	}
+	return staged.Namespace, nil, nil
+}
+
+// finishStage records a completed staged import
+func (h *ImportHandler) finishStage(ctx context.Context, namespace string, stage bool, imported int64, configApplied bool) *RPCError {
+	if !stage {
+		return nil
+	}
+	if err := FinishStagedImport(ctx, h.store, namespace, imported, configApplied); err != nil {
+		return stagedImportError(err)
+	}
+	return nil
+}
+
+// mirrorImportError maps AcceptMirror errors to an HTTP status and error code
+func mirrorImportError(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrMirrorPromoted):
+		return http.StatusConflict, "MIRROR_PROMOTED"
+	case errors.Is(err, ErrMirrorConflict):
+		return http.StatusConflict, "MIRROR_CONFLICT"
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return http.StatusNotFound, "NAMESPACE_NOT_FOUND"
+	}
+	return http.StatusInternalServerError, "BACKEND_ERROR"
+}
+
 // handleImportError handles errors from ImportBatch
 func (h *ImportHandler) handleImportError(ctx *fasthttp.RequestCtx, err error, lineNum int64) {
 	if errors.Is(err, store.ErrPositionExists) {
 		h.sendError(ctx, "POSITION_EXISTS", err.Error(), lineNum)
+	} else if store.IsOverloaded(err) {
+		h.sendError(ctx, "OVERLOADED", err.Error(), lineNum)
 	} else {
 		h.sendError(ctx, "IMPORT_FAILED", err.Error(), lineNum)
 	}
@@ -221,11 +419,14 @@ func (h *ImportHandler) sendProgress(ctx *fasthttp.RequestCtx, imported, gpos in
 }
 
 // sendDone sends the completion event
-func (h *ImportHandler) sendDone(ctx *fasthttp.RequestCtx, imported int64, elapsed time.Duration) {
+func (h *ImportHandler) sendDone(ctx *fasthttp.RequestCtx, imported, skipped int64, elapsed time.Duration, configApplied, staged bool) {
 	done := ImportDone{
 		Done:     true,
 		Imported: imported,
+		Skipped:  skipped,
 		Elapsed:  fmt.Sprintf("%.1fs", elapsed.Seconds()),
+		Config:   configApplied,
+		Staged:   staged,
 	}
 	data, _ := json.Marshal(done)
 	fmt.Fprintf(ctx, "data: %s\n\n", data)
@@ -246,14 +447,17 @@ func (h *ImportHandler) sendError(ctx *fasthttp.RequestCtx, code, message string
 
 // writeError writes a JSON error response (for pre-streaming errors)
 func (h *ImportHandler) writeError(ctx *fasthttp.RequestCtx, statusCode int, code, message string) {
-	ctx.SetContentType("application/json")
-	ctx.SetStatusCode(statusCode)
-
-	resp := ErrorResponse{Error: &RPCError{
+	h.writeRPCError(ctx, statusCode, &RPCError{
 		Code:    code,
 		Message: message,
-	}}
-	json.NewEncoder(ctx).Encode(resp)
+	})
+}
+
+// writeRPCError writes a JSON error response with the error's details
+func (h *ImportHandler) writeRPCError(ctx *fasthttp.RequestCtx, statusCode int, rpcErr *RPCError) {
+	ctx.SetContentType("application/json")
+	ctx.SetStatusCode(statusCode)
+	json.NewEncoder(ctx).Encode(ErrorResponse{Error: rpcErr})
 }
 
 // ServeHTTP implements http.Handler for net/http compatibility (used in tests)
@@ -270,6 +474,60 @@ func (h *ImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Mirror pushes from the namespace's primary may write to the read-only mirror
+	mirrorSource := r.URL.Query().Get("mirror")
+
+	// Reject imports into frozen or suspended namespaces and read-only servers
+	if err := h.guard.Check(r.Context(), namespace); err != nil && !(mirrorSource != "" && errors.Is(err, ErrNamespaceMirrored)) {
+		status, rpcErr := guardHTTPError(namespace, err)
+		if seconds, ok := retryAfter(rpcErr); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+		h.writeHTTPRPCError(w, status, rpcErr)
+		return
+	}
+	if mirrorSource != "" {
+		if err := AcceptMirror(r.Context(), h.store, h.guard, namespace, mirrorSource); err != nil {
+			status, code := mirrorImportError(err)
+			h.writeHTTPError(w, status, code, err.Error())
+			return
+		}
+	}
+
+	// Reject imports while interactive writes need the remaining capacity
+	if wait, ok := h.admit.Admit(namespace, PriorityImport, 0); !ok {
+		rpcErr := h.admit.rateLimitedError(namespace, PriorityImport, wait)
+		seconds, _ := retryAfter(rpcErr)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		h.writeHTTPRPCError(w, http.StatusTooManyRequests, rpcErr)
+		return
+	}
+
+	// Namespace config lines are applied unless config=false
+	applyConfig := r.URL.Query().Get("config") != "false"
+
+	rules, err := ParseImportRules(r.URL.Query().Get("streamPrefixMap"), r.URL.Query().Get("typeMap"))
+	if err != nil {
+		h.writeHTTPError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	dedupe, err := ParseImportDedupe(r.URL.Query().Get("dedupeBy"))
+	if err != nil {
+		h.writeHTTPError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	// Staged imports write to a hidden namespace that import.commit activates
+	target, shipper, rpcErr := h.stageTarget(r.Context(), namespace, r.URL.Query().Get("stage") == "true", r.URL.Query().Get("force") == "true")
+	if rpcErr != nil {
+		if seconds, ok := retryAfter(rpcErr); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+		h.writeHTTPRPCError(w, stagedImportStatus(rpcErr), rpcErr)
+		return
+	}
+	stage := target != namespace
+
 	// Set up SSE response headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -285,7 +543,11 @@ func (h *ImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	if len(body) == 0 {
 		// Empty body is valid - just return done with 0 imported
-		h.sendHTTPDone(w, 0, time.Duration(0))
+		if rpcErr := h.finishStage(r.Context(), namespace, stage, 0, false); rpcErr != nil {
+			h.sendHTTPError(w, rpcErr.Code, rpcErr.Message, 0)
+			return
+		}
+		h.sendHTTPDone(w, 0, 0, time.Duration(0), false, stage)
 		return
 	}
 
@@ -297,9 +559,10 @@ func (h *ImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
 
 	batch := make([]*store.Message, 0, importBatchSize)
-	var imported int64
+	var imported, skipped int64
 	var lineNum int64
 	var lastGPos int64
+	var configApplied bool
 
 	for scanner.Scan() {
 		lineNum++
@@ -317,23 +580,42 @@ func (h *ImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Apply namespace config instead of importing it as a message
+		if record.NamespaceConfig != nil {
+			if applyConfig {
+				if err := ApplyNamespaceConfig(r.Context(), h.store, shipper, target, record.NamespaceConfig); err != nil {
+					h.sendHTTPError(w, "CONFIG_FAILED", fmt.Sprintf("failed to apply namespace config at line %d: %v", lineNum, err), lineNum)
+					return
+				}
+				configApplied = true
+			}
+			continue
+		}
+
 		// Convert to store.Message
-		msg, err := h.recordToMessage(&record)
+		msg, err := recordToMessage(&record)
 		if err != nil {
 			h.sendHTTPError(w, "INVALID_RECORD", fmt.Sprintf("invalid record at line %d: %v", lineNum, err), lineNum)
 			return
 		}
+		rules.Apply(msg)
 
 		batch = append(batch, msg)
 		lastGPos = msg.GlobalPosition
 
 		// Batch insert when we reach batch size
 		if len(batch) >= importBatchSize {
-			if err := h.store.ImportBatch(r.Context(), namespace, batch); err != nil {
+			if err := h.admit.Wait(r.Context(), namespace, PriorityImport, len(batch)); err != nil {
+				h.sendHTTPError(w, "IMPORT_CANCELLED", fmt.Sprintf("import cancelled at line %d: %v", lineNum, err), lineNum)
+				return
+			}
+			n, err := h.importBatch(r.Context(), target, batch, dedupe)
+			if err != nil {
 				h.handleHTTPImportError(w, err, lineNum)
 				return
 			}
-			imported += int64(len(batch))
+			imported += n
+			skipped += int64(len(batch)) - n
 			h.sendHTTPProgress(w, imported, lastGPos)
 			batch = batch[:0] // Reset batch, reuse slice
 		}
@@ -347,15 +629,26 @@ func (h *ImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Flush remaining batch
 	if len(batch) > 0 {
-		if err := h.store.ImportBatch(r.Context(), namespace, batch); err != nil {
+		if err := h.admit.Wait(r.Context(), namespace, PriorityImport, len(batch)); err != nil {
+			h.sendHTTPError(w, "IMPORT_CANCELLED", fmt.Sprintf("import cancelled at line %d: %v", lineNum, err), lineNum)
+			return
+		}
+		n, err := h.importBatch(r.Context(), target, batch, dedupe)
+		if err != nil {
 			h.handleHTTPImportError(w, err, lineNum)
 			return
 		}
-		imported += int64(len(batch))
+		imported += n
+		skipped += int64(len(batch)) - n
+	}
+
+	if rpcErr := h.finishStage(r.Context(), namespace, stage, imported, configApplied); rpcErr != nil {
+		h.sendHTTPError(w, rpcErr.Code, rpcErr.Message, lineNum)
+		return
 	}
 
 	// Send completion event
-	h.sendHTTPDone(w, imported, time.Since(start))
+	h.sendHTTPDone(w, imported, skipped, time.Since(start), configApplied, stage)
 
 	logger.Get().Info().
 		Str("namespace", namespace).
@@ -368,6 +661,8 @@ func (h *ImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (h *ImportHandler) handleHTTPImportError(w http.ResponseWriter, err error, lineNum int64) {
 	if errors.Is(err, store.ErrPositionExists) {
 		h.sendHTTPError(w, "POSITION_EXISTS", err.Error(), lineNum)
+	} else if store.IsOverloaded(err) {
+		h.sendHTTPError(w, "OVERLOADED", err.Error(), lineNum)
 	} else {
 		h.sendHTTPError(w, "IMPORT_FAILED", err.Error(), lineNum)
 	}
@@ -387,11 +682,14 @@ func (h *ImportHandler) sendHTTPProgress(w http.ResponseWriter, imported, gpos i
 }
 
 // sendHTTPDone sends the completion event (net/http version)
-func (h *ImportHandler) sendHTTPDone(w http.ResponseWriter, imported int64, elapsed time.Duration) {
+func (h *ImportHandler) sendHTTPDone(w http.ResponseWriter, imported, skipped int64, elapsed time.Duration, configApplied, staged bool) {
 	done := ImportDone{
 		Done:     true,
 		Imported: imported,
+		Skipped:  skipped,
 		Elapsed:  fmt.Sprintf("%.1fs", elapsed.Seconds()),
+		Config:   configApplied,
+		Staged:   staged,
 	}
 	data, _ := json.Marshal(done)
 	fmt.Fprintf(w, "data: %s\n\n", data)
@@ -418,12 +716,16 @@ func (h *ImportHandler) sendHTTPError(w http.ResponseWriter, code, message strin
 
 // writeHTTPError writes a JSON error response (net/http version)
 func (h *ImportHandler) writeHTTPError(w http.ResponseWriter, statusCode int, code, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	resp := ErrorResponse{Error: &RPCError{
+	h.writeHTTPRPCError(w, statusCode, &RPCError{
 		Code:    code,
 		Message: message,
-	}}
-	json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// writeHTTPRPCError writes a JSON error response with the error's details
+// (net/http version)
+func (h *ImportHandler) writeHTTPRPCError(w http.ResponseWriter, statusCode int, rpcErr *RPCError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: rpcErr})
 }
@@ -0,0 +1,108 @@
+// Package api provides renaming of imported records.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// maxImportRules bounds the entries of each import rule map
+const maxImportRules = 256
+
+// ImportRules rename records while they are imported, so data written under
+// an old naming convention can be loaded into the new one without rewriting
+// the export.
+//
+// StreamPrefixMap replaces the longest matching prefix of stream names,
+// including stream names referenced in metadata (correlationStreamName,
+// causationMessageStreamName, replyStreamName and any other *StreamName
+// field). TypeMap replaces whole message types.
+type ImportRules struct {
+	StreamPrefixMap map[string]string `json:"streamPrefixMap,omitempty"`
+	TypeMap         map[string]string `json:"typeMap,omitempty"`
+
+	prefixes []string // StreamPrefixMap keys, longest first
+}
+
+// ParseImportRules parses the streamPrefixMap and typeMap /import parameters,
+// each a JSON object of old to new names. It returns nil if both are empty.
+func ParseImportRules(streamPrefixMap, typeMap string) (*ImportRules, error) {
+	if streamPrefixMap == "" && typeMap == "" {
+		return nil, nil
+	}
+
+	rules := &ImportRules{}
+	if streamPrefixMap != "" {
+		if err := json.Unmarshal([]byte(streamPrefixMap), &rules.StreamPrefixMap); err != nil {
+			return nil, fmt.Errorf("streamPrefixMap must be a JSON object of strings: %w", err)
+		}
+	}
+	if typeMap != "" {
+		if err := json.Unmarshal([]byte(typeMap), &rules.TypeMap); err != nil {
+			return nil, fmt.Errorf("typeMap must be a JSON object of strings: %w", err)
+		}
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Validate checks the rules and prepares them for Apply
+func (r *ImportRules) Validate() error {
+	for name, m := range map[string]map[string]string{"streamPrefixMap": r.StreamPrefixMap, "typeMap": r.TypeMap} {
+		if len(m) > maxImportRules {
+			return fmt.Errorf("%s has more than %d entries", name, maxImportRules)
+		}
+		for from, to := range m {
+			if from == "" || to == "" {
+				return fmt.Errorf("%s entries must not be empty", name)
+			}
+		}
+	}
+
+	r.prefixes = make([]string, 0, len(r.StreamPrefixMap))
+	for prefix := range r.StreamPrefixMap {
+		r.prefixes = append(r.prefixes, prefix)
+	}
+	sort.Slice(r.prefixes, func(i, j int) bool {
+		if len(r.prefixes[i]) != len(r.prefixes[j]) {
+			return len(r.prefixes[i]) > len(r.prefixes[j])
+		}
+		return r.prefixes[i] < r.prefixes[j]
+	})
+	return nil
+}
+
+// Apply renames a message in place; nil rules leave it unchanged
+func (r *ImportRules) Apply(msg *store.Message) {
+	if r == nil {
+		return
+	}
+	msg.StreamName = r.renameStream(msg.StreamName)
+	if to, ok := r.TypeMap[msg.Type]; ok {
+		msg.Type = to
+	}
+	if len(r.prefixes) == 0 {
+		return
+	}
+	for key, value := range msg.Metadata {
+		if s, ok := value.(string); ok && strings.HasSuffix(key, "StreamName") {
+			msg.Metadata[key] = r.renameStream(s)
+		}
+	}
+}
+
+// renameStream replaces the longest mapped prefix of a stream name
+func (r *ImportRules) renameStream(stream string) string {
+	for _, prefix := range r.prefixes {
+		if strings.HasPrefix(stream, prefix) {
+			return r.StreamPrefixMap[prefix] + stream[len(prefix):]
+		}
+	}
+	return stream
+}
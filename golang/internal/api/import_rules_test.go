@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestImportRules tests renaming streams, metadata stream names and types
+func TestImportRules(t *testing.T) {
+	if rules, err := ParseImportRules("", ""); rules != nil || err != nil {
+		t.Errorf("Expected no rules, got %v (%v)", rules, err)
+	}
+	if _, err := ParseImportRules(`{"account-":""}`, ""); err == nil {
+		t.Error("Expected an error for an empty replacement")
+	}
+	if _, err := ParseImportRules(`["account-"]`, ""); err == nil {
+		t.Error("Expected an error for a non-object map")
+	}
+
+	rules, err := ParseImportRules(`{"account":"customer","account:command-":"customer:command-"}`, `{"Opened":"AccountOpened"}`)
+	if err != nil {
+		t.Fatalf("ParseImportRules failed: %v", err)
+	}
+
+	msg := &store.Message{
+		StreamName: "account:command-42",
+		Type:       "Opened",
+		Metadata: map[string]interface{}{
+			"correlationStreamName": "account-7",
+			"replyStreamName":       "billing-1",
+			"causationMessageId":    "account-9",
+		},
+	}
+	rules.Apply(msg)
+	if msg.StreamName != "customer:command-42" {
+		t.Errorf("Expected the longest prefix to apply, got %s", msg.StreamName)
+	}
+	if msg.Type != "AccountOpened" {
+		t.Errorf("Expected type AccountOpened, got %s", msg.Type)
+	}
+	if msg.Metadata["correlationStreamName"] != "customer-7" || msg.Metadata["replyStreamName"] != "billing-1" || msg.Metadata["causationMessageId"] != "account-9" {
+		t.Errorf("Unexpected metadata: %v", msg.Metadata)
+	}
+
+	// Nil rules leave messages unchanged
+	var none *ImportRules
+	none.Apply(msg)
+	if msg.StreamName != "customer:command-42" {
+		t.Errorf("Expected nil rules to do nothing, got %s", msg.StreamName)
+	}
+}
+
+// TestImportRules_Errors tests invalid maps, the entry limit, type-only rules
+// and rejected imports that leave the namespace untouched
+func TestImportRules_Errors(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i <= maxImportRules; i++ {
+		tooMany[fmt.Sprintf("t%d", i)] = "T"
+	}
+	tooManyJSON, _ := json.Marshal(tooMany)
+	for _, tt := range []struct{ streamPrefixMap, typeMap string }{
+		{`{"":"customer-"}`, ""},
+		{"", `{"Opened":""}`},
+		{"", `["Opened"]`},
+		{`{"account-":1}`, ""},
+		{"not json", ""},
+		{"", string(tooManyJSON)},
+	} {
+		if _, err := ParseImportRules(tt.streamPrefixMap, tt.typeMap); err == nil {
+			t.Errorf("Expected an error for %q %q", tt.streamPrefixMap, tt.typeMap)
+		}
+	}
+
+	// Type-only rules leave streams and metadata alone
+	rules, err := ParseImportRules("", `{"Opened":"AccountOpened"}`)
+	if err != nil {
+		t.Fatalf("ParseImportRules failed: %v", err)
+	}
+	msg := &store.Message{StreamName: "account-1", Type: "Closed", Metadata: map[string]interface{}{"replyStreamName": "account-2"}}
+	rules.Apply(msg)
+	if msg.StreamName != "account-1" || msg.Type != "Closed" || msg.Metadata["replyStreamName"] != "account-2" {
+		t.Errorf("Expected an unmatched message to be unchanged, got %+v", msg)
+	}
+
+	// Messages without metadata and non-string stream fields are renamed safely
+	rules, err = ParseImportRules(`{"account-":"customer-"}`, "")
+	if err != nil {
+		t.Fatalf("ParseImportRules failed: %v", err)
+	}
+	msg = &store.Message{StreamName: "account-1", Type: "Opened"}
+	rules.Apply(msg)
+	if msg.StreamName != "customer-1" {
+		t.Errorf("Expected customer-1, got %s", msg.StreamName)
+	}
+	msg = &store.Message{StreamName: "billing-1", Metadata: map[string]interface{}{"correlationStreamName": float64(1)}}
+	rules.Apply(msg)
+	if msg.StreamName != "billing-1" || msg.Metadata["correlationStreamName"] != float64(1) {
+		t.Errorf("Expected an unmatched message to be unchanged, got %+v", msg)
+	}
+
+	// Invalid rules are rejected before a forced import clears the namespace
+	st := newTestStore(t)
+	ctx := context.Background()
+	if _, err := st.WriteMessage(ctx, "test-ns", "account-1", &store.Message{Type: "Opened", Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/import?force=true&typeMap=%5B%5D", strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	NewImportHandler(st).ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), ContextKeyNamespace, "test-ns")))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "INVALID_REQUEST") {
+		t.Errorf("Expected 400 INVALID_REQUEST, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if version, err := st.GetStreamVersion(ctx, "test-ns", "account-1"); err != nil || version != 0 {
+		t.Errorf("Expected the namespace to be untouched, got version %d (%v)", version, err)
+	}
+}
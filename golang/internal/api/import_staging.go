@@ -0,0 +1,245 @@
+// Package api provides staged imports, activated all at once.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/google/uuid"
+)
+
+// importStagingKey is the namespace metadata key of its staged import
+const importStagingKey = "importStaging"
+
+// StagingNamespacePrefix starts the IDs of the hidden namespaces staged
+// imports write to. ns.list leaves them out and ns.create rejects them.
+const StagingNamespacePrefix = "_staging-"
+
+var (
+	// ErrNoStagedImport is returned by import.commit and import.abort when
+	// the namespace has no staged import
+	ErrNoStagedImport = errors.New("no staged import; import with stage=true first")
+
+	// ErrStagedImportFailed is returned by import.commit when the last import
+	// into the staging area failed or is still running
+	ErrStagedImportFailed = errors.New("the staging area is incomplete; re-run the import with dedupeBy=id or abort it")
+
+	// ErrStagedImportInvalid is returned by import.commit when the staged
+	// messages fail validation
+	ErrStagedImportInvalid = errors.New("staged import failed validation")
+)
+
+// StagedImport is a namespace's import in progress, kept in its metadata
+type StagedImport struct {
+	Namespace string    `json:"namespace"`        // Hidden staging namespace
+	StartedAt time.Time `json:"startedAt"`        // First import into the staging area
+	Imported  int64     `json:"imported"`         // Messages imported so far
+	Config    bool      `json:"config,omitempty"` // Namespace config was staged
+	Failed    string    `json:"failed,omitempty"` // Why the staging area is incomplete
+}
+
+// StagedImportFromMetadata returns the staged import recorded in namespace
+// metadata, or nil
+func StagedImportFromMetadata(metadata map[string]interface{}) *StagedImport {
+	raw, ok := metadata[importStagingKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	staged := &StagedImport{}
+	if err := decodeMetadataValue(raw, staged); err != nil || staged.Namespace == "" {
+		return nil
+	}
+	return staged
+}
+
+// IsStagingNamespace reports whether id names a staging namespace
+func IsStagingNamespace(id string) bool {
+	return strings.HasPrefix(id, StagingNamespacePrefix)
+}
+
+// stagedImportRunning is the Failed reason of a staged import while it runs,
+// so a commit before it finishes, or after it stopped, is rejected
+const stagedImportRunning = "an import into the staging area is running or was interrupted"
+
+// BeginStagedImport prepares a staged import into namespace and returns its
+// staged import, whose Namespace the messages are written to. The staging
+// namespace is created by the first staged import, on the namespace's shard,
+// since messages never move between shards. Later imports add to it.
+func BeginStagedImport(ctx context.Context, st store.Store, namespace string) (*StagedImport, error) {
+	if _, ok := st.(store.NamespaceSwapper); !ok {
+		return nil, fmt.Errorf("%w: staged imports", store.ErrNotSupported)
+	}
+	// Activating replaces every message of the namespace
+	if err := CheckWorm(ctx, st, namespace); err != nil {
+		return nil, err
+	}
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	staged := StagedImportFromMetadata(ns.Metadata)
+	if staged == nil {
+		staged = &StagedImport{
+			Namespace: StagingNamespacePrefix + namespace + "-" + uuid.NewString()[:8],
+			StartedAt: time.Now().UTC(),
+		}
+		createCtx := ctx
+		if shard, ok := ns.Metadata[store.ShardMetadataKey].(string); ok && shard != "" {
+			createCtx = store.WithShard(ctx, shard)
+		}
+		// No token hashes to a random UUID, so only the server writes here
+		tokenHash := auth.HashToken(uuid.NewString())
+		if err := st.CreateNamespace(createCtx, staged.Namespace, tokenHash, "Staged import into "+namespace); err != nil {
+			return nil, fmt.Errorf("failed to create staging namespace: %w", err)
+		}
+	}
+	staged.Failed = stagedImportRunning
+	if err := setStagedImport(ctx, st, namespace, staged); err != nil {
+		return nil, err
+	}
+	return staged, nil
+}
+
+// FinishStagedImport records a staged import into namespace that completed
+// with imported messages, so import.commit accepts the staging area
+func FinishStagedImport(ctx context.Context, st store.Store, namespace string, imported int64, config bool) error {
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	staged := StagedImportFromMetadata(ns.Metadata)
+	if staged == nil {
+		return ErrNoStagedImport
+	}
+	staged.Imported += imported
+	staged.Config = staged.Config || config
+	staged.Failed = ""
+	return setStagedImport(ctx, st, namespace, staged)
+}
+
+// setStagedImport records staged in namespace metadata; nil removes it
+func setStagedImport(ctx context.Context, st store.Store, namespace string, staged *StagedImport) error {
+	return updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		if staged == nil {
+			delete(metadata, importStagingKey)
+		} else {
+			metadata[importStagingKey] = encodeMetadataValue(staged)
+		}
+	})
+}
+
+// StagedImportResult describes an activated import
+type StagedImportResult struct {
+	Activated int64 // Messages the namespace holds now
+	Replaced  int64 // Messages it held before, now deleted
+	Config    bool  // Staged namespace config was applied
+}
+
+// CommitStagedImport validates the staged messages of namespace and swaps
+// them in place of its messages in one step, then deletes the replaced ones.
+// With expectedCount, the staging area must hold exactly that many messages.
+//
+// The staged import is forgotten before the swap, so a retry after a crash
+// never swaps the old messages back; at worst it leaves a hidden staging
+// namespace behind.
+func CommitStagedImport(ctx context.Context, st store.Store, shipper *LogShipper, namespace string, expectedCount *int64) (*StagedImportResult, error) {
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	staged := StagedImportFromMetadata(ns.Metadata)
+	if staged == nil {
+		return nil, ErrNoStagedImport
+	}
+	if staged.Failed != "" {
+		return nil, fmt.Errorf("%w: %s", ErrStagedImportFailed, staged.Failed)
+	}
+	swapper, ok := st.(store.NamespaceSwapper)
+	if !ok {
+		return nil, fmt.Errorf("%w: staged imports", store.ErrNotSupported)
+	}
+	if err := CheckWorm(ctx, st, namespace); err != nil {
+		return nil, err
+	}
+
+	count, err := st.GetNamespaceMessageCount(ctx, staged.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count staged messages: %w", err)
+	}
+	if expectedCount != nil && count != *expectedCount {
+		return nil, fmt.Errorf("%w: %d messages staged, expected %d", ErrStagedImportInvalid, count, *expectedCount)
+	}
+	if checker, ok := st.(store.IntegrityChecker); ok {
+		problems, err := checker.CheckStorage(ctx, staged.Namespace)
+		if err != nil && !errors.Is(err, store.ErrNotSupported) {
+			return nil, fmt.Errorf("failed to check staged messages: %w", err)
+		}
+		if len(problems) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrStagedImportInvalid, strings.Join(problems, "; "))
+		}
+	}
+	var cfg *NamespaceConfig
+	if staged.Config {
+		if cfg, err = ExportNamespaceConfig(ctx, st, nil, staged.Namespace); err != nil {
+			return nil, fmt.Errorf("failed to read staged config: %w", err)
+		}
+	}
+	replaced, err := st.GetNamespaceMessageCount(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	if err := setStagedImport(ctx, st, namespace, nil); err != nil {
+		return nil, err
+	}
+	if err := swapper.SwapNamespaceMessages(ctx, namespace, staged.Namespace); err != nil {
+		setStagedImport(context.Background(), st, namespace, staged)
+		return nil, fmt.Errorf("failed to activate staged import: %w", err)
+	}
+
+	if err := resetTypeStats(ctx, st, namespace); err != nil {
+		logger.Get().Warn().Err(err).Str("namespace", namespace).Msg("Failed to reset type counts after activating staged import")
+	}
+
+	// The staging namespace now holds the replaced messages
+	if err := st.DeleteNamespace(ctx, staged.Namespace); err != nil {
+		logger.Get().Warn().Err(err).
+			Str("namespace", namespace).
+			Str("staging", staged.Namespace).
+			Msg("Failed to delete replaced messages after activating staged import")
+	}
+	result := &StagedImportResult{Activated: count, Replaced: replaced}
+	if cfg != nil {
+		if err := ApplyNamespaceConfig(ctx, st, shipper, namespace, cfg); err != nil {
+			return nil, fmt.Errorf("messages activated, but staged config failed: %w", err)
+		}
+		result.Config = true
+	}
+	return result, nil
+}
+
+// AbortStagedImport deletes the staged import of namespace
+func AbortStagedImport(ctx context.Context, st store.Store, namespace string) (*StagedImport, error) {
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	staged := StagedImportFromMetadata(ns.Metadata)
+	if staged == nil {
+		return nil, ErrNoStagedImport
+	}
+	if err := st.DeleteNamespace(ctx, staged.Namespace); err != nil && !errors.Is(err, store.ErrNamespaceNotFound) {
+		return nil, fmt.Errorf("failed to delete staging namespace: %w", err)
+	}
+	if err := setStagedImport(ctx, st, namespace, nil); err != nil {
+		return nil, err
+	}
+	return staged, nil
+}
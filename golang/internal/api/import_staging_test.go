@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestStagedImport tests that a staged import stays invisible until
+// import.commit swaps it in place of the namespace's messages
+func TestStagedImport(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+	importHandler := NewImportHandler(st)
+
+	if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{Type: "Placed", Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+
+	stage := func(query, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/import?"+query, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		importHandler.ServeHTTP(rec, req.WithContext(ctx))
+		return rec
+	}
+	records := `{"id":"a1b2c3d4-0000-4000-8000-000000000001","stream":"account-1","type":"Opened","pos":0,"gpos":1,"data":{},"meta":null,"time":"2024-01-01T00:00:00Z"}
+{"id":"a1b2c3d4-0000-4000-8000-000000000002","stream":"account-1","type":"Deposited","pos":1,"gpos":2,"data":{},"meta":null,"time":"2024-01-01T00:00:01Z"}
+`
+	if rec := stage("stage=true&force=true", records); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected stage with force to be rejected, got %d", rec.Code)
+	}
+	if _, rpcErr := h.route(ctx, "import.commit", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without a staged import, got %v", rpcErr)
+	}
+
+	rec := stage("stage=true", records)
+	if !strings.Contains(rec.Body.String(), `"staged":true`) {
+		t.Fatalf("Expected a staged import, got %s", rec.Body.String())
+	}
+
+	// The namespace is unchanged and the staging namespace is hidden
+	msgs, err := st.GetCategoryMessages(ctx, "test-ns", "", &store.CategoryOpts{Position: 1, BatchSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to read messages: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].StreamName != "order-1" {
+		t.Fatalf("Expected the staged messages to be invisible, got %d messages", len(msgs))
+	}
+	listed, rpcErr := h.route(ctx, "ns.list", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.list failed: %v", rpcErr.Message)
+	}
+	if n := len(listed.([]interface{})); n != 1 {
+		t.Errorf("Expected the staging namespace to be hidden, got %d namespaces", n)
+	}
+
+	if _, rpcErr := h.route(ctx, "import.commit", []interface{}{map[string]interface{}{"expectedCount": float64(3)}}); rpcErr == nil || rpcErr.Code != "IMPORT_INVALID" {
+		t.Errorf("Expected IMPORT_INVALID for a wrong count, got %v", rpcErr)
+	}
+	result, rpcErr := h.route(ctx, "import.commit", []interface{}{map[string]interface{}{"expectedCount": float64(2)}})
+	if rpcErr != nil {
+		t.Fatalf("import.commit failed: %v", rpcErr.Message)
+	}
+	committed := result.(map[string]interface{})
+	if committed["activated"] != int64(2) || committed["replaced"] != int64(1) {
+		t.Errorf("Expected 2 messages activated and 1 replaced, got %v", committed)
+	}
+
+	msgs, err = st.GetCategoryMessages(ctx, "test-ns", "", &store.CategoryOpts{Position: 1, BatchSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to read messages: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].StreamName != "account-1" || msgs[1].Type != "Deposited" {
+		t.Fatalf("Expected the imported messages after commit, got %d messages", len(msgs))
+	}
+	namespaces, err := st.ListNamespaces(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list namespaces: %v", err)
+	}
+	if len(namespaces) != 1 {
+		t.Errorf("Expected the staging namespace to be deleted, got %d namespaces", len(namespaces))
+	}
+
+	// Aborting discards the staging area
+	stage("stage=true", records)
+	if _, rpcErr := h.route(ctx, "import.abort", nil); rpcErr != nil {
+		t.Fatalf("import.abort failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "import.commit", nil); rpcErr == nil {
+		t.Error("Expected import.commit to fail after import.abort")
+	}
+}
+
+// TestStagedImport_Errors tests invalid import.commit options, that staged
+// imports add up, that an incomplete staging area cannot be committed and
+// that staging namespace IDs are reserved
+func TestStagedImport_Errors(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+	importHandler := NewImportHandler(st)
+
+	for _, args := range [][]interface{}{
+		{"all"},
+		{map[string]interface{}{"expectedCount": float64(-1)}},
+		{map[string]interface{}{"expectedCount": "2"}},
+	} {
+		if _, rpcErr := h.route(ctx, "import.commit", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "import.abort", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without a staged import, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(context.Background(), "ns.create", []interface{}{StagingNamespacePrefix + "tenant"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a staging namespace ID, got %v", rpcErr)
+	}
+
+	// A second staged import adds to the first
+	for _, body := range []string{
+		`{"id":"a1b2c3d4-0000-4000-8000-000000000001","stream":"account-1","type":"Opened","pos":0,"gpos":1,"data":{},"meta":null,"time":"2024-01-01T00:00:00Z"}` + "\n",
+		`{"id":"a1b2c3d4-0000-4000-8000-000000000002","stream":"account-2","type":"Opened","pos":0,"gpos":2,"data":{},"meta":null,"time":"2024-01-01T00:00:01Z"}` + "\n",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/import?stage=true", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		importHandler.ServeHTTP(rec, req.WithContext(ctx))
+		if !strings.Contains(rec.Body.String(), `"staged":true`) {
+			t.Fatalf("Expected a staged import, got %s", rec.Body.String())
+		}
+	}
+	ns, err := st.GetNamespace(ctx, "test-ns")
+	if err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	staged := StagedImportFromMetadata(ns.Metadata)
+	if staged == nil || staged.Imported != 2 {
+		t.Fatalf("Expected 2 staged messages, got %+v", staged)
+	}
+
+	// An interrupted import leaves the staging area incomplete
+	incomplete := *staged
+	incomplete.Failed = stagedImportRunning
+	if err := setStagedImport(ctx, st, "test-ns", &incomplete); err != nil {
+		t.Fatalf("Failed to record staged import: %v", err)
+	}
+	if _, rpcErr := h.route(ctx, "import.commit", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an incomplete staging area, got %v", rpcErr)
+	}
+	if err := setStagedImport(ctx, st, "test-ns", staged); err != nil {
+		t.Fatalf("Failed to record staged import: %v", err)
+	}
+
+	guard := NewWriteGuard(NewNamespaceMetadata(st))
+	guard.SetReadOnly(true)
+	h.SetWriteGuard(guard)
+	if _, rpcErr := h.route(ctx, "import.commit", nil); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY in read-only mode, got %v", rpcErr)
+	}
+	guard.SetReadOnly(false)
+	result, rpcErr := h.route(ctx, "import.commit", []interface{}{map[string]interface{}{"expectedCount": float64(2)}})
+	if rpcErr != nil {
+		t.Fatalf("import.commit failed: %v", rpcErr.Message)
+	}
+	if committed := result.(map[string]interface{}); committed["activated"] != int64(2) || committed["replaced"] != int64(0) {
+		t.Errorf("Expected 2 messages activated and none replaced, got %v", committed)
+	}
+}
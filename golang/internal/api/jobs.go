@@ -0,0 +1,527 @@
+// Package api provides the fair scheduler of background work.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultJobWorkers is the number of background jobs run at once
+	DefaultJobWorkers = 16
+
+	// DefaultJobsPerNamespace is the number of background jobs one namespace
+	// may run at once
+	DefaultJobsPerNamespace = 4
+
+	// jobHistory is the number of finished jobs kept for sys.jobs
+	jobHistory = 100
+
+	// jobEvents is the number of events kept per job, the latest ones
+	jobEvents = 50
+)
+
+var (
+	// ErrJobsDisabled is returned when the server was started without the
+	// job scheduler
+	ErrJobsDisabled = errors.New("background job scheduling is not enabled")
+
+	// ErrJobNotFound is returned for a job ID the scheduler does not have,
+	// including finished jobs no longer kept
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobState is returned for canceling a finished job or retrying one
+	// that did not fail
+	ErrJobState = errors.New("job cannot be changed in its state")
+)
+
+// Background job kinds
+const (
+	JobKindRetention = "retention"
+	JobKindSnapshot  = "snapshot"
+	JobKindExport    = "export"
+	JobKindWebhook   = "webhook"
+)
+
+// Background job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// JobSchedulerConfig configures the background job scheduler
+type JobSchedulerConfig struct {
+	Workers      int // Jobs run at once (default: 16)
+	PerNamespace int // Jobs one namespace may run at once (default: 4)
+}
+
+// Job is a unit of background work of a namespace
+type Job struct {
+	ID         int64      `json:"id"`
+	Namespace  string     `json:"namespace"`
+	Kind       string     `json:"kind"`
+	State      string     `json:"state"`
+	Progress   float64    `json:"progress"`             // Percent, reported by the job
+	QueuedAt   string     `json:"queuedAt"`             // RFC 3339
+	StartedAt  string     `json:"startedAt,omitempty"`  // RFC 3339
+	FinishedAt string     `json:"finishedAt,omitempty"` // RFC 3339
+	Error      string     `json:"error,omitempty"`
+	RetryOf    int64      `json:"retryOf,omitempty"` // Job this one retries
+	Events     []JobEvent `json:"events"`            // Oldest first, the latest jobEvents
+
+	fn       func(ctx context.Context) error
+	cancel   context.CancelFunc
+	canceled bool // Set by Cancel
+	queued   time.Time
+	ready    chan struct{} // Closed when the job may start
+}
+
+// JobEvent is an entry in a job's trail: its state changes and what the job
+// reported
+type JobEvent struct {
+	Time    string `json:"time"` // RFC 3339
+	Message string `json:"message"`
+}
+
+// jobContextKey holds the running job in the context passed to it
+type jobContextKey struct{}
+
+// runningJob is a job and its scheduler, as seen by the job
+type runningJob struct {
+	scheduler *JobScheduler
+	job       *Job
+}
+
+// JobNamespaceStats are the job counters of one namespace
+type JobNamespaceStats struct {
+	Queued    int64   `json:"queued"`
+	Running   int64   `json:"running"`
+	Succeeded int64   `json:"succeeded"`
+	Failed    int64   `json:"failed"`
+	Canceled  int64   `json:"canceled"`
+	WaitSecs  float64 `json:"waitSeconds"` // Time jobs spent queued, in all
+}
+
+// JobSchedulerStats summarizes the scheduler
+type JobSchedulerStats struct {
+	Workers      int                           `json:"workers"`
+	PerNamespace int                           `json:"perNamespace"`
+	Queued       int64                         `json:"queued"`
+	Running      int64                         `json:"running"`
+	Namespaces   map[string]*JobNamespaceStats `json:"namespaces"`
+}
+
+// JobScheduler runs the background work of namespaces (retention sweeps,
+// snapshots, scheduled exports and webhook deliveries) with concurrency
+// caps: Workers jobs at once, at most PerNamespace of them from one
+// namespace. Waiting namespaces take turns, so one tenant's long retention
+// sweep or burst of deliveries does not delay the others'. Jobs report
+// progress and events through their context (JobProgress, JobEventf) and
+// can be canceled and, when failed, retried. A nil *JobScheduler runs jobs
+// right away.
+type JobScheduler struct {
+	cfg JobSchedulerConfig
+
+	// ctx bounds retried jobs, which have no caller; Close cancels it
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	nextID   int64
+	running  int
+	queues   map[string]*jobQueue // Namespaces with queued or running jobs
+	turns    []string             // Namespaces with queued jobs, next turn first
+	active   map[int64]*Job
+	finished []*Job // Oldest first, at most jobHistory
+	stats    map[string]*JobNamespaceStats
+}
+
+// jobQueue holds a namespace's queued jobs, oldest first
+type jobQueue struct {
+	running int
+	queued  []*Job
+}
+
+// NewJobScheduler creates a scheduler; nil is returned when cfg.Workers is
+// negative, leaving background work unscheduled
+func NewJobScheduler(cfg JobSchedulerConfig) *JobScheduler {
+	if cfg.Workers < 0 {
+		return nil
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = DefaultJobWorkers
+	}
+	if cfg.PerNamespace <= 0 {
+		cfg.PerNamespace = DefaultJobsPerNamespace
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobScheduler{
+		cfg:    cfg,
+		ctx:    ctx,
+		cancel: cancel,
+		queues: make(map[string]*jobQueue),
+		active: make(map[int64]*Job),
+		stats:  make(map[string]*JobNamespaceStats),
+	}
+}
+
+// Close cancels retried jobs and waits for them
+func (s *JobScheduler) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Run runs fn as a job of namespace once the namespace's turn comes and
+// returns its error. Waiting ends with ctx's error if ctx is done first.
+func (s *JobScheduler) Run(ctx context.Context, namespace, kind string, fn func(ctx context.Context) error) error {
+	if s == nil {
+		return fn(ctx)
+	}
+	job, ctx := s.enqueue(ctx, namespace, kind, fn, 0)
+	return s.run(ctx, job)
+}
+
+// run waits for a queued job's turn and runs it
+func (s *JobScheduler) run(ctx context.Context, job *Job) error {
+	select {
+	case <-job.ready:
+	case <-ctx.Done():
+		s.abandon(job)
+		return ctx.Err()
+	}
+	err := job.fn(ctx)
+	s.finish(job, err)
+	return err
+}
+
+// enqueue queues a job and starts it if a slot is free. The returned
+// context is the job's: Cancel cancels it and JobProgress reports to it.
+func (s *JobScheduler) enqueue(ctx context.Context, namespace, kind string, fn func(ctx context.Context) error, retryOf int64) (*Job, context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	now := time.Now().UTC()
+	job := &Job{
+		ID:        s.nextID,
+		Namespace: namespace,
+		Kind:      kind,
+		State:     JobQueued,
+		QueuedAt:  now.Format(time.RFC3339Nano),
+		RetryOf:   retryOf,
+		fn:        fn,
+		queued:    now,
+		ready:     make(chan struct{}),
+	}
+	if retryOf != 0 {
+		job.event(fmt.Sprintf("Queued as a retry of job %d", retryOf))
+	} else {
+		job.event("Queued")
+	}
+	ctx, job.cancel = context.WithCancel(ctx)
+	ctx = context.WithValue(ctx, jobContextKey{}, &runningJob{scheduler: s, job: job})
+	s.active[job.ID] = job
+	q, ok := s.queues[namespace]
+	if !ok {
+		q = &jobQueue{}
+		s.queues[namespace] = q
+	}
+	if len(q.queued) == 0 {
+		s.turns = append(s.turns, namespace)
+	}
+	q.queued = append(q.queued, job)
+	s.namespaceStats(namespace).Queued++
+	s.dispatch()
+	return job, ctx
+}
+
+// dispatch starts queued jobs while slots are free, taking namespaces in
+// turn; the caller holds mu
+func (s *JobScheduler) dispatch() {
+	for s.running < s.cfg.Workers {
+		i := 0
+		for ; i < len(s.turns); i++ {
+			if s.queues[s.turns[i]].running < s.cfg.PerNamespace {
+				break
+			}
+		}
+		if i == len(s.turns) {
+			return
+		}
+		namespace := s.turns[i]
+		q := s.queues[namespace]
+		job := q.queued[0]
+		q.queued = q.queued[1:]
+		q.running++
+		s.running++
+
+		// The namespace's next job waits behind the other namespaces
+		s.turns = append(s.turns[:i], s.turns[i+1:]...)
+		if len(q.queued) > 0 {
+			s.turns = append(s.turns, namespace)
+		}
+
+		now := time.Now().UTC()
+		job.State = JobRunning
+		job.StartedAt = now.Format(time.RFC3339Nano)
+		job.event("Started")
+		stats := s.namespaceStats(namespace)
+		stats.Queued--
+		stats.Running++
+		stats.WaitSecs += now.Sub(job.queued).Seconds()
+		close(job.ready)
+	}
+}
+
+// abandon removes a job whose caller stopped waiting. A job started in the
+// meantime is finished as canceled instead.
+func (s *JobScheduler) abandon(job *Job) {
+	s.mu.Lock()
+	if job.State != JobQueued {
+		s.mu.Unlock()
+		s.finish(job, context.Canceled)
+		return
+	}
+	defer s.mu.Unlock()
+
+	q := s.queues[job.Namespace]
+	for i, queued := range q.queued {
+		if queued == job {
+			q.queued = append(q.queued[:i], q.queued[i+1:]...)
+			break
+		}
+	}
+	if len(q.queued) == 0 {
+		for i, namespace := range s.turns {
+			if namespace == job.Namespace {
+				s.turns = append(s.turns[:i], s.turns[i+1:]...)
+				break
+			}
+		}
+		if q.running == 0 {
+			delete(s.queues, job.Namespace)
+		}
+	}
+	stats := s.namespaceStats(job.Namespace)
+	stats.Queued--
+	stats.Canceled++
+	s.retire(job, JobCanceled, "")
+}
+
+// finish records the outcome of a running job and frees its slot
+func (s *JobScheduler) finish(job *Job, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := s.queues[job.Namespace]
+	q.running--
+	s.running--
+	if q.running == 0 && len(q.queued) == 0 {
+		delete(s.queues, job.Namespace)
+	}
+
+	stats := s.namespaceStats(job.Namespace)
+	stats.Running--
+	switch {
+	case err == nil:
+		stats.Succeeded++
+		job.Progress = 100
+		s.retire(job, JobSucceeded, "")
+	case job.canceled || errors.Is(err, context.Canceled):
+		stats.Canceled++
+		s.retire(job, JobCanceled, err.Error())
+	default:
+		stats.Failed++
+		s.retire(job, JobFailed, err.Error())
+	}
+	s.dispatch()
+}
+
+// retire moves a job to the finished jobs; the caller holds mu
+func (s *JobScheduler) retire(job *Job, state, errMsg string) {
+	job.cancel()
+	job.State = state
+	job.Error = errMsg
+	job.FinishedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	switch state {
+	case JobSucceeded:
+		job.event("Succeeded")
+	case JobFailed:
+		job.event("Failed: " + errMsg)
+	default:
+		job.event("Canceled")
+	}
+	delete(s.active, job.ID)
+	s.finished = append(s.finished, job)
+	if len(s.finished) > jobHistory {
+		s.finished = s.finished[len(s.finished)-jobHistory:]
+	}
+}
+
+// event adds an entry to the job's trail; the caller holds the scheduler's mu
+func (job *Job) event(message string) {
+	if len(job.Events) == jobEvents {
+		job.Events = job.Events[1:]
+	}
+	job.Events = append(job.Events, JobEvent{Time: time.Now().UTC().Format(time.RFC3339Nano), Message: message})
+}
+
+// snapshot returns a copy of the job for callers; the caller holds mu
+func (job *Job) snapshot() Job {
+	c := *job
+	c.Events = append([]JobEvent(nil), job.Events...)
+	return c
+}
+
+// JobProgress records the progress of the job running with ctx, in percent.
+// Outside jobs it does nothing.
+func JobProgress(ctx context.Context, percent float64) {
+	if r, ok := ctx.Value(jobContextKey{}).(*runningJob); ok {
+		r.scheduler.mu.Lock()
+		r.job.Progress = max(0, min(percent, 100))
+		r.scheduler.mu.Unlock()
+	}
+}
+
+// JobEventf adds a message to the events of the job running with ctx.
+// Outside jobs it does nothing.
+func JobEventf(ctx context.Context, format string, args ...interface{}) {
+	if r, ok := ctx.Value(jobContextKey{}).(*runningJob); ok {
+		r.scheduler.mu.Lock()
+		r.job.event(fmt.Sprintf(format, args...))
+		r.scheduler.mu.Unlock()
+	}
+}
+
+// Cancel cancels a queued or running job of namespace, or of any namespace
+// when it is empty. A running job stops once its work sees its context
+// canceled.
+func (s *JobScheduler) Cancel(namespace string, id int64) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.active[id]
+	if !ok || (namespace != "" && job.Namespace != namespace) {
+		if finished := s.finishedJob(namespace, id); finished != nil {
+			return finished.snapshot(), fmt.Errorf("%w: job %d is %s", ErrJobState, id, finished.State)
+		}
+		return Job{}, fmt.Errorf("%w: %d", ErrJobNotFound, id)
+	}
+	if !job.canceled {
+		job.canceled = true
+		job.event("Cancel requested")
+		job.cancel()
+	}
+	return job.snapshot(), nil
+}
+
+// Retry queues a failed or canceled job of namespace, or of any namespace
+// when it is empty, again as a new job and returns it. Webhook deliveries
+// are retried by their hook and cannot be retried here.
+func (s *JobScheduler) Retry(namespace string, id int64) (Job, error) {
+	s.mu.Lock()
+	job := s.finishedJob(namespace, id)
+	if job == nil {
+		active, ok := s.active[id]
+		s.mu.Unlock()
+		if ok && (namespace == "" || active.Namespace == namespace) {
+			return Job{}, fmt.Errorf("%w: job %d is %s", ErrJobState, id, active.State)
+		}
+		return Job{}, fmt.Errorf("%w: %d", ErrJobNotFound, id)
+	}
+	if (job.State != JobFailed && job.State != JobCanceled) || job.Kind == JobKindWebhook {
+		state := job.State
+		s.mu.Unlock()
+		if job.Kind == JobKindWebhook {
+			return Job{}, fmt.Errorf("%w: webhook deliveries are retried by their hook", ErrJobState)
+		}
+		return Job{}, fmt.Errorf("%w: job %d %s", ErrJobState, id, state)
+	}
+	namespace, kind, fn := job.Namespace, job.Kind, job.fn
+	s.mu.Unlock()
+
+	retry, ctx := s.enqueue(s.ctx, namespace, kind, fn, id)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx, retry)
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return retry.snapshot(), nil
+}
+
+// finishedJob returns a kept finished job of namespace, or of any namespace
+// when it is empty; the caller holds mu
+func (s *JobScheduler) finishedJob(namespace string, id int64) *Job {
+	for _, job := range s.finished {
+		if job.ID == id && (namespace == "" || job.Namespace == namespace) {
+			return job
+		}
+	}
+	return nil
+}
+
+// namespaceStats returns a namespace's counters; the caller holds mu
+func (s *JobScheduler) namespaceStats(namespace string) *JobNamespaceStats {
+	stats, ok := s.stats[namespace]
+	if !ok {
+		stats = &JobNamespaceStats{}
+		s.stats[namespace] = stats
+	}
+	return stats
+}
+
+// Jobs returns copies of the queued and running jobs of namespace, then its
+// recently finished ones, newest first; an empty namespace returns those of
+// every namespace
+func (s *JobScheduler) Jobs(namespace string) []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := []Job{}
+	for _, job := range s.active {
+		if namespace == "" || job.Namespace == namespace {
+			jobs = append(jobs, job.snapshot())
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+	for i := len(s.finished) - 1; i >= 0; i-- {
+		if job := s.finished[i]; namespace == "" || job.Namespace == namespace {
+			jobs = append(jobs, job.snapshot())
+		}
+	}
+	return jobs
+}
+
+// Stats returns the scheduler's counters for namespace, or for every
+// namespace when it is empty
+func (s *JobScheduler) Stats(namespace string) JobSchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := JobSchedulerStats{
+		Workers:      s.cfg.Workers,
+		PerNamespace: s.cfg.PerNamespace,
+		Namespaces:   make(map[string]*JobNamespaceStats),
+	}
+	for ns, counters := range s.stats {
+		if namespace != "" && ns != namespace {
+			continue
+		}
+		stats.Queued += counters.Queued
+		stats.Running += counters.Running
+		c := *counters
+		stats.Namespaces[ns] = &c
+	}
+	return stats
+}
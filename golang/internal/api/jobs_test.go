@@ -0,0 +1,491 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startJob runs a job that records its start and then blocks on release
+func startJob(t *testing.T, s *JobScheduler, namespace string, started chan<- string, release <-chan struct{}, wg *sync.WaitGroup) {
+	t.Helper()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.Run(context.Background(), namespace, JobKindRetention, func(ctx context.Context) error {
+			started <- namespace
+			<-release
+			return nil
+		})
+	}()
+}
+
+// waitQueued waits until namespace has queued jobs
+func waitQueued(t *testing.T, s *JobScheduler, namespace string, queued int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if stats := s.Stats(namespace).Namespaces[namespace]; stats != nil && stats.Queued == queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected %d queued jobs of %s", queued, namespace)
+}
+
+func TestJobScheduler_Fairness(t *testing.T) {
+	s := NewJobScheduler(JobSchedulerConfig{Workers: 1, PerNamespace: 1})
+	started := make(chan string, 10)
+	var wg sync.WaitGroup
+
+	// A heavy namespace queues several jobs before another namespace's one;
+	// each job runs until a token is sent on release
+	release := make(chan struct{}, 10)
+	startJob(t, s, "heavy", started, release, &wg)
+	if ns := <-started; ns != "heavy" {
+		t.Fatalf("Expected heavy to start, got %s", ns)
+	}
+	for i := 1; i <= 4; i++ {
+		startJob(t, s, "heavy", started, release, &wg)
+		waitQueued(t, s, "heavy", int64(i))
+	}
+	startJob(t, s, "light", started, release, &wg)
+	waitQueued(t, s, "light", 1)
+
+	// Namespaces take turns: light runs after one more heavy job, not four
+	var order []string
+	release <- struct{}{}
+	for i := 0; i < 5; i++ {
+		order = append(order, <-started)
+		release <- struct{}{}
+	}
+	wg.Wait()
+	if want := []string{"heavy", "light", "heavy", "heavy", "heavy"}; strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, order)
+	}
+
+	stats := s.Stats("")
+	if stats.Queued != 0 || stats.Running != 0 || stats.Namespaces["heavy"].Succeeded != 5 || stats.Namespaces["light"].Succeeded != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if jobs := s.Jobs("light"); len(jobs) != 1 || jobs[0].State != JobSucceeded || jobs[0].FinishedAt == "" {
+		t.Errorf("Expected light's finished job, got %+v", jobs)
+	}
+}
+
+func TestJobScheduler_NamespaceCap(t *testing.T) {
+	s := NewJobScheduler(JobSchedulerConfig{Workers: 2, PerNamespace: 1})
+	started := make(chan string, 10)
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+
+	startJob(t, s, "a", started, release, &wg)
+	<-started
+	startJob(t, s, "a", started, release, &wg)
+	waitQueued(t, s, "a", 1)
+
+	// Another namespace gets the free worker while a's second job waits
+	startJob(t, s, "b", started, release, &wg)
+	if ns := <-started; ns != "b" {
+		t.Errorf("Expected b to start, got %s", ns)
+	}
+	close(release)
+	<-started
+	wg.Wait()
+}
+
+func TestJobScheduler_CancelAndFailure(t *testing.T) {
+	s := NewJobScheduler(JobSchedulerConfig{Workers: 1, PerNamespace: 1})
+	started := make(chan string, 10)
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	startJob(t, s, "a", started, release, &wg)
+	<-started
+
+	// A caller that stops waiting leaves the queue
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx, "b", JobKindExport, func(ctx context.Context) error {
+			t.Error("Canceled job ran")
+			return nil
+		})
+	}()
+	waitQueued(t, s, "b", 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	close(release)
+	wg.Wait()
+
+	// Failures are recorded
+	failure := errors.New("disk full")
+	if err := s.Run(context.Background(), "b", JobKindExport, func(ctx context.Context) error { return failure }); err != failure {
+		t.Errorf("Expected the job's error, got %v", err)
+	}
+	jobs := s.Jobs("b")
+	if len(jobs) != 2 || jobs[0].State != JobFailed || jobs[0].Error != "disk full" || jobs[1].State != JobCanceled {
+		t.Errorf("Unexpected jobs: %+v", jobs)
+	}
+
+	// A nil scheduler runs jobs right away
+	var unscheduled *JobScheduler
+	if err := unscheduled.Run(context.Background(), "b", JobKindExport, func(ctx context.Context) error { return failure }); err != failure {
+		t.Errorf("Expected the job's error, got %v", err)
+	}
+}
+
+// TestJobScheduler_ProgressCancelRetry tests job progress and events,
+// canceling running jobs and retrying failed ones
+func TestJobScheduler_ProgressCancelRetry(t *testing.T) {
+	s := NewJobScheduler(JobSchedulerConfig{})
+	defer s.Close()
+
+	// Jobs report progress and events; success completes the progress
+	reported := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- s.Run(context.Background(), "a", JobKindRetention, func(ctx context.Context) error {
+			JobProgress(ctx, 40)
+			JobEventf(ctx, "Swept %d streams", 3)
+			close(reported)
+			<-release
+			return nil
+		})
+	}()
+	<-reported
+	jobs := s.Jobs("a")
+	if len(jobs) != 1 || jobs[0].Progress != 40 || jobs[0].Events[len(jobs[0].Events)-1].Message != "Swept 3 streams" {
+		t.Errorf("Expected the reported progress and event, got %+v", jobs)
+	}
+	close(release)
+	<-done
+	if job := s.Jobs("a")[0]; job.State != JobSucceeded || job.Progress != 100 || len(job.Events) != 4 {
+		t.Errorf("Expected a completed job with 4 events, got %+v", job)
+	}
+
+	// Canceling a running job cancels its context
+	started := make(chan struct{})
+	go func() {
+		done <- s.Run(context.Background(), "a", JobKindExport, func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+	<-started
+	running := s.Jobs("a")[0]
+	if _, err := s.Cancel("b", running.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound for another namespace's job, got %v", err)
+	}
+	if _, err := s.Cancel("a", running.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := s.Cancel("a", running.ID); !errors.Is(err, ErrJobState) {
+		t.Errorf("Expected ErrJobState for a finished job, got %v", err)
+	}
+
+	// Failed and canceled jobs can be retried as new jobs
+	attempts := make(chan int, 2)
+	attempt := 0
+	err := s.Run(context.Background(), "a", JobKindSnapshot, func(ctx context.Context) error {
+		attempt++
+		attempts <- attempt
+		if attempt == 1 {
+			return errors.New("reducer timed out")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Expected the first attempt to fail")
+	}
+	<-attempts
+	failed := s.Jobs("a")[0]
+	retry, err := s.Retry("a", failed.ID)
+	if err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if retry.RetryOf != failed.ID || retry.ID == failed.ID {
+		t.Errorf("Expected a new job retrying %d, got %+v", failed.ID, retry)
+	}
+	if n := <-attempts; n != 2 {
+		t.Errorf("Expected a second attempt, got %d", n)
+	}
+	if _, err := s.Retry("a", jobs[0].ID); !errors.Is(err, ErrJobState) {
+		t.Errorf("Expected ErrJobState for a succeeded job, got %v", err)
+	}
+	if _, err := s.Retry("a", 999); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+
+	// Webhook deliveries are retried by their hook
+	s.Run(context.Background(), "a", JobKindWebhook, func(ctx context.Context) error { return errors.New("502") })
+	if _, err := s.Retry("a", s.Jobs("a")[0].ID); !errors.Is(err, ErrJobState) {
+		t.Errorf("Expected ErrJobState for a webhook delivery, got %v", err)
+	}
+}
+
+// TestJobScheduler_ProgressCancelRetryEdges tests canceling queued jobs and
+// jobs already being canceled, progress outside its bounds or outside jobs,
+// the kept events, and retries of running, canceled, other namespaces' and
+// no longer kept jobs
+func TestJobScheduler_ProgressCancelRetryEdges(t *testing.T) {
+	s := NewJobScheduler(JobSchedulerConfig{Workers: 1, PerNamespace: 1})
+	defer s.Close()
+
+	// Progress is clamped, events beyond the kept ones drop the oldest, and
+	// both do nothing outside a job
+	JobProgress(context.Background(), 50)
+	JobEventf(context.Background(), "not a job")
+	started := make(chan struct{}, 2) // Also sent by the retry
+	release := make(chan struct{})
+	done := make(chan error, 2)
+	go func() {
+		done <- s.Run(context.Background(), "a", JobKindRetention, func(ctx context.Context) error {
+			JobProgress(ctx, 150)
+			for i := 0; i < jobEvents; i++ {
+				JobEventf(ctx, "Swept %d", i)
+			}
+			started <- struct{}{}
+			<-release
+			if ctx.Err() != nil {
+				return errors.New("sweep interrupted")
+			}
+			return nil
+		})
+	}()
+	<-started
+	running := s.Jobs("a")[0]
+	if running.Progress != 100 || len(running.Events) != jobEvents || running.Events[0].Message != "Swept 0" {
+		t.Errorf("Expected progress 100 and the last %d events, got %+v", jobEvents, running)
+	}
+
+	// A queued job canceled before its turn never runs
+	go func() {
+		done <- s.Run(context.Background(), "b", JobKindExport, func(ctx context.Context) error {
+			t.Error("Canceled job ran")
+			return nil
+		})
+	}()
+	waitQueued(t, s, "b", 1)
+	queued := s.Jobs("b")[0]
+	if _, err := s.Retry("b", queued.ID); !errors.Is(err, ErrJobState) {
+		t.Errorf("Expected ErrJobState for a queued job, got %v", err)
+	}
+	if job, err := s.Cancel("", queued.ID); err != nil || job.State != JobQueued {
+		t.Fatalf("Expected the queued job, got %+v (%v)", job, err)
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if stats := s.Stats("b").Namespaces["b"]; stats.Queued != 0 || stats.Canceled != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Canceling twice records one request; a job that fails once canceled
+	// is canceled, not failed
+	if _, err := s.Retry("a", running.ID); !errors.Is(err, ErrJobState) {
+		t.Errorf("Expected ErrJobState for a running job, got %v", err)
+	}
+	s.Cancel("a", running.ID)
+	job, err := s.Cancel("a", running.ID)
+	if err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if last := job.Events[len(job.Events)-1]; last.Message != "Cancel requested" || job.Events[len(job.Events)-2].Message == "Cancel requested" {
+		t.Errorf("Expected one cancel request, got %+v", job.Events)
+	}
+	close(release)
+	if err := <-done; err == nil || err.Error() != "sweep interrupted" {
+		t.Errorf("Expected the job's error, got %v", err)
+	}
+	if job := s.Jobs("a")[0]; job.State != JobCanceled || job.Error != "sweep interrupted" {
+		t.Errorf("Expected the job canceled, got %+v", job)
+	}
+
+	// Canceled jobs are retried; other namespaces' jobs are not found, except
+	// with no namespace
+	if _, err := s.Retry("a", queued.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound for another namespace's job, got %v", err)
+	}
+	if _, err := s.Retry("", running.ID); err != nil {
+		t.Errorf("Expected the canceled job to be retried, got %v", err)
+	}
+
+	// Jobs beyond the kept history are not found
+	for i := 0; i <= jobHistory; i++ {
+		s.Run(context.Background(), "c", JobKindSnapshot, func(ctx context.Context) error { return errors.New("reducer timed out") })
+	}
+	if _, err := s.Retry("", queued.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound for a job no longer kept, got %v", err)
+	}
+	if jobs := s.Jobs(""); len(jobs) != jobHistory {
+		t.Errorf("Expected %d kept jobs, got %d", jobHistory, len(jobs))
+	}
+}
+
+func TestSysJobs(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, nil)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	result, rpcErr := h.route(ctx, "sys.jobs", nil)
+	if rpcErr != nil {
+		t.Fatalf("sys.jobs failed: %v", rpcErr.Message)
+	}
+	if result.(map[string]interface{})["enabled"] != false {
+		t.Errorf("Expected sys.jobs to be disabled, got %v", result)
+	}
+
+	jobs := NewJobScheduler(JobSchedulerConfig{})
+	defer jobs.Close()
+	h.SetJobScheduler(jobs)
+	for _, ns := range []string{"test-ns", "other"} {
+		if err := jobs.Run(ctx, ns, JobKindSnapshot, func(ctx context.Context) error { return nil }); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+
+	// Callers see the jobs of their own namespace
+	result, rpcErr = h.route(ctx, "sys.jobs", nil)
+	if rpcErr != nil {
+		t.Fatalf("sys.jobs failed: %v", rpcErr.Message)
+	}
+	info := result.(map[string]interface{})
+	if list := info["jobs"].([]Job); len(list) != 1 || list[0].Namespace != "test-ns" || list[0].Kind != JobKindSnapshot {
+		t.Errorf("Expected test-ns's job, got %+v", list)
+	}
+	if info["workers"] != DefaultJobWorkers || len(info["namespaces"].(map[string]*JobNamespaceStats)) != 1 {
+		t.Errorf("Unexpected sys.jobs result: %v", info)
+	}
+
+	// sys.jobs.list filters, sys.jobs.retry and sys.jobs.cancel act on the caller's jobs
+	jobs.Run(ctx, "test-ns", JobKindExport, func(ctx context.Context) error { return errors.New("bucket missing") })
+	result, rpcErr = h.route(ctx, "sys.jobs.list", []interface{}{map[string]interface{}{"state": "failed"}})
+	if rpcErr != nil {
+		t.Fatalf("sys.jobs.list failed: %v", rpcErr.Message)
+	}
+	list := result.([]Job)
+	if len(list) != 1 || list[0].Kind != JobKindExport || list[0].Error != "bucket missing" {
+		t.Fatalf("Expected the failed export, got %+v", list)
+	}
+	result, rpcErr = h.route(ctx, "sys.jobs.retry", []interface{}{float64(list[0].ID)})
+	if rpcErr != nil {
+		t.Fatalf("sys.jobs.retry failed: %v", rpcErr.Message)
+	}
+	if retry := result.(Job); retry.RetryOf != list[0].ID {
+		t.Errorf("Expected a retry of job %d, got %+v", list[0].ID, retry)
+	}
+	other := jobs.Jobs("other")[0]
+	if _, rpcErr := h.route(ctx, "sys.jobs.cancel", []interface{}{float64(other.ID)}); rpcErr == nil || rpcErr.Code != "JOB_NOT_FOUND" {
+		t.Errorf("Expected JOB_NOT_FOUND for another namespace's job, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "sys.jobs.cancel", []interface{}{"x"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST, got %v", rpcErr)
+	}
+}
+
+// TestSysJobs_Errors tests invalid options and IDs, disabled scheduling,
+// callers without a namespace, the default namespace's view of all jobs, and
+// changes to jobs in the wrong state
+func TestSysJobs_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, nil)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, method := range []string{"sys.jobs.list", "sys.jobs.cancel", "sys.jobs.retry"} {
+		if _, rpcErr := h.route(ctx, method, []interface{}{float64(1)}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %s without a scheduler, got %v", method, rpcErr)
+		}
+		if _, rpcErr := h.route(context.Background(), method, []interface{}{float64(1)}); rpcErr == nil || rpcErr.Code != "AUTH_REQUIRED" {
+			t.Errorf("Expected AUTH_REQUIRED for %s without a namespace, got %v", method, rpcErr)
+		}
+	}
+
+	jobs := NewJobScheduler(JobSchedulerConfig{})
+	defer jobs.Close()
+	h.SetJobScheduler(jobs)
+	for _, args := range [][]interface{}{
+		{"failed"},
+		{map[string]interface{}{"kind": float64(1)}},
+		{map[string]interface{}{"state": true}},
+		{map[string]interface{}{"limit": float64(0)}},
+		{map[string]interface{}{"limit": 1.5}},
+		{map[string]interface{}{"limit": "10"}},
+	} {
+		if _, rpcErr := h.route(ctx, "sys.jobs.list", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for sys.jobs.list %v, got %v", args, rpcErr)
+		}
+	}
+	for _, method := range []string{"sys.jobs.cancel", "sys.jobs.retry"} {
+		for _, args := range [][]interface{}{{}, {float64(0)}, {1.5}, {"1"}} {
+			if _, rpcErr := h.route(ctx, method, args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+				t.Errorf("Expected INVALID_REQUEST for %s %v, got %v", method, args, rpcErr)
+			}
+		}
+	}
+
+	for _, ns := range []string{"test-ns", "test-ns", "other"} {
+		jobs.Run(ctx, ns, JobKindExport, func(ctx context.Context) error { return errors.New("bucket missing") })
+	}
+	ids := func(result interface{}) []int64 {
+		var out []int64
+		for _, job := range result.([]Job) {
+			out = append(out, job.ID)
+		}
+		return out
+	}
+
+	// Namespace tokens cannot look at other namespaces; the default namespace
+	// and test mode see all of them and may filter
+	result, rpcErr := h.route(ctx, "sys.jobs.list", []interface{}{map[string]interface{}{"namespace": "other"}})
+	if rpcErr != nil || len(ids(result)) != 2 {
+		t.Errorf("Expected test-ns's 2 jobs, got %v (%v)", result, rpcErr)
+	}
+	admin := context.WithValue(context.Background(), ContextKeyNamespace, "default")
+	if result, rpcErr = h.route(admin, "sys.jobs.list", nil); rpcErr != nil || len(ids(result)) != 3 {
+		t.Errorf("Expected all 3 jobs, got %v (%v)", result, rpcErr)
+	}
+	if result, rpcErr = h.route(admin, "sys.jobs.list", []interface{}{map[string]interface{}{"namespace": "other"}}); rpcErr != nil || len(ids(result)) != 1 {
+		t.Errorf("Expected other's job, got %v (%v)", result, rpcErr)
+	}
+	if _, rpcErr = h.route(admin, "sys.jobs.list", []interface{}{map[string]interface{}{"namespace": float64(1)}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a non-string namespace, got %v", rpcErr)
+	}
+	testMode := context.WithValue(ctx, ContextKeyTestMode, true)
+	if result, rpcErr = h.route(testMode, "sys.jobs", nil); rpcErr != nil || len(result.(map[string]interface{})["jobs"].([]Job)) != 3 {
+		t.Errorf("Expected all 3 jobs in test mode, got %v (%v)", result, rpcErr)
+	}
+
+	// Filters and limits, newest first
+	result, rpcErr = h.route(ctx, "sys.jobs.list", []interface{}{map[string]interface{}{"kind": JobKindExport, "state": JobFailed, "limit": float64(1)}})
+	if rpcErr != nil {
+		t.Fatalf("sys.jobs.list failed: %v", rpcErr.Message)
+	}
+	list := ids(result)
+	if len(list) != 1 || list[0] != 2 {
+		t.Errorf("Expected job 2, got %v", list)
+	}
+	if result, rpcErr = h.route(ctx, "sys.jobs.list", []interface{}{map[string]interface{}{"kind": JobKindSnapshot}}); rpcErr != nil || len(ids(result)) != 0 {
+		t.Errorf("Expected no snapshot jobs, got %v (%v)", result, rpcErr)
+	}
+
+	// Finished jobs cannot be canceled, and unknown jobs are not found
+	if _, rpcErr := h.route(ctx, "sys.jobs.cancel", []interface{}{float64(1)}); rpcErr == nil || rpcErr.Code != "JOB_STATE_CONFLICT" {
+		t.Errorf("Expected JOB_STATE_CONFLICT, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "sys.jobs.retry", []interface{}{float64(99)}); rpcErr == nil || rpcErr.Code != "JOB_NOT_FOUND" {
+		t.Errorf("Expected JOB_NOT_FOUND, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(admin, "sys.jobs.retry", []interface{}{float64(3)}); rpcErr != nil {
+		t.Errorf("Expected the default namespace to retry other's job, got %v", rpcErr)
+	}
+}
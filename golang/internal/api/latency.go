@@ -0,0 +1,260 @@
+// Package api provides rolling RPC latency histograms.
+package api
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// latencySlotWidth is the time covered by one histogram slot
+	latencySlotWidth = 10 * time.Second
+
+	// latencySlots is the number of slots kept, covering the longest window
+	latencySlots = int(time.Hour / latencySlotWidth)
+
+	// latencySubBuckets is the number of buckets per power of two. 8 keeps
+	// reported percentiles within 12.5% of the recorded latency.
+	latencySubBuckets = 8
+
+	// latencySubBucketBits is log2(latencySubBuckets)
+	latencySubBucketBits = 3
+)
+
+// LatencyWindow is a window latency percentiles are reported over
+type LatencyWindow struct {
+	Name     string
+	Duration time.Duration
+}
+
+// LatencyWindows are the windows reported by sys.stats, shortest first
+var LatencyWindows = []LatencyWindow{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+}
+
+// latencyQuantiles are the percentiles reported besides the maximum
+var latencyQuantiles = []struct {
+	name string
+	q    float64
+}{{"p50", 0.5}, {"p95", 0.95}, {"p99", 0.99}}
+
+// LatencySummary is the latency of the calls in one window. Durations are in
+// milliseconds.
+type LatencySummary struct {
+	Count int64   `json:"count"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// LatencyStats is the latency of one method called in one namespace
+type LatencyStats struct {
+	Method    string                    `json:"method"`
+	Namespace string                    `json:"namespace"`
+	Windows   map[string]LatencySummary `json:"windows"`
+
+	// Total calls and their summed duration in seconds since startup, for
+	// the Prometheus summary
+	Count int64   `json:"-"`
+	Sum   float64 `json:"-"`
+}
+
+// latencyKey identifies a histogram
+type latencyKey struct {
+	method    string
+	namespace string
+}
+
+// latencySlot counts the calls that completed in one slot, by bucket
+type latencySlot struct {
+	start  int64 // Slot number, time / latencySlotWidth
+	counts map[int]int64
+	max    time.Duration
+}
+
+// latencyHistogram is a ring of slots covering the last hour
+type latencyHistogram struct {
+	slots [latencySlots]*latencySlot
+	count int64
+	sum   time.Duration
+}
+
+// LatencyTracker keeps rolling latency histograms per RPC method and
+// namespace. Latencies are bucketed HDR-style with 8 buckets per power of two
+// of microseconds, so memory stays bounded and percentiles are within 12.5%.
+// Histograms are kept in memory and restart with the server.
+type LatencyTracker struct {
+	mu    sync.Mutex
+	hists map[latencyKey]*latencyHistogram
+	now   func() time.Time
+}
+
+// NewLatencyTracker creates an empty latency tracker
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{
+		hists: make(map[latencyKey]*latencyHistogram),
+		now:   time.Now,
+	}
+}
+
+// Observe records a call of method in namespace that took d. It is a no-op on
+// a nil tracker.
+func (t *LatencyTracker) Observe(method, namespace string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	if d < 0 {
+		d = 0
+	}
+	slot := t.now().UnixNano() / int64(latencySlotWidth)
+	bucket := latencyBucket(d)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := latencyKey{method, namespace}
+	hist := t.hists[key]
+	if hist == nil {
+		hist = &latencyHistogram{}
+		t.hists[key] = hist
+	}
+	hist.count++
+	hist.sum += d
+
+	i := int(slot % int64(latencySlots))
+	s := hist.slots[i]
+	if s == nil || s.start != slot {
+		s = &latencySlot{start: slot, counts: make(map[int]int64)}
+		hist.slots[i] = s
+	}
+	s.counts[bucket]++
+	if d > s.max {
+		s.max = d
+	}
+}
+
+// Stats returns the latency of every method and namespace called in the last
+// hour, sorted by method and namespace. An empty namespace returns all of them.
+func (t *LatencyTracker) Stats(namespace string) []LatencyStats {
+	if t == nil {
+		return nil
+	}
+	now := t.now().UnixNano() / int64(latencySlotWidth)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := make([]LatencyStats, 0, len(t.hists))
+	for key, hist := range t.hists {
+		if namespace != "" && key.namespace != namespace {
+			continue
+		}
+		windows := make(map[string]LatencySummary, len(LatencyWindows))
+		for _, w := range LatencyWindows {
+			windows[w.Name] = hist.summary(now, int64(w.Duration/latencySlotWidth))
+		}
+		if windows[LatencyWindows[len(LatencyWindows)-1].Name].Count == 0 {
+			// Not called within the longest window
+			delete(t.hists, key)
+			continue
+		}
+		stats = append(stats, LatencyStats{
+			Method:    key.method,
+			Namespace: key.namespace,
+			Windows:   windows,
+			Count:     hist.count,
+			Sum:       hist.sum.Seconds(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Method != stats[j].Method {
+			return stats[i].Method < stats[j].Method
+		}
+		return stats[i].Namespace < stats[j].Namespace
+	})
+	return stats
+}
+
+// summary merges the slots of the last n slot widths up to slot now
+func (h *latencyHistogram) summary(now, n int64) LatencySummary {
+	counts := make(map[int]int64)
+	var total int64
+	var max time.Duration
+	for _, s := range h.slots {
+		if s == nil || s.start > now || s.start <= now-n {
+			continue
+		}
+		for bucket, c := range s.counts {
+			counts[bucket] += c
+			total += c
+		}
+		if s.max > max {
+			max = s.max
+		}
+	}
+	summary := LatencySummary{Count: total, Max: durationMillis(max)}
+	if total == 0 {
+		return summary
+	}
+
+	buckets := make([]int, 0, len(counts))
+	for bucket := range counts {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+	values := make([]float64, len(latencyQuantiles))
+	for i, q := range latencyQuantiles {
+		rank := int64(q.q*float64(total) + 0.5)
+		if rank < 1 {
+			rank = 1
+		}
+		var seen int64
+		for _, bucket := range buckets {
+			seen += counts[bucket]
+			if seen >= rank {
+				// The highest latency the bucket holds, but no more than was seen
+				d := latencyBucketMax(bucket)
+				if d > max {
+					d = max
+				}
+				values[i] = durationMillis(d)
+				break
+			}
+		}
+	}
+	summary.P50, summary.P95, summary.P99 = values[0], values[1], values[2]
+	return summary
+}
+
+// latencyBucket returns the bucket of a latency. Latencies under 8µs get a
+// bucket each; above that each power of two is split into 8 buckets.
+func latencyBucket(d time.Duration) int {
+	us := uint64(d / time.Microsecond)
+	if us < latencySubBuckets {
+		return int(us)
+	}
+	exp := bits.Len64(us) - 1
+	sub := int(us>>(exp-latencySubBucketBits)) - latencySubBuckets
+	return latencySubBuckets + (exp-latencySubBucketBits)*latencySubBuckets + sub
+}
+
+// latencyBucketMax returns the highest latency in a bucket
+func latencyBucketMax(bucket int) time.Duration {
+	if bucket < latencySubBuckets {
+		return time.Duration(bucket+1)*time.Microsecond - 1
+	}
+	shift := (bucket - latencySubBuckets) / latencySubBuckets
+	sub := (bucket - latencySubBuckets) % latencySubBuckets
+	us := uint64(latencySubBuckets+sub+1) << shift
+	return time.Duration(us)*time.Microsecond - 1
+}
+
+// durationMillis returns d in milliseconds
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// TestLatencyTracker verifies rolling latency percentiles per method and
+// namespace, and their exposure in sys.stats and /metrics
+func TestLatencyTracker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tracker := NewLatencyTracker()
+	tracker.now = func() time.Time { return now }
+
+	// 100 calls of 1ms to 100ms ten minutes ago, then 10 calls of 2ms now
+	now = now.Add(-10 * time.Minute)
+	for i := 1; i <= 100; i++ {
+		tracker.Observe("stream.get", "ns-a", time.Duration(i)*time.Millisecond)
+	}
+	now = now.Add(10 * time.Minute)
+	for i := 0; i < 10; i++ {
+		tracker.Observe("stream.get", "ns-a", 2*time.Millisecond)
+	}
+	tracker.Observe("stream.write", "ns-b", time.Millisecond)
+
+	stats := tracker.Stats("")
+	if len(stats) != 2 || stats[0].Method != "stream.get" || stats[1].Method != "stream.write" {
+		t.Fatalf("Expected stats for 2 methods, got %+v", stats)
+	}
+	hour, minute := stats[0].Windows["1h"], stats[0].Windows["1m"]
+	if hour.Count != 110 || hour.Max != 100 {
+		t.Errorf("Expected 110 calls up to 100ms in the last hour, got %+v", hour)
+	}
+	// Percentiles are within 12.5% of the recorded latency
+	if hour.P50 < 40 || hour.P50 > 50*1.125 || hour.P99 < 90 || hour.P99 > 100 {
+		t.Errorf("Unexpected percentiles for the last hour: %+v", hour)
+	}
+	if minute.Count != 10 || minute.P50 < 2 || minute.P50 > 2.25 || minute.Max != 2 {
+		t.Errorf("Expected 10 calls of 2ms in the last minute, got %+v", minute)
+	}
+	if filtered := tracker.Stats("ns-b"); len(filtered) != 1 || filtered[0].Namespace != "ns-b" {
+		t.Errorf("Expected stats for ns-b only, got %+v", filtered)
+	}
+
+	// Histograms are dropped an hour after their last call
+	now = now.Add(time.Hour)
+	if stats := tracker.Stats(""); len(stats) != 0 {
+		t.Errorf("Expected no stats after an hour, got %+v", stats)
+	}
+
+	for _, d := range []time.Duration{0, time.Microsecond, 7 * time.Microsecond, 8 * time.Microsecond, 999 * time.Microsecond, time.Second, time.Hour} {
+		if max := latencyBucketMax(latencyBucket(d)); max < d || float64(max) > float64(d)*1.125+float64(time.Microsecond) {
+			t.Errorf("Bucket of %v holds up to %v", d, max)
+		}
+	}
+}
+
+// TestSysStats verifies that RPC calls are timed and reported by sys.stats
+func TestSysStats(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	tracker := NewLatencyTracker()
+	h.SetLatencyTracker(tracker)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := h.route(ctx, "stream.version", []interface{}{"order-1"}); rpcErr != nil {
+		t.Fatalf("stream.version failed: %v", rpcErr.Message)
+	}
+	result, rpcErr := h.route(ctx, "sys.stats", nil)
+	if rpcErr != nil {
+		t.Fatalf("sys.stats failed: %v", rpcErr.Message)
+	}
+	latency := result.(map[string]interface{})["latency"].([]LatencyStats)
+	if len(latency) != 1 || latency[0].Method != "stream.version" || latency[0].Namespace != "test-ns" || latency[0].Windows["1m"].Count != 1 {
+		t.Fatalf("Expected the stream.version call, got %+v", latency)
+	}
+
+	var reqCtx fasthttp.RequestCtx
+	MetricsHandler(MetricsSources{Latency: tracker})(&reqCtx)
+	body := string(reqCtx.Response.Body())
+	for _, line := range []string{
+		`eventodb_rpc_latency_seconds{method="stream.version",namespace="test-ns",quantile="0.99"}`,
+		`eventodb_rpc_latency_seconds_count{method="stream.version",namespace="test-ns"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %s in metrics, got:\n%s", line, body)
+		}
+	}
+}
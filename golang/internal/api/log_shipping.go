@@ -0,0 +1,563 @@
+// Package api provides per-namespace log shipping to tenant-owned destinations.
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	// Namespace metadata keys holding the shipping config and its progress
+	logShippingMetadataKey       = "logShipping"
+	logShippingStatusMetadataKey = "logShippingStatus"
+
+	// logShippingDefaultBatch is the number of messages per shipped object
+	logShippingDefaultBatch = 500
+
+	// logShippingMaxBatch caps the configurable batch size
+	logShippingMaxBatch = 1000
+
+	// logShippingMaxBackoff caps the retry delay after consecutive failures
+	logShippingMaxBackoff = time.Minute
+
+	// logShippingRedacted replaces secrets when a config is read back
+	logShippingRedacted = "********"
+)
+
+// Log shipping destination types
+const (
+	LogShippingWebhook = "webhook"
+	LogShippingS3      = "s3"
+)
+
+// Log shipping states reported in ns.info
+const (
+	LogShippingStateActive  = "active"
+	LogShippingStateFailing = "failing"
+)
+
+// ErrLogShippingDisabled is returned when the server was started without a log shipper
+var ErrLogShippingDisabled = errors.New("log shipping is not enabled")
+
+// LogShippingConfig describes where a namespace's messages are shipped.
+//
+// Webhook destinations receive each batch as an NDJSON POST signed like
+// webhook deliveries (X-Eventodb-Signature). S3 destinations receive each
+// batch as an object named {prefix}{namespace}/{firstGpos}-{lastGpos}.ndjson,
+// so a retried batch overwrites the same object. Records use the export
+// format and can be loaded back with /import.
+type LogShippingConfig struct {
+	Type      string `json:"type"`                // webhook or s3
+	URL       string `json:"url,omitempty"`       // Webhook: delivery URL (POST)
+	Secret    string `json:"secret,omitempty"`    // Webhook: signing secret
+	Bucket    string `json:"bucket,omitempty"`    // S3: bucket name
+	Region    string `json:"region,omitempty"`    // S3: bucket region
+	Prefix    string `json:"prefix,omitempty"`    // S3: object key prefix
+	Endpoint  string `json:"endpoint,omitempty"`  // S3: endpoint (default: https://s3.{region}.amazonaws.com)
+	AccessKey string `json:"accessKey,omitempty"` // S3: access key ID
+	SecretKey string `json:"secretKey,omitempty"` // S3: secret access key
+	BatchSize int    `json:"batchSize,omitempty"` // Messages per batch (default: 500, max: 1000)
+}
+
+// LogShippingStatus reports the progress of a namespace's log shipping
+type LogShippingStatus struct {
+	State         string `json:"state"`                   // active or failing
+	Position      int64  `json:"position"`                // Last shipped global position
+	LastShippedAt string `json:"lastShippedAt,omitempty"` // Time of the last shipped batch
+	LastError     string `json:"lastError,omitempty"`     // Most recent failure, cleared on success
+	LastErrorAt   string `json:"lastErrorAt,omitempty"`
+}
+
+// Validate checks the config and applies defaults
+func (c *LogShippingConfig) Validate() error {
+	switch c.Type {
+	case LogShippingWebhook:
+		if err := validateShippingURL(c.URL); err != nil {
+			return fmt.Errorf("url: %w", err)
+		}
+	case LogShippingS3:
+		if c.Bucket == "" || c.Region == "" {
+			return fmt.Errorf("s3 destinations require bucket and region")
+		}
+		if c.AccessKey == "" || c.SecretKey == "" {
+			return fmt.Errorf("s3 destinations require accessKey and secretKey")
+		}
+		if c.Endpoint == "" {
+			c.Endpoint = "https://s3." + c.Region + ".amazonaws.com"
+		}
+		if err := validateShippingURL(c.Endpoint); err != nil {
+			return fmt.Errorf("endpoint: %w", err)
+		}
+	default:
+		return fmt.Errorf("type must be %q or %q", LogShippingWebhook, LogShippingS3)
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = logShippingDefaultBatch
+	}
+	if c.BatchSize > logShippingMaxBatch {
+		c.BatchSize = logShippingMaxBatch
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with secrets masked
+func (c LogShippingConfig) Redacted() LogShippingConfig {
+	if c.Secret != "" {
+		c.Secret = logShippingRedacted
+	}
+	if c.SecretKey != "" {
+		c.SecretKey = logShippingRedacted
+	}
+	return c
+}
+
+// sameDestination reports whether two configs ship to the same place
+func (c LogShippingConfig) sameDestination(o LogShippingConfig) bool {
+	return c.Type == o.Type && c.URL == o.URL && c.Bucket == o.Bucket &&
+		c.Endpoint == o.Endpoint && c.Prefix == o.Prefix
+}
+
+// validateShippingURL requires an absolute http(s) URL
+func validateShippingURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// LogShippingFromMetadata returns the shipping config and status stored in
+// namespace metadata. Either may be nil.
+func LogShippingFromMetadata(metadata map[string]interface{}) (*LogShippingConfig, *LogShippingStatus) {
+	var cfg *LogShippingConfig
+	if raw, ok := metadata[logShippingMetadataKey]; ok && raw != nil {
+		var c LogShippingConfig
+		if decodeMetadataValue(raw, &c) == nil {
+			cfg = &c
+		}
+	}
+
+	var status *LogShippingStatus
+	if raw, ok := metadata[logShippingStatusMetadataKey]; ok && raw != nil {
+		var s LogShippingStatus
+		if decodeMetadataValue(raw, &s) == nil {
+			status = &s
+		}
+	}
+	return cfg, status
+}
+
+// decodeMetadataValue converts a generic metadata value into a typed struct
+func decodeMetadataValue(raw interface{}, v interface{}) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// encodeMetadataValue converts a typed struct into a generic metadata value
+func encodeMetadataValue(v interface{}) interface{} {
+	data, _ := json.Marshal(v)
+	var out map[string]interface{}
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// LogShipper continuously exports namespaces to destinations configured by
+// their tenants with ns.logShipping.set.
+//
+// Configuration and progress live in namespace metadata, so shipping resumes
+// after a restart from the last shipped position. One worker runs per
+// configured namespace.
+type LogShipper struct {
+	store    store.Store
+	pubsub   *PubSub
+	client   *http.Client
+	notifier *Notifier
+
+	// mu guards workers
+	mu      sync.Mutex
+	workers map[string]*logShipWorker
+	closed  bool
+}
+
+// NewLogShipper creates a log shipper
+func NewLogShipper(st store.Store, pubsub *PubSub) *LogShipper {
+	return &LogShipper{
+		store:   st,
+		pubsub:  pubsub,
+		client:  &http.Client{Timeout: webhookTimeout},
+		workers: make(map[string]*logShipWorker),
+	}
+}
+
+// SetNotifier routes shipping failures to a notifier
+func (s *LogShipper) SetNotifier(n *Notifier) {
+	s.notifier = n
+}
+
+// Start launches a worker for every namespace with shipping configured
+func (s *LogShipper) Start(ctx context.Context) error {
+	namespaces, err := s.store.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ns := range namespaces {
+		cfg, _ := LogShippingFromMetadata(ns.Metadata)
+		if cfg == nil {
+			continue
+		}
+		if err := cfg.Validate(); err != nil {
+			logger.Get().Warn().Err(err).Str("namespace", ns.ID).Msg("Ignoring invalid log shipping config")
+			continue
+		}
+		s.startWorker(ns.ID, *cfg)
+	}
+	return nil
+}
+
+// Configure stores a namespace's shipping config and (re)starts its worker.
+// A nil config disables shipping. Changing the destination restarts shipping
+// from the beginning of the namespace.
+func (s *LogShipper) Configure(ctx context.Context, namespace string, cfg *LogShippingConfig) error {
+	if cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w, ok := s.workers[namespace]; ok {
+		w.halt()
+		delete(s.workers, namespace)
+	}
+
+	err := updateNamespaceMetadata(ctx, s.store, namespace, func(metadata map[string]interface{}) {
+		previous, _ := LogShippingFromMetadata(metadata)
+
+		// Masked secrets echoed back from ns.logShipping.get keep their stored value
+		if cfg != nil && previous != nil {
+			if cfg.Secret == logShippingRedacted {
+				cfg.Secret = previous.Secret
+			}
+			if cfg.SecretKey == logShippingRedacted {
+				cfg.SecretKey = previous.SecretKey
+			}
+		}
+
+		if cfg == nil {
+			delete(metadata, logShippingMetadataKey)
+			delete(metadata, logShippingStatusMetadataKey)
+		} else {
+			metadata[logShippingMetadataKey] = encodeMetadataValue(cfg)
+			if previous == nil || !previous.sameDestination(*cfg) {
+				delete(metadata, logShippingStatusMetadataKey)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg != nil && !s.closed {
+		s.startWorker(namespace, *cfg)
+	}
+	return nil
+}
+
+// Close stops all workers
+func (s *LogShipper) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	workers := s.workers
+	s.workers = make(map[string]*logShipWorker)
+	s.mu.Unlock()
+
+	for _, w := range workers {
+		w.halt()
+	}
+	return nil
+}
+
+// startWorker launches a worker; the caller holds mu
+func (s *LogShipper) startWorker(namespace string, cfg LogShippingConfig) {
+	w := &logShipWorker{
+		shipper:   s,
+		namespace: namespace,
+		cfg:       cfg,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	s.workers[namespace] = w
+	go w.run()
+}
+
+// saveStatus records shipping progress in namespace metadata
+func (s *LogShipper) saveStatus(ctx context.Context, w *logShipWorker, status LogShippingStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A worker that has been replaced must not overwrite its successor's status
+	if s.workers[w.namespace] != w {
+		return nil
+	}
+
+	return updateNamespaceMetadata(ctx, s.store, w.namespace, func(metadata map[string]interface{}) {
+		metadata[logShippingStatusMetadataKey] = encodeMetadataValue(status)
+	})
+}
+
+// logShipWorker ships one namespace
+type logShipWorker struct {
+	shipper   *LogShipper
+	namespace string
+	cfg       LogShippingConfig
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// halt stops the worker and waits for it to exit
+func (w *logShipWorker) halt() {
+	close(w.stop)
+	<-w.done
+}
+
+// run ships the namespace until stopped
+func (w *logShipWorker) run() {
+	defer close(w.done)
+
+	ctx := context.Background()
+	s := w.shipper
+	log := logger.Get().With().Str("namespace", w.namespace).Str("logShipping", w.cfg.Type).Logger()
+
+	var status LogShippingStatus
+	if ns, err := s.store.GetNamespace(ctx, w.namespace); err == nil {
+		if _, saved := LogShippingFromMetadata(ns.Metadata); saved != nil {
+			status = *saved
+		}
+	}
+
+	var events Subscriber
+	if s.pubsub != nil {
+		events = s.pubsub.SubscribeAll(w.namespace)
+		defer s.pubsub.UnsubscribeAll(w.namespace, events)
+	}
+
+	backoff := tailerPollInterval
+	for {
+		err := w.drain(ctx, &status)
+		if err != nil {
+			log.Warn().Err(err).Int64("position", status.Position).Msg("Log shipping failed, will retry")
+			status.State = LogShippingStateFailing
+			status.LastError = err.Error()
+			status.LastErrorAt = time.Now().UTC().Format(time.RFC3339Nano)
+			if err := s.saveStatus(ctx, w, status); err != nil {
+				log.Warn().Err(err).Msg("Failed to record log shipping status")
+			}
+			s.notifier.Notify(SystemEvent{
+				Type:      SystemEventConnectorFailed,
+				Severity:  "error",
+				Namespace: w.namespace,
+				Subject:   "logShipping",
+				Message:   err.Error(),
+				Data:      map[string]interface{}{"position": status.Position},
+			})
+		}
+
+		wait := tailerPollInterval
+		if err != nil {
+			wait = backoff
+			backoff = min(backoff*2, logShippingMaxBackoff)
+		} else {
+			backoff = tailerPollInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-w.stop:
+			timer.Stop()
+			return
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+			}
+			// Keep backing off while the destination is failing
+			if err != nil {
+				<-timer.C
+			}
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// drain ships all messages after the recorded position
+func (w *logShipWorker) drain(ctx context.Context, status *LogShippingStatus) error {
+	s := w.shipper
+	for {
+		msgs, err := s.store.GetCategoryMessages(ctx, w.namespace, "", &store.CategoryOpts{
+			Position:  status.Position + 1,
+			BatchSize: int64(w.cfg.BatchSize),
+		})
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+
+		body, err := encodeShippingBatch(msgs)
+		if err != nil {
+			return err
+		}
+		first, last := msgs[0].GlobalPosition, msgs[len(msgs)-1].GlobalPosition
+		if err := w.ship(ctx, first, last, body); err != nil {
+			return err
+		}
+
+		status.State = LogShippingStateActive
+		status.Position = last
+		status.LastShippedAt = time.Now().UTC().Format(time.RFC3339Nano)
+		status.LastError = ""
+		status.LastErrorAt = ""
+		if err := s.saveStatus(ctx, w, *status); err != nil {
+			return fmt.Errorf("failed to record position: %w", err)
+		}
+
+		select {
+		case <-w.stop:
+			return nil
+		default:
+		}
+	}
+}
+
+// ship sends one batch to the configured destination
+func (w *logShipWorker) ship(ctx context.Context, first, last int64, body []byte) error {
+	var req *http.Request
+	var err error
+
+	switch w.cfg.Type {
+	case LogShippingWebhook:
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		req.Header.Set(WebhookHeaderDelivery, uuid.New().String())
+		if w.cfg.Secret != "" {
+			ts := time.Now().Unix()
+			req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(ts, 10))
+			req.Header.Set(WebhookHeaderSignature, SignWebhook([]string{w.cfg.Secret}, ts, body))
+		}
+
+	case LogShippingS3:
+		key := fmt.Sprintf("%s%s/%020d-%020d.ndjson", w.cfg.Prefix, w.namespace, first, last)
+		target := strings.TrimRight(w.cfg.Endpoint, "/") + "/" + w.cfg.Bucket + "/" + key
+		req, err = http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		signS3Request(req, body, w.cfg.Region, w.cfg.AccessKey, w.cfg.SecretKey, time.Now())
+	}
+
+	resp, err := w.shipper.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("destination responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// encodeShippingBatch renders messages as export-format NDJSON
+func encodeShippingBatch(msgs []*store.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, msg := range msgs {
+		if err := enc.Encode(exportRecord(msg)); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// signS3Request signs a request with AWS Signature Version 4
+func signS3Request(req *http.Request, body []byte, region, accessKey, secretKey string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns HMAC-SHA256(key, data)
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
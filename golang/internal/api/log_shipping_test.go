@@ -0,0 +1,230 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// waitForShippedPosition polls ns.info until the shipped position is reached
+func waitForShippedPosition(t *testing.T, h *RPCHandler, position int64) map[string]interface{} {
+	t.Helper()
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, rpcErr := h.route(ctx, "ns.info", []interface{}{"test-ns"})
+		if rpcErr != nil {
+			t.Fatalf("ns.info failed: %v", rpcErr.Message)
+		}
+		info, _ := result.(map[string]interface{})["logShipping"].(map[string]interface{})
+		if info != nil {
+			status := info["status"].(map[string]interface{})
+			if pos, _ := status["position"].(float64); int64(pos) >= position {
+				return info
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for position %d, last info: %v", position, info)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestLogShipper_Webhook verifies signed NDJSON batches reach a webhook and status is reported
+func TestLogShipper_Webhook(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var records []ExportRecord
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := VerifyWebhook(r.Header.Get(WebhookHeaderSignature), body, "tenant-secret", time.Minute, time.Now()); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			var rec ExportRecord
+			json.Unmarshal(scanner.Bytes(), &rec)
+			records = append(records, rec)
+		}
+	}))
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{
+			StreamName: "order-1",
+			Type:       "Placed",
+			Data:       map[string]interface{}{"n": i},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+
+	shipper := NewLogShipper(st, NewPubSub())
+	defer shipper.Close()
+	h := NewRPCHandler("test", st, NewPubSub())
+	h.SetLogShipper(shipper)
+	rpcCtx := context.WithValue(ctx, ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := h.route(rpcCtx, "ns.logShipping.set", []interface{}{map[string]interface{}{
+		"type":   "webhook",
+		"url":    server.URL,
+		"secret": "tenant-secret",
+	}}); rpcErr != nil {
+		t.Fatalf("ns.logShipping.set failed: %v", rpcErr.Message)
+	}
+
+	// Failures are reported in ns.info while the destination is down
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		result, _ := h.route(rpcCtx, "ns.logShipping.get", nil)
+		status := result.(map[string]interface{})["status"].(map[string]interface{})
+		if status["state"] == LogShippingStateFailing {
+			if !strings.Contains(status["lastError"].(string), "502") {
+				t.Errorf("Expected 502 in lastError, got %v", status["lastError"])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for failing state")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	failing.Store(false)
+	info := waitForShippedPosition(t, h, 3)
+	if info["status"].(map[string]interface{})["state"] != LogShippingStateActive {
+		t.Errorf("Expected active state, got %v", info["status"])
+	}
+	if info["config"].(map[string]interface{})["secret"] != logShippingRedacted {
+		t.Errorf("Expected secret to be masked, got %v", info["config"])
+	}
+
+	mu.Lock()
+	if len(records) != 3 || records[0].Stream != "order-1" || records[2].GPos != 3 {
+		t.Errorf("Unexpected shipped records: %+v", records)
+	}
+	mu.Unlock()
+
+	// Disabling removes config and status
+	if _, rpcErr := h.route(rpcCtx, "ns.logShipping.set", []interface{}{nil}); rpcErr != nil {
+		t.Fatalf("Failed to disable log shipping: %v", rpcErr.Message)
+	}
+	if result, _ := h.route(rpcCtx, "ns.logShipping.get", nil); result != nil {
+		t.Errorf("Expected nil after disabling, got %v", result)
+	}
+}
+
+// TestLogShipper_S3 verifies batches are written as SigV4-signed objects
+func TestLogShipper_S3(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	objects := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if r.Method != http.MethodPut || !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDTEST/") ||
+			!strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		objects[r.URL.Path] = string(body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{
+		StreamName: "order-1",
+		Type:       "Placed",
+		Data:       map[string]interface{}{},
+	}); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+
+	shipper := NewLogShipper(st, NewPubSub())
+	defer shipper.Close()
+	h := NewRPCHandler("test", st, NewPubSub())
+	h.SetLogShipper(shipper)
+
+	if err := shipper.Configure(ctx, "test-ns", &LogShippingConfig{
+		Type:      LogShippingS3,
+		Bucket:    "tenant-bucket",
+		Region:    "eu-west-1",
+		Prefix:    "eventodb/",
+		Endpoint:  server.URL,
+		AccessKey: "AKIDTEST",
+		SecretKey: "secret",
+	}); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+
+	waitForShippedPosition(t, h, 1)
+
+	mu.Lock()
+	defer mu.Unlock()
+	body, ok := objects["/tenant-bucket/eventodb/test-ns/00000000000000000001-00000000000000000001.ndjson"]
+	if !ok {
+		t.Fatalf("Expected object for first batch, got %v", objects)
+	}
+	if !strings.Contains(body, `"stream":"order-1"`) {
+		t.Errorf("Unexpected object body: %s", body)
+	}
+}
+
+// TestLogShippingConfig_Validate verifies destination validation
+func TestLogShippingConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  LogShippingConfig
+	}{
+		{"unknown type", LogShippingConfig{Type: "ftp"}},
+		{"webhook without url", LogShippingConfig{Type: LogShippingWebhook}},
+		{"webhook relative url", LogShippingConfig{Type: LogShippingWebhook, URL: "/hooks"}},
+		{"s3 without bucket", LogShippingConfig{Type: LogShippingS3, Region: "us-east-1", AccessKey: "a", SecretKey: "b"}},
+		{"s3 without credentials", LogShippingConfig{Type: LogShippingS3, Bucket: "b", Region: "us-east-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+
+	cfg := LogShippingConfig{Type: LogShippingS3, Bucket: "b", Region: "us-east-1", AccessKey: "a", SecretKey: "b"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected valid config: %v", err)
+	}
+	if cfg.Endpoint != "https://s3.us-east-1.amazonaws.com" || cfg.BatchSize != logShippingDefaultBatch {
+		t.Errorf("Expected defaults to be applied, got %+v", cfg)
+	}
+}
@@ -0,0 +1,166 @@
+// Package api provides the per-namespace strategy for auto-assigned message IDs.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	// messageIDsMetadataKey holds the ID strategy in namespace metadata
+	messageIDsMetadataKey = "messageIds"
+)
+
+// IDStrategy selects how IDs are generated for messages written without one
+type IDStrategy string
+
+const (
+	// IDStrategyUUIDv7 generates time-ordered UUIDv7s (the default)
+	IDStrategyUUIDv7 IDStrategy = "uuidv7"
+
+	// IDStrategyUUIDv4 generates random UUIDv4s
+	IDStrategyUUIDv4 IDStrategy = "uuidv4"
+
+	// IDStrategyULID generates ULIDs: a 48-bit millisecond timestamp followed
+	// by 80 random bits, monotonic within a millisecond. Message IDs are
+	// stored as UUIDs on every backend, so the 128 bits are written in UUID
+	// form; their Crockford base32 encoding is the canonical ULID.
+	IDStrategyULID IDStrategy = "ulid"
+)
+
+// IDStrategies lists the strategies, default first
+var IDStrategies = []IDStrategy{IDStrategyUUIDv7, IDStrategyUUIDv4, IDStrategyULID}
+
+// ParseIDStrategy parses a strategy name
+func ParseIDStrategy(s string) (IDStrategy, error) {
+	for _, strategy := range IDStrategies {
+		if string(strategy) == s {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown ID strategy %q (uuidv7, uuidv4 or ulid)", s)
+}
+
+// IDStrategyFromMetadata returns the ID strategy stored in namespace metadata,
+// or IDStrategyUUIDv7
+func IDStrategyFromMetadata(metadata map[string]interface{}) IDStrategy {
+	if name, ok := metadata[messageIDsMetadataKey].(string); ok {
+		if strategy, err := ParseIDStrategy(name); err == nil {
+			return strategy
+		}
+	}
+	return IDStrategyUUIDv7
+}
+
+// NewID generates an ID with the strategy
+func (s IDStrategy) NewID() (string, error) {
+	switch s {
+	case IDStrategyUUIDv4:
+		id, err := uuid.NewRandom()
+		return id.String(), err
+	case IDStrategyULID:
+		id, err := ulids.next(time.Now())
+		return id.String(), err
+	default:
+		id, err := uuid.NewV7()
+		return id.String(), err
+	}
+}
+
+// ulidGenerator generates monotonic ULIDs: within a millisecond each ULID is
+// the previous one plus one, so IDs generated by this server sort in order
+var ulids ulidGenerator
+
+type ulidGenerator struct {
+	mu   sync.Mutex
+	ms   uint64
+	last uuid.UUID
+}
+
+// next returns the next ULID for time now
+func (g *ulidGenerator) next(now time.Time) (uuid.UUID, error) {
+	ms := uint64(now.UnixMilli())
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var id uuid.UUID
+	if ms <= g.ms {
+		// Same millisecond, or the clock went back: increment the random part
+		id = g.last
+		for i := len(id) - 1; i >= 6; i-- {
+			id[i]++
+			if id[i] != 0 {
+				g.last = id
+				return id, nil
+			}
+		}
+		// The random part overflowed; move to the next millisecond
+		ms = g.ms + 1
+	}
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(id[:6], ts[2:])
+	if _, err := rand.Read(id[6:]); err != nil {
+		return uuid.UUID{}, err
+	}
+	g.ms, g.last = ms, id
+	return id, nil
+}
+
+// MessageIDs assigns IDs to messages written without one, with the strategy
+// of their namespace. A nil *MessageIDs leaves IDs to the store, which
+// assigns UUIDv7s.
+type MessageIDs struct {
+	meta *NamespaceMetadata
+}
+
+// NewMessageIDs creates an ID strategy cache backed by namespace metadata
+func NewMessageIDs(meta *NamespaceMetadata) *MessageIDs {
+	return &MessageIDs{meta: meta}
+}
+
+// Strategy returns the namespace's ID strategy. Lookup failures return the
+// default so the store reports its own error on the write.
+func (m *MessageIDs) Strategy(ctx context.Context, namespace string) IDStrategy {
+	if m == nil {
+		return IDStrategyUUIDv7
+	}
+
+	strategy, err := m.meta.decode(ctx, namespace, messageIDsMetadataKey, func(metadata map[string]interface{}) interface{} {
+		return IDStrategyFromMetadata(metadata)
+	})
+	if err != nil {
+		return IDStrategyUUIDv7
+	}
+	return strategy.(IDStrategy)
+}
+
+// Assign sets msg.ID with the namespace's strategy if it is empty. If no ID
+// can be generated, msg.ID stays empty and the store assigns one.
+func (m *MessageIDs) Assign(ctx context.Context, namespace string, msg *store.Message) {
+	if m == nil || msg.ID != "" {
+		return
+	}
+	if id, err := m.Strategy(ctx, namespace).NewID(); err == nil {
+		msg.ID = id
+	}
+}
+
+// Set changes a namespace's ID strategy
+func (m *MessageIDs) Set(ctx context.Context, namespace string, strategy IDStrategy) error {
+	return m.meta.Update(ctx, namespace, func(metadata map[string]interface{}) {
+		if strategy == IDStrategyUUIDv7 {
+			delete(metadata, messageIDsMetadataKey)
+			return
+		}
+		metadata[messageIDsMetadataKey] = string(strategy)
+	})
+}
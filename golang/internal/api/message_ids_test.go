@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestMessageIDStrategies tests that writes get IDs of the namespace's
+// strategy and that ULIDs are ordered
+func TestMessageIDStrategies(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	write := func() uuid.UUID {
+		_, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-1", map[string]interface{}{
+			"type": "Placed",
+			"data": map[string]interface{}{},
+		}})
+		if rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr)
+		}
+		msgs, _ := st.GetStreamMessages(ctx, "test-ns", "order-1", nil)
+		return uuid.MustParse(msgs[len(msgs)-1].ID)
+	}
+	strategy := func() interface{} {
+		result, rpcErr := h.route(ctx, "sys.capabilities", nil)
+		if rpcErr != nil {
+			t.Fatalf("sys.capabilities failed: %v", rpcErr)
+		}
+		return result.(map[string]interface{})["messageIds"].(map[string]interface{})["strategy"]
+	}
+
+	if id := write(); id.Version() != 7 || strategy() != "uuidv7" {
+		t.Errorf("Expected UUIDv7 IDs by default, got %s (%v)", id, strategy())
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.messageIds.set", []interface{}{"uuidv4"}); rpcErr != nil {
+		t.Fatalf("ns.messageIds.set failed: %v", rpcErr)
+	}
+	if id := write(); id.Version() != 4 || strategy() != "uuidv4" {
+		t.Errorf("Expected UUIDv4 IDs, got %s (%v)", id, strategy())
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.messageIds.set", []interface{}{"ulid"}); rpcErr != nil {
+		t.Fatalf("ns.messageIds.set failed: %v", rpcErr)
+	}
+	start := time.Now().UnixMilli()
+	first, second := write(), write()
+	var ts [8]byte
+	copy(ts[2:], first[:6])
+	if ms := int64(binary.BigEndian.Uint64(ts[:])); ms < start || ms > time.Now().UnixMilli() {
+		t.Errorf("Expected a ULID with the current time, got %s (%d)", first, ms)
+	}
+	if bytes.Compare(first[:], second[:]) >= 0 {
+		t.Errorf("Expected ULIDs in order, got %s then %s", first, second)
+	}
+	if result, _ := h.route(ctx, "ns.messageIds.get", nil); result.(map[string]interface{})["strategy"] != "ulid" {
+		t.Errorf("Expected the ulid strategy, got %v", result)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.messageIds.set", []interface{}{"snowflake"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an unknown strategy, got %v", rpcErr)
+	}
+
+	// ULIDs generated within a millisecond are monotonic
+	var g ulidGenerator
+	now := time.Now()
+	a, _ := g.next(now)
+	b, _ := g.next(now)
+	if bytes.Compare(a[:], b[:]) >= 0 || !bytes.Equal(a[:6], b[:6]) {
+		t.Errorf("Expected monotonic ULIDs within a millisecond, got %s then %s", a, b)
+	}
+}
+
+// TestMessageIDStrategies_Edges tests invalid arguments, that explicit IDs
+// are kept, that the default strategy is not stored and that ULIDs stay
+// ordered when the clock goes back or the random part overflows
+func TestMessageIDStrategies_Edges(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, args := range [][]interface{}{{}, {float64(4)}, {""}} {
+		if _, rpcErr := h.route(ctx, "ns.messageIds.set", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	missing := context.WithValue(context.Background(), ContextKeyNamespace, "missing-ns")
+	if _, rpcErr := h.route(missing, "ns.messageIds.set", []interface{}{"ulid"}); rpcErr == nil || rpcErr.Code != "NAMESPACE_NOT_FOUND" {
+		t.Errorf("Expected NAMESPACE_NOT_FOUND for a missing namespace, got %v", rpcErr)
+	}
+
+	// Explicit IDs are kept whatever the strategy
+	if _, rpcErr := h.route(ctx, "ns.messageIds.set", []interface{}{"ulid"}); rpcErr != nil {
+		t.Fatalf("ns.messageIds.set failed: %v", rpcErr)
+	}
+	explicit := uuid.NewString()
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-1", map[string]interface{}{"type": "Placed", "data": map[string]interface{}{}}, map[string]interface{}{"id": explicit}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr)
+	}
+	if msg, err := st.GetLastStreamMessage(ctx, "test-ns", "order-1", nil); err != nil || msg.ID != explicit {
+		t.Errorf("Expected the explicit ID %s, got %v (%v)", explicit, msg, err)
+	}
+
+	// Setting the default removes the stored strategy
+	if _, rpcErr := h.route(ctx, "ns.messageIds.set", []interface{}{"uuidv7"}); rpcErr != nil {
+		t.Fatalf("ns.messageIds.set failed: %v", rpcErr)
+	}
+	ns, err := st.GetNamespace(ctx, "test-ns")
+	if err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	if _, ok := ns.Metadata[messageIDsMetadataKey]; ok {
+		t.Errorf("Expected no stored strategy for the default, got %v", ns.Metadata[messageIDsMetadataKey])
+	}
+
+	var g ulidGenerator
+	now := time.Now()
+	a, _ := g.next(now)
+	b, _ := g.next(now.Add(-time.Second))
+	if bytes.Compare(a[:], b[:]) >= 0 {
+		t.Errorf("Expected ULIDs in order when the clock goes back, got %s then %s", a, b)
+	}
+	for i := 6; i < len(g.last); i++ {
+		g.last[i] = 0xff
+	}
+	c, _ := g.next(now)
+	if bytes.Compare(b[:6], c[:6]) >= 0 {
+		t.Errorf("Expected an overflow to move to the next millisecond, got %s then %s", b, c)
+	}
+}
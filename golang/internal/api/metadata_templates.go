@@ -0,0 +1,120 @@
+// Package api provides per-type default metadata merged into writes.
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// metadataTemplatesMetadataKey holds the metadata templates in namespace metadata
+	metadataTemplatesMetadataKey = "metadataTemplates"
+
+	// maxMetadataTemplates bounds the templates kept per namespace
+	maxMetadataTemplates = 64
+
+	// maxMetadataTemplateKeys bounds the keys one template adds
+	maxMetadataTemplateKeys = 32
+)
+
+// MetadataTemplate adds metadata to every message of matching types, such as
+// a schemaVersion for "OrderPlaced" or a producer for all types ("*").
+type MetadataTemplate struct {
+	Type     string                 `json:"type"`               // Message type; '*' matches any run of characters
+	Metadata map[string]interface{} `json:"metadata"`           // Keys added to the message metadata
+	Override bool                   `json:"override,omitempty"` // Replace values set by the writer
+}
+
+// MetadataTemplatesConfig holds a namespace's metadata templates. Every
+// template matching a message applies, in order.
+type MetadataTemplatesConfig struct {
+	Templates []MetadataTemplate `json:"templates"`
+}
+
+// Validate checks the templates
+func (c *MetadataTemplatesConfig) Validate() error {
+	if len(c.Templates) > maxMetadataTemplates {
+		return fmt.Errorf("at most %d metadata templates are allowed", maxMetadataTemplates)
+	}
+	for i, tmpl := range c.Templates {
+		if tmpl.Type == "" {
+			return fmt.Errorf("template %d: type must not be empty", i)
+		}
+		if len(tmpl.Metadata) == 0 || len(tmpl.Metadata) > maxMetadataTemplateKeys {
+			return fmt.Errorf("template %d: metadata needs 1 to %d keys", i, maxMetadataTemplateKeys)
+		}
+		if _, ok := tmpl.Metadata[""]; ok {
+			return fmt.Errorf("template %d: metadata keys must not be empty", i)
+		}
+	}
+	return nil
+}
+
+// MetadataTemplatesFromMetadata returns the metadata templates stored in namespace metadata (never nil)
+func MetadataTemplatesFromMetadata(metadata map[string]interface{}) *MetadataTemplatesConfig {
+	cfg := &MetadataTemplatesConfig{}
+	if raw, ok := metadata[metadataTemplatesMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, cfg)
+	}
+	return cfg
+}
+
+// Apply merges the templates matching msg.Type into msg.Metadata. Keys the
+// writer or an earlier template set are kept, unless a template overrides
+// them.
+func (c *MetadataTemplatesConfig) Apply(msg *store.Message) {
+	for _, tmpl := range c.Templates {
+		if !globMatch(tmpl.Type, msg.Type) {
+			continue
+		}
+		if msg.Metadata == nil {
+			msg.Metadata = make(map[string]interface{}, len(tmpl.Metadata))
+		}
+		for k, v := range tmpl.Metadata {
+			if _, exists := msg.Metadata[k]; !exists || tmpl.Override {
+				msg.Metadata[k] = v
+			}
+		}
+	}
+}
+
+// MetadataTemplates applies the metadata templates of namespaces to writes.
+// A nil *MetadataTemplates adds nothing.
+type MetadataTemplates struct {
+	meta *NamespaceMetadata
+}
+
+// NewMetadataTemplates creates a template cache backed by namespace metadata
+func NewMetadataTemplates(meta *NamespaceMetadata) *MetadataTemplates {
+	return &MetadataTemplates{meta: meta}
+}
+
+// Apply merges the namespace's templates matching msg.Type into
+// msg.Metadata. Lookup failures add nothing so the store reports its own
+// error on the write.
+func (m *MetadataTemplates) Apply(ctx context.Context, namespace string, msg *store.Message) {
+	if m == nil {
+		return
+	}
+
+	cfg, err := m.meta.decode(ctx, namespace, metadataTemplatesMetadataKey, func(metadata map[string]interface{}) interface{} {
+		return MetadataTemplatesFromMetadata(metadata)
+	})
+	if err != nil {
+		return
+	}
+	cfg.(*MetadataTemplatesConfig).Apply(msg)
+}
+
+// Set replaces a namespace's metadata templates; no templates removes them
+func (m *MetadataTemplates) Set(ctx context.Context, namespace string, cfg *MetadataTemplatesConfig) error {
+	return m.meta.Update(ctx, namespace, func(metadata map[string]interface{}) {
+		if cfg == nil || len(cfg.Templates) == 0 {
+			delete(metadata, metadataTemplatesMetadataKey)
+			return
+		}
+		metadata[metadataTemplatesMetadataKey] = encodeMetadataValue(cfg)
+	})
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestMetadataTemplates tests that templates set over RPC merge default
+// metadata into writes of matching types
+func TestMetadataTemplates(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, tmpl := range []map[string]interface{}{
+		{"type": "", "metadata": map[string]interface{}{"producer": "billing"}},
+		{"type": "*", "metadata": map[string]interface{}{}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.metadataTemplates.set", []interface{}{map[string]interface{}{
+			"templates": []interface{}{tmpl},
+		}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", tmpl, rpcErr)
+		}
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.metadataTemplates.set", []interface{}{map[string]interface{}{
+		"templates": []interface{}{
+			map[string]interface{}{"type": "Order*", "metadata": map[string]interface{}{"schemaVersion": 2.0}},
+			map[string]interface{}{"type": "*", "metadata": map[string]interface{}{"producer": "billing", "schemaVersion": 1.0}},
+			map[string]interface{}{"type": "*", "metadata": map[string]interface{}{"tenant": "acme"}, "override": true},
+		},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.metadataTemplates.set failed: %v", rpcErr.Message)
+	}
+	result, rpcErr := h.route(ctx, "ns.metadataTemplates.get", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.metadataTemplates.get failed: %v", rpcErr.Message)
+	}
+	if templates := result.(map[string]interface{})["templates"].([]interface{}); len(templates) != 3 {
+		t.Errorf("Expected 3 templates, got %v", templates)
+	}
+
+	for _, w := range []struct {
+		msgType  string
+		metadata map[string]interface{}
+	}{
+		{"OrderPlaced", map[string]interface{}{"producer": "checkout", "tenant": "other"}},
+		{"Shipped", nil},
+	} {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-1", map[string]interface{}{
+			"type":     w.msgType,
+			"data":     map[string]interface{}{},
+			"metadata": w.metadata,
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "order-1", nil)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d (%v)", len(msgs), err)
+	}
+	if md := msgs[0].Metadata; md["producer"] != "checkout" || md["schemaVersion"] != 2.0 || md["tenant"] != "acme" {
+		t.Errorf("Expected the writer's producer, the first matching schemaVersion and the overridden tenant, got %v", md)
+	}
+	if md := msgs[1].Metadata; md["producer"] != "billing" || md["schemaVersion"] != 1.0 || md["tenant"] != "acme" {
+		t.Errorf("Expected all defaults, got %v", md)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.metadataTemplates.set", []interface{}{nil}); rpcErr != nil {
+		t.Fatalf("ns.metadataTemplates.set null failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-1", map[string]interface{}{
+		"type": "Shipped",
+		"data": map[string]interface{}{},
+	}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	msgs, _ = st.GetStreamMessages(ctx, "test-ns", "order-1", &store.GetOpts{Position: 2})
+	if len(msgs) != 1 || len(msgs[0].Metadata) != 0 {
+		t.Errorf("Expected no metadata after removing the templates, got %v", msgs)
+	}
+}
+
+// TestMetadataTemplates_Errors tests invalid configs and the template limits,
+// and that non-matching types and templates without an override leave
+// writer metadata alone
+func TestMetadataTemplates_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	tooMany := make([]interface{}, maxMetadataTemplates+1)
+	for i := range tooMany {
+		tooMany[i] = map[string]interface{}{"type": "*", "metadata": map[string]interface{}{"k": 1.0}}
+	}
+	tooManyKeys := make(map[string]interface{}, maxMetadataTemplateKeys+1)
+	for i := 0; i <= maxMetadataTemplateKeys; i++ {
+		tooManyKeys[fmt.Sprintf("k%d", i)] = 1.0
+	}
+	for _, args := range [][]interface{}{
+		{},
+		{"templates"},
+		{map[string]interface{}{"templates": "*"}},
+		{map[string]interface{}{"templates": tooMany}},
+		{map[string]interface{}{"templates": []interface{}{map[string]interface{}{"type": "*", "metadata": tooManyKeys}}}},
+		{map[string]interface{}{"templates": []interface{}{map[string]interface{}{"type": "*", "metadata": map[string]interface{}{"": 1.0}}}}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.metadataTemplates.set", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	result, rpcErr := h.route(ctx, "ns.metadataTemplates.get", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.metadataTemplates.get failed: %v", rpcErr.Message)
+	}
+	if templates := result.(map[string]interface{})["templates"].([]interface{}); len(templates) != 0 {
+		t.Errorf("Expected no templates after rejected configs, got %v", templates)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.metadataTemplates.set", []interface{}{map[string]interface{}{
+		"templates": []interface{}{map[string]interface{}{"type": "Order*", "metadata": map[string]interface{}{"schemaVersion": 2.0}}},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.metadataTemplates.set failed: %v", rpcErr.Message)
+	}
+	for _, w := range []struct {
+		msgType  string
+		metadata map[string]interface{}
+	}{
+		{"Shipped", nil},
+		{"OrderPlaced", map[string]interface{}{"schemaVersion": 3.0}},
+	} {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-1", map[string]interface{}{
+			"type":     w.msgType,
+			"data":     map[string]interface{}{},
+			"metadata": w.metadata,
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "order-1", nil)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d (%v)", len(msgs), err)
+	}
+	if md := msgs[0].Metadata; len(md) != 0 {
+		t.Errorf("Expected no metadata for a type no template matches, got %v", md)
+	}
+	if md := msgs[1].Metadata; md["schemaVersion"] != 3.0 {
+		t.Errorf("Expected the writer's schemaVersion to be kept, got %v", md)
+	}
+}
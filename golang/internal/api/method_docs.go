@@ -0,0 +1,521 @@
+// Package api provides the RPC method registry behind sys.describe and /schema.
+package api
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/valyala/fasthttp"
+)
+
+// MethodArg describes a positional argument of an RPC method
+type MethodArg struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+}
+
+// MethodOption describes a key of a method's options argument
+type MethodOption struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// MethodDoc documents one RPC method: its positional arguments, the keys of
+// its options object and example requests
+type MethodDoc struct {
+	Method   string            `json:"method"`
+	Summary  string            `json:"summary"`
+	Args     []MethodArg       `json:"args"`
+	Options  []MethodOption    `json:"options,omitempty"`
+	Examples []json.RawMessage `json:"examples"`
+	Admin    bool              `json:"admin"` // Served only on the admin listener when one is configured
+}
+
+// Argument shorthands shared by many methods
+var (
+	argStream    = MethodArg{Name: "streamName", Type: "string", Required: true, Description: "Stream name, e.g. account-123"}
+	argNamespace = MethodArg{Name: "namespace", Type: "string", Required: true, Description: "Namespace ID"}
+	argOptions   = MethodArg{Name: "options", Type: "object", Description: "Options, see below"}
+)
+
+// Option shorthands shared by the read methods
+var (
+	optDecode   = MethodOption{Name: "decode", Type: "boolean", Description: "Decode payloads with the namespace's read plugins"}
+	optPriority = MethodOption{Name: "priority", Type: "string", Description: "low, normal (default) or high read priority"}
+	optEnvelope = MethodOption{Name: "envelope", Type: "boolean", Description: "Return {items, nextCursor, hasMore, count}"}
+)
+
+// config returns the argument of the *.set methods taking a config object
+func config(description string) MethodArg {
+	return MethodArg{Name: "config", Type: "object|null", Required: true, Description: description}
+}
+
+// examples converts JSON requests into raw messages
+func examples(requests ...string) []json.RawMessage {
+	out := make([]json.RawMessage, len(requests))
+	for i, r := range requests {
+		out[i] = json.RawMessage(r)
+	}
+	return out
+}
+
+// methodDocs documents every registered RPC method; a test keeps it in step
+// with NewRPCHandler
+var methodDocs = []MethodDoc{
+	// System methods
+	{Method: "sys.version", Summary: "Return the server version.", Args: []MethodArg{},
+		Examples: examples(`["sys.version"]`)},
+	{Method: "sys.health", Summary: "Return server health; deep mode probes the store, pub/sub and disk.", Args: []MethodArg{argOptions},
+		Options:  []MethodOption{{Name: "deep", Type: "boolean", Description: "Run the store, pub/sub and disk checks (default namespace token only)"}},
+		Examples: examples(`["sys.health"]`, `["sys.health", {"deep": true}]`)},
+	{Method: "sys.parseStreamName", Summary: "Break a stream name into its category, ID, cardinal ID and compound IDs.", Args: []MethodArg{argStream},
+		Examples: examples(`["sys.parseStreamName", "account-123+456"]`)},
+	{Method: "sys.capabilities", Summary: "Describe server behavior clients reproduce, such as consumer group partitioning and message ID strategies.", Args: []MethodArg{},
+		Examples: examples(`["sys.capabilities"]`)},
+	{Method: "sys.profile", Summary: "Capture a runtime profile (default namespace token only).", Args: []MethodArg{argOptions},
+		Options: []MethodOption{
+			{Name: "type", Type: "string", Description: "cpu, heap, goroutine, allocs, block or mutex"},
+			{Name: "seconds", Type: "number", Description: "CPU profile duration"},
+			{Name: "debug", Type: "number", Description: "Text format level for non-CPU profiles"},
+		},
+		Examples: examples(`["sys.profile", {"type": "cpu", "seconds": 10}]`)},
+	{Method: "sys.stats", Summary: "Report rolling RPC latency percentiles and write rate limits with their usage.", Args: []MethodArg{},
+		Examples: examples(`["sys.stats"]`)},
+	{Method: "sys.jobs", Summary: "Report the background job scheduler's queues and recent jobs.", Args: []MethodArg{},
+		Examples: examples(`["sys.jobs"]`)},
+	{Method: "sys.jobs.list", Summary: "List background jobs with their progress and events, newest first.", Args: []MethodArg{argOptions},
+		Options: []MethodOption{
+			{Name: "namespace", Type: "string", Description: "Only jobs of this namespace (default namespace token only)"},
+			{Name: "kind", Type: "string", Description: "retention, snapshot, export or webhook"},
+			{Name: "state", Type: "string", Description: "queued, running, succeeded, failed or canceled"},
+			{Name: "limit", Type: "number", Description: "Maximum jobs returned (default: 100)"},
+		},
+		Examples: examples(`["sys.jobs.list"]`, `["sys.jobs.list", {"state": "failed"}]`)},
+	{Method: "sys.jobs.cancel", Summary: "Cancel a queued or running background job.", Args: []MethodArg{
+		{Name: "id", Type: "number", Required: true, Description: "Job ID"},
+	},
+		Examples: examples(`["sys.jobs.cancel", 910]`)},
+	{Method: "sys.jobs.retry", Summary: "Queue a failed or canceled background job again as a new job.", Args: []MethodArg{
+		{Name: "id", Type: "number", Required: true, Description: "Job ID"},
+	},
+		Examples: examples(`["sys.jobs.retry", 905]`)},
+	{Method: "sys.describe", Summary: "Describe an RPC method's arguments, options and examples, or list all methods.", Args: []MethodArg{
+		{Name: "method", Type: "string", Description: "Method to describe; omit to list all methods"},
+	},
+		Examples: examples(`["sys.describe", "stream.write"]`, `["sys.describe"]`)},
+
+	// Auth methods
+	{Method: "auth.whoami", Summary: "Describe the presented token: namespace, scopes, label, expiry and namespace state.", Args: []MethodArg{},
+		Examples: examples(`["auth.whoami"]`)},
+
+	// Stream methods
+	{Method: "stream.write", Summary: "Write a message to a stream.", Args: []MethodArg{
+		argStream,
+		{Name: "message", Type: "object", Required: true, Description: "{type, data, metadata}"},
+		argOptions,
+	},
+		Options: []MethodOption{
+			{Name: "id", Type: "string", Description: "Message ID (generated with the namespace's ID strategy if omitted)"},
+			{Name: "expectedVersion", Type: "number", Description: "Expected stream version for optimistic locking (-1: stream must not exist)"},
+		},
+		Examples: examples(`["stream.write", "account-123", {"type": "Deposited", "data": {"amount": 100}}]`,
+			`["stream.write", "account-123", {"type": "Withdrawn", "data": {"amount": 50}}, {"expectedVersion": 5}]`)},
+	{Method: "stream.writeBatch", Summary: "Append several messages to a stream in one transaction with one expectedVersion check.", Args: []MethodArg{
+		argStream,
+		{Name: "messages", Type: "array", Required: true, Description: "[{type, data, metadata, id}], at most 1000"},
+		argOptions,
+	},
+		Options: []MethodOption{
+			{Name: "expectedVersion", Type: "number", Description: "Expected stream version before the first message (-1: stream must not exist)"},
+		},
+		Examples: examples(`["stream.writeBatch", "account-123", [{"type": "Withdrawn", "data": {"amount": 50}}, {"type": "FeeCharged", "data": {"amount": 1}}], {"expectedVersion": 5}]`)},
+	{Method: "stream.casWrite", Summary: "Append a message only if the stream's last message satisfies a condition, checked atomically.", Args: []MethodArg{
+		argStream,
+		{Name: "condition", Type: "object|null", Required: true, Description: "{exists, type, where} checked on the last message; null matches any stream"},
+		{Name: "message", Type: "object", Required: true, Description: "{type, data, metadata}"},
+		argOptions,
+	},
+		Options: []MethodOption{
+			{Name: "id", Type: "string", Description: "Message ID (generated with the namespace's ID strategy if omitted)"},
+		},
+		Examples: examples(`["stream.casWrite", "seat-12A", {"type": ["Released", "Expired"]}, {"type": "Reserved", "data": {"by": "order-7"}}]`,
+			`["stream.casWrite", "username-alice", {"exists": false}, {"type": "Claimed", "data": {"userId": "42"}}]`)},
+	{Method: "stream.get", Summary: "Read messages from a stream.", Args: []MethodArg{argStream, argOptions},
+		Options: []MethodOption{
+			{Name: "position", Type: "number", Description: "Starting stream position, inclusive (default 0)"},
+			{Name: "globalPosition", Type: "number|string", Description: "Starting global position, or a bookmark name"},
+			{Name: "batchSize", Type: "number", Description: "Maximum messages returned (default 1000, -1 for unlimited, streamed)"},
+			optEnvelope, optDecode, optPriority,
+		},
+		Examples: examples(`["stream.get", "account-123", {"position": 0, "batchSize": 100}]`)},
+	{Method: "stream.last", Summary: "Read the last message of a stream, or null.", Args: []MethodArg{argStream, argOptions},
+		Options: []MethodOption{
+			{Name: "type", Type: "string", Description: "Last message of this type"},
+			optDecode, optPriority,
+		},
+		Examples: examples(`["stream.last", "account-123"]`, `["stream.last", "account-123", {"type": "Deposited"}]`)},
+	{Method: "stream.version", Summary: "Return the version of a stream, or null if it does not exist.", Args: []MethodArg{argStream},
+		Examples: examples(`["stream.version", "account-123"]`)},
+	{Method: "stream.info", Summary: "Summarize a stream: version, message count, time range, size and types.", Args: []MethodArg{argStream},
+		Examples: examples(`["stream.info", "account-123"]`)},
+	{Method: "stream.claim", Summary: "Return the state of a write queued while the backend was unavailable.", Args: []MethodArg{
+		{Name: "claim", Type: "string", Required: true, Description: "Claim returned by a queued stream.write"},
+	},
+		Examples: examples(`["stream.claim", "01941b2c-7f3a-7c4e-9a1b-2c3d4e5f6a7b"]`)},
+	{Method: "stream.merge", Summary: "Move the messages of case or whitespace variants of a stream into it.", Args: []MethodArg{
+		argStream,
+		{Name: "sources", Type: "array", Required: true, Description: "Variant stream names to merge"},
+		argOptions,
+	},
+		Options:  []MethodOption{{Name: "reason", Type: "string", Description: "Reason recorded in the audit stream"}},
+		Examples: examples(`["stream.merge", "account-123", ["Account-123", "account-123 "], {"reason": "Mixed-case IDs"}]`)},
+	{Method: "stream.rename", Summary: "Move a stream's messages to a new name and keep the old name as an alias.", Args: []MethodArg{
+		argStream,
+		{Name: "newName", Type: "string", Required: true, Description: "New stream name"},
+	},
+		Examples: examples(`["stream.rename", "customer-123", "client-123"]`)},
+	{Method: "entity.load", Summary: "Return a stream's latest snapshot and the events after it in one call.", Args: []MethodArg{argStream, argOptions},
+		Options: []MethodOption{
+			{Name: "snapshotStream", Type: "string", Description: "Snapshot stream (default {category}:snapshot-{id})"},
+			{Name: "batchSize", Type: "number", Description: "Maximum events after the snapshot (default 1000)"},
+			optDecode, optPriority,
+		},
+		Examples: examples(`["entity.load", "account-123", {"batchSize": 1000}]`)},
+
+	// Document methods
+	{Method: "doc.put", Summary: "Store a read-model document, skipped when it already reflects globalPosition.", Args: []MethodArg{
+		{Name: "collection", Type: "string", Required: true, Description: "Collection name"},
+		{Name: "id", Type: "string", Required: true, Description: "Document ID"},
+		{Name: "doc", Type: "object", Required: true, Description: "Document"},
+		argOptions,
+	},
+		Options: []MethodOption{
+			{Name: "globalPosition", Type: "number", Description: "Global position of the event the document reflects"},
+			{Name: "expectedVersion", Type: "number", Description: "Expected document version"},
+		},
+		Examples: examples(`["doc.put", "accountSummary", "123", {"balance": 80}, {"globalPosition": 1301}]`)},
+	{Method: "doc.get", Summary: "Return a document.", Args: []MethodArg{
+		{Name: "collection", Type: "string", Required: true, Description: "Collection name"},
+		{Name: "id", Type: "string", Required: true, Description: "Document ID"},
+	},
+		Examples: examples(`["doc.get", "accountSummary", "123"]`)},
+	{Method: "doc.delete", Summary: "Delete a document.", Args: []MethodArg{
+		{Name: "collection", Type: "string", Required: true, Description: "Collection name"},
+		{Name: "id", Type: "string", Required: true, Description: "Document ID"},
+		argOptions,
+	},
+		Options: []MethodOption{
+			{Name: "globalPosition", Type: "number", Description: "Global position of the event the delete reflects"},
+			{Name: "expectedVersion", Type: "number", Description: "Expected document version"},
+		},
+		Examples: examples(`["doc.delete", "accountSummary", "123", {"globalPosition": 1350}]`)},
+	{Method: "doc.write", Summary: "Write document changes and messages in one transaction.", Args: []MethodArg{
+		{Name: "batch", Type: "object", Required: true, Description: "{docs: [{collection, id, doc, expectedVersion}], messages: [{stream, type, data, metadata, id, expectedVersion}], globalPosition}"},
+	},
+		Examples: examples(`["doc.write", {"docs": [{"collection": "accountSummary", "id": "123", "doc": {"balance": 80}}], "globalPosition": 1301}]`)},
+	{Method: "doc.position", Summary: "Return the last global position applied to a collection, or null.", Args: []MethodArg{
+		{Name: "collection", Type: "string", Required: true, Description: "Collection name"},
+	},
+		Examples: examples(`["doc.position", "accountSummary"]`)},
+
+	{Method: "message.redact", Summary: "Replace the data of one message with a tombstone recording the reason.", Args: []MethodArg{
+		argStream,
+		{Name: "position", Type: "number", Required: true, Description: "Stream position of the message"},
+		argOptions,
+	},
+		Options: []MethodOption{
+			{Name: "reason", Type: "string", Description: "Reason recorded in the tombstone (required)"},
+			{Name: "metadata", Type: "boolean", Description: "Also redact the metadata"},
+		},
+		Examples: examples(`["message.redact", "customer-123", 4, {"reason": "Card number written by mistake"}]`)},
+	{Method: "edge.push", Summary: "Append messages from an edge server, skipping IDs the hub already has.", Args: []MethodArg{
+		{Name: "messages", Type: "array", Required: true, Description: "[{id, stream, type, data, metadata}]"},
+	},
+		Examples: examples(`["edge.push", [{"id": "uuid-1", "stream": "storeOrder-42", "type": "Placed", "data": {"total": 12}, "metadata": null}]]`)},
+
+	// Category methods
+	{Method: "category.get", Summary: "Read messages from all streams in a category.", Args: []MethodArg{
+		{Name: "categoryName", Type: "string", Required: true, Description: "Category, e.g. account, or a category view"},
+		argOptions,
+	},
+		Options: []MethodOption{
+			{Name: "position", Type: "number|string", Description: "Starting global position, or a bookmark name (default 0)"},
+			{Name: "globalPosition", Type: "number|string", Description: "Alternative to position"},
+			{Name: "batchSize", Type: "number", Description: "Maximum messages returned (default 1000)"},
+			{Name: "correlation", Type: "string", Description: "Only messages correlated with this category"},
+			{Name: "where", Type: "string", Description: "EQL expression messages must match"},
+			{Name: "sample", Type: "number", Description: "Fraction of messages returned, in (0, 1]"},
+			{Name: "consumerGroup", Type: "object", Description: "{member, size, partitioner, orderingKey}"},
+			{Name: "cursor", Type: "string", Description: "Keyset cursor, \"\" to start"},
+			{Name: "waitForGaps", Type: "number", Description: "Milliseconds to wait for in-flight gaps among the messages"},
+			optEnvelope, optDecode, optPriority,
+		},
+		Examples: examples(`["category.get", "account", {"position": 0, "batchSize": 100}]`,
+			`["category.get", "account", {"consumerGroup": {"member": 0, "size": 4}}]`)},
+	{Method: "category.gaps", Summary: "List global positions holding no message and whether writes may still commit at them.", Args: []MethodArg{
+		{Name: "categoryName", Type: "string", Required: true, Description: "Category"},
+		argOptions,
+	},
+		Options: []MethodOption{
+			{Name: "position", Type: "number|string", Description: "First global position, or a bookmark name (default 1)"},
+			{Name: "to", Type: "number", Description: "Last global position searched"},
+		},
+		Examples: examples(`["category.gaps", "account", {"position": 1001, "to": 1100}]`)},
+	{Method: "viewCategory.create", Summary: "Define a virtual category that reads as the union of categories.", Args: []MethodArg{
+		{Name: "name", Type: "string", Required: true, Description: "View name"},
+		{Name: "categories", Type: "array", Required: true, Description: "Categories in the view"},
+	},
+		Examples: examples(`["viewCategory.create", "sales", ["order", "invoice"]]`)},
+	{Method: "viewCategory.delete", Summary: "Remove a category view.", Args: []MethodArg{
+		{Name: "name", Type: "string", Required: true, Description: "View name"},
+	},
+		Examples: examples(`["viewCategory.delete", "sales"]`)},
+	{Method: "viewCategory.list", Summary: "List the namespace's category views.", Args: []MethodArg{},
+		Examples: examples(`["viewCategory.list"]`)},
+
+	// Namespace methods
+	{Method: "ns.create", Summary: "Create a namespace and return its token.", Args: []MethodArg{argNamespace, argOptions},
+		Options: []MethodOption{
+			{Name: "description", Type: "string", Description: "Namespace description"},
+			{Name: "token", Type: "string", Description: "Token to use instead of a generated one"},
+			{Name: "label", Type: "string", Description: "Token label"},
+			{Name: "expiresAt", Type: "string", Description: "Token expiry, RFC 3339"},
+			{Name: "shard", Type: "string", Description: "Shard to place the namespace on"},
+			{Name: "template", Type: "string", Description: "Blueprint to provision the namespace from"},
+		},
+		Examples: examples(`["ns.create", "tenant-a", {"description": "Tenant A"}]`)},
+	{Method: "ns.delete", Summary: "Delete a namespace and all its messages.", Args: []MethodArg{argNamespace, argOptions},
+		Options:  []MethodOption{{Name: "dryRun", Type: "boolean", Description: "Report what would be deleted without deleting"}},
+		Examples: examples(`["ns.delete", "tenant-a"]`, `["ns.delete", "tenant-a", {"dryRun": true}]`)},
+	{Method: "ns.list", Summary: "List namespaces.", Args: []MethodArg{},
+		Examples: examples(`["ns.list"]`)},
+	{Method: "ns.info", Summary: "Return a namespace's description, size and state.", Args: []MethodArg{argNamespace},
+		Examples: examples(`["ns.info", "tenant-a"]`)},
+	{Method: "ns.rotateToken", Summary: "Replace a namespace's token; the old one stops working.", Args: []MethodArg{argNamespace, argOptions},
+		Options: []MethodOption{
+			{Name: "token", Type: "string", Description: "Token to use instead of a generated one"},
+			{Name: "label", Type: "string", Description: "Token label"},
+			{Name: "expiresAt", Type: "string", Description: "Token expiry, RFC 3339"},
+		},
+		Examples: examples(`["ns.rotateToken", "tenant-a"]`)},
+	{Method: "ns.streams", Summary: "List the namespace's streams with their activity.", Args: []MethodArg{argOptions},
+		Options: []MethodOption{
+			{Name: "prefix", Type: "string", Description: "Only streams starting with this string"},
+			{Name: "limit", Type: "number", Description: "Maximum streams returned (default 100, max 1000)"},
+			{Name: "cursor", Type: "string", Description: "Return streams after this name"},
+			{Name: "sort", Type: "string", Description: "name, hottest or stalest"},
+			{Name: "inactiveSince", Type: "string", Description: "Only streams not written since this time or duration ago"},
+			{Name: "olderThan", Type: "string", Description: "Only streams first written before this time or duration ago"},
+		},
+		Examples: examples(`["ns.streams", {"prefix": "account", "limit": 100}]`)},
+	{Method: "ns.duplicateStreams", Summary: "List streams whose names differ only by case or whitespace.", Args: []MethodArg{argOptions},
+		Options:  []MethodOption{{Name: "prefix", Type: "string", Description: "Only streams starting with this string"}},
+		Examples: examples(`["ns.duplicateStreams", {"prefix": "account"}]`)},
+	{Method: "ns.categories", Summary: "List the namespace's categories with stream and message counts.", Args: []MethodArg{},
+		Examples: examples(`["ns.categories"]`)},
+	{Method: "ns.typeStats", Summary: "Count the namespace's messages by type, with when each type was first and last written.", Args: []MethodArg{},
+		Examples: examples(`["ns.typeStats"]`)},
+	{Method: "ns.logShipping.set", Summary: "Configure continuous export of the namespace to a tenant-owned destination.", Args: []MethodArg{config("LogShippingConfig, or null to disable")},
+		Examples: examples(`["ns.logShipping.set", {"type": "s3", "bucket": "acme-eventodb", "region": "eu-west-1", "accessKey": "AKIA...", "secretKey": "..."}]`)},
+	{Method: "ns.logShipping.get", Summary: "Return the log shipping config (secrets masked) and status, or null.", Args: []MethodArg{},
+		Examples: examples(`["ns.logShipping.get"]`)},
+	{Method: "ns.exportSchedule.set", Summary: "Export the namespace on a cron schedule to a server directory or S3.", Args: []MethodArg{config("ExportScheduleConfig {cron, type, keep, ...}, or null to disable")},
+		Examples: examples(`["ns.exportSchedule.set", {"cron": "30 2 * * *", "type": "dir", "keep": 14}]`)},
+	{Method: "ns.exportSchedule.get", Summary: "Return the export schedule (secrets masked) and status, or null.", Args: []MethodArg{},
+		Examples: examples(`["ns.exportSchedule.get"]`)},
+	{Method: "ns.exportSchedule.run", Summary: "Export the namespace now to its scheduled destination.", Args: []MethodArg{},
+		Examples: examples(`["ns.exportSchedule.run"]`)},
+	{Method: "ns.backups.list", Summary: "List the namespace's completed backups, newest first.", Args: []MethodArg{argOptions},
+		Options:  []MethodOption{{Name: "limit", Type: "number", Description: "Maximum backups returned"}},
+		Examples: examples(`["ns.backups.list", {"limit": 10}]`)},
+	{Method: "ns.backups.restore", Summary: "Create a namespace from one of the namespace's backups.", Args: []MethodArg{
+		{Name: "backupId", Type: "string", Required: true, Description: "Backup ID from ns.backups.list"},
+		{Name: "newNamespace", Type: "string", Required: true, Description: "Namespace to create"},
+		argOptions,
+	},
+		Options:  []MethodOption{{Name: "description", Type: "string", Description: "Description of the new namespace"}},
+		Examples: examples(`["ns.backups.restore", "20250115T023000Z", "tenant-a-restored"]`)},
+	{Method: "ns.mirror.set", Summary: "Configure asynchronous mirroring of the namespace to a remote server.", Args: []MethodArg{config("MirrorConfig {url, token}, or null to stop")},
+		Examples: examples(`["ns.mirror.set", {"url": "https://eu.eventodb.example.com", "token": "ns_..."}]`)},
+	{Method: "ns.mirror.status", Summary: "Return the mirror config (token masked) and progress.", Args: []MethodArg{},
+		Examples: examples(`["ns.mirror.status"]`)},
+	{Method: "ns.mirror.promote", Summary: "Make a mirror namespace accept its own writes.", Args: []MethodArg{},
+		Examples: examples(`["ns.mirror.promote"]`)},
+	{Method: "ns.edgeSync.set", Summary: "Configure the namespace as an edge syncing categories with a hub.", Args: []MethodArg{config("EdgeSyncConfig {url, token, push, pull}, or null to stop")},
+		Examples: examples(`["ns.edgeSync.set", {"url": "https://hub.example.com", "token": "ns_...", "push": ["storeOrder"], "pull": ["catalog"]}]`)},
+	{Method: "ns.edgeSync.status", Summary: "Return the edge sync config (token masked) and progress.", Args: []MethodArg{},
+		Examples: examples(`["ns.edgeSync.status"]`)},
+	{Method: "ns.worm.enable", Summary: "Put the namespace in WORM mode for good.", Args: []MethodArg{},
+		Examples: examples(`["ns.worm.enable"]`)},
+	{Method: "ns.worm.status", Summary: "Return whether the namespace is in WORM mode and its latest attestation.", Args: []MethodArg{},
+		Examples: examples(`["ns.worm.status"]`)},
+	{Method: "ns.worm.attest", Summary: "Sign the current head hash of a WORM namespace.", Args: []MethodArg{},
+		Examples: examples(`["ns.worm.attest"]`)},
+	{Method: "ns.config.export", Summary: "Return the namespace configuration as a NamespaceConfig object.", Args: []MethodArg{},
+		Examples: examples(`["ns.config.export"]`)},
+	{Method: "ns.config.import", Summary: "Replace the namespace configuration.", Args: []MethodArg{config("NamespaceConfig from ns.config.export")},
+		Examples: examples(`["ns.config.import", {"version": 1, "namespace": "tenant-a", "metadata": {}}]`)},
+	{Method: "import.commit", Summary: "Activate the staged import, replacing the namespace's messages.", Args: []MethodArg{argOptions},
+		Options:  []MethodOption{{Name: "expectedCount", Type: "number", Description: "Messages the staging area must hold"}},
+		Examples: examples(`["import.commit", {"expectedCount": 3456}]`)},
+	{Method: "import.abort", Summary: "Discard the staged import.", Args: []MethodArg{},
+		Examples: examples(`["import.abort"]`)},
+	{Method: "ns.freeze", Summary: "Reject writes to the namespace until ns.unfreeze.", Args: []MethodArg{argOptions},
+		Options:  []MethodOption{{Name: "reason", Type: "string", Description: "Reason shown in ns.info"}},
+		Examples: examples(`["ns.freeze", {"reason": "migrating to eu-west-1"}]`)},
+	{Method: "ns.unfreeze", Summary: "Allow writes to the namespace again.", Args: []MethodArg{},
+		Examples: examples(`["ns.unfreeze"]`)},
+	{Method: "ns.suspend", Summary: "Reject writes, and optionally reads, to a namespace while keeping its data.", Args: []MethodArg{argNamespace, argOptions},
+		Options: []MethodOption{
+			{Name: "reason", Type: "string", Description: "Reason shown in ns.info"},
+			{Name: "blockReads", Type: "boolean", Description: "Also reject reads and subscriptions"},
+		},
+		Examples: examples(`["ns.suspend", "tenant-a", {"reason": "payment overdue"}]`)},
+	{Method: "ns.resume", Summary: "Lift a namespace's suspension.", Args: []MethodArg{argNamespace},
+		Examples: examples(`["ns.resume", "tenant-a"]`)},
+	{Method: "ns.shards", Summary: "List the shard backends with their namespace counts.", Args: []MethodArg{},
+		Examples: examples(`["ns.shards"]`)},
+	{Method: "ns.storage", Summary: "Measure the bytes a namespace uses, by category, and its growth.", Args: []MethodArg{argNamespace},
+		Examples: examples(`["ns.storage", "tenant-a"]`)},
+	{Method: "ns.retention.set", Summary: "Replace the namespace's retention rules.", Args: []MethodArg{config("{rules: [{streams, keep}]}, or null to remove")},
+		Examples: examples(`["ns.retention.set", {"rules": [{"streams": "*:position-*", "keep": 1}]}]`)},
+	{Method: "ns.retention.get", Summary: "Return the namespace's retention rules.", Args: []MethodArg{},
+		Examples: examples(`["ns.retention.get"]`)},
+	{Method: "ns.compact", Summary: "Apply the namespace's retention rules now.", Args: []MethodArg{argOptions},
+		Options:  []MethodOption{{Name: "dryRun", Type: "boolean", Description: "Report what would be deleted without deleting"}},
+		Examples: examples(`["ns.compact"]`, `["ns.compact", {"dryRun": true}]`)},
+	{Method: "ns.derivedStreams.set", Summary: "Replace the namespace's derived stream rules.", Args: []MethodArg{config("{rules: [{category, types, stream, type, fields}]}, or null to remove")},
+		Examples: examples(`["ns.derivedStreams.set", {"rules": [{"category": "order", "types": ["Placed"], "stream": "orderSummary-{id}", "type": "Order{type}", "fields": ["total"]}]}]`)},
+	{Method: "ns.derivedStreams.get", Summary: "Return the namespace's derived stream rules.", Args: []MethodArg{},
+		Examples: examples(`["ns.derivedStreams.get"]`)},
+	{Method: "ns.queries.set", Summary: "Replace the namespace's standing queries.", Args: []MethodArg{config("{queries: [{name, category, types, where, stream}]}, or null to remove")},
+		Examples: examples(`["ns.queries.set", {"queries": [{"name": "largeOrders", "category": "order", "where": [{"path": "$.data.total", "op": "gte", "value": 1000}]}]}]`)},
+	{Method: "ns.queries.get", Summary: "Return the namespace's standing queries with their result streams.", Args: []MethodArg{},
+		Examples: examples(`["ns.queries.get"]`)},
+	{Method: "ns.metadataTemplates.set", Summary: "Replace the namespace's metadata templates.", Args: []MethodArg{config("{templates: [{type, metadata, override}]}, or null to remove")},
+		Examples: examples(`["ns.metadataTemplates.set", {"templates": [{"type": "Order*", "metadata": {"schemaVersion": 2}}]}]`)},
+	{Method: "ns.metadataTemplates.get", Summary: "Return the namespace's metadata templates.", Args: []MethodArg{},
+		Examples: examples(`["ns.metadataTemplates.get"]`)},
+	{Method: "ns.messageIds.set", Summary: "Set how IDs are generated for messages written without one.", Args: []MethodArg{
+		{Name: "strategy", Type: "string", Required: true, Description: "uuidv7 (default), uuidv4 or ulid"},
+	},
+		Examples: examples(`["ns.messageIds.set", "ulid"]`)},
+	{Method: "ns.messageIds.get", Summary: "Return the namespace's message ID strategy.", Args: []MethodArg{},
+		Examples: examples(`["ns.messageIds.get"]`)},
+	{Method: "ns.plugins.set", Summary: "Replace the namespace's write plugins.", Args: []MethodArg{config("{plugins: [name, ...]}, or null to remove")},
+		Examples: examples(`["ns.plugins.set", {"plugins": ["validate-orders"]}]`)},
+	{Method: "ns.plugins.get", Summary: "Return the namespace's write plugins.", Args: []MethodArg{},
+		Examples: examples(`["ns.plugins.get"]`)},
+	{Method: "ns.readPlugins.set", Summary: "Replace the namespace's read plugin rules.", Args: []MethodArg{config("{rules: [{category, plugin}]}, or null to remove")},
+		Examples: examples(`["ns.readPlugins.set", {"rules": [{"category": "payment", "plugin": "decrypt-payment"}]}]`)},
+	{Method: "ns.readPlugins.get", Summary: "Return the namespace's read plugin rules.", Args: []MethodArg{},
+		Examples: examples(`["ns.readPlugins.get"]`)},
+	{Method: "ns.snapshots.set", Summary: "Replace the namespace's automatic snapshot rules.", Args: []MethodArg{config("{rules: [{category, every, plugin | url, secret}]}, or null to remove")},
+		Examples: examples(`["ns.snapshots.set", {"rules": [{"category": "account", "every": 100, "plugin": "account-reducer"}]}]`)},
+	{Method: "ns.snapshots.get", Summary: "Return the snapshot rules (secrets masked) and counters.", Args: []MethodArg{},
+		Examples: examples(`["ns.snapshots.get"]`)},
+	{Method: "ns.udpDevices.add", Summary: "Issue a signing key for a device sending UDP datagrams; the key is shown once.", Args: []MethodArg{
+		{Name: "deviceId", Type: "string", Required: true, Description: "Device ID, 1-128 letters, digits, '.', '_', ':' or '-'"},
+	},
+		Examples: examples(`["ns.udpDevices.add", "sensor-7"]`)},
+	{Method: "ns.udpDevices.list", Summary: "Return the namespace's UDP devices, without their keys.", Args: []MethodArg{},
+		Examples: examples(`["ns.udpDevices.list"]`)},
+	{Method: "ns.udpDevices.remove", Summary: "Revoke a UDP device's key.", Args: []MethodArg{
+		{Name: "deviceId", Type: "string", Required: true, Description: "Device ID"},
+	},
+		Examples: examples(`["ns.udpDevices.remove", "sensor-7"]`)},
+
+	// Bookmark methods
+	{Method: "bookmark.set", Summary: "Create or move a named global position.", Args: []MethodArg{
+		{Name: "name", Type: "string", Required: true, Description: "Bookmark name"},
+		{Name: "globalPosition", Type: "number", Required: true, Description: "Global position"},
+		argOptions,
+	},
+		Options:  []MethodOption{{Name: "note", Type: "string", Description: "Note stored with the bookmark"}},
+		Examples: examples(`["bookmark.set", "release-2024-10", 48213, {"note": "cutover to v2 schema"}]`)},
+	{Method: "bookmark.get", Summary: "Return a bookmark.", Args: []MethodArg{
+		{Name: "name", Type: "string", Required: true, Description: "Bookmark name"},
+	},
+		Examples: examples(`["bookmark.get", "release-2024-10"]`)},
+	{Method: "bookmark.list", Summary: "List the namespace's bookmarks by global position.", Args: []MethodArg{},
+		Examples: examples(`["bookmark.list"]`)},
+	{Method: "bookmark.delete", Summary: "Delete a bookmark.", Args: []MethodArg{
+		{Name: "name", Type: "string", Required: true, Description: "Bookmark name"},
+	},
+		Examples: examples(`["bookmark.delete", "release-2024-10"]`)},
+
+	// Consumer position methods
+	{Method: "consumer.setPosition", Summary: "Record the last global position a consumer has processed.", Args: []MethodArg{
+		{Name: "consumer", Type: "string", Required: true, Description: "Consumer identifier, e.g. \"billing-projector\""},
+		{Name: "globalPosition", Type: "number", Required: true, Description: "Global position"},
+	},
+		Examples: examples(`["consumer.setPosition", "billing-projector", 48213]`)},
+	{Method: "consumer.getPosition", Summary: "Return the last position recorded for a consumer.", Args: []MethodArg{
+		{Name: "consumer", Type: "string", Required: true, Description: "Consumer identifier"},
+	},
+		Examples: examples(`["consumer.getPosition", "billing-projector"]`)},
+	{Method: "consumer.ack", Summary: "Acknowledge a message of an at-least-once durable subscription.", Args: []MethodArg{
+		{Name: "consumer", Type: "string", Required: true, Description: "Consumer identifier of the subscription"},
+		{Name: "globalPosition", Type: "number", Required: true, Description: "Global position of the message"},
+	},
+		Examples: examples(`["consumer.ack", "billing-projector", 48214]`)},
+
+	// Tick methods
+	{Method: "tick.create", Summary: "Append a recurring tick event to a stream on a cron schedule.", Args: []MethodArg{
+		{Name: "name", Type: "string", Required: true, Description: "Tick name, unique in the namespace"},
+		{Name: "config", Type: "object", Required: true, Description: "{cron, stream, type, data}"},
+	},
+		Examples: examples(`["tick.create", "invoicing", {"cron": "0 6 * * *", "stream": "billingClock-daily", "type": "DayStarted"}]`)},
+	{Method: "tick.list", Summary: "List the namespace's ticks with their next occurrence.", Args: []MethodArg{},
+		Examples: examples(`["tick.list"]`)},
+	{Method: "tick.delete", Summary: "Stop a tick; the messages it wrote are kept.", Args: []MethodArg{
+		{Name: "name", Type: "string", Required: true, Description: "Tick name"},
+	},
+		Examples: examples(`["tick.delete", "invoicing"]`)},
+
+	// Hook methods
+	{Method: "hook.redeliver", Summary: "Replay a failed webhook delivery from the hook's dead-letter stream.", Args: []MethodArg{
+		{Name: "hookName", Type: "string", Required: true, Description: "Webhook name"},
+		{Name: "position", Type: "number", Required: true, Description: "Position in the dead-letter stream"},
+	},
+		Examples: examples(`["hook.redeliver", "billing", 3]`)},
+}
+
+// MethodDocs returns the documentation of every RPC method, sorted by name
+func MethodDocs() []MethodDoc {
+	docs := make([]MethodDoc, len(methodDocs))
+	for i, doc := range methodDocs {
+		doc.Admin = isAdminMethod(doc.Method)
+		docs[i] = doc
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Method < docs[j].Method })
+	return docs
+}
+
+// DescribeMethod returns the documentation of one RPC method
+func DescribeMethod(method string) (*MethodDoc, bool) {
+	for _, doc := range methodDocs {
+		if doc.Method == method {
+			doc.Admin = isAdminMethod(method)
+			return &doc, true
+		}
+	}
+	return nil, false
+}
+
+// SchemaHandler serves the documentation of every RPC method as JSON, the
+// same registry sys.describe reads
+func SchemaHandler() fasthttp.RequestHandler {
+	body, _ := json.Marshal(map[string]interface{}{
+		"protocol": ProtocolVersion,
+		"methods":  MethodDocs(),
+	})
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("application/json")
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		ctx.SetBody(body)
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// TestMethodDocs tests that every registered RPC method is documented with
+// valid example requests, and that sys.describe serves the registry
+func TestMethodDocs(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	documented := make(map[string]bool)
+	for _, doc := range MethodDocs() {
+		if documented[doc.Method] {
+			t.Errorf("%s is documented twice", doc.Method)
+		}
+		documented[doc.Method] = true
+		if _, ok := h.methods[doc.Method]; !ok {
+			t.Errorf("%s is documented but not registered", doc.Method)
+		}
+		if doc.Summary == "" || len(doc.Examples) == 0 {
+			t.Errorf("%s needs a summary and an example", doc.Method)
+		}
+		for _, example := range doc.Examples {
+			var req []interface{}
+			if err := json.Unmarshal(example, &req); err != nil {
+				t.Errorf("%s example %s is not a JSON array: %v", doc.Method, example, err)
+				continue
+			}
+			if len(req) == 0 || req[0] != doc.Method {
+				t.Errorf("%s example %s does not call the method", doc.Method, example)
+			}
+			if args := len(req) - 1; args > len(doc.Args) {
+				t.Errorf("%s example %s passes %d arguments, %d documented", doc.Method, example, args, len(doc.Args))
+			}
+		}
+	}
+	for method := range h.methods {
+		if !documented[method] {
+			t.Errorf("%s is registered but not documented", method)
+		}
+	}
+
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	result, rpcErr := h.route(ctx, "sys.describe", []interface{}{"ns.delete"})
+	if rpcErr != nil {
+		t.Fatalf("sys.describe failed: %v", rpcErr.Message)
+	}
+	doc := result.(*MethodDoc)
+	if !doc.Admin || len(doc.Options) != 1 || doc.Options[0].Name != "dryRun" {
+		t.Errorf("Unexpected ns.delete doc: %+v", doc)
+	}
+
+	result, rpcErr = h.route(ctx, "sys.describe", nil)
+	if rpcErr != nil {
+		t.Fatalf("sys.describe failed: %v", rpcErr.Message)
+	}
+	if methods := result.(map[string]interface{})["methods"].([]map[string]interface{}); len(methods) != len(h.methods) {
+		t.Errorf("Expected %d methods, got %d", len(h.methods), len(methods))
+	}
+
+	if _, rpcErr := h.route(ctx, "sys.describe", []interface{}{"stream.nope"}); rpcErr == nil || rpcErr.Code != "METHOD_NOT_FOUND" {
+		t.Errorf("Expected METHOD_NOT_FOUND, got %v", rpcErr)
+	}
+}
+
+// TestMethodDocs_Edges tests invalid sys.describe arguments, that required
+// arguments come first, that described docs are copies and that /schema
+// serves the registry
+func TestMethodDocs_Edges(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, args := range [][]interface{}{{""}, {float64(1)}} {
+		if _, rpcErr := h.route(ctx, "sys.describe", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	for _, doc := range MethodDocs() {
+		optional := false
+		for _, arg := range doc.Args {
+			if arg.Required && optional {
+				t.Errorf("%s documents required argument %s after an optional one", doc.Method, arg.Name)
+			}
+			optional = optional || !arg.Required
+		}
+	}
+
+	doc, ok := DescribeMethod("stream.write")
+	if !ok {
+		t.Fatal("Expected stream.write to be documented")
+	}
+	doc.Summary = "changed"
+	if again, _ := DescribeMethod("stream.write"); again.Summary == "changed" {
+		t.Error("Expected DescribeMethod to return a copy")
+	}
+
+	var reqCtx fasthttp.RequestCtx
+	SchemaHandler()(&reqCtx)
+	var schema struct {
+		Protocol string      `json:"protocol"`
+		Methods  []MethodDoc `json:"methods"`
+	}
+	if err := json.Unmarshal(reqCtx.Response.Body(), &schema); err != nil {
+		t.Fatalf("Failed to decode /schema: %v", err)
+	}
+	if schema.Protocol != ProtocolVersion || len(schema.Methods) != len(h.methods) {
+		t.Errorf("Expected protocol %s and %d methods, got %s and %d", ProtocolVersion, len(h.methods), schema.Protocol, len(schema.Methods))
+	}
+}
@@ -0,0 +1,359 @@
+// Package api provides periodic push of metrics for environments that can't scrape.
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/klauspost/compress/s2"
+)
+
+const (
+	// DefaultMetricsPushInterval is the time between pushes when none is configured
+	DefaultMetricsPushInterval = 15 * time.Second
+
+	// metricsPushTimeout bounds one push to a remote-write endpoint
+	metricsPushTimeout = 10 * time.Second
+
+	// statsdMaxPacket keeps StatsD packets under a typical network MTU
+	statsdMaxPacket = 1432
+)
+
+// MetricsPushConfig configures a MetricsPusher
+type MetricsPushConfig struct {
+	// URL is a Prometheus remote-write endpoint (http:// or https://, with
+	// optional user:password for basic auth) or a StatsD server (statsd://host:port)
+	URL string
+
+	// Interval is the time between pushes (default: 15s)
+	Interval time.Duration
+
+	// Labels are added to every pushed sample, e.g. instance=node-1
+	Labels map[string]string
+}
+
+// statsdTagEscaper replaces the characters that delimit StatsD tags
+var statsdTagEscaper = strings.NewReplacer(",", "_", "|", "_", ":", "_", "\n", "_")
+
+// metricSample is one sample parsed from the text exposition
+type metricSample struct {
+	name   string
+	labels [][2]string // Sorted by name
+	value  float64
+}
+
+// MetricsPusher periodically pushes the metrics served on /metrics to a
+// Prometheus remote-write endpoint or a StatsD server, for environments
+// where nothing can scrape the server. Counters are pushed as their running
+// totals; StatsD receives every sample as a gauge, with labels as tags.
+type MetricsPusher struct {
+	src    MetricsSources
+	cfg    MetricsPushConfig
+	target *url.URL
+	client *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// ParseMetricsPushLabels parses a comma-separated list of name=value labels
+func ParseMetricsPushLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || !isMetricLabelName(name) || value == "" {
+			return nil, fmt.Errorf("invalid metrics label %q (want name=value)", part)
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
+
+// isMetricLabelName reports whether name is a valid Prometheus label name
+func isMetricLabelName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "__") {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// NewMetricsPusher creates a pusher for the metrics of src; call Start to begin pushing
+func NewMetricsPusher(src MetricsSources, cfg MetricsPushConfig) (*MetricsPusher, error) {
+	target, err := url.Parse(cfg.URL)
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("invalid metrics push URL %q", cfg.URL)
+	}
+	switch target.Scheme {
+	case "http", "https", "statsd":
+	default:
+		return nil, fmt.Errorf("metrics push URL must use http://, https:// or statsd://, got %q", cfg.URL)
+	}
+	for name := range cfg.Labels {
+		if !isMetricLabelName(name) {
+			return nil, fmt.Errorf("invalid metrics label name %q", name)
+		}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultMetricsPushInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &MetricsPusher{
+		src:    src,
+		cfg:    cfg,
+		target: target,
+		client: &http.Client{Timeout: metricsPushTimeout},
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Start pushes metrics in the background until Close
+func (p *MetricsPusher) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := p.Push(p.ctx); err != nil && p.ctx.Err() == nil {
+				logger.Get().Warn().Err(err).Str("target", p.target.Redacted()).Msg("Failed to push metrics")
+			}
+		}
+	}()
+	logger.Get().Info().Str("target", p.target.Redacted()).Dur("interval", p.cfg.Interval).Msg("Metrics push started")
+}
+
+// Close stops pushing
+func (p *MetricsPusher) Close() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Push sends the current metrics once
+func (p *MetricsPusher) Push(ctx context.Context) error {
+	samples, err := p.gather()
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	if p.target.Scheme == "statsd" {
+		return p.pushStatsD(samples)
+	}
+	return p.pushRemoteWrite(ctx, samples, time.Now())
+}
+
+// gather renders the current metrics and parses them into samples with the
+// configured labels added
+func (p *MetricsPusher) gather() ([]metricSample, error) {
+	var buf bytes.Buffer
+	writeMetrics(&buf, p.src)
+
+	var samples []metricSample
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sample, err := parseMetricSample(line)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range p.cfg.Labels {
+			sample.labels = append(sample.labels, [2]string{name, value})
+		}
+		sort.Slice(sample.labels, func(i, j int) bool { return sample.labels[i][0] < sample.labels[j][0] })
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+// parseMetricSample parses one sample line of the text exposition, such as
+// name{label="value"} 1.5
+func parseMetricSample(line string) (metricSample, error) {
+	var sample metricSample
+	i := strings.IndexAny(line, "{ ")
+	if i < 0 {
+		return sample, fmt.Errorf("invalid metric line %q", line)
+	}
+	sample.name = line[:i]
+	rest := line[i:]
+
+	if strings.HasPrefix(rest, "{") {
+		rest = rest[1:]
+		for !strings.HasPrefix(rest, "}") {
+			name, after, ok := strings.Cut(rest, "=")
+			if !ok {
+				return sample, fmt.Errorf("invalid metric line %q", line)
+			}
+			quoted, err := strconv.QuotedPrefix(after)
+			if err != nil {
+				return sample, fmt.Errorf("invalid metric line %q", line)
+			}
+			value, _ := strconv.Unquote(quoted)
+			sample.labels = append(sample.labels, [2]string{name, value})
+			rest = strings.TrimPrefix(after[len(quoted):], ",")
+		}
+		rest = rest[1:]
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(rest), 64)
+	if err != nil {
+		return sample, fmt.Errorf("invalid metric line %q", line)
+	}
+	sample.value = value
+	return sample, nil
+}
+
+// pushRemoteWrite sends samples to a Prometheus remote-write endpoint as a
+// snappy-compressed WriteRequest
+func (p *MetricsPusher) pushRemoteWrite(ctx context.Context, samples []metricSample, now time.Time) error {
+	body := s2.EncodeSnappy(nil, encodeWriteRequest(samples, now.UnixMilli()))
+
+	target := *p.target
+	target.User = nil
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if p.target.User != nil {
+		password, _ := p.target.User.Password()
+		req.SetBasicAuth(p.target.User.Username(), password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("remote write returned %s", resp.Status)
+	}
+	return nil
+}
+
+// encodeWriteRequest encodes samples as a remote-write WriteRequest protobuf:
+// each sample is a TimeSeries with a __name__ label and one Sample
+func encodeWriteRequest(samples []metricSample, timestamp int64) []byte {
+	var out []byte
+	for _, sample := range samples {
+		var series []byte
+		series = appendProtoBytes(series, 1, encodeProtoLabel("__name__", sample.name))
+		for _, label := range sample.labels {
+			series = appendProtoBytes(series, 1, encodeProtoLabel(label[0], label[1]))
+		}
+
+		var point []byte
+		point = binary.AppendUvarint(point, 1<<3|1) // value, fixed64
+		point = binary.LittleEndian.AppendUint64(point, math.Float64bits(sample.value))
+		point = binary.AppendUvarint(point, 2<<3) // timestamp, varint
+		point = binary.AppendUvarint(point, uint64(timestamp))
+		series = appendProtoBytes(series, 2, point)
+
+		out = appendProtoBytes(out, 1, series)
+	}
+	return out
+}
+
+// encodeProtoLabel encodes a remote-write Label message
+func encodeProtoLabel(name, value string) []byte {
+	var label []byte
+	label = appendProtoBytes(label, 1, []byte(name))
+	return appendProtoBytes(label, 2, []byte(value))
+}
+
+// appendProtoBytes appends a length-delimited protobuf field
+func appendProtoBytes(b []byte, field int, value []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// pushStatsD sends samples to a StatsD server over UDP as gauges with
+// DogStatsD-style tags, packing several per packet
+func (p *MetricsPusher) pushStatsD(samples []metricSample) error {
+	conn, err := net.Dial("udp", p.target.Host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var packet []byte
+	flush := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet)
+		packet = packet[:0]
+		return err
+	}
+	for _, sample := range samples {
+		line := formatStatsDGauge(sample)
+		if len(packet) > 0 && len(packet)+1+len(line) > statsdMaxPacket {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	return flush()
+}
+
+// formatStatsDGauge formats a sample as a StatsD gauge, e.g.
+// eventodb_store_in_flight:3|g|#kind:write
+func formatStatsDGauge(sample metricSample) string {
+	var b strings.Builder
+	b.WriteString(sample.name)
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(sample.value, 'g', -1, 64))
+	b.WriteString("|g")
+	for i, label := range sample.labels {
+		if i == 0 {
+			b.WriteString("|#")
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(label[0])
+		b.WriteByte(':')
+		b.WriteString(statsdTagEscaper.Replace(label[1]))
+	}
+	return b.String()
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+)
+
+// protoFields splits a protobuf message into its fields, keyed by number;
+// fixed64 values are returned as their 8 bytes
+func protoFields(t *testing.T, b []byte) map[uint64][][]byte {
+	t.Helper()
+	fields := make(map[uint64][][]byte)
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		switch key & 7 {
+		case 0:
+			_, n := binary.Uvarint(b)
+			fields[key>>3] = append(fields[key>>3], b[:n])
+			b = b[n:]
+		case 1:
+			fields[key>>3] = append(fields[key>>3], b[:8])
+			b = b[8:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			fields[key>>3] = append(fields[key>>3], b[n:n+int(size)])
+			b = b[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return fields
+}
+
+func latencySources() MetricsSources {
+	latency := NewLatencyTracker()
+	latency.Observe("stream.write", "default", 20*time.Millisecond)
+	return MetricsSources{Latency: latency}
+}
+
+func TestParseMetricSample(t *testing.T) {
+	sample, err := parseMetricSample(`eventodb_store_in_flight{kind="write",note="a \"b\", c"} 3`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.name != "eventodb_store_in_flight" || sample.value != 3 {
+		t.Fatalf("unexpected sample %+v", sample)
+	}
+	if len(sample.labels) != 2 || sample.labels[0] != [2]string{"kind", "write"} || sample.labels[1] != [2]string{"note", `a "b", c`} {
+		t.Fatalf("unexpected labels %v", sample.labels)
+	}
+
+	sample, err = parseMetricSample("eventodb_queued_writes 0.5")
+	if err != nil || sample.name != "eventodb_queued_writes" || sample.value != 0.5 || len(sample.labels) != 0 {
+		t.Fatalf("unexpected sample %+v, %v", sample, err)
+	}
+
+	if _, err := parseMetricSample("eventodb_queued_writes"); err == nil {
+		t.Fatal("expected error for a line without a value")
+	}
+}
+
+func TestParseMetricsPushLabels(t *testing.T) {
+	labels, err := ParseMetricsPushLabels("instance=node-1, region = eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 2 || labels["instance"] != "node-1" || labels["region"] != "eu" {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+	for _, bad := range []string{"instance", "1a=b", "__name__=x", "a="} {
+		if _, err := ParseMetricsPushLabels(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestNewMetricsPusher_Validation(t *testing.T) {
+	for _, bad := range []string{"", "ftp://example.com", "statsd://", "localhost:8125"} {
+		if _, err := NewMetricsPusher(MetricsSources{}, MetricsPushConfig{URL: bad}); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestMetricsPusher_RemoteWrite(t *testing.T) {
+	var body []byte
+	var header http.Header
+	var user, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		user, password, _ = r.BasicAuth()
+		compressed, _ := io.ReadAll(r.Body)
+		var err error
+		if body, err = s2.Decode(nil, compressed); err != nil {
+			t.Errorf("body is not snappy: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	pushURL := strings.Replace(server.URL, "http://", "http://prom:secret@", 1) + "/api/v1/write"
+	pusher, err := NewMetricsPusher(latencySources(), MetricsPushConfig{
+		URL:    pushURL,
+		Labels: map[string]string{"instance": "node-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pusher.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Get("Content-Encoding") != "snappy" || header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		t.Fatalf("unexpected headers %v", header)
+	}
+	if user != "prom" || password != "secret" {
+		t.Fatalf("expected basic auth prom:secret, got %s:%s", user, password)
+	}
+
+	// Find the count series and check its labels and value
+	var found bool
+	for _, series := range protoFields(t, body)[1] {
+		fields := protoFields(t, series)
+		labels := make(map[string]string)
+		for _, label := range fields[1] {
+			lf := protoFields(t, label)
+			labels[string(lf[1][0])] = string(lf[2][0])
+		}
+		if labels["__name__"] != "eventodb_rpc_latency_seconds_count" {
+			continue
+		}
+		found = true
+		if labels["instance"] != "node-1" || labels["method"] != "stream.write" || labels["namespace"] != "default" {
+			t.Fatalf("unexpected labels %v", labels)
+		}
+		sample := protoFields(t, fields[2][0])
+		if value := math.Float64frombits(binary.LittleEndian.Uint64(sample[1][0])); value != 1 {
+			t.Fatalf("expected count 1, got %g", value)
+		}
+		if ts, _ := binary.Uvarint(sample[2][0]); time.Since(time.UnixMilli(int64(ts))) > time.Minute {
+			t.Fatalf("unexpected timestamp %d", ts)
+		}
+	}
+	if !found {
+		t.Fatal("count series not pushed")
+	}
+}
+
+func TestMetricsPusher_RemoteWriteError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	pusher, err := NewMetricsPusher(latencySources(), MetricsPushConfig{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pusher.Push(context.Background()); err == nil {
+		t.Fatal("expected error for a 400 response")
+	}
+}
+
+func TestMetricsPusher_StatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	pusher, err := NewMetricsPusher(latencySources(), MetricsPushConfig{
+		URL:    "statsd://" + conn.LocalAddr().String(),
+		Labels: map[string]string{"instance": "node-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pusher.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64*1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "eventodb_rpc_latency_seconds_count:1|g|#instance:node-1,method:stream.write,namespace:default"
+	if !strings.Contains(string(buf[:n]), want) {
+		t.Fatalf("expected %q in packet:\n%s", want, buf[:n])
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -55,6 +56,13 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush passes flushes through, so streamed responses are sent as written
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // contextKey is a type for context keys to avoid collisions
 type contextKey string
 
@@ -72,6 +80,7 @@ type NamespaceGetter interface {
 
 // AuthMiddleware validates authentication tokens and adds namespace to context
 func AuthMiddleware(st NamespaceGetter, testMode bool) func(http.Handler) http.Handler {
+	audit := newAuthAuditor(st)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
@@ -119,18 +128,22 @@ func AuthMiddleware(st NamespaceGetter, testMode bool) func(http.Handler) http.H
 					next.ServeHTTP(w, r.WithContext(ctx))
 					return
 				}
-				writeAuthError(w, http.StatusUnauthorized, &RPCError{
-					Code:    "AUTH_INVALID_TOKEN",
-					Message: "Invalid token format",
-					Details: map[string]interface{}{"error": err.Error()},
-				})
+				rpcErr := malformedTokenError(err)
+				audit.record("", rpcErr, r.RemoteAddr)
+				writeAuthError(w, http.StatusUnauthorized, rpcErr)
 				return
 			}
 
 			// Validate token against database (skip in test mode if namespace doesn't exist)
-			tokenHash := auth.HashToken(token)
 			ns, err := st.GetNamespace(r.Context(), namespace)
 			if err != nil {
+				if store.IsBackendUnavailable(err) {
+					rpcErr := backendUnavailableError(err)
+					seconds, _ := retryAfter(rpcErr)
+					w.Header().Set("Retry-After", strconv.Itoa(seconds))
+					writeAuthError(w, http.StatusServiceUnavailable, rpcErr)
+					return
+				}
 				if testMode {
 					// In test mode, allow non-existent namespaces - they'll be auto-created
 					ctx = context.WithValue(ctx, ContextKeyNamespace, namespace)
@@ -145,13 +158,21 @@ func AuthMiddleware(st NamespaceGetter, testMode bool) func(http.Handler) http.H
 				return
 			}
 
-			// Verify token hash matches (skip in test mode)
-			if !testMode && ns.TokenHash != tokenHash {
-				writeAuthError(w, http.StatusForbidden, &RPCError{
-					Code:    "AUTH_UNAUTHORIZED",
-					Message: "Token not authorized for namespace",
-					Details: map[string]interface{}{"namespace": namespace},
-				})
+			// Verify the token is current and unexpired (skip in test mode)
+			if !testMode {
+				if err := verifyNamespaceToken(ns, token, time.Now()); err != nil {
+					statusCode, rpcErr := tokenAuthError(ns, err)
+					audit.record(namespace, rpcErr, r.RemoteAddr)
+					writeAuthError(w, statusCode, rpcErr)
+					return
+				}
+			}
+
+			// Namespaces suspended with blockReads reject every request
+			if state := SuspensionStateFromMetadata(ns.Metadata); state != nil && state.BlockReads {
+				rpcErr := suspendedError(namespace)
+				audit.record(namespace, rpcErr, r.RemoteAddr)
+				writeAuthError(w, http.StatusForbidden, rpcErr)
 				return
 			}
 
@@ -4,11 +4,13 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/eventodb/eventodb/internal/auth"
 	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
 	"github.com/rs/zerolog"
 	"github.com/valyala/fasthttp"
 )
@@ -42,6 +44,7 @@ func LoggingMiddlewareFast(next fasthttp.RequestHandler) fasthttp.RequestHandler
 
 // AuthMiddlewareFast validates authentication tokens and adds namespace to context (fasthttp version)
 func AuthMiddlewareFast(st NamespaceGetter, testMode bool) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	audit := newAuthAuditor(st)
 	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 		return func(ctx *fasthttp.RequestCtx) {
 			reqCtx := context.Background()
@@ -118,18 +121,22 @@ func AuthMiddlewareFast(st NamespaceGetter, testMode bool) func(fasthttp.Request
 					next(ctx)
 					return
 				}
-				writeAuthErrorFast(ctx, fasthttp.StatusUnauthorized, &RPCError{
-					Code:    "AUTH_INVALID_TOKEN",
-					Message: "Invalid token format",
-					Details: map[string]interface{}{"error": err.Error()},
-				})
+				rpcErr := malformedTokenError(err)
+				audit.record("", rpcErr, ctx.RemoteAddr().String())
+				writeAuthErrorFast(ctx, fasthttp.StatusUnauthorized, rpcErr)
 				return
 			}
 
 			// Validate token against database (skip in test mode if namespace doesn't exist)
-			tokenHash := auth.HashToken(token)
 			ns, err := st.GetNamespace(reqCtx, namespace)
 			if err != nil {
+				if store.IsBackendUnavailable(err) {
+					rpcErr := backendUnavailableError(err)
+					seconds, _ := retryAfter(rpcErr)
+					ctx.Response.Header.Set("Retry-After", strconv.Itoa(seconds))
+					writeAuthErrorFast(ctx, fasthttp.StatusServiceUnavailable, rpcErr)
+					return
+				}
 				if testMode {
 					// In test mode, allow non-existent namespaces - they'll be auto-created
 					ctx.SetUserValue("namespace", namespace)
@@ -145,13 +152,21 @@ func AuthMiddlewareFast(st NamespaceGetter, testMode bool) func(fasthttp.Request
 				return
 			}
 
-			// Verify token hash matches (skip in test mode)
-			if !testMode && ns.TokenHash != tokenHash {
-				writeAuthErrorFast(ctx, fasthttp.StatusForbidden, &RPCError{
-					Code:    "AUTH_UNAUTHORIZED",
-					Message: "Token not authorized for namespace",
-					Details: map[string]interface{}{"namespace": namespace},
-				})
+			// Verify the token is current and unexpired (skip in test mode)
+			if !testMode {
+				if err := verifyNamespaceToken(ns, token, time.Now()); err != nil {
+					statusCode, rpcErr := tokenAuthError(ns, err)
+					audit.record(namespace, rpcErr, ctx.RemoteAddr().String())
+					writeAuthErrorFast(ctx, statusCode, rpcErr)
+					return
+				}
+			}
+
+			// Namespaces suspended with blockReads reject every request
+			if state := SuspensionStateFromMetadata(ns.Metadata); state != nil && state.BlockReads {
+				rpcErr := suspendedError(namespace)
+				audit.record(namespace, rpcErr, ctx.RemoteAddr().String())
+				writeAuthErrorFast(ctx, fasthttp.StatusForbidden, rpcErr)
 				return
 			}
 
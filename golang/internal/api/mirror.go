@@ -0,0 +1,543 @@
+// Package api provides asynchronous mirroring of namespaces to a remote server.
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// Namespace metadata keys holding the mirror config, its progress, and
+	// the state of a namespace that receives a mirror
+	mirrorMetadataKey       = "mirror"
+	mirrorStatusMetadataKey = "mirrorStatus"
+	mirrorOfMetadataKey     = "mirrorOf"
+
+	// mirrorDefaultBatch is the number of messages per pushed batch
+	mirrorDefaultBatch = 500
+
+	// mirrorMaxBatch caps the configurable batch size
+	mirrorMaxBatch = 1000
+
+	// mirrorMaxBackoff caps the retry delay after consecutive failures
+	mirrorMaxBackoff = time.Minute
+
+	// mirrorTimeout bounds one batch push, including the remote import
+	mirrorTimeout = 30 * time.Second
+)
+
+// Mirror states reported by ns.mirror.status
+const (
+	MirrorStateActive  = "active"
+	MirrorStateFailing = "failing"
+)
+
+var (
+	// ErrMirrorDisabled is returned when the server was started without mirroring
+	ErrMirrorDisabled = errors.New("namespace mirroring is not enabled")
+
+	// ErrNamespaceMirrored is returned when writes are rejected for a namespace
+	// that receives a mirror
+	ErrNamespaceMirrored = errors.New("namespace is a read-only mirror")
+
+	// ErrMirrorConflict is returned when a mirror push would mix two histories:
+	// the namespace has messages of its own or mirrors another source
+	ErrMirrorConflict = errors.New("mirror conflict")
+
+	// ErrMirrorPromoted is returned for mirror pushes to a promoted namespace
+	ErrMirrorPromoted = errors.New("namespace was promoted and no longer accepts its mirror")
+
+	// ErrNotMirror is returned when promoting a namespace that receives no mirror
+	ErrNotMirror = errors.New("namespace is not a mirror")
+)
+
+// MirrorConfig describes the remote namespace a namespace is mirrored to.
+//
+// Batches are pushed in global position order to the remote server's /import
+// endpoint with dedupeBy=id, so messages keep their IDs, positions and times
+// and a retried batch is not imported twice.
+type MirrorConfig struct {
+	URL       string `json:"url"`                 // Base URL of the remote server
+	Token     string `json:"token"`               // Token of the remote namespace
+	BatchSize int    `json:"batchSize,omitempty"` // Messages per batch (default: 500, max: 1000)
+}
+
+// MirrorStatus reports the progress of a namespace's mirror
+type MirrorStatus struct {
+	State          string `json:"state"`                    // active or failing
+	Position       int64  `json:"position"`                 // Last mirrored global position
+	LastMirroredAt string `json:"lastMirroredAt,omitempty"` // Time of the last pushed batch
+	LastError      string `json:"lastError,omitempty"`      // Most recent failure, cleared on success
+	LastErrorAt    string `json:"lastErrorAt,omitempty"`
+}
+
+// MirrorTarget describes a namespace that receives a mirror. It rejects
+// writes other than the mirror's until it is promoted.
+type MirrorTarget struct {
+	Source     string `json:"source"`               // Namespace mirrored from, as sent by the primary
+	Since      string `json:"since"`                // Time of the first mirror push
+	PromotedAt string `json:"promotedAt,omitempty"` // Set once the namespace accepts its own writes
+}
+
+// Validate checks the config and applies defaults
+func (c *MirrorConfig) Validate() error {
+	if err := validateShippingURL(c.URL); err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+	if c.Token == "" {
+		return fmt.Errorf("token must not be empty")
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = mirrorDefaultBatch
+	}
+	if c.BatchSize > mirrorMaxBatch {
+		c.BatchSize = mirrorMaxBatch
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with the token masked
+func (c MirrorConfig) Redacted() MirrorConfig {
+	if c.Token != "" {
+		c.Token = logShippingRedacted
+	}
+	return c
+}
+
+// MirrorFromMetadata returns the mirror config and status stored in namespace
+// metadata. Either may be nil.
+func MirrorFromMetadata(metadata map[string]interface{}) (*MirrorConfig, *MirrorStatus) {
+	var cfg *MirrorConfig
+	if raw, ok := metadata[mirrorMetadataKey]; ok && raw != nil {
+		var c MirrorConfig
+		if decodeMetadataValue(raw, &c) == nil {
+			cfg = &c
+		}
+	}
+
+	var status *MirrorStatus
+	if raw, ok := metadata[mirrorStatusMetadataKey]; ok && raw != nil {
+		var s MirrorStatus
+		if decodeMetadataValue(raw, &s) == nil {
+			status = &s
+		}
+	}
+	return cfg, status
+}
+
+// MirrorTargetFromMetadata returns the mirror target state stored in namespace metadata, or nil
+func MirrorTargetFromMetadata(metadata map[string]interface{}) *MirrorTarget {
+	raw, ok := metadata[mirrorOfMetadataKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	var target MirrorTarget
+	if decodeMetadataValue(raw, &target) != nil {
+		return nil
+	}
+	return &target
+}
+
+// Mirrored reports whether the namespace rejects writes other than its mirror's
+func (t *MirrorTarget) Mirrored() bool {
+	return t != nil && t.PromotedAt == ""
+}
+
+// AcceptMirror records that namespace receives the mirror of source. The
+// first push marks the namespace as a mirror, which requires it to have no
+// messages. Pushes from another source, or to a promoted namespace, fail.
+func AcceptMirror(ctx context.Context, st store.Store, guard *WriteGuard, namespace, source string) error {
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if target := MirrorTargetFromMetadata(ns.Metadata); target != nil {
+		if target.PromotedAt != "" {
+			return ErrMirrorPromoted
+		}
+		if target.Source != source {
+			return fmt.Errorf("%w: namespace mirrors %q", ErrMirrorConflict, target.Source)
+		}
+		return nil
+	}
+
+	msgs, err := st.GetCategoryMessages(ctx, namespace, "", &store.CategoryOpts{Position: 1, BatchSize: 1})
+	if err != nil {
+		return err
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("%w: namespace has messages of its own", ErrMirrorConflict)
+	}
+
+	err = updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		metadata[mirrorOfMetadataKey] = encodeMetadataValue(&MirrorTarget{
+			Source: source,
+			Since:  time.Now().UTC().Format(time.RFC3339Nano),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	guard.forget(namespace)
+	return nil
+}
+
+// PromoteMirror makes a mirror namespace accept its own writes, e.g. after
+// a failover. Later pushes from the former primary fail with ErrMirrorPromoted,
+// so the two histories cannot diverge silently.
+func PromoteMirror(ctx context.Context, st store.Store, guard *WriteGuard, namespace string) (*MirrorTarget, error) {
+	var target *MirrorTarget
+	err := updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		if target = MirrorTargetFromMetadata(metadata); target == nil || target.PromotedAt != "" {
+			return
+		}
+		target.PromotedAt = time.Now().UTC().Format(time.RFC3339Nano)
+		metadata[mirrorOfMetadataKey] = encodeMetadataValue(target)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrNotMirror
+	}
+	guard.forget(namespace)
+	return target, nil
+}
+
+// Mirror continuously pushes namespaces to remote servers configured with
+// ns.mirror.set.
+//
+// Configuration and progress live in namespace metadata, so mirroring
+// resumes after a restart from the last pushed position. One worker runs per
+// mirrored namespace.
+type Mirror struct {
+	store    store.Store
+	pubsub   *PubSub
+	client   *http.Client
+	notifier *Notifier
+
+	// mu guards workers
+	mu      sync.Mutex
+	workers map[string]*mirrorWorker
+	closed  bool
+}
+
+// NewMirror creates a namespace mirror
+func NewMirror(st store.Store, pubsub *PubSub) *Mirror {
+	return &Mirror{
+		store:   st,
+		pubsub:  pubsub,
+		client:  &http.Client{Timeout: mirrorTimeout},
+		workers: make(map[string]*mirrorWorker),
+	}
+}
+
+// SetNotifier routes mirror failures to a notifier
+func (m *Mirror) SetNotifier(n *Notifier) {
+	m.notifier = n
+}
+
+// Start launches a worker for every namespace with a mirror configured
+func (m *Mirror) Start(ctx context.Context) error {
+	namespaces, err := m.store.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ns := range namespaces {
+		cfg, _ := MirrorFromMetadata(ns.Metadata)
+		if cfg == nil {
+			continue
+		}
+		if err := cfg.Validate(); err != nil {
+			logger.Get().Warn().Err(err).Str("namespace", ns.ID).Msg("Ignoring invalid mirror config")
+			continue
+		}
+		m.startWorker(ns.ID, *cfg)
+	}
+	return nil
+}
+
+// Configure stores a namespace's mirror config and (re)starts its worker.
+// A nil config stops mirroring. Changing the URL restarts mirroring from the
+// beginning of the namespace; the remote import skips messages it already has.
+func (m *Mirror) Configure(ctx context.Context, namespace string, cfg *MirrorConfig) error {
+	if cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if w, ok := m.workers[namespace]; ok {
+		w.halt()
+		delete(m.workers, namespace)
+	}
+
+	err := updateNamespaceMetadata(ctx, m.store, namespace, func(metadata map[string]interface{}) {
+		previous, _ := MirrorFromMetadata(metadata)
+
+		// A masked token echoed back from ns.mirror.status keeps its stored value
+		if cfg != nil && previous != nil && cfg.Token == logShippingRedacted {
+			cfg.Token = previous.Token
+		}
+
+		if cfg == nil {
+			delete(metadata, mirrorMetadataKey)
+			delete(metadata, mirrorStatusMetadataKey)
+		} else {
+			metadata[mirrorMetadataKey] = encodeMetadataValue(cfg)
+			if previous == nil || previous.URL != cfg.URL {
+				delete(metadata, mirrorStatusMetadataKey)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg != nil && !m.closed {
+		m.startWorker(namespace, *cfg)
+	}
+	return nil
+}
+
+// Close stops all workers
+func (m *Mirror) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	workers := m.workers
+	m.workers = make(map[string]*mirrorWorker)
+	m.mu.Unlock()
+
+	for _, w := range workers {
+		w.halt()
+	}
+	return nil
+}
+
+// startWorker launches a worker; the caller holds mu
+func (m *Mirror) startWorker(namespace string, cfg MirrorConfig) {
+	w := &mirrorWorker{
+		mirror:    m,
+		namespace: namespace,
+		cfg:       cfg,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	m.workers[namespace] = w
+	go w.run()
+}
+
+// saveStatus records mirror progress in namespace metadata
+func (m *Mirror) saveStatus(ctx context.Context, w *mirrorWorker, status MirrorStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A worker that has been replaced must not overwrite its successor's status
+	if m.workers[w.namespace] != w {
+		return nil
+	}
+
+	return updateNamespaceMetadata(ctx, m.store, w.namespace, func(metadata map[string]interface{}) {
+		metadata[mirrorStatusMetadataKey] = encodeMetadataValue(status)
+	})
+}
+
+// mirrorWorker pushes one namespace
+type mirrorWorker struct {
+	mirror    *Mirror
+	namespace string
+	cfg       MirrorConfig
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// halt stops the worker and waits for it to exit
+func (w *mirrorWorker) halt() {
+	close(w.stop)
+	<-w.done
+}
+
+// run pushes the namespace until stopped
+func (w *mirrorWorker) run() {
+	defer close(w.done)
+
+	ctx := context.Background()
+	m := w.mirror
+	log := logger.Get().With().Str("namespace", w.namespace).Str("mirror", w.cfg.URL).Logger()
+
+	var status MirrorStatus
+	if ns, err := m.store.GetNamespace(ctx, w.namespace); err == nil {
+		if _, saved := MirrorFromMetadata(ns.Metadata); saved != nil {
+			status = *saved
+		}
+	}
+
+	var events Subscriber
+	if m.pubsub != nil {
+		events = m.pubsub.SubscribeAll(w.namespace)
+		defer m.pubsub.UnsubscribeAll(w.namespace, events)
+	}
+
+	backoff := tailerPollInterval
+	for {
+		err := w.drain(ctx, &status)
+		if err != nil {
+			log.Warn().Err(err).Int64("position", status.Position).Msg("Mirror push failed, will retry")
+			status.State = MirrorStateFailing
+			status.LastError = err.Error()
+			status.LastErrorAt = time.Now().UTC().Format(time.RFC3339Nano)
+			if err := m.saveStatus(ctx, w, status); err != nil {
+				log.Warn().Err(err).Msg("Failed to record mirror status")
+			}
+			m.notifier.Notify(SystemEvent{
+				Type:      SystemEventConnectorFailed,
+				Severity:  "error",
+				Namespace: w.namespace,
+				Subject:   "mirror",
+				Message:   err.Error(),
+				Data:      map[string]interface{}{"position": status.Position},
+			})
+		}
+
+		wait := tailerPollInterval
+		if err != nil {
+			wait = backoff
+			backoff = min(backoff*2, mirrorMaxBackoff)
+		} else {
+			backoff = tailerPollInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-w.stop:
+			timer.Stop()
+			return
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+			}
+			// Keep backing off while the remote is failing
+			if err != nil {
+				<-timer.C
+			}
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// drain pushes all messages after the recorded position
+func (w *mirrorWorker) drain(ctx context.Context, status *MirrorStatus) error {
+	m := w.mirror
+	for {
+		msgs, err := m.store.GetCategoryMessages(ctx, w.namespace, "", &store.CategoryOpts{
+			Position:  status.Position + 1,
+			BatchSize: int64(w.cfg.BatchSize),
+		})
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+
+		body, err := encodeShippingBatch(msgs)
+		if err != nil {
+			return err
+		}
+		if err := w.push(ctx, body); err != nil {
+			return err
+		}
+
+		status.State = MirrorStateActive
+		status.Position = msgs[len(msgs)-1].GlobalPosition
+		status.LastMirroredAt = time.Now().UTC().Format(time.RFC3339Nano)
+		status.LastError = ""
+		status.LastErrorAt = ""
+		if err := m.saveStatus(ctx, w, *status); err != nil {
+			return fmt.Errorf("failed to record position: %w", err)
+		}
+
+		select {
+		case <-w.stop:
+			return nil
+		default:
+		}
+	}
+}
+
+// push imports one batch into the remote namespace and waits for the result
+func (w *mirrorWorker) push(ctx context.Context, body []byte) error {
+	query := url.Values{
+		"dedupeBy": {ImportDedupeByID},
+		"config":   {"false"},
+		"mirror":   {w.namespace},
+	}
+	target := strings.TrimRight(w.cfg.URL, "/") + "/import?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Authorization", "Bearer "+w.cfg.Token)
+
+	resp, err := w.mirror.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp ErrorResponse
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error != nil {
+			return fmt.Errorf("remote responded with status %d: %s: %s", resp.StatusCode, errResp.Error.Code, errResp.Error.Message)
+		}
+		return fmt.Errorf("remote responded with status %d", resp.StatusCode)
+	}
+
+	// The import streams progress events and ends with done or an error
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event struct {
+			ImportDone
+			ImportError
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return fmt.Errorf("invalid import response: %w", err)
+		}
+		if event.Error != "" {
+			return fmt.Errorf("remote import failed: %s: %s", event.Error, event.Message)
+		}
+		if event.Done {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("remote import ended without a result")
+}
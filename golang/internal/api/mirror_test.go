@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// waitForMirrorStatus polls ns.mirror.status until done accepts the status
+func waitForMirrorStatus(t *testing.T, h *RPCHandler, done func(status map[string]interface{}) bool) map[string]interface{} {
+	t.Helper()
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, rpcErr := h.route(ctx, "ns.mirror.status", nil)
+		if rpcErr != nil {
+			t.Fatalf("ns.mirror.status failed: %v", rpcErr.Message)
+		}
+		info := result.(map[string]interface{})
+		if status, ok := info["status"].(map[string]interface{}); ok && done(status) {
+			return info
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for mirror status, last: %v", info)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestMirror verifies that a namespace is pushed to a remote namespace that
+// rejects its own writes until promoted, after which pushes fail
+func TestMirror(t *testing.T) {
+	ctx := context.Background()
+	primary := newTestStore(t)
+	// Test mode namespaces share one in-memory database per name, so the
+	// replica uses its own namespace
+	replica := newTestStore(t)
+	if err := replica.CreateNamespace(ctx, "replica-ns", "replica-hash", "Replica namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	replicaGuard := NewWriteGuard(NewNamespaceMetadata(replica))
+	importHandler := NewImportHandler(replica)
+	importHandler.SetWriteGuard(replicaGuard)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer replica-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		importHandler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ContextKeyNamespace, "replica-ns")))
+	}))
+	defer server.Close()
+
+	write := func(st store.Store) {
+		t.Helper()
+		if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{
+			StreamName: "order-1",
+			Type:       "Placed",
+			Data:       map[string]interface{}{},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	write(primary)
+	write(primary)
+
+	mirror := NewMirror(primary, NewPubSub())
+	defer mirror.Close()
+	h := NewRPCHandler("test", primary, NewPubSub())
+	h.SetMirror(mirror)
+	rpcCtx := context.WithValue(ctx, ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := h.route(rpcCtx, "ns.mirror.set", []interface{}{map[string]interface{}{
+		"url": "ftp://replica",
+	}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an invalid config, got %v", rpcErr)
+	}
+	result, rpcErr := h.route(rpcCtx, "ns.mirror.set", []interface{}{map[string]interface{}{
+		"url":   server.URL,
+		"token": "replica-token",
+	}})
+	if rpcErr != nil {
+		t.Fatalf("ns.mirror.set failed: %v", rpcErr.Message)
+	}
+	if cfg := result.(map[string]interface{})["config"].(map[string]interface{}); cfg["token"] != logShippingRedacted {
+		t.Errorf("Expected the token to be masked, got %v", cfg["token"])
+	}
+
+	info := waitForMirrorStatus(t, h, func(status map[string]interface{}) bool {
+		return status["position"] == 2.0
+	})
+	if info["caughtUp"] != true {
+		t.Errorf("Expected the mirror to be caught up, got %v", info)
+	}
+	msgs, err := replica.GetStreamMessages(ctx, "replica-ns", "order-1", nil)
+	if err != nil || len(msgs) != 2 || msgs[1].GlobalPosition != 2 {
+		t.Fatalf("Expected 2 mirrored messages, got %v (%v)", msgs, err)
+	}
+
+	// The mirror is read-only for everyone but its primary
+	if err := replicaGuard.Check(ctx, "replica-ns"); !errors.Is(err, ErrNamespaceMirrored) {
+		t.Errorf("Expected ErrNamespaceMirrored, got %v", err)
+	}
+
+	replicaRPC := NewRPCHandler("test", replica, NewPubSub())
+	replicaRPC.SetWriteGuard(replicaGuard)
+	result, rpcErr = replicaRPC.route(context.WithValue(ctx, ContextKeyNamespace, "replica-ns"), "ns.mirror.promote", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.mirror.promote failed: %v", rpcErr.Message)
+	}
+	if result.(map[string]interface{})["source"] != "test-ns" {
+		t.Errorf("Expected source test-ns, got %v", result)
+	}
+	if err := replicaGuard.Check(ctx, "replica-ns"); err != nil {
+		t.Errorf("Expected a promoted mirror to accept writes, got %v", err)
+	}
+
+	// Pushes to the promoted namespace fail instead of mixing histories
+	write(primary)
+	waitForMirrorStatus(t, h, func(status map[string]interface{}) bool {
+		lastError, _ := status["lastError"].(string)
+		return status["state"] == MirrorStateFailing && strings.Contains(lastError, "MIRROR_PROMOTED")
+	})
+}
+
+// TestMirror_Errors tests invalid configs, that mirroring needs a mirror,
+// that only empty namespaces accept a mirror and from one source only, and
+// that promoting a namespace that is not a mirror fails
+func TestMirror_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	valid := map[string]interface{}{"url": "http://127.0.0.1:1", "token": "replica-token"}
+	if _, rpcErr := h.route(ctx, "ns.mirror.set", []interface{}{valid}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without a mirror, got %v", rpcErr)
+	}
+	mirror := NewMirror(st, NewPubSub())
+	defer mirror.Close()
+	h.SetMirror(mirror)
+	for _, args := range [][]interface{}{
+		{},
+		{"http://replica"},
+		{map[string]interface{}{"url": "http://replica"}},
+		{map[string]interface{}{"url": "replica:8080", "token": "replica-token"}},
+		{map[string]interface{}{"url": "http://replica", "token": "replica-token", "batchSize": "10"}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.mirror.set", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// Batch sizes default and are capped
+	for batchSize, want := range map[int]int{0: mirrorDefaultBatch, mirrorMaxBatch + 1: mirrorMaxBatch} {
+		cfg := MirrorConfig{URL: "http://replica", Token: "replica-token", BatchSize: batchSize}
+		if err := cfg.Validate(); err != nil || cfg.BatchSize != want {
+			t.Errorf("Expected batch size %d for %d, got %d (%v)", want, batchSize, cfg.BatchSize, err)
+		}
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.mirror.set", []interface{}{valid}); rpcErr != nil {
+		t.Fatalf("ns.mirror.set failed: %v", rpcErr.Message)
+	}
+	result, rpcErr := h.route(ctx, "ns.mirror.set", []interface{}{nil})
+	if rpcErr != nil {
+		t.Fatalf("ns.mirror.set null failed: %v", rpcErr.Message)
+	}
+	if info := result.(map[string]interface{}); info["enabled"] != false {
+		t.Errorf("Expected mirroring to be disabled, got %v", info)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.mirror.promote", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for promoting a namespace that is not a mirror, got %v", rpcErr)
+	}
+
+	// A namespace with messages of its own cannot become a mirror
+	guard := NewWriteGuard(NewNamespaceMetadata(st))
+	if err := st.CreateNamespace(context.Background(), "replica-ns", "replica-hash", ""); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{Type: "Placed", Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	if err := AcceptMirror(ctx, st, guard, "test-ns", "primary-ns"); !errors.Is(err, ErrMirrorConflict) {
+		t.Errorf("Expected ErrMirrorConflict for a namespace with messages, got %v", err)
+	}
+
+	// Once a mirror, only its source may push, until it is promoted
+	if err := AcceptMirror(ctx, st, guard, "replica-ns", "primary-ns"); err != nil {
+		t.Fatalf("AcceptMirror failed: %v", err)
+	}
+	if err := AcceptMirror(ctx, st, guard, "replica-ns", "other-ns"); !errors.Is(err, ErrMirrorConflict) {
+		t.Errorf("Expected ErrMirrorConflict for another source, got %v", err)
+	}
+	if _, err := PromoteMirror(ctx, st, guard, "replica-ns"); err != nil {
+		t.Fatalf("PromoteMirror failed: %v", err)
+	}
+	if err := AcceptMirror(ctx, st, guard, "replica-ns", "primary-ns"); !errors.Is(err, ErrMirrorPromoted) {
+		t.Errorf("Expected ErrMirrorPromoted after promotion, got %v", err)
+	}
+}
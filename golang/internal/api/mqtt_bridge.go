@@ -0,0 +1,380 @@
+// Package api provides an MQTT bridge for IoT device ingestion.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// MQTTBridgeConfig configures the MQTT bridge
+//
+// Example config file:
+//
+//	{
+//	  "broker": "tcp://mqtt.local:1883",
+//	  "routes": [
+//	    {"topic": "devices/+/telemetry", "stream": "telemetry-{device}", "type": "Reading", "deviceLevel": 2}
+//	  ],
+//	  "devices": {"dev1": "ns_..."}
+//	}
+type MQTTBridgeConfig struct {
+	Broker   string            `json:"broker"`             // Broker URL, e.g. tcp://host:1883
+	ClientID string            `json:"clientId,omitempty"` // MQTT client ID (default: eventodb-bridge)
+	Username string            `json:"username,omitempty"`
+	Password string            `json:"password,omitempty"`
+	QoS      byte              `json:"qos,omitempty"`     // Subscription QoS (0 or 1)
+	Routes   []MQTTRoute       `json:"routes"`            // Topic → stream mappings
+	Devices  map[string]string `json:"devices,omitempty"` // Device ID → namespace token
+	TestMode bool              `json:"-"`                 // Skip token hash verification
+}
+
+// MQTTRoute maps an MQTT topic filter to a stream name template.
+//
+// Stream templates may reference topic levels by 1-based index ({1}, {2}, ...)
+// and the device ID ({device}). The device ID is the topic level at DeviceLevel
+// and selects the namespace token from MQTTBridgeConfig.Devices. Routes without
+// a device level use Token for every message.
+type MQTTRoute struct {
+	Topic       string `json:"topic"`                 // Topic filter (supports + and #)
+	Stream      string `json:"stream"`                // Stream name template
+	Type        string `json:"type,omitempty"`        // Message type when the payload has none
+	Token       string `json:"token,omitempty"`       // Namespace token for the route
+	DeviceLevel int    `json:"deviceLevel,omitempty"` // 1-based topic level holding the device ID
+}
+
+// LoadMQTTBridgeConfig reads an MQTT bridge config from a JSON file
+func LoadMQTTBridgeConfig(path string) (*MQTTBridgeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MQTT config: %w", err)
+	}
+
+	var cfg MQTTBridgeConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse MQTT config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// MQTTBridge subscribes to MQTT topics and writes received payloads to streams
+type MQTTBridge struct {
+	store  store.Store
+	pubsub *PubSub
+	cfg    MQTTBridgeConfig
+	client mqtt.Client
+	guard  *WriteGuard             // Optional, rejects writes to frozen namespaces
+	names  *store.StreamNamePolicy // Optional, rejects invalid rendered stream names
+	admit  *AdmissionController    // Optional, drops messages over the write rate limits
+	tmpls  *MetadataTemplates      // Optional, merges per-type default metadata
+	ids    *MessageIDs             // Optional, generates IDs with the namespace's strategy
+	plugin *PluginHost             // Optional, runs the namespace's write plugins
+}
+
+// NewMQTTBridge creates a new MQTT bridge after validating its routes
+func NewMQTTBridge(st store.Store, pubsub *PubSub, cfg MQTTBridgeConfig) (*MQTTBridge, error) {
+	if cfg.ClientID == "" {
+		cfg.ClientID = "eventodb-bridge"
+	}
+	if cfg.QoS > 1 {
+		return nil, fmt.Errorf("unsupported MQTT QoS %d (use 0 or 1)", cfg.QoS)
+	}
+	if len(cfg.Routes) == 0 {
+		return nil, fmt.Errorf("MQTT config requires at least one route")
+	}
+
+	for i, route := range cfg.Routes {
+		if route.Topic == "" || route.Stream == "" {
+			return nil, fmt.Errorf("route %d: topic and stream are required", i)
+		}
+		if route.DeviceLevel == 0 && route.Token == "" {
+			return nil, fmt.Errorf("route %d: either token or deviceLevel is required", i)
+		}
+		if route.DeviceLevel == 0 && strings.Contains(route.Stream, "{device}") {
+			return nil, fmt.Errorf("route %d: {device} requires deviceLevel", i)
+		}
+	}
+
+	return &MQTTBridge{
+		store:  st,
+		pubsub: pubsub,
+		cfg:    cfg,
+	}, nil
+}
+
+// SetWriteGuard rejects messages for frozen namespaces and read-only servers
+func (b *MQTTBridge) SetWriteGuard(g *WriteGuard) {
+	b.guard = g
+}
+
+// SetMetadataTemplates merges the namespace's metadata templates into bridged messages
+func (b *MQTTBridge) SetMetadataTemplates(m *MetadataTemplates) {
+	b.tmpls = m
+}
+
+// SetMessageIDs generates the IDs of bridged messages with the namespace's strategy
+func (b *MQTTBridge) SetMessageIDs(m *MessageIDs) {
+	b.ids = m
+}
+
+// SetPluginHost runs the namespace's write plugins on bridged messages
+func (b *MQTTBridge) SetPluginHost(p *PluginHost) {
+	b.plugin = p
+}
+
+// SetStreamNamePolicy rejects messages whose rendered stream name breaks the policy
+func (b *MQTTBridge) SetStreamNamePolicy(p *store.StreamNamePolicy) {
+	b.names = p
+}
+
+// SetAdmission drops messages while the write rate limits are exhausted
+func (b *MQTTBridge) SetAdmission(a *AdmissionController) {
+	b.admit = a
+}
+
+// Start connects to the broker and subscribes to all route topics
+func (b *MQTTBridge) Start() error {
+	opts := mqtt.NewClientOptions().
+		AddBroker(b.cfg.Broker).
+		SetClientID(b.cfg.ClientID).
+		SetUsername(b.cfg.Username).
+		SetPassword(b.cfg.Password).
+		SetAutoReconnect(true).
+		SetCleanSession(false).
+		SetConnectTimeout(10 * time.Second)
+
+	// Resubscribe on every (re)connect
+	opts.SetOnConnectHandler(func(c mqtt.Client) {
+		for _, route := range b.cfg.Routes {
+			token := c.Subscribe(route.Topic, b.cfg.QoS, b.onMessage)
+			if token.Wait() && token.Error() != nil {
+				logger.Get().Error().
+					Err(token.Error()).
+					Str("topic", route.Topic).
+					Msg("MQTT subscribe failed")
+			}
+		}
+	})
+
+	b.client = mqtt.NewClient(opts)
+	token := b.client.Connect()
+	if token.Wait() && token.Error() != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", token.Error())
+	}
+
+	logger.Get().Info().
+		Str("broker", b.cfg.Broker).
+		Int("routes", len(b.cfg.Routes)).
+		Msg("MQTT bridge started")
+	return nil
+}
+
+// Close disconnects from the broker
+func (b *MQTTBridge) Close() {
+	if b.client != nil {
+		b.client.Disconnect(250)
+	}
+}
+
+// onMessage is the paho callback for all subscriptions
+func (b *MQTTBridge) onMessage(_ mqtt.Client, m mqtt.Message) {
+	if err := b.HandleMessage(context.Background(), m.Topic(), m.Payload()); err != nil {
+		logger.Get().Warn().
+			Err(err).
+			Str("topic", m.Topic()).
+			Msg("MQTT message dropped")
+	}
+}
+
+// HandleMessage routes a single MQTT message to its stream and writes it
+func (b *MQTTBridge) HandleMessage(ctx context.Context, topic string, payload []byte) error {
+	levels := strings.Split(topic, "/")
+
+	route, ok := b.matchRoute(levels)
+	if !ok {
+		return fmt.Errorf("no route for topic %s", topic)
+	}
+
+	// Resolve device and token
+	var device string
+	token := route.Token
+	if route.DeviceLevel > 0 {
+		if route.DeviceLevel > len(levels) {
+			return fmt.Errorf("topic %s has no level %d", topic, route.DeviceLevel)
+		}
+		device = levels[route.DeviceLevel-1]
+		deviceToken, ok := b.cfg.Devices[device]
+		if !ok {
+			return fmt.Errorf("unknown device %s", device)
+		}
+		token = deviceToken
+	}
+
+	namespace, err := b.authorize(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := b.guard.Check(ctx, namespace); err != nil {
+		return err
+	}
+	if wait, ok := b.admit.Admit(namespace, PriorityCDC, 1); !ok {
+		return fmt.Errorf("write rate limit exceeded, retry in %s", wait)
+	}
+
+	streamName := renderStreamTemplate(route.Stream, levels, device)
+	if err := b.names.Validate(streamName); err != nil {
+		return err
+	}
+
+	msgType, data, metadata, err := decodeMQTTPayload(payload, route.Type)
+	if err != nil {
+		return err
+	}
+
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["mqttTopic"] = topic
+
+	msg := &store.Message{
+		StreamName: streamName,
+		Type:       msgType,
+		Data:       data,
+		Metadata:   metadata,
+	}
+	b.ids.Assign(ctx, namespace, msg)
+	b.tmpls.Apply(ctx, namespace, msg)
+	if err := b.plugin.Apply(ctx, namespace, msg); err != nil {
+		return err
+	}
+
+	result, err := b.store.WriteMessage(ctx, namespace, streamName, msg)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	if b.pubsub != nil {
+		b.pubsub.Publish(WriteEvent{
+			Namespace:      namespace,
+			Stream:         streamName,
+			Category:       store.Category(streamName),
+			Position:       result.Position,
+			GlobalPosition: result.GlobalPosition,
+		})
+	}
+	return nil
+}
+
+// matchRoute returns the first route whose topic filter matches
+func (b *MQTTBridge) matchRoute(levels []string) (MQTTRoute, bool) {
+	for _, route := range b.cfg.Routes {
+		if topicMatches(route.Topic, levels) {
+			return route, true
+		}
+	}
+	return MQTTRoute{}, false
+}
+
+// authorize resolves and verifies the namespace for a token
+func (b *MQTTBridge) authorize(ctx context.Context, token string) (string, error) {
+	namespace, err := auth.ParseToken(token)
+	if err != nil {
+		return "", fmt.Errorf("invalid token: %w", err)
+	}
+
+	ns, err := b.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return "", fmt.Errorf("namespace %s: %w", namespace, err)
+	}
+	if !b.cfg.TestMode {
+		if err := verifyNamespaceToken(ns, token, time.Now()); err != nil {
+			return "", fmt.Errorf("namespace %s: %w", namespace, err)
+		}
+	}
+	return namespace, nil
+}
+
+// topicMatches reports whether topic levels match an MQTT topic filter
+func topicMatches(filter string, levels []string) bool {
+	parts := strings.Split(filter, "/")
+	for i, part := range parts {
+		if part == "#" {
+			return true
+		}
+		if i >= len(levels) {
+			return false
+		}
+		if part != "+" && part != levels[i] {
+			return false
+		}
+	}
+	return len(parts) == len(levels)
+}
+
+// renderStreamTemplate substitutes {device} and {N} topic level placeholders
+func renderStreamTemplate(tmpl string, levels []string, device string) string {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			sb.WriteString(tmpl)
+			return sb.String()
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			sb.WriteString(tmpl)
+			return sb.String()
+		}
+		end += start
+
+		sb.WriteString(tmpl[:start])
+		key := tmpl[start+1 : end]
+		if key == "device" {
+			sb.WriteString(device)
+		} else if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(levels) {
+			sb.WriteString(levels[n-1])
+		} else {
+			// Leave unknown placeholders untouched
+			sb.WriteString(tmpl[start : end+1])
+		}
+		tmpl = tmpl[end+1:]
+	}
+}
+
+// decodeMQTTPayload extracts type, data, and metadata from a JSON payload.
+//
+// Payloads shaped like {"type": ..., "data": {...}, "metadata": {...}} are used
+// as-is. Any other JSON object is treated as the data of a message with the
+// route's default type.
+func decodeMQTTPayload(payload []byte, defaultType string) (string, map[string]interface{}, map[string]interface{}, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(payload, &obj); err != nil {
+		return "", nil, nil, fmt.Errorf("payload must be a JSON object: %w", err)
+	}
+
+	if data, ok := obj["data"].(map[string]interface{}); ok {
+		msgType, _ := obj["type"].(string)
+		if msgType == "" {
+			msgType = defaultType
+		}
+		if msgType == "" {
+			return "", nil, nil, fmt.Errorf("message type is required")
+		}
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		return msgType, data, metadata, nil
+	}
+
+	if defaultType == "" {
+		return "", nil, nil, fmt.Errorf("message type is required")
+	}
+	return defaultType, obj, nil, nil
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/eventodb/eventodb/internal/store/sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// setupMQTTBridgeStore creates an in-memory store with one namespace and returns its token
+func setupMQTTBridgeStore(t *testing.T) (store.Store, string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	st, err := sqlite.New(db, &sqlite.Config{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	token, err := auth.GenerateToken("fleet")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if err := st.CreateNamespace(context.Background(), "fleet", auth.HashToken(token), "Fleet"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	return st, token
+}
+
+// TestTopicMatches verifies MQTT topic filter matching
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		filter string
+		topic  string
+		want   bool
+	}{
+		{"devices/+/telemetry", "devices/dev1/telemetry", true},
+		{"devices/+/telemetry", "devices/dev1/status", false},
+		{"devices/+/telemetry", "devices/dev1/telemetry/extra", false},
+		{"devices/#", "devices/dev1/telemetry", true},
+		{"devices/#", "sensors/dev1", false},
+		{"a/b", "a/b", true},
+		{"a/b/c", "a/b", false},
+	}
+
+	for _, tt := range tests {
+		got := topicMatches(tt.filter, strings.Split(tt.topic, "/"))
+		if got != tt.want {
+			t.Errorf("topicMatches(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
+
+// TestRenderStreamTemplate verifies placeholder substitution in stream templates
+func TestRenderStreamTemplate(t *testing.T) {
+	levels := strings.Split("site7/devices/dev1/telemetry", "/")
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"telemetry-{device}", "telemetry-dev1"},
+		{"telemetry:{1}-{3}", "telemetry:site7-dev1"},
+		{"telemetry-{9}", "telemetry-{9}"},
+		{"telemetry-{other}", "telemetry-{other}"},
+		{"telemetry-{unterminated", "telemetry-{unterminated"},
+	}
+
+	for _, tt := range tests {
+		got := renderStreamTemplate(tt.tmpl, levels, "dev1")
+		if got != tt.want {
+			t.Errorf("renderStreamTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+// TestMQTTBridge_HandleMessage_DeviceToken verifies per-device token routing
+func TestMQTTBridge_HandleMessage_DeviceToken(t *testing.T) {
+	st, token := setupMQTTBridgeStore(t)
+	ctx := context.Background()
+
+	bridge, err := NewMQTTBridge(st, NewPubSub(), MQTTBridgeConfig{
+		Broker: "tcp://localhost:1883",
+		Routes: []MQTTRoute{
+			{Topic: "devices/+/telemetry", Stream: "telemetry-{device}", Type: "Reading", DeviceLevel: 2},
+		},
+		Devices: map[string]string{"dev1": token},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create bridge: %v", err)
+	}
+
+	// Plain JSON object uses the route's default type
+	if err := bridge.HandleMessage(ctx, "devices/dev1/telemetry", []byte(`{"t": 21.5}`)); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	// Envelope payload carries its own type
+	if err := bridge.HandleMessage(ctx, "devices/dev1/telemetry", []byte(`{"type": "Alarm", "data": {"code": 3}}`)); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "fleet", "telemetry-dev1", &store.GetOpts{BatchSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to read stream: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(msgs))
+	}
+	if msgs[0].Type != "Reading" || msgs[0].Data["t"] != 21.5 {
+		t.Errorf("Unexpected first message: %+v", msgs[0])
+	}
+	if msgs[1].Type != "Alarm" {
+		t.Errorf("Expected type Alarm, got %s", msgs[1].Type)
+	}
+	if msgs[0].Metadata["mqttTopic"] != "devices/dev1/telemetry" {
+		t.Errorf("Expected mqttTopic metadata, got %v", msgs[0].Metadata)
+	}
+}
+
+// TestMQTTBridge_HandleMessage_Rejects verifies unroutable and unauthorized messages are rejected
+func TestMQTTBridge_HandleMessage_Rejects(t *testing.T) {
+	st, token := setupMQTTBridgeStore(t)
+	ctx := context.Background()
+
+	otherToken, _ := auth.GenerateToken("fleet")
+
+	bridge, err := NewMQTTBridge(st, nil, MQTTBridgeConfig{
+		Broker: "tcp://localhost:1883",
+		Routes: []MQTTRoute{
+			{Topic: "devices/+/telemetry", Stream: "telemetry-{device}", Type: "Reading", DeviceLevel: 2},
+		},
+		Devices: map[string]string{"dev1": token, "dev2": otherToken},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create bridge: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		topic   string
+		payload string
+	}{
+		{"no route", "sensors/dev1", `{"t": 1}`},
+		{"unknown device", "devices/dev9/telemetry", `{"t": 1}`},
+		{"wrong token", "devices/dev2/telemetry", `{"t": 1}`},
+		{"not json", "devices/dev1/telemetry", `21.5`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := bridge.HandleMessage(ctx, tt.topic, []byte(tt.payload)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestNewMQTTBridge_InvalidConfig verifies route validation
+func TestNewMQTTBridge_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  MQTTBridgeConfig
+	}{
+		{"no routes", MQTTBridgeConfig{}},
+		{"missing stream", MQTTBridgeConfig{Routes: []MQTTRoute{{Topic: "a", Token: "t"}}}},
+		{"no token source", MQTTBridgeConfig{Routes: []MQTTRoute{{Topic: "a", Stream: "s"}}}},
+		{"device without level", MQTTBridgeConfig{Routes: []MQTTRoute{{Topic: "a", Stream: "s-{device}", Token: "t"}}}},
+		{"qos 2", MQTTBridgeConfig{QoS: 2, Routes: []MQTTRoute{{Topic: "a", Stream: "s", Token: "t"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewMQTTBridge(nil, nil, tt.cfg); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Package api provides export and import of namespace configuration.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// namespaceConfigVersion is the current NamespaceConfig format version
+const namespaceConfigVersion = 1
+
+// ErrInvalidNamespaceConfig is returned when a config snapshot cannot be applied
+var ErrInvalidNamespaceConfig = errors.New("invalid namespace config")
+
+// namespaceRuntimeMetadataKeys are metadata keys that describe state on the
+// current cluster rather than tenant configuration. They are never exported
+// and are preserved on the target when config is imported.
+var namespaceRuntimeMetadataKeys = map[string]bool{
+	logShippingStatusMetadataKey:    true,
+	exportScheduleStatusMetadataKey: true,
+	backupsMetadataKey:              true, // Lists archives written by this cluster
+	frozenMetadataKey:               true, // Freezes are not carried to a re-created namespace
+	suspendedMetadataKey:            true, // Set by the hosting platform, never by the tenant
+	"backend":                       true, // TimescaleDB backend marker
+	store.ShardMetadataKey:          true, // Placement is chosen when the namespace is created
+	tokenMetadataKey:                true, // Describes the token issued by this cluster
+	revokedTokensMetadataKey:        true,
+	mirrorMetadataKey:               true, // Holds the remote token and is set up per cluster
+	mirrorStatusMetadataKey:         true,
+	mirrorOfMetadataKey:             true,
+	edgeSyncMetadataKey:             true, // Holds the hub token and is set up per edge
+	edgeSyncStatusMetadataKey:       true,
+	wormMetadataKey:                 true, // Can never be removed, so imports must not drop it
+	wormAttestationMetadataKey:      true,
+	blueprintMetadataKey:            true, // Starts the blueprint's webhooks on this cluster
+	importStagingKey:                true, // Points at a staging namespace on this cluster
+	typeStatsMetadataKey:            true, // Counts the messages stored on this cluster
+	udpDevicesMetadataKey:           true, // Holds device keys issued by this cluster
+}
+
+// NamespaceConfig is a portable snapshot of a namespace's configuration.
+//
+// It travels as the first line of an export ({"namespaceConfig": {...}}) or
+// through ns.config.export / ns.config.import. Token hashes are never included.
+// Webhooks are operator-managed (--webhook-config), so they are exported
+// without secrets for reference and are not applied on import.
+type NamespaceConfig struct {
+	Version     int                    `json:"version"`
+	Namespace   string                 `json:"namespace"`
+	Description string                 `json:"description"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Webhooks    []WebhookRoute         `json:"webhooks,omitempty"`
+}
+
+// ExportNamespaceConfig builds the config snapshot of a namespace.
+// hooks may be nil.
+func ExportNamespaceConfig(ctx context.Context, st store.Store, hooks *WebhookPublisher, namespace string) (*NamespaceConfig, error) {
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &NamespaceConfig{
+		Version:     namespaceConfigVersion,
+		Namespace:   ns.ID,
+		Description: ns.Description,
+		Metadata:    make(map[string]interface{}, len(ns.Metadata)),
+	}
+	for k, v := range ns.Metadata {
+		if !namespaceRuntimeMetadataKeys[k] {
+			cfg.Metadata[k] = v
+		}
+	}
+
+	if hooks != nil {
+		for _, hook := range hooks.Hooks(namespace) {
+			hook.Secrets = nil
+			cfg.Webhooks = append(cfg.Webhooks, hook)
+		}
+	}
+	return cfg, nil
+}
+
+// namespaceConfigValidators check the configuration keys of namespace
+// metadata as the methods setting them do, so ApplyNamespaceConfig stores no
+// value those methods would reject. Other keys are stored as they are.
+var namespaceConfigValidators = map[string]func(raw interface{}) error{
+	logShippingMetadataKey: func(raw interface{}) error {
+		var cfg LogShippingConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	exportScheduleMetadataKey: func(raw interface{}) error {
+		var cfg ExportScheduleConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	retentionMetadataKey: func(raw interface{}) error {
+		var cfg RetentionConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	categoryViewsMetadataKey: func(raw interface{}) error {
+		var cfg CategoryViewsConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	streamAliasesMetadataKey: func(raw interface{}) error {
+		var cfg StreamAliasesConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	metadataTemplatesMetadataKey: func(raw interface{}) error {
+		var cfg MetadataTemplatesConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	pluginsMetadataKey: func(raw interface{}) error {
+		var cfg PluginsConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	readPluginsMetadataKey: func(raw interface{}) error {
+		var cfg ReadPluginsConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	derivedStreamsMetadataKey: func(raw interface{}) error {
+		var cfg DerivedStreamsConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	standingQueriesMetadataKey: func(raw interface{}) error {
+		var cfg StandingQueriesConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	snapshotsMetadataKey: func(raw interface{}) error {
+		var cfg SnapshotsConfig
+		return decodeAndValidate(raw, &cfg, cfg.Validate)
+	},
+	messageIDsMetadataKey: func(raw interface{}) error {
+		name, ok := raw.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		_, err := ParseIDStrategy(name)
+		return err
+	},
+	ticksMetadataKey: func(raw interface{}) error {
+		var set tickSet
+		return decodeAndValidate(raw, &set, func() error {
+			seen := make(map[string]bool, len(set.Ticks))
+			for i := range set.Ticks {
+				if err := set.Ticks[i].Validate(); err != nil {
+					return err
+				}
+				if seen[set.Ticks[i].Name] {
+					return fmt.Errorf("%w: %q", ErrTickExists, set.Ticks[i].Name)
+				}
+				seen[set.Ticks[i].Name] = true
+			}
+			return nil
+		})
+	},
+	bookmarksMetadataKey: func(raw interface{}) error {
+		var bookmarks map[string]Bookmark
+		return decodeAndValidate(raw, &bookmarks, func() error {
+			for name := range bookmarks {
+				if err := ValidateBookmarkName(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	},
+}
+
+// decodeAndValidate decodes a metadata value into v and runs validate on it
+func decodeAndValidate(raw interface{}, v interface{}, validate func() error) error {
+	if err := decodeMetadataValue(raw, v); err != nil {
+		return fmt.Errorf("cannot decode: %v", err)
+	}
+	return validate()
+}
+
+// ApplyNamespaceConfig replaces a namespace's configuration with a snapshot.
+//
+// Runtime metadata of the target is kept. When shipper is set, log shipping is
+// reconfigured immediately; otherwise the stored config takes effect when a
+// shipper next starts. Description and webhooks are not applied.
+func ApplyNamespaceConfig(ctx context.Context, st store.Store, shipper *LogShipper, namespace string, cfg *NamespaceConfig) error {
+	if cfg.Version > namespaceConfigVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidNamespaceConfig, cfg.Version)
+	}
+
+	imported := make(map[string]interface{}, len(cfg.Metadata))
+	for k, v := range cfg.Metadata {
+		if !namespaceRuntimeMetadataKeys[k] {
+			imported[k] = v
+		}
+	}
+
+	keys := make([]string, 0, len(imported))
+	for k := range imported {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		validate, ok := namespaceConfigValidators[k]
+		if !ok || imported[k] == nil {
+			continue
+		}
+		if err := validate(imported[k]); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidNamespaceConfig, k, err)
+		}
+	}
+	if len(RetentionFromMetadata(imported).Rules) > 0 {
+		if err := CheckWorm(ctx, st, namespace); errors.Is(err, ErrWormProtected) {
+			return fmt.Errorf("%w: retention: %v", ErrInvalidNamespaceConfig, err)
+		}
+	}
+	shipping, _ := LogShippingFromMetadata(imported)
+
+	err := updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		current := metadata[logShippingMetadataKey]
+		for k := range metadata {
+			if !namespaceRuntimeMetadataKeys[k] {
+				delete(metadata, k)
+			}
+		}
+		for k, v := range imported {
+			metadata[k] = v
+		}
+
+		// Let the shipper own the logShipping key so its worker is restarted
+		if shipper != nil {
+			delete(metadata, logShippingMetadataKey)
+			if current != nil {
+				metadata[logShippingMetadataKey] = current
+			}
+		}
+	})
+	if err != nil || shipper == nil {
+		return err
+	}
+	return shipper.Configure(ctx, namespace, shipping)
+}
+
+// namespaceMetadataMu serializes read-modify-write of namespace metadata
+var namespaceMetadataMu sync.Mutex
+
+// updateNamespaceMetadata applies update to a copy of a namespace's metadata and stores it
+func updateNamespaceMetadata(ctx context.Context, st store.Store, namespace string, update func(metadata map[string]interface{})) error {
+	namespaceMetadataMu.Lock()
+	defer namespaceMetadataMu.Unlock()
+
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	metadata := copyMetadata(ns.Metadata)
+	update(metadata)
+	if err := st.UpdateNamespaceMetadata(ctx, namespace, metadata); err != nil {
+		return err
+	}
+	return recordConfigChanges(ctx, st, namespace, ns.Metadata, metadata)
+}
+
+// copyMetadata returns a shallow copy of a metadata map (never nil)
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(metadata)+2)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	return out
+}
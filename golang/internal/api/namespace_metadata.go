@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// namespaceMetadataTTL bounds how long cached namespace metadata is trusted,
+// so settings changed through another instance take effect within this time
+const namespaceMetadataTTL = 2 * time.Second
+
+// NamespaceMetadata caches the metadata of namespaces for the features
+// configured by it (write guard, derived streams, standing queries, category
+// views, stream aliases, metadata templates, message IDs and plugins), so
+// writes and reads do not read it each time. Each feature decodes its own
+// key; decoded values are kept until the metadata is read again.
+type NamespaceMetadata struct {
+	store store.Store
+
+	mu      sync.Mutex
+	entries map[string]*namespaceMetadataEntry
+}
+
+// namespaceMetadataEntry is the cached metadata of a namespace
+type namespaceMetadataEntry struct {
+	metadata map[string]interface{}
+	checked  time.Time
+	decoded  map[string]interface{} // By decoder name
+}
+
+// NewNamespaceMetadata creates a metadata cache backed by st
+func NewNamespaceMetadata(st store.Store) *NamespaceMetadata {
+	return &NamespaceMetadata{
+		store:   st,
+		entries: make(map[string]*namespaceMetadataEntry),
+	}
+}
+
+// entry returns the cached metadata of namespace, read again once older than
+// namespaceMetadataTTL. If it cannot be read, the last entry read is
+// returned, or nil, with the error.
+func (m *NamespaceMetadata) entry(ctx context.Context, namespace string) (*namespaceMetadataEntry, error) {
+	m.mu.Lock()
+	entry, ok := m.entries[namespace]
+	m.mu.Unlock()
+	if ok && time.Since(entry.checked) <= namespaceMetadataTTL {
+		return entry, nil
+	}
+
+	ns, err := m.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return entry, err
+	}
+	entry = &namespaceMetadataEntry{
+		metadata: ns.Metadata,
+		checked:  time.Now(),
+		decoded:  make(map[string]interface{}),
+	}
+	m.mu.Lock()
+	m.entries[namespace] = entry
+	m.mu.Unlock()
+	return entry, nil
+}
+
+// decode returns what decode derives from namespace's metadata, decoded once
+// per read of the metadata; name identifies the decoder. If the metadata
+// cannot be read, the value decoded from the last metadata read is returned,
+// or nil, with the error.
+func (m *NamespaceMetadata) decode(ctx context.Context, namespace, name string, decode func(metadata map[string]interface{}) interface{}) (interface{}, error) {
+	entry, err := m.entry(ctx, namespace)
+	if entry == nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := entry.decoded[name]
+	if !ok {
+		value = decode(entry.metadata)
+		entry.decoded[name] = value
+	}
+	return value, err
+}
+
+// Update applies update to a copy of a namespace's metadata, stores it and
+// drops the cached metadata, so the next lookup sees the change
+func (m *NamespaceMetadata) Update(ctx context.Context, namespace string, update func(metadata map[string]interface{})) error {
+	err := updateNamespaceMetadata(ctx, m.store, namespace, update)
+	if err != nil {
+		return err
+	}
+	m.Forget(namespace)
+	return nil
+}
+
+// Forget drops the cached metadata of a namespace, e.g. after it was
+// changed or deleted
+func (m *NamespaceMetadata) Forget(namespace string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	delete(m.entries, namespace)
+	m.mu.Unlock()
+}
@@ -0,0 +1,273 @@
+// Package api provides a notifier that routes system events to Slack or email.
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+)
+
+const (
+	// notifierQueueSize bounds the number of pending notifications
+	notifierQueueSize = 256
+
+	// notifierDefaultInterval is the default minimum time between repeats of the same event
+	notifierDefaultInterval = 5 * time.Minute
+)
+
+// System event types
+const (
+	SystemEventWebhookDeadLettered = "webhook.deadLettered"
+	SystemEventConnectorFailed     = "connector.failed"
+	SystemEventIntegrityAnomaly    = "integrity.anomaly"
+	SystemEventExportFailed        = "export.failed"
+)
+
+// SystemEvent describes an operational event worth alerting on
+type SystemEvent struct {
+	Type      string                 // Event type, e.g. webhook.deadLettered
+	Severity  string                 // info, warning, or error
+	Namespace string                 // Affected namespace (empty for server-wide events)
+	Subject   string                 // What the event is about, e.g. a hook or connector name
+	Message   string                 // Human-readable summary
+	Data      map[string]interface{} // Extra detail
+	Time      time.Time
+}
+
+// NotifierConfig configures where system events are sent
+//
+// Example config file:
+//
+//	{
+//	  "slack": {"webhookUrl": "https://hooks.slack.com/services/..."},
+//	  "smtp": {"addr": "smtp.example.com:587", "from": "eventodb@example.com", "to": ["ops@example.com"]},
+//	  "events": ["webhook.deadLettered"],
+//	  "minInterval": "10m"
+//	}
+type NotifierConfig struct {
+	Slack       *SlackConfig `json:"slack,omitempty"`
+	SMTP        *SMTPConfig  `json:"smtp,omitempty"`
+	Events      []string     `json:"events,omitempty"`      // Event types to send (empty = all)
+	MinInterval string       `json:"minInterval,omitempty"` // Suppress repeats of the same event (default: 5m)
+}
+
+// SlackConfig configures a Slack incoming webhook
+type SlackConfig struct {
+	WebhookURL string `json:"webhookUrl"`
+}
+
+// SMTPConfig configures email delivery
+type SMTPConfig struct {
+	Addr     string   `json:"addr"` // host:port
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+}
+
+// LoadNotifierConfig reads a notifier config from a JSON file
+func LoadNotifierConfig(path string) (*NotifierConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notifier config: %w", err)
+	}
+
+	var cfg NotifierConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse notifier config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Notifier sends system events to Slack and/or SMTP.
+//
+// Notify never blocks: events are queued and sent by a background goroutine,
+// repeats of the same event (type, namespace, subject) within MinInterval are
+// suppressed, and events arriving while the queue is full are dropped.
+// A nil *Notifier is valid and discards all events.
+type Notifier struct {
+	cfg         NotifierConfig
+	events      map[string]bool
+	minInterval time.Duration
+	client      *http.Client
+	sendMail    func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+
+	queue chan SystemEvent
+	done  chan struct{}
+}
+
+// NewNotifier creates a notifier and starts its delivery goroutine
+func NewNotifier(cfg NotifierConfig) (*Notifier, error) {
+	if cfg.Slack == nil && cfg.SMTP == nil {
+		return nil, fmt.Errorf("notifier requires slack or smtp configuration")
+	}
+	if cfg.Slack != nil && cfg.Slack.WebhookURL == "" {
+		return nil, fmt.Errorf("slack.webhookUrl is required")
+	}
+	if cfg.SMTP != nil && (cfg.SMTP.Addr == "" || cfg.SMTP.From == "" || len(cfg.SMTP.To) == 0) {
+		return nil, fmt.Errorf("smtp.addr, smtp.from and smtp.to are required")
+	}
+
+	minInterval := notifierDefaultInterval
+	if cfg.MinInterval != "" {
+		d, err := time.ParseDuration(cfg.MinInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minInterval: %w", err)
+		}
+		minInterval = d
+	}
+
+	events := make(map[string]bool, len(cfg.Events))
+	for _, e := range cfg.Events {
+		events[e] = true
+	}
+
+	n := &Notifier{
+		cfg:         cfg,
+		events:      events,
+		minInterval: minInterval,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		sendMail:    smtp.SendMail,
+		lastSent:    make(map[string]time.Time),
+		queue:       make(chan SystemEvent, notifierQueueSize),
+		done:        make(chan struct{}),
+	}
+	go n.loop()
+	return n, nil
+}
+
+// Notify queues a system event for delivery
+func (n *Notifier) Notify(event SystemEvent) {
+	if n == nil {
+		return
+	}
+	if len(n.events) > 0 && !n.events[event.Type] {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	// Suppress repeats within the minimum interval
+	key := event.Type + "|" + event.Namespace + "|" + event.Subject
+	n.mu.Lock()
+	if last, ok := n.lastSent[key]; ok && event.Time.Sub(last) < n.minInterval {
+		n.mu.Unlock()
+		return
+	}
+	n.lastSent[key] = event.Time
+	n.mu.Unlock()
+
+	select {
+	case n.queue <- event:
+	default:
+		logger.Get().Warn().Str("event", event.Type).Msg("Notifier queue full, dropping event")
+	}
+}
+
+// Close flushes queued events and stops the delivery goroutine
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	close(n.queue)
+	<-n.done
+}
+
+// loop delivers queued events until the queue is closed
+func (n *Notifier) loop() {
+	defer close(n.done)
+	for event := range n.queue {
+		n.send(event)
+	}
+}
+
+// send delivers a single event to every configured destination
+func (n *Notifier) send(event SystemEvent) {
+	text := formatSystemEvent(event)
+
+	if n.cfg.Slack != nil {
+		if err := n.sendSlack(text); err != nil {
+			logger.Get().Warn().Err(err).Str("event", event.Type).Msg("Failed to send Slack notification")
+		}
+	}
+	if n.cfg.SMTP != nil {
+		if err := n.sendSMTP(event, text); err != nil {
+			logger.Get().Warn().Err(err).Str("event", event.Type).Msg("Failed to send email notification")
+		}
+	}
+}
+
+// sendSlack posts a message to a Slack incoming webhook
+func (n *Notifier) sendSlack(text string) error {
+	body, _ := json.Marshal(map[string]string{"text": text})
+	resp, err := n.client.Post(n.cfg.Slack.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendSMTP emails the event to all recipients
+func (n *Notifier) sendSMTP(event SystemEvent, text string) error {
+	cfg := n.cfg.SMTP
+
+	var a smtp.Auth
+	if cfg.Username != "" {
+		host := cfg.Addr
+		if i := strings.LastIndexByte(host, ':'); i >= 0 {
+			host = host[:i]
+		}
+		a = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [EventoDB] %s %s\r\n", strings.ToUpper(event.Severity), event.Type)
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(text)
+	msg.WriteString("\r\n")
+
+	return n.sendMail(cfg.Addr, a, cfg.From, cfg.To, msg.Bytes())
+}
+
+// formatSystemEvent renders an event as plain text
+func formatSystemEvent(event SystemEvent) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] %s: %s", strings.ToUpper(event.Severity), event.Type, event.Message)
+	if event.Namespace != "" {
+		fmt.Fprintf(&sb, "\nnamespace: %s", event.Namespace)
+	}
+	if event.Subject != "" {
+		fmt.Fprintf(&sb, "\nsubject: %s", event.Subject)
+	}
+
+	keys := make([]string, 0, len(event.Data))
+	for k := range event.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "\n%s: %v", k, event.Data[k])
+	}
+
+	fmt.Fprintf(&sb, "\ntime: %s", event.Time.UTC().Format(time.RFC3339))
+	return sb.String()
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestNotifier_SlackAndSMTP verifies events reach both destinations and repeats are suppressed
+func TestNotifier_SlackAndSMTP(t *testing.T) {
+	var mu sync.Mutex
+	var slackTexts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		slackTexts = append(slackTexts, body["text"])
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	n, err := NewNotifier(NotifierConfig{
+		Slack: &SlackConfig{WebhookURL: server.URL},
+		SMTP:  &SMTPConfig{Addr: "smtp.example.com:25", From: "eventodb@example.com", To: []string{"ops@example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+
+	var mails []string
+	n.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		mu.Lock()
+		mails = append(mails, string(msg))
+		mu.Unlock()
+		return nil
+	}
+
+	event := SystemEvent{
+		Type:      SystemEventWebhookDeadLettered,
+		Severity:  "warning",
+		Namespace: "tenant-a",
+		Subject:   "billing",
+		Message:   "Delivery failed",
+	}
+	n.Notify(event)
+	n.Notify(event) // suppressed: same type/namespace/subject within minInterval
+
+	other := event
+	other.Subject = "shipping"
+	n.Notify(other)
+
+	n.Close()
+
+	if len(slackTexts) != 2 {
+		t.Fatalf("Expected 2 Slack messages, got %d: %v", len(slackTexts), slackTexts)
+	}
+	if !strings.Contains(slackTexts[0], "webhook.deadLettered") || !strings.Contains(slackTexts[0], "tenant-a") {
+		t.Errorf("Unexpected Slack text: %s", slackTexts[0])
+	}
+	if len(mails) != 2 {
+		t.Fatalf("Expected 2 emails, got %d", len(mails))
+	}
+	if !strings.Contains(mails[0], "Subject: [EventoDB] WARNING webhook.deadLettered") {
+		t.Errorf("Unexpected email: %s", mails[0])
+	}
+}
+
+// TestNotifier_EventFilter verifies only configured event types are sent
+func TestNotifier_EventFilter(t *testing.T) {
+	n, err := NewNotifier(NotifierConfig{
+		SMTP:   &SMTPConfig{Addr: "smtp.example.com:25", From: "a@example.com", To: []string{"b@example.com"}},
+		Events: []string{SystemEventConnectorFailed},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+
+	var count int
+	n.sendMail = func(string, smtp.Auth, string, []string, []byte) error {
+		count++
+		return nil
+	}
+
+	n.Notify(SystemEvent{Type: SystemEventWebhookDeadLettered, Time: time.Now()})
+	n.Notify(SystemEvent{Type: SystemEventConnectorFailed, Time: time.Now()})
+	n.Close()
+
+	if count != 1 {
+		t.Errorf("Expected 1 email, got %d", count)
+	}
+}
+
+// TestNotifier_NilIsNoop verifies a nil notifier discards events
+func TestNotifier_NilIsNoop(t *testing.T) {
+	var n *Notifier
+	n.Notify(SystemEvent{Type: SystemEventConnectorFailed})
+	n.Close()
+}
+
+// TestNewNotifier_InvalidConfig verifies destination validation
+func TestNewNotifier_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  NotifierConfig
+	}{
+		{"no destinations", NotifierConfig{}},
+		{"slack without url", NotifierConfig{Slack: &SlackConfig{}}},
+		{"smtp without recipients", NotifierConfig{SMTP: &SMTPConfig{Addr: "x:25", From: "a@b"}}},
+		{"bad interval", NotifierConfig{Slack: &SlackConfig{WebhookURL: "http://x"}, MinInterval: "soon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewNotifier(tt.cfg); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+// Package api provides WASM plugins that validate or enrich writes.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// pluginsMetadataKey holds the namespace's write plugins in namespace metadata
+	pluginsMetadataKey = "plugins"
+
+	// maxNamespacePlugins bounds the plugins run on each write
+	maxNamespacePlugins = 8
+
+	// maxPluginOutput bounds the result a plugin may return
+	maxPluginOutput = 4 << 20
+
+	// pluginModuleTTL bounds how long a compiled plugin is trusted, so
+	// changes to the plugin files apply within this time
+	pluginModuleTTL = 2 * time.Second
+
+	// DefaultPluginTimeout is the time one plugin call may take
+	DefaultPluginTimeout = 50 * time.Millisecond
+
+	// DefaultPluginMemoryMB is the memory one plugin instance may use
+	DefaultPluginMemoryMB = 16
+)
+
+var (
+	// ErrPluginsDisabled is returned when plugins are set on a server without a plugin directory
+	ErrPluginsDisabled = errors.New("plugins are not enabled (start the server with --plugin-dir)")
+
+	// ErrPluginNotFound is returned for plugins missing from the plugin directory
+	ErrPluginNotFound = errors.New("plugin not found")
+
+	// ErrPluginFailed is returned when a plugin cannot be loaded, traps, times
+	// out or returns an invalid result
+	ErrPluginFailed = errors.New("plugin failed")
+)
+
+// pluginNamePattern restricts plugin names to files directly in the plugin directory
+var pluginNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// PluginRejection is returned when a plugin rejects a message
+type PluginRejection struct {
+	Plugin string
+	Reason string
+}
+
+func (e *PluginRejection) Error() string {
+	return fmt.Sprintf("rejected by plugin %s: %s", e.Plugin, e.Reason)
+}
+
+// PluginsConfig lists the plugins run, in order, on writes to a namespace
+type PluginsConfig struct {
+	Plugins []string `json:"plugins"`
+}
+
+// Validate checks the plugin names
+func (c *PluginsConfig) Validate() error {
+	if len(c.Plugins) > maxNamespacePlugins {
+		return fmt.Errorf("at most %d plugins are allowed", maxNamespacePlugins)
+	}
+	seen := make(map[string]bool, len(c.Plugins))
+	for _, name := range c.Plugins {
+		if !pluginNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid plugin name %q (use letters, digits, '-' and '_')", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("plugin %s is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// PluginsFromMetadata returns the plugins stored in namespace metadata (never nil)
+func PluginsFromMetadata(metadata map[string]interface{}) *PluginsConfig {
+	cfg := &PluginsConfig{}
+	if raw, ok := metadata[pluginsMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, cfg)
+	}
+	return cfg
+}
+
+// PluginHostConfig configures the WASM plugin host
+type PluginHostConfig struct {
+	Dir      string        // Directory holding <name>.wasm plugins
+	Timeout  time.Duration // Time one plugin call may take
+	MemoryMB int           // Memory one plugin instance may use
+}
+
+// PluginHost runs the WASM plugins selected by each namespace on the write
+// path, and on reads that ask for decoding. Plugins are loaded from the plugin directory, so they can be added or
+// replaced without restarting the server.
+//
+// Every call runs in a fresh instance with no filesystem, network, clock or
+// environment access, bounded in time and memory, so plugins cannot keep
+// state between writes or affect other namespaces.
+//
+// A plugin exports memory, alloc(size i32) i32 and transform(ptr i32, len i32) i64.
+// transform receives the message as JSON and returns the location of its
+// result packed as ptr<<32 | len; an empty result accepts the message as is.
+// A nil *PluginHost runs nothing.
+type PluginHost struct {
+	meta    *NamespaceMetadata
+	cfg     PluginHostConfig
+	runtime wazero.Runtime
+
+	mu      sync.Mutex
+	modules map[string]*pluginModule
+}
+
+// pluginModule is a compiled plugin and the file it was compiled from
+type pluginModule struct {
+	compiled wazero.CompiledModule
+	modTime  time.Time
+	size     int64
+	checked  time.Time
+}
+
+// pluginsEntry holds a namespace's write and read plugins
+type pluginsEntry struct {
+	cfg  *PluginsConfig
+	read *ReadPluginsConfig
+}
+
+// pluginInput is the message passed to a plugin
+type pluginInput struct {
+	Namespace  string                 `json:"namespace"`
+	StreamName string                 `json:"streamName"`
+	Type       string                 `json:"type"`
+	Data       map[string]interface{} `json:"data"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// pluginOutput is a plugin's result; fields left out keep the message's values
+type pluginOutput struct {
+	Reject   string          `json:"reject"`
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+// NewPluginHost creates a plugin host for the plugins in cfg.Dir, selected
+// by the namespace metadata in meta
+func NewPluginHost(meta *NamespaceMetadata, cfg PluginHostConfig) (*PluginHost, error) {
+	info, err := os.Stat(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("plugin directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("plugin directory %s is not a directory", cfg.Dir)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultPluginTimeout
+	}
+	if cfg.MemoryMB <= 0 {
+		cfg.MemoryMB = DefaultPluginMemoryMB
+	}
+
+	ctx := context.Background()
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(uint32(cfg.MemoryMB)*16)) // 64 KiB pages
+	// WASI lets plugins built for wasip1 link; the module config grants no
+	// filesystem, environment or real clock
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		rt.Close(ctx)
+		return nil, fmt.Errorf("failed to set up the plugin runtime: %w", err)
+	}
+
+	return &PluginHost{
+		meta:    meta,
+		cfg:     cfg,
+		runtime: rt,
+		modules: make(map[string]*pluginModule),
+	}, nil
+}
+
+// Close releases the runtime and every compiled plugin
+func (h *PluginHost) Close() error {
+	if h == nil {
+		return nil
+	}
+	return h.runtime.Close(context.Background())
+}
+
+// Apply runs the namespace's plugins, in order, on msg. A plugin may replace
+// the message's type, data and metadata; the stream name is kept. Returns a
+// *PluginRejection when a plugin rejects the message, and ErrPluginFailed when
+// a plugin cannot be run, so writes fail closed.
+func (h *PluginHost) Apply(ctx context.Context, namespace string, msg *store.Message) error {
+	if h == nil {
+		return nil
+	}
+
+	entry, ok := h.config(ctx, namespace)
+	if !ok {
+		return nil // The store reports its own error on the write
+	}
+	for _, name := range entry.cfg.Plugins {
+		if err := h.call(ctx, name, namespace, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// config returns the namespace's plugin lists
+func (h *PluginHost) config(ctx context.Context, namespace string) (pluginsEntry, bool) {
+	entry, err := h.meta.decode(ctx, namespace, pluginsMetadataKey, func(metadata map[string]interface{}) interface{} {
+		return pluginsEntry{
+			cfg:  PluginsFromMetadata(metadata),
+			read: ReadPluginsFromMetadata(metadata),
+		}
+	})
+	if err != nil {
+		return pluginsEntry{}, false
+	}
+	return entry.(pluginsEntry), true
+}
+
+// call loads the named plugin and runs it on msg
+func (h *PluginHost) call(ctx context.Context, name, namespace string, msg *store.Message) error {
+	compiled, err := h.load(ctx, name)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrPluginFailed, name, err)
+	}
+	return h.run(ctx, name, compiled, namespace, msg)
+}
+
+// Set replaces a namespace's plugins after checking that they load; no
+// plugins removes them
+func (h *PluginHost) Set(ctx context.Context, namespace string, cfg *PluginsConfig) error {
+	if h == nil {
+		return ErrPluginsDisabled
+	}
+	for _, name := range cfg.Plugins {
+		if _, err := h.load(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return h.meta.Update(ctx, namespace, func(metadata map[string]interface{}) {
+		if len(cfg.Plugins) == 0 {
+			delete(metadata, pluginsMetadataKey)
+			return
+		}
+		metadata[pluginsMetadataKey] = encodeMetadataValue(cfg)
+	})
+}
+
+// load returns the compiled plugin, compiling it again when its file changed
+func (h *PluginHost) load(ctx context.Context, name string) (wazero.CompiledModule, error) {
+	h.mu.Lock()
+	mod, ok := h.modules[name]
+	h.mu.Unlock()
+	if ok && time.Since(mod.checked) <= pluginModuleTTL {
+		return mod.compiled, nil
+	}
+
+	path := filepath.Join(h.cfg.Dir, name+".wasm")
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrPluginNotFound, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ok && info.ModTime().Equal(mod.modTime) && info.Size() == mod.size {
+		h.mu.Lock()
+		mod.checked = time.Now()
+		h.mu.Unlock()
+		return mod.compiled, nil
+	}
+
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := h.runtime.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrPluginFailed, name, err)
+	}
+
+	// A replaced module may still be running, so it is released with the runtime
+	h.mu.Lock()
+	h.modules[name] = &pluginModule{compiled: compiled, modTime: info.ModTime(), size: info.Size(), checked: time.Now()}
+	h.mu.Unlock()
+	return compiled, nil
+}
+
+// run calls one plugin on msg in a fresh instance
+func (h *PluginHost) run(ctx context.Context, name string, compiled wazero.CompiledModule, namespace string, msg *store.Message) error {
+	input, err := json.Marshal(pluginInput{
+		Namespace:  namespace,
+		StreamName: msg.StreamName,
+		Type:       msg.Type,
+		Data:       msg.Data,
+		Metadata:   msg.Metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrPluginFailed, name, err)
+	}
+
+	out, err := h.invoke(ctx, name, compiled, "transform", input)
+	if err != nil || len(out) == 0 {
+		return err
+	}
+
+	failed := func(err error) error {
+		return fmt.Errorf("%w: %s: %v", ErrPluginFailed, name, err)
+	}
+
+	var result pluginOutput
+	if err := json.Unmarshal(out, &result); err != nil {
+		return failed(fmt.Errorf("invalid result: %v", err))
+	}
+	if result.Reject != "" {
+		return &PluginRejection{Plugin: name, Reason: result.Reject}
+	}
+
+	var data, metadata map[string]interface{}
+	if len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, &data); err != nil || data == nil {
+			return failed(errors.New("invalid result: data must be an object"))
+		}
+	}
+	if len(result.Metadata) > 0 {
+		if err := json.Unmarshal(result.Metadata, &metadata); err != nil {
+			return failed(errors.New("invalid result: metadata must be an object or null"))
+		}
+	}
+
+	if result.Type != "" {
+		msg.Type = result.Type
+	}
+	if data != nil {
+		msg.Data = data
+	}
+	if len(result.Metadata) > 0 {
+		msg.Metadata = metadata
+	}
+	return nil
+}
+
+// invoke calls a plugin export taking (ptr i32, len i32) and returning
+// ptr<<32 | len in a fresh instance, passing input and returning the result
+func (h *PluginHost) invoke(ctx context.Context, name string, compiled wazero.CompiledModule, export string, input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	defer cancel()
+
+	failed := func(err error) error {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %s: timed out after %s", ErrPluginFailed, name, h.cfg.Timeout)
+		}
+		return fmt.Errorf("%w: %s: %v", ErrPluginFailed, name, err)
+	}
+
+	mod, err := h.runtime.InstantiateModule(ctx, compiled,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, failed(err)
+	}
+	defer mod.Close(context.Background())
+
+	alloc := mod.ExportedFunction("alloc")
+	fn := mod.ExportedFunction(export)
+	mem := mod.Memory()
+	if alloc == nil || fn == nil || mem == nil {
+		return nil, failed(fmt.Errorf("must export memory, alloc and %s", export))
+	}
+
+	res, err := alloc.Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, failed(err)
+	}
+	ptr := uint32(res[0])
+	if !mem.Write(ptr, input) {
+		return nil, failed(errors.New("alloc returned memory out of range"))
+	}
+
+	res, err = fn.Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, failed(err)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	if outLen == 0 {
+		return nil, nil
+	}
+	if outLen > maxPluginOutput {
+		return nil, failed(fmt.Errorf("result exceeds %d bytes", maxPluginOutput))
+	}
+	out, ok := mem.Read(outPtr, outLen)
+	if !ok {
+		return nil, failed(errors.New("result out of memory range"))
+	}
+	// The instance's memory is released on return
+	return append([]byte(nil), out...), nil
+}
@@ -0,0 +1,482 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// testPluginModule assembles a WASM plugin whose transform returns output.
+// With loop set, transform never returns.
+func testPluginModule(output string, loop bool) []byte {
+	return testPluginModuleExport("transform", output, loop)
+}
+
+// testPluginModuleExport assembles a WASM plugin whose export returns output
+func testPluginModuleExport(export, output string, loop bool) []byte {
+	uleb := func(v uint64) []byte {
+		var b []byte
+		for {
+			c := byte(v & 0x7f)
+			v >>= 7
+			if v != 0 {
+				c |= 0x80
+			}
+			b = append(b, c)
+			if v == 0 {
+				return b
+			}
+		}
+	}
+	sleb := func(v int64) []byte {
+		var b []byte
+		for {
+			c := byte(v & 0x7f)
+			v >>= 7
+			if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+				return append(b, c)
+			}
+			b = append(b, c|0x80)
+		}
+	}
+	vec := func(items ...[]byte) []byte {
+		b := uleb(uint64(len(items)))
+		for _, item := range items {
+			b = append(b, item...)
+		}
+		return b
+	}
+	sized := func(b []byte) []byte {
+		return append(uleb(uint64(len(b))), b...)
+	}
+	section := func(id byte, content []byte) []byte {
+		return append([]byte{id}, sized(content)...)
+	}
+	name := func(s string) []byte {
+		return sized([]byte(s))
+	}
+
+	const outPtr = 1024
+	transform := []byte{0x00} // No locals
+	if loop {
+		transform = append(transform, 0x03, 0x40, 0x0c, 0x00, 0x0b) // loop br 0 end
+	}
+	transform = append(transform, 0x42) // i64.const ptr<<32 | len
+	transform = append(transform, sleb(int64(outPtr)<<32|int64(len(output)))...)
+	transform = append(transform, 0x0b)
+
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	module = append(module, section(1, vec(
+		[]byte{0x60, 0x01, 0x7f, 0x01, 0x7f},       // (i32) -> i32
+		[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e}, // (i32, i32) -> i64
+	))...)
+	module = append(module, section(3, vec([]byte{0x00}, []byte{0x01}))...)
+	module = append(module, section(5, vec([]byte{0x00, 0x01}))...)
+	module = append(module, section(7, vec(
+		append(name("memory"), 0x02, 0x00),
+		append(name("alloc"), 0x00, 0x00),
+		append(name(export), 0x00, 0x01),
+	))...)
+	module = append(module, section(10, vec(
+		sized([]byte{0x00, 0x41, 0x00, 0x0b}), // alloc returns 0
+		sized(transform),
+	))...)
+	module = append(module, section(11, vec(
+		append(append([]byte{0x00, 0x41}, append(sleb(outPtr), 0x0b)...), name(output)...),
+	))...)
+	return module
+}
+
+// TestWritePlugins tests that plugins set over RPC enrich and reject writes,
+// and that plugins which do not return in time fail the write
+func TestWritePlugins(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := h.route(ctx, "ns.plugins.set", []interface{}{map[string]interface{}{
+		"plugins": []interface{}{"enrich"},
+	}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without a plugin directory, got %v", rpcErr)
+	}
+
+	dir := t.TempDir()
+	for name, module := range map[string][]byte{
+		"enrich": testPluginModule(`{"type":"Checked","metadata":{"checked":true}}`, false),
+		"reject": testPluginModule(`{"reject":"amount too large"}`, false),
+		"loop":   testPluginModule("", true),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name+".wasm"), module, 0o644); err != nil {
+			t.Fatalf("Failed to write plugin: %v", err)
+		}
+	}
+	host, err := NewPluginHost(NewNamespaceMetadata(st), PluginHostConfig{Dir: dir, Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create plugin host: %v", err)
+	}
+	defer host.Close()
+	h.SetPluginHost(host)
+
+	for _, tc := range []struct {
+		plugins []interface{}
+		code    string
+	}{
+		{[]interface{}{"../enrich"}, "INVALID_REQUEST"},
+		{[]interface{}{"enrich", "enrich"}, "INVALID_REQUEST"},
+		{[]interface{}{"missing"}, "PLUGIN_NOT_FOUND"},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.plugins.set", []interface{}{map[string]interface{}{
+			"plugins": tc.plugins,
+		}}); rpcErr == nil || rpcErr.Code != tc.code {
+			t.Errorf("Expected %s for %v, got %v", tc.code, tc.plugins, rpcErr)
+		}
+	}
+
+	write := func() *RPCError {
+		_, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-1", map[string]interface{}{
+			"type": "Placed",
+			"data": map[string]interface{}{"amount": 10.0},
+		}})
+		return rpcErr
+	}
+	set := func(plugins ...interface{}) {
+		t.Helper()
+		if _, rpcErr := h.route(ctx, "ns.plugins.set", []interface{}{map[string]interface{}{
+			"plugins": plugins,
+		}}); rpcErr != nil {
+			t.Fatalf("ns.plugins.set failed: %v", rpcErr.Message)
+		}
+	}
+
+	set("enrich")
+	if rpcErr := write(); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "order-1", nil)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("Expected 1 message, got %d (%v)", len(msgs), err)
+	}
+	if msgs[0].Type != "Checked" || msgs[0].Metadata["checked"] != true || msgs[0].Data["amount"] != 10.0 {
+		t.Errorf("Expected the plugin's type and metadata with the original data, got %+v", msgs[0])
+	}
+
+	set("enrich", "reject")
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "PLUGIN_REJECTED" || rpcErr.Details["plugin"] != "reject" {
+		t.Errorf("Expected PLUGIN_REJECTED by reject, got %v", rpcErr)
+	}
+
+	set("loop")
+	if rpcErr := write(); rpcErr == nil || rpcErr.Code != "PLUGIN_FAILED" {
+		t.Errorf("Expected PLUGIN_FAILED for a plugin that does not return, got %v", rpcErr)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.plugins.set", []interface{}{nil}); rpcErr != nil {
+		t.Fatalf("ns.plugins.set null failed: %v", rpcErr.Message)
+	}
+	result, rpcErr := h.route(ctx, "ns.plugins.get", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.plugins.get failed: %v", rpcErr.Message)
+	}
+	if plugins := result.(map[string]interface{})["plugins"].([]interface{}); len(plugins) != 0 {
+		t.Errorf("Expected no plugins, got %v", plugins)
+	}
+	if rpcErr := write(); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	msgs, _ = st.GetStreamMessages(ctx, "test-ns", "order-1", nil)
+	if len(msgs) != 2 || msgs[1].Type != "Placed" {
+		t.Errorf("Expected the message unchanged after removing the plugins, got %v", msgs)
+	}
+}
+
+// TestWritePlugins_Errors tests invalid configs and plugin directories,
+// modules that do not compile or lack the export, invalid results, and
+// results that clear metadata or change nothing
+func TestWritePlugins_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	dir := t.TempDir()
+	for name, module := range map[string][]byte{
+		"garbage":  []byte("not wasm"),
+		"other":    testPluginModuleExport("other", `{}`, false),
+		"invalid":  testPluginModule(`not json`, false),
+		"list":     testPluginModule(`{"data":[1]}`, false),
+		"nulldata": testPluginModule(`{"data":null}`, false),
+		"textmeta": testPluginModule(`{"metadata":"x"}`, false),
+		"nullmeta": testPluginModule(`{"metadata":null}`, false),
+		"empty":    testPluginModule("", false),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name+".wasm"), module, 0o644); err != nil {
+			t.Fatalf("Failed to write plugin: %v", err)
+		}
+	}
+	if _, err := NewPluginHost(NewNamespaceMetadata(st), PluginHostConfig{Dir: filepath.Join(dir, "missing")}); err == nil {
+		t.Error("Expected an error for a missing plugin directory")
+	}
+	if _, err := NewPluginHost(NewNamespaceMetadata(st), PluginHostConfig{Dir: filepath.Join(dir, "empty.wasm")}); err == nil {
+		t.Error("Expected an error for a plugin directory that is a file")
+	}
+	host, err := NewPluginHost(NewNamespaceMetadata(st), PluginHostConfig{Dir: dir, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create plugin host: %v", err)
+	}
+	defer host.Close()
+	h.SetPluginHost(host)
+
+	tooMany := make([]interface{}, maxNamespacePlugins+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("p%d", i)
+	}
+	for _, args := range [][]interface{}{
+		{},
+		{"enrich"},
+		{map[string]interface{}{"plugins": "enrich"}},
+		{map[string]interface{}{"plugins": []interface{}{float64(1)}}},
+		{map[string]interface{}{"plugins": tooMany}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.plugins.set", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "ns.plugins.set", []interface{}{map[string]interface{}{
+		"plugins": []interface{}{"garbage"},
+	}}); rpcErr == nil || rpcErr.Code != "PLUGIN_FAILED" {
+		t.Errorf("Expected PLUGIN_FAILED for a module that does not compile, got %v", rpcErr)
+	}
+
+	write := func(plugin string) (*store.Message, *RPCError) {
+		t.Helper()
+		if _, rpcErr := h.route(ctx, "ns.plugins.set", []interface{}{map[string]interface{}{
+			"plugins": []interface{}{plugin},
+		}}); rpcErr != nil {
+			t.Fatalf("ns.plugins.set failed: %v", rpcErr.Message)
+		}
+		stream := "order-" + plugin
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{stream, map[string]interface{}{
+			"type":     "Placed",
+			"data":     map[string]interface{}{"amount": 10.0},
+			"metadata": map[string]interface{}{"source": "web"},
+		}}); rpcErr != nil {
+			return nil, rpcErr
+		}
+		msgs, err := st.GetStreamMessages(ctx, "test-ns", stream, nil)
+		if err != nil || len(msgs) != 1 {
+			t.Fatalf("Expected 1 message, got %d (%v)", len(msgs), err)
+		}
+		return msgs[0], nil
+	}
+
+	for _, plugin := range []string{"other", "invalid", "list", "nulldata", "textmeta"} {
+		if _, rpcErr := write(plugin); rpcErr == nil || rpcErr.Code != "PLUGIN_FAILED" {
+			t.Errorf("Expected PLUGIN_FAILED for %s, got %v", plugin, rpcErr)
+		}
+	}
+
+	// A null metadata result clears it; an empty result changes nothing
+	if msg, rpcErr := write("nullmeta"); rpcErr != nil || msg.Metadata != nil || msg.Data["amount"] != 10.0 {
+		t.Errorf("Expected the metadata to be cleared, got %+v (%v)", msg, rpcErr)
+	}
+	if msg, rpcErr := write("empty"); rpcErr != nil || msg.Type != "Placed" || msg.Metadata["source"] != "web" {
+		t.Errorf("Expected the message unchanged, got %+v (%v)", msg, rpcErr)
+	}
+}
+
+// TestReadPlugins tests that read plugins set per category decode messages
+// of that category on reads with options.decode only
+func TestReadPlugins(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	dir := t.TempDir()
+	module := testPluginModule(`{"data":{"amount":10}}`, false)
+	if err := os.WriteFile(filepath.Join(dir, "decrypt.wasm"), module, 0o644); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	host, err := NewPluginHost(NewNamespaceMetadata(st), PluginHostConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("Failed to create plugin host: %v", err)
+	}
+	defer host.Close()
+	h.SetPluginHost(host)
+
+	for _, rules := range [][]interface{}{
+		{map[string]interface{}{"category": "payment-1", "plugin": "decrypt"}},
+		{map[string]interface{}{"category": "payment", "plugin": "decrypt"}, map[string]interface{}{"category": "payment", "plugin": "decrypt"}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.readPlugins.set", []interface{}{map[string]interface{}{
+			"rules": rules,
+		}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", rules, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "ns.readPlugins.set", []interface{}{map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{"category": "payment", "plugin": "decrypt"}},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.readPlugins.set failed: %v", rpcErr.Message)
+	}
+
+	for _, stream := range []string{"payment-1", "order-1"} {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{stream, map[string]interface{}{
+			"type": "Encrypted",
+			"data": map[string]interface{}{"ciphertext": "x"},
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+
+	dataOf := func(result interface{}, index int) map[string]interface{} {
+		return result.([]interface{})[0].([]interface{})[index].(map[string]interface{})
+	}
+	result, rpcErr := h.route(ctx, "stream.get", []interface{}{"payment-1"})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	if data := dataOf(result, 4); data["ciphertext"] != "x" {
+		t.Errorf("Expected the stored data without decode, got %v", data)
+	}
+	result, rpcErr = h.route(ctx, "stream.get", []interface{}{"payment-1", map[string]interface{}{"decode": true}})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	if data := dataOf(result, 4); data["amount"] != 10.0 {
+		t.Errorf("Expected the decoded data, got %v", data)
+	}
+	result, rpcErr = h.route(ctx, "category.get", []interface{}{"order", map[string]interface{}{"decode": true}})
+	if rpcErr != nil {
+		t.Fatalf("category.get failed: %v", rpcErr.Message)
+	}
+	if data := dataOf(result, 5); data["ciphertext"] != "x" {
+		t.Errorf("Expected categories without a rule to be left as stored, got %v", data)
+	}
+	result, rpcErr = h.route(ctx, "stream.last", []interface{}{"payment-1", map[string]interface{}{"decode": true}})
+	if rpcErr != nil {
+		t.Fatalf("stream.last failed: %v", rpcErr.Message)
+	}
+	if data := result.([]interface{})[4].(map[string]interface{}); data["amount"] != 10.0 {
+		t.Errorf("Expected the decoded data, got %v", data)
+	}
+}
+
+// TestReadPlugins_Errors tests invalid rules and decode options, decoding
+// without a plugin host, plugins that fail or reject on read, and removing
+// the rules
+func TestReadPlugins_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"payment-1", map[string]interface{}{
+		"type": "Encrypted",
+		"data": map[string]interface{}{"ciphertext": "x"},
+	}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	decode := map[string]interface{}{"decode": true}
+	if _, rpcErr := h.route(ctx, "stream.get", []interface{}{"payment-1", decode}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for decode without plugins, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "ns.readPlugins.set", []interface{}{nil}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without plugins, got %v", rpcErr)
+	}
+
+	dir := t.TempDir()
+	for name, module := range map[string][]byte{
+		"decrypt": testPluginModule(`{"data":{"amount":10}}`, false),
+		"broken":  testPluginModule(`not json`, false),
+		"deny":    testPluginModule(`{"reject":"sealed"}`, false),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name+".wasm"), module, 0o644); err != nil {
+			t.Fatalf("Failed to write plugin: %v", err)
+		}
+	}
+	host, err := NewPluginHost(NewNamespaceMetadata(st), PluginHostConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("Failed to create plugin host: %v", err)
+	}
+	defer host.Close()
+	h.SetPluginHost(host)
+
+	tooMany := make([]interface{}, maxReadPluginRules+1)
+	for i := range tooMany {
+		tooMany[i] = map[string]interface{}{"category": fmt.Sprintf("c%d", i), "plugin": "decrypt"}
+	}
+	for _, args := range [][]interface{}{
+		{},
+		{"decrypt"},
+		{map[string]interface{}{"rules": "decrypt"}},
+		{map[string]interface{}{"rules": []interface{}{map[string]interface{}{"category": "", "plugin": "decrypt"}}}},
+		{map[string]interface{}{"rules": []interface{}{map[string]interface{}{"category": "payment", "plugin": "../decrypt"}}}},
+		{map[string]interface{}{"rules": tooMany}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.readPlugins.set", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "ns.readPlugins.set", []interface{}{map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{"category": "payment", "plugin": "missing"}},
+	}}); rpcErr == nil || rpcErr.Code != "PLUGIN_NOT_FOUND" {
+		t.Errorf("Expected PLUGIN_NOT_FOUND, got %v", rpcErr)
+	}
+	for _, method := range []string{"stream.get", "stream.last"} {
+		if _, rpcErr := h.route(ctx, method, []interface{}{"payment-1", map[string]interface{}{"decode": "yes"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for a non-boolean decode on %s, got %v", method, rpcErr)
+		}
+	}
+
+	setRule := func(plugin string) {
+		t.Helper()
+		if _, rpcErr := h.route(ctx, "ns.readPlugins.set", []interface{}{map[string]interface{}{
+			"rules": []interface{}{map[string]interface{}{"category": "payment", "plugin": plugin}},
+		}}); rpcErr != nil {
+			t.Fatalf("ns.readPlugins.set failed: %v", rpcErr.Message)
+		}
+	}
+	setRule("broken")
+	if _, rpcErr := h.route(ctx, "stream.get", []interface{}{"payment-1", decode}); rpcErr == nil || rpcErr.Code != "PLUGIN_FAILED" {
+		t.Errorf("Expected PLUGIN_FAILED, got %v", rpcErr)
+	}
+	setRule("deny")
+	if _, rpcErr := h.route(ctx, "category.get", []interface{}{"payment", decode}); rpcErr == nil || rpcErr.Code != "PLUGIN_REJECTED" {
+		t.Errorf("Expected PLUGIN_REJECTED, got %v", rpcErr)
+	}
+
+	// Read rules do not run on writes, and decoding leaves the stored data as is
+	setRule("decrypt")
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"payment-1", map[string]interface{}{
+		"type": "Encrypted",
+		"data": map[string]interface{}{"ciphertext": "y"},
+	}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.get", []interface{}{"payment-1", decode}); rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "payment-1", nil)
+	if err != nil || len(msgs) != 2 || msgs[0].Data["ciphertext"] != "x" || msgs[1].Data["ciphertext"] != "y" {
+		t.Errorf("Expected the stored data unchanged, got %+v (%v)", msgs, err)
+	}
+
+	// Null removes the rules
+	result, rpcErr := h.route(ctx, "ns.readPlugins.set", []interface{}{nil})
+	if rpcErr != nil || len(result.(map[string]interface{})["rules"].([]interface{})) != 0 {
+		t.Fatalf("Expected no rules, got %v (%v)", result, rpcErr)
+	}
+	if result, rpcErr = h.route(ctx, "ns.readPlugins.get", nil); rpcErr != nil || len(result.(map[string]interface{})["rules"].([]interface{})) != 0 {
+		t.Errorf("Expected no rules, got %v (%v)", result, rpcErr)
+	}
+	result, rpcErr = h.route(ctx, "stream.last", []interface{}{"payment-1", decode})
+	if rpcErr != nil {
+		t.Fatalf("stream.last failed: %v", rpcErr.Message)
+	}
+	if data := result.([]interface{})[4].(map[string]interface{}); data["ciphertext"] != "y" {
+		t.Errorf("Expected the stored data once the rules are removed, got %v", data)
+	}
+}
@@ -0,0 +1,24 @@
+// Package api provides the /debug/pprof handler.
+package api
+
+import (
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/pprofhandler"
+)
+
+// PprofHandlerFast serves /debug/pprof/ to callers holding the default
+// namespace token, which administers the server. In test mode, requests
+// without a valid token fall back to the default namespace and are served.
+// authMiddleware resolves the caller's namespace, so pass AuthMiddlewareFast.
+func PprofHandlerFast(authMiddleware func(fasthttp.RequestHandler) fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return authMiddleware(func(ctx *fasthttp.RequestCtx) {
+		if namespace, _ := GetNamespaceFromFastHTTP(ctx); namespace != "default" && !IsTestModeFastHTTP(ctx) {
+			writeAuthErrorFast(ctx, fasthttp.StatusForbidden, &RPCError{
+				Code:    "AUTH_UNAUTHORIZED",
+				Message: "Profiling requires the default namespace token",
+			})
+			return
+		}
+		pprofhandler.PprofHandler(ctx)
+	})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/valyala/fasthttp"
+)
+
+// TestPprofHandler_RequiresDefaultNamespace tests that profiles are served
+// to the default namespace token only
+func TestPprofHandler_RequiresDefaultNamespace(t *testing.T) {
+	st := newTestStore(t)
+	adminToken, _ := auth.GenerateToken("default")
+	userToken, _ := auth.GenerateToken("other-ns")
+	bg := context.Background()
+	if err := st.CreateNamespace(bg, "default", auth.HashToken(adminToken), "Default namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	if err := st.CreateNamespace(bg, "other-ns", auth.HashToken(userToken), "Other namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	handler := PprofHandlerFast(AuthMiddlewareFast(st, false))
+
+	get := func(token string) int {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI("/debug/pprof/goroutine?debug=1")
+		if token != "" {
+			ctx.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		handler(&ctx)
+		return ctx.Response.StatusCode()
+	}
+
+	if code := get(""); code != fasthttp.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", code)
+	}
+	if code := get(userToken); code != fasthttp.StatusForbidden {
+		t.Errorf("Expected 403 for a non-default namespace, got %d", code)
+	}
+	if code := get(adminToken); code != fasthttp.StatusOK {
+		t.Errorf("Expected 200 for the default namespace, got %d", code)
+	}
+}
+
+// TestPprofHandler_Errors tests that invalid tokens are rejected, that the
+// forbidden response is an RPC error and that test mode serves requests
+// without a valid token but not other namespaces' tokens
+func TestPprofHandler_Errors(t *testing.T) {
+	st := newTestStore(t)
+	userToken, _ := auth.GenerateToken("other-ns")
+	if err := st.CreateNamespace(context.Background(), "other-ns", auth.HashToken(userToken), "Other namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	get := func(handler fasthttp.RequestHandler, token string) *fasthttp.Response {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetRequestURI("/debug/pprof/")
+		ctx.Request.Header.Set("Authorization", "Bearer "+token)
+		handler(&ctx)
+		resp := &fasthttp.Response{}
+		ctx.Response.CopyTo(resp)
+		return resp
+	}
+
+	handler := PprofHandlerFast(AuthMiddlewareFast(st, false))
+	if resp := get(handler, "not-a-token"); resp.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("Expected 401 for an invalid token, got %d", resp.StatusCode())
+	}
+	resp := get(handler, userToken)
+	if resp.StatusCode() != fasthttp.StatusForbidden || !strings.Contains(string(resp.Body()), "AUTH_UNAUTHORIZED") {
+		t.Errorf("Expected a 403 AUTH_UNAUTHORIZED error, got %d %s", resp.StatusCode(), resp.Body())
+	}
+
+	testMode := PprofHandlerFast(AuthMiddlewareFast(st, true))
+	if resp := get(testMode, "not-a-token"); resp.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("Expected 200 for an invalid token in test mode, got %d", resp.StatusCode())
+	}
+	if resp := get(testMode, userToken); resp.StatusCode() != fasthttp.StatusForbidden {
+		t.Errorf("Expected 403 for a non-default namespace in test mode, got %d", resp.StatusCode())
+	}
+}
@@ -0,0 +1,78 @@
+// Package api provides protocol version negotiation with clients.
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProtocolVersion is the version of the RPC protocol as MAJOR.MINOR. The
+	// minor version grows when responses gain fields or methods are added; the
+	// major version grows when existing responses change shape.
+	ProtocolVersion = "1.0"
+
+	// ProtocolHeader carries ProtocolVersion on every RPC response
+	ProtocolHeader = "X-Eventodb-Protocol"
+
+	// MinVersionHeader is sent by clients with the protocol version they were
+	// built for. Requests are refused with UPGRADE_REQUIRED unless the server
+	// speaks that version or a later minor version of the same major version.
+	MinVersionHeader = "X-Eventodb-Min-Version"
+
+	// ContextKeyMinVersion is the context key for the MinVersionHeader value
+	ContextKeyMinVersion contextKey = "minVersion"
+)
+
+// parseProtocolVersion parses MAJOR or MAJOR.MINOR
+func parseProtocolVersion(s string) (major, minor int, err error) {
+	majorStr, minorStr, hasMinor := strings.Cut(strings.TrimSpace(s), ".")
+	major, err = strconv.Atoi(majorStr)
+	if err != nil || major < 0 {
+		return 0, 0, fmt.Errorf("invalid protocol version %q", s)
+	}
+	if hasMinor {
+		minor, err = strconv.Atoi(minorStr)
+		if err != nil || minor < 0 {
+			return 0, 0, fmt.Errorf("invalid protocol version %q", s)
+		}
+	}
+	return major, minor, nil
+}
+
+// checkMinVersion refuses requests from clients that need a protocol version
+// this server does not speak
+func checkMinVersion(ctx context.Context) *RPCError {
+	required, _ := ctx.Value(ContextKeyMinVersion).(string)
+	if required == "" {
+		return nil
+	}
+
+	major, minor, err := parseProtocolVersion(required)
+	if err != nil {
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("%s: %v", MinVersionHeader, err),
+		}
+	}
+	serverMajor, serverMinor, _ := parseProtocolVersion(ProtocolVersion)
+	if major == serverMajor && minor <= serverMinor {
+		return nil
+	}
+
+	upgrade := "server"
+	if major < serverMajor {
+		upgrade = "client"
+	}
+	return &RPCError{
+		Code:    "UPGRADE_REQUIRED",
+		Message: fmt.Sprintf("Client requires protocol %s, server speaks %s; upgrade the %s", required, ProtocolVersion, upgrade),
+		Details: map[string]interface{}{
+			"serverVersion":   ProtocolVersion,
+			"requiredVersion": required,
+			"upgrade":         upgrade,
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// TestRPC_MinVersion tests that requests needing a newer or older protocol
+// are refused with UPGRADE_REQUIRED
+func TestRPC_MinVersion(t *testing.T) {
+	h := NewRPCHandler("test", newTestStore(t), NewPubSub())
+
+	post := func(minVersion string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`["stream.version", "account-1"]`))
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyNamespace, "test-ns"))
+		if minVersion != "" {
+			req.Header.Set(MinVersionHeader, minVersion)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, version := range []string{"", "1", "1.0", ProtocolVersion} {
+		rec := post(version)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Min version %q: expected 200, got %d: %s", version, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get(ProtocolHeader); got != ProtocolVersion {
+			t.Errorf("Expected protocol header %s, got %q", ProtocolVersion, got)
+		}
+	}
+
+	for version, upgrade := range map[string]string{"1.99": "server", "2.0": "server", "0.9": "client"} {
+		rec := post(version)
+		if rec.Code != http.StatusUpgradeRequired {
+			t.Errorf("Min version %s: expected 426, got %d", version, rec.Code)
+			continue
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, `"UPGRADE_REQUIRED"`) || !strings.Contains(body, `"upgrade":"`+upgrade+`"`) {
+			t.Errorf("Min version %s: unexpected error %s", version, body)
+		}
+	}
+
+	if rec := post("one"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed version, got %d", rec.Code)
+	}
+}
+
+// TestRPC_MinVersionEdges tests protocol version parsing and that the
+// fasthttp handler checks the minimum version too
+func TestRPC_MinVersionEdges(t *testing.T) {
+	for version, want := range map[string][2]int{"1": {1, 0}, " 1.2 ": {1, 2}, "0.10": {0, 10}} {
+		major, minor, err := parseProtocolVersion(version)
+		if err != nil || major != want[0] || minor != want[1] {
+			t.Errorf("Expected %q to parse as %v, got %d.%d (%v)", version, want, major, minor, err)
+		}
+	}
+	for _, version := range []string{"1.", ".1", "1.0.0", "-1.0", "1.-1", "v1"} {
+		if _, _, err := parseProtocolVersion(version); err == nil {
+			t.Errorf("Expected %q to be rejected", version)
+		}
+	}
+
+	h := NewRPCHandler("test", newTestStore(t), NewPubSub())
+	handler := FastHTTPRPCHandler(h, false)
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+	ctx.Request.SetRequestURI("/rpc")
+	ctx.Request.Header.Set(MinVersionHeader, "2.0")
+	ctx.Request.SetBodyString(`["stream.version", "account-1"]`)
+	ctx.SetUserValue("namespace", "test-ns")
+	handler(&ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusUpgradeRequired || string(ctx.Response.Header.Peek(ProtocolHeader)) != ProtocolVersion {
+		t.Errorf("Expected 426 with the protocol header, got %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+}
@@ -17,10 +17,22 @@ type WriteEvent struct {
 // Subscriber is a channel that receives write events
 type Subscriber chan WriteEvent
 
+// PubSubRelay carries write events between EventoDB instances that share a backend
+type PubSubRelay interface {
+	// Start connects to the transport and starts relaying
+	Start() error
+	// Forward sends a locally published event to the other instances. It must not block.
+	Forward(event WriteEvent)
+	Close() error
+}
+
 // PubSub manages subscriptions for real-time notifications
 type PubSub struct {
 	mu sync.RWMutex
 
+	// relay forwards published events to other instances (nil on a single node)
+	relay PubSubRelay
+
 	// Stream subscribers: namespace -> stream -> subscribers
 	streamSubs map[string]map[string]map[Subscriber]struct{}
 
@@ -30,6 +42,9 @@ type PubSub struct {
 	// All subscribers: namespace -> subscribers (for ?all=true subscriptions)
 	allSubs map[string]map[Subscriber]struct{}
 
+	// Pattern subscribers: namespace -> subscriber -> wildcard pattern
+	patternSubs map[string]map[Subscriber]*SubjectPattern
+
 	// closed indicates if Close() has been called
 	closed bool
 }
@@ -40,6 +55,7 @@ func NewPubSub() *PubSub {
 		streamSubs:   make(map[string]map[string]map[Subscriber]struct{}),
 		categorySubs: make(map[string]map[string]map[Subscriber]struct{}),
 		allSubs:      make(map[string]map[Subscriber]struct{}),
+		patternSubs:  make(map[string]map[Subscriber]*SubjectPattern),
 	}
 }
 
@@ -157,8 +173,62 @@ func (ps *PubSub) UnsubscribeAll(namespace string, sub Subscriber) {
 	close(sub)
 }
 
-// Publish notifies all relevant subscribers about a write
+// SubscribePattern subscribes to the streams or categories matching a wildcard pattern
+func (ps *PubSub) SubscribePattern(namespace string, pattern *SubjectPattern) Subscriber {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	sub := make(Subscriber, 100) // Buffer to avoid blocking
+
+	if ps.patternSubs[namespace] == nil {
+		ps.patternSubs[namespace] = make(map[Subscriber]*SubjectPattern)
+	}
+	ps.patternSubs[namespace][sub] = pattern
+
+	return sub
+}
+
+// UnsubscribePattern removes a pattern subscription
+func (ps *PubSub) UnsubscribePattern(namespace string, sub Subscriber) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	// If already closed globally, channel is already closed
+	if ps.closed {
+		return
+	}
+
+	if ps.patternSubs[namespace] != nil {
+		delete(ps.patternSubs[namespace], sub)
+		if len(ps.patternSubs[namespace]) == 0 {
+			delete(ps.patternSubs, namespace)
+		}
+	}
+	close(sub)
+}
+
+// SetRelay forwards published events to other instances through relay
+func (ps *PubSub) SetRelay(relay PubSubRelay) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.relay = relay
+}
+
+// Publish notifies all relevant subscribers about a write, on this instance
+// and, with a relay, on the other instances
 func (ps *PubSub) Publish(event WriteEvent) {
+	ps.mu.RLock()
+	relay := ps.relay
+	ps.mu.RUnlock()
+
+	ps.deliver(event)
+	if relay != nil {
+		relay.Forward(event)
+	}
+}
+
+// deliver notifies subscribers on this instance about a write
+func (ps *PubSub) deliver(event WriteEvent) {
 	ps.mu.RLock()
 	defer ps.mu.RUnlock()
 
@@ -198,6 +268,18 @@ func (ps *PubSub) Publish(event WriteEvent) {
 			}
 		}
 	}
+
+	// Notify pattern subscribers whose pattern matches
+	for sub, pattern := range ps.patternSubs[event.Namespace] {
+		if !pattern.Match(event) {
+			continue
+		}
+		select {
+		case sub <- event:
+		default:
+			// Channel full, skip (subscriber is slow)
+		}
+	}
 }
 
 // Close closes all subscriber channels, causing all SSE handlers to exit
@@ -237,4 +319,12 @@ func (ps *PubSub) Close() {
 		}
 	}
 	ps.allSubs = make(map[string]map[Subscriber]struct{})
+
+	// Close all pattern subscribers
+	for _, subs := range ps.patternSubs {
+		for sub := range subs {
+			close(sub)
+		}
+	}
+	ps.patternSubs = make(map[string]map[Subscriber]*SubjectPattern)
 }
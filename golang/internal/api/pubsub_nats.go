@@ -0,0 +1,73 @@
+// Package api provides a NATS relay for multi-instance pubsub.
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/nats-io/nats.go"
+)
+
+// natsMaxPayload stays under the default NATS server max_payload of 1MB
+const natsMaxPayload = 512 * 1024
+
+// NATSRelay propagates write events between stateless EventoDB instances
+// through core NATS publish/subscribe on the eventodb_writes subject. The
+// connection reconnects and resubscribes on its own.
+type NATSRelay struct {
+	relayCore
+	url  string
+	conn *nats.Conn
+}
+
+// NewNATSRelay creates a relay for a nats:// URL (comma-separate several servers)
+func NewNATSRelay(url string, pubsub *PubSub) *NATSRelay {
+	return &NATSRelay{
+		relayCore: newRelayCore(pubsub, natsMaxPayload),
+		url:       url,
+	}
+}
+
+// Start connects, subscribes and starts relaying. It fails if NATS cannot be reached.
+func (r *NATSRelay) Start() error {
+	conn, err := nats.Connect(r.url,
+		nats.Name("eventodb-"+r.instance),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Get().Warn().Err(err).Msg("Pubsub NATS connection lost")
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			logger.Get().Info().Msg("Pubsub NATS connection restored")
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	if _, err := conn.Subscribe(RelayChannel, func(msg *nats.Msg) {
+		r.receive(msg.Data)
+	}); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to subscribe to NATS subject %s: %w", RelayChannel, err)
+	}
+	r.conn = conn
+
+	r.startSender(func(_ context.Context, payload []byte) error {
+		return r.conn.Publish(RelayChannel, payload)
+	})
+
+	logger.Get().Info().
+		Str("subject", RelayChannel).
+		Str("instance", r.instance).
+		Msg("Relaying write events through NATS")
+	return nil
+}
+
+// Close stops relaying and closes the NATS connection
+func (r *NATSRelay) Close() error {
+	r.stop()
+	if r.conn != nil {
+		r.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Package api provides a Postgres LISTEN/NOTIFY relay for multi-instance pubsub.
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/jackc/pgx/v5"
+)
+
+// postgresMaxPayload keeps a notification under Postgres' 8000 byte payload limit
+const postgresMaxPayload = 7000
+
+// PostgresRelay propagates write events between EventoDB instances that share
+// a Postgres backend, so SSE subscribers on any instance are poked for writes
+// handled by another one. Events are sent with pg_notify after the write has
+// committed; each instance LISTENs on a dedicated connection.
+type PostgresRelay struct {
+	relayCore
+	connStr  string
+	notifier *pgx.Conn // Owned by the sender goroutine
+}
+
+// NewPostgresRelay creates a relay that delivers remote events to pubsub
+func NewPostgresRelay(connStr string, pubsub *PubSub) *PostgresRelay {
+	return &PostgresRelay{
+		relayCore: newRelayCore(pubsub, postgresMaxPayload),
+		connStr:   connStr,
+	}
+}
+
+// Start connects the listener and starts relaying. It fails if Postgres
+// cannot be reached; later connection losses are retried in the background.
+func (r *PostgresRelay) Start() error {
+	listener, err := r.listen(r.ctx)
+	if err != nil {
+		return err
+	}
+
+	r.wg.Add(1)
+	go r.listenLoop(listener)
+	r.startSender(r.notify)
+
+	logger.Get().Info().
+		Str("channel", RelayChannel).
+		Str("instance", r.instance).
+		Msg("Relaying write events through Postgres LISTEN/NOTIFY")
+	return nil
+}
+
+// Close stops relaying and closes the connections
+func (r *PostgresRelay) Close() error {
+	r.stop()
+	if r.notifier != nil {
+		r.notifier.Close(context.Background())
+	}
+	return nil
+}
+
+// listen opens a connection subscribed to the relay channel
+func (r *PostgresRelay) listen(ctx context.Context) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, r.connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect pubsub listener: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{RelayChannel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, fmt.Errorf("failed to listen on %s: %w", RelayChannel, err)
+	}
+	return conn, nil
+}
+
+// listenLoop delivers notifications from other instances, reconnecting as needed
+func (r *PostgresRelay) listenLoop(conn *pgx.Conn) {
+	defer r.wg.Done()
+
+	backoff := time.Second
+	for {
+		if conn == nil {
+			var err error
+			if conn, err = r.listen(r.ctx); err != nil {
+				logger.Get().Warn().Err(err).Msg("Pubsub listener reconnect failed")
+				if !r.sleep(backoff) {
+					return
+				}
+				backoff = min(backoff*2, relayMaxBackoff)
+				continue
+			}
+			backoff = time.Second
+			logger.Get().Info().Msg("Pubsub listener reconnected")
+		}
+
+		notification, err := conn.WaitForNotification(r.ctx)
+		if err != nil {
+			conn.Close(context.Background())
+			conn = nil
+			if r.ctx.Err() != nil {
+				return
+			}
+			logger.Get().Warn().Err(err).Msg("Pubsub listener disconnected")
+			continue
+		}
+		r.receive([]byte(notification.Payload))
+	}
+}
+
+// notify sends one payload with pg_notify, reconnecting if needed
+func (r *PostgresRelay) notify(ctx context.Context, payload []byte) error {
+	if r.notifier == nil {
+		conn, err := pgx.Connect(ctx, r.connStr)
+		if err != nil {
+			return fmt.Errorf("failed to connect pubsub notifier: %w", err)
+		}
+		r.notifier = conn
+	}
+	if _, err := r.notifier.Exec(ctx, "SELECT pg_notify($1, $2)", RelayChannel, string(payload)); err != nil {
+		r.notifier.Close(context.Background())
+		r.notifier = nil
+		return err
+	}
+	return nil
+}
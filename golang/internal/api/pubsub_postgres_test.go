@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// postgresRelayConnStr returns a connection string for the test database, or skips
+func postgresRelayConnStr(t *testing.T) string {
+	t.Helper()
+	env := func(key, def string) string {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+		return def
+	}
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		env("POSTGRES_HOST", "localhost"), env("POSTGRES_PORT", "5432"),
+		env("POSTGRES_USER", "postgres"), env("POSTGRES_PASSWORD", "postgres"),
+		env("POSTGRES_DB", "postgres"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		t.Skipf("PostgreSQL not available: %v", err)
+	}
+	conn.Close(ctx)
+	return connStr
+}
+
+func TestPostgresRelay_DeliversAcrossInstances(t *testing.T) {
+	connStr := postgresRelayConnStr(t)
+	testRelayPair(t, func(ps *PubSub) PubSubRelay {
+		return NewPostgresRelay(connStr, ps)
+	})
+}
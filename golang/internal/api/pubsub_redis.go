@@ -0,0 +1,83 @@
+// Package api provides a Redis pub/sub relay for multi-instance pubsub.
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisMaxPayload bounds the size of one published batch
+const redisMaxPayload = 64 * 1024
+
+// RedisRelay propagates write events between stateless EventoDB instances
+// through Redis PUBLISH/SUBSCRIBE on the eventodb_writes channel. The client
+// reconnects and resubscribes on its own after connection losses.
+type RedisRelay struct {
+	relayCore
+	client *redis.Client
+	sub    *redis.PubSub
+}
+
+// NewRedisRelay creates a relay for a redis:// or rediss:// URL
+func NewRedisRelay(rawURL string, pubsub *PubSub) (*RedisRelay, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	return &RedisRelay{
+		relayCore: newRelayCore(pubsub, redisMaxPayload),
+		client:    redis.NewClient(opts),
+	}, nil
+}
+
+// Start subscribes and starts relaying. It fails if Redis cannot be reached.
+func (r *RedisRelay) Start() error {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	r.sub = r.client.Subscribe(r.ctx, RelayChannel)
+	if _, err := r.sub.Receive(ctx); err != nil {
+		r.sub.Close()
+		r.client.Close()
+		return fmt.Errorf("failed to subscribe to Redis channel %s: %w", RelayChannel, err)
+	}
+
+	messages := r.sub.Channel()
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				r.receive([]byte(msg.Payload))
+			}
+		}
+	}()
+	r.startSender(func(ctx context.Context, payload []byte) error {
+		return r.client.Publish(ctx, RelayChannel, payload).Err()
+	})
+
+	logger.Get().Info().
+		Str("channel", RelayChannel).
+		Str("instance", r.instance).
+		Msg("Relaying write events through Redis")
+	return nil
+}
+
+// Close stops relaying and closes the Redis connections
+func (r *RedisRelay) Close() error {
+	r.stop()
+	if r.sub != nil {
+		r.sub.Close()
+	}
+	return r.client.Close()
+}
@@ -0,0 +1,221 @@
+// Package api provides the shared machinery for relaying write events between instances.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	// RelayChannel is the channel (Postgres, Redis) or subject (NATS) write events are sent on
+	RelayChannel = "eventodb_writes"
+
+	// relayQueueSize bounds events waiting to be sent to other instances
+	relayQueueSize = 4096
+
+	// relayMaxBackoff bounds the delay between reconnect attempts
+	relayMaxBackoff = 10 * time.Second
+)
+
+// relayMessage is the payload of one relay message: a batch of write events
+// from one instance
+type relayMessage struct {
+	Instance string       `json:"i"`
+	Events   []relayEvent `json:"e"`
+}
+
+// relayEvent is the wire form of a WriteEvent (the category is derived from the stream)
+type relayEvent struct {
+	Namespace      string `json:"n"`
+	Stream         string `json:"s"`
+	Position       int64  `json:"p"`
+	GlobalPosition int64  `json:"g"`
+}
+
+// NewPubSubRelay creates the relay for a pubsub URL, chosen by its scheme:
+// postgres://, redis:// (rediss:// for TLS) or nats://
+func NewPubSubRelay(rawURL string, pubsub *PubSub) (PubSubRelay, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pubsub URL: %w", err)
+	}
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		return NewPostgresRelay(rawURL, pubsub), nil
+	case "redis", "rediss":
+		return NewRedisRelay(rawURL, pubsub)
+	case "nats", "tls":
+		return NewNATSRelay(rawURL, pubsub), nil
+	default:
+		return nil, fmt.Errorf("unsupported pubsub URL scheme %q (use postgres, redis, or nats)", u.Scheme)
+	}
+}
+
+// relayCore queues local events, sends them in batches through a transport,
+// and delivers events from other instances to local subscribers.
+//
+// Relaying is best effort, like local pokes: an event dropped while a
+// transport reconnects or the queue is full only delays subscribers until
+// the next write in their stream.
+type relayCore struct {
+	instance   string
+	pubsub     *PubSub
+	out        chan WriteEvent
+	maxPayload int // Maximum payload size of one message
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newRelayCore creates the shared part of a relay
+func newRelayCore(pubsub *PubSub, maxPayload int) relayCore {
+	ctx, cancel := context.WithCancel(context.Background())
+	return relayCore{
+		instance:   uuid.New().String(),
+		pubsub:     pubsub,
+		out:        make(chan WriteEvent, relayQueueSize),
+		maxPayload: maxPayload,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Forward queues a local event for the other instances
+func (c *relayCore) Forward(event WriteEvent) {
+	select {
+	case c.out <- event:
+	default:
+		// Queue full (transport slow or unreachable), subscribers elsewhere catch up on the next poke
+		logger.Get().Debug().Str("stream", event.Stream).Msg("Pubsub relay queue full, dropping event")
+	}
+}
+
+// stop cancels the relay and waits for its goroutines
+func (c *relayCore) stop() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// receive delivers a message from another instance to local subscribers
+func (c *relayCore) receive(payload []byte) {
+	var msg relayMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		logger.Get().Warn().Err(err).Msg("Ignoring malformed pubsub relay message")
+		return
+	}
+	if msg.Instance == c.instance {
+		return
+	}
+	for _, e := range msg.Events {
+		c.pubsub.deliver(WriteEvent{
+			Namespace:      e.Namespace,
+			Stream:         e.Stream,
+			Category:       store.Category(e.Stream),
+			Position:       e.Position,
+			GlobalPosition: e.GlobalPosition,
+		})
+	}
+}
+
+// startSender sends queued events with publish, retrying failed sends with backoff
+func (c *relayCore) startSender(publish func(ctx context.Context, payload []byte) error) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		backoff := time.Second
+		for {
+			var event WriteEvent
+			select {
+			case <-c.ctx.Done():
+				return
+			case event = <-c.out:
+			}
+
+			for _, payload := range encodeRelayBatches(c.instance, c.drain(event), c.maxPayload) {
+				for {
+					err := publish(c.ctx, payload)
+					if err == nil {
+						backoff = time.Second
+						break
+					}
+					if c.ctx.Err() != nil {
+						return
+					}
+					logger.Get().Warn().Err(err).Msg("Pubsub relay publish failed")
+					if !c.sleep(backoff) {
+						return
+					}
+					backoff = min(backoff*2, relayMaxBackoff)
+				}
+			}
+		}
+	}()
+}
+
+// drain returns first plus any events already waiting in the queue
+func (c *relayCore) drain(first WriteEvent) []WriteEvent {
+	events := []WriteEvent{first}
+	for len(events) < relayQueueSize {
+		select {
+		case event := <-c.out:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+	return events
+}
+
+// sleep waits for d, returning false if the relay is closed meanwhile
+func (c *relayCore) sleep(d time.Duration) bool {
+	select {
+	case <-c.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// encodeRelayBatches packs events into payloads of at most maxPayload bytes
+func encodeRelayBatches(instance string, events []WriteEvent, maxPayload int) [][]byte {
+	var payloads [][]byte
+	msg := relayMessage{Instance: instance}
+	size := 0
+
+	flush := func() {
+		if len(msg.Events) == 0 {
+			return
+		}
+		data, _ := json.Marshal(msg)
+		payloads = append(payloads, data)
+		msg.Events = nil
+		size = 0
+	}
+
+	for _, event := range events {
+		e := relayEvent{
+			Namespace:      event.Namespace,
+			Stream:         event.Stream,
+			Position:       event.Position,
+			GlobalPosition: event.GlobalPosition,
+		}
+		data, _ := json.Marshal(e)
+		if size+len(data)+len(instance)+16 > maxPayload {
+			flush()
+		}
+		msg.Events = append(msg.Events, e)
+		size += len(data) + 1
+	}
+	flush()
+	return payloads
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRelayPair checks that an event published on one instance reaches the other exactly once
+func testRelayPair(t *testing.T, newRelay func(ps *PubSub) PubSubRelay) {
+	t.Helper()
+
+	// Two instances, each with its own pubsub and relay
+	psA, psB := NewPubSub(), NewPubSub()
+	relayA, relayB := newRelay(psA), newRelay(psB)
+	for _, r := range []PubSubRelay{relayA, relayB} {
+		if err := r.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer r.Close()
+	}
+	psA.SetRelay(relayA)
+	psB.SetRelay(relayB)
+
+	subA := psA.SubscribeCategory("ns", "account")
+	subB := psB.SubscribeCategory("ns", "account")
+
+	psA.Publish(WriteEvent{Namespace: "ns", Stream: "account-1", Category: "account", Position: 0, GlobalPosition: 7})
+
+	select {
+	case event := <-subB:
+		if event.Stream != "account-1" || event.Category != "account" || event.GlobalPosition != 7 {
+			t.Errorf("Unexpected relayed event: %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Event not relayed to other instance")
+	}
+
+	// The publishing instance is poked once, not again by its own message
+	<-subA
+	select {
+	case event := <-subA:
+		t.Errorf("Unexpected duplicate local event: %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// requireListener skips the test unless something listens on addr
+func requireListener(t *testing.T, name, addr string) {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Skipf("%s not available: %v", name, err)
+	}
+	conn.Close()
+}
+
+func TestRedisRelay_DeliversAcrossInstances(t *testing.T) {
+	requireListener(t, "Redis", "localhost:6379")
+	testRelayPair(t, func(ps *PubSub) PubSubRelay {
+		relay, err := NewPubSubRelay("redis://localhost:6379/0", ps)
+		if err != nil {
+			t.Fatalf("NewPubSubRelay failed: %v", err)
+		}
+		return relay
+	})
+}
+
+func TestNATSRelay_DeliversAcrossInstances(t *testing.T) {
+	requireListener(t, "NATS", "localhost:4222")
+	testRelayPair(t, func(ps *PubSub) PubSubRelay {
+		relay, err := NewPubSubRelay("nats://localhost:4222", ps)
+		if err != nil {
+			t.Fatalf("NewPubSubRelay failed: %v", err)
+		}
+		return relay
+	})
+}
+
+func TestNewPubSubRelay_RejectsUnknownScheme(t *testing.T) {
+	if _, err := NewPubSubRelay("kafka://localhost:9092", NewPubSub()); err == nil {
+		t.Error("Expected error for unsupported scheme")
+	}
+}
+
+func TestRelayCore_ReceiveSkipsOwnMessages(t *testing.T) {
+	ps := NewPubSub()
+	core := newRelayCore(ps, postgresMaxPayload)
+	sub := ps.SubscribeStream("ns", "account-1")
+
+	events := []WriteEvent{{Namespace: "ns", Stream: "account-1", GlobalPosition: 3}}
+	core.receive(encodeRelayBatches(core.instance, events, postgresMaxPayload)[0])
+	core.receive(encodeRelayBatches("other-instance", events, postgresMaxPayload)[0])
+
+	select {
+	case event := <-sub:
+		if event.Category != "account" || event.GlobalPosition != 3 {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	default:
+		t.Fatal("Expected event from other instance")
+	}
+	select {
+	case event := <-sub:
+		t.Errorf("Own message must be skipped, got %+v", event)
+	default:
+	}
+}
+
+func TestEncodeRelayBatches(t *testing.T) {
+	var events []WriteEvent
+	for i := 0; i < 500; i++ {
+		events = append(events, WriteEvent{
+			Namespace:      "tenant-a",
+			Stream:         fmt.Sprintf("account-%s", strings.Repeat("x", 20)),
+			Position:       int64(i),
+			GlobalPosition: int64(1000 + i),
+		})
+	}
+
+	payloads := encodeRelayBatches("instance-1", events, postgresMaxPayload)
+	if len(payloads) < 2 {
+		t.Fatalf("Expected events to be split over several notifications, got %d", len(payloads))
+	}
+
+	var total int
+	for _, payload := range payloads {
+		if len(payload) > postgresMaxPayload {
+			t.Errorf("Payload of %d bytes exceeds limit", len(payload))
+		}
+		var msg relayMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatalf("Invalid payload: %v", err)
+		}
+		if msg.Instance != "instance-1" || msg.Events[0].GlobalPosition != int64(1000+total) {
+			t.Errorf("Unexpected batch start: %+v", msg.Events[0])
+		}
+		total += len(msg.Events)
+	}
+	if total != len(events) {
+		t.Errorf("Expected %d events, got %d", len(events), total)
+	}
+}
@@ -0,0 +1,56 @@
+// Package api provides the paginated envelope for read responses.
+package api
+
+import (
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// parseEnvelopeOption reads options.envelope. When set, the read fetches one
+// message more than requested so hasMore can be reported exactly.
+func parseEnvelopeOption(optsObj map[string]interface{}, batchSize *int64) (bool, *RPCError) {
+	val, exists := optsObj["envelope"]
+	if !exists {
+		return false, nil
+	}
+	envelope, ok := val.(bool)
+	if !ok {
+		return false, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options.envelope must be a boolean",
+		}
+	}
+	if envelope && *batchSize > 0 {
+		*batchSize++
+	}
+	return envelope, nil
+}
+
+// trimEnvelopeBatch drops the extra message fetched for an envelope read and
+// reports whether more messages follow the batch. batchSize is the adjusted
+// size passed to the store.
+func trimEnvelopeBatch(messages []*store.Message, batchSize int64) ([]*store.Message, bool) {
+	if batchSize <= 0 {
+		return messages, false
+	}
+	requested := int(batchSize - 1)
+	if len(messages) > requested {
+		return messages[:requested], true
+	}
+	return messages, false
+}
+
+// readEnvelope wraps read results as {items, nextCursor, hasMore, count}.
+// nextCursor is the position to pass on the next read and is null only when
+// it is unknown.
+func readEnvelope(items []interface{}, nextCursor *int64, hasMore bool) map[string]interface{} {
+	envelope := map[string]interface{}{
+		"items":      items,
+		"nextCursor": nil,
+		"hasMore":    hasMore,
+		"count":      len(items),
+	}
+	if nextCursor != nil {
+		envelope["nextCursor"] = *nextCursor
+	}
+	return envelope
+}
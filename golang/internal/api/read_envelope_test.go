@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestRPC_ReadEnvelope tests that envelope reads page through streams and
+// categories with exact hasMore flags
+func TestRPC_ReadEnvelope(t *testing.T) {
+	st := newTestStore(t)
+	for i := 0; i < 3; i++ {
+		if _, err := st.WriteMessage(context.Background(), "test-ns", "page-1", &store.Message{
+			StreamName: "page-1",
+			Type:       "Noted",
+			Data:       map[string]interface{}{"n": i},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	get := func(method string, args ...interface{}) map[string]interface{} {
+		result, rpcErr := h.route(ctx, method, args)
+		if rpcErr != nil {
+			t.Fatalf("%s failed: %v", method, rpcErr)
+		}
+		envelope, ok := result.(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected an envelope, got %T", result)
+		}
+		return envelope
+	}
+
+	// Page through the stream two messages at a time
+	first := get("stream.get", "page-1", map[string]interface{}{"batchSize": float64(2), "envelope": true})
+	if first["count"] != 2 || first["hasMore"] != true || first["nextCursor"] != int64(2) {
+		t.Fatalf("Unexpected first page: %v", first)
+	}
+	if items := first["items"].([]interface{}); len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(items))
+	}
+
+	second := get("stream.get", "page-1", map[string]interface{}{"position": float64(2), "batchSize": float64(2), "envelope": true})
+	if second["count"] != 1 || second["hasMore"] != false || second["nextCursor"] != int64(3) {
+		t.Fatalf("Unexpected second page: %v", second)
+	}
+
+	// An exactly full final page reports no more
+	exact := get("stream.get", "page-1", map[string]interface{}{"batchSize": float64(3), "envelope": true})
+	if exact["count"] != 3 || exact["hasMore"] != false {
+		t.Fatalf("Unexpected exact page: %v", exact)
+	}
+
+	// Category reads page by global position
+	category := get("category.get", "page", map[string]interface{}{"batchSize": float64(1), "envelope": true})
+	if category["count"] != 1 || category["hasMore"] != true || category["nextCursor"] == nil {
+		t.Fatalf("Unexpected category page: %v", category)
+	}
+
+	// Without the option, reads still return a bare array
+	result, rpcErr := h.route(ctx, "stream.get", []interface{}{"page-1"})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr)
+	}
+	if _, ok := result.([]interface{}); !ok {
+		t.Fatalf("Expected a bare array, got %T", result)
+	}
+
+	_, rpcErr = h.route(ctx, "stream.get", []interface{}{"page-1", map[string]interface{}{"envelope": "yes"}})
+	if rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Fatalf("Expected INVALID_REQUEST for a non-boolean envelope, got %v", rpcErr)
+	}
+}
+
+// TestRPC_ReadEnvelopeEdges tests envelopes of empty reads, that an empty
+// page continues where it started and that envelope false returns a bare array
+func TestRPC_ReadEnvelopeEdges(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	if _, err := st.WriteMessage(ctx, "test-ns", "page-1", &store.Message{Type: "Noted", Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+
+	for _, read := range []struct {
+		method     string
+		args       []interface{}
+		nextCursor int64
+	}{
+		{"stream.get", []interface{}{"page-2", map[string]interface{}{"envelope": true}}, 0},
+		{"stream.get", []interface{}{"page-1", map[string]interface{}{"position": float64(5), "envelope": true}}, 5},
+		{"category.get", []interface{}{"other", map[string]interface{}{"position": float64(7), "envelope": true}}, 7},
+	} {
+		result, rpcErr := h.route(ctx, read.method, read.args)
+		if rpcErr != nil {
+			t.Fatalf("%s failed: %v", read.method, rpcErr)
+		}
+		envelope := result.(map[string]interface{})
+		if envelope["count"] != 0 || envelope["hasMore"] != false || envelope["nextCursor"] != read.nextCursor || len(envelope["items"].([]interface{})) != 0 {
+			t.Errorf("Expected an empty page continuing at %d for %s %v, got %v", read.nextCursor, read.method, read.args, envelope)
+		}
+	}
+
+	result, rpcErr := h.route(ctx, "stream.get", []interface{}{"page-1", map[string]interface{}{"envelope": false, "batchSize": float64(1)}})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr)
+	}
+	if items, ok := result.([]interface{}); !ok || len(items) != 1 {
+		t.Errorf("Expected a bare array of 1 message, got %v", result)
+	}
+}
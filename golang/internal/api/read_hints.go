@@ -0,0 +1,111 @@
+// Package api provides paging hints for read responses.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// Paging hint headers set on stream.get and category.get responses. The
+	// body stays a bare array unless options.envelope is set.
+	NextPositionHeader       = "X-Eventodb-Next-Position"
+	NextGposHeader           = "X-Eventodb-Next-Gpos"
+	SuggestedBatchSizeHeader = "X-Eventodb-Suggested-Batch-Size"
+
+	// ContextKeyResponseHeaders is the context key for headers handlers add
+	// to a successful response
+	ContextKeyResponseHeaders contextKey = "responseHeaders"
+
+	// targetResponseBytes is the response size suggested batch sizes aim for,
+	// well below the 4 MB limit common in proxies and client libraries
+	targetResponseBytes = 1 << 20
+
+	// hintSampleSize is the number of messages encoded to estimate the
+	// average record size
+	hintSampleSize = 20
+
+	// maxBatchSize is the largest batchSize reads accept
+	maxBatchSize = 10000
+)
+
+// responseHeaders collects headers added by a handler
+type responseHeaders map[string]string
+
+// withResponseHeaders lets handlers called with the returned context add
+// response headers
+func withResponseHeaders(ctx context.Context) (context.Context, responseHeaders) {
+	headers := make(responseHeaders)
+	return context.WithValue(ctx, ContextKeyResponseHeaders, headers), headers
+}
+
+// setResponseHeader adds a header to the response, if the transport supports it
+func setResponseHeader(ctx context.Context, key, value string) {
+	if headers, ok := ctx.Value(ContextKeyResponseHeaders).(responseHeaders); ok {
+		headers[key] = value
+	}
+}
+
+// ReadHints tell a client where to continue reading and how many messages
+// to ask for to get responses of about 1 MB
+type ReadHints struct {
+	NextPosition       *int64 // Stream position after the last message (stream.get)
+	NextGpos           *int64 // Global position after the last message
+	SuggestedBatchSize int64
+}
+
+// newReadHints computes the hints for a batch of messages. nextPosition and
+// nextGpos are the cursors to report for an empty batch (nil if unknown);
+// withPosition is false for category reads, where stream positions do not
+// continue.
+func newReadHints(messages []*store.Message, nextPosition, nextGpos *int64, withPosition bool) ReadHints {
+	hints := ReadHints{NextPosition: nextPosition, NextGpos: nextGpos, SuggestedBatchSize: maxBatchSize}
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		position, gpos := last.Position+1, last.GlobalPosition+1
+		hints.NextPosition, hints.NextGpos = &position, &gpos
+		hints.SuggestedBatchSize = suggestBatchSize(messages)
+	}
+	if !withPosition {
+		hints.NextPosition = nil
+	}
+	return hints
+}
+
+// setHeaders adds the hints to the response headers
+func (r ReadHints) setHeaders(ctx context.Context) {
+	if r.NextPosition != nil {
+		setResponseHeader(ctx, NextPositionHeader, strconv.FormatInt(*r.NextPosition, 10))
+	}
+	if r.NextGpos != nil {
+		setResponseHeader(ctx, NextGposHeader, strconv.FormatInt(*r.NextGpos, 10))
+	}
+	setResponseHeader(ctx, SuggestedBatchSizeHeader, strconv.FormatInt(r.SuggestedBatchSize, 10))
+}
+
+// suggestBatchSize estimates the average encoded message size from a sample
+// spread over the batch and returns the batch size that fits the target
+func suggestBatchSize(messages []*store.Message) int64 {
+	step := len(messages)/hintSampleSize + 1
+	var bytes, sampled int64
+	for i := 0; i < len(messages); i += step {
+		msg := messages[i]
+		data, _ := json.Marshal(msg.Data)
+		metadata, _ := json.Marshal(msg.Metadata)
+		// ID, type, stream name, positions, time and array punctuation
+		bytes += int64(len(data)+len(metadata)+len(msg.ID)+len(msg.Type)+len(msg.StreamName)) + 80
+		sampled++
+	}
+
+	suggested := targetResponseBytes / (bytes / sampled)
+	if suggested < 1 {
+		return 1
+	}
+	if suggested > maxBatchSize {
+		return maxBatchSize
+	}
+	return suggested
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+func TestRPC_ReadHintHeaders(t *testing.T) {
+	st := newTestStore(t)
+	for i := 0; i < 3; i++ {
+		if _, err := st.WriteMessage(context.Background(), "test-ns", "hint-1", &store.Message{
+			StreamName: "hint-1",
+			Type:       "Noted",
+			Data:       map[string]interface{}{"text": strings.Repeat("x", 100)},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyNamespace, "test-ns"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	rec := post(`["stream.get", "hint-1", {"position": 1, "batchSize": 1}]`)
+	if got := rec.Header().Get(NextPositionHeader); got != "2" {
+		t.Errorf("Expected next position 2, got %q", got)
+	}
+	if got := rec.Header().Get(NextGposHeader); got == "" {
+		t.Error("Expected next global position")
+	}
+	if got := rec.Header().Get(SuggestedBatchSizeHeader); got == "" || got == "10000" {
+		t.Errorf("Expected a suggested batch size below the maximum, got %q", got)
+	}
+
+	// Past the end, the cursor stays put
+	rec = post(`["stream.get", "hint-1", {"position": 7}]`)
+	if got := rec.Header().Get(NextPositionHeader); got != "7" {
+		t.Errorf("Expected next position 7 for an empty batch, got %q", got)
+	}
+
+	// Category reads continue by global position only
+	rec = post(`["category.get", "hint"]`)
+	if rec.Header().Get(NextPositionHeader) != "" || rec.Header().Get(NextGposHeader) == "" {
+		t.Errorf("Unexpected category hints: %v", rec.Header())
+	}
+}
+
+func TestSuggestBatchSize(t *testing.T) {
+	small := []*store.Message{{ID: "a", Type: "T", Data: map[string]interface{}{}}}
+	if got := suggestBatchSize(small); got != maxBatchSize {
+		t.Errorf("Expected the maximum for tiny messages, got %d", got)
+	}
+
+	large := make([]*store.Message, 50)
+	for i := range large {
+		large[i] = &store.Message{ID: "a", Type: "T", Data: map[string]interface{}{"blob": strings.Repeat("x", 100_000)}}
+	}
+	if got := suggestBatchSize(large); got < 9 || got > 11 {
+		t.Errorf("Expected about 10 messages of 100 KB per MB, got %d", got)
+	}
+
+	huge := []*store.Message{{ID: "a", Type: "T", Data: map[string]interface{}{"blob": strings.Repeat("x", 3<<20)}}}
+	if got := suggestBatchSize(huge); got != 1 {
+		t.Errorf("Expected 1 for messages over the target, got %d", got)
+	}
+}
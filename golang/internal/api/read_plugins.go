@@ -0,0 +1,149 @@
+// Package api provides per-category read plugins that decode stored payloads.
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// readPluginsMetadataKey holds the namespace's read plugin rules in namespace metadata
+	readPluginsMetadataKey = "readPlugins"
+
+	// maxReadPluginRules bounds the read plugin rules kept per namespace
+	maxReadPluginRules = 32
+)
+
+// ReadPluginRule decodes messages of a category on reads that ask for it,
+// such as decompressing or decrypting payloads stored that way by producers
+type ReadPluginRule struct {
+	Category string `json:"category"` // Category whose messages are decoded, e.g. "payment"
+	Plugin   string `json:"plugin"`   // Plugin in the plugin directory
+}
+
+// ReadPluginsConfig holds a namespace's read plugin rules, at most one per category
+type ReadPluginsConfig struct {
+	Rules []ReadPluginRule `json:"rules"`
+}
+
+// Validate checks the rules
+func (c *ReadPluginsConfig) Validate() error {
+	if len(c.Rules) > maxReadPluginRules {
+		return fmt.Errorf("at most %d read plugin rules are allowed", maxReadPluginRules)
+	}
+	seen := make(map[string]bool, len(c.Rules))
+	for i, rule := range c.Rules {
+		if rule.Category == "" || strings.Contains(rule.Category, "-") {
+			return fmt.Errorf("rule %d: category must be a non-empty category name", i)
+		}
+		if seen[rule.Category] {
+			return fmt.Errorf("rule %d: category %q already has a rule", i, rule.Category)
+		}
+		seen[rule.Category] = true
+		if !pluginNamePattern.MatchString(rule.Plugin) {
+			return fmt.Errorf("rule %d: invalid plugin name %q (use letters, digits, '-' and '_')", i, rule.Plugin)
+		}
+	}
+	return nil
+}
+
+// ReadPluginsFromMetadata returns the read plugin rules stored in namespace metadata (never nil)
+func ReadPluginsFromMetadata(metadata map[string]interface{}) *ReadPluginsConfig {
+	cfg := &ReadPluginsConfig{}
+	if raw, ok := metadata[readPluginsMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, cfg)
+	}
+	return cfg
+}
+
+// plugin returns the plugin decoding messages of category, or ""
+func (c *ReadPluginsConfig) plugin(category string) string {
+	for _, rule := range c.Rules {
+		if rule.Category == category {
+			return rule.Plugin
+		}
+	}
+	return ""
+}
+
+// Decode runs the namespace's read plugins on the messages of categories
+// with a rule, replacing their type, data and metadata with the plugin's
+// result. Messages of other categories are left as stored. The messages are
+// changed in place, so they must not be shared with other readers.
+func (h *PluginHost) Decode(ctx context.Context, namespace string, msgs []*store.Message) error {
+	if h == nil || len(msgs) == 0 {
+		return nil
+	}
+	entry, ok := h.config(ctx, namespace)
+	if !ok || len(entry.read.Rules) == 0 {
+		return nil
+	}
+	for _, msg := range msgs {
+		name := entry.read.plugin(store.Category(msg.StreamName))
+		if name == "" {
+			continue
+		}
+		if err := h.call(ctx, name, namespace, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRead replaces a namespace's read plugin rules after checking that their
+// plugins load; no rules removes them
+func (h *PluginHost) SetRead(ctx context.Context, namespace string, cfg *ReadPluginsConfig) error {
+	if h == nil {
+		return ErrPluginsDisabled
+	}
+	for _, rule := range cfg.Rules {
+		if _, err := h.load(ctx, rule.Plugin); err != nil {
+			return err
+		}
+	}
+
+	return h.meta.Update(ctx, namespace, func(metadata map[string]interface{}) {
+		if len(cfg.Rules) == 0 {
+			delete(metadata, readPluginsMetadataKey)
+			return
+		}
+		metadata[readPluginsMetadataKey] = encodeMetadataValue(cfg)
+	})
+}
+
+// parseDecodeOption reads options.decode, which runs the namespace's read
+// plugins on the messages returned
+func parseDecodeOption(optsObj map[string]interface{}) (bool, *RPCError) {
+	val, exists := optsObj["decode"]
+	if !exists {
+		return false, nil
+	}
+	decode, ok := val.(bool)
+	if !ok {
+		return false, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "options.decode must be a boolean",
+		}
+	}
+	return decode, nil
+}
+
+// decodeMessages runs the namespace's read plugins on msgs when decode is set
+func (h *RPCHandler) decodeMessages(ctx context.Context, namespace string, decode bool, msgs []*store.Message) *RPCError {
+	if !decode {
+		return nil
+	}
+	if h.plugins == nil {
+		return &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: ErrPluginsDisabled.Error(),
+		}
+	}
+	if err := h.plugins.Decode(ctx, namespace, msgs); err != nil {
+		return pluginsError(namespace, err)
+	}
+	return nil
+}
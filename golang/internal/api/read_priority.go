@@ -0,0 +1,27 @@
+// Package api provides the priority hint for read requests.
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// parsePriorityOption reads options.priority ("low", "normal" or "high") and
+// returns ctx scheduling the read's store calls with it
+func parsePriorityOption(ctx context.Context, optsObj map[string]interface{}) (context.Context, *RPCError) {
+	val, exists := optsObj["priority"]
+	if !exists {
+		return ctx, nil
+	}
+	name, _ := val.(string)
+	priority, err := store.ParseReadPriority(name)
+	if err != nil {
+		return ctx, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("options.priority: %v", err),
+		}
+	}
+	return store.WithReadPriority(ctx, priority), nil
+}
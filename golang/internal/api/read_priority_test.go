@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// priorityStore records the read priority of the last read
+type priorityStore struct {
+	store.Store
+	last store.ReadPriority
+}
+
+func (s *priorityStore) GetStreamMessages(ctx context.Context, namespace, streamName string, opts *store.GetOpts) ([]*store.Message, error) {
+	s.last = store.ReadPriorityFromContext(ctx)
+	return s.Store.GetStreamMessages(ctx, namespace, streamName, opts)
+}
+
+func (s *priorityStore) GetCategoryMessages(ctx context.Context, namespace, categoryName string, opts *store.CategoryOpts) ([]*store.Message, error) {
+	s.last = store.ReadPriorityFromContext(ctx)
+	return s.Store.GetCategoryMessages(ctx, namespace, categoryName, opts)
+}
+
+func (s *priorityStore) GetLastStreamMessage(ctx context.Context, namespace, streamName string, msgType *string) (*store.Message, error) {
+	s.last = store.ReadPriorityFromContext(ctx)
+	return s.Store.GetLastStreamMessage(ctx, namespace, streamName, msgType)
+}
+
+// TestRPC_ReadPriority tests that the priority option schedules a read's
+// store calls
+func TestRPC_ReadPriority(t *testing.T) {
+	st := &priorityStore{Store: newTestStore(t)}
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, tc := range []struct {
+		method   string
+		name     string
+		priority string
+		want     store.ReadPriority
+	}{
+		{"stream.get", "order-1", "low", store.ReadPriorityLow},
+		{"stream.last", "order-1", "high", store.ReadPriorityHigh},
+		{"category.get", "order", "low", store.ReadPriorityLow},
+		{"category.get", "order", "", store.ReadPriorityNormal},
+	} {
+		opts := map[string]interface{}{}
+		if tc.priority != "" {
+			opts["priority"] = tc.priority
+		}
+		if _, rpcErr := h.route(ctx, tc.method, []interface{}{tc.name, opts}); rpcErr != nil {
+			t.Fatalf("%s failed: %v", tc.method, rpcErr)
+		}
+		if st.last != tc.want {
+			t.Errorf("Expected %s with priority %q to read at %s, got %s", tc.method, tc.priority, tc.want, st.last)
+		}
+	}
+
+	if _, rpcErr := h.route(ctx, "stream.get", []interface{}{"order-1", map[string]interface{}{"priority": "urgent"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an unknown priority, got %v", rpcErr)
+	}
+}
+
+// TestRPC_ReadPriorityEdges tests invalid priorities and that exports read
+// at low priority unless asked otherwise
+func TestRPC_ReadPriorityEdges(t *testing.T) {
+	st := &priorityStore{Store: newTestStore(t)}
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, priority := range []interface{}{"", "HIGH", float64(2), true} {
+		args := []interface{}{"order", map[string]interface{}{"priority": priority}}
+		if _, rpcErr := h.route(ctx, "category.get", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "entity.load", []interface{}{"order-1", map[string]interface{}{"priority": "high"}}); rpcErr != nil {
+		t.Fatalf("entity.load failed: %v", rpcErr)
+	}
+	if st.last != store.ReadPriorityHigh {
+		t.Errorf("Expected entity.load to read at high priority, got %s", st.last)
+	}
+
+	exports := NewExportHandler(st, nil)
+	for _, tc := range []struct {
+		query string
+		code  int
+		want  store.ReadPriority
+	}{
+		{"", http.StatusOK, store.ReadPriorityLow},
+		{"?priority=high", http.StatusOK, store.ReadPriorityHigh},
+		{"?priority=now", http.StatusBadRequest, store.ReadPriorityNormal},
+	} {
+		st.last = store.ReadPriorityNormal
+		req := httptest.NewRequest(http.MethodGet, "/export"+tc.query, nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		exports.ServeHTTP(rec, req)
+		if rec.Code != tc.code || st.last != tc.want {
+			t.Errorf("Expected export%s to return %d and read at %s, got %d and %s", tc.query, tc.code, tc.want, rec.Code, st.last)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Package api provides readiness and metrics endpoints for backend health.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/valyala/fasthttp"
+)
+
+// breakerStateValues are the numeric breaker states exported as a metric
+var breakerStateValues = map[string]int{
+	store.BreakerClosed:   0,
+	store.BreakerHalfOpen: 1,
+	store.BreakerOpen:     2,
+}
+
+// backendUnavailableError converts a backend outage into a BACKEND_UNAVAILABLE error
+func backendUnavailableError(err error) *RPCError {
+	wait := time.Second
+	var unavailable *store.UnavailableError
+	if errors.As(err, &unavailable) {
+		wait = unavailable.RetryAfter
+	}
+	return &RPCError{
+		Code:    "BACKEND_UNAVAILABLE",
+		Message: "Backend unavailable, retry later",
+		Details: map[string]interface{}{
+			"retryAfter": int(math.Ceil(wait.Seconds())),
+		},
+	}
+}
+
+// overloadedError converts a concurrency limiter rejection into an OVERLOADED error
+func overloadedError(err error) *RPCError {
+	details := map[string]interface{}{"retryAfter": 1}
+	var overloaded *store.OverloadedError
+	if errors.As(err, &overloaded) {
+		details["retryAfter"] = int(math.Ceil(overloaded.RetryAfter.Seconds()))
+		details["limit"] = overloaded.Limit
+		details["inFlight"] = overloaded.InFlight
+	}
+	return &RPCError{
+		Code:    "OVERLOADED",
+		Message: "Server overloaded, retry later",
+		Details: details,
+	}
+}
+
+// retryAfter returns the Retry-After seconds carried by an error, if any
+func retryAfter(rpcErr *RPCError) (int, bool) {
+	seconds, ok := rpcErr.Details["retryAfter"].(int)
+	return max(seconds, 1), ok
+}
+
+// ReadyzHandler reports whether the server can serve requests that need the
+// backend. It responds 503 while the circuit breaker is open; breaker may be nil.
+func ReadyzHandler(breaker *store.BreakerStore, queue *WriteQueue) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		status := "ready"
+		statusCode := fasthttp.StatusOK
+		resp := map[string]interface{}{}
+
+		if breaker != nil {
+			stats := breaker.Stats()
+			resp["breaker"] = stats
+			if err := breaker.Check(); err != nil {
+				status = "unavailable"
+				statusCode = fasthttp.StatusServiceUnavailable
+				seconds, _ := retryAfter(backendUnavailableError(err))
+				ctx.Response.Header.Set("Retry-After", strconv.Itoa(seconds))
+			}
+		}
+		if queue != nil {
+			resp["queuedWrites"] = queue.Pending()
+		}
+		resp["status"] = status
+
+		ctx.SetContentType("application/json")
+		ctx.SetStatusCode(statusCode)
+		if err := json.NewEncoder(ctx).Encode(resp); err != nil {
+			logger.Get().Error().Err(err).Msg("Error encoding readiness response")
+		}
+	}
+}
+
+// MetricsSources are the optional components reported by MetricsHandler; nil ones are skipped
+type MetricsSources struct {
+	Breaker   *store.BreakerStore
+	Queue     *WriteQueue
+	Scrubber  *Scrubber
+	Compactor *Compactor
+	Admission *AdmissionController
+	Limiter   *store.LimiterStore
+	Latency   *LatencyTracker
+	Results   *ResultBudget
+	Jobs      *JobScheduler
+	Durable   *DurableSubscriptions
+}
+
+// MetricsHandler serves backend health in the Prometheus text format
+func MetricsHandler(src MetricsSources) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/plain; version=0.0.4")
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		writeMetrics(ctx, src)
+	}
+}
+
+// writeMetrics writes the metrics of src to w in the Prometheus text format
+func writeMetrics(w io.Writer, src MetricsSources) {
+	breaker, queue := src.Breaker, src.Queue
+
+	if breaker != nil {
+		stats := breaker.Stats()
+		fmt.Fprintf(w, "# HELP eventodb_backend_breaker_state Circuit breaker state (0=closed, 1=half-open, 2=open).\n")
+		fmt.Fprintf(w, "# TYPE eventodb_backend_breaker_state gauge\n")
+		fmt.Fprintf(w, "eventodb_backend_breaker_state %d\n", breakerStateValues[stats.State])
+		fmt.Fprintf(w, "# HELP eventodb_backend_breaker_trips_total Times the circuit breaker opened.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_backend_breaker_trips_total counter\n")
+		fmt.Fprintf(w, "eventodb_backend_breaker_trips_total %d\n", stats.Trips)
+		fmt.Fprintf(w, "# HELP eventodb_backend_failures_total Backend calls that failed because the backend was unreachable.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_backend_failures_total counter\n")
+		fmt.Fprintf(w, "eventodb_backend_failures_total %d\n", stats.Failures)
+		fmt.Fprintf(w, "# HELP eventodb_backend_rejected_total Backend calls failed fast while the breaker was open.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_backend_rejected_total counter\n")
+		fmt.Fprintf(w, "eventodb_backend_rejected_total %d\n", stats.Rejected)
+	}
+	if queue != nil {
+		fmt.Fprintf(w, "# HELP eventodb_queued_writes Writes waiting for the backend.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_queued_writes gauge\n")
+		fmt.Fprintf(w, "eventodb_queued_writes %d\n", queue.Pending())
+	}
+	if src.Scrubber != nil {
+		stats := src.Scrubber.Stats()
+		fmt.Fprintf(w, "# HELP eventodb_integrity_passes_total Completed integrity scrub passes.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_integrity_passes_total counter\n")
+		fmt.Fprintf(w, "eventodb_integrity_passes_total %d\n", stats.Passes)
+		fmt.Fprintf(w, "# HELP eventodb_integrity_messages_checked_total Messages verified by the integrity scrubber.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_integrity_messages_checked_total counter\n")
+		fmt.Fprintf(w, "eventodb_integrity_messages_checked_total %d\n", stats.MessagesChecked)
+		if !stats.LastPassAt.IsZero() {
+			fmt.Fprintf(w, "# HELP eventodb_integrity_last_pass_timestamp_seconds End of the last completed scrub pass.\n")
+			fmt.Fprintf(w, "# TYPE eventodb_integrity_last_pass_timestamp_seconds gauge\n")
+			fmt.Fprintf(w, "eventodb_integrity_last_pass_timestamp_seconds %d\n", stats.LastPassAt.Unix())
+		}
+		fmt.Fprintf(w, "# HELP eventodb_integrity_anomalies_total Distinct integrity anomalies found, by kind.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_integrity_anomalies_total counter\n")
+		for _, kind := range []string{AnomalyPositionGap, AnomalyDuplicateGlobalPosition, AnomalyInvalidJSON, AnomalyStorage, AnomalyUnreadable} {
+			fmt.Fprintf(w, "eventodb_integrity_anomalies_total{kind=%q} %d\n", kind, stats.Anomalies[kind])
+		}
+	}
+	if src.Compactor != nil {
+		stats := src.Compactor.Stats()
+		fmt.Fprintf(w, "# HELP eventodb_compaction_passes_total Completed stream compaction passes.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_compaction_passes_total counter\n")
+		fmt.Fprintf(w, "eventodb_compaction_passes_total %d\n", stats.Passes)
+		fmt.Fprintf(w, "# HELP eventodb_compaction_streams_compacted_total Stream truncations that deleted messages.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_compaction_streams_compacted_total counter\n")
+		fmt.Fprintf(w, "eventodb_compaction_streams_compacted_total %d\n", stats.StreamsCompacted)
+		fmt.Fprintf(w, "# HELP eventodb_compaction_messages_deleted_total Messages deleted by retention rules.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_compaction_messages_deleted_total counter\n")
+		fmt.Fprintf(w, "eventodb_compaction_messages_deleted_total %d\n", stats.MessagesDeleted)
+	}
+	if src.Limiter != nil {
+		writes, reads := src.Limiter.Stats()
+		limits := []struct {
+			kind  string
+			stats store.ConcurrencyStats
+		}{{"write", writes}, {"read", reads}}
+		fmt.Fprintf(w, "# HELP eventodb_store_concurrency_limit Current adaptive limit on concurrent database calls.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_store_concurrency_limit gauge\n")
+		for _, l := range limits {
+			fmt.Fprintf(w, "eventodb_store_concurrency_limit{kind=%q} %d\n", l.kind, l.stats.Limit)
+		}
+		fmt.Fprintf(w, "# HELP eventodb_store_in_flight Database calls in progress.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_store_in_flight gauge\n")
+		for _, l := range limits {
+			fmt.Fprintf(w, "eventodb_store_in_flight{kind=%q} %d\n", l.kind, l.stats.InFlight)
+		}
+		fmt.Fprintf(w, "# HELP eventodb_store_waiting Database calls waiting for a slot.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_store_waiting gauge\n")
+		for _, l := range limits {
+			fmt.Fprintf(w, "eventodb_store_waiting{kind=%q} %d\n", l.kind, l.stats.Waiting)
+		}
+		fmt.Fprintf(w, "# HELP eventodb_store_overloaded_total Database calls shed by the concurrency limiter.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_store_overloaded_total counter\n")
+		for _, l := range limits {
+			fmt.Fprintf(w, "eventodb_store_overloaded_total{kind=%q} %d\n", l.kind, l.stats.Rejected)
+		}
+	}
+	if src.Admission != nil {
+		stats := src.Admission.Stats()
+		fmt.Fprintf(w, "# HELP eventodb_admission_admitted_total Messages admitted by write admission control, by priority.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_admission_admitted_total counter\n")
+		for _, p := range writePriorities {
+			fmt.Fprintf(w, "eventodb_admission_admitted_total{priority=%q} %d\n", p.String(), stats[p.String()].Admitted)
+		}
+		fmt.Fprintf(w, "# HELP eventodb_admission_rejected_total Writes rejected or delayed by write admission control, by priority.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_admission_rejected_total counter\n")
+		for _, p := range writePriorities {
+			fmt.Fprintf(w, "eventodb_admission_rejected_total{priority=%q} %d\n", p.String(), stats[p.String()].Rejected)
+		}
+	}
+	if src.Results != nil {
+		stats := src.Results.Stats()
+		fmt.Fprintf(w, "# HELP eventodb_response_memory_bytes Bytes of messages read for RPC results being answered.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_response_memory_bytes gauge\n")
+		fmt.Fprintf(w, "eventodb_response_memory_bytes %d\n", stats.InFlight)
+		fmt.Fprintf(w, "# HELP eventodb_results_rejected_total RPC results rejected, by reason (too_large, budget).\n")
+		fmt.Fprintf(w, "# TYPE eventodb_results_rejected_total counter\n")
+		fmt.Fprintf(w, "eventodb_results_rejected_total{reason=\"too_large\"} %d\n", stats.TooLarge)
+		fmt.Fprintf(w, "eventodb_results_rejected_total{reason=\"budget\"} %d\n", stats.Overloaded)
+	}
+	if src.Jobs != nil {
+		stats := src.Jobs.Stats("")
+		fmt.Fprintf(w, "# HELP eventodb_jobs Background jobs by state (queued, running).\n")
+		fmt.Fprintf(w, "# TYPE eventodb_jobs gauge\n")
+		fmt.Fprintf(w, "eventodb_jobs{state=\"queued\"} %d\n", stats.Queued)
+		fmt.Fprintf(w, "eventodb_jobs{state=\"running\"} %d\n", stats.Running)
+		fmt.Fprintf(w, "# HELP eventodb_job_wait_seconds_total Time background jobs spent queued, by namespace.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_job_wait_seconds_total counter\n")
+		namespaces := make([]string, 0, len(stats.Namespaces))
+		for ns := range stats.Namespaces {
+			namespaces = append(namespaces, ns)
+		}
+		sort.Strings(namespaces)
+		for _, ns := range namespaces {
+			fmt.Fprintf(w, "eventodb_job_wait_seconds_total{namespace=%q} %g\n", ns, stats.Namespaces[ns].WaitSecs)
+		}
+	}
+	if src.Durable != nil {
+		stats := src.Durable.Stats()
+		fmt.Fprintf(w, "# HELP eventodb_durable_subscriptions Durable subscriptions connected.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_durable_subscriptions gauge\n")
+		fmt.Fprintf(w, "eventodb_durable_subscriptions %d\n", stats.Connected)
+		fmt.Fprintf(w, "# HELP eventodb_durable_unacked_messages Messages sent to at-least-once subscriptions and not acknowledged.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_durable_unacked_messages gauge\n")
+		fmt.Fprintf(w, "eventodb_durable_unacked_messages %d\n", stats.Unacked)
+		fmt.Fprintf(w, "# HELP eventodb_durable_deliveries_total Messages sent to durable subscriptions, excluding redeliveries.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_durable_deliveries_total counter\n")
+		fmt.Fprintf(w, "eventodb_durable_deliveries_total %d\n", stats.Delivered)
+		fmt.Fprintf(w, "# HELP eventodb_durable_redeliveries_total Messages sent again because their ack timed out.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_durable_redeliveries_total counter\n")
+		fmt.Fprintf(w, "eventodb_durable_redeliveries_total %d\n", stats.Redeliveries)
+		fmt.Fprintf(w, "# HELP eventodb_durable_acks_total Messages acknowledged with consumer.ack.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_durable_acks_total counter\n")
+		fmt.Fprintf(w, "eventodb_durable_acks_total %d\n", stats.Acks)
+	}
+	if src.Latency != nil {
+		// Quantiles are over the last 5 minutes; sys.stats has other windows
+		fmt.Fprintf(w, "# HELP eventodb_rpc_latency_seconds RPC call latency by method and namespace over the last 5 minutes.\n")
+		fmt.Fprintf(w, "# TYPE eventodb_rpc_latency_seconds summary\n")
+		for _, stats := range src.Latency.Stats("") {
+			labels := fmt.Sprintf("method=%q,namespace=%q", stats.Method, stats.Namespace)
+			window := stats.Windows["5m"]
+			for _, q := range []struct {
+				quantile string
+				millis   float64
+			}{{"0.5", window.P50}, {"0.95", window.P95}, {"0.99", window.P99}, {"1", window.Max}} {
+				fmt.Fprintf(w, "eventodb_rpc_latency_seconds{%s,quantile=%q} %g\n", labels, q.quantile, q.millis/1000)
+			}
+			fmt.Fprintf(w, "eventodb_rpc_latency_seconds_sum{%s} %g\n", labels, stats.Sum)
+			fmt.Fprintf(w, "eventodb_rpc_latency_seconds_count{%s} %d\n", labels, stats.Count)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package api provides redaction of messages written with sensitive data.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// RedactionAuditStream records redacted messages in their namespace
+	RedactionAuditStream = "eventodb:audit-redaction"
+
+	// RedactedDataKey holds the tombstone that replaces a redacted message's data
+	RedactedDataKey = "$redacted"
+
+	// maxRedactionReasonLength bounds the reason kept in the tombstone
+	maxRedactionReasonLength = 1024
+)
+
+// ErrInvalidRedaction is returned for redactions that are not allowed
+var ErrInvalidRedaction = errors.New("invalid redaction")
+
+// Redaction describes a redacted message
+type Redaction struct {
+	StreamName     string
+	Position       int64
+	GlobalPosition int64
+	MessageID      string
+	MessageType    string
+	Reason         string
+	Metadata       bool // Whether the metadata was removed too
+	RedactedAt     time.Time
+
+	// AuditGlobalPosition is where the MessageRedacted event was written
+	AuditGlobalPosition int64
+}
+
+// RedactMessage replaces the data of the message at position in streamName
+// with a tombstone holding reason, keeping its ID, type, positions and time,
+// and records a MessageRedacted event in RedactionAuditStream. When metadata
+// is true the metadata is removed as well.
+//
+// Redacting a message again replaces its tombstone. If the audit event cannot
+// be written the message stays redacted and an error is returned, so a retry
+// records it.
+func RedactMessage(ctx context.Context, st store.Store, namespace, streamName string, position int64, reason string, metadata bool) (*Redaction, error) {
+	if reason == "" || len(reason) > maxRedactionReasonLength {
+		return nil, fmt.Errorf("%w: reason must be 1 to %d bytes", ErrInvalidRedaction, maxRedactionReasonLength)
+	}
+	if streamName == RedactionAuditStream {
+		return nil, fmt.Errorf("%w: the audit stream cannot be redacted", ErrInvalidRedaction)
+	}
+	redactor, ok := st.(store.MessageRedactor)
+	if !ok {
+		return nil, store.ErrNotSupported
+	}
+	if err := CheckWorm(ctx, st, namespace); err != nil {
+		return nil, err
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, namespace, streamName, &store.GetOpts{Position: position, BatchSize: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 || msgs[0].Position != position {
+		return nil, store.ErrMessageNotFound
+	}
+	msg := msgs[0]
+
+	r := &Redaction{
+		StreamName:     streamName,
+		Position:       position,
+		GlobalPosition: msg.GlobalPosition,
+		MessageID:      msg.ID,
+		MessageType:    msg.Type,
+		Reason:         reason,
+		Metadata:       metadata,
+		RedactedAt:     time.Now().UTC(),
+	}
+	tombstone := map[string]interface{}{
+		RedactedDataKey: map[string]interface{}{
+			"reason":     reason,
+			"redactedAt": r.RedactedAt.Format(time.RFC3339Nano),
+		},
+	}
+	keptMetadata := msg.Metadata
+	if metadata {
+		keptMetadata = nil
+	}
+	if err := redactor.RedactMessage(ctx, namespace, streamName, position, tombstone, keptMetadata); err != nil {
+		return nil, err
+	}
+
+	result, err := st.WriteMessage(ctx, namespace, RedactionAuditStream, &store.Message{
+		StreamName: RedactionAuditStream,
+		Type:       "MessageRedacted",
+		Data: map[string]interface{}{
+			"streamName":       streamName,
+			"position":         position,
+			"globalPosition":   msg.GlobalPosition,
+			"messageId":        msg.ID,
+			"messageType":      msg.Type,
+			"reason":           reason,
+			"metadataRedacted": metadata,
+			"time":             r.RedactedAt.Format(time.RFC3339Nano),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("message redacted, but the audit event was not recorded (retry to record it): %w", err)
+	}
+	r.AuditGlobalPosition = result.GlobalPosition
+	return r, nil
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestMessageRedact tests that message.redact replaces a message's data with
+// a tombstone, keeps positions and records an audit event
+func TestMessageRedact(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, data := range []map[string]interface{}{{"card": "4111111111111111"}, {"status": "active"}} {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"customer-1", map[string]interface{}{
+			"type":     "Updated",
+			"data":     data,
+			"metadata": map[string]interface{}{"correlationStreamName": "signup-1"},
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+
+	for _, args := range [][]interface{}{
+		{"customer-1", float64(0), map[string]interface{}{}},
+		{"customer-1", float64(-1), map[string]interface{}{"reason": "PII"}},
+		{RedactionAuditStream, float64(0), map[string]interface{}{"reason": "PII"}},
+	} {
+		if _, rpcErr := h.route(ctx, "message.redact", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "message.redact", []interface{}{"customer-1", float64(7), map[string]interface{}{"reason": "PII"}}); rpcErr == nil || rpcErr.Code != "MESSAGE_NOT_FOUND" {
+		t.Errorf("Expected MESSAGE_NOT_FOUND, got %v", rpcErr)
+	}
+
+	result, rpcErr := h.route(ctx, "message.redact", []interface{}{"customer-1", float64(0), map[string]interface{}{
+		"reason":   "Card number written by mistake (TICKET-42)",
+		"metadata": true,
+	}})
+	if rpcErr != nil {
+		t.Fatalf("message.redact failed: %v", rpcErr.Message)
+	}
+	info := result.(map[string]interface{})
+	if info["position"] != int64(0) || info["globalPosition"] != int64(1) {
+		t.Errorf("Unexpected redaction result: %v", info)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "customer-1", nil)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d (%v)", len(msgs), err)
+	}
+	tombstone, ok := msgs[0].Data[RedactedDataKey].(map[string]interface{})
+	if !ok || len(msgs[0].Data) != 1 || tombstone["reason"] != "Card number written by mistake (TICKET-42)" {
+		t.Errorf("Expected a tombstone, got %v", msgs[0].Data)
+	}
+	if msgs[0].Type != "Updated" || msgs[0].Metadata != nil {
+		t.Errorf("Expected the type kept and the metadata removed, got %s %v", msgs[0].Type, msgs[0].Metadata)
+	}
+	if msgs[1].Data["status"] != "active" {
+		t.Errorf("Expected the next message unchanged, got %v", msgs[1].Data)
+	}
+
+	audit, err := st.GetStreamMessages(ctx, "test-ns", RedactionAuditStream, nil)
+	if err != nil || len(audit) != 1 {
+		t.Fatalf("Expected 1 audit event, got %d (%v)", len(audit), err)
+	}
+	if audit[0].Type != "MessageRedacted" || audit[0].Data["streamName"] != "customer-1" ||
+		audit[0].Data["messageId"] != msgs[0].ID || audit[0].Data["card"] != nil {
+		t.Errorf("Unexpected audit event: %s %v", audit[0].Type, audit[0].Data)
+	}
+	if audit[0].GlobalPosition != info["auditGlobalPosition"] {
+		t.Errorf("Expected auditGlobalPosition %d, got %v", audit[0].GlobalPosition, info["auditGlobalPosition"])
+	}
+}
+
+// TestMessageRedact_Errors tests invalid arguments, that metadata is kept
+// by default and that frozen namespaces reject redactions
+func TestMessageRedact_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"customer-1", map[string]interface{}{
+		"type":     "Updated",
+		"data":     map[string]interface{}{"card": "4111111111111111"},
+		"metadata": map[string]interface{}{"correlationStreamName": "signup-1"},
+	}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+
+	reason := map[string]interface{}{"reason": "PII"}
+	for _, args := range [][]interface{}{
+		{"customer-1", float64(0)},
+		{"", float64(0), reason},
+		{"customer-1", "0", reason},
+		{"customer-1", float64(0), "PII"},
+		{"customer-1", float64(0), map[string]interface{}{"reason": float64(1)}},
+		{"customer-1", float64(0), map[string]interface{}{"reason": strings.Repeat("r", maxRedactionReasonLength+1)}},
+		{"customer-1", float64(0), map[string]interface{}{"reason": "PII", "metadata": "yes"}},
+	} {
+		if _, rpcErr := h.route(ctx, "message.redact", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "message.redact", []interface{}{"customer-2", float64(0), reason}); rpcErr == nil || rpcErr.Code != "MESSAGE_NOT_FOUND" {
+		t.Errorf("Expected MESSAGE_NOT_FOUND for a missing stream, got %v", rpcErr)
+	}
+
+	if _, rpcErr := h.route(ctx, "message.redact", []interface{}{"customer-1", float64(0), reason}); rpcErr != nil {
+		t.Fatalf("message.redact failed: %v", rpcErr.Message)
+	}
+	msg, err := st.GetLastStreamMessage(ctx, "test-ns", "customer-1", nil)
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	if msg.Data["card"] != nil || msg.Metadata["correlationStreamName"] != "signup-1" {
+		t.Errorf("Expected the data redacted and the metadata kept, got %v %v", msg.Data, msg.Metadata)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "message.redact", []interface{}{"customer-1", float64(0), reason}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for a frozen namespace, got %v", rpcErr)
+	}
+}
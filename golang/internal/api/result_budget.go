@@ -0,0 +1,186 @@
+// Package api provides size limits for RPC results held in memory.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// DefaultMaxResultMB is the largest result one RPC call may read
+	DefaultMaxResultMB = 64
+
+	// DefaultResponseMemoryMB is the memory the results of all calls being
+	// answered may use together
+	DefaultResponseMemoryMB = 512
+)
+
+// ResultBudget bounds the memory the message reads of RPC results use: each
+// result against MaxResult, and the results of all calls being answered
+// against a shared budget. Messages are counted as the store loads them, so
+// a read too large stops at the limit with RESULT_TOO_LARGE, and clients
+// read in pages instead; reads that do not fit the budget while other large
+// responses are out stop with OVERLOADED and are retried. Streamed responses
+// hold one page at a time and are not counted. A nil *ResultBudget admits
+// everything.
+type ResultBudget struct {
+	maxResult int64 // Bytes, 0 for no limit
+	budget    int64 // Bytes, 0 for no limit
+
+	mu       sync.Mutex
+	inFlight int64
+
+	tooLarge   atomic.Int64
+	overloaded atomic.Int64
+}
+
+// NewResultBudget creates a budget of budget bytes for results of up to
+// maxResult bytes. Either may be 0 for no limit; nil is returned when both are.
+func NewResultBudget(maxResult, budget int64) *ResultBudget {
+	if maxResult <= 0 && budget <= 0 {
+		return nil
+	}
+	return &ResultBudget{maxResult: max(maxResult, 0), budget: max(budget, 0)}
+}
+
+// limit returns the largest result that can ever be admitted, or 0
+func (b *ResultBudget) limit() int64 {
+	switch {
+	case b.maxResult == 0:
+		return b.budget
+	case b.budget == 0:
+		return b.maxResult
+	}
+	return min(b.maxResult, b.budget)
+}
+
+// resultMeter counts the result of one call against a ResultBudget
+type resultMeter struct {
+	budget *ResultBudget
+	size   int64 // Bytes counted, all taken from the budget; guarded by budget.mu
+}
+
+// resultMeterKey is the context key for the resultMeter of a call
+type resultMeterKey struct{}
+
+// resultLimitError stops a read that does not fit its call's result meter
+type resultLimitError struct {
+	rpcErr *RPCError
+}
+
+func (e *resultLimitError) Error() string {
+	return e.rpcErr.Message
+}
+
+// begin returns ctx with a meter for the result of one call. The returned
+// function gives the bytes counted back to the budget and must be called once
+// the result is written.
+func (b *ResultBudget) begin(ctx context.Context) (context.Context, func()) {
+	if b == nil {
+		return ctx, func() {}
+	}
+	m := &resultMeter{budget: b}
+	var once sync.Once
+	return context.WithValue(ctx, resultMeterKey{}, m), func() { once.Do(m.release) }
+}
+
+// add counts size more bytes of the result, taking them from the budget
+func (m *resultMeter) add(size int64) error {
+	b := m.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if limit := b.limit(); limit > 0 && m.size+size > limit {
+		b.tooLarge.Add(1)
+		return &resultLimitError{&RPCError{
+			Code:    "RESULT_TOO_LARGE",
+			Message: fmt.Sprintf("Result exceeds the limit of %d bytes; read in pages with a smaller batchSize", limit),
+			Details: map[string]interface{}{"limit": limit},
+		}}
+	}
+	if b.budget > 0 && b.inFlight+size > b.budget {
+		b.overloaded.Add(1)
+		return &resultLimitError{&RPCError{
+			Code:    "OVERLOADED",
+			Message: "Response memory budget exhausted, retry later",
+			Details: map[string]interface{}{"retryAfter": 1, "budget": b.budget, "inFlight": b.inFlight},
+		}}
+	}
+	m.size += size
+	if b.budget > 0 {
+		b.inFlight += size
+	}
+	return nil
+}
+
+// release gives the bytes counted back to the budget
+func (m *resultMeter) release() {
+	b := m.budget
+	b.mu.Lock()
+	if b.budget > 0 {
+		b.inFlight -= m.size
+	}
+	m.size = 0
+	b.mu.Unlock()
+}
+
+// meteredReads returns ctx whose store reads count the messages they load
+// against the result meter of the call, if it has one. Handlers use it for
+// the reads their result is built from.
+func meteredReads(ctx context.Context) context.Context {
+	m, ok := ctx.Value(resultMeterKey{}).(*resultMeter)
+	if !ok {
+		return ctx
+	}
+	return store.WithReadMeter(ctx, m.add)
+}
+
+// heldResultKey marks calls whose transport holds the whole result in
+// memory, as gRPC Call does, even when the method streams it
+type heldResultKey struct{}
+
+// withHeldResult marks ctx as the call of a transport holding the whole result
+func withHeldResult(ctx context.Context) context.Context {
+	return context.WithValue(ctx, heldResultKey{}, true)
+}
+
+// resultHeld reports whether the transport of the call holds the whole
+// result, so streamed reads count against the result limits as well
+func resultHeld(ctx context.Context) bool {
+	held, _ := ctx.Value(heldResultKey{}).(bool)
+	return held
+}
+
+// resultLimit returns the RPC error of a read stopped by its result meter
+func resultLimit(err error) (*RPCError, bool) {
+	var limitErr *resultLimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.rpcErr, true
+	}
+	return nil, false
+}
+
+// ResultBudgetStats are the counters of a ResultBudget
+type ResultBudgetStats struct {
+	InFlight   int64 `json:"inFlight"`   // Bytes of results being read or written
+	Budget     int64 `json:"budget"`     // 0 for no limit
+	TooLarge   int64 `json:"tooLarge"`   // Results rejected with RESULT_TOO_LARGE
+	Overloaded int64 `json:"overloaded"` // Results rejected while the budget was used up
+}
+
+// Stats returns the budget's counters
+func (b *ResultBudget) Stats() ResultBudgetStats {
+	b.mu.Lock()
+	inFlight := b.inFlight
+	b.mu.Unlock()
+	return ResultBudgetStats{
+		InFlight:   inFlight,
+		Budget:     b.budget,
+		TooLarge:   b.tooLarge.Load(),
+		Overloaded: b.overloaded.Load(),
+	}
+}
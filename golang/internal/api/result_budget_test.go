@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/api/eventodbv1"
+	"github.com/eventodb/eventodb/internal/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TestResultBudget tests that results over the cap get RESULT_TOO_LARGE and
+// results that do not fit the budget get OVERLOADED until it is released
+func TestResultBudget(t *testing.T) {
+	b := NewResultBudget(100, 150)
+	meter := func() (*resultMeter, func()) {
+		ctx, release := b.begin(context.Background())
+		return ctx.Value(resultMeterKey{}).(*resultMeter), release
+	}
+
+	first, releaseFirst := meter()
+	if err := first.add(60); err != nil {
+		t.Fatalf("Expected 60 bytes to be admitted, got %v", err)
+	}
+	if rpcErr, ok := resultLimit(first.add(41)); !ok || rpcErr.Code != "RESULT_TOO_LARGE" || rpcErr.Details["limit"] != int64(100) {
+		t.Fatalf("Expected RESULT_TOO_LARGE with limit 100, got %v", rpcErr)
+	}
+	if err := first.add(40); err != nil {
+		t.Fatalf("Expected up to 100 bytes to be admitted, got %v", err)
+	}
+	second, releaseSecond := meter()
+	if rpcErr, ok := resultLimit(second.add(60)); !ok || rpcErr.Code != "OVERLOADED" {
+		t.Fatalf("Expected OVERLOADED over the budget, got %v", rpcErr)
+	}
+	releaseFirst()
+	releaseFirst() // Releasing twice gives the bytes back once
+	if stats := b.Stats(); stats.InFlight != 0 || stats.TooLarge != 1 || stats.Overloaded != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if err := second.add(60); err != nil {
+		t.Errorf("Expected 60 bytes to be admitted after the release, got %v", err)
+	}
+	releaseSecond()
+
+	// A budget smaller than the cap bounds single results too
+	ctx, release := NewResultBudget(0, 50).begin(context.Background())
+	defer release()
+	if rpcErr, ok := resultLimit(ctx.Value(resultMeterKey{}).(*resultMeter).add(51)); !ok || rpcErr.Code != "RESULT_TOO_LARGE" {
+		t.Errorf("Expected RESULT_TOO_LARGE over the budget, got %v", rpcErr)
+	}
+	if NewResultBudget(0, 0) != nil {
+		t.Error("Expected no budget without limits")
+	}
+
+	// Calls without a budget read unmetered
+	var none *ResultBudget
+	ctx, release = none.begin(context.Background())
+	release()
+	if meteredReads(ctx) != ctx {
+		t.Error("Expected reads without a budget to be unmetered")
+	}
+}
+
+func TestRPC_ResultTooLarge(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	h.SetResultBudget(NewResultBudget(2048, 0))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyNamespace, "test-ns"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 20; i++ {
+		if rec := post(`["stream.write", "account-1", {"type": "Noted", "data": {"note": "` + strings.Repeat("x", 100) + `"}}]`); rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := post(`["category.get", "account", {"batchSize": -1}]`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"RESULT_TOO_LARGE"`) {
+		t.Fatalf("Expected 422 RESULT_TOO_LARGE, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Pages under the cap are answered, and streamed reads are not limited
+	if rec := post(`["category.get", "account", {"batchSize": 5}]`); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a page, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = post(`["stream.get", "account-1", {"batchSize": -1}]`)
+	if rec.Code != http.StatusOK || strings.Count(rec.Body.String(), `"Noted"`) != 20 {
+		t.Errorf("Expected the streamed read of 20 messages, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Bounded reads are limited too, and every call gives its bytes back
+	if rec := post(`["stream.get", "account-1", {"batchSize": 100}]`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a large page, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(`["category.get", "account", {"batchSize": -1, "where": "type = 'Other'"}]`); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a filtered read, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stats := h.results.Stats(); stats.InFlight != 0 || stats.TooLarge != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestGRPC_ResultTooLarge(t *testing.T) {
+	st := newTestStore(t)
+	if err := st.CreateNamespace(context.Background(), "default", "default-token-hash", "Default namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	h := NewRPCHandler("test", st, NewPubSub())
+	h.SetResultBudget(NewResultBudget(2048, 0))
+	client := dialGRPC(t, h, st, true)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer x")
+
+	for i := 0; i < 20; i++ {
+		if _, err := st.WriteMessage(context.Background(), "default", "account-1", &store.Message{
+			Type: "Noted",
+			Data: map[string]interface{}{"note": strings.Repeat("x", 100)},
+		}); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+	call := func(method string, args ...interface{}) error {
+		values := make([]*structpb.Value, len(args))
+		for i, arg := range args {
+			values[i], _ = structpb.NewValue(arg)
+		}
+		_, err := client.Call(ctx, &eventodbv1.CallRequest{Method: method, Args: values})
+		return err
+	}
+
+	// Call is limited like POST /rpc, including reads streamed over HTTP
+	for method, target := range map[string]string{"category.get": "account", "stream.get": "account-1"} {
+		err := call(method, target, map[string]interface{}{"batchSize": -1})
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("Expected ResourceExhausted for %s, got %v", method, err)
+		}
+	}
+	if err := call("category.get", "account", map[string]interface{}{"batchSize": 5}); err != nil {
+		t.Errorf("Expected a page to be answered, got %v", err)
+	}
+
+	// So is GetCategory; GetStream streams unlimited reads
+	categoryRead, err := client.GetCategory(ctx, &eventodbv1.GetCategoryRequest{Category: "account", BatchSize: -1})
+	if err == nil {
+		_, err = categoryRead.Recv()
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted for GetCategory, got %v", err)
+	}
+	streamRead, err := client.GetStream(ctx, &eventodbv1.GetStreamRequest{StreamName: "account-1", BatchSize: -1})
+	if err != nil {
+		t.Fatalf("GetStream failed: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := streamRead.Recv(); err != nil {
+			t.Fatalf("GetStream failed at message %d: %v", i, err)
+		}
+	}
+	if stats := h.results.Stats(); stats.InFlight != 0 || stats.TooLarge != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,172 @@
+// Package api provides retention rules for compacting checkpoint streams.
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// retentionMetadataKey holds the retention rules in namespace metadata
+	retentionMetadataKey = "retention"
+
+	// maxRetentionRules bounds the rules kept per namespace
+	maxRetentionRules = 32
+)
+
+// RetentionRule keeps only the latest Keep messages of the streams matching
+// a pattern. It is meant for consumer position and other checkpoint streams,
+// such as "*:position-*", where only the last entry is ever read.
+//
+// Compaction deletes older messages but never changes stream versions, so
+// expected-version writes and stream.last keep working.
+type RetentionRule struct {
+	Streams string `json:"streams"` // Stream name pattern, '*' matches any run of characters
+	Keep    int64  `json:"keep"`    // Messages kept per stream, at least 1
+}
+
+// RetentionConfig holds a namespace's retention rules. The first rule
+// matching a stream applies to it.
+type RetentionConfig struct {
+	Rules []RetentionRule `json:"rules"`
+}
+
+// Validate checks the rules
+func (c *RetentionConfig) Validate() error {
+	if len(c.Rules) > maxRetentionRules {
+		return fmt.Errorf("at most %d retention rules are allowed", maxRetentionRules)
+	}
+	for i, rule := range c.Rules {
+		if rule.Streams == "" {
+			return fmt.Errorf("rule %d: streams must not be empty", i)
+		}
+		if strings.Contains(rule.Streams, "|") {
+			return fmt.Errorf("rule %d: streams must be a single pattern, use one rule per alternative", i)
+		}
+		if rule.Keep < 1 {
+			return fmt.Errorf("rule %d: keep must be at least 1", i)
+		}
+	}
+	return nil
+}
+
+// RetentionFromMetadata returns the retention config stored in namespace metadata (never nil)
+func RetentionFromMetadata(metadata map[string]interface{}) *RetentionConfig {
+	cfg := &RetentionConfig{}
+	if raw, ok := metadata[retentionMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, cfg)
+	}
+	return cfg
+}
+
+// Match returns the first rule matching stream, or nil
+func (c *RetentionConfig) Match(stream string) *RetentionRule {
+	for i := range c.Rules {
+		if globMatch(c.Rules[i].Streams, stream) {
+			return &c.Rules[i]
+		}
+	}
+	return nil
+}
+
+// SetRetention replaces a namespace's retention rules; no rules removes them
+func SetRetention(ctx context.Context, st store.Store, namespace string, cfg *RetentionConfig) error {
+	var protected bool
+	err := updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		if cfg == nil || len(cfg.Rules) == 0 {
+			delete(metadata, retentionMetadataKey)
+			return
+		}
+		// Compaction deletes messages, which WORM namespaces never do
+		if protected = WormFromMetadata(metadata) != nil; protected {
+			return
+		}
+		metadata[retentionMetadataKey] = encodeMetadataValue(cfg)
+	})
+	if err == nil && protected {
+		return ErrWormProtected
+	}
+	return err
+}
+
+// retentionPrefix returns the literal stream name prefix of a rule's pattern,
+// used to list only candidate streams
+func retentionPrefix(rule RetentionRule) string {
+	prefix, _, _ := strings.Cut(rule.Streams, "*")
+	return prefix
+}
+
+// forEachRetainedStream calls fn with each stream of a namespace and the
+// first retention rule matching it, reporting the job progress by rule
+func forEachRetainedStream(ctx context.Context, st store.Store, namespace string, cfg *RetentionConfig, fn func(info *store.StreamInfo, rule *RetentionRule) error) error {
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		cursor := ""
+		for {
+			streams, err := st.ListStreams(ctx, namespace, &store.ListStreamsOpts{
+				Prefix: retentionPrefix(*rule),
+				Limit:  compactorStreamPage,
+				Cursor: cursor,
+			})
+			if err != nil {
+				return err
+			}
+			for _, info := range streams {
+				// A stream is compacted by the first rule it matches only
+				if cfg.Match(info.StreamName) != rule {
+					continue
+				}
+				if err := fn(info, rule); err != nil {
+					return err
+				}
+			}
+			if len(streams) < compactorStreamPage {
+				break
+			}
+			cursor = streams[len(streams)-1].StreamName
+		}
+		JobProgress(ctx, float64(i+1)*100/float64(len(cfg.Rules)))
+	}
+	return nil
+}
+
+// RetentionDryRun reports the messages compacting a namespace would delete
+// now, without deleting them
+func RetentionDryRun(ctx context.Context, st store.Store, namespace string) (*DeletionReport, error) {
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if WormFromMetadata(ns.Metadata) != nil {
+		return nil, ErrWormProtected
+	}
+
+	// The first position kept in each stream with messages to delete
+	cutoffs := make(map[string]int64)
+	err = forEachRetainedStream(ctx, st, namespace, RetentionFromMetadata(ns.Metadata), func(info *store.StreamInfo, rule *RetentionRule) error {
+		if position := info.Version - rule.Keep + 1; position > 0 {
+			cutoffs[info.StreamName] = position
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DeletionReport{}
+	if len(cutoffs) > 0 {
+		report, err = scanDeletion(ctx, st, namespace, func(msg *store.Message) bool {
+			cutoff, ok := cutoffs[msg.StreamName]
+			return ok && msg.Position < cutoff
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	report.Operation = "ns.compact"
+	report.Target = namespace
+	return report, nil
+}
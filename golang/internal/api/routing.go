@@ -0,0 +1,165 @@
+// Package api provides namespace routing for multi-node deployments.
+package api
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strings"
+)
+
+const (
+	// RouteHeader carries the routing token of the caller's namespace. It is
+	// set on every RPC response; clients echo it on requests so load balancers
+	// can hash on it and send a namespace to the same node.
+	RouteHeader = "X-Eventodb-Route"
+
+	// RoutedParam marks a request redirected by another node. It is served
+	// locally even if this node does not own the namespace, so nodes with
+	// mismatched node lists cannot redirect a write back and forth.
+	RoutedParam = "routed"
+
+	// ContextKeyRouted is the context key set for redirected requests
+	ContextKeyRouted contextKey = "routed"
+)
+
+// routedMethods are the methods redirected to the namespace owner. Writes to
+// one stream from several nodes are what cause optimistic-lock conflicts;
+// reads are served by any node.
+var routedMethods = map[string]bool{
+	"stream.write":      true,
+	"stream.writeBatch": true,
+	"stream.casWrite":   true,
+	"edge.push":         true,
+	"doc.put":           true,
+	"doc.delete":        true,
+	"doc.write":         true,
+	"stream.merge":      true,
+	"stream.rename":     true,
+	"import.commit":     true,
+}
+
+// RouteToken returns the routing token of a namespace: the FNV-1a 64-bit
+// hash of its name as 16 hex digits. Clients can compute it themselves.
+func RouteToken(namespace string) string {
+	return fmt.Sprintf("%016x", hashString(namespace))
+}
+
+// RouteNode is one node of a multi-node deployment
+type RouteNode struct {
+	ID  string // Stable node name, e.g. the pod name
+	URL string // Base URL clients reach the node at
+}
+
+// Router assigns each namespace to one node with rendezvous hashing, so
+// adding or removing a node only moves the namespaces it owns.
+type Router struct {
+	self  string
+	nodes []RouteNode
+}
+
+// ParseRouteNodes parses a comma-separated list of id=url pairs
+func ParseRouteNodes(s string) ([]RouteNode, error) {
+	var nodes []RouteNode
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		id, rawURL, ok := strings.Cut(part, "=")
+		id, rawURL = strings.TrimSpace(id), strings.TrimSpace(rawURL)
+		if !ok || id == "" || rawURL == "" {
+			return nil, fmt.Errorf("invalid route node %q (want id=url)", part)
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL for route node %s: %q", id, rawURL)
+		}
+		nodes = append(nodes, RouteNode{ID: id, URL: strings.TrimRight(rawURL, "/")})
+	}
+	return nodes, nil
+}
+
+// NewRouter creates a router for the node self among nodes
+func NewRouter(self string, nodes []RouteNode) (*Router, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no route nodes configured")
+	}
+	seen := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		if seen[n.ID] {
+			return nil, fmt.Errorf("duplicate route node %s", n.ID)
+		}
+		seen[n.ID] = true
+	}
+	if !seen[self] {
+		return nil, fmt.Errorf("node ID %q is not in the route node list", self)
+	}
+	return &Router{self: self, nodes: nodes}, nil
+}
+
+// Owner returns the node that owns namespace
+func (r *Router) Owner(namespace string) RouteNode {
+	var owner RouteNode
+	var best uint64
+	for i, n := range r.nodes {
+		if score := mix64(hashString(n.ID + "/" + namespace)); i == 0 || score > best {
+			owner, best = n, score
+		}
+	}
+	return owner
+}
+
+// check returns a MISROUTED error if method should be served by another node
+func (r *Router) check(ctx context.Context, method, namespace string) *RPCError {
+	if r == nil || !routedMethods[method] || namespace == "" {
+		return nil
+	}
+	if routed, _ := ctx.Value(ContextKeyRouted).(bool); routed {
+		return nil
+	}
+	owner := r.Owner(namespace)
+	if owner.ID == r.self {
+		return nil
+	}
+	return &RPCError{
+		Code:    "MISROUTED",
+		Message: fmt.Sprintf("Namespace %s is written through node %s", namespace, owner.ID),
+		Details: map[string]interface{}{
+			"node":  owner.ID,
+			"url":   owner.URL,
+			"route": RouteToken(namespace),
+		},
+	}
+}
+
+// redirectLocation returns where a MISROUTED request should be sent
+func redirectLocation(rpcErr *RPCError) (string, bool) {
+	if rpcErr.Code != "MISROUTED" {
+		return "", false
+	}
+	base, ok := rpcErr.Details["url"].(string)
+	if !ok {
+		return "", false
+	}
+	return base + "/rpc?" + RoutedParam + "=1", true
+}
+
+// hashString returns the FNV-1a 64-bit hash of s
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// mix64 spreads the bits of an FNV hash (the splitmix64 finalizer). FNV alone
+// barely changes its high bits for keys that differ only in their prefix,
+// which would send every namespace to the same node.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
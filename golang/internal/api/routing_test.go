@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouter_Owner(t *testing.T) {
+	nodes, err := ParseRouteNodes("a=http://a:8080, b=http://b:8080/,c=https://c")
+	if err != nil {
+		t.Fatalf("ParseRouteNodes failed: %v", err)
+	}
+	if len(nodes) != 3 || nodes[1].URL != "http://b:8080" {
+		t.Fatalf("Unexpected nodes: %+v", nodes)
+	}
+
+	r, err := NewRouter("a", nodes)
+	if err != nil {
+		t.Fatalf("NewRouter failed: %v", err)
+	}
+	owned := map[string]int{}
+	for i := 0; i < 300; i++ {
+		ns := fmt.Sprintf("tenant-%d", i)
+		owner := r.Owner(ns)
+		if again := r.Owner(ns); again != owner {
+			t.Fatalf("Owner of %s is not stable: %s then %s", ns, owner.ID, again.ID)
+		}
+		owned[owner.ID]++
+
+		// Removing another node never moves a namespace off its owner
+		if owner.ID != "c" {
+			smaller, _ := NewRouter("a", nodes[:2])
+			if moved := smaller.Owner(ns); moved.ID != owner.ID {
+				t.Fatalf("Namespace %s moved from %s to %s", ns, owner.ID, moved.ID)
+			}
+		}
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if owned[id] < 50 {
+			t.Errorf("Node %s owns only %d of 300 namespaces", id, owned[id])
+		}
+	}
+}
+
+func TestRouter_InvalidConfig(t *testing.T) {
+	for _, list := range []string{"a", "a=", "a=ftp://x", "=http://x"} {
+		if _, err := ParseRouteNodes(list); err == nil {
+			t.Errorf("Expected error for %q", list)
+		}
+	}
+	nodes, _ := ParseRouteNodes("a=http://a,a=http://b")
+	if _, err := NewRouter("a", nodes); err == nil {
+		t.Error("Expected error for duplicate node")
+	}
+	nodes, _ = ParseRouteNodes("a=http://a")
+	if _, err := NewRouter("b", nodes); err == nil {
+		t.Error("Expected error for node missing from the list")
+	}
+}
+
+func TestRPC_MisroutedWriteRedirects(t *testing.T) {
+	st := newTestStore(t)
+	nodes, _ := ParseRouteNodes("a=http://a:8080,b=http://b:8080")
+	router, _ := NewRouter("a", nodes)
+	owner := router.Owner("test-ns")
+
+	// Serve as the node that does not own the namespace
+	self := "a"
+	if owner.ID == "a" {
+		self = "b"
+	}
+	misrouted, _ := NewRouter(self, nodes)
+	h := NewRPCHandler("test", st, NewPubSub())
+	h.SetRouter(misrouted)
+
+	post := func(target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyNamespace, "test-ns"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	write := `["stream.write", "account-1", {"type": "Opened", "data": {}}]`
+
+	rec := post("/rpc", write)
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("Expected 307, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if loc := rec.Header().Get("Location"); loc != owner.URL+"/rpc?routed=1" {
+		t.Errorf("Unexpected Location %q", loc)
+	}
+	if !strings.Contains(rec.Body.String(), `"MISROUTED"`) {
+		t.Errorf("Expected MISROUTED error, got %s", rec.Body.String())
+	}
+	if got := rec.Header().Get(RouteHeader); got != RouteToken("test-ns") {
+		t.Errorf("Expected route token %s, got %q", RouteToken("test-ns"), got)
+	}
+
+	// Reads are served by any node
+	if rec := post("/rpc", `["stream.version", "account-1"]`); rec.Code != http.StatusOK {
+		t.Errorf("Expected read to be served, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A redirected write is served even if the node lists disagree
+	if rec := post("/rpc?routed=1", write); rec.Code != http.StatusOK {
+		t.Errorf("Expected redirected write to be served, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/eventodb/eventodb/internal/logger"
 	"github.com/eventodb/eventodb/internal/store"
@@ -17,6 +20,36 @@ type RPCHandler struct {
 	version string
 	store   store.Store
 	pubsub  *PubSub
+	hooks   *WebhookPublisher       // Optional, nil when webhooks are not configured
+	shipper *LogShipper             // Optional, nil when log shipping is disabled
+	exports *ExportScheduler        // Optional, nil when scheduled exports are disabled
+	ticks   *TickScheduler          // Optional, nil when ticks are disabled
+	mirror  *Mirror                 // Optional, nil when namespace mirroring is disabled
+	edge    *EdgeSync               // Optional, nil when edge sync is disabled
+	attest  *Attestor               // Optional, nil without an attestation key
+	compact *Compactor              // Optional, nil when stream compaction is disabled
+	snaps   *Snapshotter            // Optional, nil when automatic snapshots are disabled
+	guard   *WriteGuard             // Rejects writes to frozen namespaces
+	derived *DerivedStreams         // Appends events derived from writes by namespace rules
+	queries *StandingQueries        // Appends writes matching standing queries to result streams
+	tmpls   *MetadataTemplates      // Merges per-type default metadata into writes
+	ids     *MessageIDs             // Generates IDs of messages written without one
+	plugins *PluginHost             // Optional, nil when write plugins are disabled
+	bps     *Blueprints             // Optional, nil when ns.create templates are disabled
+	views   *CategoryViews          // Resolves virtual categories read by category.get
+	aliases *StreamAliases          // Redirects renamed streams to their new names
+	queue   *WriteQueue             // Optional, nil when writes are not queued during outages
+	breaker *store.BreakerStore     // Optional, nil when the store has no circuit breaker
+	router  *Router                 // Optional, nil when writes are not routed to an owner node
+	shards  *store.ShardedStore     // Optional, nil when namespaces are not sharded
+	dataDir string                  // Optional, disk checked by sys.health deep mode
+	storage *StorageTracker         // Optional, nil when storage growth is not sampled
+	names   *store.StreamNamePolicy // Stream name rules for writes, nil to accept any name
+	admit   *AdmissionController    // Optional, nil when write rates are not limited
+	latency *LatencyTracker         // Optional, nil when RPC latency is not recorded
+	results *ResultBudget           // Optional, nil when result sizes are not limited
+	jobs    *JobScheduler           // Optional, nil when background work is not scheduled
+	durable *DurableSubscriptions   // Durable SSE subscriptions acked by consumer.ack
 	methods map[string]RPCMethod
 	nsMu    sync.Mutex // Protects namespace auto-creation in test mode
 }
@@ -38,37 +71,270 @@ type ErrorResponse struct {
 
 // NewRPCHandler creates a new RPC handler
 func NewRPCHandler(version string, st store.Store, pubsub *PubSub) *RPCHandler {
+	meta := NewNamespaceMetadata(st)
 	h := &RPCHandler{
 		version: version,
 		store:   st,
 		pubsub:  pubsub,
+		guard:   NewWriteGuard(meta),
+		derived: NewDerivedStreams(meta),
+		queries: NewStandingQueries(meta),
+		tmpls:   NewMetadataTemplates(meta),
+		ids:     NewMessageIDs(meta),
+		views:   NewCategoryViews(meta),
+		aliases: NewStreamAliases(meta),
+		durable: NewDurableSubscriptions(),
+		names:   store.DefaultStreamNamePolicy(),
 		methods: make(map[string]RPCMethod),
 	}
 
 	// Register system methods
 	h.registerMethod("sys.version", h.handleSysVersion)
 	h.registerMethod("sys.health", h.handleSysHealth)
+	h.registerMethod("sys.parseStreamName", h.handleSysParseStreamName)
+	h.registerMethod("sys.capabilities", h.handleSysCapabilities)
+	h.registerMethod("sys.profile", h.handleSysProfile)
+	h.registerMethod("sys.stats", h.handleSysStats)
+	h.registerMethod("sys.describe", h.handleSysDescribe)
+	h.registerMethod("sys.jobs", h.handleSysJobs)
+	h.registerMethod("sys.jobs.list", h.handleSysJobsList)
+	h.registerMethod("sys.jobs.cancel", h.handleSysJobsCancel)
+	h.registerMethod("sys.jobs.retry", h.handleSysJobsRetry)
+
+	// Register auth methods
+	h.registerMethod("auth.whoami", h.handleAuthWhoami)
 
 	// Register stream methods
 	h.registerMethod("stream.write", h.handleStreamWrite)
+	h.registerMethod("stream.writeBatch", h.handleStreamWriteBatch)
+	h.registerMethod("stream.casWrite", h.handleStreamCASWrite)
 	h.registerMethod("stream.get", h.handleStreamGet)
 	h.registerMethod("stream.last", h.handleStreamLast)
 	h.registerMethod("stream.version", h.handleStreamVersion)
+	h.registerMethod("stream.info", h.handleStreamInfo)
+	h.registerMethod("stream.claim", h.handleStreamClaim)
+	h.registerMethod("stream.merge", h.handleStreamMerge)
+	h.registerMethod("stream.rename", h.handleStreamRename)
+
+	// Register entity methods
+	h.registerMethod("entity.load", h.handleEntityLoad)
+
+	// Register document methods
+	h.registerMethod("doc.put", h.handleDocPut)
+	h.registerMethod("doc.get", h.handleDocGet)
+	h.registerMethod("doc.delete", h.handleDocDelete)
+	h.registerMethod("doc.write", h.handleDocWrite)
+	h.registerMethod("doc.position", h.handleDocPosition)
+
+	// Register message methods
+	h.registerMethod("message.redact", h.handleMessageRedact)
+
+	// Register edge sync methods
+	h.registerMethod("edge.push", h.handleEdgePush)
 
 	// Register category methods
 	h.registerMethod("category.get", h.handleCategoryGet)
+	h.registerMethod("category.gaps", h.handleCategoryGaps)
+	h.registerMethod("viewCategory.create", h.handleCategoryViewCreate)
+	h.registerMethod("viewCategory.delete", h.handleCategoryViewDelete)
+	h.registerMethod("viewCategory.list", h.handleCategoryViewList)
 
 	// Register namespace methods
 	h.registerMethod("ns.create", h.handleNamespaceCreate)
 	h.registerMethod("ns.delete", h.handleNamespaceDelete)
 	h.registerMethod("ns.list", h.handleNamespaceList)
 	h.registerMethod("ns.info", h.handleNamespaceInfo)
+	h.registerMethod("ns.rotateToken", h.handleNamespaceRotateToken)
 	h.registerMethod("ns.streams", h.handleNamespaceStreams)
+	h.registerMethod("ns.duplicateStreams", h.handleNamespaceDuplicateStreams)
 	h.registerMethod("ns.categories", h.handleNamespaceCategories)
+	h.registerMethod("ns.typeStats", h.handleNamespaceTypeStats)
+	h.registerMethod("ns.logShipping.set", h.handleLogShippingSet)
+	h.registerMethod("ns.logShipping.get", h.handleLogShippingGet)
+	h.registerMethod("ns.exportSchedule.set", h.handleExportScheduleSet)
+	h.registerMethod("ns.exportSchedule.get", h.handleExportScheduleGet)
+	h.registerMethod("ns.exportSchedule.run", h.handleExportScheduleRun)
+	h.registerMethod("ns.backups.list", h.handleBackupsList)
+	h.registerMethod("ns.backups.restore", h.handleBackupsRestore)
+	h.registerMethod("ns.mirror.set", h.handleMirrorSet)
+	h.registerMethod("ns.mirror.status", h.handleMirrorStatus)
+	h.registerMethod("ns.mirror.promote", h.handleMirrorPromote)
+	h.registerMethod("ns.edgeSync.set", h.handleEdgeSyncSet)
+	h.registerMethod("ns.edgeSync.status", h.handleEdgeSyncStatus)
+	h.registerMethod("ns.worm.enable", h.handleWormEnable)
+	h.registerMethod("ns.worm.status", h.handleWormStatus)
+	h.registerMethod("ns.worm.attest", h.handleWormAttest)
+	h.registerMethod("ns.config.export", h.handleNamespaceConfigExport)
+	h.registerMethod("ns.config.import", h.handleNamespaceConfigImport)
+	h.registerMethod("import.commit", h.handleImportCommit)
+	h.registerMethod("import.abort", h.handleImportAbort)
+	h.registerMethod("ns.freeze", h.handleNamespaceFreeze)
+	h.registerMethod("ns.unfreeze", h.handleNamespaceUnfreeze)
+	h.registerMethod("ns.suspend", h.handleNamespaceSuspend)
+	h.registerMethod("ns.resume", h.handleNamespaceResume)
+	h.registerMethod("ns.shards", h.handleNamespaceShards)
+	h.registerMethod("ns.storage", h.handleNamespaceStorage)
+	h.registerMethod("ns.retention.set", h.handleRetentionSet)
+	h.registerMethod("ns.retention.get", h.handleRetentionGet)
+	h.registerMethod("ns.compact", h.handleNamespaceCompact)
+	h.registerMethod("ns.derivedStreams.set", h.handleDerivedStreamsSet)
+	h.registerMethod("ns.derivedStreams.get", h.handleDerivedStreamsGet)
+	h.registerMethod("ns.queries.set", h.handleQueriesSet)
+	h.registerMethod("ns.queries.get", h.handleQueriesGet)
+	h.registerMethod("ns.metadataTemplates.set", h.handleMetadataTemplatesSet)
+	h.registerMethod("ns.metadataTemplates.get", h.handleMetadataTemplatesGet)
+	h.registerMethod("ns.messageIds.set", h.handleMessageIDsSet)
+	h.registerMethod("ns.messageIds.get", h.handleMessageIDsGet)
+	h.registerMethod("ns.plugins.set", h.handlePluginsSet)
+	h.registerMethod("ns.plugins.get", h.handlePluginsGet)
+	h.registerMethod("ns.readPlugins.set", h.handleReadPluginsSet)
+	h.registerMethod("ns.readPlugins.get", h.handleReadPluginsGet)
+	h.registerMethod("ns.snapshots.set", h.handleSnapshotsSet)
+	h.registerMethod("ns.snapshots.get", h.handleSnapshotsGet)
+	h.registerMethod("ns.udpDevices.add", h.handleUDPDevicesAdd)
+	h.registerMethod("ns.udpDevices.list", h.handleUDPDevicesList)
+	h.registerMethod("ns.udpDevices.remove", h.handleUDPDevicesRemove)
+
+	// Register bookmark methods
+	h.registerMethod("bookmark.set", h.handleBookmarkSet)
+	h.registerMethod("bookmark.get", h.handleBookmarkGet)
+	h.registerMethod("bookmark.list", h.handleBookmarkList)
+	h.registerMethod("bookmark.delete", h.handleBookmarkDelete)
+
+	// Register consumer position methods
+	h.registerMethod("consumer.setPosition", h.handleConsumerSetPosition)
+	h.registerMethod("consumer.getPosition", h.handleConsumerGetPosition)
+	h.registerMethod("consumer.ack", h.handleConsumerAck)
+
+	// Register tick methods
+	h.registerMethod("tick.create", h.handleTickCreate)
+	h.registerMethod("tick.list", h.handleTickList)
+	h.registerMethod("tick.delete", h.handleTickDelete)
+
+	// Register webhook methods
+	h.registerMethod("hook.redeliver", h.handleHookRedeliver)
 
 	return h
 }
 
+// SetWebhookPublisher attaches the webhook publisher used by hook.* methods
+func (h *RPCHandler) SetWebhookPublisher(p *WebhookPublisher) {
+	h.hooks = p
+}
+
+// SetNamespaceMetadata reads derived streams, standing queries, category
+// views and stream aliases through meta, so they share its cache with other
+// handlers
+func (h *RPCHandler) SetNamespaceMetadata(meta *NamespaceMetadata) {
+	h.derived = NewDerivedStreams(meta)
+	h.queries = NewStandingQueries(meta)
+	h.views = NewCategoryViews(meta)
+	h.aliases = NewStreamAliases(meta)
+}
+
+// SetWriteGuard replaces the write guard, so it can be shared with other write paths
+func (h *RPCHandler) SetWriteGuard(g *WriteGuard) {
+	h.guard = g
+}
+
+// SetMetadataTemplates replaces the metadata templates, so they can be shared with other write paths
+func (h *RPCHandler) SetMetadataTemplates(m *MetadataTemplates) {
+	h.tmpls = m
+}
+
+// SetMessageIDs replaces the message ID strategies, so they can be shared with other write paths
+func (h *RPCHandler) SetMessageIDs(m *MessageIDs) {
+	h.ids = m
+}
+
+// SetPluginHost runs the namespace's write plugins on stream.write and its
+// read plugins on reads with options.decode
+func (h *RPCHandler) SetPluginHost(p *PluginHost) {
+	h.plugins = p
+}
+
+// SetBlueprints lets ns.create provision namespaces from blueprints
+func (h *RPCHandler) SetBlueprints(b *Blueprints) {
+	h.bps = b
+}
+
+// SetWriteQueue queues stream.write calls while the backend is unavailable
+func (h *RPCHandler) SetWriteQueue(q *WriteQueue) {
+	h.queue = q
+}
+
+// SetAdmission rate limits stream.write calls with RATE_LIMITED
+func (h *RPCHandler) SetAdmission(a *AdmissionController) {
+	h.admit = a
+}
+
+// SetResultBudget rejects oversized results with RESULT_TOO_LARGE and bounds
+// the memory of results being written
+func (h *RPCHandler) SetResultBudget(b *ResultBudget) {
+	h.results = b
+}
+
+// SetBreaker makes RPC methods fail fast with BACKEND_UNAVAILABLE while the breaker is open
+func (h *RPCHandler) SetBreaker(b *store.BreakerStore) {
+	h.breaker = b
+}
+
+// SetRouter redirects writes for namespaces owned by another node with MISROUTED
+func (h *RPCHandler) SetRouter(r *Router) {
+	h.router = r
+}
+
+// SetShards enables shard placement in ns.create and the ns.shards method
+func (h *RPCHandler) SetShards(s *store.ShardedStore) {
+	h.shards = s
+}
+
+// SetLogShipper attaches the log shipper used by ns.logShipping.* methods
+func (h *RPCHandler) SetLogShipper(s *LogShipper) {
+	h.shipper = s
+}
+
+// SetExportScheduler attaches the scheduler used by ns.exportSchedule.* methods
+func (h *RPCHandler) SetExportScheduler(s *ExportScheduler) {
+	h.exports = s
+}
+
+// SetTickScheduler attaches the scheduler used by tick.* methods
+func (h *RPCHandler) SetTickScheduler(s *TickScheduler) {
+	h.ticks = s
+}
+
+// SetMirror attaches the namespace mirror used by ns.mirror.set
+func (h *RPCHandler) SetMirror(m *Mirror) {
+	h.mirror = m
+}
+
+// SetEdgeSync attaches the edge sync manager used by ns.edgeSync.set
+func (h *RPCHandler) SetEdgeSync(e *EdgeSync) {
+	h.edge = e
+}
+
+// SetAttestor attaches the attestor used by ns.worm.attest
+func (h *RPCHandler) SetAttestor(a *Attestor) {
+	h.attest = a
+}
+
+// SetSnapshotter attaches the snapshotter used by ns.snapshots.* methods
+func (h *RPCHandler) SetSnapshotter(s *Snapshotter) {
+	h.snaps = s
+}
+
+// SetCompactor attaches the compactor used by ns.compact
+func (h *RPCHandler) SetCompactor(c *Compactor) {
+	h.compact = c
+}
+
+// SetDurableSubscriptions attaches the durable subscriptions of the SSE
+// handler, whose messages consumer.ack acknowledges
+func (h *RPCHandler) SetDurableSubscriptions(d *DurableSubscriptions) {
+	h.durable = d
+}
+
 // registerMethod registers an RPC method handler
 func (h *RPCHandler) registerMethod(name string, handler RPCMethod) {
 	h.methods[name] = handler
@@ -77,6 +343,8 @@ func (h *RPCHandler) registerMethod(name string, handler RPCMethod) {
 // ServeHTTP implements http.Handler
 func (h *RPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	logger.Get().Debug().Msg("RPC ServeHTTP called")
+	w.Header().Set(ProtocolHeader, ProtocolVersion)
+
 	// Only accept POST requests
 	if r.Method != http.MethodPost {
 		h.writeError(w, http.StatusMethodNotAllowed, &RPCError{
@@ -123,28 +391,37 @@ func (h *RPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		args = req[1:]
 	}
 
+	// Tell the client which routing token to send for its namespace
+	ctx := r.Context()
+	if namespace, ok := GetNamespaceFromContext(ctx); ok {
+		w.Header().Set(RouteHeader, RouteToken(namespace))
+	}
+	if r.URL.Query().Has(RoutedParam) {
+		ctx = context.WithValue(ctx, ContextKeyRouted, true)
+	}
+	if required := r.Header.Get(MinVersionHeader); required != "" {
+		ctx = context.WithValue(ctx, ContextKeyMinVersion, required)
+	}
+	if acceptsNDJSON(r.Header.Get("Accept")) {
+		ctx = context.WithValue(ctx, ContextKeyNDJSON, true)
+	}
+
 	// Route to handler
 	logger.Get().Debug().
 		Str("method", method).
 		Int("args_count", len(args)).
 		Msg("RPC method invoked")
-	result, err := h.route(r.Context(), method, args)
+	ctx, headers := withResponseHeaders(ctx)
+	ctx, release := h.results.begin(ctx)
+	defer release()
+	result, err := h.route(ctx, method, args)
 	if err != nil {
-		// Determine HTTP status code based on error code
-		statusCode := http.StatusInternalServerError
-		switch err.Code {
-		case "INVALID_REQUEST":
-			statusCode = http.StatusBadRequest
-		case "METHOD_NOT_FOUND":
-			statusCode = http.StatusNotFound
-		case "AUTH_REQUIRED", "AUTH_INVALID_TOKEN":
-			statusCode = http.StatusUnauthorized
-		case "AUTH_UNAUTHORIZED":
-			statusCode = http.StatusForbidden
-		case "STREAM_NOT_FOUND", "NAMESPACE_NOT_FOUND":
-			statusCode = http.StatusNotFound
-		case "STREAM_VERSION_CONFLICT", "NAMESPACE_EXISTS":
-			statusCode = http.StatusConflict
+		statusCode := httpStatus(err.Code)
+		if seconds, ok := retryAfter(err); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+		if location, ok := redirectLocation(err); ok {
+			w.Header().Set("Location", location)
 		}
 		if statusCode == http.StatusInternalServerError {
 			logger.Get().Error().
@@ -158,11 +435,56 @@ func (h *RPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Write success response
+	for key, value := range headers {
+		w.Header().Set(key, value)
+	}
 	h.writeSuccess(w, result)
 }
 
+// httpStatus returns the HTTP status of an RPC error code, shared by both
+// HTTP transports
+func httpStatus(code string) int {
+	switch code {
+	case "INVALID_REQUEST", "INVALID_STREAM_NAME":
+		return http.StatusBadRequest
+	case "METHOD_NOT_FOUND":
+		return http.StatusNotFound
+	case "AUTH_REQUIRED", "TOKEN_MALFORMED", "TOKEN_EXPIRED", "TOKEN_REVOKED":
+		return http.StatusUnauthorized
+	case "AUTH_UNAUTHORIZED", "READ_ONLY", "NAMESPACE_SUSPENDED", "ADMIN_LISTENER_ONLY", "WORM_PROTECTED":
+		return http.StatusForbidden
+	case "STREAM_NOT_FOUND", "NAMESPACE_NOT_FOUND", "HOOK_NOT_FOUND", "CLAIM_NOT_FOUND", "BOOKMARK_NOT_FOUND",
+		"MESSAGE_NOT_FOUND", "VIEW_NOT_FOUND", "PLUGIN_NOT_FOUND", "BLUEPRINT_NOT_FOUND", "DOCUMENT_NOT_FOUND", "BACKUP_NOT_FOUND",
+		"TICK_NOT_FOUND", "CONSUMER_POSITION_NOT_FOUND", "SUBSCRIPTION_NOT_FOUND", "JOB_NOT_FOUND":
+		return http.StatusNotFound
+	case "PLUGIN_REJECTED", "IMPORT_INVALID", "RESULT_TOO_LARGE":
+		return http.StatusUnprocessableEntity
+	case "STREAM_VERSION_CONFLICT", "NAMESPACE_EXISTS", "PROFILE_IN_PROGRESS", "VIEW_EXISTS", "TICK_EXISTS",
+		"CONDITION_FAILED", "JOB_STATE_CONFLICT":
+		return http.StatusConflict
+	case "RATE_LIMITED":
+		return http.StatusTooManyRequests
+	case "QUEUE_FULL", "BACKEND_UNAVAILABLE", "OVERLOADED":
+		return http.StatusServiceUnavailable
+	case "MISROUTED":
+		return http.StatusTemporaryRedirect
+	case "UPGRADE_REQUIRED":
+		return http.StatusUpgradeRequired
+	case "PLUGIN_FAILED":
+		return http.StatusBadGateway
+	case "INTERNAL_ERROR":
+		return http.StatusInternalServerError
+	}
+	return http.StatusInternalServerError
+}
+
 // route dispatches the request to the appropriate method handler
 func (h *RPCHandler) route(ctx context.Context, method string, args []interface{}) (interface{}, *RPCError) {
+	// Old clients fail clearly instead of misreading newer responses
+	if rpcErr := checkMinVersion(ctx); rpcErr != nil {
+		return nil, rpcErr
+	}
+
 	handler, exists := h.methods[method]
 	if !exists {
 		return nil, &RPCError{
@@ -171,7 +493,40 @@ func (h *RPCHandler) route(ctx context.Context, method string, args []interface{
 		}
 	}
 
-	return handler(ctx, args)
+	// Admin methods are not served on the public listener when an admin one exists
+	if rpcErr := checkListener(ctx, method); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Writes for a namespace owned by another node are sent there
+	if h.router != nil {
+		namespace, _ := GetNamespaceFromContext(ctx)
+		if rpcErr := h.router.check(ctx, method, namespace); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	// Fail fast while the backend is down. sys.* methods do not need it, and
+	// stream.write is queued instead when a write queue is configured.
+	if h.breaker != nil && !strings.HasPrefix(method, "sys.") && (method != "stream.write" || h.queue == nil) {
+		if err := h.breaker.Check(); err != nil {
+			return nil, backendUnavailableError(err)
+		}
+	}
+
+	start := time.Now()
+	result, rpcErr := handler(ctx, args)
+	if h.latency != nil {
+		namespace, _ := GetNamespaceFromContext(ctx)
+		h.latency.Observe(method, namespace, time.Since(start))
+	}
+	if rpcErr != nil && (rpcErr.Code == "BACKEND_ERROR" || rpcErr.Code == "BACKEND_UNAVAILABLE") && h.breaker != nil {
+		// The call that trips the breaker reports the outage like the ones after it
+		if err := h.breaker.Check(); err != nil {
+			return nil, backendUnavailableError(err)
+		}
+	}
+	return result, rpcErr
 }
 
 // handleSysVersion returns the server version
@@ -188,15 +543,30 @@ func (h *RPCHandler) handleSysHealth(ctx context.Context, args []interface{}) (i
 		backend = "unknown"
 	}
 
-	return map[string]interface{}{
+	health := map[string]interface{}{
 		"status":      "ok",
 		"backend":     backend,
 		"connections": 0,
-	}, nil
+	}
+	if h.queue != nil {
+		health["queuedWrites"] = h.queue.Pending()
+	}
+	if len(args) > 0 {
+		if opts, ok := args[0].(map[string]interface{}); ok && opts["deep"] == true {
+			if rpcErr := h.deepHealth(ctx, health); rpcErr != nil {
+				return nil, rpcErr
+			}
+		}
+	}
+	return health, nil
 }
 
 // writeSuccess writes a successful JSON response
 func (h *RPCHandler) writeSuccess(w http.ResponseWriter, result interface{}) {
+	if streamed, ok := result.(*streamedResponse); ok {
+		streamed.writeHTTP(w)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -4,6 +4,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 
 	"github.com/eventodb/eventodb/internal/logger"
 	"github.com/valyala/fasthttp"
@@ -73,23 +74,17 @@ func (h *RPCHandler) ServeHTTPFast(ctx *fasthttp.RequestCtx) {
 		Int("args_count", len(args)).
 		Msg("RPC method invoked")
 
+	reqCtx, headers := withResponseHeaders(reqCtx)
+	reqCtx, release := h.results.begin(reqCtx)
+	defer release()
 	result, err := h.route(reqCtx, method, args)
 	if err != nil {
-		// Determine HTTP status code based on error code
-		statusCode := fasthttp.StatusInternalServerError
-		switch err.Code {
-		case "INVALID_REQUEST":
-			statusCode = fasthttp.StatusBadRequest
-		case "METHOD_NOT_FOUND":
-			statusCode = fasthttp.StatusNotFound
-		case "AUTH_REQUIRED", "AUTH_INVALID_TOKEN":
-			statusCode = fasthttp.StatusUnauthorized
-		case "AUTH_UNAUTHORIZED":
-			statusCode = fasthttp.StatusForbidden
-		case "STREAM_NOT_FOUND", "NAMESPACE_NOT_FOUND":
-			statusCode = fasthttp.StatusNotFound
-		case "STREAM_VERSION_CONFLICT", "NAMESPACE_EXISTS":
-			statusCode = fasthttp.StatusConflict
+		statusCode := httpStatus(err.Code)
+		if seconds, ok := retryAfter(err); ok {
+			ctx.Response.Header.Set("Retry-After", strconv.Itoa(seconds))
+		}
+		if location, ok := redirectLocation(err); ok {
+			ctx.Response.Header.Set("Location", location)
 		}
 		if statusCode == fasthttp.StatusInternalServerError {
 			logger.Get().Error().
@@ -103,11 +98,18 @@ func (h *RPCHandler) ServeHTTPFast(ctx *fasthttp.RequestCtx) {
 	}
 
 	// Write success response
+	for key, value := range headers {
+		ctx.Response.Header.Set(key, value)
+	}
 	h.writeSuccessFast(ctx, result)
 }
 
 // writeSuccessFast writes a successful JSON response using fasthttp
 func (h *RPCHandler) writeSuccessFast(ctx *fasthttp.RequestCtx, result interface{}) {
+	if streamed, ok := result.(*streamedResponse); ok {
+		streamed.writeFast(ctx)
+		return
+	}
 	ctx.SetContentType("application/json")
 	ctx.SetStatusCode(fasthttp.StatusOK)
 
@@ -15,12 +15,29 @@ func FastHTTPRPCHandler(h *RPCHandler, testMode bool) fasthttp.RequestHandler {
 
 		if namespace, ok := GetNamespaceFromFastHTTP(ctx); ok {
 			reqCtx = context.WithValue(reqCtx, ContextKeyNamespace, namespace)
+			ctx.Response.Header.Set(RouteHeader, RouteToken(namespace))
+		}
+
+		if ctx.QueryArgs().Has(RoutedParam) {
+			reqCtx = context.WithValue(reqCtx, ContextKeyRouted, true)
+		}
+
+		ctx.Response.Header.Set(ProtocolHeader, ProtocolVersion)
+		if required := ctx.Request.Header.Peek(MinVersionHeader); len(required) > 0 {
+			reqCtx = context.WithValue(reqCtx, ContextKeyMinVersion, string(required))
+		}
+		if acceptsNDJSON(string(ctx.Request.Header.Peek("Accept"))) {
+			reqCtx = context.WithValue(reqCtx, ContextKeyNDJSON, true)
 		}
 
 		if IsTestModeFastHTTP(ctx) {
 			reqCtx = context.WithValue(reqCtx, ContextKeyTestMode, true)
 		}
 
+		if dataPathOnly, _ := ctx.UserValue("dataPathOnly").(bool); dataPathOnly {
+			reqCtx = context.WithValue(reqCtx, ContextKeyDataPathOnly, true)
+		}
+
 		// Store context in fasthttp user values for handlers to access
 		ctx.SetUserValue("ctx", reqCtx)
 
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// errorStatuses posts a call of namespace over both HTTP transports and
+// returns their statuses
+func errorStatuses(t *testing.T, h *RPCHandler, namespace, body string) (int, int) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), ContextKeyNamespace, namespace))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+	ctx.Request.SetRequestURI("/rpc")
+	ctx.Request.SetBodyString(body)
+	ctx.SetUserValue("namespace", namespace)
+	FastHTTPRPCHandler(h, false)(&ctx)
+	return rec.Code, ctx.Response.StatusCode()
+}
+
+func TestRPC_ErrorStatuses(t *testing.T) {
+	h := NewRPCHandler("test", newTestStore(t), NewPubSub())
+	h.registerMethod("test.fail", func(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+		return nil, &RPCError{Code: args[0].(string), Message: "failed"}
+	})
+
+	for code, want := range map[string]int{
+		"TOKEN_MALFORMED":     http.StatusUnauthorized,
+		"TOKEN_EXPIRED":       http.StatusUnauthorized,
+		"TOKEN_REVOKED":       http.StatusUnauthorized,
+		"AUTH_REQUIRED":       http.StatusUnauthorized,
+		"NAMESPACE_SUSPENDED": http.StatusForbidden,
+		"STREAM_NOT_FOUND":    http.StatusNotFound,
+		"PLUGIN_FAILED":       http.StatusBadGateway,
+		"INTERNAL_ERROR":      http.StatusInternalServerError,
+		"SOMETHING_UNKNOWN":   http.StatusInternalServerError,
+		"BACKEND_UNAVAILABLE": http.StatusServiceUnavailable,
+	} {
+		status, fastStatus := errorStatuses(t, h, "test-ns", `["test.fail", "`+code+`"]`)
+		if status != want || fastStatus != want {
+			t.Errorf("Expected %d for %s, got %d over net/http and %d over fasthttp", want, code, status, fastStatus)
+		}
+	}
+}
+
+func TestRPC_SuspendedWriteStatus(t *testing.T) {
+	h := NewRPCHandler("test", newTestStore(t), NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	if _, rpcErr := h.route(ctx, "ns.create", []interface{}{"tenant-a"}); rpcErr != nil {
+		t.Fatalf("ns.create failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "ns.suspend", []interface{}{"tenant-a"}); rpcErr != nil {
+		t.Fatalf("ns.suspend failed: %v", rpcErr.Message)
+	}
+
+	for _, body := range []string{
+		`["stream.write", "account-1", {"type": "Opened", "data": {}}]`,
+		`["stream.writeBatch", "account-1", [{"type": "Opened", "data": {}}]]`,
+	} {
+		status, fastStatus := errorStatuses(t, h, "tenant-a", body)
+		if status != http.StatusForbidden || fastStatus != http.StatusForbidden {
+			t.Errorf("Expected 403 for %s, got %d over net/http and %d over fasthttp", body, status, fastStatus)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+// Package api provides a background integrity checker for stored messages.
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// IntegrityAnomalyCategory is the category of the streams anomalies are
+	// reported to, one stream per affected namespace
+	IntegrityAnomalyCategory = "integrityAnomaly"
+
+	// scrubberStartDelay keeps the first pass out of server startup
+	scrubberStartDelay = time.Minute
+
+	// scrubberStreamPage is the number of streams listed per call
+	scrubberStreamPage = 500
+)
+
+// Integrity anomaly kinds
+const (
+	AnomalyPositionGap             = "positionGap"             // Stream positions skip or repeat
+	AnomalyDuplicateGlobalPosition = "duplicateGlobalPosition" // Two messages share a global position
+	AnomalyInvalidJSON             = "invalidJson"             // Stored data or metadata does not parse
+	AnomalyStorage                 = "storage"                 // The backend's own check failed
+	AnomalyUnreadable              = "unreadable"              // Reading the stream failed
+)
+
+// ScrubberConfig configures the integrity scrubber
+type ScrubberConfig struct {
+	Interval        time.Duration // Time between passes (default: 6h)
+	Pause           time.Duration // Pause after each read, keeps the scrubber low priority (default: 20ms, negative for none)
+	BatchSize       int64         // Messages per read (default: 500)
+	ReportNamespace string        // Namespace of the anomaly streams (default: "default")
+}
+
+// IntegrityAnomaly is one problem found by the scrubber
+type IntegrityAnomaly struct {
+	Kind      string
+	Namespace string
+	Stream    string // Empty for namespace-wide anomalies
+	Position  int64  // Stream or global position, -1 if not applicable
+	Detail    string
+}
+
+// ScrubberStats summarizes the scrubber's work
+type ScrubberStats struct {
+	Passes           int64            `json:"passes"`
+	LastPassAt       time.Time        `json:"lastPassAt"`
+	LastPassDuration time.Duration    `json:"lastPassDuration"`
+	StreamsChecked   int64            `json:"streamsChecked"`  // In all passes
+	MessagesChecked  int64            `json:"messagesChecked"` // In all passes
+	Anomalies        map[string]int64 `json:"anomalies"`       // Distinct anomalies found, by kind
+}
+
+// Scrubber continuously verifies stored messages: stream positions are
+// contiguous from 0, global positions are unique within a namespace, and
+// stored JSON parses (on backends implementing store.IntegrityChecker).
+//
+// Each anomaly is reported once per server run: as an AnomalyDetected
+// message in the integrityAnomaly-<namespace> stream of the report namespace,
+// as a system event, and in the metrics.
+type Scrubber struct {
+	store    store.Store
+	pubsub   *PubSub
+	notifier *Notifier
+	cfg      ScrubberConfig
+
+	mu       sync.Mutex
+	stats    ScrubberStats
+	reported map[string]bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScrubber creates a scrubber; call Start to begin checking
+func NewScrubber(st store.Store, pubsub *PubSub, cfg ScrubberConfig) *Scrubber {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 6 * time.Hour
+	}
+	if cfg.Pause < 0 {
+		cfg.Pause = 0
+	} else if cfg.Pause == 0 {
+		cfg.Pause = 20 * time.Millisecond
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.ReportNamespace == "" {
+		cfg.ReportNamespace = "default"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scrubber{
+		store:    st,
+		pubsub:   pubsub,
+		cfg:      cfg,
+		stats:    ScrubberStats{Anomalies: make(map[string]int64)},
+		reported: make(map[string]bool),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// SetNotifier sends anomalies as integrity.anomaly system events
+func (s *Scrubber) SetNotifier(n *Notifier) {
+	s.notifier = n
+}
+
+// Start runs passes in the background until Close
+func (s *Scrubber) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		delay := scrubberStartDelay
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			// Scrubbing reads run behind interactive reads
+			if err := s.pass(store.WithReadPriority(s.ctx, store.ReadPriorityLow)); err != nil && s.ctx.Err() == nil {
+				logger.Get().Warn().Err(err).Msg("Integrity scrub pass failed")
+			}
+			delay = s.cfg.Interval
+		}
+	}()
+	logger.Get().Info().Dur("interval", s.cfg.Interval).Msg("Integrity scrubber started")
+}
+
+// Close stops the scrubber, abandoning a pass in progress
+func (s *Scrubber) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Stats returns a snapshot of the scrubber's counters
+func (s *Scrubber) Stats() ScrubberStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Anomalies = make(map[string]int64, len(s.stats.Anomalies))
+	for k, v := range s.stats.Anomalies {
+		stats.Anomalies[k] = v
+	}
+	return stats
+}
+
+// pass checks every namespace once
+func (s *Scrubber) pass(ctx context.Context) error {
+	started := time.Now()
+	namespaces, err := s.store.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	for _, ns := range namespaces {
+		if err := s.scrubNamespace(ctx, ns.ID, RetentionFromMetadata(ns.Metadata)); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Get().Warn().Err(err).Str("namespace", ns.ID).Msg("Integrity scrub of namespace failed")
+		}
+	}
+
+	s.mu.Lock()
+	s.stats.Passes++
+	s.stats.LastPassAt = time.Now().UTC()
+	s.stats.LastPassDuration = time.Since(started)
+	s.mu.Unlock()
+	return nil
+}
+
+// scrubNamespace checks all streams of a namespace, then global position
+// uniqueness. Streams compacted by retention rules may start after position 0.
+func (s *Scrubber) scrubNamespace(ctx context.Context, namespace string, retention *RetentionConfig) error {
+	checker, _ := s.store.(store.IntegrityChecker)
+	if checker != nil {
+		problems, err := checker.CheckStorage(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			s.report(ctx, IntegrityAnomaly{Kind: AnomalyStorage, Namespace: namespace, Position: -1, Detail: problem})
+		}
+	}
+
+	// Global positions of every message, 8 bytes each, checked for duplicates at the end
+	var globals []int64
+	cursor := ""
+	for {
+		streams, err := s.store.ListStreams(ctx, namespace, &store.ListStreamsOpts{Limit: scrubberStreamPage, Cursor: cursor})
+		if err != nil {
+			return err
+		}
+		for _, info := range streams {
+			compacted := retention.Match(info.StreamName) != nil
+			globals, err = s.scrubStream(ctx, checker, namespace, info.StreamName, compacted, globals)
+			if err != nil {
+				return err
+			}
+		}
+		if len(streams) < scrubberStreamPage {
+			break
+		}
+		cursor = streams[len(streams)-1].StreamName
+	}
+
+	sort.Slice(globals, func(i, j int) bool { return globals[i] < globals[j] })
+	for i := 1; i < len(globals); i++ {
+		if globals[i] == globals[i-1] && (i < 2 || globals[i-2] != globals[i]) {
+			s.report(ctx, IntegrityAnomaly{
+				Kind:      AnomalyDuplicateGlobalPosition,
+				Namespace: namespace,
+				Position:  globals[i],
+				Detail:    fmt.Sprintf("global position %d is used by more than one message", globals[i]),
+			})
+		}
+	}
+	return nil
+}
+
+// scrubStream checks one stream and appends its global positions to globals
+func (s *Scrubber) scrubStream(ctx context.Context, checker store.IntegrityChecker, namespace, stream string, compacted bool, globals []int64) ([]int64, error) {
+	expected := int64(0)
+	first := true
+	for {
+		msgs, err := s.store.GetStreamMessages(ctx, namespace, stream, &store.GetOpts{Position: expected, BatchSize: s.cfg.BatchSize})
+		if err != nil {
+			if ctx.Err() != nil || store.IsBackendUnavailable(err) {
+				return globals, err
+			}
+			s.report(ctx, IntegrityAnomaly{Kind: AnomalyUnreadable, Namespace: namespace, Stream: stream, Position: expected, Detail: err.Error()})
+			break
+		}
+		for _, msg := range msgs {
+			if first && compacted {
+				expected = msg.Position
+			}
+			first = false
+			if msg.Position != expected {
+				s.report(ctx, IntegrityAnomaly{
+					Kind:      AnomalyPositionGap,
+					Namespace: namespace,
+					Stream:    stream,
+					Position:  expected,
+					Detail:    fmt.Sprintf("expected position %d, found %d", expected, msg.Position),
+				})
+			}
+			expected = msg.Position + 1
+			globals = append(globals, msg.GlobalPosition)
+		}
+
+		s.mu.Lock()
+		s.stats.MessagesChecked += int64(len(msgs))
+		s.mu.Unlock()
+		if !s.sleep(ctx) {
+			return globals, ctx.Err()
+		}
+		if int64(len(msgs)) < s.cfg.BatchSize {
+			break
+		}
+	}
+
+	if checker != nil {
+		positions, err := checker.InvalidJSON(ctx, namespace, stream)
+		if err != nil {
+			return globals, err
+		}
+		for _, position := range positions {
+			s.report(ctx, IntegrityAnomaly{
+				Kind:      AnomalyInvalidJSON,
+				Namespace: namespace,
+				Stream:    stream,
+				Position:  position,
+				Detail:    "stored data or metadata is not valid JSON",
+			})
+		}
+	}
+
+	s.mu.Lock()
+	s.stats.StreamsChecked++
+	s.mu.Unlock()
+	return globals, nil
+}
+
+// sleep pauses between reads, returning false if ctx is done meanwhile
+func (s *Scrubber) sleep(ctx context.Context) bool {
+	if s.cfg.Pause <= 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(s.cfg.Pause):
+		return true
+	}
+}
+
+// report records an anomaly the first time it is found
+func (s *Scrubber) report(ctx context.Context, a IntegrityAnomaly) {
+	key := fmt.Sprintf("%s|%s|%s|%d|%s", a.Kind, a.Namespace, a.Stream, a.Position, a.Detail)
+	s.mu.Lock()
+	if s.reported[key] {
+		s.mu.Unlock()
+		return
+	}
+	s.reported[key] = true
+	s.stats.Anomalies[a.Kind]++
+	s.mu.Unlock()
+
+	logger.Get().Error().
+		Str("kind", a.Kind).
+		Str("namespace", a.Namespace).
+		Str("stream", a.Stream).
+		Int64("position", a.Position).
+		Str("detail", a.Detail).
+		Msg("Integrity anomaly detected")
+
+	data := map[string]interface{}{
+		"kind":      a.Kind,
+		"namespace": a.Namespace,
+		"detail":    a.Detail,
+	}
+	if a.Stream != "" {
+		data["stream"] = a.Stream
+	}
+	if a.Position >= 0 {
+		data["position"] = a.Position
+	}
+
+	s.notifier.Notify(SystemEvent{
+		Type:      SystemEventIntegrityAnomaly,
+		Severity:  "error",
+		Namespace: a.Namespace,
+		Subject:   a.Stream,
+		Message:   fmt.Sprintf("Integrity anomaly (%s): %s", a.Kind, a.Detail),
+		Data:      data,
+	})
+
+	stream := IntegrityAnomalyCategory + "-" + a.Namespace
+	result, err := s.store.WriteMessage(ctx, s.cfg.ReportNamespace, stream, &store.Message{
+		StreamName: stream,
+		Type:       "AnomalyDetected",
+		Data:       data,
+	})
+	if err != nil {
+		logger.Get().Warn().Err(err).Str("stream", stream).Msg("Failed to record integrity anomaly")
+		return
+	}
+	if s.pubsub != nil {
+		s.pubsub.Publish(WriteEvent{
+			Namespace:      s.cfg.ReportNamespace,
+			Stream:         stream,
+			Category:       IntegrityAnomalyCategory,
+			Position:       result.Position,
+			GlobalPosition: result.GlobalPosition,
+		})
+	}
+}
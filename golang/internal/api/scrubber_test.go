@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/eventodb/eventodb/internal/store/sqlite"
+)
+
+// newScrubberTestStore returns a test-mode SQLite store with the default
+// namespace and a scrub-ns namespace holding account-1 (3 messages) and
+// account-2 (1 message)
+func newScrubberTestStore(t *testing.T) store.Store {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	st, err := sqlite.New(db, &sqlite.Config{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	ctx := context.Background()
+	for _, ns := range []string{"default", "scrub-ns"} {
+		if err := st.CreateNamespace(ctx, ns, "hash-"+ns, ""); err != nil {
+			t.Fatalf("Failed to create namespace %s: %v", ns, err)
+		}
+	}
+	for _, stream := range []string{"account-1", "account-1", "account-1", "account-2"} {
+		if _, err := st.WriteMessage(ctx, "scrub-ns", stream, &store.Message{
+			StreamName: stream,
+			Type:       "Deposited",
+			Data:       map[string]interface{}{"amount": 1},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	return st
+}
+
+func TestScrubber_ReportsAnomalies(t *testing.T) {
+	st := newScrubberTestStore(t)
+	ctx := context.Background()
+
+	// Corrupt the namespace database behind the store's back
+	raw, err := sql.Open("sqlite", "file:scrub-ns?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("Failed to open namespace database: %v", err)
+	}
+	defer raw.Close()
+	if _, err := raw.Exec(`UPDATE messages SET position = 5 WHERE stream_name = 'account-1' AND position = 2`); err != nil {
+		t.Fatalf("Failed to corrupt positions: %v", err)
+	}
+	if _, err := raw.Exec(`UPDATE messages SET data = '{"amount": 1' WHERE stream_name = 'account-2'`); err != nil {
+		t.Fatalf("Failed to corrupt data: %v", err)
+	}
+
+	s := NewScrubber(st, nil, ScrubberConfig{Pause: -1, BatchSize: 2})
+	if err := s.pass(ctx); err != nil {
+		t.Fatalf("Scrub pass failed: %v", err)
+	}
+
+	stats := s.Stats()
+	if stats.Anomalies[AnomalyPositionGap] != 1 || stats.Anomalies[AnomalyInvalidJSON] != 1 || len(stats.Anomalies) != 2 {
+		t.Errorf("Unexpected anomalies: %v", stats.Anomalies)
+	}
+	if stats.Passes != 1 || stats.MessagesChecked != 4 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "default", "integrityAnomaly-scrub-ns", nil)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("Expected 2 anomaly messages, got %d (%v)", len(msgs), err)
+	}
+	if msgs[0].Type != "AnomalyDetected" || msgs[0].Data["kind"] != AnomalyPositionGap || msgs[0].Data["stream"] != "account-1" {
+		t.Errorf("Unexpected anomaly message: %v", msgs[0].Data)
+	}
+
+	// Known anomalies are not reported again
+	if err := s.pass(ctx); err != nil {
+		t.Fatalf("Second scrub pass failed: %v", err)
+	}
+	if msgs, _ := st.GetStreamMessages(ctx, "default", "integrityAnomaly-scrub-ns", nil); len(msgs) != 2 {
+		t.Errorf("Expected anomalies to be reported once, got %d messages", len(msgs))
+	}
+}
+
+// duplicateGlobalStore reports every message of account-2 at global position 1
+type duplicateGlobalStore struct {
+	store.Store
+}
+
+func (s *duplicateGlobalStore) GetStreamMessages(ctx context.Context, namespace, streamName string, opts *store.GetOpts) ([]*store.Message, error) {
+	msgs, err := s.Store.GetStreamMessages(ctx, namespace, streamName, opts)
+	if streamName == "account-2" {
+		for _, msg := range msgs {
+			msg.GlobalPosition = 1
+		}
+	}
+	return msgs, err
+}
+
+func TestScrubber_DuplicateGlobalPositions(t *testing.T) {
+	st := &duplicateGlobalStore{Store: newScrubberTestStore(t)}
+
+	s := NewScrubber(st, nil, ScrubberConfig{Pause: -1})
+	if err := s.pass(context.Background()); err != nil {
+		t.Fatalf("Scrub pass failed: %v", err)
+	}
+	if got := s.Stats().Anomalies; got[AnomalyDuplicateGlobalPosition] != 1 || len(got) != 1 {
+		t.Errorf("Expected one duplicate global position, got %v", got)
+	}
+}
@@ -0,0 +1,524 @@
+// Package api provides automatic entity snapshots computed by reducers.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	// snapshotsMetadataKey holds the namespace's snapshot rules in namespace metadata
+	snapshotsMetadataKey = "snapshots"
+
+	// maxSnapshotRules bounds the snapshot rules kept per namespace
+	maxSnapshotRules = 32
+
+	// snapshotReduceBatch is the number of events passed to one reducer call
+	snapshotReduceBatch = 500
+
+	// SnapshotMessageType is the type of the snapshots the snapshotter writes
+	SnapshotMessageType = "Recorded"
+)
+
+// ErrSnapshotsDisabled is returned when the server was started without a snapshotter
+var ErrSnapshotsDisabled = errors.New("automatic snapshots are not enabled")
+
+// SnapshotRule snapshots the streams of a category every Every events. The
+// reducer is either a plugin in the plugin directory exporting reduce, or a
+// URL that receives the same input as a signed POST.
+type SnapshotRule struct {
+	Category string `json:"category"`         // Category whose streams are snapshotted, e.g. "account"
+	Every    int64  `json:"every"`            // Snapshot at every Every-th event of a stream
+	Plugin   string `json:"plugin,omitempty"` // Reducer plugin
+	URL      string `json:"url,omitempty"`    // Reducer webhook (POST)
+	Secret   string `json:"secret,omitempty"` // Webhook signing secret
+}
+
+// SnapshotsConfig holds a namespace's snapshot rules, at most one per category
+type SnapshotsConfig struct {
+	Rules []SnapshotRule `json:"rules"`
+}
+
+// Validate checks the rules
+func (c *SnapshotsConfig) Validate() error {
+	if len(c.Rules) > maxSnapshotRules {
+		return fmt.Errorf("at most %d snapshot rules are allowed", maxSnapshotRules)
+	}
+	seen := make(map[string]bool, len(c.Rules))
+	for i, rule := range c.Rules {
+		if rule.Category == "" || strings.Contains(rule.Category, "-") {
+			return fmt.Errorf("rule %d: category must be a non-empty category name", i)
+		}
+		if seen[rule.Category] {
+			return fmt.Errorf("rule %d: category %q already has a rule", i, rule.Category)
+		}
+		seen[rule.Category] = true
+		if rule.Every < 1 {
+			return fmt.Errorf("rule %d: every must be at least 1", i)
+		}
+		switch {
+		case rule.Plugin != "" && rule.URL != "":
+			return fmt.Errorf("rule %d: set plugin or url, not both", i)
+		case rule.Plugin != "":
+			if !pluginNamePattern.MatchString(rule.Plugin) {
+				return fmt.Errorf("rule %d: invalid plugin name %q (use letters, digits, '-' and '_')", i, rule.Plugin)
+			}
+		case rule.URL != "":
+			if err := validateShippingURL(rule.URL); err != nil {
+				return fmt.Errorf("rule %d: url: %w", i, err)
+			}
+		default:
+			return fmt.Errorf("rule %d: a reducer plugin or url is required", i)
+		}
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with secrets masked
+func (c SnapshotsConfig) Redacted() SnapshotsConfig {
+	rules := make([]SnapshotRule, len(c.Rules))
+	for i, rule := range c.Rules {
+		if rule.Secret != "" {
+			rule.Secret = logShippingRedacted
+		}
+		rules[i] = rule
+	}
+	return SnapshotsConfig{Rules: rules}
+}
+
+// SnapshotsFromMetadata returns the snapshot rules stored in namespace metadata (never nil)
+func SnapshotsFromMetadata(metadata map[string]interface{}) *SnapshotsConfig {
+	cfg := &SnapshotsConfig{}
+	if raw, ok := metadata[snapshotsMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, cfg)
+	}
+	return cfg
+}
+
+// reduceInput is passed to reducers: the state of the last snapshot (null
+// for none) at version, and the events that follow it
+type reduceInput struct {
+	Namespace  string                 `json:"namespace"`
+	StreamName string                 `json:"streamName"`
+	State      map[string]interface{} `json:"state"`
+	Version    int64                  `json:"version"`
+	Events     []reduceEvent          `json:"events"`
+}
+
+// reduceEvent is an event passed to a reducer
+type reduceEvent struct {
+	ID             string                 `json:"id"`
+	Type           string                 `json:"type"`
+	Position       int64                  `json:"position"`
+	GlobalPosition int64                  `json:"globalPosition"`
+	Data           map[string]interface{} `json:"data"`
+	Metadata       map[string]interface{} `json:"metadata"`
+	Time           string                 `json:"time"`
+}
+
+// reduceOutput is a reducer's result: the state after the events
+type reduceOutput struct {
+	State map[string]interface{} `json:"state"`
+}
+
+// SnapshotStats counts a namespace's snapshots since startup
+type SnapshotStats struct {
+	Written     int64  `json:"written"`
+	Failed      int64  `json:"failed"`
+	LastError   string `json:"lastError,omitempty"`
+	LastErrorAt string `json:"lastErrorAt,omitempty"`
+}
+
+// Snapshotter writes snapshots of entity streams as events arrive, by the
+// rules namespaces configure with ns.snapshots.set, so entity.load stays fast
+// without client-side schedulers.
+//
+// One tailer runs per rule and records its progress in the stream
+// snapshotter:position-{category}. When an event lands at a multiple of the
+// rule's interval, the events after the stream's last snapshot are folded into
+// its state by the reducer, in batches, and the result is written to the
+// snapshot stream read by entity.load. A failed snapshot is reported and
+// skipped; the stream's next one covers the same events.
+type Snapshotter struct {
+	store    store.Store
+	pubsub   *PubSub
+	plugins  *PluginHost
+	client   *http.Client
+	notifier *Notifier
+	jobs     *JobScheduler
+
+	// mu guards workers
+	mu      sync.Mutex
+	workers map[string]*snapshotWorker
+	closed  bool
+
+	// statsMu guards stats; workers update them while mu is held to stop them
+	statsMu sync.Mutex
+	stats   map[string]*SnapshotStats
+}
+
+// snapshotWorker runs the tailers of one namespace
+type snapshotWorker struct {
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// halt stops the worker's tailers and waits for them to exit
+func (w *snapshotWorker) halt() {
+	close(w.stop)
+	w.wg.Wait()
+}
+
+// NewSnapshotter creates a snapshotter. plugins may be nil, which leaves
+// only webhook reducers.
+func NewSnapshotter(st store.Store, pubsub *PubSub, plugins *PluginHost) *Snapshotter {
+	return &Snapshotter{
+		store:   st,
+		pubsub:  pubsub,
+		plugins: plugins,
+		client:  &http.Client{Timeout: webhookTimeout},
+		workers: make(map[string]*snapshotWorker),
+		stats:   make(map[string]*SnapshotStats),
+	}
+}
+
+// SetNotifier routes snapshot failures to a notifier
+func (s *Snapshotter) SetNotifier(n *Notifier) {
+	s.notifier = n
+}
+
+// SetJobScheduler runs each snapshot as a job of s (call before Start)
+func (s *Snapshotter) SetJobScheduler(j *JobScheduler) {
+	s.jobs = j
+}
+
+// Start launches a worker for every namespace with snapshot rules
+func (s *Snapshotter) Start(ctx context.Context) error {
+	namespaces, err := s.store.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ns := range namespaces {
+		cfg := SnapshotsFromMetadata(ns.Metadata)
+		if len(cfg.Rules) == 0 {
+			continue
+		}
+		if err := cfg.Validate(); err != nil {
+			logger.Get().Warn().Err(err).Str("namespace", ns.ID).Msg("Ignoring invalid snapshot rules")
+			continue
+		}
+		s.startWorker(ns.ID, *cfg)
+	}
+	return nil
+}
+
+// Configure stores a namespace's snapshot rules, after checking that their
+// plugins load, and restarts its worker. No rules stops snapshotting.
+func (s *Snapshotter) Configure(ctx context.Context, namespace string, cfg *SnapshotsConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	for _, rule := range cfg.Rules {
+		if rule.Plugin == "" {
+			continue
+		}
+		if s.plugins == nil {
+			return ErrPluginsDisabled
+		}
+		if _, err := s.plugins.load(ctx, rule.Plugin); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w, ok := s.workers[namespace]; ok {
+		w.halt()
+		delete(s.workers, namespace)
+	}
+
+	err := updateNamespaceMetadata(ctx, s.store, namespace, func(metadata map[string]interface{}) {
+		// Masked secrets echoed back from ns.snapshots.get keep their stored value
+		previous := SnapshotsFromMetadata(metadata)
+		for i, rule := range cfg.Rules {
+			if rule.Secret != logShippingRedacted {
+				continue
+			}
+			cfg.Rules[i].Secret = ""
+			for _, p := range previous.Rules {
+				if p.Category == rule.Category {
+					cfg.Rules[i].Secret = p.Secret
+				}
+			}
+		}
+
+		if len(cfg.Rules) == 0 {
+			delete(metadata, snapshotsMetadataKey)
+			return
+		}
+		metadata[snapshotsMetadataKey] = encodeMetadataValue(cfg)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Rules) > 0 && !s.closed {
+		s.startWorker(namespace, *cfg)
+	}
+	return nil
+}
+
+// Stats returns a namespace's snapshot counters
+func (s *Snapshotter) Stats(namespace string) SnapshotStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	if stats, ok := s.stats[namespace]; ok {
+		return *stats
+	}
+	return SnapshotStats{}
+}
+
+// Close stops all workers
+func (s *Snapshotter) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	workers := s.workers
+	s.workers = make(map[string]*snapshotWorker)
+	s.mu.Unlock()
+
+	for _, w := range workers {
+		w.halt()
+	}
+	return nil
+}
+
+// startWorker launches a tailer per rule; the caller holds mu
+func (s *Snapshotter) startWorker(namespace string, cfg SnapshotsConfig) {
+	w := &snapshotWorker{stop: make(chan struct{})}
+	for _, rule := range cfg.Rules {
+		rule := rule
+		tailer := &categoryTailer{
+			name:           "snapshots:" + namespace + ":" + rule.Category,
+			store:          s.store,
+			pubsub:         s.pubsub,
+			namespace:      namespace,
+			category:       rule.Category,
+			positionStream: "snapshotter:position-" + rule.Category,
+			handle: func(ctx context.Context, msg *store.Message) error {
+				s.handle(ctx, namespace, rule, msg)
+				return nil
+			},
+			notifier: s.notifier,
+			stop:     w.stop,
+		}
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			tailer.run()
+		}()
+	}
+	s.workers[namespace] = w
+}
+
+// handle snapshots msg's stream when msg is at a multiple of the rule's interval
+func (s *Snapshotter) handle(ctx context.Context, namespace string, rule SnapshotRule, msg *store.Message) {
+	if (msg.Position+1)%rule.Every != 0 {
+		return
+	}
+	err := s.jobs.Run(ctx, namespace, JobKindSnapshot, func(ctx context.Context) error {
+		return s.Snapshot(ctx, namespace, rule, msg.StreamName, msg.Position)
+	})
+
+	s.statsMu.Lock()
+	stats, ok := s.stats[namespace]
+	if !ok {
+		stats = &SnapshotStats{}
+		s.stats[namespace] = stats
+	}
+	if err == nil {
+		stats.Written++
+	} else {
+		stats.Failed++
+		stats.LastError = err.Error()
+		stats.LastErrorAt = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	s.statsMu.Unlock()
+
+	if err != nil {
+		logger.Get().Warn().Err(err).
+			Str("namespace", namespace).
+			Str("stream", msg.StreamName).
+			Int64("version", msg.Position).
+			Msg("Snapshot failed")
+		s.notifier.Notify(SystemEvent{
+			Type:      SystemEventConnectorFailed,
+			Severity:  "warning",
+			Namespace: namespace,
+			Subject:   "snapshots:" + rule.Category,
+			Message:   err.Error(),
+			Data:      map[string]interface{}{"stream": msg.StreamName, "version": msg.Position},
+		})
+	}
+}
+
+// Snapshot brings the snapshot of a stream up to version: the events after
+// its last snapshot are reduced onto that snapshot's state and the result is
+// written to the stream's snapshot stream. Streams already snapshotted at or
+// past version are left alone.
+func (s *Snapshotter) Snapshot(ctx context.Context, namespace string, rule SnapshotRule, streamName string, version int64) error {
+	snapshotStream := SnapshotStreamName(streamName)
+	last, err := s.store.GetLastStreamMessage(ctx, namespace, snapshotStream, nil)
+	if err != nil && !errors.Is(err, store.ErrStreamNotFound) {
+		return err
+	}
+
+	var state map[string]interface{}
+	from := int64(0)
+	if last != nil {
+		if v, ok := snapshotVersion(last); ok {
+			if v >= version {
+				return nil
+			}
+			state, from = last.Data, v+1
+		}
+	}
+
+	reduced := int64(-1)
+	for from <= version {
+		msgs, err := s.store.GetStreamMessages(ctx, namespace, streamName, &store.GetOpts{
+			Position:  from,
+			BatchSize: min(snapshotReduceBatch, version-from+1),
+		})
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+
+		input := reduceInput{
+			Namespace:  namespace,
+			StreamName: streamName,
+			State:      state,
+			Version:    from - 1,
+			Events:     make([]reduceEvent, len(msgs)),
+		}
+		for i, msg := range msgs {
+			input.Events[i] = reduceEvent{
+				ID:             msg.ID,
+				Type:           msg.Type,
+				Position:       msg.Position,
+				GlobalPosition: msg.GlobalPosition,
+				Data:           msg.Data,
+				Metadata:       msg.Metadata,
+				Time:           msg.Time.UTC().Format(time.RFC3339Nano),
+			}
+		}
+		if state, err = s.reduce(ctx, rule, input); err != nil {
+			return err
+		}
+		reduced = msgs[len(msgs)-1].Position
+		from = reduced + 1
+	}
+	if reduced < 0 {
+		return nil
+	}
+
+	_, err = s.store.WriteMessage(ctx, namespace, snapshotStream, &store.Message{
+		StreamName: snapshotStream,
+		Type:       SnapshotMessageType,
+		Data:       state,
+		Metadata:   map[string]interface{}{"version": reduced},
+	})
+	return err
+}
+
+// reduce calls the rule's reducer and returns the state it computed
+func (s *Snapshotter) reduce(ctx context.Context, rule SnapshotRule, input reduceInput) (map[string]interface{}, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if rule.Plugin != "" {
+		out, err = s.plugins.Reduce(ctx, rule.Plugin, body)
+	} else {
+		out, err = s.reduceWebhook(ctx, rule, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result reduceOutput
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("invalid reducer result: %v", err)
+	}
+	if result.State == nil {
+		return nil, errors.New("invalid reducer result: state must be an object")
+	}
+	return result.State, nil
+}
+
+// reduceWebhook POSTs a reducer input to the rule's URL, signed like webhook
+// deliveries when the rule has a secret, and returns the response body
+func (s *Snapshotter) reduceWebhook(ctx context.Context, rule SnapshotRule, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderDelivery, uuid.New().String())
+	if rule.Secret != "" {
+		ts := time.Now().Unix()
+		req.Header.Set(WebhookHeaderTimestamp, fmt.Sprint(ts))
+		req.Header.Set(WebhookHeaderSignature, SignWebhook([]string{rule.Secret}, ts, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(io.LimitReader(resp.Body, maxPluginOutput+1))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("reducer responded with status %d", resp.StatusCode)
+	}
+	if len(out) > maxPluginOutput {
+		return nil, fmt.Errorf("reducer result exceeds %d bytes", maxPluginOutput)
+	}
+	return out, nil
+}
+
+// Reduce calls the reduce export of the named plugin with a reducer input
+// and returns its result
+func (h *PluginHost) Reduce(ctx context.Context, name string, input []byte) ([]byte, error) {
+	if h == nil {
+		return nil, ErrPluginsDisabled
+	}
+	compiled, err := h.load(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrPluginFailed, name, err)
+	}
+	return h.invoke(ctx, name, compiled, "reduce", input)
+}
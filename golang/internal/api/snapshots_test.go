@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestSnapshotter verifies that snapshot rules set over RPC fold events into
+// snapshots with a webhook reducer, continuing from the previous snapshot,
+// and that entity.load picks them up
+func TestSnapshotter(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	var mu sync.Mutex
+	var calls []reduceInput
+	reducer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := VerifyWebhook(r.Header.Get(WebhookHeaderSignature), body, "s3cret", time.Minute, time.Now()); err != nil {
+			t.Errorf("Invalid signature: %v", err)
+		}
+		var input reduceInput
+		if err := json.Unmarshal(body, &input); err != nil {
+			t.Errorf("Invalid reducer input: %v", err)
+		}
+		mu.Lock()
+		calls = append(calls, input)
+		mu.Unlock()
+		balance := 0.0
+		if input.State != nil {
+			balance = input.State["balance"].(float64)
+		}
+		for _, e := range input.Events {
+			balance += e.Data["amount"].(float64)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"state": map[string]interface{}{"balance": balance}})
+	}))
+	defer reducer.Close()
+
+	config := map[string]interface{}{"rules": []interface{}{map[string]interface{}{
+		"category": "account", "every": 3.0, "url": reducer.URL, "secret": "s3cret",
+	}}}
+	if _, rpcErr := h.route(ctx, "ns.snapshots.set", []interface{}{config}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without a snapshotter, got %v", rpcErr)
+	}
+
+	snapshotter := NewSnapshotter(st, h.pubsub, nil)
+	defer snapshotter.Close()
+	h.SetSnapshotter(snapshotter)
+
+	for _, rules := range []interface{}{
+		[]interface{}{map[string]interface{}{"category": "account", "every": 0.0, "url": reducer.URL}},
+		[]interface{}{map[string]interface{}{"category": "account", "every": 3.0}},
+		[]interface{}{map[string]interface{}{"category": "account-1", "every": 3.0, "url": reducer.URL}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.snapshots.set", []interface{}{map[string]interface{}{"rules": rules}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", rules, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "ns.snapshots.set", []interface{}{map[string]interface{}{"rules": []interface{}{
+		map[string]interface{}{"category": "account", "every": 3.0, "plugin": "balance"},
+	}}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a plugin reducer without plugins, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "ns.snapshots.set", []interface{}{config}); rpcErr != nil {
+		t.Fatalf("ns.snapshots.set failed: %v", rpcErr.Message)
+	}
+
+	for i := 1; i <= 7; i++ {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-1", map[string]interface{}{
+			"type": "Deposited",
+			"data": map[string]interface{}{"amount": float64(i)},
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+
+	// Snapshots at positions 2 and 5; the second continues from the first
+	deadline := time.Now().Add(5 * time.Second)
+	for snapshotter.Stats("test-ns").Written < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for snapshots: %+v", snapshotter.Stats("test-ns"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	snapshots, _ := st.GetStreamMessages(ctx, "test-ns", "account:snapshot-1", nil)
+	if len(snapshots) != 2 || snapshots[1].Data["balance"] != 21.0 || snapshots[1].Metadata["version"] != 5.0 {
+		t.Fatalf("Expected snapshots up to version 5 with balance 21, got %d", len(snapshots))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 || calls[1].Version != 2 || len(calls[1].Events) != 3 || calls[1].State["balance"] != 6.0 {
+		t.Errorf("Expected the second reduce to start from the first snapshot, got %+v", calls)
+	}
+
+	result, rpcErr := h.route(ctx, "entity.load", []interface{}{"account-1"})
+	if rpcErr != nil {
+		t.Fatalf("entity.load failed: %v", rpcErr.Message)
+	}
+	loaded := result.(map[string]interface{})
+	if loaded["snapshotVersion"] != int64(5) || len(loaded["events"].([]interface{})) != 1 {
+		t.Errorf("Expected entity.load to use the snapshot at 5, got %v", loaded)
+	}
+
+	result, rpcErr = h.route(ctx, "ns.snapshots.get", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.snapshots.get failed: %v", rpcErr.Message)
+	}
+	rule := result.(map[string]interface{})["rules"].([]interface{})[0].(map[string]interface{})
+	if rule["secret"] != logShippingRedacted {
+		t.Errorf("Expected the secret to be masked, got %v", rule["secret"])
+	}
+}
+
+// TestSnapshotter_Plugin verifies reducers that are WASM plugins
+func TestSnapshotter_Plugin(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	module := testPluginModuleExport("reduce", `{"state":{"reduced":true}}`, false)
+	if err := os.WriteFile(filepath.Join(dir, "reducer.wasm"), module, 0o644); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	host, err := NewPluginHost(NewNamespaceMetadata(st), PluginHostConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("Failed to create plugin host: %v", err)
+	}
+	defer host.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{
+			StreamName: "order-1",
+			Type:       "Placed",
+			Data:       map[string]interface{}{},
+		}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+
+	snapshotter := NewSnapshotter(st, nil, host)
+	rule := SnapshotRule{Category: "order", Every: 2, Plugin: "reducer"}
+	if err := snapshotter.Snapshot(ctx, "test-ns", rule, "order-1", 1); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	snapshot, err := st.GetLastStreamMessage(ctx, "test-ns", "order:snapshot-1", nil)
+	if err != nil || snapshot == nil || snapshot.Data["reduced"] != true || snapshot.Type != SnapshotMessageType {
+		t.Fatalf("Expected a reduced snapshot, got %v (%v)", snapshot, err)
+	}
+
+	// Already snapshotted at this version
+	if err := snapshotter.Snapshot(ctx, "test-ns", rule, "order-1", 1); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if snapshots, _ := st.GetStreamMessages(ctx, "test-ns", "order:snapshot-1", nil); len(snapshots) != 1 {
+		t.Errorf("Expected 1 snapshot, got %d", len(snapshots))
+	}
+}
@@ -3,11 +3,13 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/eventodb/eventodb/internal/auth"
 	"github.com/eventodb/eventodb/internal/logger"
@@ -28,20 +30,75 @@ var pokePool = sync.Pool{
 	},
 }
 
+// SSEMessage is a message sent via SSE to subscriptions with payload=full,
+// in place of its poke
+type SSEMessage struct {
+	ID             string                 `json:"id"`
+	Type           string                 `json:"type"`
+	Stream         string                 `json:"stream"`
+	Position       int64                  `json:"position"`
+	GlobalPosition int64                  `json:"globalPosition"`
+	Data           map[string]interface{} `json:"data"`
+	Metadata       map[string]interface{} `json:"metadata"`
+	Time           string                 `json:"time"`
+	DeliveryCount  int                    `json:"deliveryCount,omitempty"` // At-least-once durable subscriptions only
+}
+
+// newSSEMessage converts a message of stream for sending via SSE
+func newSSEMessage(stream string, msg *store.Message) *SSEMessage {
+	return &SSEMessage{
+		ID:             msg.ID,
+		Type:           msg.Type,
+		Stream:         stream,
+		Position:       msg.Position,
+		GlobalPosition: msg.GlobalPosition,
+		Data:           msg.Data,
+		Metadata:       msg.Metadata,
+		Time:           msg.Time.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// parsePayload parses the payload parameter and reports whether whole
+// messages are sent instead of pokes
+func parsePayload(payload string) (bool, error) {
+	switch payload {
+	case "", "poke":
+		return false, nil
+	case "full":
+		return true, nil
+	}
+	return false, fmt.Errorf("Invalid payload parameter: must be 'poke' or 'full'")
+}
+
 // SSEHandler manages Server-Sent Events subscriptions
 type SSEHandler struct {
 	Store    store.Store
 	Pubsub   *PubSub
+	Views    *CategoryViews   // Resolves category subscriptions to views
+	Aliases  *StreamAliases   // Resolves stream subscriptions to renamed streams
+	Queries  *StandingQueries // Resolves query subscriptions to result streams
+	Durable  *DurableSubscriptions
 	TestMode bool
 }
 
 // NewSSEHandler creates a new SSE handler
 func NewSSEHandler(st store.Store, pubsub *PubSub, testMode bool) *SSEHandler {
-	return &SSEHandler{
+	h := &SSEHandler{
 		Store:    st,
 		Pubsub:   pubsub,
+		Durable:  NewDurableSubscriptions(),
 		TestMode: testMode,
 	}
+	h.SetNamespaceMetadata(NewNamespaceMetadata(st))
+	return h
+}
+
+// SetNamespaceMetadata resolves views, aliases and standing queries through
+// meta, so they share its cache with other handlers
+func (h *SSEHandler) SetNamespaceMetadata(meta *NamespaceMetadata) {
+	h.Views = NewCategoryViews(meta)
+	h.Aliases = NewStreamAliases(meta)
+	h.Queries = NewStandingQueries(meta)
 }
 
 // HandleSubscribe handles SSE subscription requests
@@ -66,9 +123,22 @@ func (h *SSEHandler) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 	categoryName := query.Get("category")
 	subscribeAll := query.Get("all") == "true"
 
+	// A standing query subscription follows the query's result stream
+	if queryName := query.Get("query"); queryName != "" {
+		if subscribeAll || streamName != "" || categoryName != "" {
+			http.Error(w, "Cannot combine 'query' with 'stream', 'category' or 'all'", http.StatusBadRequest)
+			return
+		}
+		streamName, err = h.queryStream(r.Context(), namespace, queryName)
+		if err != nil {
+			http.Error(w, err.Error(), queryErrorStatus(err))
+			return
+		}
+	}
+
 	// Validate: need exactly one of stream, category, or all
 	if !subscribeAll && streamName == "" && categoryName == "" {
-		http.Error(w, "Either 'stream', 'category', or 'all=true' parameter required", http.StatusBadRequest)
+		http.Error(w, "Either 'stream', 'category', 'query', or 'all=true' parameter required", http.StatusBadRequest)
 		return
 	}
 	if subscribeAll && (streamName != "" || categoryName != "") {
@@ -83,16 +153,24 @@ func (h *SSEHandler) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 	// Parse position parameter
 	position := int64(0)
 	if posStr := query.Get("position"); posStr != "" {
-		pos, err := strconv.ParseInt(posStr, 10, 64)
+		pos, err := h.parsePosition(r.Context(), namespace, posStr, streamName)
 		if err != nil {
-			http.Error(w, "Invalid position parameter", http.StatusBadRequest)
+			http.Error(w, err.Error(), positionErrorStatus(err))
 			return
 		}
 		position = pos
 	}
 
+	// Parse payload parameter: pokes, or the messages themselves
+	full, err := parsePayload(query.Get("payload"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Parse consumer group parameters (for category subscriptions)
 	var consumerMember, consumerSize int64
+	var partitioner store.Partitioner
 	if categoryName != "" {
 		if memberStr := query.Get("consumer"); memberStr != "" {
 			member, err := strconv.ParseInt(memberStr, 10, 64)
@@ -115,11 +193,54 @@ func (h *SSEHandler) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid consumer group: member must be < size", http.StatusBadRequest)
 			return
 		}
+		p, err := store.ParsePartitioner(query.Get("partitioner"))
+		if err != nil {
+			http.Error(w, "Invalid partitioner parameter", http.StatusBadRequest)
+			return
+		}
+		partitioner = p
+	}
+
+	// Names with '*' or '|' follow every matching stream or category
+	var pattern *SubjectPattern
+	if IsSubjectPattern(streamName) || IsSubjectPattern(categoryName) {
+		p, err := ParseSubjectPattern(streamName+categoryName, categoryName != "")
+		if err != nil {
+			http.Error(w, "Invalid pattern: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		pattern = p
+	}
+
+	// A durable subscription delivers with acks or stored positions
+	durable, err := parseDurable(query.Get, categoryName, pattern != nil, full)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	// Get context for this request
 	ctx := r.Context()
 
+	if durable != nil {
+		start := int64(-1)
+		if query.Get("position") != "" {
+			start = position
+		}
+		sub, err := h.openDurable(ctx, namespace, durable, start)
+		if err != nil {
+			http.Error(w, err.Error(), durableErrorStatus(err))
+			return
+		}
+		defer h.Durable.close(sub)
+
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		h.subscribeDurable(ctx, w, sub, categoryName, consumerMember, consumerSize, partitioner)
+		return
+	}
+
 	// Flush headers immediately
 	if flusher, ok := w.(http.Flusher); ok {
 		flusher.Flush()
@@ -127,16 +248,18 @@ func (h *SSEHandler) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 
 	// Start subscription
 	if subscribeAll {
-		h.subscribeToAll(ctx, w, namespace, position)
+		h.subscribeToAll(ctx, w, namespace, position, full)
+	} else if pattern != nil {
+		h.subscribeToPattern(ctx, w, namespace, pattern, position, consumerMember, consumerSize, partitioner, full)
 	} else if streamName != "" {
-		h.subscribeToStream(ctx, w, namespace, streamName, position)
+		h.subscribeToStream(ctx, w, namespace, streamName, position, full)
 	} else {
-		h.subscribeToCategory(ctx, w, namespace, categoryName, position, consumerMember, consumerSize)
+		h.subscribeToCategory(ctx, w, namespace, categoryName, position, consumerMember, consumerSize, partitioner, full)
 	}
 }
 
 // subscribeToAll handles namespace-wide subscriptions (all events)
-func (h *SSEHandler) subscribeToAll(ctx context.Context, w http.ResponseWriter, namespace string, startPosition int64) {
+func (h *SSEHandler) subscribeToAll(ctx context.Context, w http.ResponseWriter, namespace string, startPosition int64, full bool) {
 	// Subscribe to all events for this namespace
 	var sub Subscriber
 	if h.Pubsub != nil {
@@ -168,13 +291,7 @@ func (h *SSEHandler) subscribeToAll(ctx context.Context, w http.ResponseWriter,
 			}
 			// Only send if globalPosition >= startPosition
 			if event.GlobalPosition >= startPosition {
-				poke := pokePool.Get().(*Poke)
-				poke.Stream = event.Stream
-				poke.Position = event.Position
-				poke.GlobalPosition = event.GlobalPosition
-
-				err := h.sendPoke(w, poke)
-				pokePool.Put(poke)
+				err := h.sendWrite(ctx, w, namespace, event, full)
 
 				if err != nil {
 					return
@@ -184,8 +301,56 @@ func (h *SSEHandler) subscribeToAll(ctx context.Context, w http.ResponseWriter,
 	}
 }
 
+// subscribeToPattern handles wildcard subscriptions. Like all=true, they
+// deliver new writes only.
+func (h *SSEHandler) subscribeToPattern(ctx context.Context, w http.ResponseWriter, namespace string, pattern *SubjectPattern, startPosition, consumerMember, consumerSize int64, partitioner store.Partitioner, full bool) {
+	var sub Subscriber
+	if h.Pubsub != nil {
+		sub = h.Pubsub.SubscribePattern(namespace, pattern)
+		defer h.Pubsub.UnsubscribePattern(namespace, sub)
+	}
+
+	// Send a ready comment to signal subscription is established
+	fmt.Fprintf(w, ": ready\n\n")
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	// If no pubsub, just wait for context cancellation
+	if h.Pubsub == nil {
+		<-ctx.Done()
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub:
+			if !ok {
+				return
+			}
+			// Only send if globalPosition >= startPosition
+			if event.GlobalPosition < startPosition {
+				continue
+			}
+			// Apply consumer group filter if needed
+			if consumerSize > 0 && !partitioner.IsAssigned(event.Stream, consumerMember, consumerSize) {
+				continue
+			}
+			err := h.sendWrite(ctx, w, namespace, event, full)
+
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
 // subscribeToStream handles stream-specific subscriptions
-func (h *SSEHandler) subscribeToStream(ctx context.Context, w http.ResponseWriter, namespace, streamName string, startPosition int64) {
+func (h *SSEHandler) subscribeToStream(ctx context.Context, w http.ResponseWriter, namespace, streamName string, startPosition int64, full bool) {
+	streamName = h.Aliases.Resolve(ctx, namespace, streamName)
+
 	// Subscribe to real-time updates FIRST (before fetching existing messages)
 	// This prevents a race where messages written between fetch and subscribe are missed
 	var sub Subscriber
@@ -216,13 +381,7 @@ func (h *SSEHandler) subscribeToStream(ctx context.Context, w http.ResponseWrite
 
 	lastPosition := startPosition
 	for _, msg := range messages {
-		poke := pokePool.Get().(*Poke)
-		poke.Stream = streamName
-		poke.Position = msg.Position
-		poke.GlobalPosition = msg.GlobalPosition
-
-		err := h.sendPoke(w, poke)
-		pokePool.Put(poke)
+		err := h.sendStored(w, streamName, msg, full)
 
 		if err != nil {
 			return
@@ -246,13 +405,7 @@ func (h *SSEHandler) subscribeToStream(ctx context.Context, w http.ResponseWrite
 			}
 			// Only send if position >= our tracking position
 			if event.Position >= lastPosition {
-				poke := pokePool.Get().(*Poke)
-				poke.Stream = event.Stream
-				poke.Position = event.Position
-				poke.GlobalPosition = event.GlobalPosition
-
-				err := h.sendPoke(w, poke)
-				pokePool.Put(poke)
+				err := h.sendWrite(ctx, w, namespace, event, full)
 
 				if err != nil {
 					return
@@ -264,13 +417,14 @@ func (h *SSEHandler) subscribeToStream(ctx context.Context, w http.ResponseWrite
 }
 
 // subscribeToCategory handles category-specific subscriptions
-func (h *SSEHandler) subscribeToCategory(ctx context.Context, w http.ResponseWriter, namespace, categoryName string, startPosition int64, consumerMember, consumerSize int64) {
+func (h *SSEHandler) subscribeToCategory(ctx context.Context, w http.ResponseWriter, namespace, categoryName string, startPosition int64, consumerMember, consumerSize int64, partitioner store.Partitioner, full bool) {
 	// Subscribe to real-time updates FIRST (before fetching existing messages)
 	// This prevents a race where messages written between fetch and subscribe are missed
 	var sub Subscriber
 	if h.Pubsub != nil {
-		sub = h.Pubsub.SubscribeCategory(namespace, categoryName)
-		defer h.Pubsub.UnsubscribeCategory(namespace, categoryName, sub)
+		var unsubscribe func()
+		sub, unsubscribe = h.subscribeCategory(ctx, namespace, categoryName)
+		defer unsubscribe()
 	}
 
 	// Send a ready comment to signal subscription is established
@@ -287,10 +441,11 @@ func (h *SSEHandler) subscribeToCategory(ctx context.Context, w http.ResponseWri
 	if consumerSize > 0 {
 		opts.ConsumerMember = &consumerMember
 		opts.ConsumerSize = &consumerSize
+		opts.Partitioner = partitioner
 	}
 
 	// Now fetch any existing messages from startPosition
-	messages, err := h.Store.GetCategoryMessages(ctx, namespace, categoryName, opts)
+	messages, err := getCategoryMessages(ctx, h.Store, h.Views, namespace, categoryName, opts)
 	if err != nil {
 		logger.Get().Error().
 			Err(err).
@@ -303,13 +458,7 @@ func (h *SSEHandler) subscribeToCategory(ctx context.Context, w http.ResponseWri
 	lastGlobalPosition := startPosition
 	for _, msg := range messages {
 		// Note: consumer group filtering already done by GetCategoryMessages
-		poke := pokePool.Get().(*Poke)
-		poke.Stream = msg.StreamName
-		poke.Position = msg.Position
-		poke.GlobalPosition = msg.GlobalPosition
-
-		err := h.sendPoke(w, poke)
-		pokePool.Put(poke)
+		err := h.sendStored(w, msg.StreamName, msg, full)
 
 		if err != nil {
 			return
@@ -334,16 +483,10 @@ func (h *SSEHandler) subscribeToCategory(ctx context.Context, w http.ResponseWri
 			// Only send if globalPosition >= our tracking position
 			if event.GlobalPosition >= lastGlobalPosition {
 				// Apply consumer group filter if needed
-				if consumerSize > 0 && !h.matchesConsumerGroup(event.Stream, consumerMember, consumerSize) {
+				if consumerSize > 0 && !partitioner.IsAssigned(event.Stream, consumerMember, consumerSize) {
 					continue
 				}
-				poke := pokePool.Get().(*Poke)
-				poke.Stream = event.Stream
-				poke.Position = event.Position
-				poke.GlobalPosition = event.GlobalPosition
-
-				err := h.sendPoke(w, poke)
-				pokePool.Put(poke)
+				err := h.sendWrite(ctx, w, namespace, event, full)
 
 				if err != nil {
 					return
@@ -354,9 +497,69 @@ func (h *SSEHandler) subscribeToCategory(ctx context.Context, w http.ResponseWri
 	}
 }
 
-// matchesConsumerGroup checks if a stream belongs to a consumer group member
-func (h *SSEHandler) matchesConsumerGroup(streamName string, member, size int64) bool {
-	return store.IsAssignedToConsumerMember(streamName, member, size)
+// subscribeDurable handles durable category subscriptions. Messages are read
+// from the store from the subscription's start, and writes only wake the
+// delivery loop.
+func (h *SSEHandler) subscribeDurable(ctx context.Context, w http.ResponseWriter, sub *durableSubscription, categoryName string, consumerMember, consumerSize int64, partitioner store.Partitioner) {
+	var wake Subscriber
+	if h.Pubsub != nil {
+		var unsubscribe func()
+		wake, unsubscribe = h.subscribeCategory(ctx, sub.namespace, categoryName)
+		defer unsubscribe()
+	}
+
+	// Send a ready comment to signal subscription is established
+	fmt.Fprintf(w, ": ready\n\n")
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	fetch := h.durableFetcher(ctx, sub.namespace, categoryName, consumerMember, consumerSize, partitioner)
+	send := func(msg *store.Message, deliveryCount int) error {
+		return h.sendDelivery(w, msg, deliveryCount)
+	}
+	keepAlive := func() error {
+		if _, err := fmt.Fprintf(w, ": keepalive\n\n"); err != nil {
+			return err
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+	sub.run(ctx, ctx.Done(), wake, fetch, send, keepAlive)
+}
+
+// durableFetcher reads the messages of a durable category subscription,
+// restricted to a consumer group member if size is set
+func (h *SSEHandler) durableFetcher(ctx context.Context, namespace, categoryName string, consumerMember, consumerSize int64, partitioner store.Partitioner) durableFetcher {
+	return func(globalPosition, limit int64) ([]*store.Message, error) {
+		opts := &store.CategoryOpts{
+			Position:  globalPosition,
+			BatchSize: limit,
+		}
+		if consumerSize > 0 {
+			opts.ConsumerMember = &consumerMember
+			opts.ConsumerSize = &consumerSize
+			opts.Partitioner = partitioner
+		}
+		return getCategoryMessages(ctx, h.Store, h.Views, namespace, categoryName, opts)
+	}
+}
+
+// subscribeCategory subscribes to the writes of a category, or of the
+// categories of the view of that name, and returns the function that
+// unsubscribes
+func (h *SSEHandler) subscribeCategory(ctx context.Context, namespace, categoryName string) (Subscriber, func()) {
+	categories := h.Views.Lookup(ctx, namespace, categoryName)
+	if categories == nil {
+		sub := h.Pubsub.SubscribeCategory(namespace, categoryName)
+		return sub, func() { h.Pubsub.UnsubscribeCategory(namespace, categoryName, sub) }
+	}
+	// View categories have no wildcards, so they match exactly
+	pattern := &SubjectPattern{Category: true, alternatives: categories}
+	sub := h.Pubsub.SubscribePattern(namespace, pattern)
+	return sub, func() { h.Pubsub.UnsubscribePattern(namespace, sub) }
 }
 
 // sendPoke sends a poke event via SSE
@@ -379,6 +582,229 @@ func (h *SSEHandler) sendPoke(w http.ResponseWriter, poke *Poke) error {
 	return nil
 }
 
+// sendMessage sends a message event via SSE
+func (h *SSEHandler) sendMessage(w http.ResponseWriter, stream string, msg *store.Message) error {
+	data, err := json.Marshal(newSSEMessage(stream, msg))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	if err != nil {
+		return err
+	}
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+// sendDelivery sends a message of a durable subscription with its delivery
+// count
+func (h *SSEHandler) sendDelivery(w http.ResponseWriter, msg *store.Message, deliveryCount int) error {
+	event := newSSEMessage(msg.StreamName, msg)
+	event.DeliveryCount = deliveryCount
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	if err != nil {
+		return err
+	}
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+// sendStored sends a message read from the store: the message itself, or
+// its poke
+func (h *SSEHandler) sendStored(w http.ResponseWriter, stream string, msg *store.Message, full bool) error {
+	if full {
+		return h.sendMessage(w, stream, msg)
+	}
+	poke := pokePool.Get().(*Poke)
+	poke.Stream = stream
+	poke.Position = msg.Position
+	poke.GlobalPosition = msg.GlobalPosition
+
+	err := h.sendPoke(w, poke)
+	pokePool.Put(poke)
+	return err
+}
+
+// sendWrite sends a write event: its poke, or the message written
+func (h *SSEHandler) sendWrite(ctx context.Context, w http.ResponseWriter, namespace string, event WriteEvent, full bool) error {
+	if full {
+		if msg := h.readWritten(ctx, namespace, event); msg != nil {
+			return h.sendMessage(w, event.Stream, msg)
+		}
+	}
+	poke := pokePool.Get().(*Poke)
+	poke.Stream = event.Stream
+	poke.Position = event.Position
+	poke.GlobalPosition = event.GlobalPosition
+
+	err := h.sendPoke(w, poke)
+	pokePool.Put(poke)
+	return err
+}
+
+// readWritten reads the message of a write event. Events carry positions
+// only, including those relayed from other instances, so the message is read
+// back from the store. Returns nil if it cannot be read, and a poke is sent
+// instead.
+func (h *SSEHandler) readWritten(ctx context.Context, namespace string, event WriteEvent) *store.Message {
+	messages, err := h.Store.GetStreamMessages(ctx, namespace, event.Stream, &store.GetOpts{
+		Position:  event.Position,
+		BatchSize: 1,
+	})
+	if err != nil {
+		logger.Get().Warn().
+			Err(err).
+			Str("stream", event.Stream).
+			Str("namespace", namespace).
+			Int64("position", event.Position).
+			Msg("Error reading message for SSE, sending poke")
+		return nil
+	}
+	if len(messages) == 0 || messages[0].Position != event.Position {
+		return nil
+	}
+	return messages[0]
+}
+
+// parsePosition parses the position parameter. Subscriptions other than to a
+// single stream start at a global position, which may also be given as a
+// bookmark name.
+func (h *SSEHandler) parsePosition(ctx context.Context, namespace, position, streamName string) (int64, error) {
+	if pos, err := strconv.ParseInt(position, 10, 64); err == nil {
+		return pos, nil
+	}
+	if streamName != "" && !IsSubjectPattern(streamName) {
+		return 0, errInvalidPosition
+	}
+	pos, err := ParsePosition(ctx, h.Store, namespace, position)
+	if errors.Is(err, ErrBookmarkNotFound) {
+		return 0, fmt.Errorf("%w: %s", ErrBookmarkNotFound, position)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Failed to resolve position: %w", err)
+	}
+	return pos, nil
+}
+
+// durableParams are the parameters of a durable subscription
+type durableParams struct {
+	consumer   string
+	mode       DeliveryMode
+	ackTimeout time.Duration
+}
+
+// parseDurable parses the durable, delivery and ackTimeout parameters.
+// Returns nil if the subscription is not durable. Durable subscriptions
+// follow a single category or view and send whole messages, whose global
+// positions are acked or stored.
+func parseDurable(get func(string) string, categoryName string, pattern, full bool) (*durableParams, error) {
+	consumer := get("durable")
+	if consumer == "" {
+		if get("delivery") != "" || get("ackTimeout") != "" {
+			return nil, fmt.Errorf("'delivery' and 'ackTimeout' require 'durable'")
+		}
+		return nil, nil
+	}
+	if _, rpcErr := parseConsumer(consumer); rpcErr != nil {
+		return nil, fmt.Errorf("Invalid durable parameter: %s", rpcErr.Message)
+	}
+	if categoryName == "" || pattern {
+		return nil, fmt.Errorf("'durable' requires a 'category' subscription without patterns")
+	}
+	if !full {
+		return nil, fmt.Errorf("'durable' requires payload=full")
+	}
+	mode, err := ParseDeliveryMode(get("delivery"))
+	if err != nil {
+		return nil, err
+	}
+	ackTimeout, err := parseAckTimeout(get("ackTimeout"))
+	if err != nil {
+		return nil, err
+	}
+	return &durableParams{consumer: consumer, mode: mode, ackTimeout: ackTimeout}, nil
+}
+
+// openDurable connects a durable subscription starting at start, or after
+// the consumer's stored position if start is negative
+func (h *SSEHandler) openDurable(ctx context.Context, namespace string, params *durableParams, start int64) (*durableSubscription, error) {
+	positions, ok := h.Store.(store.ConsumerPositionStore)
+	if !ok {
+		return nil, errDurableUnsupported
+	}
+	sub, err := h.Durable.open(ctx, positions, namespace, params.consumer, params.mode, params.ackTimeout, start)
+	if errors.Is(err, ErrDurableSubscriptionActive) {
+		return nil, fmt.Errorf("%w: %s", ErrDurableSubscriptionActive, params.consumer)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read consumer position: %w", err)
+	}
+	return sub, nil
+}
+
+// errDurableUnsupported is returned for durable subscriptions on backends
+// without consumer positions
+var errDurableUnsupported = errors.New("Consumer positions are not supported by the backend")
+
+// durableErrorStatus is the HTTP status for an openDurable error
+func durableErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errDurableUnsupported):
+		return http.StatusNotImplemented
+	case store.IsOverloaded(err):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrDurableSubscriptionActive):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// queryStream returns the result stream of a standing query
+func (h *SSEHandler) queryStream(ctx context.Context, namespace, name string) (string, error) {
+	stream, err := h.Queries.ResultStream(ctx, namespace, name)
+	if err != nil && !errors.Is(err, ErrQueryNotFound) {
+		return "", fmt.Errorf("Failed to resolve query: %w", err)
+	}
+	return stream, err
+}
+
+// queryErrorStatus is the HTTP status for a queryStream error
+func queryErrorStatus(err error) int {
+	if errors.Is(err, ErrQueryNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// errInvalidPosition is returned for a position parameter that is not a number
+// where only numbers are accepted
+var errInvalidPosition = errors.New("Invalid position parameter")
+
+// positionErrorStatus is the HTTP status for a parsePosition error
+func positionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errInvalidPosition):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrBookmarkNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
 // extractNamespace extracts and validates the namespace from the request
 func (h *SSEHandler) extractNamespace(r *http.Request) (string, error) {
 	// First check if namespace is already in context (set by auth middleware)
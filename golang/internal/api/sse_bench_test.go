@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"sync"
 	"testing"
 )
@@ -241,3 +242,41 @@ func TestPokePoolReuse(t *testing.T) {
 
 	pokePool.Put(poke2)
 }
+
+// BenchmarkSSEFanOut measures delivering one write to every category
+// subscriber, each encoding its poke like the SSE handler does
+func BenchmarkSSEFanOut(b *testing.B) {
+	for _, subscribers := range []int{1, 100, 1000} {
+		b.Run(fmt.Sprintf("subscribers=%d", subscribers), func(b *testing.B) {
+			ps := NewPubSub()
+			defer ps.Close()
+
+			var delivered sync.WaitGroup
+			for i := 0; i < subscribers; i++ {
+				sub := ps.SubscribeCategory("bench", "account")
+				go func() {
+					for event := range sub {
+						if _, err := sendPokePooled(event.Stream, event.Position, event.GlobalPosition); err != nil {
+							panic(err)
+						}
+						delivered.Done()
+					}
+				}()
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				delivered.Add(subscribers)
+				ps.Publish(WriteEvent{
+					Namespace:      "bench",
+					Stream:         "account-123",
+					Category:       "account",
+					Position:       int64(i),
+					GlobalPosition: int64(i),
+				})
+				delivered.Wait()
+			}
+		})
+	}
+}
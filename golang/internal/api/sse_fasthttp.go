@@ -28,10 +28,26 @@ func FastHTTPSSEHandler(h *SSEHandler, testMode bool) fasthttp.RequestHandler {
 		categoryName := string(args.Peek("category"))
 		subscribeAll := string(args.Peek("all")) == "true"
 
+		// A standing query subscription follows the query's result stream
+		if queryName := string(args.Peek("query")); queryName != "" {
+			if subscribeAll || streamName != "" || categoryName != "" {
+				ctx.SetStatusCode(fasthttp.StatusBadRequest)
+				ctx.SetBodyString("Cannot combine 'query' with 'stream', 'category' or 'all'")
+				return
+			}
+			var err error
+			streamName, err = h.queryStream(ctx, namespace, queryName)
+			if err != nil {
+				ctx.SetStatusCode(queryErrorStatus(err))
+				ctx.SetBodyString(err.Error())
+				return
+			}
+		}
+
 		// Validate: need exactly one of stream, category, or all
 		if !subscribeAll && streamName == "" && categoryName == "" {
 			ctx.SetStatusCode(fasthttp.StatusBadRequest)
-			ctx.SetBodyString("Either 'stream', 'category', or 'all=true' parameter required")
+			ctx.SetBodyString("Either 'stream', 'category', 'query', or 'all=true' parameter required")
 			return
 		}
 		if subscribeAll && (streamName != "" || categoryName != "") {
@@ -48,17 +64,26 @@ func FastHTTPSSEHandler(h *SSEHandler, testMode bool) fasthttp.RequestHandler {
 		// Parse position parameter
 		position := int64(0)
 		if posStr := string(args.Peek("position")); posStr != "" {
-			pos, err := strconv.ParseInt(posStr, 10, 64)
+			pos, err := h.parsePosition(ctx, namespace, posStr, streamName)
 			if err != nil {
-				ctx.SetStatusCode(fasthttp.StatusBadRequest)
-				ctx.SetBodyString("Invalid position parameter")
+				ctx.SetStatusCode(positionErrorStatus(err))
+				ctx.SetBodyString(err.Error())
 				return
 			}
 			position = pos
 		}
 
+		// Parse payload parameter: pokes, or the messages themselves
+		full, err := parsePayload(string(args.Peek("payload")))
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			ctx.SetBodyString(err.Error())
+			return
+		}
+
 		// Parse consumer group parameters (for category subscriptions)
 		var consumerMember, consumerSize int64
+		var partitioner store.Partitioner
 		if categoryName != "" {
 			if memberStr := string(args.Peek("consumer")); memberStr != "" {
 				member, err := strconv.ParseInt(memberStr, 10, 64)
@@ -84,6 +109,46 @@ func FastHTTPSSEHandler(h *SSEHandler, testMode bool) fasthttp.RequestHandler {
 				ctx.SetBodyString("Invalid consumer group: member must be < size")
 				return
 			}
+			p, err := store.ParsePartitioner(string(args.Peek("partitioner")))
+			if err != nil {
+				ctx.SetStatusCode(fasthttp.StatusBadRequest)
+				ctx.SetBodyString("Invalid partitioner parameter")
+				return
+			}
+			partitioner = p
+		}
+
+		// Names with '*' or '|' follow every matching stream or category
+		var pattern *SubjectPattern
+		if IsSubjectPattern(streamName) || IsSubjectPattern(categoryName) {
+			p, err := ParseSubjectPattern(streamName+categoryName, categoryName != "")
+			if err != nil {
+				ctx.SetStatusCode(fasthttp.StatusBadRequest)
+				ctx.SetBodyString("Invalid pattern: " + err.Error())
+				return
+			}
+			pattern = p
+		}
+
+		// A durable subscription delivers with acks or stored positions
+		durable, err := parseDurable(func(key string) string { return string(args.Peek(key)) }, categoryName, pattern != nil, full)
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			ctx.SetBodyString(err.Error())
+			return
+		}
+		var durableSub *durableSubscription
+		if durable != nil {
+			start := int64(-1)
+			if len(args.Peek("position")) > 0 {
+				start = position
+			}
+			durableSub, err = h.openDurable(ctx, namespace, durable, start)
+			if err != nil {
+				ctx.SetStatusCode(durableErrorStatus(err))
+				ctx.SetBodyString(err.Error())
+				return
+			}
 		}
 
 		// Set SSE headers
@@ -92,22 +157,34 @@ func FastHTTPSSEHandler(h *SSEHandler, testMode bool) fasthttp.RequestHandler {
 		ctx.Response.Header.Set("Connection", "keep-alive")
 		ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
 
+		if durableSub != nil {
+			ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+				defer h.Durable.close(durableSub)
+				handleDurableSubscriptionFast(w, h, durableSub, categoryName, consumerMember, consumerSize, partitioner)
+			})
+			return
+		}
+
 		// Use SetBodyStreamWriter for streaming response
 		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
 			// Start subscription based on type
 			if subscribeAll {
-				handleAllSubscriptionFast(w, h, namespace, position)
+				handleAllSubscriptionFast(w, h, namespace, position, full)
+			} else if pattern != nil {
+				handlePatternSubscriptionFast(w, h, namespace, pattern, position, consumerMember, consumerSize, partitioner, full)
 			} else if streamName != "" {
-				handleStreamSubscriptionFast(w, h, namespace, streamName, position)
+				handleStreamSubscriptionFast(w, h, namespace, streamName, position, full)
 			} else {
-				handleCategorySubscriptionFast(w, h, namespace, categoryName, position, consumerMember, consumerSize)
+				handleCategorySubscriptionFast(w, h, namespace, categoryName, position, consumerMember, consumerSize, partitioner, full)
 			}
 		})
 	}
 }
 
 // handleStreamSubscriptionFast handles stream-specific subscriptions for fasthttp
-func handleStreamSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, streamName string, startPosition int64) {
+func handleStreamSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, streamName string, startPosition int64, full bool) {
+	streamName = h.Aliases.Resolve(context.Background(), namespace, streamName)
+
 	// First, send any existing messages from startPosition
 	messages, err := h.Store.GetStreamMessages(context.Background(), namespace, streamName, &store.GetOpts{
 		Position:  startPosition,
@@ -124,13 +201,7 @@ func handleStreamSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, str
 
 	lastPosition := startPosition
 	for _, msg := range messages {
-		poke := pokePool.Get().(*Poke)
-		poke.Stream = streamName
-		poke.Position = msg.Position
-		poke.GlobalPosition = msg.GlobalPosition
-
-		err := sendPokeFast(w, poke)
-		pokePool.Put(poke)
+		err := sendStoredFast(w, streamName, msg, full)
 
 		if err != nil {
 			return
@@ -149,13 +220,7 @@ func handleStreamSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, str
 	for event := range sub {
 		// Only send if position >= our tracking position
 		if event.Position >= lastPosition {
-			poke := pokePool.Get().(*Poke)
-			poke.Stream = event.Stream
-			poke.Position = event.Position
-			poke.GlobalPosition = event.GlobalPosition
-
-			err := sendPokeFast(w, poke)
-			pokePool.Put(poke)
+			err := sendWriteFast(w, h, namespace, event, full)
 
 			if err != nil {
 				return
@@ -166,7 +231,7 @@ func handleStreamSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, str
 }
 
 // handleCategorySubscriptionFast handles category subscriptions for fasthttp
-func handleCategorySubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, categoryName string, startPosition, consumerMember, consumerSize int64) {
+func handleCategorySubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, categoryName string, startPosition, consumerMember, consumerSize int64, partitioner store.Partitioner, full bool) {
 	// First, send any existing messages from startPosition
 	opts := &store.CategoryOpts{
 		Position:  startPosition,
@@ -175,9 +240,10 @@ func handleCategorySubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, c
 	if consumerSize > 0 {
 		opts.ConsumerSize = &consumerSize
 		opts.ConsumerMember = &consumerMember
+		opts.Partitioner = partitioner
 	}
 
-	messages, err := h.Store.GetCategoryMessages(context.Background(), namespace, categoryName, opts)
+	messages, err := getCategoryMessages(context.Background(), h.Store, h.Views, namespace, categoryName, opts)
 	if err != nil {
 		logger.Get().Error().
 			Err(err).
@@ -189,13 +255,7 @@ func handleCategorySubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, c
 
 	lastGlobalPosition := startPosition
 	for _, msg := range messages {
-		poke := pokePool.Get().(*Poke)
-		poke.Stream = msg.StreamName
-		poke.Position = msg.Position
-		poke.GlobalPosition = msg.GlobalPosition
-
-		err := sendPokeFast(w, poke)
-		pokePool.Put(poke)
+		err := sendStoredFast(w, msg.StreamName, msg, full)
 
 		if err != nil {
 			return
@@ -208,23 +268,17 @@ func handleCategorySubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, c
 		return
 	}
 
-	sub := h.Pubsub.SubscribeCategory(namespace, categoryName)
-	defer h.Pubsub.UnsubscribeCategory(namespace, categoryName, sub)
+	sub, unsubscribe := h.subscribeCategory(context.Background(), namespace, categoryName)
+	defer unsubscribe()
 
 	for event := range sub {
 		// Only send if globalPosition >= our tracking position
 		if event.GlobalPosition >= lastGlobalPosition {
 			// Apply consumer group filter if needed
-			if consumerSize > 0 && !matchesConsumerGroup(event.Stream, consumerMember, consumerSize) {
+			if consumerSize > 0 && !partitioner.IsAssigned(event.Stream, consumerMember, consumerSize) {
 				continue
 			}
-			poke := pokePool.Get().(*Poke)
-			poke.Stream = event.Stream
-			poke.Position = event.Position
-			poke.GlobalPosition = event.GlobalPosition
-
-			err := sendPokeFast(w, poke)
-			pokePool.Put(poke)
+			err := sendWriteFast(w, h, namespace, event, full)
 
 			if err != nil {
 				return
@@ -234,6 +288,54 @@ func handleCategorySubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace, c
 	}
 }
 
+// handleDurableSubscriptionFast handles durable category subscriptions for
+// fasthttp. A closed connection is noticed by the next send or keepalive.
+func handleDurableSubscriptionFast(w *bufio.Writer, h *SSEHandler, sub *durableSubscription, categoryName string, consumerMember, consumerSize int64, partitioner store.Partitioner) {
+	ctx := context.Background()
+	var wake Subscriber
+	if h.Pubsub != nil {
+		var unsubscribe func()
+		wake, unsubscribe = h.subscribeCategory(ctx, sub.namespace, categoryName)
+		defer unsubscribe()
+	}
+
+	// Send ready signal
+	fmt.Fprintf(w, ": ready\n\n")
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	fetch := h.durableFetcher(ctx, sub.namespace, categoryName, consumerMember, consumerSize, partitioner)
+	send := func(msg *store.Message, deliveryCount int) error {
+		return sendDeliveryFast(w, msg, deliveryCount)
+	}
+	keepAlive := func() error {
+		if _, err := fmt.Fprintf(w, ": keepalive\n\n"); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+	sub.run(ctx, nil, wake, fetch, send, keepAlive)
+}
+
+// sendDeliveryFast sends a message of a durable subscription with its
+// delivery count using fasthttp buffered writer
+func sendDeliveryFast(w *bufio.Writer, msg *store.Message, deliveryCount int) error {
+	event := newSSEMessage(msg.StreamName, msg)
+	event.DeliveryCount = deliveryCount
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
 // sendPokeFast sends a poke event via SSE using fasthttp buffered writer
 func sendPokeFast(w *bufio.Writer, poke *Poke) error {
 	data, err := json.Marshal(poke)
@@ -250,18 +352,56 @@ func sendPokeFast(w *bufio.Writer, poke *Poke) error {
 	return w.Flush()
 }
 
-// matchesConsumerGroup checks if a stream belongs to a consumer group member
-func matchesConsumerGroup(streamName string, member, size int64) bool {
-	// Hash the stream name to determine which consumer it belongs to
-	hash := uint64(0)
-	for _, c := range streamName {
-		hash = hash*31 + uint64(c)
+// sendMessageFast sends a message event via SSE using fasthttp buffered writer
+func sendMessageFast(w *bufio.Writer, stream string, msg *store.Message) error {
+	data, err := json.Marshal(newSSEMessage(stream, msg))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	if err != nil {
+		return err
 	}
-	return int64(hash%uint64(size)) == member
+
+	return w.Flush()
+}
+
+// sendStoredFast sends a message read from the store: the message itself, or
+// its poke
+func sendStoredFast(w *bufio.Writer, stream string, msg *store.Message, full bool) error {
+	if full {
+		return sendMessageFast(w, stream, msg)
+	}
+	poke := pokePool.Get().(*Poke)
+	poke.Stream = stream
+	poke.Position = msg.Position
+	poke.GlobalPosition = msg.GlobalPosition
+
+	err := sendPokeFast(w, poke)
+	pokePool.Put(poke)
+	return err
+}
+
+// sendWriteFast sends a write event: its poke, or the message written
+func sendWriteFast(w *bufio.Writer, h *SSEHandler, namespace string, event WriteEvent, full bool) error {
+	if full {
+		if msg := h.readWritten(context.Background(), namespace, event); msg != nil {
+			return sendMessageFast(w, event.Stream, msg)
+		}
+	}
+	poke := pokePool.Get().(*Poke)
+	poke.Stream = event.Stream
+	poke.Position = event.Position
+	poke.GlobalPosition = event.GlobalPosition
+
+	err := sendPokeFast(w, poke)
+	pokePool.Put(poke)
+	return err
 }
 
 // handleAllSubscriptionFast handles namespace-wide subscriptions for fasthttp
-func handleAllSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace string, startPosition int64) {
+func handleAllSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace string, startPosition int64, full bool) {
 	// Send ready signal
 	fmt.Fprintf(w, ": ready\n\n")
 	w.Flush()
@@ -277,13 +417,7 @@ func handleAllSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace string,
 	for event := range sub {
 		// Only send if globalPosition >= startPosition
 		if event.GlobalPosition >= startPosition {
-			poke := pokePool.Get().(*Poke)
-			poke.Stream = event.Stream
-			poke.Position = event.Position
-			poke.GlobalPosition = event.GlobalPosition
-
-			err := sendPokeFast(w, poke)
-			pokePool.Put(poke)
+			err := sendWriteFast(w, h, namespace, event, full)
 
 			if err != nil {
 				return
@@ -291,3 +425,35 @@ func handleAllSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace string,
 		}
 	}
 }
+
+// handlePatternSubscriptionFast handles wildcard subscriptions for fasthttp.
+// Like all=true, they deliver new writes only.
+func handlePatternSubscriptionFast(w *bufio.Writer, h *SSEHandler, namespace string, pattern *SubjectPattern, startPosition, consumerMember, consumerSize int64, partitioner store.Partitioner, full bool) {
+	// Send ready signal
+	fmt.Fprintf(w, ": ready\n\n")
+	w.Flush()
+
+	// Subscribe to real-time updates (if pubsub is available)
+	if h.Pubsub == nil {
+		return
+	}
+
+	sub := h.Pubsub.SubscribePattern(namespace, pattern)
+	defer h.Pubsub.UnsubscribePattern(namespace, sub)
+
+	for event := range sub {
+		// Only send if globalPosition >= startPosition
+		if event.GlobalPosition < startPosition {
+			continue
+		}
+		// Apply consumer group filter if needed
+		if consumerSize > 0 && !partitioner.IsAssigned(event.Stream, consumerMember, consumerSize) {
+			continue
+		}
+		err := sendWriteFast(w, h, namespace, event, full)
+
+		if err != nil {
+			return
+		}
+	}
+}
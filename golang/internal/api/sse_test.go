@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// readSSEEvent reads the next event of an SSE response, skipping comments
+func readSSEEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// TestSSE_PayloadFull tests that payload=full subscriptions send existing
+// and new messages in full and fall back to pokes
+func TestSSE_PayloadFull(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+	sse := NewSSEHandler(st, NewPubSub(), true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sse.HandleSubscribe(w, r.WithContext(context.WithValue(r.Context(), ContextKeyNamespace, "test-ns")))
+	}))
+	defer server.Close()
+
+	if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{
+		Type:     "Placed",
+		Data:     map[string]interface{}{"total": 10.0},
+		Metadata: map[string]interface{}{"user": "ann"},
+	}); err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+
+	// Unknown payloads are rejected
+	resp, err := http.Get(server.URL + "?stream=order-1&payload=body")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown payload, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "?category=order&payload=full")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+
+	// Existing messages are sent in full
+	event, data := readSSEEvent(t, r)
+	var msg SSEMessage
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	if event != "message" || msg.Stream != "order-1" || msg.Type != "Placed" || msg.Position != 0 ||
+		msg.Data["total"] != 10.0 || msg.Metadata["user"] != "ann" || msg.ID == "" || msg.Time == "" {
+		t.Errorf("Unexpected %s event: %s", event, data)
+	}
+
+	// So are new writes, read back from the store
+	written, err := st.WriteMessage(ctx, "test-ns", "order-2", &store.Message{
+		Type: "Shipped",
+		Data: map[string]interface{}{"carrier": "post"},
+	})
+	if err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+	sse.Pubsub.Publish(WriteEvent{
+		Namespace:      "test-ns",
+		Stream:         "order-2",
+		Category:       "order",
+		Position:       written.Position,
+		GlobalPosition: written.GlobalPosition,
+	})
+	event, data = readSSEEvent(t, r)
+	msg = SSEMessage{}
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	if event != "message" || msg.Stream != "order-2" || msg.Type != "Shipped" ||
+		msg.GlobalPosition != written.GlobalPosition || msg.Data["carrier"] != "post" {
+		t.Errorf("Unexpected %s event: %s", event, data)
+	}
+
+	// A write that cannot be read back falls back to a poke
+	sse.Pubsub.Publish(WriteEvent{Namespace: "test-ns", Stream: "order-3", Category: "order", GlobalPosition: written.GlobalPosition + 10})
+	if event, data = readSSEEvent(t, r); event != "poke" || !strings.Contains(data, `"stream":"order-3"`) {
+		t.Errorf("Expected a poke for order-3, got %s %s", event, data)
+	}
+}
+
+// TestSSE_PayloadPoke tests that subscriptions send pokes by default and
+// with payload=poke, and that full stream subscriptions start at position
+func TestSSE_PayloadPoke(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+	sse := NewSSEHandler(st, NewPubSub(), true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sse.HandleSubscribe(w, r.WithContext(context.WithValue(r.Context(), ContextKeyNamespace, "test-ns")))
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{
+			Type: "Placed",
+			Data: map[string]interface{}{"n": float64(i)},
+		}); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+
+	for _, query := range []string{"?stream=order-1", "?stream=order-1&payload=poke"} {
+		resp, err := http.Get(server.URL + query)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		event, data := readSSEEvent(t, bufio.NewReader(resp.Body))
+		resp.Body.Close()
+		if event != "poke" || strings.Contains(data, `"data"`) {
+			t.Errorf("Expected a poke without the message for %s, got %s %s", query, event, data)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "?stream=order-1&payload=full&position=1")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	event, data := readSSEEvent(t, bufio.NewReader(resp.Body))
+	var msg SSEMessage
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	if event != "message" || msg.Position != 1 || msg.Data["n"] != 1.0 {
+		t.Errorf("Expected the message at position 1, got %s %s", event, data)
+	}
+}
@@ -0,0 +1,444 @@
+// Package api provides standing queries that collect matching writes into result streams.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// standingQueriesMetadataKey holds the standing queries in namespace metadata
+	standingQueriesMetadataKey = "queries"
+
+	// maxStandingQueries bounds the standing queries kept per namespace
+	maxStandingQueries = 32
+
+	// maxQueryPredicates bounds the predicates of one query
+	maxQueryPredicates = 16
+
+	// queryResultCategory is the category of default result streams
+	queryResultCategory = "query"
+)
+
+// ErrQueryNotFound is returned for standing queries a namespace does not have
+var ErrQueryNotFound = errors.New("standing query not found")
+
+// queryNamePattern restricts standing query names
+var queryNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// Predicate operators
+const (
+	QueryOpEq     = "eq"
+	QueryOpNe     = "ne"
+	QueryOpGt     = "gt"
+	QueryOpGte    = "gte"
+	QueryOpLt     = "lt"
+	QueryOpLte    = "lte"
+	QueryOpIn     = "in"
+	QueryOpPrefix = "prefix"
+	QueryOpExists = "exists"
+)
+
+// queryOps lists the predicate operators
+var queryOps = []string{QueryOpEq, QueryOpNe, QueryOpGt, QueryOpGte, QueryOpLt, QueryOpLte, QueryOpIn, QueryOpPrefix, QueryOpExists}
+
+// QueryPredicate tests the value at a JSONPath of a written message. Paths
+// start at the message: $.data.amount, $.metadata.region, $.data.items[0].sku.
+type QueryPredicate struct {
+	Path  string      `json:"path"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+
+	steps []queryPathStep
+}
+
+// queryPathStep is one field name or array index of a parsed path
+type queryPathStep struct {
+	field string
+	index int // -1 for a field
+}
+
+// StandingQuery appends every write that matches it to a result stream, so
+// clients read or subscribe to the matches instead of running a projector
+// that filters the source categories.
+type StandingQuery struct {
+	Name     string           `json:"name"`               // Query name, e.g. "largeOrders"
+	Category string           `json:"category,omitempty"` // Source category; empty matches all
+	Types    []string         `json:"types,omitempty"`    // Message types; empty matches all
+	Where    []QueryPredicate `json:"where,omitempty"`    // Predicates that must all hold
+	Filter   string           `json:"filter,omitempty"`   // EQL expression that must hold
+	Stream   string           `json:"stream,omitempty"`   // Result stream (default "query-{name}")
+
+	filter *store.Filter
+}
+
+// ResultStream returns the stream the query's matches are appended to
+func (q *StandingQuery) ResultStream() string {
+	if q.Stream != "" {
+		return q.Stream
+	}
+	return queryResultCategory + "-" + q.Name
+}
+
+// StandingQueriesConfig holds a namespace's standing queries
+type StandingQueriesConfig struct {
+	Queries []StandingQuery `json:"queries"`
+}
+
+// Validate checks the queries and parses their paths
+func (c *StandingQueriesConfig) Validate() error {
+	if len(c.Queries) > maxStandingQueries {
+		return fmt.Errorf("at most %d standing queries are allowed", maxStandingQueries)
+	}
+	seen := make(map[string]bool, len(c.Queries))
+	for i := range c.Queries {
+		q := &c.Queries[i]
+		if !queryNamePattern.MatchString(q.Name) {
+			return fmt.Errorf("query %d: invalid name %q (use letters, digits and '_')", i, q.Name)
+		}
+		if seen[q.Name] {
+			return fmt.Errorf("query %d: name %q is used twice", i, q.Name)
+		}
+		seen[q.Name] = true
+		if strings.Contains(q.Category, "-") {
+			return fmt.Errorf("query %s: category must be a category name", q.Name)
+		}
+		// Results are never matched again, but a source category holding
+		// results would mix them with the events they were matched from
+		if q.Category != "" && store.Category(q.ResultStream()) == q.Category {
+			return fmt.Errorf("query %s: stream must be outside the %q category", q.Name, q.Category)
+		}
+		if len(q.Where) > maxQueryPredicates {
+			return fmt.Errorf("query %s: at most %d predicates are allowed", q.Name, maxQueryPredicates)
+		}
+		for j := range q.Where {
+			if err := q.Where[j].parse(); err != nil {
+				return fmt.Errorf("query %s: predicate %d: %v", q.Name, j, err)
+			}
+		}
+		q.filter = nil
+		if q.Filter != "" {
+			filter, err := store.ParseFilter(q.Filter)
+			if err != nil {
+				return fmt.Errorf("query %s: %v", q.Name, err)
+			}
+			// Queries match before the write assigns positions and time
+			for _, field := range []string{"position", "globalPosition", "time"} {
+				if filter.Uses(field) {
+					return fmt.Errorf("query %s: filter cannot use %s, writes are matched before they are stored", q.Name, field)
+				}
+			}
+			q.filter = filter
+		}
+	}
+	return nil
+}
+
+// parse checks the predicate and parses its path
+func (p *QueryPredicate) parse() error {
+	if !containsType(queryOps, p.Op) {
+		return fmt.Errorf("op must be one of %s", strings.Join(queryOps, ", "))
+	}
+	switch p.Op {
+	case QueryOpGt, QueryOpGte, QueryOpLt, QueryOpLte:
+		if _, ok := queryNumber(p.Value); !ok {
+			if _, ok := p.Value.(string); !ok {
+				return fmt.Errorf("%s needs a number or string value", p.Op)
+			}
+		}
+	case QueryOpIn:
+		if _, ok := p.Value.([]interface{}); !ok {
+			return errors.New("in needs an array value")
+		}
+	case QueryOpPrefix:
+		if _, ok := p.Value.(string); !ok {
+			return errors.New("prefix needs a string value")
+		}
+	}
+
+	rest, ok := strings.CutPrefix(p.Path, "$")
+	if !ok {
+		return fmt.Errorf("path %q must start with $", p.Path)
+	}
+	p.steps = nil
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			field := rest[1 : end+1]
+			if field == "" {
+				return fmt.Errorf("path %q has an empty field", p.Path)
+			}
+			p.steps = append(p.steps, queryPathStep{field: field, index: -1})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return fmt.Errorf("path %q has an unclosed [", p.Path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return fmt.Errorf("path %q has an invalid index", p.Path)
+			}
+			p.steps = append(p.steps, queryPathStep{index: index})
+			rest = rest[end+1:]
+		default:
+			return fmt.Errorf("path %q is not a JSONPath like $.data.field", p.Path)
+		}
+	}
+	if len(p.steps) == 0 || p.steps[0].index >= 0 {
+		return fmt.Errorf("path %q must select a message field", p.Path)
+	}
+	return nil
+}
+
+// lookup returns the value at the predicate's path in msg
+func (p *QueryPredicate) lookup(msg *store.Message) (interface{}, bool) {
+	var value interface{} = map[string]interface{}{
+		"streamName": msg.StreamName,
+		"type":       msg.Type,
+		"data":       msg.Data,
+		"metadata":   msg.Metadata,
+	}
+	for _, step := range p.steps {
+		if step.index >= 0 {
+			items, ok := value.([]interface{})
+			if !ok || step.index >= len(items) {
+				return nil, false
+			}
+			value = items[step.index]
+			continue
+		}
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = fields[step.field]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// match evaluates the predicate on msg
+func (p *QueryPredicate) match(msg *store.Message) bool {
+	value, found := p.lookup(msg)
+	switch p.Op {
+	case QueryOpExists:
+		want, _ := p.Value.(bool)
+		if p.Value == nil {
+			want = true
+		}
+		return found == want
+	case QueryOpNe:
+		return !found || !queryEqual(value, p.Value)
+	}
+	if !found {
+		return false
+	}
+
+	switch p.Op {
+	case QueryOpEq:
+		return queryEqual(value, p.Value)
+	case QueryOpIn:
+		for _, v := range p.Value.([]interface{}) {
+			if queryEqual(value, v) {
+				return true
+			}
+		}
+		return false
+	case QueryOpPrefix:
+		s, ok := value.(string)
+		return ok && strings.HasPrefix(s, p.Value.(string))
+	}
+
+	// Ordering compares numbers with numbers and strings with strings
+	var cmp int
+	if a, ok := queryNumber(value); ok {
+		b, ok := queryNumber(p.Value)
+		if !ok {
+			return false
+		}
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	} else if a, ok := value.(string); ok {
+		b, ok := p.Value.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(a, b)
+	} else {
+		return false
+	}
+	switch p.Op {
+	case QueryOpGt:
+		return cmp > 0
+	case QueryOpGte:
+		return cmp >= 0
+	case QueryOpLt:
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+// queryNumber returns v as a float64 if it is a number
+func queryNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// queryEqual compares JSON values, numbers by value
+func queryEqual(a, b interface{}) bool {
+	if x, ok := queryNumber(a); ok {
+		y, ok := queryNumber(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Match reports whether msg matches the query
+func (q *StandingQuery) Match(msg *store.Message) bool {
+	if q.Category != "" && store.Category(msg.StreamName) != q.Category {
+		return false
+	}
+	if len(q.Types) > 0 && !containsType(q.Types, msg.Type) {
+		return false
+	}
+	for i := range q.Where {
+		if !q.Where[i].match(msg) {
+			return false
+		}
+	}
+	return q.filter == nil || q.filter.Match(msg)
+}
+
+// Results returns the result events of the queries msg matches. A result
+// keeps the message's type and data and points back to it in its metadata.
+func (c *StandingQueriesConfig) Results(msg *store.Message) []*store.Message {
+	var results []*store.Message
+	for i := range c.Queries {
+		q := &c.Queries[i]
+		if !q.Match(msg) {
+			continue
+		}
+		metadata := map[string]interface{}{
+			"causationMessageStreamName": msg.StreamName,
+			"causationMessageId":         msg.ID,
+			"query":                      q.Name,
+		}
+		if correlation, ok := msg.Metadata["correlationStreamName"]; ok {
+			metadata["correlationStreamName"] = correlation
+		}
+		results = append(results, &store.Message{
+			StreamName: q.ResultStream(),
+			Type:       msg.Type,
+			Data:       msg.Data,
+			Metadata:   metadata,
+		})
+	}
+	return results
+}
+
+// query returns the named query, or nil
+func (c *StandingQueriesConfig) query(name string) *StandingQuery {
+	for i := range c.Queries {
+		if c.Queries[i].Name == name {
+			return &c.Queries[i]
+		}
+	}
+	return nil
+}
+
+// StandingQueriesFromMetadata returns the standing queries stored in
+// namespace metadata, with their paths parsed (never nil)
+func StandingQueriesFromMetadata(metadata map[string]interface{}) *StandingQueriesConfig {
+	cfg := &StandingQueriesConfig{}
+	if raw, ok := metadata[standingQueriesMetadataKey]; ok && raw != nil {
+		if decodeMetadataValue(raw, cfg) != nil || cfg.Validate() != nil {
+			return &StandingQueriesConfig{}
+		}
+	}
+	return cfg
+}
+
+// StandingQueries evaluates the standing queries of namespaces on writes.
+// A nil *StandingQueries matches nothing.
+type StandingQueries struct {
+	meta *NamespaceMetadata
+}
+
+// NewStandingQueries creates a query cache backed by namespace metadata
+func NewStandingQueries(meta *NamespaceMetadata) *StandingQueries {
+	return &StandingQueries{meta: meta}
+}
+
+// config returns the namespace's cached queries
+func (s *StandingQueries) config(ctx context.Context, namespace string) (*StandingQueriesConfig, error) {
+	cfg, err := s.meta.decode(ctx, namespace, standingQueriesMetadataKey, func(metadata map[string]interface{}) interface{} {
+		return StandingQueriesFromMetadata(metadata)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cfg.(*StandingQueriesConfig), nil
+}
+
+// Results returns the result events of the namespace's queries that msg
+// matches. Lookup failures match nothing so the store reports its own error.
+func (s *StandingQueries) Results(ctx context.Context, namespace string, msg *store.Message) []*store.Message {
+	if s == nil {
+		return nil
+	}
+	cfg, err := s.config(ctx, namespace)
+	if err != nil || len(cfg.Queries) == 0 {
+		return nil
+	}
+	return cfg.Results(msg)
+}
+
+// ResultStream returns the result stream of a namespace's query, or
+// ErrQueryNotFound
+func (s *StandingQueries) ResultStream(ctx context.Context, namespace, name string) (string, error) {
+	cfg, err := s.config(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+	q := cfg.query(name)
+	if q == nil {
+		return "", fmt.Errorf("%w: %s", ErrQueryNotFound, name)
+	}
+	return q.ResultStream(), nil
+}
+
+// Set replaces a namespace's standing queries; no queries removes them
+func (s *StandingQueries) Set(ctx context.Context, namespace string, cfg *StandingQueriesConfig) error {
+	return s.meta.Update(ctx, namespace, func(metadata map[string]interface{}) {
+		if cfg == nil || len(cfg.Queries) == 0 {
+			delete(metadata, standingQueriesMetadataKey)
+			return
+		}
+		metadata[standingQueriesMetadataKey] = encodeMetadataValue(cfg)
+	})
+}
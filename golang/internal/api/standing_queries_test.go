@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestQueryPredicate tests predicate evaluation on message fields
+func TestQueryPredicate(t *testing.T) {
+	msg := &store.Message{
+		StreamName: "order-1",
+		Type:       "Placed",
+		Data: map[string]interface{}{
+			"total": 120.0,
+			"items": []interface{}{map[string]interface{}{"sku": "abc-1"}},
+		},
+		Metadata: map[string]interface{}{"region": "eu"},
+	}
+
+	tests := []struct {
+		path  string
+		op    string
+		value interface{}
+		want  bool
+	}{
+		{"$.data.total", QueryOpGt, 100.0, true},
+		{"$.data.total", QueryOpLte, 100.0, false},
+		{"$.data.total", QueryOpEq, 120.0, true},
+		{"$.data.total", QueryOpGt, "100", false},
+		{"$.metadata.region", QueryOpIn, []interface{}{"us", "eu"}, true},
+		{"$.metadata.region", QueryOpNe, "eu", false},
+		{"$.metadata.missing", QueryOpNe, "eu", true},
+		{"$.data.items[0].sku", QueryOpPrefix, "abc-", true},
+		{"$.data.items[1].sku", QueryOpExists, nil, false},
+		{"$.data.items[1].sku", QueryOpExists, false, true},
+		{"$.type", QueryOpEq, "Placed", true},
+		{"$.streamName", QueryOpGte, "order-2", false},
+	}
+	for _, tt := range tests {
+		p := QueryPredicate{Path: tt.path, Op: tt.op, Value: tt.value}
+		if err := p.parse(); err != nil {
+			t.Fatalf("%s %s: %v", tt.path, tt.op, err)
+		}
+		if got := p.match(msg); got != tt.want {
+			t.Errorf("%s %s %v = %v, want %v", tt.path, tt.op, tt.value, got, tt.want)
+		}
+	}
+
+	for _, p := range []QueryPredicate{
+		{Path: "data.total", Op: QueryOpEq},
+		{Path: "$[0]", Op: QueryOpEq},
+		{Path: "$.data..total", Op: QueryOpEq},
+		{Path: "$.data.items[x]", Op: QueryOpEq},
+		{Path: "$.data.total", Op: "like"},
+		{Path: "$.data.total", Op: QueryOpIn, Value: "eu"},
+		{Path: "$.data.total", Op: QueryOpGt, Value: true},
+	} {
+		if err := p.parse(); err == nil {
+			t.Errorf("Expected an error for %s %s %v", p.Path, p.Op, p.Value)
+		}
+	}
+}
+
+// TestStandingQueries tests that queries set over RPC append matching writes
+// to their result streams in the same write
+func TestStandingQueries(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, query := range []map[string]interface{}{
+		{"name": "bad name"},
+		{"name": "q", "category": "order-1"},
+		{"name": "q", "category": "order", "stream": "order-matches"},
+		{"name": "q", "where": []interface{}{map[string]interface{}{"path": "$.data.total", "op": "between"}}},
+		{"name": "q", "filter": "data.total >"},
+		{"name": "q", "filter": "globalPosition > 10"},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.queries.set", []interface{}{map[string]interface{}{
+			"queries": []interface{}{query},
+		}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", query, rpcErr)
+		}
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.queries.set", []interface{}{map[string]interface{}{
+		"queries": []interface{}{map[string]interface{}{
+			"name":     "largeOrders",
+			"category": "order",
+			"types":    []interface{}{"Placed"},
+			"where": []interface{}{
+				map[string]interface{}{"path": "$.data.total", "op": "gte", "value": 100.0},
+			},
+			"filter": "metadata.correlationStreamName = 'checkout-1'",
+		}},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.queries.set failed: %v", rpcErr.Message)
+	}
+	result, rpcErr := h.route(ctx, "ns.queries.get", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.queries.get failed: %v", rpcErr.Message)
+	}
+	queries := result.(map[string]interface{})["queries"].([]interface{})
+	if len(queries) != 1 || queries[0].(map[string]interface{})["stream"] != "query-largeOrders" {
+		t.Errorf("Expected 1 query with stream query-largeOrders, got %v", queries)
+	}
+
+	for _, total := range []float64{50, 150} {
+		if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-7", map[string]interface{}{
+			"type":     "Placed",
+			"data":     map[string]interface{}{"total": total},
+			"metadata": map[string]interface{}{"correlationStreamName": "checkout-1"},
+		}}); rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+	}
+	// Outside the filter
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-7", map[string]interface{}{
+		"type":     "Placed",
+		"data":     map[string]interface{}{"total": 300.0},
+		"metadata": map[string]interface{}{"correlationStreamName": "checkout-2"},
+	}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "query-largeOrders", nil)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("Expected 1 result, got %d (%v)", len(msgs), err)
+	}
+	msg := msgs[0]
+	if msg.Type != "Placed" || msg.Data["total"] != 150.0 {
+		t.Errorf("Unexpected result: %s %v", msg.Type, msg.Data)
+	}
+	if msg.Metadata["query"] != "largeOrders" ||
+		msg.Metadata["causationMessageStreamName"] != "order-7" ||
+		msg.Metadata["correlationStreamName"] != "checkout-1" {
+		t.Errorf("Unexpected result metadata: %v", msg.Metadata)
+	}
+
+	// Subscriptions resolve query names to result streams
+	sse := NewSSEHandler(st, NewPubSub(), true)
+	if stream, err := sse.queryStream(ctx, "test-ns", "largeOrders"); err != nil || stream != "query-largeOrders" {
+		t.Errorf("Expected query-largeOrders, got %q (%v)", stream, err)
+	}
+	if _, err := sse.queryStream(ctx, "test-ns", "missing"); !errors.Is(err, ErrQueryNotFound) {
+		t.Errorf("Expected ErrQueryNotFound, got %v", err)
+	}
+
+	// Removing the queries stops the results
+	if _, rpcErr := h.route(ctx, "ns.queries.set", []interface{}{nil}); rpcErr != nil {
+		t.Fatalf("ns.queries.set failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-8", map[string]interface{}{
+		"type": "Placed",
+		"data": map[string]interface{}{"total": 500.0},
+	}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	if msgs, _ := st.GetStreamMessages(ctx, "test-ns", "query-largeOrders", nil); len(msgs) != 1 {
+		t.Errorf("Expected no new results after removing the queries, got %d", len(msgs))
+	}
+}
@@ -0,0 +1,154 @@
+// Package api provides background sampling of namespace storage usage.
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// storageGrowthWindow is how far back samples are kept for growth rates
+	storageGrowthWindow = 7 * 24 * time.Hour
+
+	// storageMinGrowthSpan is the minimum time between the oldest and newest
+	// sample before a growth rate is reported
+	storageMinGrowthSpan = time.Hour
+
+	// storageTrackerStartDelay keeps the first sampling out of server startup
+	storageTrackerStartDelay = time.Minute
+)
+
+// storageSample is the size of a namespace at one point in time
+type storageSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// StorageTracker samples the size of every namespace in the background, so
+// ns.storage can report how fast a namespace grows. Samples are kept in
+// memory for 7 days; growth rates restart with the server.
+type StorageTracker struct {
+	store    store.Store
+	reporter store.StorageReporter
+	interval time.Duration
+
+	mu      sync.Mutex
+	samples map[string][]storageSample // Oldest first
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewStorageTracker creates a tracker sampling every interval (default: 1h);
+// call Start to begin sampling. It returns nil if st cannot report storage.
+func NewStorageTracker(st store.Store, interval time.Duration) *StorageTracker {
+	reporter, ok := st.(store.StorageReporter)
+	if !ok {
+		return nil
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StorageTracker{
+		store:    st,
+		reporter: reporter,
+		interval: interval,
+		samples:  make(map[string][]storageSample),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start samples all namespaces in the background until Close
+func (t *StorageTracker) Start() {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		delay := storageTrackerStartDelay
+		for {
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			t.sampleAll(t.ctx)
+			delay = t.interval
+		}
+	}()
+	logger.Get().Info().Dur("interval", t.interval).Msg("Storage tracker started")
+}
+
+// Close stops sampling
+func (t *StorageTracker) Close() {
+	t.cancel()
+	t.wg.Wait()
+}
+
+// Record adds a sample of a namespace's size
+func (t *StorageTracker) Record(namespace string, bytes int64, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := append(t.samples[namespace], storageSample{at: at, bytes: bytes})
+	cutoff := at.Add(-storageGrowthWindow)
+	for len(samples) > 1 && samples[0].at.Before(cutoff) {
+		samples = samples[1:]
+	}
+	t.samples[namespace] = samples
+}
+
+// Growth returns the growth of a namespace in bytes per day over the kept
+// samples, and false until they span at least an hour
+func (t *StorageTracker) Growth(namespace string) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := t.samples[namespace]
+	if len(samples) < 2 {
+		return 0, false
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	span := last.at.Sub(first.at)
+	if span < storageMinGrowthSpan {
+		return 0, false
+	}
+	return float64(last.bytes-first.bytes) / span.Hours() * 24, true
+}
+
+// sampleAll records the size of every namespace
+func (t *StorageTracker) sampleAll(ctx context.Context) {
+	namespaces, err := t.store.ListNamespaces(ctx)
+	if err != nil {
+		logger.Get().Warn().Err(err).Msg("Storage sampling failed to list namespaces")
+		return
+	}
+
+	live := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		live[ns.ID] = true
+		usage, err := t.reporter.NamespaceStorage(ctx, ns.ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Get().Warn().Err(err).Str("namespace", ns.ID).Msg("Storage sampling failed")
+			continue
+		}
+		t.Record(ns.ID, usage.Bytes, time.Now())
+	}
+
+	// Forget deleted namespaces
+	t.mu.Lock()
+	for namespace := range t.samples {
+		if !live[namespace] {
+			delete(t.samples, namespace)
+		}
+	}
+	t.mu.Unlock()
+}
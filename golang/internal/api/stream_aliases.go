@@ -0,0 +1,229 @@
+// Package api provides stream renames that leave the old name as an alias.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// streamAliasesMetadataKey holds the stream aliases in namespace metadata
+	streamAliasesMetadataKey = "streamAliases"
+
+	// maxStreamAliases bounds the aliases kept per namespace
+	maxStreamAliases = 1000
+)
+
+// ErrInvalidRename is returned for stream renames that are not allowed
+var ErrInvalidRename = errors.New("invalid stream rename")
+
+// StreamAlias redirects reads and writes of a renamed stream to its new name
+type StreamAlias struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// StreamAliasesConfig holds a namespace's stream aliases
+type StreamAliasesConfig struct {
+	Aliases []StreamAlias `json:"aliases"`
+}
+
+// Validate checks the aliases. Aliases never point at other aliases, so
+// resolving a name takes one lookup.
+func (c *StreamAliasesConfig) Validate() error {
+	if len(c.Aliases) > maxStreamAliases {
+		return fmt.Errorf("at most %d stream aliases are allowed", maxStreamAliases)
+	}
+	for i, a := range c.Aliases {
+		if a.From == "" || a.To == "" {
+			return fmt.Errorf("alias %d needs from and to", i)
+		}
+		if a.From == a.To {
+			return fmt.Errorf("alias %q points at itself", a.From)
+		}
+		if c.Find(a.From) != &c.Aliases[i] {
+			return fmt.Errorf("alias %q is defined twice", a.From)
+		}
+		if c.Find(a.To) != nil {
+			return fmt.Errorf("alias %q points at alias %q", a.From, a.To)
+		}
+	}
+	return nil
+}
+
+// Find returns the alias of the stream called from, or nil
+func (c *StreamAliasesConfig) Find(from string) *StreamAlias {
+	for i := range c.Aliases {
+		if c.Aliases[i].From == from {
+			return &c.Aliases[i]
+		}
+	}
+	return nil
+}
+
+// AliasesOf returns the old names redirected to the stream called to
+func (c *StreamAliasesConfig) AliasesOf(to string) []string {
+	var names []string
+	for _, a := range c.Aliases {
+		if a.To == to {
+			names = append(names, a.From)
+		}
+	}
+	return names
+}
+
+// StreamAliasesFromMetadata returns the stream aliases stored in namespace metadata (never nil)
+func StreamAliasesFromMetadata(metadata map[string]interface{}) *StreamAliasesConfig {
+	cfg := &StreamAliasesConfig{}
+	if raw, ok := metadata[streamAliasesMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, cfg)
+	}
+	return cfg
+}
+
+// StreamAliases resolves renamed stream names to their new names. A nil
+// *StreamAliases resolves nothing.
+type StreamAliases struct {
+	meta *NamespaceMetadata
+}
+
+// NewStreamAliases creates an alias resolver backed by namespace metadata
+func NewStreamAliases(meta *NamespaceMetadata) *StreamAliases {
+	return &StreamAliases{meta: meta}
+}
+
+// Config returns the aliases of namespace. Lookup failures return no
+// aliases so the store reports its own error on the read.
+func (a *StreamAliases) Config(ctx context.Context, namespace string) *StreamAliasesConfig {
+	if a == nil {
+		return &StreamAliasesConfig{}
+	}
+
+	cfg, err := a.meta.decode(ctx, namespace, streamAliasesMetadataKey, func(metadata map[string]interface{}) interface{} {
+		return StreamAliasesFromMetadata(metadata)
+	})
+	if err != nil {
+		return &StreamAliasesConfig{}
+	}
+	return cfg.(*StreamAliasesConfig)
+}
+
+// Resolve returns the name streamName was renamed to, or streamName when it
+// is not an alias
+func (a *StreamAliases) Resolve(ctx context.Context, namespace, streamName string) string {
+	if alias := a.Config(ctx, namespace).Find(streamName); alias != nil {
+		return alias.To
+	}
+	return streamName
+}
+
+// StreamRename describes a completed stream rename
+type StreamRename struct {
+	From    string
+	To      string
+	Moved   int64 // Messages moved from the old name
+	Version int64 // Version of the renamed stream
+}
+
+// Rename moves the messages of stream from into stream to, which must not
+// have messages yet, and makes from an alias of to. Existing aliases of from
+// are pointed at to. The alias is recorded before the messages move, so
+// writes racing the rename land in to, and retrying a rename that failed
+// half way finishes it. Messages keep their IDs and global positions.
+func (a *StreamAliases) Rename(ctx context.Context, namespace, from, to string) (*StreamRename, error) {
+	if from == to {
+		return nil, fmt.Errorf("%w: a stream cannot be renamed to itself", ErrInvalidRename)
+	}
+	if strings.HasPrefix(from, "eventodb:") || strings.HasPrefix(to, "eventodb:") {
+		return nil, fmt.Errorf("%w: system streams cannot be renamed", ErrInvalidRename)
+	}
+	merger, ok := a.meta.store.(store.StreamMerger)
+	if !ok {
+		return nil, store.ErrNotSupported
+	}
+	if err := CheckWorm(ctx, a.meta.store, namespace); err != nil {
+		return nil, err
+	}
+
+	ns, err := a.meta.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if alias := StreamAliasesFromMetadata(ns.Metadata).Find(from); alias == nil || alias.To != to {
+		// Not a retry: from needs messages to move and to must be new
+		version, err := a.meta.store.GetStreamVersion(ctx, namespace, from)
+		if err != nil {
+			return nil, err
+		}
+		if version < 0 {
+			return nil, fmt.Errorf("%w: stream '%s' has no messages", ErrInvalidRename, from)
+		}
+		if version, err = a.meta.store.GetStreamVersion(ctx, namespace, to); err != nil {
+			return nil, err
+		}
+		if version >= 0 {
+			return nil, fmt.Errorf("%w: stream '%s' already has messages", ErrInvalidRename, to)
+		}
+	}
+
+	err = a.update(ctx, namespace, func(cfg *StreamAliasesConfig) error {
+		if alias := cfg.Find(from); alias != nil && alias.To != to {
+			return fmt.Errorf("%w: stream '%s' was renamed to '%s'", ErrInvalidRename, from, alias.To)
+		}
+		if alias := cfg.Find(to); alias != nil {
+			return fmt.Errorf("%w: '%s' is an alias of '%s'", ErrInvalidRename, to, alias.To)
+		}
+		if cfg.Find(from) == nil {
+			cfg.Aliases = append(cfg.Aliases, StreamAlias{From: from, To: to})
+		}
+		for i := range cfg.Aliases {
+			if cfg.Aliases[i].To == from {
+				cfg.Aliases[i].To = to
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRename, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	moved, err := merger.MergeStreams(ctx, namespace, to, []string{from})
+	if err != nil {
+		return nil, err
+	}
+	version, err := a.meta.store.GetStreamVersion(ctx, namespace, to)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamRename{From: from, To: to, Moved: moved, Version: version}, nil
+}
+
+// update applies change to a namespace's aliases and stores them unless
+// change fails
+func (a *StreamAliases) update(ctx context.Context, namespace string, change func(cfg *StreamAliasesConfig) error) error {
+	var changeErr error
+	err := a.meta.Update(ctx, namespace, func(metadata map[string]interface{}) {
+		cfg := StreamAliasesFromMetadata(metadata)
+		if changeErr = change(cfg); changeErr != nil {
+			return
+		}
+		if len(cfg.Aliases) == 0 {
+			delete(metadata, streamAliasesMetadataKey)
+			return
+		}
+		sort.Slice(cfg.Aliases, func(i, j int) bool { return cfg.Aliases[i].From < cfg.Aliases[j].From })
+		metadata[streamAliasesMetadataKey] = encodeMetadataValue(cfg)
+	})
+	if changeErr != nil {
+		return changeErr
+	}
+	return err
+}
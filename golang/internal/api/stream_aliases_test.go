@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"testing"
+)
+
+// TestStreamRename tests that stream.rename moves a stream's messages and
+// that reads and writes of the old name reach the new stream
+func TestStreamRename(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	write := func(stream string) map[string]interface{} {
+		t.Helper()
+		result, rpcErr := h.route(ctx, "stream.write", []interface{}{stream, map[string]interface{}{"type": "Noted", "data": map[string]interface{}{}}})
+		if rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+		return result.(map[string]interface{})
+	}
+	write("customer-1")
+	write("customer-1")
+	write("client-9")
+
+	for _, args := range []interface{}{
+		[]interface{}{"customer-1", "customer-1"},
+		[]interface{}{"customer-1", "client-9"},
+		[]interface{}{"customer-404", "client-404"},
+		[]interface{}{"customer-1"},
+	} {
+		if _, rpcErr := h.route(ctx, "stream.rename", args.([]interface{})); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	result, rpcErr := h.route(ctx, "stream.rename", []interface{}{"customer-1", "client-1"})
+	if rpcErr != nil {
+		t.Fatalf("stream.rename failed: %v", rpcErr.Message)
+	}
+	if info := result.(map[string]interface{}); info["moved"] != int64(2) || info["version"] != int64(1) {
+		t.Errorf("Unexpected rename result: %v", info)
+	}
+
+	// Writes to the old name go to the new stream
+	write("customer-1")
+	if version, _ := st.GetStreamVersion(ctx, "test-ns", "client-1"); version != 2 {
+		t.Errorf("Expected client-1 at version 2, got %d", version)
+	}
+	if version, _ := st.GetStreamVersion(ctx, "test-ns", "customer-1"); version != -1 {
+		t.Errorf("Expected customer-1 to be empty, got version %d", version)
+	}
+
+	// Reads of the old name read the new stream
+	result, rpcErr = h.route(ctx, "stream.get", []interface{}{"customer-1"})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	if msgs := result.([]interface{}); len(msgs) != 3 {
+		t.Errorf("Expected 3 messages through the alias, got %d", len(msgs))
+	}
+	if result, _ := h.route(ctx, "stream.version", []interface{}{"customer-1"}); result != int64(2) {
+		t.Errorf("Expected version 2 through the alias, got %v", result)
+	}
+
+	// Renaming again points the first alias at the newest name
+	if _, rpcErr := h.route(ctx, "stream.rename", []interface{}{"client-1", "client-0001"}); rpcErr != nil {
+		t.Fatalf("stream.rename failed: %v", rpcErr.Message)
+	}
+	if got := h.aliases.Resolve(ctx, "test-ns", "customer-1"); got != "client-0001" {
+		t.Errorf("Expected customer-1 to resolve to client-0001, got %s", got)
+	}
+
+	// A rename cannot target an alias
+	if _, rpcErr := h.route(ctx, "stream.rename", []interface{}{"client-9", "customer-1"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST renaming to an alias, got %v", rpcErr)
+	}
+
+	result, rpcErr = h.route(ctx, "ns.streams", []interface{}{map[string]interface{}{"prefix": "client-0"}})
+	if rpcErr != nil {
+		t.Fatalf("ns.streams failed: %v", rpcErr.Message)
+	}
+	streams := result.([]interface{})
+	if len(streams) != 1 {
+		t.Fatalf("Expected one stream, got %v", streams)
+	}
+	aliases, _ := streams[0].(map[string]interface{})["aliases"].([]string)
+	if len(aliases) != 2 || aliases[0] != "client-1" || aliases[1] != "customer-1" {
+		t.Errorf("Expected aliases [client-1 customer-1], got %v", aliases)
+	}
+}
+
+// TestStreamRename_Errors tests invalid arguments and alias configs, that a
+// repeated rename is a retry and that frozen namespaces reject renames
+func TestStreamRename_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"customer-1", map[string]interface{}{"type": "Noted", "data": map[string]interface{}{}}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	for _, args := range [][]interface{}{
+		{float64(1), "client-1"},
+		{"customer-1", ""},
+		{"customer-1", "eventodb:audit"},
+	} {
+		if _, rpcErr := h.route(ctx, "stream.rename", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// Renaming again to the same name finishes or repeats the rename
+	for i := 0; i < 2; i++ {
+		result, rpcErr := h.route(ctx, "stream.rename", []interface{}{"customer-1", "client-1"})
+		if rpcErr != nil {
+			t.Fatalf("stream.rename failed: %v", rpcErr.Message)
+		}
+		if info := result.(map[string]interface{}); info["version"] != int64(0) {
+			t.Errorf("Expected client-1 at version 0, got %v", info)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "stream.rename", []interface{}{"customer-1", "client-2"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST renaming an alias elsewhere, got %v", rpcErr)
+	}
+
+	for _, aliases := range [][]StreamAlias{
+		{{From: "a-1", To: ""}},
+		{{From: "a-1", To: "a-1"}},
+		{{From: "a-1", To: "b-1"}, {From: "a-1", To: "c-1"}},
+		{{From: "a-1", To: "b-1"}, {From: "b-1", To: "c-1"}},
+		make([]StreamAlias, maxStreamAliases+1),
+	} {
+		cfg := StreamAliasesConfig{Aliases: aliases}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected aliases %v to be rejected", aliases)
+		}
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.rename", []interface{}{"client-1", "client-3"}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for a frozen namespace, got %v", rpcErr)
+	}
+}
@@ -0,0 +1,167 @@
+// Package api provides detection and merging of streams whose names differ
+// only by case or whitespace.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// MergeAuditStream records stream merges in their namespace
+	MergeAuditStream = "eventodb:audit-merge"
+
+	// maxMergeSources bounds the streams merged into a target at once
+	maxMergeSources = 100
+)
+
+// ErrInvalidMerge is returned for stream merges that are not allowed
+var ErrInvalidMerge = errors.New("invalid stream merge")
+
+// StreamNameKey returns the name streams are compared by to find variants:
+// lower case, without whitespace. "Account-123", "account-123 " and
+// "account- 123" share the key "account-123".
+func StreamNameKey(streamName string) string {
+	return strings.ToLower(strings.Join(strings.Fields(streamName), ""))
+}
+
+// DuplicateStreams is a set of streams with the same StreamNameKey
+type DuplicateStreams struct {
+	Key     string
+	Streams []*store.StreamInfo // By version, highest first
+
+	// Canonical is the suggested target of a merge: the stream with the
+	// most messages, and of those the one written to first
+	Canonical string
+}
+
+// FindDuplicateStreams lists every stream of a namespace and returns the
+// sets of streams that share a StreamNameKey starting with the key of
+// prefix, ordered by key. It reads all streams, so its cost grows with the
+// namespace.
+func FindDuplicateStreams(ctx context.Context, st store.Store, namespace, prefix string) ([]*DuplicateStreams, error) {
+	prefix = StreamNameKey(prefix)
+	byKey := make(map[string][]*store.StreamInfo)
+	page := &store.ListStreamsOpts{Limit: 1000}
+	for {
+		batch, err := st.ListStreams(ctx, namespace, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range batch {
+			if key := StreamNameKey(s.StreamName); strings.HasPrefix(key, prefix) {
+				byKey[key] = append(byKey[key], s)
+			}
+		}
+		if int64(len(batch)) < page.Limit {
+			break
+		}
+		page.Cursor = batch[len(batch)-1].StreamName
+	}
+
+	var duplicates []*DuplicateStreams
+	for key, streams := range byKey {
+		if len(streams) < 2 {
+			continue
+		}
+		sort.SliceStable(streams, func(i, j int) bool {
+			if streams[i].Version != streams[j].Version {
+				return streams[i].Version > streams[j].Version
+			}
+			return streams[i].FirstActivity.Before(streams[j].FirstActivity)
+		})
+		duplicates = append(duplicates, &DuplicateStreams{Key: key, Streams: streams, Canonical: streams[0].StreamName})
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].Key < duplicates[j].Key })
+	return duplicates, nil
+}
+
+// StreamMerge describes a completed stream merge
+type StreamMerge struct {
+	StreamName string
+	Sources    []string
+	Moved      int64 // Messages moved from the sources
+	Version    int64 // Version of the merged stream
+	Reason     string
+	MergedAt   time.Time
+
+	// AuditGlobalPosition is where the StreamsMerged event was written
+	AuditGlobalPosition int64
+}
+
+// MergeStreams moves the messages of sources into target, which may not
+// exist yet, and records a StreamsMerged event in MergeAuditStream. Every
+// source must be a variant of target (see StreamNameKey). Messages keep
+// their IDs and global positions; the merged stream is renumbered in global
+// position order, so positions in target change when sources have messages
+// older than its own.
+//
+// If the audit event cannot be written the streams stay merged and an error
+// is returned.
+func MergeStreams(ctx context.Context, st store.Store, namespace, target string, sources []string, reason string) (*StreamMerge, error) {
+	if len(sources) == 0 || len(sources) > maxMergeSources {
+		return nil, fmt.Errorf("%w: merge 1 to %d sources at once", ErrInvalidMerge, maxMergeSources)
+	}
+	key := StreamNameKey(target)
+	seen := map[string]bool{target: true}
+	for _, source := range sources {
+		if seen[source] {
+			return nil, fmt.Errorf("%w: stream '%s' is listed twice", ErrInvalidMerge, source)
+		}
+		seen[source] = true
+		if StreamNameKey(source) != key {
+			return nil, fmt.Errorf("%w: '%s' is not a case or whitespace variant of '%s'", ErrInvalidMerge, source, target)
+		}
+	}
+	if strings.HasPrefix(key, "eventodb:") {
+		return nil, fmt.Errorf("%w: system streams cannot be merged", ErrInvalidMerge)
+	}
+	merger, ok := st.(store.StreamMerger)
+	if !ok {
+		return nil, store.ErrNotSupported
+	}
+	if err := CheckWorm(ctx, st, namespace); err != nil {
+		return nil, err
+	}
+
+	moved, err := merger.MergeStreams(ctx, namespace, target, sources)
+	if err != nil {
+		return nil, err
+	}
+	version, err := st.GetStreamVersion(ctx, namespace, target)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &StreamMerge{
+		StreamName: target,
+		Sources:    sources,
+		Moved:      moved,
+		Version:    version,
+		Reason:     reason,
+		MergedAt:   time.Now().UTC(),
+	}
+	result, err := st.WriteMessage(ctx, namespace, MergeAuditStream, &store.Message{
+		StreamName: MergeAuditStream,
+		Type:       "StreamsMerged",
+		Data: map[string]interface{}{
+			"streamName": target,
+			"sources":    sources,
+			"moved":      moved,
+			"version":    version,
+			"reason":     reason,
+			"time":       m.MergedAt.Format(time.RFC3339Nano),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("streams merged, but the audit event was not recorded: %w", err)
+	}
+	m.AuditGlobalPosition = result.GlobalPosition
+	return m, nil
+}
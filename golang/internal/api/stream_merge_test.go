@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestStreamMerge tests that ns.duplicateStreams finds case and whitespace
+// variants and that stream.merge moves their messages and records the merge
+func TestStreamMerge(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	// stream.write rejects whitespace, so the variants are written to the
+	// store, as an import from another system would
+	for _, stream := range []string{"account-1", "Account-1", "account-1", "account-1 ", "account-2", "order-1"} {
+		if _, err := st.WriteMessage(ctx, "test-ns", stream, &store.Message{Type: "Noted", Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+
+	result, rpcErr := h.route(ctx, "ns.duplicateStreams", []interface{}{map[string]interface{}{"prefix": "ACCOUNT"}})
+	if rpcErr != nil {
+		t.Fatalf("ns.duplicateStreams failed: %v", rpcErr.Message)
+	}
+	duplicates := result.([]interface{})
+	if len(duplicates) != 1 {
+		t.Fatalf("Expected one set of duplicates, got %v", duplicates)
+	}
+	d := duplicates[0].(map[string]interface{})
+	if d["key"] != "account-1" || d["canonical"] != "account-1" || len(d["streams"].([]interface{})) != 3 {
+		t.Errorf("Unexpected duplicates: %v", d)
+	}
+
+	for _, args := range [][]interface{}{
+		{"account-1", []interface{}{}},
+		{"account-1", []interface{}{"account-2"}},
+		{"account-1", []interface{}{"Account-1", "Account-1"}},
+		{"account-1", []interface{}{"account-1"}},
+		{"account-1", "Account-1"},
+	} {
+		if _, rpcErr := h.route(ctx, "stream.merge", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	result, rpcErr = h.route(ctx, "stream.merge", []interface{}{"account-1", []interface{}{"Account-1", "account-1 "}, map[string]interface{}{
+		"reason": "Client wrote mixed-case IDs (TICKET-7)",
+	}})
+	if rpcErr != nil {
+		t.Fatalf("stream.merge failed: %v", rpcErr.Message)
+	}
+	info := result.(map[string]interface{})
+	if info["moved"] != int64(2) || info["version"] != int64(3) {
+		t.Errorf("Unexpected merge result: %v", info)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "account-1", nil)
+	if err != nil || len(msgs) != 4 {
+		t.Fatalf("Expected 4 messages, got %d (%v)", len(msgs), err)
+	}
+	if msgs[1].GlobalPosition != 2 || msgs[3].GlobalPosition != 4 {
+		t.Errorf("Expected the messages in global position order, got %d and %d", msgs[1].GlobalPosition, msgs[3].GlobalPosition)
+	}
+
+	audit, err := st.GetStreamMessages(ctx, "test-ns", MergeAuditStream, nil)
+	if err != nil || len(audit) != 1 {
+		t.Fatalf("Expected one audit event, got %d (%v)", len(audit), err)
+	}
+	if audit[0].Type != "StreamsMerged" || audit[0].Data["streamName"] != "account-1" || audit[0].Data["reason"] != "Client wrote mixed-case IDs (TICKET-7)" {
+		t.Errorf("Unexpected audit event: %+v", audit[0])
+	}
+	if audit[0].GlobalPosition != info["auditGlobalPosition"] {
+		t.Errorf("Expected auditGlobalPosition %d, got %v", audit[0].GlobalPosition, info["auditGlobalPosition"])
+	}
+
+	// Nothing is left to merge
+	result, rpcErr = h.route(ctx, "ns.duplicateStreams", nil)
+	if rpcErr != nil || len(result.([]interface{})) != 0 {
+		t.Errorf("Expected no duplicates after the merge, got %v, %v", result, rpcErr)
+	}
+}
+
+// TestStreamMerge_Errors tests invalid arguments, the source limit, that
+// empty variants move nothing and that frozen namespaces reject merges
+func TestStreamMerge_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	if _, err := st.WriteMessage(ctx, "test-ns", "account-1", &store.Message{Type: "Noted", Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+
+	for _, args := range [][]interface{}{
+		{"ACCOUNT"},
+		{map[string]interface{}{"prefix": float64(1)}},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.duplicateStreams", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	tooMany := make([]interface{}, maxMergeSources+1)
+	for i := range tooMany {
+		tooMany[i] = "account-1" + strings.Repeat(" ", i+1)
+	}
+	for _, args := range [][]interface{}{
+		{"account-1"},
+		{"", []interface{}{"Account-1"}},
+		{"account-1", []interface{}{float64(1)}},
+		{"account-1", []interface{}{"Account-1"}, "reason"},
+		{"account-1", []interface{}{"Account-1"}, map[string]interface{}{"reason": float64(7)}},
+		{"account-1", tooMany},
+	} {
+		if _, rpcErr := h.route(ctx, "stream.merge", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// A variant without messages moves nothing
+	result, rpcErr := h.route(ctx, "stream.merge", []interface{}{"account-1", []interface{}{"ACCOUNT-1"}})
+	if rpcErr != nil {
+		t.Fatalf("stream.merge failed: %v", rpcErr.Message)
+	}
+	if info := result.(map[string]interface{}); info["moved"] != int64(0) || info["version"] != int64(0) {
+		t.Errorf("Expected nothing moved, got %v", info)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.merge", []interface{}{"account-1", []interface{}{"Account-1"}}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for a frozen namespace, got %v", rpcErr)
+	}
+}
@@ -0,0 +1,74 @@
+// Package api provides stream name validation and parsing for clients.
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// SetStreamNamePolicy sets the rules stream.write applies to stream names.
+// A nil policy accepts every name.
+func (h *RPCHandler) SetStreamNamePolicy(p *store.StreamNamePolicy) {
+	h.names = p
+}
+
+// invalidStreamNameError converts a policy violation to an RPC error
+func invalidStreamNameError(err error) *RPCError {
+	rpcErr := &RPCError{
+		Code:    "INVALID_STREAM_NAME",
+		Message: err.Error(),
+	}
+	var nameErr *store.StreamNameError
+	if errors.As(err, &nameErr) {
+		rpcErr.Details = map[string]interface{}{
+			"streamName": nameErr.StreamName,
+			"reason":     nameErr.Reason,
+		}
+	}
+	return rpcErr
+}
+
+// handleSysParseStreamName breaks a stream name into the parts the server
+// derives from it, so clients never have to reimplement the rules
+// Request: ["sys.parseStreamName", "account-123+456"]
+// Response: {"streamName": "account-123+456", "category": "account", "id": "123+456",
+//
+//	"cardinalId": "123", "compoundIds": ["123", "456"], "isCategory": false, "valid": true}
+func (h *RPCHandler) handleSysParseStreamName(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	if len(args) < 1 {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "sys.parseStreamName requires 1 argument: streamName",
+		}
+	}
+
+	streamName, ok := args[0].(string)
+	if !ok {
+		return nil, &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "streamName must be a string",
+		}
+	}
+
+	parts := store.ParseStreamName(streamName)
+	result := map[string]interface{}{
+		"streamName":  parts.StreamName,
+		"category":    parts.Category,
+		"id":          parts.ID,
+		"cardinalId":  parts.CardinalID,
+		"compoundIds": parts.CompoundIDs,
+		"isCategory":  parts.IsCategory,
+		"valid":       true,
+	}
+
+	// Report whether stream.write would accept the name
+	var nameErr *store.StreamNameError
+	if err := h.names.Validate(streamName); errors.As(err, &nameErr) {
+		result["valid"] = false
+		result["reason"] = nameErr.Reason
+	}
+
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRPC_InvalidStreamName(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	msg := map[string]interface{}{"type": "Noted", "data": map[string]interface{}{}}
+
+	_, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-", msg})
+	if rpcErr == nil || rpcErr.Code != "INVALID_STREAM_NAME" {
+		t.Fatalf("Expected INVALID_STREAM_NAME, got %v", rpcErr)
+	}
+	if rpcErr.Details["streamName"] != "account-" || rpcErr.Details["reason"] != "ID after '-' is empty" {
+		t.Errorf("Unexpected details: %v", rpcErr.Details)
+	}
+
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"$system-1", msg}); rpcErr == nil || rpcErr.Code != "INVALID_STREAM_NAME" {
+		t.Fatalf("Expected reserved names to be rejected, got %v", rpcErr)
+	}
+
+	// Validation can be turned off
+	h.SetStreamNamePolicy(nil)
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-", msg}); rpcErr != nil {
+		t.Fatalf("Expected write without validation to succeed, got %v", rpcErr)
+	}
+}
+
+func TestSysParseStreamName(t *testing.T) {
+	h := NewRPCHandler("test", nil, NewPubSub())
+	ctx := context.Background()
+
+	result, rpcErr := h.route(ctx, "sys.parseStreamName", []interface{}{"account-123+456"})
+	if rpcErr != nil {
+		t.Fatalf("sys.parseStreamName failed: %v", rpcErr)
+	}
+	parts := result.(map[string]interface{})
+	if parts["category"] != "account" || parts["id"] != "123+456" || parts["cardinalId"] != "123" ||
+		parts["isCategory"] != false || parts["valid"] != true {
+		t.Errorf("Unexpected parts: %v", parts)
+	}
+	if ids := parts["compoundIds"].([]string); len(ids) != 2 || ids[0] != "123" || ids[1] != "456" {
+		t.Errorf("Unexpected compound IDs: %v", ids)
+	}
+
+	result, rpcErr = h.route(ctx, "sys.parseStreamName", []interface{}{"account-"})
+	if rpcErr != nil {
+		t.Fatalf("sys.parseStreamName failed: %v", rpcErr)
+	}
+	parts = result.(map[string]interface{})
+	if parts["valid"] != false || parts["reason"] != "ID after '-' is empty" {
+		t.Errorf("Expected an invalid name with a reason, got %v", parts)
+	}
+}
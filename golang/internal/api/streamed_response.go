@@ -0,0 +1,262 @@
+// Package api provides RPC responses written incrementally, for reads too
+// large to build in memory.
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// ndjsonContentType is sent by clients in Accept to read stream.get
+	// results as one message per line
+	ndjsonContentType = "application/x-ndjson"
+
+	// ContextKeyNDJSON is the context key set when the client accepts NDJSON
+	ContextKeyNDJSON contextKey = "ndjson"
+
+	// streamedReadPage is the number of messages read per call to the store
+	// while a streamed response is written
+	streamedReadPage = 1000
+)
+
+// acceptsNDJSON reports whether an Accept header asks for NDJSON
+func acceptsNDJSON(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		if strings.EqualFold(strings.TrimSpace(mediaType), ndjsonContentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// wantsNDJSON reports whether the request accepts NDJSON responses
+func wantsNDJSON(ctx context.Context) bool {
+	ndjson, _ := ctx.Value(ContextKeyNDJSON).(bool)
+	return ndjson
+}
+
+// streamedResponse is an RPC result written as it is read: a JSON array, or
+// NDJSON with one item per line. items are written first, then the batches
+// returned by next until it returns none.
+type streamedResponse struct {
+	ctx    context.Context
+	ndjson bool
+	items  []interface{}
+	next   func(ctx context.Context) ([]interface{}, error) // Nil when items are all
+}
+
+// contentType returns the Content-Type of the response
+func (s *streamedResponse) contentType() string {
+	if s.ndjson {
+		return ndjsonContentType
+	}
+	return "application/json"
+}
+
+// batches calls fn with the items, then with each batch returned by next.
+// It stops at the first error of fn or next; errors of next are wrapped in
+// errStreamedRead.
+func (s *streamedResponse) batches(fn func(items []interface{}) error) error {
+	items := s.items
+	for len(items) > 0 {
+		if err := fn(items); err != nil {
+			return err
+		}
+		if s.next == nil {
+			return nil
+		}
+		var err error
+		if items, err = s.next(s.ctx); err != nil {
+			return fmt.Errorf("%w: %w", errStreamedRead, err)
+		}
+	}
+	return nil
+}
+
+// errStreamedRead is returned by batches when reading a batch failed
+var errStreamedRead = errors.New("streamed read failed")
+
+// write writes the response to w, flushing after every batch. A read failure
+// after the response has started ends a JSON array unclosed, so the client
+// fails to parse it, and NDJSON with a final {"error", "message"} line.
+func (s *streamedResponse) write(w io.Writer, flush func() error) {
+	enc := json.NewEncoder(w)
+	if !s.ndjson {
+		io.WriteString(w, "[")
+	}
+
+	written := 0
+	err := s.batches(func(items []interface{}) error {
+		for _, item := range items {
+			if written > 0 && !s.ndjson {
+				io.WriteString(w, ",")
+			}
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+			written++
+		}
+		return flush()
+	})
+	if errors.Is(err, errStreamedRead) {
+		logger.Get().Error().Err(err).Msg("Streamed response failed")
+		if s.ndjson {
+			enc.Encode(map[string]string{"error": "BACKEND_ERROR", "message": err.Error()})
+		}
+		flush()
+		return
+	}
+	if err != nil {
+		return // The client went away
+	}
+
+	if !s.ndjson {
+		io.WriteString(w, "]\n")
+	}
+	flush()
+}
+
+// writeHTTP writes the response with net/http
+func (s *streamedResponse) writeHTTP(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", s.contentType())
+	w.WriteHeader(http.StatusOK)
+	s.write(w, func() error {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	})
+}
+
+// writeFast writes the response with fasthttp, after the handler returns
+func (s *streamedResponse) writeFast(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType(s.contentType())
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		s.write(w, w.Flush)
+	})
+}
+
+// streamStreamGet answers a stream.get without a batch size limit. It reads
+// the stream up to the message that was last when the read started, a page
+// at a time while the response is written, so the whole stream is never
+// held in memory. The first page is read here, so its errors are reported
+// as usual. Transports that collect the pages into one result count them
+// against the result limits.
+func (h *RPCHandler) streamStreamGet(ctx context.Context, namespace, streamName string, opts *store.GetOpts, decode bool) (interface{}, *RPCError) {
+	if resultHeld(ctx) {
+		ctx = meteredReads(ctx)
+	}
+	last, err := h.store.GetLastStreamMessage(ctx, namespace, streamName, nil)
+	if errors.Is(err, store.ErrStreamNotFound) {
+		last, err = nil, nil
+	}
+	if err != nil {
+		return nil, streamGetError(err)
+	}
+
+	pageOpts := *opts
+	pageOpts.BatchSize = streamedReadPage
+	done := last == nil
+
+	// readPage reads the next page, without messages written after last
+	readPage := func(ctx context.Context) ([]*store.Message, error) {
+		if done {
+			return nil, nil
+		}
+		messages, err := h.store.GetStreamMessages(ctx, namespace, streamName, &pageOpts)
+		if err != nil {
+			return nil, err
+		}
+		for i, msg := range messages {
+			if msg.Position >= last.Position {
+				messages, done = messages[:i+1], true
+				break
+			}
+		}
+		if len(messages) < streamedReadPage {
+			done = true
+		}
+		if len(messages) > 0 {
+			end := messages[len(messages)-1]
+			if pageOpts.GlobalPosition != nil {
+				gpos := end.GlobalPosition + 1
+				pageOpts.GlobalPosition = &gpos
+			} else {
+				pageOpts.Position = end.Position + 1
+			}
+		}
+		return messages, nil
+	}
+
+	// format decodes a page and formats it as stream.get does
+	format := func(ctx context.Context, messages []*store.Message) ([]interface{}, *RPCError) {
+		if rpcErr := h.decodeMessages(ctx, namespace, decode, messages); rpcErr != nil {
+			return nil, rpcErr
+		}
+		items := make([]interface{}, len(messages))
+		for i, msg := range messages {
+			items[i] = formatMessage(msg)
+		}
+		return items, nil
+	}
+
+	first, err := readPage(ctx)
+	if err != nil {
+		return nil, streamGetError(err)
+	}
+	items, rpcErr := format(ctx, first)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Paging hints point past the last message the response ends with
+	hints := newReadHints(nil, &opts.Position, opts.GlobalPosition, opts.GlobalPosition == nil)
+	if len(first) > 0 {
+		hints = newReadHints([]*store.Message{last}, nil, nil, opts.GlobalPosition == nil)
+		hints.SuggestedBatchSize = suggestBatchSize(first)
+	}
+	hints.setHeaders(ctx)
+
+	response := &streamedResponse{ctx: ctx, ndjson: wantsNDJSON(ctx), items: items}
+	if !done {
+		response.next = func(ctx context.Context) ([]interface{}, error) {
+			messages, err := readPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			items, rpcErr := format(ctx, messages)
+			if rpcErr != nil {
+				return nil, fmt.Errorf("%s: %s", rpcErr.Code, rpcErr.Message)
+			}
+			return items, nil
+		}
+	}
+	return response, nil
+}
+
+// streamGetError reports a failed stream.get read
+func streamGetError(err error) *RPCError {
+	if rpcErr, ok := resultLimit(err); ok {
+		return rpcErr
+	}
+	if store.IsOverloaded(err) {
+		return overloadedError(err)
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to get messages: %v", err),
+	}
+}
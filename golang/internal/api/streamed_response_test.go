@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestStreamGetStreamed tests that unlimited stream.get reads are written
+// page by page, up to the message that was last when the read started
+func TestStreamGetStreamed(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	write := func(n int) {
+		t.Helper()
+		msgs := make([]interface{}, n)
+		for i := range msgs {
+			msgs[i] = map[string]interface{}{"type": "Counted", "data": map[string]interface{}{"n": i}}
+		}
+		if _, rpcErr := h.route(ctx, "stream.writeBatch", []interface{}{"big-1", msgs}); rpcErr != nil {
+			t.Fatalf("stream.writeBatch failed: %v", rpcErr.Message)
+		}
+	}
+	write(1000)
+	write(1000)
+	write(500)
+
+	result, rpcErr := h.route(ctx, "stream.get", []interface{}{"big-1", map[string]interface{}{"batchSize": float64(-1)}})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	streamed, ok := result.(*streamedResponse)
+	if !ok {
+		t.Fatalf("Expected a streamed response, got %T", result)
+	}
+
+	// Messages written after the read started are not included
+	write(10)
+
+	var buf bytes.Buffer
+	streamed.write(&buf, func() error { return nil })
+	var messages [][]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &messages); err != nil {
+		t.Fatalf("Response is not a JSON array: %v", err)
+	}
+	if len(messages) != 2500 {
+		t.Fatalf("Expected 2500 messages, got %d", len(messages))
+	}
+	for i, msg := range messages {
+		if msg[2] != float64(i) {
+			t.Fatalf("Message %d has position %v", i, msg[2])
+		}
+	}
+
+	// NDJSON writes one message per line
+	ndjsonCtx := context.WithValue(ctx, ContextKeyNDJSON, true)
+	result, rpcErr = h.route(ndjsonCtx, "stream.get", []interface{}{"big-1", map[string]interface{}{"position": float64(2000), "batchSize": float64(-1)}})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	buf.Reset()
+	result.(*streamedResponse).write(&buf, func() error { return nil })
+	lines := 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var msg []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Line %d is not a message: %v", lines, err)
+		}
+		lines++
+	}
+	if lines != 510 {
+		t.Errorf("Expected 510 lines, got %d", lines)
+	}
+
+	// Limited reads keep the in-memory response
+	result, rpcErr = h.route(ctx, "stream.get", []interface{}{"big-1", map[string]interface{}{"batchSize": float64(10)}})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	if messages, ok := result.([]interface{}); !ok || len(messages) != 10 {
+		t.Errorf("Expected 10 messages, got %v", result)
+	}
+
+	if !acceptsNDJSON("application/json;q=0.9, application/x-ndjson") || acceptsNDJSON("application/json") {
+		t.Error("acceptsNDJSON did not match the Accept header")
+	}
+}
+
+// TestStreamGetStreamed_Errors tests that an empty stream is an empty array
+// and that a read failing after the response started ends it unparseable
+// for JSON and with an error line for NDJSON
+func TestStreamGetStreamed_Errors(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	result, rpcErr := h.route(ctx, "stream.get", []interface{}{"empty-1", map[string]interface{}{"batchSize": float64(-1)}})
+	if rpcErr != nil {
+		t.Fatalf("stream.get failed: %v", rpcErr.Message)
+	}
+	var buf bytes.Buffer
+	result.(*streamedResponse).write(&buf, func() error { return nil })
+	if buf.String() != "[]\n" {
+		t.Errorf("Expected an empty array, got %q", buf.String())
+	}
+
+	failing := func(ndjson bool) *streamedResponse {
+		return &streamedResponse{
+			ctx:    ctx,
+			ndjson: ndjson,
+			items:  []interface{}{[]interface{}{"id-1"}},
+			next: func(ctx context.Context) ([]interface{}, error) {
+				return nil, errors.New("connection reset")
+			},
+		}
+	}
+	buf.Reset()
+	failing(false).write(&buf, func() error { return nil })
+	var messages []interface{}
+	if err := json.Unmarshal(buf.Bytes(), &messages); err == nil {
+		t.Errorf("Expected a failed JSON response not to parse, got %q", buf.String())
+	}
+
+	buf.Reset()
+	failing(true).write(&buf, func() error { return nil })
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"error":"BACKEND_ERROR"`) {
+		t.Errorf("Expected a message and an error line, got %q", buf.String())
+	}
+
+	// A client that went away stops the response after the first batch
+	buf.Reset()
+	calls := 0
+	response := failing(false)
+	response.next = func(ctx context.Context) ([]interface{}, error) {
+		calls++
+		return []interface{}{[]interface{}{"id-2"}}, nil
+	}
+	response.write(&buf, func() error { return errors.New("broken pipe") })
+	if calls != 0 || buf.String() != "[[\"id-1\"]\n" {
+		t.Errorf("Expected the response to stop, got %d reads and %q", calls, buf.String())
+	}
+}
@@ -0,0 +1,85 @@
+// Package api provides wildcard subscription patterns for pubsub.
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxPatternAlternatives bounds the '|' alternatives in one pattern
+const maxPatternAlternatives = 32
+
+// SubjectPattern matches writes by stream or category name. Alternatives are
+// separated by '|' and '*' matches any run of characters, so stream pattern
+// "order-*" follows every order stream and category pattern "order|invoice"
+// follows two categories over one subscription.
+type SubjectPattern struct {
+	Category     bool // Match category names instead of stream names
+	alternatives []string
+}
+
+// IsSubjectPattern reports whether a subscription name is a pattern
+func IsSubjectPattern(name string) bool {
+	return strings.ContainsAny(name, "*|")
+}
+
+// ParseSubjectPattern parses a stream pattern, or a category pattern when
+// category is true
+func ParseSubjectPattern(pattern string, category bool) (*SubjectPattern, error) {
+	alternatives := strings.Split(pattern, "|")
+	if len(alternatives) > maxPatternAlternatives {
+		return nil, fmt.Errorf("pattern has %d alternatives, more than the limit of %d", len(alternatives), maxPatternAlternatives)
+	}
+	for _, alt := range alternatives {
+		if alt == "" {
+			return nil, fmt.Errorf("pattern %q has an empty alternative", pattern)
+		}
+	}
+	return &SubjectPattern{Category: category, alternatives: alternatives}, nil
+}
+
+// Match reports whether a write matches the pattern
+func (p *SubjectPattern) Match(event WriteEvent) bool {
+	name := event.Stream
+	if p.Category {
+		name = event.Category
+	}
+	for _, alt := range p.alternatives {
+		if globMatch(alt, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the pattern as given
+func (p *SubjectPattern) String() string {
+	return strings.Join(p.alternatives, "|")
+}
+
+// globMatch matches name against pattern, where '*' matches any run of
+// characters, including none
+func globMatch(pattern, name string) bool {
+	literal, rest, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, literal) {
+		return false
+	}
+	name = name[len(literal):]
+
+	// Each later literal matches at its first occurrence, and the last one
+	// at the end of the name
+	for {
+		literal, rest, wildcard = strings.Cut(rest, "*")
+		if !wildcard {
+			return strings.HasSuffix(name, literal)
+		}
+		i := strings.Index(name, literal)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(literal):]
+	}
+}
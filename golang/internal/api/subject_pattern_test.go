@@ -0,0 +1,93 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"order-*", "order-123", true},
+		{"order-*", "order-", true},
+		{"order-*", "order", false},
+		{"order-*", "orders-1", false},
+		{"*", "anything", true},
+		{"*-123", "order-123", true},
+		{"*-123", "order-1234", false},
+		{"order*:command-*", "orderFulfillment:command-1", true},
+		{"order*:command-*", "order-1", false},
+		{"a*b*c", "abbc", true},
+		{"a*b*c", "acb", false},
+		{"a*a", "a", false},
+		{"order", "order", true},
+	}
+	for _, tt := range tests {
+		if got := globMatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSubjectPattern(t *testing.T) {
+	if _, err := ParseSubjectPattern("order||invoice", true); err == nil {
+		t.Error("Expected an error for an empty alternative")
+	}
+	if _, err := ParseSubjectPattern("order|", true); err == nil {
+		t.Error("Expected an error for a trailing '|'")
+	}
+
+	p, err := ParseSubjectPattern("order|invoice", true)
+	if err != nil {
+		t.Fatalf("ParseSubjectPattern failed: %v", err)
+	}
+	if !p.Match(WriteEvent{Stream: "invoice-7", Category: "invoice"}) {
+		t.Error("Expected the category pattern to match invoice")
+	}
+	if p.Match(WriteEvent{Stream: "order-7", Category: "orderLine"}) {
+		t.Error("Expected category patterns to match whole names")
+	}
+}
+
+func TestPubSub_SubscribePattern(t *testing.T) {
+	ps := NewPubSub()
+	streams, _ := ParseSubjectPattern("order-*", false)
+	categories, _ := ParseSubjectPattern("order|invoice", true)
+	streamSub := ps.SubscribePattern("test-ns", streams)
+	categorySub := ps.SubscribePattern("test-ns", categories)
+
+	ps.Publish(WriteEvent{Namespace: "test-ns", Stream: "order-1", Category: "order", GlobalPosition: 1})
+	ps.Publish(WriteEvent{Namespace: "test-ns", Stream: "invoice-1", Category: "invoice", GlobalPosition: 2})
+	ps.Publish(WriteEvent{Namespace: "test-ns", Stream: "account-1", Category: "account", GlobalPosition: 3})
+	ps.Publish(WriteEvent{Namespace: "other-ns", Stream: "order-2", Category: "order", GlobalPosition: 4})
+
+	if got := drain(streamSub); len(got) != 1 || got[0].Stream != "order-1" {
+		t.Errorf("Expected order-1 only, got %+v", got)
+	}
+	if got := drain(categorySub); len(got) != 2 || got[0].Stream != "order-1" || got[1].Stream != "invoice-1" {
+		t.Errorf("Expected order-1 and invoice-1, got %+v", got)
+	}
+
+	ps.UnsubscribePattern("test-ns", streamSub)
+	if _, ok := <-streamSub; ok {
+		t.Error("Expected the unsubscribed channel to be closed")
+	}
+	ps.Close()
+	if _, ok := <-categorySub; ok {
+		t.Error("Expected Close to close pattern subscriptions")
+	}
+}
+
+// drain returns the events buffered in sub
+func drain(sub Subscriber) []WriteEvent {
+	var events []WriteEvent
+	for {
+		select {
+		case event := <-sub:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
@@ -0,0 +1,439 @@
+// Package api provides recurring tick events written on cron schedules.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// ticksMetadataKey is the namespace metadata key holding its ticks
+	ticksMetadataKey = "ticks"
+
+	// TickDefaultType is the message type of ticks created without one
+	TickDefaultType = "Tick"
+
+	// tickLimit is the number of ticks a namespace can have
+	tickLimit = 100
+
+	// tickInterval is how often the scheduler looks for due ticks
+	tickInterval = 5 * time.Second
+
+	// tickReloadInterval is how often ticks are reloaded from namespace
+	// metadata, picking up ticks created on other nodes
+	tickReloadInterval = time.Minute
+
+	// tickWriteAttempts bounds the retries of a tick whose stream was
+	// written concurrently
+	tickWriteAttempts = 3
+
+	// tickRecent is the number of messages at the end of a stream searched
+	// for a tick already written by another node
+	tickRecent = 20
+)
+
+var (
+	// ErrTicksDisabled is returned when the server was started without a
+	// tick scheduler
+	ErrTicksDisabled = errors.New("ticks are not enabled")
+
+	// ErrTickNotFound is returned for a tick name the namespace does not have
+	ErrTickNotFound = errors.New("tick not found")
+
+	// ErrTickExists is returned when creating a tick with a name in use
+	ErrTickExists = errors.New("tick already exists")
+
+	// ErrInvalidTick is returned for a tick config that cannot be scheduled
+	ErrInvalidTick = errors.New("invalid tick")
+)
+
+// TickConfig is a recurring event appended to a stream on a cron schedule.
+//
+// Each occurrence writes a message of Type to Stream whose data is Data
+// plus "tick" (the tick name) and "scheduledAt" (the minute it is due, RFC
+// 3339). Occurrences missed while no server was running are not caught up.
+type TickConfig struct {
+	Name      string                 `json:"name"`
+	Cron      string                 `json:"cron"`           // Five-field cron expression (UTC) or @hourly etc.
+	Stream    string                 `json:"stream"`         // Stream the ticks are appended to
+	Type      string                 `json:"type,omitempty"` // Message type (default: Tick)
+	Data      map[string]interface{} `json:"data,omitempty"` // Extra message data
+	CreatedAt string                 `json:"createdAt,omitempty"`
+}
+
+// TickStatus is a tick with its next occurrence
+type TickStatus struct {
+	TickConfig
+	NextAt string `json:"nextAt"`
+}
+
+// Validate checks the config and applies defaults
+func (c *TickConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: name must be a non-empty string", ErrInvalidTick)
+	}
+	if _, err := ParseCron(c.Cron); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTick, err)
+	}
+	if c.Stream == "" {
+		return fmt.Errorf("%w: stream must be a non-empty string", ErrInvalidTick)
+	}
+	if c.Type == "" {
+		c.Type = TickDefaultType
+	}
+	return nil
+}
+
+// tickSet is the stored form of a namespace's ticks
+type tickSet struct {
+	Ticks []TickConfig `json:"ticks"`
+}
+
+// TicksFromMetadata returns the ticks stored in namespace metadata, by name
+func TicksFromMetadata(metadata map[string]interface{}) []TickConfig {
+	var set tickSet
+	if raw, ok := metadata[ticksMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, &set)
+	}
+	sort.Slice(set.Ticks, func(i, j int) bool { return set.Ticks[i].Name < set.Ticks[j].Name })
+	return set.Ticks
+}
+
+// TickScheduler appends the ticks tenants create with tick.create, so
+// time-driven process managers need no external scheduler.
+//
+// Ticks live in namespace metadata and are reloaded every minute, so every
+// node of a deployment knows them. Each occurrence is written with the tick
+// stream's version as expectedVersion after checking the stream's latest
+// messages for it, so nodes sharing a backend write it once; with a router
+// only the namespace's owner writes it.
+type TickScheduler struct {
+	store  store.Store
+	pubsub *PubSub
+	router *Router // Optional, only namespaces this node owns are ticked
+
+	// mu guards ticks
+	mu    sync.Mutex
+	ticks map[string]map[string]*tick // namespace -> name -> tick
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// tick is a parsed tick and its next occurrence
+type tick struct {
+	cfg  TickConfig
+	cron *CronSchedule
+	next time.Time
+}
+
+// NewTickScheduler creates a scheduler publishing ticks to pubsub (may be
+// nil); call Start to begin ticking
+func NewTickScheduler(st store.Store, pubsub *PubSub) *TickScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TickScheduler{
+		store:  st,
+		pubsub: pubsub,
+		ticks:  make(map[string]map[string]*tick),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// SetRouter limits ticks to the namespaces this node owns
+func (s *TickScheduler) SetRouter(r *Router) {
+	s.router = r
+}
+
+// Start loads the ticks of all namespaces and begins writing them
+func (s *TickScheduler) Start(ctx context.Context) error {
+	if err := s.reload(ctx); err != nil {
+		return err
+	}
+	s.wg.Add(1)
+	go s.loop()
+	return nil
+}
+
+// Close stops the scheduler and waits for a tick being written
+func (s *TickScheduler) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// reload replaces the scheduled ticks with those in namespace metadata,
+// keeping the next occurrence of unchanged ticks
+func (s *TickScheduler) reload(ctx context.Context) error {
+	namespaces, err := s.store.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	loaded := make(map[string]map[string]*tick, len(namespaces))
+	for _, ns := range namespaces {
+		for _, cfg := range TicksFromMetadata(ns.Metadata) {
+			if err := cfg.Validate(); err != nil {
+				logger.Get().Warn().Err(err).Str("namespace", ns.ID).Str("tick", cfg.Name).Msg("Ignoring invalid tick")
+				continue
+			}
+			t := newTick(cfg, now)
+			if old, ok := s.ticks[ns.ID][cfg.Name]; ok && old.cfg.Cron == cfg.Cron {
+				t.next = old.next
+			}
+			if loaded[ns.ID] == nil {
+				loaded[ns.ID] = make(map[string]*tick)
+			}
+			loaded[ns.ID][cfg.Name] = t
+		}
+	}
+	s.ticks = loaded
+	return nil
+}
+
+// newTick returns the schedule of a validated config
+func newTick(cfg TickConfig, now time.Time) *tick {
+	cron, _ := ParseCron(cfg.Cron)
+	return &tick{cfg: cfg, cron: cron, next: cron.Next(now)}
+}
+
+// Create adds a tick to a namespace and returns it with its first occurrence
+func (s *TickScheduler) Create(ctx context.Context, namespace string, cfg TickConfig) (*TickStatus, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.CreatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var exists, full bool
+	err := updateNamespaceMetadata(ctx, s.store, namespace, func(metadata map[string]interface{}) {
+		ticks := TicksFromMetadata(metadata)
+		for _, t := range ticks {
+			if t.Name == cfg.Name {
+				exists = true
+				return
+			}
+		}
+		if len(ticks) >= tickLimit {
+			full = true
+			return
+		}
+		metadata[ticksMetadataKey] = encodeMetadataValue(tickSet{Ticks: append(ticks, cfg)})
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case exists:
+		return nil, fmt.Errorf("%w: %q", ErrTickExists, cfg.Name)
+	case full:
+		return nil, fmt.Errorf("%w: a namespace can have at most %d ticks", ErrInvalidTick, tickLimit)
+	}
+
+	t := newTick(cfg, time.Now())
+	if s.ticks[namespace] == nil {
+		s.ticks[namespace] = make(map[string]*tick)
+	}
+	s.ticks[namespace][cfg.Name] = t
+	return &TickStatus{TickConfig: cfg, NextAt: t.next.Format(time.RFC3339)}, nil
+}
+
+// Delete removes a tick from a namespace
+func (s *TickScheduler) Delete(ctx context.Context, namespace, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	err := updateNamespaceMetadata(ctx, s.store, namespace, func(metadata map[string]interface{}) {
+		ticks := TicksFromMetadata(metadata)
+		kept := make([]TickConfig, 0, len(ticks))
+		for _, t := range ticks {
+			if t.Name == name {
+				found = true
+				continue
+			}
+			kept = append(kept, t)
+		}
+		if !found {
+			return
+		}
+		if len(kept) == 0 {
+			delete(metadata, ticksMetadataKey)
+		} else {
+			metadata[ticksMetadataKey] = encodeMetadataValue(tickSet{Ticks: kept})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: %q", ErrTickNotFound, name)
+	}
+	delete(s.ticks[namespace], name)
+	return nil
+}
+
+// List returns a namespace's ticks with their next occurrence, by name
+func (s *TickScheduler) List(ctx context.Context, namespace string) ([]TickStatus, error) {
+	ns, err := s.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	ticks := TicksFromMetadata(ns.Metadata)
+	out := make([]TickStatus, 0, len(ticks))
+	for _, cfg := range ticks {
+		cron, err := ParseCron(cfg.Cron)
+		if err != nil {
+			continue
+		}
+		out = append(out, TickStatus{TickConfig: cfg, NextAt: cron.Next(now).Format(time.RFC3339)})
+	}
+	return out, nil
+}
+
+// loop writes due ticks until the scheduler is closed
+func (s *TickScheduler) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	reloaded := time.Now()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		if now.Sub(reloaded) >= tickReloadInterval {
+			if err := s.reload(s.ctx); err != nil && s.ctx.Err() == nil {
+				logger.Get().Warn().Err(err).Msg("Failed to reload ticks")
+			}
+			reloaded = now
+		}
+
+		type dueTick struct {
+			namespace string
+			cfg       TickConfig
+			at        time.Time
+		}
+		var due []dueTick
+		s.mu.Lock()
+		for namespace, ticks := range s.ticks {
+			for _, t := range ticks {
+				if t.next.IsZero() || now.Before(t.next) {
+					continue
+				}
+				at := t.next
+				t.next = t.cron.Next(now)
+				if s.router == nil || s.router.Owner(namespace).ID == s.router.self {
+					due = append(due, dueTick{namespace, t.cfg, at})
+				}
+			}
+		}
+		s.mu.Unlock()
+
+		for _, d := range due {
+			if s.ctx.Err() != nil {
+				return
+			}
+			if err := s.fire(s.ctx, d.namespace, d.cfg, d.at); err != nil && s.ctx.Err() == nil {
+				logger.Get().Warn().Err(err).
+					Str("namespace", d.namespace).
+					Str("tick", d.cfg.Name).
+					Msg("Failed to write tick")
+			}
+		}
+	}
+}
+
+// fire writes one occurrence of a tick unless another node already did.
+// Frozen and suspended namespaces skip their ticks.
+func (s *TickScheduler) fire(ctx context.Context, namespace string, cfg TickConfig, at time.Time) error {
+	ns, err := s.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if FreezeStateFromMetadata(ns.Metadata) != nil || SuspensionStateFromMetadata(ns.Metadata) != nil {
+		return nil
+	}
+
+	scheduledAt := at.UTC().Format(time.RFC3339)
+	data := make(map[string]interface{}, len(cfg.Data)+2)
+	for k, v := range cfg.Data {
+		data[k] = v
+	}
+	data["tick"] = cfg.Name
+	data["scheduledAt"] = scheduledAt
+
+	for attempt := 0; attempt < tickWriteAttempts; attempt++ {
+		version, err := s.store.GetStreamVersion(ctx, namespace, cfg.Stream)
+		if err != nil {
+			return err
+		}
+		written, err := s.written(ctx, namespace, cfg, scheduledAt, version)
+		if err != nil || written {
+			return err
+		}
+
+		expected := version
+		result, err := s.store.WriteMessage(ctx, namespace, cfg.Stream, &store.Message{
+			StreamName:      cfg.Stream,
+			Type:            cfg.Type,
+			Data:            data,
+			ExpectedVersion: &expected,
+		})
+		if store.IsVersionConflict(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if s.pubsub != nil {
+			s.pubsub.Publish(WriteEvent{
+				Namespace:      namespace,
+				Stream:         cfg.Stream,
+				Category:       store.Category(cfg.Stream),
+				Position:       result.Position,
+				GlobalPosition: result.GlobalPosition,
+			})
+		}
+		return nil
+	}
+	return fmt.Errorf("stream %s kept changing while writing the tick", cfg.Stream)
+}
+
+// written reports whether the latest messages of a tick's stream, up to
+// version, hold the occurrence due at scheduledAt
+func (s *TickScheduler) written(ctx context.Context, namespace string, cfg TickConfig, scheduledAt string, version int64) (bool, error) {
+	if version < 0 {
+		return false, nil
+	}
+	msgs, err := s.store.GetStreamMessages(ctx, namespace, cfg.Stream, &store.GetOpts{
+		Position:  max(version-tickRecent+1, 0),
+		BatchSize: tickRecent,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, msg := range msgs {
+		if msg.Type == cfg.Type && msg.Data["tick"] == cfg.Name && msg.Data["scheduledAt"] == scheduledAt {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestTicks tests that tick.create schedules a tick whose occurrences are
+// written once even when several nodes fire them
+func TestTicks(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	if _, rpcErr := h.route(ctx, "tick.create", []interface{}{"daily", map[string]interface{}{"cron": "0 6 * * *", "stream": "clock-daily"}}); rpcErr == nil || rpcErr.Message != ErrTicksDisabled.Error() {
+		t.Fatalf("Expected ticks to be disabled, got %v", rpcErr)
+	}
+
+	ticks := NewTickScheduler(st, nil)
+	h.SetTickScheduler(ticks)
+	result, rpcErr := h.route(ctx, "tick.create", []interface{}{"daily", map[string]interface{}{
+		"cron":   "0 6 * * *",
+		"stream": "clock-daily",
+		"data":   map[string]interface{}{"zone": "eu"},
+	}})
+	if rpcErr != nil {
+		t.Fatalf("tick.create failed: %v", rpcErr.Message)
+	}
+	created := result.(map[string]interface{})
+	if created["type"] != TickDefaultType || created["nextAt"] == "" {
+		t.Errorf("Unexpected tick: %v", created)
+	}
+	if _, rpcErr := h.route(ctx, "tick.create", []interface{}{"daily", map[string]interface{}{"cron": "@hourly", "stream": "clock-daily"}}); rpcErr == nil || rpcErr.Code != "TICK_EXISTS" {
+		t.Errorf("Expected TICK_EXISTS, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "tick.create", []interface{}{"bad", map[string]interface{}{"cron": "61 * * * *", "stream": "clock-bad"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a bad cron, got %v", rpcErr)
+	}
+
+	// A second node firing the same occurrence writes nothing
+	ns, err := st.GetNamespace(ctx, "test-ns")
+	if err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	cfg := TicksFromMetadata(ns.Metadata)[0]
+	at := time.Date(2025, 1, 15, 6, 0, 0, 0, time.UTC)
+	other := NewTickScheduler(st, nil)
+	for _, s := range []*TickScheduler{ticks, other} {
+		if err := s.fire(ctx, "test-ns", cfg, at); err != nil {
+			t.Fatalf("fire failed: %v", err)
+		}
+	}
+	if err := ticks.fire(ctx, "test-ns", cfg, at.Add(24*time.Hour)); err != nil {
+		t.Fatalf("fire failed: %v", err)
+	}
+	msgs, err := st.GetStreamMessages(ctx, "test-ns", "clock-daily", nil)
+	if err != nil {
+		t.Fatalf("Failed to read ticks: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 ticks, got %d", len(msgs))
+	}
+	if msgs[0].Type != TickDefaultType || msgs[0].Data["scheduledAt"] != "2025-01-15T06:00:00Z" || msgs[0].Data["zone"] != "eu" || msgs[0].Data["tick"] != "daily" {
+		t.Errorf("Unexpected tick message: %+v", msgs[0])
+	}
+
+	result, rpcErr = h.route(ctx, "tick.list", nil)
+	if rpcErr != nil {
+		t.Fatalf("tick.list failed: %v", rpcErr.Message)
+	}
+	if list := result.([]interface{}); len(list) != 1 {
+		t.Errorf("Expected 1 tick, got %d", len(list))
+	}
+
+	if _, rpcErr := h.route(ctx, "tick.delete", []interface{}{"daily"}); rpcErr != nil {
+		t.Fatalf("tick.delete failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "tick.delete", []interface{}{"daily"}); rpcErr == nil || rpcErr.Code != "TICK_NOT_FOUND" {
+		t.Errorf("Expected TICK_NOT_FOUND, got %v", rpcErr)
+	}
+}
+
+// TestTicks_Errors tests invalid arguments and the tick limit, that ticks
+// sharing a stream each write their occurrence and that frozen namespaces
+// skip their ticks
+func TestTicks_Errors(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+	ticks := NewTickScheduler(st, nil)
+	h.SetTickScheduler(ticks)
+
+	for _, args := range [][]interface{}{
+		{"daily"},
+		{"", map[string]interface{}{"cron": "@daily", "stream": "clock-daily"}},
+		{"daily", "@daily"},
+		{"daily", map[string]interface{}{"cron": "@daily"}},
+		{"daily", map[string]interface{}{"cron": "@daily", "stream": "clock-daily", "data": "eu"}},
+	} {
+		if _, rpcErr := h.route(ctx, "tick.create", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "tick.delete", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for tick.delete without a name, got %v", rpcErr)
+	}
+	if result, rpcErr := h.route(ctx, "tick.list", nil); rpcErr != nil || len(result.([]interface{})) != 0 {
+		t.Errorf("Expected no ticks, got %v (%v)", result, rpcErr)
+	}
+
+	for i := 0; i < tickLimit; i++ {
+		if _, err := ticks.Create(ctx, "test-ns", TickConfig{Name: fmt.Sprintf("tick-%d", i), Cron: "@daily", Stream: "clock-daily"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	if _, rpcErr := h.route(ctx, "tick.create", []interface{}{"one-more", map[string]interface{}{"cron": "@daily", "stream": "clock-daily"}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST past the tick limit, got %v", rpcErr)
+	}
+
+	// Two ticks sharing a stream each write their occurrence
+	at := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"tick-0", "tick-1"} {
+		cfg := TickConfig{Name: name, Cron: "@daily", Stream: "clock-daily", Type: TickDefaultType}
+		if err := ticks.fire(ctx, "test-ns", cfg, at); err != nil {
+			t.Fatalf("fire failed: %v", err)
+		}
+	}
+	if version, _ := st.GetStreamVersion(ctx, "test-ns", "clock-daily"); version != 1 {
+		t.Errorf("Expected 2 ticks, got version %d", version)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	cfg := TickConfig{Name: "tick-0", Cron: "@daily", Stream: "clock-daily", Type: TickDefaultType}
+	if err := ticks.fire(ctx, "test-ns", cfg, at.Add(24*time.Hour)); err != nil {
+		t.Fatalf("fire failed: %v", err)
+	}
+	if version, _ := st.GetStreamVersion(ctx, "test-ns", "clock-daily"); version != 1 {
+		t.Errorf("Expected no tick in a frozen namespace, got version %d", version)
+	}
+}
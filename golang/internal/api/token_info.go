@@ -0,0 +1,257 @@
+// Package api provides token labels, expiry and introspection.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/auth"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// tokenMetadataKey holds the label and expiry of the namespace's token in
+	// namespace metadata
+	tokenMetadataKey = "token"
+
+	// revokedTokensMetadataKey holds the hashes of replaced tokens, newest first
+	revokedTokensMetadataKey = "revokedTokens"
+
+	// maxRevokedTokens bounds how many replaced tokens are reported as
+	// revoked; older ones are reported as not authorized
+	maxRevokedTokens = 16
+)
+
+var (
+	// errTokenNotAuthorized is returned when a token is not the namespace's current token
+	errTokenNotAuthorized = errors.New("token not authorized for namespace")
+
+	// errTokenExpired is returned when the namespace's token is past its expiry
+	errTokenExpired = errors.New("token expired")
+
+	// errTokenRevoked is returned when a token was replaced by ns.rotateToken
+	errTokenRevoked = errors.New("token revoked")
+)
+
+// TokenInfo describes the current token of a namespace
+type TokenInfo struct {
+	Label     string `json:"label,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"` // RFC 3339, empty if the token does not expire
+}
+
+// TokenInfoFromMetadata returns the token info stored in namespace metadata, or nil
+func TokenInfoFromMetadata(metadata map[string]interface{}) *TokenInfo {
+	raw, ok := metadata[tokenMetadataKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	var info TokenInfo
+	if decodeMetadataValue(raw, &info) != nil {
+		return nil
+	}
+	return &info
+}
+
+// Expired reports whether the token is past its expiry at now
+func (t *TokenInfo) Expired(now time.Time) bool {
+	if t == nil || t.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, t.ExpiresAt)
+	return err == nil && !now.Before(expiresAt)
+}
+
+// revokedTokenHashes returns the hashes of replaced tokens stored in namespace metadata
+func revokedTokenHashes(metadata map[string]interface{}) []string {
+	raw, ok := metadata[revokedTokensMetadataKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	var hashes []string
+	if decodeMetadataValue(raw, &hashes) != nil {
+		return nil
+	}
+	return hashes
+}
+
+// verifyNamespaceToken checks that token is the current, unexpired token of ns
+func verifyNamespaceToken(ns *store.Namespace, token string, now time.Time) error {
+	hash := auth.HashToken(token)
+	if ns.TokenHash != hash {
+		for _, revoked := range revokedTokenHashes(ns.Metadata) {
+			if revoked == hash {
+				return errTokenRevoked
+			}
+		}
+		return errTokenNotAuthorized
+	}
+	if TokenInfoFromMetadata(ns.Metadata).Expired(now) {
+		return errTokenExpired
+	}
+	return nil
+}
+
+// tokenAuthError maps a verifyNamespaceToken error to an HTTP status and RPC
+// error. Expired and revoked tokens are 401, so clients fetch a new token;
+// tokens that were never valid are 403.
+func tokenAuthError(ns *store.Namespace, err error) (int, *RPCError) {
+	switch {
+	case errors.Is(err, errTokenExpired):
+		return http.StatusUnauthorized, &RPCError{
+			Code:    "TOKEN_EXPIRED",
+			Message: "Token expired",
+			Details: map[string]interface{}{
+				"namespace": ns.ID,
+				"expiresAt": TokenInfoFromMetadata(ns.Metadata).ExpiresAt,
+			},
+		}
+	case errors.Is(err, errTokenRevoked):
+		return http.StatusUnauthorized, &RPCError{
+			Code:    "TOKEN_REVOKED",
+			Message: "Token was replaced by a newer token",
+			Details: map[string]interface{}{"namespace": ns.ID},
+		}
+	}
+	return http.StatusForbidden, &RPCError{
+		Code:    "AUTH_UNAUTHORIZED",
+		Message: "Token not authorized for namespace",
+		Details: map[string]interface{}{"namespace": ns.ID},
+	}
+}
+
+// malformedTokenError is the RPC error for a token that cannot be parsed
+func malformedTokenError(err error) *RPCError {
+	return &RPCError{
+		Code:    "TOKEN_MALFORMED",
+		Message: "Invalid token format",
+		Details: map[string]interface{}{"error": err.Error()},
+	}
+}
+
+// parseTokenOptions reads the label and expiresAt options of ns.create and
+// ns.rotateToken. It returns nil if neither is set.
+func parseTokenOptions(opts map[string]interface{}) (*TokenInfo, *RPCError) {
+	var info TokenInfo
+	if v, exists := opts["label"]; exists {
+		label, ok := v.(string)
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options.label must be a string",
+			}
+		}
+		info.Label = label
+	}
+	if v, exists := opts["expiresAt"]; exists {
+		s, ok := v.(string)
+		if !ok {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options.expiresAt must be an RFC 3339 timestamp",
+			}
+		}
+		expiresAt, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("options.expiresAt must be an RFC 3339 timestamp: %v", err),
+			}
+		}
+		if !expiresAt.After(time.Now()) {
+			return nil, &RPCError{
+				Code:    "INVALID_REQUEST",
+				Message: "options.expiresAt must be in the future",
+			}
+		}
+		info.ExpiresAt = expiresAt.UTC().Format(time.RFC3339Nano)
+	}
+	if info == (TokenInfo{}) {
+		return nil, nil
+	}
+	return &info, nil
+}
+
+// setTokenInfo stores the info of a namespace's new token; nil clears it.
+// revokedHash is the hash of the token it replaces, empty for a new namespace.
+func setTokenInfo(ctx context.Context, st store.Store, namespace string, info *TokenInfo, revokedHash string) error {
+	return updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		if info == nil {
+			delete(metadata, tokenMetadataKey)
+		} else {
+			metadata[tokenMetadataKey] = encodeMetadataValue(info)
+		}
+		if revokedHash != "" {
+			revoked := []interface{}{revokedHash}
+			for _, hash := range revokedTokenHashes(metadata) {
+				if len(revoked) == maxRevokedTokens {
+					break
+				}
+				revoked = append(revoked, hash)
+			}
+			metadata[revokedTokensMetadataKey] = revoked
+		}
+	})
+}
+
+// addTokenInfo adds the label and expiry of a token to an RPC result
+func addTokenInfo(result map[string]interface{}, info *TokenInfo) {
+	if info == nil {
+		return
+	}
+	if info.Label != "" {
+		result["label"] = info.Label
+	}
+	if info.ExpiresAt != "" {
+		result["expiresAt"] = info.ExpiresAt
+	}
+}
+
+// handleAuthWhoami describes the presented token
+// Request: ["auth.whoami"]
+// Response: {"namespace": "tenant-a", "scopes": ["read", "write", "admin"], "label": "ci",
+// "expiresAt": "...", "frozen": null, "suspended": null, "testMode": false}
+func (h *RPCHandler) handleAuthWhoami(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Test mode accepts tokens for namespaces that don't exist yet
+	var metadata map[string]interface{}
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	switch {
+	case err == nil:
+		metadata = ns.Metadata
+	case errors.Is(err, store.ErrNamespaceNotFound) && IsTestMode(ctx):
+	case store.IsOverloaded(err):
+		return nil, overloadedError(err)
+	default:
+		return nil, &RPCError{
+			Code:    "BACKEND_ERROR",
+			Message: fmt.Sprintf("Failed to get namespace: %v", err),
+		}
+	}
+
+	// Tokens grant full access to their namespace. Admin methods are
+	// unavailable on the public listener when an admin listener exists.
+	scopes := []string{"read", "write"}
+	if dataPathOnly, _ := ctx.Value(ContextKeyDataPathOnly).(bool); !dataPathOnly {
+		scopes = append(scopes, "admin")
+	}
+
+	result := map[string]interface{}{
+		"namespace": namespace,
+		"scopes":    scopes,
+		"label":     nil,
+		"expiresAt": nil,
+		"frozen":    FreezeStateFromMetadata(metadata),
+		"suspended": SuspensionStateFromMetadata(metadata),
+		"testMode":  IsTestMode(ctx),
+	}
+	addTokenInfo(result, TokenInfoFromMetadata(metadata))
+	return result, nil
+}
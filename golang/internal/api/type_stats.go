@@ -0,0 +1,177 @@
+// Package api provides per-type message counts of a namespace.
+package api
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// typeStatsMetadataKey is the namespace metadata key holding the counts
+	// and the global position they cover
+	typeStatsMetadataKey = "typeStats"
+
+	// typeStatsPage is the number of messages read per call to the store
+	typeStatsPage = 1000
+
+	// typeStatsScanLimit bounds the messages counted by one catch-up, so the
+	// first call on a large namespace returns; later calls continue from there
+	typeStatsScanLimit = 100000
+)
+
+// TypeStat counts the messages of one type written to a namespace
+type TypeStat struct {
+	Count     int64  `json:"count"`
+	FirstSeen string `json:"firstSeen"` // Time of the first message counted
+	LastSeen  string `json:"lastSeen"`  // Time of the last message counted
+}
+
+// TypeStats are the per-type counts of a namespace, covering messages up to
+// Position. Messages are counted once as they are written; redaction,
+// compaction and retention do not lower the counts.
+type TypeStats struct {
+	Position  int64               `json:"position"`
+	UpdatedAt string              `json:"updatedAt,omitempty"`
+	Types     map[string]TypeStat `json:"types"`
+}
+
+// TypeStatsFromMetadata returns the type counts stored in namespace
+// metadata, empty when none were counted yet
+func TypeStatsFromMetadata(metadata map[string]interface{}) *TypeStats {
+	stats := &TypeStats{}
+	if raw, ok := metadata[typeStatsMetadataKey]; ok && raw != nil {
+		decodeMetadataValue(raw, stats)
+	}
+	if stats.Types == nil {
+		stats.Types = make(map[string]TypeStat)
+	}
+	return stats
+}
+
+// UpdateTypeStats counts the messages written to namespace since the stored
+// position, up to typeStatsScanLimit of them, and saves the result. It
+// reports whether the counts reached the end of the namespace. Counting stops
+// before a position a write may still commit, so where commits land out of
+// order (Postgres) a message is never passed over; a later call counts it.
+func UpdateTypeStats(ctx context.Context, st store.Store, namespace string) (*TypeStats, bool, error) {
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, false, err
+	}
+	stats := TypeStatsFromMetadata(ns.Metadata)
+	start := stats.Position
+
+	var scanned int64
+	upToDate := false
+	for scanned < typeStatsScanLimit {
+		msgs, err := st.GetCategoryMessages(ctx, namespace, "", &store.CategoryOpts{
+			Position:  stats.Position + 1,
+			BatchSize: typeStatsPage,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		if len(msgs) == 0 {
+			upToDate = true
+			break
+		}
+
+		// Writes still being committed may appear below messages already
+		// visible; counting stops before them so they are not skipped
+		settled, covered, held, err := settleTypeStatsPage(ctx, st, namespace, stats.Position+1, msgs)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, msg := range settled {
+			stats.add(msg)
+		}
+		stats.Position = covered
+		scanned += int64(len(settled))
+		if held {
+			break
+		}
+		if len(msgs) < typeStatsPage && covered == msgs[len(msgs)-1].GlobalPosition {
+			upToDate = true
+			break
+		}
+	}
+	if stats.Position == start {
+		return stats, upToDate, nil
+	}
+
+	// A concurrent update already counted these messages when the stored
+	// position moved; its counts are kept
+	stats.UpdatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	err = updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		if TypeStatsFromMetadata(metadata).Position == start {
+			metadata[typeStatsMetadataKey] = encodeMetadataValue(stats)
+		}
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return stats, upToDate, nil
+}
+
+// settleTypeStatsPage returns the messages of a page read from from that
+// can be counted, the global position they cover, and whether an in-flight
+// gap held later ones back, found as category reads with waitForGaps find
+// them. Without gaps between its messages the whole page is settled; on
+// backends that commit in order no gap is in flight. At most
+// store.MaxGapRange positions are settled per call.
+func settleTypeStatsPage(ctx context.Context, st store.Store, namespace string, from int64, msgs []*store.Message) ([]*store.Message, int64, bool, error) {
+	last := msgs[len(msgs)-1].GlobalPosition
+	if last-from == int64(len(msgs)-1) {
+		return msgs, last, false, nil
+	}
+
+	to := min(last, from+store.MaxGapRange-1)
+	gaps, err := store.FindPositionGaps(ctx, st, namespace, "", from, to)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	held := false
+	for _, gap := range gaps {
+		if gap.InFlight {
+			to = gap.From - 1
+			held = true
+			break
+		}
+	}
+	n := sort.Search(len(msgs), func(i int) bool { return msgs[i].GlobalPosition > to })
+	return msgs[:n], to, held, nil
+}
+
+// add counts one message
+func (s *TypeStats) add(msg *store.Message) {
+	seen := msg.Time.UTC().Format(time.RFC3339Nano)
+	stat := s.Types[msg.Type]
+	if stat.Count == 0 {
+		stat.FirstSeen = seen
+	}
+	stat.Count++
+	stat.LastSeen = seen
+	s.Types[msg.Type] = stat
+	s.Position = msg.GlobalPosition
+}
+
+// Sorted returns the counted types by name
+func (s *TypeStats) Sorted() []string {
+	types := make([]string, 0, len(s.Types))
+	for t := range s.Types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// resetTypeStats drops the counts of namespace after its messages were
+// replaced, so the next ns.typeStats counts them again
+func resetTypeStats(ctx context.Context, st store.Store, namespace string) error {
+	return updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		delete(metadata, typeStatsMetadataKey)
+	})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestTypeStats tests that ns.typeStats counts messages by type and picks up
+// later writes from its stored position
+func TestTypeStats(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	write := func(msgType string) {
+		t.Helper()
+		if _, err := st.WriteMessage(ctx, "test-ns", "order-1", &store.Message{Type: msgType, Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	write("Placed")
+	write("Placed")
+	write("Shipped")
+
+	h := NewRPCHandler("test", st, NewPubSub())
+	counts := func() map[string]int64 {
+		t.Helper()
+		result, rpcErr := h.route(ctx, "ns.typeStats", nil)
+		if rpcErr != nil {
+			t.Fatalf("ns.typeStats failed: %v", rpcErr.Message)
+		}
+		res := result.(map[string]interface{})
+		if res["upToDate"] != true {
+			t.Errorf("Expected upToDate, got %v", res["upToDate"])
+		}
+		out := make(map[string]int64)
+		for _, entry := range res["types"].([]interface{}) {
+			e := entry.(map[string]interface{})
+			if e["lastSeen"] == "" || e["firstSeen"] == "" {
+				t.Errorf("Expected first and last seen times, got %v", e)
+			}
+			out[e["type"].(string)] = e["count"].(int64)
+		}
+		return out
+	}
+
+	if got := counts(); got["Placed"] != 2 || got["Shipped"] != 1 || len(got) != 2 {
+		t.Fatalf("Unexpected counts: %v", got)
+	}
+
+	// Only the new message is counted on the next call
+	write("Delivered")
+	if got := counts(); got["Placed"] != 2 || got["Delivered"] != 1 || len(got) != 3 {
+		t.Fatalf("Unexpected counts after write: %v", got)
+	}
+
+	// Counts are runtime state and stay out of exported config
+	cfg, err := ExportNamespaceConfig(ctx, st, nil, "test-ns")
+	if err != nil {
+		t.Fatalf("Failed to export config: %v", err)
+	}
+	if _, ok := cfg.Metadata[typeStatsMetadataKey]; ok {
+		t.Errorf("Expected type counts to be left out of exported config")
+	}
+
+	if err := resetTypeStats(ctx, st, "test-ns"); err != nil {
+		t.Fatalf("Failed to reset type counts: %v", err)
+	}
+	if got := counts(); got["Placed"] != 2 || len(got) != 3 {
+		t.Fatalf("Unexpected counts after reset: %v", got)
+	}
+}
+
+// TestTypeStats_InFlightGaps tests that counting stops before positions a
+// write may still commit and goes past them once they are settled
+func TestTypeStats_InFlightGaps(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	// Nothing written yet
+	h := NewRPCHandler("test", st, NewPubSub())
+	result, rpcErr := h.route(ctx, "ns.typeStats", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.typeStats failed: %v", rpcErr.Message)
+	}
+	if res := result.(map[string]interface{}); res["upToDate"] != true || len(res["types"].([]interface{})) != 0 {
+		t.Fatalf("Expected no counts for an empty namespace, got %v", res)
+	}
+
+	for _, stream := range []string{"order-1", "order-1", "order-2"} {
+		if _, err := st.WriteMessage(ctx, "test-ns", stream, &store.Message{Type: "Placed", Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+	}
+	// Global position 1 holds no visible message
+	if _, err := st.(store.StreamTruncater).TruncateStream(ctx, "test-ns", "order-1", 1); err != nil {
+		t.Fatalf("Failed to truncate stream: %v", err)
+	}
+
+	// While a write is in flight the gap may still be filled
+	pending := &pendingWritesStore{Store: st, pending: &store.PendingWrites{Allocated: 3, InFlight: true}}
+	stats, upToDate, err := UpdateTypeStats(ctx, pending, "test-ns")
+	if err != nil {
+		t.Fatalf("Failed to update type counts: %v", err)
+	}
+	if upToDate || stats.Position != 0 || len(stats.Types) != 0 {
+		t.Fatalf("Expected counting to stop before the gap, got %+v (upToDate %v)", stats, upToDate)
+	}
+
+	// Once settled the gap is passed
+	pending.pending = &store.PendingWrites{Allocated: 3}
+	stats, upToDate, err = UpdateTypeStats(ctx, pending, "test-ns")
+	if err != nil {
+		t.Fatalf("Failed to update type counts: %v", err)
+	}
+	if !upToDate || stats.Position != 3 || stats.Types["Placed"].Count != 2 {
+		t.Fatalf("Expected both visible messages counted, got %+v (upToDate %v)", stats, upToDate)
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// udpDevicesMetadataKey is the namespace metadata key holding the devices
+// allowed to send UDP datagrams, with their signing keys
+const udpDevicesMetadataKey = "udpDevices"
+
+// udpDeviceKeySize is the size of a device signing key in bytes
+const udpDeviceKeySize = 32
+
+// udpDeviceIDPattern matches valid device IDs
+var udpDeviceIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// UDPDevice is a device allowed to send signed datagrams to a namespace's
+// UDP ingest. Its key is only returned by ns.udpDevices.add.
+type UDPDevice struct {
+	Key       string    `json:"key"` // Hex-encoded HMAC-SHA256 key
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UDPDevicesFromMetadata returns the UDP devices stored in namespace metadata, by device ID
+func UDPDevicesFromMetadata(metadata map[string]interface{}) map[string]UDPDevice {
+	raw, ok := metadata[udpDevicesMetadataKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	var devices map[string]UDPDevice
+	if decodeMetadataValue(raw, &devices) != nil {
+		return nil
+	}
+	return devices
+}
+
+// handleUDPDevicesAdd implements ns.udpDevices.add
+// Args: [deviceId]
+// Issues a signing key for a device of the caller's namespace and returns it.
+// The key is not shown again; adding an existing device replaces its key.
+func (h *RPCHandler) handleUDPDevicesAdd(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	device, rpcErr := parseUDPDeviceID("ns.udpDevices.add", args)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	key := make([]byte, udpDeviceKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, &RPCError{
+			Code:    "INTERNAL_ERROR",
+			Message: fmt.Sprintf("failed to generate device key: %v", err),
+		}
+	}
+	entry := UDPDevice{Key: hex.EncodeToString(key), CreatedAt: time.Now().UTC()}
+
+	err := updateNamespaceMetadata(ctx, h.store, namespace, func(metadata map[string]interface{}) {
+		devices := UDPDevicesFromMetadata(metadata)
+		if devices == nil {
+			devices = make(map[string]UDPDevice, 1)
+		}
+		devices[device] = entry
+		metadata[udpDevicesMetadataKey] = encodeMetadataValue(devices)
+	})
+	if err != nil {
+		return nil, udpDevicesError(namespace, err)
+	}
+	return map[string]interface{}{
+		"device":    device,
+		"key":       entry.Key,
+		"createdAt": entry.CreatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// handleUDPDevicesList implements ns.udpDevices.list
+// Args: []
+// Returns the UDP devices of the caller's namespace, without their keys.
+func (h *RPCHandler) handleUDPDevicesList(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	ns, err := h.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, udpDevicesError(namespace, err)
+	}
+	devices := UDPDevicesFromMetadata(ns.Metadata)
+	ids := make([]string, 0, len(devices))
+	for id := range devices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	list := make([]interface{}, len(ids))
+	for i, id := range ids {
+		list[i] = map[string]interface{}{
+			"device":    id,
+			"createdAt": devices[id].CreatedAt.Format(time.RFC3339),
+		}
+	}
+	return map[string]interface{}{"devices": list}, nil
+}
+
+// handleUDPDevicesRemove implements ns.udpDevices.remove
+// Args: [deviceId]
+// Revokes a device's key. The UDP listener stops accepting its datagrams
+// within udpAuthCacheTTL.
+func (h *RPCHandler) handleUDPDevicesRemove(ctx context.Context, args []interface{}) (interface{}, *RPCError) {
+	device, rpcErr := parseUDPDeviceID("ns.udpDevices.remove", args)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	// Get namespace from context
+	namespace, rpcErr := h.getNamespace(ctx)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	removed := false
+	err := updateNamespaceMetadata(ctx, h.store, namespace, func(metadata map[string]interface{}) {
+		devices := UDPDevicesFromMetadata(metadata)
+		if _, ok := devices[device]; !ok {
+			return
+		}
+		removed = true
+		delete(devices, device)
+		if len(devices) == 0 {
+			delete(metadata, udpDevicesMetadataKey)
+			return
+		}
+		metadata[udpDevicesMetadataKey] = encodeMetadataValue(devices)
+	})
+	if err != nil {
+		return nil, udpDevicesError(namespace, err)
+	}
+	return map[string]interface{}{"removed": removed}, nil
+}
+
+// parseUDPDeviceID parses the deviceId argument of the ns.udpDevices methods
+func parseUDPDeviceID(method string, args []interface{}) (string, *RPCError) {
+	if len(args) < 1 {
+		return "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: method + " requires 1 argument: deviceId",
+		}
+	}
+	device, _ := args[0].(string)
+	if !udpDeviceIDPattern.MatchString(device) {
+		return "", &RPCError{
+			Code:    "INVALID_REQUEST",
+			Message: "deviceId must be 1-128 letters, digits, '.', '_', ':' or '-'",
+		}
+	}
+	return device, nil
+}
+
+// udpDevicesError maps UDP device errors to RPC errors
+func udpDevicesError(namespace string, err error) *RPCError {
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		return &RPCError{
+			Code:    "NAMESPACE_NOT_FOUND",
+			Message: fmt.Sprintf("Namespace '%s' not found", namespace),
+		}
+	}
+	return &RPCError{
+		Code:    "BACKEND_ERROR",
+		Message: fmt.Sprintf("Failed to update UDP devices: %v", err),
+	}
+}
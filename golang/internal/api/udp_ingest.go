@@ -0,0 +1,472 @@
+// Package api provides a UDP ingest listener for loss-tolerant telemetry events.
+//
+// The listener speaks plain UDP with HMAC-signed JSON datagrams, not QUIC or
+// HTTP/3: the module has no QUIC implementation, and one is not written
+// here. A QUIC or HTTP/3 listener needs github.com/quic-go/quic-go added as a
+// dependency and remains to be done.
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// udpMaxDatagramSize is the largest datagram the listener will read
+	udpMaxDatagramSize = 64 * 1024
+
+	// udpQueueSize bounds the number of messages waiting to be flushed
+	udpQueueSize = 10000
+
+	// udpMaxClockSkew is how far a datagram's ts may be from the server clock
+	udpMaxClockSkew = 30 * time.Second
+
+	// udpReplayWindowSize is how far below a device's highest seq a delayed
+	// datagram is still accepted
+	udpReplayWindowSize = 64
+
+	// udpAuthCacheTTL is how long a namespace's device keys are cached, and
+	// so how long a revoked key keeps working
+	udpAuthCacheTTL = 5 * time.Second
+)
+
+// UDPIngestConfig configures the UDP telemetry ingest listener
+type UDPIngestConfig struct {
+	Addr          string        // Listen address, e.g. ":8081"
+	Namespaces    []string      // Namespaces allowed to ingest over UDP (empty = none)
+	BatchSize     int           // Max messages per flush (default: 500)
+	FlushInterval time.Duration // Max time a message waits before flush (default: 100ms)
+}
+
+// UDPDatagram is the signed JSON body of a single ingest datagram. A
+// datagram is the hex HMAC-SHA256 of the body under the device's key (see
+// ns.udpDevices.add), a newline, and the body. seq must increase per device;
+// a datagram whose (device, seq) was already accepted, or whose ts (Unix
+// milliseconds) is more than udpMaxClockSkew from the server clock, is
+// dropped.
+//
+// Example body:
+//
+//	{"namespace": "tenant-a", "device": "sensor-7", "seq": 42, "ts": 1736935200000,
+//	 "messages": [{"stream": "telemetry-dev1", "type": "Reading", "data": {"t": 21.5}}]}
+type UDPDatagram struct {
+	Namespace string       `json:"namespace"`
+	Device    string       `json:"device"`
+	Seq       uint64       `json:"seq"`
+	Timestamp int64        `json:"ts"`
+	Messages  []UDPMessage `json:"messages"`
+}
+
+// UDPMessage is a single message inside a UDPDatagram
+type UDPMessage struct {
+	Stream   string                 `json:"stream"`
+	Type     string                 `json:"type"`
+	Data     map[string]interface{} `json:"data"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// UDPIngestStats holds counters for the UDP ingest listener
+type UDPIngestStats struct {
+	Received int64 // Datagrams received
+	Written  int64 // Messages written to the store
+	Dropped  int64 // Messages dropped (queue full, invalid, unauthorized, replayed, write failure)
+}
+
+// udpRecord is a queued message with its namespace
+type udpRecord struct {
+	namespace string
+	msg       UDPMessage
+}
+
+// udpDevice identifies a device of a namespace
+type udpDevice struct {
+	namespace string
+	device    string
+}
+
+// udpNamespaceKeys caches the device keys of a namespace
+type udpNamespaceKeys struct {
+	keys   map[string][]byte // Device ID -> key
+	loaded time.Time
+}
+
+// udpReplayWindow tracks the seqs a device has used, as a bitmap of the
+// udpReplayWindowSize seqs up to the highest
+type udpReplayWindow struct {
+	highest uint64
+	seen    uint64 // Bit i is set if seq highest-i was accepted
+	lastTs  int64  // Highest ts accepted, to prune idle devices
+}
+
+// accept records seq and reports whether it was not used before
+func (w *udpReplayWindow) accept(seq uint64) bool {
+	switch {
+	case seq > w.highest:
+		if shift := seq - w.highest; shift < udpReplayWindowSize {
+			w.seen <<= shift
+		} else {
+			w.seen = 0
+		}
+		w.highest = seq
+		w.seen |= 1
+		return true
+	case w.highest-seq >= udpReplayWindowSize:
+		return false
+	}
+	bit := uint64(1) << (w.highest - seq)
+	if w.seen&bit != 0 {
+		return false
+	}
+	w.seen |= bit
+	return true
+}
+
+// UDPIngest receives signed telemetry datagrams and writes them through the
+// stream.write pipeline, one transaction per namespace per flush.
+//
+// Delivery is best-effort: malformed, unsigned, replayed or stale datagrams,
+// and messages arriving while the queue is full, are dropped and counted.
+// Datagrams are authenticated but not encrypted.
+type UDPIngest struct {
+	h       *RPCHandler
+	cfg     UDPIngestConfig
+	allow   map[string]struct{}
+	started time.Time
+	now     func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*udpNamespaceKeys
+	windows   map[udpDevice]*udpReplayWindow
+	lastPrune time.Time
+
+	conn  *net.UDPConn
+	queue chan udpRecord
+	wg    sync.WaitGroup
+
+	received atomic.Int64
+	written  atomic.Int64
+	dropped  atomic.Int64
+}
+
+// NewUDPIngest creates a new UDP ingest listener writing through h
+func NewUDPIngest(h *RPCHandler, cfg UDPIngestConfig) *UDPIngest {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 100 * time.Millisecond
+	}
+
+	allow := make(map[string]struct{}, len(cfg.Namespaces))
+	for _, ns := range cfg.Namespaces {
+		allow[ns] = struct{}{}
+	}
+
+	return &UDPIngest{
+		h:       h,
+		cfg:     cfg,
+		allow:   allow,
+		started: time.Now(),
+		now:     time.Now,
+		keys:    make(map[string]*udpNamespaceKeys),
+		windows: make(map[udpDevice]*udpReplayWindow),
+		queue:   make(chan udpRecord, udpQueueSize),
+	}
+}
+
+// Start binds the UDP socket and starts the reader and flusher goroutines
+func (u *UDPIngest) Start() error {
+	addr, err := net.ResolveUDPAddr("udp", u.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	u.conn = conn
+
+	u.wg.Add(2)
+	go u.readLoop()
+	go u.flushLoop()
+
+	logger.Get().Info().
+		Str("address", conn.LocalAddr().String()).
+		Strs("namespaces", u.cfg.Namespaces).
+		Msg("UDP ingest listener started")
+	return nil
+}
+
+// Addr returns the bound address of the listener
+func (u *UDPIngest) Addr() net.Addr {
+	if u.conn == nil {
+		return nil
+	}
+	return u.conn.LocalAddr()
+}
+
+// Stats returns a snapshot of the ingest counters
+func (u *UDPIngest) Stats() UDPIngestStats {
+	return UDPIngestStats{
+		Received: u.received.Load(),
+		Written:  u.written.Load(),
+		Dropped:  u.dropped.Load(),
+	}
+}
+
+// Close stops the listener and flushes any queued messages
+func (u *UDPIngest) Close() error {
+	if u.conn == nil {
+		return nil
+	}
+	err := u.conn.Close()
+	u.wg.Wait()
+	return err
+}
+
+// readLoop reads datagrams until the socket is closed
+func (u *UDPIngest) readLoop() {
+	defer u.wg.Done()
+	defer close(u.queue)
+
+	buf := make([]byte, udpMaxDatagramSize)
+	for {
+		n, _, err := u.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Get().Warn().Err(err).Msg("UDP ingest read error")
+			continue
+		}
+		u.received.Add(1)
+		u.handleDatagram(buf[:n])
+	}
+}
+
+// handleDatagram verifies a datagram and enqueues its messages
+func (u *UDPIngest) handleDatagram(payload []byte) {
+	sig, body, ok := bytes.Cut(payload, []byte("\n"))
+	var dgram UDPDatagram
+	if !ok || json.Unmarshal(body, &dgram) != nil {
+		u.dropped.Add(1)
+		return
+	}
+
+	if !u.authorize(&dgram, sig, body) {
+		u.dropped.Add(int64(len(dgram.Messages)))
+		return
+	}
+
+	for _, msg := range dgram.Messages {
+		if msg.Stream == "" || msg.Type == "" || msg.Data == nil || u.h.names.Validate(msg.Stream) != nil {
+			u.dropped.Add(1)
+			continue
+		}
+		select {
+		case u.queue <- udpRecord{namespace: dgram.Namespace, msg: msg}:
+		default:
+			// Queue full, drop (telemetry is loss-tolerant)
+			u.dropped.Add(1)
+		}
+	}
+}
+
+// authorize checks that a datagram is signed with its device's key, is
+// recent, and was not accepted before
+func (u *UDPIngest) authorize(dgram *UDPDatagram, sig, body []byte) bool {
+	if _, ok := u.allow[dgram.Namespace]; !ok {
+		return false
+	}
+
+	now := u.now()
+	skew := udpMaxClockSkew.Milliseconds()
+	if dgram.Timestamp < now.UnixMilli()-skew || dgram.Timestamp > now.UnixMilli()+skew {
+		return false
+	}
+	// Windows are lost on restart, so older datagrams could be replayed
+	if dgram.Timestamp < u.started.UnixMilli() {
+		return false
+	}
+
+	key := u.deviceKey(dgram.Namespace, dgram.Device, now)
+	want, err := hex.DecodeString(string(sig))
+	if key == nil || err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), want) {
+		return false
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.pruneWindows(now)
+	id := udpDevice{namespace: dgram.Namespace, device: dgram.Device}
+	w, ok := u.windows[id]
+	if !ok {
+		w = &udpReplayWindow{}
+		u.windows[id] = w
+	}
+	if !w.accept(dgram.Seq) {
+		return false
+	}
+	w.lastTs = max(w.lastTs, dgram.Timestamp)
+	return true
+}
+
+// deviceKey returns a device's key, from a cache refreshed every udpAuthCacheTTL
+func (u *UDPIngest) deviceKey(namespace, device string, now time.Time) []byte {
+	u.mu.Lock()
+	cached, ok := u.keys[namespace]
+	u.mu.Unlock()
+	if ok && now.Sub(cached.loaded) < udpAuthCacheTTL {
+		return cached.keys[device]
+	}
+
+	// A namespace that cannot be read is cached without devices, so junk
+	// datagrams do not reach the store
+	cached = &udpNamespaceKeys{keys: make(map[string][]byte), loaded: now}
+	if ns, err := u.h.store.GetNamespace(context.Background(), namespace); err == nil {
+		for id, d := range UDPDevicesFromMetadata(ns.Metadata) {
+			if key, err := hex.DecodeString(d.Key); err == nil && len(key) == udpDeviceKeySize {
+				cached.keys[id] = key
+			}
+		}
+	}
+	u.mu.Lock()
+	u.keys[namespace] = cached
+	u.mu.Unlock()
+	return cached.keys[device]
+}
+
+// pruneWindows forgets devices idle for longer than udpMaxClockSkew: every
+// datagram they sent is now rejected for its ts. Callers hold u.mu.
+func (u *UDPIngest) pruneWindows(now time.Time) {
+	if now.Sub(u.lastPrune) < udpMaxClockSkew {
+		return
+	}
+	u.lastPrune = now
+	cutoff := now.Add(-udpMaxClockSkew).UnixMilli()
+	for id, w := range u.windows {
+		if w.lastTs < cutoff {
+			delete(u.windows, id)
+		}
+	}
+}
+
+// flushLoop batches queued messages and writes them to the store
+func (u *UDPIngest) flushLoop() {
+	defer u.wg.Done()
+
+	ticker := time.NewTicker(u.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]udpRecord, 0, u.cfg.BatchSize)
+	for {
+		select {
+		case rec, ok := <-u.queue:
+			if !ok {
+				u.flush(batch)
+				return
+			}
+			batch = append(batch, rec)
+			if len(batch) >= u.cfg.BatchSize {
+				u.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				u.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush writes a batch of messages, one transaction per namespace
+func (u *UDPIngest) flush(batch []udpRecord) {
+	groups := make(map[string][]UDPMessage)
+	var order []string
+	for _, rec := range batch {
+		if _, ok := groups[rec.namespace]; !ok {
+			order = append(order, rec.namespace)
+		}
+		groups[rec.namespace] = append(groups[rec.namespace], rec.msg)
+	}
+
+	ctx := context.Background()
+	for _, namespace := range order {
+		msgs := groups[namespace]
+		if rpcErr := u.h.checkWritable(ctx, namespace); rpcErr != nil {
+			u.drop(namespace, msgs, rpcErr)
+			continue
+		}
+		u.write(ctx, namespace, msgs)
+	}
+}
+
+// write writes msgs to a namespace in one transaction. When a message of its
+// own fails the batch, e.g. rejected by a plugin, the others are written one
+// by one.
+func (u *UDPIngest) write(ctx context.Context, namespace string, msgs []UDPMessage) {
+	batch := make([]*store.Message, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = &store.Message{
+			StreamName: msg.Stream,
+			Type:       msg.Type,
+			Data:       copyUDPMap(msg.Data),
+			Metadata:   copyUDPMap(msg.Metadata),
+		}
+	}
+	_, rpcErr := u.h.writeMessages(ctx, namespace, batch, PriorityCDC, false)
+	if rpcErr == nil {
+		u.written.Add(int64(len(msgs)))
+		return
+	}
+
+	switch rpcErr.Code {
+	case "PLUGIN_REJECTED", "INVALID_REQUEST", "READ_ONLY", "BACKEND_ERROR":
+		if len(msgs) > 1 {
+			for i := range msgs {
+				u.write(ctx, namespace, msgs[i:i+1])
+			}
+			return
+		}
+	}
+	u.drop(namespace, msgs, rpcErr)
+}
+
+// drop counts and logs messages that could not be written
+func (u *UDPIngest) drop(namespace string, msgs []UDPMessage, rpcErr *RPCError) {
+	u.dropped.Add(int64(len(msgs)))
+	logger.Get().Warn().
+		Str("namespace", namespace).
+		Int("messages", len(msgs)).
+		Str("code", rpcErr.Code).
+		Str("error", rpcErr.Message).
+		Msg("UDP ingest write failed")
+}
+
+// copyUDPMap copies a message's data or metadata so a retried write starts
+// from what the device sent
+func copyUDPMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	return copyMetadata(m)
+}
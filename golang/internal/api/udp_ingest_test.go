@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestUDPIngest tests that signed datagrams are written through the
+// stream.write pipeline and that replayed datagrams and revoked devices are
+// dropped
+func TestUDPIngest(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	// A renamed stream and a derived stream rule
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"reading-old", map[string]interface{}{"type": "Reading", "data": map[string]interface{}{}}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.rename", []interface{}{"reading-old", "reading-1"}); rpcErr != nil {
+		t.Fatalf("stream.rename failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "ns.derivedStreams.set", []interface{}{map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{
+			"category": "reading",
+			"stream":   "latest-{id}",
+			"type":     "Latest{type}",
+			"fields":   []interface{}{"t"},
+		}},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.derivedStreams.set failed: %v", rpcErr.Message)
+	}
+
+	if _, rpcErr := h.route(ctx, "ns.udpDevices.add", []interface{}{"bad/id"}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an invalid device ID, got %v", rpcErr)
+	}
+	result, rpcErr := h.route(ctx, "ns.udpDevices.add", []interface{}{"sensor-1"})
+	if rpcErr != nil {
+		t.Fatalf("ns.udpDevices.add failed: %v", rpcErr.Message)
+	}
+	key, err := hex.DecodeString(result.(map[string]interface{})["key"].(string))
+	if err != nil || len(key) != udpDeviceKeySize {
+		t.Fatalf("Expected a %d-byte key, got %v", udpDeviceKeySize, result)
+	}
+	result, rpcErr = h.route(ctx, "ns.udpDevices.list", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.udpDevices.list failed: %v", rpcErr.Message)
+	}
+	devices := result.(map[string]interface{})["devices"].([]interface{})
+	if len(devices) != 1 || devices[0].(map[string]interface{})["key"] != nil {
+		t.Errorf("Expected one device without its key, got %v", devices)
+	}
+
+	u := NewUDPIngest(h, UDPIngestConfig{Namespaces: []string{"test-ns"}})
+	now := time.Now()
+	u.now = func() time.Time { return now }
+	send := func(seq uint64, signingKey []byte) {
+		t.Helper()
+		body, err := json.Marshal(UDPDatagram{
+			Namespace: "test-ns",
+			Device:    "sensor-1",
+			Seq:       seq,
+			Timestamp: now.UnixMilli(),
+			Messages: []UDPMessage{
+				{Stream: "reading-old", Type: "Reading", Data: map[string]interface{}{"t": 21.5}},
+				{Stream: "reading-2", Type: "Reading", Data: map[string]interface{}{"t": 19.0}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal datagram: %v", err)
+		}
+		mac := hmac.New(sha256.New, signingKey)
+		mac.Write(body)
+		u.handleDatagram(append([]byte(hex.EncodeToString(mac.Sum(nil))+"\n"), body...))
+
+		var batch []udpRecord
+		for len(u.queue) > 0 {
+			batch = append(batch, <-u.queue)
+		}
+		u.flush(batch)
+	}
+
+	send(100, key)
+	if stats := u.Stats(); stats.Written != 2 || stats.Dropped != 0 {
+		t.Fatalf("Expected 2 written, got %+v", stats)
+	}
+	for stream, want := range map[string]int64{"reading-1": 1, "reading-old": -1, "latest-1": 0, "latest-2": 0} {
+		if version, err := st.GetStreamVersion(ctx, "test-ns", stream); err != nil || version != want {
+			t.Errorf("Expected %s at version %d, got %d (%v)", stream, want, version, err)
+		}
+	}
+
+	// Replays, seqs below the window and other keys are dropped; a late seq
+	// inside the window is not
+	send(100, key)
+	send(100-udpReplayWindowSize, key)
+	send(101, []byte("0123456789abcdef0123456789abcdef"))
+	if stats := u.Stats(); stats.Written != 2 || stats.Dropped != 6 {
+		t.Errorf("Expected 6 dropped, got %+v", stats)
+	}
+	send(99, key)
+	if stats := u.Stats(); stats.Written != 4 {
+		t.Errorf("Expected a late seq inside the window to be written, got %+v", stats)
+	}
+
+	// Removed devices are dropped once the cached keys expire
+	result, rpcErr = h.route(ctx, "ns.udpDevices.remove", []interface{}{"sensor-1"})
+	if rpcErr != nil || result.(map[string]interface{})["removed"] != true {
+		t.Fatalf("ns.udpDevices.remove failed: %v %v", result, rpcErr)
+	}
+	now = now.Add(udpAuthCacheTTL)
+	send(102, key)
+	if stats := u.Stats(); stats.Written != 4 || stats.Dropped != 8 {
+		t.Errorf("Expected the removed device to be dropped, got %+v", stats)
+	}
+}
+
+// TestUDPIngest_Errors tests invalid device IDs, the replay window, datagrams
+// dropped before they are queued, and writes to frozen namespaces
+func TestUDPIngest_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	for _, args := range [][]interface{}{
+		{},
+		{float64(1)},
+		{""},
+		{strings.Repeat("d", 129)},
+	} {
+		if _, rpcErr := h.route(ctx, "ns.udpDevices.add", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+	if result, rpcErr := h.route(ctx, "ns.udpDevices.remove", []interface{}{"sensor-9"}); rpcErr != nil || result.(map[string]interface{})["removed"] != false {
+		t.Errorf("Expected removing an unknown device to report false, got %v %v", result, rpcErr)
+	}
+	missing := context.WithValue(context.Background(), ContextKeyNamespace, "missing-ns")
+	if _, rpcErr := h.route(missing, "ns.udpDevices.add", []interface{}{"sensor-1"}); rpcErr == nil || rpcErr.Code != "NAMESPACE_NOT_FOUND" {
+		t.Errorf("Expected NAMESPACE_NOT_FOUND, got %v", rpcErr)
+	}
+
+	// Seqs are accepted once, in any order within the window
+	var w udpReplayWindow
+	for _, tt := range []struct {
+		seq  uint64
+		want bool
+	}{
+		{5, true}, {5, false}, {3, true}, {3, false},
+		{100, true}, {100 - udpReplayWindowSize + 1, true}, {100 - udpReplayWindowSize, false}, {5, false},
+	} {
+		if got := w.accept(tt.seq); got != tt.want {
+			t.Errorf("accept(%d) = %v, want %v", tt.seq, got, tt.want)
+		}
+	}
+
+	result, rpcErr := h.route(ctx, "ns.udpDevices.add", []interface{}{"sensor-1"})
+	if rpcErr != nil {
+		t.Fatalf("ns.udpDevices.add failed: %v", rpcErr.Message)
+	}
+	key, _ := hex.DecodeString(result.(map[string]interface{})["key"].(string))
+
+	u := NewUDPIngest(h, UDPIngestConfig{Namespaces: []string{"test-ns"}})
+	now := time.Now()
+	u.started = now
+	u.now = func() time.Time { return now }
+	sign := func(dgram UDPDatagram) []byte {
+		t.Helper()
+		body, err := json.Marshal(dgram)
+		if err != nil {
+			t.Fatalf("Failed to marshal datagram: %v", err)
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(body)
+		return append([]byte(hex.EncodeToString(mac.Sum(nil))+"\n"), body...)
+	}
+	reading := UDPMessage{Stream: "reading-1", Type: "Reading", Data: map[string]interface{}{"t": 21.5}}
+	datagram := func(seq uint64, ts time.Time, msgs ...UDPMessage) UDPDatagram {
+		return UDPDatagram{Namespace: "test-ns", Device: "sensor-1", Seq: seq, Timestamp: ts.UnixMilli(), Messages: msgs}
+	}
+	flush := func() {
+		var batch []udpRecord
+		for len(u.queue) > 0 {
+			batch = append(batch, <-u.queue)
+		}
+		u.flush(batch)
+	}
+
+	other := datagram(1, now, reading, reading)
+	other.Namespace = "other-ns"
+	unknown := datagram(2, now, reading)
+	unknown.Device = "sensor-2"
+	badSig := sign(datagram(3, now, reading))
+	badSig[0] = 'x'
+	for _, payload := range [][]byte{
+		[]byte("no newline"),
+		[]byte("00\n{not json"),
+		sign(other),
+		sign(datagram(4, now.Add(-udpMaxClockSkew-time.Second), reading)),
+		sign(datagram(5, now.Add(udpMaxClockSkew+time.Second), reading)),
+		sign(datagram(6, now.Add(-time.Second), reading)), // Sent before the listener started
+		sign(unknown),
+		badSig,
+	} {
+		u.handleDatagram(payload)
+	}
+	if stats := u.Stats(); stats.Dropped != 9 || len(u.queue) != 0 {
+		t.Fatalf("Expected 9 dropped and nothing queued, got %+v with %d queued", stats, len(u.queue))
+	}
+
+	// Invalid messages are dropped one by one
+	u.handleDatagram(sign(datagram(10, now,
+		reading,
+		UDPMessage{Type: "Reading", Data: map[string]interface{}{}},
+		UDPMessage{Stream: "reading-1", Data: map[string]interface{}{}},
+		UDPMessage{Stream: "reading-1", Type: "Reading"},
+	)))
+	flush()
+	if stats := u.Stats(); stats.Written != 1 || stats.Dropped != 12 {
+		t.Errorf("Expected 1 written and 12 dropped, got %+v", stats)
+	}
+
+	// Frozen namespaces drop queued messages
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	u.handleDatagram(sign(datagram(11, now, reading)))
+	flush()
+	if stats := u.Stats(); stats.Written != 1 || stats.Dropped != 13 {
+		t.Errorf("Expected the frozen namespace's message to be dropped, got %+v", stats)
+	}
+	if version, err := st.GetStreamVersion(ctx, "test-ns", "reading-1"); err != nil || version != 0 {
+		t.Errorf("Expected reading-1 at version 0, got %d (%v)", version, err)
+	}
+}
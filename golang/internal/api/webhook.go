@@ -0,0 +1,453 @@
+// Package api provides a signed webhook publisher for category events.
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	// webhookDefaultAttempts is the number of delivery attempts before dead-lettering
+	webhookDefaultAttempts = 5
+
+	// webhookInitialBackoff is the delay before the first retry (doubles each attempt)
+	webhookInitialBackoff = 250 * time.Millisecond
+
+	// webhookTimeout bounds a single delivery attempt
+	webhookTimeout = 10 * time.Second
+)
+
+// Webhook delivery headers
+const (
+	WebhookHeaderDelivery  = "X-Eventodb-Delivery"
+	WebhookHeaderTimestamp = "X-Eventodb-Timestamp"
+	WebhookHeaderSignature = "X-Eventodb-Signature"
+	WebhookHeaderMessageID = "X-Eventodb-Message-Id"
+)
+
+var (
+	// ErrHookNotFound is returned when a webhook name is not configured for a namespace
+	ErrHookNotFound = errors.New("webhook not found")
+
+	// ErrDeliveryNotFound is returned when no failed delivery exists at a dead-letter position
+	ErrDeliveryNotFound = errors.New("failed delivery not found")
+)
+
+// WebhookConfig configures the webhook publisher
+//
+// Example config file:
+//
+//	{
+//	  "hooks": [
+//	    {"name": "billing", "namespace": "default", "category": "invoice",
+//	     "url": "https://billing.example.com/hooks/eventodb", "secrets": ["whsec_new", "whsec_old"]}
+//	  ]
+//	}
+type WebhookConfig struct {
+	Hooks []WebhookRoute `json:"hooks"`
+}
+
+// WebhookRoute delivers one category of a namespace to a URL.
+//
+// Every secret in Secrets produces a v1 signature, so a receiver can roll to a
+// new secret while the old one is still listed. Deliveries that fail
+// MaxAttempts times are written to the dead-letter stream webhookDlq-{name}
+// and can be replayed with hook.redeliver.
+type WebhookRoute struct {
+	Name        string   `json:"name"`                  // Unique hook name
+	Namespace   string   `json:"namespace"`             // Source namespace
+	Category    string   `json:"category"`              // Source category
+	URL         string   `json:"url"`                   // Delivery URL (POST)
+	Secrets     []string `json:"secrets"`               // Signing secrets, newest first
+	MaxAttempts int      `json:"maxAttempts,omitempty"` // Attempts before dead-lettering (default: 5)
+}
+
+// LoadWebhookConfig reads a webhook config from a JSON file
+func LoadWebhookConfig(path string) (*WebhookConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook config: %w", err)
+	}
+
+	var cfg WebhookConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// WebhookPublisher tails categories and POSTs signed messages to webhook URLs
+type WebhookPublisher struct {
+	store    store.Store
+	pubsub   *PubSub
+	client   *http.Client
+	notifier *Notifier
+	jobs     *JobScheduler
+
+	mu      sync.Mutex
+	cfg     WebhookConfig
+	started bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewWebhookPublisher creates a new webhook publisher after validating its hooks
+func NewWebhookPublisher(st store.Store, pubsub *PubSub, cfg WebhookConfig) (*WebhookPublisher, error) {
+	if err := validateWebhookRoutes(cfg.Hooks, nil); err != nil {
+		return nil, err
+	}
+
+	return &WebhookPublisher{
+		store:  st,
+		pubsub: pubsub,
+		cfg:    cfg,
+		client: &http.Client{Timeout: webhookTimeout},
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+// validateWebhookRoutes checks hooks and fills in their defaults. Names are
+// unique within a namespace, across hooks and the existing ones.
+func validateWebhookRoutes(hooks, existing []WebhookRoute) error {
+	names := make(map[string]bool, len(hooks)+len(existing))
+	for _, hook := range existing {
+		names[hook.Namespace+"/"+hook.Name] = true
+	}
+	for i := range hooks {
+		hook := &hooks[i]
+		if hook.Name == "" || hook.Namespace == "" || hook.Category == "" || hook.URL == "" {
+			return fmt.Errorf("hook %d: name, namespace, category and url are required", i)
+		}
+		if len(hook.Secrets) == 0 {
+			return fmt.Errorf("hook %d: at least one signing secret is required", i)
+		}
+		if names[hook.Namespace+"/"+hook.Name] {
+			return fmt.Errorf("hook %d: duplicate name %s", i, hook.Name)
+		}
+		names[hook.Namespace+"/"+hook.Name] = true
+		if hook.MaxAttempts <= 0 {
+			hook.MaxAttempts = webhookDefaultAttempts
+		}
+	}
+	return nil
+}
+
+// SetNotifier sets the notifier that receives dead-letter events (call before Start)
+func (p *WebhookPublisher) SetNotifier(n *Notifier) {
+	p.notifier = n
+}
+
+// SetJobScheduler runs each delivery, with its retries, as a job of s (call
+// before Start)
+func (p *WebhookPublisher) SetJobScheduler(s *JobScheduler) {
+	p.jobs = s
+}
+
+// Start starts one worker per hook
+func (p *WebhookPublisher) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.started = true
+	for _, hook := range p.cfg.Hooks {
+		p.wg.Add(1)
+		go p.run(hook)
+	}
+
+	logger.Get().Info().
+		Int("hooks", len(p.cfg.Hooks)).
+		Msg("Webhook publisher started")
+}
+
+// AddHooks validates hooks and adds them, starting their workers if the
+// publisher is running
+func (p *WebhookPublisher) AddHooks(hooks []WebhookRoute) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := validateWebhookRoutes(hooks, p.cfg.Hooks); err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		p.cfg.Hooks = append(p.cfg.Hooks, hook)
+		if p.started {
+			p.wg.Add(1)
+			go p.run(hook)
+		}
+	}
+	return nil
+}
+
+// Hooks returns the hooks of a namespace
+func (p *WebhookPublisher) Hooks(namespace string) []WebhookRoute {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var hooks []WebhookRoute
+	for _, hook := range p.cfg.Hooks {
+		if hook.Namespace == namespace {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks
+}
+
+// Close stops all workers
+func (p *WebhookPublisher) Close() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// run tails a single hook's category until the publisher is stopped
+func (p *WebhookPublisher) run(hook WebhookRoute) {
+	defer p.wg.Done()
+
+	tailer := &categoryTailer{
+		name:           "webhook:" + hook.Name,
+		store:          p.store,
+		pubsub:         p.pubsub,
+		namespace:      hook.Namespace,
+		category:       hook.Category,
+		positionStream: "webhook:position-" + hook.Name,
+		handle: func(ctx context.Context, msg *store.Message) error {
+			return p.jobs.Run(ctx, hook.Namespace, JobKindWebhook, func(ctx context.Context) error {
+				return p.handle(ctx, hook, msg)
+			})
+		},
+		notifier: p.notifier,
+		stop:     p.stop,
+	}
+	tailer.run()
+}
+
+// handle delivers a message with retries, dead-lettering it if all attempts fail
+func (p *WebhookPublisher) handle(ctx context.Context, hook WebhookRoute, msg *store.Message) error {
+	body, err := EncodeEnvelope(EnvelopeEventoDB, hook.Namespace, msg)
+	if err != nil {
+		return err
+	}
+
+	deliveryID := uuid.NewString()
+	backoff := webhookInitialBackoff
+
+	var lastErr error
+	for attempt := 1; attempt <= hook.MaxAttempts; attempt++ {
+		if lastErr = p.deliver(ctx, hook, msg.ID, deliveryID, body); lastErr == nil {
+			return nil
+		}
+		if attempt == hook.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-p.stop:
+			// Shutting down: leave the message for the next run
+			return lastErr
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	logger.Get().Warn().
+		Err(lastErr).
+		Str("hook", hook.Name).
+		Str("message_id", msg.ID).
+		Msg("Webhook delivery failed, dead-lettering")
+
+	p.notifier.Notify(SystemEvent{
+		Type:      SystemEventWebhookDeadLettered,
+		Severity:  "warning",
+		Namespace: hook.Namespace,
+		Subject:   hook.Name,
+		Message:   fmt.Sprintf("Delivery of %s failed after %d attempts: %v", msg.ID, hook.MaxAttempts, lastErr),
+		Data:      map[string]interface{}{"stream": msg.StreamName, "globalPosition": msg.GlobalPosition},
+	})
+
+	dlqStream := webhookDLQStream(hook.Name)
+	_, err = p.store.WriteMessage(ctx, hook.Namespace, dlqStream, &store.Message{
+		StreamName: dlqStream,
+		Type:       "DeliveryFailed",
+		Data: map[string]interface{}{
+			"deliveryId":     deliveryID,
+			"messageId":      msg.ID,
+			"stream":         msg.StreamName,
+			"globalPosition": msg.GlobalPosition,
+			"payload":        string(body),
+			"attempts":       hook.MaxAttempts,
+			"error":          lastErr.Error(),
+		},
+	})
+	return err
+}
+
+// deliver POSTs a signed payload once, succeeding on any 2xx response
+func (p *WebhookPublisher) deliver(ctx context.Context, hook WebhookRoute, messageID, deliveryID string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderDelivery, deliveryID)
+	req.Header.Set(WebhookHeaderMessageID, messageID)
+	req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(hook.Secrets, timestamp, body))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Redeliver replays a dead-lettered delivery once and records the outcome.
+//
+// The replay gets a new delivery ID and a fresh timestamp and signature.
+func (p *WebhookPublisher) Redeliver(ctx context.Context, namespace, hookName string, dlqPosition int64) (map[string]interface{}, error) {
+	hook, ok := p.findHook(namespace, hookName)
+	if !ok {
+		return nil, ErrHookNotFound
+	}
+
+	dlqStream := webhookDLQStream(hook.Name)
+	msgs, err := p.store.GetStreamMessages(ctx, namespace, dlqStream, &store.GetOpts{
+		Position:  dlqPosition,
+		BatchSize: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 || msgs[0].Position != dlqPosition || msgs[0].Type != "DeliveryFailed" {
+		return nil, fmt.Errorf("%w: position %d of %s", ErrDeliveryNotFound, dlqPosition, dlqStream)
+	}
+
+	failed := msgs[0]
+	payload, _ := failed.Data["payload"].(string)
+	messageID, _ := failed.Data["messageId"].(string)
+
+	deliveryID := uuid.NewString()
+	result := map[string]interface{}{
+		"deliveryId": deliveryID,
+		"messageId":  messageID,
+		"delivered":  true,
+	}
+
+	eventType := "Redelivered"
+	eventData := map[string]interface{}{
+		"deliveryId": deliveryID,
+		"messageId":  messageID,
+		"position":   dlqPosition,
+	}
+	if err := p.deliver(ctx, hook, messageID, deliveryID, []byte(payload)); err != nil {
+		result["delivered"] = false
+		result["error"] = err.Error()
+		eventType = "RedeliveryFailed"
+		eventData["error"] = err.Error()
+	}
+
+	if _, err := p.store.WriteMessage(ctx, namespace, dlqStream, &store.Message{
+		StreamName: dlqStream,
+		Type:       eventType,
+		Data:       eventData,
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// findHook returns the hook with the given name in a namespace
+func (p *WebhookPublisher) findHook(namespace, name string) (WebhookRoute, bool) {
+	for _, hook := range p.Hooks(namespace) {
+		if hook.Name == name && hook.Namespace == namespace {
+			return hook, true
+		}
+	}
+	return WebhookRoute{}, false
+}
+
+// webhookDLQStream returns the dead-letter stream for a hook
+func webhookDLQStream(hookName string) string {
+	return "webhookDlq-" + hookName
+}
+
+// SignWebhook returns the signature header value for a payload.
+//
+// Format: t=<unix seconds>,v1=<hex hmac>[,v1=<hex hmac>...] where each HMAC is
+// SHA-256 over "<timestamp>.<body>" with one of the secrets.
+func SignWebhook(secrets []string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	parts := []string{"t=" + ts}
+	for _, secret := range secrets {
+		parts = append(parts, "v1="+webhookHMAC(secret, ts, body))
+	}
+	return strings.Join(parts, ",")
+}
+
+// VerifyWebhook checks a signature header against a secret and rejects
+// timestamps older than tolerance to prevent replays.
+func VerifyWebhook(header string, body []byte, secret string, tolerance time.Duration, now time.Time) error {
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid timestamp")
+	}
+	if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("timestamp outside tolerance")
+	}
+
+	expected := webhookHMAC(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no matching signature")
+}
+
+// webhookHMAC computes the hex HMAC-SHA256 of "<timestamp>.<body>"
+func webhookHMAC(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
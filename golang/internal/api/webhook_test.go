@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/eventodb/eventodb/internal/store/sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// TestSignWebhook_VerifyRoundTrip verifies signing, secret rotation, and replay protection
+func TestSignWebhook_VerifyRoundTrip(t *testing.T) {
+	body := []byte(`{"type":"Placed"}`)
+	now := time.Unix(1700000000, 0)
+
+	header := SignWebhook([]string{"new-secret", "old-secret"}, now.Unix(), body)
+
+	// Both the new and the old secret verify during rotation
+	if err := VerifyWebhook(header, body, "new-secret", 5*time.Minute, now); err != nil {
+		t.Errorf("Expected new secret to verify: %v", err)
+	}
+	if err := VerifyWebhook(header, body, "old-secret", 5*time.Minute, now); err != nil {
+		t.Errorf("Expected old secret to verify: %v", err)
+	}
+
+	if err := VerifyWebhook(header, body, "other-secret", 5*time.Minute, now); err == nil {
+		t.Error("Expected unknown secret to fail")
+	}
+	if err := VerifyWebhook(header, []byte(`{"type":"Tampered"}`), "new-secret", 5*time.Minute, now); err == nil {
+		t.Error("Expected tampered body to fail")
+	}
+	if err := VerifyWebhook(header, body, "new-secret", 5*time.Minute, now.Add(10*time.Minute)); err == nil {
+		t.Error("Expected stale timestamp to fail")
+	}
+}
+
+// TestWebhookPublisher_DeadLetterAndRedeliver verifies failed deliveries are dead-lettered and replayable
+func TestWebhookPublisher_DeadLetterAndRedeliver(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	st, err := sqlite.New(db, &sqlite.Config{TestMode: true})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateNamespace(ctx, "test-ns", "token-hash", "Test namespace"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	// Receiver fails until told otherwise and records verified deliveries
+	var healthy atomic.Bool
+	var mu sync.Mutex
+	var deliveries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := VerifyWebhook(r.Header.Get(WebhookHeaderSignature), body, "secret", time.Minute, time.Now()); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		mu.Lock()
+		deliveries = append(deliveries, r.Header.Get(WebhookHeaderDelivery))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	publisher, err := NewWebhookPublisher(st, NewPubSub(), WebhookConfig{Hooks: []WebhookRoute{
+		{Name: "billing", Namespace: "test-ns", Category: "invoice", URL: server.URL, Secrets: []string{"secret"}, MaxAttempts: 1},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create publisher: %v", err)
+	}
+
+	if _, err := st.WriteMessage(ctx, "test-ns", "invoice-1", &store.Message{
+		StreamName: "invoice-1",
+		Type:       "Issued",
+		Data:       map[string]interface{}{"amount": 10},
+	}); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+
+	publisher.Start()
+
+	// Wait for the delivery to be dead-lettered
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		msgs, _ := st.GetStreamMessages(ctx, "test-ns", "webhookDlq-billing", &store.GetOpts{BatchSize: 10})
+		if len(msgs) == 1 && msgs[0].Type == "DeliveryFailed" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for dead-lettered delivery")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	publisher.Close()
+
+	// Replay via RPC once the receiver has recovered
+	healthy.Store(true)
+	h := NewRPCHandler("test", st, NewPubSub())
+	h.SetWebhookPublisher(publisher)
+	rpcCtx := context.WithValue(ctx, ContextKeyNamespace, "test-ns")
+
+	result, rpcErr := h.route(rpcCtx, "hook.redeliver", []interface{}{"billing", float64(0)})
+	if rpcErr != nil {
+		t.Fatalf("hook.redeliver failed: %v", rpcErr.Message)
+	}
+	res := result.(map[string]interface{})
+	if res["delivered"] != true {
+		t.Fatalf("Expected delivered=true, got %v", res)
+	}
+
+	mu.Lock()
+	if len(deliveries) != 1 || deliveries[0] != res["deliveryId"] {
+		t.Errorf("Expected one delivery with id %v, got %v", res["deliveryId"], deliveries)
+	}
+	mu.Unlock()
+
+	msgs, _ := st.GetStreamMessages(ctx, "test-ns", "webhookDlq-billing", &store.GetOpts{BatchSize: 10})
+	if len(msgs) != 2 || msgs[1].Type != "Redelivered" {
+		t.Errorf("Expected Redelivered entry in dead-letter stream, got %d messages", len(msgs))
+	}
+
+	// Unknown hooks and positions are rejected
+	if _, rpcErr := h.route(rpcCtx, "hook.redeliver", []interface{}{"missing", float64(0)}); rpcErr == nil || rpcErr.Code != "HOOK_NOT_FOUND" {
+		t.Errorf("Expected HOOK_NOT_FOUND, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(rpcCtx, "hook.redeliver", []interface{}{"billing", float64(1)}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for non-failure position, got %v", rpcErr)
+	}
+
+	// Hooks are scoped to their namespace
+	otherCtx := context.WithValue(ctx, ContextKeyNamespace, "other-ns")
+	if _, rpcErr := h.route(otherCtx, "hook.redeliver", []interface{}{"billing", float64(0)}); rpcErr == nil || rpcErr.Code != "HOOK_NOT_FOUND" {
+		t.Errorf("Expected HOOK_NOT_FOUND from another namespace, got %v", rpcErr)
+	}
+}
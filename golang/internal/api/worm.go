@@ -0,0 +1,417 @@
+// Package api provides WORM mode and signed attestations of namespace logs.
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+const (
+	// Namespace metadata keys holding WORM mode and the latest attestation
+	wormMetadataKey            = "worm"
+	wormAttestationMetadataKey = "wormAttestation"
+
+	// ConfigAuditStream records configuration changes of WORM namespaces
+	ConfigAuditStream = "eventodb:audit-config"
+
+	// AttestationStream records the attestations of a WORM namespace
+	AttestationStream = "eventodb:audit-attestation"
+
+	// attestationBatch is the number of messages hashed per read
+	attestationBatch = 1000
+)
+
+var (
+	// ErrWormProtected is returned for operations that would delete or change
+	// messages of a namespace in WORM mode
+	ErrWormProtected = errors.New("namespace is in WORM mode: messages cannot be deleted or changed")
+
+	// ErrNotWorm is returned when attesting a namespace that is not in WORM mode
+	ErrNotWorm = errors.New("namespace is not in WORM mode")
+
+	// ErrAttestationDisabled is returned when the server has no attestation key
+	ErrAttestationDisabled = errors.New("attestations are not enabled (start the server with --attestation-key)")
+)
+
+// wormGenesisHash is the head hash of a namespace with no messages
+var wormGenesisHash = strings.Repeat("0", 64)
+
+// wormUnrecordedMetadataKeys hold progress rather than configuration, so
+// their changes are not recorded in ConfigAuditStream
+var wormUnrecordedMetadataKeys = map[string]bool{
+	logShippingStatusMetadataKey: true,
+	mirrorStatusMetadataKey:      true,
+	edgeSyncStatusMetadataKey:    true,
+	wormAttestationMetadataKey:   true,
+	typeStatsMetadataKey:         true,
+}
+
+// wormSecretMetadataKeys hold credentials; only a hash of their new value is recorded
+var wormSecretMetadataKeys = map[string]bool{
+	logShippingMetadataKey:   true,
+	mirrorMetadataKey:        true,
+	edgeSyncMetadataKey:      true,
+	tokenMetadataKey:         true,
+	revokedTokensMetadataKey: true,
+	udpDevicesMetadataKey:    true,
+}
+
+// WormState describes a namespace in WORM (write once, read many) mode.
+// Messages can be appended but never deleted, truncated or redacted, and
+// every configuration change is recorded as an event. WORM mode cannot be
+// turned off.
+type WormState struct {
+	EnabledAt string `json:"enabledAt"`
+}
+
+// WormFromMetadata returns the WORM state stored in namespace metadata, or nil
+func WormFromMetadata(metadata map[string]interface{}) *WormState {
+	raw, ok := metadata[wormMetadataKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	var state WormState
+	if decodeMetadataValue(raw, &state) != nil {
+		return nil
+	}
+	return &state
+}
+
+// CheckWorm returns ErrWormProtected if namespace is in WORM mode. It is
+// called before every operation that deletes or changes stored messages.
+func CheckWorm(ctx context.Context, st store.Store, namespace string) error {
+	ns, err := st.GetNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if WormFromMetadata(ns.Metadata) != nil {
+		return ErrWormProtected
+	}
+	return nil
+}
+
+// EnableWorm puts a namespace in WORM mode. Enabling it again keeps the
+// original state. Enabling fails while retention rules would delete messages.
+func EnableWorm(ctx context.Context, st store.Store, namespace string) (*WormState, error) {
+	var state *WormState
+	var conflict error
+	err := updateNamespaceMetadata(ctx, st, namespace, func(metadata map[string]interface{}) {
+		if state = WormFromMetadata(metadata); state != nil {
+			return
+		}
+		if len(RetentionFromMetadata(metadata).Rules) > 0 {
+			conflict = fmt.Errorf("%w: retention rules would delete messages, remove them first", ErrInvalidNamespaceConfig)
+			return
+		}
+		state = &WormState{EnabledAt: time.Now().UTC().Format(time.RFC3339Nano)}
+		metadata[wormMetadataKey] = encodeMetadataValue(state)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if conflict != nil {
+		return nil, conflict
+	}
+	return state, nil
+}
+
+// recordConfigChanges writes a ConfigChanged event to ConfigAuditStream when
+// the configuration of a WORM namespace changed between before and after
+func recordConfigChanges(ctx context.Context, st store.Store, namespace string, before, after map[string]interface{}) error {
+	if WormFromMetadata(before) == nil && WormFromMetadata(after) == nil {
+		return nil
+	}
+
+	keys := make(map[string]bool, len(after))
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+
+	var changes []interface{}
+	for k := range keys {
+		if wormUnrecordedMetadataKeys[k] {
+			continue
+		}
+		old, _ := json.Marshal(before[k])
+		value, exists := after[k]
+		encoded, _ := json.Marshal(value)
+		if bytes.Equal(old, encoded) {
+			continue
+		}
+		change := map[string]interface{}{"key": k}
+		switch {
+		case !exists:
+			change["removed"] = true
+		case wormSecretMetadataKeys[k]:
+			change["sha256"] = sha256Hex(encoded)
+		default:
+			change["value"] = value
+			change["sha256"] = sha256Hex(encoded)
+		}
+		changes = append(changes, change)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].(map[string]interface{})["key"].(string) < changes[j].(map[string]interface{})["key"].(string)
+	})
+
+	_, err := st.WriteMessage(ctx, namespace, ConfigAuditStream, &store.Message{
+		StreamName: ConfigAuditStream,
+		Type:       "ConfigChanged",
+		Data: map[string]interface{}{
+			"changes": changes,
+			"time":    time.Now().UTC().Format(time.RFC3339Nano),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("configuration changed, but the audit event was not recorded: %w", err)
+	}
+	return nil
+}
+
+// Attestation is a signed statement of a namespace's head hash: a SHA-256
+// chain over its messages in global position order, in the NDJSON format of
+// GET /export. Each message extends the chain as
+//
+//	hash = SHA-256(previous hash || export line including its newline)
+//
+// starting from 32 zero bytes, so anyone holding an export can recompute it.
+type Attestation struct {
+	Namespace    string `json:"namespace"`
+	Position     int64  `json:"position"`     // Last global position covered
+	Messages     int64  `json:"messages"`     // Messages covered
+	HeadHash     string `json:"headHash"`     // Hex chain hash after the last covered message
+	PreviousHash string `json:"previousHash"` // Head hash of the previous attestation
+	AttestedAt   string `json:"attestedAt"`
+	PublicKey    string `json:"publicKey"` // Base64 Ed25519 public key
+	Signature    string `json:"signature"` // Base64 Ed25519 signature of SignedText
+}
+
+// SignedText returns the text an attestation's signature covers
+func (a *Attestation) SignedText() string {
+	return fmt.Sprintf("eventodb-attestation\n%s\n%d\n%d\n%s\n%s\n%s\n",
+		a.Namespace, a.Position, a.Messages, a.HeadHash, a.PreviousHash, a.AttestedAt)
+}
+
+// Verify checks the attestation's signature against its public key
+func (a *Attestation) Verify() bool {
+	key, err := base64.StdEncoding.DecodeString(a.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(key), []byte(a.SignedText()), sig)
+}
+
+// AttestationFromMetadata returns the latest attestation stored in namespace metadata, or nil
+func AttestationFromMetadata(metadata map[string]interface{}) *Attestation {
+	raw, ok := metadata[wormAttestationMetadataKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	var a Attestation
+	if decodeMetadataValue(raw, &a) != nil {
+		return nil
+	}
+	return &a
+}
+
+// ParseAttestationKey parses a 32-byte Ed25519 seed given in hex or base64
+func ParseAttestationKey(s string) (ed25519.PrivateKey, error) {
+	s = strings.TrimSpace(s)
+	seed, err := hex.DecodeString(s)
+	if err != nil {
+		if seed, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("attestation key must be hex or base64")
+		}
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("attestation key must be %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Attestor periodically signs the head hashes of WORM namespaces. Each
+// attestation extends the previous one and is appended to the namespace's
+// AttestationStream, so the attestations are part of the log they attest.
+type Attestor struct {
+	store    store.Store
+	key      ed25519.PrivateKey
+	interval time.Duration
+
+	// mu serializes attestations so each extends the one before it
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewAttestor creates an attestor signing with key. An interval of zero
+// attests only on request (ns.worm.attest).
+func NewAttestor(st store.Store, key ed25519.PrivateKey, interval time.Duration) *Attestor {
+	return &Attestor{
+		store:    st,
+		key:      key,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs periodic attestations in the background until Close
+func (a *Attestor) Start() {
+	if a.interval <= 0 {
+		close(a.done)
+		return
+	}
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+				a.pass(context.Background())
+			}
+		}
+	}()
+}
+
+// Close stops periodic attestations
+func (a *Attestor) Close() {
+	close(a.stop)
+	<-a.done
+}
+
+// pass attests every WORM namespace with messages since its last attestation
+func (a *Attestor) pass(ctx context.Context) {
+	namespaces, err := a.store.ListNamespaces(ctx)
+	if err != nil {
+		logger.Get().Warn().Err(err).Msg("Attestation pass failed to list namespaces")
+		return
+	}
+	for _, ns := range namespaces {
+		if WormFromMetadata(ns.Metadata) == nil {
+			continue
+		}
+		if _, err := a.attest(ctx, ns.ID, false); err != nil {
+			logger.Get().Warn().Err(err).Str("namespace", ns.ID).Msg("Attestation failed")
+		}
+	}
+}
+
+// Attest signs the namespace's current head hash now
+func (a *Attestor) Attest(ctx context.Context, namespace string) (*Attestation, error) {
+	if a == nil {
+		return nil, ErrAttestationDisabled
+	}
+	return a.attest(ctx, namespace, true)
+}
+
+// attest extends the namespace's hash chain from its last attestation and
+// records a new one. Unless force is set, nothing is recorded when the only
+// new messages are earlier attestations.
+func (a *Attestor) attest(ctx context.Context, namespace string, force bool) (*Attestation, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ns, err := a.store.GetNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if WormFromMetadata(ns.Metadata) == nil {
+		return nil, ErrNotWorm
+	}
+
+	previous := AttestationFromMetadata(ns.Metadata)
+	att := &Attestation{
+		Namespace:    namespace,
+		HeadHash:     wormGenesisHash,
+		PreviousHash: wormGenesisHash,
+	}
+	if previous != nil {
+		att.Position = previous.Position
+		att.Messages = previous.Messages
+		att.HeadHash = previous.HeadHash
+		att.PreviousHash = previous.HeadHash
+	}
+
+	head, err := hex.DecodeString(att.HeadHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid head hash in previous attestation: %w", err)
+	}
+	changed := false
+	var line bytes.Buffer
+	enc := json.NewEncoder(&line)
+	for {
+		msgs, err := a.store.GetCategoryMessages(ctx, namespace, "", &store.CategoryOpts{
+			Position:  att.Position + 1,
+			BatchSize: attestationBatch,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			line.Reset()
+			if err := enc.Encode(exportRecord(msg)); err != nil {
+				return nil, err
+			}
+			h := sha256.New()
+			h.Write(head)
+			h.Write(line.Bytes())
+			head = h.Sum(nil)
+			att.Position = msg.GlobalPosition
+			att.Messages++
+			changed = changed || msg.StreamName != AttestationStream
+		}
+		if len(msgs) < attestationBatch {
+			break
+		}
+	}
+	if !changed && !force {
+		return previous, nil
+	}
+
+	att.HeadHash = hex.EncodeToString(head)
+	att.AttestedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	att.PublicKey = base64.StdEncoding.EncodeToString(a.key.Public().(ed25519.PublicKey))
+	att.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(a.key, []byte(att.SignedText())))
+
+	if _, err := a.store.WriteMessage(ctx, namespace, AttestationStream, &store.Message{
+		StreamName: AttestationStream,
+		Type:       "Attested",
+		Data:       encodeMetadataValue(att).(map[string]interface{}),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record attestation: %w", err)
+	}
+	err = updateNamespaceMetadata(ctx, a.store, namespace, func(metadata map[string]interface{}) {
+		metadata[wormAttestationMetadataKey] = encodeMetadataValue(att)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("attestation recorded, but its state was not saved: %w", err)
+	}
+	return att, nil
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestWormMode verifies that WORM namespaces reject deletes, truncation and
+// redaction, record configuration changes, and get chained signed attestations
+func TestWormMode(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	if _, rpcErr := h.route(ctx, "ns.worm.attest", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without an attestation key, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"order-1", map[string]interface{}{
+		"type": "Placed",
+		"data": map[string]interface{}{"amount": 10.0},
+	}}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+
+	result, rpcErr := h.route(ctx, "ns.worm.enable", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.worm.enable failed: %v", rpcErr.Message)
+	}
+	if result.(map[string]interface{})["enabled"] != true {
+		t.Errorf("Expected WORM mode to be enabled, got %v", result)
+	}
+
+	for _, call := range []struct {
+		method string
+		args   []interface{}
+	}{
+		{"message.redact", []interface{}{"order-1", 0.0, map[string]interface{}{"reason": "mistake"}}},
+		{"ns.retention.set", []interface{}{map[string]interface{}{"rules": []interface{}{map[string]interface{}{"streams": "order-*", "keep": 1.0}}}}},
+		{"ns.delete", []interface{}{"test-ns"}},
+	} {
+		if _, rpcErr := h.route(ctx, call.method, call.args); rpcErr == nil || rpcErr.Code != "WORM_PROTECTED" {
+			t.Errorf("Expected WORM_PROTECTED for %s, got %v", call.method, rpcErr)
+		}
+	}
+
+	// Enabling WORM mode and freezing are configuration changes
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "audit"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	changes, err := st.GetStreamMessages(ctx, "test-ns", ConfigAuditStream, nil)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("Expected 2 ConfigChanged events, got %d (%v)", len(changes), err)
+	}
+	for i, key := range []string{wormMetadataKey, frozenMetadataKey} {
+		change := changes[i].Data["changes"].([]interface{})[0].(map[string]interface{})
+		if changes[i].Type != "ConfigChanged" || change["key"] != key || change["value"] == nil {
+			t.Errorf("Expected a ConfigChanged event for %s, got %v", key, changes[i].Data)
+		}
+	}
+
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	attestor := NewAttestor(st, key, 0)
+	h.SetAttestor(attestor)
+	result, rpcErr = h.route(ctx, "ns.worm.attest", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.worm.attest failed: %v", rpcErr.Message)
+	}
+	var att Attestation
+	if err := decodeMetadataValue(result, &att); err != nil {
+		t.Fatalf("Invalid attestation: %v", err)
+	}
+	if !att.Verify() || att.Messages != 3 || att.PreviousHash != wormGenesisHash {
+		t.Errorf("Expected a valid attestation of 3 messages, got %+v", att)
+	}
+
+	// The head hash can be recomputed from an export
+	msgs, _ := st.GetCategoryMessages(ctx, "test-ns", "", &store.CategoryOpts{Position: 1, BatchSize: 100})
+	head := make([]byte, sha256.Size)
+	for _, msg := range msgs[:3] {
+		line, _ := json.Marshal(exportRecord(msg))
+		sum := sha256.Sum256(append(append(head, line...), '\n'))
+		head = sum[:]
+	}
+	if hex.EncodeToString(head) != att.HeadHash {
+		t.Errorf("Expected head hash %s, got %s", hex.EncodeToString(head), att.HeadHash)
+	}
+
+	// Periodic passes skip namespaces whose only new message is the attestation
+	attestor.pass(context.Background())
+	if attestations, _ := st.GetStreamMessages(ctx, "test-ns", AttestationStream, nil); len(attestations) != 1 {
+		t.Errorf("Expected 1 attestation, got %d", len(attestations))
+	}
+	next, err := attestor.Attest(context.Background(), "test-ns")
+	if err != nil {
+		t.Fatalf("Attest failed: %v", err)
+	}
+	if next.PreviousHash != att.HeadHash || next.Messages != 4 || !next.Verify() {
+		t.Errorf("Expected the attestation to extend the previous one, got %+v", next)
+	}
+
+	next.Namespace = "other-ns"
+	if next.Verify() {
+		t.Error("Expected a changed attestation to fail verification")
+	}
+	if !strings.HasPrefix(next.SignedText(), "eventodb-attestation\n") {
+		t.Errorf("Unexpected signed text %q", next.SignedText())
+	}
+}
+
+// TestWormMode_Errors tests that WORM mode cannot be enabled over retention
+// rules, that enabling it again keeps its state, that attesting requires
+// WORM mode and that attestation keys are parsed from hex or base64
+func TestWormMode_Errors(t *testing.T) {
+	st := newTestStore(t)
+	h := NewRPCHandler("test", st, NewPubSub())
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h.SetAttestor(NewAttestor(st, ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize)), 0))
+
+	if _, rpcErr := h.route(ctx, "ns.worm.attest", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST attesting a namespace not in WORM mode, got %v", rpcErr)
+	}
+	result, rpcErr := h.route(ctx, "ns.worm.status", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.worm.status failed: %v", rpcErr.Message)
+	}
+	if status := result.(map[string]interface{}); status["enabled"] != false || status["attestations"] != true {
+		t.Errorf("Unexpected status: %v", status)
+	}
+
+	rules := []interface{}{map[string]interface{}{"rules": []interface{}{map[string]interface{}{"streams": "order-*", "keep": 1.0}}}}
+	if _, rpcErr := h.route(ctx, "ns.retention.set", rules); rpcErr != nil {
+		t.Fatalf("ns.retention.set failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "ns.worm.enable", nil); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST with retention rules, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "ns.retention.set", []interface{}{nil}); rpcErr != nil {
+		t.Fatalf("ns.retention.set null failed: %v", rpcErr.Message)
+	}
+
+	first, rpcErr := h.route(ctx, "ns.worm.enable", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.worm.enable failed: %v", rpcErr.Message)
+	}
+	again, rpcErr := h.route(ctx, "ns.worm.enable", nil)
+	if rpcErr != nil {
+		t.Fatalf("ns.worm.enable failed: %v", rpcErr.Message)
+	}
+	if first.(map[string]interface{})["enabledAt"] != again.(map[string]interface{})["enabledAt"] {
+		t.Errorf("Expected enabling again to keep the state, got %v then %v", first, again)
+	}
+
+	seed := bytes.Repeat([]byte{7}, ed25519.SeedSize)
+	for _, s := range []string{hex.EncodeToString(seed), " " + base64.StdEncoding.EncodeToString(seed) + "\n"} {
+		if key, err := ParseAttestationKey(s); err != nil || !bytes.Equal(key.Seed(), seed) {
+			t.Errorf("Expected %q to parse, got %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "not a key", hex.EncodeToString(seed[:16])} {
+		if _, err := ParseAttestationKey(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestStreamWriteBatch tests that stream.writeBatch appends all messages or,
+// on a version conflict, none of them
+func TestStreamWriteBatch(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	batch := []interface{}{
+		map[string]interface{}{"type": "Withdrawn", "data": map[string]interface{}{"amount": float64(50)}},
+		map[string]interface{}{"type": "FeeCharged", "data": map[string]interface{}{"amount": float64(1)}, "id": "0194a1b2-7f3a-7c4e-9a1b-2c3d4e5f6a7b"},
+	}
+	result, rpcErr := h.route(ctx, "stream.writeBatch", []interface{}{"account-1", batch, map[string]interface{}{"expectedVersion": float64(-1)}})
+	if rpcErr != nil {
+		t.Fatalf("stream.writeBatch failed: %v", rpcErr.Message)
+	}
+	res := result.(map[string]interface{})
+	if res["position"] != int64(1) {
+		t.Errorf("Expected last position 1, got %v", res["position"])
+	}
+	written := res["messages"].([]interface{})
+	if len(written) != 2 || written[1].(map[string]interface{})["id"] != "0194a1b2-7f3a-7c4e-9a1b-2c3d4e5f6a7b" {
+		t.Errorf("Unexpected messages: %v", written)
+	}
+
+	// A stale expectedVersion writes nothing
+	_, rpcErr = h.route(ctx, "stream.writeBatch", []interface{}{"account-1", batch, map[string]interface{}{"expectedVersion": float64(0)}})
+	if rpcErr == nil || rpcErr.Code != "STREAM_VERSION_CONFLICT" {
+		t.Fatalf("Expected STREAM_VERSION_CONFLICT, got %v", rpcErr)
+	}
+	if version, err := st.GetStreamVersion(ctx, "test-ns", "account-1"); err != nil || version != 1 {
+		t.Errorf("Expected stream version 1, got %d (%v)", version, err)
+	}
+
+	// An empty batch and too many messages are rejected
+	if _, rpcErr := h.route(ctx, "stream.writeBatch", []interface{}{"account-1", []interface{}{}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for an empty batch, got %v", rpcErr)
+	}
+	tooMany := make([]interface{}, streamWriteBatchLimit+1)
+	for i := range tooMany {
+		tooMany[i] = batch[0]
+	}
+	if _, rpcErr := h.route(ctx, "stream.writeBatch", []interface{}{"account-1", tooMany}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for too many messages, got %v", rpcErr)
+	}
+
+	// expectedVersion is parsed as stream.write parses it
+	result, rpcErr = h.route(ctx, "stream.writeBatch", []interface{}{"account-1", batch[:1], map[string]interface{}{"expectedVersion": int64(1)}})
+	if rpcErr != nil {
+		t.Fatalf("stream.writeBatch with an int64 expectedVersion failed: %v", rpcErr.Message)
+	}
+	if res := result.(map[string]interface{}); res["position"] != int64(2) {
+		t.Errorf("Expected position 2, got %v", res["position"])
+	}
+	if _, rpcErr := h.route(ctx, "stream.writeBatch", []interface{}{"account-1", batch[:1], map[string]interface{}{"expectedVersion": int(2)}}); rpcErr != nil {
+		t.Errorf("stream.writeBatch with an int expectedVersion failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.writeBatch", []interface{}{"account-1", batch[:1], map[string]interface{}{"expectedVersion": "3"}}); rpcErr == nil || rpcErr.Message != "options.expectedVersion must be a number" {
+		t.Errorf("Expected invalid expectedVersion error, got %v", rpcErr)
+	}
+
+	// stream.write shares the pipeline and keeps its own response
+	result, rpcErr = h.route(ctx, "stream.write", []interface{}{"account-1", batch[0], map[string]interface{}{"expectedVersion": int64(3)}})
+	if rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	if res := result.(map[string]interface{}); res["position"] != int64(4) || res["messages"] != nil {
+		t.Errorf("Unexpected stream.write response: %v", res)
+	}
+
+	// One invalid message rejects the batch
+	bad := []interface{}{batch[0], map[string]interface{}{"type": "Broken"}}
+	if _, rpcErr := h.route(ctx, "stream.writeBatch", []interface{}{"account-1", bad}); rpcErr == nil || rpcErr.Message != "messages[1].data must be an object" {
+		t.Errorf("Expected invalid message error, got %v", rpcErr)
+	}
+}
+
+// TestStreamWriteBatch_Errors tests invalid arguments, renamed streams,
+// derived events, frozen namespaces and backends without atomic writes
+func TestStreamWriteBatch_Errors(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+	h := NewRPCHandler("test", st, NewPubSub())
+
+	msg := map[string]interface{}{"type": "Deposited", "data": map[string]interface{}{"amount": float64(10)}}
+	for _, args := range [][]interface{}{
+		{"account-1"},
+		{float64(1), []interface{}{msg}},
+		{"", []interface{}{msg}},
+		{"account-1", msg},
+		{"account-1", []interface{}{"Deposited"}},
+		{"account-1", []interface{}{map[string]interface{}{"data": map[string]interface{}{}}}},
+		{"account-1", []interface{}{map[string]interface{}{"type": "Deposited", "data": map[string]interface{}{}, "id": float64(1)}}},
+		{"account-1", []interface{}{msg}, "options"},
+	} {
+		if _, rpcErr := h.route(ctx, "stream.writeBatch", args); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, rpcErr)
+		}
+	}
+
+	// Batches to a renamed stream go to its new name, with a derived event
+	// per message
+	if _, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-old", msg}); rpcErr != nil {
+		t.Fatalf("stream.write failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.rename", []interface{}{"account-old", "account-1"}); rpcErr != nil {
+		t.Fatalf("stream.rename failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "ns.derivedStreams.set", []interface{}{map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{
+			"category": "account",
+			"stream":   "balance-{id}",
+			"type":     "Latest{type}",
+			"fields":   []interface{}{"amount"},
+		}},
+	}}); rpcErr != nil {
+		t.Fatalf("ns.derivedStreams.set failed: %v", rpcErr.Message)
+	}
+	result, rpcErr := h.route(ctx, "stream.writeBatch", []interface{}{"account-old", []interface{}{msg, msg}, map[string]interface{}{"expectedVersion": float64(0)}})
+	if rpcErr != nil {
+		t.Fatalf("stream.writeBatch failed: %v", rpcErr.Message)
+	}
+	res := result.(map[string]interface{})
+	if res["position"] != int64(2) || len(res["derived"].([]interface{})) != 2 {
+		t.Errorf("Expected position 2 with 2 derived events, got %v", res)
+	}
+	if version, err := st.GetStreamVersion(ctx, "test-ns", "balance-1"); err != nil || version != 1 {
+		t.Errorf("Expected balance-1 at version 1, got %d (%v)", version, err)
+	}
+
+	// Backends without atomic writes take single messages only
+	plain := NewRPCHandler("test", struct{ store.Store }{st}, NewPubSub())
+	if _, rpcErr := plain.route(ctx, "stream.writeBatch", []interface{}{"other-1", []interface{}{msg, msg}}); rpcErr == nil || rpcErr.Code != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST without atomic writes, got %v", rpcErr)
+	}
+	if _, rpcErr := plain.route(ctx, "stream.writeBatch", []interface{}{"other-1", []interface{}{msg}}); rpcErr != nil {
+		t.Errorf("Expected a single message to be written, got %v", rpcErr)
+	}
+
+	// Frozen namespaces reject batches
+	if _, rpcErr := h.route(ctx, "ns.freeze", []interface{}{map[string]interface{}{"reason": "migration"}}); rpcErr != nil {
+		t.Fatalf("ns.freeze failed: %v", rpcErr.Message)
+	}
+	if _, rpcErr := h.route(ctx, "stream.writeBatch", []interface{}{"account-1", []interface{}{msg}}); rpcErr == nil || rpcErr.Code != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY for a frozen namespace, got %v", rpcErr)
+	}
+}
@@ -0,0 +1,525 @@
+// Package api provides a durable local write queue for short backend outages.
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/logger"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/google/uuid"
+)
+
+const (
+	// writeQueueFile is the journal file name inside the queue directory
+	writeQueueFile = "writes.log"
+
+	// writeQueueDefaultMax is the default bound on pending writes
+	writeQueueDefaultMax = 10000
+
+	// writeQueueInitialBackoff and writeQueueMaxBackoff bound retries while the backend is down
+	writeQueueInitialBackoff = 250 * time.Millisecond
+	writeQueueMaxBackoff     = 5 * time.Second
+)
+
+// Claim states
+const (
+	ClaimPending = "pending"
+	ClaimWritten = "written"
+	ClaimFailed  = "failed"
+)
+
+var (
+	// ErrWriteQueueFull is returned when the queue holds its maximum number of pending writes
+	ErrWriteQueueFull = errors.New("write queue is full")
+
+	// ErrClaimNotFound is returned for unknown or expired claims
+	ErrClaimNotFound = errors.New("claim not found")
+)
+
+// WriteQueueConfig configures the write queue
+type WriteQueueConfig struct {
+	Dir        string // Directory for the journal file (required)
+	MaxEntries int    // Maximum pending writes, also the number of results kept (default: 10000)
+}
+
+// WriteClaim is the claim check for a queued write
+type WriteClaim struct {
+	Claim          string `json:"claim"`
+	Namespace      string `json:"namespace"`
+	Stream         string `json:"stream"`
+	Status         string `json:"status"` // pending, written, or failed
+	Position       *int64 `json:"position,omitempty"`
+	GlobalPosition *int64 `json:"globalPosition,omitempty"`
+	ErrorCode      string `json:"errorCode,omitempty"`
+	Error          string `json:"error,omitempty"`
+	QueuedAt       string `json:"queuedAt"`
+	CompletedAt    string `json:"completedAt,omitempty"`
+}
+
+// queuedWrite is a write waiting for the backend
+type queuedWrite struct {
+	Claim           string                 `json:"claim"`
+	Namespace       string                 `json:"namespace"`
+	Stream          string                 `json:"stream"`
+	ID              string                 `json:"id"`
+	Type            string                 `json:"type"`
+	Data            map[string]interface{} `json:"data"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	ExpectedVersion *int64                 `json:"expectedVersion,omitempty"`
+	QueuedAt        string                 `json:"queuedAt"`
+}
+
+// writeQueueEntry is one journal line: either a queued write or its result
+type writeQueueEntry struct {
+	Write  *queuedWrite `json:"write,omitempty"`
+	Result *WriteClaim  `json:"result,omitempty"`
+}
+
+// WriteQueue accepts writes while the backend is briefly unavailable (e.g. a
+// Postgres failover) and drains them in order once it is back.
+//
+// Every queued write is appended to a journal and synced before the caller
+// gets its claim, so queued writes survive a restart. While any write is
+// pending, new writes are queued behind it rather than written directly, which
+// keeps writes in arrival order. Expected versions are checked when a write
+// is drained, not when it is queued.
+type WriteQueue struct {
+	store  store.Store
+	pubsub *PubSub
+	cfg    WriteQueueConfig
+
+	mu      sync.Mutex
+	file    *os.File
+	pending []*queuedWrite
+	claims  map[string]*WriteClaim
+	results []string // Completed claim IDs, oldest first
+	wake    chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWriteQueue opens the queue journal in cfg.Dir and restores pending writes
+func NewWriteQueue(st store.Store, pubsub *PubSub, cfg WriteQueueConfig) (*WriteQueue, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("write queue directory is required")
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = writeQueueDefaultMax
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create write queue directory: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &WriteQueue{
+		store:  st,
+		pubsub: pubsub,
+		cfg:    cfg,
+		claims: make(map[string]*WriteClaim),
+		wake:   make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if err := q.load(); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := q.compact(); err != nil {
+		cancel()
+		return nil, err
+	}
+	return q, nil
+}
+
+// Start begins draining queued writes
+func (q *WriteQueue) Start() {
+	q.mu.Lock()
+	pending := len(q.pending)
+	q.mu.Unlock()
+	if pending > 0 {
+		logger.Get().Info().Int("pending", pending).Msg("Draining queued writes from previous run")
+	}
+
+	q.wg.Add(1)
+	go q.drain()
+}
+
+// Close stops draining and closes the journal. Pending writes stay in the
+// journal and are drained on the next start.
+func (q *WriteQueue) Close() error {
+	q.cancel()
+	q.wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.file == nil {
+		return nil
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
+
+// Active reports whether writes are waiting for the backend
+func (q *WriteQueue) Active() bool {
+	if q == nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending) > 0
+}
+
+// Pending returns the number of queued writes
+func (q *WriteQueue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Enqueue durably queues a write and returns its claim
+func (q *WriteQueue) Enqueue(namespace string, msg *store.Message) (*WriteClaim, error) {
+	claimID, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate claim: %w", err)
+	}
+	write := &queuedWrite{
+		Claim:           claimID.String(),
+		Namespace:       namespace,
+		Stream:          msg.StreamName,
+		ID:              msg.ID,
+		Type:            msg.Type,
+		Data:            msg.Data,
+		Metadata:        msg.Metadata,
+		ExpectedVersion: msg.ExpectedVersion,
+		QueuedAt:        time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) >= q.cfg.MaxEntries {
+		return nil, ErrWriteQueueFull
+	}
+	if err := q.append(writeQueueEntry{Write: write}); err != nil {
+		return nil, err
+	}
+	if len(q.pending) == 0 {
+		logger.Get().Warn().Msg("Backend unavailable, queueing writes")
+	}
+
+	q.pending = append(q.pending, write)
+	claim := &WriteClaim{
+		Claim:     write.Claim,
+		Namespace: namespace,
+		Stream:    write.Stream,
+		Status:    ClaimPending,
+		QueuedAt:  write.QueuedAt,
+	}
+	q.claims[claim.Claim] = claim
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	result := *claim
+	return &result, nil
+}
+
+// Claim returns the state of a queued write in namespace
+func (q *WriteQueue) Claim(namespace, claimID string) (*WriteClaim, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	claim, ok := q.claims[claimID]
+	if !ok || claim.Namespace != namespace {
+		return nil, ErrClaimNotFound
+	}
+	result := *claim
+	return &result, nil
+}
+
+// drain writes queued messages to the backend in order
+func (q *WriteQueue) drain() {
+	defer q.wg.Done()
+
+	backoff := writeQueueInitialBackoff
+	for {
+		q.mu.Lock()
+		var next *queuedWrite
+		if len(q.pending) > 0 {
+			next = q.pending[0]
+		}
+		q.mu.Unlock()
+
+		if next == nil {
+			select {
+			case <-q.ctx.Done():
+				return
+			case <-q.wake:
+			}
+			continue
+		}
+
+		msg := &store.Message{
+			ID:              next.ID,
+			StreamName:      next.Stream,
+			Type:            next.Type,
+			Data:            next.Data,
+			Metadata:        next.Metadata,
+			ExpectedVersion: next.ExpectedVersion,
+		}
+		result, err := q.store.WriteMessage(q.ctx, next.Namespace, next.Stream, msg)
+		if err != nil && (store.IsBackendUnavailable(err) || q.ctx.Err() != nil) {
+			select {
+			case <-q.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, writeQueueMaxBackoff)
+			continue
+		}
+		backoff = writeQueueInitialBackoff
+
+		claim := &WriteClaim{
+			Claim:       next.Claim,
+			Namespace:   next.Namespace,
+			Stream:      next.Stream,
+			Status:      ClaimWritten,
+			QueuedAt:    next.QueuedAt,
+			CompletedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		}
+		if err != nil {
+			claim.Status = ClaimFailed
+			claim.ErrorCode, claim.Error = writeErrorCode(err), err.Error()
+		} else {
+			claim.Position = &result.Position
+			claim.GlobalPosition = &result.GlobalPosition
+			if q.pubsub != nil {
+				q.pubsub.Publish(WriteEvent{
+					Namespace:      next.Namespace,
+					Stream:         next.Stream,
+					Category:       store.Category(next.Stream),
+					Position:       result.Position,
+					GlobalPosition: result.GlobalPosition,
+				})
+			}
+		}
+
+		if err := q.complete(claim); err != nil {
+			logger.Get().Error().Err(err).Str("claim", claim.Claim).Msg("Failed to record queued write result")
+		}
+	}
+}
+
+// complete records the result of the oldest pending write
+func (q *WriteQueue) complete(claim *WriteClaim) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending = q.pending[1:]
+	q.remember(claim)
+	if err := q.append(writeQueueEntry{Result: claim}); err != nil {
+		return err
+	}
+	if len(q.pending) == 0 {
+		logger.Get().Info().Msg("Write queue drained")
+		return q.compactLocked()
+	}
+	return nil
+}
+
+// remember stores a completed claim, dropping the oldest results beyond MaxEntries
+func (q *WriteQueue) remember(claim *WriteClaim) {
+	if _, ok := q.claims[claim.Claim]; !ok || q.claims[claim.Claim].Status == ClaimPending {
+		q.results = append(q.results, claim.Claim)
+	}
+	q.claims[claim.Claim] = claim
+	for len(q.results) > q.cfg.MaxEntries {
+		delete(q.claims, q.results[0])
+		q.results = q.results[1:]
+	}
+}
+
+// append writes one journal entry and syncs it to disk. Caller holds q.mu.
+func (q *WriteQueue) append(entry writeQueueEntry) error {
+	if q.file == nil {
+		return fmt.Errorf("write queue is closed")
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := q.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write queue journal: %w", err)
+	}
+	if err := q.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync queue journal: %w", err)
+	}
+	return nil
+}
+
+// load replays the journal into pending writes and completed claims
+func (q *WriteQueue) load() error {
+	f, err := os.Open(filepath.Join(q.cfg.Dir, writeQueueFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open queue journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry writeQueueEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A torn last line from a crash mid-append was never acknowledged
+			logger.Get().Warn().Err(err).Msg("Skipping unreadable queue journal entry")
+			continue
+		}
+		switch {
+		case entry.Write != nil:
+			q.pending = append(q.pending, entry.Write)
+			q.claims[entry.Write.Claim] = &WriteClaim{
+				Claim:     entry.Write.Claim,
+				Namespace: entry.Write.Namespace,
+				Stream:    entry.Write.Stream,
+				Status:    ClaimPending,
+				QueuedAt:  entry.Write.QueuedAt,
+			}
+		case entry.Result != nil:
+			for i, write := range q.pending {
+				if write.Claim == entry.Result.Claim {
+					q.pending = append(q.pending[:i], q.pending[i+1:]...)
+					break
+				}
+			}
+			q.remember(entry.Result)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the journal with only pending writes and retained results
+func (q *WriteQueue) compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.compactLocked()
+}
+
+// compactLocked rewrites the journal. Caller holds q.mu.
+func (q *WriteQueue) compactLocked() error {
+	path := filepath.Join(q.cfg.Dir, writeQueueFile)
+	tmp, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create queue journal: %w", err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, id := range q.results {
+		if err := enc.Encode(writeQueueEntry{Result: q.claims[id]}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	for _, write := range q.pending {
+		if err := enc.Encode(writeQueueEntry{Write: write}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to replace queue journal: %w", err)
+	}
+
+	if q.file != nil {
+		q.file.Close()
+	}
+	q.file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open queue journal: %w", err)
+	}
+	return nil
+}
+
+// writeErrorCode maps a drained write's error to an RPC error code
+func writeErrorCode(err error) string {
+	switch {
+	case store.IsVersionConflict(err):
+		return "STREAM_VERSION_CONFLICT"
+	case errors.Is(err, store.ErrNamespaceNotFound):
+		return "NAMESPACE_NOT_FOUND"
+	default:
+		return "BACKEND_ERROR"
+	}
+}
+
+// FallbackNamespaceGetter serves the last known namespace when the backend is
+// unavailable, so authentication keeps working while writes are queued
+type FallbackNamespaceGetter struct {
+	getter NamespaceGetter
+
+	mu    sync.RWMutex
+	known map[string]*store.Namespace
+}
+
+// NewFallbackNamespaceGetter wraps getter with a last-known-good cache
+func NewFallbackNamespaceGetter(getter NamespaceGetter) *FallbackNamespaceGetter {
+	return &FallbackNamespaceGetter{
+		getter: getter,
+		known:  make(map[string]*store.Namespace),
+	}
+}
+
+// GetNamespace returns the namespace from the backend, or the last known copy
+// if the backend is unavailable
+func (g *FallbackNamespaceGetter) GetNamespace(ctx context.Context, id string) (*store.Namespace, error) {
+	ns, err := g.getter.GetNamespace(ctx, id)
+	if err == nil {
+		g.mu.Lock()
+		g.known[id] = ns
+		g.mu.Unlock()
+		return ns, nil
+	}
+	if errors.Is(err, store.ErrNamespaceNotFound) {
+		g.mu.Lock()
+		delete(g.known, id)
+		g.mu.Unlock()
+		return nil, err
+	}
+	if store.IsBackendUnavailable(err) {
+		g.mu.RLock()
+		known, ok := g.known[id]
+		g.mu.RUnlock()
+		if ok {
+			return known, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// flakyStore fails writes as if the backend were unreachable while down is set
+type flakyStore struct {
+	store.Store
+	down atomic.Bool
+}
+
+func (s *flakyStore) WriteMessage(ctx context.Context, namespace, streamName string, msg *store.Message) (*store.WriteResult, error) {
+	if s.down.Load() {
+		return nil, syscall.ECONNREFUSED
+	}
+	return s.Store.WriteMessage(ctx, namespace, streamName, msg)
+}
+
+// waitForClaim polls until a claim leaves the pending state
+func waitForClaim(t *testing.T, q *WriteQueue, claimID string) *WriteClaim {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		claim, err := q.Claim("test-ns", claimID)
+		if err != nil {
+			t.Fatalf("Claim failed: %v", err)
+		}
+		if claim.Status != ClaimPending {
+			return claim
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Claim %s still pending", claimID)
+	return nil
+}
+
+func TestWriteQueue_QueuesDuringOutageAndDrainsInOrder(t *testing.T) {
+	st := &flakyStore{Store: newTestStore(t)}
+	st.down.Store(true)
+	dir := t.TempDir()
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	q, err := NewWriteQueue(st, NewPubSub(), WriteQueueConfig{Dir: dir, MaxEntries: 2})
+	if err != nil {
+		t.Fatalf("NewWriteQueue failed: %v", err)
+	}
+	h := NewRPCHandler("test", st, NewPubSub())
+	h.SetWriteQueue(q)
+
+	write := func(expectedVersion interface{}) (interface{}, *RPCError) {
+		args := []interface{}{"account-1", map[string]interface{}{"type": "Deposited", "data": map[string]interface{}{}}}
+		if expectedVersion != nil {
+			args = append(args, map[string]interface{}{"expectedVersion": expectedVersion})
+		}
+		return h.route(ctx, "stream.write", args)
+	}
+
+	// Writes during the outage are queued and return claims
+	var claims []string
+	for _, ev := range []interface{}{nil, float64(5)} {
+		result, rpcErr := write(ev)
+		if rpcErr != nil {
+			t.Fatalf("stream.write failed: %v", rpcErr.Message)
+		}
+		res := result.(map[string]interface{})
+		if res["queued"] != true {
+			t.Fatalf("Expected queued write, got %v", res)
+		}
+		claims = append(claims, res["claim"].(string))
+	}
+	if _, rpcErr := write(nil); rpcErr == nil || rpcErr.Code != "QUEUE_FULL" {
+		t.Fatalf("Expected QUEUE_FULL, got %v", rpcErr)
+	}
+
+	// Claims are scoped to their namespace
+	if _, rpcErr := h.route(context.WithValue(context.Background(), ContextKeyNamespace, "other-ns"), "stream.claim", []interface{}{claims[0]}); rpcErr == nil || rpcErr.Code != "CLAIM_NOT_FOUND" {
+		t.Errorf("Expected CLAIM_NOT_FOUND from another namespace, got %v", rpcErr)
+	}
+
+	// Queued writes survive a restart
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	q, err = NewWriteQueue(st, NewPubSub(), WriteQueueConfig{Dir: dir, MaxEntries: 2})
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer q.Close()
+	if q.Pending() != 2 {
+		t.Fatalf("Expected 2 pending writes after restart, got %d", q.Pending())
+	}
+
+	st.down.Store(false)
+	q.Start()
+
+	first := waitForClaim(t, q, claims[0])
+	if first.Status != ClaimWritten || first.Position == nil || *first.Position != 0 {
+		t.Errorf("Expected first write at position 0, got %+v", first)
+	}
+	second := waitForClaim(t, q, claims[1])
+	if second.Status != ClaimFailed || second.ErrorCode != "STREAM_VERSION_CONFLICT" {
+		t.Errorf("Expected version conflict for second write, got %+v", second)
+	}
+
+	// Results are kept in the compacted journal
+	q.Close()
+	q, err = NewWriteQueue(st, NewPubSub(), WriteQueueConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer q.Close()
+	if claim, err := q.Claim("test-ns", claims[0]); err != nil || claim.Status != ClaimWritten || q.Active() {
+		t.Errorf("Expected written claim and idle queue after restart, got %+v (err: %v)", claim, err)
+	}
+}
+
+func TestFallbackNamespaceGetter(t *testing.T) {
+	st := &flakyStore{Store: newTestStore(t)}
+	getter := NewFallbackNamespaceGetter(flakyNamespaces{st})
+	ctx := context.Background()
+
+	if _, err := getter.GetNamespace(ctx, "test-ns"); err != nil {
+		t.Fatalf("GetNamespace failed: %v", err)
+	}
+
+	// Known namespaces are served from cache during an outage, unknown ones fail
+	st.down.Store(true)
+	if ns, err := getter.GetNamespace(ctx, "test-ns"); err != nil || ns.ID != "test-ns" {
+		t.Errorf("Expected cached namespace, got %+v (err: %v)", ns, err)
+	}
+	if _, err := getter.GetNamespace(ctx, "other-ns"); err == nil {
+		t.Errorf("Expected error for namespace that was never seen")
+	}
+}
+
+// flakyNamespaces fails namespace lookups while the flaky store is down
+type flakyNamespaces struct {
+	st *flakyStore
+}
+
+func (f flakyNamespaces) GetNamespace(ctx context.Context, id string) (*store.Namespace, error) {
+	if f.st.down.Load() {
+		return nil, syscall.ECONNREFUSED
+	}
+	return f.st.GetNamespace(ctx, id)
+}
+
+func TestRPC_BreakerFailsFast(t *testing.T) {
+	st := &flakyStore{Store: newTestStore(t)}
+	breaker := store.NewBreakerStore(st, store.BreakerConfig{Threshold: 1, Cooldown: time.Minute})
+	h := NewRPCHandler("test", breaker, NewPubSub())
+	h.SetBreaker(breaker)
+	ctx := context.WithValue(context.Background(), ContextKeyNamespace, "test-ns")
+
+	// The write that trips the breaker is reported as an outage
+	st.down.Store(true)
+	_, rpcErr := h.route(ctx, "stream.write", []interface{}{"account-1", map[string]interface{}{"type": "Opened", "data": map[string]interface{}{}}})
+	if rpcErr == nil || rpcErr.Code != "BACKEND_UNAVAILABLE" {
+		t.Fatalf("Expected BACKEND_UNAVAILABLE, got %v", rpcErr)
+	}
+	if seconds, ok := retryAfter(rpcErr); !ok || seconds != 60 {
+		t.Errorf("Expected retryAfter of 60s, got %v", rpcErr.Details)
+	}
+
+	// Reads fail fast too, system methods keep working
+	if _, rpcErr := h.route(ctx, "stream.get", []interface{}{"account-1"}); rpcErr == nil || rpcErr.Code != "BACKEND_UNAVAILABLE" {
+		t.Errorf("Expected BACKEND_UNAVAILABLE for read, got %v", rpcErr)
+	}
+	if _, rpcErr := h.route(ctx, "sys.version", nil); rpcErr != nil {
+		t.Errorf("sys.version failed: %v", rpcErr.Message)
+	}
+}
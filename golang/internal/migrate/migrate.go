@@ -167,6 +167,98 @@ func extractVersionFromFilename(filename string) int {
 	return version
 }
 
+// IsDownFile reports whether a migration file is the rollback of another,
+// named like "002_xxx.down.sql"
+func IsDownFile(filename string) bool {
+	return strings.HasSuffix(filename, ".down.sql")
+}
+
+// Migration is one versioned schema change and its optional rollback
+type Migration struct {
+	Version int
+	Name    string // Up file name, e.g. "002_category_get_all.sql"
+	Up      string
+	Down    string // Empty if the migration cannot be rolled back
+}
+
+// Load reads the versioned migrations in dir, pairing each NNN_name.sql with
+// its NNN_name.down.sql rollback, sorted by version
+func Load(fsys embed.FS, dir string) ([]Migration, error) {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		version := extractVersionFromFilename(name)
+		if version == 0 {
+			return nil, fmt.Errorf("migration %s has no version prefix", name)
+		}
+
+		content, err := fsys.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version}
+			byVersion[version] = mig
+		}
+		if IsDownFile(name) {
+			mig.Down = string(content)
+			continue
+		}
+		if mig.Name != "" {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", mig.Name, name, version)
+		}
+		mig.Name = name
+		mig.Up = string(content)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Name == "" {
+			return nil, fmt.Errorf("rollback for version %d has no migration", mig.Version)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Latest returns the highest version in migrations, 0 if there are none
+func Latest(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Rollbacks returns the migrations to revert, newest first, to take a schema
+// from version current down to target
+func Rollbacks(migrations []Migration, current, target int) ([]Migration, error) {
+	var down []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mig := migrations[i]
+		if mig.Version <= target || mig.Version > current {
+			continue
+		}
+		if mig.Down == "" {
+			return nil, fmt.Errorf("migration %s cannot be rolled back", mig.Name)
+		}
+		down = append(down, mig)
+	}
+	return down, nil
+}
+
 // migration represents a single migration file
 type migration struct {
 	name    string
@@ -234,7 +326,7 @@ func (m *Migrator) loadMigrationsFromDir(dir string) ([]migration, error) {
 	var migrations []migration
 
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") || IsDownFile(entry.Name()) {
 			continue
 		}
 
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	_ "modernc.org/sqlite"
+
+	"github.com/eventodb/eventodb/migrations"
 )
 
 //go:embed testdata
@@ -173,3 +175,63 @@ func setupTestDB(t *testing.T) *sql.DB {
 	}
 	return db
 }
+
+// TestLoad_PairsRollbacks tests pairing the embedded migrations with their rollbacks
+func TestLoad_PairsRollbacks(t *testing.T) {
+	for _, dir := range []string{"namespace/postgres", "namespace/sqlite", "namespace/timescale"} {
+		fsys := map[string]embed.FS{
+			"namespace/postgres":  migrations.NamespacePostgresFS,
+			"namespace/sqlite":    migrations.NamespaceSQLiteFS,
+			"namespace/timescale": migrations.NamespaceTimescaleFS,
+		}[dir]
+		migs, err := Load(fsys, dir)
+		if err != nil {
+			t.Fatalf("Load %s failed: %v", dir, err)
+		}
+		if len(migs) < 2 || migs[0].Version != 1 || Latest(migs) != migs[len(migs)-1].Version {
+			t.Fatalf("Unexpected migrations in %s: %+v", dir, migs)
+		}
+
+		// Every migration after the initial schema can be rolled back
+		down, err := Rollbacks(migs, Latest(migs), 1)
+		if err != nil || len(down) != len(migs)-1 || down[0].Version != Latest(migs) {
+			t.Errorf("Unexpected rollbacks in %s: %d (%v)", dir, len(down), err)
+		}
+		if _, err := Rollbacks(migs, Latest(migs), 0); err == nil {
+			t.Errorf("Expected %s initial schema to have no rollback", dir)
+		}
+	}
+}
+
+// TestRollbacks_Edges tests rollbacks to the current or a later version,
+// partial ranges, migrations without a rollback and missing directories
+func TestRollbacks_Edges(t *testing.T) {
+	migs := []Migration{
+		{Version: 1, Name: "001_initial.sql"},
+		{Version: 2, Name: "002_index.sql", Down: "DROP INDEX"},
+		{Version: 3, Name: "003_view.sql"},
+		{Version: 4, Name: "004_column.sql", Down: "ALTER TABLE"},
+	}
+	for _, tt := range []struct{ current, target int }{{4, 4}, {4, 5}, {2, 3}} {
+		if down, err := Rollbacks(migs, tt.current, tt.target); err != nil || len(down) != 0 {
+			t.Errorf("Expected no rollbacks from %d to %d, got %v (%v)", tt.current, tt.target, down, err)
+		}
+	}
+	if down, err := Rollbacks(migs, 4, 3); err != nil || len(down) != 1 || down[0].Version != 4 {
+		t.Errorf("Expected to roll back version 4 only, got %v (%v)", down, err)
+	}
+	// Versions above current were never applied, so need no rollback
+	if down, err := Rollbacks(migs, 2, 1); err != nil || len(down) != 1 || down[0].Version != 2 {
+		t.Errorf("Expected to roll back version 2 only, got %v (%v)", down, err)
+	}
+	if _, err := Rollbacks(migs, 4, 2); err == nil {
+		t.Error("Expected an error rolling back a migration without a rollback")
+	}
+
+	if Latest(nil) != 0 {
+		t.Errorf("Expected version 0 without migrations, got %d", Latest(nil))
+	}
+	if _, err := Load(migrations.NamespaceSQLiteFS, "namespace/missing"); err == nil {
+		t.Error("Expected an error loading a missing directory")
+	}
+}
@@ -0,0 +1,253 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// AWSSecretsManagerResolver reads awssm://<secret-id>#<key> references from
+// AWS Secrets Manager. The secret ID is a name or an ARN. Without a key the
+// whole SecretString is used; with one, SecretString must be a JSON object.
+//
+// The region comes from the ARN, AWS_REGION or AWS_DEFAULT_REGION.
+// Credentials come from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN, else from the ECS task role or the EC2 instance role.
+// AWS_ENDPOINT_URL_SECRETS_MANAGER overrides the endpoint.
+type AWSSecretsManagerResolver struct {
+	Client *http.Client // nil uses a client with a short timeout
+}
+
+// AWSCredentials are the credentials used to sign requests
+type AWSCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+}
+
+// Resolve implements Resolver
+func (a *AWSSecretsManagerResolver) Resolve(ctx context.Context, ref Reference) (string, error) {
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	secretID := ref.Path
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.Split(secretID, ":"); len(parts) >= 7 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return "", fmt.Errorf("AWS_REGION is not set")
+	}
+
+	creds, err := LoadAWSCredentials(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER")
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	SignAWSRequest(req, body, "secretsmanager", region, creds, time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type string `json:"__type"`
+		}
+		json.Unmarshal(respBody, &awsErr)
+		return "", fmt.Errorf("secrets manager responded with status %d %s", resp.StatusCode, awsErr.Type)
+	}
+
+	var secret struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(respBody, &secret); err != nil {
+		return "", fmt.Errorf("invalid secrets manager response: %w", err)
+	}
+	if secret.SecretString == nil {
+		return "", fmt.Errorf("secret has no SecretString (binary secrets are not supported)")
+	}
+	if ref.Field == "" {
+		return *secret.SecretString, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(*secret.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so #%s cannot be selected", ref.Field)
+	}
+	return selectField(fields, ref.Field)
+}
+
+// LoadAWSCredentials returns credentials from the environment, the ECS task
+// role or the EC2 instance role. Role credentials expire, so callers that run
+// for long reload them periodically.
+func LoadAWSCredentials(ctx context.Context, client *http.Client) (*AWSCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return &AWSCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			Token:           os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		var creds AWSCredentials
+		if err := getJSON(ctx, client, "http://169.254.170.2"+uri, nil, &creds); err != nil {
+			return nil, fmt.Errorf("failed to get ECS task credentials: %w", err)
+		}
+		return &creds, nil
+	}
+
+	// EC2 instance metadata (IMDSv2)
+	const imds = "http://169.254.169.254/latest"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, imds+"/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("no AWS credentials: AWS_ACCESS_KEY_ID is not set and instance metadata is unreachable")
+	}
+	token, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("instance metadata token request failed with status %d", resp.StatusCode)
+	}
+	header := http.Header{"X-Aws-Ec2-Metadata-Token": {string(token)}}
+
+	role, err := getText(ctx, client, imds+"/meta-data/iam/security-credentials/", header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance role: %w", err)
+	}
+	var creds AWSCredentials
+	if err := getJSON(ctx, client, imds+"/meta-data/iam/security-credentials/"+strings.TrimSpace(role), header, &creds); err != nil {
+		return nil, fmt.Errorf("failed to get instance role credentials: %w", err)
+	}
+	return &creds, nil
+}
+
+// getText GETs url and returns the body
+func getText(ctx context.Context, client *http.Client, url string, header http.Header) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return string(body), nil
+}
+
+// getJSON GETs url and decodes the body into v
+func getJSON(ctx context.Context, client *http.Client, url string, header http.Header, v interface{}) error {
+	body, err := getText(ctx, client, url, header)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(body), v)
+}
+
+// SignAWSRequest signs a request to an AWS JSON API (one selected by an
+// X-Amz-Target header) with AWS Signature Version 4
+func SignAWSRequest(req *http.Request, body []byte, service, region string, creds *AWSCredentials, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	signedHeaders := "host;x-amz-date;x-amz-target"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"x-amz-target:" + req.Header.Get("X-Amz-Target") + "\n"
+	if creds.Token != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.Token)
+		signedHeaders = "host;x-amz-date;x-amz-security-token;x-amz-target"
+		canonicalHeaders = "host:" + req.URL.Host + "\n" +
+			"x-amz-date:" + amzDate + "\n" +
+			"x-amz-security-token:" + creds.Token + "\n" +
+			"x-amz-target:" + req.Header.Get("X-Amz-Target") + "\n"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns HMAC-SHA256(key, data)
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,143 @@
+// Package secrets resolves references like vault://secret/data/eventodb#dbUrl
+// to credentials at startup, so credentials are not passed in process
+// arguments or the environment.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resolveTimeout bounds the lookup of one reference
+const resolveTimeout = 30 * time.Second
+
+// Reference points to a secret: <scheme>://<path>#<field>
+type Reference struct {
+	Scheme string // Selects the resolver, e.g. "vault"
+	Path   string // Where the secret is, e.g. "secret/data/eventodb"
+	Field  string // Selects a value of a secret holding several; may be empty
+}
+
+// String returns the reference in <scheme>://<path>#<field> form
+func (r Reference) String() string {
+	s := r.Scheme + "://" + r.Path
+	if r.Field != "" {
+		s += "#" + r.Field
+	}
+	return s
+}
+
+// Resolver looks up the secret a reference points to
+type Resolver interface {
+	Resolve(ctx context.Context, ref Reference) (string, error)
+}
+
+// ResolverFunc adapts a function to Resolver
+type ResolverFunc func(ctx context.Context, ref Reference) (string, error)
+
+// Resolve calls f
+func (f ResolverFunc) Resolve(ctx context.Context, ref Reference) (string, error) {
+	return f(ctx, ref)
+}
+
+var (
+	mu        sync.RWMutex
+	resolvers = map[string]Resolver{
+		"file":  ResolverFunc(resolveFile),
+		"vault": &VaultResolver{},
+		"awssm": &AWSSecretsManagerResolver{},
+	}
+)
+
+// Register adds or replaces the resolver for a URL scheme
+func Register(scheme string, r Resolver) {
+	mu.Lock()
+	resolvers[scheme] = r
+	mu.Unlock()
+}
+
+// resolverFor returns the resolver for the scheme of value, or nil
+func resolverFor(value string) Resolver {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return nil
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return resolvers[scheme]
+}
+
+// IsReference reports whether value is a reference to a secret rather than
+// a literal value
+func IsReference(value string) bool {
+	return resolverFor(value) != nil
+}
+
+// Resolve returns the secret value references, or value itself when it is
+// not a reference
+func Resolve(ctx context.Context, value string) (string, error) {
+	r := resolverFor(value)
+	if r == nil {
+		return value, nil
+	}
+	var ref Reference
+	ref.Scheme, ref.Path, _ = strings.Cut(value, "://")
+	ref.Path, ref.Field, _ = strings.Cut(ref.Path, "#")
+	if ref.Path == "" {
+		return "", fmt.Errorf("invalid secret reference %s: missing path", ref)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	secret, err := r.Resolve(ctx, ref)
+	if err != nil {
+		// The reference holds no secret, so it is safe to report
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return secret, nil
+}
+
+// ReadFile returns the trimmed contents of a file holding one secret, as
+// given to --token-file and --db-url-file
+func ReadFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// resolveFile resolves file:///path references
+func resolveFile(ctx context.Context, ref Reference) (string, error) {
+	return ReadFile(ref.Path)
+}
+
+// selectField returns fields[field], or the only value when field is empty
+func selectField(fields map[string]interface{}, field string) (string, error) {
+	if field == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secret has %d fields, select one with #field", len(fields))
+		}
+		for _, v := range fields {
+			return fieldString(v)
+		}
+	}
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", field)
+	}
+	return fieldString(v)
+}
+
+// fieldString returns a secret field that must be a string
+func fieldString(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("secret field is not a string")
+	}
+	return s, nil
+}
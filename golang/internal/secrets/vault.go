@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VaultResolver reads vault://<path>#<field> references from HashiCorp Vault
+// KV secrets engines, version 1 or 2. For KV v2, path includes "data/", as in
+// vault://secret/data/eventodb#dbUrl.
+//
+// The server address comes from VAULT_ADDR. The token comes from VAULT_TOKEN,
+// the file named by VAULT_TOKEN_FILE, or ~/.vault-token, in that order.
+// VAULT_NAMESPACE selects a Vault Enterprise namespace.
+type VaultResolver struct {
+	Client *http.Client // nil uses http.DefaultClient
+}
+
+// Resolve implements Resolver
+func (v *VaultResolver) Resolve(ctx context.Context, ref Reference) (string, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token, err := vaultToken()
+	if err != nil {
+		return "", err
+	}
+
+	path := strings.Trim(ref.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(addr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault responded with status %d", resp.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+
+	// KV v2 nests the fields under data.data, next to data.metadata
+	fields := secret.Data
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := fields["metadata"]; hasMetadata {
+			fields = nested
+		}
+	}
+	return selectField(fields, ref.Field)
+}
+
+// vaultToken returns the token used to authenticate with Vault
+func vaultToken() (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	path := os.Getenv("VAULT_TOKEN_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("VAULT_TOKEN is not set")
+		}
+		path = filepath.Join(home, ".vault-token")
+	}
+	token, err := ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("VAULT_TOKEN is not set and the token file cannot be read: %w", err)
+	}
+	return token, nil
+}
@@ -11,6 +11,7 @@ import (
 	_ "modernc.org/sqlite"
 
 	"github.com/eventodb/eventodb/internal/store"
+	"github.com/eventodb/eventodb/internal/store/pebble"
 	"github.com/eventodb/eventodb/internal/store/postgres"
 	"github.com/eventodb/eventodb/internal/store/sqlite"
 )
@@ -405,6 +406,80 @@ func BenchmarkGetCategoryMessages_SQLiteMemory(b *testing.B) {
 	}
 }
 
+// BenchmarkWriteMessage_Pebble benchmarks WriteMessage operation on Pebble backend
+// Target: <1ms per operation
+func BenchmarkWriteMessage_Pebble(b *testing.B) {
+	ctx := context.Background()
+	pebbleStore := setupPebbleForBenchmark(b)
+
+	// Create namespace once
+	err := pebbleStore.CreateNamespace(ctx, "bench_ns", "bench_token_hash", "Benchmark namespace")
+	if err != nil {
+		b.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg := &store.Message{
+			StreamName: fmt.Sprintf("account-%d", i),
+			Type:       "AccountCreated",
+			Data: map[string]interface{}{
+				"accountId": i,
+				"name":      "Test Account",
+			},
+		}
+		_, err := pebbleStore.WriteMessage(ctx, "bench_ns", msg.StreamName, msg)
+		if err != nil {
+			b.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkGetCategoryMessages_Pebble benchmarks GetCategoryMessages on Pebble
+// Target: <5ms for 100 messages
+func BenchmarkGetCategoryMessages_Pebble(b *testing.B) {
+	ctx := context.Background()
+	pebbleStore := setupPebbleForBenchmark(b)
+
+	// Setup: create namespace and write messages
+	err := pebbleStore.CreateNamespace(ctx, "bench_ns", "bench_token_hash", "Benchmark namespace")
+	if err != nil {
+		b.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	// Write 100 messages across 10 streams
+	for streamIdx := 0; streamIdx < 10; streamIdx++ {
+		for msgIdx := 0; msgIdx < 10; msgIdx++ {
+			msg := &store.Message{
+				StreamName: fmt.Sprintf("account-%d", streamIdx),
+				Type:       "AccountEvent",
+				Data: map[string]interface{}{
+					"sequence": msgIdx,
+				},
+			}
+			_, err := pebbleStore.WriteMessage(ctx, "bench_ns", msg.StreamName, msg)
+			if err != nil {
+				b.Fatalf("WriteMessage failed: %v", err)
+			}
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		opts := &store.CategoryOpts{
+			Position:  1,
+			BatchSize: 100,
+		}
+		msgs, err := pebbleStore.GetCategoryMessages(ctx, "bench_ns", "account", opts)
+		if err != nil {
+			b.Fatalf("GetCategoryMessages failed: %v", err)
+		}
+		if len(msgs) != 100 {
+			b.Fatalf("Expected 100 messages, got %d", len(msgs))
+		}
+	}
+}
+
 // Helper functions for benchmark setup
 
 func setupPostgresForBenchmark(b *testing.B) store.Store {
@@ -415,6 +490,11 @@ func setupPostgresForBenchmark(b *testing.B) store.Store {
 	if err != nil {
 		b.Fatalf("Failed to connect to Postgres: %v", err)
 	}
+	if err := db.Ping(); err != nil {
+		// Keep the suite runnable on machines without Postgres
+		db.Close()
+		b.Skipf("Postgres not available: %v", err)
+	}
 
 	pgStore, err := postgres.New(db)
 	if err != nil {
@@ -475,3 +555,17 @@ func setupSQLiteMemoryForBenchmark(b *testing.B) store.Store {
 
 	return sqliteStore
 }
+
+func setupPebbleForBenchmark(b *testing.B) store.Store {
+	b.Helper()
+
+	pebbleStore, err := pebble.New(b.TempDir())
+	if err != nil {
+		b.Fatalf("Failed to create Pebble store: %v", err)
+	}
+	b.Cleanup(func() {
+		pebbleStore.Close()
+	})
+
+	return pebbleStore
+}
@@ -0,0 +1,460 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Breaker states
+const (
+	BreakerClosed   = "closed"    // Calls go through
+	BreakerOpen     = "open"      // Calls fail fast with UnavailableError
+	BreakerHalfOpen = "half-open" // One probe call goes through
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive failures that trips the breaker
+	DefaultBreakerThreshold = 5
+
+	// DefaultBreakerCooldown is how long the breaker stays open before probing
+	DefaultBreakerCooldown = 5 * time.Second
+)
+
+// BreakerConfig configures a BreakerStore
+type BreakerConfig struct {
+	Threshold int           // Consecutive backend failures before opening (default: 5)
+	Cooldown  time.Duration // Time open before a probe call is let through (default: 5s)
+
+	// OnStateChange is called when the breaker opens or closes (optional)
+	OnStateChange func(from, to string)
+}
+
+// BreakerStats describes the breaker for health checks and metrics
+type BreakerStats struct {
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	Trips               int64     `json:"trips"`    // Times the breaker opened
+	Failures            int64     `json:"failures"` // Backend failures seen
+	Rejected            int64     `json:"rejected"` // Calls failed fast while open
+	OpenedAt            time.Time `json:"openedAt,omitzero"`
+	RetryAfter          float64   `json:"retryAfter,omitempty"` // Seconds until the next probe
+}
+
+// BreakerStore wraps a Store in a circuit breaker.
+//
+// Calls that fail because the backend cannot be reached are counted; after
+// Threshold consecutive failures the breaker opens and every call fails
+// immediately with an UnavailableError instead of waiting on the backend.
+// After Cooldown one probe call is let through: success closes the breaker,
+// failure opens it for another Cooldown. Request errors such as version
+// conflicts count as successes, since the backend answered.
+type BreakerStore struct {
+	Store
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+	trips    int64
+	total    int64
+	rejected int64
+}
+
+// NewBreakerStore wraps st in a circuit breaker
+func NewBreakerStore(st Store, cfg BreakerConfig) *BreakerStore {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultBreakerThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultBreakerCooldown
+	}
+	return &BreakerStore{Store: st, cfg: cfg, state: BreakerClosed}
+}
+
+// Check returns an UnavailableError if a call would be rejected right now,
+// without using up the probe
+func (b *BreakerStore) Check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen {
+		if wait := b.cfg.Cooldown - time.Since(b.openedAt); wait > 0 {
+			return &UnavailableError{RetryAfter: wait}
+		}
+	}
+	if b.state == BreakerHalfOpen && b.probing {
+		return &UnavailableError{RetryAfter: b.cfg.Cooldown}
+	}
+	return nil
+}
+
+// Stats returns the breaker's current state and counters
+func (b *BreakerStore) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := BreakerStats{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Trips:               b.trips,
+		Failures:            b.total,
+		Rejected:            b.rejected,
+	}
+	if b.state != BreakerClosed {
+		stats.OpenedAt = b.openedAt
+		stats.RetryAfter = max(b.cfg.Cooldown-time.Since(b.openedAt), 0).Seconds()
+	}
+	return stats
+}
+
+// allow reports whether a call may go to the backend, and whether it is the probe
+func (b *BreakerStore) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		wait := b.cfg.Cooldown - time.Since(b.openedAt)
+		if wait > 0 {
+			b.rejected++
+			return false, &UnavailableError{RetryAfter: wait}
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true, nil
+	case BreakerHalfOpen:
+		if b.probing {
+			b.rejected++
+			return false, &UnavailableError{RetryAfter: b.cfg.Cooldown}
+		}
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// record updates the breaker with the outcome of a call
+func (b *BreakerStore) record(probe bool, err error) {
+	failed := IsBackendUnavailable(err) || errors.Is(err, context.DeadlineExceeded)
+
+	b.mu.Lock()
+	from := b.state
+	if probe {
+		b.probing = false
+	}
+	if !failed {
+		if probe || b.state == BreakerClosed {
+			b.state = BreakerClosed
+			b.failures = 0
+		}
+	} else {
+		b.total++
+		b.failures++
+		if probe || (b.state == BreakerClosed && b.failures >= b.cfg.Threshold) {
+			if b.state == BreakerClosed {
+				b.trips++
+			}
+			b.state = BreakerOpen
+			b.openedAt = time.Now()
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	// Probes move through half-open; only report opening and closing
+	if b.cfg.OnStateChange != nil && (from == BreakerClosed) != (to == BreakerClosed) {
+		if from == BreakerHalfOpen {
+			from = BreakerOpen
+		}
+		b.cfg.OnStateChange(from, to)
+	}
+}
+
+// call runs fn through the breaker
+func (b *BreakerStore) call(fn func() error) error {
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	b.record(probe, err)
+	return err
+}
+
+func (b *BreakerStore) WriteMessage(ctx context.Context, namespace, streamName string, msg *Message) (result *WriteResult, err error) {
+	err = b.call(func() error {
+		result, err = b.Store.WriteMessage(ctx, namespace, streamName, msg)
+		return err
+	})
+	return result, err
+}
+
+func (b *BreakerStore) ImportBatch(ctx context.Context, namespace string, messages []*Message) error {
+	return b.call(func() error {
+		return b.Store.ImportBatch(ctx, namespace, messages)
+	})
+}
+
+func (b *BreakerStore) ClearNamespaceMessages(ctx context.Context, namespace string) (count int64, err error) {
+	err = b.call(func() error {
+		count, err = b.Store.ClearNamespaceMessages(ctx, namespace)
+		return err
+	})
+	return count, err
+}
+
+func (b *BreakerStore) GetStreamMessages(ctx context.Context, namespace, streamName string, opts *GetOpts) (msgs []*Message, err error) {
+	err = b.call(func() error {
+		msgs, err = b.Store.GetStreamMessages(ctx, namespace, streamName, opts)
+		return err
+	})
+	return msgs, err
+}
+
+func (b *BreakerStore) GetCategoryMessages(ctx context.Context, namespace, categoryName string, opts *CategoryOpts) (msgs []*Message, err error) {
+	err = b.call(func() error {
+		msgs, err = b.Store.GetCategoryMessages(ctx, namespace, categoryName, opts)
+		return err
+	})
+	return msgs, err
+}
+
+func (b *BreakerStore) GetLastStreamMessage(ctx context.Context, namespace, streamName string, msgType *string) (msg *Message, err error) {
+	err = b.call(func() error {
+		msg, err = b.Store.GetLastStreamMessage(ctx, namespace, streamName, msgType)
+		return err
+	})
+	return msg, err
+}
+
+func (b *BreakerStore) GetStreamVersion(ctx context.Context, namespace, streamName string) (version int64, err error) {
+	err = b.call(func() error {
+		version, err = b.Store.GetStreamVersion(ctx, namespace, streamName)
+		return err
+	})
+	return version, err
+}
+
+func (b *BreakerStore) CreateNamespace(ctx context.Context, id, tokenHash, description string) error {
+	return b.call(func() error {
+		return b.Store.CreateNamespace(ctx, id, tokenHash, description)
+	})
+}
+
+func (b *BreakerStore) DeleteNamespace(ctx context.Context, id string) error {
+	return b.call(func() error {
+		return b.Store.DeleteNamespace(ctx, id)
+	})
+}
+
+func (b *BreakerStore) GetNamespace(ctx context.Context, id string) (ns *Namespace, err error) {
+	err = b.call(func() error {
+		ns, err = b.Store.GetNamespace(ctx, id)
+		return err
+	})
+	return ns, err
+}
+
+func (b *BreakerStore) ListNamespaces(ctx context.Context) (namespaces []*Namespace, err error) {
+	err = b.call(func() error {
+		namespaces, err = b.Store.ListNamespaces(ctx)
+		return err
+	})
+	return namespaces, err
+}
+
+func (b *BreakerStore) UpdateNamespaceMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	return b.call(func() error {
+		return b.Store.UpdateNamespaceMetadata(ctx, id, metadata)
+	})
+}
+
+func (b *BreakerStore) UpdateNamespaceToken(ctx context.Context, id, tokenHash string) error {
+	return b.call(func() error {
+		return b.Store.UpdateNamespaceToken(ctx, id, tokenHash)
+	})
+}
+
+func (b *BreakerStore) MigrateNamespaces(ctx context.Context) (count int, err error) {
+	err = b.call(func() error {
+		count, err = b.Store.MigrateNamespaces(ctx)
+		return err
+	})
+	return count, err
+}
+
+func (b *BreakerStore) GetNamespaceMessageCount(ctx context.Context, namespace string) (count int64, err error) {
+	err = b.call(func() error {
+		count, err = b.Store.GetNamespaceMessageCount(ctx, namespace)
+		return err
+	})
+	return count, err
+}
+
+func (b *BreakerStore) ListStreams(ctx context.Context, namespace string, opts *ListStreamsOpts) (streams []*StreamInfo, err error) {
+	err = b.call(func() error {
+		streams, err = b.Store.ListStreams(ctx, namespace, opts)
+		return err
+	})
+	return streams, err
+}
+
+func (b *BreakerStore) ListCategories(ctx context.Context, namespace string) (categories []*CategoryInfo, err error) {
+	err = b.call(func() error {
+		categories, err = b.Store.ListCategories(ctx, namespace)
+		return err
+	})
+	return categories, err
+}
+
+// CheckStorage forwards to the backend if it implements IntegrityChecker
+func (b *BreakerStore) CheckStorage(ctx context.Context, namespace string) (problems []string, err error) {
+	checker, ok := b.Store.(IntegrityChecker)
+	if !ok {
+		return nil, nil
+	}
+	err = b.call(func() error {
+		problems, err = checker.CheckStorage(ctx, namespace)
+		return err
+	})
+	return problems, err
+}
+
+// InvalidJSON forwards to the backend if it implements IntegrityChecker
+func (b *BreakerStore) InvalidJSON(ctx context.Context, namespace, streamName string) (positions []int64, err error) {
+	checker, ok := b.Store.(IntegrityChecker)
+	if !ok {
+		return nil, nil
+	}
+	err = b.call(func() error {
+		positions, err = checker.InvalidJSON(ctx, namespace, streamName)
+		return err
+	})
+	return positions, err
+}
+
+// NamespaceStorage forwards to the backend if it implements StorageReporter
+func (b *BreakerStore) NamespaceStorage(ctx context.Context, namespace string) (usage *StorageUsage, err error) {
+	reporter, ok := b.Store.(StorageReporter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = b.call(func() error {
+		usage, err = reporter.NamespaceStorage(ctx, namespace)
+		return err
+	})
+	return usage, err
+}
+
+// TruncateStream forwards to the backend if it implements StreamTruncater
+func (b *BreakerStore) TruncateStream(ctx context.Context, namespace, streamName string, position int64) (deleted int64, err error) {
+	truncater, ok := b.Store.(StreamTruncater)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	err = b.call(func() error {
+		deleted, err = truncater.TruncateStream(ctx, namespace, streamName, position)
+		return err
+	})
+	return deleted, err
+}
+
+// RedactMessage forwards to the backend if it implements MessageRedactor
+func (b *BreakerStore) RedactMessage(ctx context.Context, namespace, streamName string, position int64, data, metadata map[string]interface{}) error {
+	redactor, ok := b.Store.(MessageRedactor)
+	if !ok {
+		return ErrNotSupported
+	}
+	return b.call(func() error {
+		return redactor.RedactMessage(ctx, namespace, streamName, position, data, metadata)
+	})
+}
+
+// MergeStreams forwards to the backend if it implements StreamMerger
+func (b *BreakerStore) MergeStreams(ctx context.Context, namespace, target string, sources []string) (moved int64, err error) {
+	merger, ok := b.Store.(StreamMerger)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	err = b.call(func() error {
+		moved, err = merger.MergeStreams(ctx, namespace, target, sources)
+		return err
+	})
+	return moved, err
+}
+
+// WriteMessages forwards to the backend if it implements AtomicWriter
+func (b *BreakerStore) WriteMessages(ctx context.Context, namespace string, msgs []*Message) (results []*WriteResult, err error) {
+	writer, ok := b.Store.(AtomicWriter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = b.call(func() error {
+		results, err = writer.WriteMessages(ctx, namespace, msgs)
+		return err
+	})
+	return results, err
+}
+
+// SetConsumerPosition forwards to the backend if it implements ConsumerPositionStore
+func (b *BreakerStore) SetConsumerPosition(ctx context.Context, namespace, consumer string, globalPosition int64) (position *ConsumerPosition, err error) {
+	positions, ok := b.Store.(ConsumerPositionStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = b.call(func() error {
+		position, err = positions.SetConsumerPosition(ctx, namespace, consumer, globalPosition)
+		return err
+	})
+	return position, err
+}
+
+// GetConsumerPosition forwards to the backend if it implements ConsumerPositionStore
+func (b *BreakerStore) GetConsumerPosition(ctx context.Context, namespace, consumer string) (position *ConsumerPosition, err error) {
+	positions, ok := b.Store.(ConsumerPositionStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = b.call(func() error {
+		position, err = positions.GetConsumerPosition(ctx, namespace, consumer)
+		return err
+	})
+	return position, err
+}
+
+// PositionGuarantee forwards to the backend
+func (b *BreakerStore) PositionGuarantee(ctx context.Context, namespace string) PositionGuarantee {
+	return PositionGuaranteeOf(ctx, b.Store, namespace)
+}
+
+// CommitsInOrder forwards to the backend if it implements CommitOrderer
+func (b *BreakerStore) CommitsInOrder(ctx context.Context, namespace string) bool {
+	orderer, ok := b.Store.(CommitOrderer)
+	return ok && orderer.CommitsInOrder(ctx, namespace)
+}
+
+// PendingWrites forwards to the backend if it implements PendingWriteInspector
+func (b *BreakerStore) PendingWrites(ctx context.Context, namespace, category string) (pending *PendingWrites, err error) {
+	inspector, ok := b.Store.(PendingWriteInspector)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = b.call(func() error {
+		pending, err = inspector.PendingWrites(ctx, namespace, category)
+		return err
+	})
+	return pending, err
+}
+
+// SwapNamespaceMessages forwards to the backend if it implements NamespaceSwapper
+func (b *BreakerStore) SwapNamespaceMessages(ctx context.Context, first, second string) error {
+	swapper, ok := b.Store.(NamespaceSwapper)
+	if !ok {
+		return ErrNotSupported
+	}
+	return b.call(func() error {
+		return swapper.SwapNamespaceMessages(ctx, first, second)
+	})
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// outageStore answers GetStreamVersion, or fails as unreachable while down
+type outageStore struct {
+	Store
+	down  bool
+	calls int
+}
+
+func (s *outageStore) GetStreamVersion(ctx context.Context, namespace, streamName string) (int64, error) {
+	s.calls++
+	if s.down {
+		return 0, syscall.ECONNREFUSED
+	}
+	return 3, nil
+}
+
+func TestBreakerStore_TripsAndRecovers(t *testing.T) {
+	inner := &outageStore{down: true}
+	var transitions []string
+	b := NewBreakerStore(inner, BreakerConfig{
+		Threshold: 3,
+		Cooldown:  50 * time.Millisecond,
+		OnStateChange: func(from, to string) {
+			transitions = append(transitions, from+"->"+to)
+		},
+	})
+	ctx := context.Background()
+
+	// Consecutive backend failures open the breaker
+	for i := 0; i < 3; i++ {
+		if _, err := b.GetStreamVersion(ctx, "ns", "account-1"); !errors.Is(err, syscall.ECONNREFUSED) {
+			t.Fatalf("Call %d: expected backend error, got %v", i, err)
+		}
+	}
+	if state := b.Stats().State; state != BreakerOpen {
+		t.Fatalf("Expected open breaker, got %s", state)
+	}
+
+	// While open, calls fail fast without reaching the backend
+	_, err := b.GetStreamVersion(ctx, "ns", "account-1")
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) || !IsBackendUnavailable(err) || unavailable.RetryAfter <= 0 {
+		t.Fatalf("Expected UnavailableError with RetryAfter, got %v", err)
+	}
+	if inner.calls != 3 {
+		t.Errorf("Expected backend not to be called while open, got %d calls", inner.calls)
+	}
+
+	// A failed probe keeps it open for another cooldown
+	time.Sleep(60 * time.Millisecond)
+	if _, err := b.GetStreamVersion(ctx, "ns", "account-1"); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("Expected probe to reach the backend, got %v", err)
+	}
+	if err := b.Check(); err == nil {
+		t.Fatalf("Expected breaker to reopen after failed probe")
+	}
+
+	// A successful probe closes it
+	inner.down = false
+	time.Sleep(60 * time.Millisecond)
+	if v, err := b.GetStreamVersion(ctx, "ns", "account-1"); err != nil || v != 3 {
+		t.Fatalf("Expected probe to succeed, got %d, %v", v, err)
+	}
+	stats := b.Stats()
+	if stats.State != BreakerClosed || stats.Trips != 1 || stats.Failures != 4 || stats.Rejected != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if len(transitions) != 2 || transitions[0] != "closed->open" || transitions[1] != "open->closed" {
+		t.Errorf("Unexpected transitions: %v", transitions)
+	}
+}
+
+func TestBreakerStore_IgnoresRequestErrors(t *testing.T) {
+	b := NewBreakerStore(&outageStore{}, BreakerConfig{Threshold: 1})
+	b.record(false, ErrVersionConflict)
+	b.record(false, context.Canceled)
+	if state := b.Stats().State; state != BreakerClosed {
+		t.Errorf("Request errors must not open the breaker, got %s", state)
+	}
+}
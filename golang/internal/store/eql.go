@@ -0,0 +1,738 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxFilterLength bounds the length of an EQL expression in bytes
+const MaxFilterLength = 4096
+
+// ErrInvalidFilter occurs when an EQL expression cannot be parsed
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// eqlPathPattern restricts the keys of data and metadata paths, so paths
+// can be written into SQL as literals
+var eqlPathPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLDialect selects the SQL a Filter compiles to
+type SQLDialect int
+
+const (
+	// DialectSQLite compiles to json_type/json_extract on TEXT columns, with
+	// time in Unix seconds
+	DialectSQLite SQLDialect = iota
+
+	// DialectPostgres compiles to jsonb operators, for Postgres and TimescaleDB
+	DialectPostgres
+)
+
+// Filter is a parsed EQL (EventoDB Query Language) expression that selects
+// messages, e.g.
+//
+//	type = 'Deposited' AND data.amount > 100 AND time > '2025-01-01'
+//
+// Comparisons take a field on the left and a literal on the right. Fields
+// are type, stream, position, globalPosition, time, and data.{path} or
+// metadata.{path} with dot-separated keys. Literals are strings in single
+// quotes (doubled inside a string), numbers, true and false; time takes an
+// RFC3339 or YYYY-MM-DD string. Operators are =, !=, <>, <, <=, >, >=,
+// IN (...), and IS [NOT] NULL for data and metadata paths, combined with
+// AND, OR, NOT and parentheses.
+//
+// A comparison is false when the path is missing or holds a value of
+// another JSON type than the literal, for != too: NOT data.x = 1 also
+// selects messages without data.x. Strings compare byte-wise.
+//
+// Every backend evaluates a filter identically: Pebble through Match,
+// SQLite, Postgres and TimescaleDB through the SQL compiled by SQL.
+type Filter struct {
+	expr string
+	root eqlNode
+}
+
+// ParseFilter parses an EQL expression
+func ParseFilter(expr string) (*Filter, error) {
+	if len(expr) > MaxFilterLength {
+		return nil, fmt.Errorf("%w: longer than %d bytes", ErrInvalidFilter, MaxFilterLength)
+	}
+	tokens, err := lexEQL(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	p := &eqlParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != eqlEOF {
+		err = fmt.Errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// String returns the expression the filter was parsed from
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match reports whether msg matches the filter
+func (f *Filter) Match(msg *Message) bool {
+	return f.root.match(msg)
+}
+
+// Uses reports whether the filter refers to a field: type, stream,
+// position, globalPosition, time, data or metadata
+func (f *Filter) Uses(field string) bool {
+	return eqlUses(f.root, field)
+}
+
+// eqlUses reports whether a node refers to field
+func eqlUses(n eqlNode, field string) bool {
+	switch n := n.(type) {
+	case *eqlLogic:
+		return eqlUses(n.left, field) || eqlUses(n.right, field)
+	case *eqlNot:
+		return eqlUses(n.expr, field)
+	case *eqlCompare:
+		return n.field == field
+	}
+	return false
+}
+
+// SQL compiles the filter to a boolean SQL expression over the columns of
+// the messages table, appending its parameters to args. Postgres
+// placeholders are numbered after the parameters already in args.
+func (f *Filter) SQL(dialect SQLDialect, args []interface{}) (string, []interface{}) {
+	c := &eqlCompiler{dialect: dialect, args: args}
+	return f.root.sql(c), c.args
+}
+
+// eqlNode is a node of a parsed expression
+type eqlNode interface {
+	match(msg *Message) bool
+	sql(c *eqlCompiler) string
+}
+
+// eqlLogic is AND or OR of two expressions
+type eqlLogic struct {
+	or          bool
+	left, right eqlNode
+}
+
+func (n *eqlLogic) match(msg *Message) bool {
+	if n.or {
+		return n.left.match(msg) || n.right.match(msg)
+	}
+	return n.left.match(msg) && n.right.match(msg)
+}
+
+func (n *eqlLogic) sql(c *eqlCompiler) string {
+	op := "AND"
+	if n.or {
+		op = "OR"
+	}
+	return "(" + n.left.sql(c) + " " + op + " " + n.right.sql(c) + ")"
+}
+
+// eqlNot negates an expression
+type eqlNot struct {
+	expr eqlNode
+}
+
+func (n *eqlNot) match(msg *Message) bool {
+	return !n.expr.match(msg)
+}
+
+func (n *eqlNot) sql(c *eqlCompiler) string {
+	return "(NOT " + n.expr.sql(c) + ")"
+}
+
+// eqlKind is the type of a literal
+type eqlKind int
+
+const (
+	eqlString eqlKind = iota
+	eqlNumber
+	eqlBool
+	eqlTime
+)
+
+// eqlValue is a literal
+type eqlValue struct {
+	kind eqlKind
+	s    string
+	n    float64
+	b    bool
+	t    time.Time
+}
+
+// eqlCompare compares a field with literals. Op is a comparison operator,
+// "in" (equal to any of values), "null" or "notnull".
+type eqlCompare struct {
+	field  string   // type, stream, position, globalPosition, time, data or metadata
+	path   []string // Keys below data or metadata
+	op     string
+	values []eqlValue
+}
+
+func (n *eqlCompare) match(msg *Message) bool {
+	var actual interface{}
+	found := true
+	switch n.field {
+	case "type":
+		actual = msg.Type
+	case "stream":
+		actual = msg.StreamName
+	case "position":
+		actual = float64(msg.Position)
+	case "globalPosition":
+		actual = float64(msg.GlobalPosition)
+	case "time":
+		actual = msg.Time
+	case "data":
+		actual, found = eqlLookup(msg.Data, n.path)
+	case "metadata":
+		actual, found = eqlLookup(msg.Metadata, n.path)
+	}
+
+	switch n.op {
+	case "null":
+		return !found || actual == nil
+	case "notnull":
+		return found && actual != nil
+	case "in":
+		for _, v := range n.values {
+			if cmp, ok := eqlCompareValue(actual, v); ok && cmp == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	if !found {
+		return false
+	}
+	cmp, ok := eqlCompareValue(actual, n.values[0])
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// eqlLookup returns the value at path in a JSON object
+func eqlLookup(obj map[string]interface{}, path []string) (interface{}, bool) {
+	var value interface{} = obj
+	for _, key := range path {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = fields[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// eqlCompareValue compares a message value with a literal of the same type.
+// Booleans compare equal or not; ok is false for values of another type.
+func eqlCompareValue(actual interface{}, v eqlValue) (int, bool) {
+	switch v.kind {
+	case eqlString:
+		s, ok := actual.(string)
+		return strings.Compare(s, v.s), ok
+	case eqlNumber:
+		var n float64
+		switch a := actual.(type) {
+		case float64:
+			n = a
+		case int64:
+			n = float64(a)
+		case int:
+			n = float64(a)
+		default:
+			return 0, false
+		}
+		switch {
+		case n < v.n:
+			return -1, true
+		case n > v.n:
+			return 1, true
+		}
+		return 0, true
+	case eqlBool:
+		b, ok := actual.(bool)
+		if b == v.b {
+			return 0, ok
+		}
+		return 1, ok
+	default:
+		t, ok := actual.(time.Time)
+		return t.Compare(v.t), ok
+	}
+}
+
+func (n *eqlCompare) sql(c *eqlCompiler) string {
+	switch n.op {
+	case "null", "notnull":
+		kind := c.jsonType(n.field, n.path)
+		if c.dialect == DialectPostgres {
+			kind = "jsonb_typeof(" + kind + ")"
+		}
+		if n.op == "null" {
+			return "COALESCE(" + kind + ", 'null') = 'null'"
+		}
+		return "COALESCE(" + kind + ", 'null') <> 'null'"
+	case "in":
+		terms := make([]string, len(n.values))
+		for i, v := range n.values {
+			terms[i] = c.compare(n, "=", v)
+		}
+		if len(terms) == 1 {
+			return terms[0]
+		}
+		return "(" + strings.Join(terms, " OR ") + ")"
+	}
+	op := n.op
+	if op == "!=" {
+		op = "<>"
+	}
+	return c.compare(n, op, n.values[0])
+}
+
+// eqlCompiler accumulates the parameters of a compiled filter
+type eqlCompiler struct {
+	dialect SQLDialect
+	args    []interface{}
+}
+
+// arg adds a parameter and returns its placeholder
+func (c *eqlCompiler) arg(v interface{}) string {
+	c.args = append(c.args, v)
+	if c.dialect == DialectPostgres {
+		return "$" + strconv.Itoa(len(c.args))
+	}
+	return "?"
+}
+
+// column returns the column of a message field
+func (c *eqlCompiler) column(field string) string {
+	switch field {
+	case "stream":
+		return "stream_name"
+	case "globalPosition":
+		return "global_position"
+	case "data", "metadata":
+		return field
+	}
+	return `"` + field + `"`
+}
+
+// jsonType returns an expression for the JSON type at a path: jsonb for
+// Postgres, json_type's name for SQLite. Path keys are validated
+// identifiers, so they are written as literals.
+func (c *eqlCompiler) jsonType(field string, path []string) string {
+	if c.dialect == DialectPostgres {
+		return field + " #> '{" + strings.Join(path, ",") + "}'"
+	}
+	return "json_type(" + field + ", '$." + strings.Join(path, ".") + "')"
+}
+
+// jsonValue returns an expression for the scalar at a path, as text for
+// Postgres and as an SQL value for SQLite
+func (c *eqlCompiler) jsonValue(field string, path []string) string {
+	if c.dialect == DialectPostgres {
+		return field + " #>> '{" + strings.Join(path, ",") + "}'"
+	}
+	return "json_extract(" + field + ", '$." + strings.Join(path, ".") + "')"
+}
+
+// compare compiles one comparison of n's field with v
+func (c *eqlCompiler) compare(n *eqlCompare, op string, v eqlValue) string {
+	if n.field != "data" && n.field != "metadata" {
+		column := c.column(n.field)
+		switch v.kind {
+		case eqlNumber:
+			return column + " " + op + " " + c.arg(int64(v.n))
+		case eqlTime:
+			if c.dialect == DialectPostgres {
+				return column + " " + op + " " + c.arg(v.t.UTC())
+			}
+			// SQLite stores whole Unix seconds: a literal with a fraction
+			// compares as the second before or after it
+			sec := v.t.Unix()
+			if v.t.Nanosecond() != 0 {
+				switch op {
+				case "=":
+					return "0"
+				case "<>":
+					return "1"
+				case ">=", "<":
+					sec++
+				}
+			}
+			return column + " " + op + " " + c.arg(sec)
+		}
+		if c.dialect == DialectPostgres && op != "=" && op != "<>" {
+			column += ` COLLATE "C"`
+		}
+		return column + " " + op + " " + c.arg(v.s)
+	}
+
+	// JSON values compare only with literals of their own type. CASE keeps
+	// the casts from seeing other types and makes missing paths false.
+	kind := c.jsonType(n.field, n.path)
+	value := c.jsonValue(n.field, n.path)
+	if c.dialect == DialectPostgres {
+		kind = "jsonb_typeof(" + kind + ")"
+		switch v.kind {
+		case eqlNumber:
+			return "CASE WHEN " + kind + " = 'number' THEN (" + value + ")::numeric " + op + " " + c.arg(v.n) + " ELSE false END"
+		case eqlBool:
+			return "CASE WHEN " + kind + " = 'boolean' THEN (" + value + ")::boolean " + op + " " + c.arg(v.b) + " ELSE false END"
+		}
+		return "CASE WHEN " + kind + " = 'string' THEN (" + value + `) COLLATE "C" ` + op + " " + c.arg(v.s) + " ELSE false END"
+	}
+	switch v.kind {
+	case eqlNumber:
+		return "CASE WHEN " + kind + " IN ('integer', 'real') THEN " + value + " " + op + " " + c.arg(v.n) + " ELSE 0 END"
+	case eqlBool:
+		want := "'true'"
+		if v.b != (op == "=") {
+			want = "'false'"
+		}
+		return "COALESCE(" + kind + " = " + want + ", 0)"
+	}
+	return "CASE WHEN " + kind + " = 'text' THEN " + value + " " + op + " " + c.arg(v.s) + " ELSE 0 END"
+}
+
+// eqlTokenKind classifies tokens
+type eqlTokenKind int
+
+const (
+	eqlEOF eqlTokenKind = iota
+	eqlIdent
+	eqlKeyword
+	eqlStringLit
+	eqlNumberLit
+	eqlOp
+	eqlPunct
+)
+
+// eqlToken is a lexed token; keywords are upper-cased
+type eqlToken struct {
+	kind eqlTokenKind
+	text string
+	pos  int
+}
+
+func (t eqlToken) String() string {
+	switch t.kind {
+	case eqlEOF:
+		return "end of filter"
+	case eqlStringLit:
+		return fmt.Sprintf("string at %d", t.pos)
+	}
+	return fmt.Sprintf("%q at %d", t.text, t.pos)
+}
+
+// eqlKeywords are the reserved words, matched case-insensitively
+var eqlKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true, "IS": true,
+	"NULL": true, "TRUE": true, "FALSE": true,
+}
+
+// lexEQL splits an expression into tokens
+func lexEQL(s string) ([]eqlToken, error) {
+	var tokens []eqlToken
+	i := 0
+	for i < len(s) {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(' || ch == ')' || ch == ',':
+			tokens = append(tokens, eqlToken{eqlPunct, string(ch), i})
+			i++
+		case ch == '=':
+			tokens = append(tokens, eqlToken{eqlOp, "=", i})
+			i++
+		case ch == '!' || ch == '<' || ch == '>':
+			op := s[i : i+1]
+			if i+1 < len(s) && (s[i+1] == '=' || (ch == '<' && s[i+1] == '>')) {
+				op = s[i : i+2]
+			}
+			switch op {
+			case "!":
+				return nil, fmt.Errorf("unexpected '!' at %d", i)
+			case "<>":
+				tokens = append(tokens, eqlToken{eqlOp, "!=", i})
+			default:
+				tokens = append(tokens, eqlToken{eqlOp, op, i})
+			}
+			i += len(op)
+		case ch == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(s) {
+					return nil, fmt.Errorf("unterminated string at %d", start)
+				}
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			tokens = append(tokens, eqlToken{eqlStringLit, b.String(), start})
+		case ch == '-' || ch == '.' || (ch >= '0' && ch <= '9'):
+			start := i
+			i++
+			for i < len(s) && (s[i] == '.' || s[i] == 'e' || s[i] == 'E' || (s[i] >= '0' && s[i] <= '9') ||
+				((s[i] == '+' || s[i] == '-') && (s[i-1] == 'e' || s[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, eqlToken{eqlNumberLit, s[start:i], start})
+		case ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z'):
+			start := i
+			for i < len(s) && (s[i] == '_' || s[i] == '.' || (s[i] >= 'a' && s[i] <= 'z') || (s[i] >= 'A' && s[i] <= 'Z') || (s[i] >= '0' && s[i] <= '9')) {
+				i++
+			}
+			word := s[start:i]
+			if upper := strings.ToUpper(word); eqlKeywords[upper] {
+				tokens = append(tokens, eqlToken{eqlKeyword, upper, start})
+			} else {
+				tokens = append(tokens, eqlToken{eqlIdent, word, start})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", ch, i)
+		}
+	}
+	return append(tokens, eqlToken{kind: eqlEOF, pos: len(s)}), nil
+}
+
+// eqlParser is a recursive descent parser:
+//
+//	or      = and { OR and }
+//	and     = not { AND not }
+//	not     = NOT not | "(" or ")" | compare
+//	compare = field op literal | field IN "(" literal { "," literal } ")" | field IS [NOT] NULL
+type eqlParser struct {
+	tokens []eqlToken
+	pos    int
+}
+
+func (p *eqlParser) peek() eqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *eqlParser) next() eqlToken {
+	t := p.tokens[p.pos]
+	if t.kind != eqlEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given keyword or punctuation
+func (p *eqlParser) accept(text string) bool {
+	if t := p.peek(); (t.kind == eqlKeyword || t.kind == eqlPunct) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *eqlParser) parseOr() (eqlNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &eqlLogic{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *eqlParser) parseAnd() (eqlNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &eqlLogic{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *eqlParser) parseNot() (eqlNode, error) {
+	if p.accept("NOT") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &eqlNot{expr: expr}, nil
+	}
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("expected ')', got %s", p.peek())
+		}
+		return expr, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *eqlParser) parseCompare() (eqlNode, error) {
+	t := p.next()
+	if t.kind != eqlIdent {
+		return nil, fmt.Errorf("expected a field, got %s", t)
+	}
+	n := &eqlCompare{}
+	field, rest, nested := strings.Cut(t.text, ".")
+	switch field {
+	case "type", "stream", "position", "globalPosition", "time":
+		if nested {
+			return nil, fmt.Errorf("field %s has no keys, at %d", field, t.pos)
+		}
+	case "data", "metadata":
+		if !nested {
+			return nil, fmt.Errorf("%s needs a path such as %s.amount, at %d", field, field, t.pos)
+		}
+		n.path = strings.Split(rest, ".")
+		for _, key := range n.path {
+			if !eqlPathPattern.MatchString(key) {
+				return nil, fmt.Errorf("invalid key %q in %s at %d", key, t.text, t.pos)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown field %q at %d (use type, stream, position, globalPosition, time, data.* or metadata.*)", t.text, t.pos)
+	}
+	n.field = field
+
+	switch op := p.next(); {
+	case op.kind == eqlOp:
+		n.op = op.text
+		v, err := p.parseValue(n)
+		if err != nil {
+			return nil, err
+		}
+		if v.kind == eqlBool && n.op != "=" && n.op != "!=" {
+			return nil, fmt.Errorf("booleans only compare with = and !=, at %d", op.pos)
+		}
+		n.values = []eqlValue{v}
+	case op.kind == eqlKeyword && op.text == "IN":
+		n.op = "in"
+		if !p.accept("(") {
+			return nil, fmt.Errorf("expected '(' after IN, got %s", p.peek())
+		}
+		for {
+			v, err := p.parseValue(n)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, fmt.Errorf("expected ',' or ')', got %s", p.peek())
+			}
+		}
+	case op.kind == eqlKeyword && op.text == "IS":
+		n.op = "null"
+		if p.accept("NOT") {
+			n.op = "notnull"
+		}
+		if !p.accept("NULL") {
+			return nil, fmt.Errorf("expected NULL, got %s", p.peek())
+		}
+		if n.path == nil {
+			return nil, fmt.Errorf("IS NULL applies to data and metadata paths, at %d", op.pos)
+		}
+	default:
+		return nil, fmt.Errorf("expected an operator after %s, got %s", t.text, op)
+	}
+	return n, nil
+}
+
+// parseValue parses a literal and checks it suits n's field
+func (p *eqlParser) parseValue(n *eqlCompare) (eqlValue, error) {
+	t := p.next()
+	var v eqlValue
+	switch {
+	case t.kind == eqlStringLit:
+		v = eqlValue{kind: eqlString, s: t.text}
+	case t.kind == eqlNumberLit:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil || math.IsInf(f, 0) {
+			return v, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		v = eqlValue{kind: eqlNumber, n: f}
+	case t.kind == eqlKeyword && (t.text == "TRUE" || t.text == "FALSE"):
+		v = eqlValue{kind: eqlBool, b: t.text == "TRUE"}
+	case t.kind == eqlKeyword && t.text == "NULL":
+		return v, fmt.Errorf("use IS NULL or IS NOT NULL to test for null, at %d", t.pos)
+	default:
+		return v, fmt.Errorf("expected a value, got %s", t)
+	}
+
+	switch n.field {
+	case "type", "stream":
+		if v.kind != eqlString {
+			return v, fmt.Errorf("%s compares with strings, at %d", n.field, t.pos)
+		}
+	case "position", "globalPosition":
+		if v.kind != eqlNumber || v.n != math.Trunc(v.n) || math.Abs(v.n) > 1<<53 {
+			return v, fmt.Errorf("%s compares with integers, at %d", n.field, t.pos)
+		}
+	case "time":
+		if v.kind != eqlString {
+			return v, fmt.Errorf("time compares with an RFC3339 or YYYY-MM-DD string, at %d", t.pos)
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, v.s)
+		if err != nil {
+			if parsed, err = time.Parse("2006-01-02", v.s); err != nil {
+				return v, fmt.Errorf("invalid time %q at %d (use RFC3339 or YYYY-MM-DD)", v.s, t.pos)
+			}
+		}
+		v = eqlValue{kind: eqlTime, t: parsed.UTC()}
+	}
+	return v, nil
+}
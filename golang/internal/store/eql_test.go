@@ -0,0 +1,117 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseFilter_Match(t *testing.T) {
+	msg := &Message{
+		StreamName:     "account-123",
+		Type:           "Deposited",
+		Position:       4,
+		GlobalPosition: 42,
+		Data: map[string]interface{}{
+			"amount":   float64(150),
+			"currency": "EUR",
+			"flagged":  false,
+			"note":     nil,
+			"owner":    map[string]interface{}{"name": "O'Brien"},
+		},
+		Metadata: map[string]interface{}{"region": "eu"},
+		Time:     time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"type = 'Deposited' AND data.amount > 100 AND time > '2025-01-01'", true},
+		{"type = 'Withdrawn' OR data.amount >= 150", true},
+		{"NOT type = 'Deposited'", false},
+		{"type IN ('Withdrawn', 'Deposited')", true},
+		{"type <> 'Deposited'", false},
+		{"stream = 'account-123' and globalPosition > 40 and position <= 4", true},
+		{"data.owner.name = 'O''Brien'", true},
+		{"data.currency < 'USD'", true},
+		{"data.flagged = false", true},
+		{"data.flagged != true", true},
+		{"metadata.region = 'eu' AND (data.amount < 10 OR data.amount > 100)", true},
+		{"time >= '2025-03-01T12:00:00Z' AND time < '2025-03-01T12:00:00.5Z'", true},
+
+		// Missing paths and other types compare false, for != too
+		{"data.missing != 1", false},
+		{"NOT data.missing = 1", true},
+		{"data.currency > 100", false},
+		{"data.amount = '150'", false},
+
+		{"data.note IS NULL", true},
+		{"data.missing IS NULL", true},
+		{"data.amount IS NOT NULL", true},
+		{"data.note IS NOT NULL", false},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(msg); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"type",
+		"type = ",
+		"type = 1",
+		"category = 'x'",
+		"data = 1",
+		"data.a-b = 1",
+		"position > 1.5",
+		"time > 'yesterday'",
+		"data.x = null",
+		"type IS NULL",
+		"data.flag > true",
+		"(type = 'x'",
+		"type = 'x' extra",
+		"type = 'unterminated",
+		"type ! 'x'",
+	} {
+		if _, err := ParseFilter(expr); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("ParseFilter(%q): expected ErrInvalidFilter, got %v", expr, err)
+		}
+	}
+}
+
+func TestFilter_SQL(t *testing.T) {
+	f, err := ParseFilter("type = 'Deposited' AND (data.amount > 100 OR NOT metadata.tags.vip IS NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sql, args := f.SQL(DialectSQLite, nil)
+	want := `("type" = ? AND (CASE WHEN json_type(data, '$.amount') IN ('integer', 'real') THEN json_extract(data, '$.amount') > ? ELSE 0 END OR ` +
+		`(NOT COALESCE(json_type(metadata, '$.tags.vip'), 'null') = 'null')))`
+	if sql != want {
+		t.Errorf("SQLite:\n got %s\nwant %s", sql, want)
+	}
+	if len(args) != 2 || args[0] != "Deposited" || args[1] != float64(100) {
+		t.Errorf("unexpected SQLite args %v", args)
+	}
+
+	// Postgres placeholders follow the caller's parameters
+	sql, args = f.SQL(DialectPostgres, []interface{}{int64(1)})
+	want = `("type" = $2 AND (CASE WHEN jsonb_typeof(data #> '{amount}') = 'number' THEN (data #>> '{amount}')::numeric > $3 ELSE false END OR ` +
+		`(NOT COALESCE(jsonb_typeof(metadata #> '{tags,vip}'), 'null') = 'null')))`
+	if sql != want {
+		t.Errorf("Postgres:\n got %s\nwant %s", sql, want)
+	}
+	if len(args) != 3 || args[1] != "Deposited" {
+		t.Errorf("unexpected Postgres args %v", args)
+	}
+}
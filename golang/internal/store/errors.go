@@ -1,8 +1,14 @@
 package store
 
 import (
+	"context"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
 )
 
 var (
@@ -21,6 +27,12 @@ var (
 	// ErrStreamNotFound occurs when stream doesn't exist
 	ErrStreamNotFound = errors.New("stream not found")
 
+	// ErrMessageNotFound occurs when no message is at a stream position
+	ErrMessageNotFound = errors.New("message not found")
+
+	// ErrConsumerPositionNotFound occurs when a consumer has no recorded position
+	ErrConsumerPositionNotFound = errors.New("consumer position not found")
+
 	// ErrInvalidStreamName occurs when stream name format is invalid
 	ErrInvalidStreamName = errors.New("invalid stream name format")
 
@@ -32,6 +44,12 @@ var (
 
 	// ErrClosed occurs when operating on a closed store
 	ErrClosed = errors.New("store is closed")
+
+	// ErrBackendUnavailable occurs when the backend cannot be reached
+	ErrBackendUnavailable = errors.New("backend unavailable")
+
+	// ErrNotSupported occurs when the backend lacks an optional capability
+	ErrNotSupported = errors.New("not supported by this backend")
 )
 
 // VersionConflictError provides detailed information about version conflicts
@@ -66,3 +84,54 @@ func IsVersionConflict(err error) bool {
 	}
 	return errors.Is(err, ErrVersionConflict)
 }
+
+// UnavailableError is returned by a circuit breaker that rejects calls while
+// the backend is down
+type UnavailableError struct {
+	RetryAfter time.Duration // Time until the breaker next probes the backend
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("backend unavailable, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrBackendUnavailable
+}
+
+// IsBackendUnavailable reports whether err means the backend could not be
+// reached, as opposed to rejecting the request
+func IsBackendUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrBackendUnavailable) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// Postgres: connection exceptions (class 08) and shutdown/startup (57P01-57P03)
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		state := pgErr.SQLState()
+		return strings.HasPrefix(state, "08") || state == "57P01" || state == "57P02" || state == "57P03"
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"connection refused", "connection reset", "broken pipe", "failed to connect", "conn closed"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
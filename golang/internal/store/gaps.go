@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxGapRange bounds the global positions one gap search reads
+const MaxGapRange = 10000
+
+// PositionGap is a run of global positions, From to To inclusive, that hold
+// no visible message
+type PositionGap struct {
+	From int64
+	To   int64
+
+	// InFlight is true when a write to the searched category may still
+	// commit messages at these positions. Other gaps are final: the positions
+	// were deleted, rolled back, or belong to no write of the category.
+	InFlight bool
+}
+
+// PendingWrites is what a backend knows of writes not yet visible
+type PendingWrites struct {
+	// Allocated is the highest global position handed out, committed or not
+	Allocated int64
+
+	// InFlight is true when a write to the category may be uncommitted
+	InFlight bool
+}
+
+// PendingWriteInspector is implemented by backends whose global positions
+// may become visible out of order (Postgres, TimescaleDB). It is used to
+// tell gaps left by rolled back writes from writes still being committed.
+type PendingWriteInspector interface {
+	// PendingWrites returns the namespace's allocated positions and whether a
+	// write to category, or to any category for "", is in progress. Writes
+	// to one category are serialized, so an in-progress write only holds
+	// positions above the category's last visible message.
+	PendingWrites(ctx context.Context, namespace, category string) (*PendingWrites, error)
+}
+
+// FindPositionGaps returns the gaps among the namespace's global positions
+// from from to to. Positions above the last visible message count as gaps
+// only when the backend reports them allocated.
+//
+// Pending writes are inspected before the messages are read: a write to the
+// category that starts later allocates positions above the ones reported,
+// and one that finished earlier is visible. On backends that commit in order
+// no gap is in flight.
+func FindPositionGaps(ctx context.Context, st Store, namespace, category string, from, to int64) ([]PositionGap, error) {
+	if from < 1 {
+		from = 1
+	}
+	if to < from {
+		return nil, nil
+	}
+	if to-from >= MaxGapRange {
+		return nil, fmt.Errorf("gap searches span at most %d positions", MaxGapRange)
+	}
+
+	var pending *PendingWrites
+	if inspector, ok := st.(PendingWriteInspector); ok {
+		if orderer, ok := st.(CommitOrderer); !ok || !orderer.CommitsInOrder(ctx, namespace) {
+			var err error
+			pending, err = inspector.PendingWrites(ctx, namespace, category)
+			if err != nil && !errors.Is(err, ErrNotSupported) {
+				return nil, err
+			}
+		}
+	}
+
+	msgs, err := st.GetCategoryMessages(ctx, namespace, "", &CategoryOpts{Position: from, BatchSize: to - from + 1})
+	if err != nil {
+		return nil, err
+	}
+
+	// The category's last visible message; in-progress writes are above it.
+	// Any gap may belong to a write when searching all categories.
+	categoryLast := from - 1
+	last := from - 1
+	for _, msg := range msgs {
+		if msg.GlobalPosition > to {
+			break
+		}
+		if category != "" && Category(msg.StreamName) == category {
+			categoryLast = msg.GlobalPosition
+		}
+		last = msg.GlobalPosition
+	}
+	if pending != nil && pending.Allocated > last {
+		last = min(pending.Allocated, to)
+	}
+
+	var gaps []PositionGap
+	addGap := func(gapFrom, gapTo int64) {
+		if gapFrom > gapTo {
+			return
+		}
+		gaps = append(gaps, PositionGap{
+			From:     gapFrom,
+			To:       gapTo,
+			InFlight: pending != nil && pending.InFlight && gapFrom > categoryLast,
+		})
+	}
+	next := from
+	for _, msg := range msgs {
+		if msg.GlobalPosition > last {
+			break
+		}
+		addGap(next, msg.GlobalPosition-1)
+		next = msg.GlobalPosition + 1
+	}
+	addGap(next, last)
+	return gaps, nil
+}
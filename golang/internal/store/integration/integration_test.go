@@ -3,6 +3,7 @@ package integration
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -718,3 +719,291 @@ func TestMDB001_6A_T7_ConcurrentWritesSameStream(t *testing.T) {
 		}
 	})
 }
+
+// MDB001_6A_T5: Test that every backend assigns streams like store.Partitioner
+func TestMDB001_6A_T5_PartitionerParity(t *testing.T) {
+	runWithBothBackends(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+
+		ns := fmt.Sprintf("test_ns_%d", time.Now().UnixNano())
+		if err := s.CreateNamespace(ctx, ns, "token_hash", "Test namespace"); err != nil {
+			t.Fatalf("Failed to create namespace: %v", err)
+		}
+
+		numStreams := 30
+		for i := 0; i < numStreams; i++ {
+			msg := &store.Message{
+				StreamName: fmt.Sprintf("account-%d+x", i),
+				Type:       "AccountCreated",
+				Data:       map[string]interface{}{"id": i},
+			}
+			if _, err := s.WriteMessage(ctx, ns, msg.StreamName, msg); err != nil {
+				t.Fatalf("Failed to write message: %v", err)
+			}
+		}
+
+		consumerSize := int64(3)
+		for _, p := range store.Partitioners {
+			total := 0
+			for member := int64(0); member < consumerSize; member++ {
+				m := member
+				opts := store.NewCategoryOpts()
+				opts.ConsumerMember = &m
+				opts.ConsumerSize = &consumerSize
+				opts.Partitioner = p
+				opts.BatchSize = 100
+
+				messages, err := s.GetCategoryMessages(ctx, ns, "account", opts)
+				if err != nil {
+					t.Fatalf("%s: failed to get messages for consumer %d: %v", p, member, err)
+				}
+				for _, msg := range messages {
+					if got := p.Member(msg.StreamName, consumerSize); got != member {
+						t.Errorf("%s: stream %s read by consumer %d, assigned to %d", p, msg.StreamName, member, got)
+					}
+				}
+				total += len(messages)
+			}
+			if total != numStreams {
+				t.Errorf("%s: expected %d messages across consumers, got %d", p, numStreams, total)
+			}
+		}
+	})
+}
+
+// MDB001_6A_T9: Test that every backend selects the messages Filter.Match selects
+func TestMDB001_6A_T9_FilterParity(t *testing.T) {
+	runWithBothBackends(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+
+		ns := fmt.Sprintf("test_ns_%d", time.Now().UnixNano())
+		if err := s.CreateNamespace(ctx, ns, "token_hash", "Test namespace"); err != nil {
+			t.Fatalf("Failed to create namespace: %v", err)
+		}
+
+		amounts := []interface{}{float64(50), float64(150), "150", nil, float64(250), true}
+		for i, amount := range amounts {
+			data := map[string]interface{}{"owner": map[string]interface{}{"tier": "gold"}}
+			if i != 3 {
+				data["amount"] = amount
+			}
+			msgType := "Deposited"
+			if i%2 == 1 {
+				msgType = "Withdrawn"
+			}
+			msg := &store.Message{
+				StreamName: fmt.Sprintf("account-%d", i%3),
+				Type:       msgType,
+				Data:       data,
+				Metadata:   map[string]interface{}{"region": []string{"eu", "us"}[i%2]},
+			}
+			if _, err := s.WriteMessage(ctx, ns, msg.StreamName, msg); err != nil {
+				t.Fatalf("Failed to write message: %v", err)
+			}
+		}
+
+		opts := store.NewCategoryOpts()
+		all, err := s.GetCategoryMessages(ctx, ns, "account", opts)
+		if err != nil {
+			t.Fatalf("Failed to get category messages: %v", err)
+		}
+
+		for _, expr := range []string{
+			"type = 'Deposited' AND data.amount > 100",
+			"data.amount >= 150 OR metadata.region = 'us'",
+			"NOT data.amount = 150",
+			"data.amount != 150",
+			"data.amount IN (50, '150')",
+			"data.amount IS NULL",
+			"data.amount = true",
+			"data.owner.tier = 'gold' AND stream > 'account-0' AND position >= 1",
+			"time > '2000-01-01' AND time <= '2999-01-01T00:00:00.5Z'",
+		} {
+			filter, err := store.ParseFilter(expr)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q): %v", expr, err)
+			}
+			var want []int64
+			for _, msg := range all {
+				if filter.Match(msg) {
+					want = append(want, msg.GlobalPosition)
+				}
+			}
+
+			filtered := store.NewCategoryOpts()
+			filtered.Filter = filter
+			messages, err := s.GetCategoryMessages(ctx, ns, "account", filtered)
+			if err != nil {
+				t.Fatalf("%q: failed to get messages: %v", expr, err)
+			}
+			got := make([]int64, len(messages))
+			for i, msg := range messages {
+				got[i] = msg.GlobalPosition
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%q: expected %v, got %v", expr, want, got)
+			}
+		}
+	})
+}
+
+// TestMDB001_6A_T10_SampleParity tests that every backend samples the same
+// messages as InSample, before applying the batch size
+func TestMDB001_6A_T10_SampleParity(t *testing.T) {
+	runWithBothBackends(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+
+		ns := fmt.Sprintf("test_ns_%d", time.Now().UnixNano())
+		if err := s.CreateNamespace(ctx, ns, "token_hash", "Test namespace"); err != nil {
+			t.Fatalf("Failed to create namespace: %v", err)
+		}
+
+		for i := 0; i < 200; i++ {
+			msg := &store.Message{
+				StreamName: fmt.Sprintf("account-%d", i%7),
+				Type:       "Deposited",
+				Data:       map[string]interface{}{"amount": float64(i)},
+			}
+			if _, err := s.WriteMessage(ctx, ns, msg.StreamName, msg); err != nil {
+				t.Fatalf("Failed to write message: %v", err)
+			}
+		}
+
+		all, err := s.GetCategoryMessages(ctx, ns, "account", store.NewCategoryOpts())
+		if err != nil {
+			t.Fatalf("Failed to get category messages: %v", err)
+		}
+		var want []int64
+		for _, msg := range all {
+			if store.InSample(msg.ID, 0.2) {
+				want = append(want, msg.GlobalPosition)
+			}
+		}
+		if len(want) < 2 {
+			t.Fatalf("Expected a sample of several messages, got %v", want)
+		}
+
+		sampled := store.NewCategoryOpts()
+		sampled.Sample = 0.2
+		messages, err := s.GetCategoryMessages(ctx, ns, "account", sampled)
+		if err != nil {
+			t.Fatalf("Failed to get sampled messages: %v", err)
+		}
+		got := make([]int64, len(messages))
+		for i, msg := range messages {
+			got[i] = msg.GlobalPosition
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+
+		// Messages outside the sample do not count toward the batch
+		sampled.BatchSize = 2
+		messages, err = s.GetCategoryMessages(ctx, ns, "account", sampled)
+		if err != nil {
+			t.Fatalf("Failed to get sampled messages: %v", err)
+		}
+		if len(messages) != 2 || messages[1].GlobalPosition != want[1] {
+			t.Errorf("Expected the first two sampled messages, got %d messages", len(messages))
+		}
+	})
+}
+
+// TestMDB001_6A_T11_MergeStreams tests that merged streams keep their
+// messages' global positions and are renumbered in global position order
+func TestMDB001_6A_T11_MergeStreams(t *testing.T) {
+	runWithBothBackends(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+
+		ns := fmt.Sprintf("test_ns_%d", time.Now().UnixNano())
+		if err := s.CreateNamespace(ctx, ns, "token_hash", "Test namespace"); err != nil {
+			t.Fatalf("Failed to create namespace: %v", err)
+		}
+
+		streams := []string{"account-1", "Account-1", "account-1 ", "account-1"}
+		for i, stream := range streams {
+			msg := &store.Message{Type: fmt.Sprintf("Event%d", i), Data: map[string]interface{}{"n": float64(i)}}
+			if _, err := s.WriteMessage(ctx, ns, stream, msg); err != nil {
+				t.Fatalf("Failed to write message: %v", err)
+			}
+		}
+
+		moved, err := s.(store.StreamMerger).MergeStreams(ctx, ns, "account-1", []string{"Account-1", "account-1 "})
+		if err != nil {
+			t.Fatalf("MergeStreams failed: %v", err)
+		}
+		if moved != 2 {
+			t.Errorf("Expected 2 messages moved, got %d", moved)
+		}
+
+		msgs, err := s.GetStreamMessages(ctx, ns, "account-1", store.NewGetOpts())
+		if err != nil {
+			t.Fatalf("Failed to get stream messages: %v", err)
+		}
+		if len(msgs) != 4 {
+			t.Fatalf("Expected 4 messages, got %d", len(msgs))
+		}
+		for i, msg := range msgs {
+			if msg.Position != int64(i) || msg.GlobalPosition != int64(i+1) || msg.Data["n"] != float64(i) || msg.StreamName != "account-1" {
+				t.Errorf("Unexpected message at %d: %+v", i, msg)
+			}
+		}
+		for _, source := range []string{"Account-1", "account-1 "} {
+			if version, err := s.GetStreamVersion(ctx, ns, source); err != nil || version != -1 {
+				t.Errorf("Expected %q to be empty, got version %d (%v)", source, version, err)
+			}
+		}
+
+		// Moved messages are found by category and type, and writes continue
+		// after the merged stream's last position
+		all, err := s.GetCategoryMessages(ctx, ns, "account", store.NewCategoryOpts())
+		if err != nil || len(all) != 4 {
+			t.Errorf("Expected 4 category messages, got %d (%v)", len(all), err)
+		}
+		msgType := "Event1"
+		if last, err := s.GetLastStreamMessage(ctx, ns, "account-1", &msgType); err != nil || last.Position != 1 {
+			t.Errorf("Expected Event1 at position 1, got %+v (%v)", last, err)
+		}
+		result, err := s.WriteMessage(ctx, ns, "account-1", &store.Message{Type: "Event4", Data: map[string]interface{}{}})
+		if err != nil || result.Position != 4 {
+			t.Errorf("Expected the next write at position 4, got %+v (%v)", result, err)
+		}
+	})
+}
+
+// TestMDB001_6A_T12_ConsumerPositions tests that consumer positions are
+// replaced by later sets and kept apart per consumer
+func TestMDB001_6A_T12_ConsumerPositions(t *testing.T) {
+	runWithBothBackends(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+
+		ns := fmt.Sprintf("test_ns_%d", time.Now().UnixNano())
+		if err := s.CreateNamespace(ctx, ns, "token_hash", "Test namespace"); err != nil {
+			t.Fatalf("Failed to create namespace: %v", err)
+		}
+		positions := s.(store.ConsumerPositionStore)
+
+		if _, err := positions.GetConsumerPosition(ctx, ns, "billing"); !errors.Is(err, store.ErrConsumerPositionNotFound) {
+			t.Fatalf("Expected ErrConsumerPositionNotFound, got %v", err)
+		}
+
+		for _, gp := range []int64{10, 42} {
+			if _, err := positions.SetConsumerPosition(ctx, ns, "billing", gp); err != nil {
+				t.Fatalf("SetConsumerPosition failed: %v", err)
+			}
+		}
+		if _, err := positions.SetConsumerPosition(ctx, ns, "shipping", 7); err != nil {
+			t.Fatalf("SetConsumerPosition failed: %v", err)
+		}
+
+		for consumer, want := range map[string]int64{"billing": 42, "shipping": 7} {
+			position, err := positions.GetConsumerPosition(ctx, ns, consumer)
+			if err != nil {
+				t.Fatalf("GetConsumerPosition failed: %v", err)
+			}
+			if position.GlobalPosition != want || position.Updated.IsZero() {
+				t.Errorf("Expected %s at %d, got %+v", consumer, want, position)
+			}
+		}
+	})
+}
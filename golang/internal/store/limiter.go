@@ -0,0 +1,541 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTargetLatency is the store call latency above which the
+	// concurrency limit shrinks
+	DefaultTargetLatency = 50 * time.Millisecond
+
+	// DefaultLimiterMaxWait is how long a call waits for a free slot
+	DefaultLimiterMaxWait = time.Second
+
+	// limiterBackoff is the factor applied to the limit after a slow call
+	limiterBackoff = 0.9
+)
+
+// ErrOverloaded occurs when a call is shed by the concurrency limiter
+var ErrOverloaded = errors.New("store overloaded")
+
+// OverloadedError is returned when no concurrency slot frees up in time
+type OverloadedError struct {
+	RetryAfter time.Duration // Suggested wait before retrying
+	Limit      int           // Concurrency limit when the call was shed
+	InFlight   int           // Calls running when the call was shed
+}
+
+func (e *OverloadedError) Error() string {
+	return fmt.Sprintf("store overloaded, retry in %s", e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *OverloadedError) Is(target error) bool {
+	return target == ErrOverloaded
+}
+
+// IsOverloaded checks if an error is a concurrency limiter rejection
+func IsOverloaded(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrOverloaded)
+}
+
+// ConcurrencyConfig configures a ConcurrencyLimiter
+type ConcurrencyConfig struct {
+	InitialLimit  int           // Starting limit (default: MaxLimit / 4, at least MinLimit)
+	MinLimit      int           // Lowest limit (default: 1)
+	MaxLimit      int           // Highest limit (required)
+	TargetLatency time.Duration // Calls slower than this shrink the limit (default: 50ms)
+	MaxWait       time.Duration // Time a call waits for a slot (default: 1s)
+	MaxQueue      int           // Calls allowed to wait (default: MaxLimit)
+}
+
+// ConcurrencyStats describes a limiter for metrics
+type ConcurrencyStats struct {
+	Limit    int   `json:"limit"`
+	InFlight int   `json:"inFlight"`
+	Waiting  int   `json:"waiting"`
+	Rejected int64 `json:"rejected"` // Calls shed since start
+}
+
+// ReadPriority ranks reads for scheduling by the concurrency limiter. Under
+// load, waiting high-priority calls get free slots first and low-priority
+// calls run behind them, so rebuilds and exports do not slow interactive
+// reads down.
+type ReadPriority int
+
+const (
+	// ReadPriorityLow covers background work such as rebuilds and exports
+	ReadPriorityLow ReadPriority = iota
+
+	// ReadPriorityNormal is the default
+	ReadPriorityNormal
+
+	// ReadPriorityHigh covers latency-sensitive interactive reads
+	ReadPriorityHigh
+)
+
+// lowPriorityShare is the fraction of the limit low-priority calls may use,
+// leaving the rest free for other calls arriving while they run
+const lowPriorityShare = 0.75
+
+// ParseReadPriority parses "low", "normal" or "high"
+func ParseReadPriority(s string) (ReadPriority, error) {
+	switch s {
+	case "low":
+		return ReadPriorityLow, nil
+	case "normal":
+		return ReadPriorityNormal, nil
+	case "high":
+		return ReadPriorityHigh, nil
+	}
+	return ReadPriorityNormal, fmt.Errorf("unknown priority %q (low, normal or high)", s)
+}
+
+// String returns the priority name
+func (p ReadPriority) String() string {
+	switch p {
+	case ReadPriorityLow:
+		return "low"
+	case ReadPriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// readPriorityKey is the context key for the priority of store calls
+type readPriorityKey struct{}
+
+// WithReadPriority returns a context whose store calls are scheduled with priority
+func WithReadPriority(ctx context.Context, priority ReadPriority) context.Context {
+	return context.WithValue(ctx, readPriorityKey{}, priority)
+}
+
+// ReadPriorityFromContext returns the priority set by WithReadPriority, or
+// ReadPriorityNormal
+func ReadPriorityFromContext(ctx context.Context) ReadPriority {
+	if priority, ok := ctx.Value(readPriorityKey{}).(ReadPriority); ok {
+		return priority
+	}
+	return ReadPriorityNormal
+}
+
+// ConcurrencyLimiter bounds concurrent calls with an AIMD limit.
+//
+// Each call that finishes within TargetLatency while the limiter is busy
+// raises the limit by one; each slower call, deadline or outage multiplies it
+// by 0.9. Calls over the limit wait up to MaxWait, and at most MaxQueue of
+// them wait at once, so a burst degrades into fast OverloadedError rejections
+// instead of thousands of goroutines piling up on the backend.
+//
+// Waiting calls get free slots by priority (see WithReadPriority), FIFO
+// within a priority. Low-priority calls use at most 3/4 of the limit, and when
+// the queue is full a call displaces the newest waiter of a lower priority.
+type ConcurrencyLimiter struct {
+	cfg ConcurrencyConfig
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	waiters  [3]list.List // of *limiterWaiter, by priority
+	waiting  int
+	rejected int64
+}
+
+// limiterWaiter is a call waiting for a slot
+type limiterWaiter struct {
+	ready   chan struct{}
+	granted bool
+	shed    bool // Displaced by a higher-priority call
+}
+
+// NewConcurrencyLimiter creates a limiter
+func NewConcurrencyLimiter(cfg ConcurrencyConfig) *ConcurrencyLimiter {
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = 1
+	}
+	cfg.MaxLimit = max(cfg.MaxLimit, cfg.MinLimit)
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = max(cfg.MaxLimit/4, cfg.MinLimit)
+	}
+	cfg.InitialLimit = min(cfg.InitialLimit, cfg.MaxLimit)
+	if cfg.TargetLatency <= 0 {
+		cfg.TargetLatency = DefaultTargetLatency
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = DefaultLimiterMaxWait
+	}
+	if cfg.MaxQueue <= 0 {
+		cfg.MaxQueue = cfg.MaxLimit
+	}
+	return &ConcurrencyLimiter{cfg: cfg, limit: float64(cfg.InitialLimit)}
+}
+
+// Acquire waits for a slot, scheduled by the priority of ctx. The returned
+// function must be called with the call's result when it finishes.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (func(error), error) {
+	priority := ReadPriorityFromContext(ctx)
+
+	l.mu.Lock()
+	if l.inFlight < l.slots(priority) && !l.queuedAtOrAbove(priority) {
+		l.inFlight++
+		l.mu.Unlock()
+		return l.releaser(), nil
+	}
+	if l.waiting >= l.cfg.MaxQueue && !l.shedBelow(priority) {
+		l.rejected++
+		err := l.overloaded()
+		l.mu.Unlock()
+		return nil, err
+	}
+	w := &limiterWaiter{ready: make(chan struct{})}
+	elem := l.waiters[priority].PushBack(w)
+	l.waiting++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.cfg.MaxWait)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+	case <-timer.C:
+		err = &OverloadedError{RetryAfter: l.cfg.MaxWait}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.granted {
+		// The slot was handed over, possibly as we gave up; use it
+		return l.releaser(), nil
+	}
+	if w.shed {
+		// Already removed and counted as rejected
+		return nil, l.overloaded()
+	}
+	l.waiters[priority].Remove(elem)
+	l.waiting--
+	if IsOverloaded(err) {
+		l.rejected++
+		err = l.overloaded()
+	}
+	return nil, err
+}
+
+// overloaded returns the error for a shed call; l.mu must be held
+func (l *ConcurrencyLimiter) overloaded() error {
+	return &OverloadedError{RetryAfter: l.cfg.MaxWait, Limit: int(l.limit), InFlight: l.inFlight}
+}
+
+// slots returns the number of calls in flight below which priority may start
+func (l *ConcurrencyLimiter) slots(priority ReadPriority) int {
+	if priority == ReadPriorityLow {
+		return max(1, int(l.limit*lowPriorityShare))
+	}
+	return int(l.limit)
+}
+
+// queuedAtOrAbove reports whether calls of priority or higher are waiting
+func (l *ConcurrencyLimiter) queuedAtOrAbove(priority ReadPriority) bool {
+	for p := priority; p <= ReadPriorityHigh; p++ {
+		if l.waiters[p].Len() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// shedBelow rejects the newest waiter of the lowest priority below priority
+// to make room in the queue, reporting whether there was one
+func (l *ConcurrencyLimiter) shedBelow(priority ReadPriority) bool {
+	for p := ReadPriorityLow; p < priority; p++ {
+		if back := l.waiters[p].Back(); back != nil {
+			w := l.waiters[p].Remove(back).(*limiterWaiter)
+			w.shed = true
+			l.waiting--
+			l.rejected++
+			close(w.ready)
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the limiter's current limit and counters
+func (l *ConcurrencyLimiter) Stats() ConcurrencyStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return ConcurrencyStats{
+		Limit:    int(l.limit),
+		InFlight: l.inFlight,
+		Waiting:  l.waiting,
+		Rejected: l.rejected,
+	}
+}
+
+// releaser returns the function that frees a slot and adjusts the limit
+func (l *ConcurrencyLimiter) releaser() func(error) {
+	start := time.Now()
+	return func(err error) {
+		slow := time.Since(start) > l.cfg.TargetLatency ||
+			errors.Is(err, context.DeadlineExceeded) || IsBackendUnavailable(err)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		// Only grow while the limit is actually used, so an idle server
+		// does not drift to MaxLimit
+		busy := l.inFlight*2 >= int(l.limit)
+		l.inFlight--
+		if slow {
+			l.limit = math.Max(float64(l.cfg.MinLimit), l.limit*limiterBackoff)
+		} else if busy {
+			l.limit = math.Min(float64(l.cfg.MaxLimit), l.limit+1)
+		}
+
+		// Hand free slots to waiters, highest priority first
+		for p := ReadPriorityHigh; p >= ReadPriorityLow; p-- {
+			for l.inFlight < l.slots(p) && l.waiters[p].Len() > 0 {
+				w := l.waiters[p].Remove(l.waiters[p].Front()).(*limiterWaiter)
+				w.granted = true
+				l.waiting--
+				l.inFlight++
+				close(w.ready)
+			}
+			if l.waiters[p].Len() > 0 {
+				// Lower priorities wait behind this one
+				break
+			}
+		}
+	}
+}
+
+// LimiterStore wraps a Store with adaptive concurrency limits, one for
+// message writes and one for message reads. SQLite serializes writes, so the
+// write limit settles low while reads keep their own budget. Namespace
+// management calls are not limited.
+type LimiterStore struct {
+	Store
+	writes *ConcurrencyLimiter
+	reads  *ConcurrencyLimiter
+}
+
+// NewLimiterStore wraps st with separate write and read limiters built from cfg
+func NewLimiterStore(st Store, cfg ConcurrencyConfig) *LimiterStore {
+	return &LimiterStore{
+		Store:  st,
+		writes: NewConcurrencyLimiter(cfg),
+		reads:  NewConcurrencyLimiter(cfg),
+	}
+}
+
+// Stats returns the write and read limiter stats
+func (s *LimiterStore) Stats() (writes, reads ConcurrencyStats) {
+	return s.writes.Stats(), s.reads.Stats()
+}
+
+// call runs fn with a slot from l
+func (s *LimiterStore) call(ctx context.Context, l *ConcurrencyLimiter, fn func() error) error {
+	release, err := l.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn()
+	release(err)
+	return err
+}
+
+func (s *LimiterStore) WriteMessage(ctx context.Context, namespace, streamName string, msg *Message) (result *WriteResult, err error) {
+	err = s.call(ctx, s.writes, func() error {
+		result, err = s.Store.WriteMessage(ctx, namespace, streamName, msg)
+		return err
+	})
+	return result, err
+}
+
+func (s *LimiterStore) ImportBatch(ctx context.Context, namespace string, messages []*Message) error {
+	return s.call(ctx, s.writes, func() error {
+		return s.Store.ImportBatch(ctx, namespace, messages)
+	})
+}
+
+func (s *LimiterStore) GetStreamMessages(ctx context.Context, namespace, streamName string, opts *GetOpts) (msgs []*Message, err error) {
+	err = s.call(ctx, s.reads, func() error {
+		msgs, err = s.Store.GetStreamMessages(ctx, namespace, streamName, opts)
+		return err
+	})
+	return msgs, err
+}
+
+func (s *LimiterStore) GetCategoryMessages(ctx context.Context, namespace, categoryName string, opts *CategoryOpts) (msgs []*Message, err error) {
+	err = s.call(ctx, s.reads, func() error {
+		msgs, err = s.Store.GetCategoryMessages(ctx, namespace, categoryName, opts)
+		return err
+	})
+	return msgs, err
+}
+
+func (s *LimiterStore) GetLastStreamMessage(ctx context.Context, namespace, streamName string, msgType *string) (msg *Message, err error) {
+	err = s.call(ctx, s.reads, func() error {
+		msg, err = s.Store.GetLastStreamMessage(ctx, namespace, streamName, msgType)
+		return err
+	})
+	return msg, err
+}
+
+func (s *LimiterStore) GetStreamVersion(ctx context.Context, namespace, streamName string) (version int64, err error) {
+	err = s.call(ctx, s.reads, func() error {
+		version, err = s.Store.GetStreamVersion(ctx, namespace, streamName)
+		return err
+	})
+	return version, err
+}
+
+// CheckStorage forwards to the backend if it implements IntegrityChecker
+func (s *LimiterStore) CheckStorage(ctx context.Context, namespace string) ([]string, error) {
+	checker, ok := s.Store.(IntegrityChecker)
+	if !ok {
+		return nil, nil
+	}
+	return checker.CheckStorage(ctx, namespace)
+}
+
+// InvalidJSON forwards to the backend if it implements IntegrityChecker
+func (s *LimiterStore) InvalidJSON(ctx context.Context, namespace, streamName string) ([]int64, error) {
+	checker, ok := s.Store.(IntegrityChecker)
+	if !ok {
+		return nil, nil
+	}
+	return checker.InvalidJSON(ctx, namespace, streamName)
+}
+
+// NamespaceStorage forwards to the backend if it implements StorageReporter
+func (s *LimiterStore) NamespaceStorage(ctx context.Context, namespace string) (*StorageUsage, error) {
+	reporter, ok := s.Store.(StorageReporter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return reporter.NamespaceStorage(ctx, namespace)
+}
+
+// TruncateStream forwards to the backend if it implements StreamTruncater
+func (s *LimiterStore) TruncateStream(ctx context.Context, namespace, streamName string, position int64) (deleted int64, err error) {
+	truncater, ok := s.Store.(StreamTruncater)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	err = s.call(ctx, s.writes, func() error {
+		deleted, err = truncater.TruncateStream(ctx, namespace, streamName, position)
+		return err
+	})
+	return deleted, err
+}
+
+// RedactMessage forwards to the backend if it implements MessageRedactor
+func (s *LimiterStore) RedactMessage(ctx context.Context, namespace, streamName string, position int64, data, metadata map[string]interface{}) error {
+	redactor, ok := s.Store.(MessageRedactor)
+	if !ok {
+		return ErrNotSupported
+	}
+	return s.call(ctx, s.writes, func() error {
+		return redactor.RedactMessage(ctx, namespace, streamName, position, data, metadata)
+	})
+}
+
+// MergeStreams forwards to the backend if it implements StreamMerger
+func (s *LimiterStore) MergeStreams(ctx context.Context, namespace, target string, sources []string) (moved int64, err error) {
+	merger, ok := s.Store.(StreamMerger)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	err = s.call(ctx, s.writes, func() error {
+		moved, err = merger.MergeStreams(ctx, namespace, target, sources)
+		return err
+	})
+	return moved, err
+}
+
+// WriteMessages forwards to the backend if it implements AtomicWriter
+func (s *LimiterStore) WriteMessages(ctx context.Context, namespace string, msgs []*Message) (results []*WriteResult, err error) {
+	writer, ok := s.Store.(AtomicWriter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = s.call(ctx, s.writes, func() error {
+		results, err = writer.WriteMessages(ctx, namespace, msgs)
+		return err
+	})
+	return results, err
+}
+
+// SetConsumerPosition forwards to the backend if it implements ConsumerPositionStore
+func (s *LimiterStore) SetConsumerPosition(ctx context.Context, namespace, consumer string, globalPosition int64) (position *ConsumerPosition, err error) {
+	positions, ok := s.Store.(ConsumerPositionStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = s.call(ctx, s.writes, func() error {
+		position, err = positions.SetConsumerPosition(ctx, namespace, consumer, globalPosition)
+		return err
+	})
+	return position, err
+}
+
+// GetConsumerPosition forwards to the backend if it implements ConsumerPositionStore
+func (s *LimiterStore) GetConsumerPosition(ctx context.Context, namespace, consumer string) (position *ConsumerPosition, err error) {
+	positions, ok := s.Store.(ConsumerPositionStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = s.call(ctx, s.reads, func() error {
+		position, err = positions.GetConsumerPosition(ctx, namespace, consumer)
+		return err
+	})
+	return position, err
+}
+
+// PositionGuarantee forwards to the backend
+func (s *LimiterStore) PositionGuarantee(ctx context.Context, namespace string) PositionGuarantee {
+	return PositionGuaranteeOf(ctx, s.Store, namespace)
+}
+
+// CommitsInOrder forwards to the backend if it implements CommitOrderer
+func (s *LimiterStore) CommitsInOrder(ctx context.Context, namespace string) bool {
+	orderer, ok := s.Store.(CommitOrderer)
+	return ok && orderer.CommitsInOrder(ctx, namespace)
+}
+
+// PendingWrites forwards to the backend if it implements PendingWriteInspector
+func (s *LimiterStore) PendingWrites(ctx context.Context, namespace, category string) (pending *PendingWrites, err error) {
+	inspector, ok := s.Store.(PendingWriteInspector)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = s.call(ctx, s.reads, func() error {
+		pending, err = inspector.PendingWrites(ctx, namespace, category)
+		return err
+	})
+	return pending, err
+}
+
+// SwapNamespaceMessages forwards to the backend if it implements NamespaceSwapper
+func (s *LimiterStore) SwapNamespaceMessages(ctx context.Context, a, b string) error {
+	swapper, ok := s.Store.(NamespaceSwapper)
+	if !ok {
+		return ErrNotSupported
+	}
+	return s.call(ctx, s.writes, func() error {
+		return swapper.SwapNamespaceMessages(ctx, a, b)
+	})
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowStore blocks writes until released and answers reads after readDelay
+type slowStore struct {
+	Store
+	release   chan struct{}
+	readDelay time.Duration
+}
+
+func (s *slowStore) WriteMessage(ctx context.Context, namespace, streamName string, msg *Message) (*WriteResult, error) {
+	<-s.release
+	return &WriteResult{}, nil
+}
+
+func (s *slowStore) GetStreamVersion(ctx context.Context, namespace, streamName string) (int64, error) {
+	time.Sleep(s.readDelay)
+	return 0, nil
+}
+
+func TestConcurrencyLimiter_ShedsExcessCalls(t *testing.T) {
+	inner := &slowStore{release: make(chan struct{})}
+	s := NewLimiterStore(inner, ConcurrencyConfig{
+		InitialLimit: 2,
+		MaxLimit:     4,
+		MaxQueue:     1,
+		MaxWait:      20 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	// Two writes take the slots and a third waits
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.WriteMessage(ctx, "ns", "account-1", &Message{})
+			errs <- err
+		}()
+	}
+	waitFor(t, func() bool { w, _ := s.Stats(); return w.InFlight == 2 && w.Waiting == 1 })
+
+	// The queue is full, so the next call is rejected right away
+	_, err := s.WriteMessage(ctx, "ns", "account-1", &Message{})
+	var overloaded *OverloadedError
+	if !errors.As(err, &overloaded) || !IsOverloaded(err) || overloaded.RetryAfter <= 0 {
+		t.Fatalf("Expected OverloadedError, got %v", err)
+	}
+
+	// The waiting call gives up after MaxWait
+	waitFor(t, func() bool { w, _ := s.Stats(); return w.Waiting == 0 })
+	close(inner.release)
+	wg.Wait()
+	close(errs)
+	var failed int
+	for err := range errs {
+		if err != nil {
+			if !IsOverloaded(err) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("Expected one timed out call, got %d", failed)
+	}
+	if writes, reads := s.Stats(); writes.Rejected != 2 || writes.InFlight != 0 || reads.Rejected != 0 {
+		t.Errorf("Unexpected stats: writes %+v, reads %+v", writes, reads)
+	}
+}
+
+func TestConcurrencyLimiter_Adapts(t *testing.T) {
+	inner := &slowStore{}
+	s := NewLimiterStore(inner, ConcurrencyConfig{
+		InitialLimit:  10,
+		MaxLimit:      20,
+		TargetLatency: 20 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	// Slow calls shrink the limit
+	inner.readDelay = 30 * time.Millisecond
+	for i := 0; i < 5; i++ {
+		if _, err := s.GetStreamVersion(ctx, "ns", "account-1"); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+	}
+	_, reads := s.Stats()
+	if reads.Limit >= 10 {
+		t.Fatalf("Expected slow calls to shrink the limit, got %d", reads.Limit)
+	}
+	shrunk := reads.Limit
+
+	// Fast calls only grow it while it is in use
+	inner.readDelay = 0
+	for i := 0; i < 5; i++ {
+		if _, err := s.GetStreamVersion(ctx, "ns", "account-1"); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+	}
+	if _, reads := s.Stats(); reads.Limit != shrunk {
+		t.Errorf("Expected an idle limiter to keep its limit %d, got %d", shrunk, reads.Limit)
+	}
+
+	inner.readDelay = 2 * time.Millisecond
+	var wg sync.WaitGroup
+	for i := 0; i < shrunk; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				s.GetStreamVersion(ctx, "ns", "account-1")
+			}
+		}()
+	}
+	wg.Wait()
+	if _, reads := s.Stats(); reads.Limit <= shrunk || reads.Limit > 20 {
+		t.Errorf("Expected busy fast calls to grow the limit above %d (max 20), got %d", shrunk, reads.Limit)
+	}
+}
+
+func TestConcurrencyLimiter_Priorities(t *testing.T) {
+	inner := &slowStore{release: make(chan struct{})}
+	s := NewLimiterStore(inner, ConcurrencyConfig{
+		InitialLimit: 1,
+		MaxLimit:     1,
+		MaxQueue:     3,
+		MaxWait:      5 * time.Second,
+	})
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, 5)
+	write := func(name string, priority ReadPriority, waiting int) {
+		go func() {
+			ctx := WithReadPriority(context.Background(), priority)
+			_, err := s.WriteMessage(ctx, "ns", "account-1", &Message{})
+			results <- result{name, err}
+		}()
+		waitFor(t, func() bool { w, _ := s.Stats(); return w.InFlight == 1 && w.Waiting == waiting })
+	}
+
+	// One call runs while a low, a normal and a high priority call wait
+	write("running", ReadPriorityNormal, 0)
+	write("low", ReadPriorityLow, 1)
+	write("normal", ReadPriorityNormal, 2)
+	write("high", ReadPriorityHigh, 3)
+
+	// The queue is full, so another high priority call displaces the low one
+	write("high-2", ReadPriorityHigh, 3)
+	if r := <-results; r.name != "low" || !IsOverloaded(r.err) {
+		t.Fatalf("Expected the low priority call to be shed, got %s (%v)", r.name, r.err)
+	}
+
+	// Slots are handed out by priority, FIFO within a priority
+	for _, name := range []string{"running", "high", "high-2", "normal"} {
+		inner.release <- struct{}{}
+		if r := <-results; r.name != name || r.err != nil {
+			t.Errorf("Expected %s to finish next, got %s (%v)", name, r.name, r.err)
+		}
+	}
+
+	if _, err := ParseReadPriority("urgent"); err == nil {
+		t.Error("Expected an unknown priority to be rejected")
+	}
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
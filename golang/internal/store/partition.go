@@ -0,0 +1,202 @@
+package store
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// Partitioner selects the algorithm that assigns streams to consumer group
+// members. Every backend implements each one identically: SQLite and Pebble
+// through the functions below, Postgres and TimescaleDB through the SQL
+// functions in migration 003.
+type Partitioner string
+
+const (
+	// PartitionerMD5 is the Message DB compatible default: the first 8 bytes of
+	// MD5(cardinalID) as a signed big-endian integer, |hash| mod size
+	PartitionerMD5 Partitioner = "md5"
+
+	// PartitionerMurmur3 is MurmurHash3 x86 32-bit (seed 0) of the cardinal ID
+	// as an unsigned integer, hash mod size
+	PartitionerMurmur3 Partitioner = "murmur3"
+
+	// PartitionerJump is jump consistent hashing (Lamping & Veach) keyed by the
+	// first 8 bytes of MD5(cardinalID) as an unsigned big-endian integer.
+	// Growing a group from n to n+1 members moves only 1/(n+1) of the streams.
+	PartitionerJump Partitioner = "jump"
+)
+
+// Partitioners lists the supported partitioners, default first
+var Partitioners = []Partitioner{PartitionerMD5, PartitionerMurmur3, PartitionerJump}
+
+// ParsePartitioner validates a partitioner name; empty selects the default
+func ParsePartitioner(name string) (Partitioner, error) {
+	if name == "" {
+		return PartitionerMD5, nil
+	}
+	for _, p := range Partitioners {
+		if Partitioner(name) == p {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown partitioner %q (supported: md5, murmur3, jump)", name)
+}
+
+// Description documents the algorithm precisely enough to reimplement it
+func (p Partitioner) Description() string {
+	switch p {
+	case PartitionerMurmur3:
+		return "MurmurHash3 x86 32-bit (seed 0) of the cardinal ID's UTF-8 bytes as an unsigned integer, hash mod size"
+	case PartitionerJump:
+		return "Jump consistent hash (Lamping & Veach) keyed by the first 8 bytes of MD5(cardinal ID) as an unsigned big-endian integer"
+	default:
+		return "First 8 bytes of MD5(cardinal ID) as a signed big-endian integer, abs(hash) mod size (Message DB compatible)"
+	}
+}
+
+// Member returns the consumer group member a stream is assigned to, or -1
+// for a stream without an ID, which no member handles
+func (p Partitioner) Member(streamName string, size int64) int64 {
+	return p.KeyMember(CardinalID(streamName), size)
+}
+
+// KeyMember returns the consumer group member a partition key is assigned
+// to, or -1 for an empty key, which no member handles
+func (p Partitioner) KeyMember(key string, size int64) int64 {
+	if key == "" || size <= 0 {
+		return -1
+	}
+
+	switch p {
+	case PartitionerMurmur3:
+		return int64(Murmur3(key)) % size
+	case PartitionerJump:
+		hash := md5.Sum([]byte(key))
+		return JumpHash(binary.BigEndian.Uint64(hash[:8]), size)
+	default:
+		hash := Hash64(key)
+		// Use absolute value to handle negative hashes
+		if hash < 0 {
+			hash = -hash
+		}
+		return hash % size
+	}
+}
+
+// IsAssigned determines whether a stream belongs to the given member
+func (p Partitioner) IsAssigned(streamName string, member, size int64) bool {
+	if size <= 0 || member < 0 || member >= size {
+		return false
+	}
+	return p.Member(streamName, size) == member
+}
+
+// OrderingKeyMetadataKey is the message metadata key consumer groups that opt
+// in with CategoryOpts.OrderingKey partition by, so messages of several
+// categories that share a key (such as a customer ID) go to the same member
+const OrderingKeyMetadataKey = "orderingKey"
+
+// PartitionKey returns the key a consumer group partitions msg by: its
+// non-empty string metadata.orderingKey when orderingKey is set, otherwise
+// the cardinal ID of its stream
+func PartitionKey(msg *Message, orderingKey bool) string {
+	if orderingKey {
+		if key, ok := msg.Metadata[OrderingKeyMetadataKey].(string); ok && key != "" {
+			return key
+		}
+	}
+	return CardinalID(msg.StreamName)
+}
+
+// GetOrderingKeyMessages reads a consumer group member's messages when the
+// group partitions by ordering key. Backends partition by stream in the
+// query, which cannot see message metadata, so this reads the category
+// unpartitioned through read and keeps the member's messages, reading on
+// until the batch is full or the category ends.
+func GetOrderingKeyMessages(opts *CategoryOpts, read func(opts *CategoryOpts) ([]*Message, error)) ([]*Message, error) {
+	member, size := *opts.ConsumerMember, *opts.ConsumerSize
+	scan := *opts
+	scan.ConsumerMember, scan.ConsumerSize, scan.OrderingKey = nil, nil, false
+	if scan.GlobalPosition != nil {
+		scan.Position, scan.GlobalPosition = *scan.GlobalPosition, nil
+	}
+
+	var messages []*Message
+	for {
+		batch, err := read(&scan)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range batch {
+			if opts.Partitioner.KeyMember(PartitionKey(msg, true), size) != member {
+				continue
+			}
+			messages = append(messages, msg)
+			if opts.BatchSize > 0 && int64(len(messages)) >= opts.BatchSize {
+				return messages, nil
+			}
+		}
+		if scan.BatchSize <= 0 || int64(len(batch)) < scan.BatchSize {
+			return messages, nil
+		}
+		scan.Position = batch[len(batch)-1].GlobalPosition + 1
+	}
+}
+
+// Murmur3 computes MurmurHash3 x86 32-bit with seed 0
+func Murmur3(value string) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	data := []byte(value)
+	var h uint32
+	n := len(data) / 4 * 4
+	for i := 0; i < n; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	switch len(data) - n {
+	case 3:
+		k ^= uint32(data[n+2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[n+1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[n])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// JumpHash maps a key to one of buckets using jump consistent hashing
+func JumpHash(key uint64, buckets int64) int64 {
+	var b, j int64 = -1, 0
+	for j < buckets {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return b
+}
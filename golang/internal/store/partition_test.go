@@ -0,0 +1,159 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMurmur3(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint32
+	}{
+		{"", 0},
+		{"foo", 0xf6a5c420},
+		{"hello", 0x248bfa47},
+		{"hello, world", 0x149bbb7f},
+	}
+
+	for _, tt := range tests {
+		if got := Murmur3(tt.input); got != tt.expected {
+			t.Errorf("Murmur3(%q) = %#x, expected %#x", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestJumpHash(t *testing.T) {
+	tests := []struct {
+		key      uint64
+		buckets  int64
+		expected int64
+	}{
+		{1, 1, 0},
+		{42, 57, 43},
+		{0xDEAD10CC, 1, 0},
+		{0xDEAD10CC, 666, 361},
+		{256, 1024, 520},
+	}
+
+	for _, tt := range tests {
+		if got := JumpHash(tt.key, tt.buckets); got != tt.expected {
+			t.Errorf("JumpHash(%d, %d) = %d, expected %d", tt.key, tt.buckets, got, tt.expected)
+		}
+	}
+}
+
+func TestParsePartitioner(t *testing.T) {
+	if p, err := ParsePartitioner(""); err != nil || p != PartitionerMD5 {
+		t.Errorf("Expected the md5 default, got %q, %v", p, err)
+	}
+	for _, p := range Partitioners {
+		if got, err := ParsePartitioner(string(p)); err != nil || got != p {
+			t.Errorf("ParsePartitioner(%s) = %q, %v", p, got, err)
+		}
+	}
+	if _, err := ParsePartitioner("crc32"); err == nil {
+		t.Error("Expected an error for an unknown partitioner")
+	}
+}
+
+func TestPartitioner_Member(t *testing.T) {
+	// md5 matches the Message DB compatible assignment
+	for i := 0; i < 100; i++ {
+		stream := fmt.Sprintf("account-%d", i)
+		member := PartitionerMD5.Member(stream, 3)
+		if !IsAssignedToConsumerMember(stream, member, 3) {
+			t.Errorf("md5 assigned %s to %d, IsAssignedToConsumerMember disagrees", stream, member)
+		}
+	}
+
+	for _, p := range Partitioners {
+		if got := p.Member("account", 3); got != -1 {
+			t.Errorf("%s: expected no member for a category, got %d", p, got)
+		}
+		if p.Member("account-1+a", 3) != p.Member("account-1+b", 3) {
+			t.Errorf("%s: expected compound IDs to follow the cardinal ID", p)
+		}
+	}
+}
+
+func TestPartitionerJump_MinimalRebalance(t *testing.T) {
+	const streams = 10000
+	moved := 0
+	for i := 0; i < streams; i++ {
+		stream := fmt.Sprintf("account-%d", i)
+		before, after := PartitionerJump.Member(stream, 4), PartitionerJump.Member(stream, 5)
+		if before != after {
+			if after != 4 {
+				t.Fatalf("%s moved from %d to %d; jump hashing only moves streams to the new member", stream, before, after)
+			}
+			moved++
+		}
+	}
+
+	// About 1/5 of the streams move to the new member
+	if moved < streams/5-300 || moved > streams/5+300 {
+		t.Errorf("Expected about %d streams to move, got %d", streams/5, moved)
+	}
+}
+
+func TestGetOrderingKeyMessages(t *testing.T) {
+	// Two categories keyed by customer, plus messages without a key
+	var all []*Message
+	for i := int64(1); i <= 40; i++ {
+		msg := &Message{StreamName: fmt.Sprintf("order-%d", i), GlobalPosition: i, Metadata: map[string]interface{}{}}
+		if i%4 != 0 {
+			msg.Metadata[OrderingKeyMetadataKey] = fmt.Sprintf("customer-%d", i%3)
+		}
+		all = append(all, msg)
+	}
+	read := func(opts *CategoryOpts) ([]*Message, error) {
+		if opts.ConsumerMember != nil || opts.OrderingKey || opts.GlobalPosition != nil {
+			t.Fatalf("Expected an unpartitioned read by position, got %+v", opts)
+		}
+		var batch []*Message
+		for _, msg := range all {
+			if msg.GlobalPosition >= opts.Position && int64(len(batch)) < opts.BatchSize {
+				batch = append(batch, msg)
+			}
+		}
+		return batch, nil
+	}
+
+	size := int64(3)
+	seen := make(map[int64]bool)
+	for member := int64(0); member < size; member++ {
+		m := member
+		next := int64(1)
+		for {
+			msgs, err := GetOrderingKeyMessages(&CategoryOpts{GlobalPosition: &next, BatchSize: 4, ConsumerMember: &m, ConsumerSize: &size}, read)
+			if err != nil {
+				t.Fatalf("GetOrderingKeyMessages failed: %v", err)
+			}
+			for _, msg := range msgs {
+				if PartitionerMD5.KeyMember(PartitionKey(msg, true), size) != member {
+					t.Errorf("Member %d got %s at %d", member, msg.StreamName, msg.GlobalPosition)
+				}
+				if seen[msg.GlobalPosition] {
+					t.Errorf("Position %d returned twice", msg.GlobalPosition)
+				}
+				seen[msg.GlobalPosition] = true
+				next = msg.GlobalPosition + 1
+			}
+			if len(msgs) < 4 {
+				break
+			}
+		}
+	}
+	if len(seen) != len(all) {
+		t.Errorf("Expected every message once, got %d of %d", len(seen), len(all))
+	}
+
+	keyed := all[0]
+	if PartitionKey(keyed, false) != "1" || PartitionKey(keyed, true) != "customer-1" {
+		t.Errorf("Unexpected partition keys for %v", keyed.Metadata)
+	}
+	if PartitionKey(all[3], true) != "4" {
+		t.Errorf("Expected a message without a key to use its cardinal ID")
+	}
+}
@@ -0,0 +1,63 @@
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// consumerPositionRecord is the value of a CP: key
+type consumerPositionRecord struct {
+	GlobalPosition int64     `json:"globalPosition"`
+	Updated        time.Time `json:"updated"`
+}
+
+// SetConsumerPosition records the last global position consumer has processed
+func (s *PebbleStore) SetConsumerPosition(ctx context.Context, namespace, consumer string, globalPosition int64) (*store.ConsumerPosition, error) {
+	handle, err := s.getNamespaceDB(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	record := consumerPositionRecord{GlobalPosition: globalPosition, Updated: time.Now().UTC()}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize consumer position: %w", err)
+	}
+	if err := handle.db.Set(formatConsumerPositionKey(consumer), value, pebble.NoSync); err != nil {
+		return nil, fmt.Errorf("failed to set consumer position: %w", err)
+	}
+
+	return &store.ConsumerPosition{Consumer: consumer, GlobalPosition: globalPosition, Updated: record.Updated}, nil
+}
+
+// GetConsumerPosition returns the last recorded position of consumer
+func (s *PebbleStore) GetConsumerPosition(ctx context.Context, namespace, consumer string) (*store.ConsumerPosition, error) {
+	handle, err := s.getNamespaceDB(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	value, closer, err := handle.db.Get(formatConsumerPositionKey(consumer))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, store.ErrConsumerPositionNotFound
+		}
+		return nil, fmt.Errorf("failed to get consumer position: %w", err)
+	}
+	var record consumerPositionRecord
+	err = json.Unmarshal(value, &record)
+	closer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize consumer position: %w", err)
+	}
+
+	return &store.ConsumerPosition{
+		Consumer:       consumer,
+		GlobalPosition: record.GlobalPosition,
+		Updated:        record.Updated.UTC(),
+	}, nil
+}
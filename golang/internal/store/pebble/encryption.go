@@ -0,0 +1,616 @@
+package pebble
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// Encrypted files start with a header sector: an 8-byte magic, a random
+// 16-byte file ID and 8 bytes of an HMAC of the ID, which tells a wrong key
+// apart from corruption. The data follows in slots of slotSize bytes, each a
+// slot header (an HMAC, a random AES-CTR IV and the data length) and up to
+// slotDataSize bytes of AES-256-CTR ciphertext, so any offset can be read or
+// written on its own and every slot read is authenticated.
+//
+// The HMAC covers the file ID, slot index, IV, length and ciphertext, so
+// slots cannot be altered, moved or swapped between files.
+//
+// A slot written by the open file can be appended to under its IV, writing
+// only the new bytes and then the header: nothing past its length was
+// encrypted with that IV, and a crash before the header write leaves the
+// previous slot intact. Any other write to a slot holding data, such as the
+// first append after the file was opened, whose bytes past the length may
+// have been written by a write that crashed, takes a fresh IV, so no
+// keystream encrypts two plaintexts. As that rewrites data that may have been
+// synced, the new slot is first written and synced to the journal between
+// the header and the slots, and a slot that fails authentication is read from
+// the journal when it holds that slot. A crash thus leaves either the old or
+// the new slot.
+const (
+	encryptionMagic      = "EVDBENC3"
+	encryptionFileIDSize = 16
+	encryptionHeaderSize = 512 // Padded to a sector, so slot headers are sector aligned
+
+	slotSize       = 4096
+	slotMACSize    = 16
+	slotHeaderSize = slotMACSize + aes.BlockSize + 4
+	slotDataSize   = slotSize - slotHeaderSize
+
+	// The journal holds the index of the last slot rewritten, padded to a
+	// sector, and a copy of that slot
+	journalOffset = encryptionHeaderSize
+	slotsOffset   = journalOffset + encryptionHeaderSize + slotSize
+
+	// EncryptionKeySize is the size of the AES-256 key
+	EncryptionKeySize = 32
+)
+
+var (
+	// ErrWrongEncryptionKey is returned when files were encrypted with another key
+	ErrWrongEncryptionKey = errors.New("wrong encryption key")
+
+	// ErrDataEncrypted is returned when encrypted data is opened without a key
+	ErrDataEncrypted = errors.New("data directory is encrypted, an encryption key is required")
+
+	// ErrDataNotEncrypted is returned when plaintext data is opened with a key
+	ErrDataNotEncrypted = errors.New("data directory is not encrypted")
+
+	// ErrEncryptedDataCorrupted is returned when encrypted data fails authentication
+	ErrEncryptedDataCorrupted = errors.New("encrypted data failed authentication")
+)
+
+// encryptedFS encrypts the files Pebble writes through it. Directory
+// operations and the LOCK file are passed through unchanged.
+type encryptedFS struct {
+	vfs.FS
+	block  cipher.Block
+	macKey []byte
+	key    []byte
+}
+
+// newEncryptedFS wraps fs with AES-256-CTR encryption and HMAC-SHA256
+// authentication under keys derived from key
+func newEncryptedFS(fs vfs.FS, key []byte) (*encryptedFS, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(deriveKey(key, "eventodb data encryption"))
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedFS{FS: fs, block: block, macKey: deriveKey(key, "eventodb data authentication"), key: key}, nil
+}
+
+// deriveKey derives a 256-bit key for one purpose from key
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// keyCheck returns the header bytes that verify key against the file ID
+func (fs *encryptedFS) keyCheck(id []byte) []byte {
+	mac := hmac.New(sha256.New, fs.key)
+	mac.Write([]byte(encryptionMagic))
+	mac.Write(id)
+	return mac.Sum(nil)[:8]
+}
+
+// Create creates a file with a fresh ID
+func (fs *encryptedFS) Create(name string) (vfs.File, error) {
+	f, err := fs.FS.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	ef, err := fs.initFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return ef, nil
+}
+
+// Open opens a file for reading
+func (fs *encryptedFS) Open(name string, opts ...vfs.OpenOption) (vfs.File, error) {
+	f, err := fs.FS.Open(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ef, err := fs.readHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return ef, nil
+}
+
+// OpenReadWrite opens a file for reading and writing, giving it a header if it is empty
+func (fs *encryptedFS) OpenReadWrite(name string, opts ...vfs.OpenOption) (vfs.File, error) {
+	f, err := fs.FS.OpenReadWrite(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	var ef *encryptedFile
+	switch {
+	case err != nil:
+	case info.Size() == 0:
+		ef, err = fs.initFile(f)
+	default:
+		ef, err = fs.readHeader(f)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return ef, nil
+}
+
+// ReuseForWrite renames oldname and starts it over with a fresh ID, so
+// recycled WAL files never reuse their slots' authentication
+func (fs *encryptedFS) ReuseForWrite(oldname, newname string) (vfs.File, error) {
+	if err := fs.FS.Rename(oldname, newname); err != nil {
+		return nil, err
+	}
+	return fs.Create(newname)
+}
+
+// Stat reports the size of a file's data, read from its last slot
+func (fs *encryptedFS) Stat(name string) (os.FileInfo, error) {
+	info, err := fs.FS.Stat(name)
+	if err != nil || !info.Mode().IsRegular() {
+		return info, err
+	}
+	f, err := fs.Open(name)
+	if errors.Is(err, ErrDataNotEncrypted) {
+		return info, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// initFile writes the header of a new file
+func (fs *encryptedFS) initFile(f vfs.File) (*encryptedFile, error) {
+	id := make([]byte, encryptionFileIDSize)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	header := make([]byte, encryptionHeaderSize)
+	n := copy(header, encryptionMagic)
+	n += copy(header[n:], id)
+	copy(header[n:], fs.keyCheck(id))
+	if _, err := f.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptedFile{File: f, fs: fs, id: id}, nil
+}
+
+// readHeader reads and verifies the header of an existing file and finds
+// the size of its data
+func (fs *encryptedFS) readHeader(f vfs.File) (*encryptedFile, error) {
+	header := make([]byte, encryptionHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrDataNotEncrypted
+		}
+		return nil, err
+	}
+	if !bytes.HasPrefix(header, []byte(encryptionMagic)) {
+		return nil, ErrDataNotEncrypted
+	}
+	id := header[len(encryptionMagic) : len(encryptionMagic)+encryptionFileIDSize]
+	check := header[len(encryptionMagic)+encryptionFileIDSize : len(encryptionMagic)+encryptionFileIDSize+8]
+	if !hmac.Equal(check, fs.keyCheck(id)) {
+		return nil, ErrWrongEncryptionKey
+	}
+	ef := &encryptedFile{File: f, fs: fs, id: id}
+	if err := ef.loadSize(); err != nil {
+		return nil, err
+	}
+	return ef, nil
+}
+
+// encryptedFile encrypts writes and decrypts reads at logical offsets, which
+// map to slots after the header
+type encryptedFile struct {
+	vfs.File
+	fs *encryptedFS
+	id []byte
+
+	mu      sync.Mutex
+	size    int64 // Logical size
+	pos     int64 // Logical offset of sequential Read and Write
+	cached  *slot // Last slot read or written
+	written *slot // Last slot written by this file, which appends may extend
+	journal *slot // Slot held by the journal, nil if it holds none
+}
+
+// slot is the decrypted content of one slot
+type slot struct {
+	index      int64
+	iv         []byte
+	ciphertext []byte
+	plain      []byte
+}
+
+// slotOffset returns the underlying offset of slot index
+func slotOffset(index int64) int64 {
+	return slotsOffset + index*slotSize
+}
+
+// physicalOffset returns the underlying offset of logical offset off
+func physicalOffset(off int64) int64 {
+	return slotOffset(off/slotDataSize) + slotHeaderSize + off%slotDataSize
+}
+
+// mac authenticates one slot of the file
+func (f *encryptedFile) mac(index int64, iv, ciphertext []byte) []byte {
+	mac := hmac.New(sha256.New, f.fs.macKey)
+	var buf [12]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(index))
+	binary.BigEndian.PutUint32(buf[8:], uint32(len(ciphertext)))
+	mac.Write(f.id)
+	mac.Write(buf[:])
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	return mac.Sum(nil)[:slotMACSize]
+}
+
+// xorAt applies the keystream of iv at offset off within a slot to buf
+func (f *encryptedFile) xorAt(iv, buf []byte, off int) {
+	if len(buf) == 0 {
+		return
+	}
+	// The counter is the IV plus the block index, as a 128-bit big-endian number
+	var ctr [aes.BlockSize]byte
+	copy(ctr[:], iv)
+	hi := binary.BigEndian.Uint64(ctr[:8])
+	lo := binary.BigEndian.Uint64(ctr[8:])
+	next := lo + uint64(off/aes.BlockSize)
+	if next < lo {
+		hi++
+	}
+	binary.BigEndian.PutUint64(ctr[:8], hi)
+	binary.BigEndian.PutUint64(ctr[8:], next)
+
+	stream := cipher.NewCTR(f.fs.block, ctr[:])
+	if skip := off % aes.BlockSize; skip > 0 {
+		var discard [aes.BlockSize]byte
+		stream.XORKeyStream(discard[:skip], discard[:skip])
+	}
+	stream.XORKeyStream(buf, buf)
+}
+
+// readSlot reads and authenticates slot index. It returns nil for a slot
+// that was never written, whose header is zero. A slot that fails
+// authentication, or was never written, is read from the journal if the
+// journal holds it, as a rewrite of it was cut short.
+func (f *encryptedFile) readSlot(index int64) (*slot, error) {
+	if f.written != nil && f.written.index == index {
+		return f.written, nil
+	}
+	if f.cached != nil && f.cached.index == index {
+		return f.cached, nil
+	}
+	buf := make([]byte, slotSize)
+	n, err := f.File.ReadAt(buf, slotOffset(index))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	s, err := f.decodeSlot(index, buf[:n])
+	if (s == nil || err != nil) && f.journal != nil && f.journal.index == index {
+		s, err = f.journal, nil
+	}
+	if s != nil {
+		f.cached = s
+	}
+	return s, err
+}
+
+// decodeSlot authenticates and decrypts buf, the content of slot index. It
+// returns nil if the slot header is zero.
+func (f *encryptedFile) decodeSlot(index int64, buf []byte) (*slot, error) {
+	n := len(buf)
+	if n < slotHeaderSize || bytes.Count(buf[:slotHeaderSize], []byte{0}) == slotHeaderSize {
+		return nil, nil
+	}
+
+	iv := buf[slotMACSize : slotMACSize+aes.BlockSize]
+	length := int(binary.BigEndian.Uint32(buf[slotMACSize+aes.BlockSize : slotHeaderSize]))
+	if length > slotDataSize || slotHeaderSize+length > n {
+		return nil, fmt.Errorf("%w: slot %d", ErrEncryptedDataCorrupted, index)
+	}
+	ciphertext := buf[slotHeaderSize : slotHeaderSize+length]
+	if !hmac.Equal(buf[:slotMACSize], f.mac(index, iv, ciphertext)) {
+		return nil, fmt.Errorf("%w: slot %d", ErrEncryptedDataCorrupted, index)
+	}
+	plain := append([]byte(nil), ciphertext...)
+	f.xorAt(iv, plain, 0)
+	return &slot{index: index, iv: iv, ciphertext: ciphertext, plain: plain}, nil
+}
+
+// loadJournal reads the slot held by the journal. A journal that does not
+// authenticate was cut short, and the slot it was written for is intact.
+func (f *encryptedFile) loadJournal() error {
+	buf := make([]byte, encryptionHeaderSize+slotSize)
+	n, err := f.File.ReadAt(buf, journalOffset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if n < encryptionHeaderSize {
+		return nil
+	}
+	index := int64(binary.BigEndian.Uint64(buf[:8]))
+	if s, err := f.decodeSlot(index, buf[encryptionHeaderSize:n]); err == nil {
+		f.journal = s
+	}
+	return nil
+}
+
+// loadSize finds the logical size from the last written slot. Slots past it
+// were started by a write that stopped before their header.
+func (f *encryptedFile) loadSize() error {
+	info, err := f.File.Stat()
+	if err != nil {
+		return err
+	}
+	if err := f.loadJournal(); err != nil {
+		return err
+	}
+	last := (info.Size() - slotsOffset - 1) / slotSize
+	if f.journal != nil {
+		last = max(last, f.journal.index)
+	}
+	for index := last; index >= 0; index-- {
+		s, err := f.readSlot(index)
+		if err != nil {
+			return err
+		}
+		if s != nil {
+			f.size = index*slotDataSize + int64(len(s.plain))
+			return nil
+		}
+	}
+	f.size = 0
+	return nil
+}
+
+// writeSlot writes piece at offset in of slot index
+func (f *encryptedFile) writeSlot(index int64, in int, piece []byte) error {
+	old, err := f.readSlot(index)
+	if err != nil {
+		return err
+	}
+	var iv, plain, ciphertext []byte
+	if old != nil {
+		iv = old.iv
+		plain = append([]byte(nil), old.plain...)
+		ciphertext = append([]byte(nil), old.ciphertext...)
+	}
+
+	// Ciphertext already on disk is kept when the write only repeats bytes,
+	// or appends to a slot this file wrote; other data takes a fresh IV and
+	// rewrites the slot
+	keep := len(plain)
+	end := in + len(piece)
+	changed := in < len(plain) && !bytes.Equal(plain[in:min(end, len(plain))], piece[:min(end, len(plain))-in])
+	appended := end > len(plain) && old != f.written
+	if old == nil || changed || appended {
+		iv = make([]byte, aes.BlockSize)
+		if _, err := rand.Read(iv); err != nil {
+			return err
+		}
+		keep = 0
+	}
+	if end > len(plain) {
+		plain = append(plain, make([]byte, end-len(plain))...)
+	}
+	copy(plain[in:], piece)
+	ciphertext = append(ciphertext[:keep], plain[keep:]...)
+	f.xorAt(iv, ciphertext[keep:], keep)
+
+	header := make([]byte, 0, slotHeaderSize)
+	header = append(header, f.mac(index, iv, ciphertext)...)
+	header = append(header, iv...)
+	header = binary.BigEndian.AppendUint32(header, uint32(len(ciphertext)))
+	s := &slot{index: index, iv: iv, ciphertext: ciphertext, plain: plain}
+
+	if old != nil && keep == 0 {
+		err = f.rewriteSlot(s, header)
+	} else {
+		err = f.appendSlot(s, header, keep)
+	}
+	if err != nil {
+		f.cached, f.written = nil, nil
+		return err
+	}
+	f.cached, f.written = s, s
+	return nil
+}
+
+// appendSlot writes the ciphertext of s past keep, then the header that
+// makes it count
+func (f *encryptedFile) appendSlot(s *slot, header []byte, keep int) error {
+	if len(s.ciphertext) > keep {
+		if _, err := f.File.WriteAt(s.ciphertext[keep:], slotOffset(s.index)+slotHeaderSize+int64(keep)); err != nil {
+			return err
+		}
+	}
+	_, err := f.File.WriteAt(header, slotOffset(s.index))
+	return err
+}
+
+// rewriteSlot replaces a slot holding data with s. The new slot is synced to
+// the journal first, so a crash while the slot is written leaves it readable
+// from the journal.
+func (f *encryptedFile) rewriteSlot(s *slot, header []byte) error {
+	image := append(header, s.ciphertext...)
+	journal := make([]byte, encryptionHeaderSize, encryptionHeaderSize+len(image))
+	binary.BigEndian.PutUint64(journal, uint64(s.index))
+	journal = append(journal, image...)
+	if _, err := f.File.WriteAt(journal, journalOffset); err != nil {
+		f.journal = nil
+		return err
+	}
+	if err := f.File.Sync(); err != nil {
+		f.journal = nil
+		return err
+	}
+	f.journal = s
+	_, err := f.File.WriteAt(image, slotOffset(s.index))
+	return err
+}
+
+// readAt reads at logical offset off; f.mu is held
+func (f *encryptedFile) readAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		at := off + int64(n)
+		if at >= f.size {
+			return n, io.EOF
+		}
+		s, err := f.readSlot(at / slotDataSize)
+		if err != nil {
+			return n, err
+		}
+		in := int(at % slotDataSize)
+		if s == nil || in >= len(s.plain) {
+			return n, fmt.Errorf("%w: slot %d is missing", ErrEncryptedDataCorrupted, at/slotDataSize)
+		}
+		n += copy(p[n:], s.plain[in:])
+	}
+	return n, nil
+}
+
+// writeAt writes at logical offset off; f.mu is held. A write past the end
+// fills the gap with zeros, so every slot before it authenticates.
+func (f *encryptedFile) writeAt(p []byte, off int64) (int, error) {
+	if off > f.size {
+		if _, err := f.writeAt(make([]byte, off-f.size), f.size); err != nil {
+			return 0, err
+		}
+	}
+	n := 0
+	for n < len(p) {
+		at := off + int64(n)
+		in := int(at % slotDataSize)
+		piece := p[n:min(len(p), n+slotDataSize-in)]
+		if err := f.writeSlot(at/slotDataSize, in, piece); err != nil {
+			return n, err
+		}
+		n += len(piece)
+		f.size = max(f.size, at+int64(len(piece)))
+	}
+	return n, nil
+}
+
+func (f *encryptedFile) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.readAt(p, f.pos)
+	f.pos += int64(n)
+	return n, err
+}
+
+func (f *encryptedFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.readAt(p, off)
+}
+
+func (f *encryptedFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.writeAt(p, f.pos)
+	f.pos += int64(n)
+	return n, err
+}
+
+func (f *encryptedFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writeAt(p, off)
+}
+
+func (f *encryptedFile) Preallocate(offset, length int64) error {
+	start := physicalOffset(offset)
+	return f.File.Preallocate(start, physicalOffset(offset+length)-start)
+}
+
+func (f *encryptedFile) SyncTo(length int64) (bool, error) {
+	return f.File.SyncTo(physicalOffset(length))
+}
+
+func (f *encryptedFile) Prefetch(offset, length int64) error {
+	start := physicalOffset(offset)
+	return f.File.Prefetch(start, physicalOffset(offset+length)-start)
+}
+
+func (f *encryptedFile) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return encryptedFileInfo{FileInfo: info, size: f.size}, nil
+}
+
+// encryptedFileInfo reports the logical size of an encrypted file
+type encryptedFileInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (i encryptedFileInfo) Size() int64 {
+	return i.size
+}
+
+// checkDataDirEncryption reports whether the existing data in dataDir matches
+// key: ErrDataEncrypted, ErrDataNotEncrypted or ErrWrongEncryptionKey. A new
+// data directory matches any key.
+func checkDataDirEncryption(dataDir string, key []byte) error {
+	current, err := os.ReadFile(filepath.Join(dataDir, metadataDBName, "CURRENT"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	encrypted := bytes.HasPrefix(current, []byte(encryptionMagic))
+	switch {
+	case key == nil && encrypted:
+		return ErrDataEncrypted
+	case key == nil:
+		return nil
+	case !encrypted:
+		return ErrDataNotEncrypted
+	}
+
+	fs, err := newEncryptedFS(vfs.Default, key)
+	if err != nil {
+		return err
+	}
+	f, err := fs.Open(filepath.Join(dataDir, metadataDBName, "CURRENT"))
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
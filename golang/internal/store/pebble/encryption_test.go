@@ -0,0 +1,314 @@
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+func TestEncryptedFile_RandomAccess(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, EncryptionKeySize)
+	fs, err := newEncryptedFS(vfs.NewMem(), key)
+	if err != nil {
+		t.Fatalf("newEncryptedFS failed: %v", err)
+	}
+
+	plain := bytes.Repeat([]byte("0123456789abcdef-"), 100)
+	f, err := fs.Create("file")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := f.Write(plain[:500]); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := f.WriteAt(plain[500:], 500); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	f.Close()
+
+	// Reads at unaligned offsets decrypt correctly
+	f, err = fs.Open("file")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+	for _, off := range []int64{0, 1, 15, 16, 17, 499, 1000} {
+		buf := make([]byte, 37)
+		if _, err := f.ReadAt(buf, off); err != nil {
+			t.Fatalf("ReadAt(%d) failed: %v", off, err)
+		}
+		if !bytes.Equal(buf, plain[off:off+37]) {
+			t.Errorf("ReadAt(%d) = %q, want %q", off, buf, plain[off:off+37])
+		}
+	}
+	if info, err := fs.Stat("file"); err != nil || info.Size() != int64(len(plain)) {
+		t.Errorf("Stat size = %v (%v), want %d", info.Size(), err, len(plain))
+	}
+}
+
+// TestEncryptedFile_Authentication tests that altered or swapped slots fail
+// to read, that an append cut short before its slot header is dropped, and
+// that a rewrite cut short leaves the old or the new slot
+func TestEncryptedFile_Authentication(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, EncryptionKeySize)
+	mem := vfs.NewMem()
+	fs, err := newEncryptedFS(mem, key)
+	if err != nil {
+		t.Fatalf("newEncryptedFS failed: %v", err)
+	}
+	plain := bytes.Repeat([]byte("0123456789abcdef-"), 600)
+	write := func(name string) {
+		t.Helper()
+		f, err := fs.Create(name)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if _, err := f.Write(plain); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		f.Close()
+	}
+	raw := func(name string) []byte {
+		t.Helper()
+		f, err := mem.Open(name)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatalf("ReadAll failed: %v", err)
+		}
+		return data
+	}
+	replace := func(name string, data []byte) {
+		t.Helper()
+		f, err := mem.Create(name)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		f.Write(data)
+		f.Close()
+	}
+	readAll := func(name string) ([]byte, error) {
+		t.Helper()
+		f, err := fs.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		buf := make([]byte, len(plain))
+		n, err := f.ReadAt(buf, 0)
+		return buf[:n], err
+	}
+
+	// A flipped ciphertext bit is detected
+	write("flipped")
+	data := raw("flipped")
+	data[slotsOffset+slotSize+slotHeaderSize+10] ^= 1
+	replace("flipped", data)
+	if _, err := readAll("flipped"); !errors.Is(err, ErrEncryptedDataCorrupted) {
+		t.Errorf("Expected ErrEncryptedDataCorrupted for a flipped bit, got %v", err)
+	}
+
+	// Slots cannot be swapped within a file or moved between files
+	write("swapped")
+	data = raw("swapped")
+	first := append([]byte(nil), data[slotsOffset:slotsOffset+slotSize]...)
+	copy(data[slotsOffset:], data[slotsOffset+slotSize:slotsOffset+2*slotSize])
+	copy(data[slotsOffset+slotSize:], first)
+	replace("swapped", data)
+	if _, err := readAll("swapped"); !errors.Is(err, ErrEncryptedDataCorrupted) {
+		t.Errorf("Expected ErrEncryptedDataCorrupted for swapped slots, got %v", err)
+	}
+	write("other")
+	other := raw("other")
+	copy(other[slotsOffset:], raw("swapped")[slotsOffset:slotsOffset+slotSize])
+	replace("other", other)
+	if _, err := readAll("other"); !errors.Is(err, ErrEncryptedDataCorrupted) {
+		t.Errorf("Expected ErrEncryptedDataCorrupted for a slot of another file, got %v", err)
+	}
+
+	// An append whose slot header was never written leaves the synced data
+	f, err := fs.Create("torn")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	f.Write(plain[:100])
+	before := raw("torn")
+	f.Write(plain[100:200])
+	f.Close()
+	torn := raw("torn")
+	copy(torn, before[:slotsOffset+slotHeaderSize])
+	replace("torn", torn)
+	if got, err := readAll("torn"); !errors.Is(err, io.EOF) || !bytes.Equal(got, plain[:100]) {
+		t.Errorf("Expected the first 100 bytes after a torn append, got %d bytes, %v", len(got), err)
+	}
+
+	// The first append after opening takes a fresh IV, as bytes past the
+	// slot's length may have been written with its IV by a crashed append
+	replace("torn", before)
+	f, _ = fs.OpenReadWrite("torn")
+	f.WriteAt(plain[100:200], 100)
+	f.Close()
+	after := raw("torn")
+	iv := func(data []byte) []byte { return data[slotsOffset+slotMACSize : slotsOffset+slotMACSize+16] }
+	if bytes.Equal(iv(before), iv(after)) {
+		t.Error("Expected a fresh IV for the first append after opening")
+	}
+
+	// A rewrite cut short leaves the old slot, or the new one in the journal
+	old := append([]byte(nil), after...)
+	copy(old[slotsOffset:], before[slotsOffset:])
+	replace("torn", old)
+	if got, err := readAll("torn"); !errors.Is(err, io.EOF) || !bytes.Equal(got, plain[:100]) {
+		t.Errorf("Expected the old slot before the rewrite, got %d bytes, %v", len(got), err)
+	}
+	half := append([]byte(nil), after...)
+	copy(half[slotsOffset:], before[slotsOffset:slotsOffset+slotHeaderSize])
+	replace("torn", half)
+	if got, err := readAll("torn"); !errors.Is(err, io.EOF) || !bytes.Equal(got, plain[:200]) {
+		t.Errorf("Expected the new slot from the journal, got %d bytes, %v", len(got), err)
+	}
+}
+
+// TestEncryptedFile_Rewrite tests that rewriting bytes with other data uses
+// a fresh keystream while appends keep the ciphertext already written
+func TestEncryptedFile_Rewrite(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, EncryptionKeySize)
+	mem := vfs.NewMem()
+	fs, err := newEncryptedFS(mem, key)
+	if err != nil {
+		t.Fatalf("newEncryptedFS failed: %v", err)
+	}
+	ciphertext := func() []byte {
+		t.Helper()
+		f, err := mem.Open("file")
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer f.Close()
+		data, _ := io.ReadAll(f)
+		return data[slotsOffset+slotHeaderSize:]
+	}
+
+	f, err := fs.OpenReadWrite("file")
+	if err != nil {
+		t.Fatalf("OpenReadWrite failed: %v", err)
+	}
+	defer f.Close()
+	a := bytes.Repeat([]byte{'a'}, 64)
+	b := bytes.Repeat([]byte{'b'}, 64)
+	f.WriteAt(a, 0)
+	first := append([]byte(nil), ciphertext()[:64]...)
+
+	// Appending leaves earlier ciphertext as it was
+	f.WriteAt(b, 64)
+	if !bytes.Equal(ciphertext()[:64], first) {
+		t.Error("Expected an append to keep the ciphertext written before")
+	}
+
+	// Other data at the same offset is not encrypted with the same keystream
+	f.WriteAt(b, 0)
+	second := ciphertext()[:64]
+	reused := true
+	for i := range first {
+		if first[i]^second[i] != a[i]^b[i] {
+			reused = false
+			break
+		}
+	}
+	if reused {
+		t.Error("Expected a fresh keystream for rewritten bytes")
+	}
+	buf := make([]byte, 128)
+	if _, err := f.ReadAt(buf, 0); err != nil || !bytes.Equal(buf, append(b, b...)) {
+		t.Errorf("Expected the rewritten data, got %q (%v)", buf, err)
+	}
+
+	// A write past the end fills the gap with zeros
+	f.WriteAt(a, 3*slotDataSize)
+	if info, err := f.Stat(); err != nil || info.Size() != 3*slotDataSize+64 {
+		t.Fatalf("Expected size %d, got %v (%v)", 3*slotDataSize+64, info.Size(), err)
+	}
+	buf = make([]byte, 16)
+	if _, err := f.ReadAt(buf, 2*slotDataSize); err != nil || !bytes.Equal(buf, make([]byte, 16)) {
+		t.Errorf("Expected zeros in the gap, got %q (%v)", buf, err)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	key := bytes.Repeat([]byte{1}, EncryptionKeySize)
+	ctx := context.Background()
+
+	st, err := NewWithConfig(tmpDir, &Config{EncryptionKey: key})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if err := st.CreateNamespace(ctx, "test", "hash123", "Test namespace"); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+	if _, err := st.WriteMessage(ctx, "test", "account-123", &store.Message{
+		Type: "AccountCreated",
+		Data: map[string]interface{}{"owner": "plaintext-marker"},
+	}); err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+	st.Close()
+
+	// Nothing readable is left on disk
+	filepath.Walk(tmpDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(data, []byte("plaintext-marker")) || bytes.Contains(data, []byte("AccountCreated")) {
+			t.Errorf("%s contains plaintext", path)
+		}
+		return nil
+	})
+
+	// The same key reads the data back
+	st, err = NewWithConfig(tmpDir, &Config{EncryptionKey: key})
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	msgs, err := st.GetStreamMessages(ctx, "test", "account-123", nil)
+	if err != nil {
+		t.Fatalf("GetStreamMessages failed: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].Data["owner"] != "plaintext-marker" {
+		t.Errorf("unexpected messages after reopen: %+v", msgs)
+	}
+	st.Close()
+
+	// A wrong key or no key is refused
+	if _, err := NewWithConfig(tmpDir, &Config{EncryptionKey: bytes.Repeat([]byte{2}, EncryptionKeySize)}); !errors.Is(err, ErrWrongEncryptionKey) {
+		t.Errorf("expected ErrWrongEncryptionKey, got %v", err)
+	}
+	if _, err := NewWithConfig(tmpDir, nil); !errors.Is(err, ErrDataEncrypted) {
+		t.Errorf("expected ErrDataEncrypted, got %v", err)
+	}
+
+	// A key is refused for plaintext data
+	plainDir := t.TempDir()
+	st, err = New(plainDir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	st.Close()
+	if _, err := NewWithConfig(plainDir, &Config{EncryptionKey: key}); !errors.Is(err, ErrDataNotEncrypted) {
+		t.Errorf("expected ErrDataNotEncrypted, got %v", err)
+	}
+}
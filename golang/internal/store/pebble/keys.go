@@ -5,7 +5,10 @@
 //   - SI:{stream}:{pos_20}         → {gp_20}             Stream index
 //   - CI:{category}:{gp_20}        → {stream}            Category index
 //   - VI:{stream}                  → {pos_20}            Version index
+//   - TI:{stream}\x00{type}         → {pos_20}            Last position of each type in a stream
+//   - TX                           → {gp_20}             First global position covered by TI
 //   - GP                           → {next_gp_20}        Global position counter
+//   - CP:{consumer}                → {position_json}     Last position processed by a consumer
 //
 // Metadata DB Schema:
 //   - NS:{namespace_id}            → {namespace_json}    Namespace registry
@@ -23,7 +26,10 @@ const (
 	prefixStreamIndex    = "SI:" // Stream index
 	prefixCategoryIndex  = "CI:" // Category index
 	prefixVersionIndex   = "VI:" // Version index
+	prefixTypeIndex      = "TI:" // Last position per stream and type
+	prefixTypeIndexStart = "TX"  // First global position covered by the type index
 	prefixGlobalPosition = "GP"  // Global position counter
+	prefixConsumerPos    = "CP:" // Consumer positions
 	prefixNamespace      = "NS:" // Namespace metadata (in metadata DB)
 )
 
@@ -58,11 +64,27 @@ func formatVersionIndexKey(stream string) []byte {
 	return []byte(fmt.Sprintf("%s%s", prefixVersionIndex, stream))
 }
 
+// formatTypeIndexKey creates a type index key: TI:{stream}\x00{type}. Stream
+// names may contain ':', so the type follows a NUL byte.
+func formatTypeIndexKey(stream, msgType string) []byte {
+	return []byte(prefixTypeIndex + stream + "\x00" + msgType)
+}
+
+// formatTypeIndexStartKey creates the type index coverage key: TX
+func formatTypeIndexStartKey() []byte {
+	return []byte(prefixTypeIndexStart)
+}
+
 // formatGlobalPositionKey creates the global position counter key: GP
 func formatGlobalPositionKey() []byte {
 	return []byte(prefixGlobalPosition)
 }
 
+// formatConsumerPositionKey creates a consumer position key: CP:{consumer}
+func formatConsumerPositionKey(consumer string) []byte {
+	return []byte(prefixConsumerPos + consumer)
+}
+
 // formatNamespaceKey creates a namespace metadata key: NS:{nsID}
 func formatNamespaceKey(nsID string) []byte {
 	return []byte(fmt.Sprintf("%s%s", prefixNamespace, nsID))
@@ -101,6 +101,62 @@ func (s *PebbleStore) GetNamespace(ctx context.Context, id string) (*store.Names
 	return &ns, nil
 }
 
+// UpdateNamespaceMetadata replaces a namespace's metadata
+func (s *PebbleStore) UpdateNamespaceMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	return s.updateNamespace(id, func(ns *store.Namespace) {
+		ns.Metadata = metadata
+	})
+}
+
+// UpdateNamespaceToken replaces a namespace's token hash
+func (s *PebbleStore) UpdateNamespaceToken(ctx context.Context, id, tokenHash string) error {
+	return s.updateNamespace(id, func(ns *store.Namespace) {
+		ns.TokenHash = tokenHash
+	})
+}
+
+// updateNamespace applies update to a namespace record and writes it back
+func (s *PebbleStore) updateNamespace(id string, update func(ns *store.Namespace)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := formatNamespaceKey(id)
+	value, closer, err := s.metadataDB.Get(key)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return store.ErrNamespaceNotFound
+		}
+		return fmt.Errorf("failed to read namespace metadata: %w", err)
+	}
+
+	var ns store.Namespace
+	err = json.Unmarshal(value, &ns)
+	closer.Close()
+	if err != nil {
+		return fmt.Errorf("failed to deserialize namespace: %w", err)
+	}
+
+	update(&ns)
+
+	updated, err := json.Marshal(&ns)
+	if err != nil {
+		return fmt.Errorf("failed to serialize namespace: %w", err)
+	}
+
+	writeOpts := pebble.Sync
+	if s.config != nil && (s.config.TestMode || s.config.InMemory) {
+		writeOpts = pebble.NoSync
+	}
+
+	if err := s.metadataDB.Set(key, updated, writeOpts); err != nil {
+		return fmt.Errorf("failed to write namespace metadata: %w", err)
+	}
+	return nil
+}
+
 // ListNamespaces returns all namespaces
 func (s *PebbleStore) ListNamespaces(ctx context.Context) ([]*store.Namespace, error) {
 	s.mu.RLock()
@@ -144,14 +200,19 @@ func (s *PebbleStore) DeleteNamespace(ctx context.Context, id string) error {
 
 	// Check if namespace exists
 	key := formatNamespaceKey(id)
-	_, closer, err := s.metadataDB.Get(key)
+	value, closer, err := s.metadataDB.Get(key)
 	if err != nil {
 		if err == pebble.ErrNotFound {
 			return store.ErrNamespaceNotFound
 		}
 		return fmt.Errorf("failed to check namespace existence: %w", err)
 	}
+	var ns store.Namespace
+	err = json.Unmarshal(value, &ns)
 	closer.Close()
+	if err != nil {
+		return fmt.Errorf("failed to deserialize namespace: %w", err)
+	}
 
 	// Close namespace DB if it's open
 	if handle, ok := s.namespaces[id]; ok {
@@ -174,8 +235,7 @@ func (s *PebbleStore) DeleteNamespace(ctx context.Context, id string) error {
 
 	// Delete namespace directory (skip in memory mode)
 	if s.config == nil || !s.config.InMemory {
-		nsDir := filepath.Join(s.dataDir, id)
-		if err := os.RemoveAll(nsDir); err != nil {
+		if err := os.RemoveAll(s.namespaceDir(&ns)); err != nil {
 			return fmt.Errorf("failed to delete namespace directory: %w", err)
 		}
 	}
@@ -234,3 +294,63 @@ func (s *PebbleStore) MigrateNamespaces(ctx context.Context) (int, error) {
 	// Schema version is tracked but migrations are applied via code updates
 	return 0, nil
 }
+
+// SwapNamespaceMessages exchanges the database directories of two
+// namespaces in one metadata batch, along with their open handles
+func (s *PebbleStore) SwapNamespaceMessages(ctx context.Context, a, b string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]*store.Namespace, 2)
+	for i, id := range []string{a, b} {
+		value, closer, err := s.metadataDB.Get(formatNamespaceKey(id))
+		if err != nil {
+			if err == pebble.ErrNotFound {
+				return store.ErrNamespaceNotFound
+			}
+			return fmt.Errorf("failed to read namespace metadata: %w", err)
+		}
+		var ns store.Namespace
+		err = json.Unmarshal(value, &ns)
+		closer.Close()
+		if err != nil {
+			return fmt.Errorf("failed to deserialize namespace: %w", err)
+		}
+		if ns.DBPath == "" {
+			ns.DBPath = ns.ID
+		}
+		records[i] = &ns
+	}
+	records[0].DBPath, records[1].DBPath = records[1].DBPath, records[0].DBPath
+
+	batch := s.metadataDB.NewBatch()
+	defer batch.Close()
+	for _, ns := range records {
+		value, err := json.Marshal(ns)
+		if err != nil {
+			return fmt.Errorf("failed to serialize namespace: %w", err)
+		}
+		if err := batch.Set(formatNamespaceKey(ns.ID), value, nil); err != nil {
+			return fmt.Errorf("failed to write namespace metadata: %w", err)
+		}
+	}
+	writeOpts := pebble.Sync
+	if s.config != nil && (s.config.TestMode || s.config.InMemory) {
+		writeOpts = pebble.NoSync
+	}
+	if err := batch.Commit(writeOpts); err != nil {
+		return fmt.Errorf("failed to write namespace metadata: %w", err)
+	}
+
+	handleA, okA := s.namespaces[a]
+	handleB, okB := s.namespaces[b]
+	delete(s.namespaces, a)
+	delete(s.namespaces, b)
+	if okA {
+		s.namespaces[b] = handleA
+	}
+	if okB {
+		s.namespaces[a] = handleB
+	}
+	return nil
+}
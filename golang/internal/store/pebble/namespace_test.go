@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
 )
 
 func TestCreateNamespace(t *testing.T) {
@@ -126,6 +128,43 @@ func TestGetNamespace_NotFound(t *testing.T) {
 	}
 }
 
+func TestUpdateNamespaceMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, err := New(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	if err := store.CreateNamespace(ctx, "test", "hash123", "Test namespace"); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+
+	err = store.UpdateNamespaceMetadata(ctx, "test", map[string]interface{}{"logShipping": map[string]interface{}{"type": "s3"}})
+	if err != nil {
+		t.Fatalf("UpdateNamespaceMetadata failed: %v", err)
+	}
+
+	ns, err := store.GetNamespace(ctx, "test")
+	if err != nil {
+		t.Fatalf("GetNamespace failed: %v", err)
+	}
+	shipping, ok := ns.Metadata["logShipping"].(map[string]interface{})
+	if !ok || shipping["type"] != "s3" {
+		t.Errorf("expected updated metadata, got %v", ns.Metadata)
+	}
+	if ns.Description != "Test namespace" {
+		t.Errorf("expected description to be preserved, got %q", ns.Description)
+	}
+
+	// Updating a missing namespace fails
+	if err := store.UpdateNamespaceMetadata(ctx, "nonexistent", nil); err == nil {
+		t.Error("expected error for non-existent namespace, got nil")
+	}
+}
+
 func TestListNamespaces(t *testing.T) {
 	tmpDir := t.TempDir()
 	store, err := New(tmpDir)
@@ -307,3 +346,55 @@ func TestNamespaceTokenHashing(t *testing.T) {
 		t.Error("token should be hashed, not plaintext")
 	}
 }
+
+func TestSwapNamespaceMessages(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+
+	st, err := New(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	for _, id := range []string{"live", "staged"} {
+		if err := st.CreateNamespace(ctx, id, "hash-"+id, ""); err != nil {
+			t.Fatalf("CreateNamespace failed: %v", err)
+		}
+	}
+	if _, err := st.WriteMessage(ctx, "staged", "account-1", &store.Message{Type: "Opened", Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+
+	if err := st.SwapNamespaceMessages(ctx, "live", "staged"); err != nil {
+		t.Fatalf("SwapNamespaceMessages failed: %v", err)
+	}
+	if err := st.DeleteNamespace(ctx, "staged"); err != nil {
+		t.Fatalf("DeleteNamespace failed: %v", err)
+	}
+	st.Close()
+
+	// The swapped directories survive a restart
+	st, err = New(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	defer st.Close()
+
+	ns, err := st.GetNamespace(ctx, "live")
+	if err != nil {
+		t.Fatalf("GetNamespace failed: %v", err)
+	}
+	if ns.TokenHash != "hash-live" {
+		t.Errorf("TokenHash = %s, want hash-live", ns.TokenHash)
+	}
+	msgs, err := st.GetStreamMessages(ctx, "live", "account-1", nil)
+	if err != nil {
+		t.Fatalf("GetStreamMessages failed: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Errorf("got %d messages, want 1", len(msgs))
+	}
+
+	if err := st.SwapNamespaceMessages(ctx, "live", "missing"); err == nil {
+		t.Error("expected error swapping with a missing namespace, got nil")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/pebble"
 	"github.com/eventodb/eventodb/internal/store"
@@ -90,6 +91,9 @@ func (s *PebbleStore) GetStreamMessages(ctx context.Context, namespace, streamNa
 			if err := json.Unmarshal(msgData, &msg); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal message: %w", err)
 			}
+			if err := store.MeterRead(ctx, len(msgData)); err != nil {
+				return nil, err
+			}
 
 			messages = append(messages, &msg)
 		}
@@ -150,6 +154,9 @@ func (s *PebbleStore) GetStreamMessages(ctx context.Context, namespace, streamNa
 			if err := json.Unmarshal(msgData, &msg); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal message: %w", err)
 			}
+			if err := store.MeterRead(ctx, len(msgData)); err != nil {
+				return nil, err
+			}
 
 			messages = append(messages, &msg)
 		}
@@ -165,6 +172,13 @@ func (s *PebbleStore) GetStreamMessages(ctx context.Context, namespace, streamNa
 // GetCategoryMessages retrieves messages from all streams in a category
 // If categoryName is empty, returns all messages ordered by global position
 func (s *PebbleStore) GetCategoryMessages(ctx context.Context, namespace, categoryName string, opts *store.CategoryOpts) ([]*store.Message, error) {
+	// Ordering key groups filter on metadata, after reading
+	if opts != nil && opts.OrderingKey && opts.ConsumerMember != nil && opts.ConsumerSize != nil {
+		return store.GetOrderingKeyMessages(opts, func(opts *store.CategoryOpts) ([]*store.Message, error) {
+			return s.GetCategoryMessages(ctx, namespace, categoryName, opts)
+		})
+	}
+
 	// Get namespace handle
 	handle, err := s.getNamespaceDB(ctx, namespace)
 	if err != nil {
@@ -206,9 +220,11 @@ func (s *PebbleStore) GetCategoryMessages(ctx context.Context, namespace, catego
 	// Parse consumer group options
 	var consumerMember *int64
 	var consumerSize *int64
+	var partitioner store.Partitioner
 	if opts != nil {
 		consumerMember = opts.ConsumerMember
 		consumerSize = opts.ConsumerSize
+		partitioner = opts.Partitioner
 	}
 	hasConsumerGroup := consumerMember != nil && consumerSize != nil && *consumerSize > 0
 
@@ -228,6 +244,10 @@ func (s *PebbleStore) GetCategoryMessages(ctx context.Context, namespace, catego
 		// Read more keys for consumer group filtering
 		maxScan = batchSize * (*consumerSize)
 	}
+	if opts != nil && (opts.Filter != nil || store.Sampled(opts.Sample)) {
+		// A filter or sample may skip any number of messages
+		maxScan = -1
+	}
 
 	for iter.First(); iter.Valid(); iter.Next() {
 		// Check if we've scanned enough keys
@@ -249,7 +269,7 @@ func (s *PebbleStore) GetCategoryMessages(ctx context.Context, namespace, catego
 
 		// Apply consumer group filter if specified
 		if hasConsumerGroup {
-			if !store.IsAssignedToConsumerMember(streamName, *consumerMember, *consumerSize) {
+			if !partitioner.IsAssigned(streamName, *consumerMember, *consumerSize) {
 				continue // Skip this message
 			}
 		}
@@ -293,6 +313,17 @@ func (s *PebbleStore) GetCategoryMessages(ctx context.Context, namespace, catego
 			}
 		}
 
+		// Apply EQL filter if specified
+		if opts != nil && opts.Filter != nil && !opts.Filter.Match(&msg) {
+			continue
+		}
+		if opts != nil && !store.InSample(msg.ID, opts.Sample) {
+			continue
+		}
+
+		if err := store.MeterRead(ctx, len(msgData)); err != nil {
+			return nil, err
+		}
 		messages = append(messages, &msg)
 
 		// Check if we've collected enough messages
@@ -364,6 +395,16 @@ func (s *PebbleStore) getAllMessages(ctx context.Context, handle *namespaceHandl
 			}
 		}
 
+		if opts != nil && opts.Filter != nil && !opts.Filter.Match(&msg) {
+			continue
+		}
+		if opts != nil && !store.InSample(msg.ID, opts.Sample) {
+			continue
+		}
+
+		if err := store.MeterRead(ctx, len(msgData)); err != nil {
+			return nil, err
+		}
 		messages = append(messages, &msg)
 
 		if batchSize != -1 && int64(len(messages)) >= batchSize {
@@ -391,66 +432,113 @@ func (s *PebbleStore) GetLastStreamMessage(ctx context.Context, namespace, strea
 		return nil, err
 	}
 
-	// Get stream version (last position)
-	versionKey := formatVersionIndexKey(streamName)
-	versionData, closer, err := handle.db.Get(versionKey)
-	if err != nil {
-		if err == pebble.ErrNotFound {
+	// If no type filter, get message at last position directly
+	if msgType == nil {
+		lastPosition, err := getStreamVersion(handle.db, streamName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stream version: %w", err)
+		}
+		if lastPosition < 0 {
 			return nil, store.ErrStreamNotFound
 		}
-		return nil, fmt.Errorf("failed to get stream version: %w", err)
+		return getStreamMessageAt(handle.db, streamName, lastPosition)
 	}
 
-	lastPosition, err := decodeInt64(versionData)
-	closer.Close()
+	// With type filter: TI:{stream}{type} holds the last position of the type
+	typePosition, err := getTypeIndex(handle.db, formatTypeIndexKey(streamName, *msgType))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode stream version: %w", err)
+		return nil, fmt.Errorf("failed to get type index: %w", err)
+	}
+	if typePosition >= 0 {
+		return getStreamMessageAt(handle.db, streamName, typePosition)
 	}
 
-	// If no type filter, get message at last position directly
-	if msgType == nil {
-		// Get global position from stream index
-		siKey := formatStreamIndexKey(streamName, lastPosition)
-		gpData, closer, err := handle.db.Get(siKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get global position: %w", err)
-		}
+	// Not indexed: the stream has no message of this type, unless it has
+	// messages from before the type index existed
+	covered, err := typeIndexCovers(handle.db, streamName)
+	if err != nil {
+		return nil, err
+	}
+	if covered {
+		return nil, store.ErrStreamNotFound
+	}
+	return scanLastStreamMessage(handle.db, streamName, *msgType)
+}
 
-		gp, err := decodeInt64(gpData)
-		closer.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode global position: %w", err)
-		}
+// getStreamMessageAt reads the message at a stream position
+func getStreamMessageAt(db *pebble.DB, streamName string, position int64) (*store.Message, error) {
+	// Get global position from stream index
+	gpData, closer, err := db.Get(formatStreamIndexKey(streamName, position))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global position: %w", err)
+	}
+	gp, err := decodeInt64(gpData)
+	closer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode global position: %w", err)
+	}
+	return getMessageAt(db, gp)
+}
 
-		// Get message
-		msgKey := formatMessageKey(gp)
-		compressedData, closer, err := handle.db.Get(msgKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get message: %w", err)
-		}
+// getMessageAt reads the message at a global position
+func getMessageAt(db *pebble.DB, gp int64) (*store.Message, error) {
+	compressedData, closer, err := db.Get(formatMessageKey(gp))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message at gp=%d: %w", gp, err)
+	}
 
-		// Decompress S2-compressed data
-		msgData, err := decompressJSON(compressedData)
-		closer.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to decompress message: %w", err)
-		}
+	// Decompress S2-compressed data
+	msgData, err := decompressJSON(compressedData)
+	closer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress message: %w", err)
+	}
 
-		var msg store.Message
-		if err := json.Unmarshal(msgData, &msg); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal message: %w", err)
-		}
+	var msg store.Message
+	if err := json.Unmarshal(msgData, &msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+	return &msg, nil
+}
 
-		return &msg, nil
+// typeIndexCovers reports whether every message of a stream is in the type
+// index, which holds when its first message was written after the index began
+func typeIndexCovers(db *pebble.DB, streamName string) (bool, error) {
+	startData, closer, err := db.Get(formatTypeIndexStartKey())
+	if err != nil {
+		return false, fmt.Errorf("failed to get type index start: %w", err)
+	}
+	start, err := decodeInt64(startData)
+	closer.Close()
+	if err != nil {
+		return false, fmt.Errorf("failed to decode type index start: %w", err)
 	}
 
-	// With type filter: iterate backwards through stream index
-	startKey := formatStreamIndexKey(streamName, 0)
-	endKey := formatStreamIndexKey(streamName, 999999999999999999) // Max 18-digit number
+	iter, err := db.NewIter(&pebble.IterOptions{
+		LowerBound: formatStreamIndexKey(streamName, 0),
+		UpperBound: formatStreamIndexKey(streamName, 999999999999999999),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
 
-	iter, err := handle.db.NewIter(&pebble.IterOptions{
-		LowerBound: startKey,
-		UpperBound: endKey,
+	if !iter.First() {
+		return true, iter.Error()
+	}
+	firstGP, err := decodeInt64(iter.Value())
+	if err != nil {
+		return false, fmt.Errorf("failed to decode global position: %w", err)
+	}
+	return firstGP >= start, nil
+}
+
+// scanLastStreamMessage finds the last message of a type by iterating
+// backwards through the stream index, for streams older than the type index
+func scanLastStreamMessage(db *pebble.DB, streamName, msgType string) (*store.Message, error) {
+	iter, err := db.NewIter(&pebble.IterOptions{
+		LowerBound: formatStreamIndexKey(streamName, 0),
+		UpperBound: formatStreamIndexKey(streamName, 999999999999999999), // Max 18-digit number
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create iterator: %w", err)
@@ -460,15 +548,13 @@ func (s *PebbleStore) GetLastStreamMessage(ctx context.Context, namespace, strea
 	// Iterate backwards from last position
 	for valid := iter.Last(); valid; valid = iter.Prev() {
 		// Extract global position from value
-		gpData := iter.Value()
-		gp, err := decodeInt64(gpData)
+		gp, err := decodeInt64(iter.Value())
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode global position: %w", err)
 		}
 
 		// Get message
-		msgKey := formatMessageKey(gp)
-		compressedData, closer, err := handle.db.Get(msgKey)
+		compressedData, closer, err := db.Get(formatMessageKey(gp))
 		if err != nil {
 			return nil, fmt.Errorf("failed to get message at gp=%d: %w", gp, err)
 		}
@@ -486,7 +572,7 @@ func (s *PebbleStore) GetLastStreamMessage(ctx context.Context, namespace, strea
 		}
 
 		// Check if type matches
-		if msg.Type == *msgType {
+		if msg.Type == msgType {
 			return &msg, nil
 		}
 	}
@@ -533,7 +619,7 @@ func (s *PebbleStore) GetStreamVersion(ctx context.Context, namespace, streamNam
 
 // ListStreams returns streams in a namespace with optional prefix filtering and pagination.
 // It iterates the version index (VI:{stream}) for stream names, then looks up
-// the last message for version and lastActivity.
+// the first and last messages for firstActivity and lastActivity.
 func (s *PebbleStore) ListStreams(ctx context.Context, namespace string, opts *store.ListStreamsOpts) ([]*store.StreamInfo, error) {
 	handle, err := s.getNamespaceDB(ctx, namespace)
 	if err != nil {
@@ -591,40 +677,33 @@ func (s *PebbleStore) ListStreams(ctx context.Context, namespace string, opts *s
 			return nil, fmt.Errorf("failed to decode version for stream %s: %w", streamName, err)
 		}
 
-		// Look up last message to get lastActivity: SI:{stream}:{version}
-		siKey := formatStreamIndexKey(streamName, version)
-		gpData, closer, err := handle.db.Get(siKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get stream index for %s: %w", streamName, err)
-		}
-		gp, err := decodeInt64(gpData)
-		closer.Close()
+		// Look up the last message for lastActivity: SI:{stream}:{version}
+		lastActivity, err := streamIndexTime(handle, formatStreamIndexKey(streamName, version))
 		if err != nil {
-			return nil, fmt.Errorf("failed to decode global position for stream %s: %w", streamName, err)
+			return nil, fmt.Errorf("failed to get last message of stream %s: %w", streamName, err)
 		}
 
-		// Get message to find time
-		msgKey := formatMessageKey(gp)
-		compressedData, closer, err := handle.db.Get(msgKey)
+		// And the first remaining message for firstActivity
+		firstKey, err := firstStreamIndexKey(handle, streamName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get message at gp=%d for stream %s: %w", gp, streamName, err)
+			return nil, fmt.Errorf("failed to get first message of stream %s: %w", streamName, err)
 		}
-		msgData, err := decompressJSON(compressedData)
-		closer.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to decompress message: %w", err)
+		firstActivity := lastActivity
+		if firstKey != nil {
+			if firstActivity, err = streamIndexTime(handle, firstKey); err != nil {
+				return nil, fmt.Errorf("failed to get first message of stream %s: %w", streamName, err)
+			}
 		}
 
-		var msg store.Message
-		if err := json.Unmarshal(msgData, &msg); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+		si := &store.StreamInfo{
+			StreamName:    streamName,
+			Version:       version,
+			FirstActivity: firstActivity,
+			LastActivity:  lastActivity,
+		}
+		if opts.Matches(si) {
+			results = append(results, si)
 		}
-
-		results = append(results, &store.StreamInfo{
-			StreamName:   streamName,
-			Version:      version,
-			LastActivity: msg.Time.UTC(),
-		})
 	}
 
 	if err := iter.Error(); err != nil {
@@ -634,6 +713,54 @@ func (s *PebbleStore) ListStreams(ctx context.Context, namespace string, opts *s
 	return results, nil
 }
 
+// streamIndexTime returns the time of the message a stream index key points to
+func streamIndexTime(handle *namespaceHandle, siKey []byte) (time.Time, error) {
+	gpData, closer, err := handle.db.Get(siKey)
+	if err != nil {
+		return time.Time{}, err
+	}
+	gp, err := decodeInt64(gpData)
+	closer.Close()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode global position: %w", err)
+	}
+
+	compressedData, closer, err := handle.db.Get(formatMessageKey(gp))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get message at gp=%d: %w", gp, err)
+	}
+	msgData, err := decompressJSON(compressedData)
+	closer.Close()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decompress message: %w", err)
+	}
+
+	var msg store.Message
+	if err := json.Unmarshal(msgData, &msg); err != nil {
+		return time.Time{}, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+	return msg.Time.UTC(), nil
+}
+
+// firstStreamIndexKey returns the stream index key of a stream's first
+// remaining message, or nil if it has none
+func firstStreamIndexKey(handle *namespaceHandle, streamName string) ([]byte, error) {
+	prefix := prefixStreamIndex + streamName + keySeparator
+	iter, err := handle.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix + encodeInt64(0)),
+		UpperBound: []byte(prefix + "\xff"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	if !iter.First() {
+		return nil, iter.Error()
+	}
+	return append([]byte(nil), iter.Key()...), nil
+}
+
 // ListCategories returns distinct categories in a namespace with stream and message counts.
 // It iterates the version index (VI:{stream}) to enumerate streams, derives categories,
 // then counts messages via the category index (CI:{category}:*).
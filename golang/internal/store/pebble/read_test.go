@@ -2,6 +2,8 @@ package pebble
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -328,6 +330,53 @@ func TestGetCategoryMessages_Correlation(t *testing.T) {
 	}
 }
 
+func TestGetCategoryMessages_Filter(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	if err := s.CreateNamespace(ctx, "test", "secret123", "Test namespace"); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		msg := &store.Message{
+			StreamName: fmt.Sprintf("account-%d", i%2),
+			Type:       "Deposited",
+			Data:       map[string]interface{}{"amount": float64(i * 10)},
+		}
+		if _, err := s.WriteMessage(ctx, "test", msg.StreamName, msg); err != nil {
+			t.Fatalf("failed to write message: %v", err)
+		}
+	}
+
+	// Filtered messages do not count toward the batch
+	filter, err := store.ParseFilter("data.amount >= 50 AND stream = 'account-1'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := s.GetCategoryMessages(ctx, "test", "account", &store.CategoryOpts{Position: 1, BatchSize: 2, Filter: filter})
+	if err != nil {
+		t.Fatalf("failed to get category messages: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Data["amount"] != float64(50) || msgs[1].Data["amount"] != float64(70) {
+		t.Errorf("expected amounts 50 and 70, got %v", msgs)
+	}
+
+	// All messages
+	msgs, err = s.GetCategoryMessages(ctx, "test", "", &store.CategoryOpts{Position: 1, BatchSize: 100, Filter: filter})
+	if err != nil {
+		t.Fatalf("failed to get all messages: %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Errorf("expected 3 messages, got %d", len(msgs))
+	}
+}
+
 func TestGetLastStreamMessage(t *testing.T) {
 	// Setup
 	dir := t.TempDir()
@@ -395,6 +444,75 @@ func TestGetLastStreamMessage(t *testing.T) {
 	}
 }
 
+func TestGetLastStreamMessage_TypeIndex(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+	s, err := New(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+
+	// Create namespace
+	if err := s.CreateNamespace(ctx, "test", "secret123", "Test namespace"); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+
+	// A stream written before the type index existed is found by scanning
+	legacy := "account-1"
+	if _, err := s.WriteMessage(ctx, "test", legacy, &store.Message{Type: "Reserved", Data: map[string]interface{}{}}); err != nil {
+		t.Fatalf("failed to write message: %v", err)
+	}
+	handle, err := s.getNamespaceDB(ctx, "test")
+	if err != nil {
+		t.Fatalf("failed to get namespace DB: %v", err)
+	}
+	if err := handle.db.Delete(formatTypeIndexKey(legacy, "Reserved"), nil); err != nil {
+		t.Fatalf("failed to delete type index: %v", err)
+	}
+	if err := handle.db.Set(formatTypeIndexStartKey(), []byte(encodeInt64(2)), nil); err != nil {
+		t.Fatalf("failed to set type index start: %v", err)
+	}
+
+	msgType := "Reserved"
+	msg, err := s.GetLastStreamMessage(ctx, "test", legacy, &msgType)
+	if err != nil || msg.Position != 0 {
+		t.Errorf("expected legacy Reserved at position 0, got %v (%v)", msg, err)
+	}
+
+	// Imported messages are indexed, including several of a type in one batch
+	imported := "account-2"
+	batch := []*store.Message{
+		{ID: "00000000-0000-0000-0000-000000000010", StreamName: imported, Type: "Reserved", Position: 0, GlobalPosition: 10, Data: map[string]interface{}{}, Time: time.Now().UTC()},
+		{ID: "00000000-0000-0000-0000-000000000011", StreamName: imported, Type: "Reserved", Position: 1, GlobalPosition: 11, Data: map[string]interface{}{}, Time: time.Now().UTC()},
+		{ID: "00000000-0000-0000-0000-000000000012", StreamName: imported, Type: "Released", Position: 2, GlobalPosition: 12, Data: map[string]interface{}{}, Time: time.Now().UTC()},
+	}
+	if err := s.ImportBatch(ctx, "test", batch); err != nil {
+		t.Fatalf("failed to import batch: %v", err)
+	}
+	msg, err = s.GetLastStreamMessage(ctx, "test", imported, &msgType)
+	if err != nil || msg.Position != 1 {
+		t.Errorf("expected imported Reserved at position 1, got %v (%v)", msg, err)
+	}
+
+	// A missing type in an indexed stream is answered from the index
+	msgType = "Cancelled"
+	if _, err := s.GetLastStreamMessage(ctx, "test", imported, &msgType); err != store.ErrStreamNotFound {
+		t.Errorf("expected ErrStreamNotFound, got %v", err)
+	}
+
+	// Clearing the namespace clears the index
+	if _, err := s.ClearNamespaceMessages(ctx, "test"); err != nil {
+		t.Fatalf("failed to clear namespace: %v", err)
+	}
+	msgType = "Reserved"
+	if _, err := s.GetLastStreamMessage(ctx, "test", imported, &msgType); err != store.ErrStreamNotFound {
+		t.Errorf("expected ErrStreamNotFound after clear, got %v", err)
+	}
+}
+
 func TestGetLastStreamMessage_StreamNotFound(t *testing.T) {
 	// Setup
 	dir := t.TempDir()
@@ -518,3 +636,96 @@ func TestGetStreamMessages_InvalidNamespace(t *testing.T) {
 		t.Error("expected error for non-existent namespace, got nil")
 	}
 }
+
+func TestGetCategoryMessages_Partitioners(t *testing.T) {
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	if err := s.CreateNamespace(ctx, "test", "secret123", "Test namespace"); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+
+	for i := 0; i < 30; i++ {
+		stream := fmt.Sprintf("account-%d", i)
+		if _, err := s.WriteMessage(ctx, "test", stream, &store.Message{
+			StreamName: stream,
+			Type:       "AccountEvent",
+			Data:       map[string]interface{}{},
+		}); err != nil {
+			t.Fatalf("failed to write message: %v", err)
+		}
+	}
+
+	size := int64(3)
+	for _, p := range store.Partitioners {
+		total := 0
+		for member := int64(0); member < size; member++ {
+			m := member
+			msgs, err := s.GetCategoryMessages(ctx, "test", "account", &store.CategoryOpts{
+				Position:       1,
+				BatchSize:      100,
+				ConsumerMember: &m,
+				ConsumerSize:   &size,
+				Partitioner:    p,
+			})
+			if err != nil {
+				t.Fatalf("%s: failed to get messages for member %d: %v", p, member, err)
+			}
+			for _, msg := range msgs {
+				if got := p.Member(msg.StreamName, size); got != member {
+					t.Errorf("%s: stream %s read by member %d, assigned to %d", p, msg.StreamName, member, got)
+				}
+			}
+			total += len(msgs)
+		}
+		if total != 30 {
+			t.Errorf("%s: expected 30 messages across members, got %d", p, total)
+		}
+	}
+}
+
+func TestGetStreamMessages_ReadMeter(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	if err := s.CreateNamespace(ctx, "test", "secret123", "Test namespace"); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		msg := &store.Message{StreamName: "account-1", Type: "Deposited", Data: map[string]interface{}{"amount": float64(i)}}
+		if _, err := s.WriteMessage(ctx, "test", msg.StreamName, msg); err != nil {
+			t.Fatalf("failed to write message: %v", err)
+		}
+	}
+
+	// The meter sees every loaded message and stops the read at its error
+	errFull := errors.New("full")
+	var loaded int
+	var total int64
+	meterCtx := store.WithReadMeter(ctx, func(size int64) error {
+		loaded++
+		total += size
+		if total > 500 {
+			return errFull
+		}
+		return nil
+	})
+	if _, err := s.GetStreamMessages(meterCtx, "test", "account-1", &store.GetOpts{BatchSize: -1}); !errors.Is(err, errFull) {
+		t.Fatalf("expected the meter's error, got %v", err)
+	}
+	if loaded == 0 || loaded >= 10 {
+		t.Errorf("expected the read to stop early, loaded %d", loaded)
+	}
+	if _, err := s.GetCategoryMessages(meterCtx, "test", "account", &store.CategoryOpts{Position: 1, BatchSize: 100}); !errors.Is(err, errFull) {
+		t.Errorf("expected the meter's error for a category read, got %v", err)
+	}
+}
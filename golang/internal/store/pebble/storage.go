@@ -0,0 +1,72 @@
+package pebble
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// NamespaceStorage returns the estimated disk usage of a namespace's key
+// range, split by category in proportion to each category's message count
+// in the category index
+func (s *PebbleStore) NamespaceStorage(ctx context.Context, namespace string) (*store.StorageUsage, error) {
+	handle, err := s.getNamespaceDB(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := handle.db.EstimateDiskUsage([]byte{0x00}, []byte{0xff})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate disk usage: %w", err)
+	}
+
+	// Index keys are CI:<category>:<20-digit global position>
+	prefix := []byte(prefixCategoryIndex)
+	iter, err := handle.db.NewIter(&pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: prefixUpperBound(prefix),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	counts := make(map[string]int64)
+	for iter.First(); iter.Valid(); iter.Next() {
+		key := iter.Key()
+		if len(key) < len(prefix)+len(keySeparator)+intWidth {
+			continue
+		}
+		counts[string(key[len(prefix):len(key)-len(keySeparator)-intWidth])]++
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate category index: %w", err)
+	}
+
+	// Data still in the memtable has no disk usage yet; count its raw size
+	if total == 0 {
+		raw, err := rawSize(handle.db)
+		if err != nil {
+			return nil, err
+		}
+		total = raw
+	}
+	return store.NewStorageUsage(int64(total), counts), nil
+}
+
+// rawSize returns the summed size of all keys and values in db
+func rawSize(db *pebble.DB) (uint64, error) {
+	iter, err := db.NewIter(nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var size uint64
+	for iter.First(); iter.Valid(); iter.Next() {
+		size += uint64(len(iter.Key()) + len(iter.Value()))
+	}
+	return size, iter.Error()
+}
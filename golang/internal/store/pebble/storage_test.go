@@ -0,0 +1,103 @@
+package pebble
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestNamespaceStorage tests splitting disk usage over categories
+func TestNamespaceStorage(t *testing.T) {
+	st, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateNamespace(ctx, "sized", "hash123", ""); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+	for _, stream := range []string{"account-1", "account-2", "account-3", "order-1"} {
+		msg := &store.Message{StreamName: stream, Type: "Created", Data: map[string]interface{}{"note": "x"}}
+		if _, err := st.WriteMessage(ctx, "sized", stream, msg); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+
+	usage, err := st.NamespaceStorage(ctx, "sized")
+	if err != nil {
+		t.Fatalf("NamespaceStorage failed: %v", err)
+	}
+	if usage.Bytes <= 0 || len(usage.Categories) != 2 {
+		t.Fatalf("Unexpected usage: %+v", usage)
+	}
+	if diff := usage.Categories["account"] - 3*usage.Categories["order"]; diff < -3 || diff > 3 {
+		t.Errorf("Expected account to take 3/4 of the space, got %v", usage.Categories)
+	}
+
+	if _, err := st.NamespaceStorage(ctx, "missing"); err == nil {
+		t.Error("Expected error for unknown namespace")
+	}
+}
+
+// TestNamespaceStorage_Edges tests empty namespaces, compound categories,
+// flushed data and unknown namespaces
+func TestNamespaceStorage_Edges(t *testing.T) {
+	st, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateNamespace(ctx, "sized", "hash123", ""); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+
+	// An empty namespace has no categories
+	usage, err := st.NamespaceStorage(ctx, "sized")
+	if err != nil {
+		t.Fatalf("NamespaceStorage failed: %v", err)
+	}
+	if len(usage.Categories) != 0 {
+		t.Errorf("Expected no categories, got %v", usage.Categories)
+	}
+
+	// Compound categories are kept apart from their base category
+	for _, stream := range []string{"account-1", "account:command-1"} {
+		msg := &store.Message{StreamName: stream, Type: "Created", Data: map[string]interface{}{"note": "x"}}
+		if _, err := st.WriteMessage(ctx, "sized", stream, msg); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+	usage, err = st.NamespaceStorage(ctx, "sized")
+	if err != nil {
+		t.Fatalf("NamespaceStorage failed: %v", err)
+	}
+	if len(usage.Categories) != 2 || usage.Categories["account"] <= 0 || usage.Categories["account:command"] <= 0 {
+		t.Errorf("Expected account and account:command, got %v", usage.Categories)
+	}
+
+	// Flushed data is measured on disk
+	handle, err := st.getNamespaceDB(ctx, "sized")
+	if err != nil {
+		t.Fatalf("getNamespaceDB failed: %v", err)
+	}
+	if err := handle.db.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	flushed, err := st.NamespaceStorage(ctx, "sized")
+	if err != nil {
+		t.Fatalf("NamespaceStorage failed: %v", err)
+	}
+	if flushed.Bytes <= 0 || len(flushed.Categories) != 2 {
+		t.Errorf("Unexpected usage after flush: %+v", flushed)
+	}
+
+	if _, err := st.NamespaceStorage(ctx, "missing"); !errors.Is(err, store.ErrNamespaceNotFound) {
+		t.Errorf("Expected ErrNamespaceNotFound, got %v", err)
+	}
+}
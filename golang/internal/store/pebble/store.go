@@ -20,6 +20,13 @@ const (
 type Config struct {
 	TestMode bool // Use reduced memory settings optimized for tests
 	InMemory bool // Use in-memory storage (faster, no disk persistence)
+
+	// EncryptionKey encrypts all files written under the data directory with
+	// AES-256 (EncryptionKeySize bytes). Nil stores plaintext. Ignored in memory.
+	EncryptionKey []byte
+
+	// Positions assigns global positions; nil continues from the last one
+	Positions store.PositionAllocator
 }
 
 // PebbleStore implements store.Store using Pebble key-value store
@@ -28,6 +35,7 @@ type PebbleStore struct {
 	namespaces map[string]*namespaceHandle // Lazy-loaded namespace DBs
 	dataDir    string                      // Base directory for all databases
 	config     *Config                     // Configuration options
+	fs         vfs.FS                      // Encrypting filesystem, nil for plaintext
 	mu         sync.RWMutex                // Protects namespaces map
 }
 
@@ -51,14 +59,30 @@ func NewWithConfig(dataDir string, config *Config) (*PebbleStore, error) {
 		}
 	}
 	// Create data directory if not in-memory mode
+	var fs vfs.FS
 	if !config.InMemory {
 		if err := os.MkdirAll(dataDir, 0755); err != nil {
 			return nil, fmt.Errorf("failed to create data directory: %w", err)
 		}
+
+		// Refuse to mix encrypted and plaintext data
+		if err := checkDataDirEncryption(dataDir, config.EncryptionKey); err != nil {
+			return nil, err
+		}
+		if config.EncryptionKey != nil {
+			encFS, err := newEncryptedFS(vfs.Default, config.EncryptionKey)
+			if err != nil {
+				return nil, err
+			}
+			fs = encFS
+		}
 	}
 
 	// Get options based on config
 	metadataOpts := getMetadataDBOptions(config)
+	if fs != nil {
+		metadataOpts.FS = fs
+	}
 
 	// Open metadata DB
 	var metadataPath string
@@ -78,6 +102,7 @@ func NewWithConfig(dataDir string, config *Config) (*PebbleStore, error) {
 		namespaces: make(map[string]*namespaceHandle),
 		dataDir:    dataDir,
 		config:     config,
+		fs:         fs,
 	}, nil
 }
 
@@ -117,14 +142,19 @@ func (s *PebbleStore) getNamespaceDB(ctx context.Context, nsID string) (*namespa
 
 	// Verify namespace exists in metadata DB (before acquiring write lock)
 	key := formatNamespaceKey(nsID)
-	_, closer, err := s.metadataDB.Get(key)
+	value, closer, err := s.metadataDB.Get(key)
 	if err != nil {
 		if err == pebble.ErrNotFound {
-			return nil, fmt.Errorf("namespace %s not found", nsID)
+			return nil, fmt.Errorf("namespace %s: %w", nsID, store.ErrNamespaceNotFound)
 		}
 		return nil, fmt.Errorf("failed to check namespace existence: %w", err)
 	}
+	var ns store.Namespace
+	err = json.Unmarshal(value, &ns)
 	closer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize namespace: %w", err)
+	}
 
 	// Slow path: open namespace DB
 	s.mu.Lock()
@@ -137,19 +167,26 @@ func (s *PebbleStore) getNamespaceDB(ctx context.Context, nsID string) (*namespa
 
 	// Get options based on config
 	namespaceOpts := getNamespaceDBOptions(s.config)
+	if s.fs != nil {
+		namespaceOpts.FS = s.fs
+	}
 
 	// Open namespace Pebble DB
 	var dbPath string
 	if s.config.InMemory {
 		dbPath = "" // Empty path triggers in-memory mode
 	} else {
-		dbPath = filepath.Join(s.dataDir, nsID)
+		dbPath = s.namespaceDir(&ns)
 	}
 
 	db, err := pebble.Open(dbPath, namespaceOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open namespace DB: %w", err)
 	}
+	if err := initTypeIndex(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize type index: %w", err)
+	}
 
 	// Cache handle
 	handle := &namespaceHandle{db: db}
@@ -157,6 +194,15 @@ func (s *PebbleStore) getNamespaceDB(ctx context.Context, nsID string) (*namespa
 	return handle, nil
 }
 
+// namespaceDir returns the directory of a namespace's database: its ID, or
+// the directory it took over with SwapNamespaceMessages
+func (s *PebbleStore) namespaceDir(ns *store.Namespace) string {
+	if ns.DBPath != "" {
+		return filepath.Join(s.dataDir, ns.DBPath)
+	}
+	return filepath.Join(s.dataDir, ns.ID)
+}
+
 // Verify PebbleStore implements store.Store interface
 var _ store.Store = (*PebbleStore)(nil)
 
@@ -2,7 +2,9 @@ package pebble
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/cockroachdb/pebble"
@@ -42,8 +44,8 @@ func (s *PebbleStore) WriteMessage(ctx context.Context, namespace, streamName st
 	// Calculate new position
 	newPosition := currentVersion + 1
 
-	// Get and increment global position
-	globalPosition, err := getAndIncrementGlobalPosition(handle.db)
+	// Allocate the global position
+	globalPosition, err := s.allocatePositions(ctx, namespace, handle.db, 1)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get global position: %w", err)
 	}
@@ -63,47 +65,307 @@ func (s *PebbleStore) WriteMessage(ctx context.Context, namespace, streamName st
 		msg.ID = id.String()
 	}
 
-	// Serialize message to JSON using jsoniter
-	messageJSON, err := json.Marshal(msg)
+	// Create atomic batch with all 6 keys
+	batch := handle.db.NewBatch()
+	defer batch.Close()
+
+	// 1-5. Message and index keys
+	if err := setMessageKeys(batch, msg); err != nil {
+		return nil, err
+	}
+
+	// 6. GP → incremented global position
+	batch.Set(formatGlobalPositionKey(), []byte(encodeInt64(globalPosition+1)), nil)
+
+	// Commit batch WITHOUT sync for performance (WAL provides durability)
+	// The WAL (Write-Ahead Log) ensures durability without forcing fsync on every write
+	if err := batch.Commit(pebble.NoSync); err != nil {
+		return nil, fmt.Errorf("failed to commit write batch: %w", err)
+	}
+
+	return &store.WriteResult{
+		Position:       newPosition,
+		GlobalPosition: globalPosition,
+	}, nil
+}
+
+// WriteMessages writes messages to their streams in one atomic batch
+func (s *PebbleStore) WriteMessages(ctx context.Context, namespace string, msgs []*store.Message) ([]*store.WriteResult, error) {
+	handle, err := s.getNamespaceDB(ctx, namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to serialize message: %w", err)
+		return nil, err
 	}
 
-	// Compress JSON using S2
-	compressedMessage := compressJSON(messageJSON)
+	handle.writeMu.Lock()
+	defer handle.writeMu.Unlock()
 
-	// Extract category
-	category := extractCategory(streamName)
+	globalPosition, err := s.allocatePositions(ctx, namespace, handle.db, int64(len(msgs)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global position: %w", err)
+	}
 
-	// Create atomic batch with all 5 keys
 	batch := handle.db.NewBatch()
 	defer batch.Close()
 
+	// Versions of the streams written so far, including this batch
+	versions := make(map[string]int64, len(msgs))
+	results := make([]*store.WriteResult, 0, len(msgs))
+	now := time.Now().UTC()
+	for _, msg := range msgs {
+		if msg.StreamName == "" {
+			return nil, fmt.Errorf("stream name cannot be empty")
+		}
+		version, ok := versions[msg.StreamName]
+		if !ok {
+			if version, err = getStreamVersion(handle.db, msg.StreamName); err != nil {
+				return nil, fmt.Errorf("failed to get stream version: %w", err)
+			}
+		}
+		if msg.ExpectedVersion != nil && *msg.ExpectedVersion != version {
+			return nil, store.ErrVersionConflict
+		}
+
+		msg.Position = version + 1
+		msg.GlobalPosition = globalPosition
+		msg.Time = now
+		if msg.ID == "" {
+			id, err := uuid.NewV7()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate UUID: %w", err)
+			}
+			msg.ID = id.String()
+		}
+		if err := setMessageKeys(batch, msg); err != nil {
+			return nil, err
+		}
+
+		versions[msg.StreamName] = msg.Position
+		results = append(results, &store.WriteResult{Position: msg.Position, GlobalPosition: globalPosition})
+		globalPosition++
+	}
+	batch.Set(formatGlobalPositionKey(), []byte(encodeInt64(globalPosition)), nil)
+
+	if err := batch.Commit(pebble.NoSync); err != nil {
+		return nil, fmt.Errorf("failed to commit write batch: %w", err)
+	}
+	return results, nil
+}
+
+// setMessageKeys adds a positioned message and its index entries to batch
+func setMessageKeys(batch *pebble.Batch, msg *store.Message) error {
+	// Serialize message to JSON using jsoniter, then compress using S2
+	messageJSON, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message: %w", err)
+	}
+	compressedMessage := compressJSON(messageJSON)
+	position := []byte(encodeInt64(msg.Position))
+
 	// 1. M:{gp} → compressed message JSON
-	batch.Set(formatMessageKey(globalPosition), compressedMessage, nil)
+	batch.Set(formatMessageKey(msg.GlobalPosition), compressedMessage, nil)
 
 	// 2. SI:{stream}:{position} → global position
-	batch.Set(formatStreamIndexKey(streamName, newPosition), []byte(encodeInt64(globalPosition)), nil)
+	batch.Set(formatStreamIndexKey(msg.StreamName, msg.Position), []byte(encodeInt64(msg.GlobalPosition)), nil)
 
 	// 3. CI:{category}:{gp} → stream name
-	batch.Set(formatCategoryIndexKey(category, globalPosition), []byte(streamName), nil)
+	batch.Set(formatCategoryIndexKey(extractCategory(msg.StreamName), msg.GlobalPosition), []byte(msg.StreamName), nil)
 
 	// 4. VI:{stream} → new position
-	batch.Set(formatVersionIndexKey(streamName), []byte(encodeInt64(newPosition)), nil)
+	batch.Set(formatVersionIndexKey(msg.StreamName), position, nil)
 
-	// 5. GP → incremented global position
-	batch.Set(formatGlobalPositionKey(), []byte(encodeInt64(globalPosition+1)), nil)
+	// 5. TI:{stream}{type} → new position
+	batch.Set(formatTypeIndexKey(msg.StreamName, msg.Type), position, nil)
+	return nil
+}
+
+// RedactMessage replaces the data and metadata of the message at position,
+// then compacts its key so the old value is dropped from the data files.
+// Write-ahead log files keep it until they are recycled.
+func (s *PebbleStore) RedactMessage(ctx context.Context, namespace, streamName string, position int64, data, metadata map[string]interface{}) error {
+	handle, err := s.getNamespaceDB(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	handle.writeMu.Lock()
+	defer handle.writeMu.Unlock()
+
+	msg, err := getStreamMessageAt(handle.db, streamName, position)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return store.ErrMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+	msg.Data = data
+	msg.Metadata = metadata
+
+	messageJSON, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message: %w", err)
+	}
+	key := formatMessageKey(msg.GlobalPosition)
+	if err := handle.db.Set(key, compressJSON(messageJSON), pebble.Sync); err != nil {
+		return fmt.Errorf("failed to redact message: %w", err)
+	}
+	if err := handle.db.Compact(key, append(key, 0), false); err != nil {
+		return fmt.Errorf("failed to compact redacted message: %w", err)
+	}
+	return nil
+}
+
+// TruncateStream deletes the messages of a stream below position, always
+// keeping the last one, and drops type index entries that pointed to them
+func (s *PebbleStore) TruncateStream(ctx context.Context, namespace, streamName string, position int64) (int64, error) {
+	handle, err := s.getNamespaceDB(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	handle.writeMu.Lock()
+	defer handle.writeMu.Unlock()
+
+	version, err := getStreamVersion(handle.db, streamName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stream version: %w", err)
+	}
+	if position > version {
+		position = version
+	}
+	if position <= 0 {
+		return 0, nil
+	}
+
+	batch := handle.db.NewBatch()
+	defer batch.Close()
+
+	// SI:{stream}:{pos} below position, with the M: and CI: keys they point to
+	iter, err := handle.db.NewIter(&pebble.IterOptions{
+		LowerBound: formatStreamIndexKey(streamName, 0),
+		UpperBound: formatStreamIndexKey(streamName, position),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	category := extractCategory(streamName)
+	var deleted int64
+	for iter.First(); iter.Valid(); iter.Next() {
+		gp, err := decodeInt64(iter.Value())
+		if err != nil {
+			iter.Close()
+			return 0, fmt.Errorf("failed to decode global position: %w", err)
+		}
+		batch.Delete(iter.Key(), nil)
+		batch.Delete(formatMessageKey(gp), nil)
+		batch.Delete(formatCategoryIndexKey(category, gp), nil)
+		deleted++
+	}
+	if err := iter.Close(); err != nil {
+		return 0, fmt.Errorf("iterator error: %w", err)
+	}
+
+	// TI:{stream}{type} entries whose last position was deleted
+	typePrefix := formatTypeIndexKey(streamName, "")
+	iter, err = handle.db.NewIter(&pebble.IterOptions{
+		LowerBound: typePrefix,
+		UpperBound: prefixUpperBound(typePrefix),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	for iter.First(); iter.Valid(); iter.Next() {
+		if pos, err := decodeInt64(iter.Value()); err == nil && pos < position {
+			batch.Delete(iter.Key(), nil)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return 0, fmt.Errorf("iterator error: %w", err)
+	}
 
-	// Commit batch WITHOUT sync for performance (WAL provides durability)
-	// The WAL (Write-Ahead Log) ensures durability without forcing fsync on every write
 	if err := batch.Commit(pebble.NoSync); err != nil {
-		return nil, fmt.Errorf("failed to commit write batch: %w", err)
+		return 0, fmt.Errorf("failed to commit truncate batch: %w", err)
 	}
+	return deleted, nil
+}
 
-	return &store.WriteResult{
-		Position:       newPosition,
-		GlobalPosition: globalPosition,
-	}, nil
+// MergeStreams moves the messages of sources into target and renumbers
+// target in global position order. The old stream, category, version and
+// type index entries of every stream involved are deleted and the merged
+// messages are written back under their global positions in one batch.
+func (s *PebbleStore) MergeStreams(ctx context.Context, namespace, target string, sources []string) (int64, error) {
+	handle, err := s.getNamespaceDB(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	handle.writeMu.Lock()
+	defer handle.writeMu.Unlock()
+
+	batch := handle.db.NewBatch()
+	defer batch.Close()
+
+	var merged []*store.Message
+	for _, stream := range append([]string{target}, sources...) {
+		version, err := getStreamVersion(handle.db, stream)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get stream version: %w", err)
+		}
+		if version < 0 {
+			continue
+		}
+
+		iter, err := handle.db.NewIter(&pebble.IterOptions{
+			LowerBound: formatStreamIndexKey(stream, 0),
+			UpperBound: formatStreamIndexKey(stream, version+1),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to create iterator: %w", err)
+		}
+		category := extractCategory(stream)
+		for iter.First(); iter.Valid(); iter.Next() {
+			gp, err := decodeInt64(iter.Value())
+			if err != nil {
+				iter.Close()
+				return 0, fmt.Errorf("failed to decode global position: %w", err)
+			}
+			msg, err := getMessageAt(handle.db, gp)
+			if err != nil {
+				iter.Close()
+				return 0, err
+			}
+			batch.Delete(iter.Key(), nil)
+			batch.Delete(formatCategoryIndexKey(category, gp), nil)
+			merged = append(merged, msg)
+		}
+		if err := iter.Close(); err != nil {
+			return 0, fmt.Errorf("iterator error: %w", err)
+		}
+
+		batch.Delete(formatVersionIndexKey(stream), nil)
+		typePrefix := formatTypeIndexKey(stream, "")
+		if err := batch.DeleteRange(typePrefix, prefixUpperBound(typePrefix), nil); err != nil {
+			return 0, fmt.Errorf("failed to delete type index: %w", err)
+		}
+	}
+
+	// Later sets of the same keys replace the deletes above
+	sort.Slice(merged, func(i, j int) bool { return merged[i].GlobalPosition < merged[j].GlobalPosition })
+	var moved int64
+	for i, msg := range merged {
+		if msg.StreamName != target {
+			moved++
+		}
+		msg.StreamName = target
+		msg.Position = int64(i)
+		if err := setMessageKeys(batch, msg); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := batch.Commit(pebble.NoSync); err != nil {
+		return 0, fmt.Errorf("failed to commit merge batch: %w", err)
+	}
+	return moved, nil
 }
 
 // getStreamVersion reads the current version from VI:{stream} or returns -1
@@ -126,6 +388,55 @@ func getStreamVersion(db *pebble.DB, stream string) (int64, error) {
 	return version, nil
 }
 
+// getTypeIndex reads the last position of a type from a TI key or returns -1
+func getTypeIndex(db *pebble.DB, key []byte) (int64, error) {
+	value, closer, err := db.Get(key)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return -1, nil
+		}
+		return -1, err
+	}
+	defer closer.Close()
+
+	position, err := decodeInt64(value)
+	if err != nil {
+		return -1, fmt.Errorf("failed to decode type index: %w", err)
+	}
+	return position, nil
+}
+
+// initTypeIndex records where the type index starts in a namespace DB that
+// predates it: messages written from the next global position on are indexed
+func initTypeIndex(db *pebble.DB) error {
+	_, closer, err := db.Get(formatTypeIndexStartKey())
+	if err == nil {
+		return closer.Close()
+	}
+	if err != pebble.ErrNotFound {
+		return err
+	}
+	next, err := getAndIncrementGlobalPosition(db)
+	if err != nil {
+		return err
+	}
+	return db.Set(formatTypeIndexStartKey(), []byte(encodeInt64(next)), pebble.NoSync)
+}
+
+// allocatePositions returns the first of n global positions from the
+// configured allocator, continuing from the GP counter by default. Callers
+// hold the namespace's writeMu and set the counter past the positions used.
+func (s *PebbleStore) allocatePositions(ctx context.Context, namespace string, db *pebble.DB, n int64) (int64, error) {
+	next, err := getAndIncrementGlobalPosition(db)
+	if err != nil {
+		return 0, err
+	}
+	if s.config.Positions == nil {
+		return next, nil
+	}
+	return s.config.Positions.Allocate(ctx, namespace, n, next-1)
+}
+
 // getAndIncrementGlobalPosition reads and increments the GP counter
 func getAndIncrementGlobalPosition(db *pebble.DB) (int64, error) {
 	key := formatGlobalPositionKey()
@@ -184,6 +495,9 @@ func (s *PebbleStore) ImportBatch(ctx context.Context, namespace string, message
 	// Track max global position for updating GP counter
 	var maxGlobalPosition int64 = 0
 
+	// Type index positions set earlier in this batch, which reads of the DB don't see
+	typePositions := make(map[string]int64)
+
 	for _, msg := range messages {
 		// Serialize message to JSON
 		messageJSON, err := json.Marshal(msg)
@@ -212,13 +526,24 @@ func (s *PebbleStore) ImportBatch(ctx context.Context, namespace string, message
 			batch.Set(formatVersionIndexKey(msg.StreamName), []byte(encodeInt64(msg.Position)), nil)
 		}
 
+		// 5. TI:{stream}{type} → update if this position is higher than current
+		typeKey := formatTypeIndexKey(msg.StreamName, msg.Type)
+		typePosition, ok := typePositions[string(typeKey)]
+		if !ok {
+			typePosition, _ = getTypeIndex(handle.db, typeKey)
+		}
+		if msg.Position > typePosition {
+			batch.Set(typeKey, []byte(encodeInt64(msg.Position)), nil)
+			typePositions[string(typeKey)] = msg.Position
+		}
+
 		// Track max for GP counter update
 		if msg.GlobalPosition > maxGlobalPosition {
 			maxGlobalPosition = msg.GlobalPosition
 		}
 	}
 
-	// 5. Update GP counter if imported positions exceed current
+	// 6. Update GP counter if imported positions exceed current
 	currentGP, _ := getAndIncrementGlobalPosition(handle.db)
 	if maxGlobalPosition >= currentGP {
 		batch.Set(formatGlobalPositionKey(), []byte(encodeInt64(maxGlobalPosition+1)), nil)
@@ -257,8 +582,9 @@ func (s *PebbleStore) ClearNamespaceMessages(ctx context.Context, namespace stri
 	iter.Close()
 
 	// Delete all data using prefix deletion
-	// Delete M: (messages), SI: (stream index), CI: (category index), VI: (version index)
-	prefixes := []string{"M:", "SI:", "CI:", "VI:"}
+	// Delete M: (messages), SI: (stream index), CI: (category index), VI: (version index),
+	// TI: (type index)
+	prefixes := []string{"M:", "SI:", "CI:", "VI:", prefixTypeIndex}
 
 	batch := handle.db.NewBatch()
 	defer batch.Close()
@@ -278,8 +604,9 @@ func (s *PebbleStore) ClearNamespaceMessages(ctx context.Context, namespace stri
 		iter.Close()
 	}
 
-	// Reset global position counter
+	// Reset global position counter; the type index now covers every message
 	batch.Set(formatGlobalPositionKey(), []byte(encodeInt64(1)), nil)
+	batch.Set(formatTypeIndexStartKey(), []byte(encodeInt64(1)), nil)
 
 	if err := batch.Commit(pebble.Sync); err != nil {
 		return 0, fmt.Errorf("failed to commit clear batch: %w", err)
@@ -287,3 +614,21 @@ func (s *PebbleStore) ClearNamespaceMessages(ctx context.Context, namespace stri
 
 	return count, nil
 }
+
+// CommitsInOrder reports that global positions become visible in order,
+// since writes to a namespace are serialized
+func (s *PebbleStore) CommitsInOrder(ctx context.Context, namespace string) bool {
+	return true
+}
+
+// PositionGuarantee reports the configured allocator's positions, which
+// become visible in order
+func (s *PebbleStore) PositionGuarantee(ctx context.Context, namespace string) store.PositionGuarantee {
+	var allocator store.PositionAllocator = store.CounterAllocator{}
+	if s.config.Positions != nil {
+		allocator = s.config.Positions
+	}
+	g := allocator.Guarantee()
+	g.CommitOrdered = true
+	return g
+}
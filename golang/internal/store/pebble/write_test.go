@@ -2,7 +2,10 @@ package pebble
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/eventodb/eventodb/internal/store"
 )
@@ -323,3 +326,300 @@ func TestWriteMessage_Atomicity(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteMessages_Atomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	st, err := New(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateNamespace(ctx, "test", "hash123", "Test namespace"); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+
+	results, err := st.WriteMessages(ctx, "test", []*store.Message{
+		{StreamName: "order-1", Type: "OrderPlaced", Data: map[string]interface{}{"total": 10}},
+		{StreamName: "orderSummary-1", Type: "OrderPlaced", Data: map[string]interface{}{"total": 10}},
+		{StreamName: "order-1", Type: "OrderPaid", Data: map[string]interface{}{}},
+	})
+	if err != nil {
+		t.Fatalf("WriteMessages failed: %v", err)
+	}
+	want := []store.WriteResult{{Position: 0, GlobalPosition: 1}, {Position: 0, GlobalPosition: 2}, {Position: 1, GlobalPosition: 3}}
+	for i, r := range results {
+		if *r != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, *r, want[i])
+		}
+	}
+
+	// A conflict on any message writes nothing
+	wrongVersion := int64(0)
+	_, err = st.WriteMessages(ctx, "test", []*store.Message{
+		{StreamName: "orderSummary-1", Type: "OrderShipped", Data: map[string]interface{}{}},
+		{StreamName: "order-1", Type: "OrderShipped", Data: map[string]interface{}{}, ExpectedVersion: &wrongVersion},
+	})
+	if err != store.ErrVersionConflict {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if version, _ := st.GetStreamVersion(ctx, "test", "orderSummary-1"); version != 0 {
+		t.Errorf("orderSummary-1 version = %d, want 0", version)
+	}
+
+	// The global position continues after the batch
+	result, err := st.WriteMessage(ctx, "test", "order-2", &store.Message{Type: "OrderPlaced", Data: map[string]interface{}{}})
+	if err != nil || result.GlobalPosition != 4 {
+		t.Errorf("next write = %+v (%v), want global position 4", result, err)
+	}
+	msgs, err := st.GetCategoryMessages(ctx, "test", "orderSummary", nil)
+	if err != nil || len(msgs) != 1 {
+		t.Errorf("orderSummary category = %d messages (%v), want 1", len(msgs), err)
+	}
+}
+
+func TestRedactMessage(t *testing.T) {
+	tmpDir := t.TempDir()
+	st, err := New(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateNamespace(ctx, "test", "hash123", "Test namespace"); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+	for _, data := range []map[string]interface{}{{"ssn": "123-45-6789"}, {"ok": true}} {
+		if _, err := st.WriteMessage(ctx, "test", "user-1", &store.Message{
+			Type: "Registered", Data: data, Metadata: map[string]interface{}{"ip": "10.0.0.1"},
+		}); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+
+	tombstone := map[string]interface{}{"$redacted": map[string]interface{}{"reason": "PII"}}
+	if err := st.RedactMessage(ctx, "test", "user-1", 0, tombstone, nil); err != nil {
+		t.Fatalf("RedactMessage failed: %v", err)
+	}
+	if err := st.RedactMessage(ctx, "test", "user-1", 5, tombstone, nil); !errors.Is(err, store.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test", "user-1", nil)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d (%v)", len(msgs), err)
+	}
+	if msgs[0].Data["ssn"] != nil || msgs[0].Data["$redacted"] == nil || msgs[0].Metadata != nil || msgs[0].Type != "Registered" {
+		t.Errorf("Unexpected redacted message: %+v", msgs[0])
+	}
+	if msgs[1].Data["ok"] != true || msgs[1].Metadata["ip"] != "10.0.0.1" {
+		t.Errorf("Expected the next message unchanged, got %+v", msgs[1])
+	}
+
+	// The category index still finds the redacted message
+	cat, err := st.GetCategoryMessages(ctx, "test", "user", nil)
+	if err != nil || len(cat) != 2 || cat[0].Data["$redacted"] == nil {
+		t.Errorf("Expected the redacted message in the category, got %v (%v)", cat, err)
+	}
+}
+
+func TestMergeStreams(t *testing.T) {
+	st, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	if err := st.CreateNamespace(ctx, "test", "hash123", "Test namespace"); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+
+	for i, stream := range []string{"Account-1", "account-1", "Account-1", "account-1 "} {
+		msg := &store.Message{Type: fmt.Sprintf("Event%d", i), Data: map[string]interface{}{"n": float64(i)}}
+		if _, err := st.WriteMessage(ctx, "test", stream, msg); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+
+	moved, err := st.MergeStreams(ctx, "test", "account-1", []string{"Account-1", "account-1 "})
+	if err != nil {
+		t.Fatalf("MergeStreams failed: %v", err)
+	}
+	if moved != 3 {
+		t.Errorf("expected 3 moved messages, got %d", moved)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test", "account-1", store.NewGetOpts())
+	if err != nil {
+		t.Fatalf("GetStreamMessages failed: %v", err)
+	}
+	if len(msgs) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(msgs))
+	}
+	for i, msg := range msgs {
+		if msg.Position != int64(i) || msg.GlobalPosition != int64(i+1) || msg.StreamName != "account-1" {
+			t.Errorf("unexpected message at %d: %+v", i, msg)
+		}
+	}
+	if version, err := st.GetStreamVersion(ctx, "test", "Account-1"); err != nil || version != -1 {
+		t.Errorf("expected Account-1 to be empty, got version %d (%v)", version, err)
+	}
+
+	// The category and type indexes follow the messages
+	if msgs, err := st.GetCategoryMessages(ctx, "test", "Account", store.NewCategoryOpts()); err != nil || len(msgs) != 0 {
+		t.Errorf("expected no Account messages, got %d (%v)", len(msgs), err)
+	}
+	if msgs, err := st.GetCategoryMessages(ctx, "test", "account", store.NewCategoryOpts()); err != nil || len(msgs) != 4 {
+		t.Errorf("expected 4 account messages, got %d (%v)", len(msgs), err)
+	}
+	msgType := "Event2"
+	if last, err := st.GetLastStreamMessage(ctx, "test", "account-1", &msgType); err != nil || last.Position != 2 {
+		t.Errorf("expected Event2 at position 2, got %+v (%v)", last, err)
+	}
+	if version, err := st.GetStreamVersion(ctx, "test", "account-1"); err != nil || version != 3 {
+		t.Errorf("expected version 3, got %d (%v)", version, err)
+	}
+}
+
+func TestTruncateStream(t *testing.T) {
+	tmpDir := t.TempDir()
+	st, err := New(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	// Create namespace
+	if err := st.CreateNamespace(ctx, "test", "hash123", "Test namespace"); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+
+	stream := "order:position-worker"
+	for _, msgType := range []string{"Started", "Recorded", "Recorded", "Recorded"} {
+		if _, err := st.WriteMessage(ctx, "test", stream, &store.Message{Type: msgType, Data: map[string]interface{}{}}); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+
+	deleted, err := st.TruncateStream(ctx, "test", stream, 2)
+	if err != nil {
+		t.Fatalf("TruncateStream failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 deleted messages, got %d", deleted)
+	}
+
+	msgs, err := st.GetStreamMessages(ctx, "test", stream, store.NewGetOpts())
+	if err != nil {
+		t.Fatalf("GetStreamMessages failed: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Position != 2 {
+		t.Errorf("expected positions 2 and 3, got %d messages", len(msgs))
+	}
+	msgs, err = st.GetCategoryMessages(ctx, "test", "order:position", store.NewCategoryOpts())
+	if err != nil {
+		t.Fatalf("GetCategoryMessages failed: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Errorf("expected 2 category messages, got %d", len(msgs))
+	}
+
+	// The version is unchanged and deleted types are no longer found
+	if version, err := st.GetStreamVersion(ctx, "test", stream); err != nil || version != 3 {
+		t.Errorf("expected version 3, got %d (%v)", version, err)
+	}
+	msgType := "Started"
+	if _, err := st.GetLastStreamMessage(ctx, "test", stream, &msgType); err != store.ErrStreamNotFound {
+		t.Errorf("expected ErrStreamNotFound for a truncated type, got %v", err)
+	}
+
+	// The last message is always kept
+	if deleted, err := st.TruncateStream(ctx, "test", stream, 100); err != nil || deleted != 1 {
+		t.Errorf("expected 1 deleted message, got %d (%v)", deleted, err)
+	}
+	if msg, err := st.GetLastStreamMessage(ctx, "test", stream, nil); err != nil || msg.Position != 3 {
+		t.Errorf("expected last message at position 3, got %v (%v)", msg, err)
+	}
+}
+
+func TestConsumerPositions(t *testing.T) {
+	tmpDir := t.TempDir()
+	st, err := New(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+
+	// Create namespace
+	if err := st.CreateNamespace(ctx, "test", "hash123", "Test namespace"); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+
+	if _, err := st.GetConsumerPosition(ctx, "test", "worker"); !errors.Is(err, store.ErrConsumerPositionNotFound) {
+		t.Fatalf("expected ErrConsumerPositionNotFound, got %v", err)
+	}
+	for _, gp := range []int64{5, 3} {
+		if _, err := st.SetConsumerPosition(ctx, "test", "worker", gp); err != nil {
+			t.Fatalf("SetConsumerPosition failed: %v", err)
+		}
+	}
+
+	// Positions are kept when the namespace's messages are cleared
+	if _, err := st.ClearNamespaceMessages(ctx, "test"); err != nil {
+		t.Fatalf("ClearNamespaceMessages failed: %v", err)
+	}
+	position, err := st.GetConsumerPosition(ctx, "test", "worker")
+	if err != nil {
+		t.Fatalf("GetConsumerPosition failed: %v", err)
+	}
+	if position.GlobalPosition != 3 || position.Consumer != "worker" {
+		t.Errorf("expected worker at 3, got %+v", position)
+	}
+}
+
+func TestPositionAllocator(t *testing.T) {
+	ctx := context.Background()
+	st, err := NewWithConfig(t.TempDir(), &Config{InMemory: true, Positions: store.NewHLCAllocator()})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer st.Close()
+	if err := st.CreateNamespace(ctx, "test", "hash123", "Test namespace"); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+
+	before := time.Now().Add(-time.Millisecond)
+	first, err := st.WriteMessage(ctx, "test", "account-1", &store.Message{Type: "Opened", Data: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+	if written := store.HLCTime(first.GlobalPosition); written.Before(before) || written.After(time.Now()) {
+		t.Errorf("Expected position %d to encode the write time, got %v", first.GlobalPosition, written)
+	}
+
+	results, err := st.WriteMessages(ctx, "test", []*store.Message{
+		{StreamName: "account-1", Type: "Deposited", Data: map[string]interface{}{}},
+		{StreamName: "account-2", Type: "Opened", Data: map[string]interface{}{}},
+	})
+	if err != nil {
+		t.Fatalf("WriteMessages failed: %v", err)
+	}
+	if results[0].GlobalPosition <= first.GlobalPosition || results[1].GlobalPosition != results[0].GlobalPosition+1 {
+		t.Errorf("Expected increasing consecutive positions, got %d then %d, %d", first.GlobalPosition, results[0].GlobalPosition, results[1].GlobalPosition)
+	}
+
+	msgs, err := st.GetCategoryMessages(ctx, "test", "account", &store.CategoryOpts{Position: 1, BatchSize: 10})
+	if err != nil || len(msgs) != 3 {
+		t.Fatalf("Expected 3 category messages, got %d, %v", len(msgs), err)
+	}
+	if g := st.PositionGuarantee(ctx, "test"); g.Allocator != store.AllocatorHLC || g.Gapless || !g.CommitOrdered {
+		t.Errorf("Unexpected guarantee %+v", g)
+	}
+}
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Position allocators
+const (
+	AllocatorSequence = "sequence" // A database sequence (Postgres, TimescaleDB, SQLite)
+	AllocatorCounter  = "counter"  // The namespace's last position + 1 (Pebble default)
+	AllocatorHLC      = "hlc"      // A hybrid logical clock
+	AllocatorBlock    = "block"    // Blocks reserved from a shared source
+)
+
+// hlcLogicalBits is the share of an HLC position counting positions within
+// one millisecond. Positions stay below 2^53, so JavaScript clients read them
+// exactly, until the year 2248.
+const hlcLogicalBits = 10
+
+// PositionGuarantee describes the global positions a backend assigns. Every
+// allocator gives increasing positions within a namespace; deleted messages
+// leave gaps whatever the allocator.
+type PositionGuarantee struct {
+	Allocator string
+
+	// Gapless is true when every allocated position is written, so a
+	// position missing from a category read was deleted
+	Gapless bool
+
+	// CommitOrdered is true when a message never becomes visible before one
+	// with a lower global position (see CommitOrderer)
+	CommitOrdered bool
+
+	Description string
+}
+
+// PositionGuarantor is implemented by backends that report how they assign
+// global positions (SQLite, Pebble, Postgres, TimescaleDB). It is used to
+// tell clients whether they can rely on gapless positions.
+type PositionGuarantor interface {
+	// PositionGuarantee returns the guarantee of the namespace's positions
+	PositionGuarantee(ctx context.Context, namespace string) PositionGuarantee
+}
+
+// PositionAllocator assigns global positions for backends that do not take
+// them from a database sequence (Pebble). Calls for one namespace are
+// serialized by the backend.
+type PositionAllocator interface {
+	// Allocate returns the first of n consecutive positions for namespace,
+	// which must all be above last, the highest position it has used
+	Allocate(ctx context.Context, namespace string, n, last int64) (int64, error)
+
+	// Guarantee describes the positions, without CommitOrdered, which
+	// depends on the backend
+	Guarantee() PositionGuarantee
+}
+
+// CounterAllocator continues from the last position, so positions are
+// gapless
+type CounterAllocator struct{}
+
+// Allocate returns last + 1
+func (CounterAllocator) Allocate(ctx context.Context, namespace string, n, last int64) (int64, error) {
+	return last + 1, nil
+}
+
+// Guarantee reports gapless positions
+func (CounterAllocator) Guarantee() PositionGuarantee {
+	return PositionGuarantee{
+		Allocator:   AllocatorCounter,
+		Gapless:     true,
+		Description: "Each write continues from the namespace's last global position",
+	}
+}
+
+// HLCAllocator assigns positions from a hybrid logical clock: milliseconds
+// since the Unix epoch shifted left by 10 bits, plus a counter for positions
+// within the same millisecond. Positions of different namespaces and servers
+// order roughly by write time, so merged reads of sharded deployments
+// interleave them plausibly. A clock running backwards never lowers
+// positions; they continue from the last one.
+type HLCAllocator struct {
+	Now func() time.Time // Clock, nil for time.Now
+}
+
+// NewHLCAllocator creates an allocator on the system clock
+func NewHLCAllocator() *HLCAllocator {
+	return &HLCAllocator{}
+}
+
+// Allocate returns the clock's position, or last + 1 when the clock is not
+// past it
+func (a *HLCAllocator) Allocate(ctx context.Context, namespace string, n, last int64) (int64, error) {
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+	first := now().UnixMilli() << hlcLogicalBits
+	if first <= last {
+		first = last + 1
+	}
+	return first, nil
+}
+
+// Guarantee reports positions with gaps between milliseconds
+func (a *HLCAllocator) Guarantee() PositionGuarantee {
+	return PositionGuarantee{
+		Allocator:   AllocatorHLC,
+		Description: "Hybrid logical clock: milliseconds since the Unix epoch × 1024 plus a counter; positions have gaps and encode their write time",
+	}
+}
+
+// HLCTime returns the write time encoded in a position of an HLCAllocator
+func HLCTime(position int64) time.Time {
+	return time.UnixMilli(position >> hlcLogicalBits).UTC()
+}
+
+// BlockSource reserves size consecutive positions for a namespace from a
+// service shared by several writers and returns the first. Blocks of one
+// namespace must increase.
+type BlockSource func(ctx context.Context, namespace string, size int64) (int64, error)
+
+// BlockAllocator hands out positions from blocks reserved from a
+// BlockSource, so writers sharing the source only coordinate once per
+// block. Positions a writer has not used when it stops are never assigned,
+// and positions of different writers interleave in block order rather than
+// write order.
+type BlockAllocator struct {
+	source BlockSource
+	size   int64
+
+	mu     sync.Mutex
+	blocks map[string]*positionBlock
+}
+
+// positionBlock is the unused rest of a namespace's block
+type positionBlock struct {
+	next, end int64 // Positions next to end-1 are free
+}
+
+// NewBlockAllocator creates an allocator reserving size positions at a time
+func NewBlockAllocator(source BlockSource, size int64) *BlockAllocator {
+	if size < 1 {
+		size = 1
+	}
+	return &BlockAllocator{
+		source: source,
+		size:   size,
+		blocks: make(map[string]*positionBlock),
+	}
+}
+
+// Allocate returns positions from the namespace's block, reserving a new
+// block when the rest is too small or not above last
+func (a *BlockAllocator) Allocate(ctx context.Context, namespace string, n, last int64) (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	b := a.blocks[namespace]
+	if b == nil || b.next <= last || b.end-b.next < n {
+		size := a.size
+		if n > size {
+			size = n
+		}
+		first, err := a.source(ctx, namespace, size)
+		if err != nil {
+			return 0, fmt.Errorf("failed to reserve positions: %w", err)
+		}
+		if first <= last {
+			return 0, fmt.Errorf("reserved positions from %d are not above the last position %d", first, last)
+		}
+		b = &positionBlock{next: first, end: first + size}
+		a.blocks[namespace] = b
+	}
+	first := b.next
+	b.next += n
+	return first, nil
+}
+
+// Guarantee reports positions with gaps where blocks were left unused
+func (a *BlockAllocator) Guarantee() PositionGuarantee {
+	return PositionGuarantee{
+		Allocator:   AllocatorBlock,
+		Description: fmt.Sprintf("Blocks of %d positions reserved from a shared source; unused rests of blocks leave gaps", a.size),
+	}
+}
+
+// ParsePositionAllocator returns the allocator named by name for backends
+// that take a PositionAllocator: "" or "counter", or "hlc". Block allocators
+// need a source and are created with NewBlockAllocator.
+func ParsePositionAllocator(name string) (PositionAllocator, error) {
+	switch name {
+	case "", AllocatorCounter:
+		return CounterAllocator{}, nil
+	case AllocatorHLC:
+		return NewHLCAllocator(), nil
+	}
+	return nil, fmt.Errorf("unknown position allocator %q (use counter or hlc)", name)
+}
+
+// PositionGuaranteeOf returns the guarantee of st for namespace, or an
+// "unknown" allocator without guarantees when st does not report one
+func PositionGuaranteeOf(ctx context.Context, st Store, namespace string) PositionGuarantee {
+	if g, ok := st.(PositionGuarantor); ok {
+		return g.PositionGuarantee(ctx, namespace)
+	}
+	return PositionGuarantee{Allocator: "unknown", Description: "The backend does not report how it assigns global positions"}
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHLCAllocator(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := &HLCAllocator{Now: func() time.Time { return now }}
+
+	first, err := a.Allocate(ctx, "ns", 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HLCTime(first).Equal(now) {
+		t.Errorf("Expected the position to encode %v, got %v", now, HLCTime(first))
+	}
+
+	// Within the same millisecond, and with the clock going backwards,
+	// positions continue from the last one
+	if next, _ := a.Allocate(ctx, "ns", 1, first+2); next != first+3 {
+		t.Errorf("Expected %d in the same millisecond, got %d", first+3, next)
+	}
+	now = now.Add(-time.Second)
+	if next, _ := a.Allocate(ctx, "ns", 1, first+3); next != first+4 {
+		t.Errorf("Expected %d after the clock went back, got %d", first+4, next)
+	}
+
+	// Positions stay exact in JavaScript for centuries
+	if first >= 1<<53 {
+		t.Errorf("Position %d is not below 2^53", first)
+	}
+}
+
+func TestBlockAllocator(t *testing.T) {
+	ctx := context.Background()
+	var reserved int64
+	var calls int
+	source := func(ctx context.Context, namespace string, size int64) (int64, error) {
+		calls++
+		first := reserved + 1
+		reserved += size
+		return first, nil
+	}
+	a := NewBlockAllocator(source, 10)
+
+	if first, _ := a.Allocate(ctx, "ns", 4, 0); first != 1 {
+		t.Errorf("Expected 1, got %d", first)
+	}
+	if first, _ := a.Allocate(ctx, "ns", 4, 4); first != 5 {
+		t.Errorf("Expected 5, got %d", first)
+	}
+	// Too few positions left: the rest of the block is skipped
+	if first, _ := a.Allocate(ctx, "ns", 4, 8); first != 11 {
+		t.Errorf("Expected 11, got %d", first)
+	}
+	// Larger requests reserve larger blocks
+	if first, _ := a.Allocate(ctx, "ns", 25, 14); first != 21 {
+		t.Errorf("Expected 21, got %d", first)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 reservations, got %d", calls)
+	}
+
+	// A block below positions written elsewhere is refused
+	stale := NewBlockAllocator(func(ctx context.Context, namespace string, size int64) (int64, error) { return 1, nil }, 10)
+	if _, err := stale.Allocate(ctx, "ns", 1, 100); err == nil {
+		t.Error("Expected an error for a block below the last position")
+	}
+	failing := NewBlockAllocator(func(ctx context.Context, namespace string, size int64) (int64, error) {
+		return 0, ErrBackendUnavailable
+	}, 10)
+	if _, err := failing.Allocate(ctx, "ns", 1, 0); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("Expected the source error, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// MaxMessageTables bounds the message tables of one namespace
+const MaxMessageTables = 64
+
+// Affinity chooses the part of a stream name that picks its message table.
+// Every stream sharing the key lands in the same table.
+type Affinity string
+
+const (
+	// AffinityStream spreads streams by their full name (default)
+	AffinityStream Affinity = "stream"
+
+	// AffinityCardinalID keeps the streams of one entity together, e.g.
+	// account-123 and accountSnapshot-123+2024; category streams use their name
+	AffinityCardinalID Affinity = "cardinalId"
+
+	// AffinityCategory keeps each category in one table
+	AffinityCategory Affinity = "category"
+)
+
+// ParseAffinity parses an affinity name; "" is AffinityStream
+func ParseAffinity(s string) (Affinity, error) {
+	switch a := Affinity(s); a {
+	case "":
+		return AffinityStream, nil
+	case AffinityStream, AffinityCardinalID, AffinityCategory:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown table affinity %q (use stream, cardinalId or category)", s)
+}
+
+// Key returns the part of streamName that picks its message table
+func (a Affinity) Key(streamName string) string {
+	switch a {
+	case AffinityCardinalID:
+		if id := store.CardinalID(streamName); id != "" {
+			return id
+		}
+	case AffinityCategory:
+		return store.Category(streamName)
+	}
+	return streamName
+}
+
+// sqlKey is Key as an SQL expression over stream_name
+func (a Affinity) sqlKey(schemaName string) string {
+	switch a {
+	case AffinityCardinalID:
+		return fmt.Sprintf(`COALESCE(NULLIF("%s".cardinal_id(stream_name), ''), stream_name)`, schemaName)
+	case AffinityCategory:
+		return fmt.Sprintf(`"%s".category(stream_name)`, schemaName)
+	}
+	return "stream_name"
+}
+
+// MessageTable returns the table, 0 to tables-1, holding streamName. It
+// matches the message_table function of partitioned namespaces.
+func (a Affinity) MessageTable(streamName string, tables int) int {
+	n := int64(tables)
+	return int((store.Hash64(a.Key(streamName))%n + n) % n)
+}
+
+// Config holds PostgresStore options
+type Config struct {
+	// MessageTables spreads the messages of namespaces created by this store
+	// over this many tables, messages_00 to messages_NN, to relieve insert
+	// contention on a single table. 0 or 1 keeps one table.
+	MessageTables int
+
+	// Affinity picks each stream's table; "" is AffinityStream
+	Affinity Affinity
+}
+
+// Validate checks the options
+func (c *Config) Validate() error {
+	if c.MessageTables < 0 || c.MessageTables > MaxMessageTables {
+		return fmt.Errorf("message tables must be from 0 to %d", MaxMessageTables)
+	}
+	if _, err := ParseAffinity(string(c.Affinity)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// uniqueIndexDef matches pg_indexes definitions of unique indexes on messages
+var uniqueIndexDef = regexp.MustCompile(`^CREATE UNIQUE INDEX (\S+) ON (\S+)\.messages USING (.+)$`)
+
+// partitionMessages replaces the new, empty messages table of schemaName
+// with one partitioned by message_table(stream_name) over cfg.MessageTables
+// tables. Rows keep the global_position sequence, so one global order spans
+// all tables; updates that change a stream name move rows between tables.
+//
+// Postgres only enforces unique indexes per table here, so the ones of the
+// unpartitioned table are created on each table, where they stay exact for
+// stream positions, which belong to one table. Message IDs and global
+// positions are kept unique across tables by message_keys, which triggers on
+// messages keep in step with its rows in the writing transaction.
+func partitionMessages(ctx context.Context, tx *sql.Tx, schemaName string, cfg *Config) error {
+	tables := cfg.MessageTables
+	affinity, _ := ParseAffinity(string(cfg.Affinity))
+
+	// Index definitions of the unpartitioned table, as left by migrations
+	rows, err := tx.QueryContext(ctx,
+		`SELECT indexdef FROM pg_indexes WHERE schemaname = $1 AND tablename = 'messages' ORDER BY indexname`,
+		schemaName)
+	if err != nil {
+		return fmt.Errorf("failed to read message indexes: %w", err)
+	}
+	var indexes, unique []string
+	for rows.Next() {
+		var def string
+		if err := rows.Scan(&def); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read message indexes: %w", err)
+		}
+		if strings.HasPrefix(def, "CREATE UNIQUE INDEX ") {
+			unique = append(unique, def)
+		} else {
+			indexes = append(indexes, def)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read message indexes: %w", err)
+	}
+
+	steps := []string{
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION "%s".message_table(stream_name VARCHAR)
+RETURNS INTEGER AS $$
+    SELECT ((("%s".hash_64(%s) %% %d) + %d) %% %d)::integer
+$$ LANGUAGE sql IMMUTABLE`, schemaName, schemaName, affinity.sqlKey(schemaName), tables, tables, tables),
+		fmt.Sprintf(`ALTER TABLE "%s".messages RENAME TO messages_unpartitioned`, schemaName),
+		fmt.Sprintf(`CREATE TABLE "%s".messages (LIKE "%s".messages_unpartitioned INCLUDING DEFAULTS)
+PARTITION BY LIST (("%s".message_table(stream_name)))`, schemaName, schemaName, schemaName),
+		fmt.Sprintf(`ALTER SEQUENCE "%s".messages_global_position_seq OWNED BY "%s".messages.global_position`, schemaName, schemaName),
+		fmt.Sprintf(`DROP TABLE "%s".messages_unpartitioned`, schemaName),
+		fmt.Sprintf(`CREATE TABLE "%s".message_keys (
+    global_position BIGINT PRIMARY KEY,
+    id UUID NOT NULL UNIQUE
+)`, schemaName),
+		fmt.Sprintf(`CREATE FUNCTION "%s".track_message_keys()
+RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO "%s".message_keys (global_position, id) VALUES (NEW.global_position, NEW.id);
+    ELSIF TG_OP = 'UPDATE' THEN
+        UPDATE "%s".message_keys SET global_position = NEW.global_position, id = NEW.id
+        WHERE global_position = OLD.global_position;
+    ELSE
+        DELETE FROM "%s".message_keys WHERE global_position = OLD.global_position;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`, schemaName, schemaName, schemaName, schemaName),
+		// Rows moved between tables by an update fire the delete and insert
+		// triggers instead of the update one
+		fmt.Sprintf(`CREATE TRIGGER messages_keys AFTER INSERT OR DELETE OR UPDATE OF id, global_position
+ON "%s".messages FOR EACH ROW EXECUTE FUNCTION "%s".track_message_keys()`, schemaName, schemaName),
+	}
+	for i := 0; i < tables; i++ {
+		steps = append(steps, fmt.Sprintf(`CREATE TABLE "%s".messages_%02d PARTITION OF "%s".messages FOR VALUES IN (%d)`,
+			schemaName, i, schemaName, i))
+		for _, def := range unique {
+			m := uniqueIndexDef.FindStringSubmatch(def)
+			if m == nil {
+				return fmt.Errorf("unexpected message index: %s", def)
+			}
+			steps = append(steps, fmt.Sprintf(`CREATE UNIQUE INDEX %s_%02d ON %s.messages_%02d USING %s`, m[1], i, m[2], i, m[3]))
+		}
+	}
+	// Other indexes are created on the partitioned table and so on each table
+	steps = append(steps, indexes...)
+
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step); err != nil {
+			return fmt.Errorf("failed to partition messages: %w", err)
+		}
+	}
+	return nil
+}
+
+// MessageTables returns the number of message tables of a namespace: 1
+// unless it was created with Config.MessageTables
+func (s *PostgresStore) MessageTables(ctx context.Context, namespace string) (int, error) {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return 0, err
+	}
+	var tables int
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM pg_inherits
+		WHERE inhparent = (quote_ident($1) || '.messages')::regclass
+	`, schemaName).Scan(&tables)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count message tables: %w", err)
+	}
+	if tables == 0 {
+		tables = 1
+	}
+	return tables, nil
+}
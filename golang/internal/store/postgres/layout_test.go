@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// TestAffinity_MessageTable tests that affinities key streams as documented
+// and that tables stay in range for negative hashes
+func TestAffinity_MessageTable(t *testing.T) {
+	tests := []struct {
+		affinity Affinity
+		stream   string
+		key      string
+	}{
+		{AffinityStream, "account-123+2024", "account-123+2024"},
+		{AffinityCardinalID, "account-123+2024", "123"},
+		{AffinityCardinalID, "account", "account"},
+		{AffinityCardinalID, "account-", "account-"},
+		{AffinityCategory, "account-123", "account"},
+	}
+	for _, tt := range tests {
+		if got := tt.affinity.Key(tt.stream); got != tt.key {
+			t.Errorf("%s key of %q: expected %q, got %q", tt.affinity, tt.stream, tt.key, got)
+		}
+	}
+
+	if AffinityCardinalID.MessageTable("account-7", 16) != AffinityCardinalID.MessageTable("accountSnapshot-7+1", 16) {
+		t.Error("Expected the streams of one entity in the same table")
+	}
+	for i := 0; i < 200; i++ {
+		stream := "account-" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		if table := AffinityStream.MessageTable(stream, 16); table < 0 || table >= 16 {
+			t.Fatalf("Table %d of %q out of range", table, stream)
+		}
+	}
+
+	if _, err := ParseAffinity("entity"); err == nil {
+		t.Error("Expected an unknown affinity to be rejected")
+	}
+	if err := (&Config{MessageTables: MaxMessageTables + 1}).Validate(); err == nil {
+		t.Error("Expected too many message tables to be rejected")
+	}
+}
+
+// TestPartitionedMessages tests that a namespace created with several
+// message tables spreads streams over them with one global order
+func TestPartitionedMessages(t *testing.T) {
+	db := getTestDB(t)
+	defer db.Close()
+
+	pgStore, err := NewWithConfig(db, &Config{MessageTables: 4, Affinity: AffinityCardinalID})
+	if err != nil {
+		t.Fatalf("failed to create postgres store: %v", err)
+	}
+	defer pgStore.Close()
+
+	ctx := context.Background()
+	namespace := "test-ns-partitioned"
+	cleanupNamespace(t, pgStore, namespace)
+	defer cleanupNamespace(t, pgStore, namespace)
+
+	if err := pgStore.CreateNamespace(ctx, namespace, "token-hash-p", "Partitioned"); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+	if tables, err := pgStore.MessageTables(ctx, namespace); err != nil || tables != 4 {
+		t.Fatalf("expected 4 message tables, got %d, %v", tables, err)
+	}
+
+	var last int64
+	for i := 0; i < 20; i++ {
+		stream := "account-" + string(rune('a'+i))
+		result, err := pgStore.WriteMessage(ctx, namespace, stream, &store.Message{Type: "Opened", Data: map[string]interface{}{}})
+		if err != nil {
+			t.Fatalf("failed to write message: %v", err)
+		}
+		if result.GlobalPosition <= last {
+			t.Fatalf("expected increasing global positions, got %d after %d", result.GlobalPosition, last)
+		}
+		last = result.GlobalPosition
+
+		var table int
+		row := db.QueryRowContext(ctx, `SELECT "eventodb_test_ns_partitioned".message_table($1)`, stream)
+		if err := row.Scan(&table); err != nil {
+			t.Fatalf("failed to read message table: %v", err)
+		}
+		if want := AffinityCardinalID.MessageTable(stream, 4); table != want {
+			t.Errorf("%s: Postgres table %d, Go table %d", stream, table, want)
+		}
+	}
+
+	msgs, err := pgStore.GetCategoryMessages(ctx, namespace, "account", &store.CategoryOpts{Position: 1, BatchSize: 100})
+	if err != nil {
+		t.Fatalf("failed to read category: %v", err)
+	}
+	if len(msgs) != 20 {
+		t.Fatalf("expected 20 messages, got %d", len(msgs))
+	}
+	for i := 1; i < len(msgs); i++ {
+		if msgs[i].GlobalPosition <= msgs[i-1].GlobalPosition {
+			t.Fatalf("expected category in global order, got %d after %d", msgs[i].GlobalPosition, msgs[i-1].GlobalPosition)
+		}
+	}
+
+	// Taken global positions are found across tables
+	err = pgStore.ImportBatch(ctx, namespace, []*store.Message{{
+		ID: "00000000-0000-0000-0000-000000000001", StreamName: "account-zz", Type: "Opened",
+		GlobalPosition: msgs[0].GlobalPosition, Time: msgs[0].Time,
+	}})
+	if !errors.Is(err, store.ErrPositionExists) {
+		t.Errorf("expected ErrPositionExists, got %v", err)
+	}
+
+	// Message IDs are unique across tables too
+	var other string
+	for i := 0; other == ""; i++ {
+		stream := "account-" + string(rune('a'+i)) + "x"
+		if AffinityCardinalID.MessageTable(stream, 4) != AffinityCardinalID.MessageTable(msgs[0].StreamName, 4) {
+			other = stream
+		}
+	}
+	_, err = pgStore.WriteMessage(ctx, namespace, other, &store.Message{ID: msgs[0].ID, Type: "Opened", Data: map[string]interface{}{}})
+	if err == nil {
+		t.Error("expected a message ID taken in another table to be rejected")
+	}
+
+	// Moving a stream to another table keeps its keys taken
+	if _, err := pgStore.MergeStreams(ctx, namespace, other, []string{msgs[0].StreamName}); err != nil {
+		t.Fatalf("failed to merge streams: %v", err)
+	}
+	var keys, messages int
+	row := db.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM "eventodb_test_ns_partitioned".message_keys),
+		(SELECT COUNT(*) FROM "eventodb_test_ns_partitioned".messages)`)
+	if err := row.Scan(&keys, &messages); err != nil {
+		t.Fatalf("failed to count message keys: %v", err)
+	}
+	if keys != messages {
+		t.Errorf("expected a key per message, got %d keys for %d messages", keys, messages)
+	}
+	err = pgStore.ImportBatch(ctx, namespace, []*store.Message{{
+		ID: "00000000-0000-0000-0000-000000000002", StreamName: "account-zz", Type: "Opened",
+		GlobalPosition: msgs[0].GlobalPosition, Time: msgs[0].Time,
+	}})
+	if !errors.Is(err, store.ErrPositionExists) {
+		t.Errorf("expected ErrPositionExists after a move, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/eventodb/eventodb/internal/migrate"
 	"github.com/eventodb/eventodb/internal/store"
 	"github.com/eventodb/eventodb/migrations"
 )
@@ -39,6 +40,13 @@ func (s *PostgresStore) CreateNamespace(ctx context.Context, id, tokenHash, desc
 		return fmt.Errorf("failed to apply namespace migrations: %w", err)
 	}
 
+	// Spread messages over several tables when configured
+	if s.config.MessageTables > 1 {
+		if err := partitionMessages(ctx, tx, schemaName, s.config); err != nil {
+			return err
+		}
+	}
+
 	// Insert into eventodb_store.namespaces
 	insertQuery := `
 		INSERT INTO eventodb_store.namespaces (id, token_hash, schema_name, description, created_at, metadata)
@@ -156,6 +164,40 @@ func (s *PostgresStore) GetNamespace(ctx context.Context, id string) (*store.Nam
 	return &ns, nil
 }
 
+// UpdateNamespaceMetadata replaces a namespace's metadata
+func (s *PostgresStore) UpdateNamespaceMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to serialize metadata: %w", err)
+	}
+
+	query := `UPDATE eventodb_store.namespaces SET metadata = $1 WHERE id = $2`
+	result, err := s.db.ExecContext(ctx, query, string(metadataJSON), id)
+	if err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrNamespaceNotFound
+	}
+	return nil
+}
+
+// UpdateNamespaceToken replaces a namespace's token hash
+func (s *PostgresStore) UpdateNamespaceToken(ctx context.Context, id, tokenHash string) error {
+	query := `UPDATE eventodb_store.namespaces SET token_hash = $1 WHERE id = $2`
+	result, err := s.db.ExecContext(ctx, query, tokenHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrNamespaceNotFound
+	}
+	return nil
+}
+
 // ListNamespaces retrieves all namespaces
 func (s *PostgresStore) ListNamespaces(ctx context.Context) ([]*store.Namespace, error) {
 	query := `
@@ -261,7 +303,7 @@ func (s *PostgresStore) migrateNamespaceSchema(ctx context.Context, schemaName s
 
 	applied := 0
 	for _, entry := range migrationFiles {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") || migrate.IsDownFile(entry.Name()) {
 			continue
 		}
 
@@ -325,7 +367,7 @@ func applyNamespaceMigrations(ctx context.Context, tx *sql.Tx, schemaName string
 	}
 
 	for _, entry := range migrationFiles {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") || migrate.IsDownFile(entry.Name()) {
 			continue
 		}
 
@@ -347,3 +389,52 @@ func applyNamespaceMigrations(ctx context.Context, tx *sql.Tx, schemaName string
 
 	return nil
 }
+
+// SwapNamespaceMessages exchanges the schemas of two namespaces in the
+// registry, so one transaction moves all of their messages
+func (s *PostgresStore) SwapNamespaceMessages(ctx context.Context, a, b string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	schemas := make(map[string]string, 2)
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, schema_name FROM eventodb_store.namespaces WHERE id IN ($1, $2) FOR UPDATE`, a, b)
+	if err != nil {
+		return fmt.Errorf("failed to read schema names: %w", err)
+	}
+	for rows.Next() {
+		var id, schemaName string
+		if err := rows.Scan(&id, &schemaName); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read schema names: %w", err)
+		}
+		schemas[id] = schemaName
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read schema names: %w", err)
+	}
+	if len(schemas) != 2 {
+		return store.ErrNamespaceNotFound
+	}
+
+	// Schema names are unique, so a moves aside first
+	for _, step := range []struct{ id, schemaName string }{
+		{a, schemas[a] + ":swap"},
+		{b, schemas[a]},
+		{a, schemas[b]},
+	} {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE eventodb_store.namespaces SET schema_name = $2 WHERE id = $1`, step.id, step.schemaName); err != nil {
+			return fmt.Errorf("failed to swap schema names: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eventodb/eventodb/internal/migrate"
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/eventodb/eventodb/migrations"
+)
+
+// LatestSchemaVersion returns the namespace schema version of this build
+func (s *PostgresStore) LatestSchemaVersion() int {
+	migs, err := migrate.Load(migrations.NamespacePostgresFS, "namespace/postgres")
+	if err != nil {
+		return 0
+	}
+	return migrate.Latest(migs)
+}
+
+// NamespaceSchemaVersions returns the schema version of each namespace
+func (s *PostgresStore) NamespaceSchemaVersions(ctx context.Context) ([]store.NamespaceSchemaVersion, error) {
+	namespaces, err := s.ListNamespaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	versions := make([]store.NamespaceSchemaVersion, 0, len(namespaces))
+	for _, ns := range namespaces {
+		versions = append(versions, store.NamespaceSchemaVersion{
+			Namespace: ns.ID,
+			Version:   s.getNamespaceSchemaVersion(ctx, ns.SchemaName),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Namespace < versions[j].Namespace })
+	return versions, nil
+}
+
+// RollbackNamespaces reverts every namespace above version down to it
+func (s *PostgresStore) RollbackNamespaces(ctx context.Context, version int) (int, error) {
+	migs, err := migrate.Load(migrations.NamespacePostgresFS, "namespace/postgres")
+	if err != nil {
+		return 0, fmt.Errorf("failed to load namespace migrations: %w", err)
+	}
+	namespaces, err := s.ListNamespaces(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	reverted := 0
+	for _, ns := range namespaces {
+		down, err := migrate.Rollbacks(migs, s.getNamespaceSchemaVersion(ctx, ns.SchemaName), version)
+		if err != nil {
+			return reverted, fmt.Errorf("failed to roll back namespace %s: %w", ns.ID, err)
+		}
+		for _, mig := range down {
+			sql := strings.ReplaceAll(mig.Down, "{{SCHEMA_NAME}}", ns.SchemaName)
+			if _, err := s.db.ExecContext(ctx, sql); err != nil {
+				return reverted, fmt.Errorf("failed to roll back migration %s of namespace %s: %w", mig.Name, ns.ID, err)
+			}
+			reverted++
+		}
+	}
+	return reverted, nil
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// NamespaceStorage returns the size of a namespace schema, split by category
+// in proportion to the size of each category's rows
+func (s *PostgresStore) NamespaceStorage(ctx context.Context, namespace string) (*store.StorageUsage, error) {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	// Every table and index of the namespace schema
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(pg_total_relation_size(c.oid)), 0)
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'm')`, schemaName).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure namespace schema: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT "%[1]s".category(m.stream_name), SUM(pg_column_size(m.*))
+		FROM "%[1]s".messages m
+		GROUP BY 1`, schemaName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure categories: %w", err)
+	}
+	defer rows.Close()
+
+	weights := make(map[string]int64)
+	for rows.Next() {
+		var category string
+		var size int64
+		if err := rows.Scan(&category, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan category size: %w", err)
+		}
+		weights[category] = size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return store.NewStorageUsage(total, weights), nil
+}
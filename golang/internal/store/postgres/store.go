@@ -12,21 +12,34 @@ import (
 
 // PostgresStore implements the Store interface for PostgreSQL
 type PostgresStore struct {
-	db  *sql.DB
-	ctx context.Context
+	db     *sql.DB
+	ctx    context.Context
+	config *Config
 }
 
 // New creates a new PostgresStore instance
 // The provided db connection should already be connected to the database
 // and have the eventodb_store metadata schema initialized
 func New(db *sql.DB) (*PostgresStore, error) {
+	return NewWithConfig(db, nil)
+}
+
+// NewWithConfig creates a new PostgresStore with custom configuration
+func NewWithConfig(db *sql.DB, config *Config) (*PostgresStore, error) {
 	if db == nil {
 		return nil, fmt.Errorf("database connection cannot be nil")
 	}
+	if config == nil {
+		config = &Config{}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
 
 	s := &PostgresStore{
-		db:  db,
-		ctx: context.Background(),
+		db:     db,
+		ctx:    context.Background(),
+		config: config,
 	}
 
 	// Run metadata migrations to ensure eventodb_store schema exists
@@ -49,8 +62,9 @@ func (s *PostgresStore) Close() error {
 // WithContext returns a new store with the given context
 func (s *PostgresStore) WithContext(ctx context.Context) *PostgresStore {
 	return &PostgresStore{
-		db:  s.db,
-		ctx: ctx,
+		db:     s.db,
+		ctx:    ctx,
+		config: s.config,
 	}
 }
 
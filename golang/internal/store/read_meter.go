@@ -0,0 +1,30 @@
+package store
+
+import "context"
+
+// readRowOverhead approximates the encoded size of a message's positions,
+// time and punctuation, added to the size of its strings and JSON
+const readRowOverhead = 96
+
+// ReadMeter is called with the approximate encoded size of every message a
+// read loads. An error stops the read, which returns it, so a read too large
+// for its caller fails before it is held in memory whole.
+type ReadMeter func(size int64) error
+
+// readMeterKey is the context key for the ReadMeter of store reads
+type readMeterKey struct{}
+
+// WithReadMeter returns a context whose message reads call meter
+func WithReadMeter(ctx context.Context, meter ReadMeter) context.Context {
+	return context.WithValue(ctx, readMeterKey{}, meter)
+}
+
+// MeterRead reports a loaded message to the ReadMeter of ctx, if any. size is
+// the length of the message's ID, stream name, type, data and metadata as
+// read from the backend.
+func MeterRead(ctx context.Context, size int) error {
+	if meter, ok := ctx.Value(readMeterKey{}).(ReadMeter); ok {
+		return meter(int64(size) + readRowOverhead)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package store
+
+import "math"
+
+// SampleThreshold returns the Hash64 bound below which a message ID is in a
+// sample of the given rate. Hash64 values are uniform over int64, so the
+// bound sits rate of the way from math.MinInt64 to math.MaxInt64, which lets
+// Postgres compare hash_64(id::text) against the same value.
+func SampleThreshold(rate float64) int64 {
+	return int64(uint64(rate*math.Exp2(64)) ^ (1 << 63))
+}
+
+// Sampled reports whether rate selects a sample: 0 (unset) and 1 or more
+// both read every message
+func Sampled(rate float64) bool {
+	return rate > 0 && rate < 1
+}
+
+// InSample reports whether the message with the given ID is in a sample of
+// the given rate. The choice depends only on the ID, so repeated reads, and
+// reads of different backends, return the same messages.
+func InSample(id string, rate float64) bool {
+	return !Sampled(rate) || Hash64(id) < SampleThreshold(rate)
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestSampleThreshold(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		expected int64
+	}{
+		{0, math.MinInt64},
+		{0.25, math.MinInt64 / 2},
+		{0.5, 0},
+		{0.75, math.MaxInt64/2 + 1},
+	}
+	for _, tt := range tests {
+		if got := SampleThreshold(tt.rate); got != tt.expected {
+			t.Errorf("SampleThreshold(%v) = %d, want %d", tt.rate, got, tt.expected)
+		}
+	}
+}
+
+func TestInSample(t *testing.T) {
+	ids := make([]string, 10000)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("00000000-0000-4000-8000-%012d", i)
+	}
+
+	var smaller, larger int
+	for _, id := range ids {
+		if !InSample(id, 0) || !InSample(id, 1) {
+			t.Fatalf("expected rates 0 and 1 to select %s", id)
+		}
+		in := InSample(id, 0.01)
+		if in {
+			smaller++
+		}
+		if InSample(id, 0.1) {
+			larger++
+		} else if in {
+			t.Errorf("expected the 1%% sample to be part of the 10%% sample, %s is not", id)
+		}
+	}
+
+	// Hash64 is uniform, so the sample sizes are close to the rates
+	if smaller < 60 || smaller > 140 {
+		t.Errorf("expected about 100 IDs in a 1%% sample, got %d", smaller)
+	}
+	if larger < 900 || larger > 1100 {
+		t.Errorf("expected about 1000 IDs in a 10%% sample, got %d", larger)
+	}
+}
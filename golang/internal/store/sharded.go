@@ -0,0 +1,600 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// PrimaryShard names the catalog database when it also holds namespace data
+	PrimaryShard = "primary"
+
+	// ShardMetadataKey is the namespace metadata key recording its shard
+	ShardMetadataKey = "shard"
+
+	// shardCacheAfter is how old a namespace without a shard key must be before
+	// its location is cached; younger ones may still be getting their key.
+	shardCacheAfter = time.Minute
+)
+
+// ErrUnknownShard is returned when a namespace is created on a shard that is not configured
+var ErrUnknownShard = errors.New("unknown shard")
+
+// Shard is one backend database of a sharded deployment
+type Shard struct {
+	Name string // Stable name recorded in the catalog, e.g. "pg-2"
+	URL  string // Database URL, opened on first use
+}
+
+// ShardInfo describes a shard and its load
+type ShardInfo struct {
+	Name       string
+	Namespaces int
+	Open       bool // Whether this server has connected to it yet
+}
+
+// ShardedStore spreads namespaces over several backends.
+//
+// The catalog store holds every namespace record (token hash, description,
+// metadata) and is the primary shard. Messages of a namespace live on the
+// shard named by its "shard" metadata key; namespaces without one live on
+// the catalog. Shard backends are opened, and their namespaces migrated, the
+// first time one of their namespaces is used.
+//
+// A namespace stays on the shard it was created on. Locations are cached per
+// server, so a namespace deleted and re-created on another shard through a
+// different server is only seen there after a restart.
+type ShardedStore struct {
+	catalog Store
+	urls    map[string]string // Shard name -> URL (primary excluded)
+	open    func(url string) (Store, error)
+
+	mu       sync.Mutex
+	backends map[string]Store  // Opened shards
+	located  map[string]string // Namespace -> shard name
+}
+
+// shardContextKey carries the shard a new namespace should be created on
+type shardContextKey struct{}
+
+// WithShard makes CreateNamespace place the namespace on the named shard
+// instead of the least loaded one
+func WithShard(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, shardContextKey{}, name)
+}
+
+// NewShardedStore creates a store over catalog and shards. open connects to
+// a shard URL; the returned store is closed with the sharded store.
+func NewShardedStore(catalog Store, shards []Shard, open func(url string) (Store, error)) (*ShardedStore, error) {
+	urls := make(map[string]string, len(shards))
+	for _, s := range shards {
+		if s.Name == "" || s.URL == "" {
+			return nil, fmt.Errorf("shard needs a name and URL")
+		}
+		if s.Name == PrimaryShard {
+			return nil, fmt.Errorf("shard name %q is reserved for the catalog database", PrimaryShard)
+		}
+		if _, exists := urls[s.Name]; exists {
+			return nil, fmt.Errorf("duplicate shard %s", s.Name)
+		}
+		urls[s.Name] = s.URL
+	}
+	return &ShardedStore{
+		catalog:  catalog,
+		urls:     urls,
+		open:     open,
+		backends: make(map[string]Store),
+		located:  make(map[string]string),
+	}, nil
+}
+
+// Shards returns all shards, primary first, with their namespace counts
+func (s *ShardedStore) Shards(ctx context.Context) ([]ShardInfo, error) {
+	counts, err := s.namespaceCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := []ShardInfo{{Name: PrimaryShard, Namespaces: counts[PrimaryShard], Open: true}}
+	for _, name := range s.names() {
+		_, open := s.backends[name]
+		infos = append(infos, ShardInfo{Name: name, Namespaces: counts[name], Open: open})
+	}
+	return infos, nil
+}
+
+// ShardOf returns the shard holding a namespace
+func (s *ShardedStore) ShardOf(ctx context.Context, namespace string) (string, error) {
+	return s.locate(ctx, namespace)
+}
+
+// names returns the configured shard names in order (primary excluded)
+func (s *ShardedStore) names() []string {
+	names := make([]string, 0, len(s.urls))
+	for name := range s.urls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// namespaceCounts counts namespaces per shard from the catalog
+func (s *ShardedStore) namespaceCounts(ctx context.Context) (map[string]int, error) {
+	namespaces, err := s.catalog.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, ns := range namespaces {
+		counts[shardFromMetadata(ns.Metadata)]++
+	}
+	return counts, nil
+}
+
+// shardFromMetadata returns the shard recorded in namespace metadata
+func shardFromMetadata(metadata map[string]interface{}) string {
+	if name, ok := metadata[ShardMetadataKey].(string); ok && name != "" {
+		return name
+	}
+	return PrimaryShard
+}
+
+// locate returns the shard of a namespace, from the cache or the catalog
+func (s *ShardedStore) locate(ctx context.Context, namespace string) (string, error) {
+	s.mu.Lock()
+	name, ok := s.located[namespace]
+	s.mu.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	ns, err := s.catalog.GetNamespace(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+	name = shardFromMetadata(ns.Metadata)
+	if _, recorded := ns.Metadata[ShardMetadataKey]; recorded || time.Since(ns.CreatedAt) > shardCacheAfter {
+		s.mu.Lock()
+		s.located[namespace] = name
+		s.mu.Unlock()
+	}
+	return name, nil
+}
+
+// shard returns the store of a named shard, opening it on first use
+func (s *ShardedStore) shard(name string) (Store, error) {
+	if name == PrimaryShard {
+		return s.catalog, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.backends[name]; ok {
+		return st, nil
+	}
+	url, ok := s.urls[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownShard, name)
+	}
+	st, err := s.open(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open shard %s: %w", name, err)
+	}
+	if _, err := st.MigrateNamespaces(context.Background()); err != nil {
+		st.Close()
+		return nil, fmt.Errorf("failed to migrate shard %s: %w", name, err)
+	}
+	s.backends[name] = st
+	return st, nil
+}
+
+// backend returns the store holding a namespace's messages
+func (s *ShardedStore) backend(ctx context.Context, namespace string) (Store, error) {
+	name, err := s.locate(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return s.shard(name)
+}
+
+// pick returns the shard for a new namespace: the one requested through
+// WithShard, or the one holding the fewest namespaces
+func (s *ShardedStore) pick(ctx context.Context) (string, error) {
+	if name, ok := ctx.Value(shardContextKey{}).(string); ok && name != "" {
+		if _, exists := s.urls[name]; !exists && name != PrimaryShard {
+			return "", fmt.Errorf("%w: %s", ErrUnknownShard, name)
+		}
+		return name, nil
+	}
+
+	counts, err := s.namespaceCounts(ctx)
+	if err != nil {
+		return "", err
+	}
+	best := PrimaryShard
+	for _, name := range s.names() {
+		if counts[name] < counts[best] {
+			best = name
+		}
+	}
+	return best, nil
+}
+
+// Message Operations
+
+func (s *ShardedStore) WriteMessage(ctx context.Context, namespace, streamName string, msg *Message) (*WriteResult, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return st.WriteMessage(ctx, namespace, streamName, msg)
+}
+
+func (s *ShardedStore) ImportBatch(ctx context.Context, namespace string, messages []*Message) error {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	return st.ImportBatch(ctx, namespace, messages)
+}
+
+func (s *ShardedStore) ClearNamespaceMessages(ctx context.Context, namespace string) (int64, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return st.ClearNamespaceMessages(ctx, namespace)
+}
+
+func (s *ShardedStore) GetStreamMessages(ctx context.Context, namespace, streamName string, opts *GetOpts) ([]*Message, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetStreamMessages(ctx, namespace, streamName, opts)
+}
+
+func (s *ShardedStore) GetCategoryMessages(ctx context.Context, namespace, categoryName string, opts *CategoryOpts) ([]*Message, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetCategoryMessages(ctx, namespace, categoryName, opts)
+}
+
+func (s *ShardedStore) GetLastStreamMessage(ctx context.Context, namespace, streamName string, msgType *string) (*Message, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return st.GetLastStreamMessage(ctx, namespace, streamName, msgType)
+}
+
+func (s *ShardedStore) GetStreamVersion(ctx context.Context, namespace, streamName string) (int64, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return st.GetStreamVersion(ctx, namespace, streamName)
+}
+
+// Namespace Operations
+
+// CreateNamespace records the namespace in the catalog and creates its
+// storage on the chosen shard
+func (s *ShardedStore) CreateNamespace(ctx context.Context, id, tokenHash, description string) error {
+	name, err := s.pick(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.catalog.CreateNamespace(ctx, id, tokenHash, description); err != nil {
+		return err
+	}
+
+	if name != PrimaryShard {
+		st, err := s.shard(name)
+		if err == nil {
+			err = st.CreateNamespace(ctx, id, tokenHash, description)
+		}
+		if err != nil {
+			s.catalog.DeleteNamespace(context.Background(), id)
+			return fmt.Errorf("failed to create namespace on shard %s: %w", name, err)
+		}
+	}
+
+	// Keep metadata set by the catalog backend on creation
+	ns, err := s.catalog.GetNamespace(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to record shard of namespace: %w", err)
+	}
+	metadata := make(map[string]interface{}, len(ns.Metadata)+1)
+	for k, v := range ns.Metadata {
+		metadata[k] = v
+	}
+	metadata[ShardMetadataKey] = name
+	if err := s.catalog.UpdateNamespaceMetadata(ctx, id, metadata); err != nil {
+		return fmt.Errorf("failed to record shard of namespace: %w", err)
+	}
+	s.mu.Lock()
+	s.located[id] = name
+	s.mu.Unlock()
+	return nil
+}
+
+// DeleteNamespace deletes the namespace's storage and its catalog record
+func (s *ShardedStore) DeleteNamespace(ctx context.Context, id string) error {
+	name, err := s.locate(ctx, id)
+	if err != nil {
+		return err
+	}
+	if name != PrimaryShard {
+		st, err := s.shard(name)
+		if err != nil {
+			return err
+		}
+		if err := st.DeleteNamespace(ctx, id); err != nil && !errors.Is(err, ErrNamespaceNotFound) {
+			return err
+		}
+	}
+	s.mu.Lock()
+	delete(s.located, id)
+	s.mu.Unlock()
+	return s.catalog.DeleteNamespace(ctx, id)
+}
+
+func (s *ShardedStore) GetNamespace(ctx context.Context, id string) (*Namespace, error) {
+	return s.catalog.GetNamespace(ctx, id)
+}
+
+func (s *ShardedStore) ListNamespaces(ctx context.Context) ([]*Namespace, error) {
+	return s.catalog.ListNamespaces(ctx)
+}
+
+// UpdateNamespaceMetadata replaces the metadata in the catalog. The shard key
+// is kept when metadata does not carry it.
+func (s *ShardedStore) UpdateNamespaceMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	if _, ok := metadata[ShardMetadataKey]; !ok {
+		ns, err := s.catalog.GetNamespace(ctx, id)
+		if err != nil {
+			return err
+		}
+		if name, ok := ns.Metadata[ShardMetadataKey]; ok {
+			withShard := make(map[string]interface{}, len(metadata)+1)
+			for k, v := range metadata {
+				withShard[k] = v
+			}
+			withShard[ShardMetadataKey] = name
+			metadata = withShard
+		}
+	}
+	return s.catalog.UpdateNamespaceMetadata(ctx, id, metadata)
+}
+
+// UpdateNamespaceToken replaces the token hash in the catalog, which
+// authenticates requests for every shard
+func (s *ShardedStore) UpdateNamespaceToken(ctx context.Context, id, tokenHash string) error {
+	return s.catalog.UpdateNamespaceToken(ctx, id, tokenHash)
+}
+
+// MigrateNamespaces migrates the catalog. Shards are migrated when opened.
+func (s *ShardedStore) MigrateNamespaces(ctx context.Context) (int, error) {
+	return s.catalog.MigrateNamespaces(ctx)
+}
+
+func (s *ShardedStore) GetNamespaceMessageCount(ctx context.Context, namespace string) (int64, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return st.GetNamespaceMessageCount(ctx, namespace)
+}
+
+func (s *ShardedStore) ListStreams(ctx context.Context, namespace string, opts *ListStreamsOpts) ([]*StreamInfo, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return st.ListStreams(ctx, namespace, opts)
+}
+
+func (s *ShardedStore) ListCategories(ctx context.Context, namespace string) ([]*CategoryInfo, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return st.ListCategories(ctx, namespace)
+}
+
+// CheckStorage forwards to the namespace's shard if it implements IntegrityChecker
+func (s *ShardedStore) CheckStorage(ctx context.Context, namespace string) ([]string, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if checker, ok := st.(IntegrityChecker); ok {
+		return checker.CheckStorage(ctx, namespace)
+	}
+	return nil, nil
+}
+
+// InvalidJSON forwards to the namespace's shard if it implements IntegrityChecker
+func (s *ShardedStore) InvalidJSON(ctx context.Context, namespace, streamName string) ([]int64, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if checker, ok := st.(IntegrityChecker); ok {
+		return checker.InvalidJSON(ctx, namespace, streamName)
+	}
+	return nil, nil
+}
+
+// NamespaceStorage forwards to the namespace's shard if it implements StorageReporter
+func (s *ShardedStore) NamespaceStorage(ctx context.Context, namespace string) (*StorageUsage, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if reporter, ok := st.(StorageReporter); ok {
+		return reporter.NamespaceStorage(ctx, namespace)
+	}
+	return nil, ErrNotSupported
+}
+
+// TruncateStream forwards to the namespace's shard if it implements StreamTruncater
+func (s *ShardedStore) TruncateStream(ctx context.Context, namespace, streamName string, position int64) (int64, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	if truncater, ok := st.(StreamTruncater); ok {
+		return truncater.TruncateStream(ctx, namespace, streamName, position)
+	}
+	return 0, ErrNotSupported
+}
+
+// RedactMessage forwards to the namespace's shard if it implements MessageRedactor
+func (s *ShardedStore) RedactMessage(ctx context.Context, namespace, streamName string, position int64, data, metadata map[string]interface{}) error {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if redactor, ok := st.(MessageRedactor); ok {
+		return redactor.RedactMessage(ctx, namespace, streamName, position, data, metadata)
+	}
+	return ErrNotSupported
+}
+
+// MergeStreams forwards to the namespace's shard if it implements StreamMerger
+func (s *ShardedStore) MergeStreams(ctx context.Context, namespace, target string, sources []string) (int64, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	if merger, ok := st.(StreamMerger); ok {
+		return merger.MergeStreams(ctx, namespace, target, sources)
+	}
+	return 0, ErrNotSupported
+}
+
+// WriteMessages forwards to the namespace's shard if it implements AtomicWriter
+func (s *ShardedStore) WriteMessages(ctx context.Context, namespace string, msgs []*Message) ([]*WriteResult, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if writer, ok := st.(AtomicWriter); ok {
+		return writer.WriteMessages(ctx, namespace, msgs)
+	}
+	return nil, ErrNotSupported
+}
+
+// SetConsumerPosition forwards to the namespace's shard if it implements ConsumerPositionStore
+func (s *ShardedStore) SetConsumerPosition(ctx context.Context, namespace, consumer string, globalPosition int64) (*ConsumerPosition, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if positions, ok := st.(ConsumerPositionStore); ok {
+		return positions.SetConsumerPosition(ctx, namespace, consumer, globalPosition)
+	}
+	return nil, ErrNotSupported
+}
+
+// GetConsumerPosition forwards to the namespace's shard if it implements ConsumerPositionStore
+func (s *ShardedStore) GetConsumerPosition(ctx context.Context, namespace, consumer string) (*ConsumerPosition, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if positions, ok := st.(ConsumerPositionStore); ok {
+		return positions.GetConsumerPosition(ctx, namespace, consumer)
+	}
+	return nil, ErrNotSupported
+}
+
+// Utility Functions
+
+func (s *ShardedStore) Category(streamName string) string   { return s.catalog.Category(streamName) }
+func (s *ShardedStore) ID(streamName string) string         { return s.catalog.ID(streamName) }
+func (s *ShardedStore) CardinalID(streamName string) string { return s.catalog.CardinalID(streamName) }
+func (s *ShardedStore) IsCategory(name string) bool         { return s.catalog.IsCategory(name) }
+func (s *ShardedStore) Hash64(value string) int64           { return s.catalog.Hash64(value) }
+
+// Close closes the opened shards and the catalog
+func (s *ShardedStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var firstErr error
+	for name, st := range s.backends {
+		if err := st.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close shard %s: %w", name, err)
+		}
+	}
+	s.backends = make(map[string]Store)
+	if err := s.catalog.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// PositionGuarantee forwards to the namespace's shard, or to the catalog for
+// namespaces that cannot be located
+func (s *ShardedStore) PositionGuarantee(ctx context.Context, namespace string) PositionGuarantee {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		st = s.catalog
+	}
+	return PositionGuaranteeOf(ctx, st, namespace)
+}
+
+// CommitsInOrder forwards to the namespace's shard if it implements CommitOrderer
+func (s *ShardedStore) CommitsInOrder(ctx context.Context, namespace string) bool {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return false
+	}
+	orderer, ok := st.(CommitOrderer)
+	return ok && orderer.CommitsInOrder(ctx, namespace)
+}
+
+// PendingWrites forwards to the namespace's shard if it implements PendingWriteInspector
+func (s *ShardedStore) PendingWrites(ctx context.Context, namespace, category string) (*PendingWrites, error) {
+	st, err := s.backend(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if inspector, ok := st.(PendingWriteInspector); ok {
+		return inspector.PendingWrites(ctx, namespace, category)
+	}
+	return nil, ErrNotSupported
+}
+
+// SwapNamespaceMessages forwards to the shard holding both namespaces if it
+// implements NamespaceSwapper; messages never move between shards
+func (s *ShardedStore) SwapNamespaceMessages(ctx context.Context, a, b string) error {
+	shardA, err := s.locate(ctx, a)
+	if err != nil {
+		return err
+	}
+	shardB, err := s.locate(ctx, b)
+	if err != nil {
+		return err
+	}
+	if shardA != shardB {
+		return fmt.Errorf("namespaces %s and %s are on different shards (%s, %s)", a, b, shardA, shardB)
+	}
+	st, err := s.shard(shardA)
+	if err != nil {
+		return err
+	}
+	if swapper, ok := st.(NamespaceSwapper); ok {
+		return swapper.SwapNamespaceMessages(ctx, a, b)
+	}
+	return ErrNotSupported
+}
@@ -0,0 +1,122 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/eventodb/eventodb/internal/store"
+	"github.com/eventodb/eventodb/internal/store/sqlite"
+)
+
+// openFileStore opens a file-backed SQLite store in dir, so shards do not
+// share the process-wide in-memory namespace databases of test mode
+func openFileStore(dir string) (store.Store, error) {
+	db, err := sql.Open("sqlite", filepath.Join(dir, "metadata.db"))
+	if err != nil {
+		return nil, err
+	}
+	return sqlite.New(db, &sqlite.Config{DataDir: dir})
+}
+
+func newTestShardedStore(t *testing.T) (*store.ShardedStore, map[string]int) {
+	t.Helper()
+	catalog, err := openFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open catalog: %v", err)
+	}
+	dirs := map[string]string{"s1": t.TempDir(), "s2": t.TempDir()}
+	opened := map[string]int{}
+	sharded, err := store.NewShardedStore(catalog, []store.Shard{
+		{Name: "s1", URL: dirs["s1"]},
+		{Name: "s2", URL: dirs["s2"]},
+	}, func(url string) (store.Store, error) {
+		for name, dir := range dirs {
+			if dir == url {
+				opened[name]++
+			}
+		}
+		return openFileStore(url)
+	})
+	if err != nil {
+		t.Fatalf("NewShardedStore failed: %v", err)
+	}
+	t.Cleanup(func() { sharded.Close() })
+	return sharded, opened
+}
+
+func TestShardedStore_PlacesNamespaces(t *testing.T) {
+	st, opened := newTestShardedStore(t)
+	ctx := context.Background()
+
+	if len(opened) != 0 {
+		t.Fatalf("Shards must be opened lazily, got %v", opened)
+	}
+
+	// Explicit placement, then auto-balancing onto the emptiest shards
+	if err := st.CreateNamespace(store.WithShard(ctx, "s2"), "pinned", "hash-pinned", ""); err != nil {
+		t.Fatalf("CreateNamespace on s2 failed: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := st.CreateNamespace(ctx, id, "hash-"+id, ""); err != nil {
+			t.Fatalf("CreateNamespace %s failed: %v", id, err)
+		}
+	}
+	want := map[string]string{"pinned": "s2", "a": store.PrimaryShard, "b": "s1"}
+	for id, shard := range want {
+		if got, err := st.ShardOf(ctx, id); err != nil || got != shard {
+			t.Errorf("Namespace %s: expected shard %s, got %s (%v)", id, shard, got, err)
+		}
+	}
+
+	// Messages land on the namespace's shard and are read back through it
+	msg := &store.Message{StreamName: "account-1", Type: "Opened", Data: map[string]interface{}{}}
+	if _, err := st.WriteMessage(ctx, "pinned", "account-1", msg); err != nil {
+		t.Fatalf("WriteMessage failed: %v", err)
+	}
+	if v, err := st.GetStreamVersion(ctx, "pinned", "account-1"); err != nil || v != 0 {
+		t.Errorf("Expected version 0, got %d (%v)", v, err)
+	}
+	if v, err := st.GetStreamVersion(ctx, "a", "account-1"); err != nil || v != -1 {
+		t.Errorf("Expected empty stream on primary, got %d (%v)", v, err)
+	}
+	if opened["s2"] != 1 {
+		t.Errorf("Expected s2 to be opened once, got %d", opened["s2"])
+	}
+
+	// Metadata updates keep the shard
+	if err := st.UpdateNamespaceMetadata(ctx, "pinned", map[string]interface{}{"k": "v"}); err != nil {
+		t.Fatalf("UpdateNamespaceMetadata failed: %v", err)
+	}
+	ns, err := st.GetNamespace(ctx, "pinned")
+	if err != nil || ns.Metadata[store.ShardMetadataKey] != "s2" || ns.Metadata["k"] != "v" {
+		t.Errorf("Unexpected metadata: %v (%v)", ns, err)
+	}
+
+	infos, err := st.Shards(ctx)
+	if err != nil || len(infos) != 3 || infos[0].Namespaces != 1 || infos[2].Namespaces != 1 || !infos[2].Open {
+		t.Errorf("Unexpected shards: %+v (%v)", infos, err)
+	}
+
+	if err := st.DeleteNamespace(ctx, "pinned"); err != nil {
+		t.Fatalf("DeleteNamespace failed: %v", err)
+	}
+	if _, err := st.GetStreamVersion(ctx, "pinned", "account-1"); !errors.Is(err, store.ErrNamespaceNotFound) {
+		t.Errorf("Expected ErrNamespaceNotFound after delete, got %v", err)
+	}
+}
+
+func TestShardedStore_UnknownShard(t *testing.T) {
+	st, _ := newTestShardedStore(t)
+	ctx := store.WithShard(context.Background(), "nope")
+	if err := st.CreateNamespace(ctx, "x", "hash", ""); !errors.Is(err, store.ErrUnknownShard) {
+		t.Errorf("Expected ErrUnknownShard, got %v", err)
+	}
+	if _, err := store.NewShardedStore(nil, []store.Shard{{Name: store.PrimaryShard, URL: "x"}}, nil); err == nil {
+		t.Error("Expected error for reserved shard name")
+	}
+}
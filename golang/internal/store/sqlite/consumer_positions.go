@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// SetConsumerPosition records the last global position consumer has processed
+func (s *SQLiteStore) SetConsumerPosition(ctx context.Context, namespace, consumer string, globalPosition int64) (*store.ConsumerPosition, error) {
+	handle, err := s.getNamespaceHandle(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	handle.writeMu.Lock()
+	defer handle.writeMu.Unlock()
+
+	updated := time.Now().UTC()
+	_, err = handle.db.ExecContext(ctx,
+		`INSERT INTO consumer_positions (consumer, global_position, updated) VALUES (?, ?, ?)
+		ON CONFLICT (consumer) DO UPDATE SET global_position = excluded.global_position, updated = excluded.updated`,
+		consumer, globalPosition, updated.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to set consumer position: %w", err)
+	}
+
+	return &store.ConsumerPosition{Consumer: consumer, GlobalPosition: globalPosition, Updated: updated}, nil
+}
+
+// GetConsumerPosition returns the last recorded position of consumer
+func (s *SQLiteStore) GetConsumerPosition(ctx context.Context, namespace, consumer string) (*store.ConsumerPosition, error) {
+	handle, err := s.getNamespaceHandle(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var globalPosition, updated int64
+	err = handle.db.QueryRowContext(ctx,
+		`SELECT global_position, updated FROM consumer_positions WHERE consumer = ?`,
+		consumer).Scan(&globalPosition, &updated)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrConsumerPositionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumer position: %w", err)
+	}
+
+	return &store.ConsumerPosition{
+		Consumer:       consumer,
+		GlobalPosition: globalPosition,
+		Updated:        time.Unix(0, updated).UTC(),
+	}, nil
+}
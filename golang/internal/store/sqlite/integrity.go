@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+)
+
+// CheckStorage runs PRAGMA quick_check on a namespace database and returns
+// the problems SQLite reports
+func (s *SQLiteStore) CheckStorage(ctx context.Context, namespace string) ([]string, error) {
+	handle, err := s.getNamespaceHandle(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := handle.db.QueryContext(ctx, `PRAGMA quick_check`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check namespace database: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("failed to scan check result: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// InvalidJSON returns the positions of messages in a stream whose stored data
+// or metadata does not parse as JSON. Reads decode such messages with nil data.
+func (s *SQLiteStore) InvalidJSON(ctx context.Context, namespace, streamName string) ([]int64, error) {
+	handle, err := s.getNamespaceHandle(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := handle.db.QueryContext(ctx,
+		`SELECT position FROM messages
+		 WHERE stream_name = ? AND (json_valid(data) = 0 OR json_valid(metadata) = 0)
+		 ORDER BY position`,
+		streamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check message JSON: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []int64
+	for rows.Next() {
+		var position int64
+		if err := rows.Scan(&position); err != nil {
+			return nil, fmt.Errorf("failed to scan position: %w", err)
+		}
+		positions = append(positions, position)
+	}
+	return positions, rows.Err()
+}
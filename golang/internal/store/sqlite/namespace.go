@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/eventodb/eventodb/internal/migrate"
 	"github.com/eventodb/eventodb/internal/store"
 	"github.com/eventodb/eventodb/migrations"
 )
@@ -93,6 +94,40 @@ func (s *SQLiteStore) GetNamespace(ctx context.Context, id string) (*store.Names
 	return &ns, nil
 }
 
+// UpdateNamespaceMetadata replaces a namespace's metadata
+func (s *SQLiteStore) UpdateNamespaceMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to serialize metadata: %w", err)
+	}
+
+	result, err := s.metadataDB.ExecContext(ctx,
+		`UPDATE namespaces SET metadata = ? WHERE id = ?`, string(metadataJSON), id)
+	if err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrNamespaceNotFound
+	}
+	return nil
+}
+
+// UpdateNamespaceToken replaces a namespace's token hash
+func (s *SQLiteStore) UpdateNamespaceToken(ctx context.Context, id, tokenHash string) error {
+	result, err := s.metadataDB.ExecContext(ctx,
+		`UPDATE namespaces SET token_hash = ? WHERE id = ?`, tokenHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrNamespaceNotFound
+	}
+	return nil
+}
+
 // ListNamespaces retrieves all namespaces
 func (s *SQLiteStore) ListNamespaces(ctx context.Context) ([]*store.Namespace, error) {
 	rows, err := s.metadataDB.QueryContext(ctx,
@@ -178,7 +213,7 @@ func (s *SQLiteStore) migrateNamespaceDB(ctx context.Context, db *sql.DB) (int,
 
 	applied := 0
 	for _, entry := range migrationFiles {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") || migrate.IsDownFile(entry.Name()) {
 			continue
 		}
 
@@ -227,3 +262,56 @@ func extractVersionFromFilename(filename string) int {
 	}
 	return version
 }
+
+// SwapNamespaceMessages exchanges the database files of two namespaces in
+// the registry, along with their open handles
+func (s *SQLiteStore) SwapNamespaceMessages(ctx context.Context, a, b string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.metadataDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var pathA, pathB string
+	for _, ns := range []struct {
+		id   string
+		path *string
+	}{{a, &pathA}, {b, &pathB}} {
+		err := tx.QueryRowContext(ctx, `SELECT db_path FROM namespaces WHERE id = ?`, ns.id).Scan(ns.path)
+		if err == sql.ErrNoRows {
+			return store.ErrNamespaceNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get db_path: %w", err)
+		}
+	}
+
+	// Paths are unique, so a moves aside first
+	for _, step := range []struct{ id, path string }{
+		{a, pathA + ":swap"},
+		{b, pathA},
+		{a, pathB},
+	} {
+		if _, err := tx.ExecContext(ctx, `UPDATE namespaces SET db_path = ? WHERE id = ?`, step.path, step.id); err != nil {
+			return fmt.Errorf("failed to swap db_path: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	handleA, okA := s.namespaces[a]
+	handleB, okB := s.namespaces[b]
+	delete(s.namespaces, a)
+	delete(s.namespaces, b)
+	if okA {
+		s.namespaces[b] = handleA
+	}
+	if okB {
+		s.namespaces[a] = handleB
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/eventodb/eventodb/internal/store"
@@ -45,11 +46,18 @@ func (s *SQLiteStore) GetStreamMessages(ctx context.Context, namespace, streamNa
 	}
 	defer rows.Close()
 
-	return scanMessages(rows, opts.BatchSize)
+	return scanMessages(ctx, rows, opts.BatchSize, nil)
 }
 
 // GetCategoryMessages retrieves messages from a category
 func (s *SQLiteStore) GetCategoryMessages(ctx context.Context, namespace, categoryName string, opts *store.CategoryOpts) ([]*store.Message, error) {
+	// Ordering key groups filter on metadata, after reading
+	if opts != nil && opts.OrderingKey && opts.ConsumerMember != nil && opts.ConsumerSize != nil {
+		return store.GetOrderingKeyMessages(opts, func(opts *store.CategoryOpts) ([]*store.Message, error) {
+			return s.GetCategoryMessages(ctx, namespace, categoryName, opts)
+		})
+	}
+
 	handle, err := s.getNamespaceHandle(namespace)
 	if err != nil {
 		return nil, err
@@ -66,79 +74,50 @@ func (s *SQLiteStore) GetCategoryMessages(ctx context.Context, namespace, catego
 		position = *opts.GlobalPosition
 	}
 
-	var query string
+	var conditions []string
 	var args []interface{}
 
-	if categoryName == "" {
-		// Empty category = all messages
-		query = `SELECT id, stream_name, type, position, global_position, data, metadata, time
-			FROM messages
-			WHERE global_position >= ?
-			ORDER BY global_position ASC`
-		args = []interface{}{position}
-
-		if opts.Correlation != nil && *opts.Correlation != "" {
-			query = `SELECT id, stream_name, type, position, global_position, data, metadata, time
-				FROM messages
-				WHERE global_position >= ?
-				AND json_extract(metadata, '$.correlationStreamName') LIKE ?
-				ORDER BY global_position ASC`
-			args = append(args, *opts.Correlation+"-%")
-		}
-	} else {
-		query = `SELECT id, stream_name, type, position, global_position, data, metadata, time
-			FROM messages
-			WHERE substr(stream_name, 1, instr(stream_name || '-', '-') - 1) = ?
-			AND global_position >= ?
-			ORDER BY global_position ASC`
-		args = []interface{}{categoryName, position}
-
-		if opts.Correlation != nil && *opts.Correlation != "" {
-			query = `SELECT id, stream_name, type, position, global_position, data, metadata, time
-				FROM messages
-				WHERE substr(stream_name, 1, instr(stream_name || '-', '-') - 1) = ?
-				AND global_position >= ?
-				AND json_extract(metadata, '$.correlationStreamName') LIKE ?
-				ORDER BY global_position ASC`
-			args = append(args, *opts.Correlation+"-%")
-		}
+	// Empty category = all messages
+	if categoryName != "" {
+		conditions = append(conditions, "substr(stream_name, 1, instr(stream_name || '-', '-') - 1) = ?")
+		args = append(args, categoryName)
+	}
+	conditions = append(conditions, "global_position >= ?")
+	args = append(args, position)
+	if opts.Correlation != nil && *opts.Correlation != "" {
+		conditions = append(conditions, "json_extract(metadata, '$.correlationStreamName') LIKE ?")
+		args = append(args, *opts.Correlation+"-%")
+	}
+	if opts.Filter != nil {
+		var filter string
+		filter, args = opts.Filter.SQL(store.DialectSQLite, args)
+		conditions = append(conditions, filter)
 	}
 
+	query := `SELECT id, stream_name, type, position, global_position, data, metadata, time
+		FROM messages
+		WHERE ` + strings.Join(conditions, "\n\t\tAND ") + `
+		ORDER BY global_position ASC`
+
 	rows, err := handle.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query: %w", err)
 	}
 	defer rows.Close()
 
-	allMessages, err := scanMessages(rows, opts.BatchSize)
-	if err != nil {
-		return nil, err
-	}
-
-	// Consumer group filtering
-	if opts.ConsumerMember != nil && opts.ConsumerSize != nil {
-		// Pre-allocate for consumer group filtering
-		capacity := int(opts.BatchSize)
-		if capacity <= 0 || capacity > 10000 {
-			capacity = 1000
+	// Sampling hashes the message ID and consumer groups hash the stream
+	// name, which SQLite cannot do in the query; both filter while scanning,
+	// so the scan stops once the batch is full
+	keep := func(msg *store.Message) bool {
+		if !store.InSample(msg.ID, opts.Sample) {
+			return false
 		}
-		messages := make([]*store.Message, 0, capacity)
-		for _, msg := range allMessages {
-			if store.IsAssignedToConsumerMember(msg.StreamName, *opts.ConsumerMember, *opts.ConsumerSize) {
-				messages = append(messages, msg)
-				if opts.BatchSize > 0 && int64(len(messages)) >= opts.BatchSize {
-					break
-				}
-			}
+		if opts.ConsumerMember != nil && opts.ConsumerSize != nil {
+			return opts.Partitioner.IsAssigned(msg.StreamName, *opts.ConsumerMember, *opts.ConsumerSize)
 		}
-		return messages, nil
+		return true
 	}
-
-	if opts.BatchSize > 0 && int64(len(allMessages)) > opts.BatchSize {
-		return allMessages[:opts.BatchSize], nil
-	}
-
-	return allMessages, nil
+	return scanMessages(ctx, rows, opts.BatchSize, keep)
 }
 
 // GetLastStreamMessage retrieves the last message from a stream
@@ -164,7 +143,7 @@ func (s *SQLiteStore) GetLastStreamMessage(ctx context.Context, namespace, strea
 	}
 	defer rows.Close()
 
-	messages, err := scanMessages(rows, 1)
+	messages, err := scanMessages(ctx, rows, 1, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -210,17 +189,30 @@ func (s *SQLiteStore) ListStreams(ctx context.Context, namespace string, opts *s
 		limit = 1000
 	}
 
-	query := `SELECT stream_name, MAX(position) AS version, MAX(time) AS last_activity
+	// Activity filters compare unix seconds; 0 disables them
+	var inactiveSince, olderThan int64
+	if !opts.InactiveSince.IsZero() {
+		inactiveSince = opts.InactiveSince.Unix()
+	}
+	if !opts.OlderThan.IsZero() {
+		olderThan = opts.OlderThan.Unix()
+	}
+
+	query := `SELECT stream_name, MAX(position) AS version, MIN(time) AS first_activity, MAX(time) AS last_activity
 		FROM messages
 		WHERE (? = '' OR stream_name LIKE ? || '%')
 		  AND (? = '' OR stream_name > ?)
 		GROUP BY stream_name
+		HAVING (? = 0 OR MAX(time) < ?)
+		   AND (? = 0 OR MIN(time) < ?)
 		ORDER BY stream_name ASC
 		LIMIT ?`
 
 	rows, err := handle.db.QueryContext(ctx, query,
 		opts.Prefix, opts.Prefix,
 		opts.Cursor, opts.Cursor,
+		inactiveSince, inactiveSince,
+		olderThan, olderThan,
 		limit,
 	)
 	if err != nil {
@@ -231,10 +223,11 @@ func (s *SQLiteStore) ListStreams(ctx context.Context, namespace string, opts *s
 	var results []*store.StreamInfo
 	for rows.Next() {
 		var si store.StreamInfo
-		var lastActivityUnix int64
-		if err := rows.Scan(&si.StreamName, &si.Version, &lastActivityUnix); err != nil {
+		var firstActivityUnix, lastActivityUnix int64
+		if err := rows.Scan(&si.StreamName, &si.Version, &firstActivityUnix, &lastActivityUnix); err != nil {
 			return nil, fmt.Errorf("failed to scan stream info: %w", err)
 		}
+		si.FirstActivity = time.Unix(firstActivityUnix, 0).UTC()
 		si.LastActivity = time.Unix(lastActivityUnix, 0).UTC()
 		results = append(results, &si)
 	}
@@ -282,9 +275,11 @@ func (s *SQLiteStore) ListCategories(ctx context.Context, namespace string) ([]*
 	return results, rows.Err()
 }
 
-func scanMessages(rows *sql.Rows, capacityHint int64) ([]*store.Message, error) {
+// scanMessages scans up to limit messages (all for -1) that keep accepts; a
+// nil keep accepts every message
+func scanMessages(ctx context.Context, rows *sql.Rows, limit int64, keep func(*store.Message) bool) ([]*store.Message, error) {
 	// Pre-allocate slice with capacity hint to reduce allocations
-	capacity := int(capacityHint)
+	capacity := int(limit)
 	if capacity <= 0 || capacity > 10000 {
 		capacity = 1000 // reasonable default
 	}
@@ -298,25 +293,32 @@ func scanMessages(rows *sql.Rows, capacityHint int64) ([]*store.Message, error)
 		if err := rows.Scan(&id, &streamName, &msgType, &position, &globalPosition, &dataJSON, &metadataJSON, &timestamp); err != nil {
 			return nil, fmt.Errorf("failed to scan: %w", err)
 		}
-
-		var data, metadata map[string]interface{}
-		if len(dataJSON) > 0 && string(dataJSON) != "null" {
-			json.Unmarshal(dataJSON, &data)
-		}
-		if len(metadataJSON) > 0 && string(metadataJSON) != "null" {
-			json.Unmarshal(metadataJSON, &metadata)
-		}
-
-		messages = append(messages, &store.Message{
+		msg := &store.Message{
 			ID:             id,
 			StreamName:     streamName,
 			Type:           msgType,
 			Position:       position,
 			GlobalPosition: globalPosition,
-			Data:           data,
-			Metadata:       metadata,
 			Time:           time.Unix(timestamp, 0).UTC(),
-		})
+		}
+		if keep != nil && !keep(msg) {
+			continue
+		}
+		if err := store.MeterRead(ctx, len(id)+len(streamName)+len(msgType)+len(dataJSON)+len(metadataJSON)); err != nil {
+			return nil, err
+		}
+
+		if len(dataJSON) > 0 && string(dataJSON) != "null" {
+			json.Unmarshal(dataJSON, &msg.Data)
+		}
+		if len(metadataJSON) > 0 && string(metadataJSON) != "null" {
+			json.Unmarshal(metadataJSON, &msg.Metadata)
+		}
+
+		messages = append(messages, msg)
+		if limit > 0 && int64(len(messages)) >= limit {
+			break
+		}
 	}
 
 	return messages, rows.Err()
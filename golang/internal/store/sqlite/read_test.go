@@ -2,6 +2,8 @@ package sqlite
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	storepkg "github.com/eventodb/eventodb/internal/store"
@@ -755,3 +757,81 @@ func TestMDB001_5A_T15_GetCategoryMessages_WithGlobalPosition(t *testing.T) {
 		}
 	}
 }
+
+// TestGetLastStreamMessage_TypeFilterUsesIndex checks that the type-filtered
+// lookup seeks messages_stream_type rather than scanning the stream
+func TestGetLastStreamMessage_TypeFilterUsesIndex(t *testing.T) {
+	store, cleanup := getTestStore(t, true)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Create namespace
+	err := store.CreateNamespace(ctx, "test_ns_r8", "hash_r8", "Test namespace r8")
+	if err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	defer cleanupNamespace(t, store, "test_ns_r8")
+
+	handle, err := store.getNamespaceHandle("test_ns_r8")
+	if err != nil {
+		t.Fatalf("Failed to get namespace handle: %v", err)
+	}
+
+	rows, err := handle.db.QueryContext(ctx, `EXPLAIN QUERY PLAN SELECT id FROM messages
+		WHERE stream_name = ? AND type = ? ORDER BY position DESC LIMIT 1`, "account-1", "Reserved")
+	if err != nil {
+		t.Fatalf("Failed to explain query: %v", err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			t.Fatalf("Failed to scan plan: %v", err)
+		}
+		plan = append(plan, detail)
+	}
+	if len(plan) != 1 || !strings.Contains(plan[0], "messages_stream_type") {
+		t.Errorf("Expected a search of messages_stream_type, got %v", plan)
+	}
+}
+
+func TestGetCategoryMessages_ReadMeterStopsRead(t *testing.T) {
+	store, cleanup := getTestStore(t, true)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := store.CreateNamespace(ctx, "test_ns_r9", "hash_r9", "Test namespace r9"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	defer cleanupNamespace(t, store, "test_ns_r9")
+	writeTestMessages(t, store, "test_ns_r9", "account-1", 10)
+
+	// The meter sees every loaded message and stops the read at its error
+	errFull := errors.New("full")
+	loaded := 0
+	meterCtx := storepkg.WithReadMeter(ctx, func(size int64) error {
+		if size <= 0 {
+			t.Errorf("Expected a positive size, got %d", size)
+		}
+		if loaded++; loaded > 3 {
+			return errFull
+		}
+		return nil
+	})
+	if _, err := store.GetCategoryMessages(meterCtx, "test_ns_r9", "account", &storepkg.CategoryOpts{Position: 1, BatchSize: -1}); !errors.Is(err, errFull) {
+		t.Fatalf("Expected the meter's error, got %v", err)
+	}
+	if loaded != 4 {
+		t.Errorf("Expected the read to stop at the 4th message, loaded %d", loaded)
+	}
+
+	// Reads without a meter are not limited
+	msgs, err := store.GetCategoryMessages(ctx, "test_ns_r9", "account", &storepkg.CategoryOpts{Position: 1, BatchSize: -1})
+	if err != nil || len(msgs) != 10 {
+		t.Errorf("Expected 10 messages, got %d (%v)", len(msgs), err)
+	}
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/eventodb/eventodb/internal/migrate"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// LatestSchemaVersion returns the namespace schema version of this build
+func (s *SQLiteStore) LatestSchemaVersion() int {
+	migs, err := migrate.Load(sqliteMigrations, "namespace/sqlite")
+	if err != nil {
+		return 0
+	}
+	return migrate.Latest(migs)
+}
+
+// NamespaceSchemaVersions returns the schema version of each namespace
+func (s *SQLiteStore) NamespaceSchemaVersions(ctx context.Context) ([]store.NamespaceSchemaVersion, error) {
+	namespaces, err := s.ListNamespaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	versions := make([]store.NamespaceSchemaVersion, 0, len(namespaces))
+	for _, ns := range namespaces {
+		handle, err := s.getNamespaceHandle(ns.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get handle for namespace %s: %w", ns.ID, err)
+		}
+		versions = append(versions, store.NamespaceSchemaVersion{
+			Namespace: ns.ID,
+			Version:   getNamespaceSchemaVersion(ctx, handle.db),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Namespace < versions[j].Namespace })
+	return versions, nil
+}
+
+// RollbackNamespaces reverts every namespace above version down to it
+func (s *SQLiteStore) RollbackNamespaces(ctx context.Context, version int) (int, error) {
+	migs, err := migrate.Load(sqliteMigrations, "namespace/sqlite")
+	if err != nil {
+		return 0, fmt.Errorf("failed to load namespace migrations: %w", err)
+	}
+	namespaces, err := s.ListNamespaces(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	reverted := 0
+	for _, ns := range namespaces {
+		handle, err := s.getNamespaceHandle(ns.ID)
+		if err != nil {
+			return reverted, fmt.Errorf("failed to get handle for namespace %s: %w", ns.ID, err)
+		}
+		down, err := migrate.Rollbacks(migs, getNamespaceSchemaVersion(ctx, handle.db), version)
+		if err != nil {
+			return reverted, fmt.Errorf("failed to roll back namespace %s: %w", ns.ID, err)
+		}
+		for _, mig := range down {
+			if _, err := handle.db.ExecContext(ctx, mig.Down); err != nil {
+				return reverted, fmt.Errorf("failed to roll back migration %s of namespace %s: %w", mig.Name, ns.ID, err)
+			}
+			// Forget the migration so opening the database applies it again
+			// unless migrations are manual
+			if _, err := handle.db.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, mig.Name); err != nil {
+				return reverted, fmt.Errorf("failed to unrecord migration %s of namespace %s: %w", mig.Name, ns.ID, err)
+			}
+			reverted++
+		}
+	}
+	return reverted, nil
+}
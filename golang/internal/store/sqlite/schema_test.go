@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// TestSchemaMigrator_RollbackAndMigrate tests rolling namespaces back and
+// migrating them up again with manual migrations
+func TestSchemaMigrator_RollbackAndMigrate(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	open := func(manual bool) *SQLiteStore {
+		db, err := sql.Open("sqlite", filepath.Join(dir, "metadata.db"))
+		if err != nil {
+			t.Fatalf("Failed to open metadata database: %v", err)
+		}
+		st, err := New(db, &Config{DataDir: dir, ManualMigrations: manual})
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		return st
+	}
+	versionOf := func(st *SQLiteStore) int {
+		versions, err := st.NamespaceSchemaVersions(ctx)
+		if err != nil || len(versions) != 1 || versions[0].Namespace != "schema-ns" {
+			t.Fatalf("Unexpected versions: %v (%v)", versions, err)
+		}
+		return versions[0].Version
+	}
+
+	st := open(true)
+	latest := st.LatestSchemaVersion()
+	if latest < 2 {
+		t.Fatalf("Expected latest version >= 2, got %d", latest)
+	}
+	if err := st.CreateNamespace(ctx, "schema-ns", "hash-schema", ""); err != nil {
+		t.Fatalf("CreateNamespace failed: %v", err)
+	}
+	if v := versionOf(st); v != latest {
+		t.Fatalf("New namespace must get the latest schema, got %d", v)
+	}
+
+	reverted, err := st.RollbackNamespaces(ctx, 1)
+	if err != nil || reverted != latest-1 {
+		t.Fatalf("Expected %d rollbacks, got %d (%v)", latest-1, reverted, err)
+	}
+	st.Close()
+
+	// With manual migrations, reopening leaves the namespace behind
+	st = open(true)
+	defer st.Close()
+	if v := versionOf(st); v != 1 {
+		t.Fatalf("Expected version 1 after reopening, got %d", v)
+	}
+	if _, err := st.RollbackNamespaces(ctx, 0); err == nil {
+		t.Error("Expected error rolling back the initial migration")
+	}
+
+	applied, err := st.MigrateNamespaces(ctx)
+	if err != nil || applied != latest-1 {
+		t.Fatalf("Expected %d migrations, got %d (%v)", latest-1, applied, err)
+	}
+	if v := versionOf(st); v != latest {
+		t.Errorf("Expected version %d after migrating, got %d", latest, v)
+	}
+}
+
+// TestSchemaMigrator_Edges tests stores without namespaces, migrating and
+// rolling back namespaces already at the target, version order, and automatic
+// migrations on reopening
+func TestSchemaMigrator_Edges(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	open := func(manual bool) *SQLiteStore {
+		db, err := sql.Open("sqlite", filepath.Join(dir, "metadata.db"))
+		if err != nil {
+			t.Fatalf("Failed to open metadata database: %v", err)
+		}
+		st, err := New(db, &Config{DataDir: dir, ManualMigrations: manual})
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		return st
+	}
+
+	st := open(true)
+	latest := st.LatestSchemaVersion()
+	if versions, err := st.NamespaceSchemaVersions(ctx); err != nil || len(versions) != 0 {
+		t.Errorf("Expected no versions without namespaces, got %v (%v)", versions, err)
+	}
+
+	// Versions are sorted by namespace
+	for _, id := range []string{"schema-b", "schema-a"} {
+		if err := st.CreateNamespace(ctx, id, "hash-"+id, ""); err != nil {
+			t.Fatalf("CreateNamespace failed: %v", err)
+		}
+	}
+	versions, err := st.NamespaceSchemaVersions(ctx)
+	if err != nil || len(versions) != 2 || versions[0].Namespace != "schema-a" || versions[1].Namespace != "schema-b" {
+		t.Fatalf("Expected versions sorted by namespace, got %v (%v)", versions, err)
+	}
+
+	// Namespaces already at the target are left alone
+	if applied, err := st.MigrateNamespaces(ctx); err != nil || applied != 0 {
+		t.Errorf("Expected no migrations at the latest version, got %d (%v)", applied, err)
+	}
+	if reverted, err := st.RollbackNamespaces(ctx, latest); err != nil || reverted != 0 {
+		t.Errorf("Expected no rollbacks to the latest version, got %d (%v)", reverted, err)
+	}
+	if reverted, err := st.RollbackNamespaces(ctx, latest+1); err != nil || reverted != 0 {
+		t.Errorf("Expected no rollbacks to a future version, got %d (%v)", reverted, err)
+	}
+
+	reverted, err := st.RollbackNamespaces(ctx, 1)
+	if err != nil || reverted != 2*(latest-1) {
+		t.Fatalf("Expected %d rollbacks, got %d (%v)", 2*(latest-1), reverted, err)
+	}
+	st.Close()
+
+	// Without manual migrations, reopening brings every namespace back up
+	st = open(false)
+	defer st.Close()
+	versions, err = st.NamespaceSchemaVersions(ctx)
+	if err != nil {
+		t.Fatalf("NamespaceSchemaVersions failed: %v", err)
+	}
+	for _, v := range versions {
+		if v.Version != latest {
+			t.Errorf("Expected %s at version %d after reopening, got %d", v.Namespace, latest, v.Version)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// NamespaceStorage returns the size of a namespace database, split by
+// category in proportion to the size of each category's messages
+func (s *SQLiteStore) NamespaceStorage(ctx context.Context, namespace string) (*store.StorageUsage, error) {
+	handle, err := s.getNamespaceHandle(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// page_count * page_size is the file size, and also works in memory
+	var pages, pageSize int64
+	if err := handle.db.QueryRowContext(ctx, `PRAGMA page_count`).Scan(&pages); err != nil {
+		return nil, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := handle.db.QueryRowContext(ctx, `PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return nil, fmt.Errorf("failed to read page size: %w", err)
+	}
+
+	rows, err := handle.db.QueryContext(ctx, `
+		SELECT CASE WHEN instr(stream_name, '-') > 0
+		            THEN substr(stream_name, 1, instr(stream_name, '-') - 1)
+		            ELSE stream_name END AS category,
+		       SUM(length(id) + length(stream_name) + length(type) + IFNULL(length(data), 0) + IFNULL(length(metadata), 0) + 24)
+		FROM messages
+		GROUP BY category`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure categories: %w", err)
+	}
+	defer rows.Close()
+
+	weights := make(map[string]int64)
+	for rows.Next() {
+		var category string
+		var size int64
+		if err := rows.Scan(&category, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan category size: %w", err)
+		}
+		weights[category] = size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return store.NewStorageUsage(pages*pageSize, weights), nil
+}
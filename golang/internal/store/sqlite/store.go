@@ -25,6 +25,7 @@ type SQLiteStore struct {
 	namespaces map[string]*namespaceHandle
 	testMode   bool
 	dataDir    string
+	manual     bool // ManualMigrations
 	mu         sync.RWMutex
 }
 
@@ -32,6 +33,11 @@ type SQLiteStore struct {
 type Config struct {
 	TestMode bool
 	DataDir  string
+
+	// ManualMigrations leaves existing namespace databases at their schema
+	// version when opened; MigrateNamespaces upgrades them. New namespace
+	// databases always get the latest schema.
+	ManualMigrations bool
 }
 
 // New creates a new SQLiteStore instance
@@ -56,6 +62,7 @@ func New(metadataDB *sql.DB, config *Config) (*SQLiteStore, error) {
 		namespaces: make(map[string]*namespaceHandle),
 		testMode:   config.TestMode,
 		dataDir:    config.DataDir,
+		manual:     config.ManualMigrations,
 	}
 
 	migrator := migrate.New(metadataDB, "sqlite", migrations.MetadataSQLiteFS)
@@ -136,10 +143,12 @@ func (s *SQLiteStore) getNamespaceHandle(namespace string) (*namespaceHandle, er
 	db.SetMaxOpenConns(1)
 	db.SetMaxIdleConns(1)
 
-	migrator := migrate.New(db, "sqlite", migrations.NamespaceSQLiteFS)
-	if err := migrator.AutoMigrate(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to run namespace migrations: %w", err)
+	if !s.manual || getNamespaceSchemaVersion(context.Background(), db) == 0 {
+		migrator := migrate.New(db, "sqlite", migrations.NamespaceSQLiteFS)
+		if err := migrator.AutoMigrate(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to run namespace migrations: %w", err)
+		}
 	}
 
 	handle := &namespaceHandle{db: db}
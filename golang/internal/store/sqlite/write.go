@@ -34,8 +34,56 @@ func (s *SQLiteStore) WriteMessage(ctx context.Context, namespace, streamName st
 	return executeWriteMessage(ctx, handle.db, streamName, msg)
 }
 
+// WriteMessages writes messages to their streams in one transaction
+func (s *SQLiteStore) WriteMessages(ctx context.Context, namespace string, msgs []*store.Message) ([]*store.WriteResult, error) {
+	handle, err := s.getNamespaceHandle(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range msgs {
+		if msg.ID == "" {
+			id, err := uuid.NewV7()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate UUID: %w", err)
+			}
+			msg.ID = id.String()
+		}
+	}
+
+	// Serialize writes to this namespace
+	handle.writeMu.Lock()
+	defer handle.writeMu.Unlock()
+
+	tx, err := handle.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]*store.WriteResult, 0, len(msgs))
+	for _, msg := range msgs {
+		result, err := executeWriteMessage(ctx, tx, msg.StreamName, msg)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return results, nil
+}
+
+// execer is the part of *sql.DB and *sql.Tx a write uses
+type execer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
 // executeWriteMessage performs the actual write
-func executeWriteMessage(ctx context.Context, db *sql.DB, streamName string, msg *store.Message) (*store.WriteResult, error) {
+func executeWriteMessage(ctx context.Context, db execer, streamName string, msg *store.Message) (*store.WriteResult, error) {
 	if _, err := uuid.Parse(msg.ID); err != nil {
 		return nil, fmt.Errorf("invalid UUID format: %w", err)
 	}
@@ -200,3 +248,126 @@ func (s *SQLiteStore) ClearNamespaceMessages(ctx context.Context, namespace stri
 
 	return deleted, nil
 }
+
+// RedactMessage replaces the data and metadata of the message at position.
+// The old values stay in free database pages until they are reused or the
+// database is vacuumed.
+func (s *SQLiteStore) RedactMessage(ctx context.Context, namespace, streamName string, position int64, data, metadata map[string]interface{}) error {
+	handle, err := s.getNamespaceHandle(namespace)
+	if err != nil {
+		return err
+	}
+
+	var dataJSON, metadataJSON []byte
+	if data != nil {
+		if dataJSON, err = json.Marshal(data); err != nil {
+			return fmt.Errorf("failed to marshal data: %w", err)
+		}
+	}
+	if metadata != nil {
+		if metadataJSON, err = json.Marshal(metadata); err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+	}
+
+	handle.writeMu.Lock()
+	defer handle.writeMu.Unlock()
+
+	result, err := handle.db.ExecContext(ctx,
+		`UPDATE messages SET data = ?, metadata = ? WHERE stream_name = ? AND position = ?`,
+		dataJSON, metadataJSON, streamName, position)
+	if err != nil {
+		return fmt.Errorf("failed to redact message: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrMessageNotFound
+	}
+	return nil
+}
+
+// TruncateStream deletes the messages of a stream below position, always
+// keeping the last one, which the stream version is read from
+func (s *SQLiteStore) TruncateStream(ctx context.Context, namespace, streamName string, position int64) (int64, error) {
+	handle, err := s.getNamespaceHandle(namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	handle.writeMu.Lock()
+	defer handle.writeMu.Unlock()
+
+	result, err := handle.db.ExecContext(ctx,
+		`DELETE FROM messages WHERE stream_name = ? AND position < ?
+		AND position < (SELECT MAX(position) FROM messages WHERE stream_name = ?)`,
+		streamName, position, streamName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to truncate stream: %w", err)
+	}
+
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
+
+// MergeStreams moves the messages of sources into target and renumbers
+// target in global position order. Positions are first set to the negated
+// global position, which is unique, so no step collides with the
+// (stream_name, position) index.
+func (s *SQLiteStore) MergeStreams(ctx context.Context, namespace, target string, sources []string) (int64, error) {
+	handle, err := s.getNamespaceHandle(namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	handle.writeMu.Lock()
+	defer handle.writeMu.Unlock()
+
+	tx, err := handle.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE messages SET position = -global_position WHERE stream_name = ?`, target); err != nil {
+		return 0, fmt.Errorf("failed to merge streams: %w", err)
+	}
+	var moved int64
+	for _, source := range sources {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE messages SET stream_name = ?, position = -global_position WHERE stream_name = ?`, target, source)
+		if err != nil {
+			return 0, fmt.Errorf("failed to merge streams: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		moved += n
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE messages SET position = merged.position
+		FROM (SELECT global_position, ROW_NUMBER() OVER (ORDER BY global_position) - 1 AS position
+			FROM messages WHERE stream_name = ?) AS merged
+		WHERE messages.global_position = merged.global_position`, target); err != nil {
+		return 0, fmt.Errorf("failed to renumber merged stream: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return moved, nil
+}
+
+// CommitsInOrder reports that global positions become visible in order,
+// since writes to a namespace are serialized
+func (s *SQLiteStore) CommitsInOrder(ctx context.Context, namespace string) bool {
+	return true
+}
+
+// PositionGuarantee reports gapless positions: AUTOINCREMENT is rolled back
+// with failed writes, and writes are serialized
+func (s *SQLiteStore) PositionGuarantee(ctx context.Context, namespace string) store.PositionGuarantee {
+	return store.PositionGuarantee{
+		Allocator:     store.AllocatorSequence,
+		Gapless:       true,
+		CommitOrdered: true,
+		Description:   "SQLite AUTOINCREMENT; writes are serialized and failed writes release their positions",
+	}
+}
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -297,6 +298,85 @@ func TestMDB001_5A_T4_WriteMessage_ExpectedVersionConflict(t *testing.T) {
 	}
 }
 
+// Test WriteMessages writes to several streams atomically
+func TestWriteMessages_Atomic(t *testing.T) {
+	store, cleanup := getTestStore(t, true)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := store.CreateNamespace(ctx, "test_ns_wm", "hash_wm", "Test namespace wm"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	defer cleanupNamespace(t, store, "test_ns_wm")
+
+	results, err := store.WriteMessages(ctx, "test_ns_wm", []*storepkg.Message{
+		{StreamName: "order-1", Type: "OrderPlaced", Data: map[string]interface{}{"total": 10}},
+		{StreamName: "orderSummary-1", Type: "OrderPlaced", Data: map[string]interface{}{"total": 10}},
+		{StreamName: "order-1", Type: "OrderPaid", Data: map[string]interface{}{}},
+	})
+	if err != nil {
+		t.Fatalf("WriteMessages failed: %v", err)
+	}
+	if len(results) != 3 || results[0].Position != 0 || results[1].Position != 0 || results[2].Position != 1 {
+		t.Fatalf("unexpected results: %+v %+v %+v", results[0], results[1], results[2])
+	}
+	if results[1].GlobalPosition <= results[0].GlobalPosition {
+		t.Errorf("global positions not increasing: %d, %d", results[0].GlobalPosition, results[1].GlobalPosition)
+	}
+
+	// A conflict on any message writes nothing
+	wrongVersion := int64(0)
+	_, err = store.WriteMessages(ctx, "test_ns_wm", []*storepkg.Message{
+		{StreamName: "orderSummary-1", Type: "OrderShipped", Data: map[string]interface{}{}},
+		{StreamName: "order-1", Type: "OrderShipped", Data: map[string]interface{}{}, ExpectedVersion: &wrongVersion},
+	})
+	if !isVersionConflict(err) {
+		t.Fatalf("Expected version conflict error, got: %v", err)
+	}
+	version, err := store.GetStreamVersion(ctx, "test_ns_wm", "orderSummary-1")
+	if err != nil || version != 0 {
+		t.Errorf("orderSummary-1 version = %d (%v), want 0 after the rolled back write", version, err)
+	}
+}
+
+func TestRedactMessage(t *testing.T) {
+	store, cleanup := getTestStore(t, true)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := store.CreateNamespace(ctx, "test_ns_redact", "hash_redact", "Test namespace redact"); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	defer cleanupNamespace(t, store, "test_ns_redact")
+
+	for _, data := range []map[string]interface{}{{"ssn": "123-45-6789"}, {"ok": true}} {
+		if _, err := store.WriteMessage(ctx, "test_ns_redact", "user-1", &storepkg.Message{
+			Type: "Registered", Data: data, Metadata: map[string]interface{}{"ip": "10.0.0.1"},
+		}); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+
+	tombstone := map[string]interface{}{"$redacted": map[string]interface{}{"reason": "PII"}}
+	if err := store.RedactMessage(ctx, "test_ns_redact", "user-1", 0, tombstone, nil); err != nil {
+		t.Fatalf("RedactMessage failed: %v", err)
+	}
+	if err := store.RedactMessage(ctx, "test_ns_redact", "user-1", 5, tombstone, nil); !errors.Is(err, storepkg.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+
+	msgs, err := store.GetStreamMessages(ctx, "test_ns_redact", "user-1", nil)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d (%v)", len(msgs), err)
+	}
+	if msgs[0].Data["ssn"] != nil || msgs[0].Data["$redacted"] == nil || msgs[0].Metadata != nil || msgs[0].Type != "Registered" {
+		t.Errorf("Unexpected redacted message: %+v", msgs[0])
+	}
+	if msgs[1].Data["ok"] != true || msgs[1].Metadata["ip"] != "10.0.0.1" {
+		t.Errorf("Expected the next message unchanged, got %+v", msgs[1])
+	}
+}
+
 // MDB001_5A_T5: Test WriteMessage serializes JSON correctly
 func TestMDB001_5A_T5_WriteMessage_SerializesJSON(t *testing.T) {
 	store, cleanup := getTestStore(t, true)
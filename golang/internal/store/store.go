@@ -135,6 +135,21 @@ type Store interface {
 	// ListNamespaces returns all namespaces in the store.
 	ListNamespaces(ctx context.Context) ([]*Namespace, error)
 
+	// UpdateNamespaceMetadata replaces the metadata of a namespace.
+	//
+	// Metadata holds per-namespace configuration (e.g. log shipping settings).
+	// Callers should read the current metadata with GetNamespace and write back
+	// the full map.
+	//
+	// Returns ErrNamespaceNotFound if the namespace doesn't exist.
+	UpdateNamespaceMetadata(ctx context.Context, id string, metadata map[string]interface{}) error
+
+	// UpdateNamespaceToken replaces the token hash of a namespace, so the
+	// previous token stops authenticating.
+	//
+	// Returns ErrNamespaceNotFound if the namespace doesn't exist.
+	UpdateNamespaceToken(ctx context.Context, id, tokenHash string) error
+
 	// MigrateNamespaces applies pending schema migrations to all existing namespaces.
 	// Returns the total number of migrations applied across all namespaces.
 	// This should be called on server startup before processing requests.
@@ -203,6 +218,163 @@ type Store interface {
 	Close() error
 }
 
+// IntegrityChecker is implemented by backends that can inspect their own
+// storage for corruption the Store methods would not surface. SQLite
+// implements it; Postgres enforces valid JSON in its jsonb columns.
+type IntegrityChecker interface {
+	// CheckStorage runs the backend's consistency check on a namespace's
+	// storage and returns the problems found (empty when healthy).
+	CheckStorage(ctx context.Context, namespace string) ([]string, error)
+
+	// InvalidJSON returns the positions of messages in a stream whose stored
+	// data or metadata is not valid JSON.
+	InvalidJSON(ctx context.Context, namespace, streamName string) ([]int64, error)
+}
+
+// StorageReporter is implemented by backends that can report the space a
+// namespace occupies (SQLite, Pebble, Postgres, TimescaleDB). Reports scan
+// the namespace's messages, so they are meant for occasional use.
+type StorageReporter interface {
+	// NamespaceStorage returns the bytes used by a namespace and their
+	// estimated split by category.
+	NamespaceStorage(ctx context.Context, namespace string) (*StorageUsage, error)
+}
+
+// CommitOrderer is implemented by backends that serialize the writes of a
+// namespace (SQLite, Pebble), so a message is never visible before one with a
+// lower global position. On other backends a reader may see a message while a
+// concurrent write with a lower global position is still being committed.
+type CommitOrderer interface {
+	// CommitsInOrder reports whether the namespace's global positions become
+	// visible in order.
+	CommitsInOrder(ctx context.Context, namespace string) bool
+}
+
+// NamespaceSwapper is implemented by backends that can exchange the messages
+// of two namespaces in one step (SQLite, Pebble, Postgres, TimescaleDB). It
+// is used to activate staged imports, so readers see either none or all of
+// the imported messages.
+type NamespaceSwapper interface {
+	// SwapNamespaceMessages exchanges the messages of namespaces a and b.
+	// Tokens and metadata stay with their namespace.
+	SwapNamespaceMessages(ctx context.Context, a, b string) error
+}
+
+// StreamTruncater is implemented by backends that can delete the oldest
+// messages of a stream (SQLite, Pebble, Postgres, TimescaleDB). It is used to
+// compact streams where only recent messages matter, such as consumer
+// position streams.
+type StreamTruncater interface {
+	// TruncateStream deletes the messages of a stream below position and
+	// returns the number deleted. The stream version is unchanged, so later
+	// writes continue from it.
+	TruncateStream(ctx context.Context, namespace, streamName string, position int64) (int64, error)
+}
+
+// AtomicWriter is implemented by backends that can write messages to
+// several streams of a namespace in one transaction (SQLite, Pebble,
+// Postgres, TimescaleDB). It is used to append derived events together with
+// the write they are derived from.
+type AtomicWriter interface {
+	// WriteMessages writes each message to its StreamName, in order, and
+	// returns their results. Either all messages are written or none; an
+	// ExpectedVersion conflict on any message fails the whole write.
+	WriteMessages(ctx context.Context, namespace string, msgs []*Message) ([]*WriteResult, error)
+}
+
+// MessageRedactor is implemented by backends that can replace the data of a
+// stored message in place (SQLite, Pebble, Postgres, TimescaleDB). It is used
+// to remove sensitive payloads written by mistake without changing stream
+// positions.
+type MessageRedactor interface {
+	// RedactMessage replaces the data and metadata of the message at position
+	// in streamName. Its ID, type, positions and time are kept. It returns
+	// ErrMessageNotFound when the stream has no message at position.
+	RedactMessage(ctx context.Context, namespace, streamName string, position int64, data, metadata map[string]interface{}) error
+}
+
+// StreamMerger is implemented by backends that can move the messages of
+// several streams into one (SQLite, Pebble, Postgres, TimescaleDB). It is
+// used to merge streams whose names differ only by case or whitespace.
+type StreamMerger interface {
+	// MergeStreams moves the messages of sources into target in one
+	// transaction and returns the number moved. Messages keep their IDs,
+	// global positions, data and time; the target is renumbered from 0 in
+	// global position order, so its version becomes its message count - 1.
+	MergeStreams(ctx context.Context, namespace, target string, sources []string) (int64, error)
+}
+
+// ConsumerPositionStore is implemented by backends that keep the last
+// global position each consumer of a namespace has processed (SQLite,
+// Pebble, Postgres, TimescaleDB). Subscribers checkpoint with it instead of
+// writing position streams of their own.
+type ConsumerPositionStore interface {
+	// SetConsumerPosition records globalPosition as the last position
+	// consumer has processed, replacing the previous one.
+	SetConsumerPosition(ctx context.Context, namespace, consumer string, globalPosition int64) (*ConsumerPosition, error)
+
+	// GetConsumerPosition returns the last recorded position of consumer,
+	// or ErrConsumerPositionNotFound when none was set.
+	GetConsumerPosition(ctx context.Context, namespace, consumer string) (*ConsumerPosition, error)
+}
+
+// ConsumerPosition is the checkpoint of one consumer
+type ConsumerPosition struct {
+	Consumer       string
+	GlobalPosition int64
+	Updated        time.Time // When the position was last set (UTC)
+}
+
+// StorageUsage is the space used by a namespace
+type StorageUsage struct {
+	Bytes      int64            // Bytes used, including indexes
+	Categories map[string]int64 // Estimated share of Bytes per category
+}
+
+// NewStorageUsage splits total bytes over categories in proportion to their
+// weights (record sizes or counts). A total of 0, e.g. for data not yet
+// flushed to disk, is replaced by the sum of the weights.
+func NewStorageUsage(total int64, weights map[string]int64) *StorageUsage {
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+	if total <= 0 {
+		total = sum
+	}
+
+	usage := &StorageUsage{Bytes: total, Categories: make(map[string]int64, len(weights))}
+	for category, w := range weights {
+		if sum > 0 {
+			usage.Categories[category] = int64(float64(total) * float64(w) / float64(sum))
+		}
+	}
+	return usage
+}
+
+// SchemaMigrator is implemented by backends with versioned namespace schemas
+// (Postgres, TimescaleDB, SQLite). MigrateNamespaces upgrades every namespace
+// to LatestSchemaVersion; RollbackNamespaces reverts them with the down
+// migrations.
+type SchemaMigrator interface {
+	// LatestSchemaVersion returns the namespace schema version of this build.
+	LatestSchemaVersion() int
+
+	// NamespaceSchemaVersions returns the current schema version of each
+	// namespace, sorted by namespace ID.
+	NamespaceSchemaVersions(ctx context.Context) ([]NamespaceSchemaVersion, error)
+
+	// RollbackNamespaces reverts every namespace above version down to it and
+	// returns the number of down migrations applied.
+	RollbackNamespaces(ctx context.Context, version int) (int, error)
+}
+
+// NamespaceSchemaVersion is the schema version of one namespace
+type NamespaceSchemaVersion struct {
+	Namespace string
+	Version   int
+}
+
 // Message represents a message in the message store
 type Message struct {
 	ID             string                 // UUID v7 (RFC 9562) - time-ordered UUID
@@ -240,13 +412,17 @@ type GetOpts struct {
 
 // CategoryOpts specifies options for getting category messages
 type CategoryOpts struct {
-	Position       int64   // Global position for category (default: 1)
-	GlobalPosition *int64  // Alternative (same as Position for categories)
-	BatchSize      int64   // Number of messages (default: 1000, -1 for unlimited)
-	Correlation    *string // Filter by metadata.correlationStreamName category
-	ConsumerMember *int64  // Consumer group member number (0-indexed)
-	ConsumerSize   *int64  // Consumer group total size
-	Condition      *string // DEPRECATED: SQL condition (do not implement - security risk)
+	Position       int64       // Global position for category (default: 1)
+	GlobalPosition *int64      // Alternative (same as Position for categories)
+	BatchSize      int64       // Number of messages (default: 1000, -1 for unlimited)
+	Correlation    *string     // Filter by metadata.correlationStreamName category
+	ConsumerMember *int64      // Consumer group member number (0-indexed)
+	ConsumerSize   *int64      // Consumer group total size
+	Partitioner    Partitioner // Consumer group partitioner (default: md5)
+	OrderingKey    bool        // Partition consumer groups by metadata.orderingKey (see PartitionKey)
+	Filter         *Filter     // Only messages matching an EQL expression (see ParseFilter)
+	Sample         float64     // Deterministic fraction of messages by ID hash, 0 for all (see InSample)
+	Condition      *string     // DEPRECATED: SQL condition (do not implement - security risk)
 }
 
 // Namespace represents a namespace in the message store
@@ -264,16 +440,41 @@ type Namespace struct {
 
 // ListStreamsOpts specifies options for listing streams in a namespace
 type ListStreamsOpts struct {
-	Prefix string // filter by stream name prefix (empty = no filter)
-	Limit  int64  // max results (default 100, max 1000)
-	Cursor string // pagination: return streams after this name (exclusive)
+	Prefix        string    // filter by stream name prefix (empty = no filter)
+	Limit         int64     // max results (default 100, max 1000)
+	Cursor        string    // pagination: return streams after this name (exclusive)
+	InactiveSince time.Time // only streams last written before this (zero = no filter)
+	OlderThan     time.Time // only streams first written before this (zero = no filter)
 }
 
 // StreamInfo holds summary information about a stream
 type StreamInfo struct {
-	StreamName   string
-	Version      int64
-	LastActivity time.Time
+	StreamName    string
+	Version       int64
+	FirstActivity time.Time
+	LastActivity  time.Time
+}
+
+// WritesPerHour returns the stream's average write rate from its first write
+// until now, so streams that stopped being written cool down over time
+func (si *StreamInfo) WritesPerHour(now time.Time) float64 {
+	hours := now.Sub(si.FirstActivity).Hours()
+	if hours < 1.0/60 {
+		// Streams younger than a minute are rated over a minute
+		hours = 1.0 / 60
+	}
+	return float64(si.Version+1) / hours
+}
+
+// Matches reports whether the stream passes the activity filters of opts
+func (opts *ListStreamsOpts) Matches(si *StreamInfo) bool {
+	if !opts.InactiveSince.IsZero() && !si.LastActivity.Before(opts.InactiveSince) {
+		return false
+	}
+	if !opts.OlderThan.IsZero() && !si.FirstActivity.Before(opts.OlderThan) {
+		return false
+	}
+	return true
 }
 
 // CategoryInfo holds summary information about a category
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// DefaultMaxStreamNameLength is the default limit on stream name length in bytes
+	DefaultMaxStreamNameLength = 255
+
+	// ReservedStreamPrefix marks system streams; clients cannot write to them
+	ReservedStreamPrefix = "$"
+)
+
+// StreamNamePolicy defines which stream names writes accept. A nil policy
+// accepts every name.
+type StreamNamePolicy struct {
+	MaxLength     int  // Maximum length in bytes, 0 for no limit
+	AllowReserved bool // Accept names starting with ReservedStreamPrefix
+}
+
+// DefaultStreamNamePolicy returns the policy applied to client writes
+func DefaultStreamNamePolicy() *StreamNamePolicy {
+	return &StreamNamePolicy{MaxLength: DefaultMaxStreamNameLength}
+}
+
+// StreamNameError describes why a stream name was rejected
+type StreamNameError struct {
+	StreamName string
+	Reason     string
+}
+
+func (e *StreamNameError) Error() string {
+	return fmt.Sprintf("invalid stream name %q: %s", e.StreamName, e.Reason)
+}
+
+func (e *StreamNameError) Is(target error) bool {
+	return target == ErrInvalidStreamName
+}
+
+// IsInvalidStreamName checks if an error is an invalid stream name error
+func IsInvalidStreamName(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrInvalidStreamName)
+}
+
+// Validate checks a stream name against the policy. The rules keep Category,
+// ID and CardinalID unambiguous:
+//
+//	"account-123"     valid
+//	"account-123+456" valid (compound ID)
+//	"-123"            empty category
+//	"account-"        empty ID
+//	"acc+ount-123"    '+' outside the ID
+//	"account-123+"    empty compound ID part
+func (p *StreamNamePolicy) Validate(streamName string) error {
+	if p == nil {
+		return nil
+	}
+
+	invalid := func(format string, args ...interface{}) error {
+		return &StreamNameError{StreamName: streamName, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if streamName == "" {
+		return invalid("must not be empty")
+	}
+	if p.MaxLength > 0 && len(streamName) > p.MaxLength {
+		return invalid("is %d bytes, longer than the limit of %d", len(streamName), p.MaxLength)
+	}
+	if !utf8.ValidString(streamName) {
+		return invalid("is not valid UTF-8")
+	}
+	if !norm.NFC.IsNormalString(streamName) {
+		return invalid("is not in Unicode normalization form C (use %q)", norm.NFC.String(streamName))
+	}
+	for i, r := range streamName {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return invalid("contains whitespace or a control character at byte %d", i)
+		}
+	}
+	if !p.AllowReserved && strings.HasPrefix(streamName, ReservedStreamPrefix) {
+		return invalid("the %q prefix is reserved for system streams", ReservedStreamPrefix)
+	}
+
+	category := Category(streamName)
+	if category == "" {
+		return invalid("category before '-' is empty")
+	}
+	if strings.ContainsRune(category, '+') {
+		return invalid("category contains '+', which only separates compound IDs")
+	}
+	if IsCategory(streamName) {
+		return nil
+	}
+
+	id := ID(streamName)
+	if id == "" {
+		return invalid("ID after '-' is empty")
+	}
+	for _, part := range strings.Split(id, "+") {
+		if part == "" {
+			return invalid("compound ID has an empty part")
+		}
+	}
+	return nil
+}
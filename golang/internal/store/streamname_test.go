@@ -0,0 +1,61 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamNamePolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		streamName string
+		valid      bool
+	}{
+		{"simple stream", "account-123", true},
+		{"compound ID", "account-123+456", true},
+		{"category only", "account", true},
+		{"multi-dash", "account-prefix-123", true},
+		{"category type", "account:command-123", true},
+		{"unicode NFC", "caf\u00e9-1", true},
+		{"empty", "", false},
+		{"too long", "account-" + strings.Repeat("x", DefaultMaxStreamNameLength), false},
+		{"invalid UTF-8", "account-\xff", false},
+		{"not NFC", "cafe\u0301-1", false},
+		{"space", "account-1 2", false},
+		{"control character", "account-1\n", false},
+		{"reserved prefix", "$system-1", false},
+		{"empty category", "-123", false},
+		{"empty ID", "account-", false},
+		{"plus in category", "acc+ount-123", false},
+		{"empty compound part", "account-123+", false},
+		{"double plus", "account-1++2", false},
+	}
+
+	policy := DefaultStreamNamePolicy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.streamName)
+			if tt.valid && err != nil {
+				t.Errorf("Validate(%q) = %v, expected valid", tt.streamName, err)
+			}
+			if !tt.valid && !IsInvalidStreamName(err) {
+				t.Errorf("Validate(%q) = %v, expected an invalid stream name error", tt.streamName, err)
+			}
+		})
+	}
+}
+
+func TestStreamNamePolicy_Options(t *testing.T) {
+	var off *StreamNamePolicy
+	if err := off.Validate("-"); err != nil {
+		t.Errorf("Expected a nil policy to accept any name, got %v", err)
+	}
+
+	system := &StreamNamePolicy{AllowReserved: true}
+	if err := system.Validate("$system-1"); err != nil {
+		t.Errorf("Expected reserved names to be allowed, got %v", err)
+	}
+	if err := system.Validate("account-" + strings.Repeat("x", 1000)); err != nil {
+		t.Errorf("Expected no length limit, got %v", err)
+	}
+}
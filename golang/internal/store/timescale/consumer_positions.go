@@ -0,0 +1,54 @@
+package timescale
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// SetConsumerPosition records the last global position consumer has processed
+func (s *TimescaleStore) SetConsumerPosition(ctx context.Context, namespace, consumer string, globalPosition int64) (*store.ConsumerPosition, error) {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated time.Time
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf(
+		`INSERT INTO "%s".consumer_positions (consumer, global_position, updated) VALUES ($1, $2, now())
+		ON CONFLICT (consumer) DO UPDATE SET global_position = excluded.global_position, updated = excluded.updated
+		RETURNING updated`,
+		schemaName,
+	), consumer, globalPosition).Scan(&updated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set consumer position: %w", err)
+	}
+
+	return &store.ConsumerPosition{Consumer: consumer, GlobalPosition: globalPosition, Updated: updated.UTC()}, nil
+}
+
+// GetConsumerPosition returns the last recorded position of consumer
+func (s *TimescaleStore) GetConsumerPosition(ctx context.Context, namespace, consumer string) (*store.ConsumerPosition, error) {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	position := &store.ConsumerPosition{Consumer: consumer}
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf(
+		`SELECT global_position, updated FROM "%s".consumer_positions WHERE consumer = $1`,
+		schemaName,
+	), consumer).Scan(&position.GlobalPosition, &position.Updated)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrConsumerPositionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumer position: %w", err)
+	}
+
+	position.Updated = position.Updated.UTC()
+	return position, nil
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/eventodb/eventodb/internal/migrate"
 	"github.com/eventodb/eventodb/internal/store"
 	"github.com/eventodb/eventodb/migrations"
 )
@@ -141,6 +142,45 @@ func (s *TimescaleStore) GetNamespace(ctx context.Context, id string) (*store.Na
 	return &ns, nil
 }
 
+// UpdateNamespaceMetadata replaces a namespace's metadata
+func (s *TimescaleStore) UpdateNamespaceMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	// Keep the backend marker set at creation
+	if _, ok := metadata["backend"]; !ok {
+		metadata["backend"] = "timescaledb"
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to serialize metadata: %w", err)
+	}
+
+	query := `UPDATE eventodb_store.namespaces SET metadata = $1 WHERE id = $2`
+	result, err := s.db.ExecContext(ctx, query, string(metadataJSON), id)
+	if err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrNamespaceNotFound
+	}
+	return nil
+}
+
+// UpdateNamespaceToken replaces a namespace's token hash
+func (s *TimescaleStore) UpdateNamespaceToken(ctx context.Context, id, tokenHash string) error {
+	query := `UPDATE eventodb_store.namespaces SET token_hash = $1 WHERE id = $2`
+	result, err := s.db.ExecContext(ctx, query, tokenHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrNamespaceNotFound
+	}
+	return nil
+}
+
 // ListNamespaces retrieves all namespaces
 func (s *TimescaleStore) ListNamespaces(ctx context.Context) ([]*store.Namespace, error) {
 	query := `
@@ -206,7 +246,7 @@ func applyTimescaleMigrations(ctx context.Context, tx *sql.Tx, schemaName string
 	}
 
 	for _, entry := range migrationFiles {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") || migrate.IsDownFile(entry.Name()) {
 			continue
 		}
 
@@ -280,7 +320,7 @@ func (s *TimescaleStore) migrateNamespaceSchema(ctx context.Context, schemaName
 
 	applied := 0
 	for _, entry := range migrationFiles {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") || migrate.IsDownFile(entry.Name()) {
 			continue
 		}
 
@@ -331,3 +371,52 @@ func extractVersionFromFilename(filename string) int {
 	}
 	return version
 }
+
+// SwapNamespaceMessages exchanges the schemas of two namespaces in the
+// registry, so one transaction moves all of their messages
+func (s *TimescaleStore) SwapNamespaceMessages(ctx context.Context, a, b string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	schemas := make(map[string]string, 2)
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, schema_name FROM eventodb_store.namespaces WHERE id IN ($1, $2) FOR UPDATE`, a, b)
+	if err != nil {
+		return fmt.Errorf("failed to read schema names: %w", err)
+	}
+	for rows.Next() {
+		var id, schemaName string
+		if err := rows.Scan(&id, &schemaName); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read schema names: %w", err)
+		}
+		schemas[id] = schemaName
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read schema names: %w", err)
+	}
+	if len(schemas) != 2 {
+		return store.ErrNamespaceNotFound
+	}
+
+	// Schema names are unique, so a moves aside first
+	for _, step := range []struct{ id, schemaName string }{
+		{a, schemas[a] + ":swap"},
+		{b, schemas[a]},
+		{a, schemas[b]},
+	} {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE eventodb_store.namespaces SET schema_name = $2 WHERE id = $1`, step.id, step.schemaName); err != nil {
+			return fmt.Errorf("failed to swap schema names: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
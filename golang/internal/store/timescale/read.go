@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/eventodb/eventodb/internal/store"
 	"github.com/google/uuid"
@@ -42,7 +43,7 @@ func (s *TimescaleStore) GetStreamMessages(ctx context.Context, namespace, strea
 		}
 		defer rows.Close()
 
-		return s.scanMessages(rows, opts.BatchSize)
+		return s.scanMessages(ctx, rows, opts.BatchSize)
 	}
 
 	// 4. Call get_stream_messages stored procedure for position-based queries
@@ -65,11 +66,18 @@ func (s *TimescaleStore) GetStreamMessages(ctx context.Context, namespace, strea
 	defer rows.Close()
 
 	// 5. Parse results with capacity hint
-	return s.scanMessages(rows, opts.BatchSize)
+	return s.scanMessages(ctx, rows, opts.BatchSize)
 }
 
 // GetCategoryMessages retrieves messages from a category with consumer group support
 func (s *TimescaleStore) GetCategoryMessages(ctx context.Context, namespace, categoryName string, opts *store.CategoryOpts) ([]*store.Message, error) {
+	// Ordering key groups filter on metadata, after reading
+	if opts != nil && opts.OrderingKey && opts.ConsumerMember != nil && opts.ConsumerSize != nil {
+		return store.GetOrderingKeyMessages(opts, func(opts *store.CategoryOpts) ([]*store.Message, error) {
+			return s.GetCategoryMessages(ctx, namespace, categoryName, opts)
+		})
+	}
+
 	// 1. Get schema name for namespace
 	schemaName, err := s.getSchemaName(namespace)
 	if err != nil {
@@ -87,10 +95,24 @@ func (s *TimescaleStore) GetCategoryMessages(ctx context.Context, namespace, cat
 		position = *opts.GlobalPosition
 	}
 
-	// 4. Call get_category_messages stored procedure
+	// 4. Filtered and sampled reads query the messages table directly, as
+	// the stored procedures could only run the compiled filter as dynamic SQL
+	if opts.Filter != nil || store.Sampled(opts.Sample) {
+		return s.getFilteredCategoryMessages(ctx, schemaName, categoryName, position, opts)
+	}
+
+	// 5. Call get_category_messages stored procedure. Alternative consumer
+	// group partitioners use the variant added by migration 003, which takes
+	// the partitioner in place of the deprecated condition.
+	function := "get_category_messages"
+	var lastArg interface{} // condition is deprecated
+	if opts.Partitioner != "" && opts.Partitioner != store.PartitionerMD5 {
+		function = "get_category_messages_partitioned"
+		lastArg = string(opts.Partitioner)
+	}
 	query := fmt.Sprintf(
-		`SELECT * FROM "%s".get_category_messages($1, $2, $3, $4, $5, $6, $7)`,
-		schemaName,
+		`SELECT * FROM "%s".%s($1, $2, $3, $4, $5, $6, $7)`,
+		schemaName, function,
 	)
 
 	rows, err := s.db.QueryContext(
@@ -102,15 +124,71 @@ func (s *TimescaleStore) GetCategoryMessages(ctx context.Context, namespace, cat
 		opts.Correlation,
 		opts.ConsumerMember,
 		opts.ConsumerSize,
-		nil, // condition is deprecated
+		lastArg,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query category messages: %w", err)
 	}
 	defer rows.Close()
 
-	// 5. Parse results with capacity hint
-	return s.scanMessages(rows, opts.BatchSize)
+	// 6. Parse results with capacity hint
+	return s.scanMessages(ctx, rows, opts.BatchSize)
+}
+
+// getFilteredCategoryMessages reads a category with an EQL filter or a
+// sample. The category, correlation and consumer group conditions are those
+// of get_category_messages_partitioned, so filtered and unfiltered reads of
+// a consumer group member see the same streams. A sample compares hash_64 of
+// the message ID with the bound InSample uses.
+func (s *TimescaleStore) getFilteredCategoryMessages(ctx context.Context, schemaName, categoryName string, position int64, opts *store.CategoryOpts) ([]*store.Message, error) {
+	conditions := []string{"global_position >= $1"}
+	args := []interface{}{position}
+	if categoryName != "" {
+		args = append(args, categoryName)
+		conditions = append(conditions, fmt.Sprintf(`"%s".category(stream_name) = $%d`, schemaName, len(args)))
+	}
+	if opts.Correlation != nil {
+		args = append(args, *opts.Correlation)
+		conditions = append(conditions, fmt.Sprintf(`"%s".category(metadata->>'correlationStreamName') = $%d`, schemaName, len(args)))
+	}
+	if opts.ConsumerMember != nil && opts.ConsumerSize != nil {
+		partitioner := opts.Partitioner
+		if partitioner == "" {
+			partitioner = store.PartitionerMD5
+		}
+		args = append(args, *opts.ConsumerSize, string(partitioner), *opts.ConsumerMember)
+		conditions = append(conditions, fmt.Sprintf(`"%s".consumer_group_member(stream_name, $%d, $%d) = $%d`,
+			schemaName, len(args)-2, len(args)-1, len(args)))
+	}
+	if store.Sampled(opts.Sample) {
+		args = append(args, store.SampleThreshold(opts.Sample))
+		conditions = append(conditions, fmt.Sprintf(`"%s".hash_64(id::text) < $%d`, schemaName, len(args)))
+	}
+	if opts.Filter != nil {
+		var filter string
+		filter, args = opts.Filter.SQL(store.DialectPostgres, args)
+		conditions = append(conditions, filter)
+	}
+
+	query := fmt.Sprintf(
+		`SELECT id, stream_name, type, "position", global_position, data, metadata, "time"
+		FROM "%s".messages
+		WHERE %s
+		ORDER BY global_position ASC`,
+		schemaName, strings.Join(conditions, " AND "),
+	)
+	if opts.BatchSize > 0 {
+		args = append(args, opts.BatchSize)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category messages: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanMessages(ctx, rows, opts.BatchSize)
 }
 
 // GetLastStreamMessage retrieves the last message from a stream
@@ -139,7 +217,7 @@ func (s *TimescaleStore) GetLastStreamMessage(ctx context.Context, namespace, st
 	defer rows.Close()
 
 	// 3. Parse results with capacity hint (expect 1 message)
-	messages, err := s.scanMessages(rows, 1)
+	messages, err := s.scanMessages(ctx, rows, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -174,6 +252,9 @@ func (s *TimescaleStore) GetStreamVersion(ctx context.Context, namespace, stream
 	return version, nil
 }
 
+// listStreamsTimeFormat formats ListStreams activity filters as timestamp literals
+const listStreamsTimeFormat = "2006-01-02 15:04:05.999999"
+
 // ListStreams returns streams in a namespace with optional prefix filtering and pagination.
 func (s *TimescaleStore) ListStreams(ctx context.Context, namespace string, opts *store.ListStreamsOpts) ([]*store.StreamInfo, error) {
 	schemaName, err := s.getSchemaName(namespace)
@@ -192,16 +273,27 @@ func (s *TimescaleStore) ListStreams(ctx context.Context, namespace string, opts
 		limit = 1000
 	}
 
+	// Activity filters compare UTC timestamps; '' disables them
+	var inactiveSince, olderThan string
+	if !opts.InactiveSince.IsZero() {
+		inactiveSince = opts.InactiveSince.UTC().Format(listStreamsTimeFormat)
+	}
+	if !opts.OlderThan.IsZero() {
+		olderThan = opts.OlderThan.UTC().Format(listStreamsTimeFormat)
+	}
+
 	query := fmt.Sprintf(`
-		SELECT stream_name, MAX(position) AS version, MAX(time) AS last_activity
+		SELECT stream_name, MAX(position) AS version, MIN(time) AS first_activity, MAX(time) AS last_activity
 		FROM "%s".messages
 		WHERE ($1 = '' OR stream_name LIKE $1 || '%%')
 		  AND ($2 = '' OR stream_name > $2)
 		GROUP BY stream_name
+		HAVING ($4 = '' OR MAX(time) < NULLIF($4, '')::timestamp)
+		   AND ($5 = '' OR MIN(time) < NULLIF($5, '')::timestamp)
 		ORDER BY stream_name ASC
 		LIMIT $3`, schemaName)
 
-	rows, err := s.db.QueryContext(ctx, query, opts.Prefix, opts.Cursor, limit)
+	rows, err := s.db.QueryContext(ctx, query, opts.Prefix, opts.Cursor, limit, inactiveSince, olderThan)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list streams: %w", err)
 	}
@@ -210,10 +302,13 @@ func (s *TimescaleStore) ListStreams(ctx context.Context, namespace string, opts
 	var results []*store.StreamInfo
 	for rows.Next() {
 		var si store.StreamInfo
-		var lastActivity sql.NullTime
-		if err := rows.Scan(&si.StreamName, &si.Version, &lastActivity); err != nil {
+		var firstActivity, lastActivity sql.NullTime
+		if err := rows.Scan(&si.StreamName, &si.Version, &firstActivity, &lastActivity); err != nil {
 			return nil, fmt.Errorf("failed to scan stream info: %w", err)
 		}
+		if firstActivity.Valid {
+			si.FirstActivity = firstActivity.Time.UTC()
+		}
 		if lastActivity.Valid {
 			si.LastActivity = lastActivity.Time.UTC()
 		}
@@ -261,7 +356,7 @@ func (s *TimescaleStore) ListCategories(ctx context.Context, namespace string) (
 }
 
 // scanMessages is a helper function to scan rows into Message structs
-func (s *TimescaleStore) scanMessages(rows *sql.Rows, capacityHint int64) ([]*store.Message, error) {
+func (s *TimescaleStore) scanMessages(ctx context.Context, rows *sql.Rows, capacityHint int64) ([]*store.Message, error) {
 	// Pre-allocate slice with capacity hint to reduce allocations
 	capacity := int(capacityHint)
 	if capacity <= 0 || capacity > 10000 {
@@ -294,6 +389,9 @@ func (s *TimescaleStore) scanMessages(rows *sql.Rows, capacityHint int64) ([]*st
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan message row: %w", err)
 		}
+		if err := store.MeterRead(ctx, 36+len(streamName)+len(msgType)+len(dataJSON)+len(metadataJSON)); err != nil {
+			return nil, err
+		}
 
 		// Parse JSON data
 		var data map[string]interface{}
@@ -0,0 +1,52 @@
+package timescale
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// NamespaceStorage returns the size of a namespace schema, including the
+// chunks of its messages hypertable, split by category in proportion to the
+// size of each category's rows
+func (s *TimescaleStore) NamespaceStorage(ctx context.Context, namespace string) (*store.StorageUsage, error) {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	// The messages hypertable with its chunks, plus the other tables of the schema
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(hypertable_size('"%s".messages'), 0) + COALESCE((
+			SELECT SUM(pg_total_relation_size(c.oid))
+			FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relkind = 'r' AND c.relname <> 'messages'), 0)`, schemaName), schemaName).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure namespace schema: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT "%[1]s".category(m.stream_name), SUM(pg_column_size(m.*))
+		FROM "%[1]s".messages m
+		GROUP BY 1`, schemaName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure categories: %w", err)
+	}
+	defer rows.Close()
+
+	weights := make(map[string]int64)
+	for rows.Next() {
+		var category string
+		var size int64
+		if err := rows.Scan(&category, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan category size: %w", err)
+		}
+		weights[category] = size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return store.NewStorageUsage(total, weights), nil
+}
@@ -3,6 +3,7 @@ package timescale
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/eventodb/eventodb/internal/store"
@@ -96,6 +97,73 @@ func (s *TimescaleStore) WriteMessage(ctx context.Context, namespace, streamName
 	}, nil
 }
 
+// WriteMessages writes messages to their streams in one transaction
+func (s *TimescaleStore) WriteMessages(ctx context.Context, namespace string, msgs []*store.Message) ([]*store.WriteResult, error) {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	writeQuery := fmt.Sprintf(`SELECT "%s".write_message($1, $2, $3, $4::jsonb, $5::jsonb, $6)`, schemaName)
+	globalQuery := fmt.Sprintf(`SELECT global_position FROM "%s".messages WHERE stream_name = $1 AND position = $2`, schemaName)
+
+	results := make([]*store.WriteResult, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.ID == "" {
+			id, err := uuid.NewV7()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate UUID: %w", err)
+			}
+			msg.ID = id.String()
+		}
+		if _, err := uuid.Parse(msg.ID); err != nil {
+			return nil, fmt.Errorf("invalid UUID format: %w", err)
+		}
+
+		var dataParam, metadataParam interface{}
+		if msg.Data != nil {
+			dataJSON, err := json.Marshal(msg.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal data: %w", err)
+			}
+			dataParam = string(dataJSON)
+		}
+		if msg.Metadata != nil {
+			metadataJSON, err := json.Marshal(msg.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+			}
+			metadataParam = string(metadataJSON)
+		}
+
+		var position, globalPosition int64
+		err := tx.QueryRowContext(ctx, writeQuery,
+			msg.ID, msg.StreamName, msg.Type, dataParam, metadataParam, msg.ExpectedVersion,
+		).Scan(&position)
+		if err != nil {
+			if strings.Contains(err.Error(), "Wrong expected version") {
+				return nil, store.ErrVersionConflict
+			}
+			return nil, fmt.Errorf("failed to write message: %w", err)
+		}
+		if err := tx.QueryRowContext(ctx, globalQuery, msg.StreamName, position).Scan(&globalPosition); err != nil {
+			return nil, fmt.Errorf("failed to get global position: %w", err)
+		}
+		results = append(results, &store.WriteResult{Position: position, GlobalPosition: globalPosition})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return results, nil
+}
+
 // ImportBatch writes messages with explicit positions (for import/restore)
 // All messages in batch are inserted in a single transaction
 func (s *TimescaleStore) ImportBatch(ctx context.Context, namespace string, messages []*store.Message) error {
@@ -233,3 +301,176 @@ func (s *TimescaleStore) ClearNamespaceMessages(ctx context.Context, namespace s
 
 	return count, nil
 }
+
+// RedactMessage replaces the data and metadata of the message at position.
+// Rows in compressed chunks are updated by TimescaleDB 2.11 and later. The
+// old row version stays on disk until the chunk is vacuumed.
+func (s *TimescaleStore) RedactMessage(ctx context.Context, namespace, streamName string, position int64, data, metadata map[string]interface{}) error {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return err
+	}
+
+	var dataParam, metadataParam interface{}
+	if data != nil {
+		dataJSON, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal data: %w", err)
+		}
+		dataParam = string(dataJSON)
+	}
+	if metadata != nil {
+		metadataJSON, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		metadataParam = string(metadataJSON)
+	}
+
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		`UPDATE "%s".messages SET data = $3::jsonb, metadata = $4::jsonb WHERE stream_name = $1 AND "position" = $2`,
+		schemaName,
+	), streamName, position, dataParam, metadataParam)
+	if err != nil {
+		return fmt.Errorf("failed to redact message: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrMessageNotFound
+	}
+	return nil
+}
+
+// MergeStreams moves the messages of sources into target and renumbers
+// target in global position order, holding the category locks write_message
+// takes so no write interleaves. Positions are first set to the negated
+// global position, which is unique, so no step collides with the stream
+// position index. Rows in
+// compressed chunks are updated by TimescaleDB 2.11 and later.
+func (s *TimescaleStore) MergeStreams(ctx context.Context, namespace, target string, sources []string) (int64, error) {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock categories in a fixed order, so concurrent merges cannot deadlock
+	streams := append([]string{target}, sources...)
+	sort.Slice(streams, func(i, j int) bool {
+		return store.Category(streams[i]) < store.Category(streams[j])
+	})
+	for _, stream := range streams {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`SELECT "%s".acquire_lock($1)`, schemaName), stream); err != nil {
+			return 0, fmt.Errorf("failed to lock stream: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+		`UPDATE "%s".messages SET "position" = -global_position WHERE stream_name = $1`,
+		schemaName,
+	), target); err != nil {
+		return 0, fmt.Errorf("failed to merge streams: %w", err)
+	}
+	var moved int64
+	for _, source := range sources {
+		result, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`UPDATE "%s".messages SET stream_name = $1, "position" = -global_position WHERE stream_name = $2`,
+			schemaName,
+		), target, source)
+		if err != nil {
+			return 0, fmt.Errorf("failed to merge streams: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		moved += n
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+		`UPDATE "%[1]s".messages AS m SET "position" = merged."position"
+		FROM (SELECT global_position, ROW_NUMBER() OVER (ORDER BY global_position) - 1 AS "position"
+			FROM "%[1]s".messages WHERE stream_name = $1) AS merged
+		WHERE m.stream_name = $1 AND m.global_position = merged.global_position`,
+		schemaName,
+	), target); err != nil {
+		return 0, fmt.Errorf("failed to renumber merged stream: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return moved, nil
+}
+
+// TruncateStream deletes the messages of a stream below position, always
+// keeping the last one, which the stream version is read from. Rows in
+// compressed chunks are deleted by TimescaleDB 2.11 and later.
+func (s *TimescaleStore) TruncateStream(ctx context.Context, namespace, streamName string, position int64) (int64, error) {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		`DELETE FROM "%[1]s".messages WHERE stream_name = $1 AND "position" < $2
+		AND "position" < (SELECT MAX("position") FROM "%[1]s".messages WHERE stream_name = $1)`,
+		schemaName,
+	), streamName, position)
+	if err != nil {
+		return 0, fmt.Errorf("failed to truncate stream: %w", err)
+	}
+
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
+
+// PositionGuarantee reports positions from the namespace's sequence, which
+// failed writes do not return and concurrent writes commit out of order
+func (s *TimescaleStore) PositionGuarantee(ctx context.Context, namespace string) store.PositionGuarantee {
+	return store.PositionGuarantee{
+		Allocator:   store.AllocatorSequence,
+		Description: "TimescaleDB sequence; failed writes such as version conflicts leave gaps, and concurrent writes become visible out of order",
+	}
+}
+
+// PendingWrites reads the namespace's sequence, then whether another session
+// holds the advisory lock write_message takes for category. Lock keys are
+// not namespaced, so a write to the same category in another namespace also
+// counts as in flight.
+func (s *TimescaleStore) PendingWrites(ctx context.Context, namespace, category string) (*store.PendingWrites, error) {
+	schemaName, err := s.getSchemaName(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sequence first: a write locking the category after the lock check
+	// allocates above it
+	var lastValue int64
+	var isCalled bool
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf(
+		`SELECT last_value, is_called FROM "%s".messages_global_position_seq`, schemaName,
+	)).Scan(&lastValue, &isCalled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read global position sequence: %w", err)
+	}
+	pending := &store.PendingWrites{}
+	if isCalled {
+		pending.Allocated = lastValue
+	}
+
+	// pg_locks splits bigint advisory keys into two oids
+	key := uint64(store.Hash64(category))
+	err = s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND granted AND pid <> pg_backend_pid()
+			  AND database = (SELECT oid FROM pg_database WHERE datname = current_database())
+			  AND ($1 = '' OR (classid::bigint = $2 AND objid::bigint = $3 AND objsubid = 1))
+		)
+	`, category, int64(key>>32), int64(uint32(key))).Scan(&pending.InFlight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read category locks: %w", err)
+	}
+	return pending, nil
+}
@@ -52,6 +52,46 @@ func CardinalID(streamName string) string {
 	return ""
 }
 
+// CompoundIDs splits the ID portion of a stream name into its '+' separated parts
+// Examples:
+//
+//	CompoundIDs("account-123") → ["123"]
+//	CompoundIDs("account-123+456") → ["123", "456"]
+//	CompoundIDs("account") → []
+func CompoundIDs(streamName string) []string {
+	id := ID(streamName)
+	if id == "" {
+		return []string{}
+	}
+	return strings.Split(id, "+")
+}
+
+// StreamNameParts is a stream name broken down the way reads and consumer
+// groups interpret it
+type StreamNameParts struct {
+	StreamName  string
+	Category    string
+	ID          string
+	CardinalID  string
+	CompoundIDs []string
+	IsCategory  bool
+}
+
+// ParseStreamName breaks a stream name into its parts
+// Examples:
+//
+//	ParseStreamName("account-123+456") → {Category: "account", ID: "123+456", CardinalID: "123", CompoundIDs: ["123", "456"]}
+func ParseStreamName(streamName string) StreamNameParts {
+	return StreamNameParts{
+		StreamName:  streamName,
+		Category:    Category(streamName),
+		ID:          ID(streamName),
+		CardinalID:  CardinalID(streamName),
+		CompoundIDs: CompoundIDs(streamName),
+		IsCategory:  IsCategory(streamName),
+	}
+}
+
 // IsCategory determines if a name represents a category (no ID part)
 // Examples:
 //
@@ -74,20 +114,5 @@ func Hash64(value string) int64 {
 // IsAssignedToConsumerMember determines which consumer group member should handle a stream
 // Returns true if the given stream should be handled by the specified consumer member
 func IsAssignedToConsumerMember(streamName string, member, size int64) bool {
-	if size <= 0 || member < 0 || member >= size {
-		return false
-	}
-
-	cardinalID := CardinalID(streamName)
-	if cardinalID == "" {
-		return false
-	}
-
-	hash := Hash64(cardinalID)
-	// Use absolute value to handle negative hashes
-	if hash < 0 {
-		hash = -hash
-	}
-
-	return (hash % size) == member
+	return PartitionerMD5.IsAssigned(streamName, member, size)
 }
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -70,6 +71,28 @@ func TestCardinalID(t *testing.T) {
 	}
 }
 
+func TestCompoundIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		streamName string
+		expected   []string
+	}{
+		{"simple stream", "account-123", []string{"123"}},
+		{"compound ID", "account-123+456", []string{"123", "456"}},
+		{"category only", "account", []string{}},
+		{"multi-dash", "account-prefix-123", []string{"prefix-123"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CompoundIDs(tt.streamName)
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") || len(result) != len(tt.expected) {
+				t.Errorf("CompoundIDs(%s) = %v, expected %v", tt.streamName, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestIsCategory(t *testing.T) {
 	tests := []struct {
 		name     string
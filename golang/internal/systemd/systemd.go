@@ -0,0 +1,93 @@
+// Package systemd provides socket activation and readiness notification for
+// EventoDB running under systemd, without linking libsystemd.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFdsStart is the first file descriptor passed by socket activation
+const listenFdsStart = 3
+
+// Listeners returns the sockets passed by systemd socket activation, keyed by
+// their FileDescriptorName= (the socket unit's name when unset).
+//
+// It returns nil when the process was not socket activated. The LISTEN_*
+// variables are unset either way, so child processes do not inherit them.
+func Listeners() (map[string]net.Listener, error) {
+	return listenersFrom(listenFdsStart)
+}
+
+// listenersFrom is Listeners with the first descriptor as a parameter
+func listenersFrom(start int) (map[string]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	// The variables are meant for the process systemd started, not its children
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	listeners := make(map[string]net.Listener, count)
+	for i := 0; i < count; i++ {
+		fd := start + i
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		// FileListener duplicates the descriptor, so the original is closed
+		f := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("inherited socket %d (%s): %w", fd, name, err)
+		}
+		if _, exists := listeners[name]; exists {
+			ln.Close()
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("inherited socket %d: duplicate name %q", fd, name)
+		}
+		listeners[name] = ln
+	}
+	return listeners, nil
+}
+
+// Notify sends a state change such as "READY=1" or "STOPPING=1" to the
+// service manager. It does nothing when NOTIFY_SOCKET is unset, i.e. when not
+// run by systemd with Type=notify.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// Names starting with @ are abstract sockets, which net handles itself
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("notify systemd: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("notify systemd: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestListeners_NotActivated(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := Listeners()
+	if err != nil || listeners != nil {
+		t.Fatalf("Expected no listeners for another process, got %v, %v", listeners, err)
+	}
+	if os.Getenv("LISTEN_FDS") != "" {
+		t.Error("Expected LISTEN_FDS to be unset")
+	}
+}
+
+func TestListeners_Inherited(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get socket file: %v", err)
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "admin")
+
+	listeners, err := listenersFrom(int(f.Fd()))
+	if err != nil {
+		t.Fatalf("Listeners failed: %v", err)
+	}
+	inherited, ok := listeners["admin"]
+	if !ok {
+		t.Fatalf("Expected a listener named admin, got %v", listeners)
+	}
+	defer inherited.Close()
+	if inherited.Addr().String() != ln.Addr().String() {
+		t.Errorf("Expected %s, got %s", ln.Addr(), inherited.Addr())
+	}
+
+	// Connections to the original socket are accepted on the inherited one
+	go func() {
+		if conn, err := net.Dial("tcp4", ln.Addr().String()); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := inherited.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestNotify(t *testing.T) {
+	if err := Notify("READY=1"); err != nil {
+		t.Fatalf("Expected no error without NOTIFY_SOCKET, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	if err := Notify("READY=1"); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got := string(buf[:n]); got != "READY=1" {
+		t.Errorf("Expected READY=1, got %q", got)
+	}
+}
@@ -0,0 +1,6 @@
+-- Migration: 002 (rollback)
+-- Description: Revert to schema version 1
+-- Note: 001 already defines get_category_messages with empty category support,
+-- so only the version record is removed
+
+DELETE FROM "{{SCHEMA_NAME}}"._schema_version WHERE version = 2;
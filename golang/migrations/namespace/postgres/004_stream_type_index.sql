@@ -0,0 +1,46 @@
+-- Migration: 004
+-- Description: Index for the last message of a type in a stream
+-- Lets stream.last with a type filter seek instead of scanning the stream backwards
+
+CREATE INDEX IF NOT EXISTS messages_stream_type ON "{{SCHEMA_NAME}}".messages (stream_name, type, position);
+
+-- get_last_stream_message: one query per case, so the typed lookup is planned
+-- against messages_stream_type rather than as an optional filter
+CREATE OR REPLACE FUNCTION "{{SCHEMA_NAME}}".get_last_stream_message(
+    _stream_name VARCHAR,
+    _type VARCHAR DEFAULT NULL
+)
+RETURNS TABLE (
+    id UUID,
+    stream_name VARCHAR,
+    type VARCHAR,
+    "position" BIGINT,
+    global_position BIGINT,
+    data JSONB,
+    metadata JSONB,
+    "time" TIMESTAMP
+) AS $$
+BEGIN
+    IF _type IS NULL THEN
+        RETURN QUERY
+        SELECT m.id, m.stream_name, m.type, m.position, m.global_position,
+               m.data, m.metadata, m.time
+        FROM "{{SCHEMA_NAME}}".messages m
+        WHERE m.stream_name = _stream_name
+        ORDER BY m.position DESC
+        LIMIT 1;
+    ELSE
+        RETURN QUERY
+        SELECT m.id, m.stream_name, m.type, m.position, m.global_position,
+               m.data, m.metadata, m.time
+        FROM "{{SCHEMA_NAME}}".messages m
+        WHERE m.stream_name = _stream_name
+          AND m.type = _type
+        ORDER BY m.position DESC
+        LIMIT 1;
+    END IF;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- Record migration version
+INSERT INTO "{{SCHEMA_NAME}}"._schema_version (version) VALUES (4) ON CONFLICT DO NOTHING;
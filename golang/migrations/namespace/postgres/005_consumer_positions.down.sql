@@ -0,0 +1,6 @@
+-- Migration: 005 (rollback)
+-- Description: Revert to schema version 4
+
+DROP TABLE IF EXISTS "{{SCHEMA_NAME}}".consumer_positions;
+
+DELETE FROM "{{SCHEMA_NAME}}"._schema_version WHERE version = 5;
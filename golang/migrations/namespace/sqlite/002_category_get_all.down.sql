@@ -0,0 +1,4 @@
+-- Migration: 002 (rollback)
+-- Description: Revert to schema version 1
+
+DELETE FROM _schema_version WHERE version = 2;
@@ -0,0 +1,6 @@
+-- Migration: 003 (rollback)
+-- Description: Revert to schema version 2
+
+DROP INDEX IF EXISTS messages_stream_type;
+
+DELETE FROM _schema_version WHERE version = 3;
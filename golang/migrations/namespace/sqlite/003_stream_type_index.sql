@@ -0,0 +1,8 @@
+-- Migration: 003
+-- Description: Index for the last message of a type in a stream
+-- Lets stream.last with a type filter seek instead of scanning the stream backwards
+
+CREATE INDEX IF NOT EXISTS messages_stream_type ON messages (stream_name, type, position);
+
+-- Record migration version
+INSERT OR IGNORE INTO _schema_version (version) VALUES (3);
@@ -0,0 +1,6 @@
+-- Migration: 004 (rollback)
+-- Description: Revert to schema version 3
+
+DROP TABLE IF EXISTS consumer_positions;
+
+DELETE FROM _schema_version WHERE version = 4;
@@ -0,0 +1,13 @@
+-- Migration: 004
+-- Description: Consumer positions
+-- Keeps the last global position each consumer has processed, for
+-- consumer.setPosition and consumer.getPosition
+
+CREATE TABLE IF NOT EXISTS consumer_positions (
+    consumer TEXT PRIMARY KEY,
+    global_position INTEGER NOT NULL,
+    updated INTEGER NOT NULL
+);
+
+-- Record migration version
+INSERT OR IGNORE INTO _schema_version (version) VALUES (4);
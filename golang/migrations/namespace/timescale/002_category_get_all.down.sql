@@ -0,0 +1,7 @@
+-- Migration: 002 (rollback)
+-- Description: Revert to schema version 1
+
+-- Drop the overload without _condition added by 002; the 001 function remains
+DROP FUNCTION IF EXISTS "{{SCHEMA_NAME}}".get_category_messages(TEXT, BIGINT, BIGINT, TEXT, BIGINT, BIGINT);
+
+DELETE FROM "{{SCHEMA_NAME}}"._schema_version WHERE version = 2;
@@ -0,0 +1,10 @@
+-- Migration: 003 (rollback)
+-- Description: Revert to schema version 2
+
+DROP FUNCTION IF EXISTS "{{SCHEMA_NAME}}".get_category_messages_partitioned(TEXT, BIGINT, BIGINT, TEXT, BIGINT, BIGINT, TEXT);
+DROP FUNCTION IF EXISTS "{{SCHEMA_NAME}}".consumer_group_member(TEXT, BIGINT, TEXT);
+DROP FUNCTION IF EXISTS "{{SCHEMA_NAME}}".jump_hash(NUMERIC, BIGINT);
+DROP FUNCTION IF EXISTS "{{SCHEMA_NAME}}".murmur3_32(TEXT);
+DROP FUNCTION IF EXISTS "{{SCHEMA_NAME}}".mul_32(BIGINT, BIGINT);
+
+DELETE FROM "{{SCHEMA_NAME}}"._schema_version WHERE version = 3;
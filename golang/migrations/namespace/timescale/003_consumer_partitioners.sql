@@ -0,0 +1,142 @@
+-- Migration: 003
+-- Description: Alternative consumer group partitioners (murmur3, jump consistent hashing)
+-- Must match store.Partitioner in internal/store/partition.go exactly
+
+-- mul_32: (a * b) mod 2^32 for unsigned 32-bit values, without BIGINT overflow
+CREATE OR REPLACE FUNCTION "{{SCHEMA_NAME}}".mul_32(a BIGINT, b BIGINT)
+RETURNS BIGINT AS $$
+    SELECT (((((a >> 16) * b) & 65535) << 16) + (a & 65535) * b) & 4294967295;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- murmur3_32: MurmurHash3 x86 32-bit (seed 0) of the UTF-8 bytes, unsigned
+CREATE OR REPLACE FUNCTION "{{SCHEMA_NAME}}".murmur3_32(value TEXT)
+RETURNS BIGINT AS $$
+DECLARE
+    _bytes BYTEA := convert_to(value, 'UTF8');
+    _len INTEGER := length(_bytes);
+    _h BIGINT := 0;
+    _k BIGINT;
+    _i INTEGER := 0;
+BEGIN
+    WHILE _i + 4 <= _len LOOP
+        _k := get_byte(_bytes, _i)::BIGINT
+            | (get_byte(_bytes, _i + 1)::BIGINT << 8)
+            | (get_byte(_bytes, _i + 2)::BIGINT << 16)
+            | (get_byte(_bytes, _i + 3)::BIGINT << 24);
+        _k := "{{SCHEMA_NAME}}".mul_32(_k, 3432918353);
+        _k := ((_k << 15) | (_k >> 17)) & 4294967295;
+        _k := "{{SCHEMA_NAME}}".mul_32(_k, 461845907);
+        _h := _h # _k;
+        _h := ((_h << 13) | (_h >> 19)) & 4294967295;
+        _h := (_h * 5 + 3864292196) & 4294967295;
+        _i := _i + 4;
+    END LOOP;
+
+    _k := 0;
+    IF _len - _i >= 3 THEN
+        _k := _k # (get_byte(_bytes, _i + 2)::BIGINT << 16);
+    END IF;
+    IF _len - _i >= 2 THEN
+        _k := _k # (get_byte(_bytes, _i + 1)::BIGINT << 8);
+    END IF;
+    IF _len - _i >= 1 THEN
+        _k := _k # get_byte(_bytes, _i)::BIGINT;
+        _k := "{{SCHEMA_NAME}}".mul_32(_k, 3432918353);
+        _k := ((_k << 15) | (_k >> 17)) & 4294967295;
+        _k := "{{SCHEMA_NAME}}".mul_32(_k, 461845907);
+        _h := _h # _k;
+    END IF;
+
+    _h := _h # _len;
+    _h := _h # (_h >> 16);
+    _h := "{{SCHEMA_NAME}}".mul_32(_h, 2246822507);
+    _h := _h # (_h >> 13);
+    _h := "{{SCHEMA_NAME}}".mul_32(_h, 3266489909);
+    _h := _h # (_h >> 16);
+    RETURN _h;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- jump_hash: Jump consistent hash (Lamping & Veach) of an unsigned 64-bit key
+CREATE OR REPLACE FUNCTION "{{SCHEMA_NAME}}".jump_hash(_key NUMERIC, _buckets BIGINT)
+RETURNS BIGINT AS $$
+DECLARE
+    _b BIGINT := -1;
+    _j BIGINT := 0;
+BEGIN
+    WHILE _j < _buckets LOOP
+        _b := _j;
+        _key := mod(_key * 2862933555777941757 + 1, 18446744073709551616);
+        -- Double precision, truncated, as in the reference implementation
+        _j := trunc((_b + 1)::DOUBLE PRECISION * (2147483648::DOUBLE PRECISION / (div(_key, 8589934592) + 1)::DOUBLE PRECISION));
+    END LOOP;
+    RETURN _b;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- consumer_group_member: Member a stream is assigned to, NULL for streams without an ID
+CREATE OR REPLACE FUNCTION "{{SCHEMA_NAME}}".consumer_group_member(
+    _stream_name TEXT,
+    _size BIGINT,
+    _partitioner TEXT DEFAULT 'md5'
+)
+RETURNS BIGINT AS $$
+DECLARE
+    _cardinal_id TEXT := "{{SCHEMA_NAME}}".cardinal_id(_stream_name);
+    _key NUMERIC;
+BEGIN
+    IF _cardinal_id IS NULL OR _cardinal_id = '' THEN
+        RETURN NULL;
+    END IF;
+
+    IF _partitioner = 'murmur3' THEN
+        RETURN MOD("{{SCHEMA_NAME}}".murmur3_32(_cardinal_id), _size);
+    ELSIF _partitioner = 'jump' THEN
+        _key := "{{SCHEMA_NAME}}".hash_64(_cardinal_id);
+        IF _key < 0 THEN
+            _key := _key + 18446744073709551616;
+        END IF;
+        RETURN "{{SCHEMA_NAME}}".jump_hash(_key, _size);
+    END IF;
+    RETURN MOD(ABS("{{SCHEMA_NAME}}".hash_64(_cardinal_id)), _size);
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- get_category_messages_partitioned: get_category_messages with a selectable partitioner
+CREATE OR REPLACE FUNCTION "{{SCHEMA_NAME}}".get_category_messages_partitioned(
+    _category_name TEXT,
+    _position BIGINT DEFAULT 1,
+    _batch_size BIGINT DEFAULT 1000,
+    _correlation TEXT DEFAULT NULL,
+    _consumer_group_member BIGINT DEFAULT NULL,
+    _consumer_group_size BIGINT DEFAULT NULL,
+    _partitioner TEXT DEFAULT 'md5'
+)
+RETURNS TABLE (
+    id UUID,
+    stream_name TEXT,
+    type TEXT,
+    "position" BIGINT,
+    global_position BIGINT,
+    data JSONB,
+    metadata JSONB,
+    "time" TIMESTAMP
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT m.id, m.stream_name, m.type, m.position, m.global_position,
+           m.data, m.metadata, m.time
+    FROM "{{SCHEMA_NAME}}".messages m
+    WHERE (_category_name IS NULL OR _category_name = '' OR "{{SCHEMA_NAME}}".category(m.stream_name) = _category_name)
+      AND m.global_position >= _position
+      AND (_correlation IS NULL OR
+           "{{SCHEMA_NAME}}".category(m.metadata->>'correlationStreamName') = _correlation)
+      AND (_consumer_group_member IS NULL OR _consumer_group_size IS NULL OR
+           "{{SCHEMA_NAME}}".consumer_group_member(m.stream_name, _consumer_group_size, _partitioner) = _consumer_group_member)
+    ORDER BY m.global_position ASC
+    LIMIT CASE WHEN _batch_size = -1 THEN NULL ELSE _batch_size END;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- Record migration version
+INSERT INTO "{{SCHEMA_NAME}}"._schema_version (version) VALUES (3) ON CONFLICT DO NOTHING;
@@ -0,0 +1,32 @@
+-- Migration: 004 (rollback)
+-- Description: Revert to schema version 3
+
+CREATE OR REPLACE FUNCTION "{{SCHEMA_NAME}}".get_last_stream_message(
+    _stream_name TEXT,
+    _type TEXT DEFAULT NULL
+)
+RETURNS TABLE (
+    id UUID,
+    stream_name TEXT,
+    "type" TEXT,
+    "position" BIGINT,
+    global_position BIGINT,
+    data JSONB,
+    metadata JSONB,
+    "time" TIMESTAMPTZ
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT m.id, m.stream_name, m.type, m.position, m.global_position,
+           m.data, m.metadata, m.time
+    FROM "{{SCHEMA_NAME}}".messages m
+    WHERE m.stream_name = _stream_name
+      AND (_type IS NULL OR m.type = _type)
+    ORDER BY m.position DESC
+    LIMIT 1;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+DROP INDEX IF EXISTS "{{SCHEMA_NAME}}".messages_stream_type;
+
+DELETE FROM "{{SCHEMA_NAME}}"._schema_version WHERE version = 4;
@@ -0,0 +1,13 @@
+-- Migration: 005
+-- Description: Consumer positions
+-- Keeps the last global position each consumer has processed, for
+-- consumer.setPosition and consumer.getPosition
+
+CREATE TABLE IF NOT EXISTS "{{SCHEMA_NAME}}".consumer_positions (
+    consumer TEXT PRIMARY KEY,
+    global_position BIGINT NOT NULL,
+    updated TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Record migration version
+INSERT INTO "{{SCHEMA_NAME}}"._schema_version (version) VALUES (5) ON CONFLICT DO NOTHING;
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -593,3 +594,289 @@ func parseTestCategoryMsg(msg *testCategoryMessage, raw []interface{}) error {
 
 	return nil
 }
+
+// TestExport_Follow tests that /export streams historical records and, with
+// follow=true, keeps streaming new records of the exported categories
+func TestExport_Follow(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	for _, stream := range []string{"order-1", "user-1", "order-2"} {
+		if _, err := makeRPCCall(t, server.Port, server.Token, "stream.write", stream, map[string]interface{}{
+			"type": "Created",
+			"data": map[string]interface{}{},
+		}); err != nil {
+			t.Fatalf("stream.write failed: %v", err)
+		}
+	}
+
+	// Without follow the response ends after the historical records
+	resp, err := exportRequest(context.Background(), server, "")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 3 {
+		t.Fatalf("Expected 3 records, got %d: %s", len(lines), data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err = exportRequest(ctx, server, "?categories=order&follow=true")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	records := make(chan ExportRecord)
+	go func() {
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var record ExportRecord
+			if err := decoder.Decode(&record); err != nil {
+				close(records)
+				return
+			}
+			records <- record
+		}
+	}()
+	next := func() ExportRecord {
+		t.Helper()
+		record, ok := <-records
+		if !ok {
+			t.Fatalf("Export stream ended")
+		}
+		return record
+	}
+
+	if a, b := next(), next(); a.Stream != "order-1" || b.Stream != "order-2" {
+		t.Fatalf("Expected the historical order records, got %s and %s", a.Stream, b.Stream)
+	}
+
+	// New writes outside the exported categories are not streamed
+	for _, stream := range []string{"user-2", "order-3"} {
+		if _, err := makeRPCCall(t, server.Port, server.Token, "stream.write", stream, map[string]interface{}{
+			"type": "Created",
+			"data": map[string]interface{}{},
+		}); err != nil {
+			t.Fatalf("stream.write failed: %v", err)
+		}
+	}
+	if record := next(); record.Stream != "order-3" || record.Position != 0 {
+		t.Errorf("Expected the new order-3 record, got %+v", record)
+	}
+}
+
+// TestExport_FollowEdges tests starting positions, invalid and unknown
+// ones, and category lists with spaces
+func TestExport_FollowEdges(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	for _, stream := range []string{"order-1", "user-1", "order-2", "other-1"} {
+		if _, err := makeRPCCall(t, server.Port, server.Token, "stream.write", stream, map[string]interface{}{
+			"type": "Created",
+			"data": map[string]interface{}{},
+		}); err != nil {
+			t.Fatalf("stream.write failed: %v", err)
+		}
+	}
+
+	for query, status := range map[string]string{
+		"?from=-1":             "400",
+		"?from=-1&follow=true": "400",
+		"?from=no-such-mark":   "404",
+	} {
+		if _, err := exportRequest(context.Background(), server, query); err == nil || !strings.Contains(err.Error(), status) {
+			t.Errorf("Expected HTTP %s for %s, got %v", status, query, err)
+		}
+	}
+
+	// Records start at from, in the listed categories only
+	resp, err := exportRequest(context.Background(), server, "?from=2&categories="+url.QueryEscape(" order , user "))
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var streams []string
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var record ExportRecord
+		if err := decoder.Decode(&record); err != nil {
+			break
+		}
+		streams = append(streams, record.Stream)
+	}
+	if len(streams) != 2 || streams[0] != "order-2" || streams[1] != "user-1" {
+		t.Errorf("Expected order-2 then user-1, got %v", streams)
+	}
+}
+
+// TestExport_FollowFromEnd tests that from=end skips the records written
+// before the request and needs follow=true
+func TestExport_FollowFromEnd(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	write := func(stream string) {
+		t.Helper()
+		if _, err := makeRPCCall(t, server.Port, server.Token, "stream.write", stream, map[string]interface{}{
+			"type": "Created",
+			"data": map[string]interface{}{},
+		}); err != nil {
+			t.Fatalf("stream.write failed: %v", err)
+		}
+	}
+	write("order-1")
+
+	if _, err := exportRequest(context.Background(), server, "?from=end"); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected HTTP 400 without follow, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := exportRequest(ctx, server, "?categories=order&follow=true&from=end")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	write("order-2")
+	var record ExportRecord
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		t.Fatalf("Failed to read record: %v", err)
+	}
+	if record.Stream != "order-2" {
+		t.Errorf("Expected only the new order-2 record, got %+v", record)
+	}
+}
+
+// TestExport_FollowFromEndEdges tests that from=end starts each category at
+// its first write after the request and applies the filter to new records
+func TestExport_FollowFromEndEdges(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	write := func(stream string, n float64) {
+		t.Helper()
+		if _, err := makeRPCCall(t, server.Port, server.Token, "stream.write", stream, map[string]interface{}{
+			"type": "Created",
+			"data": map[string]interface{}{"n": n},
+		}); err != nil {
+			t.Fatalf("stream.write failed: %v", err)
+		}
+	}
+	write("order-1", 2)
+	write("user-1", 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := exportRequest(ctx, server, "?categories=order,user&follow=true&from=end&where="+url.QueryEscape("data.n > 1"))
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	write("user-2", 1)
+	write("user-3", 2)
+	write("order-2", 2)
+	// Categories are drained in turn, so the two may arrive in either order
+	decoder := json.NewDecoder(resp.Body)
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		var record ExportRecord
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("Failed to read record: %v", err)
+		}
+		got[record.Stream] = true
+	}
+	if !got["user-3"] || !got["order-2"] {
+		t.Errorf("Expected user-3 and order-2, got %v", got)
+	}
+}
+
+// TestExport_Where tests that /export only streams records matching an EQL
+// filter and rejects invalid filters
+func TestExport_Where(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	for _, amount := range []float64{50, 150, 250} {
+		if _, err := makeRPCCall(t, server.Port, server.Token, "stream.write", "account-1", map[string]interface{}{
+			"type": "Deposited",
+			"data": map[string]interface{}{"amount": amount},
+		}); err != nil {
+			t.Fatalf("stream.write failed: %v", err)
+		}
+	}
+
+	resp, err := exportRequest(context.Background(), server, "?where="+url.QueryEscape("type = 'Deposited' AND data.amount > 100"))
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Fatalf("Expected 2 records, got %d: %s", len(lines), data)
+	}
+
+	if _, err := exportRequest(context.Background(), server, "?where="+url.QueryEscape("data.amount >")); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected HTTP 400 for an invalid filter, got %v", err)
+	}
+}
+
+func TestExport_Sample(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	for i := 0; i < 50; i++ {
+		if _, err := makeRPCCall(t, server.Port, server.Token, "stream.write", fmt.Sprintf("order-%d", i), map[string]interface{}{
+			"type": "Placed",
+			"data": map[string]interface{}{},
+		}); err != nil {
+			t.Fatalf("stream.write failed: %v", err)
+		}
+	}
+
+	export := func() string {
+		t.Helper()
+		resp, err := exportRequest(context.Background(), server, "?sample=0.2")
+		if err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return strings.TrimSpace(string(data))
+	}
+
+	first := export()
+	if lines := strings.Split(first, "\n"); first == "" || len(lines) >= 50 {
+		t.Fatalf("Expected a partial sample, got %d records", len(lines))
+	}
+	if second := export(); second != first {
+		t.Errorf("Expected repeated exports to sample the same records")
+	}
+
+	if _, err := exportRequest(context.Background(), server, "?sample=2"); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected HTTP 400 for an invalid sample, got %v", err)
+	}
+}
+
+// exportRequest starts a GET /export request
+func exportRequest(ctx context.Context, server *TestServer, query string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL()+"/export"+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+server.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,307 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// TestNamespaceConfig_ExportImportRoundtrip tests that namespace config travels with an export
+func TestNamespaceConfig_ExportImportRoundtrip(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	ctx := context.Background()
+	ns := server.Env.Namespace
+
+	// Tenant config plus cluster-local state that must not be exported
+	err := server.Env.Store.UpdateNamespaceMetadata(ctx, ns, map[string]interface{}{
+		"team":              "billing",
+		"logShippingStatus": map[string]interface{}{"state": "active", "position": 42},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set metadata: %v", err)
+	}
+
+	result, err := makeRPCCall(t, server.Port, server.Token, "ns.config.export")
+	if err != nil {
+		t.Fatalf("ns.config.export failed: %v", err)
+	}
+	exported := result.(map[string]interface{})
+	metadata := exported["metadata"].(map[string]interface{})
+	if metadata["team"] != "billing" {
+		t.Errorf("Expected team metadata, got %v", metadata)
+	}
+	if _, ok := metadata["logShippingStatus"]; ok {
+		t.Errorf("Runtime status must not be exported: %v", metadata)
+	}
+	if exported["namespace"] != ns || exported["version"] != float64(1) {
+		t.Errorf("Unexpected config header: %v", exported)
+	}
+
+	// Reset the namespace, then import the config line followed by one message
+	if err := server.Env.Store.UpdateNamespaceMetadata(ctx, ns, map[string]interface{}{
+		"logShippingStatus": map[string]interface{}{"state": "failing"},
+	}); err != nil {
+		t.Fatalf("Failed to reset metadata: %v", err)
+	}
+
+	configLine, _ := json.Marshal(map[string]interface{}{"namespaceConfig": exported})
+	body := string(configLine) + "\n" + fmt.Sprintf(
+		`{"id":"%s","stream":"invoice-1","type":"Issued","pos":0,"gpos":1,"data":{},"meta":null,"time":"2025-01-15T10:00:00Z"}`,
+		uuid.New().String())
+
+	done := postImport(t, server, "", body)
+	if done["imported"] != float64(1) || done["config"] != true {
+		t.Fatalf("Expected 1 message and config applied, got %v", done)
+	}
+
+	nsInfo, err := server.Env.Store.GetNamespace(ctx, ns)
+	if err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	if nsInfo.Metadata["team"] != "billing" {
+		t.Errorf("Expected imported team metadata, got %v", nsInfo.Metadata)
+	}
+	status, _ := nsInfo.Metadata["logShippingStatus"].(map[string]interface{})
+	if status["state"] != "failing" {
+		t.Errorf("Expected target runtime status to be kept, got %v", nsInfo.Metadata)
+	}
+}
+
+// TestNamespaceConfig_ImportSkipConfig tests that config=false ignores the config line
+func TestNamespaceConfig_ImportSkipConfig(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	body := `{"namespaceConfig":{"version":1,"namespace":"other","metadata":{"team":"ops"}}}`
+	done := postImport(t, server, "?config=false", body)
+	if done["imported"] != float64(0) || done["config"] != nil {
+		t.Fatalf("Expected nothing imported or applied, got %v", done)
+	}
+
+	nsInfo, err := server.Env.Store.GetNamespace(context.Background(), server.Env.Namespace)
+	if err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	if _, ok := nsInfo.Metadata["team"]; ok {
+		t.Errorf("Expected config to be skipped, got %v", nsInfo.Metadata)
+	}
+
+	// Newer config versions are rejected
+	_, err = makeRPCCall(t, server.Port, server.Token, "ns.config.import", map[string]interface{}{"version": 99})
+	if err == nil || !strings.Contains(err.Error(), "INVALID_REQUEST") {
+		t.Errorf("Expected INVALID_REQUEST for unsupported version, got %v", err)
+	}
+}
+
+// TestNamespaceConfig_ImportValidatesKeys tests that ns.config.import
+// rejects values the methods setting each key would reject, and stores
+// nothing when one is invalid
+func TestNamespaceConfig_ImportValidatesKeys(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	valid := map[string]interface{}{
+		"team":           "billing",
+		"derivedStreams": map[string]interface{}{"rules": []interface{}{map[string]interface{}{"category": "order", "stream": "orderSummary-{id}"}}},
+		"queries":        map[string]interface{}{"queries": []interface{}{map[string]interface{}{"name": "large"}}},
+		"snapshots":      map[string]interface{}{"rules": []interface{}{map[string]interface{}{"category": "account", "every": 100, "url": "https://reducer.example.com"}}},
+		"messageIds":     "ulid",
+	}
+	for key, value := range map[string]interface{}{
+		"derivedStreams": map[string]interface{}{"rules": []interface{}{map[string]interface{}{"category": "order"}}},
+		"queries":        map[string]interface{}{"queries": []interface{}{map[string]interface{}{"name": ""}}},
+		"snapshots":      map[string]interface{}{"rules": []interface{}{map[string]interface{}{"category": "account", "every": 0}}},
+		"messageIds":     "sequential",
+		"ticks":          map[string]interface{}{"ticks": []interface{}{map[string]interface{}{"name": "daily", "cron": "never"}}},
+		"bookmarks":      map[string]interface{}{"123": map[string]interface{}{"globalPosition": 1}},
+		"retention":      "forever",
+	} {
+		metadata := map[string]interface{}{key: value}
+		for k, v := range valid {
+			if k != key {
+				metadata[k] = v
+			}
+		}
+		_, err := makeRPCCall(t, server.Port, server.Token, "ns.config.import", map[string]interface{}{"version": 1, "metadata": metadata})
+		if err == nil || !strings.Contains(err.Error(), "INVALID_REQUEST") || !strings.Contains(err.Error(), key) {
+			t.Errorf("%s: expected INVALID_REQUEST naming the key, got %v", key, err)
+		}
+	}
+	nsInfo, err := server.Env.Store.GetNamespace(context.Background(), server.Env.Namespace)
+	if err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	if _, ok := nsInfo.Metadata["team"]; ok {
+		t.Errorf("Expected nothing stored from rejected imports, got %v", nsInfo.Metadata)
+	}
+
+	if _, err := makeRPCCall(t, server.Port, server.Token, "ns.config.import", map[string]interface{}{"version": 1, "metadata": valid}); err != nil {
+		t.Fatalf("ns.config.import failed: %v", err)
+	}
+	nsInfo, err = server.Env.Store.GetNamespace(context.Background(), server.Env.Namespace)
+	if err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	if nsInfo.Metadata["messageIds"] != "ulid" || nsInfo.Metadata["derivedStreams"] == nil {
+		t.Errorf("Expected the valid config to be stored, got %v", nsInfo.Metadata)
+	}
+}
+
+// TestNamespaceConfig_ImportRejectsCombinedArchive tests that records of an
+// all-namespaces archive are not imported into the caller's namespace
+func TestNamespaceConfig_ImportRejectsCombinedArchive(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	body := fmt.Sprintf(
+		`{"id":"%s","stream":"invoice-1","type":"Issued","pos":0,"gpos":1,"data":{},"meta":null,"time":"2025-01-15T10:00:00Z","ns":"other"}`,
+		uuid.New().String())
+	req, err := http.NewRequest("POST", server.URL()+"/import", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+server.Token)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(data), "INVALID_RECORD") {
+		t.Errorf("Expected INVALID_RECORD, got %s", data)
+	}
+}
+
+// TestNamespaceConfig_ImportRejectsCombinedArchiveEdges tests that a tagged
+// record after untagged ones fails the import before any record is written,
+// and that an empty tag is not a combined archive
+func TestNamespaceConfig_ImportRejectsCombinedArchiveEdges(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	record := func(gpos int, ns string) string {
+		return fmt.Sprintf(
+			`{"id":"%s","stream":"invoice-1","type":"Issued","pos":%d,"gpos":%d,"data":{},"meta":null,"time":"2025-01-15T10:00:00Z","ns":"%s"}`,
+			uuid.New().String(), gpos-1, gpos, ns)
+	}
+
+	req, err := http.NewRequest("POST", server.URL()+"/import", strings.NewReader(record(1, "")+"\n"+record(2, "other")))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+server.Token)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(data), "INVALID_RECORD") || !strings.Contains(string(data), "line 2") {
+		t.Errorf("Expected INVALID_RECORD at line 2, got %s", data)
+	}
+	msgs, err := server.Env.Store.GetStreamMessages(context.Background(), server.Env.Namespace, "invoice-1", nil)
+	if err != nil || len(msgs) != 0 {
+		t.Errorf("Expected nothing to be imported, got %d (%v)", len(msgs), err)
+	}
+
+	if done := postImport(t, server, "", record(1, "")); done["imported"] != float64(1) {
+		t.Errorf("Expected an empty tag to be imported, got %v", done)
+	}
+}
+
+// TestImport_RenameRules tests that streamPrefixMap and typeMap rename imported records
+func TestImport_RenameRules(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	body := fmt.Sprintf(
+		`{"id":"%s","stream":"account-1","type":"Opened","pos":0,"gpos":1,"data":{},"meta":null,"time":"2025-01-15T10:00:00Z"}`,
+		uuid.New().String())
+	query := "?streamPrefixMap=" + url.QueryEscape(`{"account-":"customerAccount-"}`) + "&typeMap=" + url.QueryEscape(`{"Opened":"AccountOpened"}`)
+	done := postImport(t, server, query, body)
+	if done["imported"] != float64(1) {
+		t.Fatalf("Expected 1 message, got %v", done)
+	}
+
+	msgs, err := server.Env.Store.GetStreamMessages(context.Background(), server.Env.Namespace, "customerAccount-1", nil)
+	if err != nil || len(msgs) != 1 || msgs[0].Type != "AccountOpened" {
+		t.Errorf("Expected the renamed message, got %v (%v)", msgs, err)
+	}
+}
+
+// TestImport_DedupeByID tests that re-running an import with dedupeBy=id
+// skips the records written by the earlier run
+func TestImport_DedupeByID(t *testing.T) {
+	server := SetupIsolatedTestServer(t)
+	defer server.Cleanup()
+
+	var lines []string
+	for i := 0; i < 3; i++ {
+		lines = append(lines, fmt.Sprintf(
+			`{"id":"%s","stream":"account-1","type":"Deposited","pos":%d,"gpos":%d,"data":{},"meta":null,"time":"2025-01-15T10:00:00Z"}`,
+			uuid.New().String(), i, i+1))
+	}
+
+	// A partial run followed by a re-run of the whole file
+	if done := postImport(t, server, "?dedupeBy=id", strings.Join(lines[:2], "\n")); done["imported"] != float64(2) {
+		t.Fatalf("Expected 2 messages, got %v", done)
+	}
+	done := postImport(t, server, "?dedupeBy=id", strings.Join(lines, "\n"))
+	if done["imported"] != float64(1) || done["skipped"] != float64(2) {
+		t.Fatalf("Expected 1 imported and 2 skipped, got %v", done)
+	}
+
+	msgs, err := server.Env.Store.GetStreamMessages(context.Background(), server.Env.Namespace, "account-1", nil)
+	if err != nil || len(msgs) != 3 {
+		t.Errorf("Expected 3 messages, got %d (%v)", len(msgs), err)
+	}
+}
+
+// postImport posts an NDJSON body to /import and returns the done event
+func postImport(t *testing.T, server *TestServer, query, body string) map[string]interface{} {
+	t.Helper()
+	req, err := http.NewRequest("POST", server.URL()+"/import"+query, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+server.Token)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			continue
+		}
+		if event["done"] == true {
+			return event
+		}
+		if event["error"] != nil {
+			t.Fatalf("Import failed: %v", event)
+		}
+	}
+	t.Fatalf("No done event in response: %s", data)
+	return nil
+}
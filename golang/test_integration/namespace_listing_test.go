@@ -2,10 +2,13 @@ package integration
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/eventodb/eventodb/internal/api"
 	"github.com/eventodb/eventodb/internal/store"
+	"github.com/google/uuid"
 )
 
 // writeMsg is a helper to write a message directly to the store
@@ -222,6 +225,208 @@ func TestNsStreams_VersionAndLastActivity(t *testing.T) {
 	}
 }
 
+// TestNsStreams_ActivitySortAndFilters verifies hottest/stalest sorting and
+// the inactiveSince/olderThan filters
+func TestNsStreams_ActivitySortAndFilters(t *testing.T) {
+	ts := SetupTestServer(t)
+	defer ts.Cleanup()
+
+	// abandoned-1: two writes 30 and 20 days ago; busy-1: three writes in the
+	// last hour; new-1: written now
+	now := time.Now().UTC()
+	var messages []*store.Message
+	for i, msg := range []struct {
+		stream   string
+		position int64
+		age      time.Duration
+	}{
+		{"abandoned-1", 0, 30 * 24 * time.Hour},
+		{"abandoned-1", 1, 20 * 24 * time.Hour},
+		{"busy-1", 0, 50 * time.Minute},
+		{"busy-1", 1, 30 * time.Minute},
+		{"busy-1", 2, 10 * time.Minute},
+	} {
+		messages = append(messages, &store.Message{
+			ID:             uuid.New().String(),
+			StreamName:     msg.stream,
+			Type:           "Updated",
+			Position:       msg.position,
+			GlobalPosition: int64(i + 1),
+			Data:           map[string]interface{}{},
+			Time:           now.Add(-msg.age),
+		})
+	}
+	if err := ts.Env.Store.ImportBatch(context.Background(), ts.Env.Namespace, messages); err != nil {
+		t.Fatalf("ImportBatch failed: %v", err)
+	}
+	writeMsg(t, ts.Env.Store, ts.Env.Namespace, "new-1", "Created")
+
+	streamNames := func(result interface{}) []string {
+		var names []string
+		for _, item := range result.([]interface{}) {
+			names = append(names, item.(map[string]interface{})["stream"].(string))
+		}
+		return names
+	}
+
+	result, err := makeRPCCall(t, ts.Port, ts.Token, "ns.streams", map[string]interface{}{"sort": "hottest", "limit": float64(2)})
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	if names := streamNames(result); len(names) != 2 || names[0] != "new-1" || names[1] != "busy-1" {
+		t.Errorf("Expected [new-1 busy-1], got %v", names)
+	}
+	item := result.([]interface{})[1].(map[string]interface{})
+	if rate, _ := item["writesPerHour"].(float64); rate < 3 || rate > 4 {
+		t.Errorf("Expected busy-1 at about 3.6 writes per hour, got %v", item["writesPerHour"])
+	}
+	if first, _ := item["firstActivity"].(string); first == "" || first >= item["lastActivity"].(string) {
+		t.Errorf("Expected firstActivity before lastActivity, got %v", item)
+	}
+
+	result, err = makeRPCCall(t, ts.Port, ts.Token, "ns.streams", map[string]interface{}{"sort": "stalest"})
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	if names := streamNames(result); len(names) != 3 || names[0] != "abandoned-1" || names[2] != "new-1" {
+		t.Errorf("Expected abandoned-1 first and new-1 last, got %v", names)
+	}
+
+	result, err = makeRPCCall(t, ts.Port, ts.Token, "ns.streams", map[string]interface{}{"inactiveSince": "168h"})
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	if names := streamNames(result); len(names) != 1 || names[0] != "abandoned-1" {
+		t.Errorf("Expected [abandoned-1], got %v", names)
+	}
+
+	result, err = makeRPCCall(t, ts.Port, ts.Token, "ns.streams", map[string]interface{}{
+		"olderThan": now.Add(-5 * time.Minute).Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	if names := streamNames(result); len(names) != 2 || names[0] != "abandoned-1" || names[1] != "busy-1" {
+		t.Errorf("Expected [abandoned-1 busy-1], got %v", names)
+	}
+
+	for _, opts := range []map[string]interface{}{
+		{"sort": "coldest"},
+		{"sort": "hottest", "cursor": "busy-1"},
+		{"inactiveSince": "yesterday"},
+		{"olderThan": "-1h"},
+	} {
+		if _, err := makeRPCCall(t, ts.Port, ts.Token, "ns.streams", opts); err == nil {
+			t.Errorf("Expected an error for %v", opts)
+		}
+	}
+}
+
+// TestNsStreams_ActivitySortEdges verifies that the filters exclude streams
+// written exactly at their bound, that they combine with each other, a
+// prefix and either sort, that ranking reads past the first 1000 streams and
+// that sort "name" still pages with a cursor
+func TestNsStreams_ActivitySortEdges(t *testing.T) {
+	ts := SetupTestServer(t)
+	defer ts.Cleanup()
+
+	// Whole seconds, so every backend compares the same times; cold-0000 to
+	// cold-1000 are written once a day ago, warm-1 an hour ago and hot-1 now
+	now := time.Now().UTC().Truncate(time.Second)
+	dayAgo := now.Add(-24 * time.Hour)
+	hourAgo := now.Add(-time.Hour)
+	var messages []*store.Message
+	add := func(stream string, position int64, at time.Time) {
+		messages = append(messages, &store.Message{
+			ID:             uuid.New().String(),
+			StreamName:     stream,
+			Type:           "Updated",
+			Position:       position,
+			GlobalPosition: int64(len(messages) + 1),
+			Data:           map[string]interface{}{},
+			Time:           at,
+		})
+	}
+	for i := 0; i <= 1000; i++ {
+		add(fmt.Sprintf("cold-%04d", i), 0, dayAgo)
+	}
+	add("warm-1", 0, hourAgo)
+	if err := ts.Env.Store.ImportBatch(context.Background(), ts.Env.Namespace, messages); err != nil {
+		t.Fatalf("ImportBatch failed: %v", err)
+	}
+	writeMsg(t, ts.Env.Store, ts.Env.Namespace, "hot-1", "Created")
+
+	list := func(opts map[string]interface{}) []map[string]interface{} {
+		t.Helper()
+		result, err := makeRPCCall(t, ts.Port, ts.Token, "ns.streams", opts)
+		if err != nil {
+			t.Fatalf("ns.streams %v failed: %v", opts, err)
+		}
+		var items []map[string]interface{}
+		for _, item := range result.([]interface{}) {
+			items = append(items, item.(map[string]interface{}))
+		}
+		return items
+	}
+
+	// Ranking reads every stream, so hot-1 and warm-1 lead although more than
+	// 1000 streams sort before them by name; a stream written under a minute
+	// ago is rated over a minute
+	items := list(map[string]interface{}{"sort": "hottest", "limit": float64(3)})
+	if len(items) != 3 || items[0]["stream"] != "hot-1" || items[1]["stream"] != "warm-1" {
+		t.Fatalf("Expected hot-1 then warm-1, got %v", items)
+	}
+	if items[0]["writesPerHour"] != 60.0 {
+		t.Errorf("Expected hot-1 at 60 writes per hour, got %v", items[0]["writesPerHour"])
+	}
+	items = list(map[string]interface{}{"sort": "stalest", "limit": float64(1000)})
+	if len(items) != 1000 || items[0]["stream"] != "cold-0000" || items[999]["stream"] != "cold-0999" {
+		t.Errorf("Expected the cold streams in name order, got %d streams", len(items))
+	}
+
+	// Streams written exactly at the bound are not before it
+	bound := hourAgo.Format(time.RFC3339)
+	for _, opts := range []map[string]interface{}{
+		{"inactiveSince": bound, "prefix": "warm"},
+		{"olderThan": bound, "prefix": "warm"},
+		{"inactiveSince": "1h", "olderThan": "1h", "prefix": "hot"},
+		{"inactiveSince": "25h"},
+	} {
+		if items := list(opts); len(items) != 0 {
+			t.Errorf("Expected no streams for %v, got %v", opts, items)
+		}
+	}
+
+	// Filters combine with a prefix, each other and either sort
+	items = list(map[string]interface{}{"sort": "hottest", "prefix": "warm", "inactiveSince": "30m", "olderThan": "30m"})
+	if len(items) != 1 || items[0]["stream"] != "warm-1" {
+		t.Errorf("Expected [warm-1], got %v", items)
+	}
+	items = list(map[string]interface{}{"sort": "stalest", "inactiveSince": "30m", "olderThan": "2h", "limit": float64(1000)})
+	if len(items) != 1000 || items[0]["stream"] != "cold-0000" {
+		t.Errorf("Expected only cold streams, got %d streams", len(items))
+	}
+
+	// Sort "name" is the default order and pages with a cursor
+	items = list(map[string]interface{}{"sort": "name", "cursor": "cold-1000", "limit": float64(2)})
+	if len(items) != 2 || items[0]["stream"] != "hot-1" || items[1]["stream"] != "warm-1" {
+		t.Errorf("Expected [hot-1 warm-1], got %v", items)
+	}
+
+	for _, opts := range []map[string]interface{}{
+		{"sort": float64(1)},
+		{"sort": ""},
+		{"sort": "stalest", "cursor": "cold-0000"},
+		{"inactiveSince": float64(3600)},
+		{"inactiveSince": "0s"},
+		{"olderThan": ""},
+	} {
+		if _, err := makeRPCCall(t, ts.Port, ts.Token, "ns.streams", opts); err == nil {
+			t.Errorf("Expected an error for %v", opts)
+		}
+	}
+}
+
 // TestNsStreams_NamespaceScoped verifies isolation between namespaces using store directly
 func TestNsStreams_NamespaceScoped(t *testing.T) {
 	ctx := context.Background()
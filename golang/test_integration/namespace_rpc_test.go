@@ -506,3 +506,109 @@ func TestMDB002_5A_InfoNonexistentNamespace(t *testing.T) {
 		t.Errorf("Expected error code 'NAMESPACE_NOT_FOUND', got '%v'", errResult["code"])
 	}
 }
+
+// Additional test: Rotating a token replaces the old one
+func TestMDB002_5A_RotateTokenReplacesOldToken(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.Cleanup()
+
+	handler := api.NewRPCHandler("1.0.0", env.Store, nil)
+	ctx := context.Background()
+
+	result, errResult := makeDirectRPCCall(t, handler, "ns.create", "rotate_test")
+	if errResult != nil {
+		t.Fatalf("Create should succeed: %v", errResult)
+	}
+	oldToken := result.(map[string]interface{})["token"].(string)
+	defer env.Store.DeleteNamespace(ctx, "rotate_test")
+
+	result, errResult = makeDirectRPCCall(t, handler, "ns.rotateToken", "rotate_test")
+	if errResult != nil {
+		t.Fatalf("Rotate should succeed: %v", errResult)
+	}
+	resultMap := result.(map[string]interface{})
+	newToken, _ := resultMap["token"].(string)
+	if newToken == "" || newToken == oldToken {
+		t.Fatalf("Expected a new token, got %q", newToken)
+	}
+	if _, ok := resultMap["rotatedAt"].(string); !ok {
+		t.Error("Expected rotatedAt timestamp")
+	}
+
+	ns, err := env.Store.GetNamespace(ctx, "rotate_test")
+	if err != nil {
+		t.Fatalf("Namespace should still exist: %v", err)
+	}
+	if ns.TokenHash != auth.HashToken(newToken) {
+		t.Error("Stored token hash should match the new token")
+	}
+	if ns.TokenHash == auth.HashToken(oldToken) {
+		t.Error("Old token should no longer match")
+	}
+
+	// A token for another namespace is rejected
+	other, _ := auth.GenerateToken("someone_else")
+	_, errResult = makeDirectRPCCall(t, handler, "ns.rotateToken", "rotate_test", map[string]interface{}{"token": other})
+	if errResult == nil || errResult["code"] != "INVALID_REQUEST" {
+		t.Errorf("Expected INVALID_REQUEST for a foreign token, got %v", errResult)
+	}
+
+	_, errResult = makeDirectRPCCall(t, handler, "ns.rotateToken", "nonexistent")
+	if errResult == nil || errResult["code"] != "NAMESPACE_NOT_FOUND" {
+		t.Errorf("Expected NAMESPACE_NOT_FOUND, got %v", errResult)
+	}
+}
+
+// Additional test: Invalid rotations, supplied tokens and read-only servers
+func TestMDB002_5A_RotateTokenErrors(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.Cleanup()
+
+	handler := api.NewRPCHandler("1.0.0", env.Store, nil)
+	ctx := context.Background()
+
+	if _, errResult := makeDirectRPCCall(t, handler, "ns.create", "rotate_errors"); errResult != nil {
+		t.Fatalf("Create should succeed: %v", errResult)
+	}
+	defer env.Store.DeleteNamespace(ctx, "rotate_errors")
+
+	for _, args := range [][]interface{}{
+		{},
+		{float64(1)},
+		{""},
+		{"rotate_errors", "token"},
+		{"rotate_errors", map[string]interface{}{"token": float64(1)}},
+		{"rotate_errors", map[string]interface{}{"token": "not-a-token"}},
+	} {
+		if _, errResult := makeDirectRPCCall(t, handler, "ns.rotateToken", args...); errResult == nil || errResult["code"] != "INVALID_REQUEST" {
+			t.Errorf("Expected INVALID_REQUEST for %v, got %v", args, errResult)
+		}
+	}
+
+	// A supplied token for the namespace is installed as is
+	supplied, err := auth.GenerateToken("rotate_errors")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	result, errResult := makeDirectRPCCall(t, handler, "ns.rotateToken", "rotate_errors", map[string]interface{}{"token": supplied})
+	if errResult != nil {
+		t.Fatalf("Rotate should succeed: %v", errResult)
+	}
+	if result.(map[string]interface{})["token"] != supplied {
+		t.Errorf("Expected the supplied token, got %v", result)
+	}
+	if ns, err := env.Store.GetNamespace(ctx, "rotate_errors"); err != nil || ns.TokenHash != auth.HashToken(supplied) {
+		t.Errorf("Stored token hash should match the supplied token (%v)", err)
+	}
+
+	// Read-only servers keep their tokens
+	guard := api.NewWriteGuard(api.NewNamespaceMetadata(env.Store))
+	guard.SetReadOnly(true)
+	handler.SetWriteGuard(guard)
+	if _, errResult := makeDirectRPCCall(t, handler, "ns.rotateToken", "rotate_errors"); errResult == nil || errResult["code"] != "READ_ONLY" {
+		t.Errorf("Expected READ_ONLY in read-only mode, got %v", errResult)
+	}
+	if ns, err := env.Store.GetNamespace(ctx, "rotate_errors"); err != nil || ns.TokenHash != auth.HashToken(supplied) {
+		t.Errorf("Expected the token to be kept in read-only mode (%v)", err)
+	}
+}
@@ -117,6 +117,11 @@ func TestEDGE004_StreamNameEdgeCases(t *testing.T) {
 		{"many dashes", "stream-with-many-dashes", true},
 		{"with numbers", "stream123", true},
 		{"uppercase", "UPPERCASE", true},
+		{"compound ID", "stream-123+456", true},
+		{"empty category", "-123", false},
+		{"empty ID", "stream-", false},
+		{"reserved prefix", "$system-1", false},
+		{"whitespace", "stream-1 2", false},
 	}
 
 	for _, tc := range testCases {
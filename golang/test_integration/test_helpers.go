@@ -498,9 +498,11 @@ func SetupTestServer(t *testing.T) *TestServer {
 
 	// Create SSE handler
 	sseHandler := api.NewSSEHandler(env.Store, pubsub, true)
+	rpcHandler.SetDurableSubscriptions(sseHandler.Durable)
 
 	// Create import handler
 	importHandler := api.NewImportHandler(env.Store)
+	exportHandler := api.NewExportHandler(env.Store, pubsub)
 
 	// Set up HTTP routes
 	mux := http.NewServeMux()
@@ -530,6 +532,10 @@ func SetupTestServer(t *testing.T) *TestServer {
 	importWithAuth := api.AuthMiddleware(env.Store, true)(importHandler)
 	mux.Handle("/import", api.LoggingMiddleware(importWithAuth))
 
+	// Export endpoint with auth middleware (test mode)
+	exportWithAuth := api.AuthMiddleware(env.Store, true)(exportHandler)
+	mux.Handle("/export", api.LoggingMiddleware(exportWithAuth))
+
 	// Start server
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -569,7 +575,9 @@ func SetupIsolatedTestServer(t *testing.T) *TestServer {
 	pubsub := api.NewPubSub()
 	rpcHandler := api.NewRPCHandler("1.4.0", env.Store, pubsub)
 	sseHandler := api.NewSSEHandler(env.Store, pubsub, true)
+	rpcHandler.SetDurableSubscriptions(sseHandler.Durable)
 	importHandler := api.NewImportHandler(env.Store)
+	exportHandler := api.NewExportHandler(env.Store, pubsub)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -582,6 +590,8 @@ func SetupIsolatedTestServer(t *testing.T) *TestServer {
 	mux.HandleFunc("/subscribe", sseHandler.HandleSubscribe)
 	importWithAuth := api.AuthMiddleware(env.Store, true)(importHandler)
 	mux.Handle("/import", api.LoggingMiddleware(importWithAuth))
+	exportWithAuth := api.AuthMiddleware(env.Store, true)(exportHandler)
+	mux.Handle("/export", api.LoggingMiddleware(exportWithAuth))
 
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -0,0 +1,211 @@
+package integration
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eventodb/eventodb/internal/api"
+	"github.com/eventodb/eventodb/internal/store"
+)
+
+// startUDPIngest starts a UDP ingest listener on a random port for the test env
+func startUDPIngest(t *testing.T, env *TestEnv, namespaces []string) (*api.UDPIngest, *api.RPCHandler) {
+	t.Helper()
+
+	handler := api.NewRPCHandler("test", env.Store, api.NewPubSub())
+	ingest := api.NewUDPIngest(handler, api.UDPIngestConfig{
+		Addr:          "127.0.0.1:0",
+		Namespaces:    namespaces,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	if err := ingest.Start(); err != nil {
+		t.Fatalf("Failed to start UDP ingest: %v", err)
+	}
+	return ingest, handler
+}
+
+// addUDPDevice issues a device key with ns.udpDevices.add
+func addUDPDevice(t *testing.T, env *TestEnv, handler *api.RPCHandler, device string) []byte {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/rpc", strings.NewReader(`["ns.udpDevices.add", "`+device+`"]`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+env.Token)
+	w := httptest.NewRecorder()
+	api.AuthMiddleware(env.Store, false)(handler).ServeHTTP(w, req)
+
+	var result struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil || result.Key == "" {
+		t.Fatalf("ns.udpDevices.add failed: %d %v", w.Code, err)
+	}
+	key, err := hex.DecodeString(result.Key)
+	if err != nil {
+		t.Fatalf("Invalid device key: %v", err)
+	}
+	return key
+}
+
+// sendDatagram signs a datagram with key and sends it to the listener
+func sendDatagram(t *testing.T, addr net.Addr, key []byte, dgram api.UDPDatagram) {
+	t.Helper()
+
+	body, err := json.Marshal(dgram)
+	if err != nil {
+		t.Fatalf("Failed to marshal datagram: %v", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	payload := append([]byte(hex.EncodeToString(mac.Sum(nil))+"\n"), body...)
+
+	conn, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatalf("Failed to dial UDP: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(payload); err != nil {
+		t.Fatalf("Failed to send datagram: %v", err)
+	}
+}
+
+// waitForReceived waits until the listener has received n datagrams
+func waitForReceived(ingest *api.UDPIngest, n int64) {
+	deadline := time.Now().Add(2 * time.Second)
+	for ingest.Stats().Received < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForStreamMessages polls the store until the stream has n messages
+func waitForStreamMessages(t *testing.T, st store.Store, namespace, stream string, n int) []*store.Message {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		msgs, err := st.GetStreamMessages(context.Background(), namespace, stream, &store.GetOpts{BatchSize: 100})
+		if err == nil && len(msgs) >= n {
+			return msgs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d messages in %s, got %d", n, stream, len(msgs))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestUDPIngest_WritesBatchedMessages verifies datagram messages are written to the store
+func TestUDPIngest_WritesBatchedMessages(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.Cleanup()
+
+	ingest, handler := startUDPIngest(t, env, []string{env.Namespace})
+	defer ingest.Close()
+	key := addUDPDevice(t, env, handler, "sensor-1")
+
+	sendDatagram(t, ingest.Addr(), key, api.UDPDatagram{
+		Namespace: env.Namespace,
+		Device:    "sensor-1",
+		Seq:       1,
+		Timestamp: time.Now().UnixMilli(),
+		Messages: []api.UDPMessage{
+			{Stream: "telemetry-dev1", Type: "Reading", Data: map[string]interface{}{"t": 21.5}},
+			{Stream: "telemetry-dev1", Type: "Reading", Data: map[string]interface{}{"t": 21.7}},
+		},
+	})
+
+	msgs := waitForStreamMessages(t, env.Store, env.Namespace, "telemetry-dev1", 2)
+	if msgs[0].Type != "Reading" {
+		t.Errorf("Expected type Reading, got %s", msgs[0].Type)
+	}
+	if msgs[1].Position != 1 {
+		t.Errorf("Expected second message at position 1, got %d", msgs[1].Position)
+	}
+}
+
+// TestUDPIngest_DropsNamespaceNotEnabled verifies namespaces outside the allowlist are dropped
+func TestUDPIngest_DropsNamespaceNotEnabled(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.Cleanup()
+
+	ingest, handler := startUDPIngest(t, env, []string{"some-other-namespace"})
+	key := addUDPDevice(t, env, handler, "sensor-1")
+
+	sendDatagram(t, ingest.Addr(), key, api.UDPDatagram{
+		Namespace: env.Namespace,
+		Device:    "sensor-1",
+		Seq:       1,
+		Timestamp: time.Now().UnixMilli(),
+		Messages: []api.UDPMessage{
+			{Stream: "telemetry-dev1", Type: "Reading", Data: map[string]interface{}{"t": 21.5}},
+		},
+	})
+
+	// Wait for the datagram to be received before closing
+	waitForReceived(ingest, 1)
+	ingest.Close()
+
+	stats := ingest.Stats()
+	if stats.Written != 0 || stats.Dropped != 1 {
+		t.Errorf("Expected 0 written and 1 dropped, got %+v", stats)
+	}
+
+	msgs, err := env.Store.GetStreamMessages(context.Background(), env.Namespace, "telemetry-dev1", &store.GetOpts{BatchSize: 100})
+	if err != nil {
+		t.Fatalf("Failed to read stream: %v", err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("Expected no messages, got %d", len(msgs))
+	}
+}
+
+// TestUDPIngest_DropsUnsignedAndReplayed verifies datagrams signed with the
+// wrong key, replayed, or stale are dropped
+func TestUDPIngest_DropsUnsignedAndReplayed(t *testing.T) {
+	env := SetupTestEnv(t)
+	defer env.Cleanup()
+
+	ingest, handler := startUDPIngest(t, env, []string{env.Namespace})
+	key := addUDPDevice(t, env, handler, "sensor-1")
+
+	dgram := api.UDPDatagram{
+		Namespace: env.Namespace,
+		Device:    "sensor-1",
+		Seq:       7,
+		Timestamp: time.Now().UnixMilli(),
+		Messages: []api.UDPMessage{
+			{Stream: "telemetry-dev1", Type: "Reading", Data: map[string]interface{}{"t": 21.5}},
+		},
+	}
+	sendDatagram(t, ingest.Addr(), key, dgram)
+	waitForStreamMessages(t, env.Store, env.Namespace, "telemetry-dev1", 1)
+
+	// The same datagram again
+	sendDatagram(t, ingest.Addr(), key, dgram)
+
+	// Another device's key
+	sendDatagram(t, ingest.Addr(), []byte("0123456789abcdef0123456789abcdef"), api.UDPDatagram{
+		Namespace: env.Namespace, Device: "sensor-1", Seq: 8, Timestamp: time.Now().UnixMilli(), Messages: dgram.Messages,
+	})
+
+	// A timestamp outside the clock skew
+	sendDatagram(t, ingest.Addr(), key, api.UDPDatagram{
+		Namespace: env.Namespace, Device: "sensor-1", Seq: 9, Timestamp: time.Now().Add(time.Minute).UnixMilli(), Messages: dgram.Messages,
+	})
+
+	waitForReceived(ingest, 4)
+	ingest.Close()
+
+	if stats := ingest.Stats(); stats.Written != 1 || stats.Dropped != 3 {
+		t.Errorf("Expected 1 written and 3 dropped, got %+v", stats)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/eventodb/eventodb/internal/api"
 	"github.com/eventodb/eventodb/internal/auth"
@@ -21,6 +22,7 @@ type mockStore struct {
 type mockNamespace struct {
 	ID        string
 	TokenHash string
+	Metadata  map[string]interface{}
 }
 
 func newMockStore() *mockStore {
@@ -37,6 +39,7 @@ func (m *mockStore) GetNamespace(ctx context.Context, id string) (*store.Namespa
 	return &store.Namespace{
 		ID:        ns.ID,
 		TokenHash: ns.TokenHash,
+		Metadata:  ns.Metadata,
 	}, nil
 }
 
@@ -125,8 +128,8 @@ func TestMDB002_2A_T6_MissingTokenReturnsAuthRequired(t *testing.T) {
 	}
 }
 
-// Test MDB002_2A_T7: Test invalid token returns AUTH_INVALID_TOKEN
-func TestMDB002_2A_T7_InvalidTokenReturnsAuthInvalidToken(t *testing.T) {
+// Test MDB002_2A_T7: Test invalid token returns TOKEN_MALFORMED
+func TestMDB002_2A_T7_InvalidTokenReturnsTokenMalformed(t *testing.T) {
 	ms := newMockStore()
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -156,8 +159,8 @@ func TestMDB002_2A_T7_InvalidTokenReturnsAuthInvalidToken(t *testing.T) {
 		t.Fatalf("Failed to decode error response: %v", err)
 	}
 
-	if errResp.Error.Code != "AUTH_INVALID_TOKEN" {
-		t.Errorf("Expected error code 'AUTH_INVALID_TOKEN', got '%s'", errResp.Error.Code)
+	if errResp.Error.Code != "TOKEN_MALFORMED" {
+		t.Errorf("Expected error code 'TOKEN_MALFORMED', got '%s'", errResp.Error.Code)
 	}
 }
 
@@ -268,6 +271,134 @@ func TestMDB002_2A_BearerSchemeRequired(t *testing.T) {
 	}
 }
 
+// Test that an expired token is rejected
+func TestMDB002_2A_ExpiredTokenRejected(t *testing.T) {
+	ms := newMockStore()
+	token, _ := auth.GenerateToken("test-ns")
+	ms.addNamespace("test-ns", auth.HashToken(token))
+	ms.namespaces["test-ns"].Metadata = map[string]interface{}{
+		"token": map[string]interface{}{"expiresAt": time.Now().Add(-time.Minute).UTC().Format(time.RFC3339Nano)},
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called with an expired token")
+	})
+
+	authHandler := api.AuthMiddleware(ms, false)(handler)
+
+	req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewBufferString(`["sys.version"]`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	authHandler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+
+	var errResp struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	json.NewDecoder(w.Body).Decode(&errResp)
+
+	if errResp.Error.Code != "TOKEN_EXPIRED" {
+		t.Errorf("Expected error code 'TOKEN_EXPIRED', got '%s'", errResp.Error.Code)
+	}
+}
+
+// Test that a replaced token is reported as revoked, unlike one never issued
+func TestMDB002_2A_RevokedTokenRejected(t *testing.T) {
+	ms := newMockStore()
+	oldToken, _ := auth.GenerateToken("test-ns")
+	newToken, _ := auth.GenerateToken("test-ns")
+	ms.addNamespace("test-ns", auth.HashToken(newToken))
+	ms.namespaces["test-ns"].Metadata = map[string]interface{}{
+		"revokedTokens": []interface{}{auth.HashToken(oldToken)},
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called with a revoked token")
+	})
+
+	authHandler := api.AuthMiddleware(ms, false)(handler)
+
+	unknownToken, _ := auth.GenerateToken("test-ns")
+	for token, expected := range map[string]string{oldToken: "TOKEN_REVOKED", unknownToken: "AUTH_UNAUTHORIZED"} {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewBufferString(`["sys.version"]`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		authHandler.ServeHTTP(w, req)
+
+		var errResp struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		json.NewDecoder(w.Body).Decode(&errResp)
+
+		if errResp.Error.Code != expected {
+			t.Errorf("Expected error code '%s', got '%s'", expected, errResp.Error.Code)
+		}
+	}
+}
+
+// Test that namespaces suspended with blockReads reject every request, and
+// namespaces suspended for writes only still pass authentication
+func TestMDB002_2A_SuspendedNamespaceRejected(t *testing.T) {
+	ms := newMockStore()
+	token, _ := auth.GenerateToken("test-ns")
+	ms.addNamespace("test-ns", auth.HashToken(token))
+
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	authHandler := api.AuthMiddleware(ms, false)(handler)
+
+	for _, blockReads := range []bool{false, true} {
+		called = false
+		ms.namespaces["test-ns"].Metadata = map[string]interface{}{
+			"suspended": map[string]interface{}{"since": "2024-01-01T00:00:00Z", "reason": "billing", "blockReads": blockReads},
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewBufferString(`["sys.version"]`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		authHandler.ServeHTTP(w, req)
+
+		if !blockReads {
+			if !called || w.Code != http.StatusOK {
+				t.Errorf("Expected request to pass when only writes are suspended, got status %d", w.Code)
+			}
+			continue
+		}
+
+		if called {
+			t.Error("Handler should not be called for a namespace suspended with blockReads")
+		}
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", w.Code)
+		}
+
+		var errResp struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		json.NewDecoder(w.Body).Decode(&errResp)
+
+		if errResp.Error.Code != "NAMESPACE_SUSPENDED" {
+			t.Errorf("Expected error code 'NAMESPACE_SUSPENDED', got '%s'", errResp.Error.Code)
+		}
+	}
+}
+
 // Test that test mode bypasses auth
 func TestMDB002_2A_TestModeBypassesAuth(t *testing.T) {
 	ms := newMockStore()
@@ -0,0 +1,161 @@
+// Benchmark regression gate for EventoDB
+//
+// Compares two `go test -bench` outputs and exits 1 if the median ns/op of
+// any benchmark present in both grew by more than -threshold percent.
+//
+//	go run ./scripts/bench-compare.go -threshold 10 bench/baseline.txt bench/current.txt
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var threshold = flag.Float64("threshold", 10, "Maximum allowed ns/op regression in percent")
+
+// benchLine matches "BenchmarkName-8  1000  1234 ns/op  56 B/op  7 allocs/op"
+var benchLine = regexp.MustCompile(`^(Benchmark\S+?)(?:-\d+)?\s+\d+\s+(.*)$`)
+
+// Result holds the samples of one benchmark across -count runs
+type Result struct {
+	NsPerOp     []float64
+	AllocsPerOp []float64
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: bench-compare [-threshold percent] <baseline.txt> <current.txt>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	baseline, err := parseFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading baseline: %v\n", err)
+		os.Exit(2)
+	}
+	current, err := parseFile(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading current results: %v\n", err)
+		os.Exit(2)
+	}
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("%-60s %14s %14s %9s %12s\n", "benchmark", "old ns/op", "new ns/op", "delta", "allocs/op")
+	var regressions []string
+	for _, name := range names {
+		cur := current[name]
+		old, ok := baseline[name]
+		if !ok {
+			fmt.Printf("%-60s %14s %14.0f %9s %12s\n", name, "-", median(cur.NsPerOp), "new", allocs(nil, cur))
+			continue
+		}
+
+		oldNs, newNs := median(old.NsPerOp), median(cur.NsPerOp)
+		delta := (newNs - oldNs) / oldNs * 100
+		mark := ""
+		if delta > *threshold {
+			mark = "  REGRESSION"
+			regressions = append(regressions, name)
+		}
+		fmt.Printf("%-60s %14.0f %14.0f %+8.1f%% %12s%s\n", name, oldNs, newNs, delta, allocs(old, cur), mark)
+	}
+	var missing []string
+	for name := range baseline {
+		if _, ok := current[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		fmt.Printf("%-60s missing from current results\n", name)
+	}
+
+	if len(regressions) > 0 {
+		fmt.Printf("\n%d benchmark(s) regressed by more than %.0f%%:\n", len(regressions), *threshold)
+		for _, name := range regressions {
+			fmt.Printf("  %s\n", name)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("\nNo regressions above %.0f%%\n", *threshold)
+}
+
+// parseFile reads the benchmark results in a `go test -bench` output file
+func parseFile(path string) (map[string]*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := make(map[string]*Result)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := benchLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		r, ok := results[m[1]]
+		if !ok {
+			r = &Result{}
+			results[m[1]] = r
+		}
+
+		// Metrics come as "<value> <unit>" pairs
+		fields := strings.Fields(m[2])
+		for i := 0; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				r.NsPerOp = append(r.NsPerOp, value)
+			case "allocs/op":
+				r.AllocsPerOp = append(r.AllocsPerOp, value)
+			}
+		}
+	}
+	return results, scanner.Err()
+}
+
+// median returns the median sample, which is robust against a noisy run
+func median(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// allocs formats the allocs/op change, or "-" without -benchmem
+func allocs(old, cur *Result) string {
+	if len(cur.AllocsPerOp) == 0 {
+		return "-"
+	}
+	if old == nil || len(old.AllocsPerOp) == 0 {
+		return fmt.Sprintf("%.0f", median(cur.AllocsPerOp))
+	}
+	return fmt.Sprintf("%.0f -> %.0f", median(old.AllocsPerOp), median(cur.AllocsPerOp))
+}